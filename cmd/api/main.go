@@ -1,31 +1,119 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/innovelabs/microtools-go/internal/abuse"
+	"github.com/innovelabs/microtools-go/internal/app"
+	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/maintenance"
+	"github.com/innovelabs/microtools-go/internal/retention"
 	"github.com/innovelabs/microtools-go/internal/router"
-	"github.com/joho/godotenv"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"github.com/innovelabs/microtools-go/internal/uploads"
+	"github.com/innovelabs/microtools-go/internal/wifirotation"
 )
 
+// shutdownTimeout bounds how long requests in flight may take to finish
+// once the server is asked to stop
+const shutdownTimeout = 15 * time.Second
+
 func main() {
-	// Load environment variables
-	err := godotenv.Load()
+	// Load configuration once; later reads are served from the cached struct.
+	// Unset stores only disable the features that depend on them, but an
+	// invalid setting stops startup with every problem listed.
+	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Error loading .env file")
+		log.Fatalf("Configuration not loaded: %v", err)
 	}
+	// Every log line, log.Printf ones included, goes through the redacting
+	// structured logger; requests log with their ID, see LoggingMiddleware
+	slog.SetDefault(logging.New(os.Stderr, cfg.LogFormat == config.LogFormatJSON))
 
-	// Initialize databases (currently commented out)
-	// cfg := config.LoadConfig()
-	// handlers.MongoClient = database.InitMongoDB()
-	// redisClient := database.InitRedis()
+	// Tracing stays a no-op unless an OTLP exporter is configured via OTEL_*
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		log.Printf("Tracing disabled: %v", err)
+		shutdownTracing = func(context.Context) error { return nil }
+	}
 
-	log.Println("Database initialized")
+	// Connects the configured stores; unconfigured ones disable their features
+	application := app.New(cfg)
+	if application.Sweeper != nil {
+		go application.Sweeper.Run(context.Background(), retention.SweepInterval)
+	}
+	if application.Uploads != nil {
+		go application.Uploads.Run(context.Background(), uploads.SweepInterval)
+	}
+	if application.URLReputation != nil {
+		go application.URLReputation.Run(context.Background(), abuse.ReloadInterval)
+	}
+	// The forwarder stops after the server, so the hits of the last
+	// requests are sent too
+	counterCtx, stopCounter := context.WithCancel(context.Background())
+	counterDone := make(chan struct{})
+	if application.Forwarder != nil {
+		go func() {
+			application.Forwarder.Run(counterCtx)
+			close(counterDone)
+		}()
+	} else {
+		close(counterDone)
+	}
+	go application.Maintenance.Run(context.Background(), maintenance.RefreshInterval)
+	if application.WifiRotation != nil {
+		go application.WifiRotation.Run(context.Background(), wifirotation.TickInterval)
+	}
+
+	// IP lookups share the databases opened once above, so a missing one
+	// stops startup rather than failing every lookup
+	geoDB, err := application.Handlers.GeoIP.Metadata()
+	if err != nil {
+		log.Fatalf("GeoIP database not loaded: %v", err)
+	}
+	if age := time.Since(geoDB.BuiltAt); age > time.Duration(cfg.GeoDBMaxAgeDays)*24*time.Hour {
+		log.Printf("Warning: GeoLite database built %s is %d days old (limit %d)", geoDB.DatabaseDate, int(age.Hours()/24), cfg.GeoDBMaxAgeDays)
+	}
 
 	// Setup router
-	r := router.SetupRouter()
+	r := router.NewHandler(application, router.Options{LegacyRoutes: cfg.LegacyRoutes})
 
 	// Start server
-	log.Println("Server started on :8000")
-	log.Fatal(http.ListenAndServe(":8000", r))
+	server := &http.Server{
+		Addr:         ":" + strconv.Itoa(cfg.Port),
+		Handler:      r,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+	stopped, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	served := make(chan error, 1)
+	go func() { served <- server.ListenAndServe() }()
+	log.Printf("Server started on %s", server.Addr)
+	select {
+	case err = <-served:
+	case <-stopped.Done():
+		log.Printf("Shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		err = server.Shutdown(ctx)
+		cancel()
+	}
+	stopCounter()
+	<-counterDone
+	application.Handlers.GeoIP.Close()
+	shutdownTracing(context.Background())
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+)
+
+const (
+	batchFormatNDJSON = "ndjson"
+	batchFormatCSV    = "csv"
+
+	maxBatchLineBytes = 64 * 1024
+)
+
+// batchValidator validates one input value and reports whether it is valid
+type batchValidator func(value string) (result interface{}, valid bool, err error)
+
+func batchEmail(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("batch email", stderr)
+	var opts emailOptions
+	opts.register(fs)
+	input := registerBatchFlags(fs)
+	if err := parseBatchArgs(fs, args); err != nil {
+		return exitError
+	}
+	validate, err := opts.validator()
+	if err != nil {
+		return fail(stdout, stderr, false, err)
+	}
+	return runBatch(stdin, stdout, stderr, input, "email", func(value string) (interface{}, bool, error) {
+		result := validate(value)
+		return result, emailValid(result), nil
+	})
+}
+
+func batchIBAN(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("batch iban", stderr)
+	input := registerBatchFlags(fs)
+	if err := parseBatchArgs(fs, args); err != nil {
+		return exitError
+	}
+	return runBatch(stdin, stdout, stderr, input, "iban", func(value string) (interface{}, bool, error) {
+		result, err := validation.ValidateIBAN(context.Background(), value)
+		if err != nil {
+			return nil, false, err
+		}
+		return result, result.IsValid, nil
+	})
+}
+
+func batchIP(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("batch ip", stderr)
+	geoDB := fs.String("geoip-db", validation.DefaultGeoDBPath, "path of the GeoLite2 City database")
+	asnDB := fs.String("geoip-asn-db", "", "path of the GeoLite2 ASN database adding the autonomous system")
+	input := registerBatchFlags(fs)
+	if err := parseBatchArgs(fs, args); err != nil {
+		return exitError
+	}
+	geoIP, err := validation.OpenGeoIPService(*geoDB, validation.WithASNDatabase(*asnDB))
+	if err != nil {
+		return fail(stdout, stderr, false, err)
+	}
+	defer geoIP.Close()
+	return runBatch(stdin, stdout, stderr, input, "ip", func(value string) (interface{}, bool, error) {
+		result, err := geoIP.Lookup(context.Background(), value)
+		if err != nil {
+			return nil, false, err
+		}
+		return result, true, nil
+	})
+}
+
+// batchInput describes how batch input is read
+type batchInput struct {
+	format string
+	column string
+}
+
+func registerBatchFlags(fs *flag.FlagSet) *batchInput {
+	input := &batchInput{}
+	fs.StringVar(&input.format, "format", batchFormatNDJSON, "input format: ndjson or csv")
+	fs.StringVar(&input.column, "column", "", "CSV column holding the values (default the tool name, e.g. email)")
+	return input
+}
+
+func parseBatchArgs(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintf(fs.Output(), "%s reads its input from stdin and takes no arguments\n", fs.Name())
+		return errUsage
+	}
+	return nil
+}
+
+// runBatch validates every input value in order and writes one NDJSON
+// result per value, in the shape of the API batch endpoints. The exit code
+// is exitInvalid when any value is invalid or could not be read.
+func runBatch(stdin io.Reader, stdout, stderr io.Writer, input *batchInput, field string, validate batchValidator) int {
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+	enc := json.NewEncoder(out)
+
+	code := exitValid
+	emit := func(index int, value string, decodeErr error) {
+		res := models.BatchResult{Index: index}
+		if decodeErr != nil {
+			res.Error = decodeErr.Error()
+			code = exitInvalid
+		} else if result, valid, err := validate(value); err != nil {
+			res.Error = err.Error()
+			code = exitInvalid
+		} else {
+			res.Result = result
+			if !valid {
+				code = exitInvalid
+			}
+		}
+		enc.Encode(res)
+	}
+
+	var err error
+	switch input.format {
+	case batchFormatNDJSON:
+		err = readBatchNDJSON(stdin, field, emit)
+	case batchFormatCSV:
+		column := input.column
+		if column == "" {
+			column = field
+		}
+		err = readBatchCSV(stdin, column, emit)
+	default:
+		err = fmt.Errorf("unsupported format %q: must be %s or %s", input.format, batchFormatNDJSON, batchFormatCSV)
+	}
+	if err != nil {
+		out.Flush()
+		return fail(stdout, stderr, false, err)
+	}
+	return code
+}
+
+// readBatchNDJSON calls emit for every non-empty line. As in the API, a
+// line may be a JSON object holding field, a JSON string, or the bare value.
+func readBatchNDJSON(r io.Reader, field string, emit func(index int, value string, err error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxBatchLineBytes)
+	index := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		value, err := decodeBatchLine(line, field)
+		emit(index, value, err)
+		index++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("line %d: %w", index, err)
+	}
+	return nil
+}
+
+func decodeBatchLine(line []byte, field string) (string, error) {
+	switch line[0] {
+	case '{':
+		var obj map[string]interface{}
+		if err := json.Unmarshal(line, &obj); err != nil {
+			return "", errors.New("invalid JSON line")
+		}
+		value, ok := obj[field].(string)
+		if !ok {
+			return "", fmt.Errorf("line must contain a %q string", field)
+		}
+		return strings.TrimSpace(value), nil
+	case '"':
+		var value string
+		if err := json.Unmarshal(line, &value); err != nil {
+			return "", errors.New("invalid JSON string")
+		}
+		return strings.TrimSpace(value), nil
+	default:
+		return string(line), nil
+	}
+}
+
+// readBatchCSV calls emit for the named column of every row after the header
+func readBatchCSV(r io.Reader, column string, emit func(index int, value string, err error)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading CSV header: %w", err)
+	}
+	col := -1
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			col = i
+			break
+		}
+	}
+	if col < 0 {
+		return fmt.Errorf("CSV header has no %q column", column)
+	}
+
+	for index := 0; ; index++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading CSV: %w", err)
+		}
+		if col >= len(record) {
+			emit(index, "", fmt.Errorf("row has no %q column", column))
+			continue
+		}
+		emit(index, strings.TrimSpace(record[col]), nil)
+	}
+}
@@ -0,0 +1,129 @@
+// Command microtools runs the Micro API validators and generators offline,
+// calling the service packages directly instead of going through HTTP.
+//
+//	microtools validate email|iban|ip [flags] <value>
+//	microtools generate qr|barcode [flags] <data>
+//	microtools batch email|iban|ip [flags] < input
+//
+// Flags use the API option names, and --json prints the API response
+// shapes. The exit code is 0 for a valid result, 1 for an invalid one and
+// 2 for errors.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+const (
+	exitValid   = 0
+	exitInvalid = 1
+	exitError   = 2
+)
+
+// errUsage is returned after usage has already been printed
+var errUsage = errors.New("invalid usage")
+
+const usage = `Usage:
+  microtools validate email|iban|ip [flags] <value>
+  microtools generate qr|barcode [flags] <data>
+  microtools batch email|iban|ip [flags] < input
+
+Run "microtools <command> <tool> -h" for the flags of a tool.
+Exit codes: 0 valid, 1 invalid, 2 error.
+`
+
+func main() {
+	// The services log for the server; results are all the CLI reports
+	log.SetOutput(io.Discard)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) < 2 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+
+	var commands map[string]func([]string, io.Reader, io.Writer, io.Writer) int
+	switch args[0] {
+	case "validate":
+		commands = map[string]func([]string, io.Reader, io.Writer, io.Writer) int{
+			"email": validateEmail,
+			"iban":  validateIBAN,
+			"ip":    validateIP,
+		}
+	case "generate":
+		commands = map[string]func([]string, io.Reader, io.Writer, io.Writer) int{
+			"qr":      generateQR,
+			"barcode": generateBarcode,
+		}
+	case "batch":
+		commands = map[string]func([]string, io.Reader, io.Writer, io.Writer) int{
+			"email": batchEmail,
+			"iban":  batchIBAN,
+			"ip":    batchIP,
+		}
+	}
+
+	command, ok := commands[args[1]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0]+" "+args[1], usage)
+		return exitError
+	}
+	return command(args[2:], stdin, stdout, stderr)
+}
+
+// newFlagSet creates the flag set of a tool command; parse errors are
+// reported by the caller through parseArgs
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("microtools "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parseArgs parses args and returns the single positional value
+func parseArgs(fs *flag.FlagSet, args []string, valueName string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", errUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintf(fs.Output(), "%s takes exactly one %s argument\n", fs.Name(), valueName)
+		return "", errUsage
+	}
+	return fs.Arg(0), nil
+}
+
+// writeJSON writes v as a single JSON line
+func writeJSON(w io.Writer, v interface{}) {
+	json.NewEncoder(w).Encode(v)
+}
+
+// fail reports err on stderr, or as the API error shape on stdout with
+// --json, and returns exitError
+func fail(stdout, stderr io.Writer, asJSON bool, err error) int {
+	if errors.Is(err, errUsage) {
+		return exitError
+	}
+	if asJSON {
+		writeJSON(stdout, models.APIError{Message: err.Error()})
+	} else {
+		fmt.Fprintf(stderr, "microtools: %v\n", err)
+	}
+	return exitError
+}
+
+// printWarnings writes non-fatal service warnings to stderr
+func printWarnings(stderr io.Writer, list []models.Warning) {
+	for _, warning := range list {
+		fmt.Fprintf(stderr, "warning: %s: %s\n", warning.Code, warning.Message)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var geoDB = filepath.Join("..", "..", "assets", "geolite-2-city.mmdb")
+
+// TestCommands runs each command in-process and compares its stdout with
+// testdata/golden/<name>.golden
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		code  int
+	}{
+		{"email", []string{"validate", "email", "--offline", "ada@example.com"}, "", exitValid},
+		{"email-json", []string{"validate", "email", "--offline", "--json", "ada@example.com"}, "", exitValid},
+		{"email-invalid", []string{"validate", "email", "--offline", "not-an-email"}, "", exitInvalid},
+		{"iban", []string{"validate", "iban", "DE89 3704 0044 0532 0130 00"}, "", exitValid},
+		{"iban-json", []string{"validate", "iban", "--json", "GB82WEST12345698765432"}, "", exitValid},
+		{"iban-invalid", []string{"validate", "iban", "DE89370400440532013001"}, "", exitInvalid},
+		{"ip", []string{"validate", "ip", "--geoip-db", geoDB, "81.2.69.142"}, "", exitValid},
+		{"ip-invalid", []string{"validate", "ip", "--geoip-db", geoDB, "--json", "999.1.1.1"}, "", exitInvalid},
+		{"batch-iban", []string{"batch", "iban"},
+			"DE89370400440532013000\n\"GB82 WEST 1234 5698 7654 32\"\n{\"iban\":\"DE89370400440532013001\"}\n{\"nope\":1}\n", exitInvalid},
+		{"batch-email-csv", []string{"batch", "email", "--offline", "--format", "csv", "--column", "Mail"},
+			"name,mail\nAda,ada@example.com\nBob,bob@example.org\n", exitValid},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+		if code != tt.code {
+			t.Errorf("%s: exit code %d, want %d; stderr: %s", tt.name, code, tt.code, stderr.String())
+		}
+
+		path := filepath.Join("testdata", "golden", tt.name+".golden")
+		if *update {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, stdout.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%v (run go test -update to create it)", err)
+		}
+		if stdout.String() != string(want) {
+			t.Errorf("%s: output differs from %s:\n%s", tt.name, path, stdout.String())
+		}
+	}
+}
+
+func TestGenerateCommands(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"generate", "qr", "--deterministic", "-o", filepath.Join(dir, "qr.png"), "https://example.com"},
+		{"generate", "barcode", "--type", "EAN-13", "--include_text", "-o", filepath.Join(dir, "barcode.png"), "400638133393"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, nil, &stdout, &stderr); code != exitValid {
+			t.Fatalf("%v: exit code %d: %s", args, code, stderr.String())
+		}
+		f, err := os.Open(args[len(args)-2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%v: %v", args, err)
+		}
+	}
+
+	// SVG goes to stdout by default
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"generate", "qr", "--format", "svg", "hello"}, nil, &stdout, &stderr); code != exitValid ||
+		!strings.Contains(stdout.String(), "<svg") {
+		t.Errorf("SVG to stdout: exit code %d, %d bytes: %s", code, stdout.Len(), stderr.String())
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"validate"},
+		{"validate", "phone", "123"},
+		{"validate", "iban"},
+		{"validate", "iban", "--bogus", "DE89370400440532013000"},
+		{"validate", "email", "--checks", "smtp", "ada@example.com"},
+		{"validate", "ip", "--geoip-db", "missing.mmdb", "8.8.8.8"},
+		{"batch", "iban", "extra"},
+		{"batch", "iban", "--format", "xml"},
+		{"generate", "barcode", "--type", "EAN-13", "not digits"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, strings.NewReader(""), &stdout, &stderr); code != exitError {
+			t.Errorf("%v: exit code %d, want %d", args, code, exitError)
+		}
+	}
+}
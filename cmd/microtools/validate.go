@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/dnscache"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/warnings"
+)
+
+// emailOptions are the flags shared by validate email and batch email
+type emailOptions struct {
+	checks     string
+	offline    bool
+	disposable string
+}
+
+func (o *emailOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.checks, "checks", "", "comma-separated checks to run: syntax, domain, mx, disposable (default all)")
+	fs.BoolVar(&o.offline, "offline", false, "skip the network checks (domain and mx)")
+	fs.StringVar(&o.disposable, "disposable-list", "", "file of disposable domains, one per line, replacing the built-in list")
+}
+
+// validator returns the email validation function the options select
+func (o *emailOptions) validator() (func(string) models.EmailValidation, error) {
+	var names []string
+	if o.checks != "" {
+		names = strings.Split(o.checks, ",")
+	}
+	checks, err := validation.ParseEmailChecks(names)
+	if err != nil {
+		return nil, err
+	}
+	if o.disposable != "" {
+		if _, err := validation.LoadDisposableDomains(o.disposable); err != nil {
+			return nil, err
+		}
+	}
+	var checkDomain validation.DomainChecker
+	if !o.offline {
+		// batches often repeat a domain, so answers are cached per run
+		checkDomain = validation.NewDomainChecker(dnscache.New(net.DefaultResolver, clock.System(), 0))
+	}
+	return func(email string) models.EmailValidation {
+		return validation.ValidateEmailChecks(context.Background(), strings.TrimSpace(email), checks, checkDomain)
+	}, nil
+}
+
+// emailValid reports whether every check that ran passed. Disposable
+// addresses are reported but still valid.
+func emailValid(result models.EmailValidation) bool {
+	for _, passed := range []*bool{result.IsSyntaxValid, result.IsDomainValid, result.MxRecordsFound} {
+		if passed != nil && !*passed {
+			return false
+		}
+	}
+	return true
+}
+
+func validateEmail(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate email", stderr)
+	var opts emailOptions
+	opts.register(fs)
+	asJSON := fs.Bool("json", false, "print the API response JSON")
+	email, err := parseArgs(fs, args, "email")
+	if err != nil {
+		return exitError
+	}
+	validate, err := opts.validator()
+	if err != nil {
+		return fail(stdout, stderr, *asJSON, err)
+	}
+
+	result := validate(email)
+	valid := emailValid(result)
+	if *asJSON {
+		writeJSON(stdout, map[string]interface{}{"validationResult": result})
+	} else {
+		fmt.Fprintf(stdout, "%s: %s\n", result.Email, validity(valid))
+		printCheck(stdout, "syntax", result.IsSyntaxValid)
+		printCheck(stdout, "domain", result.IsDomainValid)
+		printCheck(stdout, "mx", result.MxRecordsFound)
+		if result.IsDisposable != nil {
+			fmt.Fprintf(stdout, "  disposable: %t\n", *result.IsDisposable)
+		}
+		if len(result.SkippedChecks) > 0 {
+			fmt.Fprintf(stdout, "  skipped: %s\n", strings.Join(result.SkippedChecks, ", "))
+		}
+	}
+	return exitCode(valid)
+}
+
+func validateIBAN(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate iban", stderr)
+	asJSON := fs.Bool("json", false, "print the API response JSON")
+	iban, err := parseArgs(fs, args, "IBAN")
+	if err != nil {
+		return exitError
+	}
+
+	collector := warnings.New()
+	result, err := validation.ValidateIBAN(warnings.NewContext(context.Background(), collector), strings.TrimSpace(iban))
+	if err != nil {
+		return fail(stdout, stderr, *asJSON, err)
+	}
+	result.Warnings = collector.List()
+	printWarnings(stderr, result.Warnings)
+	if *asJSON {
+		writeJSON(stdout, map[string]interface{}{"validationResult": result})
+	} else {
+		fmt.Fprintf(stdout, "%s: %s\n", result.FormattedIBAN, validity(result.IsValid))
+		if result.CountryName != "" {
+			fmt.Fprintf(stdout, "  country: %s (%s)\n", result.CountryName, result.CountryCode)
+		}
+		if result.BankCode != "" {
+			fmt.Fprintf(stdout, "  bank code: %s\n", result.BankCode)
+		}
+		fmt.Fprintf(stdout, "  format: %s, country supported: %t, length: %s, checksum: %s\n",
+			passed(result.IsFormatValid), result.IsCountrySupported, passed(result.IsLengthValid), passed(result.IsChecksumValid))
+	}
+	return exitCode(result.IsValid)
+}
+
+func validateIP(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate ip", stderr)
+	geoDB := fs.String("geoip-db", validation.DefaultGeoDBPath, "path of the GeoLite2 City database")
+	asnDB := fs.String("geoip-asn-db", "", "path of the GeoLite2 ASN database adding the autonomous system")
+	asJSON := fs.Bool("json", false, "print the API response JSON")
+	ip, err := parseArgs(fs, args, "IP")
+	if err != nil {
+		return exitError
+	}
+
+	geoIP := validation.NewGeoIPService(*geoDB, validation.WithASNDatabase(*asnDB))
+	defer geoIP.Close()
+	result, err := geoIP.Lookup(context.Background(), strings.TrimSpace(ip))
+	if err != nil {
+		code := exitInvalid
+		if errors.Is(err, validation.ErrGeoDBUnavailable) {
+			code = exitError
+		}
+		if *asJSON {
+			writeJSON(stdout, models.APIError{Message: err.Error()})
+		} else {
+			fmt.Fprintf(stderr, "microtools: %v\n", err)
+		}
+		return code
+	}
+
+	if *asJSON {
+		writeJSON(stdout, map[string]interface{}{"validationResult": result})
+	} else {
+		if result.NotFound {
+			fmt.Fprintf(stdout, "%s: not in the database\n", result.IP)
+			fmt.Fprintf(stdout, "  database: %s\n", result.Meta.DatabaseDate)
+			return exitValid
+		}
+		var place []string
+		for _, part := range []*string{result.City, result.Region, result.Country} {
+			if part != nil {
+				place = append(place, *part)
+			}
+		}
+		if len(place) == 0 {
+			fmt.Fprintf(stdout, "%s: no location data\n", result.IP)
+		} else {
+			fmt.Fprintf(stdout, "%s: %s (%s level)\n", result.IP, strings.Join(place, ", "), result.Granularity)
+		}
+		if result.Latitude != nil && result.Longitude != nil {
+			fmt.Fprintf(stdout, "  location: %.4f, %.4f\n", *result.Latitude, *result.Longitude)
+		}
+		if result.Timezone != nil {
+			fmt.Fprintf(stdout, "  timezone: %s\n", *result.Timezone)
+		}
+		fmt.Fprintf(stdout, "  database: %s\n", result.Meta.DatabaseDate)
+	}
+	return exitValid
+}
+
+func printCheck(w io.Writer, name string, result *bool) {
+	if result != nil {
+		fmt.Fprintf(w, "  %s: %s\n", name, passed(*result))
+	}
+}
+
+func validity(valid bool) string {
+	if valid {
+		return "valid"
+	}
+	return "invalid"
+}
+
+func passed(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "failed"
+}
+
+func exitCode(valid bool) int {
+	if valid {
+		return exitValid
+	}
+	return exitInvalid
+}
@@ -0,0 +1,118 @@
+// Command schemagen writes the frozen JSON Schemas of the public API
+// responses from their Go types, refusing changes that would break
+// clients written against the committed schemas.
+//
+//	schemagen [-dir internal/schema/frozen] [-check] [-force]
+//
+// -check writes nothing and exits 1 when any schema differs from its
+// file, so CI shows every response change in review. Without it, added
+// optional fields are written; removed, renamed or retyped fields and new
+// always-present fields are refused unless -force deliberately updates
+// the files.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/schema"
+)
+
+func main() {
+	dir := flag.String("dir", "internal/schema/frozen", "directory of the frozen schema files")
+	check := flag.Bool("check", false, "report differences without writing")
+	force := flag.Bool("force", false, "write incompatible changes")
+	flag.Parse()
+
+	if err := run(*dir, *check, *force); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, check, force bool) error {
+	var stale, incompatible []string
+	writes := map[string][]byte{}
+	for _, name := range schema.Names() {
+		current, err := schema.Current(name)
+		if err != nil {
+			return err
+		}
+		data, err := schema.Marshal(current)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, name+".json")
+		committed, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			fmt.Printf("%s: new schema\n", name)
+			stale = append(stale, name)
+			writes[path] = data
+			continue
+		case err != nil:
+			return err
+		case bytes.Equal(committed, data):
+			continue
+		}
+
+		stale = append(stale, name)
+		writes[path] = data
+		frozen, err := schema.Frozen(name)
+		if err != nil {
+			// The file is unreadable as a schema; show the rewrite in review
+			fmt.Printf("%s: %v\n", name, err)
+			continue
+		}
+		changes := schema.Compare(frozen, current)
+		for _, added := range changes.Added {
+			fmt.Printf("%s: added %s\n", name, added)
+		}
+		for _, change := range changes.Incompatible {
+			fmt.Printf("%s: INCOMPATIBLE %s\n", name, change)
+			incompatible = append(incompatible, name)
+		}
+	}
+
+	// A frozen file without a registered response is a removed schema
+	var removed []string
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		if !slices.Contains(schema.Names(), name) {
+			fmt.Printf("%s: INCOMPATIBLE schema removed\n", name)
+			removed = append(removed, file)
+			incompatible = append(incompatible, name)
+		}
+	}
+
+	switch {
+	case check && (len(stale) > 0 || len(removed) > 0):
+		return errors.New(`frozen schemas are out of date: run "go generate ./internal/schema" and commit the result`)
+	case check:
+		return nil
+	case len(incompatible) > 0 && !force:
+		return errors.New("incompatible response changes: keep the fields, or rerun with -force to break clients deliberately")
+	}
+	for path, data := range writes {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+	}
+	for _, file := range removed {
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package abuse
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+)
+
+// hostsFileNames are the names hosts-format lists map to the local machine
+// for their own use, not entries to block
+var hostsFileNames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+}
+
+// domainSet is a set of blocked domains. A domain blocks its subdomains.
+type domainSet map[string]bool
+
+// parseBlocklist reads a domain list: one domain per line, or hosts file
+// lines ("0.0.0.0 phish.example") whose names are all blocked. Text after
+// "#" is a comment and leading "*." or "." are ignored.
+func parseBlocklist(r io.Reader) (domainSet, error) {
+	set := domainSet{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if net.ParseIP(fields[0]) != nil && len(fields) > 1 {
+			fields = fields[1:]
+		}
+		for _, field := range fields {
+			if domain := NormalizeDomain(field); domain != "" && !hostsFileNames[domain] {
+				set[domain] = true
+			}
+		}
+	}
+	return set, scanner.Err()
+}
+
+// NormalizeDomain lower-cases a domain and drops wildcard prefixes and the
+// trailing dot. It returns "" for text that is not a host name.
+func NormalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(domain, "*.")
+	domain = strings.Trim(domain, ".")
+	if domain == "" || strings.ContainsAny(domain, "/:@ \t") {
+		return ""
+	}
+	return domain
+}
+
+// match returns the entry of set that blocks host: host itself or its
+// closest listed parent domain
+func (set domainSet) match(host string) (string, bool) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host, set[host]
+	}
+	for domain := host; domain != ""; {
+		if set[domain] {
+			return domain, true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			break
+		}
+		domain = parent
+	}
+	return "", false
+}
@@ -0,0 +1,181 @@
+// Package abuse refuses to generate codes for content known to be used
+// for abuse. The URL reputation check matches the host of a QR code's URL
+// against a blocklist file, the domains operators block by hand, and
+// optionally Google Safe Browsing.
+package abuse
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/repository"
+)
+
+const (
+	// SourceBlocklist and SourceSafeBrowsing name what flagged a URL
+	SourceBlocklist    = "blocklist"
+	SourceSafeBrowsing = "safe_browsing"
+
+	// ReloadInterval is how often Run reloads a changed blocklist file and
+	// the manual entries
+	ReloadInterval = 30 * time.Second
+)
+
+// Verdict is the outcome of a URL reputation check
+type Verdict struct {
+	Flagged bool
+	// Source is SourceBlocklist or SourceSafeBrowsing for a flagged URL
+	Source string
+	// Match is the blocklisted domain or the Safe Browsing threat type
+	Match string
+}
+
+// Checker checks URLs against the configured reputation sources. Sources
+// that fail let the URL through, so generation does not depend on them.
+type Checker struct {
+	path   string
+	manual repository.BlocklistStore
+	lookup *SafeBrowsing
+
+	mu       sync.RWMutex
+	file     domainSet
+	fileStat os.FileInfo
+	entries  domainSet
+}
+
+// NewChecker creates a Checker reading the blocklist file at path ("" for
+// none) and the domains in manual (nil for none), and looking URLs up with
+// lookup when it is not nil. The lists are loaded before it returns; a
+// file that cannot be read is an error.
+func NewChecker(ctx context.Context, path string, manual repository.BlocklistStore, lookup *SafeBrowsing) (*Checker, error) {
+	c := &Checker{path: path, manual: manual, lookup: lookup, file: domainSet{}, entries: domainSet{}}
+	if path != "" {
+		if _, err := c.reloadFile(); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.ReloadManual(ctx); err != nil {
+		log.Printf("Manual URL blocklist not loaded: %v", err)
+	}
+	return c, nil
+}
+
+// CheckURL returns the verdict for rawURL. It is not flagged when it does
+// not parse; the caller validates URLs.
+func (c *Checker) CheckURL(ctx context.Context, rawURL string) Verdict {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return Verdict{}
+	}
+	host := u.Hostname()
+
+	c.mu.RLock()
+	domain, listed := c.file.match(host)
+	if !listed {
+		domain, listed = c.entries.match(host)
+	}
+	c.mu.RUnlock()
+	if listed {
+		return Verdict{Flagged: true, Source: SourceBlocklist, Match: domain}
+	}
+
+	if c.lookup == nil {
+		return Verdict{}
+	}
+	threat, err := c.lookup.Lookup(ctx, rawURL)
+	if err != nil {
+		log.Printf("URL reputation lookup failed, URL allowed: %v", err)
+		return Verdict{}
+	}
+	if threat != "" {
+		return Verdict{Flagged: true, Source: SourceSafeBrowsing, Match: threat}
+	}
+	return Verdict{}
+}
+
+// ReloadManual reloads the domains operators blocked by hand, e.g. after
+// one was added
+func (c *Checker) ReloadManual(ctx context.Context) error {
+	if c.manual == nil {
+		return nil
+	}
+	stored, err := c.manual.List(ctx)
+	if err != nil {
+		return err
+	}
+	entries := make(domainSet, len(stored))
+	for _, entry := range stored {
+		entries[entry.Domain] = true
+	}
+	c.mu.Lock()
+	c.entries = entries
+	c.mu.Unlock()
+	return nil
+}
+
+// reloadFile reads the blocklist file when it changed since it was last
+// read, reporting whether it did. A file that fails to parse keeps the
+// previous list.
+func (c *Checker) reloadFile() (bool, error) {
+	stat, err := os.Stat(c.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read URL blocklist: %w", err)
+	}
+	c.mu.RLock()
+	previous := c.fileStat
+	c.mu.RUnlock()
+	if previous != nil && stat.ModTime().Equal(previous.ModTime()) && stat.Size() == previous.Size() {
+		return false, nil
+	}
+
+	f, err := os.Open(c.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read URL blocklist: %w", err)
+	}
+	defer f.Close()
+	set, err := parseBlocklist(f)
+	if err != nil {
+		return false, fmt.Errorf("failed to read URL blocklist: %w", err)
+	}
+	c.mu.Lock()
+	c.file = set
+	c.fileStat = stat
+	c.mu.Unlock()
+	return true, nil
+}
+
+// FileSize returns how many domains the blocklist file lists
+func (c *Checker) FileSize() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.file)
+}
+
+// Run reloads the blocklist file when it changes and the manual entries
+// every interval until ctx is done, logging failures
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if c.path != "" {
+			if reloaded, err := c.reloadFile(); err != nil {
+				log.Printf("URL blocklist not reloaded: %v", err)
+			} else if reloaded {
+				log.Printf("URL blocklist reloaded: %d domains", c.FileSize())
+			}
+		}
+		if err := c.ReloadManual(ctx); err != nil {
+			log.Printf("Manual URL blocklist not reloaded: %v", err)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package abuse_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/abuse"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+func TestCheckerBlocklistFile(t *testing.T) {
+	checker, err := abuse.NewChecker(context.Background(), "testdata/blocklist.txt", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checker.FileSize() != 6 {
+		t.Errorf("FileSize = %d, want the 6 listed domains", checker.FileSize())
+	}
+	tests := []struct {
+		url   string
+		match string
+	}{
+		{"https://phish.example/login", "phish.example"},
+		{"https://LOGIN-VERIFY.example./reset?user=ada", "login-verify.example"},
+		{"http://wallet-drainer.example", "wallet-drainer.example"},
+		// A domain blocks its subdomains, whatever the list's prefix
+		{"https://cdn.eu.malware.test/payload.apk", "malware.test"},
+		{"https://pixel.tracker.test", "tracker.test"},
+		{"http://203.0.113.9:8080/", "203.0.113.9"},
+		// but not its parents or siblings
+		{"https://example/", ""},
+		{"https://notphish.example/", ""},
+		{"https://phish.example.com/", ""},
+		// Hosts file names for the local machine are not entries
+		{"http://localhost:8080/", ""},
+		{"http://127.0.0.1/", ""},
+		{"not a URL", ""},
+	}
+	for _, tt := range tests {
+		verdict := checker.CheckURL(context.Background(), tt.url)
+		want := abuse.Verdict{}
+		if tt.match != "" {
+			want = abuse.Verdict{Flagged: true, Source: abuse.SourceBlocklist, Match: tt.match}
+		}
+		if verdict != want {
+			t.Errorf("%s: verdict %+v, want %+v", tt.url, verdict, want)
+		}
+	}
+
+	if _, err := abuse.NewChecker(context.Background(), "testdata/missing.txt", nil, nil); err == nil {
+		t.Error("a missing blocklist file is not an error")
+	}
+}
+
+func TestCheckerReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("phish.example\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	manual := testutil.NewBlocklistStore()
+	manual.Add(context.Background(), models.BlockedDomain{Domain: "scam.example"})
+	checker, err := abuse.NewChecker(context.Background(), path, manual, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flagged := func(rawURL string) bool {
+		return checker.CheckURL(context.Background(), rawURL).Flagged
+	}
+	if !flagged("https://phish.example") || !flagged("https://www.scam.example") {
+		t.Fatal("file and manual entries are not both loaded")
+	}
+
+	// Manual entries apply once reloaded
+	manual.Add(context.Background(), models.BlockedDomain{Domain: "fraud.example"})
+	manual.Remove(context.Background(), "scam.example")
+	if err := checker.ReloadManual(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !flagged("https://fraud.example") || flagged("https://scam.example") {
+		t.Error("manual changes are not applied after ReloadManual")
+	}
+
+	// A changed file is picked up by Run
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go checker.Run(ctx, 10*time.Millisecond)
+	if err := os.WriteFile(path, []byte("0.0.0.0 other-phish.example\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !flagged("https://other-phish.example") {
+		if time.Now().After(deadline) {
+			t.Fatal("the changed blocklist file was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if flagged("https://phish.example") {
+		t.Error("a domain removed from the file is still blocked")
+	}
+	if !flagged("https://fraud.example") {
+		t.Error("reloading the file dropped the manual entries")
+	}
+}
+
+// safeBrowsingServer answers Lookup API requests, listing URLs containing
+// "phish" as SOCIAL_ENGINEERING, and counts the requests
+func safeBrowsingServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Method != http.MethodPost || r.URL.Query().Get("key") != "test-key" {
+			t.Errorf("request %s %s", r.Method, r.URL)
+		}
+		var body struct {
+			ThreatInfo struct {
+				ThreatEntries []struct{ URL string }
+			}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.ThreatInfo.ThreatEntries) != 1 {
+			t.Errorf("request body: %+v, %v", body, err)
+			return
+		}
+		if strings.Contains(body.ThreatInfo.ThreatEntries[0].URL, "phish") {
+			w.Write([]byte(`{"matches":[{"threatType":"SOCIAL_ENGINEERING","cacheDuration":"300s"}]}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSafeBrowsing(t *testing.T) {
+	var requests atomic.Int32
+	server := safeBrowsingServer(t, &requests)
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	lookup := abuse.NewSafeBrowsing(server.URL, "test-key", time.Second, testutil.NewCache(clock))
+	checker, err := abuse.NewChecker(context.Background(), "testdata/blocklist.txt", nil, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := abuse.Verdict{Flagged: true, Source: abuse.SourceSafeBrowsing, Match: "SOCIAL_ENGINEERING"}
+	for range 3 {
+		if verdict := checker.CheckURL(context.Background(), "https://bank.example/phish"); verdict != want {
+			t.Fatalf("verdict %+v, want %+v", verdict, want)
+		}
+		if verdict := checker.CheckURL(context.Background(), "https://bank.example/"); verdict.Flagged {
+			t.Fatalf("clean URL: verdict %+v", verdict)
+		}
+	}
+	if requests.Load() != 2 {
+		t.Errorf("%d lookups, want one per URL with the verdicts cached", requests.Load())
+	}
+	// The blocklist is checked first
+	if verdict := checker.CheckURL(context.Background(), "https://phish.example/"); verdict.Source != abuse.SourceBlocklist || requests.Load() != 2 {
+		t.Errorf("listed URL: verdict %+v after %d lookups", verdict, requests.Load())
+	}
+
+	// A threat is cached for the duration the API gives, a clean URL for longer
+	clock.Advance(301 * time.Second)
+	checker.CheckURL(context.Background(), "https://bank.example/phish")
+	checker.CheckURL(context.Background(), "https://bank.example/")
+	if requests.Load() != 3 {
+		t.Errorf("%d lookups after 301s, want the threat looked up again", requests.Load())
+	}
+}
+
+func TestSafeBrowsingFailsOpen(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	var failures atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failures.Add(1)
+		http.Error(w, "quota exhausted", http.StatusTooManyRequests)
+	}))
+	defer failing.Close()
+
+	for name, endpoint := range map[string]string{"timeout": slow.URL, "error status": failing.URL, "unreachable": "http://127.0.0.1:1"} {
+		cache := testutil.NewCache(testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+		lookup := abuse.NewSafeBrowsing(endpoint, "secret-key", 50*time.Millisecond, cache)
+		start := time.Now()
+		if _, err := lookup.Lookup(context.Background(), "https://phish.test/"); err == nil || strings.Contains(err.Error(), "secret-key") {
+			t.Errorf("%s: err = %v, want an error without the API key", name, err)
+		}
+		checker, err := abuse.NewChecker(context.Background(), "", nil, lookup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if verdict := checker.CheckURL(context.Background(), "https://phish.test/"); verdict.Flagged {
+			t.Errorf("%s: verdict %+v, want the URL let through", name, verdict)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: took %s", name, elapsed)
+		}
+	}
+	// Failures are not cached as verdicts
+	if failures.Load() != 2 {
+		t.Errorf("%d requests to the failing API, want every lookup sent", failures.Load())
+	}
+
+	// A failing verdict cache does not stop lookups
+	var requests atomic.Int32
+	server := safeBrowsingServer(t, &requests)
+	cache := testutil.NewCache(testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	cache.Err = context.DeadlineExceeded
+	lookup := abuse.NewSafeBrowsing(server.URL, "test-key", time.Second, cache)
+	if threat, err := lookup.Lookup(context.Background(), "https://phish.test/"); err != nil || threat != "SOCIAL_ENGINEERING" {
+		t.Errorf("without the cache: %q, %v", threat, err)
+	}
+}
@@ -0,0 +1,323 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/innovelabs/microtools-go/internal/abuse"
+	"github.com/innovelabs/microtools-go/internal/cache"
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/database"
+	"github.com/innovelabs/microtools-go/internal/dnscache"
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/hitforward"
+	"github.com/innovelabs/microtools-go/internal/jobs"
+	"github.com/innovelabs/microtools-go/internal/maintenance"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/policy"
+	"github.com/innovelabs/microtools-go/internal/quota"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/resolver"
+	"github.com/innovelabs/microtools-go/internal/retention"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/services/notify"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/shortener"
+	"github.com/innovelabs/microtools-go/internal/signing"
+	"github.com/innovelabs/microtools-go/internal/tenant"
+	"github.com/innovelabs/microtools-go/internal/toolstatus"
+	"github.com/innovelabs/microtools-go/internal/uploads"
+	"github.com/innovelabs/microtools-go/internal/wifirotation"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// redisKeyPrefix namespaces this service's keys in a shared Redis
+const redisKeyPrefix = "microapi:"
+
+// App is the dependency container the router is built from
+type App struct {
+	// Config is nil when no configuration was loaded
+	Config   *config.Config
+	Handlers *handlers.Handlers
+	Counter  middleware.HitCounter
+	// Forwarder delivers Counter's hits to the counter API; nil without
+	// an API key, when hits are discarded
+	Forwarder *hitforward.Forwarder
+	// Sweeper purges deleted users; nil without user storage
+	Sweeper *retention.Sweeper
+	// Uploads deletes expired uploads when run; nil when uploads are
+	// disabled
+	Uploads *uploads.Store
+	// URLReputation reloads its blocklists when run; nil when the URL
+	// reputation check is disabled
+	URLReputation *abuse.Checker
+	// Maintenance reloads the maintenance windows other replicas set when
+	// run
+	Maintenance *maintenance.Switch
+	// WifiRotation rotates the WiFi passwords that are due when run; nil
+	// without MongoDB or WIFI_ROTATION_KEY
+	WifiRotation *wifirotation.Rotator
+	// ValidatorLimiter and GeneratorLimiter limit the requests of each
+	// client IP to the validation and generation routes; nil without a
+	// configuration
+	ValidatorLimiter *middleware.RateLimiter
+	GeneratorLimiter *middleware.RateLimiter
+	// LiteLimiter limits the requests of each client IP to the lite
+	// routes to policy.Lite; nil without a configuration, when the router
+	// counts in memory
+	LiteLimiter *middleware.RateLimiter
+	// TenantLimiters limit the requests of each client IP of a tenant
+	// with a requests_per_minute; nil without a configuration, when the
+	// router counts in memory
+	TenantLimiters *middleware.TenantLimiters
+}
+
+// New wires the application services for cfg, which may be nil. Stores
+// that are not configured or fail to connect are left out and logged, so
+// only the features depending on them are disabled.
+func New(cfg *config.Config) *App {
+	dnsCacheSize := config.DefaultDNSCacheSize
+	geoDBPath := validation.DefaultGeoDBPath
+	var geoOpts []validation.GeoIPOption
+	var doh *resolver.DoH
+	var upstream dnscache.Resolver = net.DefaultResolver
+	if cfg != nil {
+		dnsCacheSize = cfg.DNSCacheSize
+		if cfg.GeoDBPath != "" {
+			geoDBPath = cfg.GeoDBPath
+		}
+		geoOpts = append(geoOpts, validation.WithASNDatabase(cfg.GeoASNDBPath))
+		doh = resolver.NewDoH(cfg.DoHEndpoint, cfg.DoHMethod, cfg.DoHTimeout)
+		if cfg.DNSResolver == config.DNSResolverDoH {
+			upstream = doh
+		}
+	}
+	dnsCache := dnscache.New(upstream, clock.System(), dnsCacheSize)
+	h := &handlers.Handlers{
+		Config:   cfg,
+		GeoIP:    validation.NewGeoIPService(geoDBPath, geoOpts...),
+		Barcodes: generator.NewDefaultBarcodeService(),
+		Labels:   generator.NewDefaultLabelService(),
+		Jobs:     jobs.NewStore(clock.System()),
+		Clock:    clock.System(),
+		Status:   toolstatus.NewTracker(clock.System(), middleware.CounterNames()),
+		Hits:     middleware.NewHitStats(middleware.NopHitCounter(), clock.System()),
+
+		EmailDomains: validation.NewDomainChecker(dnsCache),
+		DNSCache:     dnsCache,
+		ShareLimiter: middleware.NewRateLimiter(nil, "", handlers.SharesPerHour, time.Hour, clock.System()),
+		Maintenance:  maintenance.NewSwitch(nil, clock.System(), middleware.MaintenanceTools()),
+	}
+	a := &App{Config: cfg, Handlers: h, Counter: h.Hits, Maintenance: h.Maintenance}
+	if cfg == nil {
+		return a
+	}
+	h.DoHEmailDomains = validation.NewDomainChecker(dnsCache.Via(doh))
+	h.Mailboxes = validation.NewSMTPVerifier(dnsCache, validation.NewSMTPDialer(), validation.SMTPOptions{
+		HeloName: heloName(cfg.BaseURL),
+		Deadline: cfg.SMTPCheckDeadline,
+	})
+
+	if cfg.CounterApiKey != "" {
+		a.Forwarder = hitforward.New(hitforward.NewCounterAPI(cfg.CounterApiKey), clock.System(), hitforward.Options{
+			WALPath:       cfg.CounterWALPath,
+			WALMaxBytes:   int64(cfg.CounterWALMaxBytes),
+			FlushInterval: cfg.CounterFlushInterval,
+		})
+		h.Hits = middleware.NewHitStats(a.Forwarder, clock.System())
+		a.Counter = h.Hits
+		h.Counter = a.Forwarder
+	}
+
+	if cfg.DisposableDomainsPath != "" {
+		if n, err := validation.LoadDisposableDomains(cfg.DisposableDomainsPath); err != nil {
+			log.Printf("Using the built-in disposable email domains: %v", err)
+		} else {
+			log.Printf("Loaded %d disposable email domains from %s", n, cfg.DisposableDomainsPath)
+		}
+	}
+
+	if cfg.BarcodeFontsDir != "" {
+		if fonts, err := generator.LoadFontSet(cfg.BarcodeFontsDir); err != nil {
+			log.Printf("Custom barcode fonts disabled: %v", err)
+		} else {
+			h.Barcodes = generator.NewBarcodeService(fonts)
+			h.Labels = generator.NewLabelService(fonts)
+		}
+	}
+	h.Mailer = notify.NewSMTPMailSender(cfg)
+
+	if cfg.UploadsDir == "" {
+		log.Printf("Resumable uploads disabled: UPLOADS_DIR is empty")
+	} else if objects, err := uploads.NewDirStore(cfg.UploadsDir); err != nil {
+		log.Printf("Resumable uploads disabled: %v", err)
+	} else {
+		a.Uploads = uploads.NewStore(objects, clock.System(), int64(cfg.UploadMaxBytes), cfg.UploadTTL)
+		h.Uploads = a.Uploads
+	}
+
+	if len(cfg.SigningKeyFiles) > 0 {
+		if signer, err := signing.LoadSigner(cfg.SigningKeyFiles); err != nil {
+			log.Printf("Response signing disabled: %v", err)
+		} else {
+			h.Signer = signer
+		}
+	}
+
+	if cfg.MongoURI != "" {
+		if client, err := database.InitMongoDB(cfg); err != nil {
+			log.Printf("User storage disabled: %v", err)
+		} else {
+			h.Users = newMongoUserRepository(client)
+			// Users, shares and short links have no fallback store
+			h.Dependencies = append(h.Dependencies, handlers.Dependency{
+				Name:     "mongodb",
+				Required: true,
+				Pinger:   handlers.PingFunc(func(ctx context.Context) error { return client.Ping(ctx, nil) }),
+			})
+			h.Shares = newMongoShareStore(client)
+			h.Blocklist = repository.NewMongoBlocklistStore(client)
+			a.WifiRotation = newWifiRotator(cfg, client, h.Users)
+			h.WifiRotation = a.WifiRotation
+			if h.Users != nil {
+				var owned []retention.OwnedData
+				if a.WifiRotation != nil {
+					owned = append(owned, a.WifiRotation)
+				}
+				a.Sweeper = retention.NewSweeper(h.Users, clock.System(), cfg.UserDeletionGrace, owned...)
+			}
+			h.ShortURLs = shortener.New(repository.NewMongoShortURLStore(client), clock.System())
+			h.Tenants = repository.NewMongoTenantStore(client)
+			h.TenantRegistry = tenant.NewRegistry(h.Tenants, clock.System(), tenant.CacheTTL)
+		}
+	}
+	var rateLimitRedis *redis.Client
+	if cfg.RedisURI != "" {
+		if client, err := database.InitRedis(cfg); err != nil {
+			log.Printf("Cache disabled: %v", err)
+		} else {
+			rateLimitRedis = client
+			// Rate limits, the cache and maintenance windows fall back to
+			// each instance's memory or direct lookups
+			h.Dependencies = append(h.Dependencies, handlers.Dependency{
+				Name:   "redis",
+				Pinger: handlers.PingFunc(func(ctx context.Context) error { return client.Ping(ctx).Err() }),
+			})
+			h.Cache = cache.NewRedisCache(client, redisKeyPrefix)
+			h.Pixels = quota.NewLimiter(client, redisKeyPrefix, quota.Limits{
+				Anonymous: int64(cfg.PixelQuotaAnonymous),
+				Free:      int64(cfg.PixelQuotaFree),
+				Pro:       int64(cfg.PixelQuotaPro),
+			}, clock.System())
+			if h.Shares == nil {
+				h.Shares = repository.NewCacheShareStore(h.Cache)
+			}
+			h.Maintenance = maintenance.NewSwitch(h.Cache, clock.System(), middleware.MaintenanceTools())
+			a.Maintenance = h.Maintenance
+		}
+	}
+	// Without Redis each instance counts its own requests
+	a.ValidatorLimiter = middleware.NewRateLimiter(rateLimitRedis, redisKeyPrefix+"validators:", cfg.ValidatorRateLimit, time.Minute, clock.System())
+	a.GeneratorLimiter = middleware.NewRateLimiter(rateLimitRedis, redisKeyPrefix+"generators:", cfg.GeneratorRateLimit, time.Minute, clock.System())
+	a.TenantLimiters = middleware.NewTenantLimiters(rateLimitRedis, redisKeyPrefix+"tenants:", clock.System())
+	a.LiteLimiter = middleware.NewRateLimiter(rateLimitRedis, redisKeyPrefix+"lite:", policy.Lite.RequestsPerMinute, time.Minute, clock.System())
+	h.ShareLimiter = middleware.NewRateLimiter(rateLimitRedis, redisKeyPrefix+"shares:", handlers.SharesPerHour, time.Hour, clock.System())
+	if h.Cache == nil {
+		log.Printf("Maintenance mode applies to this instance only: Redis is not configured")
+	}
+	if h.Shares == nil {
+		log.Printf("Result sharing disabled: neither MongoDB nor Redis is configured")
+	}
+	if cfg.URLReputationCheck {
+		a.URLReputation = newURLReputation(cfg, h)
+		h.URLReputation = a.URLReputation
+	}
+	return a
+}
+
+// newURLReputation creates the URL reputation check from the sources cfg
+// configures, or returns nil when the blocklist file cannot be read
+func newURLReputation(cfg *config.Config, h *handlers.Handlers) *abuse.Checker {
+	var lookup *abuse.SafeBrowsing
+	if cfg.SafeBrowsingAPIKey != "" {
+		lookup = abuse.NewSafeBrowsing(cfg.SafeBrowsingEndpoint, cfg.SafeBrowsingAPIKey, cfg.SafeBrowsingTimeout, h.Cache)
+	}
+	if cfg.URLBlocklistPath == "" && h.Blocklist == nil && lookup == nil {
+		log.Printf("URL reputation check enabled without a blocklist file, MongoDB or Safe Browsing key: no URL is flagged")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	checker, err := abuse.NewChecker(ctx, cfg.URLBlocklistPath, h.Blocklist, lookup)
+	if err != nil {
+		log.Printf("URL reputation check disabled: %v", err)
+		return nil
+	}
+	return checker
+}
+
+// newWifiRotator keeps rotating WiFi networks in MongoDB, or returns nil
+// without a rotation key or when their index cannot be created
+func newWifiRotator(cfg *config.Config, client *mongo.Client, users repository.UserRepository) *wifirotation.Rotator {
+	if cfg.WifiRotationKey == "" {
+		log.Printf("Rotating WiFi networks disabled: WIFI_ROTATION_KEY is empty")
+		return nil
+	}
+	// Validate has checked the key decodes to 32 bytes
+	key, _ := base64.StdEncoding.DecodeString(cfg.WifiRotationKey)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	store, err := repository.NewMongoWifiProfileStore(ctx, client)
+	if err != nil {
+		log.Printf("Rotating WiFi networks disabled: %v", err)
+		return nil
+	}
+	rotator, err := wifirotation.NewRotator(store, users, key, clock.System())
+	if err != nil {
+		log.Printf("Rotating WiFi networks disabled: %v", err)
+		return nil
+	}
+	return rotator
+}
+
+// newMongoUserRepository stores users in MongoDB, or returns nil when the
+// unique index on email cannot be created, e.g. because existing documents
+// share an email
+func newMongoUserRepository(client *mongo.Client) repository.UserRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	users, err := repository.NewMongoUserRepository(ctx, client)
+	if err != nil {
+		log.Printf("User storage disabled: %v", err)
+		return nil
+	}
+	return users
+}
+
+// newMongoShareStore keeps shared results in MongoDB, or returns nil when
+// its expiry index cannot be created so Redis is used instead
+func newMongoShareStore(client *mongo.Client) repository.ShareStore {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	shares, err := repository.NewMongoShareStore(ctx, client)
+	if err != nil {
+		log.Printf("Sharing results in MongoDB disabled: %v", err)
+		return nil
+	}
+	return shares
+}
+
+// heloName returns the host of baseURL, which names the service in the
+// HELO of SMTP mailbox checks
+func heloName(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Hostname() == "" {
+		return "localhost"
+	}
+	return u.Hostname()
+}
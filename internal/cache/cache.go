@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Cache defines a string key/value store with expiry
+type Cache interface {
+	// Get returns the value for key and whether it was present
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+}
+
+type redisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisCache creates a Cache that stores keys under prefix in Redis
+func NewRedisCache(client *redis.Client, prefix string) Cache {
+	return &redisCache{client: client, prefix: prefix}
+}
+
+// Get returns the value for key and whether it was present
+func (c *redisCache) Get(ctx context.Context, key string) (value string, found bool, err error) {
+	ctx, span := tracing.Start(ctx, "redis.get", redisAttributes("GET")...)
+	defer func() { tracing.End(span, err) }()
+
+	value, err = c.client.Get(ctx, c.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// Set stores value for key until ttl elapses
+func (c *redisCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	ctx, span := tracing.Start(ctx, "redis.set", redisAttributes("SET")...)
+	err := c.client.Set(ctx, c.prefix+key, value, ttl).Err()
+	tracing.End(span, err)
+	return err
+}
+
+// redisAttributes describes a Redis call without recording its key
+func redisAttributes(operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("db.system", "redis"),
+		attribute.String("db.operation.name", operation),
+	}
+}
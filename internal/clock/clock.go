@@ -0,0 +1,19 @@
+package clock
+
+import "time"
+
+// Clock abstracts the current time so time-dependent code can be tested
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+// System returns a Clock backed by time.Now
+func System() Clock {
+	return systemClock{}
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
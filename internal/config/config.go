@@ -1,33 +1,307 @@
 package config
 
 import (
-	"log"
-	"os"
+	"slices"
+	"strings"
+	"time"
+)
 
-	"github.com/joho/godotenv"
+const (
+	// DefaultPort is the HTTP port the API listens on
+	DefaultPort = 8000
+	// DefaultReadTimeout, DefaultWriteTimeout and DefaultIdleTimeout bound
+	// the HTTP server's connections
+	DefaultReadTimeout  = 15 * time.Second
+	DefaultWriteTimeout = 60 * time.Second
+	DefaultIdleTimeout  = 120 * time.Second
+	// DefaultExamplesDir is where example fixtures live when EXAMPLES_DIR is unset
+	DefaultExamplesDir = "web/examples"
+	// DefaultGeoDBPath is the GeoLite2 City database shipped with the service
+	DefaultGeoDBPath = "./assets/geolite-2-city.mmdb"
+	// DefaultGeoDBMaxAgeDays is the geolocation database age that triggers a startup warning
+	DefaultGeoDBMaxAgeDays = 45
+	// DefaultPageCacheMaxAge is the Cache-Control max-age in seconds for rendered pages
+	DefaultPageCacheMaxAge = 300
+	// DefaultSMTPPort is used when SMTP_HOST is set without SMTP_PORT
+	DefaultSMTPPort = 25
+	// DefaultBaseURL is the public origin of the service
+	DefaultBaseURL = "https://microapi.innovelabs.net"
+	// DefaultUserDeletionGrace is how long deleted users are kept before
+	// they are purged
+	DefaultUserDeletionGrace = 30 * 24 * time.Hour
+	// DefaultDNSCacheSize is the number of DNS answers kept in memory
+	DefaultDNSCacheSize = 10000
+	// DNSResolverSystem and DNSResolverDoH are the DNS_RESOLVER values
+	DNSResolverSystem = "system"
+	DNSResolverDoH    = "doh"
+	// LogFormatText and LogFormatJSON are the LOG_FORMAT values
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+	// DefaultDoHEndpoint, DefaultDoHMethod and DefaultDoHTimeout configure
+	// the DNS-over-HTTPS resolver
+	DefaultDoHEndpoint = "https://cloudflare-dns.com/dns-query"
+	DefaultDoHMethod   = "GET"
+	DefaultDoHTimeout  = 5 * time.Second
+	// DefaultEmailDNSTimeout bounds the domain and MX lookups of one email
+	// address
+	DefaultEmailDNSTimeout = 3 * time.Second
+	// DefaultSMTPCheckDeadline bounds the SMTP mailbox check of one email
+	// address
+	DefaultSMTPCheckDeadline = 10 * time.Second
+	// DefaultCounterWALPath and DefaultCounterWALMaxBytes configure the
+	// log of usage counter increments the counter API has not received
+	DefaultCounterWALPath     = "./data/counter-wal.jsonl"
+	DefaultCounterWALMaxBytes = 16 << 20
+	// DefaultCounterFlushInterval is how often usage counters are sent
+	DefaultCounterFlushInterval = 15 * time.Second
+	// DefaultPixelQuotaAnonymous, DefaultPixelQuotaFree and
+	// DefaultPixelQuotaPro are the daily pixel credits of each plan
+	DefaultPixelQuotaAnonymous = 1_000_000_000
+	DefaultPixelQuotaFree      = 5_000_000_000
+	DefaultPixelQuotaPro       = 50_000_000_000
+	// DefaultUploadsDir, DefaultUploadMaxBytes and DefaultUploadTTL
+	// configure resumable uploads
+	DefaultUploadsDir     = "./data/uploads"
+	DefaultUploadMaxBytes = 50 << 20
+	DefaultUploadTTL      = 24 * time.Hour
+	// DefaultValidatorMaxBodyBytes and DefaultGeneratorMaxBodyBytes cap
+	// the request bodies of the validation and generation routes
+	DefaultValidatorMaxBodyBytes = 64 << 10
+	DefaultGeneratorMaxBodyBytes = 1 << 20
+	// DefaultCSVConvertMaxBytes and DefaultCSVConvertMaxRows cap the input
+	// of CSV conversions
+	DefaultCSVConvertMaxBytes = 10 << 20
+	DefaultCSVConvertMaxRows  = 100_000
+	// DefaultValidatorRateLimit and DefaultGeneratorRateLimit are the
+	// requests per minute one client IP may send to each route group
+	DefaultValidatorRateLimit = 60
+	DefaultGeneratorRateLimit = 20
+	// DefaultSafeBrowsingEndpoint and DefaultSafeBrowsingTimeout configure
+	// the Safe Browsing lookups of the URL reputation check
+	DefaultSafeBrowsingEndpoint = "https://safebrowsing.googleapis.com/v4/threatMatches:find"
+	DefaultSafeBrowsingTimeout  = 2 * time.Second
 )
 
-// Config struct holds all the configuration variables.
+// DefaultCorrelationHeaders are the request headers echoed and passed on
+// when CORRELATION_HEADERS is unset
+var DefaultCorrelationHeaders = []string{"X-Correlation-ID", "X-Request-ID"}
+
+// Config struct holds all the configuration variables. Each field is read
+// from the environment variable in its env tag, or from the lower-case key
+// of the same name in CONFIG_FILE.
 type Config struct {
-	MongoURI      string
-	RedisURI      string
-	JWTSecret     string
-	CounterApiKey string
-}
+	MongoURI      string `env:"MONGO_URI"`
+	RedisURI      string `env:"REDIS_URI"`
+	JWTSecret     string `env:"JWT_SECRET"`
+	CounterApiKey string `env:"COUNTER_API_KEY"`
+	// JWTIssuer and JWTAudience are the iss and aud claims of issued
+	// tokens, required of every token presented; "" leaves them out.
+	// Setting either invalidates the tokens issued before.
+	JWTIssuer   string `env:"JWT_ISSUER"`
+	JWTAudience string `env:"JWT_AUDIENCE"`
+	// CounterWALPath is the write-ahead log of counter increments that
+	// could not be delivered, replayed when the counter API recovers; ""
+	// keeps them in memory only. CounterWALMaxBytes caps it, dropping the
+	// oldest increments first.
+	CounterWALPath     string `env:"COUNTER_WAL_PATH"`
+	CounterWALMaxBytes int    `env:"COUNTER_WAL_MAX_BYTES"`
+	// CounterFlushInterval is how often the hits summed in memory are
+	// sent to the counter API
+	CounterFlushInterval time.Duration `env:"COUNTER_FLUSH_INTERVAL"`
 
-// LoadConfig loads the environment variables from .env file and returns a Config object.
-func LoadConfig() *Config {
-	// Load environment variables from the .env file
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file")
-	}
+	// AppEnv names the deployment environment, e.g. "production"
+	AppEnv string `env:"APP_ENV"`
+	// LogFormat selects how log lines are written: "text" (key=value
+	// pairs) or "json", one object per line
+	LogFormat string `env:"LOG_FORMAT"`
+
+	// Port is the HTTP port the API listens on
+	Port int `env:"PORT"`
+	// ReadTimeout, WriteTimeout and IdleTimeout bound the HTTP server's
+	// connections
+	ReadTimeout  time.Duration `env:"READ_TIMEOUT"`
+	WriteTimeout time.Duration `env:"WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `env:"IDLE_TIMEOUT"`
+
+	// RecordExamples enables the development-only example recorder
+	RecordExamples bool `env:"RECORD_EXAMPLES"`
+	// ExamplesDir is where recorded example fixtures are read and written
+	ExamplesDir string `env:"EXAMPLES_DIR"`
+	// GeoDBPath is the GeoLite2 City database used for IP geolocation
+	GeoDBPath string `env:"GEODB_PATH"`
+	// GeoASNDBPath is the optional GeoLite2 ASN database adding the
+	// autonomous system to IP lookups; "" leaves it out
+	GeoASNDBPath string `env:"GEODB_ASN_PATH"`
+	// GeoDBMaxAgeDays is how old the GeoLite database may be before a warning is logged
+	GeoDBMaxAgeDays int `env:"GEODB_MAX_AGE_DAYS"`
+	// PageCacheMaxAge is the Cache-Control max-age in seconds for rendered pages
+	PageCacheMaxAge int `env:"PAGE_CACHE_MAX_AGE"`
+	// BaseURL is the public origin used in canonical links, sitemap.xml
+	// and robots.txt
+	BaseURL string `env:"BASE_URL"`
+	// BasePath is the path prefix the service is mounted under behind a
+	// reverse proxy that keeps it, e.g. /microtools; "" serves it at the
+	// root. Routes match under it and generated links and URLs carry it.
+	BasePath string `env:"BASE_PATH"`
+
+	// CorrelationHeaders are the client headers carrying trace or
+	// correlation IDs that are echoed on responses and kept on jobs
+	CorrelationHeaders []string `env:"CORRELATION_HEADERS"`
+
+	// LegacyRoutes serves the retired root-package API paths as deprecated aliases
+	LegacyRoutes bool `env:"LEGACY_ROUTES"`
+
+	// UserDeletionGrace is how long a deleted user's document is kept
+	// before the retention sweeper removes it
+	UserDeletionGrace time.Duration `env:"USER_DELETION_GRACE"`
+	// AdminEmails are the users whose access tokens may use the
+	// /api/v1/admin routes; empty closes them
+	AdminEmails []string `env:"ADMIN_EMAILS"`
+	// AutoVerify marks newly registered users as verified (development only)
+	AutoVerify bool `env:"AUTO_VERIFY"`
+	// SMTPHost, SMTPPort and SMTPFrom configure the relay for verification mail
+	SMTPHost string `env:"SMTP_HOST"`
+	SMTPPort int    `env:"SMTP_PORT"`
+	SMTPFrom string `env:"SMTP_FROM"`
+
+	// BarcodeFontsDir holds extra TTF/OTF fonts selectable for barcode text
+	BarcodeFontsDir string `env:"BARCODE_FONTS_DIR"`
+
+	// DNSCacheSize bounds the in-memory DNS cache of email validation
+	DNSCacheSize int `env:"DNS_CACHE_SIZE"`
+	// DNSResolver selects how email domains are resolved: "system" or
+	// "doh", which sends every lookup to DoHEndpoint
+	DNSResolver string `env:"DNS_RESOLVER"`
+	// DoHEndpoint is the RFC 8484 endpoint queried with DoHMethod (GET or
+	// POST); DoHTimeout bounds each query
+	DoHEndpoint string        `env:"DOH_ENDPOINT"`
+	DoHMethod   string        `env:"DOH_METHOD"`
+	DoHTimeout  time.Duration `env:"DOH_TIMEOUT"`
+	// EmailDNSTimeout bounds the domain and MX lookups of one email
+	// address, whichever resolver answers them
+	EmailDNSTimeout time.Duration `env:"EMAIL_DNS_TIMEOUT"`
+	// SMTPCheckDeadline bounds the SMTP mailbox check of one email address,
+	// from the MX lookup to the last reply
+	SMTPCheckDeadline time.Duration `env:"SMTP_CHECK_DEADLINE"`
+	// DisposableDomainsPath is a file of disposable email domains, one per
+	// line, replacing the built-in list; "" keeps the built-in list
+	DisposableDomainsPath string `env:"DISPOSABLE_DOMAINS_PATH"`
+
+	// SigningKeyFiles are PEM files of EC P-256 keys for response signing;
+	// the first signs, all are published in the JWKS
+	SigningKeyFiles []string `env:"SIGNING_KEY_FILES"`
+
+	// PixelQuotaAnonymous, PixelQuotaFree and PixelQuotaPro are the daily
+	// pixel credits QR, barcode and label generation may use per caller
+	// of each plan, counted in Redis; 0 is unlimited
+	PixelQuotaAnonymous int `env:"PIXEL_QUOTA_ANONYMOUS"`
+	PixelQuotaFree      int `env:"PIXEL_QUOTA_FREE"`
+	PixelQuotaPro       int `env:"PIXEL_QUOTA_PRO"`
+
+	// UploadsDir holds the data of resumable uploads; "" disables them.
+	// UploadMaxBytes caps one upload and UploadTTL is how long it is kept
+	// after it is created.
+	UploadsDir     string        `env:"UPLOADS_DIR"`
+	UploadMaxBytes int           `env:"UPLOAD_MAX_BYTES"`
+	UploadTTL      time.Duration `env:"UPLOAD_TTL"`
+
+	// ValidatorMaxBodyBytes and GeneratorMaxBodyBytes cap the request
+	// bodies of the single-value validation routes and of the generation
+	// routes; larger bodies are refused with 413
+	ValidatorMaxBodyBytes int `env:"VALIDATOR_MAX_BODY_BYTES"`
+	GeneratorMaxBodyBytes int `env:"GENERATOR_MAX_BODY_BYTES"`
+
+	// CSVConvertMaxBytes caps the request body of CSV conversions and
+	// CSVConvertMaxRows the rows of their input
+	CSVConvertMaxBytes int `env:"CSV_CONVERT_MAX_BYTES"`
+	CSVConvertMaxRows  int `env:"CSV_CONVERT_MAX_ROWS"`
 
-	// Retrieve the variables from the environment
+	// ValidatorRateLimit and GeneratorRateLimit are the requests per
+	// minute one client IP may send to the validation and generation
+	// routes, shared through Redis when it is configured; 0 disables the
+	// limit. TrustedProxy reads the client IP from X-Forwarded-For, for a
+	// service only reachable through a proxy that sets it.
+	ValidatorRateLimit int  `env:"VALIDATOR_RATE_LIMIT"`
+	GeneratorRateLimit int  `env:"GENERATOR_RATE_LIMIT"`
+	TrustedProxy       bool `env:"TRUSTED_PROXY"`
+
+	// URLReputationCheck refuses QR codes for URLs whose host is on
+	// URLBlocklistPath (a hosts or domain list, reloaded when it changes)
+	// or the operators' list in MongoDB, or that Safe Browsing flags when
+	// SafeBrowsingAPIKey is set
+	URLReputationCheck   bool          `env:"URL_REPUTATION_CHECK"`
+	URLBlocklistPath     string        `env:"URL_BLOCKLIST_PATH"`
+	SafeBrowsingAPIKey   string        `env:"SAFE_BROWSING_API_KEY"`
+	SafeBrowsingEndpoint string        `env:"SAFE_BROWSING_ENDPOINT"`
+	SafeBrowsingTimeout  time.Duration `env:"SAFE_BROWSING_TIMEOUT"`
+
+	// WifiRotationKey is the base64 AES-256 key encrypting the current
+	// passwords of rotating WiFi networks at rest; "" disables them
+	WifiRotationKey string `env:"WIFI_ROTATION_KEY"`
+}
+
+// Default returns the configuration used when nothing is set, for tests and
+// as the base Load applies the file and environment to
+func Default() *Config {
 	return &Config{
-		MongoURI:      os.Getenv("MONGO_URI"),
-		RedisURI:      os.Getenv("REDIS_URI"),
-		JWTSecret:     os.Getenv("JWT_SECRET"),
-		CounterApiKey: os.Getenv("COUNTER_API_KEY"),
+		Port:               DefaultPort,
+		ReadTimeout:        DefaultReadTimeout,
+		WriteTimeout:       DefaultWriteTimeout,
+		IdleTimeout:        DefaultIdleTimeout,
+		ExamplesDir:        DefaultExamplesDir,
+		GeoDBPath:          DefaultGeoDBPath,
+		GeoDBMaxAgeDays:    DefaultGeoDBMaxAgeDays,
+		PageCacheMaxAge:    DefaultPageCacheMaxAge,
+		BaseURL:            DefaultBaseURL,
+		CorrelationHeaders: slices.Clone(DefaultCorrelationHeaders),
+		SMTPPort:           DefaultSMTPPort,
+		UserDeletionGrace:  DefaultUserDeletionGrace,
+		DNSCacheSize:       DefaultDNSCacheSize,
+		DNSResolver:        DNSResolverSystem,
+		LogFormat:          LogFormatText,
+		DoHEndpoint:        DefaultDoHEndpoint,
+		DoHMethod:          DefaultDoHMethod,
+		DoHTimeout:         DefaultDoHTimeout,
+		EmailDNSTimeout:    DefaultEmailDNSTimeout,
+		SMTPCheckDeadline:  DefaultSMTPCheckDeadline,
+		CounterWALPath:     DefaultCounterWALPath,
+		CounterWALMaxBytes: DefaultCounterWALMaxBytes,
+
+		CounterFlushInterval: DefaultCounterFlushInterval,
+
+		PixelQuotaAnonymous: DefaultPixelQuotaAnonymous,
+		PixelQuotaFree:      DefaultPixelQuotaFree,
+		PixelQuotaPro:       DefaultPixelQuotaPro,
+
+		UploadsDir:     DefaultUploadsDir,
+		UploadMaxBytes: DefaultUploadMaxBytes,
+		UploadTTL:      DefaultUploadTTL,
+
+		ValidatorMaxBodyBytes: DefaultValidatorMaxBodyBytes,
+		GeneratorMaxBodyBytes: DefaultGeneratorMaxBodyBytes,
+		CSVConvertMaxBytes:    DefaultCSVConvertMaxBytes,
+		CSVConvertMaxRows:     DefaultCSVConvertMaxRows,
+		ValidatorRateLimit:    DefaultValidatorRateLimit,
+		GeneratorRateLimit:    DefaultGeneratorRateLimit,
+
+		SafeBrowsingEndpoint: DefaultSafeBrowsingEndpoint,
+		SafeBrowsingTimeout:  DefaultSafeBrowsingTimeout,
 	}
 }
+
+// Path returns the public path of path, a path of the service: path
+// under BasePath
+func (c *Config) Path(path string) string {
+	return c.BasePath + path
+}
+
+// AbsURL returns the public URL of path, a path of the service: BaseURL,
+// then BasePath and path
+func (c *Config) AbsURL(path string) string {
+	return strings.TrimSuffix(c.BaseURL, "/") + c.Path(path)
+}
+
+// IsProduction reports whether the service runs in the production environment
+func (c *Config) IsProduction() bool {
+	return c.AppEnv == "production"
+}
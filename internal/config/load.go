@@ -0,0 +1,178 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the optional YAML file read before the environment
+const ConfigFileEnv = "CONFIG_FILE"
+
+// ErrInvalidConfig is wrapped by every error Load returns for bad settings
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+var (
+	loadOnce  sync.Once
+	loadedCfg *Config
+	loadErr   error
+)
+
+// LoadConfig returns the configuration from Load. It is read once; later
+// calls return the cached result.
+func LoadConfig() (*Config, error) {
+	loadOnce.Do(func() {
+		loadedCfg, loadErr = Load()
+	})
+	return loadedCfg, loadErr
+}
+
+// Load reads the configuration: the environment wins over CONFIG_FILE,
+// which wins over Default. A .env file is loaded into the environment when
+// present; variables already set are kept. Every unparseable or invalid
+// setting is reported in one error wrapping ErrInvalidConfig.
+func Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: .env: %v", ErrInvalidConfig, err)
+	}
+
+	values := map[string]string{}
+	if path := os.Getenv(ConfigFileEnv); path != "" {
+		fileValues, err := readConfigFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, path, err)
+		}
+		values = fileValues
+	}
+	for _, key := range settingKeys() {
+		if value, ok := os.LookupEnv(key); ok {
+			values[key] = value
+		}
+	}
+
+	cfg := Default()
+	errs := cfg.apply(values)
+	errs = append(errs, cfg.Validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w:\n%w", ErrInvalidConfig, errors.Join(errs...))
+	}
+	return cfg, nil
+}
+
+// readConfigFile reads a flat YAML mapping of lower-case setting names to
+// scalars; lists are accepted for list settings. Unknown keys are errors so
+// typos do not pass silently.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	for _, key := range settingKeys() {
+		known[key] = true
+	}
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		key := strings.ToUpper(name)
+		if !known[key] {
+			return nil, fmt.Errorf("unknown setting %q", name)
+		}
+		switch v := value.(type) {
+		case nil:
+			values[key] = ""
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case map[string]interface{}:
+			return nil, fmt.Errorf("setting %q must be a scalar or a list", name)
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+// settingKeys returns the env tag of every Config field
+func settingKeys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("env"); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// apply parses values into the fields named by their env tags and returns
+// an error per value that does not parse
+func (c *Config) apply(values map[string]string) []error {
+	var errs []error
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("env")
+		value, ok := values[key]
+		if key == "" || !ok {
+			continue
+		}
+		if err := setField(v.Field(i), strings.TrimSpace(value)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errs
+}
+
+func setField(field reflect.Value, value string) error {
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 2m", value)
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadWithoutDotEnv(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(ConfigFileEnv, "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load without a .env file: %v", err)
+	}
+	if cfg.Port == 0 {
+		t.Errorf("Port = 0, want the default")
+	}
+}
+
+func TestLoadFailures(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	t.Setenv(ConfigFileEnv, filepath.Join(dir, "missing.yaml"))
+	if _, err := Load(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("missing CONFIG_FILE: err = %v, want ErrInvalidConfig", err)
+	}
+
+	t.Setenv(ConfigFileEnv, "")
+	if err := os.WriteFile(".env", []byte("JWT_SECRET=\"unterminated\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("malformed .env: err = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	file := filepath.Join(dir, "config.yaml")
+	yaml := "port: 9000\nread_timeout: 20s\napp_env: staging\nsigning_key_files:\n  - a.pem\n  - b.pem\n"
+	if err := os.WriteFile(file, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(".env", []byte("APP_ENV=production\nWRITE_TIMEOUT=45s\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ConfigFileEnv, file)
+	t.Setenv("PORT", "9100")
+	for _, key := range []string{"READ_TIMEOUT", "WRITE_TIMEOUT", "IDLE_TIMEOUT", "APP_ENV", "SIGNING_KEY_FILES"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The environment, including what .env adds to it, wins over the file,
+	// which wins over the defaults
+	if cfg.Port != 9100 || cfg.ReadTimeout != 20*time.Second || cfg.WriteTimeout != 45*time.Second ||
+		cfg.IdleTimeout != DefaultIdleTimeout || !cfg.IsProduction() {
+		t.Errorf("port %d, timeouts %s %s %s, environment %q", cfg.Port, cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout, cfg.AppEnv)
+	}
+	if !slices.Equal(cfg.SigningKeyFiles, []string{"a.pem", "b.pem"}) {
+		t.Errorf("signing key files %v, want the YAML list", cfg.SigningKeyFiles)
+	}
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(ConfigFileEnv, "")
+	t.Setenv("PORT", "0")
+	t.Setenv("READ_TIMEOUT", "soon")
+	t.Setenv("REDIS_URI", "redis://localhost")
+	t.Setenv("SMTP_FROM", "nobody")
+	t.Setenv("GEODB_MAX_AGE_DAYS", "0")
+
+	_, err := Load()
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("err = %v, want ErrInvalidConfig", err)
+	}
+	for _, key := range []string{"PORT", "READ_TIMEOUT", "REDIS_URI", "SMTP_FROM", "GEODB_MAX_AGE_DAYS"} {
+		if !strings.Contains(err.Error(), key+":") {
+			t.Errorf("error does not report %s:\n%v", key, err)
+		}
+	}
+}
+
+func TestReadConfigFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, yaml := range map[string]string{
+		"unknown key": "prot: 8000\n",
+		"nested map":  "port:\n  value: 8000\n",
+		"not YAML":    "port: [8000\n",
+	} {
+		path := filepath.Join(dir, "config.yaml")
+		if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readConfigFile(path); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	if errs := Default().Validate(); len(errs) > 0 {
+		t.Errorf("Default() fails validation: %v", errs)
+	}
+}
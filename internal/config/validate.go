@@ -0,0 +1,188 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// basePathPattern matches BASE_PATH: segments of URL-safe characters
+var basePathPattern = regexp.MustCompile(`^(/[A-Za-z0-9._~-]+)+$`)
+
+// Validate checks the settings that parsed for values the service cannot
+// use, returning one error per problem
+func (c *Config) Validate() []error {
+	var errs []error
+	fail := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.MongoURI != "" {
+		if u, err := url.Parse(c.MongoURI); err != nil || (u.Scheme != "mongodb" && u.Scheme != "mongodb+srv") || u.Host == "" {
+			fail("MONGO_URI", "must be a mongodb:// or mongodb+srv:// URI")
+		}
+	}
+	if c.RedisURI != "" && !validHostPort(c.RedisURI) {
+		fail("REDIS_URI", "must be host:port")
+	}
+
+	if !validPort(c.Port) {
+		fail("PORT", "must be between 1 and 65535, got %d", c.Port)
+	}
+	timeouts := []struct {
+		key   string
+		value time.Duration
+	}{{"READ_TIMEOUT", c.ReadTimeout}, {"WRITE_TIMEOUT", c.WriteTimeout}, {"IDLE_TIMEOUT", c.IdleTimeout}}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			fail(timeout.key, "must be positive, got %s", timeout.value)
+		}
+	}
+
+	if c.GeoDBPath == "" {
+		fail("GEODB_PATH", "must not be empty")
+	}
+	if c.GeoDBMaxAgeDays < 1 {
+		fail("GEODB_MAX_AGE_DAYS", "must be at least 1, got %d", c.GeoDBMaxAgeDays)
+	}
+	if c.PageCacheMaxAge < 0 {
+		fail("PAGE_CACHE_MAX_AGE", "must not be negative, got %d", c.PageCacheMaxAge)
+	}
+	if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		strings.TrimSuffix(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		fail("BASE_URL", "must be an http:// or https:// origin without a path")
+	}
+	if c.BasePath != "" && !basePathPattern.MatchString(c.BasePath) {
+		fail("BASE_PATH", "must be a path such as /microtools, without a trailing /")
+	}
+	if c.DNSCacheSize < 1 {
+		fail("DNS_CACHE_SIZE", "must be at least 1, got %d", c.DNSCacheSize)
+	}
+	if c.LogFormat != LogFormatText && c.LogFormat != LogFormatJSON {
+		fail("LOG_FORMAT", "must be %q or %q, got %q", LogFormatText, LogFormatJSON, c.LogFormat)
+	}
+	if c.DNSResolver != DNSResolverSystem && c.DNSResolver != DNSResolverDoH {
+		fail("DNS_RESOLVER", "must be %q or %q, got %q", DNSResolverSystem, DNSResolverDoH, c.DNSResolver)
+	}
+	if u, err := url.Parse(c.DoHEndpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+		fail("DOH_ENDPOINT", "must be an https:// URL")
+	}
+	if c.DoHMethod != "GET" && c.DoHMethod != "POST" {
+		fail("DOH_METHOD", "must be GET or POST, got %q", c.DoHMethod)
+	}
+	if c.DoHTimeout <= 0 {
+		fail("DOH_TIMEOUT", "must be positive, got %s", c.DoHTimeout)
+	}
+	if c.EmailDNSTimeout <= 0 {
+		fail("EMAIL_DNS_TIMEOUT", "must be positive, got %s", c.EmailDNSTimeout)
+	}
+	if c.SMTPCheckDeadline <= 0 {
+		fail("SMTP_CHECK_DEADLINE", "must be positive, got %s", c.SMTPCheckDeadline)
+	}
+	for _, header := range c.CorrelationHeaders {
+		if !httpguts.ValidHeaderFieldName(header) {
+			fail("CORRELATION_HEADERS", "%q is not a valid header name", header)
+		}
+	}
+	if c.CounterWALMaxBytes < minCounterWALBytes {
+		fail("COUNTER_WAL_MAX_BYTES", "must be at least %d, got %d", minCounterWALBytes, c.CounterWALMaxBytes)
+	}
+	if c.CounterFlushInterval < time.Second {
+		fail("COUNTER_FLUSH_INTERVAL", "must be at least 1s, got %s", c.CounterFlushInterval)
+	}
+	quotas := []struct {
+		key   string
+		value int
+	}{{"PIXEL_QUOTA_ANONYMOUS", c.PixelQuotaAnonymous}, {"PIXEL_QUOTA_FREE", c.PixelQuotaFree}, {"PIXEL_QUOTA_PRO", c.PixelQuotaPro}}
+	for _, quota := range quotas {
+		if quota.value < 0 {
+			fail(quota.key, "must not be negative, got %d", quota.value)
+		}
+	}
+	if c.UploadMaxBytes < 1 {
+		fail("UPLOAD_MAX_BYTES", "must be at least 1, got %d", c.UploadMaxBytes)
+	}
+	if c.ValidatorMaxBodyBytes < 1 {
+		fail("VALIDATOR_MAX_BODY_BYTES", "must be at least 1, got %d", c.ValidatorMaxBodyBytes)
+	}
+	if c.GeneratorMaxBodyBytes < 1 {
+		fail("GENERATOR_MAX_BODY_BYTES", "must be at least 1, got %d", c.GeneratorMaxBodyBytes)
+	}
+	if c.CSVConvertMaxBytes < 1 {
+		fail("CSV_CONVERT_MAX_BYTES", "must be at least 1, got %d", c.CSVConvertMaxBytes)
+	}
+	if c.CSVConvertMaxRows < 1 {
+		fail("CSV_CONVERT_MAX_ROWS", "must be at least 1, got %d", c.CSVConvertMaxRows)
+	}
+	if c.ValidatorRateLimit < 0 {
+		fail("VALIDATOR_RATE_LIMIT", "must not be negative, got %d", c.ValidatorRateLimit)
+	}
+	if c.GeneratorRateLimit < 0 {
+		fail("GENERATOR_RATE_LIMIT", "must not be negative, got %d", c.GeneratorRateLimit)
+	}
+	if c.UploadTTL <= 0 {
+		fail("UPLOAD_TTL", "must be positive, got %s", c.UploadTTL)
+	}
+	if u, err := url.Parse(c.SafeBrowsingEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fail("SAFE_BROWSING_ENDPOINT", "must be an http:// or https:// URL")
+	}
+	if c.SafeBrowsingTimeout <= 0 {
+		fail("SAFE_BROWSING_TIMEOUT", "must be positive, got %s", c.SafeBrowsingTimeout)
+	}
+	if c.WifiRotationKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(c.WifiRotationKey); err != nil || len(key) != 32 {
+			fail("WIFI_ROTATION_KEY", "must be 32 bytes in base64")
+		}
+	}
+	if c.ExamplesDir == "" {
+		fail("EXAMPLES_DIR", "must not be empty")
+	}
+
+	if c.UserDeletionGrace <= 0 {
+		fail("USER_DELETION_GRACE", "must be positive, got %s", c.UserDeletionGrace)
+	}
+	if !validPort(c.SMTPPort) {
+		fail("SMTP_PORT", "must be between 1 and 65535, got %d", c.SMTPPort)
+	}
+	if c.SMTPHost != "" && c.SMTPFrom == "" {
+		fail("SMTP_FROM", "is required when SMTP_HOST is set")
+	}
+	if c.SMTPFrom != "" {
+		if _, err := mail.ParseAddress(c.SMTPFrom); err != nil {
+			fail("SMTP_FROM", "must be an email address")
+		}
+	}
+	for _, email := range c.AdminEmails {
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			fail("ADMIN_EMAILS", "%q is not an email address", email)
+		}
+	}
+	if len(c.AdminEmails) > 0 && c.AutoVerify && c.IsProduction() {
+		fail("ADMIN_EMAILS", "must not be set with AUTO_VERIFY in production, which lets anyone register an admin address")
+	}
+	return errs
+}
+
+// minCounterWALBytes leaves room for a batch of every counter
+const minCounterWALBytes = 64 << 10
+
+func validPort(port int) bool {
+	return port >= 1 && port <= 65535
+}
+
+func validHostPort(addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && validPort(n)
+}
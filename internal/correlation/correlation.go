@@ -0,0 +1,101 @@
+// Package correlation carries the trace and correlation IDs a client sent
+// with a request, e.g. X-Correlation-ID, so they are echoed on the
+// response and follow the work done on the client's behalf. IDs travel in
+// the request context like the trace context does.
+package correlation
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// MaxLength is the longest ID value accepted; longer values are dropped
+const MaxLength = 128
+
+// ID is one correlation header and its value
+type ID struct {
+	Header string
+	Value  string
+}
+
+// IDs are the correlation IDs of a request in the configured header order
+type IDs []ID
+
+type contextKey struct{}
+
+// FromRequest returns the valid IDs r carries in headers. A header with an
+// invalid value is left out, as if the client had not sent it.
+func FromRequest(r *http.Request, headers []string) IDs {
+	var ids IDs
+	for _, header := range headers {
+		value := r.Header.Get(header)
+		if value == "" || !Valid(value) {
+			continue
+		}
+		ids = append(ids, ID{Header: http.CanonicalHeaderKey(header), Value: value})
+	}
+	return ids
+}
+
+// Valid reports whether value is safe to echo, store and forward: at most
+// MaxLength characters of letters, digits and ._:/+=@- so it cannot
+// split headers or log lines
+func Valid(value string) bool {
+	if value == "" || len(value) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("._:/+=@-", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns a copy of ctx carrying ids
+func NewContext(ctx context.Context, ids IDs) context.Context {
+	return context.WithValue(ctx, contextKey{}, ids)
+}
+
+// FromContext returns the IDs of ctx, or nil when the request had none
+func FromContext(ctx context.Context) IDs {
+	ids, _ := ctx.Value(contextKey{}).(IDs)
+	return ids
+}
+
+// SetHeaders sets every ID on h, for responses and outbound requests
+func (ids IDs) SetHeaders(h http.Header) {
+	for _, id := range ids {
+		h.Set(id.Header, id.Value)
+	}
+}
+
+// Map returns the IDs keyed by header, or nil when there are none
+func (ids IDs) Map() map[string]string {
+	if len(ids) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(ids))
+	for _, id := range ids {
+		m[id.Header] = id.Value
+	}
+	return m
+}
+
+// String formats the IDs as space-separated header=value pairs for log
+// lines, or "" when there are none
+func (ids IDs) String() string {
+	var b strings.Builder
+	for i, id := range ids {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(id.Header + "=" + id.Value)
+	}
+	return b.String()
+}
@@ -0,0 +1,64 @@
+package correlation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	for value, want := range map[string]bool{
+		"4bf92f3577b34da6":                 true,
+		"order-42_retry.1:a/b+c=d@partner": true,
+		strings.Repeat("a", MaxLength):     true,
+		strings.Repeat("a", MaxLength+1):   false,
+		strings.Repeat("a", 5*1024):        false,
+		"":                                 false,
+		"id\r\nSet-Cookie: session=stolen": false,
+		"id\nlevel=ERROR msg=forged":       false,
+		"id with spaces":                   false,
+		`"quoted"`:                         false,
+		"naïve":                            false,
+		"id\x00":                           false,
+	} {
+		if got := Valid(value); got != want {
+			t.Errorf("Valid(%.40q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-ID", "req-1")
+	r.Header.Set("X-Correlation-ID", "corr-1")
+	r.Header.Set("Traceparent", "00-"+strings.Repeat("x", 5*1024))
+	r.Header.Set("X-Unlisted", "ignored")
+
+	ids := FromRequest(r, []string{"x-correlation-id", "traceparent", "X-Request-ID"})
+	want := IDs{{"X-Correlation-Id", "corr-1"}, {"X-Request-Id", "req-1"}}
+	if len(ids) != len(want) || ids[0] != want[0] || ids[1] != want[1] {
+		t.Fatalf("ids = %v, want %v without the oversized traceparent", ids, want)
+	}
+	if ids.String() != "X-Correlation-Id=corr-1 X-Request-Id=req-1" {
+		t.Errorf("String = %q", ids.String())
+	}
+	if m := ids.Map(); len(m) != 2 || m["X-Request-Id"] != "req-1" {
+		t.Errorf("Map = %v", m)
+	}
+
+	h := http.Header{}
+	ids.SetHeaders(h)
+	if h.Get("X-Correlation-ID") != "corr-1" || h.Get("X-Request-ID") != "req-1" || len(h) != 2 {
+		t.Errorf("SetHeaders = %v", h)
+	}
+
+	if got := FromContext(NewContext(context.Background(), ids)); len(got) != 2 {
+		t.Errorf("FromContext = %v", got)
+	}
+	var none IDs
+	if FromContext(context.Background()) != nil || none.Map() != nil || none.String() != "" {
+		t.Error("no IDs should be nil, with a nil map and an empty string")
+	}
+}
@@ -2,24 +2,24 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/redact"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // InitMongoDB initializes MongoDB client
-func InitMongoDB() *mongo.Client {
-	cfg := config.LoadConfig()
-	log.Println("Initializing MongoDB... with uri: ", cfg.MongoURI)
+func InitMongoDB(cfg *config.Config) (*mongo.Client, error) {
+	log.Println("Initializing MongoDB... with uri: ", redact.URL(cfg.MongoURI))
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	var err error
 	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
-	return client
+	return client, nil
 }
@@ -1,14 +1,24 @@
 package database
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/go-redis/redis/v8"
 	"github.com/innovelabs/microtools-go/internal/config"
 )
 
 // InitRedis initializes Redis client
-func InitRedis() *redis.Client {
-	cfg := config.LoadConfig()
-	return redis.NewClient(&redis.Options{
+func InitRedis(cfg *config.Config) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
 		Addr: cfg.RedisURI,
 	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return client, nil
 }
@@ -0,0 +1,100 @@
+package dataset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownDataset is returned when no catalog is registered under a name
+var ErrUnknownDataset = errors.New("unknown dataset")
+
+// Dataset describes a registered catalog and the version of its contents
+type Dataset struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	Entries   int       `json:"entries"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type entry struct {
+	info Dataset
+	data interface{}
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]*entry{}
+)
+
+// Register adds or replaces a catalog. The version hash is recomputed from
+// the contents, and UpdatedAt only moves when the hash actually changes, so
+// reloading identical data does not invalidate client caches.
+func Register(name string, data interface{}) Dataset {
+	version := Version(data)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if existing, ok := registry[name]; ok && existing.info.Version == version {
+		existing.data = data
+		return existing.info
+	}
+	e := &entry{
+		info: Dataset{Name: name, Version: version, Entries: countEntries(data), UpdatedAt: now},
+		data: data,
+	}
+	registry[name] = e
+	return e.info
+}
+
+// Get returns a catalog's metadata and contents
+func Get(name string) (Dataset, interface{}, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	e, ok := registry[name]
+	if !ok {
+		return Dataset{}, nil, ErrUnknownDataset
+	}
+	return e.info, e.data, nil
+}
+
+// List returns metadata for every registered catalog, sorted by name
+func List() []Dataset {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]Dataset, 0, len(registry))
+	for _, e := range registry {
+		list = append(list, e.info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Version returns a short content hash of data's JSON encoding
+func Version(data interface{}) string {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:8])
+}
+
+func countEntries(data interface{}) int {
+	v := reflect.ValueOf(data)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return v.Len()
+	default:
+		return 1
+	}
+}
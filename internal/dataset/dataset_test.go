@@ -0,0 +1,60 @@
+package dataset
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	first := Register("test-colors", []string{"red", "green"})
+	if first.Version == "" || first.Entries != 2 {
+		t.Fatalf("first = %+v", first)
+	}
+
+	// Identical contents keep the version and the time of the last change
+	if again := Register("test-colors", []string{"red", "green"}); again != first {
+		t.Errorf("reloading identical data = %+v, want %+v", again, first)
+	}
+
+	changed := Register("test-colors", []string{"red", "green", "blue"})
+	if changed.Version == first.Version || changed.Entries != 3 {
+		t.Errorf("changed = %+v, want a new version of 3 entries", changed)
+	}
+	info, data, err := Get("test-colors")
+	if err != nil || info != changed || len(data.([]string)) != 3 {
+		t.Errorf("Get = %+v, %v, %v", info, data, err)
+	}
+
+	if _, _, err := Get("test-unknown"); !errors.Is(err, ErrUnknownDataset) {
+		t.Errorf("unknown dataset: err = %v, want ErrUnknownDataset", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	Register("test-b", map[string]int{"x": 1})
+	Register("test-a", "scalar")
+	var names []string
+	for _, d := range List() {
+		names = append(names, d.Name)
+		if d.Name == "test-a" && d.Entries != 1 {
+			t.Errorf("a scalar dataset has %d entries, want 1", d.Entries)
+		}
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i-1] >= names[i] {
+			t.Fatalf("List is not sorted by name: %v", names)
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	if Version([]int{1, 2}) != Version([]int{1, 2}) {
+		t.Error("equal data hashes differently")
+	}
+	if Version([]int{1, 2}) == Version([]int{2, 1}) {
+		t.Error("different data hashes the same")
+	}
+	if v := Version(func() {}); v != "" {
+		t.Errorf("unencodable data has version %q, want none", v)
+	}
+}
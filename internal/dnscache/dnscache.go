@@ -0,0 +1,237 @@
+// Package dnscache caches DNS answers in front of a resolver. Names that
+// do not exist (NXDOMAIN) or have no records of the asked type (NODATA)
+// are cached too, for a shorter time, so a burst of lookups for the same
+// mistyped domain reaches DNS once. Transient failures such as SERVFAIL
+// or timeouts are never cached.
+package dnscache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+const (
+	// DefaultSize is the number of answers kept before the least recently
+	// used one is evicted
+	DefaultSize = config.DefaultDNSCacheSize
+	// PositiveTTL is how long answers with records are cached
+	PositiveTTL = 10 * time.Minute
+	// NegativeTTL is how long NXDOMAIN and NODATA answers are cached when
+	// the resolver does not report the zone's SOA minimum
+	NegativeTTL = time.Minute
+	// An SOA minimum is honored within MinNegativeTTL and MaxNegativeTTL,
+	// so a zone can neither disable negative caching nor pin a typo for
+	// longer than a positive answer
+	MinNegativeTTL = 5 * time.Second
+	MaxNegativeTTL = PositiveTTL
+)
+
+// Resolver looks up the records email validation needs. *net.Resolver
+// satisfies it.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) (addrs []string, err error)
+}
+
+// SOAError is implemented by resolver errors for negative answers that
+// carry the zone's SOA record. Its minimum bounds how long the absence
+// may be cached (RFC 2308).
+type SOAError interface {
+	error
+	SOAMinimum() time.Duration
+}
+
+// IsNotFound reports whether err is a negative answer, NXDOMAIN or NODATA,
+// as opposed to a failure to get an answer at all
+func IsNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+type entry struct {
+	key     string
+	mx      []*net.MX
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// Cache is a Resolver that answers from memory when it can. Cached
+// answers are shared between callers, who must not modify them.
+type Cache struct {
+	resolver Resolver
+	clock    clock.Clock
+	size     int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds the entries from most to least recently used
+	order *list.List
+
+	hits         atomic.Int64
+	negativeHits atomic.Int64
+	misses       atomic.Int64
+	evictions    atomic.Int64
+}
+
+// New caches up to size answers of resolver; size <= 0 uses DefaultSize
+func New(resolver Resolver, c clock.Clock, size int) *Cache {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Cache{
+		resolver: resolver,
+		clock:    c,
+		size:     size,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// LookupMX returns the MX records of name
+func (c *Cache) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return c.lookupMX(ctx, c.resolver, name)
+}
+
+// LookupHost returns the addresses of host
+func (c *Cache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return c.lookupHost(ctx, c.resolver, host)
+}
+
+// Via returns a Resolver answering from the cache that sends misses to
+// resolver instead of the cache's own, e.g. a DNS-over-HTTPS resolver a
+// request asked for. Answers are shared whichever resolver fetched them.
+func (c *Cache) Via(resolver Resolver) Resolver {
+	return via{cache: c, resolver: resolver}
+}
+
+type via struct {
+	cache    *Cache
+	resolver Resolver
+}
+
+func (v via) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return v.cache.lookupMX(ctx, v.resolver, name)
+}
+
+func (v via) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return v.cache.lookupHost(ctx, v.resolver, host)
+}
+
+func (c *Cache) lookupMX(ctx context.Context, resolver Resolver, name string) ([]*net.MX, error) {
+	key := "mx:" + strings.ToLower(name)
+	if e, ok := c.get(key); ok {
+		return e.mx, e.err
+	}
+	records, err := resolver.LookupMX(ctx, name)
+	c.put(&entry{key: key, mx: records, err: err})
+	return records, err
+}
+
+func (c *Cache) lookupHost(ctx context.Context, resolver Resolver, host string) ([]string, error) {
+	key := "host:" + strings.ToLower(host)
+	if e, ok := c.get(key); ok {
+		return e.addrs, e.err
+	}
+	addrs, err := resolver.LookupHost(ctx, host)
+	c.put(&entry{key: key, addrs: addrs, err: err})
+	return addrs, err
+}
+
+// get returns the unexpired entry for key, counting the hit or miss
+func (c *Cache) get(key string) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*entry)
+		if c.clock.Now().Before(e.expires) {
+			c.order.MoveToFront(elem)
+			if e.err != nil {
+				c.negativeHits.Add(1)
+			} else {
+				c.hits.Add(1)
+			}
+			return e, true
+		}
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+	c.misses.Add(1)
+	return nil, false
+}
+
+// put caches e unless its error is transient, evicting the least recently
+// used entries over the size limit
+func (c *Cache) put(e *entry) {
+	ttl, ok := cacheTTL(e.err)
+	if !ok {
+		return
+	}
+	e.expires = c.clock.Now().Add(ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[e.key]; ok {
+		elem.Value = e
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[e.key] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+		c.evictions.Add(1)
+	}
+}
+
+// cacheTTL returns how long an answer with err may be cached, or false
+// when it must not be
+func cacheTTL(err error) (time.Duration, bool) {
+	if err == nil {
+		return PositiveTTL, true
+	}
+	if !IsNotFound(err) {
+		return 0, false
+	}
+	var soa SOAError
+	if errors.As(err, &soa) {
+		return min(max(soa.SOAMinimum(), MinNegativeTTL), MaxNegativeTTL), true
+	}
+	return NegativeTTL, true
+}
+
+// Flush drops every cached answer and returns how many there were
+func (c *Cache) Flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.order.Len()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	return n
+}
+
+// Stats returns the cache counters since it was created
+func (c *Cache) Stats() models.DNSCacheStats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+	return models.DNSCacheStats{
+		Entries:      entries,
+		Capacity:     c.size,
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		Evictions:    c.evictions.Load(),
+	}
+}
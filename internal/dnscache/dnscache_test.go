@@ -0,0 +1,212 @@
+package dnscache_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/dnscache"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+// soaError is a negative answer carrying the zone's SOA minimum
+type soaError struct {
+	*net.DNSError
+	minimum time.Duration
+}
+
+func (e *soaError) SOAMinimum() time.Duration { return e.minimum }
+func (e *soaError) Unwrap() error             { return e.DNSError }
+
+// soaResolver answers every MX lookup with NXDOMAIN and an SOA minimum
+type soaResolver struct {
+	*testutil.Resolver
+	minimum time.Duration
+}
+
+func (r soaResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	_, err := r.Resolver.LookupMX(ctx, name)
+	return nil, &soaError{DNSError: err.(*net.DNSError), minimum: r.minimum}
+}
+
+func newCache(resolver dnscache.Resolver, size int) (*dnscache.Cache, *testutil.Clock) {
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	return dnscache.New(resolver, clock, size), clock
+}
+
+func TestCacheHits(t *testing.T) {
+	resolver := testutil.NewResolver()
+	resolver.MX["example.com"] = []*net.MX{{Host: "mx.example.com.", Pref: 10}}
+	cache, _ := newCache(resolver, 0)
+
+	for _, name := range []string{"example.com", "Example.COM", "example.com"} {
+		records, err := cache.LookupMX(context.Background(), name)
+		if err != nil || len(records) != 1 {
+			t.Fatalf("%s: %v, %v", name, records, err)
+		}
+	}
+	for range 3 {
+		if _, err := cache.LookupMX(context.Background(), "exmaple.com"); !dnscache.IsNotFound(err) {
+			t.Fatalf("typo: err = %v, want a not-found answer", err)
+		}
+	}
+	// The same name's host lookup is cached on its own
+	if _, err := cache.LookupHost(context.Background(), "example.com"); !dnscache.IsNotFound(err) {
+		t.Fatalf("host: err = %v", err)
+	}
+
+	if resolver.Calls() != 3 {
+		t.Errorf("resolver calls = %d, want one per name and type", resolver.Calls())
+	}
+	want := models.DNSCacheStats{Entries: 3, Capacity: dnscache.DefaultSize, Hits: 2, NegativeHits: 2, Misses: 3}
+	if stats := cache.Stats(); stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	tests := []struct {
+		name     string
+		resolver dnscache.Resolver
+		ttl      time.Duration
+	}{
+		{"positive", func() dnscache.Resolver {
+			r := testutil.NewResolver()
+			r.MX["example.com"] = []*net.MX{{Host: "mx.example.com.", Pref: 10}}
+			return r
+		}(), dnscache.PositiveTTL},
+		{"negative without SOA", testutil.NewResolver(), dnscache.NegativeTTL},
+		{"SOA minimum", soaResolver{testutil.NewResolver(), 30 * time.Second}, 30 * time.Second},
+		{"SOA minimum below the floor", soaResolver{testutil.NewResolver(), time.Second}, dnscache.MinNegativeTTL},
+		{"SOA minimum above the ceiling", soaResolver{testutil.NewResolver(), 24 * time.Hour}, dnscache.MaxNegativeTTL},
+	}
+	for _, tt := range tests {
+		cache, clock := newCache(tt.resolver, 0)
+		lookup := func() {
+			cache.LookupMX(context.Background(), "example.com")
+		}
+		lookup()
+		clock.Advance(tt.ttl - time.Second)
+		lookup()
+		if stats := cache.Stats(); stats.Misses != 1 {
+			t.Errorf("%s: %d misses a second before the TTL, want 1", tt.name, stats.Misses)
+		}
+		clock.Advance(time.Second)
+		lookup()
+		if stats := cache.Stats(); stats.Misses != 2 || stats.Entries != 1 {
+			t.Errorf("%s: stats at the TTL = %+v, want the answer fetched again", tt.name, stats)
+		}
+	}
+}
+
+func TestCacheSkipsTransientErrors(t *testing.T) {
+	for name, err := range map[string]error{
+		"SERVFAIL": &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true},
+		"timeout":  &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true},
+		"deadline": context.DeadlineExceeded,
+	} {
+		resolver := testutil.NewResolver()
+		resolver.Err = err
+		cache, _ := newCache(resolver, 0)
+		for range 2 {
+			if _, got := cache.LookupMX(context.Background(), "example.com"); !errors.Is(got, err) || dnscache.IsNotFound(got) {
+				t.Fatalf("%s: err = %v", name, got)
+			}
+		}
+		if resolver.Calls() != 2 || cache.Stats().Entries != 0 {
+			t.Errorf("%s: %d calls, %+v; want every lookup sent to DNS", name, resolver.Calls(), cache.Stats())
+		}
+
+		// The first answer after the failure is cached
+		resolver.Err = nil
+		resolver.MX["example.com"] = []*net.MX{{Host: "mx.example.com.", Pref: 10}}
+		cache.LookupMX(context.Background(), "example.com")
+		if records, err := cache.LookupMX(context.Background(), "example.com"); err != nil || len(records) != 1 || resolver.Calls() != 3 {
+			t.Errorf("%s: after recovery %v, %v with %d calls", name, records, err, resolver.Calls())
+		}
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	resolver := testutil.NewResolver()
+	cache, _ := newCache(resolver, 2)
+	lookup := func(name string) {
+		cache.LookupHost(context.Background(), name)
+	}
+	lookup("a.example")
+	lookup("b.example")
+	lookup("a.example") // a is now the most recently used
+	lookup("c.example") // evicts b
+	calls := resolver.Calls()
+	lookup("a.example")
+	lookup("c.example")
+	if resolver.Calls() != calls {
+		t.Errorf("a and c should still be cached")
+	}
+	lookup("b.example")
+	if resolver.Calls() != calls+1 {
+		t.Errorf("b should have been evicted")
+	}
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Capacity != 2 || stats.Evictions != 2 {
+		t.Errorf("stats = %+v, want two entries after two evictions", stats)
+	}
+
+	// Pressure from many names keeps the cache at its size
+	for i := range 100 {
+		lookup(string(rune('a'+i%26)) + ".pressure.example")
+	}
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Evictions != 102 {
+		t.Errorf("under pressure: %+v", stats)
+	}
+}
+
+func TestCacheFlush(t *testing.T) {
+	resolver := testutil.NewResolver()
+	cache, _ := newCache(resolver, 0)
+	cache.LookupMX(context.Background(), "a.example")
+	cache.LookupHost(context.Background(), "a.example")
+	if n := cache.Flush(); n != 2 {
+		t.Errorf("Flush = %d, want 2", n)
+	}
+	cache.LookupMX(context.Background(), "a.example")
+	if resolver.Calls() != 3 || cache.Stats().Entries != 1 {
+		t.Errorf("after the flush: %d calls, %+v", resolver.Calls(), cache.Stats())
+	}
+}
+
+func TestCacheVia(t *testing.T) {
+	own, other := testutil.NewResolver(), testutil.NewResolver()
+	other.Hosts["example.com"] = []string{"192.0.2.1"}
+	cache, _ := newCache(own, 0)
+
+	addrs, err := cache.Via(other).LookupHost(context.Background(), "example.com")
+	if err != nil || len(addrs) != 1 || other.Calls() != 1 || own.Calls() != 0 {
+		t.Fatalf("via: %v, %v; calls %d own, %d other", addrs, err, own.Calls(), other.Calls())
+	}
+	// The answer is shared with lookups through the cache's own resolver
+	if addrs, err := cache.LookupHost(context.Background(), "example.com"); err != nil || len(addrs) != 1 || own.Calls() != 0 {
+		t.Errorf("shared answer: %v, %v; %d own calls", addrs, err, own.Calls())
+	}
+}
+
+// TestTypoStorm validates the same mistyped domain repeatedly through the
+// email domain check, which reaches DNS once for MX and once for A/AAAA,
+// then answers both lookups of the 49 later checks from the cache
+func TestTypoStorm(t *testing.T) {
+	resolver := testutil.NewResolver()
+	cache, _ := newCache(resolver, 0)
+	check := validation.NewDomainChecker(cache)
+	for range 50 {
+		exists, hasMX, err := check(context.Background(), "ada@gmial.com")
+		if err != nil || exists || hasMX {
+			t.Fatalf("check = %v, %v, %v; want a domain that does not exist", exists, hasMX, err)
+		}
+	}
+	if resolver.Calls() != 2 || cache.Stats().NegativeHits != 98 {
+		t.Errorf("%d resolver calls, %+v", resolver.Calls(), cache.Stats())
+	}
+}
@@ -0,0 +1,223 @@
+package examples
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/redact"
+)
+
+// MaxBodyBytes is the longest request or response body kept in a fixture
+const MaxBodyBytes = 2048
+
+// Example is one recorded request/response pair for a route and status
+type Example struct {
+	Method              string    `json:"method"`
+	Path                string    `json:"path"`
+	Status              int       `json:"status"`
+	RequestContentType  string    `json:"requestContentType,omitempty"`
+	Request             string    `json:"request,omitempty"`
+	ResponseContentType string    `json:"responseContentType,omitempty"`
+	Response            string    `json:"response,omitempty"`
+	Redacted            bool      `json:"redacted,omitempty"`
+	RecordedAt          time.Time `json:"recordedAt"`
+}
+
+// Mismatch describes a fixture whose replayed response no longer matches
+type Mismatch struct {
+	Path   string
+	Status int
+	Reason string
+}
+
+var fileMu sync.Mutex
+
+// FileName returns the fixture file name for a route path
+func FileName(path string) string {
+	name := strings.ReplaceAll(strings.Trim(path, "/"), "/", "_")
+	if name == "" {
+		name = "root"
+	}
+	return name + ".json"
+}
+
+// ForRoute returns the recorded examples for a route, ordered by status
+func ForRoute(dir, path string) []Example {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	list, err := readFixtures(filepath.Join(dir, FileName(path)))
+	if err != nil {
+		return nil
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Status < list[j].Status })
+	return list
+}
+
+// Record stores ex unless the route already has an example for its status.
+// Bodies are redacted and truncated before they are written.
+func Record(dir string, ex Example) error {
+	var reqRedacted, respRedacted bool
+	ex.Request, reqRedacted = sanitizeBody(ex.RequestContentType, []byte(ex.Request))
+	ex.Response, respRedacted = sanitizeBody(ex.ResponseContentType, []byte(ex.Response))
+	ex.Redacted = reqRedacted || respRedacted
+
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	file := filepath.Join(dir, FileName(ex.Path))
+	list, err := readFixtures(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, existing := range list {
+		if existing.Status == ex.Status && existing.Method == ex.Method {
+			return nil
+		}
+	}
+	list = append(list, ex)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o644)
+}
+
+// SanitizeBody redacts sensitive JSON fields, pretty-prints JSON, replaces
+// binary payloads with a placeholder, and truncates to MaxBodyBytes.
+func SanitizeBody(contentType string, body []byte) string {
+	sanitized, _ := sanitizeBody(contentType, body)
+	return sanitized
+}
+
+// sanitizeBody also reports whether any field was redacted
+func sanitizeBody(contentType string, body []byte) (string, bool) {
+	if len(body) == 0 {
+		return "", false
+	}
+
+	redacted := false
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	switch {
+	case mediaType == "application/json" || mediaType == "":
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err == nil {
+			clean := redactJSON(v)
+			redacted = !reflect.DeepEqual(v, clean)
+			if pretty, err := json.MarshalIndent(clean, "", "  "); err == nil {
+				body = pretty
+			}
+		}
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "image/svg+xml":
+	default:
+		return fmt.Sprintf("<binary %s, %d bytes>", mediaType, len(body)), false
+	}
+
+	if len(body) > MaxBodyBytes {
+		return string(body[:MaxBodyBytes]) + "\n…", redacted
+	}
+	return string(body), redacted
+}
+
+func redactJSON(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return redact.RedactMap(val)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			out[i] = redactJSON(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Replay sends every fixture in dir to h and reports fixtures whose status
+// changed or whose JSON response no longer has the same top-level keys.
+// Redacted fixtures and fixtures with truncated requests are skipped.
+func Replay(h http.Handler, dir string) ([]Mismatch, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []Mismatch
+	for _, file := range files {
+		fileMu.Lock()
+		list, err := readFixtures(file)
+		fileMu.Unlock()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		for _, ex := range list {
+			if ex.Redacted || strings.Contains(ex.Request, redact.Redacted) || strings.HasSuffix(ex.Request, "…") {
+				continue
+			}
+			req := httptest.NewRequest(ex.Method, ex.Path, strings.NewReader(ex.Request))
+			if ex.RequestContentType != "" {
+				req.Header.Set("Content-Type", ex.RequestContentType)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != ex.Status {
+				mismatches = append(mismatches, Mismatch{ex.Path, ex.Status, fmt.Sprintf("status changed to %d", rec.Code)})
+				continue
+			}
+			if reason := compareJSONShape(ex.Response, rec.Body.Bytes()); reason != "" {
+				mismatches = append(mismatches, Mismatch{ex.Path, ex.Status, reason})
+			}
+		}
+	}
+	return mismatches, nil
+}
+
+func compareJSONShape(recorded string, live []byte) string {
+	var want, got map[string]interface{}
+	if json.Unmarshal([]byte(recorded), &want) != nil {
+		return ""
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(live), &got); err != nil {
+		return "response is no longer a JSON object"
+	}
+	for k := range want {
+		if _, ok := got[k]; !ok {
+			return fmt.Sprintf("response is missing key %q", k)
+		}
+	}
+	for k := range got {
+		if _, ok := want[k]; !ok {
+			return fmt.Sprintf("response has new key %q", k)
+		}
+	}
+	return ""
+}
+
+func readFixtures(file string) ([]Example, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var list []Example
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
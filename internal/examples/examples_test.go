@@ -0,0 +1,124 @@
+package examples
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeBody(t *testing.T) {
+	body := `{"email":"ada@example.com","iban":"DE89370400440532013000","nested":{"token":"abc"},"list":[{"password":"hunter2"}],"valid":true}`
+	got, redacted := sanitizeBody("application/json; charset=utf-8", []byte(body))
+	if !redacted {
+		t.Error("redacted = false, want true")
+	}
+	for _, secret := range []string{"ada@", "532013", "abc", "hunter2"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("sanitized body keeps %q:\n%s", secret, got)
+		}
+	}
+	for _, kept := range []string{`"***@example.com"`, `"DE****************3000"`, `"valid": true`} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("sanitized body lacks %s:\n%s", kept, got)
+		}
+	}
+
+	if got, redacted := sanitizeBody("application/json", []byte(`{"valid":true}`)); redacted || got != "{\n  \"valid\": true\n}" {
+		t.Errorf("clean body = %q, redacted %v", got, redacted)
+	}
+	if got := SanitizeBody("image/png", []byte("\x89PNG...")); got != "<binary image/png, 7 bytes>" {
+		t.Errorf("PNG body = %q", got)
+	}
+	long := SanitizeBody("text/plain", []byte(strings.Repeat("a", MaxBodyBytes+10)))
+	if len(long) != MaxBodyBytes+len("\n…") || !strings.HasSuffix(long, "\n…") {
+		t.Errorf("long body is %d bytes, want truncated to %d", len(long), MaxBodyBytes)
+	}
+}
+
+func TestRecordKeepsOneExamplePerStatus(t *testing.T) {
+	dir := t.TempDir()
+	record := func(method string, status int, response string) {
+		t.Helper()
+		err := Record(dir, Example{Method: method, Path: "/api/v1/validate/iban", Status: status, ResponseContentType: "application/json", Response: response})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	record("POST", 400, `{"error":"first"}`)
+	record("POST", 200, `{"valid":true}`)
+	record("POST", 400, `{"error":"second"}`)
+	record("GET", 400, `{"error":"by GET"}`)
+
+	list := ForRoute(dir, "/api/v1/validate/iban")
+	if len(list) != 3 {
+		t.Fatalf("%d examples, want one per method and status: %+v", len(list), list)
+	}
+	if list[0].Status != 200 || list[1].Status != 400 || list[2].Status != 400 {
+		t.Errorf("statuses %d, %d, %d, want ordered by status", list[0].Status, list[1].Status, list[2].Status)
+	}
+	for _, ex := range list {
+		if ex.Method == "POST" && ex.Status == 400 && !strings.Contains(ex.Response, "first") {
+			t.Errorf("POST 400 = %s, want the first example kept", ex.Response)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "api_v1_validate_iban.json")); err != nil {
+		t.Error(err)
+	}
+	if got := ForRoute(dir, "/api/v1/unknown"); got != nil {
+		t.Errorf("unrecorded route = %+v, want none", got)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	fixtures := []Example{
+		{Method: "POST", Path: "/same", Status: 200, Request: `{}`, RequestContentType: "application/json", Response: `{"a":1,"b":2}`},
+		{Method: "POST", Path: "/status", Status: 200, Request: `{}`, Response: `{"a":1}`},
+		{Method: "POST", Path: "/missing", Status: 200, Request: `{}`, Response: `{"a":1,"gone":2}`},
+		{Method: "POST", Path: "/new", Status: 200, Request: `{}`, Response: `{"a":1}`},
+		// Redacted requests cannot be replayed faithfully and are skipped
+		{Method: "POST", Path: "/status", Status: 201, Request: `{"email":"ada@example.com"}`, Response: `{"a":1}`},
+	}
+	for _, ex := range fixtures {
+		if err := Record(dir, ex); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	reply := func(status int, body map[string]int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(body)
+		}
+	}
+	mux.Handle("/same", reply(200, map[string]int{"b": 2, "a": 3}))
+	mux.Handle("/status", reply(500, map[string]int{"a": 1}))
+	mux.Handle("/missing", reply(200, map[string]int{"a": 1}))
+	mux.Handle("/new", reply(200, map[string]int{"a": 1, "added": 2}))
+
+	mismatches, err := Replay(mux, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, m := range mismatches {
+		got[m.Path] = m.Reason
+	}
+	want := map[string]string{
+		"/status":  "status changed to 500",
+		"/missing": `response is missing key "gone"`,
+		"/new":     `response has new key "added"`,
+	}
+	if len(got) != len(want) {
+		t.Errorf("mismatches = %v, want %v", got, want)
+	}
+	for path, reason := range want {
+		if got[path] != reason {
+			t.Errorf("%s: reason = %q, want %q", path, got[path], reason)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/abuse"
+	"github.com/innovelabs/microtools-go/internal/correlation"
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/policy"
+	"github.com/innovelabs/microtools-go/internal/repository"
+)
+
+// urlFlaggedErrorCode marks a QR code or short link refused by the URL
+// reputation check
+const urlFlaggedErrorCode = "URL_FLAGGED"
+
+var errBlocklistUnavailable = errors.New("the manual URL blocklist needs MongoDB")
+
+// allowQRURL runs the URL reputation check on a "url" QR request, writing
+// the 422 response when the URL is flagged
+func (h *Handlers) allowQRURL(w http.ResponseWriter, r *http.Request, req models.QRRequest) bool {
+	if req.Type != "url" {
+		return true
+	}
+	return h.allowURL(w, r, req.Data, "QR code")
+}
+
+// allowURL runs the URL reputation check on rawURL, writing the 422
+// response refusing what, e.g. "QR code", when it is flagged. The refusal is logged with
+// the URL's host only, since paths and queries may carry the victims'
+// data.
+func (h *Handlers) allowURL(w http.ResponseWriter, r *http.Request, rawURL, what string) bool {
+	if h.URLReputation == nil {
+		return true
+	}
+	verdict := h.URLReputation.CheckURL(r.Context(), rawURL)
+	if !verdict.Flagged {
+		return true
+	}
+
+	host := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Hostname()
+	}
+	logger := logging.FromContext(r.Context()).With("host", host, "source", verdict.Source, "match", verdict.Match, "api", policy.FromContext(r.Context()).Group)
+	if ids := correlation.FromContext(r.Context()); len(ids) > 0 {
+		logger = logger.With("correlation", ids.String())
+	}
+	logger.Warn("Abuse: refused " + what + " for a flagged URL")
+	writeJSON(w, r, http.StatusUnprocessableEntity, map[string]interface{}{
+		"message": what + "s for this URL are refused: it is reported as malicious",
+		"code":    urlFlaggedErrorCode,
+		"source":  verdict.Source,
+	})
+	return false
+}
+
+// BlockedDomainsHandler lists the domains operators blocked by hand
+func (h *Handlers) BlockedDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Blocklist == nil {
+		writeError(w, http.StatusServiceUnavailable, errBlocklistUnavailable)
+		return
+	}
+	entries, err := h.Blocklist.List(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to list blocked domains", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to list blocked domains")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"domains": entries})
+}
+
+// BlockDomainHandler adds a domain to the manual blocklist. It applies to
+// this instance at once and to the others when they next reload.
+func (h *Handlers) BlockDomainHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Blocklist == nil {
+		writeError(w, http.StatusServiceUnavailable, errBlocklistUnavailable)
+		return
+	}
+	var req models.BlockDomainRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	domain := abuse.NormalizeDomain(req.Domain)
+	if domain == "" {
+		WriteError(w, http.StatusBadRequest, InvalidDataErrorCode, "domain must be a host name")
+		return
+	}
+	entry := models.BlockedDomain{
+		Domain:  domain,
+		Reason:  req.Reason,
+		AddedBy: middleware.TokenEmail(r.Context()),
+		AddedAt: h.Clock.Now().UTC(),
+	}
+	if err := h.Blocklist.Add(r.Context(), entry); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to block domain", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to block the domain")
+		return
+	}
+	h.reloadBlocklist(r)
+	logging.FromContext(r.Context()).Info("Domain blocked", "domain", domain, "by", entry.AddedBy)
+	writeJSON(w, r, http.StatusCreated, map[string]interface{}{"domain": entry})
+}
+
+// UnblockDomainHandler removes a domain from the manual blocklist. Domains
+// listed in the blocklist file stay blocked.
+func (h *Handlers) UnblockDomainHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Blocklist == nil {
+		writeError(w, http.StatusServiceUnavailable, errBlocklistUnavailable)
+		return
+	}
+	domain := abuse.NormalizeDomain(mux.Vars(r)["domain"])
+	err := h.Blocklist.Remove(r.Context(), domain)
+	switch {
+	case errors.Is(err, repository.ErrBlockedDomainNotFound):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("Failed to unblock domain", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to unblock the domain")
+		return
+	}
+	h.reloadBlocklist(r)
+	logging.FromContext(r.Context()).Info("Domain unblocked", "domain", domain, "by", middleware.TokenEmail(r.Context()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reloadBlocklist applies a manual blocklist change to this instance's check
+func (h *Handlers) reloadBlocklist(r *http.Request) {
+	if h.URLReputation == nil {
+		return
+	}
+	if err := h.URLReputation.ReloadManual(r.Context()); err != nil {
+		logging.FromContext(r.Context()).Warn("Manual URL blocklist not reloaded", "error", err)
+	}
+}
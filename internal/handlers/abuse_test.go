@@ -0,0 +1,86 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+func TestURLBlocklist(t *testing.T) {
+	h := testutil.NewHandlers()
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	generate := func(data string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/generate/qr", strings.NewReader(`{"type":"url","data":"`+data+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.QRHandler(rec, req)
+		return rec
+	}
+	target := "https://login.phish.example/reset?account=ada@example.com"
+	if rec := generate(target); rec.Code != http.StatusOK {
+		t.Fatalf("before blocking: status %d: %s", rec.Code, rec.Body)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/admin/url-blocklist", strings.NewReader(`{"domain":"*.Phish.Example.","reason":"campaign"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.BlockDomainHandler(rec, req)
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"domain":"phish.example"`) {
+		t.Fatalf("block: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = generate(target)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("after blocking: status %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Code   string `json:"code"`
+		Source string `json:"source"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Code != "URL_FLAGGED" || body.Source != "blocklist" {
+		t.Errorf("error = %+v, %v; want url_flagged by the blocklist", body, err)
+	}
+	// The abuse event names the host, not the path and query
+	if !strings.Contains(logs.String(), "host=login.phish.example source=blocklist") ||
+		strings.Contains(logs.String(), "ada@example.com") {
+		t.Errorf("abuse event: %s", logs.String())
+	}
+	// Other QR types are not checked
+	if rec := generate("phish.example"); rec.Code == http.StatusUnprocessableEntity {
+		t.Error("a text QR code was checked as a URL")
+	}
+
+	unblock := func(domain string) int {
+		req := mux.SetURLVars(httptest.NewRequest("DELETE", "/api/v1/admin/url-blocklist/"+domain, nil), map[string]string{"domain": domain})
+		rec := httptest.NewRecorder()
+		h.UnblockDomainHandler(rec, req)
+		return rec.Code
+	}
+	if code := unblock("PHISH.example"); code != http.StatusNoContent {
+		t.Fatalf("unblock: status %d", code)
+	}
+	if rec := generate(target); rec.Code != http.StatusOK {
+		t.Errorf("after unblocking: status %d: %s", rec.Code, rec.Body)
+	}
+	if code := unblock("phish.example"); code != http.StatusNotFound {
+		t.Errorf("unblocking again: status %d, want 404", code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/admin/url-blocklist", strings.NewReader(`{"domain":"https://phish.example/"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	h.BlockDomainHandler(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "domain") {
+		t.Errorf("blocking a URL: status %d: %s", rec.Code, rec.Body)
+	}
+}
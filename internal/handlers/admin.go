@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+)
+
+// DNSCacheStatsHandler reports the hit, negative hit and miss counters of
+// the DNS cache behind email validation
+func (h *Handlers) DNSCacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.DNSCache == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "DNS cache is not configured")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, h.DNSCache.Stats())
+}
+
+// FlushDNSCacheHandler drops every cached DNS answer, e.g. after a domain
+// that was cached as nonexistent has been registered
+func (h *Handlers) FlushDNSCacheHandler(w http.ResponseWriter, r *http.Request) {
+	if h.DNSCache == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "DNS cache is not configured")
+		return
+	}
+	flushed := h.DNSCache.Flush()
+	logging.FromContext(r.Context()).Info("DNS cache flushed", "entries", flushed)
+	writeJSON(w, r, http.StatusOK, map[string]int{"flushed": flushed})
+}
+
+// ReloadDisposableDomainsHandler reads the disposable email domain list
+// again from DISPOSABLE_DOMAINS_PATH, or restores the built-in list when
+// it is unset. A file that cannot be read or parsed keeps the list in use.
+func (h *Handlers) ReloadDisposableDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	n, err := validation.ReloadDisposableDomains()
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Disposable domain reload failed", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, err.Error())
+		return
+	}
+	logging.FromContext(r.Context()).Info("Disposable domains reloaded", "domains", n)
+	writeJSON(w, r, http.StatusOK, map[string]int{"domains": n})
+}
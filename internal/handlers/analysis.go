@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/imagedecode"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/analysis"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/textsafety"
+)
+
+// AnalyzeDistanceHandler handles distance and geofence analysis requests
+func (h *Handlers) AnalyzeDistanceHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.DistanceRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	result, err := analysis.Analyze(r.Context(), h.GeoIP, req)
+	if errors.Is(err, validation.ErrGeoDBUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	body := map[string]interface{}{"analysisResult": result}
+	h.addResultMeta(r, body)
+	writeJSON(w, r, http.StatusOK, body)
+}
+
+// AnalyzeQRHandler describes the symbol a QR generation request encodes
+// to instead of rendering it
+func AnalyzeQRHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.QRRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	r = collectWarnings(r)
+
+	if req.Options.SanitizeText {
+		req.Data = sanitizeGeneratorText(w, r, req.Data)
+	}
+
+	result, err := generator.AnalyzeQR(r.Context(), req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	body := map[string]interface{}{"analysisResult": result}
+	addWarnings(r, body)
+	writeJSON(w, r, http.StatusOK, body)
+}
+
+// AnalyzeDuplicatesHandler handles duplicate and near-duplicate detection requests
+func AnalyzeDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.DuplicatesRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	result, err := analysis.FindDuplicates(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"analysisResult": result})
+}
+
+// AnalyzeTextSafetyHandler handles invisible/bidi character analysis requests
+func AnalyzeTextSafetyHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.TextSafetyRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	reports, err := textsafety.AnalyzeAll(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"analysisResult": reports})
+}
+
+const (
+	// maxImageHashUploadBytes caps a multipart body of two images
+	maxImageHashUploadBytes = 2*imagedecode.MaxBytes + 1<<20
+	// maxImageHashJSONBytes caps a JSON body of two base64 images
+	maxImageHashJSONBytes = 2*(imagedecode.MaxBytes/3+1)*4 + 1<<20
+)
+
+var errImageTooLarge = fmt.Errorf("%w: at most %d MB is accepted", imagedecode.ErrTooLarge, imagedecode.MaxBytes>>20)
+
+// AnalyzeImageHashHandler returns the perceptual hashes of one image, or of
+// two with their distances. Images come as base64 strings in a JSON body or
+// as files in the multipart field "images".
+func AnalyzeImageHashHandler(w http.ResponseWriter, r *http.Request) {
+	var images [][]byte
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		images, err = readImageUploads(w, r)
+	} else {
+		r.Body = http.MaxBytesReader(w, r.Body, maxImageHashJSONBytes)
+		var req models.ImageHashRequest
+		if !decodeJSONBody(w, r, &req) {
+			return
+		}
+		images, err = decodeBase64Images(req.Images)
+	}
+	if err != nil {
+		writeImageHashError(w, err)
+		return
+	}
+
+	result, err := analysis.HashImages(images)
+	if err != nil {
+		writeImageHashError(w, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"analysisResult": result})
+}
+
+// readImageUploads reads the files of the multipart field "images"
+func readImageUploads(w http.ResponseWriter, r *http.Request) ([][]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageHashUploadBytes)
+	if err := r.ParseMultipartForm(maxImageHashUploadBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, errImageTooLarge
+		}
+		return nil, errors.New("expected a multipart/form-data body with image files")
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	var images [][]byte
+	for _, header := range r.MultipartForm.File["images"] {
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(file, imagedecode.MaxBytes+1))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, data)
+	}
+	return images, nil
+}
+
+// decodeBase64Images decodes base64 images, each optionally a data URL
+func decodeBase64Images(encoded []string) ([][]byte, error) {
+	images := make([][]byte, len(encoded))
+	for i, value := range encoded {
+		if strings.HasPrefix(value, "data:") {
+			_, payload, ok := strings.Cut(value, ";base64,")
+			if !ok {
+				return nil, fmt.Errorf("image %d: data URL must be base64 encoded", i+1)
+			}
+			value = payload
+		}
+		if base64.StdEncoding.DecodedLen(len(value)) > imagedecode.MaxBytes+2 {
+			return nil, fmt.Errorf("image %d: %w", i+1, errImageTooLarge)
+		}
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("image %d: invalid base64", i+1)
+		}
+		images[i] = data
+	}
+	return images, nil
+}
+
+func writeImageHashError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, imagedecode.ErrTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+	case errors.Is(err, imagedecode.ErrUnsupportedFormat):
+		writeError(w, http.StatusUnsupportedMediaType, err)
+	default:
+		writeError(w, http.StatusBadRequest, err)
+	}
+}
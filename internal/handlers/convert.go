@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/converter"
+)
+
+// ConvertTextHandler handles text case and slug conversion requests
+func ConvertTextHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.TextConvertRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	results, err := converter.ConvertText(req)
+	if err != nil {
+		if err == converter.ErrTextTooLarge {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"convertResult": results})
+}
+
+// ConvertTimestampHandler converts an epoch or a datetime to its UTC forms
+// and its local time in the requested zones. An unknown zone name is a 400
+// carrying the closest names the tz database has.
+func ConvertTimestampHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.TimestampConvertRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	result, err := converter.ConvertTimestamp(req)
+	var zoneErr *converter.UnknownTimezoneError
+	switch {
+	case errors.As(err, &zoneErr):
+		code, field := ErrorCode(http.StatusBadRequest, err)
+		writeAPIError(w, http.StatusBadRequest, map[string]interface{}{
+			"message":     err.Error(),
+			"code":        code,
+			"field":       field,
+			"suggestions": zoneErr.Suggestions,
+		})
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"convertResult": result})
+}
+
+// ConvertCSVHandler converts CSV to JSON and back. The input is a JSON
+// body, or a text/csv or text/plain body of CSV converted to JSON with the
+// options as query parameters. The whole input is checked before the
+// response starts, so a malformed row is a 400; the output is then
+// streamed.
+func (h *Handlers) ConvertCSVHandler(w http.ResponseWriter, r *http.Request) {
+	site := h.site()
+	r.Body = http.MaxBytesReader(w, r.Body, int64(site.CSVConvertMaxBytes))
+
+	var req models.CSVConvertRequest
+	var csvData []byte
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "", "application/json":
+		if !decodeJSONBody(w, r, &req) {
+			return
+		}
+	case "text/csv", "text/plain":
+		body, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteError(w, http.StatusRequestEntityTooLarge, BodyTooLargeErrorCode, "request body is too large")
+			return
+		}
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, InvalidRequestErrorCode, "failed to read the body")
+			return
+		}
+		csvData = body
+		query := r.URL.Query()
+		req.Mode = query.Get("mode")
+		if req.Mode == "" {
+			req.Mode = converter.CSVToJSON
+		}
+		req.Options.Delimiter = query.Get("delimiter")
+		if hasHeader, err := strconv.ParseBool(query.Get("has_header")); err == nil {
+			req.Options.HasHeader = &hasHeader
+		}
+		req.Options.InferTypes, _ = strconv.ParseBool(query.Get("infer_types"))
+	default:
+		WriteError(w, http.StatusUnsupportedMediaType, UnsupportedMediaTypeErrorCode, "unsupported content type: use application/json, text/csv or text/plain")
+		return
+	}
+
+	conversion, err := converter.ConvertCSV(req, csvData, site.CSVConvertMaxRows)
+	switch {
+	case errors.Is(err, converter.ErrTooManyRows):
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if conversion.Mode() == converter.JSONToCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if err := conversion.Stream(bodyWriter{w, r}); err != nil && err != errClientGone {
+			logging.FromContext(r.Context()).Error("CSV conversion failed while streaming", "error", err)
+		}
+		return
+	}
+
+	// The fields of models.CSVConvertResult before rows
+	head, err := json.Marshal(struct {
+		Mode     string   `json:"mode"`
+		RowCount int      `json:"rowCount"`
+		Columns  []string `json:"columns"`
+	}{conversion.Mode(), conversion.Rows(), conversion.Columns()})
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to encode response")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	body := bodyWriter{w, r}
+	if _, err := body.Write(append([]byte(`{"convertResult":`), append(head[:len(head)-1], `,"rows":`...)...)); err != nil {
+		return
+	}
+	if err := conversion.Stream(body); err != nil {
+		if err != errClientGone {
+			logging.FromContext(r.Context()).Error("CSV conversion failed while streaming", "error", err)
+		}
+		return
+	}
+	body.Write([]byte("}}\n"))
+}
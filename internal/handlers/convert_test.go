@@ -0,0 +1,77 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+func TestConvertCSVHandler(t *testing.T) {
+	h := testutil.NewHandlers()
+	convert := func(contentType, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		h.ConvertCSVHandler(rec, req)
+		return rec
+	}
+
+	rec := convert("application/json", "/api/v1/convert/csv",
+		`{"mode":"csv_to_json","data":"name;age\nAda;36\n\"Hopper; Grace\";\n","options":{"delimiter":";","infer_types":true}}`)
+	var got struct {
+		ConvertResult models.CSVConvertResult `json:"convertResult"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("csv_to_json: status %d, %v: %s", rec.Code, err, rec.Body)
+	}
+	result := got.ConvertResult
+	if result.Mode != "csv_to_json" || result.RowCount != 2 || strings.Join(result.Columns, ",") != "name,age" ||
+		len(result.Rows) != 2 || result.Rows[0]["age"] != 36.0 || result.Rows[1]["name"] != "Hopper; Grace" || result.Rows[1]["age"] != nil {
+		t.Errorf("csv_to_json = %+v", result)
+	}
+
+	rec = convert("text/csv", "/api/v1/convert/csv?has_header=false", "\xEF\xBB\xBFa,b\n")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"rows":[{"column_1":"a","column_2":"b"}]`) {
+		t.Errorf("CSV body: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = convert("application/json", "/api/v1/convert/csv", `{"mode":"json_to_csv","data":[{"id":1,"tags":"a,b"},{"id":2,"note":"x"}]}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" ||
+		rec.Body.String() != "id,tags,note\r\n1,\"a,b\",\r\n2,,x\r\n" {
+		t.Errorf("json_to_csv: status %d, %q: %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+
+	// A malformed row late in the input is a 400, not a truncated 200
+	rec = convert("text/csv", "/api/v1/convert/csv", "a,b\n"+strings.Repeat("1,2\n", 1000)+"3\n")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "line 1002: expected 2 fields, got 1") {
+		t.Errorf("ragged row: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestConvertCSVLimits(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Config.CSVConvertMaxBytes = 64
+	h.Config.CSVConvertMaxRows = 2
+	for _, tt := range []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{"a\n" + strings.Repeat("x", 64) + "\n", http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE"},
+		{"a\n1\n2\n3\n", http.StatusRequestEntityTooLarge, "DATA_TOO_LONG"},
+		{"a\n1\n2\n", http.StatusOK, ""},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/convert/csv", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "text/csv")
+		rec := httptest.NewRecorder()
+		h.ConvertCSVHandler(rec, req)
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.code) {
+			t.Errorf("%q: status %d: %s", tt.body, rec.Code, rec.Body)
+		}
+	}
+}
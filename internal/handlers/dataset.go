@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/dataset"
+)
+
+// ListDatasetsHandler lists the registered catalogs with their versions
+func ListDatasetsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"datasets": dataset.List()})
+}
+
+// GetDatasetHandler serves a catalog's contents with ETag and
+// Last-Modified validators so clients can poll for updates cheaply
+func GetDatasetHandler(w http.ResponseWriter, r *http.Request) {
+	info, data, err := dataset.Get(mux.Vars(r)["name"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	etag := `"` + info.Version + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", info.UpdatedAt.Format(http.TimeFormat))
+	w.Header().Set("X-Dataset-Version", info.Version)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if datasetNotModified(r, etag, info.UpdatedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"dataset": info,
+		"data":    data,
+	})
+}
+
+// datasetNotModified applies If-None-Match, falling back to If-Modified-Since
+// only when no entity tag was sent, as RFC 9110 requires.
+func datasetNotModified(r *http.Request, etag string, updatedAt time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil && !updatedAt.After(t) {
+			return true
+		}
+	}
+	return false
+}
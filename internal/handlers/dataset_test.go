@@ -0,0 +1,92 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/dataset"
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+)
+
+// getDataset requests the catalog name with the conditional headers
+func getDataset(name string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/api/v1/datasets/"+name, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req = mux.SetURLVars(req, map[string]string{"name": name})
+	rec := httptest.NewRecorder()
+	handlers.GetDatasetHandler(rec, req)
+	return rec
+}
+
+func TestGetDatasetConditional(t *testing.T) {
+	info := dataset.Register("test-fruit", []string{"apple", "pear"})
+	etag := `"` + info.Version + `"`
+
+	rec := getDataset("test-fruit", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != etag || rec.Header().Get("X-Dataset-Version") != info.Version {
+		t.Fatalf("status = %d, ETag = %q, X-Dataset-Version = %q", rec.Code, rec.Header().Get("ETag"), rec.Header().Get("X-Dataset-Version"))
+	}
+	lastModified := rec.Header().Get("Last-Modified")
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{"matching ETag", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"weak ETag in a list", map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified},
+		{"wildcard", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"stale ETag", map[string]string{"If-None-Match": `"0000"`}, http.StatusOK},
+		{"not modified since", map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+		{"modified since", map[string]string{"If-Modified-Since": info.UpdatedAt.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK},
+		// If-None-Match wins over If-Modified-Since
+		{"stale ETag, not modified since", map[string]string{"If-None-Match": `"0000"`, "If-Modified-Since": lastModified}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := getDataset("test-fruit", tt.headers)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if tt.status == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Errorf("%s: 304 with a body", tt.name)
+		}
+	}
+
+	// A reload with new contents invalidates the old ETag
+	reloaded := dataset.Register("test-fruit", []string{"apple", "pear", "plum"})
+	rec = getDataset("test-fruit", map[string]string{"If-None-Match": etag})
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Dataset-Version") != reloaded.Version || reloaded.Version == info.Version {
+		t.Errorf("after a reload: status = %d, version %q, want 200 with a new version", rec.Code, rec.Header().Get("X-Dataset-Version"))
+	}
+
+	if rec := getDataset("test-unknown", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown dataset: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestListDatasets(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handlers.ListDatasetsHandler(rec, httptest.NewRequest("GET", "/api/v1/datasets", nil))
+	var body struct {
+		Datasets []dataset.Dataset `json:"datasets"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, d := range body.Datasets {
+		names[d.Name] = d.Version != ""
+	}
+	for _, want := range []string{validation.DisposableDomainsDataset, validation.IBANCountriesDataset} {
+		if !names[want] {
+			t.Errorf("datasets %v lack a version of %s", names, want)
+		}
+	}
+}
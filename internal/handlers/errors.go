@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/innovelabs/microtools-go/internal/jobs"
+	"github.com/innovelabs/microtools-go/internal/maintenance"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/services/analysis"
+	"github.com/innovelabs/microtools-go/internal/services/converter"
+	"github.com/innovelabs/microtools-go/internal/services/extract"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/services/parser"
+	"github.com/innovelabs/microtools-go/internal/services/tools"
+	"github.com/innovelabs/microtools-go/internal/services/transform"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/shortener"
+	"github.com/innovelabs/microtools-go/internal/tenant"
+	"github.com/innovelabs/microtools-go/internal/uploads"
+	"github.com/innovelabs/microtools-go/internal/wifirotation"
+)
+
+// Error codes of models.APIError. They are part of the API: clients switch
+// on them, so an existing code is never renamed or reused for another
+// failure.
+const (
+	// InvalidJSONErrorCode marks a body that is not the JSON the endpoint
+	// takes
+	InvalidJSONErrorCode = "INVALID_JSON"
+	// UnknownFieldErrorCode marks a JSON body field the endpoint does not
+	// take, e.g. a misspelt option
+	UnknownFieldErrorCode = "UNKNOWN_FIELD"
+	// BodyTooLargeErrorCode marks a request body over the route's cap
+	BodyTooLargeErrorCode = "BODY_TOO_LARGE"
+	// UnsupportedMediaTypeErrorCode marks a body of a content type the
+	// endpoint does not read
+	UnsupportedMediaTypeErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	// InvalidRequestErrorCode marks a request refused for a reason without
+	// a more specific code
+	InvalidRequestErrorCode = "INVALID_REQUEST"
+	// MissingFieldErrorCode marks a required field that was not sent
+	MissingFieldErrorCode = "MISSING_FIELD"
+	// UnsupportedTypeErrorCode marks a QR, barcode or symbology type the
+	// service does not generate or validate
+	UnsupportedTypeErrorCode = "UNSUPPORTED_TYPE"
+	// UnsupportedFormatErrorCode marks an unknown output format
+	UnsupportedFormatErrorCode = "UNSUPPORTED_FORMAT"
+	// InvalidDataErrorCode marks data the requested type cannot hold
+	InvalidDataErrorCode = "INVALID_DATA"
+	// DataTooLongErrorCode marks input longer, or with more items, than
+	// the endpoint accepts
+	DataTooLongErrorCode = "DATA_TOO_LONG"
+	// InvalidOptionErrorCode marks an option out of range, unknown or not
+	// allowed with the others
+	InvalidOptionErrorCode = "INVALID_OPTION"
+	// UnauthorizedErrorCode marks a request needing an access token it
+	// did not carry
+	UnauthorizedErrorCode = "UNAUTHORIZED"
+	// NotFoundErrorCode marks an unknown route or resource
+	NotFoundErrorCode = "NOT_FOUND"
+	// MethodNotAllowedErrorCode marks a method the route does not serve
+	MethodNotAllowedErrorCode = "METHOD_NOT_ALLOWED"
+	// ConflictErrorCode marks a request conflicting with stored state
+	ConflictErrorCode = "CONFLICT"
+	// PendingDeletionErrorCode marks a registration of the email of a
+	// deleted user that is not purged yet
+	PendingDeletionErrorCode = "PENDING_DELETION"
+	// LimitExceededErrorCode marks a per-client limit on stored or
+	// running work, as opposed to the request rate limit
+	LimitExceededErrorCode = "LIMIT_EXCEEDED"
+	// UnavailableErrorCode marks a feature this instance is not
+	// configured for, or a dependency that is down
+	UnavailableErrorCode = "SERVICE_UNAVAILABLE"
+	// InternalErrorCode marks a failure of the service
+	InternalErrorCode = "INTERNAL_ERROR"
+)
+
+// errorCodes maps the errors of the services to a code and, when the
+// error is about one request field, its name. The first match wins.
+var errorCodes = []struct {
+	err   error
+	code  string
+	field string
+}{
+	{errUnsupportedContentType, UnsupportedMediaTypeErrorCode, ""},
+	{errPlainBodyTooLarge, BodyTooLargeErrorCode, ""},
+	{errEmptyPlainBody, MissingFieldErrorCode, ""},
+	{io.EOF, InvalidJSONErrorCode, ""},
+	{io.ErrUnexpectedEOF, InvalidJSONErrorCode, ""},
+
+	{generator.ErrQRTypeRequired, MissingFieldErrorCode, "type"},
+	{generator.ErrUnsupportedQRType, UnsupportedTypeErrorCode, "type"},
+	{generator.ErrQRDataRequired, MissingFieldErrorCode, "data"},
+	{generator.ErrInvalidQRData, InvalidDataErrorCode, "data"},
+	{generator.ErrQRDataTooLong, DataTooLongErrorCode, "data"},
+	{generator.ErrInvalidQRSize, InvalidOptionErrorCode, "options.size"},
+	{generator.ErrUnsupportedQRFormat, UnsupportedFormatErrorCode, "options.format"},
+	{generator.ErrUnsupportedQROutput, UnsupportedFormatErrorCode, "options.output"},
+	{generator.ErrInvalidQRLogo, InvalidOptionErrorCode, "options.logo"},
+	{generator.ErrQRLogoTooLarge, DataTooLongErrorCode, "options.logo"},
+	{generator.ErrInvalidQRLogoSize, InvalidOptionErrorCode, "options.logo_size_percent"},
+	{generator.ErrQRLogoLevel, InvalidOptionErrorCode, "options.error_correction"},
+	{generator.ErrAccessibleTextTooLong, DataTooLongErrorCode, "title"},
+	{generator.ErrInvalidBackgroundColor, InvalidOptionErrorCode, "background_color"},
+	{generator.ErrInvalidForegroundColor, InvalidOptionErrorCode, "foreground_color"},
+	{generator.ErrInvalidTextColor, InvalidOptionErrorCode, "text_color"},
+	{generator.ErrLowContrast, InvalidOptionErrorCode, "foreground_color"},
+	{generator.ErrInvalidType, UnsupportedTypeErrorCode, "type"},
+	{generator.ErrInvalidFormat, UnsupportedFormatErrorCode, "format"},
+	{generator.ErrInvalidOutput, UnsupportedFormatErrorCode, "output"},
+	{generator.ErrInvalidData, InvalidDataErrorCode, "data"},
+	{generator.ErrChecksumMismatch, InvalidDataErrorCode, "data"},
+	{generator.ErrUnknownFont, InvalidOptionErrorCode, "font"},
+	{generator.ErrInvalidOptions, InvalidOptionErrorCode, ""},
+	{generator.ErrInvalidTokenRequest, InvalidOptionErrorCode, ""},
+	{generator.ErrInvalidUUIDRequest, InvalidOptionErrorCode, ""},
+	{generator.ErrInvalidEvent, InvalidDataErrorCode, "events"},
+	{generator.ErrTooManyLabels, DataTooLongErrorCode, "file"},
+	{generator.ErrInvalidLabelCSV, InvalidDataErrorCode, "file"},
+	{generator.ErrInvalidLabelOptions, InvalidOptionErrorCode, ""},
+
+	{validation.ErrInvalidIP, InvalidDataErrorCode, "ip"},
+	{validation.ErrIPNotFound, NotFoundErrorCode, "ip"},
+	{validation.ErrGeoDBUnavailable, UnavailableErrorCode, ""},
+	{validation.ErrUnsupportedCheck, InvalidOptionErrorCode, "checks"},
+	{validation.ErrUnsupportedCountry, UnsupportedTypeErrorCode, "countryCode"},
+	{validation.ErrUnknownSymbology, UnsupportedTypeErrorCode, "symbology"},
+	{validation.ErrInvalidCardNumber, InvalidDataErrorCode, "number"},
+	{validation.ErrInvalidSchema, InvalidDataErrorCode, "schema"},
+	{validation.ErrInvalidDocument, InvalidDataErrorCode, "document"},
+
+	{parser.ErrInvalidNumber, InvalidDataErrorCode, "value"},
+	{parser.ErrUnsupportedLocale, InvalidOptionErrorCode, "locale"},
+	{parser.ErrTooManyValues, DataTooLongErrorCode, "values"},
+	{extract.ErrInputTooLarge, DataTooLongErrorCode, ""},
+	{transform.ErrInputTooLarge, DataTooLongErrorCode, "html"},
+	{transform.ErrInvalidOption, InvalidOptionErrorCode, ""},
+	{converter.ErrTextTooLarge, DataTooLongErrorCode, "text"},
+	{converter.ErrInvalidOperation, InvalidOptionErrorCode, "operations"},
+	{converter.ErrInvalidCSVRequest, InvalidOptionErrorCode, ""},
+	{converter.ErrMalformedCSV, InvalidDataErrorCode, "data"},
+	{converter.ErrMalformedRows, InvalidDataErrorCode, "data"},
+	{converter.ErrTooManyRows, DataTooLongErrorCode, "data"},
+	{converter.ErrInvalidTimestamp, InvalidOptionErrorCode, ""},
+	{converter.ErrUnknownTimezone, InvalidOptionErrorCode, "timezone"},
+	{tools.ErrRegexTooLarge, DataTooLongErrorCode, ""},
+	{tools.ErrRegexOutputTooLarge, DataTooLongErrorCode, "replacement"},
+	{tools.ErrRegexTimeout, LimitExceededErrorCode, ""},
+	{tools.ErrInvalidPattern, InvalidDataErrorCode, "pattern"},
+	{tools.ErrInvalidRegex, InvalidOptionErrorCode, ""},
+	{tools.ErrInvalidPassword, InvalidDataErrorCode, "password"},
+	{tools.ErrPasswordTooLong, DataTooLongErrorCode, "password"},
+	{tools.ErrInvalidPasswordOptions, InvalidOptionErrorCode, ""},
+	{analysis.ErrInvalidMode, InvalidOptionErrorCode, "mode"},
+	{analysis.ErrInvalidPoint, InvalidDataErrorCode, ""},
+	{analysis.ErrInvalidFence, InvalidDataErrorCode, ""},
+
+	{jobs.ErrJobNotFound, NotFoundErrorCode, ""},
+	{jobs.ErrJobNotFinished, ConflictErrorCode, ""},
+	{jobs.ErrTooManyJobs, LimitExceededErrorCode, ""},
+	{jobs.ErrClientJobLimit, LimitExceededErrorCode, ""},
+	{uploads.ErrUploadNotFound, NotFoundErrorCode, ""},
+	{uploads.ErrTooManyUploads, LimitExceededErrorCode, ""},
+	{uploads.ErrOwnerUploadLimit, LimitExceededErrorCode, ""},
+	{uploads.ErrHashMismatch, InvalidDataErrorCode, "sha256"},
+	{repository.ErrTenantNotFound, NotFoundErrorCode, ""},
+	{repository.ErrBlockedDomainNotFound, NotFoundErrorCode, "domain"},
+	{repository.ErrWifiProfileNotFound, NotFoundErrorCode, ""},
+	{repository.ErrWifiProfileExists, ConflictErrorCode, "name"},
+	{repository.ErrWifiProfileConflict, ConflictErrorCode, ""},
+	{repository.ErrShortURLNotFound, NotFoundErrorCode, ""},
+	{repository.ErrShortURLExpired, NotFoundErrorCode, ""},
+	{repository.ErrShortURLExists, ConflictErrorCode, "custom_alias"},
+	{tenant.ErrInvalidTenant, InvalidRequestErrorCode, ""},
+	{wifirotation.ErrInvalidNetwork, InvalidRequestErrorCode, ""},
+	{shortener.ErrInvalidShortURL, InvalidDataErrorCode, ""},
+	{maintenance.ErrUnknownTool, InvalidOptionErrorCode, "tool"},
+}
+
+// statusErrorCodes are the codes of errors without one of their own
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:            InvalidRequestErrorCode,
+	http.StatusUnauthorized:          UnauthorizedErrorCode,
+	http.StatusNotFound:              NotFoundErrorCode,
+	http.StatusMethodNotAllowed:      MethodNotAllowedErrorCode,
+	http.StatusConflict:              ConflictErrorCode,
+	http.StatusGone:                  NotFoundErrorCode,
+	http.StatusRequestEntityTooLarge: BodyTooLargeErrorCode,
+	http.StatusUnsupportedMediaType:  UnsupportedMediaTypeErrorCode,
+	http.StatusUnprocessableEntity:   InvalidDataErrorCode,
+	http.StatusTooManyRequests:       LimitExceededErrorCode,
+	http.StatusServiceUnavailable:    UnavailableErrorCode,
+}
+
+// ErrorCode returns the code and field of err answered with status: the
+// code of the service error it wraps, else the code of a JSON syntax,
+// type or unknown field error, else the generic code of status
+func ErrorCode(status int, err error) (code, field string) {
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known.code, known.field
+		}
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if field, ok := unknownJSONField(err); ok {
+		return UnknownFieldErrorCode, field
+	}
+	switch {
+	case errors.As(err, &syntaxErr):
+		return InvalidJSONErrorCode, ""
+	case errors.As(err, &typeErr):
+		return InvalidJSONErrorCode, typeErr.Field
+	}
+	return StatusErrorCode(status), ""
+}
+
+// StatusErrorCode returns the generic code of an error answered with
+// status
+func StatusErrorCode(status int) string {
+	if code, ok := statusErrorCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return InternalErrorCode
+	}
+	return InvalidRequestErrorCode
+}
+
+// WriteError writes the models.APIError of a failure with status, code
+// and message
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	writeAPIError(w, status, models.APIError{Message: message, Code: code})
+}
+
+// writeError answers with err and the code ErrorCode gives it
+func writeError(w http.ResponseWriter, status int, err error) {
+	code, field := ErrorCode(status, err)
+	writeAPIError(w, status, models.APIError{Message: err.Error(), Code: code, Field: field})
+}
+
+// writeAPIError writes body as the response with status
+func writeAPIError(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/extract"
+)
+
+// maxExtractBodyBytes leaves room for the JSON escaping of a text of
+// extract.MaxInputBytes
+const maxExtractBodyBytes = 2 * extract.MaxInputBytes
+
+// ExtractEmailsHandler finds the email addresses in a text or HTML page.
+// The input is a JSON body, or a text/plain or text/html body with the
+// options as query parameters.
+func ExtractEmailsHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxExtractBodyBytes)
+
+	var req models.EmailExtractRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "", "application/json":
+		if !decodeJSONBody(w, r, &req) {
+			return
+		}
+	case "text/plain", "text/html":
+		body, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, extract.ErrInputTooLarge)
+			return
+		}
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, InvalidRequestErrorCode, "failed to read the body")
+			return
+		}
+		if mediaType == "text/html" {
+			req.HTML = string(body)
+		} else {
+			req.Text = string(body)
+		}
+		query := r.URL.Query()
+		req.IncludeObfuscated, _ = strconv.ParseBool(query.Get("include_obfuscated"))
+		req.Validate, _ = strconv.ParseBool(query.Get("validate"))
+	default:
+		WriteError(w, http.StatusUnsupportedMediaType, UnsupportedMediaTypeErrorCode, "unsupported content type: use application/json, text/plain or text/html")
+		return
+	}
+
+	result, err := extract.ExtractEmails(r.Context(), req)
+	switch {
+	case errors.Is(err, extract.ErrInputTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"extractResult": result})
+}
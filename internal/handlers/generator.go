@@ -1,55 +1,444 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/innovelabs/microtools-go/internal/logging"
 	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/policy"
+	"github.com/innovelabs/microtools-go/internal/quota"
 	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/textsafety"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"github.com/innovelabs/microtools-go/internal/warnings"
+	"github.com/innovelabs/microtools-go/pkg/generate/svgmeta"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// QRHandler handles QR code generation requests
-func QRHandler(w http.ResponseWriter, r *http.Request) {
+// barcodeWidthHeader and barcodeHeightHeader report the size of a generated
+// barcode image, which differs from the request when fit_mode snapped it
+const (
+	barcodeWidthHeader  = "X-Barcode-Width"
+	barcodeHeightHeader = "X-Barcode-Height"
+)
+
+// accessibleNameHeader reports the title written into SVG output, so
+// clients embedding the image can reuse it as its alt text
+const accessibleNameHeader = "X-Accessible-Name"
+
+// setAccessibleNameHeader sets accessibleNameHeader for SVG output with a
+// title
+func setAccessibleNameHeader(w http.ResponseWriter, contentType string, meta svgmeta.Metadata) {
+	if contentType == "image/svg+xml" && meta.Title != "" {
+		w.Header().Set(accessibleNameHeader, meta.Title)
+	}
+}
+
+// QRHandler handles QR code generation requests: a JSON body on POST, or
+// the query string of GET so an <img src> can embed the image
+func (h *Handlers) QRHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.QRRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+	if r.Method == http.MethodGet {
+		var err error
+		if req, err = qrRequestFromQuery(r.URL.Query()); err != nil {
+			WriteError(w, http.StatusBadRequest, InvalidOptionErrorCode, err.Error())
+			return
+		}
+	} else if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	r = collectWarnings(r)
+
+	generator.ApplyDefaults(&req)
+	if err := policy.FromContext(r.Context()).CheckQRSize(req.Options.Size); err != nil {
+		writePolicyError(w, err)
+		return
+	}
+
+	if req.Options.SanitizeText {
+		req.Data = sanitizeGeneratorText(w, r, req.Data)
+	}
+	if !h.allowQRURL(w, r, req) {
+		return
+	}
+	if !h.spendPixels(w, r, quota.QRCost(req.Options.Size)) {
 		return
 	}
 
-	png, err := generator.GenerateQR(req)
+	ctx, span := tracing.Start(r.Context(), "qr.encode", attribute.String("qr.type", req.Type), attribute.Int("image.size", req.Options.Size))
+	data, contentType, err := generator.RenderQR(ctx, req)
+	tracing.End(span, err)
 	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, err.Error())
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "image/png")
+	writeWarningHeaders(w, r)
+	setAccessibleNameHeader(w, contentType, generator.QRAccessibleName(req))
+	if req.Options.Output == generator.OutputJSON {
+		// The payload was built without error to render the code
+		payload, _ := generator.BuildPayload(req.Type, req.Data)
+		writeJSON(w, r, http.StatusOK, models.GeneratedImage{
+			Data:          base64.StdEncoding.EncodeToString(data),
+			ContentType:   contentType,
+			Size:          req.Options.Size,
+			Type:          req.Type,
+			PayloadLength: len(payload),
+		})
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(http.StatusOK)
-	w.Write(png)
+	writeBody(w, r, data)
+}
+
+// qrRequestFromQuery maps the query string of GET /generate/qr to a
+// request: type and data, and the options by their JSON names. Values are
+// form-decoded, so a + in data is a space and a literal + is %2B.
+func qrRequestFromQuery(query url.Values) (models.QRRequest, error) {
+	req := models.QRRequest{
+		Type: query.Get("type"),
+		Data: query.Get("data"),
+		Options: models.QROptions{
+			ErrorCorrection: query.Get("error_correction"),
+			Format:          query.Get("format"),
+			Output:          query.Get("output"),
+			Title:           query.Get("title"),
+			Desc:            query.Get("desc"),
+		},
+	}
+	var err error
+	if size := query.Get("size"); size != "" {
+		if req.Options.Size, err = strconv.Atoi(size); err != nil {
+			return req, errors.New("size must be a number")
+		}
+	}
+	for name, flag := range map[string]*bool{
+		"sanitize_text": &req.Options.SanitizeText,
+		"deterministic": &req.Options.Deterministic,
+	} {
+		if value := query.Get(name); value != "" {
+			if *flag, err = strconv.ParseBool(value); err != nil {
+				return req, fmt.Errorf("%s must be true or false", name)
+			}
+		}
+	}
+	return req, nil
 }
 
-// GenerateBarcodeHandler handles barcode generation requests
-func GenerateBarcodeHandler(barcodeSvc generator.BarcodeService) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var req models.GenerateRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+// GenerateBarcodeHandler handles barcode generation requests: a JSON body
+// on POST, or the query string of GET so an <img src> can embed the image
+func (h *Handlers) GenerateBarcodeHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.GenerateRequest
+	if r.Method == http.MethodGet {
+		var err error
+		if req, err = barcodeRequestFromQuery(r.URL.Query()); err != nil {
+			WriteError(w, http.StatusBadRequest, InvalidOptionErrorCode, err.Error())
 			return
 		}
+	} else if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	r = collectWarnings(r)
+	req.Type = generator.CanonicalBarcodeType(req.Type)
 
-		data, contentType, err := barcodeSvc.Generate(req)
-		if err != nil {
-			writeJSONError(w, http.StatusBadRequest, err.Error())
+	routePolicy := policy.FromContext(r.Context())
+	if err := routePolicy.CheckBarcodeSize(req.Width, req.Height); err != nil {
+		writePolicyError(w, err)
+		return
+	}
+	req.MaxWidth = routePolicy.MaxBarcodeWidth
+
+	if req.SanitizeText {
+		req.Data = sanitizeGeneratorText(w, r, req.Data)
+	}
+	if !h.spendPixels(w, r, quota.BarcodeCost(generator.BarcodeSize(req))) {
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "barcode.encode", attribute.String("barcode.type", req.Type))
+	img, err := h.Barcodes.Generate(req)
+	tracing.End(span, err)
+	if err != nil {
+		var optErr *generator.BarcodeOptionError
+		if errors.As(err, &optErr) {
+			writeJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message":    err.Error(),
+				"code":       InvalidOptionErrorCode,
+				"violations": optErr.Violations,
+			})
 			return
 		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	collector := warnings.FromContext(r.Context())
+	for _, warning := range h.Barcodes.Warnings(req) {
+		collector.Add(warning.Code, warning.Field, warning.Message)
+	}
+	writeWarningHeaders(w, r)
+	w.Header().Set(barcodeWidthHeader, strconv.Itoa(img.Width))
+	w.Header().Set(barcodeHeightHeader, strconv.Itoa(img.Height))
+	setAccessibleNameHeader(w, img.ContentType, generator.BarcodeAccessibleName(req))
+	if req.Output == generator.OutputJSON {
+		writeJSON(w, r, http.StatusOK, models.GeneratedImage{
+			Data:          base64.StdEncoding.EncodeToString(img.Data),
+			ContentType:   img.ContentType,
+			Width:         img.Width,
+			Height:        img.Height,
+			Type:          req.Type,
+			PayloadLength: len(req.Data),
+		})
+		return
+	}
+	w.Header().Set("Content-Type", img.ContentType)
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, r, img.Data)
+}
+
+// barcodeRequestFromQuery maps the query string of GET /generate/barcode
+// to a request, the fields by their JSON names. Values are form-decoded
+// like those of qrRequestFromQuery.
+func barcodeRequestFromQuery(query url.Values) (models.GenerateRequest, error) {
+	req := models.GenerateRequest{
+		Data:            query.Get("data"),
+		Type:            query.Get("type"),
+		Format:          query.Get("format"),
+		Text:            query.Get("text"),
+		BackgroundColor: query.Get("background_color"),
+		ForegroundColor: query.Get("foreground_color"),
+		TextColor:       query.Get("text_color"),
+		TextPosition:    query.Get("text_position"),
+		Font:            query.Get("font"),
+		Supplement:      query.Get("supplement"),
+		FitMode:         query.Get("fit_mode"),
+		Output:          query.Get("output"),
+		Title:           query.Get("title"),
+		Desc:            query.Get("desc"),
+	}
+	var err error
+	for _, field := range []struct {
+		name  string
+		value *int
+	}{
+		{"width", &req.Width},
+		{"height", &req.Height},
+		{"font_size", &req.FontSize},
+		{"padding", &req.Padding},
+	} {
+		if value := query.Get(field.name); value != "" {
+			if *field.value, err = strconv.Atoi(value); err != nil {
+				return req, fmt.Errorf("%s must be a number", field.name)
+			}
+		}
+	}
+	for _, flag := range []struct {
+		name  string
+		value *bool
+	}{
+		{"include_text", &req.IncludeText},
+		{"sanitize_text", &req.SanitizeText},
+		{"include_check_digit", &req.IncludeCheckDigit},
+		{"deterministic", &req.Deterministic},
+	} {
+		if value := query.Get(flag.name); value != "" {
+			if *flag.value, err = strconv.ParseBool(value); err != nil {
+				return req, fmt.Errorf("%s must be true or false", flag.name)
+			}
+		}
+	}
+	return req, nil
+}
+
+// TestVectorsHandler publishes the deterministic generator requests with
+// the SHA-256 of their output, which stay stable across releases
+func (h *Handlers) TestVectorsHandler(w http.ResponseWriter, r *http.Request) {
+	vectors, err := generator.TestVectors(r.Context(), h.Barcodes)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Test vectors are broken", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "test vectors are unavailable")
+		return
+	}
 
-		w.Header().Set("Content-Type", contentType)
-		w.WriteHeader(http.StatusOK)
-		w.Write(data)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"vectors": vectors})
+}
+
+// GenerateTokenHandler handles passphrase generation requests
+func GenerateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.TokenRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	token, err := generator.GenerateToken(req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, generator.ErrInvalidTokenRequest) {
+			status = http.StatusBadRequest
+		}
+		writeError(w, status, err)
+		return
 	}
+
+	// Generated secrets must not be stored by caches
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, token)
 }
 
-func writeJSONError(w http.ResponseWriter, status int, message string) {
+// uuidChunkBytes is how much of the ids array GenerateUUIDHandler buffers
+// before writing it
+const uuidChunkBytes = 4 << 10
+
+// GenerateUUIDHandler generates UUIDs or ULIDs from a JSON body on POST,
+// or from the version, count, uppercase and format query parameters on
+// GET. The ids array is written in chunks as it is generated instead of
+// being built whole.
+func (h *Handlers) GenerateUUIDHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.UUIDRequest
+	if r.Method == http.MethodGet {
+		var err error
+		if req, err = uuidRequestFromQuery(r.URL.Query()); err != nil {
+			WriteError(w, http.StatusBadRequest, InvalidOptionErrorCode, err.Error())
+			return
+		}
+	} else if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	now := h.Clock.Now().UTC()
+	ids, err := generator.NewUUIDGenerator(req, now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	req = ids.Request()
+	// The fields of models.UUIDResponse before ids
+	head, err := json.Marshal(struct {
+		Version     string    `json:"version"`
+		Format      string    `json:"format"`
+		Count       int       `json:"count"`
+		GeneratedAt time.Time `json:"generatedAt"`
+	}{req.Version, req.Format, req.Count, now})
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to encode response")
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	buf := append(head[:len(head)-1], `,"ids":[`...)
+	for i := 0; i < req.Count; i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		// Identifiers are hex, base32 and "urn:uuid:", which need no escaping
+		buf = append(ids.Append(append(buf, '"')), '"')
+		if len(buf) >= uuidChunkBytes {
+			if !writeBody(w, r, buf) {
+				return
+			}
+			buf = buf[:0]
+		}
+	}
+	writeBody(w, r, append(buf, "]}\n"...))
+}
+
+// uuidRequestFromQuery reads the options of a GET UUID request
+func uuidRequestFromQuery(query url.Values) (models.UUIDRequest, error) {
+	req := models.UUIDRequest{Version: query.Get("version"), Format: query.Get("format")}
+	var err error
+	if count := query.Get("count"); count != "" {
+		if req.Count, err = strconv.Atoi(count); err != nil {
+			return req, errors.New("count must be a number")
+		}
+	}
+	if uppercase := query.Get("uppercase"); uppercase != "" {
+		if req.Uppercase, err = strconv.ParseBool(uppercase); err != nil {
+			return req, errors.New("uppercase must be true or false")
+		}
+	}
+	return req, nil
+}
+
+// GenerateICSHandler handles iCalendar file generation requests
+func GenerateICSHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.ICSRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "ics.encode")
+	data, err := generator.GenerateICS(req)
+	tracing.End(span, err)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", icsFilename(req.Filename)))
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, r, data)
+}
+
+// icsFilename reduces a requested download name to a safe .ics file name
+func icsFilename(name string) string {
+	name = strings.TrimSuffix(name, ".ics")
+	clean := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		case r == ' ':
+			return '-'
+		default:
+			return -1
+		}
+	}, name)
+	clean = strings.Trim(clean, ".-")
+	if clean == "" {
+		clean = "calendar"
+	}
+	if len(clean) > 64 {
+		clean = clean[:64]
+	}
+	return clean + ".ics"
+}
+
+// BarcodeRulesHandler lists the barcode option combination rules
+func BarcodeRulesHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"rules": generator.BarcodeOptionRules()})
+}
+
+// sanitizeGeneratorText applies textsafety.Sanitize to generator input and
+// reports the changes as warnings. The X-Text-Sanitized-* headers predate
+// the warnings and are kept for existing clients.
+func sanitizeGeneratorText(w http.ResponseWriter, r *http.Request, data string) string {
+	clean, removed := textsafety.Sanitize(data)
+	codePoints := make([]string, len(removed))
+	for i, ch := range removed {
+		codePoints[i] = ch.CodePoint
+	}
+	normalized := !textsafety.IsNFC(data)
+	w.Header().Set("X-Text-Sanitized-Removed", strings.Join(codePoints, ","))
+	w.Header().Set("X-Text-Sanitized-Normalized", strconv.FormatBool(normalized))
+
+	collector := warnings.FromContext(r.Context())
+	if len(codePoints) > 0 {
+		collector.Add(warnings.CodeTextSanitized, "data", "removed unsafe characters: "+strings.Join(codePoints, ", "))
+	}
+	if normalized {
+		collector.Add(warnings.CodeTextNormalized, "data", "text was normalized to NFC")
+	}
+	return clean
 }
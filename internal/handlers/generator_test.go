@@ -0,0 +1,491 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestGenerateBarcodeOptionRules(t *testing.T) {
+	handler := testutil.NewHandlers().GenerateBarcodeHandler
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/generate/barcode", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := post(`{"type":"EAN-13","data":"400638133393","format":"png","text_position":"top","padding":-1}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Violations []models.BarcodeOptionViolation `json:"violations"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Violations) != 2 ||
+		body.Violations[0].Rule != "text_position_requires_text" || body.Violations[1].Rule != "padding_range" {
+		t.Errorf("body = %+v, want both violations", body)
+	}
+
+	rec = post(`{"type":"EAN-13","data":"400638133393","format":"png","width":285,"height":100}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("status = %d, Content-Type %q, want the PNG", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var list []models.Warning
+	if err := json.Unmarshal([]byte(rec.Header().Get("X-Warnings")), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Code != "ean_height_ratio" || !strings.HasPrefix(rec.Header().Get("Warning"), "299 - ") {
+		t.Errorf("warnings = %+v, Warning %q", list, rec.Header().Get("Warning"))
+	}
+}
+
+func TestBarcodeRules(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handlers.BarcodeRulesHandler(rec, httptest.NewRequest("GET", "/api/v1/generate/barcode/rules", nil))
+	var body struct {
+		Rules []struct {
+			ID       string   `json:"id"`
+			Fields   []string `json:"fields"`
+			Severity string   `json:"severity"`
+		} `json:"rules"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Rules) == 0 {
+		t.Fatal("no rules")
+	}
+	for _, rule := range body.Rules {
+		if rule.ID == "" || len(rule.Fields) == 0 || (rule.Severity != "error" && rule.Severity != "warning") {
+			t.Errorf("rule %+v", rule)
+		}
+	}
+}
+
+func TestGenerateBarcodeSanitizeText(t *testing.T) {
+	handler := testutil.NewHandlers().GenerateBarcodeHandler
+	body := `{"type":"Code128","data":"AB\u202e\u200b42","format":"png","sanitize_text":true}`
+	req := httptest.NewRequest("POST", "/api/v1/generate/barcode", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("X-Text-Sanitized-Removed"); got != "U+202E,U+200B" {
+		t.Errorf("X-Text-Sanitized-Removed = %q", got)
+	}
+	if got := rec.Header().Get("X-Text-Sanitized-Normalized"); got != "false" {
+		t.Errorf("X-Text-Sanitized-Normalized = %q", got)
+	}
+}
+
+func TestGenerateBarcodeUnknownFont(t *testing.T) {
+	body := `{"type":"Code128","data":"ABC123","format":"png","include_text":true,"font":"comic-sans"}`
+	req := httptest.NewRequest("POST", "/api/v1/generate/barcode", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	testutil.NewHandlers().GenerateBarcodeHandler(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "go-mono") {
+		t.Errorf("%d %s, want a 400 listing the fonts", rec.Code, rec.Body)
+	}
+}
+
+func TestGenerateQRWarnings(t *testing.T) {
+	h := testutil.NewHandlers()
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/generate/qr", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.QRHandler(rec, req)
+		return rec
+	}
+
+	// A zero-width space, a decomposed é, an unknown error correction level
+	// and a size too small for the modules
+	rec := post(`{"type":"text","data":"Cafe\u0301 \u200bmenu: https://example.com/menu/today",` +
+		`"options":{"size":64,"error_correction":"Z","format":"png","sanitize_text":true}}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("status = %d, Content-Type %q, want the PNG: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+	var list []models.Warning
+	if err := json.Unmarshal([]byte(rec.Header().Get("X-Warnings")), &list); err != nil {
+		t.Fatal(err)
+	}
+	want := []models.Warning{
+		{Code: "text_sanitized", Field: "data"},
+		{Code: "text_normalized", Field: "data"},
+		{Code: "option_ignored", Field: "options.error_correction"},
+		{Code: "low_module_size", Field: "options.size"},
+	}
+	if len(list) != len(want) {
+		t.Fatalf("warnings = %+v, want %d", list, len(want))
+	}
+	for i, w := range want {
+		if list[i].Code != w.Code || list[i].Field != w.Field || list[i].Message == "" {
+			t.Errorf("warning %d = %+v, want %s on %s", i, list[i], w.Code, w.Field)
+		}
+	}
+	if got := len(rec.Header().Values("Warning")); got != len(want) {
+		t.Errorf("%d Warning headers, want %d", got, len(want))
+	}
+
+	rec = post(`{"type":"url","data":"https://example.com","options":{"format":"png"}}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Warnings") != "" || rec.Header().Get("Warning") != "" {
+		t.Errorf("status %d, warnings %q, want none", rec.Code, rec.Header().Get("X-Warnings"))
+	}
+}
+
+func TestGenerateBarcodeFitMode(t *testing.T) {
+	h := testutil.NewHandlers()
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/generate/barcode", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.GenerateBarcodeHandler(rec, req)
+		return rec
+	}
+
+	rec := post(`{"type":"Code128","data":"ABCDEFGHIJKLMNOPQRSTUVWX","format":"png","width":200,"fit_mode":"strict"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "at least 299") {
+		t.Errorf("strict: %d %s, want a 400 stating the minimum width", rec.Code, rec.Body)
+	}
+
+	rec = post(`{"type":"Code128","data":"ABCDEFGHIJKLMNOPQRSTUVWX","format":"png","width":200}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Barcode-Width") != "299" || rec.Header().Get("X-Barcode-Height") != "150" {
+		t.Errorf("snap: %d, size %sx%s, want 299x150", rec.Code, rec.Header().Get("X-Barcode-Width"), rec.Header().Get("X-Barcode-Height"))
+	}
+}
+
+func TestGenerateAccessibleNameHeader(t *testing.T) {
+	h := testutil.NewHandlers()
+	for _, tt := range []struct {
+		path, body, name string
+	}{
+		{"/api/v1/generate/qr", `{"type":"text","data":"hello","options":{"format":"svg"}}`, "QR code"},
+		{"/api/v1/generate/qr", `{"type":"text","data":"hello","options":{"format":"svg","title":"Menu"}}`, "Menu"},
+		{"/api/v1/generate/qr", `{"type":"text","data":"hello","options":{"format":"png"}}`, ""},
+		{"/api/v1/generate/barcode", `{"type":"Code128","data":"A1","format":"svg"}`, "Code128 barcode"},
+		{"/api/v1/generate/barcode", `{"type":"Code128","data":"A1","format":"png"}`, ""},
+	} {
+		req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		if strings.HasSuffix(tt.path, "qr") {
+			h.QRHandler(rec, req)
+		} else {
+			h.GenerateBarcodeHandler(rec, req)
+		}
+		if rec.Code != http.StatusOK || rec.Header().Get("X-Accessible-Name") != tt.name {
+			t.Errorf("%s: status %d, X-Accessible-Name %q; want %q", tt.body, rec.Code, rec.Header().Get("X-Accessible-Name"), tt.name)
+		}
+	}
+}
+
+func TestGenerateQRFromQuery(t *testing.T) {
+	h := testutil.NewHandlers()
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		h.QRHandler(rec, req)
+		return rec
+	}
+
+	// GET renders the same image as the POST of the decoded parameters
+	for _, tt := range []struct {
+		name, qrType, query, data string
+	}{
+		{"escaped URL", "url", "data=" + url.QueryEscape("https://example.com/?q=a+b&pct=100%"), "https://example.com/?q=a+b&pct=100%"},
+		{"plus is a space", "text", "data=hello+world", "hello world"},
+		{"percent sequences", "text", "data=caf%C3%A9%2B%25", "café+%"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			get := send("GET", "/api/v1/generate/qr?type="+tt.qrType+"&size=256&error_correction=M&deterministic=true&"+tt.query, "")
+			body, _ := json.Marshal(models.QRRequest{Type: tt.qrType, Data: tt.data, Options: models.QROptions{Size: 256, ErrorCorrection: "M", Deterministic: true}})
+			post := send("POST", "/api/v1/generate/qr", string(body))
+			if get.Code != http.StatusOK || get.Header().Get("Content-Type") != "image/png" {
+				t.Fatalf("GET: status %d, Content-Type %q: %s", get.Code, get.Header().Get("Content-Type"), get.Body)
+			}
+			if post.Code != http.StatusOK || !bytes.Equal(get.Body.Bytes(), post.Body.Bytes()) {
+				t.Errorf("GET image differs from the POST of %q", tt.data)
+			}
+		})
+	}
+
+	if rec := send("GET", "/api/v1/generate/qr?type=text&data=hello&format=svg&title=Menu", ""); rec.Code != http.StatusOK ||
+		rec.Header().Get("Content-Type") != "image/svg+xml" || rec.Header().Get("X-Accessible-Name") != "Menu" {
+		t.Errorf("SVG: status %d, headers %v", rec.Code, rec.Header())
+	}
+
+	for _, query := range []string{
+		"type=text&data=hello&size=big",
+		"type=text&data=hello&deterministic=maybe",
+		"type=unknown&data=hello",
+		"type=text",
+	} {
+		rec := send("GET", "/api/v1/generate/qr?"+query, "")
+		var body map[string]string
+		if rec.Code != http.StatusBadRequest || json.NewDecoder(rec.Body).Decode(&body) != nil || body["message"] == "" {
+			t.Errorf("%s: status %d, want a 400 JSON error", query, rec.Code)
+		}
+	}
+}
+
+func sendBarcode(h *handlers.Handlers, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.GenerateBarcodeHandler(rec, req)
+	return rec
+}
+
+func TestGenerateBarcodeTypeAliases(t *testing.T) {
+	h := testutil.NewHandlers()
+	for _, tt := range []struct {
+		canonical, data string
+		aliases         []string
+	}{
+		{"code128", "ABC-123", []string{"Code128", "CODE128", "code-128", "Code 128"}},
+		{"ean13", "400638133393", []string{"EAN13", "EAN-13", "ean_13"}},
+		{"upca", "03600029145", []string{"UPCA", "UPC-A", "upc_a"}},
+	} {
+		want := sendBarcode(h, "GET", "/api/v1/generate/barcode?format=svg&deterministic=true&type="+tt.canonical+"&data="+tt.data, "")
+		if want.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", tt.canonical, want.Code, want.Body)
+		}
+		for _, alias := range tt.aliases {
+			got := sendBarcode(h, "GET", "/api/v1/generate/barcode?format=svg&deterministic=true&type="+url.QueryEscape(alias)+"&data="+tt.data, "")
+			if got.Code != http.StatusOK || !bytes.Equal(got.Body.Bytes(), want.Body.Bytes()) {
+				t.Errorf("%q: status %d, image differs from %s", alias, got.Code, tt.canonical)
+			}
+		}
+	}
+
+	// The response names the canonical type
+	rec := sendBarcode(h, "POST", "/api/v1/generate/barcode", `{"type":"Code-128","data":"ABC","format":"png","output":"json"}`)
+	var body models.GeneratedImage
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &body) != nil || body.Type != "Code128" {
+		t.Errorf("status %d, type %q, want Code128", rec.Code, body.Type)
+	}
+}
+
+func TestGenerateBarcodeFromQueryErrors(t *testing.T) {
+	h := testutil.NewHandlers()
+	for _, tt := range []struct {
+		query, code string
+	}{
+		{"type=code128&format=svg", "INVALID_DATA"},
+		{"data=ABC&format=svg", "UNSUPPORTED_TYPE"},
+		{"type=code128&data=ABC", "UNSUPPORTED_FORMAT"},
+		{"type=code39x&data=ABC&format=svg", "UNSUPPORTED_TYPE"},
+		{"type=code128&data=ABC&format=svg&width=wide", "INVALID_OPTION"},
+		{"type=code128&data=ABC&format=svg&include_text=sometimes", "INVALID_OPTION"},
+	} {
+		rec := sendBarcode(h, "GET", "/api/v1/generate/barcode?"+tt.query, "")
+		var body map[string]string
+		if rec.Code != http.StatusBadRequest || json.NewDecoder(rec.Body).Decode(&body) != nil || body["code"] != tt.code {
+			t.Errorf("%s: status %d, code %q, want a 400 %s error", tt.query, rec.Code, body["code"], tt.code)
+		}
+	}
+}
+
+// GET renders the bytes the POST of the same fields does, checked against
+// a golden file so both paths stay pinned to the same output
+func TestGenerateBarcodeFromQueryGolden(t *testing.T) {
+	h := testutil.NewHandlers()
+	get := sendBarcode(h, "GET", "/api/v1/generate/barcode?type=Code128&data=ABC-123&format=svg&width=400&height=120&include_text=true&deterministic=true", "")
+	body, _ := json.Marshal(models.GenerateRequest{
+		Type: "code128", Data: "ABC-123", Format: "svg", Width: 400, Height: 120, IncludeText: true, Deterministic: true,
+	})
+	post := sendBarcode(h, "POST", "/api/v1/generate/barcode", string(body))
+	if get.Code != http.StatusOK || get.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Fatalf("GET: status %d, Content-Type %q: %s", get.Code, get.Header().Get("Content-Type"), get.Body)
+	}
+	if post.Code != http.StatusOK || !bytes.Equal(get.Body.Bytes(), post.Body.Bytes()) {
+		t.Fatalf("GET image differs from the POST: status %d", post.Code)
+	}
+
+	path := filepath.Join("testdata", "golden", "barcode-code128.svg")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, get.Body.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(get.Body.Bytes(), want) {
+		t.Errorf("barcode differs from %s:\n%s", path, get.Body)
+	}
+}
+
+func TestGenerateJSONOutput(t *testing.T) {
+	h := testutil.NewHandlers()
+	send := func(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	// decode checks an envelope's PNG and returns the envelope and the
+	// image size
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder) (models.GeneratedImage, image.Config) {
+		t.Helper()
+		var body models.GeneratedImage
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("status %d, Content-Type %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		data, err := base64.StdEncoding.DecodeString(body.Data)
+		if err != nil {
+			t.Fatalf("data is not base64: %v", err)
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("data is not a PNG: %v", err)
+		}
+		return body, config
+	}
+
+	const data = "https://example.com/menu?table=12&lang=en"
+	qr, config := decode(t, send(h.QRHandler, "POST", "/api/v1/generate/qr",
+		`{"type":"url","data":"`+data+`","options":{"size":300,"output":"json"}}`))
+	if config.Width != 300 || config.Height != 300 {
+		t.Errorf("QR image is %dx%d, want 300x300", config.Width, config.Height)
+	}
+	want := models.GeneratedImage{Data: qr.Data, ContentType: "image/png", Size: 300, Type: "url", PayloadLength: len(data)}
+	if qr != want {
+		t.Errorf("QR envelope = %+v, want %+v", qr, want)
+	}
+
+	// The wifi payload is longer than its JSON data
+	wifi, _ := decode(t, send(h.QRHandler, "POST", "/api/v1/generate/qr",
+		`{"type":"wifi","data":"{\"ssid\":\"Guest\",\"password\":\"welcome\",\"security\":\"WPA\"}","options":{"output":"json"}}`))
+	if wifi.Size != 256 || wifi.PayloadLength != len("WIFI:T:WPA;S:Guest;P:welcome;;") {
+		t.Errorf("wifi envelope: size %d, payload length %d", wifi.Size, wifi.PayloadLength)
+	}
+
+	if query, config := decode(t, send(h.QRHandler, "GET", "/api/v1/generate/qr?type=text&data=hello&size=128&output=json", "")); query.PayloadLength != 5 || config.Width != 128 {
+		t.Errorf("GET envelope = %+v, image width %d", query, config.Width)
+	}
+
+	barcode, config := decode(t, send(h.GenerateBarcodeHandler, "POST", "/api/v1/generate/barcode",
+		`{"type":"EAN-13","data":"4006381333931","format":"png","width":190,"height":80,"output":"json"}`))
+	if config.Width != barcode.Width || config.Height != barcode.Height || barcode.Height != 80 {
+		t.Errorf("barcode image is %dx%d, envelope says %dx%d", config.Width, config.Height, barcode.Width, barcode.Height)
+	}
+	if barcode.ContentType != "image/png" || barcode.Type != "EAN-13" || barcode.PayloadLength != 13 || barcode.Size != 0 {
+		t.Errorf("barcode envelope = %+v", barcode)
+	}
+
+	// The binary output is unchanged
+	if rec := send(h.QRHandler, "POST", "/api/v1/generate/qr", `{"type":"text","data":"hello","options":{"output":"binary"}}`); rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("binary output: status %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+		body    string
+		field   string
+	}{
+		{"unknown QR output", h.QRHandler, "/api/v1/generate/qr", `{"type":"text","data":"hello","options":{"output":"xml"}}`, "options.output"},
+		{"QR data URL in JSON", h.QRHandler, "/api/v1/generate/qr", `{"type":"text","data":"hello","options":{"format":"datauri","output":"json"}}`, "options.output"},
+		{"unknown barcode output", h.GenerateBarcodeHandler, "/api/v1/generate/barcode", `{"type":"EAN-13","data":"4006381333931","format":"png","output":"xml"}`, "output"},
+	} {
+		rec := send(tt.handler, "POST", tt.path, tt.body)
+		var body models.APIError
+		if rec.Code != http.StatusBadRequest || json.NewDecoder(rec.Body).Decode(&body) != nil ||
+			body.Code != handlers.UnsupportedFormatErrorCode || body.Field != tt.field {
+			t.Errorf("%s: status %d, error %+v", tt.name, rec.Code, body)
+		}
+	}
+}
+
+func TestGenerateUUID(t *testing.T) {
+	h := testutil.NewHandlers()
+	req := httptest.NewRequest("POST", "/api/v1/generate/uuid", strings.NewReader(`{"version":"v7","count":1000}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.GenerateUUIDHandler(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("status %d, Cache-Control %q: %s", rec.Code, rec.Header().Get("Cache-Control"), rec.Body)
+	}
+	var resp models.UUIDResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%v: %.300s", err, rec.Body)
+	}
+	if resp.Version != "v7" || resp.Format != "standard" || resp.Count != 1000 || len(resp.IDs) != 1000 || !resp.GeneratedAt.Equal(h.Clock.Now()) {
+		t.Fatalf("resp = %s %s %d %v with %d ids", resp.Version, resp.Format, resp.Count, resp.GeneratedAt, len(resp.IDs))
+	}
+	for i := 1; i < len(resp.IDs); i++ {
+		if resp.IDs[i-1] >= resp.IDs[i] {
+			t.Fatalf("%s is not above %s", resp.IDs[i], resp.IDs[i-1])
+		}
+	}
+}
+
+func TestGenerateUUIDFromQuery(t *testing.T) {
+	h := testutil.NewHandlers()
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.GenerateUUIDHandler(rec, httptest.NewRequest("GET", "/api/v1/generate/uuid?"+query, nil))
+		return rec
+	}
+	rec := get("version=v4&count=3&uppercase=true&format=compact")
+	var resp models.UUIDResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK || len(resp.IDs) != 3 {
+		t.Fatalf("status %d, %v: %s", rec.Code, err, rec.Body)
+	}
+	for _, id := range resp.IDs {
+		if len(id) != 32 || strings.ToUpper(id) != id {
+			t.Errorf("id %q is not 32 upper case hex digits", id)
+		}
+	}
+
+	for query, want := range map[string]string{
+		"count=many":    `{"message":"count must be a number","code":"INVALID_OPTION"}`,
+		"uppercase=yes": `{"message":"uppercase must be true or false","code":"INVALID_OPTION"}`,
+		"count=1001":    `{"message":"invalid UUID request: count must be between 1 and 1000","code":"INVALID_OPTION"}`,
+	} {
+		if rec := get(query); rec.Code != http.StatusBadRequest || strings.TrimSpace(rec.Body.String()) != want {
+			t.Errorf("%s: status %d: %s", query, rec.Code, rec.Body)
+		}
+	}
+}
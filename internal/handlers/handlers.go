@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/abuse"
+	"github.com/innovelabs/microtools-go/internal/cache"
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/dnscache"
+	"github.com/innovelabs/microtools-go/internal/hitforward"
+	"github.com/innovelabs/microtools-go/internal/jobs"
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/maintenance"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/policy"
+	"github.com/innovelabs/microtools-go/internal/quota"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/resultmeta"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/services/notify"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/shortener"
+	"github.com/innovelabs/microtools-go/internal/signing"
+	"github.com/innovelabs/microtools-go/internal/tenant"
+	"github.com/innovelabs/microtools-go/internal/toolstatus"
+	"github.com/innovelabs/microtools-go/internal/uploads"
+	"github.com/innovelabs/microtools-go/internal/utils"
+	"github.com/innovelabs/microtools-go/internal/wifirotation"
+)
+
+// emailDomainCacheTTL is how long email domain lookups are cached, and
+// emailDomainNegativeCacheTTL how long lookups that found no domain or no
+// MX records are, so a domain registered after a typo storm is seen soon
+const (
+	emailDomainCacheTTL         = time.Hour
+	emailDomainNegativeCacheTTL = dnscache.NegativeTTL
+)
+
+// emailResolverDoH is the per-request resolver option selecting
+// DoHEmailDomains
+const emailResolverDoH = "doh"
+
+// emailDomainCacheCheck names the email domain cache in result metadata
+const emailDomainCacheCheck = "email-domain-cache"
+
+// Handlers holds the dependencies of the HTTP handlers that need them.
+// Handlers without dependencies remain plain functions.
+type Handlers struct {
+	// Config is nil when no configuration was loaded
+	Config *config.Config
+	// Users is nil when no user store is configured; user endpoints then return 503
+	Users repository.UserRepository
+	// Mailer is nil when no relay is configured; verification links are
+	// then returned in the registration response
+	Mailer notify.MailSender
+	// GeoIP is the geolocation database reader
+	GeoIP validation.GeoIPService
+	// Dependencies are the stores /ready pings besides the GeoIP database
+	Dependencies []Dependency
+	// Barcodes generates barcode images
+	Barcodes generator.BarcodeService
+	// Labels renders PDF label sheets
+	Labels generator.LabelService
+	// Jobs runs large requests in the background; nil answers them with 503
+	Jobs *jobs.Store
+	// Uploads receives large inputs in resumable chunks; nil answers
+	// upload requests with 503 and unknown uploads with 404
+	Uploads *uploads.Store
+	// EmailDomains runs the email network checks; nil uses DNS lookups
+	EmailDomains validation.DomainChecker
+	// DoHEmailDomains runs the email network checks over DNS-over-HTTPS for
+	// requests with "resolver": "doh"; nil answers them with 503
+	DoHEmailDomains validation.DomainChecker
+	// Mailboxes runs the SMTP mailbox check of requests with check_smtp;
+	// nil reports it skipped
+	Mailboxes *validation.SMTPVerifier
+	// DNSCache is the in-memory DNS cache behind EmailDomains, flushed and
+	// reported by the admin endpoints; nil when lookups are not cached
+	DNSCache *dnscache.Cache
+	// Cache, when set, caches email domain lookups
+	Cache cache.Cache
+	// Clock stamps user verification times and response signatures
+	Clock clock.Clock
+	// Signer is nil when no signing keys are configured; sign_response
+	// requests then return 503
+	Signer *signing.Signer
+	// Status tracks per-tool traffic for the status page; nil disables it
+	Status *toolstatus.Tracker
+	// Shares stores shared validation results; nil answers share requests
+	// with 503
+	Shares repository.ShareStore
+	// ShareLimiter limits how often one client may share; nil is unlimited
+	ShareLimiter *middleware.RateLimiter
+	// Counter forwards usage counters and reports its state in /ready;
+	// nil when no counter API is configured
+	Counter *hitforward.Forwarder
+	// Hits counts the calls of each endpoint since startup for
+	// /api/v1/stats; nil disables it
+	Hits *middleware.HitStats
+	// Pixels meters QR, barcode and label generation by pixel budget;
+	// nil without Redis, when generation is unmetered
+	Pixels *quota.Limiter
+	// URLReputation refuses QR codes for flagged URLs; nil when the check
+	// is not enabled
+	URLReputation *abuse.Checker
+	// Blocklist stores the domains operators block by hand; nil without
+	// MongoDB, when the blocklist admin endpoints return 503
+	Blocklist repository.BlocklistStore
+	// Maintenance takes tools offline at runtime; nil never does
+	Maintenance *maintenance.Switch
+	// WifiRotation keeps rotating guest WiFi networks; nil without MongoDB
+	// or WIFI_ROTATION_KEY, when creating one returns 503
+	WifiRotation *wifirotation.Rotator
+	// ShortURLs creates and resolves short links; nil without MongoDB,
+	// when shortening returns 503 and short links 404
+	ShortURLs *shortener.Shortener
+	// Tenants stores the white-label tenants and TenantRegistry serves
+	// them cached; both nil without MongoDB, when every request is the
+	// default tenant's and the tenant admin endpoints return 503
+	Tenants        repository.TenantStore
+	TenantRegistry *tenant.Registry
+}
+
+// JWTConfig returns the configured token settings, with no secret
+// without configuration
+func (h *Handlers) JWTConfig() utils.JWTConfig {
+	if h.Config == nil {
+		return utils.JWTConfig{}
+	}
+	return utils.JWTConfig{Secret: h.Config.JWTSecret, Issuer: h.Config.JWTIssuer, Audience: h.Config.JWTAudience}
+}
+
+// networkEmailChecker returns the domain checker for r resolving with the
+// named resolver ("" for the configured one), or nil when the route group
+// does not allow network checks. Choosing a resolver requires an access
+// token; the error response is written when the choice cannot be served.
+func (h *Handlers) networkEmailChecker(w http.ResponseWriter, r *http.Request, resolver string) (validation.DomainChecker, bool) {
+	check := h.EmailDomains
+	if check == nil {
+		check = validation.CheckEmailDomain
+	}
+	switch resolver {
+	case "":
+	case emailResolverDoH:
+		if !h.validAccessToken(r) {
+			WriteError(w, http.StatusUnauthorized, UnauthorizedErrorCode, "resolver requires a valid access token")
+			return nil, false
+		}
+		if h.DoHEmailDomains == nil {
+			WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "DNS-over-HTTPS is not configured")
+			return nil, false
+		}
+		check = h.DoHEmailDomains
+	default:
+		WriteError(w, http.StatusBadRequest, InvalidOptionErrorCode, fmt.Sprintf("unknown resolver %q; supported: %s", resolver, emailResolverDoH))
+		return nil, false
+	}
+	if !policy.FromContext(r.Context()).AllowNetworkChecks {
+		return nil, true
+	}
+	return h.emailDomainChecker(withDNSTimeout(check, h.site().EmailDNSTimeout)), true
+}
+
+// withDNSTimeout bounds the lookups of each call of check by timeout, so a
+// blackholed DNS server cannot hold the request
+func withDNSTimeout(check validation.DomainChecker, timeout time.Duration) validation.DomainChecker {
+	if timeout <= 0 {
+		return check
+	}
+	return func(ctx context.Context, email string) (bool, bool, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return check(ctx, email)
+	}
+}
+
+// emailDomainChecker returns check served from the cache when one is
+// configured
+func (h *Handlers) emailDomainChecker(check validation.DomainChecker) validation.DomainChecker {
+	if h.Cache == nil {
+		return check
+	}
+	return func(ctx context.Context, email string) (bool, bool, error) {
+		domain := strings.ToLower(validation.ExtractDomain(email))
+		if domain == "" {
+			return check(ctx, email)
+		}
+		key := "email-domain:" + domain
+		cached, ok, err := h.Cache.Get(ctx, key)
+		if err == nil && ok && len(cached) == 2 {
+			return cached[0] == '1', cached[1] == '1', nil
+		}
+		if err != nil {
+			resultmeta.FromContext(ctx).SkipCheck(emailDomainCacheCheck, "cache unavailable; domain looked up directly")
+		}
+
+		domainValid, mxFound, err := check(ctx, email)
+		if err != nil {
+			return domainValid, mxFound, err
+		}
+		ttl := emailDomainCacheTTL
+		if !domainValid || !mxFound {
+			ttl = emailDomainNegativeCacheTTL
+		}
+		value := []byte("00")
+		if domainValid {
+			value[0] = '1'
+		}
+		if mxFound {
+			value[1] = '1'
+		}
+		if err := h.Cache.Set(ctx, key, string(value), ttl); err != nil {
+			logging.FromContext(ctx).Warn("Failed to cache email domain lookup", "error", err)
+			resultmeta.FromContext(ctx).SkipCheck(emailDomainCacheCheck, "cache unavailable; lookup not cached")
+		}
+		return domainValid, mxFound, nil
+	}
+}
+
+// geoDBMaxAge is the age past which the geolocation database is flagged
+// stale in result metadata
+func (h *Handlers) geoDBMaxAge() time.Duration {
+	days := config.DefaultGeoDBMaxAgeDays
+	if h.Config != nil && h.Config.GeoDBMaxAgeDays > 0 {
+		days = h.Config.GeoDBMaxAgeDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// addResultMeta adds the "meta" object to body when r asked for it with
+// ?include_meta=true
+func (h *Handlers) addResultMeta(r *http.Request, body map[string]interface{}) {
+	if collector := resultmeta.FromContext(r.Context()); collector != nil {
+		body["meta"] = collector.Meta(h.geoDBMaxAge())
+	}
+}
@@ -1,12 +1,198 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// liveBody and liveHeaders are the /live response, built once so answering
+// a probe allocates nothing
+var (
+	liveBody          = []byte("{\"message\":\"Live\"}\n")
+	liveContentType   = []string{"application/json"}
+	liveContentLength = []string{strconv.Itoa(len(liveBody))}
+	liveCacheControl  = []string{"no-store"}
 )
 
-// LiveHandler handles health check requests
+// LiveHandler answers liveness probes from a preallocated body. It reads no
+// configuration, store or counter, so it stays fast under load.
 func LiveHandler(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header["Content-Type"] = liveContentType
+	header["Content-Length"] = liveContentLength
+	header["Cache-Control"] = liveCacheControl
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Live"})
+	if r.Method != http.MethodHead {
+		w.Write(liveBody)
+	}
+}
+
+// ReadyCheckTimeout bounds each dependency check of /ready
+const ReadyCheckTimeout = time.Second
+
+// Dependency states reported by /ready
+const (
+	DependencyUp   = "up"
+	DependencyDown = "down"
+	// DependencyDegraded is an optional dependency that is down
+	DependencyDegraded = "degraded"
+)
+
+// geoIPDependency names the GeoIP database in /ready
+const geoIPDependency = "geoip"
+
+// Pinger checks that a dependency answers
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingFunc adapts a function to Pinger
+type PingFunc func(ctx context.Context) error
+
+// Ping calls f
+func (f PingFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Dependency is a service /ready checks
+type Dependency struct {
+	// Name keys the dependency in the response
+	Name   string
+	Pinger Pinger
+	// Required dependencies fail readiness when down. The others are
+	// components the service runs without, reported degraded.
+	Required bool
+}
+
+// ReadyHandler checks the GeoIP database and Dependencies and answers 503
+// when a required one is down. The usage counter state is reported but
+// never fails readiness.
+func (h *Handlers) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	var geoDB models.GeoDatabaseInfo
+	deps := append([]Dependency{{
+		Name:     geoIPDependency,
+		Required: true,
+		Pinger: PingFunc(func(context.Context) error {
+			var err error
+			geoDB, err = h.GeoIP.Metadata()
+			return err
+		}),
+	}}, h.Dependencies...)
+	statuses := checkDependencies(r.Context(), deps)
+
+	state := DependencyUp
+	var failures []string
+	for _, dep := range deps {
+		switch status := statuses[dep.Name]; status.Status {
+		case DependencyDown:
+			state = DependencyDown
+			failures = append(failures, dep.Name+": "+status.Error)
+		case DependencyDegraded:
+			if state == DependencyUp {
+				state = DependencyDegraded
+			}
+		}
+	}
+
+	body := map[string]interface{}{
+		"message":      "Ready",
+		"status":       state,
+		"dependencies": statuses,
+	}
+	code := http.StatusOK
+	if state == DependencyDown {
+		code = http.StatusServiceUnavailable
+		body["message"] = "Not ready"
+		body["error"] = strings.Join(failures, "; ")
+	}
+	if statuses[geoIPDependency].Status == DependencyUp {
+		body["geoDatabase"] = geoDB
+	}
+	if h.Counter != nil {
+		body["counter"] = h.Counter.Status()
+	}
+	// Tools in maintenance answer 503 themselves, so the instance stays in
+	// rotation and callers get the maintenance message
+	if h.Maintenance != nil {
+		body["maintenance"] = h.Maintenance.State()
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, code, body)
+}
+
+// checkDependencies pings deps concurrently, each within
+// ReadyCheckTimeout, and returns their status by name
+func checkDependencies(ctx context.Context, deps []Dependency) map[string]models.DependencyStatus {
+	statuses := make([]models.DependencyStatus, len(deps))
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, ReadyCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			err := dep.Pinger.Ping(ctx)
+			status := models.DependencyStatus{
+				Status:    DependencyUp,
+				Required:  dep.Required,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				logging.FromContext(ctx).Warn("Readiness check failed", "dependency", dep.Name, "error", err)
+				status.Status = DependencyDegraded
+				if dep.Required {
+					status.Status = DependencyDown
+				}
+				status.Error = err.Error()
+				if errors.Is(err, context.DeadlineExceeded) {
+					status.Error = fmt.Sprintf("no answer within %v", ReadyCheckTimeout)
+				}
+			}
+			statuses[i] = status
+		}()
+	}
+	wg.Wait()
+
+	byName := make(map[string]models.DependencyStatus, len(deps))
+	for i, dep := range deps {
+		byName[dep.Name] = statuses[i]
+	}
+	return byName
+}
+
+// StatsHandler reports the calls of each endpoint counted in memory since
+// startup, with the state of their delivery to the counter API
+func (h *Handlers) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Hits == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "hit counting is not configured")
+		return
+	}
+	stats := h.Hits.Snapshot()
+	if h.Counter != nil {
+		status := h.Counter.Status()
+		stats.Forwarder = &status
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, stats)
+}
+
+// StatusHandler reports the health of each tool derived from the traffic
+// of the last few minutes
+func (h *Handlers) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Status == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "status tracking is not configured")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, h.Status.Report())
 }
@@ -0,0 +1,108 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+type readyBody struct {
+	Message      string                             `json:"message"`
+	Status       string                             `json:"status"`
+	Error        string                             `json:"error"`
+	Dependencies map[string]models.DependencyStatus `json:"dependencies"`
+	GeoDatabase  *models.GeoDatabaseInfo            `json:"geoDatabase"`
+}
+
+func up(context.Context) error { return nil }
+
+func down(context.Context) error { return errors.New("connection refused") }
+
+func ready(t *testing.T, h *handlers.Handlers) (int, readyBody) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ReadyHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/ready", nil))
+	var body readyBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body is not JSON: %s", rec.Body)
+	}
+	return rec.Code, body
+}
+
+func TestReadyDependencies(t *testing.T) {
+	tests := []struct {
+		name       string
+		mongo      handlers.PingFunc
+		redis      handlers.PingFunc
+		geoErr     error
+		code       int
+		status     string
+		mongoState string
+		redisState string
+		geoState   string
+	}{
+		{"all up", up, up, nil, http.StatusOK, handlers.DependencyUp,
+			handlers.DependencyUp, handlers.DependencyUp, handlers.DependencyUp},
+		{"optional redis down", up, down, nil, http.StatusOK, handlers.DependencyDegraded,
+			handlers.DependencyUp, handlers.DependencyDegraded, handlers.DependencyUp},
+		{"required mongo down", down, up, nil, http.StatusServiceUnavailable, handlers.DependencyDown,
+			handlers.DependencyDown, handlers.DependencyUp, handlers.DependencyUp},
+		{"geoip not loaded", up, down, errors.New("database closed"), http.StatusServiceUnavailable, handlers.DependencyDown,
+			handlers.DependencyUp, handlers.DependencyDegraded, handlers.DependencyDown},
+	}
+	for _, tt := range tests {
+		h := testutil.NewHandlers()
+		h.GeoIP = &testutil.GeoIP{Err: tt.geoErr, Info: models.GeoDatabaseInfo{DatabaseDate: "2026-03-01"}}
+		h.Dependencies = []handlers.Dependency{
+			{Name: "mongodb", Required: true, Pinger: tt.mongo},
+			{Name: "redis", Pinger: tt.redis},
+		}
+		code, body := ready(t, h)
+		if code != tt.code || body.Status != tt.status {
+			t.Errorf("%s: status %d %q, want %d %q", tt.name, code, body.Status, tt.code, tt.status)
+		}
+		for name, want := range map[string]string{"mongodb": tt.mongoState, "redis": tt.redisState, "geoip": tt.geoState} {
+			dep, ok := body.Dependencies[name]
+			if !ok || dep.Status != want || dep.LatencyMs < 0 || (want == handlers.DependencyUp) != (dep.Error == "") {
+				t.Errorf("%s: %s = %+v, want %s", tt.name, name, dep, want)
+			}
+		}
+		if (code == http.StatusOK) != (body.Error == "") {
+			t.Errorf("%s: error = %q", tt.name, body.Error)
+		}
+		if (tt.geoErr == nil) != (body.GeoDatabase != nil) {
+			t.Errorf("%s: geoDatabase = %+v", tt.name, body.GeoDatabase)
+		}
+	}
+}
+
+// A dependency that never answers is down after ReadyCheckTimeout rather
+// than holding the probe
+func TestReadyDependencyTimeout(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Dependencies = []handlers.Dependency{{
+		Name:     "mongodb",
+		Required: true,
+		Pinger: handlers.PingFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	}}
+	start := time.Now()
+	code, body := ready(t, h)
+	if elapsed := time.Since(start); elapsed > handlers.ReadyCheckTimeout+time.Second {
+		t.Errorf("answered after %v", elapsed)
+	}
+	dep := body.Dependencies["mongodb"]
+	if code != http.StatusServiceUnavailable || dep.Status != handlers.DependencyDown || dep.Error != "no answer within 1s" {
+		t.Errorf("status %d, mongodb = %+v", code, dep)
+	}
+}
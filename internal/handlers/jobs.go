@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/jobs"
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// jobsPath is the base path of the job endpoints
+const jobsPath = "/api/v1/jobs/"
+
+// JobStatusHandler reports the state of a background job
+func (h *Handlers) JobStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Jobs == nil {
+		writeError(w, http.StatusNotFound, jobs.ErrJobNotFound)
+		return
+	}
+	job, err := h.Jobs.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"job": h.jobStatus(job)})
+}
+
+// JobResultHandler serves the output of a finished job, once
+func (h *Handlers) JobResultHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Jobs == nil {
+		writeError(w, http.StatusNotFound, jobs.ErrJobNotFound)
+		return
+	}
+	result, err := h.Jobs.Result(mux.Vars(r)["id"])
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFinished):
+		writeError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJobResult(w, r, result)
+}
+
+// submitJob starts render in the background and answers 202 with the job
+// status and its Location. The job outlives the request, so render gets
+// the job's context, not the request's. It counts against the jobs of the
+// access token's user, or else of the client IP.
+func (h *Handlers) submitJob(w http.ResponseWriter, r *http.Request, render jobs.Func) {
+	if h.Jobs == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "background jobs are not available")
+		return
+	}
+	client := "ip:" + h.callerIP(r)
+	if email := h.accessTokenEmail(r); email != "" {
+		client = "user:" + email
+	}
+	job, err := h.Jobs.Submit(r.Context(), client, render)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, jobs.ErrTooManyJobs):
+			status = http.StatusServiceUnavailable
+			w.Header().Set("Retry-After", "60")
+		case errors.Is(err, jobs.ErrClientJobLimit):
+			status = http.StatusTooManyRequests
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	status := h.jobStatus(job)
+	w.Header().Set("Location", status.StatusURL)
+	writeJSON(w, r, http.StatusAccepted, map[string]interface{}{"job": status})
+}
+
+func (h *Handlers) jobStatus(job jobs.Job) models.JobStatus {
+	status := models.JobStatus{
+		ID:             job.ID,
+		Status:         job.Status,
+		Error:          job.Error,
+		CreatedAt:      job.CreatedAt,
+		StatusURL:      h.site().Path(jobsPath + job.ID),
+		CorrelationIDs: job.CorrelationIDs,
+	}
+	if !job.FinishedAt.IsZero() {
+		finished := job.FinishedAt
+		status.FinishedAt = &finished
+	}
+	if job.Status == jobs.StatusDone {
+		status.ResultURL = h.site().Path(jobsPath + job.ID + "/result")
+	}
+	return status
+}
+
+// writeJobResult writes a rendered file as an attachment
+func writeJobResult(w http.ResponseWriter, r *http.Request, result jobs.Result) {
+	for name, value := range result.Headers {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set("Content-Type", result.ContentType)
+	if result.Filename != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", result.Filename))
+	}
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, r, result.Data)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/innovelabs/microtools-go/internal/jobs"
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/quota"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+)
+
+const (
+	// maxLabelUploadBytes caps the multipart body of a label sheet request
+	maxLabelUploadBytes = 4 << 20
+	// labelsAsyncRows is the row count above which sheets render as a job
+	labelsAsyncRows = 200
+
+	labelsFilename = "labels.pdf"
+	// labelPagesHeader reports the page count of a generated sheet
+	labelPagesHeader = "X-Label-Pages"
+)
+
+// GenerateLabelsHandler turns an uploaded CSV (multipart field "file") into
+// a PDF sheet of QR code and barcode labels. Every row is validated before
+// anything is rendered; sheets above labelsAsyncRows rows are rendered as a
+// background job.
+func (h *Handlers) GenerateLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLabelUploadBytes)
+	if err := r.ParseMultipartForm(maxLabelUploadBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteError(w, http.StatusRequestEntityTooLarge, BodyTooLargeErrorCode, "request body is too large")
+			return
+		}
+		WriteError(w, http.StatusBadRequest, InvalidRequestErrorCode, "expected a multipart/form-data body with a CSV file")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	opts, err := labelOptionsFromForm(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, MissingFieldErrorCode, `a CSV upload in the "file" field is required`)
+		return
+	}
+	defer file.Close()
+
+	rows, err := generator.ParseLabelCSV(file)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, generator.ErrTooManyLabels) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, err)
+		return
+	}
+	rowErrors, err := h.Labels.ValidateLabels(opts, rows)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(rowErrors) > 0 {
+		writeJSON(w, r, http.StatusUnprocessableEntity, map[string]interface{}{
+			"message":   fmt.Sprintf("%d of %d rows are invalid", len(rowErrors), len(rows)),
+			"code":      InvalidDataErrorCode,
+			"field":     "file",
+			"rowErrors": rowErrors,
+		})
+		return
+	}
+
+	width, height, err := generator.LabelSymbolPixels(opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !h.spendPixels(w, r, quota.LabelSheetCost(width, height, len(rows))) {
+		return
+	}
+
+	render := func(ctx context.Context) (jobs.Result, error) {
+		pdf, pages, err := h.Labels.GenerateLabels(ctx, opts, rows)
+		if err != nil {
+			return jobs.Result{}, err
+		}
+		return jobs.Result{
+			ContentType: "application/pdf",
+			Filename:    labelsFilename,
+			Headers:     map[string]string{labelPagesHeader: strconv.Itoa(pages)},
+			Data:        pdf,
+		}, nil
+	}
+
+	if len(rows) > labelsAsyncRows {
+		h.submitJob(w, r, render)
+		return
+	}
+	result, err := render(r.Context())
+	if errors.Is(err, context.Canceled) {
+		logging.FromContext(r.Context()).Info("Client disconnected while generating a label sheet", "rows", len(rows))
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to generate label sheet", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to generate label sheet")
+		return
+	}
+	writeJobResult(w, r, result)
+}
+
+// labelOptionsFromForm reads the sheet layout from the form fields named
+// like the JSON options
+func labelOptionsFromForm(r *http.Request) (models.LabelSheetOptions, error) {
+	opts := models.LabelSheetOptions{
+		Symbology: r.FormValue("symbology"),
+		PageSize:  r.FormValue("page_size"),
+		Font:      r.FormValue("font"),
+	}
+	floats := map[string]*float64{"label_width_mm": &opts.LabelWidthMM, "label_height_mm": &opts.LabelHeightMM}
+	for name, target := range floats {
+		if value := r.FormValue(name); value != "" {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return opts, fmt.Errorf("%s must be a number", name)
+			}
+			*target = v
+		}
+	}
+	ints := map[string]*int{"columns": &opts.Columns, "rows": &opts.Rows}
+	for name, target := range ints {
+		if value := r.FormValue(name); value != "" {
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return opts, fmt.Errorf("%s must be an integer", name)
+			}
+			*target = v
+		}
+	}
+	return opts, nil
+}
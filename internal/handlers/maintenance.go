@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/maintenance"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// maxMaintenanceMessageLength bounds the message shown to every client
+const maxMaintenanceMessageLength = 500
+
+var errMaintenanceUnavailable = errors.New("maintenance switch is not configured")
+
+// MaintenanceHandler lists the maintenance windows that have not ended
+func (h *Handlers) MaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Maintenance == nil {
+		writeError(w, http.StatusServiceUnavailable, errMaintenanceUnavailable)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"maintenance": h.Maintenance.State()})
+}
+
+// SetMaintenanceHandler starts or ends a global or per-tool maintenance
+// window. It applies to this instance at once and to the others when they
+// next refresh.
+func (h *Handlers) SetMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Maintenance == nil {
+		writeError(w, http.StatusServiceUnavailable, errMaintenanceUnavailable)
+		return
+	}
+	var req models.MaintenanceRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	change, err := maintenanceChange(req, h.Clock.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	state, err := h.Maintenance.Apply(r.Context(), change)
+	if errors.Is(err, maintenance.ErrUnknownTool) {
+		WriteError(w, http.StatusBadRequest, InvalidOptionErrorCode, "unknown tool "+req.Tool)
+		return
+	}
+	scope := "global"
+	if req.Tool != "" {
+		scope = req.Tool
+	}
+	if err != nil {
+		// The change holds on this instance; the others miss it until
+		// the store is back and it is made again
+		logging.FromContext(r.Context()).Warn("Maintenance change not stored for other instances", "scope", scope, "error", err)
+	}
+	logging.FromContext(r.Context()).Info("Maintenance set", "scope", scope, "active", change.Active, "exempt", req.Exempt, "by", middleware.TokenEmail(r.Context()))
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"maintenance": state})
+}
+
+// maintenanceChange checks req and turns it into a change starting at now
+func maintenanceChange(req models.MaintenanceRequest, now time.Time) (maintenance.Change, error) {
+	if req.Active == nil {
+		return maintenance.Change{}, errors.New("active is required")
+	}
+	change := maintenance.Change{Tool: req.Tool, Active: *req.Active}
+	if !change.Active {
+		return change, nil
+	}
+	switch {
+	case req.Exempt && req.Tool == "":
+		return change, errors.New("exempt needs a tool")
+	case len(req.Message) > maxMaintenanceMessageLength:
+		return change, errors.New("message is too long")
+	case req.RetryAfter < 0:
+		return change, errors.New("retry_after must not be negative")
+	}
+	change.Window = maintenance.Window{Message: req.Message, RetryAfter: req.RetryAfter, Exempt: req.Exempt}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			return change, errors.New("duration must be a positive Go duration, e.g. 30m")
+		}
+		change.Window.Until = now.Add(d).UTC()
+		if change.Window.RetryAfter == 0 {
+			change.Window.RetryAfter = int((d + time.Second - 1) / time.Second)
+		}
+	}
+	return change, nil
+}
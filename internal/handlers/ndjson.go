@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/policy"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+
+	maxNDJSONLines     = 500000
+	maxNDJSONLineBytes = 4 * 1024
+	ndjsonWorkers      = 16
+	ndjsonFlushEvery   = 256
+)
+
+var (
+	errNDJSONContentType = errors.New("unsupported content type: batch endpoints accept application/x-ndjson")
+	errNDJSONLineTooLong = fmt.Errorf("line exceeds %d bytes", maxNDJSONLineBytes)
+	errNDJSONTooMany     = fmt.Errorf("request exceeds %d lines", maxNDJSONLines)
+)
+
+// ndjsonValidator validates one decoded input value
+type ndjsonValidator func(value string) (interface{}, error)
+
+type ndjsonJob struct {
+	index  int
+	value  string
+	result chan models.BatchResult
+}
+
+// ValidateEmailBatchHandler streams email validation results for an NDJSON
+// body. ?checks=syntax,disposable selects the checks run on every line and
+// ?resolver=doh resolves domains over DNS-over-HTTPS.
+func (h *Handlers) ValidateEmailBatchHandler(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	if query := r.URL.Query().Get("checks"); query != "" {
+		names = strings.Split(query, ",")
+	}
+	checks, err := validation.ParseEmailChecks(names)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	checkDomain, ok := h.networkEmailChecker(w, r, r.URL.Query().Get("resolver"))
+	if !ok {
+		return
+	}
+	streamNDJSON(w, r, "email", func(value string) (interface{}, error) {
+		return validation.ValidateEmailChecks(r.Context(), value, checks, checkDomain), nil
+	})
+}
+
+// ValidateIBANBatchHandler streams IBAN validation results for an NDJSON body
+func ValidateIBANBatchHandler(w http.ResponseWriter, r *http.Request) {
+	streamNDJSON(w, r, "iban", func(value string) (interface{}, error) {
+		return validation.ValidateIBAN(r.Context(), value)
+	})
+}
+
+// ValidateIPBatchHandler streams IP geolocation results for an NDJSON body
+func (h *Handlers) ValidateIPBatchHandler(w http.ResponseWriter, r *http.Request) {
+	streamNDJSON(w, r, "ip", func(value string) (interface{}, error) {
+		return h.GeoIP.Lookup(r.Context(), value)
+	})
+}
+
+// streamNDJSON reads one value per line, validates lines on a bounded worker
+// pool and writes one NDJSON result per line as results become available.
+// Results keep input order unless ?ordered=false. At most ndjsonWorkers*2
+// lines are in flight, so a slow client or slow validation stops the reader
+// and memory stays flat regardless of the body size.
+func streamNDJSON(w http.ResponseWriter, r *http.Request, field string, validate ndjsonValidator) {
+	if err := policy.FromContext(r.Context()).CheckBatch(); err != nil {
+		writePolicyError(w, err)
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != ndjsonContentType {
+		writeError(w, http.StatusUnsupportedMediaType, errNDJSONContentType)
+		return
+	}
+	ordered := r.URL.Query().Get("ordered") != "false"
+
+	// Results are written while the body is still being read
+	rc := http.NewResponseController(w)
+	rc.EnableFullDuplex()
+
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+
+	jobs := make(chan ndjsonJob)
+	pending := make(chan chan models.BatchResult, ndjsonWorkers*2)
+	results := make(chan models.BatchResult, ndjsonWorkers*2)
+
+	var workers sync.WaitGroup
+	for i := 0; i < ndjsonWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				res := models.BatchResult{Index: job.index}
+				if err := r.Context().Err(); err != nil {
+					res.Error = err.Error()
+				} else if result, err := validate(job.value); err != nil {
+					res.Error = err.Error()
+				} else {
+					res.Result = result
+				}
+				if ordered {
+					job.result <- res
+				} else {
+					results <- res
+				}
+			}
+		}()
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- readNDJSON(r.Body, field, func(index int, value string, decodeErr error) {
+			if decodeErr != nil {
+				res := make(chan models.BatchResult, 1)
+				res <- models.BatchResult{Index: index, Error: decodeErr.Error()}
+				if ordered {
+					pending <- res
+				} else {
+					results <- <-res
+				}
+				return
+			}
+			job := ndjsonJob{index: index, value: value, result: make(chan models.BatchResult, 1)}
+			if ordered {
+				pending <- job.result
+			}
+			jobs <- job
+		})
+		close(jobs)
+		workers.Wait()
+		close(pending)
+		close(results)
+	}()
+
+	// Once a write fails the client is gone: the remaining results are
+	// drained without writing, and the workers finish quickly because the
+	// request context is cancelled and the body read fails
+	enc := json.NewEncoder(w)
+	written := 0
+	var writeErr error
+	write := func(res models.BatchResult) {
+		if writeErr != nil {
+			return
+		}
+		if writeErr = enc.Encode(res); writeErr != nil {
+			logging.FromContext(r.Context()).Info("Client disconnected during a batch", "method", r.Method, "path", r.URL.Path, "results", written, "error", writeErr)
+			return
+		}
+		written++
+		if written%ndjsonFlushEvery == 0 {
+			rc.Flush()
+		}
+	}
+	if ordered {
+		for res := range pending {
+			write(<-res)
+		}
+	} else {
+		for res := range results {
+			write(res)
+		}
+	}
+
+	if err := <-readErr; err != nil && writeErr == nil {
+		enc.Encode(map[string]string{"error": err.Error()})
+	}
+	rc.Flush()
+}
+
+// readNDJSON calls emit for every non-empty line. A line may be a JSON
+// object holding field, a JSON string, or the bare value.
+func readNDJSON(body io.Reader, field string, emit func(index int, value string, err error)) error {
+	reader := bufio.NewReaderSize(body, maxNDJSONLineBytes+1)
+	index := 0
+	for {
+		line, err := reader.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return fmt.Errorf("line %d: %w", index, errNDJSONLineTooLong)
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			if index >= maxNDJSONLines {
+				return errNDJSONTooMany
+			}
+			value, decodeErr := decodeNDJSONLine(line, field)
+			emit(index, value, decodeErr)
+			index++
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func decodeNDJSONLine(line []byte, field string) (string, error) {
+	line = bytes.TrimSpace(line)
+	switch line[0] {
+	case '{':
+		var obj map[string]interface{}
+		if err := json.Unmarshal(line, &obj); err != nil {
+			return "", errors.New("invalid JSON line")
+		}
+		value, ok := obj[field].(string)
+		if !ok {
+			return "", fmt.Errorf("line must contain a %q string", field)
+		}
+		return strings.TrimSpace(value), nil
+	case '"':
+		var value string
+		if err := json.Unmarshal(line, &value); err != nil {
+			return "", errors.New("invalid JSON string")
+		}
+		return strings.TrimSpace(value), nil
+	default:
+		return string(line), nil
+	}
+}
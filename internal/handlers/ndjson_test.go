@@ -0,0 +1,209 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+const (
+	validIBAN   = "DE89370400440532013000"
+	invalidIBAN = "DE89370400440532013001"
+)
+
+// ndjsonBody generates lines IBAN lines without holding the body in
+// memory; every tenth IBAN is invalid
+type ndjsonBody struct {
+	lines, next int
+	buf         []byte
+}
+
+func (b *ndjsonBody) Read(p []byte) (int, error) {
+	for len(b.buf) < len(p) && b.next < b.lines {
+		iban := validIBAN
+		if b.next%10 == 9 {
+			iban = invalidIBAN
+		}
+		b.buf = fmt.Appendf(b.buf, "{\"iban\":%q}\n", iban)
+		b.next++
+	}
+	if len(b.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+	return n, nil
+}
+
+// ndjsonSink checks every result line as it is written instead of keeping
+// the response, sampling the live heap as it goes
+type ndjsonSink struct {
+	t        *testing.T
+	header   http.Header
+	status   int
+	lines    int
+	baseline uint64
+	peak     uint64
+}
+
+func (s *ndjsonSink) Header() http.Header    { return s.header }
+func (s *ndjsonSink) WriteHeader(status int) { s.status = status }
+func (s *ndjsonSink) Flush()                 {}
+
+func (s *ndjsonSink) Write(p []byte) (int, error) {
+	var line struct {
+		Index  int `json:"index"`
+		Result struct {
+			IsValid bool `json:"isValid"`
+		} `json:"result"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(p, &line); err != nil {
+		s.t.Fatalf("line %d: %v: %s", s.lines, err, p)
+	}
+	if line.Index != s.lines || line.Error != "" || line.Result.IsValid != (s.lines%10 != 9) {
+		s.t.Fatalf("line %d = %s", s.lines, p)
+	}
+	s.lines++
+	if s.lines%5000 == 0 {
+		runtime.GC()
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		s.peak = max(s.peak, stats.HeapAlloc)
+	}
+	return len(p), nil
+}
+
+// TestNDJSONBatchStreams pipes 50k lines through the IBAN batch handler.
+// The results would take well over 10 MB if buffered; the live heap must
+// stay flat while they stream.
+func TestNDJSONBatchStreams(t *testing.T) {
+	const lines = 50000
+	req := httptest.NewRequest("POST", "/api/v1/validate/iban/batch", &ndjsonBody{lines: lines})
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	sink := &ndjsonSink{t: t, header: http.Header{}, baseline: stats.HeapAlloc}
+	handlers.ValidateIBANBatchHandler(sink, req)
+
+	if sink.status != http.StatusOK || sink.header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status %d, Content-Type %q", sink.status, sink.header.Get("Content-Type"))
+	}
+	if sink.lines != lines {
+		t.Fatalf("got %d results, want %d", sink.lines, lines)
+	}
+	if growth := int64(sink.peak) - int64(sink.baseline); growth > 4<<20 {
+		t.Errorf("live heap grew by %d bytes while streaming", growth)
+	}
+}
+
+func TestNDJSONBatchUnordered(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/validate/iban/batch?ordered=false", &ndjsonBody{lines: 1000})
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	handlers.ValidateIBANBatchHandler(rec, req)
+
+	seen := map[int]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n") {
+		var res struct {
+			Index int `json:"index"`
+		}
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			t.Fatal(err)
+		}
+		seen[res.Index] = true
+	}
+	if len(seen) != 1000 {
+		t.Errorf("got %d distinct indexes, want 1000", len(seen))
+	}
+}
+
+func TestNDJSONBatchErrors(t *testing.T) {
+	tests := []struct {
+		name, contentType, body string
+		status                  int
+		want                    string
+	}{
+		{"JSON array", "application/json", `["` + validIBAN + `"]`, http.StatusUnsupportedMediaType, "application/x-ndjson"},
+		{"bad line", "application/x-ndjson", "{\"iban\":1}\n\"" + validIBAN + "\"\n", http.StatusOK, `{"index":0,"error":"line must contain a \"iban\" string"}`},
+		{"long line", "application/x-ndjson", strings.Repeat("A", 5000) + "\n", http.StatusOK, `"error":"line 0: line exceeds 4096 bytes"`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/validate/iban/batch", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		handlers.ValidateIBANBatchHandler(rec, req)
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: %d %s, want %d with %s", tt.name, rec.Code, rec.Body, tt.status, tt.want)
+		}
+	}
+}
+
+// TestNDJSONBatchStopsWhenClientLeaves streams an endless email batch to
+// a real server, reads a few results and hangs up. The handler must stop
+// validating and return promptly.
+func TestNDJSONBatchStopsWhenClientLeaves(t *testing.T) {
+	h := testutil.NewHandlers()
+	var checked atomic.Int64
+	h.EmailDomains = func(ctx context.Context, email string) (bool, bool, error) {
+		checked.Add(1)
+		time.Sleep(100 * time.Microsecond)
+		return true, true, nil
+	}
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		h.ValidateEmailBatchHandler(w, r)
+	}))
+	defer server.Close()
+
+	body, lines := io.Pipe()
+	go func() {
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(lines, "user@host%d.example.com\n", i); err != nil {
+				return
+			}
+		}
+	}()
+	defer body.Close()
+
+	ctx, hangUp := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "POST", server.URL, body)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := bufio.NewScanner(resp.Body)
+	for range 300 {
+		if !results.Scan() {
+			t.Fatalf("stream ended early: %v", results.Err())
+		}
+	}
+	hangUp()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler still running a second after the client left")
+	}
+	stopped := checked.Load()
+	time.Sleep(50 * time.Millisecond)
+	if checked.Load() != stopped {
+		t.Errorf("validation continued after the handler returned: %d, then %d", stopped, checked.Load())
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/policy"
+	"github.com/innovelabs/microtools-go/internal/services/parser"
+)
+
+// ParseNumberHandler parses a locale-formatted number or amount, or a batch
+// of them in "values" where the route group allows batches
+func ParseNumberHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.NumberParseRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if req.Value != "" && req.Values != nil {
+		WriteError(w, http.StatusBadRequest, InvalidRequestErrorCode, "send either value or values, not both")
+		return
+	}
+
+	var body map[string]interface{}
+	if req.Values != nil {
+		if err := policy.FromContext(r.Context()).CheckBatch(); err != nil {
+			writePolicyError(w, err)
+			return
+		}
+		results, err := parser.ParseNumbers(req.Values, req.Locale)
+		if err != nil {
+			writeError(w, parseNumberErrorStatus(err), err)
+			return
+		}
+		body = map[string]interface{}{"parseResults": results}
+	} else {
+		if req.Value == "" {
+			WriteError(w, http.StatusBadRequest, MissingFieldErrorCode, "value is required")
+			return
+		}
+		result, err := parser.ParseNumber(req.Value, req.Locale)
+		if err != nil {
+			writeError(w, parseNumberErrorStatus(err), err)
+			return
+		}
+		body = map[string]interface{}{"parseResult": result}
+	}
+
+	writeJSON(w, r, http.StatusOK, body)
+}
+
+func parseNumberErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, parser.ErrInvalidNumber):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, parser.ErrTooManyValues):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusBadRequest
+	}
+}
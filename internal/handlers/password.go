@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/tools"
+)
+
+// PasswordCheckHandler estimates the strength of a password. The password
+// only lives in the request: it is not logged, and the result locates
+// weaknesses by offset rather than repeating it.
+func PasswordCheckHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordCheckRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	result, err := tools.CheckPassword(req)
+	switch {
+	case errors.Is(err, tools.ErrPasswordTooLong):
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"passwordCheck": result})
+}
+
+// PasswordGenerateHandler generates random passwords
+func PasswordGenerateHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordGenerateRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	result, err := tools.GeneratePasswords(req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, tools.ErrInvalidPasswordOptions) {
+			status = http.StatusBadRequest
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	// Generated secrets must not be stored by caches
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"passwordResult": result})
+}
@@ -0,0 +1,47 @@
+package handlers_test
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+)
+
+// TestPasswordCheckNotLogged checks passwords, accepted and rejected,
+// and requires them in neither the logs nor the response
+func TestPasswordCheckNotLogged(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	handler := middleware.LoggingMiddleware(http.HandlerFunc(handlers.PasswordCheckHandler))
+	for _, tt := range []struct {
+		password string
+		status   int
+	}{
+		{"Hunter2-Summer1987", http.StatusOK},
+		{"Hunter2" + strings.Repeat("z", 300), http.StatusRequestEntityTooLarge},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/tools/password/check",
+			strings.NewReader(`{"password":"`+tt.password+`"}`)))
+		if rec.Code != tt.status {
+			t.Errorf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+		}
+		if strings.Contains(rec.Body.String(), "Hunter2") {
+			t.Errorf("response repeats the password: %s", rec.Body)
+		}
+		if rec.Header().Get("Cache-Control") != "no-store" && rec.Code == http.StatusOK {
+			t.Errorf("Cache-Control = %q", rec.Header().Get("Cache-Control"))
+		}
+	}
+	if logs.Len() == 0 || strings.Contains(logs.String(), "Hunter2") {
+		t.Errorf("logs = %s", logs.String())
+	}
+}
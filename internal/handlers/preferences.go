@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// Themes pages render in. ThemeSystem follows the browser's color scheme
+// preference and is the default.
+const (
+	ThemeSystem = "system"
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+const (
+	// ThemeCookie holds the theme chosen with POST /preferences/theme
+	ThemeCookie       = "theme"
+	themeCookieMaxAge = 365 * 24 * 60 * 60
+)
+
+// Themes lists the allowed theme values
+var Themes = []string{ThemeSystem, ThemeLight, ThemeDark}
+
+// ThemeFromRequest returns the theme of r's theme cookie, or ThemeSystem
+// when it has none or an unknown value
+func ThemeFromRequest(r *http.Request) string {
+	cookie, err := r.Cookie(ThemeCookie)
+	if err != nil || !slices.Contains(Themes, cookie.Value) {
+		return ThemeSystem
+	}
+	return cookie.Value
+}
+
+// SetThemeHandler stores the theme preference in the theme cookie, which
+// pages read so they render in the chosen theme without a flash on load.
+// JSON bodies get the stored preference back; form posts from the pages
+// are redirected to their redirect field, a path on this site, or "/".
+// ThemeSystem clears the cookie.
+func SetThemeHandler(w http.ResponseWriter, r *http.Request) {
+	var pref models.ThemePreference
+	isForm := false
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		isForm = true
+		pref.Theme = r.PostFormValue("theme")
+	case "", "application/json":
+		if !decodeJSONBody(w, r, &pref) {
+			return
+		}
+	default:
+		WriteError(w, http.StatusUnsupportedMediaType, UnsupportedMediaTypeErrorCode, "unsupported content type: use application/json or application/x-www-form-urlencoded")
+		return
+	}
+
+	pref.Theme = strings.ToLower(strings.TrimSpace(pref.Theme))
+	if !slices.Contains(Themes, pref.Theme) {
+		WriteError(w, http.StatusBadRequest, InvalidOptionErrorCode, "theme must be one of: "+strings.Join(Themes, ", "))
+		return
+	}
+
+	cookie := &http.Cookie{
+		Name:     ThemeCookie,
+		Value:    pref.Theme,
+		Path:     "/",
+		MaxAge:   themeCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if pref.Theme == ThemeSystem {
+		cookie.Value = ""
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+	w.Header().Set("Cache-Control", "no-store")
+
+	if isForm {
+		http.Redirect(w, r, localRedirect(r.PostFormValue("redirect")), http.StatusSeeOther)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, pref)
+}
+
+// localRedirect returns target when it is a path on this site, so the
+// preferences form cannot be used as an open redirect, and "/" otherwise
+func localRedirect(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(target, "/") ||
+		strings.HasPrefix(target, "//") || strings.ContainsRune(target, '\\') {
+		return "/"
+	}
+	return target
+}
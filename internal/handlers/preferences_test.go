@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+func setTheme(h *handlers.Handlers, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/preferences/theme", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	handlers.SetThemeHandler(rec, req)
+	return rec
+}
+
+func themeCookie(t *testing.T, rec *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == handlers.ThemeCookie {
+			return cookie
+		}
+	}
+	t.Fatalf("no theme cookie in %v", rec.Header())
+	return nil
+}
+
+// TestThemeRoundTrip stores each theme and reads it back from the cookie
+// the way pages do
+func TestThemeRoundTrip(t *testing.T) {
+	h := testutil.NewHandlers()
+	for _, theme := range []string{handlers.ThemeDark, handlers.ThemeLight} {
+		rec := setTheme(h, "application/json", `{"theme":" `+strings.ToUpper(theme)+`"}`)
+		if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-store" {
+			t.Fatalf("%s: status %d, Cache-Control %q: %s", theme, rec.Code, rec.Header().Get("Cache-Control"), rec.Body)
+		}
+		var pref models.ThemePreference
+		if err := json.NewDecoder(rec.Body).Decode(&pref); err != nil || pref.Theme != theme {
+			t.Errorf("%s: body %+v, %v", theme, pref, err)
+		}
+		cookie := themeCookie(t, rec)
+		if cookie.Value != theme || cookie.Path != "/" || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.MaxAge <= 0 {
+			t.Errorf("%s: cookie %+v", theme, cookie)
+		}
+
+		page := httptest.NewRequest(http.MethodGet, "/", nil)
+		page.AddCookie(cookie)
+		if got := handlers.ThemeFromRequest(page); got != theme {
+			t.Errorf("%s: read back %q", theme, got)
+		}
+	}
+
+	// The system theme clears the cookie
+	if cookie := themeCookie(t, setTheme(h, "application/json", `{"theme":"system"}`)); cookie.MaxAge >= 0 || cookie.Value != "" {
+		t.Errorf("system: cookie %+v, want it deleted", cookie)
+	}
+	if got := handlers.ThemeFromRequest(httptest.NewRequest(http.MethodGet, "/", nil)); got != handlers.ThemeSystem {
+		t.Errorf("without a cookie: %q", got)
+	}
+}
+
+func TestSetThemeRejects(t *testing.T) {
+	h := testutil.NewHandlers()
+	for _, tt := range []struct {
+		contentType, body string
+		status            int
+		message           string
+	}{
+		{"application/json", `{"theme":"neon"}`, http.StatusBadRequest, "theme must be one of"},
+		{"application/json", `{}`, http.StatusBadRequest, "theme must be one of"},
+		{"application/json", `{"theme":`, http.StatusBadRequest, "invalid JSON"},
+		{"application/x-www-form-urlencoded", "theme=sepia", http.StatusBadRequest, "theme must be one of"},
+		{"text/plain", "dark", http.StatusUnsupportedMediaType, "unsupported content type"},
+	} {
+		rec := setTheme(h, tt.contentType, tt.body)
+		if rec.Code != tt.status {
+			t.Fatalf("%s %q: status %d, want %d", tt.contentType, tt.body, rec.Code, tt.status)
+		}
+		var body struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || !strings.Contains(body.Message, tt.message) {
+			t.Errorf("%s %q: error %+v, want %q", tt.contentType, tt.body, body, tt.message)
+		}
+		if len(rec.Result().Cookies()) != 0 {
+			t.Errorf("%s %q: set a cookie", tt.contentType, tt.body)
+		}
+	}
+}
+
+// TestSetThemeForm posts the page form, which is redirected back to a
+// path on this site only
+func TestSetThemeForm(t *testing.T) {
+	h := testutil.NewHandlers()
+	for redirect, want := range map[string]string{
+		"/qr-code-generator-api?x=1": "/qr-code-generator-api?x=1",
+		"":                           "/",
+		"https://evil.example/":      "/",
+		"//evil.example/":            "/",
+		`/\evil.example`:             "/",
+		"relative":                   "/",
+	} {
+		form := url.Values{"theme": {"dark"}, "redirect": {redirect}}
+		rec := setTheme(h, "application/x-www-form-urlencoded", form.Encode())
+		if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != want {
+			t.Errorf("redirect %q: status %d to %q, want %q", redirect, rec.Code, rec.Header().Get("Location"), want)
+		}
+		if cookie := themeCookie(t, rec); cookie.Value != handlers.ThemeDark {
+			t.Errorf("redirect %q: cookie %+v", redirect, cookie)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/quota"
+	"github.com/innovelabs/microtools-go/internal/utils"
+)
+
+const (
+	// pixelQuotaHeader reports the pixel credits the caller has left today
+	pixelQuotaHeader = "X-Quota-Pixels-Remaining"
+	// pixelQuotaErrorCode marks a 429 from an exhausted pixel budget, as
+	// opposed to middleware.RateLimitErrorCode
+	pixelQuotaErrorCode = "PIXEL_QUOTA_EXCEEDED"
+)
+
+// spendPixels charges cost pixel credits to the caller, writing the 429
+// response when their budget for today cannot cover it. It runs before
+// anything is rendered. When Redis fails the request is let through, so
+// generation does not depend on the quota store.
+func (h *Handlers) spendPixels(w http.ResponseWriter, r *http.Request, cost int64) bool {
+	if h.Pixels == nil {
+		return true
+	}
+	decision, err := h.Pixels.Spend(r.Context(), h.quotaSubject(r), cost)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("Pixel quota unavailable, request not metered", "error", err)
+		return true
+	}
+	if decision.Limit > 0 {
+		w.Header().Set(pixelQuotaHeader, strconv.FormatInt(decision.Remaining, 10))
+	}
+	if decision.Allowed {
+		return true
+	}
+	retryAfter := decision.ResetAt.Sub(h.Clock.Now())
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds()+0.999)))
+	writeJSON(w, r, http.StatusTooManyRequests, map[string]interface{}{
+		"message": fmt.Sprintf("daily pixel quota exceeded: this request costs %d pixel credits and %d remain", cost, decision.Remaining),
+		"code":    pixelQuotaErrorCode,
+	})
+	return false
+}
+
+// quotaSubject identifies the caller of r for the pixel quota: the user of
+// a valid access token on their plan, or else the client IP
+func (h *Handlers) quotaSubject(r *http.Request) quota.Subject {
+	anonymous := quota.Subject{Key: "ip:" + h.callerIP(r), Plan: quota.PlanAnonymous}
+	token, ok := middleware.BearerToken(r)
+	if !ok {
+		return anonymous
+	}
+	claims, err := utils.ValidateJWT(h.JWTConfig(), token)
+	if err != nil || h.CheckAccessToken(r.Context(), claims.Email, claims.IssuedAtTime()) != nil {
+		return anonymous
+	}
+	email := claims.Email
+	subject := quota.Subject{Key: "user:" + email, Plan: quota.PlanFree}
+	if h.Users == nil {
+		return subject
+	}
+	if user, err := h.Users.Get(r.Context(), email); err == nil && user.Plan != "" {
+		subject.Plan = user.Plan
+	}
+	return subject
+}
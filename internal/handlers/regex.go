@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/tools"
+)
+
+// RegexHandler tests a regular expression against the input of the
+// request. A pattern RE2 does not compile is a 400 carrying the byte
+// position of the error in the pattern.
+func RegexHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.RegexTestRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	result, err := tools.TestRegex(r.Context(), req)
+	var patternErr *tools.PatternError
+	switch {
+	case errors.As(err, &patternErr):
+		code, field := ErrorCode(http.StatusBadRequest, err)
+		writeAPIError(w, http.StatusBadRequest, map[string]interface{}{
+			"message":  err.Error(),
+			"code":     code,
+			"field":    field,
+			"position": patternErr.Position,
+		})
+		return
+	case errors.Is(err, tools.ErrRegexTooLarge), errors.Is(err, tools.ErrRegexOutputTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	case errors.Is(err, tools.ErrRegexTimeout):
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"regexResult": result})
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/policy"
+)
+
+const maxPlainBodyBytes = 4 * 1024
+
+var (
+	errUnsupportedContentType = errors.New("unsupported content type: use application/json or text/plain")
+	errMultiLinePlainBody     = errors.New("plain text body must contain a single value on one line; use the batch endpoint for multiple values")
+	errPlainBodyTooLarge      = errors.New("plain text body is too large")
+	errEmptyPlainBody         = errors.New("plain text body is empty")
+)
+
+// decodeSingleValueRequest decodes a request for a single-value endpoint.
+// JSON bodies are decoded into v; text/plain bodies are trimmed, checked to
+// be a single line and stored in *value.
+func decodeSingleValueRequest(r *http.Request, v interface{}, value *string) error {
+	mediaType := ""
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		parsed, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return errUnsupportedContentType
+		}
+		mediaType = parsed
+	}
+
+	switch mediaType {
+	case "", "application/json":
+		return newJSONDecoder(r).Decode(v)
+
+	case "text/plain":
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxPlainBodyBytes+1))
+		if err != nil {
+			return err
+		}
+		if len(body) > maxPlainBodyBytes {
+			return errPlainBodyTooLarge
+		}
+		text := strings.TrimSpace(string(body))
+		if text == "" {
+			return errEmptyPlainBody
+		}
+		if strings.ContainsAny(text, "\r\n") {
+			return errMultiLinePlainBody
+		}
+		*value = text
+		return nil
+
+	default:
+		return errUnsupportedContentType
+	}
+}
+
+// newJSONDecoder returns a decoder of r's body refusing fields the target
+// does not have, so a misspelt option is an error rather than a default
+func newJSONDecoder(r *http.Request) *json.Decoder {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec
+}
+
+// unknownJSONField returns the field named by the error of a decoder from
+// newJSONDecoder for a field the target does not have. encoding/json has
+// no error type for it, only the message.
+func unknownJSONField(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	quoted, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return "", false
+	}
+	field, unquoteErr := strconv.Unquote(quoted)
+	return field, unquoteErr == nil
+}
+
+// decodeJSONBody decodes a JSON request body into v, writing the error
+// response itself when the body is invalid, has a field v does not have
+// or is over the route group's cap
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := newJSONDecoder(r).Decode(v)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		WriteError(w, http.StatusRequestEntityTooLarge, BodyTooLargeErrorCode, "request body is too large")
+	} else if field, ok := unknownJSONField(err); ok {
+		writeAPIError(w, http.StatusBadRequest, models.APIError{Message: fmt.Sprintf("unknown field %q", field), Code: UnknownFieldErrorCode, Field: field})
+	} else {
+		WriteError(w, http.StatusBadRequest, InvalidJSONErrorCode, "invalid JSON body")
+	}
+	return false
+}
+
+// writePolicyError reports an option rejected by the route group's policy
+func writePolicyError(w http.ResponseWriter, err error) {
+	var optErr *policy.OptionError
+	if !errors.As(err, &optErr) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeAPIError(w, http.StatusBadRequest, struct {
+		models.APIError
+		Option string `json:"option"`
+		Group  string `json:"group"`
+	}{models.APIError{Message: optErr.Error(), Code: InvalidOptionErrorCode, Field: optErr.Option}, optErr.Option, optErr.Group})
+}
+
+// decodeErrorStatus maps a request decoding error to its HTTP status code
+func decodeErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	switch err {
+	case errUnsupportedContentType:
+		return http.StatusUnsupportedMediaType
+	case errPlainBodyTooLarge:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusBadRequest
+	}
+}
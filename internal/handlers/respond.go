@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/logging"
+)
+
+// A response body gets bodyWriteBase plus the time to send it at
+// minClientBytesPerSecond before its write deadline, so a client reading
+// slower than that cannot hold a worker
+const (
+	bodyWriteBase           = 10 * time.Second
+	minClientBytesPerSecond = 64 << 10
+)
+
+// bodyWriteDeadline is how long a body of size bytes may take to write
+func bodyWriteDeadline(size int) time.Duration {
+	return bodyWriteBase + time.Duration(size)*time.Second/minClientBytesPerSecond
+}
+
+// writeBody writes data after the headers, with a write deadline sized to
+// data. It reports false when the client went away, in which case the
+// caller has nothing left to do: the status middleware counts the request
+// as a client disconnect.
+func writeBody(w http.ResponseWriter, r *http.Request, data []byte) bool {
+	if err := r.Context().Err(); err != nil {
+		logging.FromContext(r.Context()).Info("Client disconnected before the response was written", "method", r.Method, "path", r.URL.Path, "error", err)
+		return false
+	}
+	rc := http.NewResponseController(w)
+	// Writers without deadline support, such as test recorders, write
+	// without one
+	_ = rc.SetWriteDeadline(time.Now().Add(bodyWriteDeadline(len(data))))
+	n, err := w.Write(data)
+	if err != nil {
+		logging.FromContext(r.Context()).Info("Client disconnected during the response", "method", r.Method, "path", r.URL.Path, "written", n, "size", len(data), "error", err)
+		return false
+	}
+	return true
+}
+
+// writeJSON encodes v as the JSON response with status, as application/json
+// unless the handler set a more specific Content-Type. v is encoded before
+// anything is written, so a value that cannot be encoded becomes a 500
+// instead of a truncated body.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to encode response", "method", r.Method, "path", r.URL.Path, "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to encode response")
+		return
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	writeBody(w, r, append(data, '\n'))
+}
+
+// errClientGone ends a streamed response whose client went away
+var errClientGone = errors.New("client disconnected")
+
+// bodyWriter streams a response body through writeBody, each write with
+// its own deadline
+type bodyWriter struct {
+	w http.ResponseWriter
+	r *http.Request
+}
+
+func (b bodyWriter) Write(p []byte) (int, error) {
+	if !writeBody(b.w, b.r, p) {
+		return 0, errClientGone
+	}
+	return len(p), nil
+}
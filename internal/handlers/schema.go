@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/schema"
+)
+
+// SchemasHandler lists the names of the public response schemas
+func SchemasHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"schemas": schema.Names()})
+}
+
+// SchemaHandler serves the JSON Schema of a public response for client
+// code generation. Like the test vectors, it answers 500 rather than
+// publish a schema the build no longer honors.
+func SchemaHandler(w http.ResponseWriter, r *http.Request) {
+	s, err := schema.Lookup(mux.Vars(r)["name"])
+	switch {
+	case errors.Is(err, schema.ErrUnknownSchema):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("Response schema is broken", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "schema is unavailable")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, r, http.StatusOK, s)
+}
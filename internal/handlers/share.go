@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/redact"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/utils"
+)
+
+const (
+	// ShareTTL is how long a shared result stays reachable
+	ShareTTL = 30 * 24 * time.Hour
+	// ShareIDLength is the number of base58 characters of a share ID,
+	// about 58 bits of randomness
+	ShareIDLength = 10
+	// SharesPerHour is how many results one client IP may share an hour
+	SharesPerHour = 30
+)
+
+// checkShareRequest reports whether a share request may be served, writing
+// the error response when sharing is not configured or the client has
+// shared too much recently. It runs before the validation.
+func (h *Handlers) checkShareRequest(w http.ResponseWriter, r *http.Request) bool {
+	if h.Shares == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "result sharing is not configured")
+		return false
+	}
+	if h.ShareLimiter == nil {
+		return true
+	}
+	if decision := h.ShareLimiter.Allow(r.Context(), h.callerIP(r)); !decision.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(decision.RetryAfter.Seconds()+0.999)))
+		WriteError(w, http.StatusTooManyRequests, middleware.RateLimitErrorCode, "share rate limit exceeded")
+		return false
+	}
+	return true
+}
+
+// shareResult stores the result of tool for a permalink and returns its
+// URL. The result is redacted with the rules used for logs and fixtures
+// before it is stored, so the raw input is never kept.
+func (h *Handlers) shareResult(ctx context.Context, tool string, result interface{}) (string, error) {
+	id, err := utils.RandomBase58(ShareIDLength)
+	if err != nil {
+		return "", err
+	}
+	redacted, err := json.Marshal(redact.RedactStruct(result))
+	if err != nil {
+		return "", err
+	}
+	now := h.Clock.Now().UTC()
+	err = h.Shares.Save(ctx, models.SharedResult{
+		ID:        id,
+		Tool:      tool,
+		Result:    redacted,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ShareTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return h.site().AbsURL("/r/" + id), nil
+}
+
+// shareAs returns tool when the request asked to share its result, the
+// shareTool argument of writeValidationResult
+func shareAs(share bool, tool string) string {
+	if !share {
+		return ""
+	}
+	return tool
+}
+
+// writeShareError reports a result that was validated but not stored
+func writeShareError(w http.ResponseWriter, r *http.Request, err error) {
+	logging.FromContext(r.Context()).Error("Failed to share result", "error", err)
+	WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to share result")
+}
+
+// site returns the configuration public URLs are built from, the
+// defaults without one
+func (h *Handlers) site() *config.Config {
+	if h.Config == nil {
+		return config.Default()
+	}
+	return h.Config
+}
+
+// LookupSharedResult returns the unexpired shared result with id. IDs that
+// cannot have been issued are repository.ErrShareNotFound without a store
+// lookup.
+func (h *Handlers) LookupSharedResult(ctx context.Context, id string) (models.SharedResult, error) {
+	if len(id) != ShareIDLength || !utils.IsBase58(id) {
+		return models.SharedResult{}, repository.ErrShareNotFound
+	}
+	return h.Shares.Get(ctx, id, h.Clock.Now())
+}
+
+// SharedResultHandler returns a shared result as JSON
+func (h *Handlers) SharedResultHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Shares == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "result sharing is not configured")
+		return
+	}
+	shared, err := h.LookupSharedResult(r.Context(), mux.Vars(r)["id"])
+	if errors.Is(err, repository.ErrShareNotFound) {
+		WriteError(w, http.StatusNotFound, NotFoundErrorCode, "shared result not found or expired")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load shared result", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to load shared result")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"sharedResult": shared})
+}
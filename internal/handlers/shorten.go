@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/shortener"
+)
+
+// shortURLPath is the path short links are served under
+const shortURLPath = "/s/"
+
+var errShortenerUnavailable = errors.New("the URL shortener needs MongoDB")
+
+// ShortenHandler stores a short link to an http or https URL and answers
+// 201 with its code and public URL. URLs flagged by the reputation check
+// are refused with 422.
+func (h *Handlers) ShortenHandler(w http.ResponseWriter, r *http.Request) {
+	if h.ShortURLs == nil {
+		writeError(w, http.StatusServiceUnavailable, errShortenerUnavailable)
+		return
+	}
+	var req models.ShortenRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if req.URL != "" && !h.allowURL(w, r, req.URL, "short link") {
+		return
+	}
+	short, err := h.ShortURLs.Create(r.Context(), req)
+	switch {
+	case errors.Is(err, shortener.ErrInvalidShortURL):
+		writeError(w, http.StatusBadRequest, err)
+		return
+	case errors.Is(err, repository.ErrShortURLExists):
+		writeError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("Failed to create short link", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to create the short link")
+		return
+	}
+
+	shortURL := h.site().AbsURL(shortURLPath + short.Code)
+	w.Header().Set("Location", shortURL)
+	writeJSON(w, r, http.StatusCreated, models.ShortenResponse{
+		Code:      short.Code,
+		ShortURL:  shortURL,
+		URL:       short.URL,
+		CreatedAt: short.CreatedAt,
+		ExpiresAt: short.ExpiresAt,
+	})
+}
+
+// ShortURLRedirectHandler counts a visit of a short link and redirects to
+// its URL with 301. Unknown codes answer 404 and expired links 410. The
+// redirect is not cacheable, so every visit is counted and expiry applies.
+func (h *Handlers) ShortURLRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	if h.ShortURLs == nil {
+		writeError(w, http.StatusNotFound, repository.ErrShortURLNotFound)
+		return
+	}
+	short, err := h.ShortURLs.Visit(r.Context(), mux.Vars(r)["code"])
+	switch {
+	case errors.Is(err, repository.ErrShortURLNotFound):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, repository.ErrShortURLExpired):
+		writeError(w, http.StatusGone, err)
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("Failed to resolve short link", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to resolve the short link")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	http.Redirect(w, r, short.URL, http.StatusMovedPermanently)
+}
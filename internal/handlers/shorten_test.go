@@ -0,0 +1,139 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/shortener"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+func newShortenHandlers() (*handlers.Handlers, *testutil.ShortURLStore, *testutil.Clock) {
+	h := testutil.NewHandlers()
+	store := testutil.NewShortURLStore()
+	clock := h.Clock.(*testutil.Clock)
+	h.ShortURLs = shortener.New(store, clock)
+	return h, store, clock
+}
+
+func shorten(h *handlers.Handlers, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/v1/shorten", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ShortenHandler(rec, req)
+	return rec
+}
+
+func visitShortURL(h *handlers.Handlers, code string) *httptest.ResponseRecorder {
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/s/"+code, nil), map[string]string{"code": code})
+	rec := httptest.NewRecorder()
+	h.ShortURLRedirectHandler(rec, req)
+	return rec
+}
+
+func TestShortenAndRedirect(t *testing.T) {
+	h, store, clock := newShortenHandlers()
+	rec := shorten(h, `{"url":"https://example.com/docs?page=2","expires_in_days":30}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("shorten: status %d: %s", rec.Code, rec.Body)
+	}
+	var created models.ShortenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Code) != shortener.CodeLength || created.ShortURL != h.Config.AbsURL("/s/"+created.Code) ||
+		rec.Header().Get("Location") != created.ShortURL {
+		t.Errorf("created = %+v, Location %q", created, rec.Header().Get("Location"))
+	}
+	if want := clock.Now().AddDate(0, 0, 30); created.ExpiresAt == nil || !created.ExpiresAt.Equal(want) {
+		t.Errorf("expires_at = %v, want %v", created.ExpiresAt, want)
+	}
+
+	for i := 0; i < 2; i++ {
+		rec = visitShortURL(h, created.Code)
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com/docs?page=2" {
+			t.Fatalf("visit: status %d, Location %q", rec.Code, rec.Header().Get("Location"))
+		}
+	}
+	if hits := store.URLs[created.Code].Hits; hits != 2 {
+		t.Errorf("hits = %d, want 2", hits)
+	}
+
+	clock.Advance(30 * 24 * time.Hour)
+	if rec := visitShortURL(h, created.Code); rec.Code != http.StatusGone {
+		t.Errorf("expired: status %d, want 410", rec.Code)
+	}
+	if hits := store.URLs[created.Code].Hits; hits != 2 {
+		t.Errorf("an expired visit was counted: hits = %d", hits)
+	}
+	if rec := visitShortURL(h, "unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown code: status %d, want 404", rec.Code)
+	}
+}
+
+func TestShortenCustomAlias(t *testing.T) {
+	h, _, _ := newShortenHandlers()
+	rec := shorten(h, `{"url":"https://example.com/","custom_alias":"launch-2026"}`)
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"short_url":"`+h.Config.AbsURL("/s/launch-2026")+`"`) ||
+		strings.Contains(rec.Body.String(), "expires_at") {
+		t.Fatalf("alias: status %d: %s", rec.Code, rec.Body)
+	}
+	rec = shorten(h, `{"url":"https://example.org/","custom_alias":"launch-2026"}`)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"code":"CONFLICT","field":"custom_alias"`) {
+		t.Errorf("taken alias: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := visitShortURL(h, "launch-2026"); rec.Header().Get("Location") != "https://example.com/" {
+		t.Errorf("taken alias was replaced: Location %q", rec.Header().Get("Location"))
+	}
+}
+
+func TestShortenRejects(t *testing.T) {
+	h, _, _ := newShortenHandlers()
+	for _, body := range []string{
+		`{"url":""}`,
+		`{"url":"ftp://example.com/file"}`,
+		`{"url":"/relative"}`,
+		`{"url":"https://example.com/","custom_alias":"a/b"}`,
+		`{"url":"https://example.com/","expires_in_days":-1}`,
+		`{"url":"https://example.com/","expires_in_days":3651}`,
+	} {
+		if rec := shorten(h, body); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"code":"INVALID_DATA"`) {
+			t.Errorf("%s: status %d: %s", body, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestShortenFlaggedURL(t *testing.T) {
+	h, store, _ := newShortenHandlers()
+	if err := h.Blocklist.Add(t.Context(), models.BlockedDomain{Domain: "phish.example"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.URLReputation.ReloadManual(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	rec := shorten(h, `{"url":"https://login.phish.example/reset"}`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"code":"URL_FLAGGED"`) {
+		t.Errorf("status %d: %s", rec.Code, rec.Body)
+	}
+	if len(store.URLs) != 0 {
+		t.Errorf("flagged URL stored: %+v", store.URLs)
+	}
+}
+
+func TestShortenUnavailable(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.ShortURLs = nil
+	if rec := shorten(h, `{"url":"https://example.com/"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("shorten: status %d, want 503", rec.Code)
+	}
+	if rec := visitShortURL(h, "abcdefg"); rec.Code != http.StatusNotFound {
+		t.Errorf("visit: status %d, want 404", rec.Code)
+	}
+}
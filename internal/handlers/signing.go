@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/utils"
+)
+
+// JWKSHandler publishes the public keys response signatures verify against
+func (h *Handlers) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Signer == nil {
+		WriteError(w, http.StatusNotFound, NotFoundErrorCode, "response signing is not configured")
+		return
+	}
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, r, http.StatusOK, h.Signer.JWKS())
+}
+
+// checkSignRequest reports whether a sign_response request may be served,
+// writing the error response when it may not. Signing requires an access
+// token so signatures are only issued to known partners.
+func (h *Handlers) checkSignRequest(w http.ResponseWriter, r *http.Request) bool {
+	if h.Signer == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "response signing is not configured")
+		return false
+	}
+	if r.Header.Get("Authorization") == "" {
+		WriteError(w, http.StatusUnauthorized, UnauthorizedErrorCode, "sign_response requires an access token")
+		return false
+	}
+	if !h.validAccessToken(r) {
+		WriteError(w, http.StatusUnauthorized, UnauthorizedErrorCode, "sign_response requires a valid access token")
+		return false
+	}
+	return true
+}
+
+// validAccessToken reports whether r carries a valid access token as
+// "Authorization: Bearer <JWT>" that has not been revoked
+func (h *Handlers) validAccessToken(r *http.Request) bool {
+	return h.accessTokenEmail(r) != ""
+}
+
+// accessTokenEmail returns the user of r's valid, unrevoked access token,
+// or "" without one
+func (h *Handlers) accessTokenEmail(r *http.Request) string {
+	token, ok := middleware.BearerToken(r)
+	if !ok {
+		return ""
+	}
+	claims, err := utils.ValidateJWT(h.JWTConfig(), token)
+	if err != nil {
+		return ""
+	}
+	if err := h.CheckAccessToken(r.Context(), claims.Email, claims.IssuedAtTime()); err != nil {
+		if !errors.Is(err, utils.ErrTokenRevoked) {
+			logging.FromContext(r.Context()).Error("Token check failed", "error", err)
+		}
+		return ""
+	}
+	return claims.Email
+}
+
+// writeValidationResult writes {"validationResult": result} with status and
+// its summary, adding a detached JWS over the canonical result when sign is set and the
+// result metadata when r asked for it. The metadata is not signed. With a
+// shareTool the redacted result is stored under that tool name and its
+// permalink returned as share_url.
+func (h *Handlers) writeValidationResult(w http.ResponseWriter, r *http.Request, status int, result interface{}, sign bool, shareTool string) {
+	body := map[string]interface{}{"validationResult": result}
+	addSummary(body, result)
+	if shareTool != "" {
+		shareURL, err := h.shareResult(r.Context(), shareTool, result)
+		if err != nil {
+			writeShareError(w, r, err)
+			return
+		}
+		body["share_url"] = shareURL
+	}
+	if sign {
+		sig, err := h.Signer.Sign(result, h.Clock.Now())
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Failed to sign validation result", "error", err)
+			WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to sign response")
+			return
+		}
+		body["signature"] = sig.JWS
+		body["signed_at"] = sig.SignedAt.Format(time.RFC3339)
+		body["key_id"] = sig.KeyID
+	}
+	h.addResultMeta(r, body)
+
+	writeJSON(w, r, status, body)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/redact"
+)
+
+// resultSummary describes a validation result in one sentence, for the
+// aria-label of the tool pages' result area, so the pages need not build it
+// from the JSON. Numbers are shortened to their last characters. The
+// service has no translations, so summaries are English. It returns ""
+// for result types without a summary.
+func resultSummary(result interface{}) string {
+	switch result := result.(type) {
+	case models.IBANValidation:
+		return ibanSummary(result)
+	case models.EmailValidation:
+		return emailSummary(result)
+	case models.GeoIPResponse:
+		return ipSummary(result)
+	case models.BankAccountValidation:
+		return bankAccountSummary(result)
+	default:
+		return ""
+	}
+}
+
+// addSummary adds the "summary" of result to body. It is outside the
+// result, so signatures do not cover it.
+func addSummary(body map[string]interface{}, result interface{}) {
+	if summary := resultSummary(result); summary != "" {
+		body["summary"] = summary
+	}
+}
+
+func ibanSummary(result models.IBANValidation) string {
+	subject := "IBAN"
+	if last := redact.Tail(result.IBAN); last != "" {
+		subject = "IBAN ending in " + last
+	}
+	if result.IsValid {
+		return fmt.Sprintf("%s is valid, from %s.", subject, result.CountryName)
+	}
+	var reason string
+	switch {
+	case !result.IsFormatValid:
+		reason = "it is not formatted as an IBAN"
+	case !result.IsCountrySupported:
+		reason = fmt.Sprintf("country %s is not supported", result.CountryCode)
+	case !result.IsLengthValid:
+		reason = fmt.Sprintf("its length is wrong for %s", result.CountryName)
+	case !result.IsChecksumValid:
+		reason = "its check digits do not match"
+	case result.IsNationalChecksumValid != nil && !*result.IsNationalChecksumValid:
+		reason = "its national check digits do not match"
+	default:
+		return subject + " is invalid."
+	}
+	return fmt.Sprintf("%s is invalid: %s.", subject, reason)
+}
+
+func emailSummary(result models.EmailValidation) string {
+	var checks []string
+	addCheck := func(passed *bool, yes, no string) {
+		switch {
+		case passed == nil:
+		case *passed:
+			checks = append(checks, yes)
+		default:
+			checks = append(checks, no)
+		}
+	}
+	addCheck(result.IsSyntaxValid, "syntax is valid", "syntax is invalid")
+	addCheck(result.IsDomainValid, "domain exists", "domain does not exist")
+	addCheck(result.MxRecordsFound, "mail servers found", "no mail servers found")
+	addCheck(result.IsDisposable, "disposable provider", "not a disposable provider")
+	if len(result.SkippedChecks) > 0 {
+		checks = append(checks, "skipped "+strings.Join(result.SkippedChecks, ", "))
+	}
+	if len(checks) == 0 {
+		return fmt.Sprintf("Email %s: no checks ran.", result.Email)
+	}
+	return fmt.Sprintf("Email %s: %s.", result.Email, strings.Join(checks, "; "))
+}
+
+func ipSummary(result models.GeoIPResponse) string {
+	switch {
+	case result.IsPrivate:
+		return fmt.Sprintf("IP %s is a private address.", result.IP)
+	case result.IsLoopback:
+		return fmt.Sprintf("IP %s is a loopback address.", result.IP)
+	case result.IsReserved:
+		return fmt.Sprintf("IP %s is in a reserved range.", result.IP)
+	case result.NotFound:
+		return fmt.Sprintf("IP %s is not in the geolocation database.", result.IP)
+	}
+	var place []string
+	for _, part := range []*string{result.City, result.Region, result.Country} {
+		if part != nil && *part != "" {
+			place = append(place, *part)
+		}
+	}
+	if len(place) == 0 {
+		return fmt.Sprintf("IP %s has no known location.", result.IP)
+	}
+	return fmt.Sprintf("IP %s is located in %s.", result.IP, strings.Join(place, ", "))
+}
+
+func bankAccountSummary(result models.BankAccountValidation) string {
+	subject := result.Country + " bank account"
+	if last := redact.Tail(result.AccountNumber); last != "" {
+		subject += " ending in " + last
+	}
+	if !result.IsValid {
+		return subject + " is invalid."
+	}
+	if result.Institution != "" {
+		return fmt.Sprintf("%s is valid, at %s.", subject, result.Institution)
+	}
+	return subject + " is valid."
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/tenant"
+)
+
+// tenantsPath is the base path of the tenant admin endpoints
+const tenantsPath = "/api/v1/admin/tenants/"
+
+var (
+	errTenantsUnavailable = errors.New("tenants need MongoDB")
+	errTenantExists       = errors.New("tenant already exists")
+	errHostTaken          = errors.New("host is served by another tenant")
+)
+
+// TenantsHandler lists the tenants. Webhook secrets are never returned.
+func (h *Handlers) TenantsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Tenants == nil {
+		writeError(w, http.StatusServiceUnavailable, errTenantsUnavailable)
+		return
+	}
+	tenants, err := h.Tenants.List(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to list tenants", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to list tenants")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"tenants": tenants})
+}
+
+// TenantHandler returns one tenant
+func (h *Handlers) TenantHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Tenants == nil {
+		writeError(w, http.StatusServiceUnavailable, errTenantsUnavailable)
+		return
+	}
+	t, err := h.Tenants.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeTenantError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"tenant": t})
+}
+
+// CreateTenantHandler stores a new tenant named by the request's id and
+// answers 201. It applies to this instance at once and to the others
+// within tenant.CacheTTL.
+func (h *Handlers) CreateTenantHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Tenants == nil {
+		writeError(w, http.StatusServiceUnavailable, errTenantsUnavailable)
+		return
+	}
+	var req models.TenantRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	_, err := h.Tenants.Get(r.Context(), req.ID)
+	switch {
+	case err == nil:
+		writeTenantError(w, r, errTenantExists)
+		return
+	case !errors.Is(err, repository.ErrTenantNotFound):
+		writeTenantError(w, r, err)
+		return
+	}
+	h.storeTenant(w, r, req.ID, req, nil)
+}
+
+// PutTenantHandler creates or replaces the tenant at the path, keeping its
+// webhook secret unless the request sets one
+func (h *Handlers) PutTenantHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Tenants == nil {
+		writeError(w, http.StatusServiceUnavailable, errTenantsUnavailable)
+		return
+	}
+	var req models.TenantRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	stored, err := h.Tenants.Get(r.Context(), id)
+	switch {
+	case err == nil:
+		h.storeTenant(w, r, id, req, &stored)
+	case errors.Is(err, repository.ErrTenantNotFound):
+		h.storeTenant(w, r, id, req, nil)
+	default:
+		writeTenantError(w, r, err)
+	}
+}
+
+// DeleteTenantHandler removes a tenant. Its users' access tokens keep
+// working for the default tenant.
+func (h *Handlers) DeleteTenantHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Tenants == nil {
+		writeError(w, http.StatusServiceUnavailable, errTenantsUnavailable)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if err := h.Tenants.Delete(r.Context(), id); err != nil {
+		writeTenantError(w, r, err)
+		return
+	}
+	h.invalidateTenants()
+	logging.FromContext(r.Context()).Info("Tenant deleted", "tenant", id, "by", middleware.TokenEmail(r.Context()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// storeTenant validates req as the tenant with id replacing stored, or a
+// new tenant when stored is nil, and writes the response
+func (h *Handlers) storeTenant(w http.ResponseWriter, r *http.Request, id string, req models.TenantRequest, stored *models.Tenant) {
+	t, err := tenant.FromRequest(id, req, stored, h.Clock.Now())
+	if err != nil {
+		writeTenantError(w, r, err)
+		return
+	}
+	if err := h.checkTenantHosts(r.Context(), t); err != nil {
+		writeTenantError(w, r, err)
+		return
+	}
+	if err := h.Tenants.Put(r.Context(), t); err != nil {
+		writeTenantError(w, r, err)
+		return
+	}
+	h.invalidateTenants()
+	logging.FromContext(r.Context()).Info("Tenant stored", "tenant", t.ID, "by", middleware.TokenEmail(r.Context()))
+
+	status := http.StatusOK
+	if stored == nil {
+		status = http.StatusCreated
+		w.Header().Set("Location", h.site().Path(tenantsPath+t.ID))
+	}
+	writeJSON(w, r, status, map[string]interface{}{"tenant": t})
+}
+
+// checkTenantHosts refuses hosts another tenant already serves
+func (h *Handlers) checkTenantHosts(ctx context.Context, t models.Tenant) error {
+	if len(t.Hosts) == 0 {
+		return nil
+	}
+	tenants, err := h.Tenants.List(ctx)
+	if err != nil {
+		return err
+	}
+	taken := map[string]string{}
+	for _, other := range tenants {
+		if other.ID == t.ID {
+			continue
+		}
+		for _, host := range other.Hosts {
+			taken[host] = other.ID
+		}
+	}
+	for _, host := range t.Hosts {
+		if owner, ok := taken[host]; ok {
+			return fmt.Errorf("%w: %s belongs to %s", errHostTaken, host, owner)
+		}
+	}
+	return nil
+}
+
+// invalidateTenants applies a tenant change to this instance's registry
+func (h *Handlers) invalidateTenants() {
+	if h.TenantRegistry != nil {
+		h.TenantRegistry.Invalidate()
+	}
+}
+
+// writeTenantError answers with the status of a tenant error
+func writeTenantError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, tenant.ErrInvalidTenant):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, repository.ErrTenantNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, errTenantExists), errors.Is(err, errHostTaken):
+		writeError(w, http.StatusConflict, err)
+	default:
+		logging.FromContext(r.Context()).Error("Failed to store tenant", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to store the tenant")
+	}
+}
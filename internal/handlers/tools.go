@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/policy"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/warnings"
+)
+
+// liteAPIPrefix replaces /api/v1 for routes served in lite mode
+const liteAPIPrefix = "/api/lite/v1"
+
+// ToolsSpecHandler describes the tools and which options each route group
+// restricts, so the widget can hide what lite mode rejects
+func ToolsSpecHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"group": policy.FromContext(r.Context()).Group,
+		"tools": toolSpecs(policy.Full, policy.Lite),
+	})
+}
+
+// LookupToolSpec returns the spec of the named tool as /api/v1/tools
+// describes it
+func LookupToolSpec(name string) (models.ToolSpec, bool) {
+	for _, spec := range toolSpecs(policy.Full, policy.Lite) {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return models.ToolSpec{}, false
+}
+
+func toolSpecs(full, lite policy.Policy) []models.ToolSpec {
+	maxPerGroup := func(fullMax, liteMax int) map[string]int {
+		return map[string]int{full.Group: fullMax, lite.Group: liteMax}
+	}
+	unavailableUnless := func(allowed bool) []string {
+		if allowed {
+			return nil
+		}
+		return []string{lite.Group}
+	}
+	tool := func(name, method, path string, inLite bool, options ...models.ToolOption) models.ToolSpec {
+		spec := models.ToolSpec{Name: name, Method: method, Path: "/api/v1" + path, Options: options}
+		if inLite {
+			spec.LitePath = liteAPIPrefix + path
+		} else {
+			spec.UnavailableIn = []string{lite.Group}
+		}
+		return spec
+	}
+	withWarnings := func(spec models.ToolSpec, codes ...string) models.ToolSpec {
+		spec.Warnings = codes
+		return spec
+	}
+	sanitizeWarnings := []string{warnings.CodeTextSanitized, warnings.CodeTextNormalized}
+	// shareOption stores the result for a permalink, see shareResult
+	shareOption := models.ToolOption{Name: "share", Type: "boolean"}
+	// uploadOption reads the body from a completed upload, see AcceptUpload
+	uploadOption := models.ToolOption{Name: "upload", Type: "string", UnavailableIn: []string{lite.Group}}
+
+	return []models.ToolSpec{
+		tool("email-validate", "POST", "/validate/email", true,
+			models.ToolOption{Name: "checks", Type: "array"},
+			models.ToolOption{Name: "domain_check", Type: "check", UnavailableIn: unavailableUnless(lite.AllowNetworkChecks)},
+			models.ToolOption{Name: "mx_check", Type: "check", UnavailableIn: unavailableUnless(lite.AllowNetworkChecks)},
+			shareOption,
+		),
+		tool("ip-validate", "POST", "/validate/ip", true, shareOption),
+		withWarnings(tool("iban-validate", "POST", "/validate/iban", true, shareOption), warnings.CodeIBANSeparators),
+		tool("bankaccount-validate", "POST", "/validate/bankaccount", true, shareOption),
+		tool("barcode-validate", "POST", "/validate/barcode", true),
+		tool("card-validate", "POST", "/validate/card", false),
+		tool("iban-format", "POST", "/iban/format", true),
+		tool("iban-format-rules", "GET", "/iban/format/{countryCode}", true),
+		tool("email-batch-validate", "POST", "/validate/email/batch", lite.AllowBatch, uploadOption),
+		tool("ip-batch-validate", "POST", "/validate/ip/batch", lite.AllowBatch, uploadOption),
+		tool("iban-batch-validate", "POST", "/validate/iban/batch", lite.AllowBatch, uploadOption),
+		withWarnings(tool("qr-generate", "POST", "/generate/qr", true,
+			models.ToolOption{Name: "size", Type: "integer", Max: maxPerGroup(full.MaxQRSize, lite.MaxQRSize)},
+		), append(sanitizeWarnings, warnings.CodeOptionIgnored, warnings.CodeLowModuleSize)...),
+		withWarnings(tool("barcode-generate", "POST", "/generate/barcode", true,
+			models.ToolOption{Name: "width", Type: "integer", Max: maxPerGroup(full.MaxBarcodeWidth, lite.MaxBarcodeWidth)},
+			models.ToolOption{Name: "height", Type: "integer", Max: maxPerGroup(full.MaxBarcodeHeight, lite.MaxBarcodeHeight)},
+			models.ToolOption{Name: "font", Type: "string"},
+		), append(sanitizeWarnings, generator.BarcodeWarningCodes()...)...),
+		tool("ics-generate", "POST", "/generate/ics", true),
+		tool("token-generate", "POST", "/generate/token", false),
+		tool("uuid-generate", "POST", "/generate/uuid", false),
+		tool("labels-generate", "POST", "/generate/labels", false),
+		tool("url-shorten", "POST", "/shorten", false),
+		tool("text-convert", "POST", "/convert/text", false),
+		tool("csv-convert", "POST", "/convert/csv", false),
+		tool("timestamp-convert", "POST", "/convert/timestamp", false),
+		tool("regex-test", "POST", "/tools/regex", false),
+		tool("password-check", "POST", "/tools/password/check", false),
+		tool("password-generate", "POST", "/tools/password/generate", false),
+		tool("email-extract", "POST", "/extract/emails", false),
+		tool("number-parse", "POST", "/parse/number", true,
+			models.ToolOption{Name: "values", Type: "array", UnavailableIn: unavailableUnless(lite.AllowBatch)},
+		),
+		tool("textsafety-analyze", "POST", "/analyze/textsafety", true, uploadOption),
+		withWarnings(tool("qr-analyze", "POST", "/analyze/qr", false), append(sanitizeWarnings, warnings.CodeOptionIgnored, warnings.CodeLowModuleSize)...),
+		tool("imagehash-analyze", "POST", "/analyze/imagehash", false),
+		tool("share-get", "GET", "/r/{id}", false),
+	}
+}
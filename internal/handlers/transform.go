@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/transform"
+)
+
+// HTML2TextHandler handles HTML to plain text conversion requests
+func HTML2TextHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.HTML2TextRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	result, err := transform.HTMLToText(req)
+	if err != nil {
+		if err == transform.ErrInputTooLarge {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"transformResult": result})
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/uploads"
+)
+
+// uploadsPath is the base path of the upload endpoints
+const uploadsPath = "/api/v1/uploads/"
+
+var (
+	errUploadsUnavailable = errors.New("resumable uploads are not configured")
+	errContentRange       = errors.New(`Content-Range must be "bytes first-last/total"`)
+)
+
+// CreateUploadHandler starts a resumable upload of the access token's user
+// and answers 201 with its status and Location
+func (h *Handlers) CreateUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Uploads == nil {
+		writeError(w, http.StatusServiceUnavailable, errUploadsUnavailable)
+		return
+	}
+	var req models.UploadRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	contentType := req.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	upload, err := h.Uploads.Create(r.Context(), middleware.TokenEmail(r.Context()), req.Size, strings.TrimSpace(req.SHA256), contentType)
+	if err != nil {
+		writeUploadError(w, r, err)
+		return
+	}
+	status := h.uploadStatus(upload)
+	w.Header().Set("Location", status.UploadURL)
+	writeJSON(w, r, http.StatusCreated, map[string]interface{}{"upload": status})
+}
+
+// UploadStatusHandler returns the state of an upload, so a client that
+// lost its connection learns where to resume
+func (h *Handlers) UploadStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Uploads == nil {
+		writeError(w, http.StatusNotFound, uploads.ErrUploadNotFound)
+		return
+	}
+	upload, err := h.Uploads.Get(middleware.TokenEmail(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		writeUploadError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"upload": h.uploadStatus(upload)})
+}
+
+// AppendUploadHandler stores the body as the chunk of the upload named by
+// its Content-Range header
+func (h *Handlers) AppendUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Uploads == nil {
+		writeError(w, http.StatusNotFound, uploads.ErrUploadNotFound)
+		return
+	}
+	start, end, total, err := parseContentRange(r.Header.Get("Content-Range"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if total >= 0 {
+		upload, err := h.Uploads.Get(middleware.TokenEmail(r.Context()), id)
+		if err != nil {
+			writeUploadError(w, r, err)
+			return
+		}
+		if total != upload.Size {
+			WriteError(w, http.StatusBadRequest, InvalidRequestErrorCode, "Content-Range total does not match the upload size")
+			return
+		}
+	}
+	if r.ContentLength >= 0 && r.ContentLength != end-start {
+		writeError(w, http.StatusBadRequest, uploads.ErrChunkLength)
+		return
+	}
+	upload, err := h.Uploads.Append(r.Context(), middleware.TokenEmail(r.Context()), id, start, end, r.Body)
+	if err != nil {
+		writeUploadError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"upload": h.uploadStatus(upload)})
+}
+
+// CompleteUploadHandler checks the size and sha256 of an upload and
+// returns the token that reads it
+func (h *Handlers) CompleteUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Uploads == nil {
+		writeError(w, http.StatusNotFound, uploads.ErrUploadNotFound)
+		return
+	}
+	upload, err := h.Uploads.Complete(r.Context(), middleware.TokenEmail(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		writeUploadError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"upload": h.uploadStatus(upload)})
+}
+
+// AcceptUpload lets next read a completed upload in place of its body:
+// with ?upload=<token> the request body and Content-Type are those of the
+// upload, which must belong to the user of the request's access token.
+// Requests without the parameter are passed on unchanged.
+func (h *Handlers) AcceptUpload(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("upload")
+		if token == "" {
+			next(w, r)
+			return
+		}
+		if h.Uploads == nil {
+			writeError(w, http.StatusServiceUnavailable, errUploadsUnavailable)
+			return
+		}
+		owner := h.accessTokenEmail(r)
+		if owner == "" {
+			WriteError(w, http.StatusUnauthorized, UnauthorizedErrorCode, "reading an upload requires the access token of the user who uploaded it")
+			return
+		}
+		data, upload, err := h.Uploads.Open(r.Context(), owner, token)
+		if err != nil {
+			writeUploadError(w, r, err)
+			return
+		}
+		defer data.Close()
+
+		r = r.Clone(r.Context())
+		r.Body = data
+		r.ContentLength = upload.Size
+		r.Header.Set("Content-Type", upload.ContentType)
+		r.Header.Del("Content-Encoding")
+		next(w, r)
+	}
+}
+
+func (h *Handlers) uploadStatus(upload uploads.Upload) models.UploadStatus {
+	return models.UploadStatus{
+		ID:          upload.ID,
+		ContentType: upload.ContentType,
+		Size:        upload.Size,
+		Received:    upload.Received,
+		SHA256:      upload.SHA256,
+		Complete:    upload.Token != "",
+		CreatedAt:   upload.CreatedAt,
+		ExpiresAt:   upload.ExpiresAt,
+		UploadURL:   h.site().Path(uploadsPath + upload.ID),
+		Token:       upload.Token,
+	}
+}
+
+// writeUploadError answers with the status of an uploads error. A chunk
+// at the wrong offset gets 409 with the offset to resume from.
+func writeUploadError(w http.ResponseWriter, r *http.Request, err error) {
+	var offset *uploads.OffsetError
+	var size *uploads.SizeError
+	switch {
+	case errors.As(err, &offset):
+		writeJSON(w, r, http.StatusConflict, map[string]interface{}{"message": err.Error(), "code": ConflictErrorCode, "received": offset.Expected})
+	case errors.As(err, &size) && size.Size > size.Max:
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+	case errors.As(err, &size):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, uploads.ErrUploadNotFound), errors.Is(err, uploads.ErrObjectNotFound):
+		writeError(w, http.StatusNotFound, uploads.ErrUploadNotFound)
+	case errors.Is(err, uploads.ErrTooManyUploads):
+		w.Header().Set("Retry-After", "60")
+		writeError(w, http.StatusServiceUnavailable, err)
+	case errors.Is(err, uploads.ErrOwnerUploadLimit):
+		writeError(w, http.StatusTooManyRequests, err)
+	case errors.Is(err, uploads.ErrUploadBusy), errors.Is(err, uploads.ErrUploadComplete),
+		errors.Is(err, uploads.ErrUploadIncomplete), errors.Is(err, uploads.ErrChunkMismatch):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, uploads.ErrHashMismatch):
+		writeError(w, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, uploads.ErrChunkLength), errors.Is(err, uploads.ErrChunkRange), errors.Is(err, uploads.ErrInvalidSHA256):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to store the upload")
+	}
+}
+
+// parseContentRange parses "bytes first-last/total" into the exclusive
+// range start to end; total is -1 when given as "*"
+func parseContentRange(header string) (start, end, total int64, err error) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, 0, 0, errContentRange
+	}
+	span, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, 0, errContentRange
+	}
+	first, last, ok := strings.Cut(span, "-")
+	if !ok {
+		return 0, 0, 0, errContentRange
+	}
+	start, err1 := strconv.ParseInt(first, 10, 64)
+	lastByte, err2 := strconv.ParseInt(last, 10, 64)
+	if err1 != nil || err2 != nil || start < 0 || lastByte < start {
+		return 0, 0, 0, errContentRange
+	}
+	total = -1
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil || total <= lastByte {
+			return 0, 0, 0, errContentRange
+		}
+	}
+	return start, lastByte + 1, total, nil
+}
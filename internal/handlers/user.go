@@ -1,46 +1,347 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
+	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/middleware"
 	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/tenant"
 	"github.com/innovelabs/microtools-go/internal/utils"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var MongoClient *mongo.Client
+// RegisterUserHandler handles user registration requests. Users
+// registering on a tenant's host belong to that tenant, which their access
+// tokens carry.
+func (h *Handlers) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Users == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "User storage unavailable")
+		return
+	}
+	if h.Config == nil {
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "Server configuration error")
+		return
+	}
 
-// RegisterUserHandler handles user registration requests
-func RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
 	var user models.UserRequest
 
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !decodeJSONBody(w, r, &user) {
 		return
 	}
 	if user.Email == "" {
-		http.Error(w, "Email is required", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, MissingFieldErrorCode, "Email is required")
 		return
 	}
-	collection := MongoClient.Database("microapps").Collection("users")
-	existingUser, err := collection.FindOne(r.Context(), bson.M{"email": user.Email}).Raw()
-	if existingUser != nil {
-		http.Error(w, "User already exists", http.StatusBadRequest)
+	if !validEmailSyntax(r.Context(), user.Email) {
+		WriteError(w, http.StatusBadRequest, InvalidDataErrorCode, "Invalid email address")
+		return
+	}
+
+	doc := models.User{
+		Email:     user.Email,
+		Name:      user.Name,
+		Company:   user.Company,
+		Country:   user.Country,
+		Tenant:    tokenTenant(tenant.FromContext(r.Context())),
+		Verified:  h.Config.AutoVerify,
+		CreatedAt: h.Clock.Now().UTC(),
+	}
+	// Unverified users can only get a token through the mailed link
+	if !doc.Verified && h.Mailer == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "Mail delivery unavailable")
+		return
+	}
+	var verifyToken string
+	if !doc.Verified {
+		var err error
+		verifyToken, doc.VerificationNonce, err = utils.GenerateVerificationToken(h.JWTConfig(), user.Email)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	} else {
+		doc.VerifiedAt = h.Clock.Now().UTC()
+	}
+
+	err := h.Users.Create(r.Context(), doc)
+	if errors.Is(err, repository.ErrUserExists) {
+		// A user whose verification link expired can only get a new one
+		// by registering again
+		err = h.Users.ReplaceUnverified(r.Context(), doc, doc.CreatedAt.Add(-utils.VerificationTokenTTL))
+	}
+	if errors.Is(err, repository.ErrUserExists) {
+		WriteError(w, http.StatusConflict, ConflictErrorCode, "User already exists")
+		return
+	}
+	if errors.Is(err, repository.ErrUserPendingDeletion) {
+		h.writePendingDeletion(w, r, user.Email)
 		return
 	}
-	_, err = collection.InsertOne(r.Context(), user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Access tokens are only issued to verified users
+	if doc.Verified {
+		jwt, jwtErr := utils.IssueToken(h.JWTConfig(), user.Email, utils.WithTenant(doc.Tenant))
+		if jwtErr != nil {
+			writeError(w, http.StatusInternalServerError, jwtErr)
+			return
+		}
+		writeJSON(w, r, http.StatusCreated, map[string]string{"message": "User registered successfully", "token": jwt})
+		return
+	}
+
+	body := fmt.Sprintf("Confirm your email address by opening this link within %s:\n\n%s\n", utils.VerificationTokenTTL, h.verificationURL(verifyToken))
+	if err := h.Mailer.Send(user.Email, "Verify your Micro API account", body); err != nil {
+		logger := logging.FromContext(r.Context())
+		logger.Error("Failed to send verification mail", "email", user.Email, "error", err)
+		// Without the mail the address could never be verified; release it
+		// so registering again works
+		if err := h.Users.DeleteUnverified(r.Context(), user.Email, doc.VerificationNonce); err != nil {
+			logger.Error("Failed to remove unverified user", "email", user.Email, "error", err)
+		}
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "Failed to send the verification mail")
 		return
 	}
-	jwt, jwtErr := utils.GenerateJWT(user.Email)
-	if jwtErr != nil {
-		http.Error(w, jwtErr.Error(), http.StatusInternalServerError)
+	writeJSON(w, r, http.StatusCreated, map[string]string{"message": "User registered; verify your email to receive an API token"})
+}
+
+// VerifyUserHandler confirms a user's email from a verification link and
+// issues their API token. Each link works once.
+func (h *Handlers) VerifyUserHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Users == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "User storage unavailable")
+		return
+	}
+
+	email, nonce, err := utils.ValidateVerificationToken(h.JWTConfig(), r.URL.Query().Get("token"))
+	switch {
+	case errors.Is(err, utils.ErrNoSecret):
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "Server configuration error")
+		return
+	case errors.Is(err, utils.ErrTokenExpired):
+		WriteError(w, http.StatusGone, middleware.TokenExpiredErrorCode, "Verification link expired")
+		return
+	case err != nil:
+		WriteError(w, http.StatusBadRequest, middleware.TokenInvalidErrorCode, "Invalid verification token")
 		return
 	}
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "User registered successfully", "token": jwt})
+
+	err = h.Users.MarkVerified(r.Context(), email, nonce, h.Clock.Now())
+	if errors.Is(err, repository.ErrVerificationNotFound) {
+		WriteError(w, http.StatusConflict, ConflictErrorCode, "Verification link already used")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	verified, err := h.Users.Get(r.Context(), email)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	jwt, err := utils.IssueToken(h.JWTConfig(), email, utils.WithTenant(verified.Tenant))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]string{"message": "Email verified", "token": jwt})
+}
+
+// UserTokenHandler issues a fresh access token to a registered, verified
+// user. Knowing an email must not be enough to act as its user, so the
+// token is mailed to the address; only with AutoVerify, where registration
+// hands out tokens without proof of the address either, is it returned.
+func (h *Handlers) UserTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Users == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "User storage unavailable")
+		return
+	}
+	if h.Config == nil {
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "Server configuration error")
+		return
+	}
+
+	var req models.UserTokenRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if req.Email == "" {
+		WriteError(w, http.StatusBadRequest, MissingFieldErrorCode, "Email is required")
+		return
+	}
+
+	user, err := h.Users.Get(r.Context(), req.Email)
+	if errors.Is(err, repository.ErrUserNotFound) || (err == nil && user.Deleted) {
+		WriteError(w, http.StatusNotFound, NotFoundErrorCode, "User not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !user.Verified {
+		WriteError(w, http.StatusConflict, ConflictErrorCode, "Email not verified; open the verification link first")
+		return
+	}
+	if !h.Config.AutoVerify && h.Mailer == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "Mail delivery unavailable")
+		return
+	}
+
+	jwt, err := utils.IssueToken(h.JWTConfig(), user.Email, utils.WithTenant(user.Tenant))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if h.Config.AutoVerify {
+		writeJSON(w, r, http.StatusOK, map[string]string{"message": "Token issued", "token": jwt})
+		return
+	}
+	body := fmt.Sprintf("Your new Micro API token, valid for %s:\n\n%s\n", utils.AccessTokenTTL, jwt)
+	if err := h.Mailer.Send(user.Email, "Your Micro API token", body); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to send token mail", "email", user.Email, "error", err)
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "Failed to send the token mail")
+		return
+	}
+	writeJSON(w, r, http.StatusAccepted, map[string]string{"message": "A new API token was sent to your email"})
+}
+
+// writePendingDeletion refuses registering the email of a deleted user
+// that is not purged yet, telling when the email becomes free
+func (h *Handlers) writePendingDeletion(w http.ResponseWriter, r *http.Request, email string) {
+	const msg = "This email belongs to a deleted account; it can register again once the account is purged"
+	deleted, err := h.Users.Get(r.Context(), email)
+	if err != nil || !deleted.Deleted {
+		WriteError(w, http.StatusConflict, PendingDeletionErrorCode, msg)
+		return
+	}
+	writeJSON(w, r, http.StatusConflict, map[string]interface{}{
+		"message":    msg,
+		"code":       PendingDeletionErrorCode,
+		"purgeAfter": deleted.DeletedAt.Add(h.userDeletionGrace()),
+	})
+}
+
+// validEmailSyntax reports whether address passes the syntax check of
+// email validation
+func validEmailSyntax(ctx context.Context, address string) bool {
+	result := validation.ValidateEmailChecks(ctx, address, validation.EmailChecks{Syntax: true}, nil)
+	return result.IsSyntaxValid != nil && *result.IsSyntaxValid
+}
+
+// tokenTenant returns the tenant claim of t's access tokens: none for the
+// default tenant
+func tokenTenant(t models.Tenant) string {
+	if tenant.IsDefault(t) {
+		return ""
+	}
+	return t.ID
+}
+
+// verificationURL returns the link verifying token on the configured
+// BASE_URL, never on the host the request claims
+func (h *Handlers) verificationURL(token string) string {
+	return h.site().AbsURL("/api/v1/user/verify") + "?" + url.Values{"token": {token}}.Encode()
+}
+
+// CheckAccessToken is the middleware.TokenCheck of access tokens: it
+// rejects tokens of users that were deleted or no longer exist, and tokens
+// issued before the user's tokens were invalidated. Without a user store
+// there is nothing to check against and every token passes.
+func (h *Handlers) CheckAccessToken(ctx context.Context, email string, issuedAt time.Time) error {
+	if h.Users == nil {
+		return nil
+	}
+	user, err := h.Users.Get(ctx, email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return utils.ErrTokenRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if user.Deleted || (!user.TokensInvalidBefore.IsZero() && issuedAt.Unix() <= user.TokensInvalidBefore.Unix()) {
+		return utils.ErrTokenRevoked
+	}
+	return nil
+}
+
+// ExportUserHandler returns the data held about the token's user
+func (h *Handlers) ExportUserHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Users == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "User storage unavailable")
+		return
+	}
+	user, err := h.Users.Get(r.Context(), middleware.TokenEmail(r.Context()))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		WriteError(w, http.StatusNotFound, NotFoundErrorCode, "User not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	export := models.UserExport{User: user, WifiNetworks: []models.WifiRotation{}, ExportedAt: h.Clock.Now().UTC()}
+	if h.WifiRotation != nil {
+		networks, err := h.WifiRotation.Networks(r.Context(), user.Email)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, network := range networks {
+			export.WifiNetworks = append(export.WifiNetworks, h.wifiRotation(network))
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Disposition", `attachment; filename="microapi-user-export.json"`)
+	writeJSON(w, r, http.StatusOK, export)
+}
+
+// DeleteUserHandler soft-deletes the token's user. Their access tokens
+// stop working at once; the document is removed by the retention sweeper
+// once the deletion grace period has passed.
+func (h *Handlers) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Users == nil {
+		WriteError(w, http.StatusServiceUnavailable, UnavailableErrorCode, "User storage unavailable")
+		return
+	}
+	email := middleware.TokenEmail(r.Context())
+	deletedAt := h.Clock.Now().UTC()
+	err := h.Users.SoftDelete(r.Context(), email, deletedAt)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		WriteError(w, http.StatusNotFound, NotFoundErrorCode, "User not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	logging.FromContext(r.Context()).Info("User deleted", "email", email)
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"message":    "User deleted; access tokens are revoked",
+		"deletedAt":  deletedAt,
+		"purgeAfter": deletedAt.Add(h.userDeletionGrace()),
+	})
+}
+
+// userDeletionGrace is how long deleted users are kept before purging
+func (h *Handlers) userDeletionGrace() time.Duration {
+	if h.Config == nil {
+		return config.DefaultUserDeletionGrace
+	}
+	return h.Config.UserDeletionGrace
 }
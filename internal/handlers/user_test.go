@@ -0,0 +1,329 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+	"github.com/innovelabs/microtools-go/internal/utils"
+)
+
+func postUser(handler http.HandlerFunc, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body models.APIError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body is not JSON: %s", rec.Body)
+	}
+	return body.Code
+}
+
+func TestRegisterUser(t *testing.T) {
+	h := testutil.NewHandlers()
+	users := h.Users.(*testutil.UserRepository)
+
+	for _, email := range []string{"not-an-email", "two@@example.com", "spaces in@example.com"} {
+		rec := postUser(h.RegisterUserHandler, "/api/v1/user/register", `{"email":"`+email+`"}`)
+		if rec.Code != http.StatusBadRequest || errorCode(t, rec) != handlers.InvalidDataErrorCode {
+			t.Errorf("%q: status %d %s, want a 400 invalid_data", email, rec.Code, rec.Body)
+		}
+	}
+	if len(users.Users) != 0 {
+		t.Errorf("invalid addresses were stored: %v", users.Users)
+	}
+
+	rec := postUser(h.RegisterUserHandler, "/api/v1/user/register", `{"email":"ada@example.com"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if created := users.Users["ada@example.com"].CreatedAt; !created.Equal(h.Clock.Now()) {
+		t.Errorf("createdAt = %v, want %v", created, h.Clock.Now())
+	}
+
+	// A store that cannot be reached is a failure, not a new user
+	users.Err = errors.New("server selection timeout")
+	rec = postUser(h.RegisterUserHandler, "/api/v1/user/register", `{"email":"bob@example.com"}`)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("store error: status %d, want 500", rec.Code)
+	}
+}
+
+// verificationLink registers email and returns the link of its
+// verification mail
+func verificationLink(t *testing.T, h *handlers.Handlers, email string) string {
+	t.Helper()
+	rec := postUser(h.RegisterUserHandler, "/api/v1/user/register", `{"email":"`+email+`"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: status %d: %s", rec.Code, rec.Body)
+	}
+	sent := h.Mailer.(*testutil.MailSender).Sent
+	if len(sent) == 0 || sent[len(sent)-1].To != email {
+		t.Fatalf("no verification mail to %s: %+v", email, sent)
+	}
+	lines := strings.Split(strings.TrimSpace(sent[len(sent)-1].Body), "\n")
+	return lines[len(lines)-1]
+}
+
+func TestRegisterUserVerificationMail(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Config.BaseURL = "https://api.example.com"
+	h.Config.BasePath = "/tools"
+	users := h.Users.(*testutil.UserRepository)
+	mailer := h.Mailer.(*testutil.MailSender)
+
+	// The link is on BASE_URL whatever host and scheme the request claims
+	req := httptest.NewRequest(http.MethodPost, "http://evil.example/api/v1/user/register", strings.NewReader(`{"email":"ada@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-Proto", "http")
+	req.Host = "evil.example"
+	rec := httptest.NewRecorder()
+	h.RegisterUserHandler(rec, req)
+	if rec.Code != http.StatusCreated || strings.Contains(rec.Body.String(), "token=") {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if len(mailer.Sent) != 1 || !strings.Contains(mailer.Sent[0].Body, "https://api.example.com/tools/api/v1/user/verify?token=") {
+		t.Fatalf("sent = %+v", mailer.Sent)
+	}
+	if strings.Contains(mailer.Sent[0].Body, "evil.example") {
+		t.Errorf("link uses the request host: %s", mailer.Sent[0].Body)
+	}
+
+	// A link that cannot be mailed is neither returned nor kept
+	mailer.Err = errors.New("relay refused")
+	rec = postUser(h.RegisterUserHandler, "/api/v1/user/register", `{"email":"bob@example.com"}`)
+	if rec.Code != http.StatusServiceUnavailable || errorCode(t, rec) != handlers.UnavailableErrorCode || strings.Contains(rec.Body.String(), "token=") {
+		t.Fatalf("mail fails: status %d: %s", rec.Code, rec.Body)
+	}
+	if _, ok := users.Users["bob@example.com"]; ok {
+		t.Error("user kept after the verification mail failed")
+	}
+	mailer.Err = nil
+	verificationLink(t, h, "bob@example.com")
+
+	h.Mailer = nil
+	rec = postUser(h.RegisterUserHandler, "/api/v1/user/register", `{"email":"carol@example.com"}`)
+	if rec.Code != http.StatusServiceUnavailable || errorCode(t, rec) != handlers.UnavailableErrorCode || strings.Contains(rec.Body.String(), "token=") {
+		t.Fatalf("no mailer: status %d: %s", rec.Code, rec.Body)
+	}
+	if _, ok := users.Users["carol@example.com"]; ok {
+		t.Error("user stored without a mailer")
+	}
+
+	// Auto-verified users need no mail
+	h.Config.AutoVerify = true
+	rec = postUser(h.RegisterUserHandler, "/api/v1/user/register", `{"email":"carol@example.com"}`)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("auto verify without a mailer: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRegisterAfterVerificationExpired(t *testing.T) {
+	h := testutil.NewHandlers()
+	clk := h.Clock.(*testutil.Clock)
+	verify := func(link string) int {
+		rec := httptest.NewRecorder()
+		h.VerifyUserHandler(rec, httptest.NewRequest(http.MethodGet, link, nil))
+		return rec.Code
+	}
+
+	first := verificationLink(t, h, "ada@example.com")
+	rec := postUser(h.RegisterUserHandler, "/api/v1/user/register", `{"email":"ada@example.com"}`)
+	if rec.Code != http.StatusConflict || errorCode(t, rec) != handlers.ConflictErrorCode {
+		t.Fatalf("while the link is valid: status %d %s, want a 409 conflict", rec.Code, rec.Body)
+	}
+
+	// Once the link expired, registering again mails a new one that
+	// replaces it
+	clk.Advance(utils.VerificationTokenTTL + time.Minute)
+	second := verificationLink(t, h, "ada@example.com")
+	if second == first {
+		t.Fatal("the same link was mailed again")
+	}
+	if code := verify(first); code != http.StatusConflict {
+		t.Errorf("replaced link: status %d, want 409", code)
+	}
+	if code := verify(second); code != http.StatusOK {
+		t.Errorf("new link: status %d, want 200", code)
+	}
+
+	// A verified user is never replaced
+	clk.Advance(utils.VerificationTokenTTL + time.Minute)
+	if rec := postUser(h.RegisterUserHandler, "/api/v1/user/register", `{"email":"ada@example.com"}`); rec.Code != http.StatusConflict {
+		t.Errorf("verified user: status %d %s, want 409", rec.Code, rec.Body)
+	}
+	if !h.Users.(*testutil.UserRepository).Users["ada@example.com"].Verified {
+		t.Error("the verified user was replaced")
+	}
+}
+
+func TestVerifyUser(t *testing.T) {
+	h := testutil.NewHandlers()
+	verify := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.VerifyUserHandler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	link := verificationLink(t, h, "ada@example.com")
+	rec := verify(link)
+	var body map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if claims, err := utils.ValidateJWT(h.JWTConfig(), body["token"]); err != nil || claims.Email != "ada@example.com" {
+		t.Errorf("issued token: %+v, %v", claims, err)
+	}
+
+	rec = verify(link)
+	if rec.Code != http.StatusConflict || errorCode(t, rec) != handlers.ConflictErrorCode {
+		t.Errorf("reused link: status %d %s, want a 409 conflict", rec.Code, rec.Body)
+	}
+
+	verificationLink(t, h, "bob@example.com")
+	nonce := h.Users.(*testutil.UserRepository).Users["bob@example.com"].VerificationNonce
+	expired, err := utils.IssueToken(h.JWTConfig(), "bob@example.com",
+		utils.WithPurpose(utils.TokenPurposeEmailVerification), utils.WithNonce(nonce), utils.WithTTL(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	access, err := utils.IssueToken(h.JWTConfig(), "bob@example.com", utils.WithNonce(nonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name   string
+		token  string
+		status int
+		code   string
+	}{
+		{"expired", expired, http.StatusGone, middleware.TokenExpiredErrorCode},
+		{"wrong purpose", access, http.StatusBadRequest, middleware.TokenInvalidErrorCode},
+		{"malformed", "not-a-token", http.StatusBadRequest, middleware.TokenInvalidErrorCode},
+	} {
+		rec := verify("/api/v1/user/verify?token=" + tt.token)
+		if rec.Code != tt.status || errorCode(t, rec) != tt.code {
+			t.Errorf("%s: status %d %s, want %d %s", tt.name, rec.Code, rec.Body, tt.status, tt.code)
+		}
+	}
+	if h.Users.(*testutil.UserRepository).Users["bob@example.com"].Verified {
+		t.Error("a refused token verified the user")
+	}
+}
+
+func TestRegisterDeletedUser(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Config.AutoVerify = true
+	users := h.Users.(*testutil.UserRepository)
+	deletedAt := h.Clock.Now().Add(-time.Hour)
+	users.Users["ada@example.com"] = models.User{Email: "ada@example.com", Verified: true, Deleted: true, DeletedAt: deletedAt}
+
+	rec := postUser(h.RegisterUserHandler, "/api/v1/user/register", `{"email":"ada@example.com"}`)
+	if rec.Code != http.StatusConflict || errorCode(t, rec) != handlers.PendingDeletionErrorCode {
+		t.Fatalf("status %d %s, want a 409 pending_deletion", rec.Code, rec.Body)
+	}
+	var body struct {
+		PurgeAfter time.Time `json:"purgeAfter"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if want := deletedAt.Add(h.Config.UserDeletionGrace); !body.PurgeAfter.Equal(want) {
+		t.Errorf("purgeAfter = %v, want %v", body.PurgeAfter, want)
+	}
+	if !users.Users["ada@example.com"].Deleted {
+		t.Error("the deleted user was overwritten")
+	}
+
+	// Free again once purged
+	if n, err := users.PurgeDeleted(context.Background(), h.Clock.Now()); err != nil || n != 1 {
+		t.Fatalf("purge = %d, %v", n, err)
+	}
+	if rec := postUser(h.RegisterUserHandler, "/api/v1/user/register", `{"email":"ada@example.com"}`); rec.Code != http.StatusCreated {
+		t.Errorf("after the purge: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestUserToken(t *testing.T) {
+	newHandlers := func(autoVerify bool) (*handlers.Handlers, *testutil.MailSender) {
+		h := testutil.NewHandlers()
+		h.Config.AutoVerify = autoVerify
+		users := h.Users.(*testutil.UserRepository)
+		users.Users["ada@example.com"] = models.User{Email: "ada@example.com", Verified: true, Tenant: "acme"}
+		users.Users["new@example.com"] = models.User{Email: "new@example.com"}
+		users.Users["gone@example.com"] = models.User{Email: "gone@example.com", Verified: true, Deleted: true}
+		return h, h.Mailer.(*testutil.MailSender)
+	}
+
+	// Mailed to the address, not returned
+	h, mailer := newHandlers(false)
+	rec := postUser(h.UserTokenHandler, "/api/v1/user/token", `{"email":"ada@example.com"}`)
+	if rec.Code != http.StatusAccepted || strings.Contains(rec.Body.String(), "token\"") {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if len(mailer.Sent) != 1 || mailer.Sent[0].To != "ada@example.com" {
+		t.Fatalf("sent = %+v", mailer.Sent)
+	}
+	lines := strings.Split(strings.TrimSpace(mailer.Sent[0].Body), "\n")
+	claims, err := utils.ValidateJWT(h.JWTConfig(), lines[len(lines)-1])
+	if err != nil || claims.Email != "ada@example.com" || claims.Tenant != "acme" {
+		t.Errorf("mailed token: %+v, %v", claims, err)
+	}
+
+	// Returned when registration needs no proof of the address either
+	h, mailer = newHandlers(true)
+	rec = postUser(h.UserTokenHandler, "/api/v1/user/token", `{"email":"ada@example.com"}`)
+	var body map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK || len(mailer.Sent) != 0 {
+		t.Fatalf("auto verify: status %d: %s", rec.Code, rec.Body)
+	}
+	if claims, err := utils.ValidateJWT(h.JWTConfig(), body["token"]); err != nil || claims.Email != "ada@example.com" {
+		t.Errorf("returned token: %+v, %v", claims, err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		setup  func(h *handlers.Handlers)
+	}{
+		{"missing email", `{}`, http.StatusBadRequest, handlers.MissingFieldErrorCode, nil},
+		{"unknown user", `{"email":"nobody@example.com"}`, http.StatusNotFound, handlers.NotFoundErrorCode, nil},
+		{"deleted user", `{"email":"gone@example.com"}`, http.StatusNotFound, handlers.NotFoundErrorCode, nil},
+		{"unverified user", `{"email":"new@example.com"}`, http.StatusConflict, handlers.ConflictErrorCode, nil},
+		{"store error", `{"email":"ada@example.com"}`, http.StatusInternalServerError, handlers.InternalErrorCode, func(h *handlers.Handlers) {
+			h.Users.(*testutil.UserRepository).Err = errors.New("server selection timeout")
+		}},
+		{"no mailer", `{"email":"ada@example.com"}`, http.StatusServiceUnavailable, handlers.UnavailableErrorCode, func(h *handlers.Handlers) {
+			h.Mailer = nil
+		}},
+		{"mail fails", `{"email":"ada@example.com"}`, http.StatusServiceUnavailable, handlers.UnavailableErrorCode, func(h *handlers.Handlers) {
+			h.Mailer.(*testutil.MailSender).Err = errors.New("relay refused")
+		}},
+	} {
+		h, _ := newHandlers(false)
+		if tt.setup != nil {
+			tt.setup(h)
+		}
+		rec := postUser(h.UserTokenHandler, "/api/v1/user/token", tt.body)
+		if rec.Code != tt.status || errorCode(t, rec) != tt.code {
+			t.Errorf("%s: status %d %s, want %d %s", tt.name, rec.Code, rec.Body, tt.status, tt.code)
+		}
+	}
+}
@@ -1,83 +1,281 @@
 package handlers
 
 import (
-	"encoding/json"
-	"log"
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"strings"
 
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/middleware"
 	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/policy"
+	"github.com/innovelabs/microtools-go/internal/resultmeta"
 	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/warnings"
 )
 
 // ValidateEmailHandler handles email validation requests
-func ValidateEmailHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ValidateEmailHandler(w http.ResponseWriter, r *http.Request) {
 	var email models.EmailRequest
 
-	err := json.NewDecoder(r.Body).Decode(&email)
+	err := decodeSingleValueRequest(r, &email, &email.Email)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, decodeErrorStatus(err), err)
 		return
 	}
-	log.Println("Validating email: ", email.Email)
+	checks, err := validation.ParseEmailChecks(email.Checks)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if email.SignResponse && !h.checkSignRequest(w, r) {
+		return
+	}
+	if email.Share && !h.checkShareRequest(w, r) {
+		return
+	}
+	checkDomain, ok := h.networkEmailChecker(w, r, email.Resolver)
+	if !ok {
+		return
+	}
+	logging.FromContext(r.Context()).Info("Validating email", "email", email.Email)
+	r = collectWarnings(r)
 	formattedEmail := strings.TrimSpace(email.Email)
-	emailValidationResult := validation.ValidateEmail(formattedEmail)
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{"validationResult": emailValidationResult})
+	emailValidationResult := validation.ValidateEmailChecks(r.Context(), formattedEmail, checks, checkDomain)
+	if email.CheckSMTP {
+		h.checkMailbox(r.Context(), &emailValidationResult)
+	}
+	emailValidationResult.Warnings = warnings.FromContext(r.Context()).List()
+	h.writeValidationResult(w, r, http.StatusCreated, emailValidationResult, email.SignResponse, shareAs(email.Share, "email-validate"))
 }
 
-// ValidateIPHandler handles IP validation/geolocation requests
-func ValidateIPHandler(w http.ResponseWriter, r *http.Request) {
-	var ip models.IPRequest
+// checkMailbox adds the SMTP mailbox check to result, or reports it
+// skipped when the route group does not allow network checks or no
+// verifier is configured
+func (h *Handlers) checkMailbox(ctx context.Context, result *models.EmailValidation) {
+	reason := ""
+	switch {
+	case !policy.FromContext(ctx).AllowNetworkChecks:
+		reason = "network checks are not allowed on this route"
+	case h.Mailboxes == nil:
+		reason = "SMTP verification is not configured"
+	}
+	if reason != "" {
+		resultmeta.FromContext(ctx).SkipCheck(validation.SMTPCheckName, reason)
+		result.SkippedChecks = append(result.SkippedChecks, validation.SMTPCheckName)
+		result.SMTP = &models.SMTPCheck{SMTPMessage: reason}
+		return
+	}
+	check := h.Mailboxes.Verify(ctx, result.Email)
+	result.SMTP = &check
+}
 
-	err := json.NewDecoder(r.Body).Decode(&ip)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":   true,
-			"message": err.Error(),
-		})
+// ValidateIPHandler handles IP validation/geolocation requests. GET reads
+// the IP from the ?ip= query parameter. An empty IP or "self" looks up the
+// caller's own address, see callerIP.
+func (h *Handlers) ValidateIPHandler(w http.ResponseWriter, r *http.Request) {
+	var ip models.IPRequest
+	source := validation.IPSourceBody
+	if r.Method == http.MethodGet {
+		ip.IP = r.URL.Query().Get("ip")
+		source = validation.IPSourceQuery
+	} else if err := decodeSingleValueRequest(r, &ip, &ip.IP); err != nil && !errors.Is(err, io.EOF) && err != errEmptyPlainBody {
+		// An empty body is a lookup of the caller
+		writeError(w, decodeErrorStatus(err), err)
 		return
 	}
-	log.Println("Validating IP: ", ip.IP)
+	if trimmed := strings.TrimSpace(ip.IP); trimmed == "" || strings.EqualFold(trimmed, "self") {
+		ip.IP = h.callerIP(r)
+		source = validation.IPSourceInferred
+	}
+	if ip.Share && !h.checkShareRequest(w, r) {
+		return
+	}
+	logging.FromContext(r.Context()).Info("Validating IP", "ip", ip.IP, "source", source)
+	r = collectWarnings(r)
 	formattedIP := strings.TrimSpace(ip.IP)
-	ipValidationResult, err := validation.ValidateIP(formattedIP)
+	ipValidationResult, err := h.GeoIP.Lookup(r.Context(), formattedIP)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":   true,
-			"message": err.Error(),
-		})
+		status := http.StatusBadRequest
+		if errors.Is(err, validation.ErrGeoDBUnavailable) {
+			status = http.StatusServiceUnavailable
+		}
+		writeError(w, status, err)
 		return
 	}
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{"validationResult": ipValidationResult})
+	ipValidationResult.Source = source
+	ipValidationResult.Warnings = warnings.FromContext(r.Context()).List()
+	body := map[string]interface{}{"validationResult": ipValidationResult}
+	addSummary(body, ipValidationResult)
+	if ip.Share {
+		shareURL, err := h.shareResult(r.Context(), "ip-validate", ipValidationResult)
+		if err != nil {
+			writeShareError(w, r, err)
+			return
+		}
+		body["share_url"] = shareURL
+	}
+	h.addResultMeta(r, body)
+	status := http.StatusCreated
+	if r.Method == http.MethodGet {
+		status = http.StatusOK
+	}
+	writeJSON(w, r, status, body)
+}
+
+// callerIP returns the address of r's client without its port. Forwarding
+// headers are only read with TRUSTED_PROXY set, as clients can send them.
+func (h *Handlers) callerIP(r *http.Request) string {
+	return middleware.TrustedClientIP(r, h.Config != nil && h.Config.TrustedProxy)
 }
 
 // ValidateIBANHandler handles IBAN validation requests
-func ValidateIBANHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ValidateIBANHandler(w http.ResponseWriter, r *http.Request) {
 	var ibanReq models.IBANRequest
 
-	err := json.NewDecoder(r.Body).Decode(&ibanReq)
+	err := decodeSingleValueRequest(r, &ibanReq, &ibanReq.IBAN)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":   true,
-			"message": err.Error(),
-		})
+		writeError(w, decodeErrorStatus(err), err)
 		return
 	}
 
-	log.Println("Validating IBAN:", ibanReq.IBAN)
+	if ibanReq.SignResponse && !h.checkSignRequest(w, r) {
+		return
+	}
+	if ibanReq.Share && !h.checkShareRequest(w, r) {
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Validating IBAN", "iban", ibanReq.IBAN)
+	r = collectWarnings(r)
 	formattedIBAN := strings.TrimSpace(ibanReq.IBAN)
-	ibanValidationResult := validation.ValidateIBAN(formattedIBAN)
+	ibanValidationResult, err := validation.ValidateIBAN(r.Context(), formattedIBAN)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to validate IBAN", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to validate IBAN")
+		return
+	}
+	ibanValidationResult.Warnings = warnings.FromContext(r.Context()).List()
+	resultmeta.FromContext(r.Context()).UseRegisteredDataset(validation.IBANCountriesDataset)
+	h.writeValidationResult(w, r, http.StatusOK, ibanValidationResult, ibanReq.SignResponse, shareAs(ibanReq.Share, "iban-validate"))
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"validationResult": ibanValidationResult,
-	})
+// ValidateBankAccountHandler validates a domestic bank account of a
+// country that does not use IBANs
+func (h *Handlers) ValidateBankAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.BankAccountRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Country) == "" {
+		WriteError(w, http.StatusBadRequest, MissingFieldErrorCode, "country is required, one of "+strings.Join(validation.BankAccountCountries(), ", "))
+		return
+	}
+	if req.Share && !h.checkShareRequest(w, r) {
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Validating bank account", "country", req.Country, "account_number", req.AccountNumber)
+	r = collectWarnings(r)
+	result := validation.ValidateBankAccount(req)
+	result.Warnings = warnings.FromContext(r.Context()).List()
+	h.writeValidationResult(w, r, http.StatusOK, result, false, shareAs(req.Share, "bankaccount-validate"))
+}
+
+// ValidateCardHandler checks a payment card number's Luhn check digit and
+// detects its scheme. The number is only logged redacted.
+func (h *Handlers) ValidateCardHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.CardRequest
+	if err := decodeSingleValueRequest(r, &req, &req.Number); err != nil {
+		writeError(w, decodeErrorStatus(err), err)
+		return
+	}
+	if strings.TrimSpace(req.Number) == "" {
+		WriteError(w, http.StatusBadRequest, MissingFieldErrorCode, "number is required")
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Validating card number", "number", req.Number)
+	result, err := validation.ValidateCardNumber(req.Number)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.writeValidationResult(w, r, http.StatusOK, result, false, "")
+}
+
+// IBANFormatRulesHandler returns the formatting rules of one country
+func IBANFormatRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := validation.IBANFormatRules(mux.Vars(r)["countryCode"])
+	if errors.Is(err, validation.ErrUnsupportedCountry) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to build IBAN format rules", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to build IBAN format rules")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"formatRules": rules})
+}
+
+// FormatIBANHandler groups a partial IBAN and checks it against the country spec
+func FormatIBANHandler(w http.ResponseWriter, r *http.Request) {
+	var ibanReq models.IBANRequest
+	if err := decodeSingleValueRequest(r, &ibanReq, &ibanReq.IBAN); err != nil {
+		writeError(w, decodeErrorStatus(err), err)
+		return
+	}
+
+	formatResult, err := validation.FormatPartialIBAN(strings.TrimSpace(ibanReq.IBAN))
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to format IBAN", "error", err)
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to format IBAN")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"formatResult": formatResult})
+}
+
+// ValidateJSONSchemaHandler handles JSON Schema validation requests
+func ValidateJSONSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.JSONSchemaRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+
+	result, err := validation.ValidateJSONSchema(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"validationResult": result})
+}
+
+// ValidateBarcodeHandler classifies a scanned barcode value and checks
+// each plausible symbology
+func (h *Handlers) ValidateBarcodeHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.BarcodeValidateRequest
+	if err := decodeSingleValueRequest(r, &req, &req.Value); err != nil {
+		writeError(w, decodeErrorStatus(err), err)
+		return
+	}
+	if strings.TrimSpace(req.Value) == "" {
+		WriteError(w, http.StatusBadRequest, MissingFieldErrorCode, "value is required")
+		return
+	}
+
+	r = collectWarnings(r)
+	result, err := validation.ValidateScannedBarcode(req, h.Clock.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	result.Warnings = warnings.FromContext(r.Context()).List()
+	h.writeValidationResult(w, r, http.StatusOK, result, false, "")
 }
@@ -0,0 +1,399 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+func TestValidateEmailCheckErrors(t *testing.T) {
+	h := testutil.NewHandlers()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/email", strings.NewReader(`{"email":"ada@example.com","checks":["pop3"]}`))
+	rec := httptest.NewRecorder()
+	h.ValidateEmailHandler(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "pop3") {
+		t.Fatalf("status = %d, want 400 naming the check: %s", rec.Code, rec.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/validate/email/batch?checks=pop3", strings.NewReader("ada@example.com\n"))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rec = httptest.NewRecorder()
+	h.ValidateEmailBatchHandler(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "pop3") {
+		t.Fatalf("batch status = %d, want 400 naming the check: %s", rec.Code, rec.Body)
+	}
+}
+
+// TestValidateEmailDNSTimeout answers within EMAIL_DNS_TIMEOUT when DNS
+// never does
+func TestValidateEmailDNSTimeout(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Config.EmailDNSTimeout = 20 * time.Millisecond
+	h.EmailDomains = func(ctx context.Context, email string) (bool, bool, error) {
+		<-ctx.Done()
+		return false, false, fmt.Errorf("%w: %w", validation.ErrDomainLookupFailed, ctx.Err())
+	}
+	start := time.Now()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/email", strings.NewReader(`{"email":"ada@mailinator.com"}`))
+	rec := httptest.NewRecorder()
+	h.ValidateEmailHandler(rec, req)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("answered after %v", elapsed)
+	}
+	var body struct {
+		ValidationResult models.EmailValidation `json:"validationResult"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	result := body.ValidationResult
+	if !result.DNSTimedOut || result.IsDomainValid == nil || *result.IsDomainValid || result.MxRecordsFound == nil || *result.MxRecordsFound {
+		t.Errorf("result = %s, want domain and MX false with dnsTimedOut", rec.Body)
+	}
+	if result.IsSyntaxValid == nil || !*result.IsSyntaxValid || result.IsDisposable == nil || !*result.IsDisposable {
+		t.Errorf("result = %s, want the syntax and disposable results", rec.Body)
+	}
+}
+
+func TestValidateEmailSMTPCheck(t *testing.T) {
+	validate := func(h *handlers.Handlers, body string) models.EmailValidation {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/email", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ValidateEmailHandler(rec, req)
+		var resp struct {
+			ValidationResult models.EmailValidation `json:"validationResult"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		return resp.ValidationResult
+	}
+
+	h := testutil.NewHandlers()
+	if result := validate(h, `{"email":"ada@example.com"}`); result.SMTP != nil {
+		t.Errorf("smtp = %+v without check_smtp", result.SMTP)
+	}
+	result := validate(h, `{"email":"ada@example.com","check_smtp":true}`)
+	if result.SMTP == nil || result.SMTP.Attempted || !slices.Contains(result.SkippedChecks, validation.SMTPCheckName) {
+		t.Errorf("unconfigured: smtp = %+v, skipped %q", result.SMTP, result.SkippedChecks)
+	}
+
+	resolver := testutil.NewResolver()
+	resolver.MX["example.com"] = []*net.MX{{Host: "mx.example.com.", Pref: 10}}
+	server := testutil.NewSMTPServer()
+	server.Recipients["ada@example.com"] = "250 2.1.5 Ok"
+	h.Mailboxes = validation.NewSMTPVerifier(resolver, server, validation.SMTPOptions{HeloName: "api.test", Deadline: time.Second})
+	result = validate(h, `{"email":" ada@example.com ","check_smtp":true}`)
+	if result.SMTP == nil || !result.SMTP.Attempted || result.SMTP.MailboxExists == nil || !*result.SMTP.MailboxExists ||
+		result.SMTP.CatchAll == nil || *result.SMTP.CatchAll {
+		t.Errorf("smtp = %+v, want an existing mailbox on a server that is no catch-all", result.SMTP)
+	}
+}
+
+func TestValidateEmailDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name, contentType, body string
+		status                  int
+	}{
+		{"invalid JSON", "application/json", `{"email":`, http.StatusBadRequest},
+		{"empty plain body", "text/plain", "  ", http.StatusBadRequest},
+		{"several plain lines", "text/plain", "a@example.com\nb@example.com", http.StatusBadRequest},
+		{"plain body too large", "text/plain", strings.Repeat("a", 64<<10), http.StatusRequestEntityTooLarge},
+		{"unsupported type", "application/xml", "<email/>", http.StatusUnsupportedMediaType},
+	}
+	h := testutil.NewHandlers()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/email", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			h.ValidateEmailHandler(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
+
+// TestValidateDecodesBodies sends each single-value endpoint its value as
+// JSON and as text/plain and checks the value reaches the validator
+func TestValidateDecodesBodies(t *testing.T) {
+	h := testutil.NewHandlers()
+	endpoints := []struct {
+		name, field, value string
+		handler            http.HandlerFunc
+		status             int
+	}{
+		{"email", "email", "ada@example.com", h.ValidateEmailHandler, http.StatusCreated},
+		{"ip", "ip", "192.0.2.9", h.ValidateIPHandler, http.StatusCreated},
+		{"iban", "iban", "DE89 3704 0044 0532 0130 00", h.ValidateIBANHandler, http.StatusOK},
+	}
+	bodies := []struct {
+		name, contentType string
+		body              func(field, value string) string
+	}{
+		{"json", "application/json", func(field, value string) string { return `{"` + field + `":"` + value + `"}` }},
+		{"json with trailing newline", "application/json", func(field, value string) string { return `{"` + field + `":"` + value + `"}` + "\n" }},
+		{"json with charset", "application/json; charset=utf-8", func(field, value string) string { return `{"` + field + `":"` + value + `"}` }},
+		{"json without content type", "", func(field, value string) string { return `{"` + field + `":"` + value + `"}` }},
+		{"plain", "text/plain", func(_, value string) string { return value }},
+		{"plain with trailing newline", "text/plain; charset=utf-8", func(_, value string) string { return value + "\n" }},
+		{"plain with CRLF and spaces", "text/plain", func(_, value string) string { return "  " + value + "\r\n" }},
+	}
+	for _, endpoint := range endpoints {
+		for _, body := range bodies {
+			t.Run(endpoint.name+"/"+body.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/"+endpoint.name, strings.NewReader(body.body(endpoint.field, endpoint.value)))
+				if body.contentType != "" {
+					req.Header.Set("Content-Type", body.contentType)
+				}
+				rec := httptest.NewRecorder()
+				endpoint.handler(rec, req)
+				if rec.Code != endpoint.status {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, endpoint.status, rec.Body)
+				}
+				var result struct {
+					ValidationResult map[string]json.RawMessage `json:"validationResult"`
+				}
+				if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+					t.Fatal(err)
+				}
+				var got string
+				if err := json.Unmarshal(result.ValidationResult[endpoint.field], &got); err != nil || got != endpoint.value {
+					t.Errorf("%s = %q, %v; want %q", endpoint.field, got, err, endpoint.value)
+				}
+			})
+		}
+	}
+}
+
+func TestValidateIBANWarnings(t *testing.T) {
+	h := testutil.NewHandlers()
+	validate := func(iban string) map[string]json.RawMessage {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/iban", strings.NewReader(`{"iban":"`+iban+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ValidateIBANHandler(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", iban, rec.Code, rec.Body)
+		}
+		var body struct {
+			ValidationResult map[string]json.RawMessage `json:"validationResult"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body.ValidationResult
+	}
+
+	body := validate("DE89-3704-0044-0532-0130-00")
+	var list []models.Warning
+	if err := json.Unmarshal(body["warnings"], &list); err != nil {
+		t.Fatalf("warnings %s: %v", body["warnings"], err)
+	}
+	if len(list) != 1 || list[0].Code != "iban_separators" || list[0].Field != "iban" {
+		t.Errorf("warnings = %+v, want iban_separators", list)
+	}
+	if string(body["isValid"]) != "true" {
+		t.Errorf("isValid = %s, want the IBAN validated after removing the separators", body["isValid"])
+	}
+
+	// An empty list is left out rather than sent as [] or null
+	if _, ok := validate("DE89 3704 0044 0532 0130 00")["warnings"]; ok {
+		t.Error("warnings present without any warning")
+	}
+}
+
+func TestValidateBankAccountHandler(t *testing.T) {
+	h := testutil.NewHandlers()
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/bankaccount", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ValidateBankAccountHandler(rec, req)
+		return rec
+	}
+
+	rec := post(`{"country":"UK","bank_code":"08-99-99","account_number":"66374958"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		ValidationResult models.BankAccountValidation `json:"validationResult"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if result := body.ValidationResult; !result.IsValid || result.Country != "GB" || result.FormattedBankCode != "08-99-99" {
+		t.Errorf("result = %+v, want a valid GB account", result)
+	}
+
+	rec = post(`{"bank_code":"011000015","account_number":"12345678"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("without a country: status = %d, want 400", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "CA, GB, US") {
+		t.Errorf("error = %s, want the supported countries listed", rec.Body)
+	}
+}
+
+func TestValidateIBANSummary(t *testing.T) {
+	h := testutil.NewHandlers()
+	for _, tt := range []struct {
+		iban, summary string
+	}{
+		{"DE89 3704 0044 0532 0130 00", "IBAN ending in 3000 is valid, from Germany."},
+		{"DE88 3704 0044 0532 0130 00", "IBAN ending in 3000 is invalid: its check digits do not match."},
+		{"DE89 3704 0044 0532 0130", "IBAN ending in 0130 is invalid: it is not formatted as an IBAN."},
+		{"DE", "IBAN is invalid: it is not formatted as an IBAN."},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/iban", strings.NewReader(`{"iban":"`+tt.iban+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ValidateIBANHandler(rec, req)
+		var body struct {
+			Summary string `json:"summary"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusOK || body.Summary != tt.summary {
+			t.Errorf("%s: status %d, summary %q; want %q", tt.iban, rec.Code, body.Summary, tt.summary)
+		}
+	}
+}
+
+func TestValidateCardHandler(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	h := testutil.NewHandlers()
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/card", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ValidateCardHandler(rec, req)
+		return rec
+	}
+
+	rec := post(`{"number":"4111 1111 1111 1111"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		ValidationResult models.CardValidation `json:"validationResult"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if result := body.ValidationResult; !result.IsValid || result.Scheme != validation.CardSchemeVisa || result.MaskedNumber != "411111******1111" {
+		t.Errorf("result = %+v, want a valid masked Visa number", result)
+	}
+
+	for body, code := range map[string]string{
+		`{"number":""}`:                    "MISSING_FIELD",
+		`{"number":"4111-1111-1111-111x"}`: "INVALID_DATA",
+	} {
+		if rec := post(body); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), code) {
+			t.Errorf("%s: status %d, body %s; want 400 %s", body, rec.Code, rec.Body, code)
+		}
+	}
+	if strings.Contains(logs.String(), "411111111111") || strings.Contains(logs.String(), "1111-1111-1111") {
+		t.Errorf("card number logged: %s", logs.String())
+	}
+}
+
+func TestValidateIPCaller(t *testing.T) {
+	tests := []struct {
+		name, method, target, body string
+		remoteAddr, forwardedFor   string
+		trustProxy                 bool
+		wantIP, wantSource         string
+	}{
+		{"body", "POST", "/api/v1/validate/ip", `{"ip":"192.0.2.9"}`, "192.0.2.1:4242", "", false, "192.0.2.9", validation.IPSourceBody},
+		{"query", "GET", "/api/v1/validate/ip?ip=192.0.2.9", "", "192.0.2.1:4242", "", false, "192.0.2.9", validation.IPSourceQuery},
+		{"empty field", "POST", "/api/v1/validate/ip", `{"ip":""}`, "192.0.2.1:4242", "", false, "192.0.2.1", validation.IPSourceInferred},
+		{"empty body", "POST", "/api/v1/validate/ip", "", "192.0.2.1:4242", "", false, "192.0.2.1", validation.IPSourceInferred},
+		{"self", "POST", "/api/v1/validate/ip", `{"ip":"self"}`, "192.0.2.1:4242", "", false, "192.0.2.1", validation.IPSourceInferred},
+		{"GET without a query", "GET", "/api/v1/validate/ip", "", "192.0.2.1:4242", "", false, "192.0.2.1", validation.IPSourceInferred},
+		{"IPv6 remote address", "GET", "/api/v1/validate/ip?ip=self", "", "[2001:db8::2]:4242", "", false, "2001:db8::2", validation.IPSourceInferred},
+		{"forwarded for ignored without a trusted proxy", "GET", "/api/v1/validate/ip", "", "10.0.0.1:4242", "192.0.2.1", false, "10.0.0.1", validation.IPSourceInferred},
+		{"multi-hop forwarded for", "GET", "/api/v1/validate/ip", "", "10.0.0.1:4242", "198.51.100.7, 203.0.113.5, 192.0.2.1", true, "192.0.2.1", validation.IPSourceInferred},
+		{"forwarded IPv6 with brackets", "GET", "/api/v1/validate/ip", "", "10.0.0.1:4242", "198.51.100.7, [2001:db8::3]:443", true, "2001:db8::3", validation.IPSourceInferred},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.NewHandlers()
+			h.Config.TrustedProxy = tt.trustProxy
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			rec := httptest.NewRecorder()
+			h.ValidateIPHandler(rec, req)
+			if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			var body struct {
+				ValidationResult models.GeoIPResponse `json:"validationResult"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if result := body.ValidationResult; result.IP != tt.wantIP || result.Source != tt.wantSource {
+				t.Errorf("ip %q from %q, want %q from %q", result.IP, result.Source, tt.wantIP, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestValidateIPSummary(t *testing.T) {
+	h := testutil.NewHandlers()
+	for _, tt := range []struct {
+		ip, summary string
+	}{
+		{"8.8.8.8", "IP 8.8.8.8 is not in the geolocation database."},
+		{"192.168.1.20", "IP 192.168.1.20 is a private address."},
+		{"::1", "IP ::1 is a loopback address."},
+		{"169.254.169.254", "IP 169.254.169.254 is in a reserved range."},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/ip", strings.NewReader(`{"ip":"`+tt.ip+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ValidateIPHandler(rec, req)
+		var body struct {
+			ValidationResult models.GeoIPResponse `json:"validationResult"`
+			Summary          string               `json:"summary"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusCreated || body.Summary != tt.summary {
+			t.Errorf("%s: status %d, summary %q; want %q", tt.ip, rec.Code, body.Summary, tt.summary)
+		}
+		if result := body.ValidationResult; result.NotFound == (result.IsPrivate || result.IsLoopback || result.IsReserved) {
+			t.Errorf("%s: not found %v, flags %v %v %v", tt.ip, result.NotFound, result.IsPrivate, result.IsLoopback, result.IsReserved)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/innovelabs/microtools-go/internal/warnings"
+)
+
+// collectWarnings attaches a warning collector to r so the services called
+// with its context can report non-fatal issues. Batch handlers do not
+// attach one, so per-line warnings are discarded.
+func collectWarnings(r *http.Request) *http.Request {
+	return r.WithContext(warnings.NewContext(r.Context(), warnings.New()))
+}
+
+// addWarnings adds the collected warnings to a JSON response body. An empty
+// list is omitted.
+func addWarnings(r *http.Request, body map[string]interface{}) {
+	if list := warnings.FromContext(r.Context()).List(); list != nil {
+		body["warnings"] = list
+	}
+}
+
+// writeWarningHeaders reports the collected warnings of a binary response
+// as RFC 7234 Warning headers and, with codes and fields, as a JSON array
+// in X-Warnings. It must be called before the status is written.
+func writeWarningHeaders(w http.ResponseWriter, r *http.Request) {
+	list := warnings.FromContext(r.Context()).List()
+	if list == nil {
+		return
+	}
+	for _, warning := range list {
+		w.Header().Add("Warning", fmt.Sprintf("299 - %q", warning.Message))
+	}
+	encoded, err := json.Marshal(list)
+	if err != nil {
+		return
+	}
+	w.Header().Set("X-Warnings", string(encoded))
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"github.com/innovelabs/microtools-go/internal/wifirotation"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// wifiRotationPath is the base path of the rotating WiFi endpoints
+const wifiRotationPath = "/api/v1/generate/qr/wifi-rotating/"
+
+// wifiQRFormatJSON returns a rotating network's password and payload
+// instead of its QR code
+const wifiQRFormatJSON = "json"
+
+var errWifiRotationUnavailable = errors.New("rotating WiFi networks are not configured")
+
+// CreateWifiRotationHandler stores a rotating guest WiFi network of the
+// access token's user and answers 201 with its first password
+func (h *Handlers) CreateWifiRotationHandler(w http.ResponseWriter, r *http.Request) {
+	if h.WifiRotation == nil {
+		writeError(w, http.StatusServiceUnavailable, errWifiRotationUnavailable)
+		return
+	}
+	var req models.WifiRotationRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	network, err := h.WifiRotation.Create(r.Context(), middleware.TokenEmail(r.Context()), req)
+	if err != nil {
+		writeWifiRotationError(w, err)
+		return
+	}
+	w.Header().Set("Location", h.site().Path(wifiRotationPath+network.Profile.Name))
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusCreated, map[string]interface{}{"wifi": h.wifiRotation(network)})
+}
+
+// WifiRotationQRHandler serves the current QR code of a rotating network:
+// a PNG by default, SVG with ?format=svg, or the password and payload as
+// JSON with ?format=json. Responses are never cached, as the password
+// changes.
+func (h *Handlers) WifiRotationQRHandler(w http.ResponseWriter, r *http.Request) {
+	if h.WifiRotation == nil {
+		writeError(w, http.StatusNotFound, repository.ErrWifiProfileNotFound)
+		return
+	}
+	network, err := h.WifiRotation.Current(r.Context(), middleware.TokenEmail(r.Context()), mux.Vars(r)["name"])
+	if err != nil {
+		writeWifiRotationError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == wifiQRFormatJSON {
+		writeJSON(w, r, http.StatusOK, map[string]interface{}{"wifi": h.wifiRotation(network)})
+		return
+	}
+	data, err := json.Marshal(network.WifiData())
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to encode the network")
+		return
+	}
+	req := models.QRRequest{Type: "wifi", Data: string(data), Options: models.QROptions{Format: format}}
+	if size := query.Get("size"); size != "" {
+		if req.Options.Size, err = strconv.Atoi(size); err != nil {
+			WriteError(w, http.StatusBadRequest, InvalidOptionErrorCode, "size must be a number")
+			return
+		}
+	}
+	r = collectWarnings(r)
+	ctx, span := tracing.Start(r.Context(), "qr.encode", attribute.String("qr.type", req.Type))
+	image, contentType, err := generator.RenderQR(ctx, req)
+	tracing.End(span, err)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeWarningHeaders(w, r)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, r, image)
+}
+
+// RotateWifiHandler gives a rotating network a new password now. Repeated
+// within wifirotation.RotationWindow it returns the password just set,
+// with "rotated": false.
+func (h *Handlers) RotateWifiHandler(w http.ResponseWriter, r *http.Request) {
+	if h.WifiRotation == nil {
+		writeError(w, http.StatusNotFound, repository.ErrWifiProfileNotFound)
+		return
+	}
+	network, rotated, err := h.WifiRotation.Rotate(r.Context(), middleware.TokenEmail(r.Context()), mux.Vars(r)["name"])
+	if err != nil {
+		writeWifiRotationError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"wifi": h.wifiRotation(network), "rotated": rotated})
+}
+
+// DeleteWifiRotationHandler removes a rotating network
+func (h *Handlers) DeleteWifiRotationHandler(w http.ResponseWriter, r *http.Request) {
+	if h.WifiRotation == nil {
+		writeError(w, http.StatusNotFound, repository.ErrWifiProfileNotFound)
+		return
+	}
+	if err := h.WifiRotation.Delete(r.Context(), middleware.TokenEmail(r.Context()), mux.Vars(r)["name"]); err != nil {
+		writeWifiRotationError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) wifiRotation(network wifirotation.Network) models.WifiRotation {
+	p := network.Profile
+	return models.WifiRotation{
+		Name:           p.Name,
+		SSID:           p.SSID,
+		Security:       p.Security,
+		PasswordLength: p.PasswordLength,
+		Charset:        p.Charset,
+		Interval:       p.Interval.String(),
+		Version:        p.Version,
+		RotatedAt:      p.RotatedAt,
+		NextRotationAt: p.NextRotationAt,
+		Password:       network.Password,
+		Payload:        network.Payload(),
+		QRURL:          h.site().Path(wifiRotationPath + p.Name),
+	}
+}
+
+// writeWifiRotationError answers with the status of a rotating WiFi error
+func writeWifiRotationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, wifirotation.ErrInvalidNetwork):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, repository.ErrWifiProfileNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, repository.ErrWifiProfileExists):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, wifirotation.ErrSealedPassword):
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		WriteError(w, http.StatusInternalServerError, InternalErrorCode, "failed to store the WiFi network")
+	}
+}
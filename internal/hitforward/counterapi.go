@@ -0,0 +1,54 @@
+package hitforward
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/tracing"
+)
+
+const counterBaseURL = "https://api.counterapi.dev/v2/fawaz-sullias-team-2926"
+
+// CounterAPI sends increments to CounterAPI.dev counters
+type CounterAPI struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewCounterAPI creates a Sender for the CounterAPI.dev workspace,
+// authenticated with apiKey
+func NewCounterAPI(apiKey string) *CounterAPI {
+	return NewCounterAPIAt(counterBaseURL, apiKey)
+}
+
+// NewCounterAPIAt creates a Sender for the workspace at baseURL, e.g. a
+// stand-in server
+func NewCounterAPIAt(baseURL, apiKey string) *CounterAPI {
+	return &CounterAPI{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 5 * time.Second, Transport: tracing.Transport(nil)},
+	}
+}
+
+// Send increments the named counter by one
+func (c *CounterAPI) Send(ctx context.Context, name string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/"+name+"/up", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("counter %s returned status %d", name, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+// Package hitforward forwards endpoint hits to an external counter service
+// such as CounterAPI.dev without losing them while it is down. Hits are
+// buffered and sent in numbered batches; increments that cannot be
+// delivered go to a write-ahead log and are replayed with backoff once
+// the service answers again, including after a restart.
+package hitforward
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// Forwarder states reported by Status
+const (
+	// StateHealthy sends every batch as it is flushed
+	StateHealthy = "healthy"
+	// StateDegraded logs batches while waiting out the backoff after a
+	// failed delivery
+	StateDegraded = "degraded"
+	// StateReplaying sends the logged batches, oldest first
+	StateReplaying = "replaying"
+)
+
+const (
+	DefaultFlushInterval = 15 * time.Second
+	DefaultConcurrency   = 8
+	DefaultMinBackoff    = 5 * time.Second
+	DefaultMaxBackoff    = 5 * time.Minute
+	// ShutdownTimeout bounds the last delivery when Run stops
+	ShutdownTimeout = 5 * time.Second
+	// replayChunk is how many increments of a counter are sent between
+	// acks while replaying, which bounds what a crash can count twice
+	replayChunk = 100
+)
+
+// Sender delivers one increment of the named counter
+type Sender interface {
+	Send(ctx context.Context, name string) error
+}
+
+// Options tunes a Forwarder; zero values use the defaults
+type Options struct {
+	// WALPath is the write-ahead log file; "" keeps undelivered
+	// increments in memory only, so they do not survive a restart
+	WALPath string
+	// WALMaxBytes caps the log; beyond it the oldest batches are dropped
+	// and counted in Status. 0 leaves it unbounded.
+	WALMaxBytes int64
+	// FlushInterval is how often buffered hits are sent as a batch
+	FlushInterval time.Duration
+	// Concurrency bounds the increments in flight
+	Concurrency int
+	// MinBackoff and MaxBackoff bound the wait before the service is tried
+	// again; it doubles with every failed attempt
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Forwarder is a HitCounter that delivers hits through a Sender. Hit only
+// adds to an in-memory buffer; Run sends it.
+type Forwarder struct {
+	sender Sender
+	clock  clock.Clock
+	opts   Options
+
+	mu     sync.Mutex
+	buffer map[string]int
+	status models.CounterForwarderStatus
+
+	// owned by Run
+	wal         *wal
+	seq         uint64
+	backoff     time.Duration
+	nextAttempt time.Time
+	// dropping is set once an outage overflowed the log, which is logged once
+	dropping bool
+}
+
+// New creates a Forwarder delivering through sender. Batches left in the
+// write-ahead log by a previous run are replayed first; a log that cannot
+// be opened is logged and replaced by one in memory.
+func New(sender Sender, c clock.Clock, opts Options) *Forwarder {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = DefaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(DefaultMaxBackoff, opts.MinBackoff)
+	}
+
+	w, err := openWAL(opts.WALPath)
+	if err != nil {
+		log.Printf("[counter] write-ahead log %s disabled: %v", opts.WALPath, err)
+		w, _ = openWAL("")
+	}
+	f := &Forwarder{
+		sender:  sender,
+		clock:   c,
+		opts:    opts,
+		buffer:  map[string]int{},
+		wal:     w,
+		seq:     w.lastSeq(),
+		backoff: opts.MinBackoff,
+	}
+	f.status.State = StateHealthy
+	if len(w.pending) > 0 {
+		log.Printf("[counter] replaying %d batches left in the write-ahead log", len(w.pending))
+		f.status.State = StateReplaying
+	}
+	f.updateStatus("")
+	return f
+}
+
+// Hit counts one call of the named endpoint without blocking the request
+func (f *Forwarder) Hit(_ context.Context, name string) {
+	f.mu.Lock()
+	f.buffer[name]++
+	f.mu.Unlock()
+}
+
+// Status reports the forwarder's state and backlog for /ready
+func (f *Forwarder) Status() models.CounterForwarderStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.status
+}
+
+// Run flushes the buffer every FlushInterval until ctx is done. Hits still
+// buffered then are sent once more, within ShutdownTimeout, and what is
+// not delivered is written to the log for the next run.
+func (f *Forwarder) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			f.stop(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			f.Flush(ctx)
+		}
+	}
+}
+
+// Flush sends the buffered hits, or logs them while the service is down,
+// and replays the log when the backoff has passed. Run calls it; it must
+// not be called concurrently.
+func (f *Forwarder) Flush(ctx context.Context) {
+	b, ok := f.takeBuffer()
+	state := f.Status().State
+
+	var failure, replayFailure error
+	switch {
+	case state == StateHealthy && ok:
+		delivered, err := f.deliver(ctx, b.counts, 0)
+		if err != nil {
+			for name, n := range delivered {
+				b.ack(name, n)
+			}
+			f.logBatch(b)
+			failure = err
+		}
+	case ok:
+		f.logBatch(b)
+	}
+	if state != StateHealthy && !f.clock.Now().Before(f.nextAttempt) {
+		f.setState(StateReplaying)
+		replayFailure = f.replay(ctx)
+	}
+
+	switch {
+	case replayFailure != nil:
+		f.backoff = min(f.backoff*2, f.opts.MaxBackoff)
+		log.Printf("[counter] replay failed, retrying in %s: %v", f.backoff, replayFailure)
+		f.degrade()
+		failure = replayFailure
+	case failure != nil:
+		log.Printf("[counter] delivery failed, logging increments until the counter service recovers: %v", failure)
+		f.degrade()
+	}
+	f.enforceCap()
+	if err := f.wal.sync(); err != nil {
+		log.Printf("[counter] failed to sync the write-ahead log: %v", err)
+	}
+	f.updateStatus(errorText(failure))
+}
+
+// takeBuffer swaps out the buffered hits as the next batch
+func (f *Forwarder) takeBuffer() (batch, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.buffer) == 0 {
+		return batch{}, false
+	}
+	counts := f.buffer
+	f.buffer = map[string]int{}
+	f.seq++
+	return batch{seq: f.seq, counts: counts}, true
+}
+
+// replay sends the logged batches oldest first, acking what was delivered
+// so nothing is sent twice, until the log is empty or a send fails
+func (f *Forwarder) replay(ctx context.Context) error {
+	for len(f.wal.pending) > 0 {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		delivered, err := f.deliver(ctx, f.wal.pending[0].counts, replayChunk)
+		for name, n := range delivered {
+			if ackErr := f.wal.appendAck(name, n); ackErr != nil {
+				log.Printf("[counter] failed to ack batch %d: %v", f.wal.pending[0].seq, ackErr)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := f.wal.popEmpty(); err != nil {
+			log.Printf("[counter] failed to truncate the write-ahead log: %v", err)
+		}
+	}
+	log.Printf("[counter] write-ahead log replayed, counter service healthy")
+	f.backoff = f.opts.MinBackoff
+	f.dropping = false
+	f.setState(StateHealthy)
+	return nil
+}
+
+// deliver sends the increments of counts, up to limit per counter when
+// limit is positive, with at most Concurrency in flight. After the first
+// failure no new increments are started. It returns how many increments of
+// each counter were delivered and the first failure.
+func (f *Forwarder) deliver(ctx context.Context, counts map[string]int, limit int) (map[string]int, error) {
+	var (
+		mu        sync.Mutex
+		delivered = make(map[string]int, len(counts))
+		firstErr  error
+		failed    atomic.Bool
+		wg        sync.WaitGroup
+		slots     = make(chan struct{}, f.opts.Concurrency)
+	)
+	for name, count := range counts {
+		if limit > 0 {
+			count = min(count, limit)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sent := 0
+			for ; sent < count && !failed.Load(); sent++ {
+				slots <- struct{}{}
+				err := f.sender.Send(ctx, name)
+				<-slots
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					failed.Store(true)
+					break
+				}
+			}
+			mu.Lock()
+			delivered[name] = sent
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return delivered, firstErr
+}
+
+// logBatch writes a batch with undelivered increments to the log
+func (f *Forwarder) logBatch(b batch) {
+	if len(b.counts) == 0 {
+		return
+	}
+	if err := f.wal.appendBatch(b); err != nil {
+		log.Printf("[counter] failed to log batch %d: %v", b.seq, err)
+	}
+}
+
+// degrade logs new batches until the backoff has passed. The backoff
+// starts at MinBackoff and doubles with each failed replay.
+func (f *Forwarder) degrade() {
+	f.nextAttempt = f.clock.Now().Add(f.backoff)
+	f.setState(StateDegraded)
+}
+
+// enforceCap drops the oldest logged batches beyond WALMaxBytes
+func (f *Forwarder) enforceCap() {
+	if f.opts.WALMaxBytes <= 0 {
+		return
+	}
+	dropped, err := f.wal.enforceCap(f.opts.WALMaxBytes)
+	if err != nil {
+		log.Printf("[counter] failed to compact the write-ahead log: %v", err)
+	}
+	if dropped > 0 {
+		if !f.dropping {
+			log.Printf("[counter] write-ahead log over %d bytes, dropping the oldest increments", f.opts.WALMaxBytes)
+			f.dropping = true
+		}
+		f.mu.Lock()
+		f.status.DroppedIncrements += int64(dropped)
+		f.mu.Unlock()
+	}
+}
+
+// stop sends the buffered hits while the service is healthy and logs
+// those it could not deliver so the next run sends them
+func (f *Forwarder) stop(ctx context.Context) {
+	if b, ok := f.takeBuffer(); ok {
+		if f.Status().State == StateHealthy {
+			ctx, cancel := context.WithTimeout(ctx, ShutdownTimeout)
+			delivered, err := f.deliver(ctx, b.counts, 0)
+			cancel()
+			for name, n := range delivered {
+				b.ack(name, n)
+			}
+			if err != nil {
+				log.Printf("[counter] delivery failed on shutdown, logging %d increments for the next run: %v", b.total(), err)
+			}
+		}
+		f.logBatch(b)
+	}
+	f.enforceCap()
+	if err := f.wal.sync(); err != nil {
+		log.Printf("[counter] failed to sync the write-ahead log: %v", err)
+	}
+	f.wal.close()
+	f.updateStatus("")
+}
+
+func (f *Forwarder) setState(state string) {
+	f.mu.Lock()
+	f.status.State = state
+	f.mu.Unlock()
+}
+
+// updateStatus publishes the log's backlog; lastError is kept until the
+// forwarder is healthy again
+func (f *Forwarder) updateStatus(lastError string) {
+	pending := 0
+	for _, b := range f.wal.pending {
+		pending += b.total()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status.PendingIncrements = int64(pending)
+	f.status.WALBytes = f.wal.size
+	if lastError != "" {
+		f.status.LastError = lastError
+	} else if f.status.State == StateHealthy {
+		f.status.LastError = ""
+	}
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
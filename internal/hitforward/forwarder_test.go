@@ -0,0 +1,285 @@
+package hitforward
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock stands in for testutil.Clock, which this package cannot
+// import because testutil imports it
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// counterServer stands in for CounterAPI.dev. While down it answers 503;
+// failAfter > 0 lets that many more increments through first.
+type counterServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	counts    map[string]int
+	down      bool
+	failAfter int
+}
+
+func newCounterServer(t *testing.T) *counterServer {
+	s := &counterServer{counts: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/up")
+		if !ok || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.down {
+			if s.failAfter == 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			s.failAfter--
+		}
+		s.counts[name]++
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *counterServer) setDown(down bool, failAfter int) {
+	s.mu.Lock()
+	s.down, s.failAfter = down, failAfter
+	s.mu.Unlock()
+}
+
+func (s *counterServer) count(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[name]
+}
+
+func hit(f *Forwarder, name string, n int) {
+	for range n {
+		f.Hit(context.Background(), name)
+	}
+}
+
+func newForwarder(t *testing.T, server *counterServer, opts Options) (*Forwarder, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if opts.MinBackoff == 0 {
+		opts.MinBackoff = time.Minute
+	}
+	return New(NewCounterAPIAt(server.URL, "key"), clock, opts), clock
+}
+
+// TestForwarderBatches requires hits to wait in memory until the next
+// flush and be sent together then
+func TestForwarderBatches(t *testing.T) {
+	server := newCounterServer(t)
+	f, _ := newForwarder(t, server, Options{})
+
+	hit(f, "email", 40)
+	hit(f, "qr", 2)
+	if server.count("email") != 0 || server.count("qr") != 0 {
+		t.Fatalf("sent before the flush: %d email, %d qr", server.count("email"), server.count("qr"))
+	}
+	f.Flush(context.Background())
+	if server.count("email") != 40 || server.count("qr") != 2 {
+		t.Errorf("counted %d email and %d qr, want 40 and 2", server.count("email"), server.count("qr"))
+	}
+	f.Flush(context.Background())
+	if server.count("email") != 40 {
+		t.Errorf("an empty flush sent again: %d email", server.count("email"))
+	}
+}
+
+// TestForwarderFlushesOnShutdown stops Run with hits still buffered and
+// requires them to be sent, or logged for the next run when the service
+// does not answer
+func TestForwarderFlushesOnShutdown(t *testing.T) {
+	server := newCounterServer(t)
+	path := filepath.Join(t.TempDir(), "counter.wal")
+	f, _ := newForwarder(t, server, Options{WALPath: path, FlushInterval: time.Hour})
+	hit(f, "email", 12)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f.Run(ctx)
+	if server.count("email") != 12 || f.Status().PendingIncrements != 0 {
+		t.Fatalf("counted %d email on shutdown, %+v", server.count("email"), f.Status())
+	}
+
+	server.setDown(true, 4)
+	f, _ = newForwarder(t, server, Options{WALPath: path, FlushInterval: time.Hour})
+	hit(f, "ip", 10)
+	f.Run(ctx)
+	if server.count("ip") != 4 || f.Status().PendingIncrements != 6 {
+		t.Fatalf("during an outage: counted %d ip, %+v", server.count("ip"), f.Status())
+	}
+
+	server.setDown(false, 0)
+	next, _ := newForwarder(t, server, Options{WALPath: path})
+	next.Flush(context.Background())
+	if server.count("ip") != 10 || next.Status().State != StateHealthy {
+		t.Errorf("after a restart: counted %d ip, %+v", server.count("ip"), next.Status())
+	}
+}
+
+// TestForwarderOutage takes the counter service down for a while and
+// requires every hit to be counted exactly once after it recovers
+func TestForwarderOutage(t *testing.T) {
+	server := newCounterServer(t)
+	f, clock := newForwarder(t, server, Options{WALPath: filepath.Join(t.TempDir(), "counter.wal")})
+	ctx := context.Background()
+
+	hit(f, "email", 10)
+	f.Flush(ctx)
+	if server.count("email") != 10 || f.Status().State != StateHealthy {
+		t.Fatalf("healthy flush: %d counted, %+v", server.count("email"), f.Status())
+	}
+
+	server.setDown(true, 0)
+	for range 3 {
+		hit(f, "email", 5)
+		hit(f, "qr", 3)
+		f.Flush(ctx)
+		clock.Advance(15 * time.Second)
+	}
+	status := f.Status()
+	if status.State != StateDegraded || status.PendingIncrements != 24 || status.WALBytes == 0 || status.LastError == "" {
+		t.Fatalf("during the outage: %+v", status)
+	}
+
+	// The first replay attempt fails and doubles the backoff
+	clock.Advance(time.Minute)
+	f.Flush(ctx)
+	if f.Status().State != StateDegraded || f.backoff != 2*time.Minute {
+		t.Fatalf("failed replay: %+v, backoff %s", f.Status(), f.backoff)
+	}
+
+	server.setDown(false, 0)
+	hit(f, "qr", 1)
+	f.Flush(ctx)
+	if f.Status().State != StateDegraded || server.count("qr") != 0 {
+		t.Errorf("before the backoff passed: %+v with %d qr counted", f.Status(), server.count("qr"))
+	}
+	clock.Advance(2 * time.Minute)
+	f.Flush(ctx)
+	status = f.Status()
+	if status.State != StateHealthy || status.PendingIncrements != 0 || status.WALBytes != 0 || status.LastError != "" {
+		t.Errorf("after recovery: %+v", status)
+	}
+	if server.count("email") != 25 || server.count("qr") != 10 {
+		t.Errorf("counted %d email and %d qr, want 25 and 10", server.count("email"), server.count("qr"))
+	}
+	if f.backoff != f.opts.MinBackoff {
+		t.Errorf("backoff %s after recovery, want it reset", f.backoff)
+	}
+}
+
+// TestForwarderReplaysAfterRestart stops a forwarder during an outage and
+// requires the next one to deliver its logged increments, including when
+// that replay is itself interrupted
+func TestForwarderReplaysAfterRestart(t *testing.T) {
+	server := newCounterServer(t)
+	path := filepath.Join(t.TempDir(), "counter.wal")
+	server.setDown(true, 0)
+
+	first, _ := newForwarder(t, server, Options{WALPath: path})
+	hit(first, "iban", 150)
+	first.Flush(context.Background())
+	hit(first, "iban", 50)
+	hit(first, "ip", 7)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	first.Run(ctx)
+
+	// The replay dies after 120 increments, some of them acked in the log
+	second, _ := newForwarder(t, server, Options{WALPath: path})
+	if status := second.Status(); status.State != StateReplaying || status.PendingIncrements != 207 {
+		t.Fatalf("after a restart: %+v", status)
+	}
+	server.setDown(true, 120)
+	second.Flush(context.Background())
+	second.wal.close()
+
+	server.setDown(false, 0)
+	third, _ := newForwarder(t, server, Options{WALPath: path})
+	third.Flush(context.Background())
+	if status := third.Status(); status.State != StateHealthy || status.PendingIncrements != 0 {
+		t.Errorf("after the replay: %+v", status)
+	}
+	if server.count("iban") != 200 || server.count("ip") != 7 {
+		t.Errorf("counted %d iban and %d ip, want 200 and 7 exactly", server.count("iban"), server.count("ip"))
+	}
+}
+
+// TestForwarderWALCap fills a capped log during an outage and requires
+// the oldest batches to be dropped and counted
+func TestForwarderWALCap(t *testing.T) {
+	for _, path := range []string{"", filepath.Join(t.TempDir(), "counter.wal")} {
+		server := newCounterServer(t)
+		const maxBytes = 200
+		f, clock := newForwarder(t, server, Options{WALPath: path, WALMaxBytes: maxBytes, MinBackoff: time.Hour})
+		server.setDown(true, 0)
+		names := []string{"batch-a", "batch-b", "batch-c", "batch-d", "batch-e", "batch-f", "batch-g", "batch-h"}
+		for _, name := range names {
+			hit(f, name, 4)
+			f.Flush(context.Background())
+		}
+		status := f.Status()
+		if status.WALBytes > maxBytes || status.DroppedIncrements == 0 || status.DroppedIncrements+status.PendingIncrements != 32 {
+			t.Fatalf("log %q over its cap: %+v", path, status)
+		}
+		if path != "" {
+			if info, err := os.Stat(path); err != nil || info.Size() != status.WALBytes {
+				t.Errorf("log file: %v, %v; want %d bytes", info, err, status.WALBytes)
+			}
+		}
+
+		server.setDown(false, 0)
+		clock.Advance(time.Hour)
+		f.Flush(context.Background())
+		dropped := int(status.DroppedIncrements) / 4
+		for i, name := range names {
+			if want := 4 * min(1, max(0, i-dropped+1)); server.count(name) != want {
+				t.Errorf("log %q: %s counted %d times, want %d with the oldest %d batches dropped", path, name, server.count(name), want, dropped)
+			}
+		}
+		if status := f.Status(); status.DroppedIncrements != int64(4*dropped) || status.State != StateHealthy {
+			t.Errorf("log %q after recovery: %+v", path, status)
+		}
+	}
+}
+
+func TestWALSkipsTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter.wal")
+	log := `{"seq":3,"counts":{"email":5,"qr":2}}
+{"ack":3,"name":"email","n":5}
+{"seq":4,"counts":{"qr":1}}
+{"ack":4,"name":"qr","n":1}
+{"seq":5,"counts":{"ip":9}}
+{"ack":5,"name":"ip","n"`
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := openWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+	if len(w.pending) != 2 || w.pending[0].seq != 3 || w.pending[0].total() != 2 || w.pending[1].total() != 9 || w.lastSeq() != 5 {
+		t.Errorf("pending = %+v", w.pending)
+	}
+	// Opening compacts the acks away
+	data, _ := os.ReadFile(path)
+	if want := "{\"seq\":3,\"counts\":{\"qr\":2}}\n{\"seq\":5,\"counts\":{\"ip\":9}}\n"; string(data) != want || w.size != int64(len(want)) {
+		t.Errorf("compacted log %q, size %d", data, w.size)
+	}
+}
@@ -0,0 +1,260 @@
+package hitforward
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// walRecord is one line of the write-ahead log: a batch of undelivered
+// increments, or an ack of n increments of one counter of batch Ack that
+// were delivered since. Replays fold the acks into their batches, so an
+// increment is only sent again when it was never acknowledged.
+type walRecord struct {
+	Seq    uint64         `json:"seq,omitempty"`
+	Counts map[string]int `json:"counts,omitempty"`
+
+	Ack  uint64 `json:"ack,omitempty"`
+	Name string `json:"name,omitempty"`
+	N    int    `json:"n,omitempty"`
+}
+
+// batch is a numbered set of increments per counter name
+type batch struct {
+	seq    uint64
+	counts map[string]int
+}
+
+func (b batch) total() int {
+	n := 0
+	for _, count := range b.counts {
+		n += count
+	}
+	return n
+}
+
+// wal keeps the pending batches in memory and, with a path, appends every
+// change to a file so pending increments survive restarts. size is the
+// encoded size of the records, which the cap applies to with or without a
+// file.
+type wal struct {
+	path    string
+	file    *os.File
+	pending []batch
+	size    int64
+}
+
+// openWAL loads the batches left in the log at path and opens it for
+// appending. An empty path keeps the log in memory only.
+func openWAL(path string) (*wal, error) {
+	w := &wal{path: path}
+	if path == "" {
+		return w, nil
+	}
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	// rewrite the folded batches so acks and torn lines do not pile up
+	if err := w.compact(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// load folds the records of the file into pending batches. A torn last
+// line from a crash mid-append is skipped.
+func (w *wal) load() error {
+	f, err := os.Open(w.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	index := map[uint64]int{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var rec walRecord
+		if json.Unmarshal(scanner.Bytes(), &rec) != nil {
+			continue
+		}
+		switch {
+		case rec.Seq != 0:
+			index[rec.Seq] = len(w.pending)
+			w.pending = append(w.pending, batch{seq: rec.Seq, counts: rec.Counts})
+		case rec.Ack != 0:
+			if i, ok := index[rec.Ack]; ok {
+				w.pending[i].ack(rec.Name, rec.N)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", w.path, err)
+	}
+	w.pending = dropEmpty(w.pending)
+	return nil
+}
+
+// ack removes n delivered increments of name from the batch
+func (b batch) ack(name string, n int) {
+	if b.counts[name] <= n {
+		delete(b.counts, name)
+	} else {
+		b.counts[name] -= n
+	}
+}
+
+func dropEmpty(batches []batch) []batch {
+	kept := batches[:0]
+	for _, b := range batches {
+		if len(b.counts) > 0 {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
+
+// lastSeq returns the highest batch sequence in the log, or 0
+func (w *wal) lastSeq() uint64 {
+	if len(w.pending) == 0 {
+		return 0
+	}
+	return w.pending[len(w.pending)-1].seq
+}
+
+// appendBatch logs a batch that could not be delivered
+func (w *wal) appendBatch(b batch) error {
+	w.pending = append(w.pending, b)
+	return w.write(walRecord{Seq: b.seq, Counts: b.counts})
+}
+
+// appendAck logs n delivered increments of name from the oldest batch
+func (w *wal) appendAck(name string, n int) error {
+	if n == 0 || len(w.pending) == 0 {
+		return nil
+	}
+	oldest := w.pending[0]
+	oldest.ack(name, n)
+	return w.write(walRecord{Ack: oldest.seq, Name: name, N: n})
+}
+
+// popEmpty drops the oldest batch once every increment of it was acked,
+// truncating the file when nothing is pending anymore
+func (w *wal) popEmpty() error {
+	if len(w.pending) == 0 || len(w.pending[0].counts) > 0 {
+		return nil
+	}
+	w.pending = w.pending[1:]
+	if len(w.pending) > 0 {
+		return nil
+	}
+	w.size = 0
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Truncate(0)
+}
+
+func (w *wal) write(rec walRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	w.size += int64(len(line))
+	if w.file == nil {
+		return nil
+	}
+	_, err = w.file.Write(line)
+	return err
+}
+
+// enforceCap drops the oldest batches until the log fits in maxBytes,
+// compacting first so acks are not counted against it. It returns the
+// number of increments dropped.
+func (w *wal) enforceCap(maxBytes int64) (int, error) {
+	if w.size <= maxBytes {
+		return 0, nil
+	}
+	dropped := 0
+	for w.encodedSize() > maxBytes && len(w.pending) > 0 {
+		dropped += w.pending[0].total()
+		w.pending = w.pending[1:]
+	}
+	return dropped, w.compact()
+}
+
+func (w *wal) encodedSize() int64 {
+	var size int64
+	for _, b := range w.pending {
+		line, _ := json.Marshal(walRecord{Seq: b.seq, Counts: b.counts})
+		size += int64(len(line)) + 1
+	}
+	return size
+}
+
+// compact replaces the file with one batch record per pending batch,
+// written to a temporary file first so a crash leaves either log intact
+func (w *wal) compact() error {
+	if w.path == "" {
+		w.size = w.encodedSize()
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(w.path), filepath.Base(w.path)+".*")
+	if err != nil {
+		return err
+	}
+	var size int64
+	writer := bufio.NewWriter(tmp)
+	for _, b := range w.pending {
+		line, _ := json.Marshal(walRecord{Seq: b.seq, Counts: b.counts})
+		writer.Write(append(line, '\n'))
+		size += int64(len(line)) + 1
+	}
+	err = writer.Flush()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), w.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if w.file != nil {
+		w.file.Close()
+	}
+	w.file, err = os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	w.size = size
+	return err
+}
+
+// sync flushes appended records to disk
+func (w *wal) sync() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+func (w *wal) close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,79 @@
+// Package imagedecode decodes untrusted uploads for the image tools with
+// guards against oversized files and decompression bombs
+package imagedecode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxBytes caps the encoded size of one image
+	MaxBytes = 5 << 20
+	// MaxPixels caps the decoded size, so a small file declaring a huge
+	// canvas is rejected before any pixel memory is allocated
+	MaxPixels = 16 << 20
+	// MaxDimension caps either side of an image
+	MaxDimension = 10000
+)
+
+var (
+	ErrTooLarge          = errors.New("image is too large")
+	ErrUnsupportedFormat = errors.New("unsupported image format: use PNG, JPEG, GIF or WebP")
+	ErrInvalidImage      = errors.New("invalid image")
+)
+
+// Decode reads at most MaxBytes from r and decodes the image after checking
+// the dimensions declared in its header. It returns the format name as
+// registered with the image package.
+func Decode(r io.Reader) (image.Image, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	return DecodeBytes(data)
+}
+
+// DecodeBytes is Decode for an image already in memory
+func DecodeBytes(data []byte) (image.Image, string, error) {
+	if len(data) > MaxBytes {
+		return nil, "", fmt.Errorf("%w: at most %d MB is accepted", ErrTooLarge, MaxBytes>>20)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if err := checkDimensions(config.Width, config.Height); err != nil {
+		return nil, "", err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	return img, format, nil
+}
+
+func checkDimensions(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("%w: image has no pixels", ErrInvalidImage)
+	}
+	if width > MaxDimension || height > MaxDimension {
+		return fmt.Errorf("%w: %dx%d exceeds %d pixels per side", ErrTooLarge, width, height, MaxDimension)
+	}
+	if width*height > MaxPixels {
+		return fmt.Errorf("%w: %dx%d exceeds %d megapixels", ErrTooLarge, width, height, MaxPixels>>20)
+	}
+	return nil
+}
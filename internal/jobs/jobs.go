@@ -0,0 +1,209 @@
+// Package jobs runs long requests in the background and keeps their
+// results in memory until they are fetched once or expire
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/correlation"
+)
+
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+
+	// DefaultTTL is how long an unfetched result is kept
+	DefaultTTL = time.Hour
+	// DefaultMaxJobs caps the jobs held at once, finished or not, and
+	// DefaultMaxJobsPerClient those of one client
+	DefaultMaxJobs          = 20
+	DefaultMaxJobsPerClient = 3
+	// DefaultWorkers is how many jobs run at the same time
+	DefaultWorkers = 2
+)
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobNotFinished = errors.New("job has not finished")
+	ErrTooManyJobs    = errors.New("too many jobs in progress")
+	ErrClientJobLimit = errors.New("too many jobs of this client; fetch a result or let one expire first")
+)
+
+// Result is the output of a finished job, served as-is
+type Result struct {
+	ContentType string
+	Filename    string
+	// Headers are extra response headers, e.g. a page count
+	Headers map[string]string
+	Data    []byte
+}
+
+// Job is a snapshot of a job's state
+type Job struct {
+	ID         string
+	Status     string
+	Error      string
+	CreatedAt  time.Time
+	FinishedAt time.Time
+	// CorrelationIDs are the IDs of the submitting request, by header
+	CorrelationIDs map[string]string
+}
+
+// Func computes a job's result
+type Func func(ctx context.Context) (Result, error)
+
+type job struct {
+	Job
+	client string
+	result Result
+}
+
+// Store runs jobs on a bounded number of workers and keeps each finished
+// job until its result is fetched, or for the TTL. A job that failed is
+// dropped when its status is read after it finished.
+type Store struct {
+	clock        clock.Clock
+	ttl          time.Duration
+	maxJobs      int
+	maxPerClient int
+	slots        chan struct{}
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// NewStore creates a Store with the default limits that stamps jobs with clk
+func NewStore(clk clock.Clock) *Store {
+	return &Store{
+		clock:        clk,
+		ttl:          DefaultTTL,
+		maxJobs:      DefaultMaxJobs,
+		maxPerClient: DefaultMaxJobsPerClient,
+		slots:        make(chan struct{}, DefaultWorkers),
+		jobs:         map[string]*job{},
+	}
+}
+
+// Submit queues run for client, e.g. a user or IP address, and returns the
+// pending job. The job keeps the correlation IDs of ctx and run gets them
+// in its context, but ctx is not otherwise used so the job outlives the
+// request.
+func (s *Store) Submit(ctx context.Context, client string, run Func) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	s.mu.Lock()
+	s.pruneLocked()
+	if len(s.jobs) >= s.maxJobs {
+		s.mu.Unlock()
+		return Job{}, ErrTooManyJobs
+	}
+	held := 0
+	for _, j := range s.jobs {
+		if j.client == client {
+			held++
+		}
+	}
+	if held >= s.maxPerClient {
+		s.mu.Unlock()
+		return Job{}, ErrClientJobLimit
+	}
+	ids := correlation.FromContext(ctx)
+	j := &job{Job: Job{ID: id, Status: StatusPending, CreatedAt: s.clock.Now().UTC(), CorrelationIDs: ids.Map()}, client: client}
+	s.jobs[id] = j
+	snapshot := j.Job
+	s.mu.Unlock()
+
+	go s.run(j, ids, run)
+	return snapshot, nil
+}
+
+func (s *Store) run(j *job, ids correlation.IDs, run Func) {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	s.mu.Lock()
+	j.Status = StatusRunning
+	s.mu.Unlock()
+
+	result, err := run(correlation.NewContext(context.Background(), ids))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.FinishedAt = s.clock.Now().UTC()
+	if err != nil {
+		if len(ids) > 0 {
+			log.Printf("Job %s failed: %v (%s)", j.ID, err, ids)
+		} else {
+			log.Printf("Job %s failed: %v", j.ID, err)
+		}
+		j.Status = StatusFailed
+		j.Error = err.Error()
+		return
+	}
+	j.Status = StatusDone
+	j.result = result
+}
+
+// Get returns the state of the job with id. A failed job is dropped once
+// its failure has been read, as it has no result to fetch.
+func (s *Store) Get(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	j, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	if j.Status == StatusFailed {
+		delete(s.jobs, id)
+	}
+	return j.Job, nil
+}
+
+// Result returns the result of a finished job and drops the job, freeing
+// its place: a result is served once
+func (s *Store) Result(id string) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	j, ok := s.jobs[id]
+	if !ok {
+		return Result{}, ErrJobNotFound
+	}
+	if j.Status != StatusDone {
+		return Result{}, ErrJobNotFinished
+	}
+	delete(s.jobs, id)
+	return j.result, nil
+}
+
+// pruneLocked drops jobs that finished more than the TTL ago without being
+// fetched
+func (s *Store) pruneLocked() {
+	now := s.clock.Now()
+	for id, j := range s.jobs {
+		if !j.FinishedAt.IsZero() && now.Sub(j.FinishedAt) > s.ttl {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
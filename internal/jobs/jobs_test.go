@@ -0,0 +1,163 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/correlation"
+	"github.com/innovelabs/microtools-go/internal/jobs"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+func newTestStore() (*jobs.Store, *testutil.Clock) {
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	return jobs.NewStore(clock), clock
+}
+
+func done(ctx context.Context) (jobs.Result, error) {
+	return jobs.Result{ContentType: "text/plain", Data: []byte("ok")}, nil
+}
+
+// wait polls the job with id until it has finished
+func wait(t *testing.T, store *jobs.Store, id string) jobs.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := store.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == jobs.StatusDone || job.Status == jobs.StatusFailed {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return jobs.Job{}
+}
+
+func TestResultIsServedOnce(t *testing.T) {
+	store, _ := newTestStore()
+	job, err := store.Submit(context.Background(), "ip:192.0.2.1", done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(t, store, job.ID)
+
+	result, err := store.Result(job.ID)
+	if err != nil || string(result.Data) != "ok" {
+		t.Fatalf("Result = %q, %v", result.Data, err)
+	}
+	if _, err := store.Result(job.ID); !errors.Is(err, jobs.ErrJobNotFound) {
+		t.Errorf("second Result: err = %v, want ErrJobNotFound", err)
+	}
+	if _, err := store.Get(job.ID); !errors.Is(err, jobs.ErrJobNotFound) {
+		t.Errorf("Get after the result was fetched: err = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestFailedJobIsDroppedOnceRead(t *testing.T) {
+	store, _ := newTestStore()
+	job, err := store.Submit(context.Background(), "ip:192.0.2.1", func(ctx context.Context) (jobs.Result, error) {
+		return jobs.Result{}, errors.New("render failed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed := wait(t, store, job.ID); failed.Status != jobs.StatusFailed || failed.Error != "render failed" {
+		t.Fatalf("job = %+v, want failed", failed)
+	}
+	if _, err := store.Get(job.ID); !errors.Is(err, jobs.ErrJobNotFound) {
+		t.Errorf("Get after the failure was read: err = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestJobsPerClientLimit(t *testing.T) {
+	store, clock := newTestStore()
+	var ids []string
+	for i := 0; i < jobs.DefaultMaxJobsPerClient; i++ {
+		job, err := store.Submit(context.Background(), "ip:192.0.2.1", done)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, job.ID)
+	}
+	if _, err := store.Submit(context.Background(), "ip:192.0.2.1", done); !errors.Is(err, jobs.ErrClientJobLimit) {
+		t.Fatalf("err = %v, want ErrClientJobLimit", err)
+	}
+	if _, err := store.Submit(context.Background(), "user:ada@example.com", done); err != nil {
+		t.Errorf("another client: %v", err)
+	}
+
+	// Fetching a result frees its place
+	wait(t, store, ids[0])
+	if _, err := store.Result(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Submit(context.Background(), "ip:192.0.2.1", done); err != nil {
+		t.Errorf("after a result was fetched: %v", err)
+	}
+
+	// So does the TTL of unfetched results
+	for _, id := range ids[1:] {
+		wait(t, store, id)
+	}
+	clock.Advance(jobs.DefaultTTL + time.Second)
+	if _, err := store.Get(ids[1]); !errors.Is(err, jobs.ErrJobNotFound) {
+		t.Errorf("Get after the TTL: err = %v, want ErrJobNotFound", err)
+	}
+}
+
+// TestJobCarriesCorrelationIDs submits a job for a request with
+// correlation IDs; the job keeps them and its outbound call, like a
+// webhook delivery would, forwards them
+func TestJobCarriesCorrelationIDs(t *testing.T) {
+	received := make(chan http.Header, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer hook.Close()
+
+	store, _ := newTestStore()
+	ids := correlation.IDs{{Header: "X-Correlation-Id", Value: "order-42"}}
+	ctx, cancel := context.WithCancel(correlation.NewContext(context.Background(), ids))
+	job, err := store.Submit(ctx, "ip:192.0.2.1", func(ctx context.Context) (jobs.Result, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", hook.URL, nil)
+		if err != nil {
+			return jobs.Result{}, err
+		}
+		correlation.FromContext(ctx).SetHeaders(req.Header)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return jobs.Result{}, err
+		}
+		resp.Body.Close()
+		return done(ctx)
+	})
+	// The request ends before the job runs
+	cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.CorrelationIDs["X-Correlation-Id"] != "order-42" {
+		t.Errorf("submitted job IDs = %v", job.CorrelationIDs)
+	}
+
+	finished := wait(t, store, job.ID)
+	if finished.Status != jobs.StatusDone || finished.CorrelationIDs["X-Correlation-Id"] != "order-42" {
+		t.Errorf("finished job = %+v", finished)
+	}
+	if header := <-received; header.Get("X-Correlation-ID") != "order-42" {
+		t.Errorf("outbound call headers = %v", header)
+	}
+
+	// Jobs of requests without IDs have none
+	job, err = store.Submit(context.Background(), "ip:192.0.2.2", done)
+	if err != nil || job.CorrelationIDs != nil {
+		t.Errorf("job without IDs = %+v, %v", job, err)
+	}
+}
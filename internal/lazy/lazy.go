@@ -0,0 +1,28 @@
+// Package lazy builds values on first use instead of at package init
+package lazy
+
+import "sync"
+
+// Lazy holds a value built by a function the first time Get is called.
+// The function runs once even under concurrent first access, and its
+// error is remembered so every caller sees the same outcome.
+type Lazy[T any] struct {
+	once  sync.Once
+	build func() (T, error)
+	value T
+	err   error
+}
+
+// New returns a Lazy that builds its value with build
+func New[T any](build func() (T, error)) *Lazy[T] {
+	return &Lazy[T]{build: build}
+}
+
+// Get returns the value, building it on the first call
+func (l *Lazy[T]) Get() (T, error) {
+	l.once.Do(func() {
+		l.value, l.err = l.build()
+		l.build = nil
+	})
+	return l.value, l.err
+}
@@ -0,0 +1,97 @@
+package lazy
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGetBuildsOnce(t *testing.T) {
+	var builds atomic.Int32
+	l := New(func() ([]int, error) {
+		builds.Add(1)
+		return []int{1, 2, 3}, nil
+	})
+
+	var wg sync.WaitGroup
+	for range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := l.Get()
+			if err != nil || len(value) != 3 {
+				t.Errorf("Get = %v, %v", value, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := builds.Load(); n != 1 {
+		t.Errorf("built %d times, want once", n)
+	}
+}
+
+func TestGetRemembersError(t *testing.T) {
+	errBroken := errors.New("broken asset")
+	builds := 0
+	l := New(func() (string, error) {
+		builds++
+		return "", errBroken
+	})
+	for range 3 {
+		if _, err := l.Get(); !errors.Is(err, errBroken) {
+			t.Errorf("err = %v, want the build error", err)
+		}
+	}
+	if builds != 1 {
+		t.Errorf("built %d times, want the error remembered", builds)
+	}
+}
+
+// expensive stands in for parsing a font or a wordlist of size bytes
+func expensive(size int) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 31)
+		}
+		return data, nil
+	}
+}
+
+var assetSizes = []struct {
+	name string
+	size int
+}{{"64KB", 64 << 10}, {"1MB", 1 << 20}, {"16MB", 16 << 20}}
+
+// BenchmarkUnused is the startup cost of an asset no request uses. It stays
+// flat whatever building the asset would cost, unlike BenchmarkFirstGet.
+func BenchmarkUnused(b *testing.B) {
+	for _, asset := range assetSizes {
+		b.Run(asset.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				New(expensive(asset.size))
+			}
+		})
+	}
+}
+
+func BenchmarkFirstGet(b *testing.B) {
+	for _, asset := range assetSizes {
+		b.Run(asset.name, func(b *testing.B) {
+			for range b.N {
+				New(expensive(asset.size)).Get()
+			}
+		})
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	l := New(expensive(1 << 20))
+	l.Get()
+	b.ResetTimer()
+	for range b.N {
+		l.Get()
+	}
+}
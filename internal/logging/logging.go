@@ -0,0 +1,98 @@
+// Package logging carries a request's structured logger in its context, so
+// handlers and services log with the ID of the request they serve. Every
+// logger it hands out masks sensitive attributes by key with the redact
+// rules, e.g. "email" keeps only the domain.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/innovelabs/microtools-go/internal/redact"
+)
+
+type contextKey struct{}
+
+// New returns a redacting logger writing to w as logfmt-style text, or as
+// one JSON object per line with asJSON
+func New(w io.Writer, asJSON bool) *slog.Logger {
+	var handler slog.Handler = slog.NewTextHandler(w, nil)
+	if asJSON {
+		handler = slog.NewJSONHandler(w, nil)
+	}
+	return slog.New(redactHandler{handler})
+}
+
+// Default returns slog.Default with redaction, for work outside a request
+func Default() *slog.Logger {
+	return Redacting(slog.Default())
+}
+
+// Redacting returns logger masking sensitive attributes, or logger itself
+// when it already does
+func Redacting(logger *slog.Logger) *slog.Logger {
+	if _, ok := logger.Handler().(redactHandler); ok {
+		return logger
+	}
+	return slog.New(redactHandler{logger.Handler()})
+}
+
+// NewContext returns a copy of ctx carrying logger
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger of ctx, or Default when the caller did not
+// set one, so services need not check where they are called from
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return Default()
+}
+
+// redactHandler masks the attributes of records and of With before they
+// reach the wrapped handler
+type redactHandler struct {
+	slog.Handler
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	masked := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		masked.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, masked)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = redactAttr(a)
+	}
+	return redactHandler{h.Handler.WithAttrs(masked)}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{h.Handler.WithGroup(name)}
+}
+
+// redactAttr applies the redact rule of a's key to its value, inside
+// groups too
+func redactAttr(a slog.Attr) slog.Attr {
+	value := a.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		group := value.Group()
+		masked := make([]any, len(group))
+		for i, member := range group {
+			masked[i] = redactAttr(member)
+		}
+		return slog.Group(a.Key, masked...)
+	}
+	if redact.IsSensitive(a.Key) {
+		return slog.String(a.Key, redact.Value(a.Key, value.String()))
+	}
+	return slog.Attr{Key: a.Key, Value: value}
+}
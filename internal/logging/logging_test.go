@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, false).With("email", "ada@example.com")
+	logger.WithGroup("req").Info("Validating",
+		"iban", "DE89 3704 0044 0532 0130 00",
+		"number", "4111 1111 1111 1111",
+		slog.Group("user", "email", "grace@example.org", "ip", "192.0.2.1"),
+		"token", "secret-value",
+		"ip", "192.0.2.1",
+	)
+	line := out.String()
+	for _, leaked := range []string{"ada@", "grace@", "370400440532", "411111", "secret-value"} {
+		if strings.Contains(line, leaked) {
+			t.Errorf("log line has %q: %s", leaked, line)
+		}
+	}
+	for _, kept := range []string{"email=***@example.com", "req.iban=DE****************3000", "req.number=************1111", "req.user.email=***@example.org", "req.user.ip=192.0.2.1", "req.ip=192.0.2.1"} {
+		if !strings.Contains(line, kept) {
+			t.Errorf("log line misses %q: %s", kept, line)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, true).With("request_id", "req-1")
+	FromContext(NewContext(context.Background(), logger)).Info("hello", "email", "ada@example.com")
+	if line := out.String(); !strings.Contains(line, `"request_id":"req-1"`) || !strings.Contains(line, `"email":"***@example.com"`) {
+		t.Errorf("log line = %s", line)
+	}
+
+	if _, ok := FromContext(context.Background()).Handler().(redactHandler); !ok {
+		t.Error("the fallback logger does not redact")
+	}
+	if Redacting(logger) != logger {
+		t.Error("a redacting logger was wrapped again")
+	}
+}
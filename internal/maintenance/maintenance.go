@@ -0,0 +1,238 @@
+// Package maintenance takes the API, or single tools, offline at runtime.
+// Windows are stored in Redis so every replica converges on the same
+// state, and end by themselves when given an end time.
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/cache"
+	"github.com/innovelabs/microtools-go/internal/clock"
+)
+
+const (
+	// RefreshInterval is how often Run reloads the state other replicas wrote
+	RefreshInterval = 5 * time.Second
+
+	// stateKey holds the JSON State in the cache
+	stateKey = "maintenance"
+	// openEndedTTL keeps a state without an end time in the cache; it is
+	// written again on every change
+	openEndedTTL = 365 * 24 * time.Hour
+	// DefaultMessage is returned when a window has no message
+	DefaultMessage = "This tool is down for maintenance"
+)
+
+// ErrUnknownTool is returned for a window on a tool that is not served
+var ErrUnknownTool = errors.New("unknown tool")
+
+// Window is one maintenance window. A zero Until lasts until it is turned
+// off. Exempt windows keep a tool served during global maintenance.
+type Window struct {
+	Message    string    `json:"message,omitempty"`
+	RetryAfter int       `json:"retryAfter,omitempty"`
+	Exempt     bool      `json:"exempt,omitempty"`
+	Since      time.Time `json:"since"`
+	Until      time.Time `json:"until,omitzero"`
+}
+
+// activeAt reports whether w has not ended at now
+func (w Window) activeAt(now time.Time) bool {
+	return w.Until.IsZero() || now.Before(w.Until)
+}
+
+// State is every maintenance window, global and per tool
+type State struct {
+	Global *Window           `json:"global,omitempty"`
+	Tools  map[string]Window `json:"tools,omitempty"`
+}
+
+// Change starts or ends the global window (Tool "") or one tool's
+type Change struct {
+	Tool   string
+	Active bool
+	// Window is the new window when Active; Since is stamped by Apply
+	Window Window
+}
+
+// Switch answers whether a tool is in maintenance from its last known
+// state. Without a cache the state is kept by this instance only.
+type Switch struct {
+	cache cache.Cache
+	clock clock.Clock
+	tools map[string]bool
+
+	mu    sync.RWMutex
+	state State
+}
+
+// NewSwitch creates a Switch for the named tools storing its state in c,
+// which may be nil
+func NewSwitch(c cache.Cache, clk clock.Clock, tools []string) *Switch {
+	known := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		known[tool] = true
+	}
+	return &Switch{cache: c, clock: clk, tools: known}
+}
+
+// Lookup returns the window keeping tool offline, if any: the tool's own
+// window unless it is exempt, else the global one. Ended windows are
+// ignored.
+func (s *Switch) Lookup(tool string) (Window, bool) {
+	now := s.clock.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if w, ok := s.state.Tools[tool]; ok && w.activeAt(now) {
+		return w, !w.Exempt
+	}
+	if g := s.state.Global; g != nil && g.activeAt(now) {
+		return *g, true
+	}
+	return Window{}, false
+}
+
+// Global returns the global window while it is active
+func (s *Switch) Global() (Window, bool) {
+	now := s.clock.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if g := s.state.Global; g != nil && g.activeAt(now) {
+		return *g, true
+	}
+	return Window{}, false
+}
+
+// State returns the windows that have not ended
+func (s *Switch) State() State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.prune(s.clock.Now())
+}
+
+// Apply makes change on top of the latest stored state and stores the
+// result, so it reaches the other replicas when they next refresh. It
+// applies to this instance at once, even when storing fails.
+func (s *Switch) Apply(ctx context.Context, change Change) (State, error) {
+	if change.Tool != "" && !s.tools[change.Tool] {
+		return State{}, ErrUnknownTool
+	}
+	if err := s.Refresh(ctx); err != nil {
+		log.Printf("Maintenance state not refreshed before a change: %v", err)
+	}
+
+	now := s.clock.Now()
+	s.mu.Lock()
+	state := s.state.prune(now)
+	if change.Active {
+		window := change.Window
+		window.Since = now
+		if change.Tool == "" {
+			state.Global = &window
+		} else {
+			state.Tools[change.Tool] = window
+		}
+	} else if change.Tool == "" {
+		state.Global = nil
+	} else {
+		delete(state.Tools, change.Tool)
+	}
+	s.state = state
+	s.mu.Unlock()
+
+	return state, s.store(ctx, state, now)
+}
+
+// Refresh loads the state stored by any replica. A missing state means
+// no maintenance.
+func (s *Switch) Refresh(ctx context.Context) error {
+	if s.cache == nil {
+		return nil
+	}
+	data, found, err := s.cache.Get(ctx, stateKey)
+	if err != nil {
+		return err
+	}
+	var state State
+	if found {
+		if err := json.Unmarshal([]byte(data), &state); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.state = state
+	s.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the state every interval until ctx is done
+func (s *Switch) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				log.Printf("Failed to refresh the maintenance state: %v", err)
+			}
+		}
+	}
+}
+
+// store writes state to the cache until its last window ends
+func (s *Switch) store(ctx context.Context, state State, now time.Time) error {
+	if s.cache == nil {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(ctx, stateKey, string(data), state.ttl(now))
+}
+
+// prune returns a copy of s without the windows that ended before now
+func (s State) prune(now time.Time) State {
+	pruned := State{Tools: make(map[string]Window, len(s.Tools))}
+	if s.Global != nil && s.Global.activeAt(now) {
+		g := *s.Global
+		pruned.Global = &g
+	}
+	for tool, w := range s.Tools {
+		if w.activeAt(now) {
+			pruned.Tools[tool] = w
+		}
+	}
+	return pruned
+}
+
+// ttl is how long the state must be kept: until its last window ends
+func (s State) ttl(now time.Time) time.Duration {
+	var last time.Time
+	windows := make([]Window, 0, len(s.Tools)+1)
+	if s.Global != nil {
+		windows = append(windows, *s.Global)
+	}
+	for _, w := range s.Tools {
+		windows = append(windows, w)
+	}
+	for _, w := range windows {
+		if w.Until.IsZero() {
+			return openEndedTTL
+		}
+		if w.Until.After(last) {
+			last = w.Until
+		}
+	}
+	if ttl := last.Sub(now); ttl > time.Second {
+		return ttl
+	}
+	return time.Second
+}
@@ -0,0 +1,107 @@
+package maintenance_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/maintenance"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+var tools = []string{"ip-validate", "iban-validate"}
+
+func TestSwitchConvergesThroughCache(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := testutil.NewCache(clock)
+	first := maintenance.NewSwitch(cache, clock, tools)
+	second := maintenance.NewSwitch(cache, clock, tools)
+
+	if _, err := first.Apply(ctx, maintenance.Change{
+		Tool:   "ip-validate",
+		Active: true,
+		Window: maintenance.Window{Message: "upgrade", Until: clock.Now().Add(time.Hour)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, down := second.Lookup("ip-validate"); down {
+		t.Fatal("second replica is down before refreshing")
+	}
+	if err := second.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	window, down := second.Lookup("ip-validate")
+	if !down || window.Message != "upgrade" {
+		t.Errorf("second replica: Lookup = %+v, %v, want the upgrade window", window, down)
+	}
+	if _, down := second.Lookup("iban-validate"); down {
+		t.Error("iban-validate is down")
+	}
+
+	// A change on the second replica keeps the first one's window
+	if _, err := second.Apply(ctx, maintenance.Change{Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	first.Refresh(ctx)
+	if _, down := first.Global(); !down {
+		t.Error("first replica misses the global window")
+	}
+	if window, _ := first.Lookup("ip-validate"); window.Message != "upgrade" {
+		t.Errorf("ip-validate window = %+v, want the tool's own", window)
+	}
+
+	// The stored state expires with its last window
+	second.Apply(ctx, maintenance.Change{Active: false})
+	clock.Advance(time.Hour)
+	first.Refresh(ctx)
+	if state := first.State(); state.Global != nil || len(state.Tools) != 0 {
+		t.Errorf("state after the window = %+v, want none", state)
+	}
+}
+
+func TestSwitchExemptTool(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	sw := maintenance.NewSwitch(nil, clock, tools)
+
+	sw.Apply(ctx, maintenance.Change{Active: true, Window: maintenance.Window{Until: clock.Now().Add(time.Minute)}})
+	sw.Apply(ctx, maintenance.Change{Tool: "ip-validate", Active: true, Window: maintenance.Window{Exempt: true}})
+	if _, down := sw.Lookup("ip-validate"); down {
+		t.Error("exempt tool is down during global maintenance")
+	}
+	if _, down := sw.Lookup("iban-validate"); !down {
+		t.Error("iban-validate is served during global maintenance")
+	}
+	clock.Advance(time.Minute)
+	if _, down := sw.Lookup("iban-validate"); down {
+		t.Error("iban-validate is down after the global window")
+	}
+
+	if _, err := sw.Apply(ctx, maintenance.Change{Tool: "qr", Active: true}); !errors.Is(err, maintenance.ErrUnknownTool) {
+		t.Errorf("Apply on an unknown tool: err = %v, want ErrUnknownTool", err)
+	}
+}
+
+func TestSwitchKeepsStateWhenCacheFails(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := testutil.NewCache(clock)
+	sw := maintenance.NewSwitch(cache, clock, tools)
+	sw.Apply(ctx, maintenance.Change{Tool: "iban-validate", Active: true})
+
+	cache.Err = errors.New("redis down")
+	if err := sw.Refresh(ctx); err == nil {
+		t.Error("Refresh with a failing cache returned nil")
+	}
+	if _, down := sw.Lookup("iban-validate"); !down {
+		t.Error("maintenance lost when the cache failed")
+	}
+	if _, err := sw.Apply(ctx, maintenance.Change{Tool: "ip-validate", Active: true}); err == nil {
+		t.Error("Apply with a failing cache returned nil")
+	}
+	if _, down := sw.Lookup("ip-validate"); !down {
+		t.Error("change not applied locally when the cache failed")
+	}
+}
@@ -1,28 +1,143 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/innovelabs/microtools-go/internal/utils"
 )
 
-// JWTAuthMiddleware validates JWT tokens
-func JWTAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Missing token", http.StatusUnauthorized)
-			return
-		}
-
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		_, err := utils.ValidateJWT(tokenString)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
+// TokenCheck rejects a verified access token of email issued at issuedAt
+// with utils.ErrTokenRevoked, e.g. because the user was deleted since.
+// Other errors mean the check could not run.
+type TokenCheck func(ctx context.Context, email string, issuedAt time.Time) error
+
+type tokenClaimsKey struct{}
+
+// TokenClaims returns the claims of the access token JWTAuthMiddleware
+// accepted for the request, or nil
+func TokenClaims(ctx context.Context) *utils.Claims {
+	claims, _ := ctx.Value(tokenClaimsKey{}).(*utils.Claims)
+	return claims
+}
+
+// TokenEmail returns the email of the access token JWTAuthMiddleware
+// accepted for the request, or ""
+func TokenEmail(ctx context.Context) string {
+	if claims := TokenClaims(ctx); claims != nil {
+		return claims.Email
+	}
+	return ""
+}
+
+// BearerToken returns the token of r's "Authorization: Bearer <token>"
+// header, the scheme matched case-insensitively, and false without one
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// Error codes of the 401 responses of JWTAuthMiddleware
+const (
+	TokenMissingErrorCode          = "TOKEN_MISSING"
+	TokenMalformedErrorCode        = "TOKEN_MALFORMED"
+	TokenInvalidSignatureErrorCode = "TOKEN_INVALID_SIGNATURE"
+	TokenExpiredErrorCode          = "TOKEN_EXPIRED"
+	TokenInvalidErrorCode          = "TOKEN_INVALID"
+	TokenRevokedErrorCode          = "TOKEN_REVOKED"
+)
+
+// JWTAuthMiddleware validates access tokens sent as "Authorization:
+// Bearer <JWT>", issued with jwtConfig, and passes their claims on in the
+// request context. check, when set, rejects tokens that were revoked.
+// Refused tokens get a 401 whose code says why.
+func JWTAuthMiddleware(jwtConfig utils.JWTConfig, check TokenCheck) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString, ok := BearerToken(r)
+			if !ok {
+				writeError(w, http.StatusUnauthorized, "missing token: send Authorization: Bearer <token>", TokenMissingErrorCode)
+				return
+			}
+
+			claims, err := utils.ValidateJWT(jwtConfig, tokenString)
+			switch {
+			case errors.Is(err, utils.ErrNoSecret):
+				writeError(w, http.StatusInternalServerError, "server configuration error", "")
+				return
+			case errors.Is(err, utils.ErrTokenMalformed):
+				writeError(w, http.StatusUnauthorized, "malformed token", TokenMalformedErrorCode)
+				return
+			case errors.Is(err, utils.ErrTokenInvalidSignature):
+				writeError(w, http.StatusUnauthorized, "invalid token signature", TokenInvalidSignatureErrorCode)
+				return
+			case errors.Is(err, utils.ErrTokenExpired):
+				writeError(w, http.StatusUnauthorized, "token expired", TokenExpiredErrorCode)
+				return
+			case err != nil:
+				writeError(w, http.StatusUnauthorized, "invalid token", TokenInvalidErrorCode)
+				return
+			}
+			if check != nil {
+				err := check(r.Context(), claims.Email, claims.IssuedAtTime())
+				if errors.Is(err, utils.ErrTokenRevoked) {
+					writeError(w, http.StatusUnauthorized, "token revoked", TokenRevokedErrorCode)
+					return
+				}
+				if err != nil {
+					log.Printf("Token check failed: %v", err)
+					writeError(w, http.StatusServiceUnavailable, "token check unavailable", "")
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenClaimsKey{}, claims)))
+		})
+	}
+}
+
+// ForbiddenErrorCode marks the 403 of AdminMiddleware
+const ForbiddenErrorCode = "FORBIDDEN"
+
+// writeError writes {"message": message, "code": code} with status,
+// leaving out an empty code
+func writeError(w http.ResponseWriter, status int, message, code string) {
+	body := map[string]string{"message": message}
+	if code != "" {
+		body["code"] = code
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// AdminMiddleware lets through requests whose access token, accepted by
+// JWTAuthMiddleware before it, belongs to one of emails, compared
+// case-insensitively. Others get a 403; with no emails every request is
+// refused.
+func AdminMiddleware(emails []string) mux.MiddlewareFunc {
+	admins := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		admins[strings.ToLower(strings.TrimSpace(email))] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			email := strings.ToLower(TokenEmail(r.Context()))
+			if email == "" || !admins[email] {
+				writeError(w, http.StatusForbidden, "admin access required", ForbiddenErrorCode)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/utils"
+)
+
+var testJWT = utils.JWTConfig{Secret: "test-secret"}
+
+func TestAdminMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		admins []string
+		email  string
+		want   int
+	}{
+		{"listed", []string{"ops@example.com"}, "ops@example.com", http.StatusNoContent},
+		{"listed in another case", []string{"Ops@Example.com"}, "ops@example.com", http.StatusNoContent},
+		{"not listed", []string{"ops@example.com"}, "someone@example.com", http.StatusForbidden},
+		{"no admins", nil, "ops@example.com", http.StatusForbidden},
+		{"no token", []string{"ops@example.com"}, "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := JWTAuthMiddleware(testJWT, nil)(AdminMiddleware(tt.admins)(ok))
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/dns-cache/flush", nil)
+			if tt.email != "" {
+				token, err := utils.GenerateJWT(testJWT, tt.email)
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWTAuthMiddlewareTokenCheck(t *testing.T) {
+	token, err := utils.GenerateJWT(testJWT, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checked string
+	var issuedAt time.Time
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := TokenClaims(r.Context())
+		if TokenEmail(r.Context()) != "ada@example.com" || claims == nil || !claims.ExpiresAtTime().After(time.Now()) {
+			t.Errorf("TokenEmail = %q, claims %+v", TokenEmail(r.Context()), claims)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name     string
+		checkErr error
+		want     int
+	}{
+		{"accepted", nil, http.StatusNoContent},
+		{"revoked", utils.ErrTokenRevoked, http.StatusUnauthorized},
+		{"check failed", errors.New("user store down"), http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		check := func(ctx context.Context, email string, at time.Time) error {
+			checked, issuedAt = email, at
+			return tt.checkErr
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/user/export", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		JWTAuthMiddleware(testJWT, check)(ok).ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Fatalf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if checked != "ada@example.com" || time.Since(issuedAt) > time.Minute {
+			t.Errorf("%s: checked %q issued at %v", tt.name, checked, issuedAt)
+		}
+	}
+}
+
+func TestJWTAuthMiddlewareErrorCodes(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	expired, err := utils.IssueToken(testJWT, "ada@example.com", utils.WithTTL(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := utils.GenerateJWT(utils.JWTConfig{Secret: "another-secret"}, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := utils.GenerateJWT(utils.JWTConfig{Secret: testJWT.Secret, Issuer: "someone-else"}, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict := utils.JWTConfig{Secret: testJWT.Secret, Issuer: "microtools"}
+	valid, err := utils.GenerateJWT(strict, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// alg none with a valid payload: the signature must be required
+	_, rest, _ := strings.Cut(valid, ".")
+	payload, _, _ := strings.Cut(rest, ".")
+	algNone := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + payload + "."
+
+	tests := []struct {
+		name, header string
+		code         string
+	}{
+		{"missing", "", TokenMissingErrorCode},
+		{"no Bearer scheme", valid, TokenMissingErrorCode},
+		{"other scheme", "Basic " + valid, TokenMissingErrorCode},
+		{"alg none", "Bearer " + algNone, TokenInvalidSignatureErrorCode},
+		{"malformed", "Bearer not-a-token", TokenMalformedErrorCode},
+		{"wrong secret", "Bearer " + forged, TokenInvalidSignatureErrorCode},
+		{"expired", "Bearer " + expired, TokenExpiredErrorCode},
+		{"wrong issuer", "Bearer " + foreign, TokenInvalidErrorCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/user/export", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			JWTAuthMiddleware(strict, nil)(ok).ServeHTTP(rec, req)
+
+			var body struct{ Code string }
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusUnauthorized || body.Code != tt.code {
+				t.Errorf("status = %d, code = %q, want 401 %q", rec.Code, body.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header, token string
+		ok            bool
+	}{
+		{"Bearer abc.def.ghi", "abc.def.ghi", true},
+		{"bearer abc.def.ghi", "abc.def.ghi", true},
+		{"abc.def.ghi", "", false},
+		{"Basic dXNlcjpwYXNz", "", false},
+		{"Bearer ", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", tt.header)
+		if token, ok := BearerToken(req); token != tt.token || ok != tt.ok {
+			t.Errorf("%q: BearerToken = %q, %v", tt.header, token, ok)
+		}
+	}
+}
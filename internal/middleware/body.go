@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Codes of the errors BodyLimitMiddleware answers, the ones the handlers
+// use for the same failures
+const (
+	bodyTooLargeErrorCode         = "BODY_TOO_LARGE"
+	unsupportedMediaTypeErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+)
+
+// BodyLimitMiddleware caps request bodies at limit bytes (0 leaves them
+// uncapped). A body declaring a larger Content-Length is refused with 413
+// before the handler runs; one that only turns out larger fails to read
+// with *http.MaxBytesError, which the handlers answer with 413 too. With
+// mediaTypes, a body of another Content-Type is refused with 415; a body
+// without one is left to the handler, which reads it as JSON.
+func BodyLimitMiddleware(limit int64, mediaTypes ...string) mux.MiddlewareFunc {
+	accepted := make(map[string]bool, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		accepted[mediaType] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit > 0 {
+				if r.ContentLength > limit {
+					writeError(w, http.StatusRequestEntityTooLarge,
+						fmt.Sprintf("request body is too large: the limit is %d bytes", limit), bodyTooLargeErrorCode)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			if ct := r.Header.Get("Content-Type"); ct != "" && len(accepted) > 0 {
+				mediaType, _, err := mime.ParseMediaType(ct)
+				if err != nil || !accepted[mediaType] {
+					writeError(w, http.StatusUnsupportedMediaType,
+						"unsupported content type: use "+strings.Join(mediaTypes, " or "), unsupportedMediaTypeErrorCode)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
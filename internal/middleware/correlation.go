@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/correlation"
+)
+
+// CorrelationMiddleware picks up the correlation IDs a client sent in
+// headers, passes them on in the request context and echoes them on the
+// response. They are set before the handler runs so error responses carry
+// them too. Values that are too long or hold unsafe characters are
+// dropped.
+func CorrelationMiddleware(headers []string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if len(headers) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ids := correlation.FromRequest(r, headers)
+			if len(ids) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ids.SetHeaders(w.Header())
+			next.ServeHTTP(w, r.WithContext(correlation.NewContext(r.Context(), ids)))
+		})
+	}
+}
@@ -1,60 +1,180 @@
 package middleware
 
 import (
-	"log"
+	"context"
 	"net/http"
+	"sort"
+	"sync/atomic"
 	"time"
 
-	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/models"
 )
 
 var counterNames = map[string]string{
-	"/api/v1/validate/email":   "email-validate",
-	"/api/v1/validate/ip":      "ip-validate",
-	"/api/v1/validate/iban":    "iban-validate",
-	"/api/v1/generate/qr":      "qr-generate",
-	"/api/v1/generate/barcode": "barcode-generate",
-	"/api/v1/live":             "live",
+	"/api/v1/validate/email":                          "email-validate",
+	"/api/v1/email/validate":                          "legacy-email-validate",
+	"/api/v1/validate/ip":                             "ip-validate",
+	"/api/v1/validate/iban":                           "iban-validate",
+	"/api/v1/validate/email/batch":                    "email-batch-validate",
+	"/api/v1/validate/ip/batch":                       "ip-batch-validate",
+	"/api/v1/validate/bankaccount":                    "bankaccount-validate",
+	"/api/v1/validate/iban/batch":                     "iban-batch-validate",
+	"/api/v1/validate/barcode":                        "barcode-validate",
+	"/api/v1/validate/card":                           "card-validate",
+	"/api/v1/iban/format":                             "iban-format",
+	"/api/v1/iban/format/{countryCode}":               "iban-format-rules",
+	"/api/v1/validate/jsonschema":                     "jsonschema-validate",
+	"/api/v1/generate/qr":                             "qr-generate",
+	"/api/v1/generate/qr/wifi-rotating":               "qr-wifi-rotating-create",
+	"/api/v1/generate/qr/wifi-rotating/{name}":        "qr-wifi-rotating",
+	"/api/v1/generate/qr/wifi-rotating/{name}/rotate": "qr-wifi-rotating-rotate",
+	"/api/v1/generate/barcode":                        "barcode-generate",
+	"/api/v1/generate/barcode/rules":                  "barcode-rules",
+	"/api/v1/generate/ics":                            "ics-generate",
+	"/api/v1/generate/token":                          "token-generate",
+	"/api/v1/generate/uuid":                           "uuid-generate",
+	"/api/v1/generate/labels":                         "labels-generate",
+	"/api/v1/shorten":                                 "url-shorten",
+	"/s/{code}":                                       "url-redirect",
+	"/api/v1/jobs/{id}":                               "job-status",
+	"/api/v1/jobs/{id}/result":                        "job-result",
+	"/api/v1/uploads":                                 "upload-create",
+	"/api/v1/uploads/{id}":                            "upload-chunk",
+	"/api/v1/uploads/{id}/complete":                   "upload-complete",
+	"/api/v1/transform/html2text":                     "html2text-transform",
+	"/api/v1/convert/text":                            "text-convert",
+	"/api/v1/convert/csv":                             "csv-convert",
+	"/api/v1/convert/timestamp":                       "timestamp-convert",
+	"/api/v1/tools/regex":                             "regex-test",
+	"/api/v1/tools/password/check":                    "password-check",
+	"/api/v1/tools/password/generate":                 "password-generate",
+	"/api/v1/extract/emails":                          "email-extract",
+	"/api/v1/parse/number":                            "number-parse",
+	"/api/v1/analyze/distance":                        "distance-analyze",
+	"/api/v1/analyze/duplicates":                      "duplicates-analyze",
+	"/api/v1/analyze/textsafety":                      "textsafety-analyze",
+	"/api/v1/analyze/qr":                              "qr-analyze",
+	"/api/v1/analyze/imagehash":                       "imagehash-analyze",
+	"/api/v1/tools":                                   "tools-spec",
+	"/api/v1/schemas":                                 "schema-list",
+	"/api/v1/schemas/{name}":                          "schema-get",
+	"/api/v1/r/{id}":                                  "share-get",
+	"/api/v1/status":                                  "status",
+	"/api/v1/stats":                                   "stats",
+	"/api/lite/v1/validate/email":                     "lite-email-validate",
+	"/api/lite/v1/validate/ip":                        "lite-ip-validate",
+	"/api/lite/v1/validate/iban":                      "lite-iban-validate",
+	"/api/lite/v1/validate/bankaccount":               "lite-bankaccount-validate",
+	"/api/lite/v1/validate/barcode":                   "lite-barcode-validate",
+	"/api/lite/v1/iban/format":                        "lite-iban-format",
+	"/api/lite/v1/iban/format/{countryCode}":          "lite-iban-format-rules",
+	"/api/lite/v1/generate/qr":                        "lite-qr-generate",
+	"/api/lite/v1/generate/barcode":                   "lite-barcode-generate",
+	"/api/lite/v1/generate/ics":                       "lite-ics-generate",
+	"/api/lite/v1/parse/number":                       "lite-number-parse",
+	"/api/lite/v1/analyze/textsafety":                 "lite-textsafety-analyze",
+	"/api/v1/datasets":                                "datasets",
+	"/api/v1/testvectors":                             "testvectors",
+	"/api/v1/ready":                                   "ready",
 }
 
-const counterBaseURL = "https://api.counterapi.dev/v2/fawaz-sullias-team-2926"
+// CounterNames returns the distinct counter names in sorted order
+func CounterNames() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, name := range counterNames {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HitCounter records one call of a named endpoint
+type HitCounter interface {
+	Hit(ctx context.Context, name string)
+}
+
+type nopHitCounter struct{}
 
-var counterHTTPClient = &http.Client{Timeout: 5 * time.Second}
+// NopHitCounter returns a HitCounter that discards hits, used when the
+// counter API is not configured
+func NopHitCounter() HitCounter {
+	return nopHitCounter{}
+}
 
-// APICounterMiddleware increments a CounterAPI.dev counter for each known endpoint
-func APICounterMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		next.ServeHTTP(w, r)
+func (nopHitCounter) Hit(context.Context, string) {}
 
-		if counterName, exists := counterNames[r.URL.Path]; exists {
-			go incrementCounter(counterName)
-		}
-	})
+// HitStats is a HitCounter that counts the hits of each counter since it
+// was created before passing them on, so the counts are known even while
+// the counter API is down. Every counter name has its own atomic counter,
+// created up front, so recording never takes a lock.
+type HitStats struct {
+	next    HitCounter
+	started time.Time
+	counts  map[string]*atomic.Int64
 }
 
-func incrementCounter(counterName string) {
-	apiKey := config.LoadConfig().CounterApiKey
-	url := counterBaseURL + "/" + counterName + "/up"
+// NewHitStats creates a HitStats passing hits on to next
+func NewHitStats(next HitCounter, c clock.Clock) *HitStats {
+	s := &HitStats{next: next, started: c.Now(), counts: map[string]*atomic.Int64{}}
+	for _, name := range CounterNames() {
+		s.counts[name] = new(atomic.Int64)
+	}
+	return s
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Printf("[counter] failed to build request for %s: %v", counterName, err)
-		return
+// Hit counts one call of the named endpoint and passes it on
+func (s *HitStats) Hit(ctx context.Context, name string) {
+	if count, ok := s.counts[name]; ok {
+		count.Add(1)
 	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	s.next.Hit(ctx, name)
+}
 
-	resp, err := counterHTTPClient.Do(req)
-	if err != nil {
-		log.Printf("[counter] failed to call %s: %v", counterName, err)
-		return
+// Snapshot returns the counts so far
+func (s *HitStats) Snapshot() models.HitStats {
+	stats := models.HitStats{Since: s.started, Counters: make(map[string]int64, len(s.counts))}
+	for name, count := range s.counts {
+		n := count.Load()
+		stats.Counters[name] = n
+		stats.Total += n
 	}
+	return stats
+}
 
-	resp.Body.Close()
+// APICounterMiddleware records a hit on counter for each known endpoint
+func APICounterMiddleware(counter HitCounter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("[counter] %s returned status %d", counterName, resp.StatusCode)
-		return
+			// CORS preflights are not API calls
+			if r.Method == http.MethodOptions {
+				return
+			}
+			if name, exists := counterName(r); exists {
+				counter.Hit(r.Context(), name)
+			}
+		})
 	}
+}
 
-	log.Printf("[counter] incremented %s", counterName)
+// counterName looks up the counter of the request path, falling back to the
+// matched route template for paths with variables
+func counterName(r *http.Request) (string, bool) {
+	if name, exists := counterNames[r.URL.Path]; exists {
+		return name, true
+	}
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			name, exists := counterNames[tmpl]
+			return name, exists
+		}
+	}
+	return "", false
 }
@@ -0,0 +1,48 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+// TestHitStats counts hits in memory and requires them passed on to the
+// counter behind it as well
+func TestHitStats(t *testing.T) {
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	forwarded := testutil.NewHitCounter()
+	stats := middleware.NewHitStats(forwarded, testutil.NewClock(started))
+	router := mux.NewRouter()
+	router.Use(middleware.APICounterMiddleware(stats))
+	respond := func(w http.ResponseWriter, r *http.Request) {}
+	router.HandleFunc("/api/v1/validate/iban", respond).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/v1/iban/format/{countryCode}", respond)
+	router.HandleFunc("/api/v1/uncounted", respond)
+
+	for _, req := range []struct{ method, path string }{
+		{"POST", "/api/v1/validate/iban"},
+		{"POST", "/api/v1/validate/iban"},
+		{"OPTIONS", "/api/v1/validate/iban"},
+		{"GET", "/api/v1/iban/format/DE"},
+		{"GET", "/api/v1/uncounted"},
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	snapshot := stats.Snapshot()
+	if snapshot.Counters["iban-validate"] != 2 || snapshot.Counters["iban-format-rules"] != 1 ||
+		snapshot.Total != 3 || !snapshot.Since.Equal(started) {
+		t.Errorf("snapshot = %+v", snapshot)
+	}
+	if _, ok := snapshot.Counters["qr-generate"]; !ok || len(snapshot.Counters) != len(middleware.CounterNames()) {
+		t.Errorf("%d counters, want every one of %d", len(snapshot.Counters), len(middleware.CounterNames()))
+	}
+	if forwarded.Hits["iban-validate"] != 2 || forwarded.Hits["iban-format-rules"] != 1 {
+		t.Errorf("forwarded %v", forwarded.Hits)
+	}
+}
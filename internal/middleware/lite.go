@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/policy"
+)
+
+// corsExposedHeaders are the response headers the widget reads
+const corsExposedHeaders = "Content-Disposition, Retry-After, Warning, X-Accessible-Name, X-Barcode-Height, X-Barcode-Width, X-Quota-Pixels-Remaining, X-RateLimit-Limit, X-RateLimit-Remaining, X-Text-Sanitized-Removed, X-Text-Sanitized-Normalized, X-Warnings"
+
+// PolicyMiddleware attaches p to each request and applies its body cap
+func PolicyMiddleware(p policy.Policy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p.MaxBodyBytes > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, p.MaxBodyBytes)
+			}
+			next.ServeHTTP(w, r.WithContext(policy.WithPolicy(r.Context(), p)))
+		})
+	}
+}
+
+// CORSMiddleware answers cross-origin requests from any origin when the
+// policy allows it. Preflight requests are answered without reaching the
+// handler.
+func CORSMiddleware(p policy.Policy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !p.AllowAnyOrigin {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitErrorCode marks a 429 from the request rate limit, as opposed
+// to an exhausted generation quota
+const RateLimitErrorCode = "RATE_LIMIT_EXCEEDED"
+
+// ClientIP returns the address of r's client
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
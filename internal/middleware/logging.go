@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/correlation"
+	"github.com/innovelabs/microtools-go/internal/logging"
+)
+
+// RequestIDHeader carries the ID every request is logged under
+const RequestIDHeader = "X-Request-ID"
+
+// LoggingMiddleware gives each request an ID, the client's X-Request-ID
+// when it is a valid correlation ID or else a random one, and echoes it
+// in the response header before the handler runs. The request context
+// carries a logger with the ID, see logging.FromContext, and one line per
+// request records its method, path, status, duration and bytes read and
+// written.
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(RequestIDHeader)
+		if !correlation.Valid(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		logger := logging.Default().With("request_id", id)
+		body := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = body
+		}
+		lw := &loggingResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(lw, r.WithContext(logging.NewContext(r.Context(), logger)))
+
+		if lw.status == 0 {
+			lw.status = http.StatusOK
+		}
+		logger.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", lw.status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"bytes_in", body.n,
+			"bytes_out", lw.n,
+		)
+	})
+}
+
+// newRequestID returns 16 random bytes in hex
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// countingReader counts the request body bytes the handler read
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// loggingResponseWriter captures the response status and body size
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	n      int64
+}
+
+func (w *loggingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *loggingResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.n += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,68 @@
+package middleware_test
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	handler := middleware.LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		logging.FromContext(r.Context()).Info("Validating email", "email", string(body))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+	send := func(requestID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/email", strings.NewReader("ada@example.com"))
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The client's ID is echoed, and the handler's line and the request
+	// line both carry it
+	rec := send("order-42")
+	if got := rec.Header().Get("X-Request-ID"); got != "order-42" {
+		t.Errorf("X-Request-ID = %q, want the client's", got)
+	}
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log lines = %q, want the handler's and the request's", lines)
+	}
+	if !strings.Contains(lines[0], "Validating email request_id=order-42 email=***@example.com") {
+		t.Errorf("handler line = %s", lines[0])
+	}
+	for _, want := range []string{"request request_id=order-42", "method=POST", "path=/api/v1/validate/email", "status=201", "duration_ms=", "bytes_in=15", "bytes_out=7"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("request line misses %q: %s", want, lines[1])
+		}
+	}
+	if strings.Contains(logs.String(), "ada@") {
+		t.Errorf("email logged unredacted: %s", logs.String())
+	}
+
+	// Without an ID, or with one unsafe to log, each request gets its own
+	first, second := send("").Header().Get("X-Request-ID"), send("id\nlevel=ERROR").Header().Get("X-Request-ID")
+	if len(first) != 32 || len(second) != 32 || first == second {
+		t.Errorf("generated IDs = %q, %q; want two different 32 character IDs", first, second)
+	}
+	if strings.Contains(logs.String(), "level=ERROR") {
+		t.Errorf("a forged ID was logged: %s", logs.String())
+	}
+}
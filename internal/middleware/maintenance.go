@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/maintenance"
+)
+
+// MaintenanceErrorCode marks a request refused because its tool is in
+// maintenance
+const MaintenanceErrorCode = "MAINTENANCE"
+
+// maintenanceExempt are the counted endpoints load balancers and operators
+// need during maintenance; /live is neither counted nor routed through
+// the middleware
+var maintenanceExempt = map[string]bool{"ready": true, "status": true, "stats": true}
+
+// MaintenanceTool returns the tool a counter name belongs to: lite and
+// legacy routes share the tool of their canonical route
+func MaintenanceTool(counter string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(counter, "lite-"), "legacy-")
+}
+
+// MaintenanceTools returns the tools that can be put in maintenance
+func MaintenanceTools() []string {
+	var tools []string
+	seen := map[string]bool{}
+	for _, name := range CounterNames() {
+		tool := MaintenanceTool(name)
+		if !maintenanceExempt[tool] && !seen[tool] {
+			seen[tool] = true
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// MaintenanceMiddleware answers requests to a tool in maintenance with 503,
+// the window's message and Retry-After. Requests already being served
+// finish; uncounted routes (pages, admin) and live, ready, status and
+// stats are never refused.
+func MaintenanceMiddleware(sw *maintenance.Switch) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name, counted := counterName(r)
+			tool := MaintenanceTool(name)
+			if !counted || maintenanceExempt[tool] || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			window, down := sw.Lookup(tool)
+			if !down {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			message := window.Message
+			if message == "" {
+				message = maintenance.DefaultMessage
+			}
+			body := map[string]interface{}{"message": message, "code": MaintenanceErrorCode, "tool": tool}
+			if !window.Until.IsZero() {
+				body["until"] = window.Until.UTC().Format(time.RFC3339)
+			}
+			if window.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(window.RetryAfter))
+			}
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(body)
+		})
+	}
+}
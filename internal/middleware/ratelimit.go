@@ -0,0 +1,261 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/clock"
+)
+
+// RateLimitGroup limits the routes under PathPrefixes with Limiter
+type RateLimitGroup struct {
+	PathPrefixes []string
+	Limiter      *RateLimiter
+}
+
+// RouteRateLimitMiddleware limits each client IP per route group. The
+// first group whose prefix matches the path applies; other paths are not
+// limited. Responses of limited routes carry X-RateLimit-Limit and
+// X-RateLimit-Remaining, and refused requests get a 429 with Retry-After.
+// With trustProxy, the client IP is read from X-Forwarded-For, see
+// TrustedClientIP.
+func RouteRateLimitMiddleware(trustProxy bool, groups ...RateLimitGroup) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limiter := groupLimiter(groups, r.URL.Path)
+			if limiter == nil || limiter.limit <= 0 || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			decision := limiter.Allow(r.Context(), TrustedClientIP(r, trustProxy))
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			if !decision.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(decision.RetryAfter.Seconds()+0.999)))
+				writeError(w, http.StatusTooManyRequests, "rate limit exceeded", RateLimitErrorCode)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func groupLimiter(groups []RateLimitGroup, path string) *RateLimiter {
+	for _, group := range groups {
+		for _, prefix := range group.PathPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				return group.Limiter
+			}
+		}
+	}
+	return nil
+}
+
+// TrustedClientIP returns the address of r's client. Behind a trusted
+// proxy it is the last valid address of X-Forwarded-For, the one the
+// proxy appended, or else X-Real-IP; addresses a client adds to the
+// header itself come before it and are ignored. Without a trusted proxy,
+// or without either header, it is ClientIP.
+func TrustedClientIP(r *http.Request, trustProxy bool) string {
+	if !trustProxy {
+		return ClientIP(r)
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if ip := parseForwardedIP(forwarded[i]); ip != "" {
+			return ip
+		}
+	}
+	if ip := parseForwardedIP(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	return ClientIP(r)
+}
+
+// parseForwardedIP returns the IP of a forwarding header entry, which may
+// carry a port and, for IPv6, brackets, or "" when it is not an address
+func parseForwardedIP(entry string) string {
+	entry = strings.TrimSpace(entry)
+	if host, _, err := net.SplitHostPort(entry); err == nil {
+		entry = host
+	}
+	ip := net.ParseIP(strings.Trim(entry, "[]"))
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// RateDecision is the outcome of RateLimiter.Allow
+type RateDecision struct {
+	Allowed bool
+	Limit   int
+	// Remaining is what is left after this request
+	Remaining int
+	// RetryAfter is how long a refused client waits before a request
+	// would be allowed
+	RetryAfter time.Duration
+}
+
+// rateScript counts a request in KEYS[1], the current window, unless the
+// sliding estimate with KEYS[2], the previous window, weighted by
+// ARGV[2] thousandths, reaches the limit ARGV[1]. It returns
+// {allowed, current, previous}; checking and counting in one script
+// keeps concurrent requests of every instance from overshooting.
+var rateScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+if previous * tonumber(ARGV[2]) / 1000 + current + 1 > tonumber(ARGV[1]) then
+	return {0, current, previous}
+end
+current = redis.call('INCR', KEYS[1])
+if current == 1 then
+	redis.call('PEXPIRE', KEYS[1], tonumber(ARGV[3]))
+end
+return {1, current, previous}
+`)
+
+// RateLimiter allows each key limit requests per sliding window: the
+// count of the current fixed window plus the previous window's, weighted
+// by how much of it the sliding window still covers. Counts are kept in
+// Redis so every instance shares them; while Redis fails, each instance
+// counts in memory instead.
+type RateLimiter struct {
+	client *redis.Client
+	prefix string
+	limit  int
+	window time.Duration
+	clock  clock.Clock
+
+	memory   *memoryWindows
+	degraded atomic.Bool
+}
+
+// NewRateLimiter creates a limiter of limit requests per window keeping
+// its counts under prefix in Redis, or in memory when client is nil. A
+// limit of 0 or less allows everything.
+func NewRateLimiter(client *redis.Client, prefix string, limit int, window time.Duration, c clock.Clock) *RateLimiter {
+	return &RateLimiter{
+		client: client,
+		prefix: prefix,
+		limit:  limit,
+		window: window,
+		clock:  c,
+		memory: &memoryWindows{counts: map[string]*windowCounts{}},
+	}
+}
+
+// Allow counts a request of key unless it is over the limit
+func (l *RateLimiter) Allow(ctx context.Context, key string) RateDecision {
+	if l.limit <= 0 {
+		return RateDecision{Allowed: true}
+	}
+	now := l.clock.Now()
+	start := now.Truncate(l.window)
+	elapsed := now.Sub(start)
+	if l.client != nil {
+		allowed, current, previous, err := l.allowRedis(ctx, key, start, elapsed)
+		if err == nil {
+			if l.degraded.Swap(false) {
+				log.Printf("Rate limiting: Redis is back, sharing counts again")
+			}
+			return l.decide(allowed, current, previous, elapsed)
+		}
+		if !l.degraded.Swap(true) {
+			log.Printf("Rate limiting: counting in memory: %v", err)
+		}
+	}
+	allowed, current, previous := l.memory.allow(key, start, l.window, l.weight(elapsed), l.limit)
+	return l.decide(allowed, current, previous, elapsed)
+}
+
+func (l *RateLimiter) allowRedis(ctx context.Context, key string, start time.Time, elapsed time.Duration) (bool, int64, int64, error) {
+	keys := []string{l.windowKey(key, start), l.windowKey(key, start.Add(-l.window))}
+	weight := int64(l.weight(elapsed) * 1000)
+	result, err := rateScript.Run(ctx, l.client, keys, l.limit, weight, (2 * l.window).Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("rate limit: %w", err)
+	}
+	return result[0] == 1, result[1], result[2], nil
+}
+
+func (l *RateLimiter) windowKey(key string, start time.Time) string {
+	return fmt.Sprintf("%sratelimit:%d:%s", l.prefix, start.UnixMilli(), key)
+}
+
+// weight is the share of the previous window the sliding window covers
+// elapsed into the current one
+func (l *RateLimiter) weight(elapsed time.Duration) float64 {
+	return float64(l.window-elapsed) / float64(l.window)
+}
+
+// decide builds the decision from the counts of the current and previous
+// windows, the current one including this request when it was allowed
+func (l *RateLimiter) decide(allowed bool, current, previous int64, elapsed time.Duration) RateDecision {
+	used := float64(previous)*l.weight(elapsed) + float64(current)
+	decision := RateDecision{Allowed: allowed, Limit: l.limit, Remaining: max(l.limit-int(used+0.999), 0)}
+	if allowed {
+		return decision
+	}
+	// The estimate falls as the previous window slides out; a current
+	// window at the limit only clears when the next one starts
+	decision.RetryAfter = l.window - elapsed
+	if free := float64(l.limit-1) - float64(current); free >= 0 && previous > 0 {
+		wait := time.Duration((float64(previous) - free) / float64(previous) * float64(l.window))
+		decision.RetryAfter = max(wait-elapsed, time.Second)
+	}
+	return decision
+}
+
+// windowCounts are a key's requests in the window starting at start and
+// the one before it
+type windowCounts struct {
+	start             time.Time
+	current, previous int64
+}
+
+// memoryWindows counts requests for one instance while Redis is down
+type memoryWindows struct {
+	mu        sync.Mutex
+	counts    map[string]*windowCounts
+	lastSweep time.Time
+}
+
+func (m *memoryWindows) allow(key string, start time.Time, window time.Duration, weight float64, limit int) (bool, int64, int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Drop keys idle for two windows so one-off clients don't accumulate
+	if start.After(m.lastSweep) {
+		for k, c := range m.counts {
+			if start.Sub(c.start) > window {
+				delete(m.counts, k)
+			}
+		}
+		m.lastSweep = start
+	}
+
+	c, ok := m.counts[key]
+	switch {
+	case !ok || start.Sub(c.start) > window:
+		c = &windowCounts{start: start}
+		m.counts[key] = c
+	case start.After(c.start):
+		c.start, c.previous, c.current = start, c.current, 0
+	}
+	if float64(c.previous)*weight+float64(c.current)+1 > float64(limit) {
+		return false, c.current, c.previous
+	}
+	c.current++
+	return true, c.current, c.previous
+}
@@ -0,0 +1,231 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/tenant"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+func newRedisLimiter(t *testing.T, limit int) (*middleware.RateLimiter, *miniredis.Miniredis, *testutil.Clock) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	clock := testutil.NewClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	return middleware.NewRateLimiter(client, "test:", limit, time.Minute, clock), server, clock
+}
+
+func TestRateLimiterWindowRollover(t *testing.T) {
+	limiter, server, clock := newRedisLimiter(t, 3)
+	ctx := context.Background()
+	for i, s := range []struct {
+		advance    time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{0, true, 2, 0},
+		{0, true, 1, 0},
+		{0, true, 0, 0},
+		// The current window is full until it ends
+		{15 * time.Second, false, 0, 45 * time.Second},
+		// The previous window still counts fully at the start of the next
+		// one; one request fits once a third of it has slid out
+		{45 * time.Second, false, 0, 20 * time.Second},
+		{30 * time.Second, true, 0, 0},
+		{0, false, 0, 10 * time.Second},
+		// Two windows later nothing is left
+		{2 * time.Minute, true, 2, 0},
+	} {
+		clock.Advance(s.advance)
+		got := limiter.Allow(ctx, "192.0.2.1")
+		if got.Allowed != s.allowed || got.Remaining != s.remaining || got.RetryAfter != s.retryAfter {
+			t.Errorf("request %d = %+v, want allowed %v, remaining %d, retry after %s", i, got, s.allowed, s.remaining, s.retryAfter)
+		}
+	}
+
+	if got := limiter.Allow(ctx, "192.0.2.2"); !got.Allowed || got.Remaining != 2 {
+		t.Errorf("another client = %+v, want its own limit", got)
+	}
+	if keys := server.Keys(); len(keys) == 0 {
+		t.Error("no counts were kept in Redis")
+	}
+}
+
+func TestRateLimiterSharedAcrossInstances(t *testing.T) {
+	first, server, clock := newRedisLimiter(t, 2)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	second := middleware.NewRateLimiter(client, "test:", 2, time.Minute, clock)
+
+	ctx := context.Background()
+	first.Allow(ctx, "192.0.2.1")
+	second.Allow(ctx, "192.0.2.1")
+	if got := first.Allow(ctx, "192.0.2.1"); got.Allowed {
+		t.Errorf("third request across instances = %+v, want refused", got)
+	}
+}
+
+func TestRateLimiterMemoryFallback(t *testing.T) {
+	limiter, server, _ := newRedisLimiter(t, 2)
+	ctx := context.Background()
+
+	server.Close()
+	for i, want := range []bool{true, true, false} {
+		if got := limiter.Allow(ctx, "192.0.2.1"); got.Allowed != want {
+			t.Errorf("request %d without Redis: allowed = %v, want %v", i, got.Allowed, want)
+		}
+	}
+
+	// Once Redis is back its counts apply again; the requests counted in
+	// memory meanwhile are not in them
+	if err := server.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	if got := limiter.Allow(ctx, "192.0.2.1"); !got.Allowed || got.Remaining != 1 {
+		t.Errorf("request after Redis is back = %+v, want allowed with 1 remaining", got)
+	}
+}
+
+func TestRateLimiterMemoryOnly(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	limiter := middleware.NewRateLimiter(nil, "", 1, time.Minute, clock)
+	ctx := context.Background()
+	if !limiter.Allow(ctx, "192.0.2.1").Allowed {
+		t.Error("first request refused")
+	}
+	if limiter.Allow(ctx, "192.0.2.1").Allowed {
+		t.Error("second request allowed")
+	}
+	clock.Advance(2 * time.Minute)
+	if !limiter.Allow(ctx, "192.0.2.1").Allowed {
+		t.Error("request two windows later refused")
+	}
+
+	unlimited := middleware.NewRateLimiter(nil, "", 0, time.Minute, clock)
+	for range 3 {
+		if !unlimited.Allow(ctx, "192.0.2.1").Allowed {
+			t.Fatal("a limit of 0 refused a request")
+		}
+	}
+}
+
+func TestRouteRateLimitMiddleware(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	limited := middleware.RouteRateLimitMiddleware(true, middleware.RateLimitGroup{
+		PathPrefixes: []string{"/api/v1/validate/"},
+		Limiter:      middleware.NewRateLimiter(nil, "", 1, time.Minute, clock),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	send := func(path, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = "10.0.0.1:4242"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		limited.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("/api/v1/validate/email", "192.0.2.1")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("X-RateLimit-Remaining") != "0" || rec.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Errorf("first request: status %d, headers %v", rec.Code, rec.Header())
+	}
+	rec = send("/api/v1/validate/ip", "192.0.2.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("second request: status %d, headers %v", rec.Code, rec.Header())
+	}
+	if want := `{"code":"RATE_LIMIT_EXCEEDED","message":"rate limit exceeded"}` + "\n"; rec.Body.String() != want {
+		t.Errorf("second request body = %s, want %s", rec.Body, want)
+	}
+
+	// Clients behind the same proxy have their own limits, and routes
+	// outside the groups are not limited
+	if rec := send("/api/v1/validate/email", "192.0.2.2"); rec.Code != http.StatusNoContent {
+		t.Errorf("another forwarded client: status %d", rec.Code)
+	}
+	if rec := send("/api/v1/status", "192.0.2.1"); rec.Code != http.StatusNoContent || rec.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("unlimited route: status %d, headers %v", rec.Code, rec.Header())
+	}
+}
+
+// TestTenantRateLimitMiddleware checks that a tenant's limit is per
+// forwarded client and shared by the instances through Redis
+func TestTenantRateLimitMiddleware(t *testing.T) {
+	server := miniredis.RunT(t)
+	clock := testutil.NewClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	var instances []http.Handler
+	for range 2 {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		limiters := middleware.NewTenantLimiters(client, "test:tenants:", clock)
+		instances = append(instances, middleware.TenantRateLimitMiddleware(limiters, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})))
+	}
+	acme := models.Tenant{ID: "acme", RequestsPerMinute: 2}
+
+	send := func(instance http.Handler, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/validate/email", nil)
+		req.RemoteAddr = "10.0.0.1:4242"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req = req.WithContext(tenant.NewContext(req.Context(), acme))
+		rec := httptest.NewRecorder()
+		instance.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		if code := send(instances[i%2], "192.0.2.1"); code != want {
+			t.Errorf("request %d: status %d, want %d", i, code, want)
+		}
+	}
+	if code := send(instances[0], "192.0.2.2"); code != http.StatusNoContent {
+		t.Errorf("another client behind the proxy: status %d, want its own limit", code)
+	}
+	if len(server.Keys()) == 0 {
+		t.Error("no counts were kept in Redis")
+	}
+}
+
+func TestTrustedClientIP(t *testing.T) {
+	tests := []struct {
+		name                             string
+		remoteAddr, forwardedFor, realIP string
+		trustProxy                       bool
+		want                             string
+	}{
+		{"remote address", "192.0.2.1:4242", "", "", false, "192.0.2.1"},
+		{"headers ignored without a trusted proxy", "10.0.0.1:4242", "192.0.2.1", "192.0.2.2", false, "10.0.0.1"},
+		{"forwarded for", "10.0.0.1:4242", "192.0.2.1", "", true, "192.0.2.1"},
+		{"last hop of a chain", "10.0.0.1:4242", "198.51.100.7, 192.0.2.1", "", true, "192.0.2.1"},
+		{"invalid last hop skipped", "10.0.0.1:4242", "192.0.2.1, unknown", "", true, "192.0.2.1"},
+		{"forwarded IPv6 with port", "10.0.0.1:4242", "[2001:db8::1]:443", "", true, "2001:db8::1"},
+		{"real IP", "10.0.0.1:4242", "", "192.0.2.3", true, "192.0.2.3"},
+		{"no headers", "[2001:db8::2]:4242", "", "", true, "2001:db8::2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := middleware.TrustedClientIP(req, tt.trustProxy); got != tt.want {
+				t.Errorf("TrustedClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/examples"
+)
+
+// recordingResponseWriter captures the status and the start of the body
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingResponseWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *recordingResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	if remaining := examples.MaxBodyBytes*4 - rw.body.Len(); remaining > 0 {
+		if len(b) > remaining {
+			rw.body.Write(b[:remaining])
+		} else {
+			rw.body.Write(b)
+		}
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// ExampleRecorderMiddleware records one sanitized request/response example
+// per API route and status into fixture files under dir. It is meant for
+// development only and is never installed in production.
+func ExampleRecorderMiddleware(dir string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var reqBody []byte
+			if r.Body != nil {
+				reqBody, _ = io.ReadAll(io.LimitReader(r.Body, examples.MaxBodyBytes*4))
+				r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(reqBody), r.Body))
+			}
+
+			rw := &recordingResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r)
+
+			path := r.URL.Path
+			if route := mux.CurrentRoute(r); route != nil {
+				if tmpl, err := route.GetPathTemplate(); err == nil {
+					path = tmpl
+				}
+			}
+
+			err := examples.Record(dir, examples.Example{
+				Method:              r.Method,
+				Path:                path,
+				Status:              rw.status,
+				RequestContentType:  r.Header.Get("Content-Type"),
+				Request:             string(reqBody),
+				ResponseContentType: rw.Header().Get("Content-Type"),
+				Response:            rw.body.String(),
+				RecordedAt:          time.Now().UTC(),
+			})
+			if err != nil {
+				log.Printf("[examples] failed to record %s %s: %v", r.Method, path, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/innovelabs/microtools-go/internal/resultmeta"
+)
+
+// ResultMetaMiddleware attaches a result metadata collector to requests
+// with ?include_meta=true. Handlers that support it then return a "meta"
+// object next to their result.
+func ResultMetaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if include, _ := strconv.ParseBool(r.URL.Query().Get("include_meta")); include {
+			r = r.WithContext(resultmeta.NewContext(r.Context(), resultmeta.New()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/toolstatus"
+)
+
+// statusResponseWriter captures the response status and whether writing
+// the body failed
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status   int
+	writeErr error
+}
+
+func (rw *statusResponseWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *statusResponseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	if err != nil && rw.writeErr == nil {
+		rw.writeErr = err
+	}
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// ToolStatusMiddleware records the outcome and latency of every request to
+// a counted endpoint in tracker, under its counter name. 5xx responses
+// count as failures; client errors do not. A request whose body write
+// failed or whose client went away is a client disconnect.
+func ToolStatusMiddleware(tracker *toolstatus.Tracker) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &statusResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r)
+
+			if r.Method == http.MethodOptions {
+				return
+			}
+			if name, exists := counterName(r); exists {
+				tracker.Record(name, requestOutcome(r, rw), time.Since(start))
+			}
+		})
+	}
+}
+
+func requestOutcome(r *http.Request, rw *statusResponseWriter) toolstatus.Outcome {
+	switch {
+	case rw.writeErr != nil, r.Context().Err() != nil:
+		return toolstatus.OutcomeClientDisconnected
+	case rw.status >= http.StatusInternalServerError:
+		return toolstatus.OutcomeFailed
+	default:
+		return toolstatus.OutcomeOK
+	}
+}
@@ -0,0 +1,57 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+	"github.com/innovelabs/microtools-go/internal/toolstatus"
+)
+
+func TestToolStatusMiddleware(t *testing.T) {
+	tracker := toolstatus.NewTracker(testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+		[]string{"iban-validate", "iban-format-rules"})
+	router := mux.NewRouter()
+	router.Use(middleware.ToolStatusMiddleware(tracker))
+	// The handler answers with the status of the X-Test-Status header
+	respond := func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.Header.Get("X-Test-Status"))
+		w.WriteHeader(status)
+	}
+	router.HandleFunc("/api/v1/validate/iban", respond).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/v1/iban/format/{countryCode}", respond)
+	router.HandleFunc("/api/v1/uncounted", respond)
+
+	send := func(method, path string, status int, ctx context.Context) {
+		req := httptest.NewRequest(method, path, nil).WithContext(ctx)
+		req.Header.Set("X-Test-Status", strconv.Itoa(status))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for _, status := range []int{200, 200, 400, 422, 500, 503} {
+		send("POST", "/api/v1/validate/iban", status, context.Background())
+	}
+	send("OPTIONS", "/api/v1/validate/iban", 500, context.Background())
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	send("POST", "/api/v1/validate/iban", 500, cancelled)
+	send("GET", "/api/v1/iban/format/DE", 200, context.Background())
+	send("GET", "/api/v1/uncounted", 500, context.Background())
+
+	report := tracker.Report()
+	if len(report.Tools) != 2 {
+		t.Fatalf("tools = %+v", report.Tools)
+	}
+	rules, iban := report.Tools[0], report.Tools[1]
+	if iban.Tool != "iban-validate" || iban.Requests != 6 || iban.Errors != 2 || iban.ClientDisconnects != 1 {
+		t.Errorf("iban-validate = %+v, want 6 requests with the two 5xx failed and one disconnect", iban)
+	}
+	if rules.Tool != "iban-format-rules" || rules.Requests != 1 || rules.Errors != 0 {
+		t.Errorf("iban-format-rules = %+v, want the templated route counted", rules)
+	}
+}
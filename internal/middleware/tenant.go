@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/tenant"
+	"github.com/innovelabs/microtools-go/internal/utils"
+)
+
+// TenantMiddleware carries the request's tenant in its context: the
+// tenant of a valid access token, else the tenant
+// serving the Host, else tenant.Default. A bad token is left for
+// JWTAuthMiddleware to refuse.
+func TenantMiddleware(registry *tenant.Registry, jwtConfig utils.JWTConfig) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := ""
+			if token, ok := BearerToken(r); ok {
+				id = utils.TokenTenant(jwtConfig, token)
+			}
+			t := registry.Resolve(r.Context(), id, r.Host)
+			next.ServeHTTP(w, r.WithContext(tenant.NewContext(r.Context(), t)))
+		})
+	}
+}
+
+// tenantLimiter is the rate limiter of one tenant, replaced when the
+// tenant's limit changes
+type tenantLimiter struct {
+	limit   int
+	limiter *RateLimiter
+}
+
+// TenantLimiters holds the rate limiter of every tenant. Like RateLimiter
+// it keeps the counts in Redis, under prefix followed by the tenant ID,
+// so every instance shares them, or in memory when client is nil.
+type TenantLimiters struct {
+	client *redis.Client
+	prefix string
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	limiters map[string]tenantLimiter
+}
+
+// NewTenantLimiters creates the tenant rate limiters
+func NewTenantLimiters(client *redis.Client, prefix string, c clock.Clock) *TenantLimiters {
+	return &TenantLimiters{client: client, prefix: prefix, clock: c, limiters: map[string]tenantLimiter{}}
+}
+
+// limiter returns the limiter of tenant id for its current limit
+func (t *TenantLimiters) limiter(id string, limit int) *RateLimiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.limiters[id]
+	if !ok || l.limit != limit {
+		l = tenantLimiter{limit: limit, limiter: NewRateLimiter(t.client, t.prefix+id+":", limit, time.Minute, t.clock)}
+		t.limiters[id] = l
+	}
+	return l.limiter
+}
+
+// TenantRateLimitMiddleware limits each client IP to the requests per
+// minute of its tenant on the counted API routes. Every tenant has its own
+// windows, so one tenant's traffic never uses up another's limit; tenants
+// without a limit, the default one included, are not limited. With
+// trustProxy, the client IP is read from X-Forwarded-For, see
+// TrustedClientIP.
+func TenantRateLimitMiddleware(limiters *TenantLimiters, trustProxy bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t := tenant.FromContext(r.Context())
+			if t.RequestsPerMinute <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, counted := counterName(r); !counted {
+				next.ServeHTTP(w, r)
+				return
+			}
+			decision := limiters.limiter(t.ID, t.RequestsPerMinute).Allow(r.Context(), TrustedClientIP(r, trustProxy))
+			if !decision.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(decision.RetryAfter.Seconds()+0.999)))
+				writeError(w, http.StatusTooManyRequests, "rate limit exceeded", RateLimitErrorCode)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
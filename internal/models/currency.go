@@ -0,0 +1,79 @@
+package models
+
+// Currency is an ISO 4217 currency
+type Currency struct {
+	Code        string
+	NumericCode string
+	Name        string
+	MinorUnits  int
+}
+
+// ISO4217Currencies contains the active ISO 4217 currencies in common use
+var ISO4217Currencies = map[string]Currency{
+	"AED": {Code: "AED", NumericCode: "784", Name: "UAE Dirham", MinorUnits: 2},
+	"ARS": {Code: "ARS", NumericCode: "032", Name: "Argentine Peso", MinorUnits: 2},
+	"AUD": {Code: "AUD", NumericCode: "036", Name: "Australian Dollar", MinorUnits: 2},
+	"AZN": {Code: "AZN", NumericCode: "944", Name: "Azerbaijan Manat", MinorUnits: 2},
+	"BDT": {Code: "BDT", NumericCode: "050", Name: "Taka", MinorUnits: 2},
+	"BGN": {Code: "BGN", NumericCode: "975", Name: "Bulgarian Lev", MinorUnits: 2},
+	"BHD": {Code: "BHD", NumericCode: "048", Name: "Bahraini Dinar", MinorUnits: 3},
+	"BRL": {Code: "BRL", NumericCode: "986", Name: "Brazilian Real", MinorUnits: 2},
+	"CAD": {Code: "CAD", NumericCode: "124", Name: "Canadian Dollar", MinorUnits: 2},
+	"CHF": {Code: "CHF", NumericCode: "756", Name: "Swiss Franc", MinorUnits: 2},
+	"CLP": {Code: "CLP", NumericCode: "152", Name: "Chilean Peso", MinorUnits: 0},
+	"CNY": {Code: "CNY", NumericCode: "156", Name: "Yuan Renminbi", MinorUnits: 2},
+	"COP": {Code: "COP", NumericCode: "170", Name: "Colombian Peso", MinorUnits: 2},
+	"CRC": {Code: "CRC", NumericCode: "188", Name: "Costa Rican Colon", MinorUnits: 2},
+	"CZK": {Code: "CZK", NumericCode: "203", Name: "Czech Koruna", MinorUnits: 2},
+	"DKK": {Code: "DKK", NumericCode: "208", Name: "Danish Krone", MinorUnits: 2},
+	"EGP": {Code: "EGP", NumericCode: "818", Name: "Egyptian Pound", MinorUnits: 2},
+	"EUR": {Code: "EUR", NumericCode: "978", Name: "Euro", MinorUnits: 2},
+	"GBP": {Code: "GBP", NumericCode: "826", Name: "Pound Sterling", MinorUnits: 2},
+	"GEL": {Code: "GEL", NumericCode: "981", Name: "Lari", MinorUnits: 2},
+	"GHS": {Code: "GHS", NumericCode: "936", Name: "Ghana Cedi", MinorUnits: 2},
+	"HKD": {Code: "HKD", NumericCode: "344", Name: "Hong Kong Dollar", MinorUnits: 2},
+	"HUF": {Code: "HUF", NumericCode: "348", Name: "Forint", MinorUnits: 2},
+	"IDR": {Code: "IDR", NumericCode: "360", Name: "Rupiah", MinorUnits: 2},
+	"ILS": {Code: "ILS", NumericCode: "376", Name: "New Israeli Sheqel", MinorUnits: 2},
+	"INR": {Code: "INR", NumericCode: "356", Name: "Indian Rupee", MinorUnits: 2},
+	"ISK": {Code: "ISK", NumericCode: "352", Name: "Iceland Krona", MinorUnits: 0},
+	"JOD": {Code: "JOD", NumericCode: "400", Name: "Jordanian Dinar", MinorUnits: 3},
+	"JPY": {Code: "JPY", NumericCode: "392", Name: "Yen", MinorUnits: 0},
+	"KES": {Code: "KES", NumericCode: "404", Name: "Kenyan Shilling", MinorUnits: 2},
+	"KRW": {Code: "KRW", NumericCode: "410", Name: "Won", MinorUnits: 0},
+	"KWD": {Code: "KWD", NumericCode: "414", Name: "Kuwaiti Dinar", MinorUnits: 3},
+	"KZT": {Code: "KZT", NumericCode: "398", Name: "Tenge", MinorUnits: 2},
+	"LKR": {Code: "LKR", NumericCode: "144", Name: "Sri Lanka Rupee", MinorUnits: 2},
+	"MAD": {Code: "MAD", NumericCode: "504", Name: "Moroccan Dirham", MinorUnits: 2},
+	"MUR": {Code: "MUR", NumericCode: "480", Name: "Mauritius Rupee", MinorUnits: 2},
+	"MXN": {Code: "MXN", NumericCode: "484", Name: "Mexican Peso", MinorUnits: 2},
+	"MYR": {Code: "MYR", NumericCode: "458", Name: "Malaysian Ringgit", MinorUnits: 2},
+	"NGN": {Code: "NGN", NumericCode: "566", Name: "Naira", MinorUnits: 2},
+	"NOK": {Code: "NOK", NumericCode: "578", Name: "Norwegian Krone", MinorUnits: 2},
+	"NPR": {Code: "NPR", NumericCode: "524", Name: "Nepalese Rupee", MinorUnits: 2},
+	"NZD": {Code: "NZD", NumericCode: "554", Name: "New Zealand Dollar", MinorUnits: 2},
+	"OMR": {Code: "OMR", NumericCode: "512", Name: "Rial Omani", MinorUnits: 3},
+	"PEN": {Code: "PEN", NumericCode: "604", Name: "Sol", MinorUnits: 2},
+	"PHP": {Code: "PHP", NumericCode: "608", Name: "Philippine Peso", MinorUnits: 2},
+	"PKR": {Code: "PKR", NumericCode: "586", Name: "Pakistan Rupee", MinorUnits: 2},
+	"PLN": {Code: "PLN", NumericCode: "985", Name: "Zloty", MinorUnits: 2},
+	"PYG": {Code: "PYG", NumericCode: "600", Name: "Guarani", MinorUnits: 0},
+	"QAR": {Code: "QAR", NumericCode: "634", Name: "Qatari Rial", MinorUnits: 2},
+	"RON": {Code: "RON", NumericCode: "946", Name: "Romanian Leu", MinorUnits: 2},
+	"RSD": {Code: "RSD", NumericCode: "941", Name: "Serbian Dinar", MinorUnits: 2},
+	"RUB": {Code: "RUB", NumericCode: "643", Name: "Russian Ruble", MinorUnits: 2},
+	"SAR": {Code: "SAR", NumericCode: "682", Name: "Saudi Riyal", MinorUnits: 2},
+	"SEK": {Code: "SEK", NumericCode: "752", Name: "Swedish Krona", MinorUnits: 2},
+	"SGD": {Code: "SGD", NumericCode: "702", Name: "Singapore Dollar", MinorUnits: 2},
+	"THB": {Code: "THB", NumericCode: "764", Name: "Baht", MinorUnits: 2},
+	"TND": {Code: "TND", NumericCode: "788", Name: "Tunisian Dinar", MinorUnits: 3},
+	"TRY": {Code: "TRY", NumericCode: "949", Name: "Turkish Lira", MinorUnits: 2},
+	"TWD": {Code: "TWD", NumericCode: "901", Name: "New Taiwan Dollar", MinorUnits: 2},
+	"UAH": {Code: "UAH", NumericCode: "980", Name: "Hryvnia", MinorUnits: 2},
+	"USD": {Code: "USD", NumericCode: "840", Name: "US Dollar", MinorUnits: 2},
+	"UYU": {Code: "UYU", NumericCode: "858", Name: "Peso Uruguayo", MinorUnits: 2},
+	"VND": {Code: "VND", NumericCode: "704", Name: "Dong", MinorUnits: 0},
+	"XAF": {Code: "XAF", NumericCode: "950", Name: "CFA Franc BEAC", MinorUnits: 0},
+	"XOF": {Code: "XOF", NumericCode: "952", Name: "CFA Franc BCEAO", MinorUnits: 0},
+	"ZAR": {Code: "ZAR", NumericCode: "710", Name: "Rand", MinorUnits: 2},
+}
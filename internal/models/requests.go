@@ -1,18 +1,62 @@
 package models
 
+import "encoding/json"
+
 // EmailRequest represents an email validation request
 type EmailRequest struct {
 	Email string `json:"email"`
+	// Checks selects the validation stages to run; empty runs all of them
+	Checks []string `json:"checks,omitempty"`
+	// SignResponse adds a detached JWS over the result (access token required)
+	SignResponse bool `json:"sign_response"`
+	// Resolver "doh" resolves the domain over DNS-over-HTTPS instead of the
+	// configured resolver (access token required)
+	Resolver string `json:"resolver,omitempty"`
+	// Share stores the redacted result for 30 days and returns its share_url
+	Share bool `json:"share"`
+	// CheckSMTP asks the domain's mail server whether it accepts mail for
+	// the address, reported in EmailValidation.SMTP
+	CheckSMTP bool `json:"check_smtp"`
 }
 
 // IPRequest represents an IP validation/geolocation request
 type IPRequest struct {
 	IP string `json:"ip"`
+	// Share stores the redacted result for 30 days and returns its share_url
+	Share bool `json:"share"`
 }
 
 // IBANRequest represents an IBAN validation request
 type IBANRequest struct {
 	IBAN string `json:"iban"`
+	// SignResponse adds a detached JWS over the result (access token required)
+	SignResponse bool `json:"sign_response"`
+	// Share stores the redacted result for 30 days and returns its share_url
+	Share bool `json:"share"`
+}
+
+// BankAccountRequest represents a domestic bank account validation
+// request. BankCode is the US ABA routing number, UK sort code or Canadian
+// transit number, depending on Country.
+type BankAccountRequest struct {
+	Country       string `json:"country"`
+	BankCode      string `json:"bank_code"`
+	AccountNumber string `json:"account_number"`
+	// Share stores the redacted result for 30 days and returns its share_url
+	Share bool `json:"share"`
+}
+
+// CardRequest represents a payment card number validation request
+type CardRequest struct {
+	Number string `json:"number"`
+}
+
+// BarcodeValidateRequest represents a scanned barcode value to classify
+// and check. Symbology is an optional hint (UPC-A, EAN-13, EAN-8, ITF-14,
+// Code39 or GS1-128) that ranks its interpretation first.
+type BarcodeValidateRequest struct {
+	Value     string `json:"value"`
+	Symbology string `json:"symbology,omitempty"`
 }
 
 // UserRequest represents a user registration request
@@ -23,10 +67,33 @@ type UserRequest struct {
 	Country string `json:"country"`
 }
 
+// UserTokenRequest asks for a fresh access token for a registered user
+type UserTokenRequest struct {
+	Email string `json:"email"`
+}
+
 // QROptions represents QR code generation options
 type QROptions struct {
 	Size            int    `json:"size"`
 	ErrorCorrection string `json:"error_correction"`
+	SanitizeText    bool   `json:"sanitize_text"`
+	// Format is "png" (default), "svg" or "datauri", a base64 PNG data URL
+	Format string `json:"format"`
+	// Output is "binary" (default), the image itself, or "json", the
+	// image base64-encoded in a GeneratedImage
+	Output string `json:"output"`
+	// Deterministic writes PNGs whose bytes depend only on the modules
+	Deterministic bool `json:"deterministic"`
+	// Title and Desc are the accessible name and description of SVG
+	// output; they default to the type and a redacted form of the data
+	Title string `json:"title"`
+	Desc  string `json:"desc"`
+	// Logo is a base64 PNG or JPEG drawn over the centre of PNG output.
+	// It raises error correction to H.
+	Logo string `json:"logo"`
+	// LogoSizePercent is the logo's width in percent of the code's, 5 to
+	// 25; 20 by default
+	LogoSizePercent int `json:"logo_size_percent"`
 }
 
 // QRRequest represents a QR code generation request
@@ -59,18 +126,354 @@ type EventData struct {
 	End     string `json:"end"`
 }
 
+// TokenRequest represents a secret token generation request
+type TokenRequest struct {
+	Mode  string `json:"mode"`
+	Words int    `json:"words"`
+	// Separator is nil when omitted so an explicit "" joins words directly
+	Separator  *string `json:"separator"`
+	Capitalize bool    `json:"capitalize"`
+	AddNumber  bool    `json:"add_number"`
+	Language   string  `json:"language"`
+	// Length and Charset shape random mode tokens
+	Length  int    `json:"length"`
+	Charset string `json:"charset"`
+}
+
+// UUIDRequest asks for Count identifiers of Version: "v4", "v7" or "ulid"
+type UUIDRequest struct {
+	Version   string `json:"version"`
+	Count     int    `json:"count"`
+	Uppercase bool   `json:"uppercase"`
+	// Format is "standard", "compact" (no dashes) or "urn"
+	Format string `json:"format"`
+}
+
+// ICSRequest represents a calendar file generation request
+type ICSRequest struct {
+	Events   []ICSEvent `json:"events"`
+	Filename string     `json:"filename"`
+}
+
+// ICSEvent represents one calendar event. Start and End are RFC 3339
+// timestamps, local "2006-01-02T15:04:05" times in Timezone, or
+// "2006-01-02" dates for all-day events.
+type ICSEvent struct {
+	UID         string     `json:"uid"`
+	Summary     string     `json:"summary"`
+	Description string     `json:"description"`
+	Location    string     `json:"location"`
+	Start       string     `json:"start"`
+	End         string     `json:"end"`
+	AllDay      bool       `json:"all_day"`
+	Timezone    string     `json:"timezone"`
+	RRule       string     `json:"rrule"`
+	ExDates     []string   `json:"exdates"`
+	Alarms      []ICSAlarm `json:"alarms"`
+}
+
+// ICSAlarm represents a display reminder before an event starts
+type ICSAlarm struct {
+	MinutesBefore int    `json:"minutes_before"`
+	Description   string `json:"description"`
+}
+
 // GenerateRequest represents a barcode generation request
 type GenerateRequest struct {
-	Data              string `json:"data"`
-	Type              string `json:"type"`
-	Format            string `json:"format"`
-	Width             int    `json:"width"`
-	Height            int    `json:"height"`
-	IncludeText       bool   `json:"include_text"`
-	BackgroundColor   string `json:"background_color"`
-	ForegroundColor   string `json:"foreground_color"`
-	TextColor         string `json:"text_color"`
-	TextPosition      string `json:"text_position"`
-	FontSize          int    `json:"font_size"`
-	Padding           int    `json:"padding"`
+	Data            string `json:"data"`
+	Type            string `json:"type"`
+	Format          string `json:"format"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	IncludeText     bool   `json:"include_text"`
+	Text            string `json:"text"`
+	BackgroundColor string `json:"background_color"`
+	ForegroundColor string `json:"foreground_color"`
+	TextColor       string `json:"text_color"`
+	TextPosition    string `json:"text_position"`
+	FontSize        int    `json:"font_size"`
+	Font            string `json:"font"`
+	Padding         int    `json:"padding"`
+	Supplement      string `json:"supplement"`
+	SanitizeText    bool   `json:"sanitize_text"`
+	FitMode         string `json:"fit_mode"`
+	// IncludeCheckDigit appends the optional mod 43 check character to
+	// Code39; the check digits of the other types are always encoded
+	IncludeCheckDigit bool `json:"include_check_digit"`
+	// Deterministic writes PNGs whose bytes depend only on the pixels
+	Deterministic bool `json:"deterministic"`
+	// Output is "binary" (default) or "json", as in QROptions
+	Output string `json:"output"`
+	// Title and Desc are the accessible name and description of SVG
+	// output; they default to the type and a redacted form of the data
+	Title string `json:"title"`
+	Desc  string `json:"desc"`
+	// MaxWidth caps the snapped width below the generator maximum; the
+	// handler sets it from the route policy
+	MaxWidth int `json:"-"`
+}
+
+// TextSafetyRequest represents a text safety analysis request
+type TextSafetyRequest struct {
+	Inputs []string `json:"inputs"`
+}
+
+// GeoPointInput represents a point given either as coordinates or as an IP to geolocate
+type GeoPointInput struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	IP        string   `json:"ip"`
+}
+
+// DistanceRequest represents a distance or geofence analysis request
+type DistanceRequest struct {
+	Mode     string          `json:"mode"`
+	From     GeoPointInput   `json:"from"`
+	To       GeoPointInput   `json:"to"`
+	Center   *GeoPointInput  `json:"center"`
+	RadiusKm float64         `json:"radius_km"`
+	Polygon  []GeoPointInput `json:"polygon"`
+	Points   []GeoPointInput `json:"points"`
+}
+
+// JSONSchemaRequest represents a JSON Schema validation request
+type JSONSchemaRequest struct {
+	Schema    json.RawMessage   `json:"schema"`
+	Document  json.RawMessage   `json:"document"`
+	Documents []json.RawMessage `json:"documents"`
+	Draft     string            `json:"draft"`
+}
+
+// HTML2TextOptions represents HTML to text conversion options
+type HTML2TextOptions struct {
+	Links     string `json:"links"`
+	Images    string `json:"images"`
+	WrapWidth int    `json:"wrap_width"`
+}
+
+// HTML2TextRequest represents an HTML to plain text conversion request
+type HTML2TextRequest struct {
+	HTML    string           `json:"html"`
+	Options HTML2TextOptions `json:"options"`
+}
+
+// TextConvertOptions represents text conversion options
+type TextConvertOptions struct {
+	// SlugMaxLength cuts slugs on a hyphen to at most this many
+	// characters; 0 is unlimited
+	SlugMaxLength int `json:"slug_max_length"`
+}
+
+// TextConvertRequest represents a text case and slug conversion request
+type TextConvertRequest struct {
+	Text       string             `json:"text"`
+	Operations []string           `json:"operations"`
+	Options    TextConvertOptions `json:"options"`
+}
+
+// CSVConvertOptions are the options of a CSV conversion
+type CSVConvertOptions struct {
+	// Delimiter is one character; "" is a comma
+	Delimiter string `json:"delimiter"`
+	// HasHeader reads the first CSV row as column names, or writes one;
+	// nil is true
+	HasHeader *bool `json:"has_header"`
+	// InferTypes turns CSV fields into JSON numbers, booleans and nulls
+	InferTypes bool `json:"infer_types"`
+}
+
+// CSVConvertRequest represents a conversion between CSV and JSON. Data is
+// a string of CSV for csv_to_json and an array of flat objects for
+// json_to_csv.
+type CSVConvertRequest struct {
+	Mode    string            `json:"mode"`
+	Data    json.RawMessage   `json:"data"`
+	Options CSVConvertOptions `json:"options"`
+}
+
+// RegexTestRequest represents a regular expression test. Flags is any of
+// i, m and s; Replacement is the template of the replace mode, with $1 or
+// ${name} naming groups.
+type RegexTestRequest struct {
+	Pattern     string `json:"pattern"`
+	Flags       string `json:"flags"`
+	Input       string `json:"input"`
+	Mode        string `json:"mode"`
+	Replacement string `json:"replacement"`
+}
+
+// PasswordCheckRequest is a password whose strength is estimated
+type PasswordCheckRequest struct {
+	Password string `json:"password"`
+}
+
+// PasswordGenerateRequest asks for Count passwords of Length characters.
+// The class toggles are nil when omitted, which enables the class.
+type PasswordGenerateRequest struct {
+	Length           int   `json:"length"`
+	Count            int   `json:"count"`
+	Lowercase        *bool `json:"lowercase"`
+	Uppercase        *bool `json:"uppercase"`
+	Digits           *bool `json:"digits"`
+	Symbols          *bool `json:"symbols"`
+	ExcludeAmbiguous bool  `json:"exclude_ambiguous"`
+}
+
+// TimestampConvertRequest converts Epoch, a decimal number in Unit (s, ms
+// or us; empty detects it by magnitude), or Datetime, read in Timezone
+// unless it carries an offset. The result shows the instant in each of
+// OutputTimezones.
+type TimestampConvertRequest struct {
+	Epoch           *json.Number `json:"epoch"`
+	Unit            string       `json:"unit"`
+	Datetime        string       `json:"datetime"`
+	Timezone        string       `json:"timezone"`
+	OutputTimezones []string     `json:"output_timezones"`
+}
+
+// EmailExtractRequest represents an email address extraction request. One
+// of Text and HTML is set; HTML is converted to text first.
+type EmailExtractRequest struct {
+	Text              string `json:"text,omitempty"`
+	HTML              string `json:"html,omitempty"`
+	IncludeObfuscated bool   `json:"include_obfuscated"`
+	Validate          bool   `json:"validate"`
+}
+
+// DuplicatesOptions represents duplicate detection options
+type DuplicatesOptions struct {
+	Mode               string  `json:"mode"`
+	Similarity         string  `json:"similarity"`
+	Threshold          float64 `json:"threshold"`
+	CaseSensitive      bool    `json:"case_sensitive"`
+	PreserveWhitespace bool    `json:"preserve_whitespace"`
+	Canonical          string  `json:"canonical"`
+}
+
+// DuplicatesRequest represents a duplicate detection request
+type DuplicatesRequest struct {
+	Items   []string          `json:"items"`
+	Options DuplicatesOptions `json:"options"`
+}
+
+// ImageHashRequest represents a perceptual hash request for one image, or
+// two to compare. Images are base64, optionally as a data URL.
+type ImageHashRequest struct {
+	Images []string `json:"images"`
+}
+
+// LabelSheetOptions are the layout options shared by every label of a sheet.
+// Sizes are in millimetres; zero values take the service defaults.
+type LabelSheetOptions struct {
+	// Symbology is used for rows without a type: "QR" or a barcode type
+	Symbology     string  `json:"symbology"`
+	LabelWidthMM  float64 `json:"label_width_mm"`
+	LabelHeightMM float64 `json:"label_height_mm"`
+	PageSize      string  `json:"page_size"`
+	Columns       int     `json:"columns"`
+	Rows          int     `json:"rows"`
+	Font          string  `json:"font"`
+}
+
+// LabelRow is one label parsed from an uploaded CSV
+type LabelRow struct {
+	// Row is the spreadsheet row number, counting the header as row 1
+	Row  int    `json:"row"`
+	Data string `json:"data"`
+	Text string `json:"text,omitempty"`
+	Type string `json:"type,omitempty"`
+}
+
+// NumberParseRequest represents a number parsing request: one value, or up
+// to 1000 values in Values
+type NumberParseRequest struct {
+	Value  string   `json:"value"`
+	Values []string `json:"values"`
+	// Locale is an optional BCP 47 hint such as "de", "en-US" or "de-CH"
+	Locale string `json:"locale"`
+}
+
+// ThemePreference is the body of POST /preferences/theme and its JSON reply
+type ThemePreference struct {
+	// Theme is "light", "dark" or "system"
+	Theme string `json:"theme"`
+}
+
+// TenantRequest creates or replaces a tenant through the admin API
+type TenantRequest struct {
+	// ID is required on creation and ignored on replacement, where the
+	// path names the tenant
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	Hosts             []string `json:"hosts"`
+	DisposableDomains []string `json:"disposable_domains"`
+	RequestsPerMinute int      `json:"requests_per_minute"`
+	// WebhookSecret replaces the stored secret when set; it is kept
+	// otherwise
+	WebhookSecret string                `json:"webhook_secret"`
+	Branding      TenantBrandingRequest `json:"branding"`
+}
+
+// TenantBrandingRequest is the branding of a TenantRequest
+type TenantBrandingRequest struct {
+	SiteName     string `json:"site_name"`
+	LogoURL      string `json:"logo_url"`
+	PrimaryColor string `json:"primary_color"`
+	SupportEmail string `json:"support_email"`
+}
+
+// ShortenRequest asks for a short link to URL
+type ShortenRequest struct {
+	URL string `json:"url"`
+	// CustomAlias is used as the code instead of a random one, when free
+	CustomAlias string `json:"custom_alias"`
+	// ExpiresInDays makes the link expire; 0 keeps it forever
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+// WifiRotationRequest creates a guest WiFi network whose password rotates
+type WifiRotationRequest struct {
+	// Name identifies the network among the caller's, in its URLs
+	Name string `json:"name"`
+	SSID string `json:"ssid"`
+	// Security is "WPA" (default) or "SAE"
+	Security string `json:"security"`
+	// PasswordLength (default 20) and Charset (default "unambiguous")
+	// shape the generated passwords, as in random mode tokens
+	PasswordLength int    `json:"password_length"`
+	Charset        string `json:"charset"`
+	// Interval is how often the password rotates, e.g. "24h" (default)
+	Interval string `json:"interval"`
+}
+
+// UploadRequest starts a resumable upload
+type UploadRequest struct {
+	// Size is the total number of bytes that will be sent
+	Size int64 `json:"size"`
+	// SHA256 is the optional hex digest the data is checked against on
+	// completion
+	SHA256 string `json:"sha256"`
+	// ContentType is the media type the data is read as in place of a
+	// request body, e.g. "application/x-ndjson"
+	ContentType string `json:"content_type"`
+}
+
+// BlockDomainRequest adds a domain to the URL blocklist
+type BlockDomainRequest struct {
+	Domain string `json:"domain"`
+	// Reason is a note for other operators, e.g. the campaign it belongs to
+	Reason string `json:"reason"`
+}
+
+// MaintenanceRequest starts or ends a maintenance window on one tool, by
+// its counter name, or globally when Tool is empty. Duration ("30m") ends
+// the window by itself; RetryAfter defaults to it.
+type MaintenanceRequest struct {
+	Tool       string `json:"tool,omitempty"`
+	Active     *bool  `json:"active"`
+	Message    string `json:"message,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+	Duration   string `json:"duration,omitempty"`
+	// Exempt keeps the tool served during global maintenance
+	Exempt bool `json:"exempt,omitempty"`
 }
@@ -1,23 +1,84 @@
 package models
 
-// EmailValidation represents the result of email validation
+import (
+	"encoding/json"
+	"time"
+)
+
+// EmailValidation represents the result of email validation. Check fields
+// are nil, and omitted from JSON, when the check was not run.
 type EmailValidation struct {
 	Email          string `json:"email"`
-	IsSyntaxValid  bool   `json:"isSyntaxValid"`
-	IsDomainValid  bool   `json:"isDomainValid"`
-	MxRecordsFound bool   `json:"mxRecordsFound"`
-	IsDisposable   bool   `json:"isDisposable"`
+	IsSyntaxValid  *bool  `json:"isSyntaxValid,omitempty"`
+	IsDomainValid  *bool  `json:"isDomainValid,omitempty"`
+	MxRecordsFound *bool  `json:"mxRecordsFound,omitempty"`
+	IsDisposable   *bool  `json:"isDisposable,omitempty"`
+	// DNSTimedOut marks isDomainValid and mxRecordsFound as false because
+	// the lookups ran out of time, not because the domain has no records
+	DNSTimedOut bool `json:"dnsTimedOut,omitempty"`
+
+	// SkippedChecks lists checks that were not run, e.g. network lookups in lite mode
+	SkippedChecks []string `json:"skippedChecks,omitempty"`
+
+	// SMTP is the mailbox check, only present when check_smtp was set
+	SMTP *SMTPCheck `json:"smtp,omitempty"`
+
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// SMTPCheck represents the answers of an address's mail server. Null
+// MailboxExists and CatchAll mean unknown: the server was not reached,
+// deferred the answer (e.g. greylisting) or refused to tell.
+type SMTPCheck struct {
+	// Attempted is false when no mail server was contacted
+	Attempted     bool  `json:"attempted"`
+	MailboxExists *bool `json:"mailboxExists"`
+	// CatchAll is true when the server accepts any local part, so its
+	// acceptance of the address proves nothing
+	CatchAll    *bool  `json:"catchAll"`
+	SMTPMessage string `json:"smtpMessage,omitempty"`
 }
 
-// GeoIPResponse represents the result of IP geolocation
+// GeoIPResponse represents the result of IP geolocation. Fields the
+// database has no data for are null; Granularity says how precise the
+// location is and NotFound marks addresses in no database record.
+// Private, loopback and reserved addresses are not looked up: they are
+// flagged instead, with no location.
 type GeoIPResponse struct {
-	IP        string  `json:"ip"`
-	Country   string  `json:"country"`
-	Region    string  `json:"region"`
-	City      string  `json:"city"`
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-	Timezone  string  `json:"timezone"`
+	IP          string   `json:"ip"`
+	NotFound    bool     `json:"notFound"`
+	Granularity string   `json:"granularity"`
+	Country     *string  `json:"country"`
+	Region      *string  `json:"region"`
+	City        *string  `json:"city"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	Timezone    *string  `json:"timezone"`
+	IsPrivate   bool     `json:"isPrivate,omitempty"`
+	IsLoopback  bool     `json:"isLoopback,omitempty"`
+	IsReserved  bool     `json:"isReserved,omitempty"`
+	// ASN, Organization and IsDatacenter come from the ASN database and
+	// are left out without one or when it does not list the address
+	ASN          *uint   `json:"asn,omitempty"`
+	Organization *string `json:"organization,omitempty"`
+	// IsDatacenter is true when the ASN belongs to a known hosting or
+	// cloud provider, so the address is unlikely to be a person's
+	IsDatacenter *bool `json:"isDatacenter,omitempty"`
+	// Source tells where IP came from; batch results have none
+	Source string `json:"source,omitempty"`
+
+	Meta GeoDatabaseInfo `json:"meta"`
+
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// GeoDatabaseInfo describes the geolocation database that answered a lookup
+type GeoDatabaseInfo struct {
+	DatabaseType string    `json:"database_type"`
+	DatabaseDate string    `json:"database_date"`
+	NodeCount    uint      `json:"node_count"`
+	Attribution  string    `json:"attribution"`
+	BuiltAt      time.Time `json:"-"`
 }
 
 // IBANValidation represents the result of IBAN validation
@@ -35,9 +96,786 @@ type IBANValidation struct {
 	IsCountrySupported bool   `json:"isCountrySupported"`
 	IsLengthValid      bool   `json:"isLengthValid"`
 	IsChecksumValid    bool   `json:"isChecksumValid"`
+	// IsNationalChecksumValid checks the check digits inside the BBAN; it
+	// is null for countries without any and when the format is invalid
+	IsNationalChecksumValid *bool `json:"isNationalChecksumValid"`
+	// ExpectedLength and ExampleIBAN come from the country spec once the
+	// country is supported
+	ExpectedLength int    `json:"expectedLength,omitempty"`
+	ExampleIBAN    string `json:"exampleIban,omitempty"`
+	// FailureReason is the first entry of Failures, which describes each
+	// failed check; both are omitted for a valid IBAN
+	FailureReason string   `json:"failureReason,omitempty"`
+	Failures      []string `json:"failures,omitempty"`
+
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// BankAccountValidation represents the result of validating a domestic
+// bank account in a country that does not use IBANs. Checksum fields are
+// nil when the country's scheme has no such check or it could not run.
+type BankAccountValidation struct {
+	Country                    string `json:"country"`
+	Scheme                     string `json:"scheme,omitempty"`
+	IsValid                    bool   `json:"isValid"`
+	BankCode                   string `json:"bankCode"`
+	FormattedBankCode          string `json:"formattedBankCode"`
+	AccountNumber              string `json:"accountNumber"`
+	IsCountrySupported         bool   `json:"isCountrySupported"`
+	IsBankCodeFormatValid      bool   `json:"isBankCodeFormatValid"`
+	IsBankCodeChecksumValid    *bool  `json:"isBankCodeChecksumValid,omitempty"`
+	IsAccountNumberFormatValid bool   `json:"isAccountNumberFormatValid"`
+	IsAccountChecksumValid     *bool  `json:"isAccountChecksumValid,omitempty"`
+	// Institution names the bank or Federal Reserve district when the
+	// bank code identifies it
+	Institution string `json:"institution,omitempty"`
+
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// CardValidation represents the result of payment card number
+// validation. The number itself is only returned masked.
+type CardValidation struct {
+	MaskedNumber string `json:"maskedNumber"`
+	Length       int    `json:"length"`
+	// IsValid reports whether the Luhn check digit matches
+	IsValid    bool   `json:"isValid"`
+	CheckDigit string `json:"checkDigit"`
+	// Scheme is empty when the issuer prefix matches no known scheme
+	Scheme                 string `json:"scheme"`
+	IsLengthValidForScheme bool   `json:"isLengthValidForScheme"`
+}
+
+// GeneratedImage is a QR code or barcode answered with output "json":
+// the image base64-encoded with what it was generated from. Size is set
+// for QR codes, Width and Height for barcodes.
+type GeneratedImage struct {
+	Data          string `json:"data"`
+	ContentType   string `json:"content_type"`
+	Size          int    `json:"size,omitempty"`
+	Width         int    `json:"width,omitempty"`
+	Height        int    `json:"height,omitempty"`
+	Type          string `json:"type"`
+	PayloadLength int    `json:"payload_length"`
+}
+
+// BarcodeValidation lists the symbologies a scanned value could have been
+// read from, most plausible first
+type BarcodeValidation struct {
+	Value string `json:"value"`
+	// ScannerSymbology is the symbology named by an AIM identifier such as
+	// "]C1" in front of the value; the identifier is not part of Data
+	ScannerSymbology string             `json:"scannerSymbology,omitempty"`
+	IsValid          bool               `json:"isValid"`
+	Candidates       []BarcodeCandidate `json:"candidates"`
+
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// BarcodeCandidate is one interpretation of a scanned value. IsChecksumValid
+// is nil when the value carries no check digit in this symbology, e.g. 12
+// digits read as an EAN-13 without its last digit.
+type BarcodeCandidate struct {
+	Rank            int    `json:"rank"`
+	Symbology       string `json:"symbology"`
+	IsValid         bool   `json:"isValid"`
+	Data            string `json:"data"`
+	IsLengthValid   bool   `json:"isLengthValid"`
+	IsCharsetValid  bool   `json:"isCharsetValid"`
+	IsChecksumValid *bool  `json:"isChecksumValid,omitempty"`
+	// CheckDigit is the check digit or character computed for the data
+	CheckDigit string `json:"checkDigit,omitempty"`
+	// CompleteValue is the data with the computed check digit appended,
+	// set when the value was read without one
+	CompleteValue string `json:"completeValue,omitempty"`
+	// GTIN is the 14-digit GTIN of retail symbols and GS1 AI (01)
+	GTIN string     `json:"gtin,omitempty"`
+	GS1  *GS1Fields `json:"gs1,omitempty"`
+	// Errors explain why the interpretation is invalid
+	Errors []string `json:"errors,omitempty"`
+}
+
+// GS1Fields is the element string of a GS1-128 value split into its
+// application identifiers, with the common ones lifted into fields
+type GS1Fields struct {
+	Elements   []GS1Element `json:"elements"`
+	GTIN       string       `json:"gtin,omitempty"`
+	SSCC       string       `json:"sscc,omitempty"`
+	ExpiryDate string       `json:"expiryDate,omitempty"`
+	BestBefore string       `json:"bestBefore,omitempty"`
+	Lot        string       `json:"lot,omitempty"`
+	Serial     string       `json:"serial,omitempty"`
+}
+
+// GS1Element is one application identifier and its data. Date is the
+// ISO 8601 form of YYMMDD dates; IsChecksumValid is set for keys with a
+// check digit.
+type GS1Element struct {
+	AI              string `json:"ai"`
+	Title           string `json:"title"`
+	Value           string `json:"value"`
+	Date            string `json:"date,omitempty"`
+	IsChecksumValid *bool  `json:"isChecksumValid,omitempty"`
+}
+
+// IBANFormatRules describes how a country's IBANs are grouped and which
+// characters each BBAN position accepts
+type IBANFormatRules struct {
+	CountryCode string `json:"countryCode"`
+	CountryName string `json:"countryName"`
+	Length      int    `json:"length"`
+	Groups      []int  `json:"groups"`
+	// Pattern is the grouped layout, e.g. "DEkk nnnn nnnn nnnn nnnn nn"
+	Pattern string `json:"pattern"`
+	// BBANStructure is the SWIFT registry notation, e.g. "8!n10!n"
+	BBANStructure string `json:"bbanStructure"`
+	// BBANPositions holds "n" (digit), "a" (letter) or "c" (either) per BBAN character
+	BBANPositions    []string `json:"bbanPositions"`
+	Example          string   `json:"example"`
+	FormattedExample string   `json:"formattedExample"`
+}
+
+// IBANPartialFormat is a possibly incomplete IBAN grouped for display
+type IBANPartialFormat struct {
+	Input       string `json:"input"`
+	Formatted   string `json:"formatted"`
+	CountryCode string `json:"countryCode,omitempty"`
+	CountryName string `json:"countryName,omitempty"`
+	Length      int    `json:"length,omitempty"`
+	// Remaining is the number of characters still missing once the country is known
+	Remaining    *int `json:"remaining,omitempty"`
+	IsConsistent bool `json:"isConsistent"`
+	IsComplete   bool `json:"isComplete"`
+	// IsChecksumValid is set once the IBAN is complete
+	IsChecksumValid *bool `json:"isChecksumValid,omitempty"`
+	// ErrorPosition is the 0-based index of the first inconsistent character
+	ErrorPosition *int   `json:"errorPosition,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// TokenResponse represents a generated secret and its strength
+type TokenResponse struct {
+	Token        string  `json:"token"`
+	Mode         string  `json:"mode"`
+	EntropyBits  float64 `json:"entropyBits"`
+	Words        int     `json:"words,omitempty"`
+	Language     string  `json:"language,omitempty"`
+	WordlistSize int     `json:"wordlistSize,omitempty"`
+	Length       int     `json:"length,omitempty"`
+	Charset      string  `json:"charset,omitempty"`
+}
+
+// UUIDResponse lists generated identifiers, in the order generated
+type UUIDResponse struct {
+	Version     string    `json:"version"`
+	Format      string    `json:"format"`
+	Count       int       `json:"count"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	IDs         []string  `json:"ids"`
+}
+
+// CSVConvertResult is the result of a csv_to_json conversion. Rows are
+// objects keyed by column, with string values unless types are inferred.
+type CSVConvertResult struct {
+	Mode     string                   `json:"mode"`
+	RowCount int                      `json:"rowCount"`
+	Columns  []string                 `json:"columns"`
+	Rows     []map[string]interface{} `json:"rows"`
+}
+
+// RegexTestResult is the result of a regex test. Matches are listed by
+// the match and find_all modes, Result is the output of replace and Parts
+// that of split. MatchCount counts the matches listed, or for replace and
+// split all matches in the input.
+type RegexTestResult struct {
+	Mode       string       `json:"mode"`
+	Matched    bool         `json:"matched"`
+	MatchCount int          `json:"matchCount"`
+	Truncated  bool         `json:"truncated,omitempty"`
+	Matches    []RegexMatch `json:"matches,omitempty"`
+	Result     *string      `json:"result,omitempty"`
+	Parts      []string     `json:"parts,omitempty"`
+}
+
+// RegexMatch is one match of a regex test, with byte offsets into the
+// input
+type RegexMatch struct {
+	Start  int          `json:"start"`
+	End    int          `json:"end"`
+	Text   string       `json:"text"`
+	Groups []RegexGroup `json:"groups"`
+}
+
+// RegexGroup is a capture group of a match, numbered from 1. Groups that
+// did not take part in the match are not matched and have offsets of -1.
+type RegexGroup struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name,omitempty"`
+	Matched bool   `json:"matched"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Text    string `json:"text"`
+}
+
+// PasswordCheckResult estimates the strength of a password. Score runs
+// from 0 (very weak) to 4 (very strong); findings locate the weaknesses
+// by rune offsets without repeating the password.
+type PasswordCheckResult struct {
+	Length       int                `json:"length"`
+	EntropyBits  float64            `json:"entropyBits"`
+	GuessesLog10 float64            `json:"guessesLog10"`
+	Score        int                `json:"score"`
+	Strength     string             `json:"strength"`
+	Common       bool               `json:"common"`
+	CrackTimes   PasswordCrackTimes `json:"crackTimes"`
+	Findings     []PasswordFinding  `json:"findings"`
+}
+
+// PasswordCrackTimes estimates the time to try every guess in online
+// attacks, throttled and not, and offline ones on slow and fast hashes
+type PasswordCrackTimes struct {
+	OnlineThrottled   PasswordCrackTime `json:"onlineThrottled"`
+	OnlineUnthrottled PasswordCrackTime `json:"onlineUnthrottled"`
+	OfflineSlowHash   PasswordCrackTime `json:"offlineSlowHash"`
+	OfflineFastHash   PasswordCrackTime `json:"offlineFastHash"`
+}
+
+// PasswordCrackTime is a crack time in seconds and in words
+type PasswordCrackTime struct {
+	Seconds float64 `json:"seconds"`
+	Display string  `json:"display"`
+}
+
+// PasswordFinding is a weakness found in the runes [Start, End) of a
+// password
+type PasswordFinding struct {
+	Type    string `json:"type"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Message string `json:"message"`
+}
+
+// PasswordGenerateResult lists generated passwords, each with a character
+// of every class in Classes
+type PasswordGenerateResult struct {
+	Passwords   []string `json:"passwords"`
+	Length      int      `json:"length"`
+	Classes     []string `json:"classes"`
+	EntropyBits float64  `json:"entropyBits"`
+}
+
+// TimestampConvertResult is an instant in UTC, with Unit the epoch unit
+// read and Local the datetime in its zone. Ambiguity is set when the
+// local time occurs twice or never around a DST transition; Candidates
+// then holds both instants, the first of which was converted.
+type TimestampConvertResult struct {
+	Unix        int64                `json:"unix"`
+	UnixMillis  int64                `json:"unixMillis"`
+	ISO8601     string               `json:"iso8601"`
+	RFC1123     string               `json:"rfc1123"`
+	DayOfWeek   string               `json:"dayOfWeek"`
+	ISOWeek     int                  `json:"isoWeek"`
+	ISOWeekYear int                  `json:"isoWeekYear"`
+	Unit        string               `json:"unit,omitempty"`
+	Local       *TimestampZone       `json:"local,omitempty"`
+	Ambiguity   string               `json:"ambiguity,omitempty"`
+	Candidates  []TimestampCandidate `json:"candidates,omitempty"`
+	Timezones   []TimestampZone      `json:"timezones"`
+}
+
+// TimestampZone is an instant in one timezone
+type TimestampZone struct {
+	Timezone      string `json:"timezone"`
+	ISO8601       string `json:"iso8601"`
+	RFC1123       string `json:"rfc1123"`
+	Offset        string `json:"offset"`
+	OffsetSeconds int    `json:"offsetSeconds"`
+	Abbreviation  string `json:"abbreviation"`
+	IsDST         bool   `json:"isDst"`
+	DayOfWeek     string `json:"dayOfWeek"`
+	ISOWeek       int    `json:"isoWeek"`
+	ISOWeekYear   int    `json:"isoWeekYear"`
+}
+
+// TimestampCandidate is one instant an ambiguous or skipped local time
+// may name
+type TimestampCandidate struct {
+	ISO8601    string `json:"iso8601"`
+	Offset     string `json:"offset"`
+	Unix       int64  `json:"unix"`
+	UnixMillis int64  `json:"unixMillis"`
+}
+
+// GeoPoint represents a resolved latitude/longitude pair
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// DistanceResult represents the great-circle relationship between two points
+type DistanceResult struct {
+	From           GeoPoint `json:"from"`
+	To             GeoPoint `json:"to"`
+	DistanceKm     float64  `json:"distanceKm"`
+	DistanceMi     float64  `json:"distanceMi"`
+	InitialBearing float64  `json:"initialBearing"`
+	Midpoint       GeoPoint `json:"midpoint"`
+}
+
+// GeofencePointResult represents whether a single point lies inside a geofence
+type GeofencePointResult struct {
+	IP     string   `json:"ip,omitempty"`
+	Point  GeoPoint `json:"point"`
+	Inside bool     `json:"inside"`
+}
+
+// GeofenceResult represents the result of a geofence check
+type GeofenceResult struct {
+	Shape   string                `json:"shape"`
+	Results []GeofencePointResult `json:"results"`
+}
+
+// ImageHashes represents the perceptual hashes of one image as 16 hex digits
+type ImageHashes struct {
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	AHash  string `json:"aHash"`
+	DHash  string `json:"dHash"`
+	PHash  string `json:"pHash"`
+}
+
+// ImageHashComparison represents the Hamming distances between the hashes
+// of two images and the verdict drawn from the pHash distance
+type ImageHashComparison struct {
+	AHashDistance int     `json:"aHashDistance"`
+	DHashDistance int     `json:"dHashDistance"`
+	PHashDistance int     `json:"pHashDistance"`
+	Similarity    float64 `json:"similarity"`
+	Verdict       string  `json:"verdict"`
 }
 
-// QRErrorResponse represents a QR generation error
-type QRErrorResponse struct {
+// ImageHashResult represents the hashes of each image and, for two images,
+// their comparison
+type ImageHashResult struct {
+	Images     []ImageHashes        `json:"images"`
+	Comparison *ImageHashComparison `json:"comparison,omitempty"`
+}
+
+// QRSegment represents a run of the QR payload encoded in one data mode.
+// Length counts bytes; Bits includes the mode and character count header.
+type QRSegment struct {
+	Mode   string `json:"mode"`
+	Data   string `json:"data"`
+	Length int    `json:"length"`
+	Bits   int    `json:"bits"`
+}
+
+// QRPrintSize represents the estimated smallest printed code, quiet zone
+// included, that scans from a distance
+type QRPrintSize struct {
+	ScanDistanceMM float64 `json:"scanDistanceMm"`
+	ModuleMM       float64 `json:"moduleMm"`
+	SizeMM         float64 `json:"sizeMm"`
+}
+
+// QRAnalysis represents the symbol a QR request encodes to: its version,
+// mask and error correction, the mode segments of the payload and how much
+// of the version's data capacity they use
+type QRAnalysis struct {
+	Version                  int           `json:"version"`
+	ModuleCount              int           `json:"moduleCount"`
+	QuietZoneModules         int           `json:"quietZoneModules"`
+	ErrorCorrection          string        `json:"errorCorrection"`
+	Mask                     int           `json:"mask"`
+	Segments                 []QRSegment   `json:"segments"`
+	DataBits                 int           `json:"dataBits"`
+	DataBitsCapacity         int           `json:"dataBitsCapacity"`
+	RemainingBits            int           `json:"remainingBits"`
+	DataCodewords            int           `json:"dataCodewords"`
+	DataCodewordsCapacity    int           `json:"dataCodewordsCapacity"`
+	ErrorCorrectionCodewords int           `json:"errorCorrectionCodewords"`
+	Utilization              float64       `json:"utilization"`
+	PrintSizes               []QRPrintSize `json:"printSizes"`
+}
+
+// APIError is the body of error responses: a human-readable Message, a
+// machine-readable Code clients switch on, and the request Field at fault
+// when there is one. Some errors add fields of their own.
+type APIError struct {
+	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+// BatchResult is one line of a streamed NDJSON batch response
+type BatchResult struct {
+	Index  int         `json:"index"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// TextSafetyIssue is one suspicious character found in user-supplied text
+type TextSafetyIssue struct {
+	Kind      string `json:"kind"`
+	Position  int    `json:"position"`
+	CodePoint string `json:"codePoint"`
+	Name      string `json:"name"`
+}
+
+// TextSafetyReport represents the safety analysis of one input string
+type TextSafetyReport struct {
+	Index        int               `json:"index"`
+	Safe         bool              `json:"safe"`
+	Findings     []TextSafetyIssue `json:"findings"`
+	Scripts      []string          `json:"scripts"`
+	MixedScripts bool              `json:"mixedScripts"`
+	IsNFC        bool              `json:"isNFC"`
+	Suggestion   string            `json:"suggestion"`
+}
+
+// BarcodeOptionViolation names an option combination rule a barcode request broke
+type BarcodeOptionViolation struct {
+	Rule    string   `json:"rule"`
+	Fields  []string `json:"fields"`
+	Message string   `json:"message"`
+}
+
+// JSONSchemaViolation represents a single schema keyword failure
+type JSONSchemaViolation struct {
+	InstancePointer string `json:"instancePointer"`
+	SchemaPointer   string `json:"schemaPointer"`
+	Keyword         string `json:"keyword"`
+	Message         string `json:"message"`
+}
+
+// JSONSchemaDocumentResult represents the validation outcome for one document
+type JSONSchemaDocumentResult struct {
+	Index      int                   `json:"index"`
+	IsValid    bool                  `json:"isValid"`
+	Violations []JSONSchemaViolation `json:"violations"`
+}
+
+// JSONSchemaValidation represents the result of JSON Schema validation
+type JSONSchemaValidation struct {
+	IsValid bool                       `json:"isValid"`
+	Results []JSONSchemaDocumentResult `json:"results"`
+}
+
+// HTML2TextResult represents the result of HTML to text conversion
+type HTML2TextResult struct {
+	Text  string `json:"text"`
+	Lines int    `json:"lines"`
+}
+
+// ExtractedEmail represents one distinct address found in a text. Offset
+// and Context are those of its first occurrence; Offset counts bytes of
+// the text scanned, which for HTML input is its text conversion.
+type ExtractedEmail struct {
+	Email       string           `json:"email"`
+	Offset      int              `json:"offset"`
+	Context     string           `json:"context"`
+	Occurrences int              `json:"occurrences"`
+	Obfuscated  bool             `json:"obfuscated,omitempty"`
+	Validation  *EmailValidation `json:"validation,omitempty"`
+}
+
+// EmailExtraction represents the addresses extracted from a text
+type EmailExtraction struct {
+	Source    string           `json:"source"`
+	Count     int              `json:"count"`
+	Truncated bool             `json:"truncated,omitempty"`
+	Emails    []ExtractedEmail `json:"emails"`
+}
+
+// DuplicateMember represents one input item within a duplicate cluster
+type DuplicateMember struct {
+	Index int    `json:"index"`
+	Value string `json:"value"`
+}
+
+// DuplicateCluster represents a group of items considered duplicates
+type DuplicateCluster struct {
+	Canonical string            `json:"canonical"`
+	Size      int               `json:"size"`
+	Members   []DuplicateMember `json:"members"`
+}
+
+// DuplicateStats represents summary counts for duplicate detection
+type DuplicateStats struct {
+	Total      int `json:"total"`
+	Unique     int `json:"unique"`
+	Duplicates int `json:"duplicates"`
+	Clusters   int `json:"clusters"`
+}
+
+// DuplicatesResult represents the result of duplicate detection
+type DuplicatesResult struct {
+	Mode     string             `json:"mode"`
+	Clusters []DuplicateCluster `json:"clusters"`
+	Stats    DuplicateStats     `json:"stats"`
+	Warnings []Warning          `json:"warnings,omitempty"`
+}
+
+// ToolOption describes one option of a tool and how route groups restrict it
+type ToolOption struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Max is the largest allowed value per route group, for numeric options
+	Max map[string]int `json:"max,omitempty"`
+	// UnavailableIn lists the route groups that reject the option
+	UnavailableIn []string `json:"unavailableIn,omitempty"`
+}
+
+// ToolSpec describes one tool endpoint for the embeddable widget
+type ToolSpec struct {
+	Name          string       `json:"name"`
+	Method        string       `json:"method"`
+	Path          string       `json:"path"`
+	LitePath      string       `json:"litePath,omitempty"`
+	UnavailableIn []string     `json:"unavailableIn,omitempty"`
+	Options       []ToolOption `json:"options,omitempty"`
+	// Warnings lists the warning codes the tool may return
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ResultMeta describes how a result was produced; it is returned alongside
+// the result when a request sets ?include_meta=true
+type ResultMeta struct {
+	Datasets         []DatasetUsage `json:"datasets"`
+	SkippedChecks    []SkippedCheck `json:"skippedChecks"`
+	ProcessingTimeMs float64        `json:"processingTimeMs"`
+}
+
+// DatasetUsage is one dataset a result was computed from
+type DatasetUsage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// BuiltAt is the build date of datasets that carry one, e.g. GeoLite2
+	BuiltAt string `json:"builtAt,omitempty"`
+	// Stale is set when the build date is older than the configured limit
+	Stale bool `json:"stale,omitempty"`
+}
+
+// SkippedCheck is an optional check that did not run
+type SkippedCheck struct {
+	Check  string `json:"check"`
+	Reason string `json:"reason"`
+}
+
+// LabelRowError reports why one CSV row cannot become a label
+type LabelRowError struct {
+	Row   int    `json:"row"`
 	Error string `json:"error"`
 }
+
+// JobStatus describes a background job
+type JobStatus struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	StatusURL  string     `json:"statusUrl"`
+	// ResultURL is set once the job is done
+	ResultURL string `json:"resultUrl,omitempty"`
+	// CorrelationIDs are the correlation headers the job was submitted with
+	CorrelationIDs map[string]string `json:"correlationIds,omitempty"`
+}
+
+// WifiRotation is the current state of a rotating guest WiFi network
+type WifiRotation struct {
+	Name           string    `json:"name"`
+	SSID           string    `json:"ssid"`
+	Security       string    `json:"security"`
+	PasswordLength int       `json:"passwordLength"`
+	Charset        string    `json:"charset"`
+	Interval       string    `json:"interval"`
+	Version        int64     `json:"version"`
+	RotatedAt      time.Time `json:"rotatedAt"`
+	NextRotationAt time.Time `json:"nextRotationAt"`
+	Password       string    `json:"password"`
+	// Payload is the WIFI: string the network's QR code encodes
+	Payload string `json:"payload"`
+	// QRURL serves the network's current QR code
+	QRURL string `json:"qrUrl"`
+}
+
+// UploadStatus describes a resumable upload
+type UploadStatus struct {
+	ID          string `json:"id"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	// Received is the number of bytes stored, where the next chunk starts
+	Received int64 `json:"received"`
+	// SHA256 is the expected digest, or the computed one once complete
+	SHA256    string    `json:"sha256,omitempty"`
+	Complete  bool      `json:"complete"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	UploadURL string    `json:"uploadUrl"`
+	// Token replaces a request body with ?upload= once the upload is complete
+	Token string `json:"token,omitempty"`
+}
+
+// ParsedNumber represents the result of parsing one numeric string
+type ParsedNumber struct {
+	Input string `json:"input"`
+	// Value is the canonical decimal, e.g. "-1234.56", kept as a string so
+	// no precision is lost
+	Value      string            `json:"value,omitempty"`
+	Convention *NumberConvention `json:"convention,omitempty"`
+	Currency   *DetectedCurrency `json:"currency,omitempty"`
+	IsPercent  bool              `json:"isPercent"`
+	// Ambiguous is set when auto-detection had to fall back to a tie-break
+	Ambiguous bool   `json:"ambiguous,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// NumberConvention describes the separators a number was read with
+type NumberConvention struct {
+	Name             string `json:"name"`
+	DecimalSeparator string `json:"decimalSeparator"`
+	GroupSeparator   string `json:"groupSeparator"`
+	// Source is "locale" when taken from the hint, otherwise "detected"
+	Source string `json:"source"`
+}
+
+// DetectedCurrency is the currency a symbol or code resolved to
+type DetectedCurrency struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	MinorUnits int    `json:"minorUnits"`
+	Symbol     string `json:"symbol"`
+	// Ambiguous is set when the symbol is shared by several currencies and
+	// the locale did not pick one
+	Ambiguous bool `json:"ambiguous,omitempty"`
+}
+
+// Warning is a non-fatal issue with a successful request: the result was
+// produced, but part of the input was changed or ignored
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+// StatusReport is the traffic-based health of every tool over the
+// rolling window ending at GeneratedAt
+type StatusReport struct {
+	// Status is the worst status of any tool
+	Status        string       `json:"status"`
+	WindowSeconds int          `json:"windowSeconds"`
+	GeneratedAt   time.Time    `json:"generatedAt"`
+	Tools         []ToolStatus `json:"tools"`
+}
+
+// ToolStatus summarises the requests one tool served in the window.
+// Errors are 5xx responses; ClientDisconnects are requests whose client
+// went away first and are not counted in Requests. P95LatencyMs is null
+// without requests.
+type ToolStatus struct {
+	Tool              string   `json:"tool"`
+	Status            string   `json:"status"`
+	Requests          int64    `json:"requests"`
+	Errors            int64    `json:"errors"`
+	ClientDisconnects int64    `json:"clientDisconnects"`
+	RequestsPerMinute float64  `json:"requestsPerMinute"`
+	ErrorRate         float64  `json:"errorRate"`
+	P95LatencyMs      *float64 `json:"p95LatencyMs"`
+}
+
+// DNSCacheStats counts the DNS cache's answers since startup. NegativeHits
+// are NXDOMAIN or NODATA answers served from the cache; Misses went to DNS.
+type DNSCacheStats struct {
+	Entries      int   `json:"entries"`
+	Capacity     int   `json:"capacity"`
+	Hits         int64 `json:"hits"`
+	NegativeHits int64 `json:"negativeHits"`
+	Misses       int64 `json:"misses"`
+	Evictions    int64 `json:"evictions"`
+}
+
+// TestVector is a generator request whose output is committed to stay
+// byte-identical across releases; SHA256 is the hex digest of the body
+type TestVector struct {
+	Name        string          `json:"name"`
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	Request     json.RawMessage `json:"request"`
+	ContentType string          `json:"contentType"`
+	SHA256      string          `json:"sha256"`
+}
+
+// HitStats counts the calls of each endpoint since Since, by counter name
+type HitStats struct {
+	Since    time.Time        `json:"since"`
+	Total    int64            `json:"total"`
+	Counters map[string]int64 `json:"counters"`
+	// Forwarder reports the delivery of the counts to the counter API;
+	// nil when none is configured
+	Forwarder *CounterForwarderStatus `json:"forwarder,omitempty"`
+}
+
+// CounterForwarderStatus reports the delivery of usage counters in /ready
+type CounterForwarderStatus struct {
+	// State is "healthy", "degraded" or "replaying"
+	State string `json:"state"`
+	// PendingIncrements are logged and waiting to be delivered
+	PendingIncrements int64 `json:"pendingIncrements"`
+	WALBytes          int64 `json:"walBytes"`
+	// DroppedIncrements were dropped to keep the log under its cap
+	DroppedIncrements int64  `json:"droppedIncrements"`
+	LastError         string `json:"lastError,omitempty"`
+}
+
+// DependencyStatus reports one dependency checked by /ready
+type DependencyStatus struct {
+	// Status is "up", "down", or "degraded" for a dependency that is down
+	// but optional
+	Status   string `json:"status"`
+	Required bool   `json:"required"`
+	// LatencyMs is how long the check took
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// SharedResult is a validation result stored for a permalink. Result is
+// the redacted validationResult object of the tool's response.
+type SharedResult struct {
+	ID        string          `bson:"_id" json:"id"`
+	Tool      string          `bson:"tool" json:"tool"`
+	Result    json.RawMessage `bson:"result" json:"result"`
+	CreatedAt time.Time       `bson:"created_at" json:"createdAt"`
+	ExpiresAt time.Time       `bson:"expires_at" json:"expiresAt"`
+}
+
+// ShortURL is a short link stored in the short_urls collection with its
+// code as the ID. ExpiresAt is nil for links that never expire.
+type ShortURL struct {
+	Code      string     `bson:"_id" json:"code"`
+	URL       string     `bson:"url" json:"url"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	ExpiresAt *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	// Hits counts the redirects served
+	Hits int64 `bson:"hits" json:"hits"`
+}
+
+// ShortenResponse describes a new short link; ShortURL is its absolute
+// URL
+type ShortenResponse struct {
+	Code      string     `json:"code"`
+	ShortURL  string     `json:"short_url"`
+	URL       string     `json:"url"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// BlockedDomain is a domain an operator added to the URL blocklist, stored
+// in the url_blocklist collection with the domain as its ID
+type BlockedDomain struct {
+	Domain  string    `bson:"_id" json:"domain"`
+	Reason  string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	AddedBy string    `bson:"added_by,omitempty" json:"addedBy,omitempty"`
+	AddedAt time.Time `bson:"added_at" json:"addedAt"`
+}
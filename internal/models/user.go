@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// User is the stored user document
+type User struct {
+	Email             string    `bson:"email" json:"email"`
+	Name              string    `bson:"name" json:"name"`
+	Company           string    `bson:"company" json:"company"`
+	Country           string    `bson:"country" json:"country"`
+	Verified          bool      `bson:"verified" json:"verified"`
+	VerificationNonce string    `bson:"verification_nonce,omitempty" json:"-"`
+	VerifiedAt        time.Time `bson:"verified_at,omitempty" json:"verifiedAt,omitempty"`
+	// CreatedAt is when the user registered; zero for users registered
+	// before it was stored
+	CreatedAt time.Time `bson:"created_at,omitempty" json:"createdAt,omitzero"`
+	// Deleted users keep their document until the deletion grace period
+	// has passed; their access tokens are rejected immediately
+	Deleted   bool      `bson:"deleted" json:"deleted"`
+	DeletedAt time.Time `bson:"deleted_at,omitempty" json:"deletedAt,omitzero"`
+	// TokensInvalidBefore rejects access tokens issued at or before it
+	TokensInvalidBefore time.Time `bson:"tokens_invalid_before,omitempty" json:"tokensInvalidBefore,omitzero"`
+	// Plan is the user's quota tier, e.g. "pro"; empty is the free tier
+	Plan string `bson:"plan,omitempty" json:"plan,omitempty"`
+	// Tenant is the white-label customer the user registered with; empty
+	// is the default tenant
+	Tenant string `bson:"tenant,omitempty" json:"tenant,omitempty"`
+}
+
+// UserExport is the personal data held about a user, as returned by the
+// data export endpoint
+type UserExport struct {
+	User User `json:"user"`
+	// WifiNetworks are the user's rotating guest WiFi networks with their
+	// current passwords
+	WifiNetworks []WifiRotation `json:"wifiNetworks"`
+	ExportedAt   time.Time      `json:"exportedAt"`
+}
+
+// WifiProfile is the stored document of a rotating guest WiFi network,
+// with "owner/name" as its ID. The current password is only kept as a
+// hash and sealed with the configured rotation key.
+type WifiProfile struct {
+	ID             string        `bson:"_id"`
+	Owner          string        `bson:"owner"`
+	Name           string        `bson:"name"`
+	SSID           string        `bson:"ssid"`
+	Security       string        `bson:"security"`
+	PasswordLength int           `bson:"password_length"`
+	Charset        string        `bson:"charset"`
+	Interval       time.Duration `bson:"interval"`
+	// PasswordHash is the hex SHA-256 of the current password
+	PasswordHash string `bson:"password_hash"`
+	// SealedPassword is the AES-GCM nonce followed by the sealed password
+	SealedPassword []byte `bson:"sealed_password"`
+	// Version counts rotations; a rotation only applies on top of the
+	// version it read
+	Version        int64     `bson:"version"`
+	CreatedAt      time.Time `bson:"created_at"`
+	RotatedAt      time.Time `bson:"rotated_at"`
+	NextRotationAt time.Time `bson:"next_rotation_at"`
+}
+
+// Tenant is a white-label customer, stored in the tenants collection with
+// its ID as the document ID. Its overrides apply to requests made with
+// its users' access tokens or to one of its Hosts.
+type Tenant struct {
+	ID   string `bson:"_id" json:"id"`
+	Name string `bson:"name" json:"name"`
+	// Hosts are the custom domains serving the tenant's pages and API
+	Hosts []string `bson:"hosts,omitempty" json:"hosts,omitempty"`
+	// DisposableDomains are reported disposable on top of the built-in list
+	DisposableDomains []string `bson:"disposable_domains,omitempty" json:"disposableDomains,omitempty"`
+	// RequestsPerMinute limits each client of the tenant per API; 0 is
+	// unlimited
+	RequestsPerMinute int `bson:"requests_per_minute,omitempty" json:"requestsPerMinute,omitempty"`
+	// WebhookSecret signs the tenant's webhook deliveries; it is never
+	// returned
+	WebhookSecret string         `bson:"webhook_secret,omitempty" json:"-"`
+	Branding      TenantBranding `bson:"branding" json:"branding"`
+	CreatedAt     time.Time      `bson:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time      `bson:"updated_at" json:"updatedAt"`
+}
+
+// TenantBranding replaces the site's name, logo, colors and contact on the
+// pages of a tenant's hosts; empty fields keep the defaults
+type TenantBranding struct {
+	SiteName     string `bson:"site_name,omitempty" json:"siteName,omitempty"`
+	LogoURL      string `bson:"logo_url,omitempty" json:"logoUrl,omitempty"`
+	PrimaryColor string `bson:"primary_color,omitempty" json:"primaryColor,omitempty"`
+	SupportEmail string `bson:"support_email,omitempty" json:"supportEmail,omitempty"`
+}
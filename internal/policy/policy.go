@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	GroupFull = "full"
+	GroupLite = "lite"
+)
+
+var ErrUnavailable = errors.New("option unavailable")
+
+// OptionError reports an option or range the route group does not allow
+type OptionError struct {
+	Group  string
+	Option string
+	Reason string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("%s is restricted in %s mode: %s", e.Option, e.Group, e.Reason)
+}
+
+func (e *OptionError) Unwrap() error {
+	return ErrUnavailable
+}
+
+// Policy holds the limits of a route group. Handlers read it from the
+// request context so the same handler serves every group.
+type Policy struct {
+	Group string
+
+	// AllowAnyOrigin answers CORS requests from any origin
+	AllowAnyOrigin bool
+	// MaxBodyBytes caps request bodies (0 leaves them uncapped)
+	MaxBodyBytes int64
+	// RequestsPerMinute is the per-IP rate limit (0 disables it)
+	RequestsPerMinute int
+
+	MaxQRSize        int
+	MaxBarcodeWidth  int
+	MaxBarcodeHeight int
+
+	// AllowBatch enables the NDJSON bulk endpoints
+	AllowBatch bool
+	// AllowNetworkChecks enables checks that call out to other hosts,
+	// such as the DNS/MX lookups of email validation
+	AllowNetworkChecks bool
+}
+
+// Full is the policy of the keyed /api/v1 routes
+var Full = Policy{
+	Group:              GroupFull,
+	MaxQRSize:          2048,
+	MaxBarcodeWidth:    1024,
+	MaxBarcodeHeight:   1024,
+	AllowBatch:         true,
+	AllowNetworkChecks: true,
+}
+
+// Lite is the policy of the anonymous, cross-origin /api/lite/v1 routes
+// used by the embeddable widget
+var Lite = Policy{
+	Group:             GroupLite,
+	AllowAnyOrigin:    true,
+	MaxBodyBytes:      16 * 1024,
+	RequestsPerMinute: 30,
+	MaxQRSize:         512,
+	MaxBarcodeWidth:   600,
+	MaxBarcodeHeight:  300,
+}
+
+// CheckQRSize rejects QR sizes above the group's maximum
+func (p Policy) CheckQRSize(size int) error {
+	if size > p.MaxQRSize {
+		return p.optionError("size", fmt.Sprintf("maximum is %d pixels", p.MaxQRSize))
+	}
+	return nil
+}
+
+// CheckBarcodeSize rejects barcode dimensions above the group's maximums
+func (p Policy) CheckBarcodeSize(width, height int) error {
+	if width > p.MaxBarcodeWidth {
+		return p.optionError("width", fmt.Sprintf("maximum is %d pixels", p.MaxBarcodeWidth))
+	}
+	if height > p.MaxBarcodeHeight {
+		return p.optionError("height", fmt.Sprintf("maximum is %d pixels", p.MaxBarcodeHeight))
+	}
+	return nil
+}
+
+// CheckBatch rejects bulk requests when the group does not allow them
+func (p Policy) CheckBatch() error {
+	if !p.AllowBatch {
+		return p.optionError("batch", "bulk validation requires the full API")
+	}
+	return nil
+}
+
+func (p Policy) optionError(option, reason string) error {
+	return &OptionError{Group: p.Group, Option: option, Reason: reason}
+}
+
+type contextKey struct{}
+
+// WithPolicy returns a copy of ctx carrying p
+func WithPolicy(ctx context.Context, p Policy) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the policy stored in ctx, or Full when none is set
+func FromContext(ctx context.Context) Policy {
+	if p, ok := ctx.Value(contextKey{}).(Policy); ok {
+		return p
+	}
+	return Full
+}
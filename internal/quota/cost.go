@@ -0,0 +1,28 @@
+package quota
+
+// PDFMultiplier weighs symbols composited into PDF label sheets, which are
+// rendered and then embedded and laid out on pages
+const PDFMultiplier = 2
+
+// Every generation cost is computed here, so the endpoints are charged
+// consistently. A cost is never below one credit.
+
+// QRCost is the cost of one QR code of size x size pixels
+func QRCost(size int) int64 {
+	return pixels(size, size, 1)
+}
+
+// BarcodeCost is the cost of one barcode of width x height pixels
+func BarcodeCost(width, height int) int64 {
+	return pixels(width, height, 1)
+}
+
+// LabelSheetCost is the cost of a PDF sheet of labels whose symbols are
+// rendered at width x height pixels
+func LabelSheetCost(width, height, labels int) int64 {
+	return pixels(width, height, labels) * PDFMultiplier
+}
+
+func pixels(width, height, items int) int64 {
+	return max(int64(width)*int64(height)*int64(items), 1)
+}
@@ -0,0 +1,27 @@
+package quota
+
+import "testing"
+
+func TestCosts(t *testing.T) {
+	tests := []struct {
+		name string
+		cost int64
+		want int64
+	}{
+		{"smallest QR", QRCost(64), 64 * 64},
+		{"largest QR", QRCost(2048), 2048 * 2048},
+		{"barcode", BarcodeCost(300, 100), 30000},
+		{"largest barcode", BarcodeCost(1024, 1024), 1 << 20},
+		{"one label", LabelSheetCost(200, 200, 1), 2 * 40000},
+		{"label sheet", LabelSheetCost(200, 120, 65), 2 * 200 * 120 * 65},
+		// Large sheets of large symbols do not overflow
+		{"huge sheet", LabelSheetCost(2048, 2048, 100000), 2 * 2048 * 2048 * 100000},
+		{"empty QR", QRCost(0), 1},
+		{"empty sheet", LabelSheetCost(200, 200, 0), 2},
+	}
+	for _, tt := range tests {
+		if tt.cost != tt.want {
+			t.Errorf("%s costs %d, want %d", tt.name, tt.cost, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package quota meters image generation by the pixels it produces rather
+// than by request count, so one caller rendering thousands of maximum-size
+// codes uses up its budget long before one sending small codes. Budgets
+// are pixel credits per caller per UTC day, kept in Redis so every
+// instance shares them.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Plan tiers. Callers without an access token are PlanAnonymous, and a
+// user document without a plan is PlanFree.
+const (
+	PlanAnonymous = "anonymous"
+	PlanFree      = "free"
+	PlanPro       = "pro"
+)
+
+// keyTTL keeps a day's counter past the end of the day so late requests
+// near midnight in any instance still find it
+const keyTTL = 48 * time.Hour
+
+// Limits are the daily pixel credits of each plan; 0 is unlimited
+type Limits struct {
+	Anonymous int64
+	Free      int64
+	Pro       int64
+}
+
+// For returns the daily limit of plan; unknown plans get the free limit
+func (l Limits) For(plan string) int64 {
+	switch plan {
+	case PlanAnonymous:
+		return l.Anonymous
+	case PlanPro:
+		return l.Pro
+	default:
+		return l.Free
+	}
+}
+
+// Subject is the caller a cost is charged to
+type Subject struct {
+	// Key identifies the caller: a user email or a client IP
+	Key  string
+	Plan string
+}
+
+// Decision is the outcome of Spend
+type Decision struct {
+	Allowed bool
+	// Limit is the subject's daily credits, 0 when unlimited
+	Limit int64
+	// Remaining is what is left after this request, or before it when it
+	// was refused
+	Remaining int64
+	// ResetAt is the start of the next UTC day
+	ResetAt time.Time
+}
+
+// spendScript charges ARGV[1] credits to KEYS[1] unless that would pass
+// the limit ARGV[2], and returns {allowed, used}. Checking and adding in
+// one script keeps concurrent requests from overshooting the limit.
+var spendScript = redis.NewScript(`
+local used = tonumber(redis.call('GET', KEYS[1]) or '0')
+local cost = tonumber(ARGV[1])
+if used + cost > tonumber(ARGV[2]) then
+	return {0, used}
+end
+used = redis.call('INCRBY', KEYS[1], cost)
+if used == cost then
+	redis.call('EXPIRE', KEYS[1], tonumber(ARGV[3]))
+end
+return {1, used}
+`)
+
+// Limiter charges generation costs to daily per-subject budgets
+type Limiter struct {
+	client *redis.Client
+	prefix string
+	limits Limits
+	clock  clock.Clock
+}
+
+// NewLimiter creates a Limiter storing counters under prefix in Redis
+func NewLimiter(client *redis.Client, prefix string, limits Limits, c clock.Clock) *Limiter {
+	return &Limiter{client: client, prefix: prefix, limits: limits, clock: c}
+}
+
+// Spend charges cost pixel credits to subject's budget for today. A
+// refused request is not charged. Subjects on an unlimited plan are
+// always allowed without touching Redis.
+func (l *Limiter) Spend(ctx context.Context, subject Subject, cost int64) (decision Decision, err error) {
+	now := l.clock.Now().UTC()
+	day := now.Truncate(24 * time.Hour)
+	decision = Decision{Allowed: true, Limit: l.limits.For(subject.Plan), ResetAt: day.Add(24 * time.Hour)}
+	if decision.Limit <= 0 {
+		decision.Limit = 0
+		return decision, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "redis.quota.spend",
+		attribute.String("db.system", "redis"),
+		attribute.String("db.operation.name", "EVALSHA"),
+		attribute.String("quota.plan", subject.Plan),
+		attribute.Int64("quota.cost", cost))
+	defer func() { tracing.End(span, err) }()
+
+	key := fmt.Sprintf("%squota:pixels:%s:%s", l.prefix, day.Format("2006-01-02"), subject.Key)
+	result, err := spendScript.Run(ctx, l.client, []string{key}, cost, decision.Limit, int(keyTTL/time.Second)).Int64Slice()
+	if err != nil {
+		return Decision{}, fmt.Errorf("pixel quota: %w", err)
+	}
+	decision.Allowed = result[0] == 1
+	decision.Remaining = max(decision.Limit-result[1], 0)
+	return decision, nil
+}
@@ -0,0 +1,117 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// fakeClock stands in for testutil.Clock, which this package cannot
+// import because testutil imports it through handlers
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newLimiter(t *testing.T, limits Limits) (*Limiter, *miniredis.Miniredis, *fakeClock) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)}
+	return NewLimiter(client, "test:", limits, clock), server, clock
+}
+
+func TestSpend(t *testing.T) {
+	limiter, server, clock := newLimiter(t, Limits{Anonymous: 1000, Free: 5000})
+	ctx := context.Background()
+	ip := Subject{Key: "ip:192.0.2.1", Plan: PlanAnonymous}
+	resetAt := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		cost      int64
+		allowed   bool
+		remaining int64
+	}{
+		{400, true, 600},
+		{600, true, 0},
+		// Refused requests are not charged
+		{1, false, 0},
+	} {
+		decision, err := limiter.Spend(ctx, ip, tt.cost)
+		if err != nil || decision.Allowed != tt.allowed || decision.Remaining != tt.remaining ||
+			decision.Limit != 1000 || !decision.ResetAt.Equal(resetAt) {
+			t.Fatalf("spending %d: %+v, %v", tt.cost, decision, err)
+		}
+	}
+	key := "test:quota:pixels:2026-03-01:ip:192.0.2.1"
+	if used, _ := server.Get(key); used != "1000" {
+		t.Errorf("used = %s, want 1000", used)
+	}
+	if ttl := server.TTL(key); ttl != keyTTL {
+		t.Errorf("TTL = %s, want %s", ttl, keyTTL)
+	}
+
+	// Other subjects have budgets of their own, and plans their limits
+	user := Subject{Key: "user:ada@example.com", Plan: PlanFree}
+	if decision, err := limiter.Spend(ctx, user, 4000); err != nil || !decision.Allowed || decision.Remaining != 1000 {
+		t.Errorf("user: %+v, %v", decision, err)
+	}
+	if decision, err := limiter.Spend(ctx, user, 1001); err != nil || decision.Allowed || decision.Remaining != 1000 {
+		t.Errorf("user over the limit: %+v, %v", decision, err)
+	}
+
+	// The budget resets at midnight UTC
+	clock.Advance(2 * time.Hour)
+	if decision, err := limiter.Spend(ctx, ip, 1000); err != nil || !decision.Allowed || decision.Remaining != 0 {
+		t.Errorf("next day: %+v, %v", decision, err)
+	}
+}
+
+func TestSpendUnlimited(t *testing.T) {
+	limiter, server, _ := newLimiter(t, Limits{Anonymous: 1000})
+	server.Close()
+	// Pro has no limit here, so Redis is not needed
+	decision, err := limiter.Spend(context.Background(), Subject{Key: "user:ada@example.com", Plan: PlanPro}, 1<<40)
+	if err != nil || !decision.Allowed || decision.Limit != 0 {
+		t.Errorf("unlimited plan: %+v, %v", decision, err)
+	}
+	if _, err := limiter.Spend(context.Background(), Subject{Key: "ip:192.0.2.1", Plan: PlanAnonymous}, 1); err == nil {
+		t.Error("limited plan without Redis: no error")
+	}
+}
+
+// TestSpendConcurrently charges a budget from many goroutines at once;
+// the script must never let the total pass the limit
+func TestSpendConcurrently(t *testing.T) {
+	limiter, server, _ := newLimiter(t, Limits{Anonymous: 100})
+	subject := Subject{Key: "ip:192.0.2.1", Plan: PlanAnonymous}
+	allowed := make(chan bool, 50)
+	for range 50 {
+		go func() {
+			decision, err := limiter.Spend(context.Background(), subject, 7)
+			allowed <- err == nil && decision.Allowed
+		}()
+	}
+	n := 0
+	for range 50 {
+		if <-allowed {
+			n++
+		}
+	}
+	if used, _ := server.Get("test:quota:pixels:2026-03-01:ip:192.0.2.1"); n != 14 || used != "98" {
+		t.Errorf("%d requests allowed using %s credits, want 14 using 98", n, used)
+	}
+}
+
+func TestLimitsFor(t *testing.T) {
+	limits := Limits{Anonymous: 1, Free: 2, Pro: 3}
+	for plan, want := range map[string]int64{PlanAnonymous: 1, PlanFree: 2, PlanPro: 3, "enterprise": 2, "": 2} {
+		if got := limits.For(plan); got != want {
+			t.Errorf("For(%q) = %d, want %d", plan, got, want)
+		}
+	}
+}
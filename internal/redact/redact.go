@@ -0,0 +1,182 @@
+package redact
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces values that are masked entirely
+const Redacted = "[REDACTED]"
+
+// Rule masks a single field value
+type Rule func(value string) string
+
+// rules maps lower-case field names to their masking rule. A new sensitive
+// field only needs to be added here to be covered everywhere. Results use
+// camelCase, so their fields appear here without separators.
+var rules = map[string]Rule{
+	"email":          Email,
+	"iban":           IBAN,
+	"formattediban":  IBAN,
+	"account_number": AccountNumber,
+	"accountnumber":  AccountNumber,
+	"bban":           AccountNumber,
+	"number":         CardNumber,
+	"pan":            Full,
+	"card_number":    Full,
+	"cvv":            Full,
+	"password":       Full,
+	"passwords":      Full,
+	"secret":         Full,
+	"token":          Full,
+	"authorization":  Full,
+	"api_key":        Full,
+	"apikey":         Full,
+}
+
+// IsSensitive reports whether field has a redaction rule
+func IsSensitive(field string) bool {
+	_, ok := rules[strings.ToLower(field)]
+	return ok
+}
+
+// Value applies the rule registered for field, or returns value unchanged
+func Value(field, value string) string {
+	if rule, ok := rules[strings.ToLower(field)]; ok {
+		return rule(value)
+	}
+	return value
+}
+
+// Full masks the whole value
+func Full(string) string {
+	return Redacted
+}
+
+// Email keeps the domain and masks the local part
+func Email(value string) string {
+	at := strings.LastIndexByte(value, '@')
+	if at < 0 {
+		return Redacted
+	}
+	return "***" + value[at:]
+}
+
+// IBAN keeps the country code and the last four characters
+func IBAN(value string) string {
+	compact := []rune(strings.ToUpper(strings.Join(strings.Fields(value), "")))
+	if len(compact) <= 6 {
+		return Redacted
+	}
+	return string(compact[:2]) + strings.Repeat("*", len(compact)-6) + string(compact[len(compact)-4:])
+}
+
+// AccountNumber keeps the last four characters of a bank account number
+func AccountNumber(value string) string {
+	compact := []rune(strings.Join(strings.Fields(value), ""))
+	if len(compact) <= 4 {
+		return Redacted
+	}
+	return strings.Repeat("*", len(compact)-4) + string(compact[len(compact)-4:])
+}
+
+// CardNumber keeps the last four digits of a payment card number,
+// dropping spaces, dashes and any other non-digit
+func CardNumber(value string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, value)
+	if len(digits) <= 4 {
+		return Redacted
+	}
+	return strings.Repeat("*", len(digits)-4) + digits[len(digits)-4:]
+}
+
+// Tail returns the last four characters AccountNumber keeps, for text such
+// as "ending in 1234", or "" when value is too short to show any
+func Tail(value string) string {
+	compact := []rune(strings.Join(strings.Fields(value), ""))
+	if len(compact) <= 4 {
+		return ""
+	}
+	return string(compact[len(compact)-4:])
+}
+
+// URL masks the password in a connection string's user info
+func URL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return Redacted
+	}
+	if u.User == nil {
+		return value
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return u.String()
+}
+
+// RedactMap returns a copy of m with every sensitive field masked,
+// descending into nested objects and arrays.
+func RedactMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if rule, ok := rules[strings.ToLower(k)]; ok {
+			out[k] = redactValue(rule, v)
+			continue
+		}
+		out[k] = redactNested(v)
+	}
+	return out
+}
+
+// RedactStruct converts v to its JSON field map and redacts it, so struct
+// fields are matched by their json tag names.
+func RedactStruct(v interface{}) map[string]interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return map[string]interface{}{}
+	}
+	return RedactMap(m)
+}
+
+func redactValue(rule Rule, v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return rule(val)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			out[i] = redactValue(rule, child)
+		}
+		return out
+	default:
+		return Redacted
+	}
+}
+
+func redactNested(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return RedactMap(val)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			out[i] = redactNested(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,213 @@
+package redact_test
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/redact"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		field, value, want string
+	}{
+		{"email", "ada.lovelace@example.com", "***@example.com"},
+		{"Email", "no-at-sign", redact.Redacted},
+		{"iban", "DE89 3704 0044 0532 0130 00", "DE****************3000"},
+		{"iban", "DE89", redact.Redacted},
+		{"account_number", "12345678", "****5678"},
+		{"pan", "4111111111111111", redact.Redacted},
+		{"number", "4111-1111 1111-1111", "************1111"},
+		{"number", "41-1", redact.Redacted},
+		{"password", "hunter2", redact.Redacted},
+		{"secret", "s3cr3t", redact.Redacted},
+		{"Authorization", "Bearer abc", redact.Redacted},
+		{"name", "Ada", "Ada"},
+	}
+	for _, tt := range tests {
+		if got := redact.Value(tt.field, tt.value); got != tt.want {
+			t.Errorf("Value(%s, %q) = %q, want %q", tt.field, tt.value, got, tt.want)
+		}
+	}
+	if got := redact.URL("mongodb://app:pa55@db:27017/microtools"); strings.Contains(got, "pa55") {
+		t.Errorf("URL keeps the password: %s", got)
+	}
+	if got := redact.Tail("GB29 NWBK 6016 1331 9268 19"); got != "6819" {
+		t.Errorf("Tail = %q, want 6819", got)
+	}
+}
+
+func TestRedactMapNested(t *testing.T) {
+	in := map[string]interface{}{
+		"email":  "ada@example.com",
+		"nested": map[string]interface{}{"token": "abc", "kept": "yes"},
+		"list":   []interface{}{map[string]interface{}{"iban": "DE89370400440532013000"}},
+		"secret": 42.0,
+	}
+	data, _ := json.Marshal(redact.RedactMap(in))
+	out := string(data)
+	for _, secret := range []string{"ada@", "abc", "532013", "42"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("redacted map keeps %q: %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, `"kept":"yes"`) {
+		t.Errorf("redacted map lost an insensitive field: %s", out)
+	}
+	if in["email"] != "ada@example.com" {
+		t.Error("RedactMap changed its input")
+	}
+}
+
+// TestRequestModels feeds every request model with a sensitive field
+// through RedactStruct; no full sensitive value may survive
+func TestRequestModels(t *testing.T) {
+	const (
+		email    = "ada.lovelace@example.com"
+		iban     = "DE89370400440532013000"
+		account  = "12345678"
+		password = "correct horse battery staple"
+		card     = "4111111111111111"
+	)
+	for _, v := range []interface{}{
+		models.EmailRequest{Email: email},
+		models.IBANRequest{IBAN: iban},
+		models.BankAccountRequest{Country: "US", BankCode: "011000015", AccountNumber: account},
+		models.CardRequest{Number: card},
+		models.UserRequest{Email: email, Name: "Ada"},
+		models.WifiData{SSID: "home", Password: password},
+		models.VCardData{FirstName: "Ada", Email: email},
+	} {
+		data, err := json.Marshal(redact.RedactStruct(v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{email, iban, account, password, card} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%T keeps %s: %s", v, secret, data)
+			}
+		}
+	}
+}
+
+// logFuncs are the names of the log and slog calls; the slog ones take
+// key-value pairs after the message
+var logFuncs = map[string]bool{
+	"Print": false, "Printf": false, "Println": false, "Fatal": false, "Fatalf": false,
+	"Debug": true, "Info": true, "Warn": true, "Error": true,
+}
+
+// unredactedArgs returns the sensitive fields call logs unredacted. The
+// logging handler masks slog attributes by key, so a sensitive field is
+// safe as the value of the key of its name, e.g. "email", req.Email.
+func unredactedArgs(call *ast.CallExpr) []string {
+	fun, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	pairs, ok := logFuncs[fun.Sel.Name]
+	if !ok {
+		return nil
+	}
+	var fields []string
+	for i, arg := range call.Args {
+		field := sensitiveField(arg)
+		if field == "" {
+			continue
+		}
+		if pairs && i >= 2 && i%2 == 0 {
+			if key, ok := call.Args[i-1].(*ast.BasicLit); ok && key.Kind == token.STRING {
+				name := strings.ReplaceAll(strings.Trim(key.Value, "`\""), "_", "")
+				if strings.EqualFold(name, field[strings.IndexByte(field, '.')+1:]) {
+					continue
+				}
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// TestHandlersDoNotLogSensitiveFields scans the handlers for log calls
+// passing a sensitive field, such as req.Email, where the logging handler
+// does not mask it and no redact call is around it. It is a best effort
+// check on the syntax only.
+func TestHandlersDoNotLogSensitiveFields(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "handlers", "*.go"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no handler sources: %v", err)
+	}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				for _, field := range unredactedArgs(call) {
+					t.Errorf("%s: logs %s unredacted", fset.Position(call.Pos()), field)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// sensitiveField returns the first field selector in expr with a redaction
+// rule, skipping the arguments of redact calls
+func sensitiveField(expr ast.Expr) string {
+	var found string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if found != "" {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok {
+				if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "redact" {
+					return false
+				}
+			}
+		case *ast.SelectorExpr:
+			if _, ok := n.X.(*ast.Ident); ok && redact.IsSensitive(n.Sel.Name) {
+				found = n.X.(*ast.Ident).Name + "." + n.Sel.Name
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+func TestUnredactedArgs(t *testing.T) {
+	tests := map[string]string{
+		`log.Printf("%s", req.Email)`:                          "req.Email",
+		`logger.Info("x", "value", strings.TrimSpace(r.IBAN))`: "r.IBAN",
+		`logger.Info("x", "ip", req.Email)`:                    "req.Email",
+		`logger.Info("x", "iban", req.Email)`:                  "req.Email",
+		`logger.Info(req.Email)`:                               "req.Email",
+		`logger.Info("x", "iban", ibanReq.IBAN)`:               "",
+		`logger.Info("x", "email", redact.Email(req.Email))`:   "",
+		`logger.Info("x", "ip", req.IP)`:                       "",
+	}
+	for src, want := range tests {
+		expr, err := parser.ParseExpr(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Join(unredactedArgs(expr.(*ast.CallExpr)), ", ")
+		if got != want {
+			t.Errorf("%s: unredacted = %q, want %q", src, got, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrBlockedDomainNotFound means the domain is not on the manual blocklist
+var ErrBlockedDomainNotFound = errors.New("domain is not on the blocklist")
+
+// BlocklistStore keeps the domains operators block by hand, merged with
+// the blocklist file by the URL reputation check
+type BlocklistStore interface {
+	// List returns every blocked domain
+	List(ctx context.Context) ([]models.BlockedDomain, error)
+	// Add blocks a domain, replacing its entry when already blocked
+	Add(ctx context.Context, entry models.BlockedDomain) error
+	// Remove unblocks a domain, or returns ErrBlockedDomainNotFound
+	Remove(ctx context.Context, domain string) error
+}
+
+type mongoBlocklistStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoBlocklistStore creates a BlocklistStore backed by the
+// url_blocklist collection of the microapps database
+func NewMongoBlocklistStore(client *mongo.Client) BlocklistStore {
+	return &mongoBlocklistStore{collection: client.Database("microapps").Collection("url_blocklist")}
+}
+
+// List returns every blocked domain
+func (s *mongoBlocklistStore) List(ctx context.Context) (entries []models.BlockedDomain, err error) {
+	ctx, span := tracing.Start(ctx, "mongo.url_blocklist.list", blocklistAttributes("find")...)
+	defer func() { tracing.End(span, err) }()
+
+	cursor, err := s.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	entries = []models.BlockedDomain{}
+	err = cursor.All(ctx, &entries)
+	return entries, err
+}
+
+// Add blocks a domain, replacing its entry when already blocked
+func (s *mongoBlocklistStore) Add(ctx context.Context, entry models.BlockedDomain) error {
+	ctx, span := tracing.Start(ctx, "mongo.url_blocklist.add", blocklistAttributes("replace")...)
+	_, err := s.collection.ReplaceOne(ctx, bson.M{"_id": entry.Domain}, entry, options.Replace().SetUpsert(true))
+	tracing.End(span, err)
+	return err
+}
+
+// Remove unblocks a domain, or returns ErrBlockedDomainNotFound
+func (s *mongoBlocklistStore) Remove(ctx context.Context, domain string) error {
+	ctx, span := tracing.Start(ctx, "mongo.url_blocklist.remove", blocklistAttributes("delete")...)
+	defer span.End()
+
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": domain})
+	if err != nil {
+		tracing.End(span, err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrBlockedDomainNotFound
+	}
+	return nil
+}
+
+// blocklistAttributes describes a call on the url_blocklist collection
+func blocklistAttributes(operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.collection.name", "url_blocklist"),
+		attribute.String("db.operation.name", operation),
+	}
+}
@@ -0,0 +1,27 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewUserRepositoryIn creates a UserRepository on the users collection of
+// db, so integration tests keep to a database of their own
+func NewUserRepositoryIn(ctx context.Context, db *mongo.Database) (UserRepository, error) {
+	return newMongoUserRepository(ctx, db.Collection("users"))
+}
+
+// NewShareStoreIn creates a ShareStore on the shared_results collection
+// of db, without the TTL index
+func NewShareStoreIn(db *mongo.Database) ShareStore {
+	return &mongoShareStore{collection: db.Collection("shared_results")}
+}
+
+// NewShortURLStoreIn creates a ShortURLStore on the short_urls collection
+// of db
+func NewShortURLStoreIn(db *mongo.Database) ShortURLStore {
+	return &mongoShortURLStore{collection: db.Collection("short_urls")}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/cache"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrShareNotFound means no shared result has the ID, or it has expired
+var ErrShareNotFound = errors.New("shared result not found")
+
+// shareKeyPrefix namespaces shared results among the cache's keys
+const shareKeyPrefix = "share:"
+
+// ShareStore keeps shared validation results until they expire. IDs are
+// random and long enough that collisions are not handled.
+type ShareStore interface {
+	// Save stores a result until its ExpiresAt
+	Save(ctx context.Context, shared models.SharedResult) error
+	// Get returns the result with id that has not expired by now, or
+	// ErrShareNotFound
+	Get(ctx context.Context, id string, now time.Time) (models.SharedResult, error)
+}
+
+type mongoShareStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoShareStore creates a ShareStore backed by the shared_results
+// collection of the microapps database. It ensures the TTL index that
+// lets MongoDB remove expired results; Get does not rely on it.
+func NewMongoShareStore(ctx context.Context, client *mongo.Client) (ShareStore, error) {
+	collection := client.Database("microapps").Collection("shared_results")
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"expires_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mongoShareStore{collection: collection}, nil
+}
+
+// Save stores a result until its ExpiresAt
+func (s *mongoShareStore) Save(ctx context.Context, shared models.SharedResult) error {
+	ctx, span := tracing.Start(ctx, "mongo.shared_results.insert", shareAttributes("insert")...)
+	_, err := s.collection.InsertOne(ctx, shared)
+	tracing.End(span, err)
+	return err
+}
+
+// Get returns the result with id that has not expired by now
+func (s *mongoShareStore) Get(ctx context.Context, id string, now time.Time) (shared models.SharedResult, err error) {
+	ctx, span := tracing.Start(ctx, "mongo.shared_results.find", shareAttributes("find")...)
+	defer func() {
+		if errors.Is(err, ErrShareNotFound) {
+			span.End()
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	err = s.collection.FindOne(ctx, bson.M{"_id": id, "expires_at": bson.M{"$gt": now.UTC()}}).Decode(&shared)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.SharedResult{}, ErrShareNotFound
+	}
+	return shared, err
+}
+
+// shareAttributes describes a call on the shared_results collection
+func shareAttributes(operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.collection.name", "shared_results"),
+		attribute.String("db.operation.name", operation),
+	}
+}
+
+type cacheShareStore struct {
+	cache cache.Cache
+}
+
+// NewCacheShareStore creates a ShareStore keeping results as JSON in c,
+// e.g. Redis, which expires them itself
+func NewCacheShareStore(c cache.Cache) ShareStore {
+	return &cacheShareStore{cache: c}
+}
+
+// Save stores a result until its ExpiresAt
+func (s *cacheShareStore) Save(ctx context.Context, shared models.SharedResult) error {
+	data, err := json.Marshal(shared)
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(ctx, shareKeyPrefix+shared.ID, string(data), shared.ExpiresAt.Sub(shared.CreatedAt))
+}
+
+// Get returns the result with id that has not expired by now
+func (s *cacheShareStore) Get(ctx context.Context, id string, now time.Time) (models.SharedResult, error) {
+	data, found, err := s.cache.Get(ctx, shareKeyPrefix+id)
+	if err != nil {
+		return models.SharedResult{}, err
+	}
+	if !found {
+		return models.SharedResult{}, ErrShareNotFound
+	}
+	var shared models.SharedResult
+	if err := json.Unmarshal([]byte(data), &shared); err != nil {
+		return models.SharedResult{}, err
+	}
+	if !now.Before(shared.ExpiresAt) {
+		return models.SharedResult{}, ErrShareNotFound
+	}
+	return shared, nil
+}
@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+// checkShareStore saves a result and reads it back until it expires at
+// the clock's time plus a day
+func checkShareStore(t *testing.T, name string, store repository.ShareStore, clock *testutil.Clock) {
+	t.Helper()
+	ctx := context.Background()
+	created := clock.Now().UTC().Truncate(time.Millisecond)
+	shared := models.SharedResult{
+		ID:        "4kTq9ZbWxY",
+		Tool:      "iban-validate",
+		Result:    json.RawMessage(`{"isValid":true}`),
+		CreatedAt: created,
+		ExpiresAt: created.Add(24 * time.Hour),
+	}
+	if err := store.Save(ctx, shared); err != nil {
+		t.Fatalf("%s: Save: %v", name, err)
+	}
+
+	got, err := store.Get(ctx, shared.ID, clock.Now())
+	if err != nil || got.ID != shared.ID || got.Tool != shared.Tool || string(got.Result) != string(shared.Result) ||
+		!got.CreatedAt.Equal(shared.CreatedAt) || !got.ExpiresAt.Equal(shared.ExpiresAt) {
+		t.Errorf("%s: Get = %+v, %v", name, got, err)
+	}
+	if _, err := store.Get(ctx, "5kTq9ZbWxY", clock.Now()); !errors.Is(err, repository.ErrShareNotFound) {
+		t.Errorf("%s: unknown ID: err = %v, want ErrShareNotFound", name, err)
+	}
+
+	clock.Advance(24*time.Hour - time.Second)
+	if _, err := store.Get(ctx, shared.ID, clock.Now()); err != nil {
+		t.Errorf("%s: a second before expiry: %v", name, err)
+	}
+	clock.Advance(time.Second)
+	if _, err := store.Get(ctx, shared.ID, clock.Now()); !errors.Is(err, repository.ErrShareNotFound) {
+		t.Errorf("%s: at expiry: err = %v, want ErrShareNotFound", name, err)
+	}
+}
+
+func TestCacheShareStore(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	checkShareStore(t, "cache", repository.NewCacheShareStore(testutil.NewCache(clock)), clock)
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrShortURLNotFound means no short link has the code
+	ErrShortURLNotFound = errors.New("short link not found")
+	// ErrShortURLExpired means the short link with the code has expired
+	ErrShortURLExpired = errors.New("short link has expired")
+	// ErrShortURLExists means the code is already taken
+	ErrShortURLExists = errors.New("short link code is already taken")
+)
+
+// ShortURLStore keeps short links. Expired links are kept, so a visit can
+// tell them from codes that never existed.
+type ShortURLStore interface {
+	// Create stores a new short link, or returns ErrShortURLExists
+	Create(ctx context.Context, short models.ShortURL) error
+	// Visit counts a visit of the link with code and returns it with the
+	// visit counted. A link expired by now is not counted and returns
+	// ErrShortURLExpired; an unknown code returns ErrShortURLNotFound.
+	Visit(ctx context.Context, code string, now time.Time) (models.ShortURL, error)
+}
+
+type mongoShortURLStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoShortURLStore creates a ShortURLStore backed by the short_urls
+// collection of the microapps database
+func NewMongoShortURLStore(client *mongo.Client) ShortURLStore {
+	return &mongoShortURLStore{collection: client.Database("microapps").Collection("short_urls")}
+}
+
+// Create stores a new short link, or returns ErrShortURLExists
+func (s *mongoShortURLStore) Create(ctx context.Context, short models.ShortURL) (err error) {
+	ctx, span := tracing.Start(ctx, "mongo.short_urls.create", shortURLAttributes("insert")...)
+	defer func() {
+		if errors.Is(err, ErrShortURLExists) {
+			span.End()
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	_, err = s.collection.InsertOne(ctx, short)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrShortURLExists
+	}
+	return err
+}
+
+// Visit counts a visit of the unexpired link with code with $inc and
+// returns it. When none matches, a second read tells an expired link from
+// an unknown code.
+func (s *mongoShortURLStore) Visit(ctx context.Context, code string, now time.Time) (short models.ShortURL, err error) {
+	ctx, span := tracing.Start(ctx, "mongo.short_urls.visit", shortURLAttributes("findAndModify")...)
+	defer func() {
+		if errors.Is(err, ErrShortURLNotFound) || errors.Is(err, ErrShortURLExpired) {
+			span.End()
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	filter := bson.M{"_id": code, "$or": bson.A{
+		bson.M{"expires_at": nil},
+		bson.M{"expires_at": bson.M{"$gt": now.UTC()}},
+	}}
+	err = s.collection.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"hits": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&short)
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return short, err
+	}
+	err = s.collection.FindOne(ctx, bson.M{"_id": code}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.ShortURL{}, ErrShortURLNotFound
+	}
+	if err != nil {
+		return models.ShortURL{}, err
+	}
+	return models.ShortURL{}, ErrShortURLExpired
+}
+
+// shortURLAttributes describes a call on the short_urls collection
+func shortURLAttributes(operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.collection.name", "short_urls"),
+		attribute.String("db.operation.name", operation),
+	}
+}
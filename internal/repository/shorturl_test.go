@@ -0,0 +1,60 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+// checkShortURLStore stores a link expiring a day after the clock's time
+// and visits it until it expires
+func checkShortURLStore(t *testing.T, name string, store repository.ShortURLStore, clock *testutil.Clock) {
+	t.Helper()
+	ctx := context.Background()
+	created := clock.Now().UTC().Truncate(time.Millisecond)
+	expires := created.Add(24 * time.Hour)
+	short := models.ShortURL{Code: "aZ09xYq", URL: "https://example.com/", CreatedAt: created, ExpiresAt: &expires}
+	if err := store.Create(ctx, short); err != nil {
+		t.Fatalf("%s: Create: %v", name, err)
+	}
+	if err := store.Create(ctx, models.ShortURL{Code: short.Code, URL: "https://example.org/", CreatedAt: created}); !errors.Is(err, repository.ErrShortURLExists) {
+		t.Errorf("%s: taken code: err = %v, want ErrShortURLExists", name, err)
+	}
+	forever := models.ShortURL{Code: "forever", URL: "https://example.org/", CreatedAt: created}
+	if err := store.Create(ctx, forever); err != nil {
+		t.Fatalf("%s: Create without expiry: %v", name, err)
+	}
+
+	for want := int64(1); want <= 2; want++ {
+		got, err := store.Visit(ctx, short.Code, clock.Now())
+		if err != nil || got.Code != short.Code || got.URL != short.URL || got.Hits != want ||
+			!got.CreatedAt.Equal(created) || got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) {
+			t.Errorf("%s: visit %d = %+v, %v", name, want, got, err)
+		}
+	}
+	if _, err := store.Visit(ctx, "unknown", clock.Now()); !errors.Is(err, repository.ErrShortURLNotFound) {
+		t.Errorf("%s: unknown code: err = %v, want ErrShortURLNotFound", name, err)
+	}
+
+	clock.Advance(24*time.Hour - time.Second)
+	if _, err := store.Visit(ctx, short.Code, clock.Now()); err != nil {
+		t.Errorf("%s: a second before expiry: %v", name, err)
+	}
+	clock.Advance(time.Second)
+	if _, err := store.Visit(ctx, short.Code, clock.Now()); !errors.Is(err, repository.ErrShortURLExpired) {
+		t.Errorf("%s: at expiry: err = %v, want ErrShortURLExpired", name, err)
+	}
+	if got, err := store.Visit(ctx, forever.Code, clock.Now()); err != nil || got.Hits != 1 || got.ExpiresAt != nil {
+		t.Errorf("%s: link without expiry = %+v, %v", name, got, err)
+	}
+}
+
+func TestFakeShortURLStore(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	checkShortURLStore(t, "fake", testutil.NewShortURLStore(), clock)
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrTenantNotFound means no tenant has the ID
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// TenantStore keeps the white-label customers' tenants
+type TenantStore interface {
+	// List returns every tenant ordered by ID
+	List(ctx context.Context) ([]models.Tenant, error)
+	// Get returns the tenant with id, or ErrTenantNotFound
+	Get(ctx context.Context, id string) (models.Tenant, error)
+	// Put stores tenant, replacing the tenant with its ID
+	Put(ctx context.Context, tenant models.Tenant) error
+	// Delete removes the tenant with id, or returns ErrTenantNotFound
+	Delete(ctx context.Context, id string) error
+}
+
+type mongoTenantStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoTenantStore creates a TenantStore backed by the tenants
+// collection of the microapps database
+func NewMongoTenantStore(client *mongo.Client) TenantStore {
+	return &mongoTenantStore{collection: client.Database("microapps").Collection("tenants")}
+}
+
+// List returns every tenant ordered by ID
+func (s *mongoTenantStore) List(ctx context.Context) (tenants []models.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "mongo.tenants.list", tenantAttributes("find")...)
+	defer func() { tracing.End(span, err) }()
+
+	cursor, err := s.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	tenants = []models.Tenant{}
+	err = cursor.All(ctx, &tenants)
+	return tenants, err
+}
+
+// Get returns the tenant with id, or ErrTenantNotFound
+func (s *mongoTenantStore) Get(ctx context.Context, id string) (tenant models.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "mongo.tenants.get", tenantAttributes("findOne")...)
+	defer func() { tracing.End(span, err) }()
+
+	err = s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&tenant)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Tenant{}, ErrTenantNotFound
+	}
+	return tenant, err
+}
+
+// Put stores tenant, replacing the tenant with its ID
+func (s *mongoTenantStore) Put(ctx context.Context, tenant models.Tenant) error {
+	ctx, span := tracing.Start(ctx, "mongo.tenants.put", tenantAttributes("replace")...)
+	_, err := s.collection.ReplaceOne(ctx, bson.M{"_id": tenant.ID}, tenant, options.Replace().SetUpsert(true))
+	tracing.End(span, err)
+	return err
+}
+
+// Delete removes the tenant with id, or returns ErrTenantNotFound
+func (s *mongoTenantStore) Delete(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "mongo.tenants.delete", tenantAttributes("delete")...)
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": id})
+	tracing.End(span, err)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
+
+// tenantAttributes describes a call on the tenants collection
+func tenantAttributes(operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.collection.name", "tenants"),
+		attribute.String("db.operation.name", operation),
+	}
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	ErrUserExists = errors.New("user already exists")
+	// ErrUserPendingDeletion means the email belongs to a user that was
+	// deleted but not yet purged, so it cannot register again until then
+	ErrUserPendingDeletion = errors.New("user pending deletion")
+	// ErrVerificationNotFound means no unverified user holds the nonce,
+	// either because the link was already used or was superseded
+	ErrVerificationNotFound = errors.New("no pending verification found")
+	// ErrUserNotFound means no user, or no user that is not deleted, has the email
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// UserRepository defines storage for registered users
+type UserRepository interface {
+	// Create stores a new user, returning ErrUserExists for a known email
+	// and ErrUserPendingDeletion for the email of a deleted, unpurged user
+	Create(ctx context.Context, user models.User) error
+	// ReplaceUnverified replaces the unverified user with user's email
+	// that registered before createdBefore, or before registration times
+	// were stored, so an expired verification can start over;
+	// ErrUserExists when there is none
+	ReplaceUnverified(ctx context.Context, user models.User, createdBefore time.Time) error
+	// MarkVerified verifies the unverified user holding nonce and clears it
+	MarkVerified(ctx context.Context, email, nonce string, at time.Time) error
+	// DeleteUnverified removes the unverified user holding nonce, a
+	// registration whose verification mail could not be sent;
+	// ErrVerificationNotFound when there is none
+	DeleteUnverified(ctx context.Context, email, nonce string) error
+	// Get returns the user with email, deleted or not, or ErrUserNotFound
+	Get(ctx context.Context, email string) (models.User, error)
+	// SoftDelete marks the user deleted at the given time and invalidates
+	// their access tokens; ErrUserNotFound when absent or already deleted
+	SoftDelete(ctx context.Context, email string, at time.Time) error
+	// DeletedBefore returns the emails of the users deleted before the
+	// given time, the ones PurgeDeleted would remove
+	DeletedBefore(ctx context.Context, before time.Time) ([]string, error)
+	// PurgeDeleted removes the users deleted before the given time and
+	// returns how many there were
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
+}
+
+type mongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoUserRepository creates a UserRepository backed by the users
+// collection of the microapps database
+func NewMongoUserRepository(ctx context.Context, client *mongo.Client) (UserRepository, error) {
+	return newMongoUserRepository(ctx, client.Database("microapps").Collection("users"))
+}
+
+// newMongoUserRepository ensures the unique index on email that Create
+// relies on to refuse a second registration, even a concurrent one
+func newMongoUserRepository(ctx context.Context, collection *mongo.Collection) (UserRepository, error) {
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"email": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mongoUserRepository{collection: collection}, nil
+}
+
+// Create stores a new user, returning ErrUserExists for a known email
+// and ErrUserPendingDeletion for the email of a deleted, unpurged user
+func (r *mongoUserRepository) Create(ctx context.Context, user models.User) (err error) {
+	ctx, span := tracing.Start(ctx, "mongo.users.create", mongoAttributes("insert")...)
+	defer func() {
+		if errors.Is(err, ErrUserExists) || errors.Is(err, ErrUserPendingDeletion) {
+			span.End()
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	_, err = r.collection.InsertOne(ctx, user)
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	count, err := r.collection.CountDocuments(ctx, bson.M{"email": user.Email, "deleted": true})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUserPendingDeletion
+	}
+	return ErrUserExists
+}
+
+// ReplaceUnverified replaces the unverified user with user's email that
+// registered before createdBefore, or ErrUserExists
+func (r *mongoUserRepository) ReplaceUnverified(ctx context.Context, user models.User, createdBefore time.Time) (err error) {
+	ctx, span := tracing.Start(ctx, "mongo.users.replace_unverified", mongoAttributes("update")...)
+	defer func() {
+		if errors.Is(err, ErrUserExists) {
+			span.End()
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	result, err := r.collection.ReplaceOne(ctx, bson.M{
+		"email":    user.Email,
+		"verified": false,
+		"deleted":  bson.M{"$ne": true},
+		"$or": bson.A{
+			bson.M{"created_at": bson.M{"$lt": createdBefore.UTC()}},
+			bson.M{"created_at": bson.M{"$exists": false}},
+		},
+	}, user)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserExists
+	}
+	return nil
+}
+
+// MarkVerified verifies the unverified user holding nonce and clears it
+func (r *mongoUserRepository) MarkVerified(ctx context.Context, email, nonce string, at time.Time) error {
+	ctx, span := tracing.Start(ctx, "mongo.users.mark_verified", mongoAttributes("update")...)
+	defer span.End()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"email": email, "verified": false, "verification_nonce": nonce, "deleted": bson.M{"$ne": true}},
+		bson.M{
+			"$set":   bson.M{"verified": true, "verified_at": at.UTC()},
+			"$unset": bson.M{"verification_nonce": ""},
+		},
+	)
+	if err != nil {
+		tracing.End(span, err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrVerificationNotFound
+	}
+	return nil
+}
+
+// DeleteUnverified removes the unverified user holding nonce
+func (r *mongoUserRepository) DeleteUnverified(ctx context.Context, email, nonce string) error {
+	ctx, span := tracing.Start(ctx, "mongo.users.delete_unverified", mongoAttributes("delete")...)
+	defer span.End()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"email": email, "verified": false, "verification_nonce": nonce})
+	if err != nil {
+		tracing.End(span, err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrVerificationNotFound
+	}
+	return nil
+}
+
+// Get returns the user with email, deleted or not, or ErrUserNotFound
+func (r *mongoUserRepository) Get(ctx context.Context, email string) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "mongo.users.get", mongoAttributes("find")...)
+	defer span.End()
+
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		tracing.End(span, err)
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// SoftDelete marks the user deleted at the given time and invalidates
+// their access tokens; ErrUserNotFound when absent or already deleted
+func (r *mongoUserRepository) SoftDelete(ctx context.Context, email string, at time.Time) error {
+	ctx, span := tracing.Start(ctx, "mongo.users.soft_delete", mongoAttributes("update")...)
+	defer span.End()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"email": email, "deleted": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"deleted": true, "deleted_at": at.UTC(), "tokens_invalid_before": at.UTC()}},
+	)
+	if err != nil {
+		tracing.End(span, err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// DeletedBefore returns the emails of the users deleted before the given
+// time
+func (r *mongoUserRepository) DeletedBefore(ctx context.Context, before time.Time) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "mongo.users.deleted_before", mongoAttributes("find")...)
+	defer span.End()
+
+	cursor, err := r.collection.Find(ctx,
+		bson.M{"deleted": true, "deleted_at": bson.M{"$lt": before.UTC()}},
+		options.Find().SetProjection(bson.M{"email": 1}))
+	if err != nil {
+		tracing.End(span, err)
+		return nil, err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		tracing.End(span, err)
+		return nil, err
+	}
+	emails := make([]string, len(users))
+	for i, user := range users {
+		emails[i] = user.Email
+	}
+	return emails, nil
+}
+
+// PurgeDeleted removes the users deleted before the given time and
+// returns how many there were
+func (r *mongoUserRepository) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "mongo.users.purge_deleted", mongoAttributes("delete")...)
+	defer span.End()
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"deleted": true, "deleted_at": bson.M{"$lt": before.UTC()}})
+	if err != nil {
+		tracing.End(span, err)
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
+
+// mongoAttributes describes a call on the users collection
+func mongoAttributes(operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.collection.name", "users"),
+		attribute.String("db.operation.name", operation),
+	}
+}
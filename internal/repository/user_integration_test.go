@@ -0,0 +1,134 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestUserDeletionCascade soft-deletes and purges users in the Mongo user
+// repository and the in-memory fake, which must behave the same. It needs
+// a MongoDB at MONGO_TEST_URI and is skipped without one.
+func TestUserDeletionCascade(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+	db := client.Database(fmt.Sprintf("microtools_test_%d", time.Now().UnixNano()))
+	defer db.Drop(context.Background())
+
+	mongoUsers, err := repository.NewUserRepositoryIn(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, users := range map[string]repository.UserRepository{
+		"mongo": mongoUsers,
+		"fake":  testutil.NewUserRepository(),
+	} {
+		deletedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		for _, email := range []string{"ada@example.com", "bob@example.com"} {
+			if err := users.Create(ctx, models.User{Email: email, Verified: true}); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+
+		if err := users.SoftDelete(ctx, "ada@example.com", deletedAt); err != nil {
+			t.Fatalf("%s: SoftDelete: %v", name, err)
+		}
+		user, err := users.Get(ctx, "ada@example.com")
+		if err != nil || !user.Deleted || !user.DeletedAt.Equal(deletedAt) || !user.TokensInvalidBefore.Equal(deletedAt) {
+			t.Errorf("%s: deleted user = %+v, %v", name, user, err)
+		}
+		if err := users.SoftDelete(ctx, "ada@example.com", deletedAt.Add(time.Hour)); !errors.Is(err, repository.ErrUserNotFound) {
+			t.Errorf("%s: deleting twice: err = %v, want ErrUserNotFound", name, err)
+		}
+		if err := users.SoftDelete(ctx, "nobody@example.com", deletedAt); !errors.Is(err, repository.ErrUserNotFound) {
+			t.Errorf("%s: deleting an unknown user: err = %v, want ErrUserNotFound", name, err)
+		}
+
+		// The email stays taken until the user is purged, with an error
+		// of its own so registration can tell why
+		if err := users.Create(ctx, models.User{Email: "ada@example.com"}); !errors.Is(err, repository.ErrUserPendingDeletion) {
+			t.Errorf("%s: registering a deleted user's email: err = %v, want ErrUserPendingDeletion", name, err)
+		}
+		if err := users.Create(ctx, models.User{Email: "bob@example.com"}); !errors.Is(err, repository.ErrUserExists) {
+			t.Errorf("%s: registering a known email: err = %v, want ErrUserExists", name, err)
+		}
+
+		if emails, err := users.DeletedBefore(ctx, deletedAt.Add(time.Second)); err != nil || len(emails) != 1 || emails[0] != "ada@example.com" {
+			t.Errorf("%s: DeletedBefore = %v, %v, want ada", name, emails, err)
+		}
+		if emails, err := users.DeletedBefore(ctx, deletedAt); err != nil || len(emails) != 0 {
+			t.Errorf("%s: DeletedBefore the deletion time = %v, %v, want none", name, emails, err)
+		}
+
+		// Purged only once deleted before the cutoff
+		if n, err := users.PurgeDeleted(ctx, deletedAt); err != nil || n != 0 {
+			t.Errorf("%s: purge at the deletion time = %d, %v, want 0", name, n, err)
+		}
+		if n, err := users.PurgeDeleted(ctx, deletedAt.Add(time.Second)); err != nil || n != 1 {
+			t.Errorf("%s: purge after the deletion = %d, %v, want 1", name, n, err)
+		}
+		if _, err := users.Get(ctx, "ada@example.com"); !errors.Is(err, repository.ErrUserNotFound) {
+			t.Errorf("%s: purged user: err = %v, want ErrUserNotFound", name, err)
+		}
+		if user, err := users.Get(ctx, "bob@example.com"); err != nil || user.Deleted {
+			t.Errorf("%s: other user = %+v, %v, want it untouched", name, user, err)
+		}
+
+		// A registration whose mail failed is removed only while unverified
+		if err := users.Create(ctx, models.User{Email: "carol@example.com", VerificationNonce: "n1"}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := users.DeleteUnverified(ctx, "carol@example.com", "other"); !errors.Is(err, repository.ErrVerificationNotFound) {
+			t.Errorf("%s: DeleteUnverified with another nonce: err = %v, want ErrVerificationNotFound", name, err)
+		}
+		if err := users.DeleteUnverified(ctx, "bob@example.com", ""); !errors.Is(err, repository.ErrVerificationNotFound) {
+			t.Errorf("%s: DeleteUnverified of a verified user: err = %v, want ErrVerificationNotFound", name, err)
+		}
+		if err := users.DeleteUnverified(ctx, "carol@example.com", "n1"); err != nil {
+			t.Errorf("%s: DeleteUnverified: %v", name, err)
+		}
+		if _, err := users.Get(ctx, "carol@example.com"); !errors.Is(err, repository.ErrUserNotFound) {
+			t.Errorf("%s: removed registration: err = %v, want ErrUserNotFound", name, err)
+		}
+
+		// An unverified registration is replaced only once older than the
+		// cutoff, and a verified user never
+		registered := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+		if err := users.Create(ctx, models.User{Email: "dave@example.com", VerificationNonce: "n1", CreatedAt: registered}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		again := models.User{Email: "dave@example.com", VerificationNonce: "n2", CreatedAt: registered.Add(time.Hour)}
+		if err := users.ReplaceUnverified(ctx, again, registered); !errors.Is(err, repository.ErrUserExists) {
+			t.Errorf("%s: ReplaceUnverified before the cutoff: err = %v, want ErrUserExists", name, err)
+		}
+		if err := users.ReplaceUnverified(ctx, again, registered.Add(time.Second)); err != nil {
+			t.Errorf("%s: ReplaceUnverified: %v", name, err)
+		}
+		if user, err := users.Get(ctx, "dave@example.com"); err != nil || user.VerificationNonce != "n2" {
+			t.Errorf("%s: replaced user = %+v, %v, want nonce n2", name, user, err)
+		}
+		if err := users.ReplaceUnverified(ctx, models.User{Email: "bob@example.com"}, registered.Add(24*time.Hour)); !errors.Is(err, repository.ErrUserExists) {
+			t.Errorf("%s: ReplaceUnverified of a verified user: err = %v, want ErrUserExists", name, err)
+		}
+	}
+}
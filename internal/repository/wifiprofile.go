@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrWifiProfileNotFound means the owner has no WiFi network by the name
+	ErrWifiProfileNotFound = errors.New("WiFi network not found")
+	// ErrWifiProfileExists means the owner already has a WiFi network by
+	// the name
+	ErrWifiProfileExists = errors.New("WiFi network already exists")
+	// ErrWifiProfileConflict means the network was rotated or deleted
+	// since it was read
+	ErrWifiProfileConflict = errors.New("WiFi network changed since it was read")
+)
+
+// WifiProfileID is the ID of the owner's network called name
+func WifiProfileID(owner, name string) string {
+	return owner + "/" + name
+}
+
+// WifiProfileStore keeps rotating guest WiFi networks. Rotations are
+// conditional on the version they read, so replicas rotating the same
+// network at once cannot both apply.
+type WifiProfileStore interface {
+	// Create stores a new network, or returns ErrWifiProfileExists
+	Create(ctx context.Context, profile models.WifiProfile) error
+	// Get returns the owner's network called name, or
+	// ErrWifiProfileNotFound
+	Get(ctx context.Context, owner, name string) (models.WifiProfile, error)
+	// Due returns the networks whose next rotation is at or before now
+	Due(ctx context.Context, now time.Time) ([]models.WifiProfile, error)
+	// Rotate replaces the password and rotation times of profile if its
+	// stored version is still version, or returns ErrWifiProfileConflict
+	Rotate(ctx context.Context, profile models.WifiProfile, version int64) error
+	// Delete removes the owner's network called name, or returns
+	// ErrWifiProfileNotFound
+	Delete(ctx context.Context, owner, name string) error
+	// ListByOwner returns the owner's networks ordered by name
+	ListByOwner(ctx context.Context, owner string) ([]models.WifiProfile, error)
+	// DeleteByOwner removes all the owner's networks and returns how many
+	// there were
+	DeleteByOwner(ctx context.Context, owner string) (int, error)
+}
+
+type mongoWifiProfileStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoWifiProfileStore creates a WifiProfileStore backed by the
+// wifi_profiles collection of the microapps database. It ensures the
+// indexes the scheduler finds due networks with and the export and the
+// retention sweep find an owner's networks with.
+func NewMongoWifiProfileStore(ctx context.Context, client *mongo.Client) (WifiProfileStore, error) {
+	collection := client.Database("microapps").Collection("wifi_profiles")
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"next_rotation_at": 1}},
+		{Keys: bson.M{"owner": 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mongoWifiProfileStore{collection: collection}, nil
+}
+
+// Create stores a new network, or returns ErrWifiProfileExists
+func (s *mongoWifiProfileStore) Create(ctx context.Context, profile models.WifiProfile) (err error) {
+	ctx, span := tracing.Start(ctx, "mongo.wifi_profiles.create", wifiProfileAttributes("insert")...)
+	defer func() {
+		if errors.Is(err, ErrWifiProfileExists) {
+			span.End()
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	_, err = s.collection.InsertOne(ctx, profile)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrWifiProfileExists
+	}
+	return err
+}
+
+// Get returns the owner's network called name
+func (s *mongoWifiProfileStore) Get(ctx context.Context, owner, name string) (profile models.WifiProfile, err error) {
+	ctx, span := tracing.Start(ctx, "mongo.wifi_profiles.find", wifiProfileAttributes("find")...)
+	defer func() {
+		if errors.Is(err, ErrWifiProfileNotFound) {
+			span.End()
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	err = s.collection.FindOne(ctx, bson.M{"_id": WifiProfileID(owner, name)}).Decode(&profile)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.WifiProfile{}, ErrWifiProfileNotFound
+	}
+	return profile, err
+}
+
+// Due returns the networks whose next rotation is at or before now
+func (s *mongoWifiProfileStore) Due(ctx context.Context, now time.Time) (profiles []models.WifiProfile, err error) {
+	ctx, span := tracing.Start(ctx, "mongo.wifi_profiles.due", wifiProfileAttributes("find")...)
+	defer func() { tracing.End(span, err) }()
+
+	cursor, err := s.collection.Find(ctx, bson.M{"next_rotation_at": bson.M{"$lte": now.UTC()}},
+		options.Find().SetSort(bson.M{"next_rotation_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &profiles)
+	return profiles, err
+}
+
+// Rotate replaces the password and rotation times of profile if its
+// stored version is still version
+func (s *mongoWifiProfileStore) Rotate(ctx context.Context, profile models.WifiProfile, version int64) (err error) {
+	ctx, span := tracing.Start(ctx, "mongo.wifi_profiles.rotate", wifiProfileAttributes("update")...)
+	defer func() {
+		if errors.Is(err, ErrWifiProfileConflict) {
+			span.End()
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": profile.ID, "version": version},
+		bson.M{"$set": bson.M{
+			"password_hash":    profile.PasswordHash,
+			"sealed_password":  profile.SealedPassword,
+			"version":          profile.Version,
+			"rotated_at":       profile.RotatedAt.UTC(),
+			"next_rotation_at": profile.NextRotationAt.UTC(),
+		}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrWifiProfileConflict
+	}
+	return nil
+}
+
+// Delete removes the owner's network called name
+func (s *mongoWifiProfileStore) Delete(ctx context.Context, owner, name string) error {
+	ctx, span := tracing.Start(ctx, "mongo.wifi_profiles.delete", wifiProfileAttributes("delete")...)
+	defer span.End()
+
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": WifiProfileID(owner, name)})
+	if err != nil {
+		tracing.End(span, err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrWifiProfileNotFound
+	}
+	return nil
+}
+
+// ListByOwner returns the owner's networks ordered by name
+func (s *mongoWifiProfileStore) ListByOwner(ctx context.Context, owner string) (profiles []models.WifiProfile, err error) {
+	ctx, span := tracing.Start(ctx, "mongo.wifi_profiles.list_by_owner", wifiProfileAttributes("find")...)
+	defer func() { tracing.End(span, err) }()
+
+	cursor, err := s.collection.Find(ctx, bson.M{"owner": owner}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &profiles)
+	return profiles, err
+}
+
+// DeleteByOwner removes all the owner's networks
+func (s *mongoWifiProfileStore) DeleteByOwner(ctx context.Context, owner string) (deleted int, err error) {
+	ctx, span := tracing.Start(ctx, "mongo.wifi_profiles.delete_by_owner", wifiProfileAttributes("delete")...)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.collection.DeleteMany(ctx, bson.M{"owner": owner})
+	if err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
+
+// wifiProfileAttributes describes a call on the wifi_profiles collection
+func wifiProfileAttributes(operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.collection.name", "wifi_profiles"),
+		attribute.String("db.operation.name", operation),
+	}
+}
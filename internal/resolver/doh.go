@@ -0,0 +1,259 @@
+// Package resolver holds DNS resolvers used in place of the system one.
+// DoH sends queries to a DNS-over-HTTPS endpoint (RFC 8484) so lookups
+// never reach the local network's resolver.
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/dnscache"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	dnsMessageType = "application/dns-message"
+	// maxResponseSize is the largest DNS message; longer bodies are rejected
+	maxResponseSize = 65535
+)
+
+var _ dnscache.Resolver = (*DoH)(nil)
+
+// DoH resolves names with a DNS-over-HTTPS endpoint. Queries are sent
+// with GET, which lets HTTP caches in between answer them, or with POST.
+// Connections to the endpoint are kept alive and reused.
+type DoH struct {
+	endpoint string
+	post     bool
+	client   *http.Client
+}
+
+// NewDoH creates a resolver for endpoint, e.g.
+// config.DefaultDoHEndpoint, sending queries with method (GET or POST)
+// and giving up on a request after timeout
+func NewDoH(endpoint, method string, timeout time.Duration) *DoH {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 16
+	return &DoH{
+		endpoint: endpoint,
+		post:     strings.EqualFold(method, http.MethodPost),
+		client:   &http.Client{Transport: tracing.Transport(transport), Timeout: timeout},
+	}
+}
+
+// LookupMX returns the MX records of name sorted by preference
+func (d *DoH) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	answers, err := d.query(ctx, name, dnsmessage.TypeMX)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*net.MX, 0, len(answers))
+	for _, answer := range answers {
+		mx := answer.Body.(*dnsmessage.MXResource)
+		records = append(records, &net.MX{Host: mx.MX.String(), Pref: mx.Pref})
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Pref < records[j].Pref })
+	return records, nil
+}
+
+// LookupHost returns the IPv4 and IPv6 addresses of host. Like
+// net.Resolver it fails only when neither family resolves.
+func (d *DoH) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	types := []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}
+	answers := make([][]dnsmessage.Resource, len(types))
+	errs := make([]error, len(types))
+	var wg sync.WaitGroup
+	for i, qtype := range types {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answers[i], errs[i] = d.query(ctx, host, qtype)
+		}()
+	}
+	wg.Wait()
+
+	var addrs []string
+	for _, family := range answers {
+		for _, answer := range family {
+			switch body := answer.Body.(type) {
+			case *dnsmessage.AResource:
+				addrs = append(addrs, net.IP(body.A[:]).String())
+			case *dnsmessage.AAAAResource:
+				addrs = append(addrs, net.IP(body.AAAA[:]).String())
+			}
+		}
+	}
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+	// a failure to get an answer outweighs a negative one
+	for _, err := range errs {
+		if err != nil && !dnscache.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return nil, errs[0]
+}
+
+// query asks the endpoint for the qtype records of name and returns the
+// answers of that type; a negative answer is a not-found *net.DNSError
+func (d *DoH) query(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	msg, err := buildQuery(name, qtype)
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: name}
+	}
+	body, err := d.exchange(ctx, msg)
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: name, Server: d.endpoint, IsTimeout: isTimeout(err), IsTemporary: true}
+	}
+	return parseResponse(body, name, qtype, d.endpoint)
+}
+
+// exchange sends one query and returns the response message
+func (d *DoH) exchange(ctx context.Context, msg []byte) ([]byte, error) {
+	var req *http.Request
+	var err error
+	if d.post {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, d.endpoint, bytes.NewReader(msg))
+		if err == nil {
+			req.Header.Set("Content-Type", dnsMessageType)
+		}
+	} else {
+		separator := "?"
+		if strings.Contains(d.endpoint, "?") {
+			separator = "&"
+		}
+		url := d.endpoint + separator + "dns=" + base64.RawURLEncoding.EncodeToString(msg)
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", dnsMessageType)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned status %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, dnsMessageType) {
+		return nil, fmt.Errorf("DoH server returned content type %q", contentType)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxResponseSize {
+		return nil, fmt.Errorf("DoH response exceeds %d bytes", maxResponseSize)
+	}
+	return body, nil
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// buildQuery encodes a recursive query for the qtype records of name. The
+// ID is 0 as RFC 8484 recommends, so identical GET queries are cacheable.
+func buildQuery(name string, qtype dnsmessage.Type) ([]byte, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	b := dnsmessage.NewBuilder(make([]byte, 0, 512), dnsmessage.Header{RecursionDesired: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// notFoundError is a negative answer that carried the zone's SOA record,
+// whose minimum dnscache honors as the negative caching TTL
+type notFoundError struct {
+	*net.DNSError
+	soaMinimum time.Duration
+}
+
+func (e *notFoundError) SOAMinimum() time.Duration {
+	return e.soaMinimum
+}
+
+func (e *notFoundError) Unwrap() error {
+	return e.DNSError
+}
+
+// parseResponse checks a response to a query for the qtype records of
+// name and returns the answers of that type, including those reached
+// through a CNAME. NXDOMAIN and answers without records of the type
+// (NODATA) are not-found errors, SERVFAIL is temporary, and truncated or
+// malformed messages are failures.
+func parseResponse(msg []byte, name string, qtype dnsmessage.Type, server string) ([]dnsmessage.Resource, error) {
+	fail := func(reason string, temporary bool) error {
+		return &net.DNSError{Err: reason, Name: name, Server: server, IsTemporary: temporary}
+	}
+
+	var m dnsmessage.Message
+	if err := m.Unpack(msg); err != nil {
+		return nil, fail("malformed DNS response", false)
+	}
+	if !m.Header.Response || len(m.Questions) != 1 || m.Questions[0].Type != qtype {
+		return nil, fail("DNS response does not answer the query", false)
+	}
+	if m.Header.Truncated {
+		return nil, fail("truncated DNS response", true)
+	}
+
+	switch m.Header.RCode {
+	case dnsmessage.RCodeSuccess, dnsmessage.RCodeNameError:
+	case dnsmessage.RCodeServerFailure:
+		return nil, fail("server misbehaving", true)
+	default:
+		return nil, fail("DNS server returned "+m.Header.RCode.String(), false)
+	}
+
+	var answers []dnsmessage.Resource
+	if m.Header.RCode == dnsmessage.RCodeSuccess {
+		for _, answer := range m.Answers {
+			if answer.Header.Type == qtype && answer.Header.Class == dnsmessage.ClassINET {
+				answers = append(answers, answer)
+			}
+		}
+	}
+	if len(answers) > 0 {
+		return answers, nil
+	}
+
+	notFound := &net.DNSError{Err: "no such host", Name: name, Server: server, IsNotFound: true}
+	for _, authority := range m.Authorities {
+		if soa, ok := authority.Body.(*dnsmessage.SOAResource); ok {
+			ttl := min(authority.Header.TTL, soa.MinTTL)
+			return nil, &notFoundError{DNSError: notFound, soaMinimum: time.Duration(ttl) * time.Second}
+		}
+	}
+	return nil, notFound
+}
@@ -0,0 +1,313 @@
+package resolver
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/dnscache"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// answerFunc builds the response to a query
+type answerFunc func(q dnsmessage.Question) []byte
+
+// dohServer is an RFC 8484 endpoint answering with answer. It counts the
+// connections clients opened and the methods they used.
+type dohServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	connections int
+	methods     []string
+}
+
+func newDoHServer(t *testing.T, answer answerFunc) *dohServer {
+	t.Helper()
+	s := &dohServer{}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg []byte
+		var err error
+		if r.Method == http.MethodPost {
+			if r.Header.Get("Content-Type") != dnsMessageType {
+				t.Errorf("POST with Content-Type %q", r.Header.Get("Content-Type"))
+			}
+			msg, err = io.ReadAll(r.Body)
+		} else {
+			msg, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		}
+		if err != nil || r.Header.Get("Accept") != dnsMessageType {
+			t.Errorf("bad %s request: %v, Accept %q", r.Method, err, r.Header.Get("Accept"))
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(msg); err != nil || len(query.Questions) != 1 || query.Header.ID != 0 || !query.Header.RecursionDesired {
+			t.Errorf("bad query %+v: %v", query, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.methods = append(s.methods, r.Method)
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", dnsMessageType)
+		w.Write(answer(query.Questions[0]))
+	}))
+	s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			s.mu.Lock()
+			s.connections++
+			s.mu.Unlock()
+		}
+	}
+	s.Start()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// response is a canned answer to q
+type response struct {
+	rcode       dnsmessage.RCode
+	truncated   bool
+	mx          map[string]uint16 // host to preference
+	addrs       []string
+	cname       string
+	soaTTL      uint32 // with soaMinimum, adds an SOA authority record
+	soaMinimum  uint32
+	otherRecord bool // adds a TXT record, which is not the asked type
+}
+
+// pack panics on a builder error, which is a bug in the fixture
+func (resp response) pack(q dnsmessage.Question) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, RecursionAvailable: true, RCode: resp.rcode, Truncated: resp.truncated})
+	b.EnableCompression()
+	must := func(err error) {
+		if err != nil {
+			panic(err)
+		}
+	}
+	must(b.StartQuestions())
+	must(b.Question(q))
+	must(b.StartAnswers())
+	header := func(qtype dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: q.Name, Type: qtype, Class: dnsmessage.ClassINET, TTL: 300}
+	}
+	if resp.cname != "" {
+		must(b.CNAMEResource(header(dnsmessage.TypeCNAME), dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(resp.cname)}))
+	}
+	if resp.otherRecord {
+		must(b.TXTResource(header(dnsmessage.TypeTXT), dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}))
+	}
+	switch q.Type {
+	case dnsmessage.TypeMX:
+		for host, pref := range resp.mx {
+			must(b.MXResource(header(dnsmessage.TypeMX), dnsmessage.MXResource{Pref: pref, MX: dnsmessage.MustNewName(host)}))
+		}
+	case dnsmessage.TypeA, dnsmessage.TypeAAAA:
+		for _, addr := range resp.addrs {
+			ip := net.ParseIP(addr)
+			if ip4 := ip.To4(); ip4 != nil && q.Type == dnsmessage.TypeA {
+				must(b.AResource(header(dnsmessage.TypeA), dnsmessage.AResource{A: [4]byte(ip4)}))
+			} else if ip4 == nil && q.Type == dnsmessage.TypeAAAA {
+				must(b.AAAAResource(header(dnsmessage.TypeAAAA), dnsmessage.AAAAResource{AAAA: [16]byte(ip)}))
+			}
+		}
+	}
+	if resp.soaMinimum != 0 {
+		must(b.StartAuthorities())
+		soa := header(dnsmessage.TypeSOA)
+		soa.TTL = resp.soaTTL
+		must(b.SOAResource(soa, dnsmessage.SOAResource{
+			NS: dnsmessage.MustNewName("ns.example."), MBox: dnsmessage.MustNewName("hostmaster.example."),
+			Serial: 1, Refresh: 3600, Retry: 600, Expire: 86400, MinTTL: resp.soaMinimum,
+		}))
+	}
+	msg, err := b.Finish()
+	must(err)
+	return msg
+}
+
+// zone answers queries from responses keyed by name without the trailing
+// dot; other names are NXDOMAIN
+func zone(responses map[string]response) answerFunc {
+	return func(q dnsmessage.Question) []byte {
+		resp, ok := responses[strings.TrimSuffix(q.Name.String(), ".")]
+		if !ok {
+			resp = response{rcode: dnsmessage.RCodeNameError}
+		}
+		return resp.pack(q)
+	}
+}
+
+func TestDoHLookups(t *testing.T) {
+	server := newDoHServer(t, zone(map[string]response{
+		"example.com": {
+			mx:    map[string]uint16{"mx2.example.com.": 20, "mx1.example.com.": 10, "mx3.example.com.": 30},
+			addrs: []string{"192.0.2.1", "2001:db8::1"},
+		},
+		"www.example.com": {cname: "example.com.", addrs: []string{"192.0.2.1"}},
+		"v4only.example":  {addrs: []string{"198.51.100.7"}, otherRecord: true},
+	}))
+
+	for _, method := range []string{"GET", "POST"} {
+		doh := NewDoH(server.URL+"/dns-query", method, time.Second)
+		ctx := context.Background()
+
+		records, err := doh.LookupMX(ctx, "example.com")
+		if err != nil {
+			t.Fatalf("%s: LookupMX: %v", method, err)
+		}
+		var hosts []string
+		for _, mx := range records {
+			hosts = append(hosts, mx.Host)
+		}
+		if !slices.Equal(hosts, []string{"mx1.example.com.", "mx2.example.com.", "mx3.example.com."}) {
+			t.Errorf("%s: MX hosts %v, want them by preference", method, hosts)
+		}
+
+		addrs, err := doh.LookupHost(ctx, "example.com")
+		slices.Sort(addrs)
+		if err != nil || !slices.Equal(addrs, []string{"192.0.2.1", "2001:db8::1"}) {
+			t.Errorf("%s: LookupHost = %v, %v", method, addrs, err)
+		}
+		if addrs, err := doh.LookupHost(ctx, "www.example.com"); err != nil || !slices.Equal(addrs, []string{"192.0.2.1"}) {
+			t.Errorf("%s: through a CNAME = %v, %v", method, addrs, err)
+		}
+		// The missing AAAA records do not fail the lookup
+		if addrs, err := doh.LookupHost(ctx, "v4only.example"); err != nil || !slices.Equal(addrs, []string{"198.51.100.7"}) {
+			t.Errorf("%s: IPv4 only = %v, %v", method, addrs, err)
+		}
+		if addrs, err := doh.LookupHost(ctx, "203.0.113.9"); err != nil || !slices.Equal(addrs, []string{"203.0.113.9"}) {
+			t.Errorf("%s: an IP address = %v, %v", method, addrs, err)
+		}
+	}
+
+	// Every query of both resolvers used its method and a kept-alive
+	// connection; LookupHost's parallel A and AAAA queries may open two
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if n := slices.Index(server.methods, "POST"); n <= 0 || slices.Contains(server.methods[:n], "POST") || slices.Contains(server.methods[n:], "GET") {
+		t.Errorf("methods = %v, want GETs then POSTs", server.methods)
+	}
+	if server.connections > 4 {
+		t.Errorf("%d connections for %d queries, want them reused", server.connections, len(server.methods))
+	}
+}
+
+func TestDoHNegativeAnswers(t *testing.T) {
+	server := newDoHServer(t, zone(map[string]response{
+		"nodata.example": {soaTTL: 3600, soaMinimum: 120},
+		"short.example":  {soaTTL: 30, soaMinimum: 900},
+		"nosoa.example":  {otherRecord: true},
+	}))
+	doh := NewDoH(server.URL, "GET", time.Second)
+
+	tests := []struct {
+		name string
+		soa  time.Duration // zero without an SOA record
+	}{
+		{"nodata.example", 120 * time.Second},
+		{"short.example", 30 * time.Second}, // the record's TTL caps its minimum
+		{"nosoa.example", 0},
+		{"nxdomain.example", 0},
+	}
+	for _, tt := range tests {
+		_, err := doh.LookupMX(context.Background(), tt.name)
+		if !dnscache.IsNotFound(err) {
+			t.Errorf("%s: err = %v, want a not-found answer", tt.name, err)
+			continue
+		}
+		soa, ok := err.(dnscache.SOAError)
+		if ok != (tt.soa != 0) || (ok && soa.SOAMinimum() != tt.soa) {
+			t.Errorf("%s: SOA error %v (%T), want a minimum of %v", tt.name, ok, err, tt.soa)
+		}
+	}
+	if _, err := doh.LookupHost(context.Background(), "nxdomain.example"); !dnscache.IsNotFound(err) {
+		t.Errorf("LookupHost: err = %v, want a not-found answer", err)
+	}
+}
+
+func TestDoHFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		temporary bool
+	}{
+		{"SERVFAIL", respond(response{rcode: dnsmessage.RCodeServerFailure}), true},
+		{"REFUSED", respond(response{rcode: dnsmessage.RCodeRefused}), false},
+		{"truncated", respond(response{truncated: true, mx: map[string]uint16{"mx.example.com.": 10}}), true},
+		{"malformed", raw([]byte{0x00, 0x00, 0x81, 0x80, 0x00, 0x01, 0x00, 0x01, 0x07}), false},
+		{"a query instead of a response", raw(func() []byte {
+			msg, _ := buildQuery("example.com", dnsmessage.TypeMX)
+			return msg
+		}()), false},
+		{"oversized", raw(make([]byte, maxResponseSize+1)), true},
+		{"HTTP error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		}, true},
+		{"not a DNS message", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		}, true},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(tt.handler)
+		_, err := NewDoH(server.URL, "POST", time.Second).LookupMX(context.Background(), "example.com")
+		server.Close()
+
+		dnsErr, ok := err.(*net.DNSError)
+		if !ok || dnsErr.IsNotFound || dnsErr.IsTemporary != tt.temporary || dnscache.IsNotFound(err) {
+			t.Errorf("%s: err = %#v, want a failure with temporary %v", tt.name, err, tt.temporary)
+		}
+	}
+}
+
+func TestDoHTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	_, err := NewDoH(server.URL, "GET", 50*time.Millisecond).LookupMX(context.Background(), "example.com")
+	if dnsErr, ok := err.(*net.DNSError); !ok || !dnsErr.IsTimeout || dnscache.IsNotFound(err) {
+		t.Errorf("err = %#v, want a timeout", err)
+	}
+
+	// A host lookup reports the failure over a negative answer
+	_, err = NewDoH(server.URL, "GET", 50*time.Millisecond).LookupHost(context.Background(), "example.com")
+	if dnsErr, ok := err.(*net.DNSError); !ok || !dnsErr.IsTimeout {
+		t.Errorf("LookupHost: err = %#v, want a timeout", err)
+	}
+}
+
+// respond serves resp to every query
+func respond(resp response) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var query dnsmessage.Message
+		if err := query.Unpack(body); err != nil || len(query.Questions) != 1 {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", dnsMessageType)
+		w.Write(resp.pack(query.Questions[0]))
+	}
+}
+
+// raw serves body as a DNS message
+func raw(body []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", dnsMessageType)
+		w.Write(body)
+	}
+}
@@ -0,0 +1,115 @@
+// Package resultmeta collects how a result was produced: the datasets it
+// was computed from and the optional checks that were skipped because a
+// dependency was unavailable. A Collector travels in the request context so
+// services record into it without a metadata field of their own.
+package resultmeta
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/dataset"
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+type contextKey struct{}
+
+// Collector records result metadata for one request. Its methods are safe
+// to call on a nil Collector, which discards everything, so callers need
+// not check whether metadata was requested.
+type Collector struct {
+	mu       sync.Mutex
+	start    time.Time
+	datasets []datasetUse
+	skipped  []models.SkippedCheck
+}
+
+type datasetUse struct {
+	name    string
+	version string
+	builtAt time.Time
+}
+
+// New creates a Collector whose processing time starts now
+func New() *Collector {
+	return &Collector{start: time.Now()}
+}
+
+// NewContext returns a copy of ctx carrying c
+func NewContext(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the Collector of ctx, or nil when metadata was not requested
+func FromContext(ctx context.Context) *Collector {
+	c, _ := ctx.Value(contextKey{}).(*Collector)
+	return c
+}
+
+// UseDataset records that the result used version of the named dataset.
+// builtAt is the zero time for datasets without a build date.
+func (c *Collector) UseDataset(name, version string, builtAt time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, d := range c.datasets {
+		if d.name == name {
+			return
+		}
+	}
+	c.datasets = append(c.datasets, datasetUse{name: name, version: version, builtAt: builtAt})
+}
+
+// UseRegisteredDataset records the current version of a catalog from the
+// dataset registry
+func (c *Collector) UseRegisteredDataset(name string) {
+	if c == nil {
+		return
+	}
+	info, _, err := dataset.Get(name)
+	if err != nil {
+		return
+	}
+	c.UseDataset(name, info.Version, time.Time{})
+}
+
+// SkipCheck records that check did not run, and why
+func (c *Collector) SkipCheck(check, reason string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.skipped {
+		if s.Check == check {
+			return
+		}
+	}
+	c.skipped = append(c.skipped, models.SkippedCheck{Check: check, Reason: reason})
+}
+
+// Meta returns the collected metadata. Datasets built more than staleAfter
+// ago are flagged stale; a zero staleAfter disables the flag.
+func (c *Collector) Meta(staleAfter time.Duration) models.ResultMeta {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	meta := models.ResultMeta{
+		Datasets:         make([]models.DatasetUsage, 0, len(c.datasets)),
+		SkippedChecks:    append([]models.SkippedCheck{}, c.skipped...),
+		ProcessingTimeMs: float64(now.Sub(c.start).Microseconds()) / 1000,
+	}
+	for _, d := range c.datasets {
+		usage := models.DatasetUsage{Name: d.name, Version: d.version}
+		if !d.builtAt.IsZero() {
+			usage.BuiltAt = d.builtAt.UTC().Format("2006-01-02")
+			usage.Stale = staleAfter > 0 && now.Sub(d.builtAt) > staleAfter
+		}
+		meta.Datasets = append(meta.Datasets, usage)
+	}
+	return meta
+}
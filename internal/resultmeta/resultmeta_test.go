@@ -0,0 +1,40 @@
+package resultmeta
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCollector(t *testing.T) {
+	// A nil Collector, as without ?include_meta=true, discards everything
+	var none *Collector
+	none.UseDataset("geolite2-city", "2025-01-01", time.Now())
+	none.SkipCheck("mx", "offline")
+	if FromContext(context.Background()) != nil {
+		t.Error("FromContext without a Collector is not nil")
+	}
+
+	c := New()
+	ctx := NewContext(context.Background(), c)
+	FromContext(ctx).UseDataset("geolite2-city", "2024-01-01", time.Now().AddDate(-1, 0, 0))
+	FromContext(ctx).UseDataset("geolite2-city", "2024-01-01", time.Now().AddDate(-1, 0, 0))
+	FromContext(ctx).UseDataset("iban-registry", "v3", time.Time{})
+	FromContext(ctx).SkipCheck("mx", "offline")
+	FromContext(ctx).SkipCheck("mx", "offline again")
+
+	meta := c.Meta(30 * 24 * time.Hour)
+	if len(meta.Datasets) != 2 || len(meta.SkippedChecks) != 1 || meta.SkippedChecks[0].Reason != "offline" {
+		t.Fatalf("meta = %+v, want each dataset and check once", meta)
+	}
+	geo, registry := meta.Datasets[0], meta.Datasets[1]
+	if !geo.Stale || geo.BuiltAt == "" || registry.Stale || registry.BuiltAt != "" || registry.Version != "v3" {
+		t.Errorf("datasets = %+v", meta.Datasets)
+	}
+	if meta.ProcessingTimeMs < 0 {
+		t.Errorf("processing time = %v", meta.ProcessingTimeMs)
+	}
+	if c.Meta(0).Datasets[0].Stale {
+		t.Error("a zero limit flags datasets stale")
+	}
+}
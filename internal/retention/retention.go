@@ -0,0 +1,80 @@
+// Package retention removes personal data once it may no longer be kept.
+// Deleting a user only marks the document; the Sweeper purges it after
+// the deletion grace period, which leaves time to undo mistakes and for
+// backups to age out consistently.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/repository"
+)
+
+// SweepInterval is how often Run purges expired users
+const SweepInterval = time.Hour
+
+// OwnedData is data stored per user, such as their rotating WiFi
+// networks, that is purged together with the user
+type OwnedData interface {
+	// DeleteOwner removes everything owner has and returns how many
+	// documents there were
+	DeleteOwner(ctx context.Context, owner string) (int, error)
+}
+
+// Sweeper hard-deletes users whose deletion grace period has passed
+type Sweeper struct {
+	users repository.UserRepository
+	owned []OwnedData
+	clock clock.Clock
+	grace time.Duration
+}
+
+// NewSweeper purges users deleted more than grace ago, as told by c,
+// with their owned data
+func NewSweeper(users repository.UserRepository, c clock.Clock, grace time.Duration, owned ...OwnedData) *Sweeper {
+	return &Sweeper{users: users, owned: owned, clock: c, grace: grace}
+}
+
+// Sweep purges the users whose grace period has passed and returns how
+// many there were. Their owned data goes first: when it cannot be
+// removed the users stay, so the next sweep retries rather than leaving
+// data without an owner.
+func (s *Sweeper) Sweep(ctx context.Context) (int, error) {
+	before := s.clock.Now().Add(-s.grace)
+	if len(s.owned) > 0 {
+		emails, err := s.users.DeletedBefore(ctx, before)
+		if err != nil {
+			return 0, err
+		}
+		for _, email := range emails {
+			for _, owned := range s.owned {
+				if _, err := owned.DeleteOwner(ctx, email); err != nil {
+					return 0, fmt.Errorf("purging the data of %s: %w", email, err)
+				}
+			}
+		}
+	}
+	return s.users.PurgeDeleted(ctx, before)
+}
+
+// Run sweeps every interval until ctx is done, logging failures
+func (s *Sweeper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if purged, err := s.Sweep(ctx); err != nil {
+			log.Printf("Failed to purge deleted users: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted users", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
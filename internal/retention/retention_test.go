@@ -0,0 +1,116 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+	"github.com/innovelabs/microtools-go/internal/wifirotation"
+)
+
+func TestSweep(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := testutil.NewClock(start)
+	users := testutil.NewUserRepository()
+	for _, email := range []string{"ada@example.com", "bob@example.com", "cy@example.com"} {
+		if err := users.Create(ctx, models.User{Email: email, Verified: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sweeper := NewSweeper(users, clock, 30*24*time.Hour)
+
+	users.SoftDelete(ctx, "ada@example.com", clock.Now())
+	clock.Advance(10 * 24 * time.Hour)
+	users.SoftDelete(ctx, "bob@example.com", clock.Now())
+
+	// Nobody's grace period has passed yet
+	clock.Advance(20*24*time.Hour - time.Second)
+	if n, err := sweeper.Sweep(ctx); err != nil || n != 0 {
+		t.Errorf("within the grace period: purged %d, %v", n, err)
+	}
+
+	clock.Advance(2 * time.Second)
+	if n, err := sweeper.Sweep(ctx); err != nil || n != 1 {
+		t.Errorf("after ada's grace period: purged %d, %v, want 1", n, err)
+	}
+	if _, err := users.Get(ctx, "ada@example.com"); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("ada: err = %v, want the user purged", err)
+	}
+	if user, err := users.Get(ctx, "bob@example.com"); err != nil || !user.Deleted {
+		t.Errorf("bob = %+v, %v, want the user kept until the grace period", user, err)
+	}
+
+	clock.Advance(10 * 24 * time.Hour)
+	if n, err := sweeper.Sweep(ctx); err != nil || n != 1 {
+		t.Errorf("after bob's grace period: purged %d, %v, want 1", n, err)
+	}
+	// Users who were never deleted stay
+	if user, err := users.Get(ctx, "cy@example.com"); err != nil || user.Deleted {
+		t.Errorf("cy = %+v, %v", user, err)
+	}
+}
+
+// failingOwnedData cannot be removed, like a store that is down
+type failingOwnedData struct{}
+
+func (failingOwnedData) DeleteOwner(ctx context.Context, owner string) (int, error) {
+	return 0, errors.New("server selection timeout")
+}
+
+func TestSweepPurgesOwnedData(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	users := testutil.NewUserRepository()
+	networks := testutil.NewWifiProfileStore()
+	rotator, err := wifirotation.NewRotator(networks, users, make([]byte, 32), clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"ada@example.com", "bob@example.com"} {
+		users.Create(ctx, models.User{Email: email, Verified: true})
+		if _, err := rotator.Create(ctx, email, models.WifiRotationRequest{Name: "lobby", SSID: "Guest"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	users.SoftDelete(ctx, "ada@example.com", clock.Now())
+	clock.Advance(time.Hour)
+
+	// Owned data that cannot be removed keeps the user for the next sweep
+	if n, err := NewSweeper(users, clock, time.Minute, rotator, failingOwnedData{}).Sweep(ctx); err == nil || n != 0 {
+		t.Errorf("failing owned data: purged %d, %v, want an error", n, err)
+	}
+	if _, err := users.Get(ctx, "ada@example.com"); err != nil {
+		t.Errorf("ada purged although their data was not: %v", err)
+	}
+
+	if n, err := NewSweeper(users, clock, time.Minute, rotator).Sweep(ctx); err != nil || n != 1 {
+		t.Fatalf("purged %d, %v, want 1", n, err)
+	}
+	if _, err := rotator.Current(ctx, "ada@example.com", "lobby"); !errors.Is(err, repository.ErrWifiProfileNotFound) {
+		t.Errorf("ada's network: err = %v, want it purged with its owner", err)
+	}
+	if _, err := rotator.Current(ctx, "bob@example.com", "lobby"); err != nil {
+		t.Errorf("bob's network: %v, want it kept", err)
+	}
+}
+
+func TestRunSweepsAtStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	users := testutil.NewUserRepository()
+	users.Create(ctx, models.User{Email: "ada@example.com"})
+	users.SoftDelete(ctx, "ada@example.com", clock.Now())
+	clock.Advance(time.Hour)
+
+	// A cancelled context still gets the first sweep, then Run returns
+	cancel()
+	NewSweeper(users, clock, time.Minute).Run(ctx, time.Hour)
+	if _, err := users.Get(context.Background(), "ada@example.com"); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("err = %v, want the user purged by the first sweep", err)
+	}
+}
@@ -0,0 +1,60 @@
+package router
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/handlers"
+)
+
+type legacyAlias struct {
+	path      string
+	canonical string
+	method    string
+	handler   http.Handler
+}
+
+// legacyAliases maps paths served by the retired root-package server to
+// their canonical routes
+func legacyAliases(h *handlers.Handlers) []legacyAlias {
+	return []legacyAlias{
+		{"/api/v1/email/validate", "/api/v1/validate/email", "POST", http.HandlerFunc(h.ValidateEmailHandler)},
+	}
+}
+
+// withLegacyAliases returns prefixes with the paths of the aliases whose
+// canonical route is under one of them
+func withLegacyAliases(aliases []legacyAlias, prefixes ...string) []string {
+	paths := prefixes
+	for _, alias := range aliases {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(alias.canonical, prefix) {
+				paths = append(paths, alias.path)
+				break
+			}
+		}
+	}
+	return paths
+}
+
+// registerLegacyAliases serves the old paths with the canonical handlers
+// behind body, the body cap and media types of their canonical validator
+// routes, marking every response deprecated so remaining traffic can be
+// measured before the aliases are removed.
+func registerLegacyAliases(router *mux.Router, h *handlers.Handlers, site *config.Config, body mux.MiddlewareFunc) {
+	for _, alias := range legacyAliases(h) {
+		router.Handle(alias.path, deprecated(alias.path, site.Path(alias.canonical), body(alias.handler))).Methods(alias.method)
+	}
+}
+
+func deprecated(path, canonical string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Warning: deprecated path %s called; use %s", path, canonical)
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+canonical+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,40 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/innovelabs/microtools-go/internal/app"
+	"github.com/innovelabs/microtools-go/internal/handlers"
+)
+
+// livePath is the liveness probe of load balancers
+const livePath = "/api/v1/live"
+
+// liveProbe answers GET and HEAD /api/v1/live ahead of the router, so the
+// probe skips routing and every middleware: a probe slowed down by request
+// logging, counters or tracing under memory pressure gets healthy
+// instances evicted. Other methods on the path, e.g. OPTIONS, and every
+// other request go to the router.
+type liveProbe struct {
+	router http.Handler
+	// path is livePath under the configured base path
+	path string
+}
+
+func (p liveProbe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == p.path && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		handlers.LiveHandler(w, r)
+		return
+	}
+	p.router.ServeHTTP(w, r)
+}
+
+// NewHandler returns the server's handler for a with opts: the router of
+// SetupRouterWithOptions behind the liveness probe
+func NewHandler(a *app.App, opts Options) http.Handler {
+	path := livePath
+	if a.Config != nil {
+		path = a.Config.Path(livePath)
+	}
+	return liveProbe{router: SetupRouterWithOptions(a, opts), path: path}
+}
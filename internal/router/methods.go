@@ -0,0 +1,132 @@
+package router
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// methodOrder is the order methods are listed in Allow headers
+var methodOrder = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// routeMethods is the path pattern and methods of one registered route
+type routeMethods struct {
+	path    *regexp.Regexp
+	methods []string
+}
+
+// methodHandler answers requests whose path matches a route but whose
+// method does not. HEAD is served as GET without a body, OPTIONS lists the
+// allowed methods, and anything else gets the 405 fallback; both carry an
+// Allow header. It replaces per-handler HEAD and OPTIONS support.
+type methodHandler struct {
+	router   *mux.Router
+	routes   []routeMethods
+	fallback http.Handler
+}
+
+// newMethodHandler walks the routes registered on router so far; it must be
+// created after the last route is added
+func newMethodHandler(router *mux.Router, fallback http.Handler) *methodHandler {
+	h := &methodHandler{router: router, fallback: fallback}
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		pattern, err := route.GetPathRegexp()
+		if err != nil {
+			return nil
+		}
+		h.routes = append(h.routes, routeMethods{path: regexp.MustCompile(pattern), methods: methods})
+		return nil
+	})
+	return h
+}
+
+// allowed returns the methods routes accept for path, or nil when no route
+// matches it. GET implies HEAD and OPTIONS is always answered.
+func (h *methodHandler) allowed(path string) []string {
+	set := map[string]bool{}
+	for _, route := range h.routes {
+		if !route.path.MatchString(path) {
+			continue
+		}
+		for _, method := range route.methods {
+			set[method] = true
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	set[http.MethodOptions] = true
+	if set[http.MethodGet] {
+		set[http.MethodHead] = true
+	}
+
+	var methods []string
+	for _, method := range methodOrder {
+		if set[method] {
+			methods = append(methods, method)
+			delete(set, method)
+		}
+	}
+	var rest []string
+	for method := range set {
+		rest = append(rest, method)
+	}
+	slices.Sort(rest)
+	return append(methods, rest...)
+}
+
+func (h *methodHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	allowed := h.allowed(r.URL.Path)
+	switch {
+	case r.Method == http.MethodHead && slices.Contains(allowed, http.MethodGet):
+		get := r.Clone(r.Context())
+		get.Method = http.MethodGet
+		h.router.ServeHTTP(headResponseWriter{w}, get)
+	case r.Method == http.MethodOptions:
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		h.fallback.ServeHTTP(w, r)
+	}
+}
+
+// orNotFound serves requests mux reports as not found through h when a
+// route matches the path under another method. Subrouter routes inherit the
+// prefix matcher, and its match on a later route clears the method mismatch
+// an earlier one recorded, so mux loses 405s for all but the last route.
+func (h *methodHandler) orNotFound(notFound http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed := h.allowed(r.URL.Path)
+		if allowed == nil || (r.Method != http.MethodHead && slices.Contains(allowed, r.Method)) {
+			notFound.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// headResponseWriter keeps the headers and status of a GET response and
+// drops its body
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,401 @@
+package router
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/maintenance"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/tenant"
+	"github.com/innovelabs/microtools-go/internal/toolstatus"
+	"github.com/innovelabs/microtools-go/web"
+)
+
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// staticAssets maps content-hashed asset paths to their embedded files so
+// they can be cached forever; a changed file gets a new URL.
+type staticAssets struct {
+	hashed map[string]string      // logical path -> hashed URL
+	files  map[string]staticAsset // hashed name -> embedded file
+
+	notFound http.Handler
+}
+
+type staticAsset struct {
+	path string
+	etag string
+}
+
+func loadStaticAssets() *staticAssets {
+	assets := &staticAssets{hashed: map[string]string{}, files: map[string]staticAsset{}}
+	root, err := fs.Sub(web.Static, "static")
+	if err != nil {
+		log.Printf("Error loading static assets: %v", err)
+		return assets
+	}
+	fs.WalkDir(root, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(root, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:4])
+		ext := path.Ext(name)
+		hashedName := strings.TrimSuffix(name, ext) + "." + hash + ext
+		assets.hashed[name] = "/static/" + hashedName
+		assets.files[hashedName] = staticAsset{path: "static/" + name, etag: `"` + hash + `"`}
+		return nil
+	})
+	return assets
+}
+
+// URL returns the hashed URL of a logical asset path for use in templates
+func (a *staticAssets) URL(name string) string {
+	if u, ok := a.hashed[name]; ok {
+		return u
+	}
+	return "/static/" + name
+}
+
+// ServeHTTP serves hashed assets with far-future caching
+func (a *staticAssets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/static/")
+	asset, ok := a.files[name]
+	if !ok {
+		a.notFound.ServeHTTP(w, r)
+		return
+	}
+	data, err := web.Static.ReadFile(asset.path)
+	if err != nil {
+		a.notFound.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", immutableCacheControl)
+	w.Header().Set("ETag", asset.etag)
+	if etagMatches(r.Header.Get("If-None-Match"), asset.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.Write(data)
+}
+
+// pageTemplate is a parsed page together with a hash of its source files
+type pageTemplate struct {
+	tmpl *template.Template
+	hash string
+}
+
+// parsePage parses a page from files. Templates link to pages with path,
+// to assets with asset and to their public URL with canonical, all under
+// the site's base path.
+func parsePage(assets *staticAssets, site *config.Config, files ...string) pageTemplate {
+	h := sha256.New()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Panicf("reading template %s: %v", file, err)
+		}
+		h.Write(data)
+	}
+	tmpl := template.Must(template.New(path.Base(files[0])).
+		Funcs(template.FuncMap{
+			"asset":     func(name string) string { return site.Path(assets.URL(name)) },
+			"path":      site.Path,
+			"percent":   percent,
+			"canonical": site.AbsURL,
+		}).
+		ParseFiles(files...))
+	return pageTemplate{tmpl: tmpl, hash: hex.EncodeToString(h.Sum(nil))}
+}
+
+// percent formats a fraction such as an error rate for display
+func percent(v float64) string {
+	return strconv.FormatFloat(v*100, 'f', 1, 64) + "%"
+}
+
+// inlineQRExample renders the QR page's live example. The SVG is
+// deterministic, so the page ETag stays stable across restarts.
+func inlineQRExample() template.HTML {
+	svg, err := generator.QRInlineSVG(models.QRRequest{
+		Type:    "url",
+		Data:    "https://microapi.innovelabs.net/qr-code-generator-api",
+		Options: models.QROptions{Size: 192},
+	})
+	if err != nil {
+		log.Printf("Failed to render the inline QR example: %v", err)
+		return ""
+	}
+	return svg
+}
+
+// pageETag identifies a rendered page by its template sources and data
+func pageETag(page pageTemplate, data PageData) string {
+	h := sha256.New()
+	h.Write([]byte(page.hash))
+	if encoded, err := json.Marshal(data); err == nil {
+		h.Write(encoded)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil))[:16] + `"`
+}
+
+// renderPage serves a page cached for maxAge seconds. Each theme renders
+// differently, so the ETag is per theme and caches vary on the cookie.
+// Pages of a branded tenant's hosts are not cached.
+func renderPage(page pageTemplate, data PageData, maxAge int) pageHandler {
+	etags := make(map[string]string, len(handlers.Themes))
+	for _, theme := range handlers.Themes {
+		themed := data
+		themed.Theme = theme
+		etags[theme] = pageETag(page, themed)
+	}
+	cacheControl := "public, max-age=" + strconv.Itoa(maxAge)
+	return pageHandler{data: data, serve: func(w http.ResponseWriter, r *http.Request) {
+		data := data
+		data.Theme = handlers.ThemeFromRequest(r)
+		// Branded pages differ per tenant and are rendered uncached
+		if data.Branding = tenantBranding(r); data.Branding != nil {
+			w.Header().Set("Cache-Control", "no-store")
+			writePage(w, page, data, http.StatusOK)
+			return
+		}
+		etag := etags[data.Theme]
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("Vary", "Cookie")
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writePage(w, page, data, http.StatusOK)
+	}}
+}
+
+// toolPage adds the JSON-LD of a tool page to data: a WebApplication
+// called name with the page's description, offering the API routes of
+// tools as described by the ToolSpec registry behind /api/v1/tools. An
+// unknown tool is a programming error and panics at startup.
+func toolPage(baseURL, name string, data PageData, tools ...string) PageData {
+	actions := make([]map[string]any, 0, len(tools))
+	for _, tool := range tools {
+		spec, ok := handlers.LookupToolSpec(tool)
+		if !ok {
+			log.Panicf("page %s names unknown tool %q", data.Canonical, tool)
+		}
+		actions = append(actions, map[string]any{
+			"@type": "ConsumeAction",
+			"name":  spec.Name,
+			"target": map[string]any{
+				"@type":       "EntryPoint",
+				"urlTemplate": baseURL + spec.Path,
+				"httpMethod":  spec.Method,
+				"contentType": "application/json",
+			},
+		})
+	}
+	// json.Marshal escapes <, > and &, so the JSON cannot end the script
+	encoded, err := json.Marshal(map[string]any{
+		"@context":            "https://schema.org",
+		"@type":               "WebApplication",
+		"name":                name,
+		"description":         data.Description,
+		"url":                 baseURL + data.Canonical,
+		"applicationCategory": "DeveloperApplication",
+		"operatingSystem":     "Any",
+		"isAccessibleForFree": true,
+		"offers":              map[string]any{"@type": "Offer", "price": "0", "priceCurrency": "USD"},
+		"potentialAction":     actions,
+	})
+	if err != nil {
+		log.Panicf("encoding structured data of %s: %v", data.Canonical, err)
+	}
+	data.StructuredData = template.JS(encoded)
+	data.tools = tools
+	return data
+}
+
+// renderToolPage serves a tool page like renderPage, except while one of
+// its tools is in maintenance: then the page carries the maintenance
+// banner and is not cached, so the banner goes away with the window.
+func renderToolPage(page pageTemplate, data PageData, maxAge int, sw *maintenance.Switch) pageHandler {
+	cached := renderPage(page, data, maxAge)
+	if sw == nil {
+		return cached
+	}
+	return pageHandler{data: data, serve: func(w http.ResponseWriter, r *http.Request) {
+		banner, down := maintenanceBanner(sw, data.tools)
+		if !down {
+			cached.serve(w, r)
+			return
+		}
+		data := data
+		data.Maintenance = banner
+		data.Theme = handlers.ThemeFromRequest(r)
+		data.Branding = tenantBranding(r)
+		w.Header().Set("Cache-Control", "no-store")
+		writePage(w, page, data, http.StatusOK)
+	}}
+}
+
+// maintenanceBanner returns the message of the first of tools in
+// maintenance
+func maintenanceBanner(sw *maintenance.Switch, tools []string) (string, bool) {
+	for _, tool := range tools {
+		if window, down := sw.Lookup(tool); down {
+			if window.Message != "" {
+				return window.Message, true
+			}
+			return maintenance.DefaultMessage, true
+		}
+	}
+	return "", false
+}
+
+// renderStatusPage renders the status page with the tracker's current
+// report; it changes with every request, so it is never cached
+func renderStatusPage(page pageTemplate, tracker *toolstatus.Tracker, data PageData) pageHandler {
+	return pageHandler{data: data, serve: func(w http.ResponseWriter, r *http.Request) {
+		report := tracker.Report()
+		data := data
+		data.ToolStatus = &report
+		data.Theme = handlers.ThemeFromRequest(r)
+		data.Branding = tenantBranding(r)
+		w.Header().Set("Cache-Control", "no-store")
+		writePage(w, page, data, http.StatusOK)
+	}}
+}
+
+// tenantBranding returns the branding of the tenant r is made for, or nil
+// when the tenant keeps the site's own
+func tenantBranding(r *http.Request) *models.TenantBranding {
+	t := tenant.FromContext(r.Context())
+	if tenant.IsDefault(t) || t.Branding == (models.TenantBranding{}) {
+		return nil
+	}
+	return &t.Branding
+}
+
+// writePage renders into a buffer first so template errors never produce a
+// half-written 200 response
+func writePage(w http.ResponseWriter, page pageTemplate, data PageData, status int) {
+	var buf bytes.Buffer
+	if err := page.tmpl.ExecuteTemplate(&buf, "base", data); err != nil {
+		log.Printf("Error rendering template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// errorPage answers unknown routes and unsupported methods with JSON under
+// /api/ and an HTML page elsewhere
+type errorPage struct {
+	page    pageTemplate
+	status  int
+	message string
+}
+
+func (e errorPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		handlers.WriteError(w, e.status, handlers.StatusErrorCode(e.status), e.message)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writePage(w, e.page, PageData{
+		Title:       e.message + " - Micro API",
+		Description: e.message,
+		Canonical:   r.URL.Path,
+		Status:      e.status,
+		Message:     e.message,
+		Theme:       handlers.ThemeFromRequest(r),
+		Branding:    tenantBranding(r),
+	}, e.status)
+}
+
+// sharedToolPages links shared results to the page of their tool
+var sharedToolPages = map[string]string{
+	"email-validate": "/email-validation-api",
+	"ip-validate":    "/ip-geolocation-api",
+	"iban-validate":  "/iban-validation-api",
+}
+
+// sharedResultPage renders a result shared with share: true. Unknown and
+// expired IDs get the HTML not found page; results are not cached or
+// indexed.
+type sharedResultPage struct {
+	page     pageTemplate
+	handlers *handlers.Handlers
+	notFound errorPage
+}
+
+func (p sharedResultPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.handlers.Shares == nil {
+		errorPage{page: p.notFound.page, status: http.StatusServiceUnavailable, message: "Result Sharing Unavailable"}.ServeHTTP(w, r)
+		return
+	}
+	shared, err := p.handlers.LookupSharedResult(r.Context(), mux.Vars(r)["id"])
+	if errors.Is(err, repository.ErrShareNotFound) {
+		p.notFound.ServeHTTP(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load shared result: %v", err)
+		errorPage{page: p.notFound.page, status: http.StatusInternalServerError, message: "Internal Server Error"}.ServeHTTP(w, r)
+		return
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, shared.Result, "", "  "); err != nil {
+		pretty.Reset()
+		pretty.Write(shared.Result)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	writePage(w, p.page, PageData{
+		Title:          "Shared " + shared.Tool + " result - Micro API",
+		Description:    "A " + shared.Tool + " result shared from Micro API.",
+		Canonical:      r.URL.Path,
+		Theme:          handlers.ThemeFromRequest(r),
+		Branding:       tenantBranding(r),
+		NoIndex:        true,
+		Shared:         &shared,
+		SharedJSON:     pretty.String(),
+		SharedToolPage: sharedToolPages[shared.Tool],
+	}, http.StatusOK)
+}
@@ -0,0 +1,259 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/app"
+	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+	"github.com/innovelabs/microtools-go/pkg/generate/qr"
+)
+
+func homePage(t *testing.T) pageTemplate {
+	t.Helper()
+	return parsePage(loadStaticAssets(), &config.Config{BaseURL: "https://example.com"}, "web/templates/base.html", "web/templates/pages/home.html")
+}
+
+func get(h http.Handler, path, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRenderPageCaching(t *testing.T) {
+	page := homePage(t)
+	data := PageData{Title: "Home", Description: "Tools", Canonical: "/"}
+	handler := renderPage(page, data, 600)
+
+	first := get(handler, "/", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Header().Get("Cache-Control") != "public, max-age=600" {
+		t.Fatalf("status %d, ETag %q, Cache-Control %q", first.Code, etag, first.Header().Get("Cache-Control"))
+	}
+	if rec := get(handler, "/", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("repeat request: status %d with %d bytes, want an empty 304", rec.Code, rec.Body.Len())
+	}
+	if rec := get(handler, "/", "W/"+etag); rec.Code != http.StatusNotModified {
+		t.Errorf("weak validator: status %d, want 304", rec.Code)
+	}
+
+	// New page data, such as a changed description, is a new ETag
+	changed := data
+	changed.Description = "More tools"
+	handler = renderPage(page, changed, 600)
+	rec := get(handler, "/", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("changed data: status %d, ETag %q, want a 200 with a new ETag", rec.Code, rec.Header().Get("ETag"))
+	}
+	if pageETag(page, data) == pageETag(page, changed) {
+		t.Error("pageETag ignores the page data")
+	}
+}
+
+func TestStaticAssetsCaching(t *testing.T) {
+	assets := loadStaticAssets()
+	assets.notFound = http.NotFoundHandler()
+	url := assets.URL("css/site.css")
+	if url == "/static/css/site.css" || !strings.HasSuffix(url, ".css") {
+		t.Fatalf("URL = %q, want a hashed name", url)
+	}
+
+	rec := get(assets, url, "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != immutableCacheControl ||
+		!strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css") {
+		t.Fatalf("status %d, headers %v", rec.Code, rec.Header())
+	}
+	if again := get(assets, url, rec.Header().Get("ETag")); again.Code != http.StatusNotModified {
+		t.Errorf("repeat request: status %d, want 304", again.Code)
+	}
+	if rec := get(assets, "/static/css/site.css", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unhashed name: status %d, want 404", rec.Code)
+	}
+}
+
+// svgModules reads back the dark modules of a QR SVG path, written as one
+// "M x yhNv1h-Nz" run per row segment
+func svgModules(t *testing.T, svg string, n int) [][]bool {
+	t.Helper()
+	_, path, ok := strings.Cut(svg, `<path fill="#000000" d="`)
+	if !ok {
+		t.Fatalf("no module path in %s", svg)
+	}
+	path, _, _ = strings.Cut(path, `"`)
+	modules := make([][]bool, n)
+	for i := range modules {
+		modules[i] = make([]bool, n)
+	}
+	for _, run := range strings.Split(strings.TrimSuffix(path, "z"), "z") {
+		var x, y, w, back int
+		if _, err := fmt.Sscanf(run, "M%d %dh%dv1h-%d", &x, &y, &w, &back); err != nil || w != back {
+			t.Fatalf("run %q: %v", run, err)
+		}
+		for i := x; i < x+w; i++ {
+			modules[y][i] = true
+		}
+	}
+	return modules
+}
+
+// TestQRPageInlineExample renders the QR tool page and checks that its
+// inline example holds exactly the modules of the page URL's QR code, so a
+// scanner reading it opens the page
+func TestQRPageInlineExample(t *testing.T) {
+	page := parsePage(loadStaticAssets(), &config.Config{BaseURL: "https://example.com"}, "web/templates/base.html", "web/templates/pages/qr.html")
+	rec := get(renderPage(page, PageData{Title: "QR", Canonical: "/qr-code-generator-api", InlineQR: inlineQRExample()}, 600), "/qr-code-generator-api", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	body := rec.Body.String()
+	start := strings.Index(body, "<svg ")
+	end := strings.Index(body, "</svg>")
+	if start < 0 || end < start {
+		t.Fatal("no inline SVG on the page")
+	}
+
+	req := models.QRRequest{Type: "url", Data: "https://microapi.innovelabs.net/qr-code-generator-api"}
+	generator.ApplyDefaults(&req)
+	payload, err := generator.BuildPayload(req.Type, req.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := qr.Encode(payload, generator.ParseErrorCorrection(req.Options.ErrorCorrection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := code.Bitmap()
+	got := svgModules(t, body[start:end], len(want))
+	for y := range want {
+		if !slices.Equal(got[y], want[y]) {
+			t.Fatalf("row %d of the inline example differs from the encoded URL", y)
+		}
+	}
+}
+
+// structuredData returns the JSON-LD block of a rendered page, or nil
+func structuredData(t *testing.T, body string) map[string]any {
+	t.Helper()
+	_, block, ok := strings.Cut(body, `<script type="application/ld+json">`)
+	if !ok {
+		return nil
+	}
+	block, _, _ = strings.Cut(block, "</script>")
+	var data map[string]any
+	if err := json.Unmarshal([]byte(block), &data); err != nil {
+		t.Fatalf("JSON-LD is not valid JSON: %v\n%s", err, block)
+	}
+	return data
+}
+
+// TestToolPagesStructuredData renders every page and checks that tool
+// pages carry a WebApplication built from the ToolSpec registry
+func TestToolPagesStructuredData(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Config.BaseURL = "https://example.com"
+	router := SetupRouter(&app.App{Config: h.Config, Handlers: h, Counter: testutil.NewHitCounter()})
+
+	toolPages := 0
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		page, ok := route.GetHandler().(pageHandler)
+		if !ok {
+			return nil
+		}
+		path, _ := route.GetPathTemplate()
+		rec := get(router, path, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", path, rec.Code)
+		}
+		data := structuredData(t, rec.Body.String())
+		if page.data.StructuredData == "" {
+			if data != nil {
+				t.Errorf("%s has JSON-LD without tools: %v", path, data)
+			}
+			return nil
+		}
+		toolPages++
+		if data == nil {
+			t.Fatalf("%s: no JSON-LD", path)
+		}
+		if data["@context"] != "https://schema.org" || data["@type"] != "WebApplication" || data["name"] == "" ||
+			data["description"] != page.data.Description || data["url"] != "https://example.com"+page.data.Canonical {
+			t.Errorf("%s: JSON-LD = %v", path, data)
+		}
+		actions, _ := data["potentialAction"].([]any)
+		if len(actions) == 0 {
+			t.Fatalf("%s: no actions", path)
+		}
+		for _, action := range actions {
+			action := action.(map[string]any)
+			tool, _ := action["name"].(string)
+			spec, ok := handlers.LookupToolSpec(tool)
+			if !ok {
+				t.Errorf("%s: action %v names no tool", path, action)
+				continue
+			}
+			target := action["target"].(map[string]any)
+			if target["urlTemplate"] != "https://example.com"+spec.Path || target["httpMethod"] != spec.Method {
+				t.Errorf("%s: action %v, want %s %s", path, action, spec.Method, spec.Path)
+			}
+		}
+		return nil
+	})
+	if toolPages != 5 {
+		t.Errorf("%d tool pages, want 5", toolPages)
+	}
+
+	// The JSON is escaped so a description cannot close the script
+	data := toolPage("https://example.com", "Test", PageData{Canonical: "/t", Description: "</script><script>alert(1)"}, "qr-generate")
+	if strings.Contains(string(data.StructuredData), "</script>") {
+		t.Errorf("structured data not escaped: %s", data.StructuredData)
+	}
+}
+
+func TestRenderPageTheme(t *testing.T) {
+	handler := renderPage(homePage(t), PageData{Title: "Home", Canonical: "/"}, 600)
+	render := func(theme string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		if theme != "" {
+			req.AddCookie(&http.Cookie{Name: handlers.ThemeCookie, Value: theme})
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	etags := map[string]string{}
+	for cookie, want := range map[string]string{
+		"":         handlers.ThemeSystem,
+		"dark":     handlers.ThemeDark,
+		"light":    handlers.ThemeLight,
+		"neon":     handlers.ThemeSystem,
+		"system":   handlers.ThemeSystem,
+		`"><x y="`: handlers.ThemeSystem,
+	} {
+		rec := render(cookie)
+		if !strings.Contains(rec.Body.String(), `<html lang="en" data-theme="`+want+`">`) || rec.Header().Get("Vary") != "Cookie" {
+			t.Errorf("cookie %q: want the %s theme, Vary %q", cookie, want, rec.Header().Get("Vary"))
+		}
+		if etag, ok := etags[want]; ok && etag != rec.Header().Get("ETag") {
+			t.Errorf("cookie %q: ETag %s, want the %s theme's %s", cookie, rec.Header().Get("ETag"), want, etag)
+		}
+		etags[want] = rec.Header().Get("ETag")
+	}
+	if len(etags) != 3 || etags[handlers.ThemeDark] == etags[handlers.ThemeLight] {
+		t.Errorf("ETags per theme = %v, want one each", etags)
+	}
+}
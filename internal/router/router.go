@@ -4,91 +4,396 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/app"
+	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/examples"
 	"github.com/innovelabs/microtools-go/internal/handlers"
 	"github.com/innovelabs/microtools-go/internal/middleware"
-	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/policy"
+)
+
+// Body caps of the API routes outside the validator and generator groups,
+// each sized to the input its service accepts with room for JSON escaping
+// and the other fields
+const (
+	// toolBodyBytes fits the 100 KiB of text conversion and the regex
+	// tester, and the short bodies of timestamps and passwords
+	toolBodyBytes = 128 << 10
+	// geoBodyBytes fits 1,000 geofence points and polygon vertices
+	geoBodyBytes = 1 << 20
+	// documentBodyBytes fits 2 MiB of HTML to convert, a JSON Schema with
+	// its documents, or 100 text safety inputs of 10,000 bytes
+	documentBodyBytes = 4 << 20
+	// duplicatesBodyBytes fits 10,000 items of 1,000 characters
+	duplicatesBodyBytes = 16 << 20
 )
 
 type PageData struct {
 	Title       string
 	Description string
 	Canonical   string
+	Examples    []examples.Example
+	GeoDB       *models.GeoDatabaseInfo
+	// InlineQR is a server-rendered example on the QR page
+	InlineQR template.HTML
+	// LastModified dates the page in sitemap.xml; zero uses the build time
+	LastModified time.Time
+	// ToolStatus is set on the status page, rendered per request
+	ToolStatus *models.StatusReport
+	// StructuredData is the JSON-LD of a tool page, see toolPage
+	StructuredData template.JS
+	// Theme is the visitor's theme cookie, set per request
+	Theme string
+	// Branding replaces the site's name, logo, color and contact on the
+	// hosts of a tenant, set per request
+	Branding *models.TenantBranding
+	// Maintenance is the banner shown while a tool of the page is down,
+	// set per request
+	Maintenance string
+	// NoIndex keeps search engines from indexing the page
+	NoIndex bool
+
+	// Shared, SharedJSON and SharedToolPage are set on shared result pages
+	Shared         *models.SharedResult
+	SharedJSON     string
+	SharedToolPage string
+
+	// Status and Message are set on error pages only
+	Status  int
+	Message string
+
+	// tools are the counter names of the tools a page documents
+	tools []string
 }
 
-func renderPage(tmpl *template.Template, data PageData) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
-			log.Printf("Error rendering template: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		}
-	}
+// Options toggles optional router behavior
+type Options struct {
+	// LegacyRoutes also registers the retired root-package paths as
+	// deprecated aliases of their canonical routes
+	LegacyRoutes bool
 }
 
-// SetupRouter configures and returns the application router
-func SetupRouter() *mux.Router {
+// SetupRouter configures and returns the application router for a
+func SetupRouter(a *app.App) *mux.Router {
+	return SetupRouterWithOptions(a, Options{})
+}
+
+// SetupRouterWithOptions configures the application router for a with opts
+func SetupRouterWithOptions(a *app.App, opts Options) *mux.Router {
 	router := mux.NewRouter()
+	h := a.Handlers
+	cfg := a.Config
+
+	examplesDir := config.DefaultExamplesDir
+	pageMaxAge := config.DefaultPageCacheMaxAge
+	site := config.Default()
+	correlationHeaders := config.DefaultCorrelationHeaders
+	// Without a configuration no user is an admin
+	var adminEmails []string
+	if cfg != nil {
+		examplesDir = cfg.ExamplesDir
+		pageMaxAge = cfg.PageCacheMaxAge
+		site = cfg
+		adminEmails = cfg.AdminEmails
+		correlationHeaders = cfg.CorrelationHeaders
+	}
+
+	// Public URLs carry the base path the router is mounted under
+	baseURL := site.AbsURL("")
+	jwtConfig := h.JWTConfig()
+
+	// Apply middleware. Router middleware skips the not found and method
+	// handlers, so they get their request log line and ID from
+	// LoggingMiddleware, correlation IDs echoed by correlate and their
+	// tenant from resolveTenant below.
+	correlate := middleware.CorrelationMiddleware(correlationHeaders)
+	resolveTenant := func(next http.Handler) http.Handler { return next }
+	if h.TenantRegistry != nil {
+		resolveTenant = middleware.TenantMiddleware(h.TenantRegistry, jwtConfig)
+	}
+	router.Use(middleware.LoggingMiddleware)
+	router.Use(correlate)
+	router.Use(middleware.TracingMiddleware)
+	router.Use(middleware.ResultMetaMiddleware)
+	router.Use(resolveTenant)
+	if h.Maintenance != nil {
+		router.Use(middleware.MaintenanceMiddleware(h.Maintenance))
+	}
+	// Requests over a route group's rate limit are refused before they
+	// are counted. Legacy aliases count against their canonical route's
+	// group.
+	if a.ValidatorLimiter != nil || a.GeneratorLimiter != nil {
+		aliases := legacyAliases(h)
+		router.Use(middleware.RouteRateLimitMiddleware(site.TrustedProxy,
+			middleware.RateLimitGroup{PathPrefixes: withLegacyAliases(aliases, "/api/v1/validate/", "/api/v1/iban/", "/api/v1/parse/"), Limiter: a.ValidatorLimiter},
+			middleware.RateLimitGroup{PathPrefixes: withLegacyAliases(aliases, "/api/v1/generate/", "/api/v1/shorten"), Limiter: a.GeneratorLimiter},
+		))
+	}
+	router.Use(middleware.APICounterMiddleware(a.Counter))
+	if h.TenantRegistry != nil {
+		tenantLimiters := a.TenantLimiters
+		if tenantLimiters == nil {
+			tenantLimiters = middleware.NewTenantLimiters(nil, "", h.Clock)
+		}
+		router.Use(middleware.TenantRateLimitMiddleware(tenantLimiters, site.TrustedProxy))
+	}
+	if h.Status != nil {
+		router.Use(middleware.ToolStatusMiddleware(h.Status))
+	}
+	if cfg != nil && cfg.RecordExamples && !cfg.IsProduction() {
+		log.Printf("Recording API examples into %s", examplesDir)
+		router.Use(middleware.ExampleRecorderMiddleware(examplesDir))
+	}
 
-	// Apply middleware
-	router.Use(middleware.APICounterMiddleware)
+	// API routes. Request bodies are capped per route group or, outside
+	// the groups, per service, and routes reading only JSON refuse other
+	// content types with 415. Batch, upload, multipart and CSV conversion
+	// routes set their own caps.
+	validatorBody := middleware.BodyLimitMiddleware(int64(site.ValidatorMaxBodyBytes), "application/json", "text/plain")
+	validatorJSON := middleware.BodyLimitMiddleware(int64(site.ValidatorMaxBodyBytes), "application/json")
+	generatorJSON := middleware.BodyLimitMiddleware(int64(site.GeneratorMaxBodyBytes), "application/json")
+	toolJSON := middleware.BodyLimitMiddleware(toolBodyBytes, "application/json")
+	geoJSON := middleware.BodyLimitMiddleware(geoBodyBytes, "application/json")
+	documentJSON := middleware.BodyLimitMiddleware(documentBodyBytes, "application/json")
+	duplicatesJSON := middleware.BodyLimitMiddleware(duplicatesBodyBytes, "application/json")
+	router.Handle("/api/v1/validate/email", validatorBody(http.HandlerFunc(h.ValidateEmailHandler))).Methods("POST")
+	router.Handle("/api/v1/validate/ip", validatorBody(http.HandlerFunc(h.ValidateIPHandler))).Methods("GET", "POST")
+	router.Handle("/api/v1/validate/iban", validatorBody(http.HandlerFunc(h.ValidateIBANHandler))).Methods("POST")
+	router.Handle("/api/v1/validate/email/batch", h.AcceptUpload(h.ValidateEmailBatchHandler)).Methods("POST")
+	router.Handle("/api/v1/validate/ip/batch", h.AcceptUpload(h.ValidateIPBatchHandler)).Methods("POST")
+	router.Handle("/api/v1/validate/bankaccount", validatorJSON(http.HandlerFunc(h.ValidateBankAccountHandler))).Methods("POST")
+	router.Handle("/api/v1/validate/barcode", validatorBody(http.HandlerFunc(h.ValidateBarcodeHandler))).Methods("POST")
+	router.Handle("/api/v1/validate/card", validatorBody(http.HandlerFunc(h.ValidateCardHandler))).Methods("POST")
+	router.Handle("/api/v1/validate/iban/batch", h.AcceptUpload(handlers.ValidateIBANBatchHandler)).Methods("POST")
+	router.Handle("/api/v1/iban/format", validatorBody(http.HandlerFunc(handlers.FormatIBANHandler))).Methods("POST")
+	router.Handle("/api/v1/iban/format/{countryCode}", http.HandlerFunc(handlers.IBANFormatRulesHandler)).Methods("GET")
+	router.Handle("/api/v1/validate/jsonschema", documentJSON(http.HandlerFunc(handlers.ValidateJSONSchemaHandler))).Methods("POST")
+	router.Handle("/api/v1/generate/qr", generatorJSON(http.HandlerFunc(h.QRHandler))).Methods("GET", "POST")
+	router.Handle("/api/v1/generate/barcode", generatorJSON(http.HandlerFunc(h.GenerateBarcodeHandler))).Methods("GET", "POST")
+	router.Handle("/api/v1/testvectors", http.HandlerFunc(h.TestVectorsHandler)).Methods("GET")
+	router.Handle("/api/v1/generate/barcode/rules", http.HandlerFunc(handlers.BarcodeRulesHandler)).Methods("GET")
+	router.Handle("/api/v1/generate/ics", generatorJSON(http.HandlerFunc(handlers.GenerateICSHandler))).Methods("POST")
+	router.Handle("/api/v1/generate/token", generatorJSON(http.HandlerFunc(handlers.GenerateTokenHandler))).Methods("POST")
+	router.Handle("/api/v1/generate/uuid", generatorJSON(http.HandlerFunc(h.GenerateUUIDHandler))).Methods("GET", "POST")
+	router.Handle("/api/v1/shorten", generatorJSON(http.HandlerFunc(h.ShortenHandler))).Methods("POST")
+	router.Handle("/api/v1/generate/labels", http.HandlerFunc(h.GenerateLabelsHandler)).Methods("POST")
+	router.Handle("/api/v1/jobs/{id}", http.HandlerFunc(h.JobStatusHandler)).Methods("GET")
+	router.Handle("/api/v1/jobs/{id}/result", http.HandlerFunc(h.JobResultHandler)).Methods("GET")
+	router.Handle("/api/v1/transform/html2text", documentJSON(http.HandlerFunc(handlers.HTML2TextHandler))).Methods("POST")
+	router.Handle("/api/v1/convert/text", toolJSON(http.HandlerFunc(handlers.ConvertTextHandler))).Methods("POST")
+	router.Handle("/api/v1/convert/csv", http.HandlerFunc(h.ConvertCSVHandler)).Methods("POST")
+	router.Handle("/api/v1/convert/timestamp", toolJSON(http.HandlerFunc(handlers.ConvertTimestampHandler))).Methods("POST")
+	router.Handle("/api/v1/tools/regex", toolJSON(http.HandlerFunc(handlers.RegexHandler))).Methods("POST")
+	router.Handle("/api/v1/tools/password/check", toolJSON(http.HandlerFunc(handlers.PasswordCheckHandler))).Methods("POST")
+	router.Handle("/api/v1/tools/password/generate", toolJSON(http.HandlerFunc(handlers.PasswordGenerateHandler))).Methods("POST")
+	router.Handle("/api/v1/extract/emails", http.HandlerFunc(handlers.ExtractEmailsHandler)).Methods("POST")
+	router.Handle("/api/v1/parse/number", validatorJSON(http.HandlerFunc(handlers.ParseNumberHandler))).Methods("POST")
+	router.Handle("/api/v1/analyze/distance", geoJSON(http.HandlerFunc(h.AnalyzeDistanceHandler))).Methods("POST")
+	router.Handle("/api/v1/analyze/duplicates", duplicatesJSON(h.AcceptUpload(handlers.AnalyzeDuplicatesHandler))).Methods("POST")
+	router.Handle("/api/v1/analyze/textsafety", documentJSON(h.AcceptUpload(handlers.AnalyzeTextSafetyHandler))).Methods("POST")
+	router.Handle("/api/v1/analyze/qr", generatorJSON(http.HandlerFunc(handlers.AnalyzeQRHandler))).Methods("POST")
+	router.Handle("/api/v1/analyze/imagehash", http.HandlerFunc(handlers.AnalyzeImageHashHandler)).Methods("POST")
 
-	// API routes
-	router.Handle("/api/v1/validate/email", http.HandlerFunc(handlers.ValidateEmailHandler)).Methods("POST")
-	router.Handle("/api/v1/validate/ip", http.HandlerFunc(handlers.ValidateIPHandler)).Methods("POST")
-	router.Handle("/api/v1/validate/iban", http.HandlerFunc(handlers.ValidateIBANHandler)).Methods("POST")
-	router.Handle("/api/v1/generate/qr", http.HandlerFunc(handlers.QRHandler)).Methods("POST")
-	barcodeSvc := generator.NewDefaultBarcodeService()
-	router.Handle("/api/v1/generate/barcode", handlers.GenerateBarcodeHandler(barcodeSvc)).Methods("POST")
+	router.Handle("/api/v1/tools", http.HandlerFunc(handlers.ToolsSpecHandler)).Methods("GET")
+	router.Handle("/api/v1/schemas", http.HandlerFunc(handlers.SchemasHandler)).Methods("GET")
+	router.Handle("/api/v1/schemas/{name}", http.HandlerFunc(handlers.SchemaHandler)).Methods("GET")
+	router.Handle("/api/v1/r/{id}", http.HandlerFunc(h.SharedResultHandler)).Methods("GET")
+	router.Handle("/s/{code}", http.HandlerFunc(h.ShortURLRedirectHandler)).Methods("GET")
+	router.Handle("/preferences/theme", http.HandlerFunc(handlers.SetThemeHandler)).Methods("POST")
+
+	// Lite routes serve the embeddable widget: anonymous, cross-origin and
+	// limited by policy.Lite, which the shared handlers read from the context.
+	// Its body cap is below the route groups' caps.
+	lite := router.PathPrefix("/api/lite/v1").Subrouter()
+	lite.Use(middleware.CORSMiddleware(policy.Lite))
+	liteLimiter := a.LiteLimiter
+	if liteLimiter == nil {
+		liteLimiter = middleware.NewRateLimiter(nil, "", policy.Lite.RequestsPerMinute, time.Minute, h.Clock)
+	}
+	lite.Use(middleware.RouteRateLimitMiddleware(site.TrustedProxy, middleware.RateLimitGroup{PathPrefixes: []string{"/api/lite/v1/"}, Limiter: liteLimiter}))
+	lite.Use(middleware.PolicyMiddleware(policy.Lite))
+	lite.Handle("/validate/email", validatorBody(http.HandlerFunc(h.ValidateEmailHandler))).Methods("POST", "OPTIONS")
+	lite.Handle("/validate/ip", validatorBody(http.HandlerFunc(h.ValidateIPHandler))).Methods("POST", "OPTIONS")
+	lite.Handle("/validate/iban", validatorBody(http.HandlerFunc(h.ValidateIBANHandler))).Methods("POST", "OPTIONS")
+	lite.Handle("/validate/bankaccount", validatorJSON(http.HandlerFunc(h.ValidateBankAccountHandler))).Methods("POST", "OPTIONS")
+	lite.Handle("/validate/barcode", validatorBody(http.HandlerFunc(h.ValidateBarcodeHandler))).Methods("POST", "OPTIONS")
+	lite.Handle("/iban/format", validatorBody(http.HandlerFunc(handlers.FormatIBANHandler))).Methods("POST", "OPTIONS")
+	lite.Handle("/iban/format/{countryCode}", http.HandlerFunc(handlers.IBANFormatRulesHandler)).Methods("GET", "OPTIONS")
+	lite.Handle("/generate/qr", generatorJSON(http.HandlerFunc(h.QRHandler))).Methods("POST", "OPTIONS")
+	lite.Handle("/generate/barcode", generatorJSON(http.HandlerFunc(h.GenerateBarcodeHandler))).Methods("POST", "OPTIONS")
+	lite.Handle("/generate/ics", generatorJSON(http.HandlerFunc(handlers.GenerateICSHandler))).Methods("POST", "OPTIONS")
+	lite.Handle("/parse/number", validatorJSON(http.HandlerFunc(handlers.ParseNumberHandler))).Methods("POST", "OPTIONS")
+	lite.Handle("/analyze/textsafety", documentJSON(http.HandlerFunc(handlers.AnalyzeTextSafetyHandler))).Methods("POST", "OPTIONS")
+	lite.Handle("/tools", http.HandlerFunc(handlers.ToolsSpecHandler)).Methods("GET", "OPTIONS")
+
+	if opts.LegacyRoutes {
+		registerLegacyAliases(router, h, site, validatorBody)
+	}
+
+	// User APIs; export and deletion act on the access token's user
+	tokenAuth := middleware.JWTAuthMiddleware(jwtConfig, h.CheckAccessToken)
+	router.Handle("/api/v1/user/register", validatorJSON(http.HandlerFunc(h.RegisterUserHandler))).Methods("POST")
+	router.Handle("/api/v1/user/verify", http.HandlerFunc(h.VerifyUserHandler)).Methods("GET")
+	router.Handle("/api/v1/user/token", validatorJSON(http.HandlerFunc(h.UserTokenHandler))).Methods("POST")
+	router.Handle("/api/v1/user/export", tokenAuth(http.HandlerFunc(h.ExportUserHandler))).Methods("GET")
+	router.Handle("/api/v1/user", tokenAuth(http.HandlerFunc(h.DeleteUserHandler))).Methods("DELETE")
+
+	// Resumable uploads belong to the access token's user, who alone can
+	// continue them and read them with ?upload=<token>
+	router.Handle("/api/v1/uploads", tokenAuth(http.HandlerFunc(h.CreateUploadHandler))).Methods("POST")
+	router.Handle("/api/v1/uploads/{id}", tokenAuth(http.HandlerFunc(h.UploadStatusHandler))).Methods("GET")
+	router.Handle("/api/v1/uploads/{id}", tokenAuth(http.HandlerFunc(h.AppendUploadHandler))).Methods("PATCH")
+	router.Handle("/api/v1/uploads/{id}/complete", tokenAuth(http.HandlerFunc(h.CompleteUploadHandler))).Methods("POST")
+
+	// Rotating guest WiFi networks belong to the access token's user, the
+	// only one served their password
+	router.Handle("/api/v1/generate/qr/wifi-rotating", tokenAuth(generatorJSON(http.HandlerFunc(h.CreateWifiRotationHandler)))).Methods("POST")
+	router.Handle("/api/v1/generate/qr/wifi-rotating/{name}", tokenAuth(http.HandlerFunc(h.WifiRotationQRHandler))).Methods("GET")
+	router.Handle("/api/v1/generate/qr/wifi-rotating/{name}", tokenAuth(http.HandlerFunc(h.DeleteWifiRotationHandler))).Methods("DELETE")
+	router.Handle("/api/v1/generate/qr/wifi-rotating/{name}/rotate", tokenAuth(http.HandlerFunc(h.RotateWifiHandler))).Methods("POST")
 
 	// Public APIs
-	router.Handle("/api/v1/live", http.HandlerFunc(handlers.LiveHandler)).Methods("GET")
+	// Served by liveProbe in front of the router; registered so other
+	// methods get Allow and 405
+	router.Handle(livePath, http.HandlerFunc(handlers.LiveHandler)).Methods("GET")
+	router.Handle("/api/v1/ready", http.HandlerFunc(h.ReadyHandler)).Methods("GET")
+	router.Handle("/api/v1/status", http.HandlerFunc(h.StatusHandler)).Methods("GET")
+	router.Handle("/api/v1/stats", http.HandlerFunc(h.StatsHandler)).Methods("GET")
+	router.Handle("/api/v1/datasets", http.HandlerFunc(handlers.ListDatasetsHandler)).Methods("GET")
+	router.Handle("/api/v1/datasets/{name}", http.HandlerFunc(handlers.GetDatasetHandler)).Methods("GET")
+	router.Handle("/.well-known/microtools-jwks.json", http.HandlerFunc(h.JWKSHandler)).Methods("GET")
+
+	// Admin APIs for operators, behind the access token of an ADMIN_EMAILS user
+	admin := router.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(tokenAuth)
+	admin.Use(middleware.AdminMiddleware(adminEmails))
+	admin.Use(validatorJSON)
+	admin.Handle("/dns-cache", http.HandlerFunc(h.DNSCacheStatsHandler)).Methods("GET")
+	admin.Handle("/dns-cache/flush", http.HandlerFunc(h.FlushDNSCacheHandler)).Methods("POST")
+	admin.Handle("/reload-disposable", http.HandlerFunc(h.ReloadDisposableDomainsHandler)).Methods("POST")
+	admin.Handle("/url-blocklist", http.HandlerFunc(h.BlockedDomainsHandler)).Methods("GET")
+	admin.Handle("/url-blocklist", http.HandlerFunc(h.BlockDomainHandler)).Methods("POST")
+	admin.Handle("/url-blocklist/{domain}", http.HandlerFunc(h.UnblockDomainHandler)).Methods("DELETE")
+	admin.Handle("/maintenance", http.HandlerFunc(h.MaintenanceHandler)).Methods("GET")
+	admin.Handle("/maintenance", http.HandlerFunc(h.SetMaintenanceHandler)).Methods("PUT")
+	admin.Handle("/tenants", http.HandlerFunc(h.TenantsHandler)).Methods("GET")
+	admin.Handle("/tenants", http.HandlerFunc(h.CreateTenantHandler)).Methods("POST")
+	admin.Handle("/tenants/{id}", http.HandlerFunc(h.TenantHandler)).Methods("GET")
+	admin.Handle("/tenants/{id}", http.HandlerFunc(h.PutTenantHandler)).Methods("PUT")
+	admin.Handle("/tenants/{id}", http.HandlerFunc(h.DeleteTenantHandler)).Methods("DELETE")
+
+	var geoDB *models.GeoDatabaseInfo
+	if info, err := h.GeoIP.Metadata(); err == nil {
+		geoDB = &info
+	}
+
+	// Static assets are embedded and served under content-hashed names
+	assets := loadStaticAssets()
+	router.PathPrefix("/static/").Handler(assets).Methods("GET", "HEAD")
 
 	// Parse templates
-	homeTmpl := template.Must(template.ParseFiles("web/templates/base.html", "web/templates/pages/home.html"))
-	emailTmpl := template.Must(template.ParseFiles("web/templates/base.html", "web/templates/pages/email.html"))
-	ipTmpl := template.Must(template.ParseFiles("web/templates/base.html", "web/templates/pages/ip.html"))
-	ibanTmpl := template.Must(template.ParseFiles("web/templates/base.html", "web/templates/pages/iban.html"))
-	qrTmpl := template.Must(template.ParseFiles("web/templates/base.html", "web/templates/pages/qr.html"))
-	barcodeTmpl := template.Must(template.ParseFiles("web/templates/base.html", "web/templates/pages/barcode.html"))
+	homeTmpl := parsePage(assets, site, "web/templates/base.html", "web/templates/pages/home.html")
+	emailTmpl := parsePage(assets, site, "web/templates/base.html", "web/templates/pages/email.html")
+	ipTmpl := parsePage(assets, site, "web/templates/base.html", "web/templates/pages/ip.html")
+	ibanTmpl := parsePage(assets, site, "web/templates/base.html", "web/templates/pages/iban.html")
+	qrTmpl := parsePage(assets, site, "web/templates/base.html", "web/templates/pages/qr.html")
+	barcodeTmpl := parsePage(assets, site, "web/templates/base.html", "web/templates/pages/barcode.html")
+	statusTmpl := parsePage(assets, site, "web/templates/base.html", "web/templates/pages/status.html")
+	errorTmpl := parsePage(assets, site, "web/templates/base.html", "web/templates/pages/error.html")
+	sharedTmpl := parsePage(assets, site, "web/templates/base.html", "web/templates/pages/shared.html")
+
+	// Unknown paths get JSON under /api/ and an HTML page elsewhere
+	router.NotFoundHandler = errorPage{page: errorTmpl, status: http.StatusNotFound, message: "Not Found"}
+	assets.notFound = router.NotFoundHandler
 
 	// UI routes
-	router.HandleFunc("/", renderPage(homeTmpl, PageData{
+	router.Handle("/", renderPage(homeTmpl, PageData{
 		Title:       "Micro API - Free Developer APIs for Email, IP, QR & Barcode",
 		Description: "Free REST APIs for email validation, IP geolocation, QR code generation, and barcode generation. Simple JSON interface, no API key required.",
 		Canonical:   "/",
-	})).Methods("GET")
+	}, pageMaxAge)).Methods("GET")
 
-	router.HandleFunc("/email-validation-api", renderPage(emailTmpl, PageData{
+	router.Handle("/email-validation-api", renderToolPage(emailTmpl, toolPage(baseURL, "Email Validation API", PageData{
 		Title:       "Free Email Validation API - Syntax, Domain & Disposable Check",
 		Description: "Validate email addresses with syntax checking, domain verification, MX record lookup, and disposable email detection. Free REST API with JSON response.",
 		Canonical:   "/email-validation-api",
-	})).Methods("GET")
+		Examples:    examples.ForRoute(examplesDir, "/api/v1/validate/email"),
+	}, "email-validate", "email-batch-validate"), pageMaxAge, h.Maintenance)).Methods("GET")
 
-	router.HandleFunc("/ip-geolocation-api", renderPage(ipTmpl, PageData{
+	router.Handle("/ip-geolocation-api", renderToolPage(ipTmpl, toolPage(baseURL, "IP Geolocation API", PageData{
 		Title:       "Free IP Geolocation API - Country, City & Timezone Lookup",
 		Description: "Look up any IP address to get country, region, city, coordinates, and timezone. Free REST API powered by MaxMind GeoIP2.",
 		Canonical:   "/ip-geolocation-api",
-	})).Methods("GET")
+		Examples:    examples.ForRoute(examplesDir, "/api/v1/validate/ip"),
+		GeoDB:       geoDB,
+	}, "ip-validate", "ip-batch-validate"), pageMaxAge, h.Maintenance)).Methods("GET")
 
-	router.HandleFunc("/iban-validation-api", renderPage(ibanTmpl, PageData{
+	router.Handle("/iban-validation-api", renderToolPage(ibanTmpl, toolPage(baseURL, "IBAN Validation API", PageData{
 		Title:       "Free IBAN Validation API - Format, Checksum & Country Verification",
 		Description: "Validate International Bank Account Numbers (IBAN) with comprehensive checks including format validation, mod-97 checksum verification, and country-specific rules for 60+ countries.",
 		Canonical:   "/iban-validation-api",
-	})).Methods("GET")
+		Examples:    examples.ForRoute(examplesDir, "/api/v1/validate/iban"),
+	}, "iban-validate", "iban-batch-validate", "iban-format"), pageMaxAge, h.Maintenance)).Methods("GET")
 
-	router.HandleFunc("/qr-code-generator-api", renderPage(qrTmpl, PageData{
+	router.Handle("/qr-code-generator-api", renderToolPage(qrTmpl, toolPage(baseURL, "QR Code Generator API", PageData{
 		Title:       "Free QR Code Generator API - Text, URL, WiFi, vCard & More",
 		Description: "Generate QR codes as PNG images. Supports text, URLs, email, phone, WiFi, vCard, geo, events, and JSON. Free REST API.",
 		Canonical:   "/qr-code-generator-api",
-	})).Methods("GET")
+		Examples:    examples.ForRoute(examplesDir, "/api/v1/generate/qr"),
+		InlineQR:    inlineQRExample(),
+	}, "qr-generate"), pageMaxAge, h.Maintenance)).Methods("GET")
 
-	router.HandleFunc("/barcode-generator-api", renderPage(barcodeTmpl, PageData{
+	router.Handle("/barcode-generator-api", renderToolPage(barcodeTmpl, toolPage(baseURL, "Barcode Generator API", PageData{
 		Title:       "Free Barcode Generator API - UPC-A, EAN-13 & Code128",
 		Description: "Generate 1D barcodes in PNG or SVG format. Supports UPC-A, EAN-13, and Code128 with optional human-readable text. Free REST API.",
 		Canonical:   "/barcode-generator-api",
-	})).Methods("GET")
+		Examples:    examples.ForRoute(examplesDir, "/api/v1/generate/barcode"),
+	}, "barcode-generate"), pageMaxAge, h.Maintenance)).Methods("GET")
+
+	if h.Status != nil {
+		router.Handle("/status", renderStatusPage(statusTmpl, h.Status, PageData{
+			Title:       "Micro API Status - Live Tool Health",
+			Description: "Request rates, error rates and latency of every Micro API tool over the last five minutes of real traffic.",
+			Canonical:   "/status",
+		})).Methods("GET")
+	}
+
+	router.Handle("/r/{id}", sharedResultPage{
+		page:     sharedTmpl,
+		handlers: h,
+		notFound: errorPage{page: errorTmpl, status: http.StatusNotFound, message: "Not Found"},
+	}).Methods("GET")
 
-	return router
+	// Registered after the last page, which they list
+	router.Handle("/sitemap.xml", newSitemap(router, baseURL, buildTime(), pageMaxAge)).Methods("GET")
+	router.Handle("/robots.txt", robotsTxt(site, pageMaxAge)).Methods("GET")
+
+	// Registered last: it walks every route above to answer HEAD, OPTIONS
+	// and unsupported methods with the right Allow header. Subrouters report
+	// method mismatches through their own handler, so lite gets it too.
+	methods := newMethodHandler(router, errorPage{page: errorTmpl, status: http.StatusMethodNotAllowed, message: "Method Not Allowed"})
+	router.MethodNotAllowedHandler = middleware.LoggingMiddleware(correlate(resolveTenant(methods)))
+	lite.MethodNotAllowedHandler = middleware.LoggingMiddleware(correlate(resolveTenant(methods)))
+	router.NotFoundHandler = middleware.LoggingMiddleware(correlate(resolveTenant(methods.orNotFound(router.NotFoundHandler))))
+
+	return mount(router, site.BasePath)
+}
+
+// mount serves router under basePath, stripped from the request path so
+// routes, middleware and handlers see the paths they are registered with.
+// The base path itself redirects to its trailing-slash form, the home
+// page, and paths outside it get the router's not found response.
+func mount(router *mux.Router, basePath string) *mux.Router {
+	if basePath == "" {
+		return router
+	}
+	root := mux.NewRouter()
+	root.Handle(basePath, http.RedirectHandler(basePath+"/", http.StatusMovedPermanently))
+	root.PathPrefix(basePath + "/").Handler(http.StripPrefix(basePath, router))
+	root.NotFoundHandler = router.NotFoundHandler
+	return root
 }
@@ -0,0 +1,1232 @@
+package router_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/innovelabs/microtools-go/internal/app"
+	"github.com/innovelabs/microtools-go/internal/examples"
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/policy"
+	"github.com/innovelabs/microtools-go/internal/quota"
+	"github.com/innovelabs/microtools-go/internal/router"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+	"github.com/innovelabs/microtools-go/internal/utils"
+	"github.com/innovelabs/microtools-go/pkg/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TestMain runs the tests from the repository root, where the router
+// finds its templates and static assets, without request logs
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// bearer registers email as a verified user of h and returns the
+// Authorization header of an access token of it
+func bearer(t *testing.T, h *handlers.Handlers, email string) string {
+	t.Helper()
+	if _, err := h.Users.Get(context.Background(), email); err != nil {
+		if err := h.Users.Create(context.Background(), models.User{Email: email, Verified: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := utils.GenerateJWT(h.JWTConfig(), email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+// newServer returns the server handler of h
+func newServer(h *handlers.Handlers) http.Handler {
+	return router.NewHandler(&app.App{Config: h.Config, Handlers: h, Counter: testutil.NewHitCounter()}, router.Options{})
+}
+
+// adminRoutes are every route of the /api/v1/admin subrouter
+var adminRoutes = []struct {
+	method, path, body string
+}{
+	{"GET", "/api/v1/admin/dns-cache", ""},
+	{"POST", "/api/v1/admin/dns-cache/flush", ""},
+	{"POST", "/api/v1/admin/reload-disposable", ""},
+	{"GET", "/api/v1/admin/url-blocklist", ""},
+	{"POST", "/api/v1/admin/url-blocklist", `{"domain":"phish.example"}`},
+	{"DELETE", "/api/v1/admin/url-blocklist/phish.example", ""},
+	{"GET", "/api/v1/admin/maintenance", ""},
+	{"PUT", "/api/v1/admin/maintenance", `{"active":true}`},
+	{"GET", "/api/v1/admin/tenants", ""},
+	{"POST", "/api/v1/admin/tenants", `{"id":"acme","name":"Acme"}`},
+	{"GET", "/api/v1/admin/tenants/acme", ""},
+	{"PUT", "/api/v1/admin/tenants/acme", `{"name":"Acme"}`},
+	{"DELETE", "/api/v1/admin/tenants/acme", ""},
+}
+
+func TestAdminRoutesRequireAdmin(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Config.AdminEmails = []string{"ops@example.com"}
+	server := newServer(h)
+
+	for _, route := range adminRoutes {
+		for _, email := range []string{"", "user@example.com"} {
+			req := httptest.NewRequest(route.method, route.path, strings.NewReader(route.body))
+			req.Header.Set("Content-Type", "application/json")
+			want := http.StatusUnauthorized
+			if email != "" {
+				req.Header.Set("Authorization", bearer(t, h, email))
+				want = http.StatusForbidden
+			}
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+			if rec.Code != want {
+				t.Errorf("%s %s as %q: status = %d, want %d", route.method, route.path, email, rec.Code, want)
+			}
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/admin/dns-cache", nil)
+	req.Header.Set("Authorization", bearer(t, h, "ops@example.com"))
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /api/v1/admin/dns-cache as admin: status = %d, want 200", rec.Code)
+	}
+
+	// Without a configuration nobody is an admin
+	h.Config.AdminEmails = nil
+	server = router.SetupRouter(&app.App{Handlers: h, Counter: testutil.NewHitCounter()})
+	req = httptest.NewRequest("GET", "/api/v1/admin/dns-cache", nil)
+	req.Header.Set("Authorization", bearer(t, h, "ops@example.com"))
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("GET /api/v1/admin/dns-cache without a config: status = %d, want 403", rec.Code)
+	}
+}
+
+// TestMaintenance flips maintenance through the admin endpoint and checks
+// that only the tool in maintenance is refused, its page shows the banner,
+// health checks keep answering and the window ends by itself
+func TestMaintenance(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Config.AdminEmails = []string{"ops@example.com"}
+	clock := h.Clock.(*testutil.Clock)
+	server := newServer(h)
+	admin := bearer(t, h, "ops@example.com")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if strings.HasPrefix(path, "/api/v1/admin/") {
+			req.Header.Set("Authorization", admin)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	ibanStatus := func() int {
+		return do("POST", "/api/v1/validate/iban", `{"iban":"DE89370400440532013000"}`).Code
+	}
+
+	for _, body := range []string{
+		`{}`,
+		`{"tool":"no-such-tool","active":true}`,
+		`{"active":true,"duration":"soon"}`,
+		`{"active":true,"exempt":true}`,
+	} {
+		if rec := do("PUT", "/api/v1/admin/maintenance", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	rec := do("PUT", "/api/v1/admin/maintenance", `{"tool":"ip-validate","active":true,"message":"GeoIP upgrade","duration":"10m"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT maintenance: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	for _, path := range []string{"/api/v1/validate/ip", "/api/lite/v1/validate/ip"} {
+		rec = do("POST", path, `{"ip":"8.8.8.8"}`)
+		var body map[string]string
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != http.StatusServiceUnavailable || body["message"] != "GeoIP upgrade" || body["code"] != middleware.MaintenanceErrorCode {
+			t.Errorf("POST %s in maintenance: status %d body %s", path, rec.Code, rec.Body)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "600" {
+			t.Errorf("POST %s: Retry-After = %q, want 600", path, got)
+		}
+	}
+	if status := ibanStatus(); status != http.StatusOK {
+		t.Errorf("IBAN validation during IP maintenance: status = %d, want 200", status)
+	}
+	for _, path := range []string{"/api/v1/live", "/api/v1/ready"} {
+		if rec := do("GET", path, ""); rec.Code != http.StatusOK {
+			t.Errorf("GET %s during maintenance: status = %d, want 200", path, rec.Code)
+		}
+	}
+	var ready struct {
+		Maintenance struct {
+			Tools map[string]json.RawMessage `json:"tools"`
+		} `json:"maintenance"`
+	}
+	json.Unmarshal(do("GET", "/api/v1/ready", "").Body.Bytes(), &ready)
+	if _, ok := ready.Maintenance.Tools["ip-validate"]; !ok {
+		t.Errorf("ready does not report the IP maintenance")
+	}
+
+	rec = do("GET", "/ip-geolocation-api", "")
+	if !strings.Contains(rec.Body.String(), `class="maintenance-banner"`) || !strings.Contains(rec.Body.String(), "GeoIP upgrade") {
+		t.Errorf("IP page in maintenance has no banner")
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("IP page in maintenance: Cache-Control = %q, want no-store", got)
+	}
+	if rec = do("GET", "/iban-validation-api", ""); strings.Contains(rec.Body.String(), `class="maintenance-banner"`) {
+		t.Errorf("IBAN page shows the IP maintenance banner")
+	}
+
+	// The window ends after its duration without another request
+	clock.Advance(10 * time.Minute)
+	if rec = do("POST", "/api/v1/validate/ip", `{"ip":"8.8.8.8"}`); rec.Code == http.StatusServiceUnavailable {
+		t.Errorf("POST /api/v1/validate/ip after the window: status = %d", rec.Code)
+	}
+	if rec = do("GET", "/ip-geolocation-api", ""); strings.Contains(rec.Body.String(), `class="maintenance-banner"`) {
+		t.Errorf("IP page shows the banner after the window")
+	}
+
+	// Global maintenance spares exempt tools and ends when turned off
+	do("PUT", "/api/v1/admin/maintenance", `{"active":true}`)
+	do("PUT", "/api/v1/admin/maintenance", `{"tool":"ip-validate","active":true,"exempt":true}`)
+	if status := ibanStatus(); status != http.StatusServiceUnavailable {
+		t.Errorf("IBAN validation during global maintenance: status = %d, want 503", status)
+	}
+	if rec = do("POST", "/api/v1/validate/ip", `{"ip":"8.8.8.8"}`); rec.Code == http.StatusServiceUnavailable {
+		t.Errorf("exempt IP validation during global maintenance: status = %d", rec.Code)
+	}
+	if rec = do("GET", "/api/v1/live", ""); rec.Code != http.StatusOK {
+		t.Errorf("GET /api/v1/live during global maintenance: status = %d, want 200", rec.Code)
+	}
+	do("PUT", "/api/v1/admin/maintenance", `{"active":false}`)
+	if status := ibanStatus(); status != http.StatusOK {
+		t.Errorf("IBAN validation after global maintenance: status = %d, want 200", status)
+	}
+}
+
+// TestServesWithoutGeoDB checks that a missing GeoLite database fails the
+// geolocation routes with 503 while the rest of the API keeps serving
+func TestServesWithoutGeoDB(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.GeoIP = validation.NewGeoIPService(filepath.Join(t.TempDir(), "missing.mmdb"))
+	server := newServer(h)
+
+	for _, tt := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"POST", "/api/v1/validate/ip", `{"ip":"8.8.8.8"}`, http.StatusServiceUnavailable},
+		{"POST", "/api/v1/validate/iban", `{"iban":"DE89370400440532013000"}`, http.StatusOK},
+		{"POST", "/api/v1/generate/ics", `{"events":[{"summary":"Sync","start":"2026-03-02T09:30:00Z"}]}`, http.StatusOK},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status = %d, want %d: %.300s", tt.method, tt.path, rec.Code, tt.status, rec.Body)
+		}
+	}
+}
+
+// TestRecordedExamplesReplay records examples through the router and
+// replays them against a fresh one, the drift check of the fixtures
+// probeWriter is a ResponseWriter that keeps nothing, reusing its header
+// map, so it adds no allocations of its own
+type probeWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *probeWriter) Header() http.Header         { return w.header }
+func (w *probeWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *probeWriter) WriteHeader(status int)      { w.status = status }
+
+// liveAllocs is the allocations per /live request served by server
+func liveAllocs(server http.Handler, runs int) float64 {
+	req := httptest.NewRequest("GET", "/api/v1/live", nil)
+	w := &probeWriter{header: http.Header{}}
+	return testing.AllocsPerRun(runs, func() {
+		clear(w.header)
+		server.ServeHTTP(w, req)
+	})
+}
+
+func TestLiveSkipsMiddleware(t *testing.T) {
+	h := testutil.NewHandlers()
+	counter := testutil.NewHitCounter()
+	server := router.NewHandler(&app.App{Config: h.Config, Handlers: h, Counter: counter}, router.Options{})
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/live", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"message\":\"Live\"}\n" || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("GET /live = %d %q", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("HEAD", "/api/v1/live", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("HEAD /live = %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+	if len(counter.Hits) != 0 {
+		t.Errorf("/live was counted: %v", counter.Hits)
+	}
+	if n := liveAllocs(server, 100); n != 0 {
+		t.Errorf("/live allocates %v times per request, want 0", n)
+	}
+
+	// Other routes still get the whole chain
+	req := httptest.NewRequest("POST", "/api/v1/validate/email", strings.NewReader(`{"email":"ada@example.com"}`))
+	req.Header.Set("X-Correlation-ID", "probe-1")
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if counter.Count("email-validate") != 1 {
+		t.Errorf("email validation counted %d times, want once", counter.Count("email-validate"))
+	}
+	if rec.Header().Get("X-Correlation-ID") != "probe-1" {
+		t.Error("email validation skipped the correlation middleware")
+	}
+
+	// Methods the probe does not answer go to the router
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/live", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /live = %d, want 405", rec.Code)
+	}
+}
+
+// BenchmarkLive is the cost of one liveness probe, which must stay free of
+// allocations
+func BenchmarkLive(b *testing.B) {
+	h := testutil.NewHandlers()
+	server := router.NewHandler(&app.App{Config: h.Config, Handlers: h, Counter: testutil.NewHitCounter()}, router.Options{})
+	if n := liveAllocs(server, 100); n != 0 {
+		b.Fatalf("/live allocates %v times per request, want 0", n)
+	}
+	req := httptest.NewRequest("GET", "/api/v1/live", nil)
+	w := &probeWriter{header: http.Header{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		clear(w.header)
+		server.ServeHTTP(w, req)
+	}
+}
+
+func TestRecordedExamplesReplay(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Config.RecordExamples = true
+	h.Config.ExamplesDir = t.TempDir()
+	recording := newServer(h)
+	for _, route := range apiRoutes[:6] {
+		req := httptest.NewRequest(route.method, route.path, strings.NewReader(route.body))
+		if route.body != "" {
+			contentType := route.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			req.Header.Set("Content-Type", contentType)
+		}
+		recording.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if list := examples.ForRoute(h.Config.ExamplesDir, "/api/v1/validate/iban"); len(list) != 1 || list[0].Status != http.StatusOK {
+		t.Fatalf("IBAN examples = %+v, want one 200", list)
+	}
+
+	h.Config.RecordExamples = false
+	mismatches, err := examples.Replay(newServer(h), h.Config.ExamplesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) > 0 {
+		t.Errorf("replayed fixtures drifted: %+v", mismatches)
+	}
+}
+
+// TestGeoDatabaseDate checks that the build date of the GeoLite database
+// reaches the IP API, /ready and the IP tool page
+func TestGeoDatabaseDate(t *testing.T) {
+	geo := validation.NewGeoIPService(validation.DefaultGeoDBPath)
+	info, err := geo.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := testutil.NewHandlers()
+	h.GeoIP = geo
+	server := newServer(h)
+
+	for _, tt := range []struct {
+		method, path, body, want string
+	}{
+		{"POST", "/api/v1/validate/ip", `{"ip":"8.8.8.8"}`, `"database_date":"` + info.DatabaseDate + `"`},
+		{"GET", "/api/v1/ready", "", `"database_date":"` + info.DatabaseDate + `"`},
+		{"GET", "/ip-geolocation-api", "", "Database built " + info.DatabaseDate},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code >= 300 || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s %s: status = %d, body lacks %s: %.500s", tt.method, tt.path, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
+
+// TestErrorPages checks that unknown routes and methods answer JSON under
+// /api/ and HTML elsewhere
+func TestErrorPages(t *testing.T) {
+	server := newServer(testutil.NewHandlers())
+	tests := []struct {
+		method, path string
+		status       int
+		contentType  string
+	}{
+		{"GET", "/api/v1/nope", http.StatusNotFound, "application/json"},
+		{"GET", "/nope", http.StatusNotFound, "text/html"},
+		{"DELETE", "/api/v1/validate/email", http.StatusMethodNotAllowed, "application/json"},
+		{"DELETE", "/email-validation-api", http.StatusMethodNotAllowed, "text/html"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.status || !strings.HasPrefix(rec.Header().Get("Content-Type"), tt.contentType) {
+			t.Errorf("%s %s: status %d, Content-Type %q, want %d %s",
+				tt.method, tt.path, rec.Code, rec.Header().Get("Content-Type"), tt.status, tt.contentType)
+		}
+	}
+
+	// Pages are served 304 on a repeat request
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	again := httptest.NewRecorder()
+	server.ServeHTTP(again, req)
+	if rec.Code != http.StatusOK || again.Code != http.StatusNotModified {
+		t.Errorf("home page: status %d then %d, want 200 then 304", rec.Code, again.Code)
+	}
+}
+
+// TestMethods checks the HEAD, OPTIONS and 405 answers the router derives
+// from the registered routes
+func TestMethods(t *testing.T) {
+	server := newServer(testutil.NewHandlers())
+	send := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	for _, path := range []string{"/api/v1/live", "/qr-code-generator-api", "/"} {
+		get, head := send("GET", path), send("HEAD", path)
+		if get.Code != http.StatusOK || head.Code != http.StatusOK || head.Body.Len() != 0 {
+			t.Errorf("HEAD %s: status %d with %d bytes, GET %d; want an empty 200", path, head.Code, head.Body.Len(), get.Code)
+		}
+		for _, name := range []string{"Content-Type", "ETag", "Cache-Control"} {
+			if head.Header().Get(name) != get.Header().Get(name) {
+				t.Errorf("HEAD %s: %s %q, GET has %q", path, name, head.Header().Get(name), get.Header().Get(name))
+			}
+		}
+	}
+
+	// A POST-only route
+	const path = "/api/v1/validate/iban"
+	if rec := send("OPTIONS", path); rec.Code != http.StatusNoContent || rec.Header().Get("Allow") != "POST, OPTIONS" {
+		t.Errorf("OPTIONS %s: status %d, Allow %q, want 204 with POST, OPTIONS", path, rec.Code, rec.Header().Get("Allow"))
+	}
+	for _, method := range []string{"GET", "HEAD", "PUT", "DELETE"} {
+		rec := send(method, path)
+		if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "POST, OPTIONS" {
+			t.Errorf("%s %s: status %d, Allow %q, want 405 with POST, OPTIONS", method, path, rec.Code, rec.Header().Get("Allow"))
+		}
+		var body map[string]string
+		if method != "HEAD" && (json.Unmarshal(rec.Body.Bytes(), &body) != nil || body["message"] == "") {
+			t.Errorf("%s %s: body %s, want the JSON error", method, path, rec.Body)
+		}
+	}
+
+	// A GET route allows HEAD too
+	if rec := send("DELETE", "/api/v1/live"); rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("DELETE /api/v1/live: status %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+	// Unknown paths are not found whatever the method
+	if rec := send("OPTIONS", "/api/v1/nope"); rec.Code != http.StatusNotFound || rec.Header().Get("Allow") != "" {
+		t.Errorf("OPTIONS /api/v1/nope: status %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
+
+// TestLegacyAliases checks that the retired paths answer like their
+// canonical routes, marked deprecated
+func TestLegacyAliases(t *testing.T) {
+	h := testutil.NewHandlers()
+	server := router.SetupRouterWithOptions(&app.App{Config: h.Config, Handlers: h, Counter: testutil.NewHitCounter()},
+		router.Options{LegacyRoutes: true})
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"email":"user@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	canonical := post("/api/v1/validate/email")
+	alias := post("/api/v1/email/validate")
+	if alias.Code != canonical.Code || alias.Body.String() != canonical.Body.String() {
+		t.Errorf("alias: %d %s, canonical: %d %s", alias.Code, alias.Body, canonical.Code, canonical.Body)
+	}
+	if alias.Header().Get("Deprecation") != "true" ||
+		alias.Header().Get("Link") != `</api/v1/validate/email>; rel="successor-version"` {
+		t.Errorf("alias headers = %v", alias.Header())
+	}
+	if canonical.Header().Get("Deprecation") != "" {
+		t.Error("the canonical route is marked deprecated")
+	}
+
+	// The alias has the body cap and media types of its canonical route
+	for _, tt := range []struct {
+		contentType, body string
+		status            int
+	}{
+		{"application/xml", `<email>user@example.com</email>`, http.StatusUnsupportedMediaType},
+		{"application/json", `{"email":"` + strings.Repeat("a", 64<<10) + `"}`, http.StatusRequestEntityTooLarge},
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/email/validate", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("alias with a %s body of %d bytes: status %d, want %d", tt.contentType, len(tt.body), rec.Code, tt.status)
+		}
+	}
+
+	// Without the option the old path is gone
+	rec := httptest.NewRecorder()
+	newServer(h).ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/email/validate", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without LegacyRoutes: status %d, want 404", rec.Code)
+	}
+}
+
+// TestRateLimitCoversLegacyAliases checks that a legacy alias counts
+// against its canonical route's limit instead of bypassing it
+func TestRateLimitCoversLegacyAliases(t *testing.T) {
+	h := testutil.NewHandlers()
+	server := router.SetupRouterWithOptions(&app.App{
+		Config:           h.Config,
+		Handlers:         h,
+		Counter:          testutil.NewHitCounter(),
+		ValidatorLimiter: middleware.NewRateLimiter(nil, "", 2, time.Minute, h.Clock),
+	}, router.Options{LegacyRoutes: true})
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"email":"user@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i, path := range []string{"/api/v1/email/validate", "/api/v1/validate/email"} {
+		rec := post(path)
+		if rec.Code != http.StatusCreated || rec.Header().Get("X-RateLimit-Remaining") != strconv.Itoa(1-i) {
+			t.Fatalf("%s: status %d, remaining %q", path, rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+		}
+	}
+	if rec := post("/api/v1/email/validate"); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("alias over the limit: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// TestLiteRateLimit checks that the lite limit is shared by every
+// instance through Redis and keyed on the forwarded client IP behind a
+// trusted proxy
+func TestLiteRateLimit(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+	var servers []http.Handler
+	for range 2 {
+		h := testutil.NewHandlers()
+		h.Config.TrustedProxy = true
+		servers = append(servers, router.SetupRouter(&app.App{
+			Config:      h.Config,
+			Handlers:    h,
+			Counter:     testutil.NewHitCounter(),
+			LiteLimiter: middleware.NewRateLimiter(redisClient, "test:lite:", policy.Lite.RequestsPerMinute, time.Minute, h.Clock),
+		}))
+	}
+	post := func(server http.Handler, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/lite/v1/validate/email", strings.NewReader(`{"email":"user@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", ip)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := range policy.Lite.RequestsPerMinute {
+		if rec := post(servers[i%2], "192.0.2.1"); rec.Code == http.StatusTooManyRequests {
+			t.Fatalf("request %d refused: %s", i+1, rec.Body)
+		}
+	}
+	if rec := post(servers[0], "192.0.2.1"); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("over the limit: status %d: %s", rec.Code, rec.Body)
+	}
+	// The proxy's own address is not what is limited
+	if rec := post(servers[1], "192.0.2.2"); rec.Code == http.StatusTooManyRequests {
+		t.Errorf("another client behind the proxy: status %d", rec.Code)
+	}
+	if len(redisServer.Keys()) == 0 {
+		t.Error("no counts in Redis")
+	}
+}
+
+// TestLiteRoutes checks that the lite group shares the handlers of the full
+// API under tighter limits and open CORS
+func TestLiteRoutes(t *testing.T) {
+	server := newServer(testutil.NewHandlers())
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", "https://blog.example")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A 1024 pixel QR code is fine on the full API and restricted in lite mode
+	qr := `{"type":"text","data":"hello","options":{"size":1024}}`
+	if rec := send("POST", "/api/v1/generate/qr", qr); rec.Code != http.StatusOK {
+		t.Errorf("full: status %d: %s", rec.Code, rec.Body)
+	}
+	rec := send("POST", "/api/lite/v1/generate/qr", qr)
+	var body map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusBadRequest || body["option"] != "size" || body["group"] != "lite" {
+		t.Errorf("lite: %d %s, want the size restricted", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("lite error without CORS headers")
+	}
+	for _, header := range []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "Retry-After"} {
+		if !strings.Contains(rec.Header().Get("Access-Control-Expose-Headers"), header) {
+			t.Errorf("%s is not exposed to cross-origin callers", header)
+		}
+	}
+	// Lite mode has no bulk endpoints
+	if rec := send("POST", "/api/lite/v1/validate/email/batch", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("lite batch: status %d, want 404", rec.Code)
+	}
+
+	// CORS is open on the lite group only
+	preflight := send("OPTIONS", "/api/lite/v1/validate/email", "")
+	if preflight.Code != http.StatusNoContent || preflight.Header().Get("Access-Control-Allow-Origin") != "*" ||
+		!strings.Contains(preflight.Header().Get("Access-Control-Allow-Methods"), "POST") {
+		t.Errorf("lite preflight: %d %v", preflight.Code, preflight.Header())
+	}
+	full := send("POST", "/api/v1/validate/email", `{"email":"ada@example.com"}`)
+	if origin := full.Header().Get("Access-Control-Allow-Origin"); origin == "*" {
+		t.Errorf("full API answers any origin")
+	}
+
+	// The tools spec tells the widget what lite mode lacks
+	var spec struct {
+		Tools []models.ToolSpec `json:"tools"`
+	}
+	json.Unmarshal(send("GET", "/api/lite/v1/tools", "").Body.Bytes(), &spec)
+	restricted := map[string]bool{}
+	for _, tool := range spec.Tools {
+		if tool.Name == "card-validate" && (tool.LitePath != "" || len(tool.UnavailableIn) == 0) {
+			t.Errorf("card-validate = %+v, want it unavailable in lite mode", tool)
+		}
+		for _, option := range tool.Options {
+			if option.Name == "size" && option.Max["lite"] == 512 {
+				restricted[tool.Name] = true
+			}
+		}
+	}
+	if !restricted["qr-generate"] {
+		t.Errorf("tools spec lacks the lite QR size: %+v", spec.Tools)
+	}
+}
+
+// TestTracing records the spans of an email validation continuing the
+// trace of an incoming traceparent header
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	h := testutil.NewHandlers()
+	resolver := testutil.NewResolver()
+	resolver.MX["example.com"] = []*net.MX{{Host: "mx.example.com.", Pref: 10}}
+	h.EmailDomains = validation.NewDomainChecker(resolver)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("POST", "/api/v1/validate/email", strings.NewReader(`{"email":"ada@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	req.RemoteAddr = "203.0.113.7:4321"
+	rec := httptest.NewRecorder()
+	newServer(h).ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	spans := recorder.Ended()
+	var server sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		if span.SpanKind() == trace.SpanKindServer {
+			server = span
+		}
+	}
+	if server == nil {
+		t.Fatalf("no server span in %d spans", len(spans))
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range server.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if server.Name() != "POST /api/v1/validate/email" || server.SpanContext().TraceID().String() != traceID ||
+		server.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("server span %s in trace %s under %s", server.Name(), server.SpanContext().TraceID(), server.Parent().SpanID())
+	}
+	if attrs["client.address"].AsString() != "203.0.113.7" || attrs["http.response.status_code"].AsInt64() != http.StatusCreated ||
+		attrs["http.route"].AsString() != "/api/v1/validate/email" {
+		t.Errorf("server span attributes = %v", server.Attributes())
+	}
+
+	// The DNS lookups are children of the request span
+	lookups := 0
+	for _, span := range spans {
+		if !strings.HasPrefix(span.Name(), "dns.") {
+			continue
+		}
+		lookups++
+		if span.Parent().SpanID() != server.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of the request span", span.Name())
+		}
+	}
+	if lookups == 0 {
+		t.Error("no DNS lookup spans")
+	}
+}
+
+// TestSignedResponses verifies signed validation results with the
+// published key set, as a partner would, and that tampering with any
+// result field breaks the signature
+func TestSignedResponses(t *testing.T) {
+	h := testutil.NewHandlers()
+	handler := newServer(h)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	set, err := client.FetchJWKS(context.Background(), server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := bearer(t, h, "partner@example.com")
+	for path, body := range map[string]string{
+		"/api/v1/validate/iban":  `{"iban":"DE89370400440532013000","sign_response":true}`,
+		"/api/v1/validate/email": `{"email":"ada@example.com","sign_response":true}`,
+	} {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var resp struct {
+			Result    map[string]json.RawMessage `json:"validationResult"`
+			Signature string                     `json:"signature"`
+			SignedAt  string                     `json:"signed_at"`
+			KeyID     string                     `json:"key_id"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Signature == "" {
+			t.Fatalf("%s: %d %s", path, rec.Code, rec.Body)
+		}
+		signedAt, err := set.Verify(resp.Result, resp.Signature)
+		if err != nil || signedAt.Format(time.RFC3339) != resp.SignedAt || resp.KeyID != set.Keys[0].KeyID {
+			t.Errorf("%s: signed at %v (%s), key %s: %v", path, signedAt, resp.SignedAt, resp.KeyID, err)
+		}
+
+		for field, value := range resp.Result {
+			tampered := maps.Clone(resp.Result)
+			switch {
+			case string(value) == "true":
+				tampered[field] = json.RawMessage("false")
+			case string(value) == "false":
+				tampered[field] = json.RawMessage("true")
+			default:
+				tampered[field] = json.RawMessage(`"tampered"`)
+			}
+			if _, err := set.Verify(tampered, resp.Signature); !errors.Is(err, client.ErrSignatureInvalid) {
+				t.Errorf("%s: changing %s: err = %v, want ErrSignatureInvalid", path, field, err)
+			}
+		}
+	}
+
+	// Signing needs an access token
+	req := httptest.NewRequest("POST", "/api/v1/validate/iban", strings.NewReader(`{"iban":"DE89370400440532013000","sign_response":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated: status %d, want 401", rec.Code)
+	}
+}
+
+// TestResultMetaReportsDegradation fails the cache and ages the GeoIP
+// database; the meta block reports both while the results stay whole
+func TestResultMetaReportsDegradation(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Cache.(*testutil.Cache).Err = errors.New("redis: connection refused")
+	geo := h.GeoIP.(*testutil.GeoIP)
+	geo.Info = models.GeoDatabaseInfo{DatabaseDate: "2020-01-01", BuiltAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	server := newServer(h)
+
+	post := func(path, body string) map[string]json.RawMessage {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		var resp map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code >= 300 {
+			t.Fatalf("%s: %d %s", path, rec.Code, rec.Body)
+		}
+		return resp
+	}
+	meta := func(resp map[string]json.RawMessage) models.ResultMeta {
+		var m models.ResultMeta
+		if err := json.Unmarshal(resp["meta"], &m); err != nil {
+			t.Fatalf("meta: %v in %s", err, resp["meta"])
+		}
+		return m
+	}
+
+	resp := post("/api/v1/validate/email?include_meta=true", `{"email":"ada@example.com"}`)
+	var email models.EmailValidation
+	if err := json.Unmarshal(resp["validationResult"], &email); err != nil || email.Email != "ada@example.com" ||
+		email.IsDomainValid == nil || !*email.IsDomainValid {
+		t.Errorf("email result = %s", resp["validationResult"])
+	}
+	m := meta(resp)
+	if len(m.SkippedChecks) != 1 || m.SkippedChecks[0].Check != "email-domain-cache" {
+		t.Errorf("skipped checks = %+v, want the domain cache", m.SkippedChecks)
+	}
+
+	m = meta(post("/api/v1/validate/ip?include_meta=true", `{"ip":"8.8.8.8"}`))
+	if len(m.Datasets) != 1 || m.Datasets[0].Name != validation.GeoLiteDataset || !m.Datasets[0].Stale ||
+		m.Datasets[0].BuiltAt != "2020-01-01" {
+		t.Errorf("datasets = %+v, want the stale GeoLite database", m.Datasets)
+	}
+
+	if resp := post("/api/v1/validate/email", `{"email":"ada@example.com"}`); resp["meta"] != nil {
+		t.Errorf("meta without include_meta: %s", resp["meta"])
+	}
+}
+
+func TestEmailResolverOption(t *testing.T) {
+	h := testutil.NewHandlers()
+	var system, doh int
+	h.EmailDomains = func(ctx context.Context, email string) (bool, bool, error) {
+		system++
+		return true, true, nil
+	}
+	h.DoHEmailDomains = func(ctx context.Context, email string) (bool, bool, error) {
+		doh++
+		return true, true, nil
+	}
+	server := newServer(h)
+	send := func(body, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/validate/email", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	token := bearer(t, h, "ada@example.com")
+	// Each request asks about another domain, which the domain cache has
+	// not seen
+
+	if rec := send(`{"email":"ada@example.com"}`, ""); rec.Code != http.StatusCreated || system != 1 || doh != 0 {
+		t.Errorf("default: status %d, %d system and %d DoH checks", rec.Code, system, doh)
+	}
+	if rec := send(`{"email":"ada@example.com","resolver":"doh"}`, ""); rec.Code != http.StatusUnauthorized || doh != 0 {
+		t.Errorf("anonymous DoH: status %d, %d DoH checks; want 401", rec.Code, doh)
+	}
+	if rec := send(`{"email":"bob@example.org","resolver":"doh"}`, token); rec.Code != http.StatusCreated || system != 1 || doh != 1 {
+		t.Errorf("authenticated DoH: status %d, %d system and %d DoH checks", rec.Code, system, doh)
+	}
+	if rec := send(`{"email":"cy@example.net","resolver":"dot"}`, token); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown resolver: status %d, want 400", rec.Code)
+	}
+
+	h.DoHEmailDomains = nil
+	if rec := send(`{"email":"di@example.edu","resolver":"doh"}`, token); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("DoH not configured: status %d, want 503", rec.Code)
+	}
+}
+
+func TestDeleteUserRevokesTokens(t *testing.T) {
+	h := testutil.NewHandlers()
+	server := newServer(h)
+	token := bearer(t, h, "ada@example.com")
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	network, err := h.WifiRotation.Create(context.Background(), "ada@example.com", models.WifiRotationRequest{Name: "lobby", SSID: "Guest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.WifiRotation.Create(context.Background(), "bob@example.com", models.WifiRotationRequest{Name: "patio", SSID: "Guest"})
+
+	rec := send("GET", "/api/v1/user/export")
+	var export models.UserExport
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&export) != nil || export.User.Email != "ada@example.com" ||
+		rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("export: status %d, %+v", rec.Code, export)
+	}
+	// The user's WiFi networks are part of their data, other users' are not
+	if len(export.WifiNetworks) != 1 || export.WifiNetworks[0].Name != "lobby" || export.WifiNetworks[0].Password != network.Password {
+		t.Errorf("exported WiFi networks = %+v, want ada's lobby", export.WifiNetworks)
+	}
+
+	if rec := send("DELETE", "/api/v1/user"); rec.Code != http.StatusOK {
+		t.Fatalf("delete: status %d: %s", rec.Code, rec.Body)
+	}
+	user, err := h.Users.Get(context.Background(), "ada@example.com")
+	if err != nil || !user.Deleted || user.TokensInvalidBefore.IsZero() {
+		t.Errorf("deleted user = %+v, %v", user, err)
+	}
+
+	// The token stops working at once, on every authenticated route
+	for _, path := range []string{"/api/v1/user/export", "/api/v1/user"} {
+		method := "GET"
+		if path == "/api/v1/user" {
+			method = "DELETE"
+		}
+		rec := send(method, path)
+		if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "revoked") {
+			t.Errorf("%s %s after deletion: status %d: %s", method, path, rec.Code, rec.Body)
+		}
+	}
+}
+
+// TestSharedResults shares an IBAN result and reads it back through the
+// JSON route and the result page until it expires
+func TestSharedResults(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.ShareLimiter = middleware.NewRateLimiter(nil, "", 2, time.Hour, h.Clock)
+	server := newServer(h)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("POST", "/api/v1/validate/iban", `{"iban":"DE44 5001 0517 5407 3249 31","share":true}`)
+	var shared struct {
+		ShareURL string `json:"share_url"`
+	}
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&shared) != nil {
+		t.Fatalf("share: status %d: %s", rec.Code, rec.Body)
+	}
+	id, ok := strings.CutPrefix(shared.ShareURL, h.Config.BaseURL+"/r/")
+	if !ok || len(id) != handlers.ShareIDLength || !utils.IsBase58(id) {
+		t.Fatalf("share_url = %q, want a %d character base58 ID", shared.ShareURL, handlers.ShareIDLength)
+	}
+	if rec := send("POST", "/api/v1/validate/iban", `{"iban":"DE89 3704 0044 0532 0130 00"}`); strings.Contains(rec.Body.String(), "share_url") {
+		t.Errorf("share_url without share: true: %s", rec.Body)
+	}
+
+	rec = send("GET", "/api/v1/r/"+id, "")
+	var body struct {
+		SharedResult models.SharedResult `json:"sharedResult"`
+	}
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&body) != nil || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("JSON: status %d: %s", rec.Code, rec.Body)
+	}
+	result := body.SharedResult
+	if result.ID != id || result.Tool != "iban-validate" || !result.ExpiresAt.Equal(result.CreatedAt.Add(handlers.ShareTTL)) {
+		t.Errorf("shared result = %+v", result)
+	}
+	// Only the redacted result was stored
+	if stored := string(result.Result); strings.Contains(stored, "5407324931") || !strings.Contains(stored, `"isValid":true`) {
+		t.Errorf("stored result is not redacted: %s", stored)
+	}
+
+	rec = send("GET", "/r/"+id, "")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") ||
+		!strings.Contains(rec.Body.String(), `<meta name="robots" content="noindex" />`) || rec.Header().Get("X-Robots-Tag") != "noindex" {
+		t.Errorf("page: status %d, headers %v", rec.Code, rec.Header())
+	}
+
+	// Unknown IDs, IDs that cannot have been issued and expired results
+	// are JSON 404s on the API and the HTML not found page otherwise
+	expired := func() { h.Clock.(*testutil.Clock).Advance(handlers.ShareTTL) }
+	for _, tt := range []struct {
+		id     string
+		before func()
+	}{
+		{"zzzzzzzzzz", nil},
+		{"0OIl000000", nil},
+		{id + "x", nil},
+		{id, expired},
+	} {
+		if tt.before != nil {
+			tt.before()
+		}
+		if rec := send("GET", "/api/v1/r/"+tt.id, ""); rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("JSON %s: status %d, Content-Type %q", tt.id, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if rec := send("GET", "/r/"+tt.id, ""); rec.Code != http.StatusNotFound || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("page %s: status %d, Content-Type %q", tt.id, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	// Creation is rate limited per client; validating without sharing is
+	// not. The earlier share is in a window the clock has moved past.
+	for range 2 {
+		send("POST", "/api/v1/validate/iban", `{"iban":"GB82 WEST 1234 5698 7654 32","share":true}`)
+	}
+	rec = send("POST", "/api/v1/validate/iban", `{"iban":"GB82 WEST 1234 5698 7654 32","share":true}`)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("third share: status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := send("POST", "/api/v1/validate/iban", `{"iban":"GB82 WEST 1234 5698 7654 32"}`); rec.Code != http.StatusOK {
+		t.Errorf("without share: status %d", rec.Code)
+	}
+
+	h.Shares = nil
+	if rec := send("POST", "/api/v1/validate/iban", `{"iban":"GB82 WEST 1234 5698 7654 32","share":true}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without a store: status %d, want 503", rec.Code)
+	}
+}
+
+func TestPixelQuota(t *testing.T) {
+	h := testutil.NewHandlers()
+	redisServer := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+	perQR := quota.QRCost(256)
+	h.Pixels = quota.NewLimiter(redisClient, "test:", quota.Limits{Anonymous: 5 * perQR, Free: 2 * perQR}, h.Clock)
+	if err := h.Users.Create(context.Background(), models.User{Email: "pro@example.com", Verified: true, Plan: quota.PlanPro}); err != nil {
+		t.Fatal(err)
+	}
+	server := newServer(h)
+	send := func(path, ip, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"type":"text","data":"hello","options":{"size":256}}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":1234"
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	code := func(rec *httptest.ResponseRecorder) string {
+		var body struct{ Code string }
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return body.Code
+	}
+
+	// The lite routes count requests and pixels; each limit answers with its own code
+	for i := 1; i <= 31; i++ {
+		rec := send("/api/lite/v1/generate/qr", "192.0.2.1", "")
+		switch {
+		case i <= 5:
+			if rec.Code != http.StatusOK || rec.Header().Get("X-Quota-Pixels-Remaining") != strconv.FormatInt((5-int64(i))*perQR, 10) {
+				t.Fatalf("request %d: status %d, %s pixels remaining", i, rec.Code, rec.Header().Get("X-Quota-Pixels-Remaining"))
+			}
+		case i <= 30:
+			if rec.Code != http.StatusTooManyRequests || code(rec) != "PIXEL_QUOTA_EXCEEDED" ||
+				rec.Header().Get("Retry-After") != "86400" || rec.Header().Get("X-Quota-Pixels-Remaining") != "0" {
+				t.Fatalf("request %d: status %d, Retry-After %q: %s", i, rec.Code, rec.Header().Get("Retry-After"), rec.Body)
+			}
+		default:
+			if rec.Code != http.StatusTooManyRequests || code(rec) != middleware.RateLimitErrorCode {
+				t.Fatalf("request %d: status %d: %s, want the request limit", i, rec.Code, rec.Body)
+			}
+		}
+	}
+
+	// Budgets are per client IP, or per user on their plan
+	if rec := send("/api/v1/generate/qr", "192.0.2.2", ""); rec.Code != http.StatusOK {
+		t.Errorf("another IP: status %d: %s", rec.Code, rec.Body)
+	}
+	user := bearer(t, h, "ada@example.com")
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if rec := send("/api/v1/generate/qr", "192.0.2.1", user); rec.Code != want {
+			t.Errorf("free user request %d: status %d, want %d", i+1, rec.Code, want)
+		}
+	}
+	for range 3 {
+		if rec := send("/api/v1/generate/qr", "192.0.2.1", bearer(t, h, "pro@example.com")); rec.Code != http.StatusOK ||
+			rec.Header().Get("X-Quota-Pixels-Remaining") != "" {
+			t.Errorf("pro user: status %d, remaining %q, want unmetered", rec.Code, rec.Header().Get("X-Quota-Pixels-Remaining"))
+		}
+	}
+	if used, _ := redisServer.Get("test:quota:pixels:2025-01-01:user:ada@example.com"); used != strconv.FormatInt(2*perQR, 10) {
+		t.Errorf("free user used %s credits, want %d", used, 2*perQR)
+	}
+
+	// The budget resets with the UTC day
+	h.Clock.(*testutil.Clock).Advance(24 * time.Hour)
+	if rec := send("/api/v1/generate/qr", "192.0.2.1", ""); rec.Code != http.StatusOK {
+		t.Errorf("next day: status %d: %s", rec.Code, rec.Body)
+	}
+
+	// Without Redis requests are let through unmetered
+	redisServer.Close()
+	for range 10 {
+		if rec := send("/api/v1/generate/qr", "192.0.2.1", user); rec.Code != http.StatusOK || rec.Header().Get("X-Quota-Pixels-Remaining") != "" {
+			t.Fatalf("Redis down: status %d: %s", rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestUploadsRequireAccessToken(t *testing.T) {
+	h := testutil.NewHandlers()
+	server := newServer(h)
+
+	req := httptest.NewRequest("POST", "/api/v1/uploads", strings.NewReader(`{"size":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous POST /api/v1/uploads: status = %d, want 401", rec.Code)
+	}
+}
+
+// TestBasePath mounts the router under BASE_PATH: routes match only under
+// the prefix, and links, redirects and shared result URLs carry it
+func TestBasePath(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Config.BaseURL = "https://example.com"
+	h.Config.BasePath = "/microtools"
+	server := newServer(h)
+	send := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tt := range []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/microtools/api/v1/live", http.StatusOK},
+		{"GET", "/microtools/", http.StatusOK},
+		{"GET", "/microtools/email-validation-api", http.StatusOK},
+		{"GET", "/microtools/api/lite/v1/tools", http.StatusOK},
+		{"GET", "/api/v1/live", http.StatusNotFound},
+		{"GET", "/email-validation-api", http.StatusNotFound},
+		{"GET", "/microtoolsx/", http.StatusNotFound},
+		{"DELETE", "/microtools/email-validation-api", http.StatusMethodNotAllowed},
+	} {
+		if rec := send(tt.method, tt.path, "", ""); rec.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, rec.Code, tt.want)
+		}
+	}
+	if rec := send("GET", "/microtools", "", ""); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/microtools/" {
+		t.Errorf("GET /microtools: status %d, Location %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	page := send("GET", "/microtools/", "", "").Body.String()
+	for _, want := range []string{
+		`href="https://example.com/microtools/"`,
+		`href="/microtools/static/css/site.`,
+		`href="/microtools/email-validation-api"`,
+		`action="/microtools/preferences/theme"`,
+		`name="redirect" value="/microtools/"`,
+		`<code>https://example.com/microtools</code>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("home page lacks %s", want)
+		}
+	}
+	if sitemap := send("GET", "/microtools/sitemap.xml", "", "").Body.String(); !strings.Contains(sitemap, "<loc>https://example.com/microtools/iban-validation-api</loc>") {
+		t.Errorf("sitemap:\n%s", sitemap)
+	}
+	if robots := send("GET", "/microtools/robots.txt", "", "").Body.String(); !strings.Contains(robots, "Disallow: /microtools/api/\n") ||
+		!strings.Contains(robots, "Sitemap: https://example.com/microtools/sitemap.xml") {
+		t.Errorf("robots.txt:\n%s", robots)
+	}
+
+	rec := send("POST", "/microtools/preferences/theme", "application/x-www-form-urlencoded", "theme=dark&redirect=%2Fmicrotools%2Fqr-code-generator-api")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/microtools/qr-code-generator-api" {
+		t.Errorf("theme form: status %d, Location %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = send("POST", "/microtools/api/v1/validate/iban", "application/json", `{"iban":"DE44 5001 0517 5407 3249 31","share":true}`)
+	var shared struct {
+		ShareURL string `json:"share_url"`
+	}
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&shared) != nil {
+		t.Fatalf("share: status %d: %s", rec.Code, rec.Body)
+	}
+	path, ok := strings.CutPrefix(shared.ShareURL, "https://example.com")
+	if !ok || !strings.HasPrefix(path, "/microtools/r/") {
+		t.Fatalf("share_url = %q, want it under the base path", shared.ShareURL)
+	}
+	rec = send("GET", path, "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="/microtools/iban-validation-api"`) {
+		t.Errorf("GET %s: status %d", path, rec.Code)
+	}
+}
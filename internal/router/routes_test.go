@@ -0,0 +1,683 @@
+package router_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+	"github.com/innovelabs/microtools-go/internal/uploads"
+	"github.com/innovelabs/microtools-go/internal/wifirotation"
+)
+
+// apiRoute is a request that one handler answers with status
+type apiRoute struct {
+	method, path, body string
+	// contentType defaults to application/json for a body
+	contentType string
+	status      int
+}
+
+// apiRoutes send one request to every API handler
+var apiRoutes = []apiRoute{
+	{method: "POST", path: "/api/v1/validate/email", body: `{"email":"ada@example.com"}`, status: 201},
+	{method: "POST", path: "/api/v1/validate/ip", body: `{"ip":"192.0.2.1"}`, status: 201},
+	{method: "GET", path: "/api/v1/validate/ip?ip=192.0.2.1", status: 200},
+	{method: "POST", path: "/api/v1/validate/iban", body: `{"iban":"DE89370400440532013000"}`, status: 200},
+	{method: "POST", path: "/api/v1/validate/email/batch", body: "ada@example.com\n", contentType: "application/x-ndjson", status: 200},
+	{method: "POST", path: "/api/v1/validate/ip/batch", body: "192.0.2.1\n", contentType: "application/x-ndjson", status: 200},
+	{method: "POST", path: "/api/v1/validate/iban/batch", body: "DE89370400440532013000\n", contentType: "application/x-ndjson", status: 200},
+	{method: "POST", path: "/api/v1/validate/bankaccount", body: `{"country":"US","bank_code":"011000015","account_number":"12345678"}`, status: 200},
+	{method: "POST", path: "/api/v1/validate/barcode", body: `{"value":"]C101095011015300031726093010AB-123"}`, status: 200},
+	{method: "POST", path: "/api/v1/iban/format", body: `{"iban":"DE89370400440532013000"}`, status: 200},
+	{method: "GET", path: "/api/v1/iban/format/DE", status: 200},
+	{method: "POST", path: "/api/v1/validate/jsonschema", body: `{"schema":{"type":"object"},"document":{}}`, status: 200},
+	{method: "POST", path: "/api/v1/generate/qr", body: `{"type":"text","data":"hello"}`, status: 200},
+	{method: "GET", path: "/api/v1/generate/qr?type=text&data=hello", status: 200},
+	{method: "POST", path: "/api/v1/generate/barcode", body: `{"type":"EAN-13","data":"400638133393","format":"png"}`, status: 200},
+	{method: "GET", path: "/api/v1/generate/barcode?type=ean13&data=400638133393&format=png", status: 200},
+	{method: "GET", path: "/api/v1/generate/barcode/rules", status: 200},
+	{method: "GET", path: "/api/v1/testvectors", status: 200},
+	{method: "POST", path: "/api/v1/generate/ics", body: `{"events":[{"summary":"Sync","start":"2026-03-02T09:30:00Z"}]}`, status: 200},
+	{method: "POST", path: "/api/v1/generate/token", body: `{}`, status: 200},
+	{method: "POST", path: "/api/v1/generate/uuid", body: `{"version":"ulid","count":2}`, status: 200},
+	{method: "GET", path: "/api/v1/generate/uuid?version=v7", status: 200},
+	{method: "POST", path: "/api/v1/shorten", body: `{"url":"https://example.com/"}`, status: 201},
+	{method: "GET", path: "/api/v1/jobs/unknown", status: 404},
+	{method: "GET", path: "/api/v1/jobs/unknown/result", status: 404},
+	{method: "POST", path: "/api/v1/transform/html2text", body: `{"html":"<p>hello</p>"}`, status: 200},
+	{method: "POST", path: "/api/v1/convert/text", body: `{"text":"Hello World","operations":["slug","snake"]}`, status: 200},
+	{method: "POST", path: "/api/v1/convert/timestamp", body: `{"datetime":"2024-10-27T02:30","timezone":"Europe/Berlin","output_timezones":["UTC"]}`, status: 200},
+	{method: "POST", path: "/api/v1/tools/password/check", body: `{"password":"Summer1987!"}`, status: 200},
+	{method: "POST", path: "/api/v1/tools/password/generate", body: `{"length":20,"count":3,"exclude_ambiguous":true}`, status: 200},
+	{method: "POST", path: "/api/v1/tools/regex", body: `{"pattern":"(?P<word>\\w+)","flags":"i","input":"Hello World","mode":"find_all"}`, status: 200},
+	{method: "POST", path: "/api/v1/convert/csv", body: "name,age\nAda,36\n", contentType: "text/csv", status: 200},
+	{method: "POST", path: "/api/v1/extract/emails", body: `{"text":"Write to ada@example.com"}`, status: 200},
+	{method: "POST", path: "/api/v1/parse/number", body: `{"value":"1.234,5","locale":"de"}`, status: 200},
+	{method: "POST", path: "/api/v1/analyze/distance", body: `{"from":{"latitude":52.52,"longitude":13.40},"to":{"latitude":48.14,"longitude":11.58}}`, status: 200},
+	{method: "POST", path: "/api/v1/analyze/duplicates", body: `{"items":["a","a","b"]}`, status: 200},
+	{method: "POST", path: "/api/v1/analyze/qr", body: `{"type":"text","data":"hello"}`, status: 200},
+	{method: "POST", path: "/api/v1/analyze/textsafety", body: `{"inputs":["hello"]}`, status: 200},
+	{method: "GET", path: "/api/v1/tools", status: 200},
+	{method: "GET", path: "/api/v1/schemas", status: 200},
+	{method: "GET", path: "/api/v1/schemas/email-validation", status: 200},
+	{method: "GET", path: "/api/v1/r/unknown", status: 404},
+	{method: "GET", path: "/s/unknown", status: 404},
+	{method: "POST", path: "/preferences/theme", body: `{"theme":"dark"}`, status: 200},
+
+	{method: "POST", path: "/api/lite/v1/validate/email", body: `{"email":"ada@example.com"}`, status: 201},
+	{method: "POST", path: "/api/lite/v1/generate/qr", body: `{"type":"text","data":"hello"}`, status: 200},
+	{method: "GET", path: "/api/lite/v1/tools", status: 200},
+
+	{method: "POST", path: "/api/v1/user/register", body: `{"email":"new@example.com"}`, status: 201},
+	{method: "GET", path: "/api/v1/user/verify?token=invalid", status: 400},
+
+	{method: "GET", path: "/api/v1/live", status: 200},
+	{method: "GET", path: "/api/v1/ready", status: 200},
+	{method: "GET", path: "/api/v1/status", status: 200},
+	{method: "GET", path: "/api/v1/stats", status: 200},
+	{method: "GET", path: "/api/v1/datasets", status: 200},
+	{method: "GET", path: "/api/v1/datasets/disposable-domains", status: 200},
+	{method: "GET", path: "/.well-known/microtools-jwks.json", status: 200},
+}
+
+// TestAPIRoutes runs every API handler through the router with the fakes
+// of testutil: no network, Mongo, Redis or GeoLite database
+func TestAPIRoutes(t *testing.T) {
+	for _, route := range apiRoutes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, strings.NewReader(route.body))
+			if route.body != "" {
+				contentType := route.contentType
+				if contentType == "" {
+					contentType = "application/json"
+				}
+				req.Header.Set("Content-Type", contentType)
+			}
+			rec := httptest.NewRecorder()
+			newServer(testutil.NewHandlers()).ServeHTTP(rec, req)
+			if rec.Code != route.status {
+				t.Errorf("status = %d, want %d: %.300s", rec.Code, route.status, rec.Body)
+			}
+		})
+	}
+}
+
+// apiErrors are a failing request to every API handler, with the exact
+// models.APIError answering it
+var apiErrors = []struct {
+	method, path, contentType, body string
+	// auth sends the access token of a verified user
+	auth   bool
+	status int
+	want   string
+}{
+	{method: "POST", path: "/api/v1/validate/email", body: `{"email":`, status: 400, want: `{"message":"unexpected EOF","code":"INVALID_JSON"}`},
+	{method: "POST", path: "/api/v1/validate/email", body: `{"email":"ada@example.com","checks":["bogus"]}`, status: 400,
+		want: `{"message":"unsupported check: \"bogus\" (supported: syntax, domain, mx, disposable)","code":"INVALID_OPTION","field":"checks"}`},
+	{method: "POST", path: "/api/v1/validate/ip", body: `{"ip":"not-an-ip"}`, status: 400, want: `{"message":"Invalid IP address","code":"INVALID_DATA","field":"ip"}`},
+	{method: "POST", path: "/api/v1/validate/ip", contentType: "application/xml", body: "<ip/>", status: 415,
+		want: `{"message":"unsupported content type: use application/json or text/plain","code":"UNSUPPORTED_MEDIA_TYPE"}`},
+	{method: "POST", path: "/api/v1/validate/iban", contentType: "text/plain", body: " ", status: 400, want: `{"message":"plain text body is empty","code":"MISSING_FIELD"}`},
+	{method: "POST", path: "/api/v1/validate/email/batch", contentType: "text/csv", body: "a", status: 415,
+		want: `{"message":"unsupported content type: batch endpoints accept application/x-ndjson","code":"UNSUPPORTED_MEDIA_TYPE"}`},
+	{method: "POST", path: "/api/v1/validate/bankaccount", body: `{}`, status: 400, want: `{"message":"country is required, one of CA, GB, US","code":"MISSING_FIELD"}`},
+	{method: "POST", path: "/api/v1/validate/barcode", body: `{"value":"1","symbology":"nope"}`, status: 400,
+		want: `{"message":"symbology must be one of UPC-A, EAN-13, EAN-8, ITF-14, Code39, GS1-128","code":"UNSUPPORTED_TYPE","field":"symbology"}`},
+	{method: "POST", path: "/api/v1/iban/format", body: `[`, status: 400, want: `{"message":"unexpected EOF","code":"INVALID_JSON"}`},
+	{method: "GET", path: "/api/v1/iban/format/XX", status: 404, want: `{"message":"unsupported IBAN country: \"XX\"","code":"UNSUPPORTED_TYPE","field":"countryCode"}`},
+	{method: "POST", path: "/api/v1/validate/jsonschema", body: `{"schema":`, status: 400, want: `{"message":"invalid JSON body","code":"INVALID_JSON"}`},
+	{method: "GET", path: "/api/v1/generate/qr?type=text", status: 400, want: `{"message":"data is required for this type","code":"MISSING_FIELD","field":"data"}`},
+	{method: "GET", path: "/api/v1/generate/qr?type=text&data=x&size=big", status: 400, want: `{"message":"size must be a number","code":"INVALID_OPTION"}`},
+	{method: "POST", path: "/api/v1/generate/qr", body: `{"type":"bogus","data":"x"}`, status: 400, want: `{"message":"unsupported type: bogus","code":"UNSUPPORTED_TYPE","field":"type"}`},
+	{method: "POST", path: "/api/v1/generate/qr", body: `{"type":"text","data":"` + strings.Repeat("x", 8000) + `"}`, status: 400,
+		want: `{"message":"failed to generate QR code","code":"DATA_TOO_LONG","field":"data"}`},
+	{method: "POST", path: "/api/v1/generate/qr", body: `{"type":"url","data":"ftp://example.com"}`, status: 400,
+		want: `{"message":"invalid data: URL must start with http:// or https://","code":"INVALID_DATA","field":"data"}`},
+	{method: "POST", path: "/api/v1/generate/qr", body: `{"type":"text","data":"x","options":{"logo":"aGVsbG8="}}`, status: 400,
+		want: `{"message":"invalid logo: must be a PNG or JPEG image","code":"INVALID_OPTION","field":"options.logo"}`},
+	{method: "POST", path: "/api/v1/generate/qr", body: `{"type":"text","data":"x","options":{"logo":"aGVsbG8=","logo_size_percent":40}}`, status: 400,
+		want: `{"message":"logo_size_percent must be between 5 and 25","code":"INVALID_OPTION","field":"options.logo_size_percent"}`},
+	{method: "POST", path: "/api/v1/generate/qr", body: `{"type":"text","data":"x","options":{"logo":"aGVsbG8=","error_correction":"L"}}`, status: 400,
+		want: `{"message":"a logo needs error correction H; L cannot restore the modules it covers","code":"INVALID_OPTION","field":"options.error_correction"}`},
+	{method: "POST", path: "/api/v1/generate/barcode", body: `{"type":"bogus","data":"1"}`, status: 400,
+		want: `{"message":"invalid barcode type: must be UPC-A, EAN-13, Code128, Code93, Code39, ITF-14, ISBN, or Pharmacode","code":"UNSUPPORTED_TYPE","field":"type"}`},
+	{method: "POST", path: "/api/v1/generate/barcode", body: `{"type":"EAN-13","data":"400638133393","format":"png","foreground_color":"yellow"}`, status: 400,
+		want: `{"message":"invalid data for the specified barcode type: foreground_color and background_color are too close to scan: contrast is 1.1:1, at least 3:1 is required","code":"INVALID_OPTION","field":"foreground_color"}`},
+	{method: "GET", path: "/api/v1/generate/barcode?type=code128&data=ABC&format=svg&height=tall", status: 400, want: `{"message":"height must be a number","code":"INVALID_OPTION"}`},
+	{method: "POST", path: "/api/v1/generate/qr", body: `{"type":"text","data":"x","options":{"eror_correction":"H"}}`, status: 400,
+		want: `{"message":"unknown field \"eror_correction\"","code":"UNKNOWN_FIELD","field":"eror_correction"}`},
+	{method: "POST", path: "/api/v1/generate/qr", contentType: "text/plain", body: "x", status: 415,
+		want: `{"message":"unsupported content type: use application/json","code":"UNSUPPORTED_MEDIA_TYPE"}`},
+	{method: "POST", path: "/api/v1/validate/email", body: `{"emial":"ada@example.com"}`, status: 400,
+		want: `{"message":"json: unknown field \"emial\"","code":"UNKNOWN_FIELD","field":"emial"}`},
+	{method: "POST", path: "/api/v1/validate/email", body: `{"email":"` + strings.Repeat("a", 64<<10) + `"}`, status: 413,
+		want: `{"message":"request body is too large: the limit is 65536 bytes","code":"BODY_TOO_LARGE"}`},
+	{method: "POST", path: "/api/v1/tools/regex", body: `{"pattern":"a","input":"` + strings.Repeat("a", 128<<10) + `"}`, status: 413,
+		want: `{"message":"request body is too large: the limit is 131072 bytes","code":"BODY_TOO_LARGE"}`},
+	{method: "POST", path: "/api/v1/validate/jsonschema", body: `{"schema":{},"document":"` + strings.Repeat("a", 4<<20) + `"}`, status: 413,
+		want: `{"message":"request body is too large: the limit is 4194304 bytes","code":"BODY_TOO_LARGE"}`},
+	{method: "POST", path: "/api/v1/generate/ics", body: `{"events":[]}`, status: 400, want: `{"message":"invalid event: at least one event is required","code":"INVALID_DATA","field":"events"}`},
+	{method: "POST", path: "/api/v1/generate/token", body: `{"mode":"bogus"}`, status: 400,
+		want: `{"message":"invalid token request: unsupported mode \"bogus\": must be passphrase or random","code":"INVALID_OPTION"}`},
+	{method: "POST", path: "/api/v1/generate/uuid", body: `{"version":"v1"}`, status: 400,
+		want: `{"message":"invalid UUID request: unsupported version \"v1\": must be v4, v7 or ulid","code":"INVALID_OPTION"}`},
+	{method: "POST", path: "/api/v1/generate/labels", body: `{}`, status: 400, want: `{"message":"expected a multipart/form-data body with a CSV file","code":"INVALID_REQUEST"}`},
+	{method: "POST", path: "/api/v1/shorten", body: `{"url":"ftp://example.com/"}`, status: 400,
+		want: `{"message":"invalid short link: url must be an absolute http or https URL","code":"INVALID_DATA"}`},
+	{method: "GET", path: "/api/v1/jobs/unknown", status: 404, want: `{"message":"job not found","code":"NOT_FOUND"}`},
+	{method: "GET", path: "/api/v1/jobs/unknown/result", status: 404, want: `{"message":"job not found","code":"NOT_FOUND"}`},
+	{method: "POST", path: "/api/v1/transform/html2text", body: `{"html":`, status: 400, want: `{"message":"invalid JSON body","code":"INVALID_JSON"}`},
+	{method: "POST", path: "/api/v1/convert/timestamp", body: `{"epoch":0,"output_timezones":["Europe/Berln"]}`, status: 400,
+		want: `{"message":"unknown timezone \"Europe/Berln\"; did you mean Europe/Berlin?","code":"INVALID_OPTION","field":"timezone","suggestions":["Europe/Berlin"]}`},
+	{method: "POST", path: "/api/v1/convert/timestamp", body: `{"datetime":"2016-12-31T23:59:60Z"}`, status: 400,
+		want: `{"message":"invalid timestamp conversion: leap seconds such as 23:59:60 have no Unix time; use :59 or the next minute","code":"INVALID_OPTION"}`},
+	{method: "POST", path: "/api/v1/tools/regex", body: `{"pattern":"ab\\q","input":"x"}`, status: 400,
+		want: `{"message":"error parsing regexp: invalid escape sequence: \u0060\\q\u0060","code":"INVALID_DATA","field":"pattern","position":2}`},
+	{method: "POST", path: "/api/v1/tools/password/check", body: `{"password":""}`, status: 400,
+		want: `{"message":"invalid password check: password is required","code":"INVALID_DATA","field":"password"}`},
+	{method: "POST", path: "/api/v1/tools/password/generate", body: `{"lowercase":false,"uppercase":false,"digits":false,"symbols":false}`, status: 400,
+		want: `{"message":"invalid password options: enable at least one of lowercase, uppercase, digits and symbols","code":"INVALID_OPTION"}`},
+	{method: "POST", path: "/api/v1/tools/regex", body: `{"pattern":"x","flags":"g","input":"x"}`, status: 400,
+		want: `{"message":"invalid regex test: unknown flag 'g'; supported: i, m, s","code":"INVALID_OPTION"}`},
+	{method: "POST", path: "/api/v1/convert/text", body: `{"text":"x","operations":["rot13"]}`, status: 400,
+		want: `{"message":"invalid text conversion: unknown operation \"rot13\"; supported: slug, camel, snake, kebab, title, upper, lower, reverse, trim","code":"INVALID_OPTION","field":"operations"}`},
+	{method: "POST", path: "/api/v1/convert/csv", body: "a,b\n1\n", contentType: "text/csv", status: 400,
+		want: `{"message":"malformed CSV: line 2: expected 2 fields, got 1","code":"INVALID_DATA","field":"data"}`},
+	{method: "POST", path: "/api/v1/extract/emails", contentType: "text/csv", body: "x", status: 415,
+		want: `{"message":"unsupported content type: use application/json, text/plain or text/html","code":"UNSUPPORTED_MEDIA_TYPE"}`},
+	{method: "POST", path: "/api/v1/parse/number", body: `{"value":"abc","locale":"en"}`, status: 422, want: `{"message":"invalid number: no digits","code":"INVALID_DATA","field":"value"}`},
+	{method: "POST", path: "/api/v1/parse/number", body: `{}`, status: 400, want: `{"message":"value is required","code":"MISSING_FIELD"}`},
+	{method: "POST", path: "/api/v1/analyze/distance", body: `{"mode":"bogus"}`, status: 400, want: `{"message":"invalid mode: must be distance or geofence","code":"INVALID_OPTION","field":"mode"}`},
+	{method: "POST", path: "/api/v1/analyze/duplicates", body: `{}`, status: 400, want: `{"message":"invalid duplicates request: items are required","code":"INVALID_REQUEST"}`},
+	{method: "POST", path: "/api/v1/analyze/textsafety", body: `{}`, status: 400, want: `{"message":"invalid text safety request: inputs are required","code":"INVALID_REQUEST"}`},
+	{method: "POST", path: "/api/v1/analyze/qr", body: `{"type":"bogus","data":"x"}`, status: 400, want: `{"message":"unsupported type: bogus","code":"UNSUPPORTED_TYPE","field":"type"}`},
+	{method: "POST", path: "/api/v1/analyze/imagehash", body: `{}`, status: 400,
+		want: `{"message":"invalid image hash request: provide one image, or two to compare","code":"INVALID_REQUEST"}`},
+	{method: "GET", path: "/api/v1/schemas/unknown", status: 404, want: `{"message":"unknown schema","code":"NOT_FOUND"}`},
+	{method: "GET", path: "/api/v1/datasets/unknown", status: 404, want: `{"message":"unknown dataset","code":"NOT_FOUND"}`},
+	{method: "GET", path: "/api/v1/r/unknown", status: 404, want: `{"message":"shared result not found or expired","code":"NOT_FOUND"}`},
+	{method: "GET", path: "/s/unknown", status: 404, want: `{"message":"short link not found","code":"NOT_FOUND"}`},
+	{method: "GET", path: "/api/v1/unknown", status: 404, want: `{"message":"Not Found","code":"NOT_FOUND"}`},
+	{method: "POST", path: "/preferences/theme", body: `{"theme":"neon"}`, status: 400, want: `{"message":"theme must be one of: system, light, dark","code":"INVALID_OPTION"}`},
+	{method: "POST", path: "/api/lite/v1/generate/qr", body: `{"type":"text","data":"x","options":{"size":2000}}`, status: 400,
+		want: `{"message":"size is restricted in lite mode: maximum is 512 pixels","code":"INVALID_OPTION","field":"size","option":"size","group":"lite"}`},
+
+	{method: "POST", path: "/api/v1/user/register", body: `{}`, status: 400, want: `{"message":"Email is required","code":"MISSING_FIELD"}`},
+	{method: "POST", path: "/api/v1/user/register", body: `{"email":"not-an-email"}`, status: 400, want: `{"message":"Invalid email address","code":"INVALID_DATA"}`},
+	{method: "POST", path: "/api/v1/user/token", body: `{"email":"nobody@example.com"}`, status: 404, want: `{"message":"User not found","code":"NOT_FOUND"}`},
+	{method: "GET", path: "/api/v1/user/verify?token=x", status: 400, want: `{"message":"Invalid verification token","code":"TOKEN_INVALID"}`},
+	{method: "POST", path: "/api/v1/uploads", body: `{"size":5}`, auth: true, status: 503, want: `{"message":"resumable uploads are not configured","code":"SERVICE_UNAVAILABLE"}`},
+	{method: "POST", path: "/api/v1/generate/qr/wifi-rotating", body: `{"name":"Bad Name"}`, auth: true, status: 400,
+		want: `{"message":"invalid WiFi network: name must be 1 to 64 lower case letters, digits, - or _","code":"INVALID_REQUEST"}`},
+	{method: "GET", path: "/api/v1/generate/qr/wifi-rotating/none", auth: true, status: 404, want: `{"message":"WiFi network not found","code":"NOT_FOUND"}`},
+	{method: "DELETE", path: "/api/v1/generate/qr/wifi-rotating/none", auth: true, status: 404, want: `{"message":"WiFi network not found","code":"NOT_FOUND"}`},
+	{method: "POST", path: "/api/v1/generate/qr/wifi-rotating/none/rotate", auth: true, status: 404, want: `{"message":"WiFi network not found","code":"NOT_FOUND"}`},
+	{method: "GET", path: "/api/v1/admin/tenants", auth: true, status: 403, want: `{"message":"admin access required","code":"FORBIDDEN"}`},
+}
+
+// TestAPIErrors checks the models.APIError of a failure of every handler
+func TestAPIErrors(t *testing.T) {
+	h := testutil.NewHandlers()
+	server := newServer(h)
+	auth := bearer(t, h, "user@example.com")
+	for _, tt := range apiErrors {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if contentType := tt.contentType; contentType != "" || tt.body != "" {
+				if contentType == "" {
+					contentType = "application/json"
+				}
+				req.Header.Set("Content-Type", contentType)
+			}
+			if tt.auth {
+				req.Header.Set("Authorization", auth)
+			}
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+			if rec.Code != tt.status || rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("status = %d, Content-Type %q; want %d JSON", rec.Code, rec.Header().Get("Content-Type"), tt.status)
+			}
+			var got, want interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("body is not JSON: %s", rec.Body)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("body = %s, want %s", strings.TrimSpace(rec.Body.String()), tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteUserRoute(t *testing.T) {
+	h := testutil.NewHandlers()
+	server := newServer(h)
+	req := httptest.NewRequest("DELETE", "/api/v1/user", nil)
+	req.Header.Set("Authorization", bearer(t, h, "user@example.com"))
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestUploadRoutes(t *testing.T) {
+	h := testutil.NewHandlers()
+	objects, err := uploads.NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Uploads = uploads.NewStore(objects, h.Clock, 1<<20, time.Hour)
+	server := newServer(h)
+	auth := bearer(t, h, "user@example.com")
+
+	send := func(method, path, header, body string, want int) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", auth)
+		if name, value, ok := strings.Cut(header, ": "); ok {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("%s %s: status = %d, want %d: %s", method, path, rec.Code, want, rec.Body)
+		}
+		return rec
+	}
+	send("POST", "/api/v1/uploads", "Content-Type: application/json", `{"size":5}`, http.StatusCreated)
+	send("GET", "/api/v1/uploads/unknown", "", "", http.StatusNotFound)
+	send("PATCH", "/api/v1/uploads/unknown", "Content-Range: bytes 0-4/5", "hello", http.StatusNotFound)
+	send("POST", "/api/v1/uploads/unknown/complete", "", "", http.StatusNotFound)
+
+	// An email list uploaded in two chunks replaces the batch body
+	const list = "ada@example.com\nnot-an-email\n"
+	sum := sha256.Sum256([]byte(list))
+	var created struct{ Upload models.UploadStatus }
+	rec := send("POST", "/api/v1/uploads", "Content-Type: application/json",
+		fmt.Sprintf(`{"size":%d,"sha256":"%x","content_type":"application/x-ndjson"}`, len(list), sum), http.StatusCreated)
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	path := "/api/v1/uploads/" + created.Upload.ID
+	send("PATCH", path, "Content-Range: bytes 0-15/29", list[:16], http.StatusOK)
+
+	// A chunk past the received data gets 409 with where to resume
+	rec = send("PATCH", path, "Content-Range: bytes 20-28/29", list[20:], http.StatusConflict)
+	var conflict struct{ Received int64 }
+	if json.Unmarshal(rec.Body.Bytes(), &conflict); conflict.Received != 16 {
+		t.Errorf("409 received = %d, want 16: %s", conflict.Received, rec.Body)
+	}
+	// Re-sending the last chunk is harmless; changing it is refused
+	rec = send("PATCH", path, "Content-Range: bytes 0-15/29", list[:16], http.StatusOK)
+	var status struct{ Upload models.UploadStatus }
+	if json.Unmarshal(rec.Body.Bytes(), &status); status.Upload.Received != 16 {
+		t.Errorf("re-sent chunk: received = %d, want 16", status.Upload.Received)
+	}
+	send("PATCH", path, "Content-Range: bytes 0-15/29", strings.ToUpper(list[:16]), http.StatusConflict)
+	send("POST", path+"/complete", "", "", http.StatusConflict)
+	send("PATCH", path, "Content-Range: bytes 16-28/29", list[16:], http.StatusOK)
+	json.Unmarshal(send("POST", path+"/complete", "", "", http.StatusOK).Body.Bytes(), &status)
+	if !status.Upload.Complete || status.Upload.Token == "" {
+		t.Fatalf("completed upload = %+v, want a token", status.Upload)
+	}
+
+	rec = send("POST", "/api/v1/validate/email/batch?checks=syntax&upload="+status.Upload.Token, "", "", http.StatusOK)
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "ada@example.com") || !strings.Contains(lines[1], "not-an-email") {
+		t.Errorf("batch of the upload: %s", rec.Body)
+	}
+
+	// Data that does not match its sha256 is refused on completion and
+	// the upload deleted
+	rec = send("POST", "/api/v1/uploads", "Content-Type: application/json", fmt.Sprintf(`{"size":5,"sha256":"%x"}`, sha256.Sum256([]byte("hello"))), http.StatusCreated)
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	path = "/api/v1/uploads/" + created.Upload.ID
+	send("PATCH", path, "Content-Range: bytes 0-4/5", "jello", http.StatusOK)
+	send("POST", path+"/complete", "", "", http.StatusUnprocessableEntity)
+	send("GET", path, "", "", http.StatusNotFound)
+}
+
+func TestWifiRotationRoutes(t *testing.T) {
+	h := testutil.NewHandlers()
+	server := newServer(h)
+	owner, other := bearer(t, h, "user@example.com"), bearer(t, h, "other@example.com")
+
+	send := func(auth, method, path, body string, want int) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("%s %s: status = %d, want %d: %s", method, path, rec.Code, want, rec.Body)
+		}
+		return rec
+	}
+	current := func(rec *httptest.ResponseRecorder) models.WifiRotation {
+		t.Helper()
+		var body struct{ Wifi models.WifiRotation }
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Wifi
+	}
+
+	const path = "/api/v1/generate/qr/wifi-rotating/lobby"
+	send("", "POST", "/api/v1/generate/qr/wifi-rotating", `{"name":"lobby","ssid":"Guest"}`, http.StatusUnauthorized)
+	created := current(send(owner, "POST", "/api/v1/generate/qr/wifi-rotating", `{"name":"lobby","ssid":"Guest","interval":"1h"}`, http.StatusCreated))
+	send(owner, "POST", "/api/v1/generate/qr/wifi-rotating", `{"name":"lobby","ssid":"Guest"}`, http.StatusConflict)
+	send(other, "GET", path, "", http.StatusNotFound)
+
+	rec := send(owner, "GET", path+"?format=json", "", http.StatusOK)
+	if got := current(rec); got.Password != created.Password || got.Payload != "WIFI:T:WPA;S:Guest;P:"+created.Password+";;" {
+		t.Errorf("current network = %+v, want the created password in its payload", got)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Error("password response may be cached")
+	}
+
+	// The QR code encodes the current password
+	rec = send(owner, "GET", path+"?format=svg", "", http.StatusOK)
+	data, _ := json.Marshal(models.WifiData{SSID: "Guest", Password: created.Password, Security: "WPA"})
+	want, _, err := generator.RenderQR(context.Background(), models.QRRequest{Type: "wifi", Data: string(data), Options: models.QROptions{Format: "svg"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), want) {
+		t.Error("QR code does not encode the current password")
+	}
+	if rec = send(owner, "GET", path, "", http.StatusOK); rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("default Content-Type = %q, want image/png", rec.Header().Get("Content-Type"))
+	}
+
+	// Rotating right after creation keeps the password; after the window
+	// it changes once
+	var rotation struct {
+		Wifi    models.WifiRotation
+		Rotated bool
+	}
+	json.Unmarshal(send(owner, "POST", path+"/rotate", "", http.StatusOK).Body.Bytes(), &rotation)
+	if rotation.Rotated || rotation.Wifi.Password != created.Password {
+		t.Errorf("rotation within the window = %+v, want the created password", rotation)
+	}
+	h.Clock.(*testutil.Clock).Advance(wifirotation.RotationWindow)
+	json.Unmarshal(send(owner, "POST", path+"/rotate", "", http.StatusOK).Body.Bytes(), &rotation)
+	if !rotation.Rotated || rotation.Wifi.Password == created.Password || rotation.Wifi.Version != 2 {
+		t.Errorf("rotation after the window = %+v, want a new password", rotation)
+	}
+	if got := current(send(owner, "GET", path+"?format=json", "", http.StatusOK)); got.Password != rotation.Wifi.Password {
+		t.Error("GET does not return the rotated password")
+	}
+
+	send(other, "DELETE", path, "", http.StatusNotFound)
+	send(owner, "DELETE", path, "", http.StatusNoContent)
+	send(owner, "POST", path+"/rotate", "", http.StatusNotFound)
+}
+
+func TestTenants(t *testing.T) {
+	h := testutil.NewHandlers()
+	h.Config.AdminEmails = []string{"ops@example.com"}
+	h.Config.AutoVerify = true
+	server := newServer(h)
+	admin := bearer(t, h, "ops@example.com")
+
+	send := func(auth, host, method, path, body string, want int) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		if host != "" {
+			req.Host = host
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("%s %s on %q: status = %d, want %d: %s", method, path, host, rec.Code, want, rec.Body)
+		}
+		return rec
+	}
+	send(admin, "", "POST", "/api/v1/admin/tenants", `{
+		"id": "acme", "name": "Acme", "hosts": ["tools.acme.example"],
+		"disposable_domains": ["burner.example"], "requests_per_minute": 2,
+		"webhook_secret": "acme-webhook-secret",
+		"branding": {"site_name": "Acme Tools", "primary_color": "#0a84ff", "support_email": "help@acme.example"}
+	}`, http.StatusCreated)
+	send(admin, "", "POST", "/api/v1/admin/tenants", `{"id":"globex","name":"Globex","hosts":["api.globex.example"],"requests_per_minute":3}`, http.StatusCreated)
+	send(admin, "", "POST", "/api/v1/admin/tenants", `{"id":"acme","name":"Acme"}`, http.StatusConflict)
+	send(admin, "", "PUT", "/api/v1/admin/tenants/initech", `{"name":"Initech","hosts":["TOOLS.acme.example"]}`, http.StatusConflict)
+	if rec := send(admin, "", "GET", "/api/v1/admin/tenants", "", http.StatusOK); strings.Contains(rec.Body.String(), "acme-webhook-secret") {
+		t.Error("tenant list returns the webhook secret")
+	}
+
+	// Users registering on a tenant's host get tokens of that tenant
+	register := func(host, email string) string {
+		t.Helper()
+		var body struct{ Token string }
+		json.Unmarshal(send("", host, "POST", "/api/v1/user/register", `{"email":"`+email+`"}`, http.StatusCreated).Body.Bytes(), &body)
+		return "Bearer " + body.Token
+	}
+	acme := register("tools.acme.example", "dev@acme.example")
+	globex := register("api.globex.example:443", "dev@globex.example")
+
+	// The same address gets each tenant's disposable verdict
+	const validate = `{"email":"ada@burner.example","checks":["syntax","disposable"]}`
+	disposable := func(auth string) bool {
+		t.Helper()
+		var body struct{ ValidationResult models.EmailValidation }
+		json.Unmarshal(send(auth, "", "POST", "/api/v1/validate/email", validate, http.StatusCreated).Body.Bytes(), &body)
+		return body.ValidationResult.IsDisposable != nil && *body.ValidationResult.IsDisposable
+	}
+	if !disposable(acme) {
+		t.Error("acme's disposable domain is not disposable for acme")
+	}
+	if disposable(globex) || disposable("") {
+		t.Error("acme's disposable domain is disposable for other tenants")
+	}
+
+	// Each tenant has its own limit; acme's second request used it up
+	send(acme, "", "POST", "/api/v1/validate/email", validate, http.StatusCreated)
+	rec := send(acme, "", "POST", "/api/v1/validate/email", validate, http.StatusTooManyRequests)
+	if rec.Header().Get("Retry-After") == "" || !strings.Contains(rec.Body.String(), "RATE_LIMIT_EXCEEDED") {
+		t.Errorf("429 without Retry-After or code: %v %s", rec.Header(), rec.Body)
+	}
+	send(acme, "", "GET", "/api/v1/user/export", "", http.StatusOK)
+	send(globex, "", "POST", "/api/v1/validate/email", validate, http.StatusCreated)
+	send(globex, "", "POST", "/api/v1/validate/email", validate, http.StatusCreated)
+	send(globex, "", "POST", "/api/v1/validate/email", validate, http.StatusTooManyRequests)
+	for range 5 {
+		send("", "", "POST", "/api/v1/validate/email", validate, http.StatusCreated)
+	}
+
+	// Pages on a tenant's host carry its branding and are not cached
+	rec = send("", "tools.acme.example", "GET", "/", "", http.StatusOK)
+	page := rec.Body.String()
+	for _, want := range []string{"Acme Tools", `style="--brand-color: #0a84ff"`, "mailto:help@acme.example"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("acme's home page misses %q", want)
+		}
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" || rec.Header().Get("ETag") != "" {
+		t.Errorf("branded page caching: Cache-Control %q, ETag %q", rec.Header().Get("Cache-Control"), rec.Header().Get("ETag"))
+	}
+	if page := send("", "tools.acme.example", "GET", "/no-such-page", "", http.StatusNotFound).Body.String(); !strings.Contains(page, "Acme Tools") {
+		t.Error("acme's not found page is not branded")
+	}
+	for _, host := range []string{"", "api.globex.example"} {
+		if page := send("", host, "GET", "/", "", http.StatusOK).Body.String(); strings.Contains(page, "Acme") || strings.Contains(page, "--brand-color") {
+			t.Errorf("page on %q carries acme's branding", host)
+		}
+	}
+
+	// Deleted tenants' users fall back to the default tenant
+	send(admin, "", "DELETE", "/api/v1/admin/tenants/acme", "", http.StatusNoContent)
+	send(admin, "", "GET", "/api/v1/admin/tenants/acme", "", http.StatusNotFound)
+	if disposable(acme) {
+		t.Error("deleted tenant's disposable domain still applies")
+	}
+}
+
+func TestGenerateLabelsRoute(t *testing.T) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "labels.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("data,type\nhttps://example.com,QR\n"))
+	form.Close()
+
+	h := testutil.NewHandlers()
+	req := httptest.NewRequest("POST", "/api/v1/generate/labels", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	newServer(h).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatalf("status = %d, Content-Type %q, want a PDF: %.300s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+}
+
+func TestAnalyzeImageHashRoute(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 16, 16))); err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(img.Bytes())
+
+	h := testutil.NewHandlers()
+	req := httptest.NewRequest("POST", "/api/v1/analyze/imagehash", strings.NewReader(`{"images":["`+encoded+`","`+encoded+`"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	newServer(h).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %.300s", rec.Code, rec.Body)
+	}
+}
+
+func TestCorrelationIDs(t *testing.T) {
+	server := newServer(testutil.NewHandlers())
+	send := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	ids := map[string]string{"X-Correlation-ID": "order-42", "X-Request-ID": "req-7"}
+
+	for _, tt := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"POST", "/api/v1/validate/iban", `{"iban":"DE89370400440532013000"}`, http.StatusOK},
+		{"POST", "/api/v1/validate/iban", `{"iban":`, http.StatusBadRequest},
+		{"GET", "/api/v1/nope", "", http.StatusNotFound},
+		{"DELETE", "/api/v1/validate/iban", "", http.StatusMethodNotAllowed},
+		{"GET", "/no-such-page", "", http.StatusNotFound},
+	} {
+		rec := send(tt.method, tt.path, tt.body, ids)
+		if rec.Code != tt.status || rec.Header().Get("X-Correlation-ID") != "order-42" || rec.Header().Get("X-Request-ID") != "req-7" {
+			t.Errorf("%s %s: status %d, headers %v; want %d echoing both IDs", tt.method, tt.path, rec.Code, rec.Header(), tt.status)
+		}
+		// Requests without an ID are given one
+		if rec := send(tt.method, tt.path, tt.body, nil); len(rec.Header().Get("X-Request-ID")) != 32 {
+			t.Errorf("%s %s without IDs: headers %v; want a generated X-Request-ID", tt.method, tt.path, rec.Header())
+		}
+	}
+
+	// A 5KB value, or one that would split a header or log line, is dropped
+	// while the valid ID is still echoed
+	for _, malicious := range []string{strings.Repeat("A", 5*1024), "x\r\nSet-Cookie: session=1", "x level=ERROR"} {
+		rec := send("POST", "/api/v1/validate/iban", `{"iban":"DE89370400440532013000"}`,
+			map[string]string{"X-Correlation-ID": malicious, "X-Request-ID": "req-7"})
+		if rec.Code != http.StatusOK || rec.Header().Get("X-Correlation-ID") != "" || rec.Header().Get("X-Request-ID") != "req-7" {
+			t.Errorf("%.20q: status %d, headers %v", malicious, rec.Code, rec.Header())
+		}
+	}
+}
+
+// TestCorrelationIDsOnJobs submits a label sheet large enough to run as a
+// background job and finds the request's correlation ID on its status
+func TestCorrelationIDsOnJobs(t *testing.T) {
+	var csv bytes.Buffer
+	csv.WriteString("data,type\n")
+	for i := range 201 {
+		fmt.Fprintf(&csv, "%d,QR\n", i)
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "labels.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(csv.Bytes())
+	form.Close()
+
+	server := newServer(testutil.NewHandlers())
+	req := httptest.NewRequest("POST", "/api/v1/generate/labels", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("X-Correlation-ID", "order-42")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	var submitted struct{ Job models.JobStatus }
+	if rec.Code != http.StatusAccepted || json.NewDecoder(rec.Body).Decode(&submitted) != nil {
+		t.Fatalf("status = %d, want 202: %.300s", rec.Code, rec.Body)
+	}
+	if submitted.Job.CorrelationIDs["X-Correlation-Id"] != "order-42" {
+		t.Errorf("submitted job IDs = %v", submitted.Job.CorrelationIDs)
+	}
+
+	// The status is reported with the IDs whoever polls it
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", submitted.Job.StatusURL, nil))
+	var polled struct{ Job models.JobStatus }
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&polled) != nil || polled.Job.CorrelationIDs["X-Correlation-Id"] != "order-42" {
+		t.Errorf("job status: %d, %s", rec.Code, rec.Body)
+	}
+}
+
+// TestTestVectorsReplay sends every published test vector request and
+// compares the response with its digest
+func TestTestVectorsReplay(t *testing.T) {
+	server := newServer(testutil.NewHandlers())
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/testvectors", nil))
+	var published struct{ Vectors []models.TestVector }
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&published) != nil || len(published.Vectors) == 0 {
+		t.Fatalf("status %d: %.300s", rec.Code, rec.Body)
+	}
+
+	for _, vector := range published.Vectors {
+		req := httptest.NewRequest(vector.Method, vector.Path, bytes.NewReader(vector.Request))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		sum := sha256.Sum256(rec.Body.Bytes())
+		if rec.Code/100 != 2 || hex.EncodeToString(sum[:]) != vector.SHA256 ||
+			!strings.HasPrefix(rec.Header().Get("Content-Type"), vector.ContentType) {
+			t.Errorf("%s: status %d, %s, sha256 %x; want %s, %s", vector.Name, rec.Code, rec.Header().Get("Content-Type"), sum, vector.ContentType, vector.SHA256)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package router
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/config"
+)
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// pageHandler serves a UI page. Routes with a pageHandler are the pages
+// listed in sitemap.xml, so a new page is included by registering it.
+type pageHandler struct {
+	data  PageData
+	serve http.HandlerFunc
+}
+
+func (p pageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.serve(w, r)
+}
+
+// newSitemap walks the routes registered on router so far and renders the
+// sitemap of their pages once; it must be created after the last page is
+// added. Pages are listed by canonical path in registration order, dated
+// by their LastModified or else by built.
+func newSitemap(router *mux.Router, baseURL string, built time.Time, maxAge int) http.HandlerFunc {
+	set := sitemapURLSet{Xmlns: sitemapNamespace}
+	seen := map[string]bool{}
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		page, ok := route.GetHandler().(pageHandler)
+		if !ok || seen[page.data.Canonical] {
+			return nil
+		}
+		seen[page.data.Canonical] = true
+		lastMod := page.data.LastModified
+		if lastMod.IsZero() {
+			lastMod = built
+		}
+		entry := sitemapURL{Loc: baseURL + page.data.Canonical}
+		if !lastMod.IsZero() {
+			entry.LastMod = lastMod.UTC().Format(time.DateOnly)
+		}
+		set.URLs = append(set.URLs, entry)
+		return nil
+	})
+
+	body, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		log.Printf("Error rendering sitemap: %v", err)
+	}
+	body = append([]byte(xml.Header), body...)
+	cacheControl := "public, max-age=" + strconv.Itoa(maxAge)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Write(body)
+	}
+}
+
+// robotsTxt keeps crawlers off the API and points them at the sitemap
+func robotsTxt(site *config.Config, maxAge int) http.HandlerFunc {
+	body := "User-agent: *\nDisallow: " + site.Path("/api/") + "\n\nSitemap: " + site.AbsURL("/sitemap.xml") + "\n"
+	cacheControl := "public, max-age=" + strconv.Itoa(maxAge)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Write([]byte(body))
+	}
+}
+
+// buildTime is the commit time the Go toolchain stamped into the binary,
+// or else the modification time of the executable; zero when neither is
+// known
+func buildTime() time.Time {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key != "vcs.time" {
+				continue
+			}
+			if t, err := time.Parse(time.RFC3339, setting.Value); err == nil {
+				return t
+			}
+		}
+	}
+	if path, err := os.Executable(); err == nil {
+		if stat, err := os.Stat(path); err == nil {
+			return stat.ModTime()
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,105 @@
+package router
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/innovelabs/microtools-go/internal/app"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+func parseSitemap(t *testing.T, body string) sitemapURLSet {
+	t.Helper()
+	var set sitemapURLSet
+	if err := xml.Unmarshal([]byte(body), &set); err != nil {
+		t.Fatalf("sitemap is not valid XML: %v\n%s", err, body)
+	}
+	if set.XMLName.Space != sitemapNamespace || set.XMLName.Local != "urlset" {
+		t.Errorf("root element %v, want a urlset in %s", set.XMLName, sitemapNamespace)
+	}
+	return set
+}
+
+func TestSitemapListsEveryPage(t *testing.T) {
+	newRouter := func(baseURL string) *mux.Router {
+		h := testutil.NewHandlers()
+		h.Config.BaseURL = baseURL
+		return SetupRouter(&app.App{Config: h.Config, Handlers: h, Counter: testutil.NewHitCounter()})
+	}
+
+	// The pages are the routes serving a pageHandler
+	pages := map[string]bool{}
+	newRouter("https://example.com").Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if page, ok := route.GetHandler().(pageHandler); ok {
+			pages[page.data.Canonical] = true
+		}
+		return nil
+	})
+	for _, canonical := range []string{"/", "/email-validation-api", "/qr-code-generator-api", "/status"} {
+		if !pages[canonical] {
+			t.Errorf("%s is not registered as a page", canonical)
+		}
+	}
+
+	router := newRouter("https://tools.example.org/")
+	rec := get(router, "/sitemap.xml", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/xml; charset=utf-8" ||
+		!strings.HasPrefix(rec.Body.String(), xml.Header) {
+		t.Fatalf("status %d, Content-Type %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+	listed := map[string]int{}
+	for _, url := range parseSitemap(t, rec.Body.String()).URLs {
+		path, ok := strings.CutPrefix(url.Loc, "https://tools.example.org")
+		if !ok {
+			t.Errorf("%s is not under the base URL", url.Loc)
+		}
+		listed[path]++
+		if _, err := time.Parse(time.DateOnly, url.LastMod); url.LastMod != "" && err != nil {
+			t.Errorf("%s: lastmod %q is not a date", url.Loc, url.LastMod)
+		}
+	}
+	for canonical := range pages {
+		if listed[canonical] != 1 {
+			t.Errorf("%s is listed %d times, want once", canonical, listed[canonical])
+		}
+	}
+	if len(listed) != len(pages) {
+		t.Errorf("sitemap lists %v, want the pages %v", listed, pages)
+	}
+
+	rec = get(router, "/robots.txt", "")
+	want := "User-agent: *\nDisallow: /api/\n\nSitemap: https://tools.example.org/sitemap.xml\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != want || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("robots.txt: status %d, %q", rec.Code, rec.Body)
+	}
+}
+
+func TestSitemapLastModified(t *testing.T) {
+	page := homePage(t)
+	built := time.Date(2026, 3, 4, 23, 30, 0, 0, time.FixedZone("", -2*60*60))
+	router := mux.NewRouter()
+	router.Handle("/", renderPage(page, PageData{Canonical: "/"}, 600))
+	router.Handle("/dated", renderPage(page, PageData{Canonical: "/dated", LastModified: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}, 600))
+	// A second route to the same page is listed once
+	router.Handle("/dated/", renderPage(page, PageData{Canonical: "/dated"}, 600))
+	router.Handle("/api/v1/live", http.NotFoundHandler())
+
+	set := parseSitemap(t, get(newSitemap(router, "https://example.com", built, 600), "/sitemap.xml", "").Body.String())
+	want := []sitemapURL{
+		{Loc: "https://example.com/", LastMod: "2026-03-05"},
+		{Loc: "https://example.com/dated", LastMod: "2025-06-01"},
+	}
+	if len(set.URLs) != len(want) || set.URLs[0] != want[0] || set.URLs[1] != want[1] {
+		t.Errorf("urls = %+v, want %+v", set.URLs, want)
+	}
+
+	// Without a build time pages without a date have no lastmod
+	set = parseSitemap(t, get(newSitemap(router, "https://example.com", time.Time{}, 600), "/sitemap.xml", "").Body.String())
+	if set.URLs[0].LastMod != "" || set.URLs[1].LastMod != "2025-06-01" {
+		t.Errorf("urls without a build time = %+v", set.URLs)
+	}
+}
@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Changes lists how a current schema differs from the frozen one it must
+// stay compatible with. Paths are JSON pointers into the response.
+type Changes struct {
+	// Added are new optional properties, which clients ignore
+	Added []string
+	// Incompatible are changes a client written against the frozen schema
+	// may break on: removed or renamed properties, changed types, values
+	// that may now be missing or null, and new properties that are always
+	// present
+	Incompatible []string
+}
+
+// IsZero reports whether the schemas describe the same responses
+func (c Changes) IsZero() bool {
+	return len(c.Added) == 0 && len(c.Incompatible) == 0
+}
+
+// Compare returns the changes from frozen to current
+func Compare(frozen, current *Schema) Changes {
+	var c Changes
+	c.compare("", frozen, current)
+	return c
+}
+
+func (c *Changes) compare(path string, frozen, current *Schema) {
+	if !typesCovered(frozen.Type, current.Type) {
+		c.Incompatible = append(c.Incompatible, fmt.Sprintf("%s: type changed from %s to %s", pointer(path), describe(frozen.Type), describe(current.Type)))
+		return
+	}
+	if frozen.Format != current.Format {
+		c.Incompatible = append(c.Incompatible, fmt.Sprintf("%s: format changed from %q to %q", pointer(path), frozen.Format, current.Format))
+	}
+
+	for _, name := range sortedKeys(frozen.Properties) {
+		property := path + "/" + name
+		next, ok := current.Properties[name]
+		if !ok {
+			c.Incompatible = append(c.Incompatible, fmt.Sprintf("%s: removed", property))
+			continue
+		}
+		if slices.Contains(frozen.Required, name) && !slices.Contains(current.Required, name) {
+			c.Incompatible = append(c.Incompatible, fmt.Sprintf("%s: no longer always present", property))
+		}
+		c.compare(property, frozen.Properties[name], next)
+	}
+	for _, name := range sortedKeys(current.Properties) {
+		if _, ok := frozen.Properties[name]; ok {
+			continue
+		}
+		property := path + "/" + name
+		if slices.Contains(current.Required, name) {
+			c.Incompatible = append(c.Incompatible, fmt.Sprintf("%s: added as always present; new properties must be optional", property))
+		} else {
+			c.Added = append(c.Added, property)
+		}
+	}
+
+	if frozen.Items != nil && current.Items != nil {
+		c.compare(path+"/items", frozen.Items, current.Items)
+	}
+	if frozen.AdditionalProperties != nil && current.AdditionalProperties != nil {
+		c.compare(path+"/additionalProperties", frozen.AdditionalProperties, current.AdditionalProperties)
+	}
+}
+
+// typesCovered reports whether every value of the current types is one of
+// the frozen types. No types means any value.
+func typesCovered(frozen, current Types) bool {
+	if len(frozen) == 0 {
+		return true
+	}
+	if len(current) == 0 {
+		return false
+	}
+	for _, t := range current {
+		if !slices.Contains(frozen, t) {
+			return false
+		}
+	}
+	return true
+}
+
+func describe(types Types) string {
+	if len(types) == 0 {
+		return "any"
+	}
+	return fmt.Sprint([]string(types))
+}
+
+func pointer(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func sortedKeys(m map[string]*Schema) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	type frozen struct {
+		FormattedIBAN string    `json:"formattedIban"`
+		Valid         bool      `json:"valid"`
+		Bank          *string   `json:"bank,omitempty"`
+		Count         int       `json:"count"`
+		Addresses     []address `json:"addresses"`
+	}
+
+	tests := []struct {
+		name         string
+		current      interface{}
+		added        []string
+		incompatible []string
+	}{
+		{"unchanged", frozen{}, nil, nil},
+		{"optional field added", struct {
+			frozen
+			Note string `json:"note,omitempty"`
+		}{}, []string{"/note"}, nil},
+		{"required field added", struct {
+			frozen
+			Note string `json:"note"`
+		}{}, nil, []string{"/note: added as always present; new properties must be optional"}},
+		{"renamed by casing", struct {
+			FormattedIBAN string    `json:"formattedIBAN"`
+			Valid         bool      `json:"valid"`
+			Bank          *string   `json:"bank,omitempty"`
+			Count         int       `json:"count"`
+			Addresses     []address `json:"addresses"`
+		}{}, nil, []string{"/formattedIban: removed", "/formattedIBAN: added as always present; new properties must be optional"}},
+		{"type changed", struct {
+			FormattedIBAN string    `json:"formattedIban"`
+			Valid         string    `json:"valid"`
+			Bank          *string   `json:"bank,omitempty"`
+			Count         float64   `json:"count"`
+			Addresses     []address `json:"addresses"`
+		}{}, nil, []string{"/valid: type changed from [boolean] to [string]", "/count: type changed from [integer] to [number]"}},
+		{"made optional", struct {
+			FormattedIBAN string    `json:"formattedIban,omitempty"`
+			Valid         bool      `json:"valid"`
+			Bank          *string   `json:"bank,omitempty"`
+			Count         int       `json:"count"`
+			Addresses     []address `json:"addresses"`
+		}{}, nil, []string{"/formattedIban: no longer always present"}},
+		{"made nullable", struct {
+			FormattedIBAN *string   `json:"formattedIban"`
+			Valid         bool      `json:"valid"`
+			Bank          *string   `json:"bank,omitempty"`
+			Count         int       `json:"count"`
+			Addresses     []address `json:"addresses"`
+		}{}, nil, []string{"/formattedIban: type changed from [string] to [string null]"}},
+		{"nested change", struct {
+			FormattedIBAN string  `json:"formattedIban"`
+			Valid         bool    `json:"valid"`
+			Bank          *string `json:"bank,omitempty"`
+			Count         int     `json:"count"`
+			Addresses     []struct {
+				Town string `json:"town,omitempty"`
+			} `json:"addresses"`
+		}{}, []string{"/addresses/items/town"}, []string{"/addresses/items/city: removed"}},
+		// An optional field may become required: clients handle both
+		{"made required", struct {
+			FormattedIBAN string    `json:"formattedIban"`
+			Valid         bool      `json:"valid"`
+			Bank          string    `json:"bank"`
+			Count         int       `json:"count"`
+			Addresses     []address `json:"addresses"`
+		}{}, nil, nil},
+	}
+	base := Generate("frozen", frozen{})
+	for _, tt := range tests {
+		changes := Compare(base, Generate("current", tt.current))
+		if !slices.Equal(changes.Added, tt.added) || !sameElements(changes.Incompatible, tt.incompatible) {
+			t.Errorf("%s: added %q, incompatible %q; want %q, %q", tt.name, changes.Added, changes.Incompatible, tt.added, tt.incompatible)
+		}
+		if changes.IsZero() != (tt.added == nil && tt.incompatible == nil) {
+			t.Errorf("%s: IsZero = %v", tt.name, changes.IsZero())
+		}
+	}
+
+	// An empty response has removed every property
+	if changes := Compare(base, Generate("current", struct{}{})); len(changes.Incompatible) != 5 {
+		t.Errorf("everything removed: %q", changes.Incompatible)
+	}
+}
+
+func sameElements(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,119 @@
+package schema
+
+//go:generate go run ../../cmd/schemagen -dir frozen
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+var (
+	// ErrUnknownSchema means no public response has the name
+	ErrUnknownSchema = errors.New("unknown schema")
+	// ErrIncompatible means a response type changed in a way its frozen
+	// schema does not allow
+	ErrIncompatible = errors.New("response type is incompatible with its frozen schema")
+)
+
+// validationEnvelope mirrors the body writeValidationResult builds around
+// a validation result
+type validationEnvelope[T any] struct {
+	ValidationResult T                  `json:"validationResult"`
+	Summary          string             `json:"summary,omitempty"`
+	ShareURL         string             `json:"share_url,omitempty"`
+	Signature        string             `json:"signature,omitempty"`
+	SignedAt         string             `json:"signed_at,omitempty"`
+	KeyID            string             `json:"key_id,omitempty"`
+	Meta             *models.ResultMeta `json:"meta,omitempty"`
+}
+
+// batchLine mirrors models.BatchResult with the result type of one batch
+// endpoint
+type batchLine[T any] struct {
+	Index  int    `json:"index"`
+	Result *T     `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// public lists the responses whose shape is frozen, by schema name. Each
+// has a file <name>.json in frozen/, written by cmd/schemagen.
+var public = map[string]interface{}{
+	"email-validation":       validationEnvelope[models.EmailValidation]{},
+	"iban-validation":        validationEnvelope[models.IBANValidation]{},
+	"ip-validation":          validationEnvelope[models.GeoIPResponse]{},
+	"bankaccount-validation": validationEnvelope[models.BankAccountValidation]{},
+	"barcode-validation":     validationEnvelope[models.BarcodeValidation]{},
+	"card-validation":        validationEnvelope[models.CardValidation]{},
+	"email-batch-result":     batchLine[models.EmailValidation]{},
+	"iban-batch-result":      batchLine[models.IBANValidation]{},
+	"ip-batch-result":        batchLine[models.GeoIPResponse]{},
+	"error":                  models.APIError{},
+}
+
+//go:embed frozen/*.json
+var frozenFiles embed.FS
+
+// Names returns the names of the public response schemas, sorted
+func Names() []string {
+	names := make([]string, 0, len(public))
+	for name := range public {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Current generates the schema of the named response from its Go type
+func Current(name string) (*Schema, error) {
+	v, ok := public[name]
+	if !ok {
+		return nil, ErrUnknownSchema
+	}
+	return Generate(name, v), nil
+}
+
+// Frozen returns the committed schema of the named response
+func Frozen(name string) (*Schema, error) {
+	data, err := frozenFiles.ReadFile("frozen/" + name + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("no frozen schema for %s: %w", name, err)
+	}
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid frozen schema for %s: %w", name, err)
+	}
+	return &s, nil
+}
+
+// Lookup returns the current schema of the named response after checking
+// it against the frozen one. Added optional properties are served before
+// the frozen file catches up; incompatible changes are an error, so a
+// client never generates code from a schema the build has broken.
+func Lookup(name string) (*Schema, error) {
+	current, err := Current(name)
+	if err != nil {
+		return nil, err
+	}
+	frozen, err := Frozen(name)
+	if err != nil {
+		return nil, err
+	}
+	if changes := Compare(frozen, current); len(changes.Incompatible) > 0 {
+		return nil, fmt.Errorf("%w: %s: %v", ErrIncompatible, name, changes.Incompatible)
+	}
+	return current, nil
+}
+
+// Marshal encodes s the way frozen files are written
+func Marshal(s *Schema) ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// TestFrozenSchemas regenerates every public schema and diffs it with its
+// frozen file. Run go generate ./internal/schema after adding optional
+// fields; removals and renames need cmd/schemagen -force and a review.
+func TestFrozenSchemas(t *testing.T) {
+	for _, name := range Names() {
+		current, err := Current(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frozen, err := Frozen(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if changes := Compare(frozen, current); !changes.IsZero() {
+			t.Errorf("%s differs from frozen/%s.json: added %v, incompatible %v", name, name, changes.Added, changes.Incompatible)
+			continue
+		}
+		data, err := Marshal(current)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, _ := frozenFiles.ReadFile("frozen/" + name + ".json")
+		if !bytes.Equal(data, file) {
+			t.Errorf("frozen/%s.json is not formatted as cmd/schemagen writes it", name)
+		}
+	}
+
+	// Every frozen file belongs to a public response
+	files, err := fs.Glob(frozenFiles, "frozen/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(file, "frozen/"), ".json")
+		if _, ok := public[name]; !ok {
+			t.Errorf("%s has no response type", file)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	s, err := Lookup("iban-validation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := s.Properties["validationResult"]
+	if result == nil || result.Properties["formattedIban"] == nil {
+		t.Errorf("iban-validation has no validationResult.formattedIban: %+v", result)
+	}
+	if _, err := Lookup("nope"); !errors.Is(err, ErrUnknownSchema) {
+		t.Errorf("unknown name: err = %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+// Package schema derives JSON Schemas (draft 2020-12) from the Go types
+// the API encodes, and keeps the frozen schemas of the public responses
+// that changes to those types are checked against.
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of generated schemas
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Types is the "type" keyword: one JSON type, written as a string, or
+// several, written as an array. Empty means any type.
+type Types []string
+
+// MarshalJSON writes a single type as a string
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON reads a type string or array
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	var several []string
+	if err := json.Unmarshal(data, &several); err != nil {
+		return err
+	}
+	*t = several
+	return nil
+}
+
+// Schema is the subset of JSON Schema the generator writes. The zero
+// Schema accepts any value.
+type Schema struct {
+	Schema     string             `json:"$schema,omitempty"`
+	Title      string             `json:"title,omitempty"`
+	Type       Types              `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// Required lists the properties always present, in field order
+	Required             []string `json:"required,omitempty"`
+	Items                *Schema  `json:"items,omitempty"`
+	AdditionalProperties *Schema  `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Generate returns the schema of the JSON encoding/json writes for values
+// of v's type, titled title
+func Generate(title string, v interface{}) *Schema {
+	s := generate(reflect.TypeOf(v), map[reflect.Type]bool{})
+	s.Schema = Draft
+	s.Title = title
+	return s
+}
+
+// generate returns the schema of non-nil values of t. inProgress holds the
+// struct types being generated, so a recursive type becomes "any" where it
+// refers to itself instead of recursing forever.
+func generate(t reflect.Type, inProgress map[reflect.Type]bool) *Schema {
+	switch {
+	case t == nil:
+		return &Schema{}
+	case t == timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// A custom encoding can write anything
+		return &Schema{}
+	case t.Kind() != reflect.Pointer && (t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)):
+		return &Schema{Type: Types{"string"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return generate(t.Elem(), inProgress)
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes []byte as base64
+			return &Schema{Type: Types{"string"}}
+		}
+		return &Schema{Type: Types{"array"}, Items: element(t.Elem(), inProgress)}
+	case reflect.Map:
+		return &Schema{Type: Types{"object"}, AdditionalProperties: element(t.Elem(), inProgress)}
+	case reflect.Struct:
+		return generateStruct(t, inProgress)
+	default:
+		// Interfaces hold any value
+		return &Schema{}
+	}
+}
+
+// element returns the schema of a slice element or map value, which is
+// written as null when it is a nil pointer, slice or map
+func element(t reflect.Type, inProgress map[reflect.Type]bool) *Schema {
+	s := generate(t, inProgress)
+	if nilable(t) {
+		s = nullable(s)
+	}
+	return s
+}
+
+// nilable reports whether values of t can be nil, which encoding/json
+// writes as null
+func nilable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// nullable adds null to the types s accepts
+func nullable(s *Schema) *Schema {
+	if len(s.Type) == 0 || slices.Contains(s.Type, "null") {
+		return s
+	}
+	s.Type = append(s.Type, "null")
+	return s
+}
+
+// field is a struct field as encoding/json sees it
+type field struct {
+	name     string
+	typ      reflect.Type
+	optional bool
+	quoted   bool
+	depth    int
+	tagged   bool
+}
+
+func generateStruct(t reflect.Type, inProgress map[reflect.Type]bool) *Schema {
+	if inProgress[t] {
+		return &Schema{}
+	}
+	inProgress[t] = true
+	defer delete(inProgress, t)
+
+	s := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{}}
+	for _, f := range structFields(t) {
+		property := generate(f.typ, inProgress)
+		if f.quoted && len(property.Type) == 1 && property.Type[0] != "object" && property.Type[0] != "array" {
+			property = &Schema{Type: Types{"string"}}
+		}
+		if !f.optional {
+			if nilable(f.typ) {
+				property = nullable(property)
+			}
+			s.Required = append(s.Required, f.name)
+		}
+		s.Properties[f.name] = property
+	}
+	return s
+}
+
+// structFields returns the fields encoding/json writes for t in order,
+// with the fields of embedded structs promoted. A name at a shallower
+// depth hides deeper ones, and of two at the same depth a tagged one wins;
+// remaining ties drop the name, as encoding/json does.
+func structFields(t reflect.Type) []field {
+	var all []field
+	// Fields promoted through an embedded pointer are missing when it is nil
+	var walk func(t reflect.Type, depth int, viaPointer bool, visited map[reflect.Type]bool)
+	walk = func(t reflect.Type, depth int, viaPointer bool, visited map[reflect.Type]bool) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		defer delete(visited, t)
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			ft := sf.Type
+			if sf.Anonymous && name == "" {
+				embedded, pointer := ft, ft.Kind() == reflect.Pointer
+				if pointer {
+					embedded = embedded.Elem()
+				}
+				if embedded.Kind() == reflect.Struct {
+					walk(embedded, depth+1, viaPointer || pointer, visited)
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			f := field{name: name, typ: ft, depth: depth, tagged: name != "", optional: viaPointer}
+			if f.name == "" {
+				f.name = sf.Name
+			}
+			for _, opt := range strings.Split(opts, ",") {
+				switch opt {
+				case "omitempty", "omitzero":
+					f.optional = true
+				case "string":
+					f.quoted = true
+				}
+			}
+			all = append(all, f)
+		}
+	}
+	walk(t, 0, false, map[reflect.Type]bool{})
+
+	var fields []field
+	for i, f := range all {
+		dominant := true
+		for j, other := range all {
+			if i == j || other.name != f.name {
+				continue
+			}
+			if other.depth < f.depth || (other.depth == f.depth && other.tagged && !f.tagged) {
+				dominant = false
+				break
+			}
+			if other.depth == f.depth && other.tagged == f.tagged {
+				dominant = false
+				break
+			}
+		}
+		if dominant {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
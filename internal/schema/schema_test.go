@@ -0,0 +1,207 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Shared string `json:"shared"`
+	Deep   int    `json:"deep"`
+}
+
+type base struct {
+	inner
+	ID string `json:"id"`
+	// Shared is written as "Shared", which differs from inner's "shared"
+	Shared bool
+}
+
+type optionalBase struct {
+	Note string `json:"note"`
+}
+
+type level string
+
+func (l level) MarshalText() ([]byte, error) { return []byte(l), nil }
+
+type custom struct{}
+
+func (custom) MarshalJSON() ([]byte, error) { return []byte(`42`), nil }
+
+type tree struct {
+	Name     string  `json:"name"`
+	Children []*tree `json:"children"`
+}
+
+type reflected struct {
+	base
+	*optionalBase
+	Plain     string            `json:"plain"`
+	Omitted   string            `json:"omitted,omitempty"`
+	Zero      time.Time         `json:"zero,omitzero"`
+	Pointer   *int              `json:"pointer"`
+	OptPtr    *float64          `json:"opt_ptr,omitempty"`
+	Slice     []string          `json:"slice"`
+	Pointers  []*int            `json:"pointers,omitempty"`
+	Map       map[string]int    `json:"map"`
+	MapOfPtr  map[string]*inner `json:"map_of_ptr,omitempty"`
+	Bytes     []byte            `json:"bytes"`
+	Raw       json.RawMessage   `json:"raw"`
+	Any       interface{}       `json:"any"`
+	Quoted    int64             `json:"quoted,string"`
+	Level     level             `json:"level"`
+	Custom    custom            `json:"custom"`
+	Tree      tree              `json:"tree"`
+	Untagged  bool
+	Skipped   string `json:"-"`
+	unexposed string
+}
+
+func TestGenerate(t *testing.T) {
+	s := Generate("reflected", reflected{})
+	if s.Schema != Draft || s.Title != "reflected" || !slices.Equal(s.Type, Types{"object"}) {
+		t.Fatalf("root = %+v", s)
+	}
+
+	want := map[string]struct {
+		types    Types
+		required bool
+	}{
+		// Promoted from the embedded structs
+		"shared": {Types{"string"}, true},
+		"deep":   {Types{"integer"}, true},
+		"id":     {Types{"string"}, true},
+		"Shared": {Types{"boolean"}, true},
+		// Fields of an embedded pointer are missing when it is nil
+		"note":       {Types{"string"}, false},
+		"plain":      {Types{"string"}, true},
+		"omitted":    {Types{"string"}, false},
+		"zero":       {Types{"string"}, false},
+		"pointer":    {Types{"integer", "null"}, true},
+		"opt_ptr":    {Types{"number"}, false},
+		"slice":      {Types{"array", "null"}, true},
+		"pointers":   {Types{"array"}, false},
+		"map":        {Types{"object", "null"}, true},
+		"map_of_ptr": {Types{"object"}, false},
+		"bytes":      {Types{"string", "null"}, true},
+		"raw":        {nil, true},
+		"any":        {nil, true},
+		"quoted":     {Types{"string"}, true},
+		"level":      {Types{"string"}, true},
+		"custom":     {nil, true},
+		"tree":       {Types{"object"}, true},
+		"Untagged":   {Types{"boolean"}, true},
+	}
+	for name, w := range want {
+		property, ok := s.Properties[name]
+		if !ok {
+			t.Errorf("%s: missing", name)
+			continue
+		}
+		if !slices.Equal(property.Type, w.types) {
+			t.Errorf("%s: type %v, want %v", name, property.Type, w.types)
+		}
+		if required := slices.Contains(s.Required, name); required != w.required {
+			t.Errorf("%s: required %v, want %v", name, required, w.required)
+		}
+	}
+	for name := range s.Properties {
+		if _, ok := want[name]; !ok {
+			t.Errorf("unexpected property %s", name)
+		}
+	}
+
+	if format := s.Properties["zero"].Format; format != "date-time" {
+		t.Errorf("time format %q", format)
+	}
+	if items := s.Properties["pointers"].Items; items == nil || !slices.Equal(items.Type, Types{"integer", "null"}) {
+		t.Errorf("pointer items = %+v, want nullable integers", items)
+	}
+	if values := s.Properties["map"].AdditionalProperties; values == nil || !slices.Equal(values.Type, Types{"integer"}) {
+		t.Errorf("map values = %+v", values)
+	}
+	if values := s.Properties["map_of_ptr"].AdditionalProperties; values == nil ||
+		!slices.Equal(values.Type, Types{"object", "null"}) || values.Properties["deep"] == nil {
+		t.Errorf("map of pointer values = %+v", values)
+	}
+	// A recursive type refers to itself as any value
+	children := s.Properties["tree"].Properties["children"]
+	if children == nil || children.Items == nil || len(children.Items.Type) != 0 || children.Items.Properties != nil {
+		t.Errorf("recursive children = %+v", children)
+	}
+	// Required properties are listed in field order
+	if s.Required[0] != "shared" || s.Required[len(s.Required)-1] != "Untagged" {
+		t.Errorf("required = %v", s.Required)
+	}
+}
+
+// TestGenerateFieldConflicts follows encoding/json's rules for fields of
+// the same name
+func TestGenerateFieldConflicts(t *testing.T) {
+	type a struct {
+		Name  string
+		Label string `json:"Label"`
+		A     int
+	}
+	type b struct {
+		Name  int
+		Label int
+	}
+	type outer struct {
+		a
+		b
+		A string `json:"A"`
+	}
+	s := Generate("conflicts", outer{})
+	// Two untagged fields at the same depth tie and are dropped
+	if _, ok := s.Properties["Name"]; ok {
+		t.Error("ambiguous fields are kept")
+	}
+	// A tagged field wins over an untagged one at the same depth
+	if p := s.Properties["Label"]; p == nil || !slices.Equal(p.Type, Types{"string"}) {
+		t.Errorf("Label = %+v", p)
+	}
+	// The shallower field hides the promoted one
+	if p := s.Properties["A"]; p == nil || !slices.Equal(p.Type, Types{"string"}) || len(s.Required) != 2 {
+		t.Errorf("A = %+v, required %v", p, s.Required)
+	}
+
+	// The schema agrees with what encoding/json writes
+	data, err := json.Marshal(outer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written map[string]interface{}
+	json.Unmarshal(data, &written)
+	for name := range written {
+		if _, ok := s.Properties[name]; !ok {
+			t.Errorf("encoding/json writes %s, the schema has no such property", name)
+		}
+	}
+	if len(written) != len(s.Properties) {
+		t.Errorf("encoding/json writes %v, schema has %d properties", reflect.ValueOf(written).MapKeys(), len(s.Properties))
+	}
+}
+
+func TestTypesJSON(t *testing.T) {
+	for _, tt := range []struct {
+		types Types
+		json  string
+	}{
+		{Types{"string"}, `"string"`},
+		{Types{"string", "null"}, `["string","null"]`},
+	} {
+		data, err := json.Marshal(tt.types)
+		if err != nil || string(data) != tt.json {
+			t.Errorf("%v encodes as %s, %v", tt.types, data, err)
+		}
+		var decoded Types
+		if err := json.Unmarshal([]byte(tt.json), &decoded); err != nil || !slices.Equal(decoded, tt.types) {
+			t.Errorf("%s decodes as %v, %v", tt.json, decoded, err)
+		}
+	}
+}
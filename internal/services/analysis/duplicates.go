@@ -0,0 +1,446 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/warnings"
+)
+
+const (
+	DuplicateModeExact = "exact"
+	DuplicateModeFuzzy = "fuzzy"
+
+	SimilarityTrigram     = "trigram"
+	SimilarityLevenshtein = "levenshtein"
+
+	CanonicalFirst    = "first"
+	CanonicalLongest  = "longest"
+	CanonicalShortest = "shortest"
+
+	maxDuplicateItems      = 10000
+	maxDuplicateItemLength = 1000
+	defaultFuzzyThreshold  = 0.85
+
+	// budgetCheckInterval is how many items are compared between clock reads
+	budgetCheckInterval = 64
+)
+
+var (
+	ErrInvalidDuplicatesRequest = errors.New("invalid duplicates request")
+
+	// duplicatesBudget bounds fuzzy matching, after which the result falls
+	// back to exact matches. Tests shorten it to reach the fallback.
+	duplicatesBudget = 2 * time.Second
+
+	errBudgetExceeded = errors.New("time budget exceeded")
+)
+
+// FindDuplicates groups exact and, optionally, near-duplicate strings into clusters
+func FindDuplicates(req models.DuplicatesRequest) (models.DuplicatesResult, error) {
+	if err := applyDuplicatesDefaults(&req); err != nil {
+		return models.DuplicatesResult{}, err
+	}
+
+	// Exact pass: identical normalized values share a key
+	keys := make([]string, 0, len(req.Items))
+	keyIndex := make(map[string]int, len(req.Items))
+	itemKeys := make([]int, len(req.Items))
+	for i, item := range req.Items {
+		norm := normalizeDuplicateItem(item, req.Options)
+		id, ok := keyIndex[norm]
+		if !ok {
+			id = len(keys)
+			keyIndex[norm] = id
+			keys = append(keys, norm)
+		}
+		itemKeys[i] = id
+	}
+
+	uf := newUnionFind(len(keys))
+	result := models.DuplicatesResult{Mode: DuplicateModeExact}
+
+	if req.Options.Mode == DuplicateModeFuzzy {
+		deadline := time.Now().Add(duplicatesBudget)
+		fuzzy := newUnionFind(len(keys))
+		var err error
+		if req.Options.Similarity == SimilarityLevenshtein {
+			err = matchLevenshtein(keys, req.Options.Threshold, fuzzy, deadline)
+		} else {
+			err = matchTrigram(keys, req.Options.Threshold, fuzzy, deadline)
+		}
+		if err == nil {
+			uf = fuzzy
+			result.Mode = DuplicateModeFuzzy
+		} else {
+			result.Warnings = append(result.Warnings, models.Warning{
+				Code:    warnings.CodeFuzzyTimeBudget,
+				Message: "fuzzy matching exceeded the time budget; results are exact-only",
+				Field:   "options.mode",
+			})
+		}
+	}
+
+	groups := map[int][]int{}
+	var roots []int
+	for i := range req.Items {
+		root := uf.find(itemKeys[i])
+		if _, ok := groups[root]; !ok {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], i)
+	}
+
+	result.Clusters = []models.DuplicateCluster{}
+	for _, root := range roots {
+		members := groups[root]
+		if len(members) < 2 {
+			continue
+		}
+		cluster := models.DuplicateCluster{Size: len(members)}
+		for _, idx := range members {
+			cluster.Members = append(cluster.Members, models.DuplicateMember{Index: idx, Value: req.Items[idx]})
+		}
+		cluster.Canonical = pickCanonical(cluster.Members, req.Options.Canonical)
+		result.Clusters = append(result.Clusters, cluster)
+		result.Stats.Duplicates += len(members) - 1
+	}
+
+	result.Stats.Total = len(req.Items)
+	result.Stats.Unique = len(roots)
+	result.Stats.Clusters = len(result.Clusters)
+	return result, nil
+}
+
+func applyDuplicatesDefaults(req *models.DuplicatesRequest) error {
+	if len(req.Items) == 0 {
+		return fmt.Errorf("%w: items are required", ErrInvalidDuplicatesRequest)
+	}
+	if len(req.Items) > maxDuplicateItems {
+		return fmt.Errorf("%w: at most %d items are allowed", ErrInvalidDuplicatesRequest, maxDuplicateItems)
+	}
+	for i, item := range req.Items {
+		if len(item) > maxDuplicateItemLength {
+			return fmt.Errorf("%w: items[%d] exceeds %d bytes", ErrInvalidDuplicatesRequest, i, maxDuplicateItemLength)
+		}
+	}
+
+	opts := &req.Options
+	switch opts.Mode {
+	case "":
+		opts.Mode = DuplicateModeExact
+	case DuplicateModeExact, DuplicateModeFuzzy:
+	default:
+		return fmt.Errorf("%w: mode must be exact or fuzzy", ErrInvalidDuplicatesRequest)
+	}
+
+	switch opts.Similarity {
+	case "":
+		opts.Similarity = SimilarityTrigram
+	case SimilarityTrigram, SimilarityLevenshtein:
+	default:
+		return fmt.Errorf("%w: similarity must be trigram or levenshtein", ErrInvalidDuplicatesRequest)
+	}
+
+	if opts.Threshold == 0 {
+		opts.Threshold = defaultFuzzyThreshold
+	}
+	if opts.Threshold <= 0 || opts.Threshold > 1 {
+		return fmt.Errorf("%w: threshold must be greater than 0 and at most 1", ErrInvalidDuplicatesRequest)
+	}
+
+	switch opts.Canonical {
+	case "":
+		opts.Canonical = CanonicalFirst
+	case CanonicalFirst, CanonicalLongest, CanonicalShortest:
+	default:
+		return fmt.Errorf("%w: canonical must be first, longest or shortest", ErrInvalidDuplicatesRequest)
+	}
+	return nil
+}
+
+func normalizeDuplicateItem(s string, opts models.DuplicatesOptions) string {
+	if !opts.PreserveWhitespace {
+		s = strings.Join(strings.Fields(s), " ")
+	}
+	if !opts.CaseSensitive {
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+func pickCanonical(members []models.DuplicateMember, strategy string) string {
+	best := members[0].Value
+	for _, m := range members[1:] {
+		switch strategy {
+		case CanonicalLongest:
+			if utf8.RuneCountInString(m.Value) > utf8.RuneCountInString(best) {
+				best = m.Value
+			}
+		case CanonicalShortest:
+			if utf8.RuneCountInString(m.Value) < utf8.RuneCountInString(best) {
+				best = m.Value
+			}
+		}
+	}
+	return best
+}
+
+// trigrams returns the distinct padded character trigrams of s
+func trigrams(s string) []string {
+	runes := []rune("  " + s + " ")
+	seen := make(map[string]bool, len(runes))
+	out := make([]string, 0, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		g := string(runes[i : i+3])
+		if !seen[g] {
+			seen[g] = true
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// matchTrigram unions keys whose trigram Jaccard similarity reaches the
+// threshold. Candidates come from a prefix-filtered inverted index: with
+// trigrams ordered rarest first, two sets with Jaccard >= t must share a
+// token within the first |A| - ceil(t*|A|) + 1 tokens of each set.
+// Trigrams are numbered so that sets compare without hashing strings.
+func matchTrigram(keys []string, threshold float64, uf *unionFind, deadline time.Time) error {
+	ids := map[string]int{}
+	var freq []int
+	sets := make([][]int, len(keys))
+	for i, k := range keys {
+		grams := trigrams(k)
+		sets[i] = make([]int, len(grams))
+		for n, g := range grams {
+			id, ok := ids[g]
+			if !ok {
+				id = len(freq)
+				ids[g] = id
+				freq = append(freq, 0)
+			}
+			freq[id]++
+			sets[i][n] = id
+		}
+	}
+	for _, set := range sets {
+		sort.Slice(set, func(a, b int) bool {
+			if freq[set[a]] != freq[set[b]] {
+				return freq[set[a]] < freq[set[b]]
+			}
+			return set[a] < set[b]
+		})
+	}
+
+	index := make([][]int, len(freq))
+	// seen[j] is i+1 once key j has been compared with key i, and
+	// member[g] is i+1 while trigram g is in key i's set
+	seen := make([]int, len(keys))
+	member := make([]int, len(freq))
+	for i, set := range sets {
+		if i%budgetCheckInterval == 0 && time.Now().After(deadline) {
+			return errBudgetExceeded
+		}
+		for _, g := range set {
+			member[g] = i + 1
+		}
+
+		prefix := len(set) - int(math.Ceil(threshold*float64(len(set)))) + 1
+		if prefix > len(set) {
+			prefix = len(set)
+		}
+		for _, g := range set[:prefix] {
+			for _, j := range index[g] {
+				if seen[j] == i+1 {
+					continue
+				}
+				seen[j] = i + 1
+				if !jaccardLengthCompatible(len(sets[j]), len(set), threshold) {
+					continue
+				}
+				shared := 0
+				for _, h := range sets[j] {
+					if member[h] == i+1 {
+						shared++
+					}
+				}
+				if jaccard(shared, len(sets[j]), len(set)) >= threshold {
+					uf.union(j, i)
+				}
+			}
+			index[g] = append(index[g], i)
+		}
+	}
+	return nil
+}
+
+func jaccardLengthCompatible(a, b int, threshold float64) bool {
+	small, large := float64(a), float64(b)
+	if small > large {
+		small, large = large, small
+	}
+	return small >= threshold*large
+}
+
+// jaccard returns the Jaccard similarity of sets of sizes a and b sharing
+// shared members
+func jaccard(shared, a, b int) float64 {
+	union := a + b - shared
+	if union == 0 {
+		return 1
+	}
+	return float64(shared) / float64(union)
+}
+
+// matchLevenshtein unions keys whose normalized edit similarity reaches the
+// threshold. Candidates come from a trigram inverted index with a count
+// filter: k edits remove at most 3k distinct trigrams from either string,
+// so pairs sharing fewer than max(|A|,|B|) - 3k trigrams are skipped
+// before the full distance is computed. When 3k covers every trigram of
+// both strings a pair may match without sharing any, so those come from
+// an index of keys by length instead.
+func matchLevenshtein(keys []string, threshold float64, uf *unionFind, deadline time.Time) error {
+	runes := make([][]rune, len(keys))
+	grams := make([][]string, len(keys))
+	index := map[string][]int{}
+	byLength := map[int][]int{}
+	// shared[j] counts the trigrams key j shares with key i; candidates
+	// lists the keys with a count, to reset them for the next key
+	shared := make([]int, len(keys))
+	var candidates []int
+
+	for i, k := range keys {
+		if i%budgetCheckInterval == 0 && time.Now().After(deadline) {
+			return errBudgetExceeded
+		}
+		runes[i] = []rune(k)
+		grams[i] = trigrams(k)
+
+		candidates = candidates[:0]
+		for _, g := range grams[i] {
+			for _, j := range index[g] {
+				if shared[j] == 0 {
+					candidates = append(candidates, j)
+				}
+				shared[j]++
+			}
+		}
+
+		for _, j := range candidates {
+			count := shared[j]
+			longest := max(len(runes[i]), len(runes[j]))
+			maxDist := int(math.Floor((1 - threshold) * float64(longest)))
+			if abs(len(runes[i])-len(runes[j])) > maxDist {
+				continue
+			}
+			if count < max(len(grams[i]), len(grams[j]))-3*maxDist {
+				continue
+			}
+			if levenshtein(runes[i], runes[j], maxDist) <= maxDist {
+				uf.union(j, i)
+			}
+		}
+
+		// Lengths within the threshold of n run from t*n to n/t
+		n := len(runes[i])
+		for length := int(math.Ceil(threshold * float64(n))); float64(length)*threshold <= float64(n); length++ {
+			maxDist := int(math.Floor((1 - threshold) * float64(max(n, length))))
+			if 3*maxDist < len(grams[i]) {
+				continue
+			}
+			for _, j := range byLength[length] {
+				if shared[j] > 0 || 3*maxDist < len(grams[j]) {
+					continue
+				}
+				if levenshtein(runes[i], runes[j], maxDist) <= maxDist {
+					uf.union(j, i)
+				}
+			}
+		}
+
+		for _, j := range candidates {
+			shared[j] = 0
+		}
+		for _, g := range grams[i] {
+			index[g] = append(index[g], i)
+		}
+		byLength[n] = append(byLength[n], i)
+	}
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// levenshtein returns the edit distance between a and b, stopping early
+// with maxDist+1 once every cell in a row exceeds maxDist.
+func levenshtein(a, b []rune, maxDist int) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > maxDist {
+			return maxDist + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+type unionFind struct {
+	parent []int
+}
+
+func newUnionFind(n int) *unionFind {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	return &unionFind{parent: parent}
+}
+
+func (u *unionFind) find(x int) int {
+	for u.parent[x] != x {
+		u.parent[x] = u.parent[u.parent[x]]
+		x = u.parent[x]
+	}
+	return x
+}
+
+// union merges the sets of a and b, keeping the smaller index as root so
+// clusters are reported in order of first appearance.
+func (u *unionFind) union(a, b int) {
+	ra, rb := u.find(a), u.find(b)
+	if ra == rb {
+		return
+	}
+	if rb < ra {
+		ra, rb = rb, ra
+	}
+	u.parent[rb] = ra
+}
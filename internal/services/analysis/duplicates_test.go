@@ -0,0 +1,282 @@
+package analysis
+
+import (
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/warnings"
+)
+
+// clusterValues returns the member values of each cluster of result
+func clusterValues(result models.DuplicatesResult) [][]string {
+	var out [][]string
+	for _, c := range result.Clusters {
+		var values []string
+		for _, m := range c.Members {
+			values = append(values, m.Value)
+		}
+		out = append(out, values)
+	}
+	return out
+}
+
+func TestFindDuplicatesLevenshtein(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     []string
+		threshold float64
+		want      [][]string
+	}{
+		{"shared trigrams", []string{"jonathan smith", "jonathon smith", "mary jones"}, 0.9, [][]string{{"jonathan smith", "jonathon smith"}}},
+		{"no shared trigram", []string{"abcd", "xbcy"}, 0.5, [][]string{{"abcd", "xbcy"}}},
+		{"no shared trigram, different lengths", []string{"abcd", "xbcyz", "q"}, 0.4, [][]string{{"abcd", "xbcyz"}}},
+		{"below the threshold", []string{"abcd", "wxyz"}, 0.5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := FindDuplicates(models.DuplicatesRequest{
+				Items:   tt.items,
+				Options: models.DuplicatesOptions{Mode: DuplicateModeFuzzy, Similarity: SimilarityLevenshtein, Threshold: tt.threshold},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := clusterValues(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clusters = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLevenshteinMatchesBruteForce compares the indexed search against
+// every pair of a set of short strings, where low thresholds allow
+// matches without a shared trigram
+func TestLevenshteinMatchesBruteForce(t *testing.T) {
+	keys := []string{"", "a", "ab", "ba", "abc", "abd", "xbc", "cab", "abcd", "xbcy", "dcba", "aaaa", "abab", "xyzxy", "abcde"}
+	for _, threshold := range []float64{0.2, 0.34, 0.5, 0.6, 0.75, 1} {
+		got := newUnionFind(len(keys))
+		if err := matchLevenshtein(keys, threshold, got, farDeadline()); err != nil {
+			t.Fatal(err)
+		}
+		for i := range keys {
+			for j := range i {
+				a, b := []rune(keys[i]), []rune(keys[j])
+				maxDist := int((1 - threshold) * float64(max(len(a), len(b))))
+				if levenshtein(a, b, maxDist) <= maxDist && got.find(i) != got.find(j) {
+					t.Errorf("threshold %v: %q and %q not matched", threshold, keys[j], keys[i])
+				}
+			}
+		}
+	}
+}
+
+func TestFindDuplicatesTrigram(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     []string
+		threshold float64
+		want      [][]string
+	}{
+		{"one typo", []string{"acme corporation", "acme corporatoin", "globex"}, 0.6, [][]string{{"acme corporation", "acme corporatoin"}}},
+		{"default threshold", []string{"jonathan smith", "jonathan smyth", "jonathan smith jr"}, 0, nil},
+		// The first and last only match through the middle one
+		{"transitive", []string{"abcdefghij", "abcdefghiz", "zbcdefghiz"}, 0.55, [][]string{{"abcdefghij", "abcdefghiz", "zbcdefghiz"}}},
+		{"exact duplicates kept", []string{"one", "two", "one"}, 0.9, [][]string{{"one", "one"}}},
+		{"threshold 1 needs equal sets", []string{"abab", "baba", "ab"}, 1, nil},
+		{"no match", []string{"kitten", "sitting"}, 0.85, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := FindDuplicates(models.DuplicatesRequest{
+				Items:   tt.items,
+				Options: models.DuplicatesOptions{Mode: DuplicateModeFuzzy, Threshold: tt.threshold},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Mode != DuplicateModeFuzzy || len(result.Warnings) > 0 {
+				t.Errorf("mode %s, warnings %v", result.Mode, result.Warnings)
+			}
+			if got := clusterValues(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clusters = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindDuplicatesCanonical(t *testing.T) {
+	items := []string{"Acme Inc", "ACME INC.", "acme inc", "Acme  Incorporated"}
+	tests := []struct {
+		strategy, want string
+	}{
+		{"", "Acme Inc"},
+		{CanonicalFirst, "Acme Inc"},
+		{CanonicalLongest, "Acme  Incorporated"},
+		// Ties keep the earliest member
+		{CanonicalShortest, "Acme Inc"},
+	}
+	for _, tt := range tests {
+		result, err := FindDuplicates(models.DuplicatesRequest{
+			Items:   items,
+			Options: models.DuplicatesOptions{Mode: DuplicateModeFuzzy, Threshold: 0.4, Canonical: tt.strategy},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Clusters) != 1 || result.Clusters[0].Canonical != tt.want {
+			t.Errorf("%q: clusters = %+v, want one with canonical %q", tt.strategy, result.Clusters, tt.want)
+		}
+	}
+
+	// Lengths are counted in characters, not bytes
+	result, err := FindDuplicates(models.DuplicatesRequest{
+		Items:   []string{"naive!", "naïve"},
+		Options: models.DuplicatesOptions{Mode: DuplicateModeFuzzy, Similarity: SimilarityLevenshtein, Threshold: 0.6, Canonical: CanonicalShortest},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Clusters) != 1 || result.Clusters[0].Canonical != "naïve" {
+		t.Errorf("shortest by characters: clusters = %+v", result.Clusters)
+	}
+}
+
+func TestFindDuplicatesNormalization(t *testing.T) {
+	items := []string{"Ada Lovelace", "ada  lovelace", " ADA LOVELACE\t", "ada lovelace"}
+	tests := []struct {
+		name string
+		opts models.DuplicatesOptions
+		want [][]string
+	}{
+		{"defaults", models.DuplicatesOptions{}, [][]string{items}},
+		{"case sensitive", models.DuplicatesOptions{CaseSensitive: true}, [][]string{{"ada  lovelace", "ada lovelace"}}},
+		{"preserve whitespace", models.DuplicatesOptions{PreserveWhitespace: true}, [][]string{{"Ada Lovelace", "ada lovelace"}}},
+		{"both", models.DuplicatesOptions{CaseSensitive: true, PreserveWhitespace: true}, nil},
+	}
+	for _, tt := range tests {
+		result, err := FindDuplicates(models.DuplicatesRequest{Items: items, Options: tt.opts})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := clusterValues(result); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: clusters = %q, want %q", tt.name, got, tt.want)
+		}
+		if result.Mode != DuplicateModeExact || result.Stats.Total != len(items) {
+			t.Errorf("%s: mode %s, stats %+v", tt.name, result.Mode, result.Stats)
+		}
+	}
+
+	// Case folding also applies to fuzzy matching
+	result, err := FindDuplicates(models.DuplicatesRequest{
+		Items:   []string{"JONATHAN SMITH", "jonathon smith"},
+		Options: models.DuplicatesOptions{Mode: DuplicateModeFuzzy, Similarity: SimilarityLevenshtein, Threshold: 0.9},
+	})
+	if err != nil || len(result.Clusters) != 1 {
+		t.Errorf("fuzzy case folding: clusters = %+v, %v", result.Clusters, err)
+	}
+}
+
+// TestFindDuplicatesBudgetFallback runs out of time in fuzzy matching and
+// expects the exact clusters with a warning instead of an error
+func TestFindDuplicatesBudgetFallback(t *testing.T) {
+	budget := duplicatesBudget
+	duplicatesBudget = -time.Second
+	t.Cleanup(func() { duplicatesBudget = budget })
+
+	for _, similarity := range []string{SimilarityTrigram, SimilarityLevenshtein} {
+		result, err := FindDuplicates(models.DuplicatesRequest{
+			Items:   []string{"jonathan smith", "Jonathan Smith", "jonathon smith"},
+			Options: models.DuplicatesOptions{Mode: DuplicateModeFuzzy, Similarity: similarity, Threshold: 0.8},
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", similarity, err)
+		}
+		if result.Mode != DuplicateModeExact {
+			t.Errorf("%s: mode = %s, want exact", similarity, result.Mode)
+		}
+		if len(result.Warnings) != 1 || result.Warnings[0].Code != warnings.CodeFuzzyTimeBudget || result.Warnings[0].Field != "options.mode" {
+			t.Errorf("%s: warnings = %+v", similarity, result.Warnings)
+		}
+		if got, want := clusterValues(result), [][]string{{"jonathan smith", "Jonathan Smith"}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: clusters = %q, want the exact match only %q", similarity, got, want)
+		}
+	}
+}
+
+func TestFindDuplicatesErrors(t *testing.T) {
+	long := make([]byte, maxDuplicateItemLength+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+	for _, req := range []models.DuplicatesRequest{
+		{},
+		{Items: make([]string, maxDuplicateItems+1)},
+		{Items: []string{string(long)}},
+		{Items: []string{"a"}, Options: models.DuplicatesOptions{Mode: "phonetic"}},
+		{Items: []string{"a"}, Options: models.DuplicatesOptions{Similarity: "soundex"}},
+		{Items: []string{"a"}, Options: models.DuplicatesOptions{Threshold: 1.5}},
+		{Items: []string{"a"}, Options: models.DuplicatesOptions{Threshold: -0.1}},
+		{Items: []string{"a"}, Options: models.DuplicatesOptions{Canonical: "newest"}},
+	} {
+		if _, err := FindDuplicates(req); !errors.Is(err, ErrInvalidDuplicatesRequest) {
+			t.Errorf("%d items, %+v: err = %v", len(req.Items), req.Options, err)
+		}
+	}
+}
+
+// nearUniqueItems returns n random lowercase strings of 24 to 40 letters
+// and spaces. Every thousandth is followed by a copy with one letter
+// changed, so the only near-duplicates are n/1000 planted pairs.
+func nearUniqueItems(n int) []string {
+	random := rand.New(rand.NewPCG(42, 7))
+	const letters = "abcdefghijklmnopqrstuvwxyz      "
+	items := make([]string, n)
+	for i := range items {
+		item := make([]byte, 24+random.IntN(17))
+		for j := range item {
+			item[j] = letters[random.IntN(len(letters))]
+		}
+		if i%1000 == 1 {
+			item = []byte(items[i-1])
+			item[len(item)/2] = 'x'
+		}
+		items[i] = string(item)
+	}
+	return items
+}
+
+// TestFindDuplicatesPerformance checks that the largest request, 10,000
+// near-unique items, completes fuzzy matching within duplicatesBudget
+// rather than falling back to exact matching
+func TestFindDuplicatesPerformance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("slow")
+	}
+	items := nearUniqueItems(maxDuplicateItems)
+	for _, similarity := range []string{SimilarityTrigram, SimilarityLevenshtein} {
+		start := time.Now()
+		result, err := FindDuplicates(models.DuplicatesRequest{
+			Items:   items,
+			Options: models.DuplicatesOptions{Mode: DuplicateModeFuzzy, Similarity: similarity, Threshold: 0.75},
+		})
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Fatalf("%s: %v", similarity, err)
+		}
+		t.Logf("%s: %v, %d clusters", similarity, elapsed, len(result.Clusters))
+		if result.Mode != DuplicateModeFuzzy || len(result.Warnings) > 0 || elapsed > duplicatesBudget {
+			t.Errorf("%s: mode %s after %v, warnings %v; want fuzzy within %v", similarity, result.Mode, elapsed, result.Warnings, duplicatesBudget)
+		}
+		if result.Stats.Total != maxDuplicateItems || result.Stats.Clusters != maxDuplicateItems/1000 {
+			t.Errorf("%s: stats = %+v, want the %d planted pairs", similarity, result.Stats, maxDuplicateItems/1000)
+		}
+	}
+}
+
+func farDeadline() time.Time {
+	return time.Now().Add(time.Minute)
+}
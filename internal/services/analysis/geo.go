@@ -0,0 +1,261 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+)
+
+const (
+	GeoModeDistance = "distance"
+	GeoModeGeofence = "geofence"
+
+	GeofenceShapeCircle  = "circle"
+	GeofenceShapePolygon = "polygon"
+
+	earthRadiusKm = 6371.0088
+	kmPerMile     = 1.609344
+
+	maxGeofencePoints  = 1000
+	maxPolygonVertices = 1000
+	polygonEdgeEpsilon = 1e-9
+)
+
+var (
+	ErrInvalidMode  = errors.New("invalid mode: must be distance or geofence")
+	ErrInvalidPoint = errors.New("invalid point: provide latitude and longitude or an ip")
+	ErrInvalidFence = errors.New("invalid geofence: provide center and radius_km or a polygon")
+)
+
+// Haversine returns the great-circle distance between two points in kilometres
+func Haversine(a, b models.GeoPoint) float64 {
+	lat1, lat2 := toRadians(a.Latitude), toRadians(b.Latitude)
+	dLat := lat2 - lat1
+	dLon := toRadians(b.Longitude - a.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// InitialBearing returns the initial compass bearing in degrees from a to b
+func InitialBearing(a, b models.GeoPoint) float64 {
+	lat1, lat2 := toRadians(a.Latitude), toRadians(b.Latitude)
+	dLon := toRadians(b.Longitude - a.Longitude)
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(toDegrees(math.Atan2(y, x))+360, 360)
+}
+
+// Midpoint returns the great-circle midpoint between a and b
+func Midpoint(a, b models.GeoPoint) models.GeoPoint {
+	lat1, lat2 := toRadians(a.Latitude), toRadians(b.Latitude)
+	lon1 := toRadians(a.Longitude)
+	dLon := toRadians(b.Longitude - a.Longitude)
+
+	bx := math.Cos(lat2) * math.Cos(dLon)
+	by := math.Cos(lat2) * math.Sin(dLon)
+	lat := math.Atan2(math.Sin(lat1)+math.Sin(lat2), math.Sqrt((math.Cos(lat1)+bx)*(math.Cos(lat1)+bx)+by*by))
+	lon := lon1 + math.Atan2(by, math.Cos(lat1)+bx)
+
+	return models.GeoPoint{
+		Latitude:  toDegrees(lat),
+		Longitude: normalizeLongitude(toDegrees(lon)),
+	}
+}
+
+// PointInPolygon reports whether p lies inside or on the edge of the polygon.
+// Polygons crossing the antimeridian are handled by unwrapping vertex
+// longitudes so that consecutive vertices never differ by more than 180°.
+func PointInPolygon(p models.GeoPoint, polygon []models.GeoPoint) bool {
+	if len(polygon) < 3 {
+		return false
+	}
+
+	unwrapped := make([]models.GeoPoint, len(polygon))
+	unwrapped[0] = polygon[0]
+	for i := 1; i < len(polygon); i++ {
+		lon := polygon[i].Longitude
+		prev := unwrapped[i-1].Longitude
+		for lon-prev > 180 {
+			lon -= 360
+		}
+		for lon-prev < -180 {
+			lon += 360
+		}
+		unwrapped[i] = models.GeoPoint{Latitude: polygon[i].Latitude, Longitude: lon}
+	}
+
+	for _, shift := range []float64{0, 360, -360} {
+		candidate := models.GeoPoint{Latitude: p.Latitude, Longitude: p.Longitude + shift}
+		if pointInPlanarPolygon(candidate, unwrapped) {
+			return true
+		}
+	}
+	return false
+}
+
+func pointInPlanarPolygon(p models.GeoPoint, polygon []models.GeoPoint) bool {
+	inside := false
+	n := len(polygon)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if pointOnSegment(p, a, b) {
+			return true
+		}
+		if (a.Latitude > p.Latitude) != (b.Latitude > p.Latitude) {
+			crossLon := (b.Longitude-a.Longitude)*(p.Latitude-a.Latitude)/(b.Latitude-a.Latitude) + a.Longitude
+			if p.Longitude < crossLon {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+func pointOnSegment(p, a, b models.GeoPoint) bool {
+	cross := (b.Longitude-a.Longitude)*(p.Latitude-a.Latitude) - (b.Latitude-a.Latitude)*(p.Longitude-a.Longitude)
+	if math.Abs(cross) > polygonEdgeEpsilon {
+		return false
+	}
+	return p.Longitude >= math.Min(a.Longitude, b.Longitude)-polygonEdgeEpsilon &&
+		p.Longitude <= math.Max(a.Longitude, b.Longitude)+polygonEdgeEpsilon &&
+		p.Latitude >= math.Min(a.Latitude, b.Latitude)-polygonEdgeEpsilon &&
+		p.Latitude <= math.Max(a.Latitude, b.Latitude)+polygonEdgeEpsilon
+}
+
+// ResolvePoint turns a coordinate or IP input into a concrete point, geolocating IPs with geo
+func ResolvePoint(ctx context.Context, geo validation.GeoIPService, input models.GeoPointInput) (models.GeoPoint, error) {
+	if input.Latitude != nil && input.Longitude != nil {
+		p := models.GeoPoint{Latitude: *input.Latitude, Longitude: *input.Longitude}
+		if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+			return models.GeoPoint{}, fmt.Errorf("%w: latitude must be within ±90 and longitude within ±180", ErrInvalidPoint)
+		}
+		return p, nil
+	}
+
+	if input.IP == "" {
+		return models.GeoPoint{}, ErrInvalidPoint
+	}
+
+	located, err := geo.Lookup(ctx, input.IP)
+	if err != nil {
+		return models.GeoPoint{}, fmt.Errorf("%w: %s: %w", ErrInvalidPoint, input.IP, err)
+	}
+	if located.Latitude == nil || located.Longitude == nil {
+		return models.GeoPoint{}, fmt.Errorf("%w: %s: no location data for this address", ErrInvalidPoint, input.IP)
+	}
+	return models.GeoPoint{Latitude: *located.Latitude, Longitude: *located.Longitude}, nil
+}
+
+// CalculateDistance resolves both endpoints and returns distance, bearing and midpoint
+func CalculateDistance(ctx context.Context, geo validation.GeoIPService, req models.DistanceRequest) (models.DistanceResult, error) {
+	from, err := ResolvePoint(ctx, geo, req.From)
+	if err != nil {
+		return models.DistanceResult{}, fmt.Errorf("from: %w", err)
+	}
+	to, err := ResolvePoint(ctx, geo, req.To)
+	if err != nil {
+		return models.DistanceResult{}, fmt.Errorf("to: %w", err)
+	}
+
+	km := Haversine(from, to)
+	return models.DistanceResult{
+		From:           from,
+		To:             to,
+		DistanceKm:     km,
+		DistanceMi:     km / kmPerMile,
+		InitialBearing: InitialBearing(from, to),
+		Midpoint:       Midpoint(from, to),
+	}, nil
+}
+
+// CheckGeofence reports which of the requested points fall inside a circle or polygon
+func CheckGeofence(ctx context.Context, geo validation.GeoIPService, req models.DistanceRequest) (models.GeofenceResult, error) {
+	if len(req.Points) == 0 {
+		return models.GeofenceResult{}, fmt.Errorf("%w: points are required", ErrInvalidFence)
+	}
+	if len(req.Points) > maxGeofencePoints {
+		return models.GeofenceResult{}, fmt.Errorf("%w: at most %d points are allowed", ErrInvalidFence, maxGeofencePoints)
+	}
+
+	var contains func(models.GeoPoint) bool
+	var shape string
+
+	switch {
+	case len(req.Polygon) > 0:
+		if len(req.Polygon) < 3 || len(req.Polygon) > maxPolygonVertices {
+			return models.GeofenceResult{}, fmt.Errorf("%w: polygon must have between 3 and %d vertices", ErrInvalidFence, maxPolygonVertices)
+		}
+		polygon := make([]models.GeoPoint, 0, len(req.Polygon))
+		for i, v := range req.Polygon {
+			p, err := ResolvePoint(ctx, geo, v)
+			if err != nil {
+				return models.GeofenceResult{}, fmt.Errorf("polygon[%d]: %w", i, err)
+			}
+			polygon = append(polygon, p)
+		}
+		shape = GeofenceShapePolygon
+		contains = func(p models.GeoPoint) bool { return PointInPolygon(p, polygon) }
+
+	case req.Center != nil:
+		if req.RadiusKm <= 0 {
+			return models.GeofenceResult{}, fmt.Errorf("%w: radius_km must be positive", ErrInvalidFence)
+		}
+		center, err := ResolvePoint(ctx, geo, *req.Center)
+		if err != nil {
+			return models.GeofenceResult{}, fmt.Errorf("center: %w", err)
+		}
+		shape = GeofenceShapeCircle
+		contains = func(p models.GeoPoint) bool { return Haversine(center, p) <= req.RadiusKm }
+
+	default:
+		return models.GeofenceResult{}, ErrInvalidFence
+	}
+
+	result := models.GeofenceResult{
+		Shape:   shape,
+		Results: make([]models.GeofencePointResult, 0, len(req.Points)),
+	}
+	for i, input := range req.Points {
+		p, err := ResolvePoint(ctx, geo, input)
+		if err != nil {
+			return models.GeofenceResult{}, fmt.Errorf("points[%d]: %w", i, err)
+		}
+		result.Results = append(result.Results, models.GeofencePointResult{
+			IP:     input.IP,
+			Point:  p,
+			Inside: contains(p),
+		})
+	}
+	return result, nil
+}
+
+// Analyze dispatches a distance request to the distance or geofence calculation
+func Analyze(ctx context.Context, geo validation.GeoIPService, req models.DistanceRequest) (interface{}, error) {
+	switch req.Mode {
+	case "", GeoModeDistance:
+		return CalculateDistance(ctx, geo, req)
+	case GeoModeGeofence:
+		return CheckGeofence(ctx, geo, req)
+	default:
+		return nil, ErrInvalidMode
+	}
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func toDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+func normalizeLongitude(lon float64) float64 {
+	return math.Mod(lon+540, 360) - 180
+}
@@ -0,0 +1,165 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// fixedGeoIP locates the addresses of its table and no others
+type fixedGeoIP map[string]models.GeoPoint
+
+func (g fixedGeoIP) Lookup(ctx context.Context, ip string) (models.GeoIPResponse, error) {
+	p, ok := g[ip]
+	if !ok {
+		return models.GeoIPResponse{IP: ip, NotFound: true}, nil
+	}
+	return models.GeoIPResponse{IP: ip, Latitude: &p.Latitude, Longitude: &p.Longitude}, nil
+}
+
+func (fixedGeoIP) Metadata() (models.GeoDatabaseInfo, error) { return models.GeoDatabaseInfo{}, nil }
+func (fixedGeoIP) Close() error                              { return nil }
+
+func pt(lat, lon float64) models.GeoPoint {
+	return models.GeoPoint{Latitude: lat, Longitude: lon}
+}
+
+func coords(lat, lon float64) models.GeoPointInput {
+	return models.GeoPointInput{Latitude: &lat, Longitude: &lon}
+}
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   models.GeoPoint
+		wantKm float64
+	}{
+		{"same point", pt(52.52, 13.405), pt(52.52, 13.405), 0},
+		{"Berlin to Paris", pt(52.52, 13.405), pt(48.8566, 2.3522), 877.5},
+		{"one degree of the equator", pt(0, 0), pt(0, 1), 111.195},
+		{"across the antimeridian", pt(0, 179), pt(0, -179), 222.39},
+		{"antipodes", pt(0, 0), pt(0, 180), math.Pi * earthRadiusKm},
+		{"pole to pole", pt(90, 0), pt(-90, 0), math.Pi * earthRadiusKm},
+	}
+	for _, tt := range tests {
+		if got := Haversine(tt.a, tt.b); math.Abs(got-tt.wantKm) > 0.5 {
+			t.Errorf("%s: Haversine = %.3f km, want %.3f", tt.name, got, tt.wantKm)
+		}
+	}
+}
+
+func TestBearingAndMidpointAcrossAntimeridian(t *testing.T) {
+	if got := InitialBearing(pt(0, 179), pt(0, -179)); math.Abs(got-90) > 1e-6 {
+		t.Errorf("bearing eastwards over the antimeridian = %f, want 90", got)
+	}
+	if got := InitialBearing(pt(0, -179), pt(0, 179)); math.Abs(got-270) > 1e-6 {
+		t.Errorf("bearing westwards over the antimeridian = %f, want 270", got)
+	}
+	mid := Midpoint(pt(10, 170), pt(10, -170))
+	if math.Abs(math.Abs(mid.Longitude)-180) > 1e-6 || mid.Latitude < 10 {
+		t.Errorf("midpoint = %+v, want on the antimeridian north of 10°", mid)
+	}
+}
+
+func TestPointInPolygon(t *testing.T) {
+	square := []models.GeoPoint{pt(0, 0), pt(0, 10), pt(10, 10), pt(10, 0)}
+	// A square of 20° straddling the antimeridian
+	dateline := []models.GeoPoint{pt(-10, 170), pt(-10, -170), pt(10, -170), pt(10, 170)}
+	tests := []struct {
+		name    string
+		p       models.GeoPoint
+		polygon []models.GeoPoint
+		want    bool
+	}{
+		{"inside", pt(5, 5), square, true},
+		{"outside", pt(15, 5), square, false},
+		{"on an edge", pt(0, 5), square, true},
+		{"on a vertex", pt(10, 10), square, true},
+		{"too few vertices", pt(0, 0), square[:2], false},
+		{"dateline, west side", pt(0, 175), dateline, true},
+		{"dateline, east side", pt(0, -175), dateline, true},
+		{"dateline, on the antimeridian", pt(0, 180), dateline, true},
+		{"dateline, other side of the world", pt(0, 0), dateline, false},
+		{"dateline, north of it", pt(20, 179), dateline, false},
+	}
+	for _, tt := range tests {
+		if got := PointInPolygon(tt.p, tt.polygon); got != tt.want {
+			t.Errorf("%s: PointInPolygon(%+v) = %v, want %v", tt.name, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestCheckGeofence(t *testing.T) {
+	geo := fixedGeoIP{"192.0.2.1": pt(0, -179.5), "192.0.2.2": pt(45, 90)}
+	center := coords(0, 179.5)
+	circle := models.DistanceRequest{
+		Mode:     GeoModeGeofence,
+		Center:   &center,
+		RadiusKm: 150,
+		Points:   []models.GeoPointInput{{IP: "192.0.2.1"}, {IP: "192.0.2.2"}, coords(0, 179)},
+	}
+	result, err := CheckGeofence(context.Background(), geo, circle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Shape != GeofenceShapeCircle || len(result.Results) != 3 {
+		t.Fatalf("result = %+v", result)
+	}
+	for i, want := range []bool{true, false, true} {
+		if result.Results[i].Inside != want {
+			t.Errorf("points[%d] inside = %v, want %v", i, result.Results[i].Inside, want)
+		}
+	}
+	if result.Results[0].IP != "192.0.2.1" {
+		t.Errorf("points[0] IP = %q, want the address it was located from", result.Results[0].IP)
+	}
+
+	polygon := models.DistanceRequest{
+		Mode:    GeoModeGeofence,
+		Polygon: []models.GeoPointInput{coords(-10, 170), coords(-10, -170), coords(10, -170), coords(10, 170)},
+		Points:  []models.GeoPointInput{{IP: "192.0.2.1"}, coords(0, 0)},
+	}
+	result, err = CheckGeofence(context.Background(), geo, polygon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Shape != GeofenceShapePolygon || !result.Results[0].Inside || result.Results[1].Inside {
+		t.Errorf("polygon across the antimeridian: %+v", result)
+	}
+}
+
+func TestGeoErrors(t *testing.T) {
+	geo := fixedGeoIP{}
+	origin := coords(0, 0)
+	tests := []struct {
+		name string
+		req  models.DistanceRequest
+		want error
+	}{
+		{"unknown mode", models.DistanceRequest{Mode: "area"}, ErrInvalidMode},
+		{"missing point", models.DistanceRequest{From: origin}, ErrInvalidPoint},
+		{"latitude out of range", models.DistanceRequest{From: origin, To: coords(91, 0)}, ErrInvalidPoint},
+		{"IP without location", models.DistanceRequest{From: origin, To: models.GeoPointInput{IP: "192.0.2.9"}}, ErrInvalidPoint},
+		{"geofence without points", models.DistanceRequest{Mode: GeoModeGeofence, Center: &origin, RadiusKm: 1}, ErrInvalidFence},
+		{"geofence without shape", models.DistanceRequest{Mode: GeoModeGeofence, Points: []models.GeoPointInput{origin}}, ErrInvalidFence},
+		{"zero radius", models.DistanceRequest{Mode: GeoModeGeofence, Center: &origin, Points: []models.GeoPointInput{origin}}, ErrInvalidFence},
+		{"two-vertex polygon", models.DistanceRequest{Mode: GeoModeGeofence, Polygon: []models.GeoPointInput{origin, origin}, Points: []models.GeoPointInput{origin}}, ErrInvalidFence},
+	}
+	for _, tt := range tests {
+		if _, err := Analyze(context.Background(), geo, tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	result, err := Analyze(context.Background(), geo, models.DistanceRequest{From: origin, To: coords(0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	distance := result.(models.DistanceResult)
+	if math.Abs(distance.DistanceMi-distance.DistanceKm/kmPerMile) > 1e-9 || math.Abs(distance.InitialBearing-90) > 1e-9 {
+		t.Errorf("distance = %+v", distance)
+	}
+}
@@ -0,0 +1,193 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+
+	"golang.org/x/image/draw"
+
+	"github.com/innovelabs/microtools-go/internal/imagedecode"
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+const (
+	ImageVerdictIdentical = "identical"
+	ImageVerdictSimilar   = "similar"
+	ImageVerdictDifferent = "different"
+
+	maxHashImages = 2
+	hashBits      = 64
+
+	// similarPHashDistance is the largest pHash distance still reported as
+	// similar; re-encoding or resizing an image typically stays well below it
+	similarPHashDistance = 10
+
+	// pHash takes the lowest 8x8 frequencies of a 32x32 DCT
+	dctSize      = 32
+	dctLowFreqs  = 8
+	averageSize  = 8
+	gradientSize = 8
+)
+
+var ErrInvalidImageHashRequest = errors.New("invalid image hash request")
+
+// dctCos holds cos((2x+1)uπ/2N) for the DCT-II of one 32-sample row
+var dctCos = func() [dctLowFreqs][dctSize]float64 {
+	var table [dctLowFreqs][dctSize]float64
+	for u := range table {
+		for x := range table[u] {
+			table[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * dctSize))
+		}
+	}
+	return table
+}()
+
+// HashImages decodes one or two encoded images and returns their aHash,
+// dHash and pHash, with Hamming distances and a verdict when there are two
+func HashImages(images [][]byte) (models.ImageHashResult, error) {
+	if len(images) == 0 || len(images) > maxHashImages {
+		return models.ImageHashResult{}, fmt.Errorf("%w: provide one image, or two to compare", ErrInvalidImageHashRequest)
+	}
+
+	result := models.ImageHashResult{Images: make([]models.ImageHashes, len(images))}
+	hashes := make([][3]uint64, len(images))
+	for i, data := range images {
+		img, format, err := imagedecode.DecodeBytes(data)
+		if err != nil {
+			return models.ImageHashResult{}, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		hashes[i] = [3]uint64{averageHash(img), differenceHash(img), perceptualHash(img)}
+		bounds := img.Bounds()
+		result.Images[i] = models.ImageHashes{
+			Format: format,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+			AHash:  formatHash(hashes[i][0]),
+			DHash:  formatHash(hashes[i][1]),
+			PHash:  formatHash(hashes[i][2]),
+		}
+	}
+
+	if len(images) == maxHashImages {
+		result.Comparison = compareHashes(hashes[0], hashes[1])
+	}
+	return result, nil
+}
+
+func compareHashes(a, b [3]uint64) *models.ImageHashComparison {
+	comparison := &models.ImageHashComparison{
+		AHashDistance: bits.OnesCount64(a[0] ^ b[0]),
+		DHashDistance: bits.OnesCount64(a[1] ^ b[1]),
+		PHashDistance: bits.OnesCount64(a[2] ^ b[2]),
+	}
+	comparison.Similarity = math.Round((1-float64(comparison.PHashDistance)/hashBits)*10000) / 10000
+	switch {
+	case comparison.AHashDistance == 0 && comparison.DHashDistance == 0 && comparison.PHashDistance == 0:
+		comparison.Verdict = ImageVerdictIdentical
+	case comparison.PHashDistance <= similarPHashDistance:
+		comparison.Verdict = ImageVerdictSimilar
+	default:
+		comparison.Verdict = ImageVerdictDifferent
+	}
+	return comparison
+}
+
+func formatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// grayscale scales img to width x height over a white background, so
+// transparent areas hash like the page they are shown on, and returns the
+// luma of each pixel row by row
+func grayscale(img image.Image, width, height int) []float64 {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.BiLinear.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
+
+	luma := make([]float64, width*height)
+	for i := range luma {
+		p := dst.Pix[i*4:]
+		luma[i] = 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+	}
+	return luma
+}
+
+// averageHash sets a bit for each pixel of an 8x8 thumbnail brighter than
+// the thumbnail's mean
+func averageHash(img image.Image) uint64 {
+	pixels := grayscale(img, averageSize, averageSize)
+	var mean float64
+	for _, v := range pixels {
+		mean += v
+	}
+	mean /= float64(len(pixels))
+	return thresholdBits(pixels, mean)
+}
+
+// differenceHash sets a bit for each pixel of a 9x8 thumbnail brighter than
+// its right neighbour
+func differenceHash(img image.Image) uint64 {
+	width := gradientSize + 1
+	pixels := grayscale(img, width, gradientSize)
+	var hash uint64
+	for y := 0; y < gradientSize; y++ {
+		for x := 0; x < gradientSize; x++ {
+			hash <<= 1
+			if pixels[y*width+x] > pixels[y*width+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// perceptualHash takes the DCT-II of a 32x32 thumbnail and sets a bit for
+// each of the lowest 8x8 frequencies above their median. The transform is
+// computed directly, rows then columns, for the 8 needed frequencies only.
+func perceptualHash(img image.Image) uint64 {
+	pixels := grayscale(img, dctSize, dctSize)
+
+	var rows [dctSize][dctLowFreqs]float64
+	for y := 0; y < dctSize; y++ {
+		for u := 0; u < dctLowFreqs; u++ {
+			var sum float64
+			for x := 0; x < dctSize; x++ {
+				sum += pixels[y*dctSize+x] * dctCos[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
+
+	coefficients := make([]float64, 0, dctLowFreqs*dctLowFreqs)
+	for v := 0; v < dctLowFreqs; v++ {
+		for u := 0; u < dctLowFreqs; u++ {
+			var sum float64
+			for y := 0; y < dctSize; y++ {
+				sum += rows[y][u] * dctCos[v][y]
+			}
+			coefficients = append(coefficients, sum)
+		}
+	}
+
+	sorted := append([]float64(nil), coefficients...)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	return thresholdBits(coefficients, median)
+}
+
+// thresholdBits packs one bit per value, set when it exceeds threshold,
+// first value in the most significant bit
+func thresholdBits(values []float64, threshold float64) uint64 {
+	var hash uint64
+	for _, v := range values {
+		hash <<= 1
+		if v > threshold {
+			hash |= 1
+		}
+	}
+	return hash
+}
@@ -0,0 +1,141 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/imagedecode"
+)
+
+// scene draws a 256x192 fixture: a diagonal gradient with a dark disc, or
+// with inverse set, vertical stripes and a light square, which share no
+// structure with it
+func scene(inverse bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 256, 192))
+	for y := 0; y < 192; y++ {
+		for x := 0; x < 256; x++ {
+			v := uint8((x + y) * 255 / (256 + 192))
+			if math.Hypot(float64(x-90), float64(y-100)) < 50 {
+				v = 30
+			}
+			if inverse {
+				v = uint8(40 + 160*((x/24)%2))
+				if x > 150 && x < 230 && y > 20 && y < 100 {
+					v = 240
+				}
+			}
+			img.Set(x, y, color.RGBA{v, v / 2, 255 - v, 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image, quality int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestHashImages(t *testing.T) {
+	original := encodePNG(t, scene(false))
+	tests := []struct {
+		name     string
+		other    []byte
+		verdicts []string
+		maxDist  int
+		minDist  int
+	}{
+		{"identical", encodePNG(t, scene(false)), []string{ImageVerdictIdentical}, 0, 0},
+		// The 8x8 and 32x32 reductions usually absorb JPEG artefacts
+		// entirely, so either near verdict is right
+		{"re-encoded JPEG", encodeJPEG(t, scene(false), 60), []string{ImageVerdictIdentical, ImageVerdictSimilar}, similarPHashDistance, 0},
+		{"unrelated", encodePNG(t, scene(true)), []string{ImageVerdictDifferent}, hashBits, similarPHashDistance + 1},
+	}
+	for _, tt := range tests {
+		result, err := HashImages([][]byte{original, tt.other})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		c := result.Comparison
+		if c == nil || !slices.Contains(tt.verdicts, c.Verdict) || c.PHashDistance > tt.maxDist || c.PHashDistance < tt.minDist {
+			t.Errorf("%s: comparison %+v, want %v with a pHash distance in [%d, %d]", tt.name, c, tt.verdicts, tt.minDist, tt.maxDist)
+			continue
+		}
+		if want := math.Round((1-float64(c.PHashDistance)/64)*10000) / 10000; c.Similarity != want {
+			t.Errorf("%s: similarity %v, want %v", tt.name, c.Similarity, want)
+		}
+	}
+}
+
+func TestHashImagesSingle(t *testing.T) {
+	result, err := HashImages([][]byte{encodeJPEG(t, scene(false), 90)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Comparison != nil || len(result.Images) != 1 {
+		t.Fatalf("result = %+v, want the hashes of one image", result)
+	}
+	hashes := result.Images[0]
+	if hashes.Format != "jpeg" || hashes.Width != 256 || hashes.Height != 192 {
+		t.Errorf("image = %+v", hashes)
+	}
+	for _, hash := range []string{hashes.AHash, hashes.DHash, hashes.PHash} {
+		if len(hash) != 16 || hash == "0000000000000000" {
+			t.Errorf("hash %q, want 16 hex digits with bits set", hash)
+		}
+	}
+}
+
+// bombPNG returns a valid 1x1 PNG whose header declares width x height
+func bombPNG(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	data := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	// The IHDR chunk follows the 8-byte signature: length, type, then the
+	// dimensions, with its CRC over type and data
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestHashImagesErrors(t *testing.T) {
+	valid := encodePNG(t, scene(false))
+	for _, tt := range []struct {
+		name   string
+		images [][]byte
+		want   error
+	}{
+		{"no images", nil, ErrInvalidImageHashRequest},
+		{"three images", [][]byte{valid, valid, valid}, ErrInvalidImageHashRequest},
+		{"not an image", [][]byte{valid, []byte("hello")}, imagedecode.ErrUnsupportedFormat},
+		{"truncated", [][]byte{valid[:len(valid)/2]}, imagedecode.ErrInvalidImage},
+		{"declared canvas", [][]byte{bombPNG(t, 8000, 8000)}, imagedecode.ErrTooLarge},
+		{"declared side", [][]byte{bombPNG(t, 20000, 1)}, imagedecode.ErrTooLarge},
+		{"over 5 MB", [][]byte{make([]byte, imagedecode.MaxBytes+1)}, imagedecode.ErrTooLarge},
+	} {
+		if _, err := HashImages(tt.images); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
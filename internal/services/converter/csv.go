@@ -0,0 +1,381 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// Directions of a CSV conversion
+const (
+	CSVToJSON = "csv_to_json"
+	JSONToCSV = "json_to_csv"
+)
+
+// maxSafeDigits is the most significant digits an inferred number may
+// have, so that clients decoding numbers as float64 read it unchanged
+const maxSafeDigits = 15
+
+var (
+	ErrInvalidCSVRequest = errors.New("invalid CSV conversion")
+	ErrMalformedCSV      = errors.New("malformed CSV")
+	ErrMalformedRows     = errors.New("malformed JSON rows")
+	ErrTooManyRows       = errors.New("too many rows")
+)
+
+// utf8BOM starts the CSV files some spreadsheet programs save
+var utf8BOM = []byte("\uFEFF")
+
+// jsonNumber matches the number grammar of RFC 8259
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// CSVConversion is a conversion whose input ConvertCSV checked, ready to
+// be streamed
+type CSVConversion struct {
+	mode       string
+	delimiter  rune
+	hasHeader  bool
+	inferTypes bool
+	maxRows    int
+
+	data    []byte
+	columns []string
+	rows    int
+}
+
+// ConvertCSV reads the input of req once to check it, so that a malformed
+// row is reported before any output is written, and returns the
+// conversion to stream. csvData is the CSV of a text/csv body; nil reads
+// the input from req.Data. Only the input is held in memory: Stream reads
+// it again as it writes.
+func ConvertCSV(req models.CSVConvertRequest, csvData []byte, maxRows int) (*CSVConversion, error) {
+	c := &CSVConversion{
+		mode:       req.Mode,
+		delimiter:  ',',
+		hasHeader:  req.Options.HasHeader == nil || *req.Options.HasHeader,
+		inferTypes: req.Options.InferTypes,
+		maxRows:    maxRows,
+	}
+	if d := req.Options.Delimiter; d != "" {
+		r, size := utf8.DecodeRuneInString(d)
+		if size != len(d) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+			return nil, fmt.Errorf("%w: delimiter must be one character other than a quote or line break", ErrInvalidCSVRequest)
+		}
+		c.delimiter = r
+	}
+
+	var err error
+	switch req.Mode {
+	case CSVToJSON:
+		c.data = csvData
+		if csvData == nil {
+			var text string
+			if err := json.Unmarshal(req.Data, &text); err != nil {
+				return nil, fmt.Errorf("%w: data must be a string of CSV", ErrInvalidCSVRequest)
+			}
+			c.data = []byte(text)
+		}
+		c.data = bytes.TrimPrefix(c.data, utf8BOM)
+		c.columns, c.rows, err = c.readCSV(nil)
+	case JSONToCSV:
+		if csvData != nil {
+			return nil, fmt.Errorf("%w: %s takes a JSON body", ErrInvalidCSVRequest, JSONToCSV)
+		}
+		c.data = req.Data
+		c.columns, c.rows, err = c.readJSON(nil)
+	default:
+		return nil, fmt.Errorf("%w: mode must be %s or %s", ErrInvalidCSVRequest, CSVToJSON, JSONToCSV)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Mode returns the direction of the conversion
+func (c *CSVConversion) Mode() string {
+	return c.mode
+}
+
+// Columns returns the CSV header, or the keys of the JSON rows in the
+// order they first appear
+func (c *CSVConversion) Columns() []string {
+	return c.columns
+}
+
+// Rows returns the number of data rows, the header not included
+func (c *CSVConversion) Rows() int {
+	return c.rows
+}
+
+// Stream writes the output: for csv_to_json a JSON array of objects keyed
+// by column, for json_to_csv RFC 4180 CSV with CRLF line endings. Line
+// breaks inside fields are written as CRLF too; encoding/csv and
+// spreadsheet programs read them back as line breaks.
+func (c *CSVConversion) Stream(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var err error
+	if c.mode == CSVToJSON {
+		err = c.streamJSON(bw)
+	} else {
+		err = c.streamCSV(bw)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (c *CSVConversion) streamJSON(w *bufio.Writer) error {
+	keys := make([][]byte, len(c.columns))
+	for i, column := range c.columns {
+		keys[i], _ = json.Marshal(column)
+	}
+	w.WriteByte('[')
+	var buf []byte
+	first := true
+	_, _, err := c.readCSV(func(record []string) error {
+		buf = buf[:0]
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, '{')
+		for i, field := range record {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = append(append(buf, keys[i]...), ':')
+			buf = c.appendField(buf, field)
+		}
+		_, err := w.Write(append(buf, '}'))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return w.WriteByte(']')
+}
+
+func (c *CSVConversion) streamCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = c.delimiter
+	cw.UseCRLF = true
+	if c.hasHeader && len(c.columns) > 0 {
+		cw.Write(c.columns)
+	}
+	_, _, err := c.readJSON(func(record []string) error {
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readCSV parses the CSV input, calling emit with each data row unless
+// it is nil, and returns the columns and the number of rows. Without a
+// header the columns are named column_1, column_2 and so on.
+func (c *CSVConversion) readCSV(emit func(record []string) error) ([]string, int, error) {
+	r := csv.NewReader(bytes.NewReader(c.data))
+	r.Comma = c.delimiter
+	r.ReuseRecord = true
+	columns := []string{}
+	rows := 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return columns, rows, nil
+		}
+		if err != nil {
+			return nil, 0, csvError(err, record, len(columns))
+		}
+		if len(columns) == 0 {
+			line, _ := r.FieldPos(0)
+			if columns, err = csvColumns(record, c.hasHeader, line); err != nil {
+				return nil, 0, err
+			}
+			if c.hasHeader {
+				continue
+			}
+		}
+		rows++
+		if rows > c.maxRows {
+			line, _ := r.FieldPos(0)
+			return nil, 0, fmt.Errorf("%w: line %d: the limit is %d rows", ErrTooManyRows, line, c.maxRows)
+		}
+		if emit != nil {
+			if err := emit(record); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+}
+
+// csvColumns names the columns after the header record, or by position
+// without a header. Columns a header leaves empty are named by position.
+func csvColumns(record []string, hasHeader bool, line int) ([]string, error) {
+	columns := make([]string, len(record))
+	seen := make(map[string]bool, len(record))
+	for i, name := range record {
+		if !hasHeader || name == "" {
+			name = "column_" + strconv.Itoa(i+1)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: line %d: duplicate column %q", ErrMalformedCSV, line, name)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	return columns, nil
+}
+
+// csvError reports a parse error of encoding/csv with its line. Rows
+// whose field count differs from the first row's report both counts.
+func csvError(err error, record []string, want int) error {
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) {
+		return err
+	}
+	if errors.Is(parseErr.Err, csv.ErrFieldCount) {
+		return fmt.Errorf("%w: line %d: expected %d fields, got %d", ErrMalformedCSV, parseErr.StartLine, want, len(record))
+	}
+	return fmt.Errorf("%w: line %d, column %d: %v", ErrMalformedCSV, parseErr.Line, parseErr.Column, parseErr.Err)
+}
+
+// appendField appends a CSV field as a JSON string or, when types are
+// inferred, as the number, boolean or null it reads as. Empty fields are
+// null. Numbers must be written as JSON writes them and have at most
+// maxSafeDigits significant digits, so "007" and card numbers stay
+// strings.
+func (c *CSVConversion) appendField(buf []byte, field string) []byte {
+	if c.inferTypes {
+		switch field {
+		case "", "null", "NULL", "Null":
+			return append(buf, "null"...)
+		case "true", "TRUE", "True":
+			return append(buf, "true"...)
+		case "false", "FALSE", "False":
+			return append(buf, "false"...)
+		}
+		if isSafeNumber(field) {
+			return append(buf, field...)
+		}
+	}
+	quoted, _ := json.Marshal(field)
+	return append(buf, quoted...)
+}
+
+// isSafeNumber reports whether s is a JSON number a float64 holds without
+// losing digits
+func isSafeNumber(s string) bool {
+	if !jsonNumber.MatchString(s) {
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return false
+	}
+	digits, leading := 0, true
+	for _, r := range s {
+		switch {
+		case r == 'e' || r == 'E':
+			return digits <= maxSafeDigits
+		case r == '0' && leading:
+		case r >= '0' && r <= '9':
+			leading = false
+			digits++
+		}
+	}
+	return digits <= maxSafeDigits
+}
+
+// readJSON walks the array of flat objects of the JSON input, calling
+// emit with the fields of each row by column unless it is nil, and
+// returns the columns, the union of the keys in the order they first
+// appear, and the number of rows. Nulls and missing keys are empty
+// fields; numbers keep the digits they were sent with.
+func (c *CSVConversion) readJSON(emit func(record []string) error) ([]string, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(c.data))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, 0, fmt.Errorf("%w: data must be an array of objects", ErrInvalidCSVRequest)
+	}
+	index := map[string]int{}
+	columns := []string{}
+	var record []string
+	if emit != nil {
+		record = make([]string, len(c.columns))
+	}
+	rows := 0
+	for dec.More() {
+		rows++
+		if rows > c.maxRows {
+			return nil, 0, fmt.Errorf("%w: the limit is %d rows", ErrTooManyRows, c.maxRows)
+		}
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil, 0, fmt.Errorf("%w: row %d is not an object", ErrMalformedRows, rows)
+		}
+		clear(record)
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, 0, fmt.Errorf("%w: row %d: %v", ErrMalformedRows, rows, err)
+			}
+			value, err := dec.Token()
+			if err != nil {
+				return nil, 0, fmt.Errorf("%w: row %d: %v", ErrMalformedRows, rows, err)
+			}
+			name := key.(string)
+			field, ok := csvField(value)
+			if !ok {
+				return nil, 0, fmt.Errorf("%w: row %d: %q is an object or array; values must be strings, numbers, booleans or null", ErrMalformedRows, rows, name)
+			}
+			i, ok := index[name]
+			if !ok {
+				i = len(columns)
+				index[name] = i
+				columns = append(columns, name)
+			}
+			if emit != nil {
+				record[i] = field
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, 0, fmt.Errorf("%w: row %d: %v", ErrMalformedRows, rows, err)
+		}
+		if emit != nil {
+			if err := emit(record); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrMalformedRows, err)
+	}
+	return columns, rows, nil
+}
+
+// csvField returns a JSON scalar token as a CSV field
+func csvField(tok json.Token) (string, bool) {
+	switch v := tok.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return string(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "", true
+	}
+	return "", false
+}
@@ -0,0 +1,230 @@
+package converter
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func csvRequest(data string, opts models.CSVConvertOptions) models.CSVConvertRequest {
+	raw, _ := json.Marshal(data)
+	return models.CSVConvertRequest{Mode: CSVToJSON, Data: raw, Options: opts}
+}
+
+func stream(t *testing.T, c *CSVConversion) string {
+	t.Helper()
+	var out strings.Builder
+	if err := c.Stream(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func noHeader() *bool {
+	b := false
+	return &b
+}
+
+func TestCSVToJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts models.CSVConvertOptions
+		want string
+	}{
+		{"header", "name,city\nAda,London\nGrace,Arlington\n", models.CSVConvertOptions{},
+			`[{"name":"Ada","city":"London"},{"name":"Grace","city":"Arlington"}]`},
+		{"CRLF and no final newline", "name,city\r\nAda,London", models.CSVConvertOptions{},
+			`[{"name":"Ada","city":"London"}]`},
+		{"quoted fields with embedded newlines", "name,note\n\"Ada\",\"line one\nline \"\"two\"\"\"\n\"Lovelace, Ada\",\"\"\n", models.CSVConvertOptions{},
+			`[{"name":"Ada","note":"line one\nline \"two\""},{"name":"Lovelace, Ada","note":""}]`},
+		{"BOM", "\uFEFFid,name\n1,Ada\n", models.CSVConvertOptions{},
+			`[{"id":"1","name":"Ada"}]`},
+		{"no header", "Ada,London\nGrace,Arlington\n", models.CSVConvertOptions{HasHeader: noHeader()},
+			`[{"column_1":"Ada","column_2":"London"},{"column_1":"Grace","column_2":"Arlington"}]`},
+		{"empty header cell", ",name\n1,Ada\n", models.CSVConvertOptions{},
+			`[{"column_1":"1","name":"Ada"}]`},
+		{"semicolons", "name;price\nTea;2,50\n", models.CSVConvertOptions{Delimiter: ";"},
+			`[{"name":"Tea","price":"2,50"}]`},
+		{"tabs", "name\tprice\nTea\t2.50\n", models.CSVConvertOptions{Delimiter: "\t"},
+			`[{"name":"Tea","price":"2.50"}]`},
+		{"blank lines skipped", "a,b\n\n1,2\n\n", models.CSVConvertOptions{},
+			`[{"a":"1","b":"2"}]`},
+		{"header only", "a,b\n", models.CSVConvertOptions{}, `[]`},
+		{"empty", "", models.CSVConvertOptions{}, `[]`},
+		{"unicode and escaping", "name,html\n東京 🚀,<b>&</b>\n", models.CSVConvertOptions{},
+			`[{"name":"東京 🚀","html":"\u003cb\u003e\u0026\u003c/b\u003e"}]`},
+	}
+	for _, tt := range tests {
+		c, err := ConvertCSV(csvRequest(tt.in, tt.opts), nil, 100)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := stream(t, c); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+		if !json.Valid([]byte(stream(t, c))) {
+			t.Errorf("%s: output is not JSON", tt.name)
+		}
+	}
+}
+
+func TestCSVToJSONInferTypes(t *testing.T) {
+	in := "v\n42\n-3.5\n1e3\n0\ntrue\nFALSE\nnull\n\n007\n+1\n4111111111111111\n123456789012345\n0.000123\n1e999\nNaN\n 1\n"
+	c, err := ConvertCSV(csvRequest(in, models.CSVConvertOptions{InferTypes: true, HasHeader: nil}), nil, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(stream(t, c)), &rows); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`42`, `-3.5`, `1e3`, `0`, `true`, `false`, `null`, `"007"`, `"+1"`,
+		`"4111111111111111"`, `123456789012345`, `0.000123`, `"1e999"`, `"NaN"`, `" 1"`}
+	// The empty line is skipped, not a null
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		if got := string(rows[i]["v"]); got != w {
+			t.Errorf("row %d = %s, want %s", i+1, got, w)
+		}
+	}
+
+	c, _ = ConvertCSV(csvRequest("a,b\n,x\n", models.CSVConvertOptions{InferTypes: true}), nil, 100)
+	if got := stream(t, c); got != `[{"a":null,"b":"x"}]` {
+		t.Errorf("empty field = %s, want null", got)
+	}
+}
+
+func TestCSVToJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts models.CSVConvertOptions
+		want error
+		msg  string
+	}{
+		{"ragged short row", "a,b,c\n1,2,3\n4,5\n", models.CSVConvertOptions{}, ErrMalformedCSV, "line 3: expected 3 fields, got 2"},
+		{"ragged long row", "a,b\n1,2,3\n", models.CSVConvertOptions{}, ErrMalformedCSV, "line 2: expected 2 fields, got 3"},
+		// The row starts on line 4, after a field spanning two lines
+		{"ragged after multiline field", "a,b\n1,\"x\ny\"\n2\n", models.CSVConvertOptions{}, ErrMalformedCSV, "line 4: expected 2 fields, got 1"},
+		{"ragged without header", "1,2\n3\n", models.CSVConvertOptions{HasHeader: noHeader()}, ErrMalformedCSV, "line 2: expected 2 fields, got 1"},
+		{"bare quote", "a,b\n1,x\"y\n", models.CSVConvertOptions{}, ErrMalformedCSV, "line 2, column 4: bare \" in non-quoted-field"},
+		{"unterminated quote", "a,b\n1,\"open\n2,3\n", models.CSVConvertOptions{}, ErrMalformedCSV, "line 3, column 5"},
+		{"duplicate column", "id,id\n1,2\n", models.CSVConvertOptions{}, ErrMalformedCSV, `line 1: duplicate column "id"`},
+		{"too many rows", "a\n1\n2\n3\n4\n", models.CSVConvertOptions{}, ErrTooManyRows, "line 5: the limit is 3 rows"},
+		{"quote delimiter", "a", models.CSVConvertOptions{Delimiter: `"`}, ErrInvalidCSVRequest, "delimiter"},
+		{"long delimiter", "a", models.CSVConvertOptions{Delimiter: "::"}, ErrInvalidCSVRequest, "delimiter"},
+	}
+	for _, tt := range tests {
+		_, err := ConvertCSV(csvRequest(tt.in, tt.opts), nil, 3)
+		if !errors.Is(err, tt.want) || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: err = %v, want %v containing %q", tt.name, err, tt.want, tt.msg)
+		}
+	}
+	// Three rows after the header are within the limit
+	if _, err := ConvertCSV(csvRequest("a\n1\n2\n3\n", models.CSVConvertOptions{}), nil, 3); err != nil {
+		t.Errorf("3 rows: %v", err)
+	}
+}
+
+func TestCSVBody(t *testing.T) {
+	req := models.CSVConvertRequest{Mode: CSVToJSON, Options: models.CSVConvertOptions{InferTypes: true}}
+	c, err := ConvertCSV(req, []byte("\uFEFFn\n1\n"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stream(t, c); got != `[{"n":1}]` || c.Rows() != 1 || strings.Join(c.Columns(), ",") != "n" {
+		t.Errorf("got %s, %d rows, columns %q", got, c.Rows(), c.Columns())
+	}
+	req.Mode = JSONToCSV
+	if _, err := ConvertCSV(req, []byte("n\n1\n"), 10); !errors.Is(err, ErrInvalidCSVRequest) {
+		t.Errorf("json_to_csv of a CSV body: err = %v", err)
+	}
+}
+
+func TestJSONToCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts models.CSVConvertOptions
+		want string
+	}{
+		{"union of keys in first-seen order", `[{"b":1,"a":"x"},{"c":true,"a":"y"},{"b":null}]`, models.CSVConvertOptions{},
+			"b,a,c\r\n1,x,\r\n,y,true\r\n,,\r\n"},
+		{"RFC 4180 quoting", `[{"text":"say \"hi\"","list":"a,b","lines":"one\ntwo","plain":"ok"}]`, models.CSVConvertOptions{},
+			"text,list,lines,plain\r\n\"say \"\"hi\"\"\",\"a,b\",\"one\r\ntwo\",ok\r\n"},
+		{"numbers keep their digits", `[{"n":4111111111111111111,"f":1.50,"e":1e3}]`, models.CSVConvertOptions{},
+			"n,f,e\r\n4111111111111111111,1.50,1e3\r\n"},
+		{"no header", `[{"a":1},{"a":2}]`, models.CSVConvertOptions{HasHeader: noHeader()},
+			"1\r\n2\r\n"},
+		{"semicolons", `[{"name":"Tea","price":"2,50"}]`, models.CSVConvertOptions{Delimiter: ";"},
+			"name;price\r\nTea;2,50\r\n"},
+		{"duplicate key keeps the last", `[{"a":1,"a":2}]`, models.CSVConvertOptions{},
+			"a\r\n2\r\n"},
+		{"unicode", `[{"城市":"東京","emoji":"🚀"}]`, models.CSVConvertOptions{},
+			"城市,emoji\r\n東京,🚀\r\n"},
+		{"empty objects", `[{},{}]`, models.CSVConvertOptions{}, "\r\n\r\n"},
+		{"empty", `[]`, models.CSVConvertOptions{}, ""},
+	}
+	for _, tt := range tests {
+		c, err := ConvertCSV(models.CSVConvertRequest{Mode: JSONToCSV, Data: json.RawMessage(tt.in), Options: tt.opts}, nil, 100)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := stream(t, c); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJSONToCSVErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want error
+		msg  string
+	}{
+		{`{"a":1}`, ErrInvalidCSVRequest, "data must be an array of objects"},
+		{`"a,b"`, ErrInvalidCSVRequest, "data must be an array of objects"},
+		{``, ErrInvalidCSVRequest, "data must be an array of objects"},
+		{`[{"a":1},[1,2]]`, ErrMalformedRows, "row 2 is not an object"},
+		{`[{"a":1},"x"]`, ErrMalformedRows, "row 2 is not an object"},
+		{`[{"a":{"b":1}}]`, ErrMalformedRows, `row 1: "a" is an object or array`},
+		{`[{"a":1},{"tags":["x"]}]`, ErrMalformedRows, `row 2: "tags" is an object or array`},
+		{`[{"a":1},{"a":2},{"a":3},{"a":4}]`, ErrTooManyRows, "the limit is 3 rows"},
+	}
+	for _, tt := range tests {
+		_, err := ConvertCSV(models.CSVConvertRequest{Mode: JSONToCSV, Data: json.RawMessage(tt.in)}, nil, 3)
+		if !errors.Is(err, tt.want) || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: err = %v, want %v containing %q", tt.in, err, tt.want, tt.msg)
+		}
+	}
+	if _, err := ConvertCSV(models.CSVConvertRequest{Mode: "xml_to_csv"}, nil, 3); !errors.Is(err, ErrInvalidCSVRequest) {
+		t.Errorf("unknown mode: err = %v", err)
+	}
+	if _, err := ConvertCSV(models.CSVConvertRequest{Mode: CSVToJSON, Data: json.RawMessage(`[{"a":1}]`)}, nil, 3); !errors.Is(err, ErrInvalidCSVRequest) {
+		t.Errorf("csv_to_json of an array: err = %v", err)
+	}
+}
+
+// Converting the CSV output back gives the rows that were sent
+func TestCSVRoundTrip(t *testing.T) {
+	rows := `[{"name":"Lovelace, Ada","note":"line one\nline \"two\"","city":""},{"name":"東京 🚀","note":" lead","city":"x"}]`
+	c, err := ConvertCSV(models.CSVConvertRequest{Mode: JSONToCSV, Data: json.RawMessage(rows)}, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := ConvertCSV(models.CSVConvertRequest{Mode: CSVToJSON}, []byte(stream(t, c)), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stream(t, back); got != rows {
+		t.Errorf("round trip = %s\nwant %s", got, rows)
+	}
+}
@@ -0,0 +1,428 @@
+// Package converter rewrites text: case conversions between naming styles,
+// URL slugs, reversal and trimming.
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Operations of a text conversion request
+const (
+	OpSlug    = "slug"
+	OpCamel   = "camel"
+	OpSnake   = "snake"
+	OpKebab   = "kebab"
+	OpTitle   = "title"
+	OpUpper   = "upper"
+	OpLower   = "lower"
+	OpReverse = "reverse"
+	OpTrim    = "trim"
+
+	// MaxTextBytes is the largest text one request converts
+	MaxTextBytes = 100 * 1024
+)
+
+var (
+	ErrTextTooLarge     = fmt.Errorf("text exceeds maximum size of %d KB", MaxTextBytes/1024)
+	ErrInvalidOperation = errors.New("invalid text conversion")
+)
+
+// operations lists every operation, in the order of the error message
+var operations = []string{OpSlug, OpCamel, OpSnake, OpKebab, OpTitle, OpUpper, OpLower, OpReverse, OpTrim}
+
+// ConvertText applies each operation of req to its text and returns the
+// results by operation name
+func ConvertText(req models.TextConvertRequest) (map[string]string, error) {
+	if len(req.Text) > MaxTextBytes {
+		return nil, ErrTextTooLarge
+	}
+	if len(req.Operations) == 0 {
+		return nil, fmt.Errorf("%w: operations are required: %s", ErrInvalidOperation, strings.Join(operations, ", "))
+	}
+	if req.Options.SlugMaxLength < 0 {
+		return nil, fmt.Errorf("%w: slug_max_length must not be negative", ErrInvalidOperation)
+	}
+	for _, op := range req.Operations {
+		if !slices.Contains(operations, op) {
+			return nil, fmt.Errorf("%w: unknown operation %q; supported: %s", ErrInvalidOperation, op, strings.Join(operations, ", "))
+		}
+	}
+
+	text := strings.ToValidUTF8(req.Text, "\uFFFD")
+	results := make(map[string]string, len(req.Operations))
+	for _, op := range req.Operations {
+		if _, done := results[op]; done {
+			continue
+		}
+		switch op {
+		case OpSlug:
+			results[op] = Slug(text, req.Options.SlugMaxLength)
+		case OpCamel:
+			results[op] = Camel(text)
+		case OpSnake:
+			results[op] = Snake(text)
+		case OpKebab:
+			results[op] = Kebab(text)
+		case OpTitle:
+			results[op] = Title(text)
+		case OpUpper:
+			results[op] = strings.ToUpper(text)
+		case OpLower:
+			results[op] = strings.ToLower(text)
+		case OpReverse:
+			results[op] = Reverse(text)
+		case OpTrim:
+			results[op] = strings.TrimFunc(text, unicode.IsSpace)
+		}
+	}
+	return results, nil
+}
+
+// Camel joins the words of s in lower camel case: "parse URL path" is
+// "parseUrlPath"
+func Camel(s string) string {
+	var b strings.Builder
+	for i, word := range Words(s) {
+		word = strings.ToLower(word)
+		if i > 0 {
+			word = upperFirst(word)
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+// Snake joins the lowercased words of s with underscores: "parseURLPath"
+// is "parse_url_path"
+func Snake(s string) string {
+	return joinLower(Words(s), "_")
+}
+
+// Kebab joins the lowercased words of s with hyphens
+func Kebab(s string) string {
+	return joinLower(Words(s), "-")
+}
+
+func joinLower(words []string, sep string) string {
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, sep)
+}
+
+// upperFirst title-cases the first letter of word, so digraphs such as
+// "ǆ" become "ǅ" rather than "Ǆ"
+func upperFirst(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	if r == utf8.RuneError {
+		return word
+	}
+	return string(unicode.ToTitle(r)) + word[size:]
+}
+
+// runeClass is how a word character takes part in case boundaries
+type runeClass int
+
+const (
+	classLower runeClass = iota
+	classUpper
+	classDigit
+	// classCaseless letters, e.g. CJK, have no case
+	classCaseless
+)
+
+func classOf(r rune) runeClass {
+	switch {
+	case unicode.IsUpper(r) || unicode.IsTitle(r):
+		return classUpper
+	case unicode.IsLower(r):
+		return classLower
+	case unicode.IsDigit(r) || unicode.IsNumber(r):
+		return classDigit
+	default:
+		return classCaseless
+	}
+}
+
+// isWordRune reports whether r belongs to a word: letters, digits and the
+// marks combining with them
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
+}
+
+// startsWord reports whether a word may start with r: marks combine with
+// the character before them, e.g. a variation selector with an emoji
+func startsWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// isApostrophe reports whether r joins the parts of a word like "don't"
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '\u2019' || r == '\u02BC'
+}
+
+// Words splits s into the words of an identifier. Anything but letters,
+// digits and combining marks separates words, and apostrophes inside a
+// word are dropped. Within a run of letters a new word starts at a lower
+// case letter or digit followed by an upper case one ("parseURL"), at the
+// last upper case letter of a run followed by a lower case one ("URLPath")
+// and where cased and caseless letters meet. Digits stay with the letters
+// before them, so "utf8" and "base64Encode" split as "utf8" and "base64",
+// "Encode".
+func Words(s string) []string {
+	var words []string
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		if !startsWord(runes[i]) {
+			i++
+			continue
+		}
+		var word []rune
+		for i < len(runes) {
+			r := runes[i]
+			if isApostrophe(r) && len(word) > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i+1]) {
+				i++
+				continue
+			}
+			if !isWordRune(r) {
+				break
+			}
+			word = append(word, r)
+			i++
+		}
+		words = append(words, splitCase(word)...)
+	}
+	return words
+}
+
+// splitCase splits a run of word characters at its case boundaries
+func splitCase(word []rune) []string {
+	// Marks take the class of the letter they combine with
+	classes := make([]runeClass, len(word))
+	bases := make([]bool, len(word))
+	prev := classCaseless
+	for i, r := range word {
+		if unicode.IsMark(r) && i > 0 {
+			classes[i] = prev
+			continue
+		}
+		prev = classOf(r)
+		classes[i] = prev
+		bases[i] = true
+	}
+
+	var parts []string
+	start := 0
+	prevBase, prevPrevBase := -1, -1
+	for i := range word {
+		if !bases[i] {
+			continue
+		}
+		cut := -1
+		if prevBase >= start {
+			before, cur := classes[prevBase], classes[i]
+			switch {
+			case cur == classUpper && (before == classLower || before == classDigit):
+				cut = i
+			case cur == classLower && before == classUpper && prevPrevBase >= start && classes[prevPrevBase] == classUpper:
+				cut = prevBase
+			case (cur == classCaseless) != (before == classCaseless) && cur != classDigit && before != classDigit:
+				cut = i
+			}
+		}
+		if cut > start {
+			parts = append(parts, string(word[start:cut]))
+			start = cut
+		}
+		prevPrevBase, prevBase = prevBase, i
+	}
+	return append(parts, string(word[start:]))
+}
+
+// Title capitalizes the first letter of each word of s, leaving the rest
+// of the word and everything between words as they are, so acronyms and
+// names like "iPhone" survive. Text written all in capitals is lowercased
+// first.
+func Title(s string) string {
+	if !strings.ContainsFunc(s, unicode.IsLower) {
+		s = strings.ToLower(s)
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	atWordStart := true
+	for _, r := range s {
+		switch {
+		case atWordStart && startsWord(r):
+			b.WriteRune(unicode.ToTitle(r))
+			atWordStart = false
+		case isWordRune(r) || isApostrophe(r) && !atWordStart:
+			b.WriteRune(r)
+			atWordStart = false
+		default:
+			b.WriteRune(r)
+			atWordStart = true
+		}
+	}
+	return b.String()
+}
+
+// Reverse reverses s by user-perceived characters, so combining marks
+// stay on their letters and emoji sequences, flags and CRLF stay whole
+func Reverse(s string) string {
+	clusters := Graphemes(s)
+	slices.Reverse(clusters)
+	return strings.Join(clusters, "")
+}
+
+const zeroWidthJoiner = '\u200D'
+
+// Graphemes splits s into user-perceived characters. It follows the rules
+// of UAX #29 that matter for reversal: combining marks, variation
+// selectors, emoji modifiers and tags extend a character, a zero width
+// joiner joins the next character to it, regional indicators pair into
+// flags and CR LF is one character.
+func Graphemes(s string) []string {
+	var clusters []string
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		start := i
+		r := runes[i]
+		i++
+		switch {
+		case r == '\r' && i < len(runes) && runes[i] == '\n':
+			i++
+		case isRegionalIndicator(r) && i < len(runes) && isRegionalIndicator(runes[i]):
+			i++
+		}
+		for i < len(runes) {
+			if runes[i] == zeroWidthJoiner && i+1 < len(runes) {
+				i += 2
+				continue
+			}
+			if !extendsGrapheme(runes[i]) {
+				break
+			}
+			i++
+		}
+		clusters = append(clusters, string(runes[start:i]))
+	}
+	return clusters
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// extendsGrapheme reports whether r belongs to the character before it
+func extendsGrapheme(r rune) bool {
+	switch {
+	case unicode.IsMark(r), r == zeroWidthJoiner:
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F, r >= 0xE0100 && r <= 0xE01EF:
+		// Variation selectors
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF:
+		// Emoji skin tone modifiers
+		return true
+	case r >= 0xE0020 && r <= 0xE007F:
+		// Tags of subdivision flags
+		return true
+	}
+	return false
+}
+
+// transliterations spell lowercase letters that do not decompose into
+// ASCII in Latin letters. Cyrillic follows the common passport-style
+// romanization, Greek the ELOT 743 basics.
+var transliterations = map[rune]string{
+	// Latin
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th",
+	'ł': "l", 'ı': "i", 'ħ': "h", 'ŧ': "t", 'ŋ': "ng", 'ĸ': "k", 'ſ': "s",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'є': "ye", 'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k",
+	'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+	// Symbols read as words
+	'&': " and ",
+}
+
+// Slug turns s into a lowercase URL path segment of words joined by single
+// hyphens. Compatibility characters are decomposed (NFKD: "ﬁ" is "fi",
+// full-width letters are ASCII) and their diacritics dropped, Latin
+// ligatures, Cyrillic and Greek are transliterated, and letters of other
+// scripts (e.g. CJK) are kept. Everything else, emoji included, separates
+// words; apostrophes inside words are dropped. A positive maxLength cuts
+// the slug at the last hyphen within maxLength characters, or within the
+// word when the first one is longer.
+func Slug(s string, maxLength int) string {
+	var spelled strings.Builder
+	for _, r := range strings.ToLower(norm.NFC.String(s)) {
+		if t, ok := transliterations[r]; ok {
+			spelled.WriteString(t)
+			continue
+		}
+		// Letters with diacritics are transliterated without them, e.g.
+		// Greek ή as η
+		for _, d := range norm.NFKD.String(string(r)) {
+			if unicode.IsMark(d) {
+				continue
+			}
+			if t, ok := transliterations[d]; ok {
+				spelled.WriteString(t)
+			} else {
+				spelled.WriteRune(d)
+			}
+		}
+	}
+
+	var b strings.Builder
+	pendingHyphen := false
+	runes := []rune(spelled.String())
+	for i, r := range runes {
+		switch {
+		case isApostrophe(r) && !pendingHyphen && b.Len() > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i+1]):
+			continue
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			// Compatibility decompositions may be cased, e.g. ㎒ is MHz
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			pendingHyphen = true
+		}
+	}
+	return truncateSlug(b.String(), maxLength)
+}
+
+// truncateSlug cuts slug to at most maxLength characters on a hyphen
+func truncateSlug(slug string, maxLength int) string {
+	if maxLength <= 0 || utf8.RuneCountInString(slug) <= maxLength {
+		return slug
+	}
+	runes := []rune(slug)
+	// The hyphen after the last whole word may be the character just past
+	// the limit
+	for i := maxLength; i > 0; i-- {
+		if runes[i] == '-' {
+			return string(runes[:i])
+		}
+	}
+	return string(runes[:maxLength])
+}
@@ -0,0 +1,244 @@
+package converter
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"parseURLPath", []string{"parse", "URL", "Path"}},
+		{"ParseURL", []string{"Parse", "URL"}},
+		{"URL", []string{"URL"}},
+		{"HTTPServer2Go", []string{"HTTP", "Server2", "Go"}},
+		{"HTML5Parser", []string{"HTML5", "Parser"}},
+		{"base64Encode", []string{"base64", "Encode"}},
+		{"utf8", []string{"utf8"}},
+		{"userID42", []string{"user", "ID42"}},
+		{"already_snake_case", []string{"already", "snake", "case"}},
+		{"kebab-case-words", []string{"kebab", "case", "words"}},
+		{"  Hello,   World!  ", []string{"Hello", "World"}},
+		{"don't stop", []string{"dont", "stop"}},
+		{"it’s here", []string{"its", "here"}},
+		{"'quoted'", []string{"quoted"}},
+		{"crèmeBrûlée", []string{"crème", "Brûlée"}},
+		// e and i followed by combining acute accents
+		{"caféNíght", []string{"café", "Níght"}},
+		{"ÉCOLENormale", []string{"ÉCOLE", "Normale"}},
+		{"東京Tower", []string{"東京", "Tower"}},
+		{"tower東京", []string{"tower", "東京"}},
+		{"東京 大阪", []string{"東京", "大阪"}},
+		{"I ❤️ Go 🚀 fast", []string{"I", "Go", "fast"}},
+		{"version 2.0.1", []string{"version", "2", "0", "1"}},
+		{"ǄemalHouse", []string{"Ǆemal", "House"}},
+		{"", nil},
+		{"🙂 --- !!!", nil},
+	}
+	for _, tt := range tests {
+		if got := Words(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("Words(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCaseConversions(t *testing.T) {
+	tests := []struct {
+		in, camel, snake, kebab string
+	}{
+		{"parseURLPath", "parseUrlPath", "parse_url_path", "parse-url-path"},
+		{"Parse URL path", "parseUrlPath", "parse_url_path", "parse-url-path"},
+		{"HTTP_SERVER_PORT", "httpServerPort", "http_server_port", "http-server-port"},
+		{"getHTTPResponseCode", "getHttpResponseCode", "get_http_response_code", "get-http-response-code"},
+		{"base64Encode the value", "base64EncodeTheValue", "base64_encode_the_value", "base64-encode-the-value"},
+		{"IPv6Address", "iPv6Address", "i_pv6_address", "i-pv6-address"},
+		{"user ID 42", "userId42", "user_id_42", "user-id-42"},
+		{"  leading and trailing  ", "leadingAndTrailing", "leading_and_trailing", "leading-and-trailing"},
+		{"crème brûlée", "crèmeBrûlée", "crème_brûlée", "crème-brûlée"},
+		{"Straße Nummer", "straßeNummer", "straße_nummer", "straße-nummer"},
+		{"ǆemal house", "ǆemalHouse", "ǆemal_house", "ǆemal-house"},
+		{"hello ǆemal", "helloǅemal", "hello_ǆemal", "hello-ǆemal"},
+		{"Привет Мир", "приветМир", "привет_мир", "привет-мир"},
+		{"東京タワー tour", "東京タワーTour", "東京タワー_tour", "東京タワー-tour"},
+		{"rocket 🚀 launch", "rocketLaunch", "rocket_launch", "rocket-launch"},
+		{"", "", "", ""},
+	}
+	for _, tt := range tests {
+		if got := Camel(tt.in); got != tt.camel {
+			t.Errorf("Camel(%q) = %q, want %q", tt.in, got, tt.camel)
+		}
+		if got := Snake(tt.in); got != tt.snake {
+			t.Errorf("Snake(%q) = %q, want %q", tt.in, got, tt.snake)
+		}
+		if got := Kebab(tt.in); got != tt.kebab {
+			t.Errorf("Kebab(%q) = %q, want %q", tt.in, got, tt.kebab)
+		}
+	}
+}
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"hello world", "Hello World"},
+		{"hello   world-wide web!", "Hello   World-Wide Web!"},
+		{"HELLO WORLD", "Hello World"},
+		{"use the NASA api", "Use The NASA Api"},
+		{"don't stop", "Don't Stop"},
+		{"élan vital", "Élan Vital"},
+		{"élan", "Élan"},
+		{"ǆemal", "ǅemal"},
+		{"1st place", "1st Place"},
+		{"東京 tower", "東京 Tower"},
+		{"🚀launch", "🚀Launch"},
+	}
+	for _, tt := range tests {
+		if got := Title(tt.in); got != tt.want {
+			t.Errorf("Title(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		in        string
+		maxLength int
+		want      string
+	}{
+		{"Hello, World!", 0, "hello-world"},
+		{"  --Already--slugged--  ", 0, "already-slugged"},
+		{"Crème Brûlée à la carte", 0, "creme-brulee-a-la-carte"},
+		// Decomposed input: e and u followed by combining marks
+		{"Crème Brûlée", 0, "creme-brulee"},
+		{"Straße, Æsir & Œuvre", 0, "strasse-aesir-and-oeuvre"},
+		{"Łódź Øresund Þingvellir", 0, "lodz-oresund-thingvellir"},
+		{"ﬁnancial ①②③ Ｆｕｌｌｗｉｄｔｈ", 0, "financial-123-fullwidth"},
+		{"Привет, мир! Щука й ёж", 0, "privet-mir-shchuka-y-yozh"},
+		{"Київ", 0, "kiyiv"},
+		{"Αθήνα Ελλάδα", 0, "athina-ellada"},
+		{"東京 タワー", 0, "東京-タワー"},
+		{"I ❤️ Go 🚀🚀 fast", 0, "i-go-fast"},
+		{"👨‍👩‍👧 family 🇫🇷", 0, "family"},
+		{"Don't stop", 0, "dont-stop"},
+		{"it’s 100% true", 0, "its-100-true"},
+		{"snake_case_words", 0, "snake-case-words"},
+		{"\t\n multi\n\nline\ttext ", 0, "multi-line-text"},
+		{"🙂🙂", 0, ""},
+		{"", 0, ""},
+		// Cut on a hyphen boundary
+		{"The quick brown fox jumps", 15, "the-quick-brown"},
+		{"The quick brown fox jumps", 16, "the-quick-brown"},
+		{"The quick brown fox jumps", 14, "the-quick"},
+		{"The quick brown fox jumps", 100, "the-quick-brown-fox-jumps"},
+		{"supercalifragilistic word", 10, "supercalif"},
+		{"ab cd", 2, "ab"},
+		{"東京 タワー 展望台", 5, "東京"},
+		{"東京 タワー 展望台", 6, "東京-タワー"},
+	}
+	for _, tt := range tests {
+		if got := Slug(tt.in, tt.maxLength); got != tt.want {
+			t.Errorf("Slug(%q, %d) = %q, want %q", tt.in, tt.maxLength, got, tt.want)
+		}
+	}
+}
+
+func TestSlugCharacters(t *testing.T) {
+	in := "Mixed ÀÉÎÕÜ ñ ç — “quotes” <tags> & 123 ½ ™ ℃"
+	got := Slug(in, 0)
+	if got != "mixed-aeiou-n-c-quotes-tags-and-123-1-2-tm-c" {
+		t.Errorf("Slug(%q) = %q", in, got)
+	}
+	if strings.Contains(got, "--") || strings.HasPrefix(got, "-") || strings.HasSuffix(got, "-") {
+		t.Errorf("Slug has stray hyphens: %q", got)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"hello", "olleh"},
+		{"", ""},
+		{"añb", "bña"},
+		// Combining acute stays on its e
+		{"café!", "!éfac"},
+		{"ẹ́x", "xẹ́"},
+		// Family emoji joined by zero width joiners
+		{"a👨‍👩‍👧b", "b👨‍👩‍👧a"},
+		// Skin tone modifier and variation selector
+		{"👍🏽 ❤️", "❤️ 👍🏽"},
+		// Regional indicator pairs are flags
+		{"🇫🇷🇩🇪", "🇩🇪🇫🇷"},
+		// England flag: black flag and tags
+		{"x🏴󠁧󠁢󠁥󠁮󠁧󠁿", "🏴󠁧󠁢󠁥󠁮󠁧󠁿x"},
+		{"a\r\nb", "b\r\na"},
+		{"東京タワー", "ーワタ京東"},
+	}
+	for _, tt := range tests {
+		if got := Reverse(tt.in); got != tt.want {
+			t.Errorf("Reverse(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestConvertText(t *testing.T) {
+	results, err := ConvertText(models.TextConvertRequest{
+		Text:       "  Parse the URLPath, Señor!  ",
+		Operations: []string{OpSlug, OpCamel, OpSnake, OpKebab, OpTitle, OpUpper, OpLower, OpReverse, OpTrim, OpSlug},
+		Options:    models.TextConvertOptions{SlugMaxLength: 18},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		OpSlug:    "parse-the-urlpath",
+		OpCamel:   "parseTheUrlPathSeñor",
+		OpSnake:   "parse_the_url_path_señor",
+		OpKebab:   "parse-the-url-path-señor",
+		OpTitle:   "  Parse The URLPath, Señor!  ",
+		OpUpper:   "  PARSE THE URLPATH, SEÑOR!  ",
+		OpLower:   "  parse the urlpath, señor!  ",
+		OpReverse: "  !roñeS ,htaPLRU eht esraP  ",
+		OpTrim:    "Parse the URLPath, Señor!",
+	}
+	if len(results) != len(want) {
+		t.Errorf("got %d results, want %d", len(results), len(want))
+	}
+	for op, w := range want {
+		if results[op] != w {
+			t.Errorf("%s = %q, want %q", op, results[op], w)
+		}
+	}
+
+	// Invalid UTF-8 is replaced, not passed through
+	results, err = ConvertText(models.TextConvertRequest{Text: "a\xffb", Operations: []string{OpUpper}})
+	if err != nil || results[OpUpper] != "A�B" {
+		t.Errorf("invalid UTF-8: %q, %v", results, err)
+	}
+}
+
+func TestConvertTextRejects(t *testing.T) {
+	tests := []struct {
+		req  models.TextConvertRequest
+		want error
+	}{
+		{models.TextConvertRequest{Text: "x"}, ErrInvalidOperation},
+		{models.TextConvertRequest{Text: "x", Operations: []string{"rot13"}}, ErrInvalidOperation},
+		{models.TextConvertRequest{Text: "x", Operations: []string{OpSlug}, Options: models.TextConvertOptions{SlugMaxLength: -1}}, ErrInvalidOperation},
+		{models.TextConvertRequest{Text: strings.Repeat("a", MaxTextBytes+1), Operations: []string{OpLower}}, ErrTextTooLarge},
+	}
+	for _, tt := range tests {
+		if _, err := ConvertText(tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("%+v: err = %v, want %v", tt.req.Operations, err, tt.want)
+		}
+	}
+	if _, err := ConvertText(models.TextConvertRequest{Text: strings.Repeat("a", MaxTextBytes), Operations: []string{OpSlug}}); err != nil {
+		t.Errorf("text of exactly %d bytes: %v", MaxTextBytes, err)
+	}
+}
@@ -0,0 +1,377 @@
+package converter
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	_ "time/tzdata" // zone names must not depend on the host's zoneinfo
+	"unicode/utf8"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// Epoch units of a timestamp conversion
+const (
+	UnitSeconds      = "s"
+	UnitMilliseconds = "ms"
+	UnitMicroseconds = "us"
+
+	// MaxOutputTimezones is the most zones one conversion renders
+	MaxOutputTimezones = 50
+)
+
+// Ambiguities of a local date and time around a DST transition
+const (
+	// AmbiguityAmbiguous marks a local time that occurs twice, when the
+	// clocks go back
+	AmbiguityAmbiguous = "ambiguous"
+	// AmbiguityNonexistent marks a local time skipped when the clocks go
+	// forward
+	AmbiguityNonexistent = "nonexistent"
+)
+
+var (
+	ErrInvalidTimestamp = errors.New("invalid timestamp conversion")
+	ErrUnknownTimezone  = errors.New("unknown timezone")
+)
+
+// unitNanos is the length of each epoch unit
+var unitNanos = map[string]int64{
+	UnitSeconds:      int64(time.Second),
+	UnitMilliseconds: int64(time.Millisecond),
+	UnitMicroseconds: int64(time.Microsecond),
+}
+
+// Epoch magnitudes from which auto-detection reads milliseconds and
+// microseconds: 1e11 seconds is the year 5138, 1e11 milliseconds 1973
+var (
+	millisFrom = big.NewRat(1e11, 1)
+	microsFrom = big.NewRat(1e14, 1)
+	detectTo   = big.NewRat(1e17, 1)
+)
+
+// ISO 8601 keeps years to four digits
+var (
+	minTime = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxTime = time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC)
+)
+
+// localLayouts are the forms of a datetime without an offset
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// leapSecond matches a time of day with a 60th second
+var leapSecond = regexp.MustCompile(`[T ]\d{2}:\d{2}:60`)
+
+// timezoneList is the tz database names suggested for unknown zones: the
+// Area/Location names of Go's embedded tzdata (lib/time/zoneinfo.zip)
+// without Etc/ and SystemV/, and UTC
+//
+//go:embed timezones.txt
+var timezoneList string
+
+var timezones = strings.Fields(timezoneList)
+
+// UnknownTimezoneError is a zone name the tz database does not have, with
+// the closest names it has
+type UnknownTimezoneError struct {
+	Name        string
+	Suggestions []string
+}
+
+func (e *UnknownTimezoneError) Error() string {
+	message := fmt.Sprintf("unknown timezone %q", e.Name)
+	if len(e.Suggestions) > 0 {
+		message += "; did you mean " + strings.Join(e.Suggestions, ", ") + "?"
+	}
+	return message
+}
+
+func (e *UnknownTimezoneError) Unwrap() error {
+	return ErrUnknownTimezone
+}
+
+// ConvertTimestamp converts an epoch, or a datetime read in req.Timezone,
+// to the instant's UTC forms and its local time in each output zone. A
+// datetime naming a local time that occurs twice or never around a DST
+// transition converts with the offset in effect before the transition and
+// lists both candidate instants.
+func ConvertTimestamp(req models.TimestampConvertRequest) (*models.TimestampConvertResult, error) {
+	if (req.Epoch == nil) == (req.Datetime == "") {
+		return nil, fmt.Errorf("%w: send one of epoch and datetime", ErrInvalidTimestamp)
+	}
+	if len(req.OutputTimezones) > MaxOutputTimezones {
+		return nil, fmt.Errorf("%w: at most %d output_timezones", ErrInvalidTimestamp, MaxOutputTimezones)
+	}
+	outputs := make([]*time.Location, len(req.OutputTimezones))
+	for i, name := range req.OutputTimezones {
+		loc, err := LoadTimezone(name)
+		if err != nil {
+			return nil, err
+		}
+		outputs[i] = loc
+	}
+
+	result := &models.TimestampConvertResult{}
+	var instant time.Time
+	if req.Epoch != nil {
+		if req.Timezone != "" {
+			return nil, fmt.Errorf("%w: timezone reads a datetime; list the zones to show an epoch in in output_timezones", ErrInvalidTimestamp)
+		}
+		var err error
+		if instant, result.Unit, err = parseEpoch(req.Epoch.String(), req.Unit); err != nil {
+			return nil, err
+		}
+	} else {
+		if req.Unit != "" {
+			return nil, fmt.Errorf("%w: unit applies to epoch only", ErrInvalidTimestamp)
+		}
+		loc := time.UTC
+		if req.Timezone != "" {
+			var err error
+			if loc, err = LoadTimezone(req.Timezone); err != nil {
+				return nil, err
+			}
+		}
+		candidates, ambiguity, err := parseDatetime(req.Datetime, loc, req.Timezone != "")
+		if err != nil {
+			return nil, err
+		}
+		instant = candidates[0]
+		if instant.Location() == loc {
+			zone := timestampZone(instant)
+			result.Local = &zone
+		}
+		if ambiguity != "" {
+			result.Ambiguity = ambiguity
+			for _, candidate := range candidates {
+				_, offset := candidate.Zone()
+				result.Candidates = append(result.Candidates, models.TimestampCandidate{
+					ISO8601:    candidate.Format(time.RFC3339Nano),
+					Offset:     formatOffset(offset),
+					Unix:       candidate.Unix(),
+					UnixMillis: candidate.UnixMilli(),
+				})
+			}
+		}
+	}
+
+	utc := instant.UTC()
+	year, week := utc.ISOWeek()
+	result.Unix = utc.Unix()
+	result.UnixMillis = utc.UnixMilli()
+	result.ISO8601 = utc.Format(time.RFC3339Nano)
+	result.RFC1123 = utc.Format(time.RFC1123)
+	result.DayOfWeek = utc.Weekday().String()
+	result.ISOWeek = week
+	result.ISOWeekYear = year
+	result.Timezones = make([]models.TimestampZone, len(outputs))
+	for i, loc := range outputs {
+		result.Timezones[i] = timestampZone(instant.In(loc))
+	}
+	return result, nil
+}
+
+// LoadTimezone loads an IANA zone, answering unknown names with an
+// UnknownTimezoneError. "Local", the server's zone, is refused.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "Local" || name == "" {
+		return nil, &UnknownTimezoneError{Name: name, Suggestions: []string{"UTC"}}
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, &UnknownTimezoneError{Name: name, Suggestions: suggestTimezones(name)}
+	}
+	return loc, nil
+}
+
+// parseEpoch reads a decimal epoch in unit, or in the unit its magnitude
+// suggests when unit is empty, and returns it with the unit used.
+// Fractions below a nanosecond are dropped.
+func parseEpoch(number, unit string) (time.Time, string, error) {
+	value, ok := new(big.Rat).SetString(number)
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("%w: epoch must be a number", ErrInvalidTimestamp)
+	}
+	if unit == "" {
+		magnitude := new(big.Rat).Abs(value)
+		switch {
+		case magnitude.Cmp(millisFrom) < 0:
+			unit = UnitSeconds
+		case magnitude.Cmp(microsFrom) < 0:
+			unit = UnitMilliseconds
+		case magnitude.Cmp(detectTo) < 0:
+			unit = UnitMicroseconds
+		default:
+			return time.Time{}, "", fmt.Errorf("%w: epoch %s is too large to be seconds, milliseconds or microseconds", ErrInvalidTimestamp, number)
+		}
+	}
+	perUnit, ok := unitNanos[unit]
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("%w: unit must be %s, %s or %s", ErrInvalidTimestamp, UnitSeconds, UnitMilliseconds, UnitMicroseconds)
+	}
+
+	nanos := value.Mul(value, big.NewRat(perUnit, 1))
+	total, rest := new(big.Int).DivMod(nanos.Num(), nanos.Denom(), new(big.Int))
+	seconds, nsec := total.DivMod(total, big.NewInt(int64(time.Second)), rest)
+	if seconds.Cmp(big.NewInt(minTime.Unix())) < 0 || seconds.Cmp(big.NewInt(maxTime.Unix())) > 0 {
+		return time.Time{}, "", fmt.Errorf("%w: epoch %s %s is outside the years 1 to 9999", ErrInvalidTimestamp, number, unit)
+	}
+	return time.Unix(seconds.Int64(), nsec.Int64()).UTC(), unit, nil
+}
+
+// parseDatetime reads an RFC 3339 datetime, or a local date and time in
+// loc, and returns the instants it names: one, or two with the ambiguity
+// around a DST transition, the interpretation with the offset before the
+// transition first. A datetime with an offset must not name a zone too.
+func parseDatetime(value string, loc *time.Location, zoneGiven bool) ([]time.Time, string, error) {
+	if leapSecond.MatchString(value) {
+		return nil, "", fmt.Errorf("%w: leap seconds such as 23:59:60 have no Unix time; use :59 or the next minute", ErrInvalidTimestamp)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		if zoneGiven {
+			return nil, "", fmt.Errorf("%w: datetime %q has an offset; leave timezone empty", ErrInvalidTimestamp, value)
+		}
+		if t.UTC().Before(minTime) || t.UTC().After(maxTime) {
+			return nil, "", fmt.Errorf("%w: datetime is outside the years 1 to 9999", ErrInvalidTimestamp)
+		}
+		return []time.Time{t}, "", nil
+	}
+	for _, layout := range localLayouts {
+		wall, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		return localInstants(wall, loc)
+	}
+	return nil, "", fmt.Errorf("%w: datetime must be RFC 3339, or YYYY-MM-DD with an optional hh:mm[:ss[.fff]] for a local time", ErrInvalidTimestamp)
+}
+
+// localInstants returns the instants at which the clocks of loc show the
+// date and time of wall, a UTC time. The offsets a day before and after
+// are the ones a transition between them can give the wall time.
+func localInstants(wall time.Time, loc *time.Location) ([]time.Time, string, error) {
+	var offsets []int
+	for _, probe := range []time.Time{wall.Add(-24 * time.Hour), wall.Add(24 * time.Hour)} {
+		if _, offset := probe.In(loc).Zone(); !slices.Contains(offsets, offset) {
+			offsets = append(offsets, offset)
+		}
+	}
+	var matching, all []time.Time
+	for _, offset := range offsets {
+		t := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		all = append(all, t)
+		if sameWall(t, wall) {
+			matching = append(matching, t)
+		}
+	}
+	if t := all[0]; t.UTC().Before(minTime) || t.UTC().After(maxTime) {
+		return nil, "", fmt.Errorf("%w: datetime is outside the years 1 to 9999", ErrInvalidTimestamp)
+	}
+	switch len(matching) {
+	case 0:
+		return all, AmbiguityNonexistent, nil
+	case 1:
+		return matching, "", nil
+	}
+	return matching, AmbiguityAmbiguous, nil
+}
+
+// sameWall reports whether t shows the date and time of wall
+func sameWall(t, wall time.Time) bool {
+	y1, m1, d1 := t.Date()
+	y2, m2, d2 := wall.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2 && t.Hour() == wall.Hour() && t.Minute() == wall.Minute() &&
+		t.Second() == wall.Second() && t.Nanosecond() == wall.Nanosecond()
+}
+
+// timestampZone describes t in its location
+func timestampZone(t time.Time) models.TimestampZone {
+	abbreviation, offset := t.Zone()
+	year, week := t.ISOWeek()
+	return models.TimestampZone{
+		Timezone:      t.Location().String(),
+		ISO8601:       t.Format(time.RFC3339Nano),
+		RFC1123:       t.Format(time.RFC1123),
+		Offset:        formatOffset(offset),
+		OffsetSeconds: offset,
+		Abbreviation:  abbreviation,
+		IsDST:         t.IsDST(),
+		DayOfWeek:     t.Weekday().String(),
+		ISOWeek:       week,
+		ISOWeekYear:   year,
+	}
+}
+
+// formatOffset writes an offset in seconds as ±hh:mm
+func formatOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, offset/3600, offset%3600/60)
+}
+
+// suggestTimezones returns up to three zone names close to name: by edit
+// distance to the whole name, or to the location part (Berlin for
+// Europe/Berlin) when name has no area. Case and spaces for underscores
+// do not count.
+func suggestTimezones(name string) []string {
+	query := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))
+	if query == "" {
+		return nil
+	}
+	maxDistance := max(1, min(3, utf8.RuneCountInString(query)/4))
+	type match struct {
+		name     string
+		distance int
+	}
+	var matches []match
+	for _, zone := range timezones {
+		lower := strings.ToLower(zone)
+		distance := editDistance(query, lower)
+		if !strings.Contains(query, "/") {
+			distance = min(distance, editDistance(query, lower[strings.LastIndex(lower, "/")+1:]))
+		}
+		if distance <= maxDistance {
+			matches = append(matches, match{zone, distance})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int { return a.distance - b.distance })
+	suggestions := make([]string, 0, 3)
+	for _, m := range matches[:min(3, len(matches))] {
+		suggestions = append(suggestions, m.name)
+	}
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between a and b in runes
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			diagonal, row[j] = row[j], min(row[j]+1, row[j-1]+1, diagonal+cost)
+		}
+	}
+	return row[len(rb)]
+}
@@ -0,0 +1,170 @@
+package converter
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func epoch(n string) *json.Number {
+	number := json.Number(n)
+	return &number
+}
+
+func TestConvertEpoch(t *testing.T) {
+	tests := []struct {
+		name   string
+		epoch  string
+		unit   string
+		want   string
+		millis int64
+		detect string
+	}{
+		{"epoch zero", "0", "", "1970-01-01T00:00:00Z", 0, UnitSeconds},
+		{"seconds", "1700000000", "", "2023-11-14T22:13:20Z", 1700000000000, UnitSeconds},
+		{"milliseconds", "1700000000123", "", "2023-11-14T22:13:20.123Z", 1700000000123, UnitMilliseconds},
+		{"microseconds", "1700000000123456", "", "2023-11-14T22:13:20.123456Z", 1700000000123, UnitMicroseconds},
+		{"unit override", "1700000000", "ms", "1970-01-20T16:13:20Z", 1700000000, UnitMilliseconds},
+		{"negative", "-86400", "", "1969-12-31T00:00:00Z", -86400000, UnitSeconds},
+		{"fraction", "1.5", "", "1970-01-01T00:00:01.5Z", 1500, UnitSeconds},
+		{"negative fraction", "-0.25", "s", "1969-12-31T23:59:59.75Z", -250, UnitSeconds},
+		{"exponent", "1e3", "", "1970-01-01T00:16:40Z", 1000000, UnitSeconds},
+	}
+	for _, tt := range tests {
+		got, err := ConvertTimestamp(models.TimestampConvertRequest{Epoch: epoch(tt.epoch), Unit: tt.unit})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.ISO8601 != tt.want || got.UnixMillis != tt.millis || got.Unit != tt.detect {
+			t.Errorf("%s: %s %d %s, want %s %d %s", tt.name, got.ISO8601, got.UnixMillis, got.Unit, tt.want, tt.millis, tt.detect)
+		}
+	}
+}
+
+func TestConvertEpochZeroFields(t *testing.T) {
+	got, err := ConvertTimestamp(models.TimestampConvertRequest{
+		Epoch:           epoch("0"),
+		OutputTimezones: []string{"America/New_York", "Asia/Kolkata"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Unix != 0 || got.RFC1123 != "Thu, 01 Jan 1970 00:00:00 UTC" || got.DayOfWeek != "Thursday" ||
+		got.ISOWeek != 1 || got.ISOWeekYear != 1970 || got.Local != nil {
+		t.Errorf("got %+v", got)
+	}
+	want := []models.TimestampZone{
+		{Timezone: "America/New_York", ISO8601: "1969-12-31T19:00:00-05:00", RFC1123: "Wed, 31 Dec 1969 19:00:00 EST",
+			Offset: "-05:00", OffsetSeconds: -18000, Abbreviation: "EST", DayOfWeek: "Wednesday", ISOWeek: 1, ISOWeekYear: 1970},
+		{Timezone: "Asia/Kolkata", ISO8601: "1970-01-01T05:30:00+05:30", RFC1123: "Thu, 01 Jan 1970 05:30:00 IST",
+			Offset: "+05:30", OffsetSeconds: 19800, Abbreviation: "IST", DayOfWeek: "Thursday", ISOWeek: 1, ISOWeekYear: 1970},
+	}
+	if !slices.Equal(got.Timezones, want) {
+		t.Errorf("timezones = %+v, want %+v", got.Timezones, want)
+	}
+}
+
+func TestConvertDatetime(t *testing.T) {
+	tests := []struct {
+		name      string
+		datetime  string
+		timezone  string
+		want      string
+		local     string
+		ambiguity string
+		candidate []string
+	}{
+		{"UTC default", "2024-06-01T12:00:00", "", "2024-06-01T12:00:00Z", "2024-06-01T12:00:00Z", "", nil},
+		{"date only", "2024-06-01", "Europe/Berlin", "2024-05-31T22:00:00Z", "2024-06-01T00:00:00+02:00", "", nil},
+		{"space and fraction", "2024-01-15 08:30:00.250", "America/New_York", "2024-01-15T13:30:00.25Z", "2024-01-15T08:30:00.25-05:00", "", nil},
+		{"offset", "2024-06-01T12:00:00+05:30", "", "2024-06-01T06:30:00Z", "", "", nil},
+		{"berlin spring forward", "2024-03-31T02:30", "Europe/Berlin", "2024-03-31T01:30:00Z", "2024-03-31T03:30:00+02:00",
+			AmbiguityNonexistent, []string{"2024-03-31T03:30:00+02:00", "2024-03-31T01:30:00+01:00"}},
+		{"berlin fall back", "2024-10-27T02:30", "Europe/Berlin", "2024-10-27T00:30:00Z", "2024-10-27T02:30:00+02:00",
+			AmbiguityAmbiguous, []string{"2024-10-27T02:30:00+02:00", "2024-10-27T02:30:00+01:00"}},
+		{"new york spring forward", "2024-03-10T02:15:00", "America/New_York", "2024-03-10T07:15:00Z", "2024-03-10T03:15:00-04:00",
+			AmbiguityNonexistent, []string{"2024-03-10T03:15:00-04:00", "2024-03-10T01:15:00-05:00"}},
+		{"new york fall back", "2024-11-03T01:30:00", "America/New_York", "2024-11-03T05:30:00Z", "2024-11-03T01:30:00-04:00",
+			AmbiguityAmbiguous, []string{"2024-11-03T01:30:00-04:00", "2024-11-03T01:30:00-05:00"}},
+		{"edge of the gap", "2024-03-31T03:00", "Europe/Berlin", "2024-03-31T01:00:00Z", "2024-03-31T03:00:00+02:00", "", nil},
+	}
+	for _, tt := range tests {
+		got, err := ConvertTimestamp(models.TimestampConvertRequest{Datetime: tt.datetime, Timezone: tt.timezone})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var local string
+		if got.Local != nil {
+			local = got.Local.ISO8601
+		}
+		var candidates []string
+		for _, c := range got.Candidates {
+			candidates = append(candidates, c.ISO8601)
+		}
+		if got.ISO8601 != tt.want || local != tt.local || got.Ambiguity != tt.ambiguity || !slices.Equal(candidates, tt.candidate) {
+			t.Errorf("%s: %s local %s %q %v, want %s local %s %q %v", tt.name,
+				got.ISO8601, local, got.Ambiguity, candidates, tt.want, tt.local, tt.ambiguity, tt.candidate)
+		}
+	}
+}
+
+func TestConvertTimestampErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.TimestampConvertRequest
+	}{
+		{"neither", models.TimestampConvertRequest{}},
+		{"both", models.TimestampConvertRequest{Epoch: epoch("0"), Datetime: "1970-01-01"}},
+		{"not a number", models.TimestampConvertRequest{Epoch: epoch("soon")}},
+		{"unknown unit", models.TimestampConvertRequest{Epoch: epoch("0"), Unit: "ns"}},
+		{"too large to detect", models.TimestampConvertRequest{Epoch: epoch("1e17")}},
+		{"after 9999", models.TimestampConvertRequest{Epoch: epoch("253402300800"), Unit: "s"}},
+		{"before year 1", models.TimestampConvertRequest{Epoch: epoch("-62135596801"), Unit: "s"}},
+		{"epoch with timezone", models.TimestampConvertRequest{Epoch: epoch("0"), Timezone: "UTC"}},
+		{"datetime with unit", models.TimestampConvertRequest{Datetime: "2024-01-01", Unit: "s"}},
+		{"offset and timezone", models.TimestampConvertRequest{Datetime: "2024-01-01T00:00:00Z", Timezone: "Europe/Berlin"}},
+		{"malformed", models.TimestampConvertRequest{Datetime: "01/02/2024"}},
+		{"leap second", models.TimestampConvertRequest{Datetime: "2016-12-31T23:59:60Z"}},
+		{"local leap second", models.TimestampConvertRequest{Datetime: "2016-12-31 23:59:60", Timezone: "UTC"}},
+		{"too many zones", models.TimestampConvertRequest{Epoch: epoch("0"), OutputTimezones: make([]string, MaxOutputTimezones+1)}},
+	}
+	for _, tt := range tests {
+		if _, err := ConvertTimestamp(tt.req); !errors.Is(err, ErrInvalidTimestamp) {
+			t.Errorf("%s: err = %v, want ErrInvalidTimestamp", tt.name, err)
+		}
+	}
+}
+
+func TestUnknownTimezoneSuggestions(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Europe/Berln", "Europe/Berlin"},
+		{"berlin", "Europe/Berlin"},
+		{"America/New York", "America/New_York"},
+		{"Local", "UTC"},
+	}
+	for _, tt := range tests {
+		_, err := ConvertTimestamp(models.TimestampConvertRequest{Datetime: "2024-01-01", Timezone: tt.name})
+		var zoneErr *UnknownTimezoneError
+		if !errors.As(err, &zoneErr) || !errors.Is(err, ErrUnknownTimezone) {
+			t.Errorf("%s: err = %v", tt.name, err)
+			continue
+		}
+		if !slices.Contains(zoneErr.Suggestions, tt.want) {
+			t.Errorf("%s: suggestions %v, want %s among them", tt.name, zoneErr.Suggestions, tt.want)
+		}
+	}
+
+	_, err := ConvertTimestamp(models.TimestampConvertRequest{Epoch: epoch("0"), OutputTimezones: []string{"UTC", "Mars/Olympus_Mons"}})
+	var zoneErr *UnknownTimezoneError
+	if !errors.As(err, &zoneErr) || zoneErr.Name != "Mars/Olympus_Mons" || len(zoneErr.Suggestions) != 0 {
+		t.Errorf("output zone: err = %v", err)
+	}
+}
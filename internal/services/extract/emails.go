@@ -0,0 +1,248 @@
+// Package extract pulls structured values out of free text
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/transform"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+)
+
+const (
+	SourceText = "text"
+	SourceHTML = "html"
+
+	// MaxInputBytes caps the text or HTML scanned for addresses
+	MaxInputBytes = 1 << 20
+	// MaxEmails caps the distinct addresses returned
+	MaxEmails = 1000
+
+	// contextBytes is how much text around an address its context shows
+	// on each side
+	contextBytes = 40
+)
+
+var (
+	ErrInputTooLarge  = errors.New("input exceeds maximum size of 1 MB")
+	ErrInvalidExtract = errors.New("invalid extraction request")
+)
+
+// emailPattern matches the addresses the syntax check accepts, without
+// anchors so they are found inside text
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9_%+-]+(?:\.[A-Za-z0-9_%+-]+)*@(?:[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?\.)+[A-Za-z]{2,63}`)
+
+// wholeEmail matches a single address, e.g. a decoded mailto: target
+var wholeEmail = regexp.MustCompile(`^` + emailPattern.String() + `$`)
+
+// mailtoPattern matches a mailto: URL up to the end of its targets
+var mailtoPattern = regexp.MustCompile(`(?i)mailto:([^\s"'<>()\[\]?]+)`)
+
+// softBreak is a quoted-printable soft line break, which mail clients
+// insert into long lines, addresses included
+var softBreak = regexp.MustCompile(`=\r?\n`)
+
+// obfuscation matches "[at]", "(dot)", " AT " and the like, which people
+// write instead of @ and . to keep their address from scrapers
+var obfuscation = regexp.MustCompile(`\s*[\[({<]\s*(?i:at|dot)\s*[\])}>]\s*|\s+(?:AT|DOT)\s+`)
+
+// textEdit is one replacement rewrite made: at is its offset in the
+// rewritten text, replaced and length the original and new byte counts
+type textEdit struct {
+	at, replaced, length int
+	obfuscation          bool
+}
+
+// rewrittenText is the text addresses are scanned in, with the edits
+// needed to map its offsets back to the original
+type rewrittenText struct {
+	text  string
+	edits []textEdit
+}
+
+// rewrite joins soft line breaks and, with deobfuscate, turns the
+// obfuscated forms of @ and . back into the characters
+func rewrite(s string, deobfuscate bool) rewrittenText {
+	type match struct {
+		start, end  int
+		replacement string
+		obfuscation bool
+	}
+	var matches []match
+	for _, m := range softBreak.FindAllStringIndex(s, -1) {
+		matches = append(matches, match{start: m[0], end: m[1]})
+	}
+	if deobfuscate {
+		for _, m := range obfuscation.FindAllStringIndex(s, -1) {
+			token := strings.ToLower(strings.TrimSpace(s[m[0]:m[1]]))
+			replacement := "@"
+			if strings.Contains(token, "dot") {
+				replacement = "."
+			}
+			matches = append(matches, match{start: m[0], end: m[1], replacement: replacement, obfuscation: true})
+		}
+	}
+	if len(matches) == 0 {
+		return rewrittenText{text: s}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	var (
+		b     strings.Builder
+		edits []textEdit
+		last  int
+	)
+	for _, m := range matches {
+		if m.start < last {
+			continue
+		}
+		b.WriteString(s[last:m.start])
+		edits = append(edits, textEdit{at: b.Len(), replaced: m.end - m.start, length: len(m.replacement), obfuscation: m.obfuscation})
+		b.WriteString(m.replacement)
+		last = m.end
+	}
+	b.WriteString(s[last:])
+	return rewrittenText{text: b.String(), edits: edits}
+}
+
+// original maps an offset of the rewritten text to the original text
+func (t rewrittenText) original(offset int) int {
+	shifted := offset
+	for _, e := range t.edits {
+		if e.at+e.length > offset {
+			break
+		}
+		shifted += e.replaced - e.length
+	}
+	return shifted
+}
+
+// obfuscated reports whether an obfuscated form was rewritten between
+// start and end
+func (t rewrittenText) obfuscated(start, end int) bool {
+	for _, e := range t.edits {
+		if e.obfuscation && e.at >= start && e.at < end {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractEmails finds the email addresses in req's text or HTML. Addresses
+// are deduplicated case-insensitively and listed in order of first
+// occurrence; with req.Validate each gets the syntax and disposable checks.
+func ExtractEmails(ctx context.Context, req models.EmailExtractRequest) (models.EmailExtraction, error) {
+	input, source := req.Text, SourceText
+	switch {
+	case req.Text != "" && req.HTML != "":
+		return models.EmailExtraction{}, fmt.Errorf("%w: text and html cannot both be set", ErrInvalidExtract)
+	case req.HTML != "":
+		input, source = req.HTML, SourceHTML
+	case req.Text == "":
+		return models.EmailExtraction{}, fmt.Errorf("%w: one of text or html is required", ErrInvalidExtract)
+	}
+	if len(input) > MaxInputBytes {
+		return models.EmailExtraction{}, ErrInputTooLarge
+	}
+	if source == SourceHTML {
+		converted, err := transform.HTMLToText(models.HTML2TextRequest{
+			HTML:    input,
+			Options: models.HTML2TextOptions{Links: transform.LinkModeInline, Images: transform.ImageModeDrop},
+		})
+		if err != nil {
+			return models.EmailExtraction{}, err
+		}
+		input = converted.Text
+	}
+
+	result := scanEmails(input, req.IncludeObfuscated)
+	result.Source = source
+	if req.Validate {
+		checks := validation.EmailChecks{Syntax: true, Disposable: true}
+		for i := range result.Emails {
+			checked := validation.ValidateEmailChecks(ctx, result.Emails[i].Email, checks, nil)
+			result.Emails[i].Validation = &checked
+		}
+	}
+	return result, nil
+}
+
+// scanEmails lists the distinct addresses in text, plain and in mailto:
+// URLs, keeping at most MaxEmails
+func scanEmails(text string, deobfuscate bool) models.EmailExtraction {
+	scanned := rewrite(text, deobfuscate)
+	result := models.EmailExtraction{Emails: []models.ExtractedEmail{}}
+	index := map[string]int{}
+	seen := map[int]bool{}
+
+	add := func(address string, start, end int) {
+		if seen[start] {
+			return
+		}
+		seen[start] = true
+		canonical := strings.ToLower(address)
+		if i, ok := index[canonical]; ok {
+			result.Emails[i].Occurrences++
+			return
+		}
+		if len(result.Emails) == MaxEmails {
+			result.Truncated = true
+			return
+		}
+		from, to := scanned.original(start), scanned.original(end-1)+1
+		index[canonical] = len(result.Emails)
+		result.Emails = append(result.Emails, models.ExtractedEmail{
+			Email:       canonical,
+			Offset:      from,
+			Context:     contextSnippet(text, from, to),
+			Occurrences: 1,
+			Obfuscated:  scanned.obfuscated(start, end),
+		})
+	}
+
+	type occurrence struct {
+		address    string
+		start, end int
+	}
+	var found []occurrence
+	for _, m := range emailPattern.FindAllStringIndex(scanned.text, -1) {
+		found = append(found, occurrence{scanned.text[m[0]:m[1]], m[0], m[1]})
+	}
+	// Percent-encoded mailto: targets are missed by the plain scan; plain
+	// ones were found by it at the same offset and are skipped by add
+	for _, m := range mailtoPattern.FindAllStringSubmatchIndex(scanned.text, -1) {
+		start := m[2]
+		for _, target := range strings.Split(scanned.text[m[2]:m[3]], ",") {
+			if decoded, err := url.PathUnescape(target); err == nil && wholeEmail.MatchString(decoded) {
+				found = append(found, occurrence{decoded, start, start + len(target)})
+			}
+			start += len(target) + 1
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].start < found[j].start })
+	for _, o := range found {
+		add(o.address, o.start, o.end)
+	}
+	result.Count = len(result.Emails)
+	return result
+}
+
+// contextSnippet returns the text around text[start:end] on one line
+func contextSnippet(text string, start, end int) string {
+	from := max(start-contextBytes, 0)
+	to := min(end+contextBytes, len(text))
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from++
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to--
+	}
+	return strings.Join(strings.Fields(text[from:to]), " ")
+}
@@ -0,0 +1,133 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func extracted(t *testing.T, req models.EmailExtractRequest) models.EmailExtraction {
+	t.Helper()
+	result, err := ExtractEmails(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count != len(result.Emails) {
+		t.Errorf("count = %d for %d emails", result.Count, len(result.Emails))
+	}
+	return result
+}
+
+func addresses(result models.EmailExtraction) []string {
+	var got []string
+	for _, e := range result.Emails {
+		got = append(got, e.Email)
+	}
+	return got
+}
+
+func TestExtractEmailsContactPage(t *testing.T) {
+	result := extracted(t, models.EmailExtractRequest{HTML: fixture(t, "contact_page.html")})
+	if result.Source != SourceHTML {
+		t.Errorf("source = %q", result.Source)
+	}
+	// Scripts are dropped, entities decoded and mailto: targets read, the
+	// percent-encoded one included
+	want := []string{"sales@acme.example", "press@acme.example", "support@acme.example"}
+	if got := addresses(result); !reflect.DeepEqual(got, want) {
+		t.Fatalf("emails = %v, want %v", got, want)
+	}
+	if sales := result.Emails[0]; sales.Occurrences != 3 || !strings.Contains(sales.Context, "Sales:") {
+		t.Errorf("sales = %+v, want 3 occurrences in the Sales: line", sales)
+	}
+}
+
+func TestExtractEmailsThread(t *testing.T) {
+	text := fixture(t, "thread.txt")
+	result := extracted(t, models.EmailExtractRequest{Text: text, Validate: true})
+
+	want := []string{
+		"billing-team@very-long-subsidiary-name.example.co.uk",
+		"jane.doe@example.org",
+		"bob.smith@example.com",
+		"accounts@mailinator.com",
+	}
+	if got := addresses(result); !reflect.DeepEqual(got, want) {
+		t.Fatalf("emails = %v, want %v", got, want)
+	}
+
+	// The address split by a soft line break is reported where it starts
+	billing := result.Emails[0]
+	if !strings.HasPrefix(text[billing.Offset:], "billing-team@") || !strings.Contains(billing.Context, "loop in billing-team@very-long-subsidiary-name.ex= ample.co.uk before") {
+		t.Errorf("billing = %+v", billing)
+	}
+	jane := result.Emails[1]
+	if jane.Occurrences != 2 || !strings.HasPrefix(text[jane.Offset:], "jane.doe@example.org") {
+		t.Errorf("jane = %+v, want 2 occurrences", jane)
+	}
+	for _, e := range result.Emails {
+		if e.Validation == nil || e.Validation.IsSyntaxValid == nil || !*e.Validation.IsSyntaxValid {
+			t.Errorf("%s: validation = %+v, want valid syntax", e.Email, e.Validation)
+			continue
+		}
+		disposable := e.Email == "accounts@mailinator.com"
+		if e.Validation.IsDisposable == nil || *e.Validation.IsDisposable != disposable {
+			t.Errorf("%s: isDisposable = %v, want %v", e.Email, e.Validation.IsDisposable, disposable)
+		}
+		if e.Validation.IsDomainValid != nil || e.Validation.MxRecordsFound != nil {
+			t.Errorf("%s: network checks ran", e.Email)
+		}
+	}
+}
+
+func TestExtractEmailsObfuscated(t *testing.T) {
+	text := fixture(t, "obfuscated.txt")
+
+	plain := extracted(t, models.EmailExtractRequest{Text: text})
+	if got := addresses(plain); !reflect.DeepEqual(got, []string{"dave@example.com"}) {
+		t.Errorf("without include_obfuscated: emails = %v", got)
+	}
+
+	result := extracted(t, models.EmailExtractRequest{Text: text, IncludeObfuscated: true})
+	want := []string{"alice@example.com", "carol@mail.example.net", "dave@example.com"}
+	if got := addresses(result); !reflect.DeepEqual(got, want) {
+		t.Fatalf("emails = %v, want %v", got, want)
+	}
+	alice := result.Emails[0]
+	if alice.Occurrences != 2 || !alice.Obfuscated || !strings.HasPrefix(text[alice.Offset:], "alice [at]") {
+		t.Errorf("alice = %+v", alice)
+	}
+	if dave := result.Emails[2]; dave.Obfuscated || !strings.HasPrefix(text[dave.Offset:], "dave@") {
+		t.Errorf("dave = %+v", dave)
+	}
+}
+
+func TestExtractEmailsErrors(t *testing.T) {
+	for _, req := range []models.EmailExtractRequest{
+		{},
+		{Text: "a@example.com", HTML: "<p>b@example.com</p>"},
+	} {
+		if _, err := ExtractEmails(context.Background(), req); !errors.Is(err, ErrInvalidExtract) {
+			t.Errorf("%+v: err = %v, want ErrInvalidExtract", req, err)
+		}
+	}
+	big := strings.Repeat("x", MaxInputBytes+1)
+	if _, err := ExtractEmails(context.Background(), models.EmailExtractRequest{Text: big}); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("err = %v, want ErrInputTooLarge", err)
+	}
+}
@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/redact"
+	"github.com/innovelabs/microtools-go/pkg/generate/svgmeta"
+)
+
+// maxAccessibleTextLength caps the title and desc options, in characters
+const maxAccessibleTextLength = 500
+
+// ErrAccessibleTextTooLong is returned for a title or desc option over
+// maxAccessibleTextLength
+var ErrAccessibleTextTooLong = fmt.Errorf("title and desc must be at most %d characters", maxAccessibleTextLength)
+
+// validateAccessibleText checks the title and desc options
+func validateAccessibleText(title, desc string) error {
+	if utf8.RuneCountInString(title) > maxAccessibleTextLength || utf8.RuneCountInString(desc) > maxAccessibleTextLength {
+		return ErrAccessibleTextTooLong
+	}
+	return nil
+}
+
+// QRAccessibleName returns the title and description of the SVG of req:
+// the requested ones, or defaults naming the QR type with the data
+// redacted, since the SVG may be published where the code itself is not
+// read. Deterministic requests get only what they ask for: the default
+// wording may change between releases, and their bytes may not.
+func QRAccessibleName(req models.QRRequest) svgmeta.Metadata {
+	meta := svgmeta.Metadata{Title: req.Options.Title, Desc: req.Options.Desc}
+	if req.Options.Deterministic {
+		return meta
+	}
+	if meta.Title == "" {
+		meta.Title = "QR code"
+	}
+	if meta.Desc == "" {
+		meta.Desc = "QR code " + describeQRData(req.Type, req.Data)
+	}
+	return meta
+}
+
+// describeQRData describes what a QR payload does without the parts that
+// may be private: passwords, full addresses and numbers, URL paths
+func describeQRData(qrType, data string) string {
+	switch qrType {
+	case "url":
+		if u, err := url.Parse(data); err == nil && u.Host != "" {
+			return "linking to " + u.Host
+		}
+		return "with a link"
+	case "email":
+		return "to send an email to " + redact.Email(data)
+	case "tel":
+		return "to call " + describeNumber(data)
+	case "sms":
+		return "to text " + describeNumber(data)
+	case "wifi":
+		var wifi models.WifiData
+		if err := json.Unmarshal([]byte(data), &wifi); err == nil && wifi.SSID != "" {
+			return "to join the WiFi network " + wifi.SSID
+		}
+		return "to join a WiFi network"
+	case "vcard":
+		var vcard models.VCardData
+		if err := json.Unmarshal([]byte(data), &vcard); err == nil {
+			if name := strings.TrimSpace(vcard.FirstName + " " + vcard.LastName); name != "" {
+				return "with the contact card of " + name
+			}
+		}
+		return "with a contact card"
+	case "event":
+		var event models.EventData
+		if err := json.Unmarshal([]byte(data), &event); err == nil && event.Summary != "" {
+			return "with the calendar event " + event.Summary
+		}
+		return "with a calendar event"
+	case "geo":
+		return "with a map location"
+	case "json":
+		return fmt.Sprintf("with %d characters of JSON", utf8.RuneCountInString(data))
+	default:
+		return fmt.Sprintf("with %d characters of text", utf8.RuneCountInString(data))
+	}
+}
+
+// describeNumber names a phone number by its last digits
+func describeNumber(number string) string {
+	if last := redact.Tail(number); last != "" {
+		return "a number ending in " + last
+	}
+	return "a phone number"
+}
+
+// BarcodeAccessibleName returns the title and description of the SVG of
+// req, defaulting like QRAccessibleName to the symbology and the last
+// characters of the data
+func BarcodeAccessibleName(req models.GenerateRequest) svgmeta.Metadata {
+	meta := svgmeta.Metadata{Title: req.Title, Desc: req.Desc}
+	if req.Deterministic {
+		return meta
+	}
+	if meta.Title == "" {
+		meta.Title = req.Type + " barcode"
+	}
+	if meta.Desc == "" {
+		if last := redact.Tail(req.Data); last != "" {
+			meta.Desc = fmt.Sprintf("%s barcode of a value ending in %s", req.Type, last)
+		} else {
+			meta.Desc = fmt.Sprintf("%s barcode of a short value", req.Type)
+		}
+	}
+	return meta
+}
@@ -0,0 +1,122 @@
+package generator
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// svgDocument is the root of an SVG with the accessibility attributes
+type svgDocument struct {
+	XMLName     xml.Name
+	Role        string `xml:"role,attr"`
+	LabelledBy  string `xml:"aria-labelledby,attr"`
+	DescribedBy string `xml:"aria-describedby,attr"`
+	Children    []struct {
+		XMLName xml.Name
+		ID      string `xml:"id,attr"`
+		Text    string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+// parseAccessibleSVG parses data and checks that the root references a
+// leading <title> and <desc>, returning their text
+func parseAccessibleSVG(t *testing.T, data []byte) (title, desc string) {
+	t.Helper()
+	var doc svgDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("SVG is not valid XML: %v\n%s", err, data)
+	}
+	if doc.XMLName.Local != "svg" || doc.Role != "img" || len(doc.Children) < 2 {
+		t.Fatalf("root %s with role %q and %d children:\n%s", doc.XMLName.Local, doc.Role, len(doc.Children), data)
+	}
+	first, second := doc.Children[0], doc.Children[1]
+	if first.XMLName.Local != "title" || first.ID == "" || first.ID != doc.LabelledBy {
+		t.Errorf("first child <%s id=%q>, want the <title> aria-labelledby %q names", first.XMLName.Local, first.ID, doc.LabelledBy)
+	}
+	if second.XMLName.Local != "desc" || second.ID == "" || second.ID != doc.DescribedBy {
+		t.Errorf("second child <%s id=%q>, want the <desc> aria-describedby %q names", second.XMLName.Local, second.ID, doc.DescribedBy)
+	}
+	return first.Text, second.Text
+}
+
+func TestQRAccessibleSVG(t *testing.T) {
+	tests := []struct {
+		name, qrType, data string
+		desc               string
+		// secret must not appear in the SVG
+		secret string
+	}{
+		{"URL", "url", "https://example.com/reset?token=s3cret", "QR code linking to example.com", "s3cret"},
+		{"email", "email", "ada.lovelace@example.com", "QR code to send an email to ***@example.com", "ada"},
+		{"phone", "tel", "+44 20 7946 0958", "QR code to call a number ending in 0958", "7946"},
+		{"WiFi", "wifi", `{"ssid":"Guest","password":"hunter22","encryption":"WPA"}`, "QR code to join the WiFi network Guest", "hunter22"},
+		{"text", "text", "PIN 4921", "QR code with 8 characters of text", "4921"},
+	}
+	for _, tt := range tests {
+		req := models.QRRequest{Type: tt.qrType, Data: tt.data, Options: models.QROptions{Format: QRFormatSVG}}
+		ApplyDefaults(&req)
+		data, _, err := RenderQR(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		title, desc := parseAccessibleSVG(t, data)
+		if title != "QR code" || desc != tt.desc {
+			t.Errorf("%s: title %q, desc %q; want %q", tt.name, title, desc, tt.desc)
+		}
+		if strings.Contains(string(data), tt.secret) {
+			t.Errorf("%s: the SVG contains %q", tt.name, tt.secret)
+		}
+	}
+
+	// Requested text is escaped
+	req := models.QRRequest{Type: "text", Data: "hello", Options: models.QROptions{
+		Format: QRFormatSVG, Title: `Menu <Café & "Bar">`, Desc: "Scan for today's menu\x00",
+	}}
+	ApplyDefaults(&req)
+	data, _, err := RenderQR(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title, desc := parseAccessibleSVG(t, data); title != `Menu <Café & "Bar">` || desc != "Scan for today's menu" {
+		t.Errorf("title %q, desc %q", title, desc)
+	}
+
+	// Deterministic output gets no default text
+	req = models.QRRequest{Type: "text", Data: "hello", Options: models.QROptions{Format: QRFormatSVG, Deterministic: true}}
+	ApplyDefaults(&req)
+	if data, _, err := RenderQR(context.Background(), req); err != nil || strings.Contains(string(data), "<title") ||
+		strings.Contains(string(data), "role=") {
+		t.Errorf("deterministic SVG: %v\n%s", err, data)
+	}
+}
+
+func TestBarcodeAccessibleSVG(t *testing.T) {
+	service := NewDefaultBarcodeService()
+	for _, tt := range []struct {
+		req         models.GenerateRequest
+		title, desc string
+	}{
+		{models.GenerateRequest{Type: BarcodeTypeCode128, Data: "ACCT-99887766"}, "Code128 barcode", "Code128 barcode of a value ending in 7766"},
+		{models.GenerateRequest{Type: BarcodeTypeCode128, Data: "A1"}, "Code128 barcode", "Code128 barcode of a short value"},
+		{models.GenerateRequest{Type: BarcodeTypeISBN, Data: "9780306406157"}, "ISBN barcode", "ISBN barcode of a value ending in 6157"},
+		{models.GenerateRequest{Type: BarcodeTypeCode128, Data: "A1", Title: "Shelf <B&C>", Desc: "Aisle 4"}, "Shelf <B&C>", "Aisle 4"},
+	} {
+		tt.req.Format = BarcodeFormatSVG
+		img, err := service.Generate(tt.req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.req.Type, err)
+		}
+		if title, desc := parseAccessibleSVG(t, img.Data); title != tt.title || desc != tt.desc {
+			t.Errorf("%s %s: title %q, desc %q; want %q, %q", tt.req.Type, tt.req.Data, title, desc, tt.title, tt.desc)
+		}
+	}
+
+	long := strings.Repeat("x", maxAccessibleTextLength+1)
+	if _, err := service.Generate(models.GenerateRequest{Type: BarcodeTypeCode128, Data: "A1", Format: BarcodeFormatSVG, Title: long}); err == nil {
+		t.Errorf("a title of %d characters is accepted", len(long))
+	}
+}
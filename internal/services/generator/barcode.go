@@ -8,26 +8,36 @@ import (
 	"image/color"
 	"image/draw"
 	"image/png"
+	"math"
 	"strings"
-	"unicode/utf8"
+	"unicode"
 
-	"github.com/boombuler/barcode"
-	"github.com/boombuler/barcode/code128"
-	"github.com/boombuler/barcode/ean"
 	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/pkg/canonicalpng"
+	"github.com/innovelabs/microtools-go/pkg/generate/barcode"
+	"github.com/innovelabs/microtools-go/pkg/generate/svgmeta"
 	"golang.org/x/image/font"
-	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/math/fixed"
 )
 
 const (
-	BarcodeTypeUPCA    = "UPC-A"
-	BarcodeTypeEAN13   = "EAN-13"
-	BarcodeTypeCode128 = "Code128"
+	BarcodeTypeUPCA       = barcode.TypeUPCA
+	BarcodeTypeEAN13      = barcode.TypeEAN13
+	BarcodeTypeCode128    = barcode.TypeCode128
+	BarcodeTypeISBN       = "ISBN"
+	BarcodeTypeCode93     = barcode.TypeCode93
+	BarcodeTypeCode39     = barcode.TypeCode39
+	BarcodeTypeITF14      = barcode.TypeITF14
+	BarcodeTypePharmacode = barcode.TypePharmacode
 
 	BarcodeFormatPNG = "png"
 	BarcodeFormatSVG = "svg"
 
+	// BarcodeFitSnap rounds the width to a whole number of pixels per
+	// module; BarcodeFitStrict keeps the requested width exactly
+	BarcodeFitSnap   = "snap"
+	BarcodeFitStrict = "strict"
+
 	defaultBarcodeWidth  = 300
 	defaultBarcodeHeight = 150
 	maxBarcodeWidth      = 1024
@@ -36,65 +46,207 @@ const (
 	minBarcodeHeight     = 50
 
 	textPaddingHeight = 20
-	maxCode128Length  = 500
 )
 
 var (
-	ErrInvalidType      = errors.New("invalid barcode type: must be UPC-A, EAN-13, or Code128")
+	ErrInvalidType      = errors.New("invalid barcode type: must be UPC-A, EAN-13, Code128, Code93, Code39, ITF-14, ISBN, or Pharmacode")
 	ErrInvalidFormat    = errors.New("invalid format: must be png or svg")
-	ErrInvalidData      = errors.New("invalid data for the specified barcode type")
-	ErrChecksumMismatch = errors.New("checksum digit does not match computed value")
+	ErrInvalidOutput    = errors.New("invalid output: must be binary or json")
+	ErrInvalidData      = barcode.ErrInvalidData
+	ErrChecksumMismatch = barcode.ErrChecksumMismatch
 )
 
+// BarcodeImage is a rendered barcode with the size of the whole image,
+// which differs from the request when the width was snapped
+type BarcodeImage struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
 // BarcodeService defines barcode generation interface
 type BarcodeService interface {
-	Generate(req models.GenerateRequest) ([]byte, string, error)
+	Generate(req models.GenerateRequest) (BarcodeImage, error)
+	Warnings(req models.GenerateRequest) []models.Warning
 }
 
-type defaultBarcodeService struct{}
+type defaultBarcodeService struct {
+	fonts *FontSet
+}
 
-// NewDefaultBarcodeService creates a new barcode service
+// NewDefaultBarcodeService creates a new barcode service with the embedded fonts
 func NewDefaultBarcodeService() BarcodeService {
-	return &defaultBarcodeService{}
+	return NewBarcodeService(NewEmbeddedFontSet())
+}
+
+// NewBarcodeService creates a barcode service rendering text with fonts
+func NewBarcodeService(fonts *FontSet) BarcodeService {
+	return &defaultBarcodeService{fonts: fonts}
 }
 
 // Generate generates a barcode image
-func (s *defaultBarcodeService) Generate(req models.GenerateRequest) ([]byte, string, error) {
+func (s *defaultBarcodeService) Generate(req models.GenerateRequest) (BarcodeImage, error) {
 	applyBarcodeDefaults(&req)
 
 	if err := validateBarcodeRequest(req); err != nil {
-		return nil, "", err
+		return BarcodeImage{}, err
+	}
+	colors, err := parseBarcodeColors(req)
+	if err != nil {
+		return BarcodeImage{}, err
+	}
+
+	if req.Type == BarcodeTypeISBN {
+		return s.generateISBN(req, colors)
 	}
 
-	bc, err := encodeBarcode(req.Type, req.Data)
+	text, err := s.barcodeText(req)
 	if err != nil {
-		return nil, "", err
+		return BarcodeImage{}, err
+	}
+
+	sym, err := barcode.Encode(req.Type, req.Data, barcode.WithCheckDigit(req.IncludeCheckDigit))
+	if err != nil {
+		return BarcodeImage{}, err
+	}
+	if err := fitBarcodeWidth(&req, sym.Width()); err != nil {
+		return BarcodeImage{}, err
 	}
 
+	layout := newBarcodeLayout(req, text.face)
+	img := BarcodeImage{Width: layout.canvasWidth, Height: layout.canvasHeight}
 	switch req.Format {
 	case BarcodeFormatPNG:
-		data, err := renderBarcodePNG(bc, req.Width, req.Height, req.IncludeText, req.Data)
-		return data, "image/png", err
+		img.Data, err = renderBarcodePNG(sym, req, text, colors)
+		img.ContentType = "image/png"
 	case BarcodeFormatSVG:
-		data, err := renderBarcodeSVG(bc, req.Width, req.Height, req.IncludeText, req.Data)
-		return data, "image/svg+xml", err
+		img.Data, err = renderBarcodeSVG(sym, req, text, colors)
+		img.ContentType = "image/svg+xml"
 	default:
-		return nil, "", ErrInvalidFormat
+		return BarcodeImage{}, ErrInvalidFormat
 	}
+	if err != nil {
+		return BarcodeImage{}, err
+	}
+	return img, nil
+}
+
+// Warnings returns the warning rules a request triggers, plus
+// text_glyphs_missing when its text cannot be drawn; the barcode is still
+// generated, but part of the requested options is ignored. Rules see the
+// width after fitting, as the barcode is drawn.
+func (s *defaultBarcodeService) Warnings(req models.GenerateRequest) []models.Warning {
+	applyBarcodeDefaults(&req)
+	if modules, err := barcodeModules(req); err == nil {
+		fitBarcodeWidth(&req, modules)
+	}
+	warnings, _ := checkBarcodeOptions(req)
+	if req.IncludeText && req.Type != BarcodeTypeISBN {
+		if text, err := s.barcodeText(req); err == nil {
+			warnings = append(warnings, text.textWarnings()...)
+		}
+	}
+	return warnings
+}
+
+// BarcodeSize returns the image size req asks for, with the defaults
+// applied; fit_mode may still adjust the width
+func BarcodeSize(req models.GenerateRequest) (int, int) {
+	applyBarcodeDefaults(&req)
+	return req.Width, req.Height
+}
+
+// barcodeTypes maps the lowercased type names without separators to the
+// types, so query strings can spell them code128, ean13 or upca
+var barcodeTypes = map[string]string{
+	"upca":       BarcodeTypeUPCA,
+	"ean13":      BarcodeTypeEAN13,
+	"code128":    BarcodeTypeCode128,
+	"isbn":       BarcodeTypeISBN,
+	"code93":     BarcodeTypeCode93,
+	"code39":     BarcodeTypeCode39,
+	"itf14":      BarcodeTypeITF14,
+	"pharmacode": BarcodeTypePharmacode,
+}
+
+// CanonicalBarcodeType returns the barcode type spelled by name in any
+// case, with or without "-", "_" or spaces (UPC-A, upca, Ean_13), or name
+// unchanged when it spells none
+func CanonicalBarcodeType(name string) string {
+	key := strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', ' ':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+	if barcodeType, ok := barcodeTypes[key]; ok {
+		return barcodeType
+	}
+	return name
 }
 
 func applyBarcodeDefaults(req *models.GenerateRequest) {
+	req.Type = CanonicalBarcodeType(req.Type)
 	if req.Width == 0 {
 		req.Width = defaultBarcodeWidth
 	}
 	if req.Height == 0 {
 		req.Height = defaultBarcodeHeight
 	}
+	if req.FitMode == "" {
+		req.FitMode = BarcodeFitSnap
+	}
+}
+
+// barcodeModules returns the number of modules across the symbol of req,
+// including an ISBN add-on and its gap
+func barcodeModules(req models.GenerateRequest) (int, error) {
+	if req.Type == BarcodeTypeISBN {
+		sym, err := buildISBNSymbol(req.Data, req.Supplement)
+		if err != nil {
+			return 0, err
+		}
+		return sym.totalModules(), nil
+	}
+	sym, err := barcode.Encode(req.Type, req.Data, barcode.WithCheckDigit(req.IncludeCheckDigit))
+	if err != nil {
+		return 0, err
+	}
+	return sym.Width(), nil
+}
+
+// fitBarcodeWidth checks req.Width against a symbol of modules modules.
+// A width below one pixel per module is an error in strict mode; in snap
+// mode the width becomes the nearest whole multiple of modules that fits,
+// so every bar edge falls on a pixel boundary.
+func fitBarcodeWidth(req *models.GenerateRequest, modules int) error {
+	maxWidth := maxBarcodeWidth - 2*req.Padding
+	if req.MaxWidth > 0 {
+		maxWidth = min(maxWidth, req.MaxWidth)
+	}
+	if modules > maxWidth {
+		return fmt.Errorf("%w: this barcode is %d modules wide, more than the maximum width of %d", ErrInvalidData, modules, maxWidth)
+	}
+	if req.FitMode == BarcodeFitStrict {
+		if req.Width < modules {
+			return fmt.Errorf("%w: width must be at least %d for this barcode (%d modules at 1 pixel each)", ErrInvalidData, modules, modules)
+		}
+		return nil
+	}
+
+	factor := max(int(math.Round(float64(req.Width)/float64(modules))), 1)
+	if factor*modules > maxWidth {
+		factor = maxWidth / modules
+	}
+	req.Width = factor * modules
+	return nil
 }
 
 func validateBarcodeRequest(req models.GenerateRequest) error {
 	switch req.Type {
-	case BarcodeTypeUPCA, BarcodeTypeEAN13, BarcodeTypeCode128:
+	case BarcodeTypeUPCA, BarcodeTypeEAN13, BarcodeTypeCode128, BarcodeTypeCode93, BarcodeTypeCode39, BarcodeTypeITF14, BarcodeTypeISBN, BarcodeTypePharmacode:
 	default:
 		return ErrInvalidType
 	}
@@ -105,6 +257,12 @@ func validateBarcodeRequest(req models.GenerateRequest) error {
 		return ErrInvalidFormat
 	}
 
+	switch req.Output {
+	case "", OutputBinary, OutputJSON:
+	default:
+		return ErrInvalidOutput
+	}
+
 	if req.Data == "" {
 		return fmt.Errorf("%w: data is required", ErrInvalidData)
 	}
@@ -120,44 +278,25 @@ func validateBarcodeRequest(req models.GenerateRequest) error {
 		return fmt.Errorf("%w: height must be between %d and %d", ErrInvalidData, minBarcodeHeight, maxBarcodeHeight)
 	}
 
+	if _, err := checkBarcodeOptions(req); err != nil {
+		return err
+	}
+
+	if req.Supplement != "" {
+		return validateSupplement(req.Supplement)
+	}
+
 	return nil
 }
 
+// validateBarcodeData checks data for barcodeType; ISBN is handled here
+// and every other type by the barcode package
 func validateBarcodeData(barcodeType, data string) error {
-	switch barcodeType {
-	case BarcodeTypeUPCA:
-		if !isNumeric(data) {
-			return fmt.Errorf("%w: UPC-A data must be numeric", ErrInvalidData)
-		}
-		n := len(data)
-		if n != 11 && n != 12 {
-			return fmt.Errorf("%w: UPC-A data must be 11 or 12 digits", ErrInvalidData)
-		}
-		if n == 12 {
-			return validateUPCAChecksum(data)
-		}
-
-	case BarcodeTypeEAN13:
-		if !isNumeric(data) {
-			return fmt.Errorf("%w: EAN-13 data must be numeric", ErrInvalidData)
-		}
-		n := len(data)
-		if n != 12 && n != 13 {
-			return fmt.Errorf("%w: EAN-13 data must be 12 or 13 digits", ErrInvalidData)
-		}
-		if n == 13 {
-			return validateEAN13Checksum(data)
-		}
-
-	case BarcodeTypeCode128:
-		if !utf8.ValidString(data) {
-			return fmt.Errorf("%w: Code128 data must be valid UTF-8", ErrInvalidData)
-		}
-		if len(data) > maxCode128Length {
-			return fmt.Errorf("%w: Code128 data exceeds maximum length of %d characters", ErrInvalidData, maxCode128Length)
-		}
+	if barcodeType == BarcodeTypeISBN {
+		_, _, err := normalizeISBN(data)
+		return err
 	}
-	return nil
+	return barcode.Validate(barcodeType, data)
 }
 
 func isNumeric(s string) bool {
@@ -172,118 +311,91 @@ func isNumeric(s string) bool {
 	return true
 }
 
-func computeUPCAChecksum(digits string) int {
-	sum := 0
-	for i := 0; i < 11; i++ {
-		d := int(digits[i] - '0')
-		if i%2 == 0 {
-			sum += d * 3
-		} else {
-			sum += d * 1
-		}
-	}
-	return (10 - (sum % 10)) % 10
+// barcodeLayout places the bars and the optional text row inside the padded canvas
+type barcodeLayout struct {
+	canvasWidth, canvasHeight int
+	barsX, barsY              int
+	textBaseline              int
 }
 
-func validateUPCAChecksum(data string) error {
-	expected := computeUPCAChecksum(data[:11])
-	actual := int(data[11] - '0')
-	if expected != actual {
-		return fmt.Errorf("%w: expected check digit %d, got %d", ErrChecksumMismatch, expected, actual)
+func newBarcodeLayout(req models.GenerateRequest, text *textFace) barcodeLayout {
+	l := barcodeLayout{
+		canvasWidth:  req.Width + 2*req.Padding,
+		canvasHeight: req.Height + 2*req.Padding,
+		barsX:        req.Padding,
+		barsY:        req.Padding,
 	}
-	return nil
-}
-
-func computeEAN13Checksum(digits string) int {
-	sum := 0
-	for i := 0; i < 12; i++ {
-		d := int(digits[i] - '0')
-		if i%2 == 0 {
-			sum += d * 1
+	if req.IncludeText {
+		l.canvasHeight += text.rowHeight
+		if req.TextPosition == TextPositionTop {
+			l.barsY += text.rowHeight
+			l.textBaseline = text.baseline(req.Padding)
 		} else {
-			sum += d * 3
+			l.textBaseline = text.baseline(l.barsY + req.Height)
 		}
 	}
-	return (10 - (sum % 10)) % 10
+	return l
 }
 
-func validateEAN13Checksum(data string) error {
-	expected := computeEAN13Checksum(data[:12])
-	actual := int(data[12] - '0')
-	if expected != actual {
-		return fmt.Errorf("%w: expected check digit %d, got %d", ErrChecksumMismatch, expected, actual)
+// renderBarcodePNG draws the barcode in colors. Without text the image is
+// the two-color one of the barcode package, encoded from the columns
+// alone; text is antialiased and needs an RGBA canvas.
+func renderBarcodePNG(sym *barcode.Symbol, req models.GenerateRequest, text barcodeText, colors barcodeColors) ([]byte, error) {
+	if !req.IncludeText {
+		return sym.PNG(req.Width, req.Height, barcode.WithPadding(req.Padding), barcode.WithDeterministic(req.Deterministic),
+			barcode.WithColors(colors.background.rgba, colors.foreground.rgba))
 	}
-	return nil
-}
-
-func encodeBarcode(barcodeType, data string) (barcode.Barcode, error) {
-	switch barcodeType {
-	case BarcodeTypeUPCA:
-		eanData := "0" + data
-		bc, err := ean.Encode(eanData)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
-		}
-		return bc, nil
-
-	case BarcodeTypeEAN13:
-		bc, err := ean.Encode(data)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
-		}
-		return bc, nil
 
-	case BarcodeTypeCode128:
-		bc, err := code128.Encode(data)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
-		}
-		return bc, nil
-
-	default:
-		return nil, ErrInvalidType
+	layout := newBarcodeLayout(req, text.face)
+	columns, err := sym.Columns(req.Width)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scale barcode: %w", err)
 	}
-}
 
-func renderBarcodePNG(bc barcode.Barcode, width, height int, includeText bool, text string) ([]byte, error) {
-	barcodeHeight := height
-	totalHeight := height
-	if includeText {
-		totalHeight = height + textPaddingHeight
+	canvas := image.NewRGBA(image.Rect(0, 0, layout.canvasWidth, layout.canvasHeight))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{colors.background.rgba}, image.Point{}, draw.Src)
+	for x, dark := range columns {
+		if dark {
+			bar := image.Rect(layout.barsX+x, layout.barsY, layout.barsX+x+1, layout.barsY+req.Height)
+			draw.Draw(canvas, bar, &image.Uniform{colors.foreground.rgba}, image.Point{}, draw.Src)
+		}
 	}
+	drawBarcodeTextInRegion(canvas, text.face, text.visual, layout.textBaseline, layout.barsX, req.Width, colors.text.rgba)
 
-	scaled, err := barcode.Scale(bc, width, barcodeHeight)
+	data, err := encodePNG(canvas, req.Deterministic)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scale barcode: %w", err)
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
 	}
+	return data, nil
+}
 
-	canvas := image.NewRGBA(image.Rect(0, 0, width, totalHeight))
-	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
-	draw.Draw(canvas, image.Rect(0, 0, width, barcodeHeight), scaled, scaled.Bounds().Min, draw.Over)
-
-	if includeText {
-		drawBarcodeTextCentered(canvas, text, height+textPaddingHeight-4, width)
+// encodePNG encodes img with the standard encoder, or with canonicalpng
+// when the request asked for deterministic output
+func encodePNG(img image.Image, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return canonicalpng.Encode(img), nil
 	}
-
 	var buf bytes.Buffer
-	if err := png.Encode(&buf, canvas); err != nil {
-		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-func drawBarcodeTextCentered(img *image.RGBA, text string, y int, canvasWidth int) {
-	face := basicfont.Face7x13
-	textWidth := len(text) * 7
-	x := (canvasWidth - textWidth) / 2
+func drawBarcodeTextInRegion(img *image.RGBA, tf *textFace, text string, y int, regionX int, regionWidth int, c color.Color) {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	textWidth := font.MeasureString(tf.face, text).Ceil()
+	x := regionX + (regionWidth-textWidth)/2
 	if x < 0 {
 		x = 0
 	}
 
 	d := &font.Drawer{
 		Dst:  img,
-		Src:  image.NewUniform(color.Black),
-		Face: face,
+		Src:  image.NewUniform(c),
+		Face: tf.face,
 		Dot: fixed.Point26_6{
 			X: fixed.I(x),
 			Y: fixed.I(y),
@@ -292,47 +404,25 @@ func drawBarcodeTextCentered(img *image.RGBA, text string, y int, canvasWidth in
 	d.DrawString(text)
 }
 
-func renderBarcodeSVG(bc barcode.Barcode, width, height int, includeText bool, text string) ([]byte, error) {
-	bounds := bc.Bounds()
-	bcWidth := bounds.Max.X - bounds.Min.X
-
-	totalHeight := height
-	barcodeHeight := height
-	if includeText {
-		totalHeight = height + textPaddingHeight
-	}
-
-	scaleX := float64(width) / float64(bcWidth)
+func renderBarcodeSVG(sym *barcode.Symbol, req models.GenerateRequest, text barcodeText, colors barcodeColors) ([]byte, error) {
+	layout := newBarcodeLayout(req, text.face)
 
 	var buf bytes.Buffer
-	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, totalHeight, width, totalHeight)
-	buf.WriteByte('\n')
-	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="white"/>`, width, totalHeight)
+	writeBarcodeSVGStart(&buf, layout.canvasWidth, layout.canvasHeight, BarcodeAccessibleName(req))
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, layout.canvasWidth, layout.canvasHeight, colors.background.svg)
 	buf.WriteByte('\n')
 
-	y := bounds.Min.Y
-	startX := -1
-	for x := bounds.Min.X; x <= bounds.Max.X; x++ {
-		isBar := false
-		if x < bounds.Max.X {
-			r, _, _, _ := bc.At(x, y).RGBA()
-			isBar = (r == 0)
-		}
-		if isBar && startX == -1 {
-			startX = x - bounds.Min.X
-		} else if !isBar && startX != -1 {
-			svgX := float64(startX) * scaleX
-			svgW := float64(x-bounds.Min.X-startX) * scaleX
-			fmt.Fprintf(&buf, `<rect x="%.2f" y="0" width="%.2f" height="%d" fill="black"/>`, svgX, svgW, barcodeHeight)
-			buf.WriteByte('\n')
-			startX = -1
-		}
-	}
+	sym.WriteSVGBarsFill(&buf, layout.barsX, layout.barsY, req.Width, req.Height, colors.foreground.svg)
 
-	if includeText {
-		textY := barcodeHeight + textPaddingHeight - 2
-		fmt.Fprintf(&buf, `<text x="%d" y="%d" text-anchor="middle" font-family="monospace" font-size="12" fill="black">%s</text>`,
-			width/2, textY, barcodeSVGEscape(text))
+	if req.IncludeText {
+		// SVG text stays in reading order: the viewer applies the bidi
+		// algorithm and shapes it, given the paragraph direction
+		direction := ""
+		if text.rtl {
+			direction = ` direction="rtl" unicode-bidi="embed"`
+		}
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" text-anchor="middle"%s font-family="%s" font-size="%d" fill="%s">%s</text>`,
+			layout.barsX+req.Width/2, layout.textBaseline+2, direction, svgmeta.Escape(text.face.family), text.face.size, colors.text.svg, svgmeta.Escape(text.logical))
 		buf.WriteByte('\n')
 	}
 
@@ -340,12 +430,14 @@ func renderBarcodeSVG(bc barcode.Barcode, width, height int, includeText bool, t
 	return buf.Bytes(), nil
 }
 
-func barcodeSVGEscape(s string) string {
-	s = strings.ReplaceAll(s, "&", "&amp;")
-	s = strings.ReplaceAll(s, "<", "&lt;")
-	s = strings.ReplaceAll(s, ">", "&gt;")
-	s = strings.ReplaceAll(s, "\"", "&quot;")
-	s = strings.ReplaceAll(s, "'", "&apos;")
-	return s
+// writeBarcodeSVGStart writes the root element of a width x height
+// barcode SVG with its accessible name, one element per line
+func writeBarcodeSVGStart(buf *bytes.Buffer, width, height int, meta svgmeta.Metadata) {
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d"%s>`,
+		width, height, width, height, meta.RootAttributes())
+	buf.WriteByte('\n')
+	if !meta.IsZero() {
+		meta.WriteElements(buf)
+		buf.WriteByte('\n')
+	}
 }
-
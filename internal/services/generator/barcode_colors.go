@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// minBarcodeContrast is the lowest WCAG contrast ratio accepted between
+// the bars and the background. Scanners read the difference in
+// reflectance, so pairs closer than this stop scanning reliably; black on
+// white is 21.
+const minBarcodeContrast = 3.0
+
+var (
+	ErrInvalidBackgroundColor = errors.New("invalid background_color")
+	ErrInvalidForegroundColor = errors.New("invalid foreground_color")
+	ErrInvalidTextColor       = errors.New("invalid text_color")
+	// ErrLowContrast is returned for foreground and background colors too
+	// close for scanners to tell the bars from the gaps
+	ErrLowContrast = errors.New("foreground_color and background_color are too close to scan")
+)
+
+// barcodeColor is a color of a barcode request, with the spelling SVG
+// output uses for it
+type barcodeColor struct {
+	rgba color.RGBA
+	svg  string
+}
+
+// namedBarcodeColors are the color names accepted besides hex values, a
+// small set of CSS names SVG understands as they are
+var namedBarcodeColors = map[string]color.RGBA{
+	"black":  {0, 0, 0, 255},
+	"white":  {255, 255, 255, 255},
+	"gray":   {128, 128, 128, 255},
+	"grey":   {128, 128, 128, 255},
+	"red":    {255, 0, 0, 255},
+	"maroon": {128, 0, 0, 255},
+	"green":  {0, 128, 0, 255},
+	"navy":   {0, 0, 128, 255},
+	"blue":   {0, 0, 255, 255},
+	"purple": {128, 0, 128, 255},
+	"teal":   {0, 128, 128, 255},
+	"yellow": {255, 255, 0, 255},
+	"orange": {255, 165, 0, 255},
+}
+
+var (
+	barcodeBackground = barcodeColor{namedBarcodeColors["white"], "white"}
+	barcodeForeground = barcodeColor{namedBarcodeColors["black"], "black"}
+)
+
+// barcodeColors are the colors a barcode is drawn with
+type barcodeColors struct {
+	background, foreground, text barcodeColor
+}
+
+// defaultBarcodeColors draws black bars and text on white
+var defaultBarcodeColors = barcodeColors{barcodeBackground, barcodeForeground, barcodeForeground}
+
+// parseBarcodeColors returns the colors of req: black on white by
+// default, with the text in the bar color unless text_color is set. The
+// bars must contrast with the background by at least minBarcodeContrast.
+func parseBarcodeColors(req models.GenerateRequest) (barcodeColors, error) {
+	colors := defaultBarcodeColors
+	var err error
+	if colors.background, err = parseBarcodeColor(req.BackgroundColor, barcodeBackground, ErrInvalidBackgroundColor); err != nil {
+		return barcodeColors{}, err
+	}
+	if colors.foreground, err = parseBarcodeColor(req.ForegroundColor, barcodeForeground, ErrInvalidForegroundColor); err != nil {
+		return barcodeColors{}, err
+	}
+	if colors.text, err = parseBarcodeColor(req.TextColor, colors.foreground, ErrInvalidTextColor); err != nil {
+		return barcodeColors{}, err
+	}
+	if ratio := contrastRatio(colors.foreground.rgba, colors.background.rgba); ratio < minBarcodeContrast {
+		return barcodeColors{}, fmt.Errorf("%w: %w: contrast is %.1f:1, at least %.0f:1 is required",
+			ErrInvalidData, ErrLowContrast, ratio, minBarcodeContrast)
+	}
+	return colors, nil
+}
+
+// parseBarcodeColor parses #RGB, #RRGGBB or a name of namedBarcodeColors,
+// returning def for "" and an error wrapping ErrInvalidData and fieldErr
+// for anything else
+func parseBarcodeColor(value string, def barcodeColor, fieldErr error) (barcodeColor, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return def, nil
+	}
+	name := strings.ToLower(value)
+	if rgba, ok := namedBarcodeColors[name]; ok {
+		return barcodeColor{rgba, name}, nil
+	}
+	hex, ok := strings.CutPrefix(name, "#")
+	if ok && len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if ok && len(hex) == 6 {
+		if n, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			rgba := color.RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 255}
+			return barcodeColor{rgba, "#" + hex}, nil
+		}
+	}
+	return barcodeColor{}, fmt.Errorf("%w: %w: %q must be #RGB, #RRGGBB or one of %s",
+		ErrInvalidData, fieldErr, value, strings.Join(barcodeColorNames(), ", "))
+}
+
+// barcodeColorNames returns the accepted color names, sorted
+func barcodeColorNames() []string {
+	names := make([]string, 0, len(namedBarcodeColors))
+	for name := range namedBarcodeColors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contrastRatio returns the WCAG 2 contrast ratio of a and b, from 1 for
+// the same color to 21 for black and white
+func contrastRatio(a, b color.RGBA) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	return (max(la, lb) + 0.05) / (min(la, lb) + 0.05)
+}
+
+// relativeLuminance returns the WCAG 2 relative luminance of c
+func relativeLuminance(c color.RGBA) float64 {
+	linear := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(c.R) + 0.7152*linear(c.G) + 0.0722*linear(c.B)
+}
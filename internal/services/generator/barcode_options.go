@@ -0,0 +1,272 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/warnings"
+)
+
+const (
+	TextPositionBottom = "bottom"
+	TextPositionTop    = "top"
+
+	BarcodeRuleError   = "error"
+	BarcodeRuleWarning = "warning"
+
+	maxBarcodePadding = 200
+	minTextBarsHeight = 70
+
+	// GS1 nominal EAN-13/UPC-A bars are 22.85 mm high over a 31.35 mm
+	// symbol (95 modules of 0.33 mm); the ratio holds at every magnification
+	eanNominalBarHeight = 2285
+	eanNominalBarsWidth = 3135
+)
+
+// ErrInvalidOptions is wrapped by BarcodeOptionError
+var ErrInvalidOptions = errors.New("invalid barcode option combination")
+
+// BarcodeOptionRule describes an option combination the generator rejects
+// (error) or accepts while ignoring part of it (warning).
+type BarcodeOptionRule struct {
+	ID       string   `json:"id"`
+	Fields   []string `json:"fields"`
+	Severity string   `json:"severity"`
+	Message  string   `json:"message"`
+
+	violated func(req models.GenerateRequest) bool
+}
+
+// BarcodeOptionError lists the error rules a request violates
+type BarcodeOptionError struct {
+	Violations []models.BarcodeOptionViolation
+}
+
+func (e *BarcodeOptionError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidOptions, strings.Join(msgs, "; "))
+}
+
+func (e *BarcodeOptionError) Unwrap() error {
+	return ErrInvalidOptions
+}
+
+// barcodeOptionRules is the single source for option combination checks.
+// Rules are evaluated after defaults are applied and the data is validated.
+var barcodeOptionRules = []BarcodeOptionRule{
+	{
+		ID:       "text_position_value",
+		Fields:   []string{"text_position"},
+		Severity: BarcodeRuleError,
+		Message:  "text_position must be top or bottom",
+		violated: func(req models.GenerateRequest) bool {
+			return req.TextPosition != "" && req.TextPosition != TextPositionTop && req.TextPosition != TextPositionBottom
+		},
+	},
+	{
+		ID:       "text_position_requires_text",
+		Fields:   []string{"text_position", "include_text"},
+		Severity: BarcodeRuleError,
+		Message:  "text_position requires include_text",
+		violated: func(req models.GenerateRequest) bool {
+			return req.TextPosition != "" && !req.IncludeText
+		},
+	},
+	{
+		ID:       "text_requires_include_text",
+		Fields:   []string{"text", "include_text"},
+		Severity: BarcodeRuleError,
+		Message:  "text requires include_text",
+		violated: func(req models.GenerateRequest) bool {
+			return req.Text != "" && !req.IncludeText
+		},
+	},
+	{
+		ID:       "pharmacode_text",
+		Fields:   []string{"type", "include_text"},
+		Severity: BarcodeRuleError,
+		Message:  "Pharmacode has no human-readable text; include_text must be false",
+		violated: func(req models.GenerateRequest) bool {
+			return req.Type == BarcodeTypePharmacode && req.IncludeText
+		},
+	},
+	{
+		ID:       "supplement_isbn_only",
+		Fields:   []string{"type", "supplement"},
+		Severity: BarcodeRuleError,
+		Message:  "supplement is only supported for ISBN",
+		violated: func(req models.GenerateRequest) bool {
+			return req.Supplement != "" && req.Type != BarcodeTypeISBN
+		},
+	},
+	{
+		ID:       "check_digit_code39_only",
+		Fields:   []string{"type", "include_check_digit"},
+		Severity: BarcodeRuleError,
+		Message:  "include_check_digit is only supported for Code39; the other types always carry their check digit",
+		violated: func(req models.GenerateRequest) bool {
+			return req.IncludeCheckDigit && req.Type != BarcodeTypeCode39
+		},
+	},
+	{
+		ID:       "isbn_fixed_layout",
+		Fields:   []string{"type", "padding", "text_position"},
+		Severity: BarcodeRuleError,
+		Message:  "ISBN uses a fixed layout; padding and text_position top are not supported",
+		violated: func(req models.GenerateRequest) bool {
+			return req.Type == BarcodeTypeISBN && (req.Padding != 0 || req.TextPosition == TextPositionTop)
+		},
+	},
+	{
+		ID:       "padding_range",
+		Fields:   []string{"padding"},
+		Severity: BarcodeRuleError,
+		Message:  fmt.Sprintf("padding must be between 0 and %d", maxBarcodePadding),
+		violated: func(req models.GenerateRequest) bool {
+			return req.Padding < 0 || req.Padding > maxBarcodePadding
+		},
+	},
+	{
+		ID:       "padding_canvas",
+		Fields:   []string{"padding", "width", "height"},
+		Severity: BarcodeRuleError,
+		Message:  fmt.Sprintf("width and height plus padding on both sides must not exceed %dx%d", maxBarcodeWidth, maxBarcodeHeight),
+		violated: func(req models.GenerateRequest) bool {
+			return req.Width+2*req.Padding > maxBarcodeWidth || req.Height+2*req.Padding > maxBarcodeHeight
+		},
+	},
+	{
+		ID:       "fit_mode_value",
+		Fields:   []string{"fit_mode"},
+		Severity: BarcodeRuleError,
+		Message:  "fit_mode must be snap or strict",
+		violated: func(req models.GenerateRequest) bool {
+			return req.FitMode != BarcodeFitSnap && req.FitMode != BarcodeFitStrict
+		},
+	},
+	{
+		ID:       "ean_height_ratio",
+		Fields:   []string{"type", "height", "width"},
+		Severity: BarcodeRuleWarning,
+		Message:  "bars are shorter than the GS1 minimum of 73% of the symbol width for EAN-13 and UPC-A; some scanners may reject them",
+		violated: func(req models.GenerateRequest) bool {
+			ean := req.Type == BarcodeTypeEAN13 || req.Type == BarcodeTypeUPCA || (req.Type == BarcodeTypeISBN && req.Supplement == "")
+			return ean && req.Height*eanNominalBarsWidth < req.Width*eanNominalBarHeight
+		},
+	},
+	{
+		ID:       "short_bars_with_text",
+		Fields:   []string{"include_text", "height"},
+		Severity: BarcodeRuleWarning,
+		Message:  fmt.Sprintf("height below %d with include_text leaves short bars that some scanners reject", minTextBarsHeight),
+		violated: func(req models.GenerateRequest) bool {
+			return req.IncludeText && req.Height < minTextBarsHeight
+		},
+	},
+	{
+		ID:       "font_size_range",
+		Fields:   []string{"font_size"},
+		Severity: BarcodeRuleError,
+		Message:  fmt.Sprintf("font_size must be between %d and %d", minFontSize, maxFontSize),
+		violated: func(req models.GenerateRequest) bool {
+			return req.FontSize != 0 && (req.FontSize < minFontSize || req.FontSize > maxFontSize)
+		},
+	},
+	{
+		ID:       "font_requires_text",
+		Fields:   []string{"font", "font_size", "include_text"},
+		Severity: BarcodeRuleError,
+		Message:  "font and font_size require include_text",
+		violated: func(req models.GenerateRequest) bool {
+			return (req.Font != "" || req.FontSize != 0) && !req.IncludeText
+		},
+	},
+	{
+		ID:       "isbn_builtin_font",
+		Fields:   []string{"type", "font", "font_size"},
+		Severity: BarcodeRuleError,
+		Message:  "ISBN labels use the built-in font; font and font_size are not supported",
+		violated: func(req models.GenerateRequest) bool {
+			return req.Type == BarcodeTypeISBN && (req.Font != "" || req.FontSize != 0)
+		},
+	},
+	{
+		ID:       "accessible_name_length",
+		Fields:   []string{"title", "desc"},
+		Severity: BarcodeRuleError,
+		Message:  fmt.Sprintf("title and desc must be at most %d characters", maxAccessibleTextLength),
+		violated: func(req models.GenerateRequest) bool {
+			return validateAccessibleText(req.Title, req.Desc) != nil
+		},
+	},
+	{
+		ID:       "accessible_name_svg_only",
+		Fields:   []string{"title", "desc", "format"},
+		Severity: BarcodeRuleWarning,
+		Message:  "title and desc only apply to svg output and were ignored",
+		violated: func(req models.GenerateRequest) bool {
+			return (req.Title != "" || req.Desc != "") && req.Format != BarcodeFormatSVG
+		},
+	},
+	{
+		ID:       "isbn_text",
+		Fields:   []string{"type", "text"},
+		Severity: BarcodeRuleError,
+		Message:  "ISBN labels print the ISBN itself; text is not supported",
+		violated: func(req models.GenerateRequest) bool {
+			return req.Type == BarcodeTypeISBN && req.Text != ""
+		},
+	},
+}
+
+// BarcodeOptionRules returns the option combination rules so clients can
+// disable invalid combinations before sending a request.
+func BarcodeOptionRules() []BarcodeOptionRule {
+	return barcodeOptionRules
+}
+
+// BarcodeWarningCodes returns the warning codes the option rules can
+// produce, plus text_glyphs_missing from the text layout
+func BarcodeWarningCodes() []string {
+	var codes []string
+	for _, rule := range barcodeOptionRules {
+		if rule.Severity == BarcodeRuleWarning {
+			codes = append(codes, rule.ID)
+		}
+	}
+	return append(codes, warnings.CodeTextGlyphsMissing)
+}
+
+// checkBarcodeOptions returns a BarcodeOptionError for violated error rules
+// and a warning, coded by rule ID, for each violated warning rule.
+func checkBarcodeOptions(req models.GenerateRequest) ([]models.Warning, error) {
+	var violations []models.BarcodeOptionViolation
+	var warnings []models.Warning
+	for _, rule := range barcodeOptionRules {
+		if !rule.violated(req) {
+			continue
+		}
+		if rule.Severity == BarcodeRuleWarning {
+			warning := models.Warning{Code: rule.ID, Message: rule.Message}
+			if len(rule.Fields) == 1 {
+				warning.Field = rule.Fields[0]
+			}
+			warnings = append(warnings, warning)
+			continue
+		}
+		violations = append(violations, models.BarcodeOptionViolation{
+			Rule:    rule.ID,
+			Fields:  rule.Fields,
+			Message: rule.Message,
+		})
+	}
+	if len(violations) > 0 {
+		return warnings, &BarcodeOptionError{Violations: violations}
+	}
+	return warnings, nil
+}
@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// eanRequest returns a valid EAN-13 request for the rules to modify
+func eanRequest(modify func(*models.GenerateRequest)) models.GenerateRequest {
+	req := models.GenerateRequest{Type: BarcodeTypeEAN13, Data: "400638133393", Format: BarcodeFormatPNG, Width: 285, Height: 220}
+	modify(&req)
+	return req
+}
+
+// TestBarcodeOptionRules sends one request per rule that violates it and
+// nothing else: error rules fail the request, warning rules are reported
+// while the barcode is still generated
+func TestBarcodeOptionRules(t *testing.T) {
+	triggers := map[string]models.GenerateRequest{
+		"text_position_value":         eanRequest(func(r *models.GenerateRequest) { r.IncludeText, r.TextPosition = true, "left" }),
+		"text_position_requires_text": eanRequest(func(r *models.GenerateRequest) { r.TextPosition = TextPositionTop }),
+		"text_requires_include_text":  eanRequest(func(r *models.GenerateRequest) { r.Text = "label" }),
+		"pharmacode_text":             {Type: BarcodeTypePharmacode, Data: "1234", Format: BarcodeFormatPNG, IncludeText: true},
+		"supplement_isbn_only":        eanRequest(func(r *models.GenerateRequest) { r.Supplement = "12" }),
+		"check_digit_code39_only":     eanRequest(func(r *models.GenerateRequest) { r.IncludeCheckDigit = true }),
+		"isbn_fixed_layout":           {Type: BarcodeTypeISBN, Data: "9783161484100", Format: BarcodeFormatPNG, Height: 200, Padding: 10},
+		"padding_range":               eanRequest(func(r *models.GenerateRequest) { r.Padding = -1 }),
+		"padding_canvas":              eanRequest(func(r *models.GenerateRequest) { r.Width, r.Padding = 1000, 20 }),
+		"short_bars_with_text":        {Type: BarcodeTypeCode128, Data: "ABC", Format: BarcodeFormatPNG, IncludeText: true, Height: 60},
+		"font_size_range":             eanRequest(func(r *models.GenerateRequest) { r.IncludeText, r.FontSize = true, 100 }),
+		"font_requires_text":          eanRequest(func(r *models.GenerateRequest) { r.FontSize = 12 }),
+		"isbn_builtin_font":           {Type: BarcodeTypeISBN, Data: "9783161484100", Format: BarcodeFormatPNG, Height: 200, IncludeText: true, FontSize: 12},
+		"isbn_text":                   {Type: BarcodeTypeISBN, Data: "9783161484100", Format: BarcodeFormatPNG, Height: 200, IncludeText: true, Text: "custom"},
+		"accessible_name_length":      eanRequest(func(r *models.GenerateRequest) { r.Format, r.Title = BarcodeFormatSVG, strings.Repeat("t", 501) }),
+		"accessible_name_svg_only":    eanRequest(func(r *models.GenerateRequest) { r.Title = "Product code" }),
+		"fit_mode_value":              eanRequest(func(r *models.GenerateRequest) { r.FitMode = "stretch" }),
+		"ean_height_ratio":            eanRequest(func(r *models.GenerateRequest) { r.Height = 100 }),
+	}
+
+	service := NewDefaultBarcodeService()
+	for _, rule := range BarcodeOptionRules() {
+		req, ok := triggers[rule.ID]
+		if !ok {
+			t.Errorf("rule %s has no triggering request", rule.ID)
+			continue
+		}
+		delete(triggers, rule.ID)
+
+		_, err := service.Generate(req)
+		var optErr *BarcodeOptionError
+		switch rule.Severity {
+		case BarcodeRuleError:
+			if !errors.As(err, &optErr) || len(optErr.Violations) != 1 || optErr.Violations[0].Rule != rule.ID {
+				t.Errorf("%s: err = %v, want this rule only", rule.ID, err)
+				continue
+			}
+			if v := optErr.Violations[0]; v.Message != rule.Message || len(v.Fields) != len(rule.Fields) {
+				t.Errorf("%s: violation = %+v", rule.ID, v)
+			}
+		case BarcodeRuleWarning:
+			if err != nil {
+				t.Errorf("%s: err = %v, want the barcode generated", rule.ID, err)
+				continue
+			}
+			var codes []string
+			for _, w := range service.Warnings(req) {
+				codes = append(codes, w.Code)
+			}
+			if len(codes) != 1 || codes[0] != rule.ID {
+				t.Errorf("%s: warnings = %v, want this rule only", rule.ID, codes)
+			}
+		default:
+			t.Errorf("%s: unknown severity %q", rule.ID, rule.Severity)
+		}
+	}
+	for id := range triggers {
+		t.Errorf("no rule %s", id)
+	}
+
+	// A request violating nothing passes without warnings
+	req := eanRequest(func(*models.GenerateRequest) {})
+	if _, err := service.Generate(req); err != nil {
+		t.Errorf("valid request: %v", err)
+	}
+	if w := service.Warnings(req); len(w) != 0 {
+		t.Errorf("valid request warnings = %+v", w)
+	}
+}
+
+func TestBarcodeOptionRulesReportEveryViolation(t *testing.T) {
+	req := eanRequest(func(r *models.GenerateRequest) { r.TextPosition, r.Padding = TextPositionTop, -1 })
+	_, err := NewDefaultBarcodeService().Generate(req)
+	var optErr *BarcodeOptionError
+	if !errors.As(err, &optErr) || len(optErr.Violations) != 2 || !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("err = %v, want two violations", err)
+	}
+}
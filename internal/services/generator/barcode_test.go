@@ -0,0 +1,210 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func TestGenerateLinearTypes(t *testing.T) {
+	service := NewDefaultBarcodeService()
+	for _, req := range []models.GenerateRequest{
+		{Type: BarcodeTypePharmacode, Data: "1234", Format: BarcodeFormatPNG},
+		{Type: BarcodeTypePharmacode, Data: "1234", Format: BarcodeFormatSVG},
+		{Type: BarcodeTypeCode93, Data: "Order #42", Format: BarcodeFormatPNG, IncludeText: true},
+		{Type: BarcodeTypeCode93, Data: "Order #42", Format: BarcodeFormatSVG, IncludeText: true},
+		{Type: BarcodeTypeCode39, Data: "*PART-42*", Format: BarcodeFormatPNG, IncludeText: true, IncludeCheckDigit: true},
+		{Type: BarcodeTypeCode39, Data: "PART-42", Format: BarcodeFormatSVG},
+		{Type: BarcodeTypeITF14, Data: "1540014128876", Format: BarcodeFormatPNG, IncludeText: true},
+		{Type: BarcodeTypeITF14, Data: "15400141288763", Format: BarcodeFormatSVG},
+	} {
+		img, err := service.Generate(req)
+		if err != nil {
+			t.Errorf("%s %s: %v", req.Type, req.Format, err)
+			continue
+		}
+		if len(img.Data) == 0 || !strings.HasPrefix(img.ContentType, "image/") {
+			t.Errorf("%s %s: %d bytes of %s", req.Type, req.Format, len(img.Data), img.ContentType)
+		}
+	}
+
+	// Pharmacode has no human-readable line
+	_, err := service.Generate(models.GenerateRequest{Type: BarcodeTypePharmacode, Data: "1234", Format: BarcodeFormatPNG, IncludeText: true})
+	var optErr *BarcodeOptionError
+	if !errors.As(err, &optErr) || len(optErr.Violations) != 1 || optErr.Violations[0].Rule != "pharmacode_text" {
+		t.Errorf("Pharmacode with include_text: err = %v, want the pharmacode_text rule", err)
+	}
+}
+
+func TestBarcodeFitMode(t *testing.T) {
+	service := NewDefaultBarcodeService()
+	// 24 characters of Code 128 set B take 299 modules
+	long := models.GenerateRequest{Type: BarcodeTypeCode128, Data: "ABCDEFGHIJKLMNOPQRSTUVWX", Format: BarcodeFormatPNG, Width: 200}
+
+	strict := long
+	strict.FitMode = BarcodeFitStrict
+	if _, err := service.Generate(strict); !errors.Is(err, ErrInvalidData) || !strings.Contains(err.Error(), "at least 299") {
+		t.Errorf("strict: err = %v, want the minimum width of 299", err)
+	}
+	strict.Width = 299
+	if img, err := service.Generate(strict); err != nil || img.Width != 299 {
+		t.Errorf("strict at the minimum: width %d, %v", img.Width, err)
+	}
+
+	tests := []struct {
+		req     models.GenerateRequest
+		width   int
+		modules int
+	}{
+		// Too narrow snaps up to one pixel per module
+		{long, 299, 299},
+		{func() models.GenerateRequest { r := long; r.Width = 700; return r }(), 598, 299},
+		// 300 pixels rounds to 3 per module
+		{models.GenerateRequest{Type: BarcodeTypeEAN13, Data: "400638133393", Format: BarcodeFormatPNG, Width: 300}, 285, 95},
+		{models.GenerateRequest{Type: BarcodeTypeEAN13, Data: "400638133393", Format: BarcodeFormatPNG, Width: 340}, 380, 95},
+	}
+	for _, tt := range tests {
+		img, err := service.Generate(tt.req)
+		if err != nil {
+			t.Fatalf("%s at %d: %v", tt.req.Type, tt.req.Width, err)
+		}
+		if img.Width != tt.width {
+			t.Errorf("%s at %d: width %d, want %d", tt.req.Type, tt.req.Width, img.Width, tt.width)
+		}
+		assertCrispBars(t, img.Data, tt.width/tt.modules)
+	}
+}
+
+// assertCrispBars checks that every column of the bar area is a single
+// pure color and every bar and space is a whole number of modules of
+// module pixels, so no bar edge is blurred or uneven
+func assertCrispBars(t *testing.T, data []byte, module int) {
+	t.Helper()
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := decoded.Bounds()
+	run, previous := 0, uint32(0)
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		top, _, _, _ := decoded.At(x, bounds.Min.Y).RGBA()
+		if top != 0 && top != 0xffff {
+			t.Fatalf("column %d is gray", x)
+		}
+		for y := bounds.Min.Y; y < bounds.Min.Y+bounds.Dy()/2; y++ {
+			if r, _, _, _ := decoded.At(x, y).RGBA(); r != top {
+				t.Fatalf("column %d changes color at row %d", x, y)
+			}
+		}
+		if x > bounds.Min.X && top != previous {
+			if run%module != 0 {
+				t.Fatalf("a %d-pixel run ends at column %d, not a multiple of %d", run, x, module)
+			}
+			run = 0
+		}
+		run, previous = run+1, top
+	}
+}
+
+// TestBarcodeColors decodes the PNGs and checks the requested colors are
+// the ones drawn
+func TestBarcodeColors(t *testing.T) {
+	var (
+		cream  = color.NRGBA{0xff, 0xff, 0xdd, 0xff}
+		navy   = color.NRGBA{0, 0, 0x80, 0xff}
+		blue   = color.NRGBA{0, 0x33, 0x66, 0xff}
+		maroon = color.NRGBA{0x80, 0, 0, 0xff}
+		green  = color.NRGBA{0, 0x80, 0, 0xff}
+		white  = color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	)
+	service := NewDefaultBarcodeService()
+	tests := []struct {
+		name string
+		req  models.GenerateRequest
+		// want are colors drawn; only, when set, means no others are
+		want []color.NRGBA
+		only bool
+	}{
+		{"bars only", eanRequest(func(r *models.GenerateRequest) {
+			r.BackgroundColor, r.ForegroundColor, r.Padding = "#FFD", "Navy", 10
+		}), []color.NRGBA{cream, navy}, true},
+		{"text color", eanRequest(func(r *models.GenerateRequest) {
+			r.ForegroundColor, r.TextColor, r.IncludeText = "#003366", "maroon", true
+		}), []color.NRGBA{white, blue, maroon}, false},
+		{"text follows the bars", models.GenerateRequest{Type: BarcodeTypeISBN, Data: "9783161484100", Format: BarcodeFormatPNG, Height: 200,
+			IncludeText: true, ForegroundColor: "green"}, []color.NRGBA{white, green}, false},
+		{"deterministic", eanRequest(func(r *models.GenerateRequest) {
+			r.BackgroundColor, r.ForegroundColor, r.Deterministic = "#ffffdd", "#000080", true
+		}), []color.NRGBA{cream, navy}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := service.Generate(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := png.Decode(bytes.NewReader(img.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			drawn := map[color.NRGBA]bool{}
+			bounds := decoded.Bounds()
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					drawn[color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)] = true
+				}
+			}
+			for _, c := range tt.want {
+				if !drawn[c] {
+					t.Errorf("no pixel of %v", c)
+				}
+			}
+			if tt.only && len(drawn) != len(tt.want) {
+				t.Errorf("%d colors drawn, want %v", len(drawn), tt.want)
+			}
+			if drawn[color.NRGBA{0, 0, 0, 0xff}] {
+				t.Error("black pixels drawn")
+			}
+		})
+	}
+
+	svg, err := service.Generate(eanRequest(func(r *models.GenerateRequest) {
+		r.Format, r.BackgroundColor, r.ForegroundColor, r.TextColor, r.IncludeText = BarcodeFormatSVG, "#FFD", "navy", "#800000", true
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fill := range []string{`<rect width="285" height="`, `fill="#ffffdd"`, `fill="navy"/>`, `fill="#800000">`} {
+		if !bytes.Contains(svg.Data, []byte(fill)) {
+			t.Errorf("SVG lacks %s:\n%s", fill, svg.Data)
+		}
+	}
+	if bytes.Contains(svg.Data, []byte(`"black"`)) || bytes.Contains(svg.Data, []byte(`"white"`)) {
+		t.Errorf("SVG still draws black on white:\n%s", svg.Data)
+	}
+
+	errorTests := []struct {
+		name  string
+		req   models.GenerateRequest
+		field error
+	}{
+		{"short hex", eanRequest(func(r *models.GenerateRequest) { r.ForegroundColor = "#12" }), ErrInvalidForegroundColor},
+		{"bad hex digit", eanRequest(func(r *models.GenerateRequest) { r.BackgroundColor = "#ffffzz" }), ErrInvalidBackgroundColor},
+		{"unknown name", eanRequest(func(r *models.GenerateRequest) { r.TextColor = "chartreuse" }), ErrInvalidTextColor},
+		{"no hash", eanRequest(func(r *models.GenerateRequest) { r.ForegroundColor = "000000" }), ErrInvalidForegroundColor},
+		{"close grays", eanRequest(func(r *models.GenerateRequest) { r.ForegroundColor, r.BackgroundColor = "#777", "#888" }), ErrLowContrast},
+		{"yellow on white", eanRequest(func(r *models.GenerateRequest) { r.ForegroundColor = "yellow" }), ErrLowContrast},
+		{"same color", eanRequest(func(r *models.GenerateRequest) { r.ForegroundColor, r.BackgroundColor = "navy", "#000080" }), ErrLowContrast},
+		{"ISBN", models.GenerateRequest{Type: BarcodeTypeISBN, Data: "9783161484100", Format: BarcodeFormatSVG, Height: 200, BackgroundColor: "black"}, ErrLowContrast},
+	}
+	for _, tt := range errorTests {
+		if _, err := service.Generate(tt.req); !errors.Is(err, ErrInvalidData) || !errors.Is(err, tt.field) {
+			t.Errorf("%s: err = %v, want ErrInvalidData and %v", tt.name, err, tt.field)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package generator
+
+import (
+	"slices"
+	"unicode"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/warnings"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// FallbackTextFont is the embedded font barcode text switches to when the
+// requested face has no glyphs for it, e.g. Arabic or Hebrew in the
+// built-in bitmap font
+const FallbackTextFont = "dejavu-sans"
+
+// barcodeText is the human-readable line of a barcode, resolved to the
+// face that draws it
+type barcodeText struct {
+	face *textFace
+	// logical is the text in reading order, as SVG output writes it
+	logical string
+	// visual is logical shaped and reordered for drawing left to right
+	visual string
+	// rtl is set when the paragraph direction is right to left
+	rtl bool
+	// glyphsMissing is set when neither face covers the requested text
+	// and the barcode data is drawn instead
+	glyphsMissing bool
+}
+
+// barcodeText resolves the text line of req: the text field, or the data
+// when it is empty. Text the requested face cannot draw switches to
+// FallbackTextFont at the same size; when that lacks glyphs too, the data
+// is drawn with the requested face.
+func (s *defaultBarcodeService) barcodeText(req models.GenerateRequest) (barcodeText, error) {
+	face, err := s.fonts.textFaceFor(req.Font, req.FontSize)
+	if err != nil {
+		return barcodeText{}, err
+	}
+	logical := req.Text
+	if logical == "" {
+		logical = req.Data
+	}
+
+	line := newBarcodeText(face, logical)
+	if face.covers(line.visual) {
+		return line, nil
+	}
+	fallback, err := s.fonts.face(FallbackTextFont, max(face.size, minFontSize))
+	if err != nil {
+		return barcodeText{}, err
+	}
+	if fallback.covers(line.visual) {
+		line.face = fallback
+		return line, nil
+	}
+
+	line = newBarcodeText(face, req.Data)
+	line.glyphsMissing = true
+	return line, nil
+}
+
+// textWarnings returns the warning for text that could not be drawn
+func (t barcodeText) textWarnings() []models.Warning {
+	if !t.glyphsMissing {
+		return nil
+	}
+	return []models.Warning{{
+		Code:    warnings.CodeTextGlyphsMissing,
+		Field:   "text",
+		Message: "no available font has glyphs for every character of the text; the barcode data is shown instead",
+	}}
+}
+
+func newBarcodeText(face *textFace, logical string) barcodeText {
+	visual, rtl := visualOrder(shapeArabic(logical))
+	return barcodeText{face: face, logical: logical, visual: visual, rtl: rtl}
+}
+
+// covers reports whether the face has a glyph for every rune of s
+func (tf *textFace) covers(s string) bool {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+	for _, r := range s {
+		if _, ok := tf.face.GlyphAdvance(r); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// visualOrder reorders s from reading order to the left to right order it
+// is drawn in, and reports whether the paragraph is right to left. Levels
+// are resolved with the Unicode bidi algorithm for a single line without
+// explicit embeddings (UAX #9 rules P2-P3, W1-W7, N1-N2, I1-I2 and L1),
+// then runs are reversed from the highest level down (L2) and brackets at
+// odd levels are mirrored (L4). The bidi package offers no levels, only
+// runs, which cannot express numbers nested in right-to-left text. Text
+// without right-to-left characters is returned as is.
+func visualOrder(s string) (string, bool) {
+	if !hasRTL(s) {
+		return s, false
+	}
+	runes := []rune(s)
+	classes := make([]bidi.Class, len(runes))
+	for i, r := range runes {
+		props, _ := bidi.LookupRune(r)
+		classes[i] = props.Class()
+		if classes[i] > bidi.AL {
+			// Explicit formatting characters are not supported and
+			// are treated as neutrals
+			classes[i] = bidi.ON
+		}
+	}
+
+	// P2-P3: the first strong character sets the paragraph level
+	paragraph := bidi.L
+	for _, c := range classes {
+		if c == bidi.L || c == bidi.R || c == bidi.AL {
+			paragraph = c
+			break
+		}
+	}
+	level := 0
+	if paragraph != bidi.L {
+		level = 1
+		paragraph = bidi.R
+	}
+
+	// W1-W3
+	previous, strong := paragraph, paragraph
+	for i, c := range classes {
+		if c == bidi.NSM {
+			c = previous
+		}
+		switch c {
+		case bidi.L, bidi.R, bidi.AL:
+			strong = c
+		case bidi.EN:
+			if strong == bidi.AL {
+				c = bidi.AN
+			}
+		}
+		previous = c
+		if c == bidi.AL {
+			c = bidi.R
+		}
+		classes[i] = c
+	}
+	// W4: a single separator between two numbers of the same kind
+	for i := 1; i+1 < len(classes); i++ {
+		before, after := classes[i-1], classes[i+1]
+		switch {
+		case classes[i] == bidi.ES && before == bidi.EN && after == bidi.EN,
+			classes[i] == bidi.CS && before == bidi.EN && after == bidi.EN:
+			classes[i] = bidi.EN
+		case classes[i] == bidi.CS && before == bidi.AN && after == bidi.AN:
+			classes[i] = bidi.AN
+		}
+	}
+	// W5: terminators next to European numbers join them
+	for i := 0; i < len(classes); i++ {
+		if classes[i] != bidi.ET {
+			continue
+		}
+		end := i
+		for end < len(classes) && classes[end] == bidi.ET {
+			end++
+		}
+		if (i > 0 && classes[i-1] == bidi.EN) || (end < len(classes) && classes[end] == bidi.EN) {
+			for j := i; j < end; j++ {
+				classes[j] = bidi.EN
+			}
+		}
+		i = end
+	}
+	// W6-W7
+	strong = paragraph
+	for i, c := range classes {
+		switch c {
+		case bidi.ES, bidi.ET, bidi.CS:
+			classes[i] = bidi.ON
+		case bidi.L, bidi.R:
+			strong = c
+		case bidi.EN:
+			if strong == bidi.L {
+				classes[i] = bidi.L
+			}
+		}
+	}
+
+	// N1-N2: neutrals between characters of the same direction take it,
+	// numbers counting as right to left; others take the paragraph's
+	direction := func(c bidi.Class) (bidi.Class, bool) {
+		switch c {
+		case bidi.L:
+			return bidi.L, true
+		case bidi.R, bidi.EN, bidi.AN:
+			return bidi.R, true
+		}
+		return 0, false
+	}
+	for i := 0; i < len(classes); i++ {
+		if _, strong := direction(classes[i]); strong {
+			continue
+		}
+		end := i
+		for end < len(classes) {
+			if _, strong := direction(classes[end]); strong {
+				break
+			}
+			end++
+		}
+		before, after := paragraph, paragraph
+		if i > 0 {
+			before, _ = direction(classes[i-1])
+		}
+		if end < len(classes) {
+			after, _ = direction(classes[end])
+		}
+		resolved := paragraph
+		if before == after {
+			resolved = before
+		}
+		for j := i; j < end; j++ {
+			classes[j] = resolved
+		}
+		i = end
+	}
+
+	// I1-I2 and L1: trailing whitespace goes back to the paragraph level
+	levels := make([]int, len(runes))
+	highest := level
+	for i, c := range classes {
+		levels[i] = level
+		switch {
+		case level%2 == 0 && c == bidi.R:
+			levels[i]++
+		case level%2 == 0 && (c == bidi.EN || c == bidi.AN):
+			levels[i] += 2
+		case level%2 == 1 && c != bidi.R:
+			levels[i]++
+		}
+		highest = max(highest, levels[i])
+	}
+	for i := len(runes) - 1; i >= 0 && unicode.IsSpace(runes[i]); i-- {
+		levels[i] = level
+	}
+
+	// L4 before L2, so mirroring sees the levels in reading order
+	for i, r := range runes {
+		if levels[i]%2 == 1 {
+			runes[i] = []rune(bidi.ReverseString(string(r)))[0]
+		}
+	}
+	for reverse := highest; reverse >= 1; reverse-- {
+		for i := 0; i < len(runes); i++ {
+			if levels[i] < reverse {
+				continue
+			}
+			end := i
+			for end < len(runes) && levels[end] >= reverse {
+				end++
+			}
+			slices.Reverse(runes[i:end])
+			slices.Reverse(levels[i:end])
+			i = end
+		}
+	}
+	return string(runes), level == 1
+}
+
+// hasRTL reports whether s contains a strong right-to-left character
+func hasRTL(s string) bool {
+	for _, r := range s {
+		props, _ := bidi.LookupRune(r)
+		if class := props.Class(); class == bidi.R || class == bidi.AL {
+			return true
+		}
+	}
+	return false
+}
+
+// arabicForm is the range of presentation forms of an Arabic letter in
+// the Arabic Presentation Forms-B block: isolated, final, and for letters
+// joining on both sides initial and medial, in that order
+type arabicForm struct {
+	isolated rune
+	forms    int
+}
+
+const (
+	arabicTatweel = '\u0640'
+	arabicLam     = '\u0644'
+)
+
+// arabicForms covers the letters of the Arabic alphabet. Letters outside
+// it, such as the Persian and Urdu additions, are drawn in their nominal
+// form.
+var arabicForms = map[rune]arabicForm{
+	'ء': {0xFE80, 1}, 'آ': {0xFE81, 2}, 'أ': {0xFE83, 2},
+	'ؤ': {0xFE85, 2}, 'إ': {0xFE87, 2}, 'ئ': {0xFE89, 4},
+	'ا': {0xFE8D, 2}, 'ب': {0xFE8F, 4}, 'ة': {0xFE93, 2},
+	'ت': {0xFE95, 4}, 'ث': {0xFE99, 4}, 'ج': {0xFE9D, 4},
+	'ح': {0xFEA1, 4}, 'خ': {0xFEA5, 4}, 'د': {0xFEA9, 2},
+	'ذ': {0xFEAB, 2}, 'ر': {0xFEAD, 2}, 'ز': {0xFEAF, 2},
+	'س': {0xFEB1, 4}, 'ش': {0xFEB5, 4}, 'ص': {0xFEB9, 4},
+	'ض': {0xFEBD, 4}, 'ط': {0xFEC1, 4}, 'ظ': {0xFEC5, 4},
+	'ع': {0xFEC9, 4}, 'غ': {0xFECD, 4}, 'ف': {0xFED1, 4},
+	'ق': {0xFED5, 4}, 'ك': {0xFED9, 4}, 'ل': {0xFEDD, 4},
+	'م': {0xFEE1, 4}, 'ن': {0xFEE5, 4}, 'ه': {0xFEE9, 4},
+	'و': {0xFEED, 2}, 'ى': {0xFEEF, 2}, 'ي': {0xFEF1, 4},
+}
+
+// lamAlef maps the alef following a lam to the isolated form of their
+// mandatory ligature; the final form follows it
+var lamAlef = map[rune]rune{
+	'آ': 0xFEF5,
+	'أ': 0xFEF7,
+	'إ': 0xFEF9,
+	'ا': 0xFEFB,
+}
+
+// shapeArabic replaces Arabic letters with the presentation form their
+// position in the word calls for, since fonts are drawn glyph by glyph
+// without OpenType shaping. Harakat between letters do not break joining.
+func shapeArabic(s string) string {
+	runes := []rune(s)
+	if !slices.ContainsFunc(runes, func(r rune) bool { _, ok := arabicForms[r]; return ok }) {
+		return s
+	}
+
+	// joinsNext reports whether the letter at i connects to the one after
+	joinsNext := func(i int) bool {
+		if i < 0 {
+			return false
+		}
+		form, ok := arabicForms[runes[i]]
+		return runes[i] == arabicTatweel || (ok && form.forms == 4)
+	}
+	// joinsPrevious reports whether the letter at i connects to the one
+	// before
+	joinsPrevious := func(i int) bool {
+		if i >= len(runes) {
+			return false
+		}
+		form, ok := arabicForms[runes[i]]
+		return runes[i] == arabicTatweel || (ok && form.forms > 1)
+	}
+	// neighbor returns the index of the next non-haraka rune from i in
+	// direction step
+	neighbor := func(i, step int) int {
+		for i += step; i >= 0 && i < len(runes) && isHaraka(runes[i]); i += step {
+		}
+		return i
+	}
+
+	shaped := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		form, ok := arabicForms[runes[i]]
+		if !ok {
+			shaped = append(shaped, runes[i])
+			continue
+		}
+		previous := joinsNext(neighbor(i, -1))
+		next := neighbor(i, 1)
+		if runes[i] == arabicLam && i+1 < len(runes) {
+			if ligature, ok := lamAlef[runes[i+1]]; ok {
+				if previous {
+					ligature++
+				}
+				shaped = append(shaped, ligature)
+				i++
+				continue
+			}
+		}
+		following := form.forms == 4 && joinsPrevious(next)
+		switch {
+		case previous && following:
+			shaped = append(shaped, form.isolated+3)
+		case following:
+			shaped = append(shaped, form.isolated+2)
+		case previous && form.forms > 1:
+			shaped = append(shaped, form.isolated+1)
+		default:
+			shaped = append(shaped, form.isolated)
+		}
+	}
+	return string(shaped)
+}
+
+// isHaraka reports whether r is an Arabic diacritic, which is drawn over
+// the letters and does not affect their joining
+func isHaraka(r rune) bool {
+	return r >= '\u064B' && r <= '\u065F' || r == '\u0670'
+}
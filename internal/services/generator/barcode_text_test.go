@@ -0,0 +1,206 @@
+package generator
+
+import (
+	"bytes"
+	"image/png"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/warnings"
+)
+
+func TestVisualOrder(t *testing.T) {
+	tests := []struct {
+		name, logical, visual string
+		rtl                   bool
+	}{
+		{"Latin", "ABC 123", "ABC 123", false},
+		{"Hebrew", "שלום", "םולש", true},
+		{"Hebrew with a number", "שלום 123", "123 םולש", true},
+		{"Latin in Hebrew", "שלום abc", "abc םולש", true},
+		{"Hebrew in Latin", "abc שלום", "abc םולש", false},
+		{"trailing space", "abc שלום ", "abc םולש ", false},
+		{"brackets mirror", "(שלום)", "(םולש)", true},
+		{"decimal separator", "מחיר 12.50", "12.50 ריחמ", true},
+		{"Arabic digits", "رقم ١٢٣", "١٢٣ مقر", true},
+	}
+	for _, tt := range tests {
+		visual, rtl := visualOrder(tt.logical)
+		if visual != tt.visual || rtl != tt.rtl {
+			t.Errorf("%s: visualOrder(%q) = %q, %v; want %q, %v", tt.name, tt.logical, visual, rtl, tt.visual, tt.rtl)
+		}
+	}
+}
+
+func TestShapeArabic(t *testing.T) {
+	tests := []struct {
+		name, text, shaped string
+	}{
+		{"not Arabic", "Shalom שלום", "Shalom שלום"},
+		{"initial and final", "بب", "ﺑﺐ"},
+		{"medial", "ببب", "ﺑﺒﺐ"},
+		{"isolated letter", "ب", "ﺏ"},
+		// Alef joins only to the letter before it
+		{"right-joining letter", "باب", "ﺑﺎﺏ"},
+		{"lam-alef ligature", "سلام", "ﺳﻼﻡ"},
+		{"lam-alef alone", "لا", "ﻻ"},
+		{"harakat keep joining", "بَب", "ﺑَﺐ"},
+		{"words", "بب بب", "ﺑﺐ ﺑﺐ"},
+	}
+	for _, tt := range tests {
+		if got := shapeArabic(tt.text); got != tt.shaped {
+			t.Errorf("%s: shapeArabic(%q) = %+q, want %+q", tt.name, tt.text, got, tt.shaped)
+		}
+	}
+}
+
+// textWords returns the widths of the words of the text line of a PNG
+// barcode from left to right, splitting where at least gap blank columns
+// separate the ink
+func textWords(t *testing.T, data []byte, gap int) []int {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := img.Bounds()
+	dark := func(x, y int) bool {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return r+g+b < 3*0x8000
+	}
+	barRow := bounds.Min.Y + bounds.Dy()/4
+	var textRows []int
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if dark(x, y) != dark(x, barRow) {
+				textRows = append(textRows, y)
+				break
+			}
+		}
+	}
+	var words []int
+	start, end := -1, -1
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		if !slices.ContainsFunc(textRows, func(y int) bool { return dark(x, y) }) {
+			continue
+		}
+		if start < 0 || x-end > gap {
+			if start >= 0 {
+				words = append(words, end-start+1)
+			}
+			start = x
+		}
+		end = x
+	}
+	if start >= 0 {
+		words = append(words, end-start+1)
+	}
+	return words
+}
+
+func TestBarcodeTextRendering(t *testing.T) {
+	service := NewDefaultBarcodeService()
+	// font is empty for the default face, which has no Hebrew or Arabic
+	font := ""
+	generate := func(text, format string) BarcodeImage {
+		t.Helper()
+		req := models.GenerateRequest{
+			Type: BarcodeTypeCode128, Data: "ORDER-42", Text: text, Format: format,
+			Width: 800, Height: 300, IncludeText: true, Font: font, FontSize: 24,
+		}
+		if w := service.Warnings(req); len(w) != 0 {
+			t.Errorf("%q: warnings %+v", text, w)
+		}
+		img, err := service.Generate(req)
+		if err != nil {
+			t.Fatalf("%q: %v", text, err)
+		}
+		return img
+	}
+	// width returns the width of text drawn as a single word
+	width := func(text string) int {
+		t.Helper()
+		words := textWords(t, generate(text, BarcodeFormatPNG).Data, 8)
+		if len(words) != 1 {
+			t.Fatalf("%q is drawn as %d words", text, len(words))
+		}
+		return words[0]
+	}
+
+	// The fallback face draws real glyphs: distinct letters leave
+	// distinct ink, where missing glyphs would all be the same box
+	hebrew, arabic := generate("שלום", BarcodeFormatPNG), generate("مرحبا", BarcodeFormatPNG)
+	for text, img := range map[string]BarcodeImage{"Hebrew": hebrew, "Arabic": arabic} {
+		if coverage := textCoverage(t, img.Data); coverage < 100 {
+			t.Errorf("%s: %d dark text pixels", text, coverage)
+		}
+	}
+	if textCoverage(t, hebrew.Data) == textCoverage(t, generate("אאאא", BarcodeFormatPNG).Data) {
+		t.Error("four different Hebrew letters cover as much as four alefs")
+	}
+
+	// Words are placed in visual order, whatever their order in the text.
+	// The whole line switches face, so words are measured in the fallback.
+	font = FallbackTextFont
+	for _, tt := range []struct {
+		text  string
+		words []string
+	}{
+		{"שלום 1111", []string{"1111", "שלום"}},
+		{"WWWW שלום", []string{"WWWW", "שלום"}},
+		{"مرحبا WWWW", []string{"WWWW", "مرحبا"}},
+	} {
+		want := []int{width(tt.words[0]), width(tt.words[1])}
+		got := textWords(t, generate(tt.text, BarcodeFormatPNG).Data, 8)
+		if len(got) != 2 || got[0]-want[0] > 1 || want[0]-got[0] > 1 || got[1]-want[1] > 1 || want[1]-got[1] > 1 {
+			t.Errorf("%q: word widths %v, want %v for %q", tt.text, got, want, tt.words)
+		}
+	}
+
+	// SVG keeps the reading order and leaves layout to the viewer
+	svg := string(generate("שלום 1111", BarcodeFormatSVG).Data)
+	if !strings.Contains(svg, `direction="rtl" unicode-bidi="embed"`) || !strings.Contains(svg, ">שלום 1111</text>") ||
+		!strings.Contains(svg, "DejaVu Sans") {
+		t.Errorf("right-to-left SVG text:\n%s", svg)
+	}
+	if svg := string(generate("WWWW שלום", BarcodeFormatSVG).Data); strings.Contains(svg, "direction=") {
+		t.Errorf("left-to-right SVG text has a direction:\n%s", svg)
+	}
+}
+
+func TestBarcodeTextGlyphsMissing(t *testing.T) {
+	service := NewDefaultBarcodeService()
+	req := models.GenerateRequest{
+		Type: BarcodeTypeCode128, Data: "ORDER-42", Text: "注文四十二", Format: BarcodeFormatPNG,
+		Width: 800, Height: 300, IncludeText: true, FontSize: 24, Deterministic: true,
+	}
+	got := service.Warnings(req)
+	if len(got) != 1 || got[0].Code != warnings.CodeTextGlyphsMissing || got[0].Field != "text" {
+		t.Errorf("warnings = %+v, want text_glyphs_missing", got)
+	}
+
+	// The data is drawn instead, in the requested face
+	img, err := service.Generate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Text = ""
+	data, err := service.Generate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(img.Data, data.Data) {
+		t.Error("unsupported text is not replaced by the data")
+	}
+	req.Format = BarcodeFormatSVG
+	req.Text = "注文四十二"
+	if svg, err := service.Generate(req); err != nil || !strings.Contains(string(svg.Data), ">ORDER-42</text>") {
+		t.Errorf("SVG: %v\n%s", err, svg.Data)
+	}
+
+	if !slices.Contains(BarcodeWarningCodes(), warnings.CodeTextGlyphsMissing) {
+		t.Error("text_glyphs_missing is not a documented warning code")
+	}
+}
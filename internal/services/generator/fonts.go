@@ -0,0 +1,247 @@
+package generator
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/innovelabs/microtools-go/internal/lazy"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+)
+
+const (
+	// DefaultBarcodeFont is used when font_size is set without a font
+	DefaultBarcodeFont = "go-mono"
+
+	defaultFontSize = 12
+	minFontSize     = 6
+	maxFontSize     = 72
+)
+
+// ErrUnknownFont is returned for a font name that is not loaded
+var ErrUnknownFont = errors.New("unknown font")
+
+// embeddedFonts are the open-licensed Go fonts and DejaVu Sans, which
+// covers Arabic and Hebrew (see fonts/LICENSE), so scalable text works
+// without font files next to the binary
+//
+//go:embed fonts/*.ttf
+var embeddedFonts embed.FS
+
+// builtinTextFace is the 7x13 bitmap face used when neither font nor
+// font_size is requested, keeping the original label layout
+var builtinTextFace = &textFace{
+	face:      basicfont.Face7x13,
+	family:    "monospace",
+	size:      12,
+	rowHeight: textPaddingHeight,
+	descent:   2,
+}
+
+// textFace is a font face with the metrics of the text row it needs.
+// Faces from opentype keep per-face buffers, so drawing holds mu.
+type textFace struct {
+	mu        sync.Mutex
+	face      font.Face
+	family    string
+	size      int
+	rowHeight int
+	descent   int
+}
+
+// FontSet holds the fonts barcode text can be rendered with and caches
+// one face per font and size. Embedded fonts are parsed on first use, so
+// services that never draw text do not pay for them.
+type FontSet struct {
+	fonts map[string]*lazy.Lazy[parsedFont]
+	// data holds the font files, which PDF output embeds
+	data map[string][]byte
+
+	mu    sync.Mutex
+	faces map[fontFaceKey]*textFace
+}
+
+// parsedFont is a parsed font file with the CSS family SVG output names
+type parsedFont struct {
+	font   *opentype.Font
+	family string
+}
+
+type fontFaceKey struct {
+	name string
+	size int
+}
+
+// NewEmbeddedFontSet returns a FontSet holding only the embedded fonts
+func NewEmbeddedFontSet() *FontSet {
+	s, err := newEmbeddedFontSet()
+	if err != nil {
+		panic(fmt.Sprintf("embedded fonts: %v", err))
+	}
+	return s
+}
+
+// LoadFontSet returns the embedded fonts plus every .ttf and .otf file in
+// dir, selectable by file name without extension (lowercased). Any file
+// that fails to parse or clashes with a loaded name is an error.
+func LoadFontSet(dir string) (*FontSet, error) {
+	s, err := newEmbeddedFontSet()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font directory: %w", err)
+	}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".ttf" && ext != ".otf") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("font %s: %w", entry.Name(), err)
+		}
+		name := strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		if err := s.add(name, data); err != nil {
+			return nil, fmt.Errorf("font %s: %w", entry.Name(), err)
+		}
+		// Files from the directory are parsed now so a broken one fails
+		// at startup rather than on the first request using it
+		if _, err := s.fonts[name].Get(); err != nil {
+			return nil, fmt.Errorf("font %s: %w", entry.Name(), err)
+		}
+	}
+	return s, nil
+}
+
+func newEmbeddedFontSet() (*FontSet, error) {
+	s := &FontSet{
+		fonts: map[string]*lazy.Lazy[parsedFont]{},
+		data:  map[string][]byte{},
+		faces: map[fontFaceKey]*textFace{},
+	}
+	files, err := embeddedFonts.ReadDir("fonts")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if path.Ext(file.Name()) != ".ttf" {
+			continue
+		}
+		data, err := embeddedFonts.ReadFile("fonts/" + file.Name())
+		if err != nil {
+			return nil, err
+		}
+		if err := s.add(strings.TrimSuffix(file.Name(), ".ttf"), data); err != nil {
+			return nil, fmt.Errorf("font %s: %w", file.Name(), err)
+		}
+	}
+	return s, nil
+}
+
+// add registers data under name; the file is parsed on first use
+func (s *FontSet) add(name string, data []byte) error {
+	if _, exists := s.fonts[name]; exists {
+		return fmt.Errorf("font name %q is already loaded", name)
+	}
+	s.data[name] = data
+	s.fonts[name] = lazy.New(func() (parsedFont, error) {
+		f, err := opentype.Parse(data)
+		if err != nil {
+			return parsedFont{}, err
+		}
+		family, err := f.Name(nil, sfnt.NameIDFamily)
+		if err != nil || family == "" {
+			family = name
+		}
+		// SVG output names the family with a generic fallback
+		return parsedFont{font: f, family: "'" + family + "', monospace"}, nil
+	})
+	return nil
+}
+
+// Names returns the loaded font names in sorted order
+func (s *FontSet) Names() []string {
+	names := make([]string, 0, len(s.fonts))
+	for name := range s.fonts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fontData returns the font file loaded as name
+func (s *FontSet) fontData(name string) ([]byte, error) {
+	data, ok := s.data[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q: must be one of %s", ErrUnknownFont, name, strings.Join(s.Names(), ", "))
+	}
+	return data, nil
+}
+
+// face returns the cached face of name at size, creating it on first use
+func (s *FontSet) face(name string, size int) (*textFace, error) {
+	loader, ok := s.fonts[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q: must be one of %s", ErrUnknownFont, name, strings.Join(s.Names(), ", "))
+	}
+	parsed, err := loader.Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load font %q: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := fontFaceKey{name: name, size: size}
+	if tf, ok := s.faces[key]; ok {
+		return tf, nil
+	}
+	face, err := opentype.NewFace(parsed.font, &opentype.FaceOptions{
+		Size:    float64(size),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load font %q: %w", name, err)
+	}
+	metrics := face.Metrics()
+	tf := &textFace{
+		face:      face,
+		family:    parsed.family,
+		size:      size,
+		rowHeight: max(textPaddingHeight, metrics.Height.Ceil()+8),
+		descent:   metrics.Descent.Ceil(),
+	}
+	s.faces[key] = tf
+	return tf, nil
+}
+
+// textFaceFor resolves the face for the font and font_size of a request
+func (s *FontSet) textFaceFor(fontName string, fontSize int) (*textFace, error) {
+	if fontName == "" && fontSize == 0 {
+		return builtinTextFace, nil
+	}
+	if fontName == "" {
+		fontName = DefaultBarcodeFont
+	}
+	if fontSize == 0 {
+		fontSize = defaultFontSize
+	}
+	return s.face(strings.ToLower(fontName), fontSize)
+}
+
+// baseline returns the text baseline for a text row starting at top
+func (tf *textFace) baseline(top int) int {
+	return top + tf.rowHeight - tf.descent - 2
+}
@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// textCoverage counts the dark pixels of a PNG barcode outside its bars,
+// which are the rows matching the bar row at a quarter of the height
+func textCoverage(t *testing.T, data []byte) int {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := img.Bounds()
+	dark := func(x, y int) bool {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return r+g+b < 3*0x8000
+	}
+	barRow := bounds.Min.Y + bounds.Dy()/4
+	count := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		same, n := true, 0
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if dark(x, y) != dark(x, barRow) {
+				same = false
+			}
+			if dark(x, y) {
+				n++
+			}
+		}
+		if !same {
+			count += n
+		}
+	}
+	return count
+}
+
+func TestTextCoverageGrowsWithFontSize(t *testing.T) {
+	service := NewDefaultBarcodeService()
+	for _, font := range []string{"go-mono", "dejavu-sans"} {
+		previous := 0
+		for _, size := range []int{8, 16, 32} {
+			img, err := service.Generate(models.GenerateRequest{
+				Type: "Code128", Data: "ABC123", Format: BarcodeFormatPNG,
+				Width: 600, Height: 300, IncludeText: true, Font: font, FontSize: size,
+			})
+			if err != nil {
+				t.Fatalf("%s %d: %v", font, size, err)
+			}
+			coverage := textCoverage(t, img.Data)
+			if coverage <= previous {
+				t.Errorf("%s: coverage at size %d is %d, not above %d", font, size, coverage, previous)
+			}
+			previous = coverage
+		}
+	}
+}
+
+func TestUnknownFont(t *testing.T) {
+	_, err := NewDefaultBarcodeService().Generate(models.GenerateRequest{
+		Type: "Code128", Data: "ABC123", Format: BarcodeFormatPNG, IncludeText: true, Font: "comic-sans",
+	})
+	if !errors.Is(err, ErrUnknownFont) || !strings.Contains(err.Error(), "dejavu-sans, go-mono, go-regular") {
+		t.Errorf("err = %v, want ErrUnknownFont listing the fonts", err)
+	}
+}
+
+func TestLoadFontSet(t *testing.T) {
+	mono, err := embeddedFonts.ReadFile("fonts/go-mono.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Label.TTF"), mono, 0o644)
+	os.WriteFile(filepath.Join(dir, "README"), []byte("not a font"), 0o644)
+	set, err := LoadFontSet(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(set.Names(), "label") {
+		t.Errorf("names = %v, want the custom font as label", set.Names())
+	}
+	face, err := set.textFaceFor("Label", 20)
+	if err != nil || face.size != 20 {
+		t.Fatalf("face = %+v, %v", face, err)
+	}
+	if again, _ := set.textFaceFor("label", 20); again != face {
+		t.Error("faces are not cached per font and size")
+	}
+
+	for name, data := range map[string][]byte{
+		"broken.ttf":  []byte("not a font"),
+		"go-mono.ttf": mono,
+	} {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, name), data, 0o644)
+		if _, err := LoadFontSet(dir); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: err = %v, want a startup error naming the file", name, err)
+		}
+	}
+	if _, err := LoadFontSet(filepath.Join(dir, "missing")); err == nil {
+		t.Error("missing directory: no error")
+	}
+}
+
+// TestFontFacesConcurrentFirstAccess asks a fresh font set for the same
+// face from many goroutines; run with -race
+func TestFontFacesConcurrentFirstAccess(t *testing.T) {
+	set := NewEmbeddedFontSet()
+	faces := make([]*textFace, 32)
+	var wg sync.WaitGroup
+	for i := range faces {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			face, err := set.textFaceFor("dejavu-sans", 18)
+			if err != nil {
+				t.Error(err)
+			}
+			faces[i] = face
+		}()
+	}
+	wg.Wait()
+	for _, face := range faces[1:] {
+		if face != faces[0] {
+			t.Fatal("concurrent first access built more than one face")
+		}
+	}
+}
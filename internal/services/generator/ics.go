@@ -0,0 +1,483 @@
+package generator
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // VTIMEZONE output must not depend on the host's zoneinfo
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+const (
+	icsProdID      = "-//InnoveLabs//Micro API//EN"
+	icsUIDDomain   = "microapi.innovelabs.net"
+	icsLineOctets  = 75
+	maxICSEvents   = 100
+	maxICSAlarms   = 5
+	maxICSExDates  = 100
+	maxAlarmMinute = 4 * 7 * 24 * 60
+
+	icsDateTimeLayout = "20060102T150405"
+	icsDateLayout     = "20060102"
+)
+
+var ErrInvalidEvent = errors.New("invalid event")
+
+var rruleFrequencies = map[string]bool{
+	"SECONDLY": true, "MINUTELY": true, "HOURLY": true, "DAILY": true,
+	"WEEKLY": true, "MONTHLY": true, "YEARLY": true,
+}
+
+var rruleWeekdays = map[string]bool{
+	"MO": true, "TU": true, "WE": true, "TH": true, "FR": true, "SA": true, "SU": true,
+}
+
+// rruleIntLists are the BYxxx parts holding comma-separated integers with
+// their allowed absolute ranges (0 is never allowed where negatives are)
+var rruleIntLists = map[string][2]int{
+	"BYSECOND": {0, 60}, "BYMINUTE": {0, 59}, "BYHOUR": {0, 23},
+	"BYMONTHDAY": {1, 31}, "BYYEARDAY": {1, 366}, "BYWEEKNO": {1, 53},
+	"BYMONTH": {1, 12}, "BYSETPOS": {1, 366},
+}
+
+// icsEvent is a validated event with its times resolved
+type icsEvent struct {
+	models.ICSEvent
+	loc     *time.Location // nil for UTC and all-day events
+	start   time.Time
+	end     time.Time
+	exDates []time.Time
+	rrule   string
+}
+
+// GenerateICS builds an iCalendar (RFC 5545) file for one or more events
+func GenerateICS(req models.ICSRequest) ([]byte, error) {
+	if len(req.Events) == 0 {
+		return nil, fmt.Errorf("%w: at least one event is required", ErrInvalidEvent)
+	}
+	if len(req.Events) > maxICSEvents {
+		return nil, fmt.Errorf("%w: at most %d events are allowed", ErrInvalidEvent, maxICSEvents)
+	}
+
+	events := make([]icsEvent, len(req.Events))
+	for i, src := range req.Events {
+		if strings.TrimSpace(src.Summary) == "" {
+			return nil, fmt.Errorf("events[%d]: %w: summary is required", i, ErrInvalidEvent)
+		}
+		ev, err := parseICSEvent(src)
+		if err != nil {
+			return nil, fmt.Errorf("events[%d]: %w", i, err)
+		}
+		events[i] = ev
+	}
+
+	w := &icsWriter{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + icsProdID)
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+
+	written := map[string]bool{}
+	for _, ev := range events {
+		if ev.loc != nil && !written[ev.loc.String()] {
+			written[ev.loc.String()] = true
+			writeVTimezone(w, ev.loc, ev.start.Year())
+		}
+	}
+	stamp := time.Now().UTC()
+	for i, ev := range events {
+		writeVEvent(w, ev, i, stamp)
+	}
+
+	w.line("END:VCALENDAR")
+	return []byte(w.String()), nil
+}
+
+// BuildEventPayload returns a single VEVENT for an event QR code, sharing
+// the calendar file builder so both outputs stay consistent. The payload
+// depends on the event alone: it has no UID or DTSTAMP, which scanners
+// ignore and which would change the code on every request, and the summary
+// may be left out.
+func BuildEventPayload(data models.EventData) (string, error) {
+	ev, err := parseICSEvent(models.ICSEvent{Summary: data.Summary, Start: data.Start, End: data.End})
+	if err != nil {
+		return "", err
+	}
+	w := &icsWriter{}
+	writeVEvent(w, ev, 0, time.Time{})
+	return strings.TrimSuffix(w.String(), "\r\n"), nil
+}
+
+// parseICSEvent validates src and resolves its times; calendar files also
+// require a summary, which GenerateICS checks
+func parseICSEvent(src models.ICSEvent) (icsEvent, error) {
+	ev := icsEvent{ICSEvent: src}
+	if src.Start == "" {
+		return ev, fmt.Errorf("%w: start is required", ErrInvalidEvent)
+	}
+
+	if src.Timezone != "" && src.Timezone != "UTC" && !src.AllDay {
+		loc, err := time.LoadLocation(src.Timezone)
+		if err != nil {
+			return ev, fmt.Errorf("%w: unknown timezone %q", ErrInvalidEvent, src.Timezone)
+		}
+		ev.loc = loc
+	}
+
+	var err error
+	if ev.start, err = parseICSTime(src.Start, ev.loc, src.AllDay); err != nil {
+		return ev, fmt.Errorf("%w: start: %v", ErrInvalidEvent, err)
+	}
+	switch {
+	case src.End != "":
+		if ev.end, err = parseICSTime(src.End, ev.loc, src.AllDay); err != nil {
+			return ev, fmt.Errorf("%w: end: %v", ErrInvalidEvent, err)
+		}
+		if !ev.end.After(ev.start) {
+			return ev, fmt.Errorf("%w: end must be after start", ErrInvalidEvent)
+		}
+	case src.AllDay:
+		ev.end = ev.start.AddDate(0, 0, 1)
+	default:
+		ev.end = ev.start.Add(time.Hour)
+	}
+
+	if src.RRule != "" {
+		if ev.rrule, err = normalizeRRule(src.RRule, src.AllDay); err != nil {
+			return ev, err
+		}
+	}
+
+	if len(src.ExDates) > maxICSExDates {
+		return ev, fmt.Errorf("%w: at most %d exdates are allowed", ErrInvalidEvent, maxICSExDates)
+	}
+	if len(src.ExDates) > 0 && ev.rrule == "" {
+		return ev, fmt.Errorf("%w: exdates require rrule", ErrInvalidEvent)
+	}
+	for i, value := range src.ExDates {
+		t, err := parseICSTime(value, ev.loc, src.AllDay)
+		if err != nil {
+			return ev, fmt.Errorf("%w: exdates[%d]: %v", ErrInvalidEvent, i, err)
+		}
+		ev.exDates = append(ev.exDates, t)
+	}
+
+	if len(src.Alarms) > maxICSAlarms {
+		return ev, fmt.Errorf("%w: at most %d alarms are allowed", ErrInvalidEvent, maxICSAlarms)
+	}
+	for i, alarm := range src.Alarms {
+		if alarm.MinutesBefore < 0 || alarm.MinutesBefore > maxAlarmMinute {
+			return ev, fmt.Errorf("%w: alarms[%d]: minutes_before must be between 0 and %d", ErrInvalidEvent, i, maxAlarmMinute)
+		}
+	}
+	return ev, nil
+}
+
+// parseICSTime accepts RFC 3339 timestamps, local date-times (interpreted in
+// loc, or UTC when loc is nil), iCalendar basic forms, and dates for all-day
+// events. Results are expressed in loc (or UTC).
+func parseICSTime(value string, loc *time.Location, allDay bool) (time.Time, error) {
+	if allDay {
+		for _, layout := range []string{"2006-01-02", icsDateLayout} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%q is not a date (YYYY-MM-DD)", value)
+	}
+
+	target := loc
+	if target == nil {
+		target = time.UTC
+	}
+	for _, layout := range []string{time.RFC3339, icsDateTimeLayout + "Z"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.In(target), nil
+		}
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", icsDateTimeLayout} {
+		if t, err := time.ParseInLocation(layout, value, target); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 or local date-time", value)
+}
+
+// normalizeRRule validates an RRULE value and returns it upper-cased
+func normalizeRRule(rule string, allDay bool) (string, error) {
+	rule = strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:"))
+	seen := map[string]bool{}
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return "", fmt.Errorf("%w: rrule part %q must be NAME=VALUE", ErrInvalidEvent, part)
+		}
+		if seen[name] {
+			return "", fmt.Errorf("%w: rrule part %s appears twice", ErrInvalidEvent, name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			if !rruleFrequencies[value] {
+				return "", fmt.Errorf("%w: rrule FREQ %q is not supported", ErrInvalidEvent, value)
+			}
+		case "INTERVAL", "COUNT":
+			if n, err := strconv.Atoi(value); err != nil || n < 1 {
+				return "", fmt.Errorf("%w: rrule %s must be a positive integer", ErrInvalidEvent, name)
+			}
+		case "UNTIL":
+			if allDay {
+				if _, err := time.Parse(icsDateLayout, value); err != nil {
+					return "", fmt.Errorf("%w: rrule UNTIL must be a date (YYYYMMDD) for all-day events", ErrInvalidEvent)
+				}
+			} else if _, err := time.Parse(icsDateTimeLayout+"Z", value); err != nil {
+				return "", fmt.Errorf("%w: rrule UNTIL must be a UTC date-time (YYYYMMDDTHHMMSSZ)", ErrInvalidEvent)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				if len(day) < 2 || !rruleWeekdays[day[len(day)-2:]] {
+					return "", fmt.Errorf("%w: rrule BYDAY %q is not a weekday", ErrInvalidEvent, day)
+				}
+				if prefix := day[:len(day)-2]; prefix != "" {
+					if n, err := strconv.Atoi(prefix); err != nil || n == 0 || n < -53 || n > 53 {
+						return "", fmt.Errorf("%w: rrule BYDAY %q has an invalid ordinal", ErrInvalidEvent, day)
+					}
+				}
+			}
+		case "WKST":
+			if !rruleWeekdays[value] {
+				return "", fmt.Errorf("%w: rrule WKST %q is not a weekday", ErrInvalidEvent, value)
+			}
+		default:
+			bounds, ok := rruleIntLists[name]
+			if !ok {
+				return "", fmt.Errorf("%w: rrule part %s is not supported", ErrInvalidEvent, name)
+			}
+			for _, item := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(item)
+				if n < 0 && bounds[0] > 0 {
+					n = -n
+				}
+				if err != nil || n < bounds[0] || n > bounds[1] {
+					return "", fmt.Errorf("%w: rrule %s value %q is out of range", ErrInvalidEvent, name, item)
+				}
+			}
+		}
+	}
+
+	if !seen["FREQ"] {
+		return "", fmt.Errorf("%w: rrule requires FREQ", ErrInvalidEvent)
+	}
+	if seen["UNTIL"] && seen["COUNT"] {
+		return "", fmt.Errorf("%w: rrule UNTIL and COUNT are mutually exclusive", ErrInvalidEvent)
+	}
+	return rule, nil
+}
+
+// writeVEvent writes ev as the index-th event of a file created at stamp.
+// A zero stamp writes the bare event of a QR payload, without UID and
+// DTSTAMP. SUMMARY is left out when ev has none.
+func writeVEvent(w *icsWriter, ev icsEvent, index int, stamp time.Time) {
+	w.line("BEGIN:VEVENT")
+	if !stamp.IsZero() {
+		uid := ev.UID
+		if uid == "" {
+			sum := sha1.Sum([]byte(fmt.Sprintf("%d\x00%s\x00%s\x00%s", index, ev.Summary, ev.Start, ev.End)))
+			uid = hex.EncodeToString(sum[:8]) + "@" + icsUIDDomain
+		}
+		w.line("UID:" + escapeICSText(uid))
+		w.line("DTSTAMP:" + stamp.Format(icsDateTimeLayout) + "Z")
+	}
+	w.line("DTSTART" + ev.formatTime(ev.start))
+	w.line("DTEND" + ev.formatTime(ev.end))
+	if ev.Summary != "" {
+		w.line("SUMMARY:" + escapeICSText(ev.Summary))
+	}
+	if ev.Description != "" {
+		w.line("DESCRIPTION:" + escapeICSText(ev.Description))
+	}
+	if ev.Location != "" {
+		w.line("LOCATION:" + escapeICSText(ev.Location))
+	}
+	if ev.rrule != "" {
+		w.line("RRULE:" + ev.rrule)
+	}
+	if len(ev.exDates) > 0 {
+		values := make([]string, len(ev.exDates))
+		for i, t := range ev.exDates {
+			values[i] = ev.formatTimeValue(t)
+		}
+		w.line("EXDATE" + ev.timeParams() + ":" + strings.Join(values, ","))
+	}
+	for _, alarm := range ev.Alarms {
+		description := alarm.Description
+		if description == "" {
+			description = ev.Summary
+		}
+		w.line("BEGIN:VALARM")
+		w.line("ACTION:DISPLAY")
+		w.line("DESCRIPTION:" + escapeICSText(description))
+		w.line(fmt.Sprintf("TRIGGER:-PT%dM", alarm.MinutesBefore))
+		w.line("END:VALARM")
+	}
+	w.line("END:VEVENT")
+}
+
+// formatTime returns the parameters and value of a date or date-time property
+func (ev icsEvent) formatTime(t time.Time) string {
+	return ev.timeParams() + ":" + ev.formatTimeValue(t)
+}
+
+func (ev icsEvent) timeParams() string {
+	switch {
+	case ev.AllDay:
+		return ";VALUE=DATE"
+	case ev.loc != nil:
+		return ";TZID=" + ev.loc.String()
+	default:
+		return ""
+	}
+}
+
+func (ev icsEvent) formatTimeValue(t time.Time) string {
+	switch {
+	case ev.AllDay:
+		return t.Format(icsDateLayout)
+	case ev.loc != nil:
+		return t.In(ev.loc).Format(icsDateTimeLayout)
+	default:
+		return t.UTC().Format(icsDateTimeLayout) + "Z"
+	}
+}
+
+// zoneTransition is an offset change of a location
+type zoneTransition struct {
+	at         time.Time
+	offsetFrom int
+	offsetTo   int
+	name       string
+	isDST      bool
+}
+
+// writeVTimezone describes loc with yearly recurring observances derived
+// from its transitions in year, or a single fixed observance when it has none
+func writeVTimezone(w *icsWriter, loc *time.Location, year int) {
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + loc.String())
+
+	transitions := zoneTransitions(loc, year)
+	if len(transitions) == 0 {
+		name, offset := time.Date(year, 1, 1, 0, 0, 0, 0, loc).Zone()
+		w.line("BEGIN:STANDARD")
+		w.line("DTSTART:19700101T000000")
+		w.line("TZOFFSETFROM:" + formatUTCOffset(offset))
+		w.line("TZOFFSETTO:" + formatUTCOffset(offset))
+		w.line("TZNAME:" + escapeICSText(name))
+		w.line("END:STANDARD")
+	}
+	for _, tr := range transitions {
+		kind := "STANDARD"
+		if tr.isDST {
+			kind = "DAYLIGHT"
+		}
+		// DTSTART is the wall-clock time of the change in the old offset
+		local := tr.at.UTC().Add(time.Duration(tr.offsetFrom) * time.Second)
+		w.line("BEGIN:" + kind)
+		w.line("DTSTART:" + local.Format(icsDateTimeLayout))
+		if len(transitions) == 2 {
+			w.line(fmt.Sprintf("RRULE:FREQ=YEARLY;BYMONTH=%d;BYDAY=%s", int(local.Month()), nthWeekday(local)))
+		}
+		w.line("TZOFFSETFROM:" + formatUTCOffset(tr.offsetFrom))
+		w.line("TZOFFSETTO:" + formatUTCOffset(tr.offsetTo))
+		w.line("TZNAME:" + escapeICSText(tr.name))
+		w.line("END:" + kind)
+	}
+	w.line("END:VTIMEZONE")
+}
+
+// zoneTransitions finds the offset changes of loc during year by stepping a
+// day at a time and bisecting to the second
+func zoneTransitions(loc *time.Location, year int) []zoneTransition {
+	var out []zoneTransition
+	t := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, offset := t.In(loc).Zone()
+	for t.Before(end) {
+		next := t.Add(24 * time.Hour)
+		_, nextOffset := next.In(loc).Zone()
+		if nextOffset != offset {
+			lo, hi := t, next
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2)
+				if _, o := mid.In(loc).Zone(); o == offset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			name, _ := hi.In(loc).Zone()
+			out = append(out, zoneTransition{at: hi, offsetFrom: offset, offsetTo: nextOffset, name: name, isDST: hi.In(loc).IsDST()})
+			offset = nextOffset
+		}
+		t = next
+	}
+	return out
+}
+
+// nthWeekday expresses a date as an RRULE BYDAY value such as 2SU or -1SU
+func nthWeekday(t time.Time) string {
+	day := strings.ToUpper(t.Weekday().String()[:2])
+	daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if t.Day()+7 > daysInMonth {
+		return "-1" + day
+	}
+	return strconv.Itoa((t.Day()-1)/7+1) + day
+}
+
+func formatUTCOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// escapeICSText escapes a TEXT value per RFC 5545 section 3.3.11
+func escapeICSText(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, ";", "\\;")
+	s = strings.ReplaceAll(s, ",", "\\,")
+	s = strings.ReplaceAll(s, "\r\n", "\\n")
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	return strings.ReplaceAll(s, "\r", "\\n")
+}
+
+// icsWriter emits CRLF-terminated content lines folded at 75 octets
+type icsWriter struct {
+	strings.Builder
+}
+
+func (w *icsWriter) line(s string) {
+	limit := icsLineOctets
+	for len(s) > limit {
+		cut := limit
+		// Never split a UTF-8 sequence across a fold
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = icsLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
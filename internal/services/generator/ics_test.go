@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// dtstamp matches the creation time of a calendar file, the one line that
+// changes between runs
+var dtstamp = regexp.MustCompile(`(?m)^DTSTAMP:\d{8}T\d{6}Z\r$`)
+
+func goldenICSRequest() models.ICSRequest {
+	return models.ICSRequest{Events: []models.ICSEvent{
+		{
+			Summary:     "Team sync; weekly, with notes",
+			Description: "Agenda:\nstatus\\blockers",
+			Location:    "Room 4, Berlin",
+			Start:       "2026-03-02T09:30:00",
+			End:         "2026-03-02T10:00:00",
+			Timezone:    "Europe/Berlin",
+			RRule:       "freq=weekly;byday=MO;count=10",
+			ExDates:     []string{"2026-03-16T09:30:00"},
+			Alarms:      []models.ICSAlarm{{MinutesBefore: 15}},
+		},
+		{
+			Summary: "Company holiday with a summary long enough to be folded onto a second line of the file",
+			Start:   "2026-05-01",
+			AllDay:  true,
+		},
+	}}
+}
+
+func TestGenerateICSGolden(t *testing.T) {
+	out, err := GenerateICS(goldenICSRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := dtstamp.ReplaceAllString(string(out), "DTSTAMP:20260101T000000Z\r")
+
+	path := filepath.Join("testdata", "golden", "calendar.ics")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("calendar differs from %s:\n%s", path, got)
+	}
+}
+
+func TestGenerateICSRoundTrip(t *testing.T) {
+	req := goldenICSRequest()
+	out, err := GenerateICS(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.SplitAfter(string(out), "\r\n") {
+		if len(line) > icsLineOctets+2 {
+			t.Errorf("line of %d octets is not folded: %q", len(line), line)
+		}
+	}
+
+	cal, err := ics.ParseCalendar(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("calendar does not parse: %v", err)
+	}
+	events := cal.Events()
+	if len(events) != len(req.Events) {
+		t.Fatalf("parsed %d events, want %d", len(events), len(req.Events))
+	}
+	value := func(ev *ics.VEvent, property ics.ComponentProperty) string {
+		if p := ev.GetProperty(property); p != nil {
+			return p.Value
+		}
+		return ""
+	}
+	for i, src := range req.Events {
+		for _, tt := range []struct {
+			property ics.ComponentProperty
+			want     string
+		}{
+			{ics.ComponentPropertySummary, src.Summary},
+			{ics.ComponentPropertyDescription, src.Description},
+			{ics.ComponentPropertyLocation, src.Location},
+		} {
+			if got := value(events[i], tt.property); got != tt.want {
+				t.Errorf("events[%d] %s = %q, want %q", i, tt.property, got, tt.want)
+			}
+		}
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, err := events[0].GetStartAt()
+	if want := time.Date(2026, 3, 2, 9, 30, 0, 0, berlin); err != nil || !start.Equal(want) {
+		t.Errorf("DTSTART = %v, %v, want %v", start, err, want)
+	}
+	if got := value(events[0], ics.ComponentPropertyRrule); got != "FREQ=WEEKLY;BYDAY=MO;COUNT=10" {
+		t.Errorf("RRULE = %q", got)
+	}
+
+	exdates := events[0].GetProperties(ics.ComponentPropertyExdate)
+	if len(exdates) != 1 {
+		t.Fatalf("got %d EXDATE properties, want 1", len(exdates))
+	}
+	if tzid := exdates[0].ICalParameters["TZID"]; len(tzid) != 1 || tzid[0] != "Europe/Berlin" {
+		t.Errorf("EXDATE TZID = %v, want Europe/Berlin", tzid)
+	}
+	excluded, err := time.ParseInLocation("20060102T150405", exdates[0].Value, berlin)
+	if want := time.Date(2026, 3, 16, 9, 30, 0, 0, berlin); err != nil || !excluded.Equal(want) {
+		t.Errorf("EXDATE = %q (%v), want %v", exdates[0].Value, err, want)
+	}
+
+	end, err := events[1].GetAllDayEndAt()
+	if err != nil || end.Format("2006-01-02") != "2026-05-02" {
+		t.Errorf("all-day DTEND = %v, %v, want the next day", end, err)
+	}
+}
+
+func TestEventPayloadIsDeterministic(t *testing.T) {
+	data := models.EventData{Start: "2026-03-02T09:30:00Z", End: "2026-03-02T10:00:00Z"}
+	first, err := BuildEventPayload(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "BEGIN:VEVENT\r\nDTSTART:20260302T093000Z\r\nDTEND:20260302T100000Z\r\nEND:VEVENT"
+	if first != want {
+		t.Errorf("payload = %q, want %q", first, want)
+	}
+	second, err := BuildEventPayload(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != first {
+		t.Errorf("payload changed between calls:\n%q\n%q", first, second)
+	}
+}
@@ -0,0 +1,443 @@
+package generator
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image"
+	"image/draw"
+	"strconv"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/pkg/generate/barcode"
+	"github.com/innovelabs/microtools-go/pkg/generate/svgmeta"
+)
+
+const (
+	// supplementGapModules is the quiet gap between the EAN-13 symbol and its add-on
+	supplementGapModules = 9
+)
+
+// EAN left-hand odd (L) and even (G) parity digit patterns, shared by the
+// EAN-2 and EAN-5 add-on symbols.
+var (
+	eanLPatterns = [10]string{
+		"0001101", "0011001", "0010011", "0111101", "0100011",
+		"0110001", "0101111", "0111011", "0110111", "0001011",
+	}
+	eanGPatterns = [10]string{
+		"0100111", "0110011", "0011011", "0100001", "0011101",
+		"0111001", "0000101", "0010001", "0001001", "0010111",
+	}
+
+	// ean5Parity is indexed by the EAN-5 checksum digit
+	ean5Parity = [10]string{
+		"GGLLL", "GLGLL", "GLLGL", "GLLLG", "LGGLL",
+		"LLGGL", "LLLGG", "LGLGL", "LGLLG", "LLGLG",
+	}
+	// ean2Parity is indexed by the EAN-2 value modulo 4
+	ean2Parity = [4]string{"LL", "LG", "GL", "GG"}
+)
+
+// isbnSymbol holds the encoded modules of an ISBN barcode and its add-on
+type isbnSymbol struct {
+	main       []bool
+	supplement []bool
+	isbn13     string
+	display    string
+	addonText  string
+}
+
+// normalizeISBN validates an ISBN-10 or ISBN-13 and returns the ISBN-13
+// digits along with the human-readable line printed above the symbol.
+// The line is hyphenated from the embedded ISBN ranges; for groups
+// without ranges the caller's hyphenation is kept. ISBN-10 input is
+// re-prefixed with 978 and given a recomputed check digit.
+func normalizeISBN(data string) (string, string, error) {
+	raw := strings.TrimSpace(data)
+	raw = strings.TrimPrefix(strings.TrimPrefix(raw, "ISBN"), ":")
+	raw = strings.TrimSpace(raw)
+	compact := strings.NewReplacer("-", "", " ", "").Replace(raw)
+
+	switch len(compact) {
+	case 10:
+		if !isNumeric(compact[:9]) {
+			return "", "", fmt.Errorf("%w: ISBN-10 must contain 9 digits followed by a digit or X", ErrInvalidData)
+		}
+		if err := validateISBN10Checksum(compact); err != nil {
+			return "", "", err
+		}
+		body := "978" + compact[:9]
+		isbn13 := body + string(rune('0'+barcode.EAN13CheckDigit(body)))
+		display, ok := hyphenateISBN(isbn13)
+		switch {
+		case ok:
+		case strings.ContainsAny(raw, "- "):
+			groups := strings.FieldsFunc(raw, func(r rune) bool { return r == '-' || r == ' ' })
+			groups[len(groups)-1] = isbn13[12:]
+			display = "978-" + strings.Join(groups, "-")
+		default:
+			display = isbn13
+		}
+		return isbn13, "ISBN " + display, nil
+
+	case 13:
+		if !isNumeric(compact) {
+			return "", "", fmt.Errorf("%w: ISBN-13 must be numeric", ErrInvalidData)
+		}
+		if !strings.HasPrefix(compact, "978") && !strings.HasPrefix(compact, "979") {
+			return "", "", fmt.Errorf("%w: ISBN-13 must start with 978 or 979", ErrInvalidData)
+		}
+		if err := barcode.Validate(barcode.TypeEAN13, compact); err != nil {
+			return "", "", err
+		}
+		display, ok := hyphenateISBN(compact)
+		switch {
+		case ok:
+		case strings.ContainsAny(raw, "- "):
+			groups := strings.FieldsFunc(raw, func(r rune) bool { return r == '-' || r == ' ' })
+			display = strings.Join(groups, "-")
+		default:
+			display = compact
+		}
+		return compact, "ISBN " + display, nil
+
+	default:
+		return "", "", fmt.Errorf("%w: ISBN must have 10 or 13 digits", ErrInvalidData)
+	}
+}
+
+// isbnRangeWidth is the number of digits ISBN range rules compare
+const isbnRangeWidth = 7
+
+// isbnRangeRule gives the length of the ISBN element whose digits, and
+// the ones after it, fall between start and end
+type isbnRangeRule struct {
+	start, end string
+	length     int
+}
+
+// isbnRangeList is the embedded subset of the ISBN range data, see the
+// file
+//
+//go:embed isbn_ranges.txt
+var isbnRangeList string
+
+// isbnRanges holds the rules of isbnRangeList by the prefix they follow
+var isbnRanges = parseISBNRanges(isbnRangeList)
+
+func parseISBNRanges(list string) map[string][]isbnRangeRule {
+	ranges := map[string][]isbnRangeRule{}
+	for i, line := range strings.Split(list, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		var rule isbnRangeRule
+		var ok bool
+		if len(fields) == 3 {
+			rule.start, rule.end, ok = strings.Cut(fields[1], "-")
+		}
+		length, err := strconv.Atoi(fields[len(fields)-1])
+		if !ok || err != nil || len(rule.start) != isbnRangeWidth || len(rule.end) != isbnRangeWidth || !isNumeric(rule.start+rule.end) {
+			panic(fmt.Sprintf("isbn_ranges.txt line %d: malformed rule %q", i+1, line))
+		}
+		rule.length = length
+		ranges[fields[0]] = append(ranges[fields[0]], rule)
+	}
+	return ranges
+}
+
+// isbnElementLength returns the length of the element following prefix
+// in digits, the rest of the ISBN, or false when prefix has no rules or
+// the range is not in use
+func isbnElementLength(prefix, digits string) (int, bool) {
+	window := (digits + strings.Repeat("0", isbnRangeWidth))[:isbnRangeWidth]
+	for _, rule := range isbnRanges[prefix] {
+		if window >= rule.start && window <= rule.end {
+			return rule.length, rule.length > 0
+		}
+	}
+	return 0, false
+}
+
+// hyphenateISBN writes a valid ISBN-13 as prefix, registration group,
+// registrant, publication and check digit, or reports false when the
+// embedded ranges do not cover it
+func hyphenateISBN(isbn13 string) (string, bool) {
+	prefix, rest := isbn13[:3], isbn13[3:12]
+	groupLength, ok := isbnElementLength(prefix, rest)
+	if !ok {
+		return "", false
+	}
+	group := rest[:groupLength]
+	registrantLength, ok := isbnElementLength(prefix+"-"+group, rest[groupLength:])
+	if !ok || groupLength+registrantLength >= len(rest) {
+		return "", false
+	}
+	registrant := rest[groupLength : groupLength+registrantLength]
+	publication := rest[groupLength+registrantLength:]
+	return strings.Join([]string{prefix, group, registrant, publication, isbn13[12:]}, "-"), true
+}
+
+func validateISBN10Checksum(isbn string) error {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(isbn[i]-'0') * (10 - i)
+	}
+	expected := (11 - sum%11) % 11
+
+	last := isbn[9]
+	actual := -1
+	switch {
+	case last == 'X' || last == 'x':
+		actual = 10
+	case last >= '0' && last <= '9':
+		actual = int(last - '0')
+	default:
+		return fmt.Errorf("%w: ISBN-10 check character must be a digit or X", ErrInvalidData)
+	}
+
+	if expected != actual {
+		check := fmt.Sprint(expected)
+		if expected == 10 {
+			check = "X"
+		}
+		return fmt.Errorf("%w: expected ISBN-10 check character %s, got %c", ErrChecksumMismatch, check, last)
+	}
+	return nil
+}
+
+func validateSupplement(supplement string) error {
+	if supplement == "" {
+		return nil
+	}
+	if !isNumeric(supplement) || (len(supplement) != 2 && len(supplement) != 5) {
+		return fmt.Errorf("%w: supplement must be 2 or 5 digits", ErrInvalidData)
+	}
+	return nil
+}
+
+// encodeEANSupplement encodes an EAN-2 or EAN-5 add-on symbol as modules
+func encodeEANSupplement(digits string) ([]bool, error) {
+	if err := validateSupplement(digits); err != nil {
+		return nil, err
+	}
+
+	var parity string
+	if len(digits) == 5 {
+		sum := 0
+		for i := 0; i < 5; i++ {
+			d := int(digits[i] - '0')
+			if i%2 == 0 {
+				sum += d * 3
+			} else {
+				sum += d * 9
+			}
+		}
+		parity = ean5Parity[sum%10]
+	} else {
+		value := int(digits[0]-'0')*10 + int(digits[1]-'0')
+		parity = ean2Parity[value%4]
+	}
+
+	pattern := "01011"
+	for i := 0; i < len(digits); i++ {
+		if i > 0 {
+			pattern += "01"
+		}
+		d := digits[i] - '0'
+		if parity[i] == 'L' {
+			pattern += eanLPatterns[d]
+		} else {
+			pattern += eanGPatterns[d]
+		}
+	}
+	return patternToModules(pattern), nil
+}
+
+func patternToModules(pattern string) []bool {
+	modules := make([]bool, len(pattern))
+	for i := range pattern {
+		modules[i] = pattern[i] == '1'
+	}
+	return modules
+}
+
+func buildISBNSymbol(data, supplement string) (*isbnSymbol, error) {
+	isbn13, display, err := normalizeISBN(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ean, err := barcode.Encode(barcode.TypeEAN13, isbn13)
+	if err != nil {
+		return nil, err
+	}
+	main := ean.Modules()
+
+	sym := &isbnSymbol{main: main, isbn13: isbn13, display: display, addonText: supplement}
+	if supplement != "" {
+		sym.supplement, err = encodeEANSupplement(supplement)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sym, nil
+}
+
+// totalModules returns the module count of the main symbol, gap and add-on
+func (s *isbnSymbol) totalModules() int {
+	if len(s.supplement) == 0 {
+		return len(s.main)
+	}
+	return len(s.main) + supplementGapModules + len(s.supplement)
+}
+
+// layout returns the module width and left offset for a canvas width
+func (s *isbnSymbol) layout(width int) (int, int, error) {
+	total := s.totalModules()
+	factor := width / total
+	if factor <= 0 {
+		return 0, 0, fmt.Errorf("%w: width must be at least %d for this ISBN symbol", ErrInvalidData, total)
+	}
+	return factor, (width - total*factor) / 2, nil
+}
+
+func (s *defaultBarcodeService) generateISBN(req models.GenerateRequest, colors barcodeColors) (BarcodeImage, error) {
+	sym, err := buildISBNSymbol(req.Data, req.Supplement)
+	if err != nil {
+		return BarcodeImage{}, err
+	}
+	if err := fitBarcodeWidth(&req, sym.totalModules()); err != nil {
+		return BarcodeImage{}, err
+	}
+
+	img := BarcodeImage{Width: req.Width, Height: isbnCanvasHeight(req.Height, req.IncludeText)}
+	switch req.Format {
+	case BarcodeFormatPNG:
+		img.Data, err = renderISBNPNG(sym, req.Width, req.Height, req.IncludeText, req.Deterministic, colors)
+		img.ContentType = "image/png"
+	case BarcodeFormatSVG:
+		img.Data, err = renderISBNSVG(sym, req.Width, req.Height, req.IncludeText, BarcodeAccessibleName(req), colors)
+		img.ContentType = "image/svg+xml"
+	default:
+		return BarcodeImage{}, ErrInvalidFormat
+	}
+	if err != nil {
+		return BarcodeImage{}, err
+	}
+	return img, nil
+}
+
+// isbnCanvasHeight returns the image height for bars of height: the ISBN
+// line above and, with includeText, the digits below
+func isbnCanvasHeight(height int, includeText bool) int {
+	if includeText {
+		return textPaddingHeight + height + textPaddingHeight
+	}
+	return textPaddingHeight + height
+}
+
+// renderISBNPNG draws the ISBN line above the symbol, the EAN-13 bars, and
+// the add-on bars shortened by one text row so their digits sit on top.
+func renderISBNPNG(sym *isbnSymbol, width, height int, includeText, deterministic bool, colors barcodeColors) ([]byte, error) {
+	factor, offset, err := sym.layout(width)
+	if err != nil {
+		return nil, err
+	}
+
+	top := textPaddingHeight
+	totalHeight := isbnCanvasHeight(height, includeText)
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, totalHeight))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{colors.background.rgba}, image.Point{}, draw.Src)
+	bars := &image.Uniform{colors.foreground.rgba}
+
+	mainWidth := len(sym.main) * factor
+	for i, bar := range sym.main {
+		if bar {
+			x := offset + i*factor
+			draw.Draw(canvas, image.Rect(x, top, x+factor, top+height), bars, image.Point{}, draw.Src)
+		}
+	}
+	drawBarcodeTextInRegion(canvas, builtinTextFace, sym.display, top-4, offset, mainWidth, colors.text.rgba)
+
+	if len(sym.supplement) > 0 {
+		addonX := offset + (len(sym.main)+supplementGapModules)*factor
+		addonWidth := len(sym.supplement) * factor
+		for i, bar := range sym.supplement {
+			if bar {
+				x := addonX + i*factor
+				draw.Draw(canvas, image.Rect(x, top+textPaddingHeight, x+factor, top+height), bars, image.Point{}, draw.Src)
+			}
+		}
+		drawBarcodeTextInRegion(canvas, builtinTextFace, sym.addonText, top+textPaddingHeight-4, addonX, addonWidth, colors.text.rgba)
+	}
+
+	if includeText {
+		drawBarcodeTextInRegion(canvas, builtinTextFace, sym.isbn13, top+height+textPaddingHeight-4, offset, mainWidth, colors.text.rgba)
+	}
+
+	data, err := encodePNG(canvas, deterministic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return data, nil
+}
+
+func renderISBNSVG(sym *isbnSymbol, width, height int, includeText bool, meta svgmeta.Metadata, colors barcodeColors) ([]byte, error) {
+	factor, offset, err := sym.layout(width)
+	if err != nil {
+		return nil, err
+	}
+
+	top := textPaddingHeight
+	totalHeight := isbnCanvasHeight(height, includeText)
+
+	var buf bytes.Buffer
+	writeBarcodeSVGStart(&buf, width, totalHeight, meta)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, width, totalHeight, colors.background.svg)
+	buf.WriteByte('\n')
+
+	mainWidth := len(sym.main) * factor
+	writeSVGModuleRuns(&buf, sym.main, offset, factor, top, height, colors.foreground.svg)
+	writeSVGText(&buf, sym.display, offset+mainWidth/2, top-4, colors.text.svg)
+
+	if len(sym.supplement) > 0 {
+		addonX := offset + (len(sym.main)+supplementGapModules)*factor
+		addonWidth := len(sym.supplement) * factor
+		writeSVGModuleRuns(&buf, sym.supplement, addonX, factor, top+textPaddingHeight, height-textPaddingHeight, colors.foreground.svg)
+		writeSVGText(&buf, sym.addonText, addonX+addonWidth/2, top+textPaddingHeight-4, colors.text.svg)
+	}
+
+	if includeText {
+		writeSVGText(&buf, sym.isbn13, offset+mainWidth/2, top+height+textPaddingHeight-2, colors.text.svg)
+	}
+
+	buf.WriteString(`</svg>`)
+	return buf.Bytes(), nil
+}
+
+// writeSVGModuleRuns writes one rect filled with fill per run of
+// consecutive dark modules
+func writeSVGModuleRuns(buf *bytes.Buffer, modules []bool, x0, factor, y, height int, fill string) {
+	start := -1
+	for i := 0; i <= len(modules); i++ {
+		bar := i < len(modules) && modules[i]
+		if bar && start == -1 {
+			start = i
+		} else if !bar && start != -1 {
+			fmt.Fprintf(buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, x0+start*factor, y, (i-start)*factor, height, fill)
+			buf.WriteByte('\n')
+			start = -1
+		}
+	}
+}
+
+func writeSVGText(buf *bytes.Buffer, text string, x, y int, fill string) {
+	fmt.Fprintf(buf, `<text x="%d" y="%d" text-anchor="middle" font-family="monospace" font-size="12" fill="%s">%s</text>`,
+		x, y, fill, svgmeta.Escape(text))
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,199 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func modulePattern(modules []bool) string {
+	var b strings.Builder
+	for _, m := range modules {
+		if m {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		data, isbn13, display string
+	}{
+		{"0-306-40615-2", "9780306406157", "ISBN 978-0-306-40615-7"},
+		{"ISBN: 0-8044-2957-X", "9780804429573", "ISBN 978-0-8044-2957-3"},
+		{"978-3-16-148410-0", "9783161484100", "ISBN 978-3-16-148410-0"},
+		{"979 10 90636 07 1", "9791090636071", "ISBN 979-10-90636-07-1"},
+		// Unhyphenated input is hyphenated from the ranges, and so is
+		// input hyphenated in the wrong places
+		{"0306406152", "9780306406157", "ISBN 978-0-306-40615-7"},
+		{"9780306406157", "9780306406157", "ISBN 978-0-306-40615-7"},
+		{"9781402894626", "9781402894626", "ISBN 978-1-4028-9462-6"},
+		{"9781861972712", "9781861972712", "ISBN 978-1-86197-271-2"},
+		{"9783161484100", "9783161484100", "ISBN 978-3-16-148410-0"},
+		{"9783040012349", "9783040012349", "ISBN 978-3-04-001234-9"},
+		{"9791090636071", "9791090636071", "ISBN 979-10-90636-07-1"},
+		{"978-03064-0615-7", "9780306406157", "ISBN 978-0-306-40615-7"},
+		// Groups without embedded ranges keep the caller's hyphens
+		{"9784101092010", "9784101092010", "ISBN 9784101092010"},
+		{"978-4-10-109201-0", "9784101092010", "ISBN 978-4-10-109201-0"},
+	}
+	for _, tt := range tests {
+		isbn13, display, err := normalizeISBN(tt.data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.data, err)
+			continue
+		}
+		if isbn13 != tt.isbn13 || display != tt.display {
+			t.Errorf("%s: normalizeISBN = %s, %q, want %s, %q", tt.data, isbn13, display, tt.isbn13, tt.display)
+		}
+	}
+
+	invalid := map[string]error{
+		"0-306-40615-3":     ErrChecksumMismatch,
+		"978-3-16-148410-1": ErrChecksumMismatch,
+		"030640615Y":        ErrInvalidData,
+		"4006381333931":     ErrInvalidData, // an EAN-13 outside the 978/979 prefixes
+		"12345":             ErrInvalidData,
+	}
+	for data, want := range invalid {
+		if _, _, err := normalizeISBN(data); !errors.Is(err, want) {
+			t.Errorf("%s: err = %v, want %v", data, err, want)
+		}
+	}
+}
+
+// TestISBNRanges checks that the rules of every embedded prefix cover
+// all 7 digit windows once, in order
+func TestISBNRanges(t *testing.T) {
+	for _, prefix := range []string{"978", "979", "978-0", "978-1", "978-3", "979-10"} {
+		rules := isbnRanges[prefix]
+		next := "0000000"
+		for _, rule := range rules {
+			if rule.start != next || rule.end < rule.start {
+				t.Errorf("%s: rule %s-%s does not follow %s", prefix, rule.start, rule.end, next)
+			}
+			n, _ := strconv.Atoi(rule.end)
+			next = fmt.Sprintf("%07d", n+1)
+		}
+		if next != "10000000" {
+			t.Errorf("%s: rules end before %s", prefix, next)
+		}
+	}
+}
+
+// The add-on patterns follow the published EAN-2/EAN-5 tables: the start
+// guard 01011, then each digit in the L or G set its parity selects,
+// separated by 01
+func TestEncodeEANSupplement(t *testing.T) {
+	tests := []struct {
+		digits, want string
+	}{
+		// 12 mod 4 = 0: LL
+		{"12", "01011" + "0011001" + "01" + "0010011"},
+		// 05 mod 4 = 1: LG
+		{"05", "01011" + "0001101" + "01" + "0111001"},
+		// 99 mod 4 = 3: GG
+		{"99", "01011" + "0010111" + "01" + "0010111"},
+		// 5·3 + 2·9 + 4·3 + 9·9 + 5·3 = 141, check digit 1: GLGLL
+		{"52495", "01011" + "0111001" + "01" + "0010011" + "01" + "0011101" + "01" + "0001011" + "01" + "0110001"},
+		// 9·3 + 0·9 + 0·3 + 0·9 + 0·3 = 27, check digit 7: LGLGL
+		{"90000", "01011" + "0001011" + "01" + "0100111" + "01" + "0001101" + "01" + "0100111" + "01" + "0001101"},
+	}
+	for _, tt := range tests {
+		modules, err := encodeEANSupplement(tt.digits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := modulePattern(modules); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.digits, got, tt.want)
+		}
+	}
+
+	for _, digits := range []string{"1", "123", "1234", "12a45"} {
+		if _, err := encodeEANSupplement(digits); !errors.Is(err, ErrInvalidData) {
+			t.Errorf("%s: err = %v, want ErrInvalidData", digits, err)
+		}
+	}
+}
+
+func TestGenerateISBNWithSupplement(t *testing.T) {
+	// EAN-13, gap and EAN-5: 95 + 9 + 48 modules at 2 pixels each
+	const factor, height = 2, 60
+	width := (95 + supplementGapModules + 48) * factor
+	addonX := (95 + supplementGapModules) * factor
+	service := NewDefaultBarcodeService()
+	req := models.GenerateRequest{
+		Type:       BarcodeTypeISBN,
+		Data:       "978-3-16-148410-0",
+		Supplement: "52495",
+		Width:      width,
+		Height:     height,
+		FitMode:    BarcodeFitStrict,
+	}
+
+	req.Format = BarcodeFormatPNG
+	img, err := service.Generate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHeight := textPaddingHeight + height
+	if b := decoded.Bounds(); b.Dx() != width || b.Dy() != wantHeight || img.Width != width || img.Height != wantHeight {
+		t.Fatalf("PNG is %v, reported %dx%d, want %dx%d", b, img.Width, img.Height, width, wantHeight)
+	}
+	dark := func(x, y int) bool {
+		r, _, _, _ := decoded.At(x, y).RGBA()
+		return r < 0x8000
+	}
+	// The first bar of the add-on starts after its leading light module,
+	// shortened by a text row for the raised digits
+	bar := addonX + factor
+	if dark(bar-1, textPaddingHeight+height-1) || !dark(bar, textPaddingHeight+height-1) {
+		t.Errorf("the add-on does not start at x = %d", bar)
+	}
+	if dark(bar, 2*textPaddingHeight-1) || !dark(bar, 2*textPaddingHeight) {
+		t.Errorf("the add-on bars do not start one text row below the main bars")
+	}
+	for x := 95 * factor; x < addonX; x++ {
+		if dark(x, textPaddingHeight+height-1) {
+			t.Fatalf("the gap between the symbols is dark at x = %d", x)
+		}
+	}
+
+	req.Format = BarcodeFormatSVG
+	req.IncludeText = true
+	img, err = service.Generate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := string(img.Data)
+	wantHeight = 2*textPaddingHeight + height
+	if img.Width != width || img.Height != wantHeight || !strings.Contains(svg, fmt.Sprintf(`width="%d" height="%d"`, width, wantHeight)) {
+		t.Errorf("SVG is %dx%d, want %dx%d", img.Width, img.Height, width, wantHeight)
+	}
+	for _, want := range []string{
+		fmt.Sprintf(`<rect x="%d" y="%d" width="%d" height="%d"`, bar, 2*textPaddingHeight, factor, height-textPaddingHeight),
+		">ISBN 978-3-16-148410-0</text>",
+		">52495</text>",
+		">9783161484100</text>",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG lacks %s", want)
+		}
+	}
+	if n := strings.Count(svg, "<svg"); n != 1 {
+		t.Errorf("SVG holds %d documents, want one", n)
+	}
+}
@@ -0,0 +1,488 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/pkg/generate/barcode"
+	"github.com/innovelabs/microtools-go/pkg/generate/qr"
+)
+
+const (
+	// LabelTypeQR selects a QR code label; other types are barcode types
+	LabelTypeQR = "QR"
+
+	// MaxLabelRows is the largest number of labels one sheet request may hold
+	MaxLabelRows = 2000
+
+	// DefaultLabelFont is the embedded font label text is set in
+	DefaultLabelFont = "go-regular"
+
+	// The defaults describe a common 3x7 A4 sheet of 63.5x38.1 mm labels
+	defaultLabelSymbology = BarcodeTypeCode128
+	defaultLabelPageSize  = "A4"
+	defaultLabelColumns   = 3
+	defaultLabelRows      = 7
+	defaultLabelWidthMM   = 63.5
+	defaultLabelHeightMM  = 38.1
+
+	maxLabelColumns   = 10
+	maxLabelRowsPage  = 20
+	minLabelSizeMM    = 15
+	labelPaddingMM    = 2
+	labelTextHeightMM = 4
+	labelTextSizePt   = 8
+	// labelPixelsPerMM is the resolution symbols are rendered at (about 200 dpi)
+	labelPixelsPerMM = 8
+	minQRLabelSize   = 64
+)
+
+var (
+	ErrInvalidLabelCSV     = errors.New("invalid label CSV")
+	ErrInvalidLabelOptions = errors.New("invalid label options")
+	ErrTooManyLabels       = fmt.Errorf("label sheets are limited to %d rows", MaxLabelRows)
+)
+
+// labelPageSizes are the supported page sizes in millimetres, keyed by
+// lowercase name
+var labelPageSizes = map[string]struct {
+	name          string
+	width, height float64
+}{
+	"a4":     {"A4", 210, 297},
+	"a5":     {"A5", 148, 210},
+	"letter": {"Letter", 215.9, 279.4},
+	"legal":  {"Legal", 215.9, 355.6},
+}
+
+// labelTypes maps lowercase type names to the canonical ones
+var labelTypes = map[string]string{
+	"qr":         LabelTypeQR,
+	"upc-a":      BarcodeTypeUPCA,
+	"ean-13":     BarcodeTypeEAN13,
+	"code128":    BarcodeTypeCode128,
+	"code93":     BarcodeTypeCode93,
+	"code39":     BarcodeTypeCode39,
+	"itf-14":     BarcodeTypeITF14,
+	"isbn":       BarcodeTypeISBN,
+	"pharmacode": BarcodeTypePharmacode,
+}
+
+// LabelService validates and renders printable label sheets
+type LabelService interface {
+	// ValidateLabels checks the options and every row without rendering
+	// anything. Bad options are an error; bad rows are reported one by one.
+	ValidateLabels(opts models.LabelSheetOptions, rows []models.LabelRow) ([]models.LabelRowError, error)
+	// GenerateLabels renders validated rows as a PDF and returns its page
+	// count. It stops with ctx's error once ctx is done.
+	GenerateLabels(ctx context.Context, opts models.LabelSheetOptions, rows []models.LabelRow) ([]byte, int, error)
+}
+
+type defaultLabelService struct {
+	fonts *FontSet
+}
+
+// NewDefaultLabelService creates a label service with the embedded fonts
+func NewDefaultLabelService() LabelService {
+	return NewLabelService(NewEmbeddedFontSet())
+}
+
+// NewLabelService creates a label service setting text in fonts
+func NewLabelService(fonts *FontSet) LabelService {
+	return &defaultLabelService{fonts: fonts}
+}
+
+// ParseLabelCSV reads label rows from a CSV whose header names a data
+// column and optionally text and type columns, in any order and case.
+// Blank rows are skipped.
+func ParseLabelCSV(r io.Reader) ([]models.LabelRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidLabelCSV)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLabelCSV, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, seen := columns[name]; !seen {
+			columns[name] = i
+		}
+	}
+	dataCol, ok := columns["data"]
+	if !ok {
+		return nil, fmt.Errorf("%w: header must have a data column", ErrInvalidLabelCSV)
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []models.LabelRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidLabelCSV, err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if len(rows) == MaxLabelRows {
+			return nil, ErrTooManyLabels
+		}
+		line, _ := reader.FieldPos(0)
+		row := models.LabelRow{Row: line, Text: field(record, "text"), Type: field(record, "type")}
+		if dataCol < len(record) {
+			row.Data = strings.TrimSpace(record[dataCol])
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no label rows", ErrInvalidLabelCSV)
+	}
+	return rows, nil
+}
+
+// labelLayout is the resolved geometry of a sheet, in millimetres
+type labelLayout struct {
+	symbology             string
+	font                  string
+	pageWidth, pageHeight float64
+	columns, rows         int
+	width, height         float64
+	marginX, marginY      float64
+}
+
+func (l labelLayout) perPage() int {
+	return l.columns * l.rows
+}
+
+func newLabelLayout(opts models.LabelSheetOptions) (labelLayout, error) {
+	l := labelLayout{
+		symbology: defaultLabelSymbology,
+		font:      DefaultLabelFont,
+		columns:   defaultLabelColumns,
+		rows:      defaultLabelRows,
+		width:     defaultLabelWidthMM,
+		height:    defaultLabelHeightMM,
+	}
+	if opts.Symbology != "" {
+		symbology, ok := labelTypes[strings.ToLower(opts.Symbology)]
+		if !ok {
+			return l, fmt.Errorf("%w: unsupported symbology %q: must be one of %s", ErrInvalidLabelOptions, opts.Symbology, labelTypeNames())
+		}
+		l.symbology = symbology
+	}
+	if opts.Font != "" {
+		l.font = opts.Font
+	}
+	pageSize := defaultLabelPageSize
+	if opts.PageSize != "" {
+		pageSize = opts.PageSize
+	}
+	page, ok := labelPageSizes[strings.ToLower(pageSize)]
+	if !ok {
+		return l, fmt.Errorf("%w: unsupported page_size %q: must be A4, A5, Letter or Legal", ErrInvalidLabelOptions, pageSize)
+	}
+	l.pageWidth, l.pageHeight = page.width, page.height
+	if opts.Columns != 0 {
+		l.columns = opts.Columns
+	}
+	if opts.Rows != 0 {
+		l.rows = opts.Rows
+	}
+	if opts.LabelWidthMM != 0 {
+		l.width = opts.LabelWidthMM
+	}
+	if opts.LabelHeightMM != 0 {
+		l.height = opts.LabelHeightMM
+	}
+
+	if l.columns < 1 || l.columns > maxLabelColumns {
+		return l, fmt.Errorf("%w: columns must be between 1 and %d", ErrInvalidLabelOptions, maxLabelColumns)
+	}
+	if l.rows < 1 || l.rows > maxLabelRowsPage {
+		return l, fmt.Errorf("%w: rows must be between 1 and %d", ErrInvalidLabelOptions, maxLabelRowsPage)
+	}
+	if l.width < minLabelSizeMM || l.height < minLabelSizeMM {
+		return l, fmt.Errorf("%w: labels must be at least %d mm on each side", ErrInvalidLabelOptions, minLabelSizeMM)
+	}
+	if float64(l.columns)*l.width > l.pageWidth || float64(l.rows)*l.height > l.pageHeight {
+		return l, fmt.Errorf("%w: %d x %d labels of %.1f x %.1f mm do not fit on %s", ErrInvalidLabelOptions, l.columns, l.rows, l.width, l.height, page.name)
+	}
+	l.marginX = (l.pageWidth - float64(l.columns)*l.width) / 2
+	l.marginY = (l.pageHeight - float64(l.rows)*l.height) / 2
+	return l, nil
+}
+
+func labelTypeNames() string {
+	names := make([]string, 0, len(labelTypes))
+	for _, name := range labelTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// labelType returns the canonical type of row
+func (l labelLayout) labelType(row models.LabelRow) (string, error) {
+	if row.Type == "" {
+		return l.symbology, nil
+	}
+	t, ok := labelTypes[strings.ToLower(row.Type)]
+	if !ok {
+		return "", fmt.Errorf("unsupported type %q: must be one of %s", row.Type, labelTypeNames())
+	}
+	return t, nil
+}
+
+// labelText is the line printed under the symbol: the row's text, or the
+// data itself for barcodes
+func labelText(row models.LabelRow, labelType string) string {
+	if row.Text != "" || labelType == LabelTypeQR {
+		return row.Text
+	}
+	return row.Data
+}
+
+// symbolPixels returns the pixel size a symbol is rendered at to fill the
+// area left on a label
+func (l labelLayout) symbolPixels(withText bool) (int, int) {
+	areaWidth := l.width - 2*labelPaddingMM
+	areaHeight := l.height - 2*labelPaddingMM
+	if withText {
+		areaHeight -= labelTextHeightMM
+	}
+	clamp := func(mm float64, lo, hi int) int {
+		return min(max(int(math.Round(mm*labelPixelsPerMM)), lo), hi)
+	}
+	return clamp(areaWidth, minBarcodeWidth, maxBarcodeWidth), clamp(areaHeight, minBarcodeHeight, maxBarcodeHeight)
+}
+
+// LabelSymbolPixels returns the largest pixel size a symbol of a sheet
+// with opts is rendered at, the size of a label without a text line
+func LabelSymbolPixels(opts models.LabelSheetOptions) (int, int, error) {
+	layout, err := newLabelLayout(opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	width, height := layout.symbolPixels(false)
+	return width, height, nil
+}
+
+// ValidateLabels checks every row against its symbology and the label size
+func (s *defaultLabelService) ValidateLabels(opts models.LabelSheetOptions, rows []models.LabelRow) ([]models.LabelRowError, error) {
+	layout, err := newLabelLayout(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > MaxLabelRows {
+		return nil, ErrTooManyLabels
+	}
+	if err := s.checkFont(layout.font); err != nil {
+		return nil, err
+	}
+
+	var rowErrors []models.LabelRowError
+	for _, row := range rows {
+		if err := layout.validateRow(row); err != nil {
+			rowErrors = append(rowErrors, models.LabelRowError{Row: row.Row, Error: err.Error()})
+		}
+	}
+	return rowErrors, nil
+}
+
+// checkFont reports whether name is loaded and can be embedded in a PDF
+func (s *defaultLabelService) checkFont(name string) error {
+	data, err := s.fonts.fontData(name)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidLabelOptions, err)
+	}
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(name, "", data)
+	if pdf.Err() {
+		return fmt.Errorf("%w: font %q cannot be embedded in a PDF: %v", ErrInvalidLabelOptions, name, pdf.Error())
+	}
+	return nil
+}
+
+func (l labelLayout) validateRow(row models.LabelRow) error {
+	if row.Data == "" {
+		return errors.New("data is required")
+	}
+	labelType, err := l.labelType(row)
+	if err != nil {
+		return err
+	}
+	width, _ := l.symbolPixels(labelText(row, labelType) != "")
+
+	switch labelType {
+	case LabelTypeQR:
+		if _, err := qr.Encode(row.Data, qr.LevelMedium); err != nil {
+			return errors.New("data is too long for a QR code")
+		}
+	case BarcodeTypeISBN:
+		sym, err := buildISBNSymbol(row.Data, "")
+		if err != nil {
+			return err
+		}
+		if _, _, err := sym.layout(width); err != nil {
+			return fmt.Errorf("%w: ISBN does not fit a %.1f mm label", ErrInvalidData, l.width)
+		}
+	default:
+		sym, err := barcode.Encode(labelType, row.Data)
+		if err != nil {
+			return err
+		}
+		if sym.Width() > width {
+			return fmt.Errorf("%w: %d modules do not fit a %.1f mm label", ErrInvalidData, sym.Width(), l.width)
+		}
+	}
+	return nil
+}
+
+// GenerateLabels fills pages left to right, top to bottom. Identical
+// symbols are rendered and embedded once. ctx is checked before each
+// label, so a cancelled request stops rendering within one label.
+func (s *defaultLabelService) GenerateLabels(ctx context.Context, opts models.LabelSheetOptions, rows []models.LabelRow) ([]byte, int, error) {
+	layout, err := newLabelLayout(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	fontData, err := s.fonts.fontData(layout.font)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidLabelOptions, err)
+	}
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: layout.pageWidth, Ht: layout.pageHeight},
+	})
+	pdf.SetCreator("Micro API", true)
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddUTF8FontFromBytes(layout.font, "", fontData)
+	pdf.SetFont(layout.font, "", labelTextSizePt)
+
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		slot := i % layout.perPage()
+		if slot == 0 {
+			pdf.AddPage()
+		}
+		x := layout.marginX + float64(slot%layout.columns)*layout.width
+		y := layout.marginY + float64(slot/layout.columns)*layout.height
+		if err := s.drawLabel(pdf, layout, row, x, y); err != nil {
+			return nil, 0, fmt.Errorf("row %d: %w", row.Row, err)
+		}
+		if pdf.Err() {
+			return nil, 0, fmt.Errorf("row %d: %w", row.Row, pdf.Error())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, 0, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return buf.Bytes(), pdf.PageCount(), nil
+}
+
+// drawLabel places the symbol centered in the label, scaled to fit while
+// keeping its aspect ratio, with the text line underneath
+func (s *defaultLabelService) drawLabel(pdf *fpdf.Fpdf, layout labelLayout, row models.LabelRow, x, y float64) error {
+	labelType, err := layout.labelType(row)
+	if err != nil {
+		return err
+	}
+	text := labelText(row, labelType)
+	width, height := layout.symbolPixels(text != "")
+
+	name := fmt.Sprintf("%s:%dx%d:%s", labelType, width, height, row.Data)
+	info := pdf.GetImageInfo(name)
+	if info == nil {
+		png, err := renderLabelSymbol(labelType, row.Data, width, height)
+		if err != nil {
+			return err
+		}
+		info = pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+		if info == nil {
+			return pdf.Error()
+		}
+	}
+
+	areaWidth := layout.width - 2*labelPaddingMM
+	areaHeight := layout.height - 2*labelPaddingMM
+	if text != "" {
+		areaHeight -= labelTextHeightMM
+	}
+	scale := math.Min(areaWidth/info.Width(), areaHeight/info.Height())
+	imageWidth, imageHeight := info.Width()*scale, info.Height()*scale
+	pdf.ImageOptions(name,
+		x+labelPaddingMM+(areaWidth-imageWidth)/2,
+		y+labelPaddingMM+(areaHeight-imageHeight)/2,
+		imageWidth, imageHeight, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+	if text != "" {
+		pdf.SetXY(x+labelPaddingMM, y+layout.height-labelPaddingMM-labelTextHeightMM)
+		pdf.CellFormat(areaWidth, labelTextHeightMM, fitLabelText(pdf, text, areaWidth), "", 0, "C", false, 0, "")
+	}
+	return nil
+}
+
+// renderLabelSymbol renders a symbol without its own text row
+func renderLabelSymbol(labelType, data string, width, height int) ([]byte, error) {
+	switch labelType {
+	case LabelTypeQR:
+		return GenerateQR(context.Background(), models.QRRequest{Type: "text", Data: data, Options: models.QROptions{Size: max(min(width, height), minQRLabelSize)}})
+	case BarcodeTypeISBN:
+		sym, err := buildISBNSymbol(data, "")
+		if err != nil {
+			return nil, err
+		}
+		return renderISBNPNG(sym, width, height, false, false, defaultBarcodeColors)
+	default:
+		sym, err := barcode.Encode(labelType, data)
+		if err != nil {
+			return nil, err
+		}
+		return renderBarcodePNG(sym, models.GenerateRequest{Type: labelType, Data: data, Width: width, Height: height}, barcodeText{}, defaultBarcodeColors)
+	}
+}
+
+// fitLabelText shortens text with an ellipsis until it fits width
+func fitLabelText(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	for len(text) > 0 {
+		_, size := utf8.DecodeLastRuneInString(text)
+		text = text[:len(text)-size]
+		if pdf.GetStringWidth(text+"…") <= width {
+			return text + "…"
+		}
+	}
+	return ""
+}
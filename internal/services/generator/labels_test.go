@@ -0,0 +1,48 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// cancelAfter reports itself cancelled from the call of Err after the
+// first n, so a render can be stopped partway through
+type cancelAfter struct {
+	context.Context
+	n, checks int
+}
+
+func (c *cancelAfter) Err() error {
+	c.checks++
+	if c.checks > c.n {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestGenerateLabelsStopsWhenCancelled(t *testing.T) {
+	rows := make([]models.LabelRow, 210)
+	for i := range rows {
+		rows[i] = models.LabelRow{Row: i + 2, Data: fmt.Sprintf("https://example.com/item/%d", i), Type: "QR"}
+	}
+	labels := NewDefaultLabelService()
+
+	pdf, pages, err := labels.GenerateLabels(context.Background(), models.LabelSheetOptions{}, rows)
+	if err != nil || pages != 10 || len(pdf) == 0 {
+		t.Fatalf("uncancelled: %d pages, %d bytes, %v", pages, len(pdf), err)
+	}
+
+	// Rendering stops at the first label after the cancellation
+	ctx := &cancelAfter{Context: context.Background(), n: 30}
+	pdf, pages, err = labels.GenerateLabels(ctx, models.LabelSheetOptions{}, rows)
+	if !errors.Is(err, context.Canceled) || pdf != nil || pages != 0 {
+		t.Fatalf("cancelled: %d pages, %d bytes, err %v", pages, len(pdf), err)
+	}
+	if ctx.checks != 31 {
+		t.Errorf("context checked %d times, want once per label up to the 31st", ctx.checks)
+	}
+}
@@ -1,13 +1,52 @@
 package generator
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
+	"image/png"
 	"strings"
 
 	"github.com/innovelabs/microtools-go/internal/models"
-	qrcode "github.com/skip2/go-qrcode"
+	"github.com/innovelabs/microtools-go/internal/warnings"
+	"github.com/innovelabs/microtools-go/pkg/canonicalpng"
+	"github.com/innovelabs/microtools-go/pkg/generate/qr"
+)
+
+// QR output formats
+const (
+	QRFormatPNG     = "png"
+	QRFormatSVG     = "svg"
+	QRFormatDataURI = "datauri"
+)
+
+// Output modes of QR codes and barcodes: the image bytes, or the image
+// base64-encoded in a JSON envelope
+const (
+	OutputBinary = "binary"
+	OutputJSON   = "json"
+)
+
+// minQRModulePixels is the smallest module size, quiet zone included,
+// below which a code is reported as hard to scan
+const minQRModulePixels = 2
+
+// Errors of QR requests, wrapped with the details of the request
+var (
+	ErrQRTypeRequired      = errors.New("type is required")
+	ErrUnsupportedQRType   = errors.New("unsupported type")
+	ErrQRDataRequired      = errors.New("data is required for this type")
+	ErrInvalidQRSize       = errors.New("size must be between 64 and 2048")
+	ErrUnsupportedQRFormat = errors.New("unsupported format")
+	ErrUnsupportedQROutput = errors.New("unsupported output")
+	ErrInvalidQRData       = errors.New("invalid data")
+	// ErrQRDataTooLong is returned for a payload too long for a QR code
+	// at the requested error correction level
+	ErrQRDataTooLong = qr.ErrEncode
 )
 
 // Supported QR types
@@ -17,31 +56,72 @@ var supportedTypes = map[string]bool{
 	"event": true, "json": true,
 }
 
-// ApplyDefaults applies default values to QR request
+// ApplyDefaults applies default values to QR request. Error correction
+// is H with a logo, M otherwise.
 func ApplyDefaults(req *models.QRRequest) {
 	if req.Options.Size == 0 {
 		req.Options.Size = 256
 	}
+	if req.Options.Logo != "" {
+		if req.Options.ErrorCorrection == "" {
+			req.Options.ErrorCorrection = string(qr.LevelHigh)
+		}
+		if req.Options.LogoSizePercent == 0 {
+			req.Options.LogoSizePercent = DefaultQRLogoSizePercent
+		}
+	}
 	if req.Options.ErrorCorrection == "" {
 		req.Options.ErrorCorrection = "M"
 	}
+	if req.Options.Format == "" {
+		req.Options.Format = QRFormatPNG
+	}
+	if req.Options.Output == "" {
+		req.Options.Output = OutputBinary
+	}
 }
 
 // ValidateRequest validates a QR generation request
 func ValidateRequest(req models.QRRequest) error {
 	if req.Type == "" {
-		return errors.New("type is required")
+		return ErrQRTypeRequired
 	}
 	if !supportedTypes[req.Type] {
-		return fmt.Errorf("unsupported type: %s", req.Type)
+		return fmt.Errorf("%w: %s", ErrUnsupportedQRType, req.Type)
 	}
 	if req.Data == "" && req.Type != "wifi" && req.Type != "vcard" && req.Type != "event" {
-		return errors.New("data is required for this type")
+		return ErrQRDataRequired
 	}
 	if req.Options.Size < 64 || req.Options.Size > 2048 {
-		return errors.New("size must be between 64 and 2048")
+		return ErrInvalidQRSize
+	}
+	switch req.Options.Format {
+	case QRFormatPNG, QRFormatSVG, QRFormatDataURI:
+	default:
+		return fmt.Errorf("%w: %s: must be png, svg or datauri", ErrUnsupportedQRFormat, req.Options.Format)
+	}
+	switch req.Options.Output {
+	case OutputBinary:
+	case OutputJSON:
+		// A data URL is already text; the envelope carries the image
+		if req.Options.Format == QRFormatDataURI {
+			return fmt.Errorf("%w: json takes format png or svg", ErrUnsupportedQROutput)
+		}
+	default:
+		return fmt.Errorf("%w: %s: must be binary or json", ErrUnsupportedQROutput, req.Options.Output)
 	}
-	return nil
+	if req.Options.Logo != "" {
+		if req.Options.Format == QRFormatSVG {
+			return fmt.Errorf("%w: svg: a logo needs png or datauri", ErrUnsupportedQRFormat)
+		}
+		if req.Options.LogoSizePercent < MinQRLogoSizePercent || req.Options.LogoSizePercent > MaxQRLogoSizePercent {
+			return ErrInvalidQRLogoSize
+		}
+		if strings.EqualFold(req.Options.ErrorCorrection, string(qr.LevelLow)) {
+			return ErrQRLogoLevel
+		}
+	}
+	return validateAccessibleText(req.Options.Title, req.Options.Desc)
 }
 
 // BuildPayload builds the QR code payload based on type
@@ -51,7 +131,7 @@ func BuildPayload(qrType, data string) (string, error) {
 		return data, nil
 	case "url":
 		if !strings.HasPrefix(data, "http://") && !strings.HasPrefix(data, "https://") {
-			return "", errors.New("URL must start with http:// or https://")
+			return "", fmt.Errorf("%w: URL must start with http:// or https://", ErrInvalidQRData)
 		}
 		return data, nil
 	case "email":
@@ -63,13 +143,13 @@ func BuildPayload(qrType, data string) (string, error) {
 	case "wifi":
 		var wifi models.WifiData
 		if err := json.Unmarshal([]byte(data), &wifi); err != nil {
-			return "", errors.New("invalid WiFi data format")
+			return "", fmt.Errorf("%w: invalid WiFi data format", ErrInvalidQRData)
 		}
-		return fmt.Sprintf("WIFI:T:%s;S:%s;P:%s;;", wifi.Security, wifi.SSID, wifi.Password), nil
+		return WifiPayload(wifi), nil
 	case "vcard":
 		var vcard models.VCardData
 		if err := json.Unmarshal([]byte(data), &vcard); err != nil {
-			return "", errors.New("invalid vCard data format")
+			return "", fmt.Errorf("%w: invalid vCard data format", ErrInvalidQRData)
 		}
 		return fmt.Sprintf("BEGIN:VCARD\nVERSION:3.0\nFN:%s %s\nORG:%s\nTEL:%s\nEMAIL:%s\nEND:VCARD",
 			vcard.FirstName, vcard.LastName, vcard.Org, vcard.Phone, vcard.Email), nil
@@ -78,52 +158,145 @@ func BuildPayload(qrType, data string) (string, error) {
 	case "event":
 		var event models.EventData
 		if err := json.Unmarshal([]byte(data), &event); err != nil {
-			return "", errors.New("invalid event data format")
+			return "", fmt.Errorf("%w: invalid event data format", ErrInvalidQRData)
 		}
-		return fmt.Sprintf("BEGIN:VEVENT\nSUMMARY:%s\nDTSTART:%s\nDTEND:%s\nEND:VEVENT",
-			event.Summary, event.Start, event.End), nil
+		return BuildEventPayload(event)
 	case "json":
 		return data, nil
 	default:
-		return "", fmt.Errorf("unsupported type: %s", qrType)
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedQRType, qrType)
 	}
 }
 
-// ParseErrorCorrection parses error correction level
-func ParseErrorCorrection(level string) qrcode.RecoveryLevel {
-	switch strings.ToUpper(level) {
-	case "L":
-		return qrcode.Low
-	case "M":
-		return qrcode.Medium
-	case "Q":
-		return qrcode.High
-	case "H":
-		return qrcode.Highest
-	default:
-		return qrcode.Medium
+// wifiEscaper escapes the characters that delimit fields of a WIFI: payload
+var wifiEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`)
+
+// WifiPayload builds the WIFI: payload joining a network, with the SSID
+// and password escaped so they may contain ; , : " and \
+func WifiPayload(wifi models.WifiData) string {
+	return fmt.Sprintf("WIFI:T:%s;S:%s;P:%s;;", wifi.Security, wifiEscaper.Replace(wifi.SSID), wifiEscaper.Replace(wifi.Password))
+}
+
+// isKnownErrorCorrection reports whether ParseErrorCorrection recognises level
+func isKnownErrorCorrection(level string) bool {
+	return qr.Level(strings.ToUpper(level)).Valid()
+}
+
+// ParseErrorCorrection parses error correction level, case-insensitively;
+// an unknown level is M
+func ParseErrorCorrection(level string) qr.Level {
+	if !isKnownErrorCorrection(level) {
+		return qr.LevelMedium
 	}
+	return qr.Level(strings.ToUpper(level))
 }
 
-// GenerateQR generates a QR code PNG image
-func GenerateQR(req models.QRRequest) ([]byte, error) {
-	ApplyDefaults(&req)
+// RenderQR generates a QR code in the requested format and returns it with
+// its content type
+func RenderQR(ctx context.Context, req models.QRRequest) ([]byte, string, error) {
+	code, logo, err := encodeQR(ctx, &req)
+	if err != nil {
+		return nil, "", err
+	}
+	switch req.Options.Format {
+	case QRFormatSVG:
+		meta := QRAccessibleName(req)
+		return code.SVG(req.Options.Size, qr.WithAccessibleName(meta.Title, meta.Desc)), "image/svg+xml", nil
+	case QRFormatDataURI:
+		png, err := qrPNG(code, logo, req.Options)
+		if err != nil {
+			return nil, "", err
+		}
+		return []byte(PNGDataURI(png)), "text/plain; charset=utf-8", nil
+	default:
+		png, err := qrPNG(code, logo, req.Options)
+		if err != nil {
+			return nil, "", err
+		}
+		return png, "image/png", nil
+	}
+}
 
-	if err := ValidateRequest(req); err != nil {
+// GenerateQR generates a QR code PNG image, whatever the requested format.
+// A logo is decoded, scaled and composited over the centre of the code.
+func GenerateQR(ctx context.Context, req models.QRRequest) ([]byte, error) {
+	code, logo, err := encodeQR(ctx, &req)
+	if err != nil {
 		return nil, err
 	}
+	return qrPNG(code, logo, req.Options)
+}
+
+// qrPNG renders code as a PNG, with logo over it unless it is nil
+func qrPNG(code *qr.Code, logo image.Image, opts models.QROptions) ([]byte, error) {
+	if logo == nil {
+		return code.PNG(opts.Size, opts.Deterministic)
+	}
+	img := withQRLogo(code, opts.Size, logo, opts.LogoSizePercent)
+	if opts.Deterministic {
+		return canonicalpng.Encode(img), nil
+	}
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, qr.ErrEncode
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeQR applies the defaults to req, validates it and encodes its
+// payload, returning the decoded logo, or nil without one. An unknown
+// error correction level falls back to M, or H with a logo, which also
+// raises M and Q; a size leaving modules smaller than minQRModulePixels is
+// still accepted. Each adds a warning to ctx.
+func encodeQR(ctx context.Context, req *models.QRRequest) (*qr.Code, image.Image, error) {
+	ApplyDefaults(req)
+
+	if err := ValidateRequest(*req); err != nil {
+		return nil, nil, err
+	}
 
 	payload, err := BuildPayload(req.Type, req.Data)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	var logo image.Image
 	level := ParseErrorCorrection(req.Options.ErrorCorrection)
+	if req.Options.Logo != "" {
+		if logo, err = decodeQRLogo(req.Options.Logo); err != nil {
+			return nil, nil, err
+		}
+		if level != qr.LevelHigh && isKnownErrorCorrection(req.Options.ErrorCorrection) {
+			warnings.Add(ctx, warnings.CodeOptionIgnored, "options.error_correction",
+				fmt.Sprintf("error_correction %s was raised to H so the code scans under the logo", level))
+		}
+		level = qr.LevelHigh
+	} else if req.Options.LogoSizePercent != 0 {
+		warnings.Add(ctx, warnings.CodeOptionIgnored, "options.logo_size_percent",
+			"logo_size_percent only applies with a logo and was ignored")
+	}
 
-	png, err := qrcode.Encode(payload, level, req.Options.Size)
+	if !isKnownErrorCorrection(req.Options.ErrorCorrection) {
+		warnings.Add(ctx, warnings.CodeOptionIgnored, "options.error_correction",
+			fmt.Sprintf("unknown error_correction %q: must be L, M, Q or H; %s was used", req.Options.ErrorCorrection, level))
+	}
+	if (req.Options.Title != "" || req.Options.Desc != "") && req.Options.Format != QRFormatSVG {
+		warnings.Add(ctx, warnings.CodeOptionIgnored, "options.title",
+			"title and desc only apply to svg output and were ignored")
+	}
+	code, err := qr.Encode(payload, level)
 	if err != nil {
-		return nil, errors.New("failed to generate QR code")
+		return nil, nil, err
+	}
+	if modules := code.Modules(); req.Options.Size/modules < minQRModulePixels {
+		warnings.Add(ctx, warnings.CodeLowModuleSize, "options.size",
+			fmt.Sprintf("%d modules at %d pixels leaves under %d pixels per module; the code may not scan", modules, req.Options.Size, minQRModulePixels))
 	}
+	return code, logo, nil
+}
 
-	return png, nil
+// PNGDataURI encodes a PNG image as a data URL
+func PNGDataURI(png []byte) string {
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
 }
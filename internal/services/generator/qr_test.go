@@ -0,0 +1,15 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func TestWifiPayloadEscapes(t *testing.T) {
+	got := WifiPayload(models.WifiData{SSID: `Cafe "Main"; 2`, Password: `a:b,c\d`, Security: "WPA"})
+	want := `WIFI:T:WPA;S:Cafe \"Main\"\; 2;P:a\:b\,c\\d;;`
+	if got != want {
+		t.Errorf("payload = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,275 @@
+package generator
+
+import (
+	"context"
+	"math"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/pkg/generate/qr"
+)
+
+// QR data modes as reported in segments
+const (
+	QRModeNumeric      = "numeric"
+	QRModeAlphanumeric = "alphanumeric"
+	QRModeByte         = "byte"
+)
+
+const (
+	// qrQuietZoneModules is the border the encoder draws around the symbol
+	qrQuietZoneModules = 4
+	// qrModeIndicatorBits is the length of a segment's mode indicator
+	qrModeIndicatorBits = 4
+	// qrFormatMask is XORed over the 15 format information bits
+	qrFormatMask = 0x5412
+
+	// qrScanDistanceRatio is the scan distance a module can be read from per
+	// millimetre of its width with a typical phone camera, and
+	// qrMinModuleMM the smallest module commonly printed reliably
+	qrScanDistanceRatio = 350
+	qrMinModuleMM       = 0.25
+)
+
+// qrScanDistancesMM are the distances print sizes are estimated for:
+// handheld label, handheld document, poster and signage
+var qrScanDistancesMM = []float64{150, 300, 1000, 3000}
+
+// qrCodewords holds, per version, the total codewords followed by the data
+// codewords at error correction L, M, Q and H (ISO/IEC 18004 table 9, as
+// used by the encoder)
+var qrCodewords = [40][5]int{
+	{26, 19, 16, 13, 9},
+	{44, 34, 28, 22, 16},
+	{70, 55, 44, 34, 26},
+	{100, 80, 64, 48, 36},
+	{134, 108, 86, 62, 46},
+	{172, 136, 108, 76, 60},
+	{196, 156, 124, 88, 66},
+	{242, 194, 154, 110, 86},
+	{292, 232, 182, 132, 100},
+	{346, 274, 216, 154, 122},
+	{404, 324, 254, 180, 140},
+	{466, 370, 290, 206, 158},
+	{532, 428, 334, 244, 180},
+	{581, 461, 365, 261, 197},
+	{655, 523, 415, 295, 223},
+	{733, 589, 453, 325, 253},
+	{815, 647, 507, 367, 283},
+	{901, 721, 563, 397, 313},
+	{991, 795, 627, 445, 341},
+	{1085, 861, 669, 485, 385},
+	{1156, 932, 714, 512, 406},
+	{1258, 1006, 782, 568, 442},
+	{1364, 1094, 860, 614, 464},
+	{1474, 1174, 914, 664, 514},
+	{1588, 1276, 1000, 718, 538},
+	{1706, 1370, 1062, 754, 596},
+	{1828, 1468, 1128, 808, 628},
+	{1921, 1531, 1193, 871, 661},
+	{2051, 1631, 1267, 911, 701},
+	{2185, 1735, 1373, 985, 745},
+	{2323, 1843, 1455, 1033, 793},
+	{2465, 1955, 1541, 1115, 845},
+	{2611, 2071, 1631, 1171, 901},
+	{2761, 2191, 1725, 1231, 961},
+	{2876, 2306, 1812, 1286, 986},
+	{3034, 2434, 1914, 1354, 1054},
+	{3196, 2566, 1992, 1426, 1096},
+	{3362, 2702, 2102, 1502, 1142},
+	{3532, 2812, 2216, 1582, 1222},
+	{3706, 2956, 2334, 1666, 1276},
+}
+
+// qrLevelColumns maps error correction levels to their qrCodewords columns
+var qrLevelColumns = map[qr.Level]int{
+	qr.LevelLow: 1, qr.LevelMedium: 2, qr.LevelQuartile: 3, qr.LevelHigh: 4,
+}
+
+// qrSegment is a run of payload bytes encoded in one mode
+type qrSegment struct {
+	mode string
+	data []byte
+}
+
+// AnalyzeQR encodes req exactly as the generator does and describes the
+// symbol: version, mask, the mode segments of the payload, capacity used
+// and estimated minimum print sizes
+func AnalyzeQR(ctx context.Context, req models.QRRequest) (models.QRAnalysis, error) {
+	code, _, err := encodeQR(ctx, &req)
+	if err != nil {
+		return models.QRAnalysis{}, err
+	}
+	payload, _ := BuildPayload(req.Type, req.Data)
+	bitmap := code.Bitmap()
+
+	version := code.Version
+	modules := 17 + 4*version
+	codewords := qrCodewords[version-1]
+	dataCapacity := codewords[qrLevelColumns[code.Level]]
+
+	analysis := models.QRAnalysis{
+		Version:                  version,
+		ModuleCount:              modules,
+		QuietZoneModules:         qrQuietZoneModules,
+		ErrorCorrection:          string(code.Level),
+		Mask:                     qrMask(bitmap, modules),
+		DataBitsCapacity:         dataCapacity * 8,
+		DataCodewordsCapacity:    dataCapacity,
+		ErrorCorrectionCodewords: codewords[0] - dataCapacity,
+	}
+	for _, segment := range qrSegments([]byte(payload), version) {
+		bits := qrSegmentBits(segment.mode, len(segment.data), version)
+		analysis.Segments = append(analysis.Segments, models.QRSegment{
+			Mode:   segment.mode,
+			Data:   string(segment.data),
+			Length: len(segment.data),
+			Bits:   bits,
+		})
+		analysis.DataBits += bits
+	}
+	analysis.DataCodewords = (analysis.DataBits + 7) / 8
+	analysis.RemainingBits = analysis.DataBitsCapacity - analysis.DataBits
+	analysis.Utilization = math.Round(float64(analysis.DataBits)/float64(analysis.DataBitsCapacity)*10000) / 10000
+
+	for _, distance := range qrScanDistancesMM {
+		module := max(distance/qrScanDistanceRatio, qrMinModuleMM)
+		analysis.PrintSizes = append(analysis.PrintSizes, models.QRPrintSize{
+			ScanDistanceMM: distance,
+			ModuleMM:       math.Round(module*100) / 100,
+			SizeMM:         math.Round(float64(modules+2*qrQuietZoneModules)*module*10) / 10,
+		})
+	}
+	return analysis, nil
+}
+
+// qrMask reads the mask pattern back from the format information next to
+// the top left finder pattern; the encoder does not expose its choice
+func qrMask(bitmap [][]bool, modules int) int {
+	quiet := (len(bitmap) - modules) / 2
+	format := 0
+	for bit := 10; bit <= 14; bit++ {
+		// bits 9 to 14 run leftwards along row 8 from column 5
+		if bitmap[quiet+8][quiet+14-bit] {
+			format |= 1 << bit
+		}
+	}
+	return (format ^ qrFormatMask) >> 10 & 0x7
+}
+
+// qrCharMode classifies one payload byte by the narrowest mode encoding it
+func qrCharMode(c byte) string {
+	switch {
+	case c >= '0' && c <= '9':
+		return QRModeNumeric
+	case c >= 'A' && c <= 'Z', c == ' ', c == '$', c == '%', c == '*', c == '+', c == '-', c == '.', c == '/', c == ':':
+		return QRModeAlphanumeric
+	default:
+		return QRModeByte
+	}
+}
+
+// qrModeRank orders modes so each can encode everything the previous can
+func qrModeRank(mode string) int {
+	switch mode {
+	case QRModeNumeric:
+		return 1
+	case QRModeAlphanumeric:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// qrCharCountBits is the length of a segment's character count for the
+// version's size class
+func qrCharCountBits(mode string, version int) int {
+	class := 0
+	switch {
+	case version >= 27:
+		class = 2
+	case version >= 10:
+		class = 1
+	}
+	switch mode {
+	case QRModeNumeric:
+		return [3]int{10, 12, 14}[class]
+	case QRModeAlphanumeric:
+		return [3]int{9, 11, 13}[class]
+	default:
+		return [3]int{8, 16, 16}[class]
+	}
+}
+
+// qrSegmentBits is the encoded length of n characters in mode, header
+// included
+func qrSegmentBits(mode string, n, version int) int {
+	bits := qrModeIndicatorBits + qrCharCountBits(mode, version)
+	switch mode {
+	case QRModeNumeric:
+		bits += 10 * (n / 3)
+		if n%3 != 0 {
+			bits += 1 + 3*(n%3)
+		}
+	case QRModeAlphanumeric:
+		bits += 11*(n/2) + 6*(n%2)
+	default:
+		bits += 8 * n
+	}
+	return bits
+}
+
+// qrSegments splits payload the way the encoder does for version: runs of
+// the narrowest mode per byte, then each run absorbs the following runs of
+// the same or a narrower mode while that is shorter than keeping them
+// apart, and the whole payload becomes one segment of its widest mode when
+// that is no longer than the merged segments
+func qrSegments(payload []byte, version int) []qrSegment {
+	if len(payload) == 0 {
+		return nil
+	}
+
+	var runs []qrSegment
+	widest := QRModeNumeric
+	start := 0
+	for i := 1; i <= len(payload); i++ {
+		if i < len(payload) && qrCharMode(payload[i]) == qrCharMode(payload[start]) {
+			continue
+		}
+		mode := qrCharMode(payload[start])
+		runs = append(runs, qrSegment{mode: mode, data: payload[start:i]})
+		if qrModeRank(mode) > qrModeRank(widest) {
+			widest = mode
+		}
+		start = i
+	}
+
+	var merged []qrSegment
+	offset := 0
+	for i := 0; i < len(runs); {
+		mode := runs[i].mode
+		n := len(runs[i].data)
+		j := i + 1
+		for ; j < len(runs); j++ {
+			next := runs[j]
+			if qrModeRank(next.mode) > qrModeRank(mode) {
+				break
+			}
+			if qrSegmentBits(mode, n+len(next.data), version) >= qrSegmentBits(mode, n, version)+qrSegmentBits(next.mode, len(next.data), version) {
+				break
+			}
+			n += len(next.data)
+		}
+		merged = append(merged, qrSegment{mode: mode, data: payload[offset : offset+n]})
+		offset += n
+		i = j
+	}
+
+	mergedBits := 0
+	for _, segment := range merged {
+		mergedBits += qrSegmentBits(segment.mode, len(segment.data), version)
+	}
+	if qrSegmentBits(widest, len(payload), version) <= mergedBits {
+		return []qrSegment{{mode: widest, data: payload}}
+	}
+	return merged
+}
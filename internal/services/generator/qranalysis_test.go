@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func analyzeText(t *testing.T, data, level string) models.QRAnalysis {
+	t.Helper()
+	analysis, err := AnalyzeQR(context.Background(), models.QRRequest{
+		Type: "text", Data: data, Options: models.QROptions{ErrorCorrection: level},
+	})
+	if err != nil {
+		t.Fatalf("%q: %v", data, err)
+	}
+	return analysis
+}
+
+// TestAnalyzeQRVersionBoundaries fills version 1 at level M (128 data bits)
+// in each mode, then adds one character
+func TestAnalyzeQRVersionBoundaries(t *testing.T) {
+	tests := []struct {
+		data      string
+		mode      string
+		version   int
+		dataBits  int
+		remaining int
+	}{
+		{strings.Repeat("1", 34), QRModeNumeric, 1, 128, 0},
+		{strings.Repeat("1", 35), QRModeNumeric, 2, 131, 93},
+		{strings.Repeat("A", 20), QRModeAlphanumeric, 1, 123, 5},
+		{strings.Repeat("A", 21), QRModeAlphanumeric, 2, 129, 95},
+		{strings.Repeat("a", 14), QRModeByte, 1, 124, 4},
+		{strings.Repeat("a", 15), QRModeByte, 2, 132, 92},
+	}
+	for _, tt := range tests {
+		a := analyzeText(t, tt.data, "M")
+		if a.Version != tt.version || a.DataBits != tt.dataBits || a.RemainingBits != tt.remaining {
+			t.Errorf("%d x %s: version %d, %d bits, %d left; want %d, %d, %d",
+				len(tt.data), tt.mode, a.Version, a.DataBits, a.RemainingBits, tt.version, tt.dataBits, tt.remaining)
+		}
+		if len(a.Segments) != 1 || a.Segments[0].Mode != tt.mode || a.Segments[0].Length != len(tt.data) {
+			t.Errorf("%d x %s: segments %+v", len(tt.data), tt.mode, a.Segments)
+		}
+		if a.ModuleCount != 17+4*tt.version || a.ErrorCorrection != "M" || a.Mask < 0 || a.Mask > 7 {
+			t.Errorf("%d x %s: %+v", len(tt.data), tt.mode, a)
+		}
+		// The encoder picked the smallest version the payload fits
+		if a.DataBits > a.DataBitsCapacity || (a.Version > 1 && a.DataBits <= qrCodewords[a.Version-2][2]*8) {
+			t.Errorf("%d x %s: %d bits in version %d", len(tt.data), tt.mode, a.DataBits, a.Version)
+		}
+	}
+
+	// A higher level leaves fewer data codewords in the same version
+	low, high := analyzeText(t, strings.Repeat("1", 34), "L"), analyzeText(t, strings.Repeat("1", 34), "H")
+	if low.Version != 1 || high.Version != 2 || high.DataCodewordsCapacity != 16 || high.ErrorCorrectionCodewords != 28 {
+		t.Errorf("34 digits: version %d at L, %+v at H", low.Version, high)
+	}
+}
+
+func TestAnalyzeQRSegments(t *testing.T) {
+	type segment struct {
+		mode, data string
+	}
+	tests := []struct {
+		data     string
+		segments []segment
+	}{
+		{"ABC123", []segment{{QRModeAlphanumeric, "ABC123"}}},
+		{"HTTPS://EXAMPLE.COM/12345678901234", []segment{
+			{QRModeAlphanumeric, "HTTPS://EXAMPLE.COM/"}, {QRModeNumeric, "12345678901234"},
+		}},
+		{"abc1234567890123xyz", []segment{
+			{QRModeByte, "abc"}, {QRModeNumeric, "1234567890123"}, {QRModeByte, "xyz"},
+		}},
+		// A short digit run costs more apart than inside the byte segment
+		{"abc123xyz", []segment{{QRModeByte, "abc123xyz"}}},
+		// Runs only absorb the narrower runs after them, as in the encoder
+		{"Order 12345678901234567890", []segment{
+			{QRModeAlphanumeric, "O"}, {QRModeByte, "rder "}, {QRModeNumeric, "12345678901234567890"},
+		}},
+	}
+	for _, tt := range tests {
+		a := analyzeText(t, tt.data, "M")
+		var got []segment
+		bits := 0
+		for _, s := range a.Segments {
+			got = append(got, segment{s.Mode, s.Data})
+			if s.Length != len(s.Data) || s.Bits != qrSegmentBits(s.Mode, s.Length, a.Version) {
+				t.Errorf("%q: segment %+v", tt.data, s)
+			}
+			bits += s.Bits
+		}
+		if len(got) != len(tt.segments) {
+			t.Errorf("%q: segments %v, want %v", tt.data, got, tt.segments)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.segments[i] {
+				t.Errorf("%q: segments %v, want %v", tt.data, got, tt.segments)
+				break
+			}
+		}
+		if bits != a.DataBits || a.DataCodewords != (bits+7)/8 {
+			t.Errorf("%q: %d data bits in %d codewords, segments add up to %d", tt.data, a.DataBits, a.DataCodewords, bits)
+		}
+	}
+}
+
+func TestAnalyzeQRPrintSizes(t *testing.T) {
+	a := analyzeText(t, "ABC123", "M")
+	if len(a.PrintSizes) != len(qrScanDistancesMM) {
+		t.Fatalf("print sizes %+v", a.PrintSizes)
+	}
+	// 21 modules and a 4-module quiet zone on each side
+	if first := a.PrintSizes[0]; first.ScanDistanceMM != 150 || first.ModuleMM != 0.43 || first.SizeMM != 12.4 {
+		t.Errorf("at 150 mm: %+v", first)
+	}
+	for i := 1; i < len(a.PrintSizes); i++ {
+		if a.PrintSizes[i].SizeMM <= a.PrintSizes[i-1].SizeMM {
+			t.Errorf("print sizes do not grow with distance: %+v", a.PrintSizes)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package generator
+
+import (
+	"context"
+	"html/template"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/pkg/generate/qr"
+)
+
+// QRDataURI renders req as a PNG data URL typed for html/template, which
+// otherwise rewrites data: URLs in src attributes to "#ZgotmplZ". The value
+// is built only from base64 output, so it cannot break out of the attribute.
+func QRDataURI(req models.QRRequest) (template.URL, error) {
+	png, err := GenerateQR(context.Background(), req)
+	if err != nil {
+		return "", err
+	}
+	return template.URL(PNGDataURI(png)), nil
+}
+
+// QRInlineSVG renders req as an SVG element typed for direct inclusion in
+// html/template output. The markup is generated from the module bitmap,
+// and the only request text in it, the accessible name, is escaped.
+func QRInlineSVG(req models.QRRequest) (template.HTML, error) {
+	code, _, err := encodeQR(context.Background(), &req)
+	if err != nil {
+		return "", err
+	}
+	meta := QRAccessibleName(req)
+	return template.HTML(code.SVG(req.Options.Size, qr.WithAccessibleName(meta.Title, meta.Desc))), nil
+}
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html"
+	"html/template"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func TestQRDataURI(t *testing.T) {
+	uri, err := QRDataURI(models.QRRequest{Type: "text", Data: "hello", Options: models.QROptions{Size: 128}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// html/template keeps a template.URL in src instead of replacing it
+	// with #ZgotmplZ; it only entity-encodes the "+" of the base64
+	var out strings.Builder
+	tmpl := template.Must(template.New("img").Parse(`<img src="{{.}}">`))
+	if err := tmpl.Execute(&out, uri); err != nil {
+		t.Fatal(err)
+	}
+	if html.UnescapeString(out.String()) != `<img src="`+string(uri)+`">` {
+		t.Fatalf("rendered %q", out.String())
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(string(uri), "data:image/png;base64,"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil || img.Bounds().Dx() != 128 {
+		t.Errorf("data URL image: %v, %v", img.Bounds(), err)
+	}
+
+	if _, err := QRDataURI(models.QRRequest{Type: "text"}); err == nil {
+		t.Error("empty data: no error")
+	}
+}
+
+func TestQRInlineSVG(t *testing.T) {
+	req := models.QRRequest{
+		Type: "text", Data: "hello",
+		Options: models.QROptions{Size: 160, Title: `Menu <script>alert("x")</script>`},
+	}
+	svg, err := QRInlineSVG(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := QRInlineSVG(req)
+	if svg != again {
+		t.Error("inline SVG is not deterministic")
+	}
+
+	var out strings.Builder
+	tmpl := template.Must(template.New("div").Parse(`<div>{{.}}</div>`))
+	if err := tmpl.Execute(&out, svg); err != nil {
+		t.Fatal(err)
+	}
+	rendered := out.String()
+	if rendered != "<div>"+string(svg)+"</div>" || !strings.HasPrefix(string(svg), "<svg ") || !strings.Contains(rendered, `width="160"`) {
+		t.Fatalf("rendered %q", rendered)
+	}
+	// The title is the only request text in the markup, and it is escaped
+	if strings.Contains(rendered, "<script>") || !strings.Contains(rendered, "&lt;script&gt;") {
+		t.Errorf("title not escaped: %s", rendered)
+	}
+}
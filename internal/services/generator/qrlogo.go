@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/imagedecode"
+	"github.com/innovelabs/microtools-go/pkg/generate/qr"
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	// MaxQRLogoBytes caps the decoded size of options.logo
+	MaxQRLogoBytes = 100 << 10
+	// MaxQRLogoDimension caps either side of the logo. The largest logo
+	// drawn is 25% of a 2048 pixel code, so larger images only cost
+	// decoding.
+	MaxQRLogoDimension = 2048
+
+	// DefaultQRLogoSizePercent, MinQRLogoSizePercent and
+	// MaxQRLogoSizePercent bound options.logo_size_percent, the logo's
+	// width in percent of the symbol's. At 25% the logo and its padding
+	// cover under a tenth of the symbol, leaving even version 1 within
+	// the 30% of codewords error correction H restores.
+	DefaultQRLogoSizePercent = 20
+	MinQRLogoSizePercent     = 5
+	MaxQRLogoSizePercent     = 25
+
+	// qrLogoPaddingDivisor sets the white margin around the logo to a
+	// tenth of its larger side
+	qrLogoPaddingDivisor = 10
+)
+
+var (
+	ErrInvalidQRLogo     = errors.New("invalid logo")
+	ErrQRLogoTooLarge    = fmt.Errorf("logo exceeds maximum size of %d KB", MaxQRLogoBytes>>10)
+	ErrInvalidQRLogoSize = fmt.Errorf("logo_size_percent must be between %d and %d", MinQRLogoSizePercent, MaxQRLogoSizePercent)
+	// ErrQRLogoLevel is returned for a logo requested with error
+	// correction L, which cannot restore the modules it covers
+	ErrQRLogoLevel = errors.New("a logo needs error correction H; L cannot restore the modules it covers")
+)
+
+// decodeQRLogo decodes options.logo: base64, optionally as a data URL,
+// of a PNG or JPEG of at most MaxQRLogoBytes
+func decodeQRLogo(logo string) (image.Image, error) {
+	if i := strings.Index(logo, ";base64,"); strings.HasPrefix(logo, "data:") && i >= 0 {
+		logo = logo[i+len(";base64,"):]
+	}
+	if base64.StdEncoding.DecodedLen(len(logo)) > MaxQRLogoBytes+2 {
+		return nil, ErrQRLogoTooLarge
+	}
+	data, err := base64.StdEncoding.DecodeString(logo)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not valid base64", ErrInvalidQRLogo)
+	}
+	if len(data) > MaxQRLogoBytes {
+		return nil, ErrQRLogoTooLarge
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "png" && format != "jpeg") {
+		return nil, fmt.Errorf("%w: must be a PNG or JPEG image", ErrInvalidQRLogo)
+	}
+	if config.Width > MaxQRLogoDimension || config.Height > MaxQRLogoDimension {
+		return nil, fmt.Errorf("%w: %dx%d exceeds %d pixels per side", ErrInvalidQRLogo, config.Width, config.Height, MaxQRLogoDimension)
+	}
+	img, _, err := imagedecode.DecodeBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQRLogo, err)
+	}
+	return img, nil
+}
+
+// qrLogoImage is a code image with a logo on a white box over its centre.
+// Only the box is held in memory; the other pixels are the code's,
+// computed on demand.
+type qrLogoImage struct {
+	image.Image
+	overlay *image.RGBA
+}
+
+func (m *qrLogoImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (m *qrLogoImage) At(x, y int) color.Color {
+	if (image.Point{x, y}).In(m.overlay.Rect) {
+		return m.overlay.At(x, y)
+	}
+	return m.Image.At(x, y)
+}
+
+// withQRLogo draws code at size with logo scaled to percent of the width
+// of the symbol, quiet zone excluded, keeping the logo's aspect ratio,
+// over a white box padded around it
+func withQRLogo(code *qr.Code, size int, logo image.Image, percent int) image.Image {
+	base := code.Image(size)
+	side := base.Bounds().Dx()
+	symbol := side * (code.Modules() - 2*qrQuietZoneModules) / code.Modules()
+	bounds := logo.Bounds()
+	width, height := symbol*percent/100, symbol*percent/100
+	if bounds.Dx() >= bounds.Dy() {
+		height = max(1, width*bounds.Dy()/bounds.Dx())
+	} else {
+		width = max(1, height*bounds.Dx()/bounds.Dy())
+	}
+	target := image.Rect(0, 0, width, height).Add(image.Pt((side-width)/2, (side-height)/2))
+	box := target.Inset(-max(1, max(width, height)/qrLogoPaddingDivisor))
+
+	overlay := image.NewRGBA(box)
+	draw.Draw(overlay, box, image.White, image.Point{}, draw.Src)
+	xdraw.CatmullRom.Scale(overlay, target, logo, bounds, draw.Over, nil)
+	return &qrLogoImage{Image: base, overlay: overlay}
+}
@@ -0,0 +1,237 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/warnings"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// logoRed is the colour of testLogo's border
+var logoRed = color.RGBA{R: 220, A: 255}
+
+// testLogo returns a width x height logo: a red border around black and
+// white stripes, whose edges look like modules to a scanner
+func testLogo(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			switch {
+			case x < width/4 || x >= width-width/4 || y < height/4 || y >= height-height/4:
+				img.Set(x, y, logoRed)
+			case x/3%2 == 0:
+				img.Set(x, y, color.Black)
+			default:
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+	return img
+}
+
+func encodeLogo(t *testing.T, img image.Image, format string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// scanQR decodes the QR code in a PNG the way a scanner would, locating it
+// in the image rather than assuming its geometry
+func scanQR(data []byte) (string, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", err
+	}
+	result, err := qrcode.NewQRCodeReader().Decode(bitmap, map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_TRY_HARDER: true})
+	if err != nil {
+		return "", err
+	}
+	return result.GetText(), nil
+}
+
+func TestQRLogoScans(t *testing.T) {
+	tests := []struct {
+		name, qrType, data    string
+		logoWidth, logoHeight int
+		format, prefix        string
+		size, percent         int
+		deterministic         bool
+	}{
+		{"default size", "text", "hello", 120, 120, "png", "", 256, 0, false},
+		{"largest logo", "text", "hello", 120, 120, "png", "", 256, MaxQRLogoSizePercent, false},
+		{"smallest logo", "text", "hello", 120, 120, "png", "", 256, MinQRLogoSizePercent, false},
+		{"url", "url", "https://example.com/menu?table=12", 120, 120, "png", "", 512, MaxQRLogoSizePercent, false},
+		{"long text", "text", strings.Repeat("The quick brown fox jumps over the lazy dog. ", 6), 120, 120, "png", "", 1024, MaxQRLogoSizePercent, false},
+		{"wide logo", "text", "hello", 300, 100, "png", "", 300, MaxQRLogoSizePercent, false},
+		{"tall logo", "text", "hello", 60, 180, "png", "", 300, MaxQRLogoSizePercent, false},
+		{"jpeg", "text", "hello", 120, 120, "jpeg", "", 256, 0, false},
+		{"data URL", "text", "hello", 120, 120, "png", "data:image/png;base64,", 256, 0, false},
+		{"deterministic", "text", "hello", 120, 120, "png", "", 256, 0, true},
+	}
+	for _, tt := range tests {
+		req := models.QRRequest{Type: tt.qrType, Data: tt.data, Options: models.QROptions{
+			Size: tt.size, Logo: tt.prefix + encodeLogo(t, testLogo(tt.logoWidth, tt.logoHeight), tt.format),
+			LogoSizePercent: tt.percent, Deterministic: tt.deterministic,
+		}}
+		data, err := GenerateQR(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got, err := scanQR(data); err != nil || got != tt.data {
+			t.Errorf("%s: scanned %q, %v; want %q", tt.name, got, err, tt.data)
+		}
+
+		// The logo is drawn over the centre. Its width is a percentage of
+		// the symbol's, at most 1.38 times less than the image's for
+		// version 1, so 3/10 of the image's percentage left of the middle
+		// lands in its red border, from 1/4 to 1/2 of its width out.
+		img, _ := png.Decode(bytes.NewReader(data))
+		percent := tt.percent
+		if percent == 0 {
+			percent = DefaultQRLogoSizePercent
+		}
+		width := tt.size * percent / 100 * min(tt.logoWidth, tt.logoHeight) / tt.logoHeight
+		r, g, b, _ := img.At(tt.size/2-width*3/10, tt.size/2).RGBA()
+		// Scaling a 120 pixel logo down to a few pixels blends the border
+		// with the stripes, so only its hue is checked
+		if r>>8 < g>>8+40 || r>>8 < b>>8+40 {
+			t.Errorf("%s: pixel left of centre is %d,%d,%d, want the logo's red", tt.name, r>>8, g>>8, b>>8)
+		}
+	}
+}
+
+func TestQRLogoDeterministic(t *testing.T) {
+	req := models.QRRequest{Type: "text", Data: "hello", Options: models.QROptions{
+		Logo: encodeLogo(t, testLogo(64, 64), "png"), Deterministic: true,
+	}}
+	first, err := GenerateQR(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GenerateQR(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("deterministic output with a logo differs between runs")
+	}
+}
+
+func TestQRLogoErrorCorrection(t *testing.T) {
+	logo := encodeLogo(t, testLogo(32, 32), "png")
+	tests := []struct {
+		level, want string
+		warned      bool
+	}{
+		{"", "H", false},
+		{"H", "H", false},
+		{"M", "H", true},
+		{"q", "H", true},
+		{"X", "H", true},
+	}
+	for _, tt := range tests {
+		collector := warnings.New()
+		req := models.QRRequest{Type: "text", Data: "hello", Options: models.QROptions{ErrorCorrection: tt.level, Logo: logo}}
+		analysis, err := AnalyzeQR(warnings.NewContext(context.Background(), collector), req)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.level, err)
+		}
+		if analysis.ErrorCorrection != tt.want || (len(collector.List()) > 0) != tt.warned {
+			t.Errorf("%q: level %s, warnings %v", tt.level, analysis.ErrorCorrection, collector.List())
+		}
+	}
+
+	_, err := GenerateQR(context.Background(), models.QRRequest{Type: "text", Data: "hello", Options: models.QROptions{ErrorCorrection: "l", Logo: logo}})
+	if !errors.Is(err, ErrQRLogoLevel) {
+		t.Errorf("level L: err = %v, want ErrQRLogoLevel", err)
+	}
+
+	// Without a logo the size is ignored with a warning
+	collector := warnings.New()
+	req := models.QRRequest{Type: "text", Data: "hello", Options: models.QROptions{LogoSizePercent: 10}}
+	if _, err := GenerateQR(warnings.NewContext(context.Background(), collector), req); err != nil {
+		t.Fatal(err)
+	}
+	if list := collector.List(); len(list) != 1 || list[0].Field != "options.logo_size_percent" {
+		t.Errorf("warnings = %v", list)
+	}
+}
+
+func TestQRLogoErrors(t *testing.T) {
+	logo := encodeLogo(t, testLogo(32, 32), "png")
+	noise := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	random := rand.New(rand.NewPCG(1, 2))
+	for i := range noise.Pix {
+		noise.Pix[i] = byte(random.UintN(256))
+	}
+	tests := []struct {
+		name string
+		opts models.QROptions
+		want error
+	}{
+		{"not base64", models.QROptions{Logo: "not base64!"}, ErrInvalidQRLogo},
+		{"not an image", models.QROptions{Logo: base64.StdEncoding.EncodeToString([]byte("hello"))}, ErrInvalidQRLogo},
+		{"gif", models.QROptions{Logo: encodeLogo(t, testLogo(32, 32), "gif")}, ErrInvalidQRLogo},
+		{"too many bytes", models.QROptions{Logo: encodeLogo(t, noise, "png")}, ErrQRLogoTooLarge},
+		{"too wide", models.QROptions{Logo: encodeLogo(t, image.NewGray(image.Rect(0, 0, MaxQRLogoDimension+1, 1)), "png")}, ErrInvalidQRLogo},
+		{"size too small", models.QROptions{Logo: logo, LogoSizePercent: MinQRLogoSizePercent - 1}, ErrInvalidQRLogoSize},
+		{"size too large", models.QROptions{Logo: logo, LogoSizePercent: MaxQRLogoSizePercent + 1}, ErrInvalidQRLogoSize},
+		{"svg", models.QROptions{Logo: logo, Format: QRFormatSVG}, ErrUnsupportedQRFormat},
+	}
+	for _, tt := range tests {
+		_, _, err := RenderQR(context.Background(), models.QRRequest{Type: "text", Data: "hello", Options: tt.opts})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestQRLogoDataURI(t *testing.T) {
+	req := models.QRRequest{Type: "text", Data: "hello", Options: models.QROptions{
+		Format: QRFormatDataURI, Logo: encodeLogo(t, testLogo(64, 64), "png"),
+	}}
+	data, contentType, err := RenderQR(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, ok := strings.CutPrefix(string(data), "data:image/png;base64,")
+	if !ok || !strings.HasPrefix(contentType, "text/plain") {
+		t.Fatalf("%s: %.40s", contentType, data)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := scanQR(decoded); err != nil || got != "hello" {
+		t.Errorf("scanned %q, %v", got, err)
+	}
+}
@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+const (
+	qrPath      = "/api/v1/generate/qr"
+	barcodePath = "/api/v1/generate/barcode"
+)
+
+// testVectors are the published deterministic requests and the digest of
+// their output. The digests are a promise to API users: a change that
+// alters one is a breaking change, never a fixture update.
+var testVectors = []models.TestVector{
+	{
+		Name:        "qr-text-png",
+		Method:      http.MethodPost,
+		Path:        qrPath,
+		Request:     json.RawMessage(`{"type":"text","data":"https://microapi.innovelabs.net","options":{"size":128,"error_correction":"M","format":"png","deterministic":true}}`),
+		ContentType: "image/png",
+		SHA256:      "88e687f108469161903328ed7dd3f445f8b30e5fa87b9be991f83a1fb718deed",
+	},
+	{
+		Name:        "qr-wifi-png",
+		Method:      http.MethodPost,
+		Path:        qrPath,
+		Request:     json.RawMessage(`{"type":"wifi","data":"{\"ssid\":\"Guest\",\"password\":\"welcome123\",\"security\":\"WPA\"}","options":{"size":256,"error_correction":"H","format":"png","deterministic":true}}`),
+		ContentType: "image/png",
+		SHA256:      "cd5afa46ddeeb6bf1d326c9efd2725e3cb14cf179f2d69c762e527851a385793",
+	},
+	{
+		Name:        "qr-url-svg",
+		Method:      http.MethodPost,
+		Path:        qrPath,
+		Request:     json.RawMessage(`{"type":"url","data":"https://example.com/","options":{"size":256,"error_correction":"L","format":"svg","deterministic":true}}`),
+		ContentType: "image/svg+xml",
+		SHA256:      "ba545b05697f5591bc4fe46d2490cb6715524f4452ab5113c106e77afb10d3b8",
+	},
+	{
+		Name:        "barcode-ean13-png",
+		Method:      http.MethodPost,
+		Path:        barcodePath,
+		Request:     json.RawMessage(`{"type":"EAN-13","data":"4006381333931","format":"png","width":190,"height":80,"deterministic":true}`),
+		ContentType: "image/png",
+		SHA256:      "b290a4b5eff9fdaa6918fc8debf4bde5bc8b90b88ca42ac8c9d11d57b50fc647",
+	},
+	{
+		Name:        "barcode-code128-svg",
+		Method:      http.MethodPost,
+		Path:        barcodePath,
+		Request:     json.RawMessage(`{"type":"Code128","data":"MICROAPI-0001","format":"svg","width":300,"height":100,"deterministic":true}`),
+		ContentType: "image/svg+xml",
+		SHA256:      "72249bf97a4699e4017e1d8b2d9c18b54457384b07dbfb14fd9ee1745bf19609",
+	},
+}
+
+// TestVectors renders every published vector with barcodes and returns
+// them once each output still hashes to its committed digest. A mismatch
+// is an error, so the service never publishes a digest it cannot produce.
+func TestVectors(ctx context.Context, barcodes BarcodeService) ([]models.TestVector, error) {
+	vectors := make([]models.TestVector, len(testVectors))
+	for i, vector := range testVectors {
+		data, contentType, err := renderTestVector(ctx, barcodes, vector)
+		if err != nil {
+			return nil, fmt.Errorf("test vector %s: %w", vector.Name, err)
+		}
+		sum := sha256.Sum256(data)
+		if digest := hex.EncodeToString(sum[:]); digest != vector.SHA256 || contentType != vector.ContentType {
+			return nil, fmt.Errorf("test vector %s: output %s (%s) no longer matches %s (%s)", vector.Name, digest, contentType, vector.SHA256, vector.ContentType)
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func renderTestVector(ctx context.Context, barcodes BarcodeService, vector models.TestVector) ([]byte, string, error) {
+	switch vector.Path {
+	case qrPath:
+		var req models.QRRequest
+		if err := json.Unmarshal(vector.Request, &req); err != nil {
+			return nil, "", err
+		}
+		return RenderQR(ctx, req)
+	case barcodePath:
+		var req models.GenerateRequest
+		if err := json.Unmarshal(vector.Request, &req); err != nil {
+			return nil, "", err
+		}
+		img, err := barcodes.Generate(req)
+		return img.Data, img.ContentType, err
+	default:
+		return nil, "", fmt.Errorf("unknown path %s", vector.Path)
+	}
+}
@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+)
+
+// TestPublishedVectors fails when a change alters the output of a
+// published test vector, which API users snapshot
+func TestPublishedVectors(t *testing.T) {
+	vectors, err := TestVectors(context.Background(), NewDefaultBarcodeService())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != len(testVectors) {
+		t.Fatalf("%d vectors, want %d", len(vectors), len(testVectors))
+	}
+	names := map[string]bool{}
+	for _, vector := range vectors {
+		if names[vector.Name] {
+			t.Errorf("%s is published twice", vector.Name)
+		}
+		names[vector.Name] = true
+	}
+}
+
+func TestVectorsAreByteStable(t *testing.T) {
+	barcodes := NewDefaultBarcodeService()
+	for _, vector := range testVectors {
+		first, _, err := renderTestVector(context.Background(), barcodes, vector)
+		if err != nil {
+			t.Fatalf("%s: %v", vector.Name, err)
+		}
+		for range 3 {
+			again, _, err := renderTestVector(context.Background(), barcodes, vector)
+			if err != nil || !bytes.Equal(again, first) {
+				t.Fatalf("%s: output changed between renders (%v)", vector.Name, err)
+			}
+		}
+		if vector.ContentType != "image/png" {
+			continue
+		}
+		// Only the critical chunks, so no timestamps or metadata
+		for rest := first[8:]; len(rest) >= 12; {
+			n := binary.BigEndian.Uint32(rest)
+			if kind := string(rest[4:8]); kind != "IHDR" && kind != "IDAT" && kind != "IEND" {
+				t.Errorf("%s: has a %s chunk", vector.Name, kind)
+			}
+			rest = rest[12+n:]
+		}
+	}
+}
+
+func TestVectorsRejectChangedOutput(t *testing.T) {
+	saved := testVectors[0].SHA256
+	testVectors[0].SHA256 = "0000000000000000000000000000000000000000000000000000000000000000"
+	defer func() { testVectors[0].SHA256 = saved }()
+	if _, err := TestVectors(context.Background(), NewDefaultBarcodeService()); err == nil {
+		t.Error("a vector whose output no longer matches its digest was published")
+	}
+}
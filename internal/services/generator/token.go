@@ -0,0 +1,256 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"embed"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/innovelabs/microtools-go/internal/lazy"
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+const (
+	TokenModePassphrase = "passphrase"
+	TokenModeRandom     = "random"
+
+	// Character sets of random tokens
+	TokenCharsetAlphanumeric = "alphanumeric"
+	TokenCharsetUnambiguous  = "unambiguous"
+	TokenCharsetSymbols      = "symbols"
+	TokenCharsetDigits       = "digits"
+
+	defaultRandomLength  = 20
+	minRandomLength      = 8
+	maxRandomLength      = 128
+	defaultRandomCharset = TokenCharsetAlphanumeric
+
+	defaultPassphraseWords     = 6
+	minPassphraseWords         = 3
+	maxPassphraseWords         = 12
+	defaultPassphraseSeparator = "-"
+	maxPassphraseSeparatorLen  = 3
+	defaultPassphraseLanguage  = "en"
+)
+
+// ErrInvalidTokenRequest is wrapped by token option errors
+var ErrInvalidTokenRequest = errors.New("invalid token request")
+
+// tokenCharsets are the characters of each random token charset.
+// Unambiguous leaves out look-alikes (0O, 1lI) for tokens typed from a
+// screen; symbols leaves out characters that need quoting in WiFi QR
+// codes and shells.
+var tokenCharsets = map[string]string{
+	TokenCharsetAlphanumeric: "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	TokenCharsetUnambiguous:  "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789",
+	TokenCharsetSymbols:      "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#%*+-=?@^_~",
+	TokenCharsetDigits:       "0123456789",
+}
+
+// wordlistFiles holds one diceware list per language, named <language>.txt.
+// en.txt is the EFF large wordlist (CC BY 3.0 US, see wordlists/NOTICE).
+//
+//go:embed wordlists/*.txt
+var wordlistFiles embed.FS
+
+// wordlists parses the embedded lists on first use
+var wordlists = lazy.New(func() (map[string][]string, error) {
+	lists := map[string][]string{}
+	files, err := wordlistFiles.ReadDir("wordlists")
+	if err != nil {
+		return nil, fmt.Errorf("embedded wordlists: %w", err)
+	}
+	for _, file := range files {
+		data, err := wordlistFiles.ReadFile("wordlists/" + file.Name())
+		if err != nil {
+			return nil, fmt.Errorf("embedded wordlist %s: %w", file.Name(), err)
+		}
+		lists[strings.TrimSuffix(file.Name(), path.Ext(file.Name()))] = parseWordlist(data)
+	}
+	return lists, nil
+})
+
+// parseWordlist reads one word per line, dropping a leading dice roll
+// column as in the EFF lists
+func parseWordlist(data []byte) []string {
+	var words []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		words = append(words, fields[len(fields)-1])
+	}
+	return words
+}
+
+// PassphraseLanguages returns the languages with an embedded wordlist
+func PassphraseLanguages() ([]string, error) {
+	lists, err := wordlists.Get()
+	if err != nil {
+		return nil, err
+	}
+	languages := make([]string, 0, len(lists))
+	for language := range lists {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages, nil
+}
+
+// GenerateToken generates a secret in the requested mode together with
+// the entropy it carries
+func GenerateToken(req models.TokenRequest) (models.TokenResponse, error) {
+	switch req.Mode {
+	case "", TokenModePassphrase:
+		return generatePassphrase(req)
+	case TokenModeRandom:
+		return generateRandomToken(req)
+	default:
+		return models.TokenResponse{}, fmt.Errorf("%w: unsupported mode %q: must be %s or %s", ErrInvalidTokenRequest, req.Mode, TokenModePassphrase, TokenModeRandom)
+	}
+}
+
+func applyPassphraseDefaults(req *models.TokenRequest) {
+	req.Mode = TokenModePassphrase
+	if req.Words == 0 {
+		req.Words = defaultPassphraseWords
+	}
+	if req.Separator == nil {
+		separator := defaultPassphraseSeparator
+		req.Separator = &separator
+	}
+	if req.Language == "" {
+		req.Language = defaultPassphraseLanguage
+	}
+}
+
+func validatePassphraseRequest(req models.TokenRequest) error {
+	if req.Words < minPassphraseWords || req.Words > maxPassphraseWords {
+		return fmt.Errorf("%w: words must be between %d and %d", ErrInvalidTokenRequest, minPassphraseWords, maxPassphraseWords)
+	}
+	separator := *req.Separator
+	if utf8.RuneCountInString(separator) > maxPassphraseSeparatorLen {
+		return fmt.Errorf("%w: separator must be at most %d characters", ErrInvalidTokenRequest, maxPassphraseSeparatorLen)
+	}
+	for _, r := range separator {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return fmt.Errorf("%w: separator must not contain letters or digits", ErrInvalidTokenRequest)
+		}
+	}
+	lists, err := wordlists.Get()
+	if err != nil {
+		return err
+	}
+	if _, ok := lists[req.Language]; !ok {
+		languages, _ := PassphraseLanguages()
+		return fmt.Errorf("%w: unsupported language %q: must be one of %s", ErrInvalidTokenRequest, req.Language, strings.Join(languages, ", "))
+	}
+	return nil
+}
+
+// generatePassphrase picks words uniformly from the language's list with
+// crypto/rand. The entropy is words*log2(list size); add_number appends a
+// digit to one random word, adding log2(10) + log2(words) bits.
+func generatePassphrase(req models.TokenRequest) (models.TokenResponse, error) {
+	applyPassphraseDefaults(&req)
+	if err := validatePassphraseRequest(req); err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	lists, err := wordlists.Get()
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	list := lists[req.Language]
+	words := make([]string, req.Words)
+	for i := range words {
+		n, err := randomInt(len(list))
+		if err != nil {
+			return models.TokenResponse{}, err
+		}
+		words[i] = list[n]
+		if req.Capitalize {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+	}
+	entropy := float64(req.Words) * math.Log2(float64(len(list)))
+
+	if req.AddNumber {
+		position, err := randomInt(len(words))
+		if err != nil {
+			return models.TokenResponse{}, err
+		}
+		digit, err := randomInt(10)
+		if err != nil {
+			return models.TokenResponse{}, err
+		}
+		words[position] += strconv.Itoa(digit)
+		entropy += math.Log2(10) + math.Log2(float64(len(words)))
+	}
+
+	return models.TokenResponse{
+		Token:        strings.Join(words, *req.Separator),
+		Mode:         req.Mode,
+		EntropyBits:  math.Round(entropy*100) / 100,
+		Words:        req.Words,
+		Language:     req.Language,
+		WordlistSize: len(list),
+	}, nil
+}
+
+// generateRandomToken picks length characters uniformly from the charset
+// with crypto/rand, carrying length*log2(charset size) bits
+func generateRandomToken(req models.TokenRequest) (models.TokenResponse, error) {
+	if req.Length == 0 {
+		req.Length = defaultRandomLength
+	}
+	if req.Charset == "" {
+		req.Charset = defaultRandomCharset
+	}
+	if req.Length < minRandomLength || req.Length > maxRandomLength {
+		return models.TokenResponse{}, fmt.Errorf("%w: length must be between %d and %d", ErrInvalidTokenRequest, minRandomLength, maxRandomLength)
+	}
+	charset, ok := tokenCharsets[req.Charset]
+	if !ok {
+		return models.TokenResponse{}, fmt.Errorf("%w: unsupported charset %q: must be %s, %s, %s or %s", ErrInvalidTokenRequest,
+			req.Charset, TokenCharsetAlphanumeric, TokenCharsetUnambiguous, TokenCharsetSymbols, TokenCharsetDigits)
+	}
+
+	token := make([]byte, req.Length)
+	for i := range token {
+		n, err := randomInt(len(charset))
+		if err != nil {
+			return models.TokenResponse{}, err
+		}
+		token[i] = charset[n]
+	}
+	entropy := float64(req.Length) * math.Log2(float64(len(charset)))
+	return models.TokenResponse{
+		Token:       string(token),
+		Mode:        TokenModeRandom,
+		EntropyBits: math.Round(entropy*100) / 100,
+		Length:      req.Length,
+		Charset:     req.Charset,
+	}, nil
+}
+
+// randomInt returns a uniform random integer in [0, n) from crypto/rand
+func randomInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read random source: %w", err)
+	}
+	return int(v.Int64()), nil
+}
@@ -0,0 +1,152 @@
+package generator
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func TestGeneratePassphrase(t *testing.T) {
+	lists, err := wordlists.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := lists["en"]
+	if len(list) != 7776 {
+		t.Fatalf("en list has %d words, want the 7776 of the EFF large list", len(list))
+	}
+	words := map[string]bool{}
+	for _, word := range list {
+		words[word] = true
+	}
+
+	separator := "."
+	resp, err := GenerateToken(models.TokenRequest{Mode: "passphrase", Words: 5, Separator: &separator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(resp.Token, ".")
+	if len(parts) != 5 || resp.Words != 5 || resp.Language != "en" || resp.WordlistSize != 7776 {
+		t.Fatalf("resp = %+v", resp)
+	}
+	for _, part := range parts {
+		if !words[part] {
+			t.Errorf("%q is not on the list", part)
+		}
+	}
+	if want := math.Round(5*math.Log2(7776)*100) / 100; resp.EntropyBits != want {
+		t.Errorf("entropy = %v, want %v", resp.EntropyBits, want)
+	}
+
+	// Defaults: six words joined by hyphens, which some words contain too
+	resp, err = GenerateToken(models.TokenRequest{})
+	if err != nil || resp.Words != 6 || strings.Count(resp.Token, "-") < 5 {
+		t.Errorf("defaults: %+v, %v", resp, err)
+	}
+
+	// Capitalized words with one digit appended
+	space := " "
+	resp, err = GenerateToken(models.TokenRequest{Words: 4, Separator: &space, Capitalize: true, AddNumber: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digits := 0
+	for _, part := range strings.Split(resp.Token, " ") {
+		if last := part[len(part)-1]; last >= '0' && last <= '9' {
+			digits++
+			part = part[:len(part)-1]
+		}
+		if !words[strings.ToLower(part[:1])+part[1:]] || part[:1] != strings.ToUpper(part[:1]) {
+			t.Errorf("%q is not a capitalized list word", part)
+		}
+	}
+	want := math.Round((4*math.Log2(7776)+math.Log2(10)+math.Log2(4))*100) / 100
+	if digits != 1 || resp.EntropyBits != want {
+		t.Errorf("%q: %d digits, entropy %v, want one digit and %v", resp.Token, digits, resp.EntropyBits, want)
+	}
+}
+
+func TestGeneratePassphraseDiffers(t *testing.T) {
+	seen := map[string]bool{}
+	for range 20 {
+		resp, err := GenerateToken(models.TokenRequest{Words: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[resp.Token] {
+			t.Fatalf("%q generated twice", resp.Token)
+		}
+		seen[resp.Token] = true
+	}
+}
+
+func TestGeneratePassphraseValidation(t *testing.T) {
+	str := func(s string) *string { return &s }
+	for name, req := range map[string]models.TokenRequest{
+		"too few words":        {Words: 2},
+		"too many words":       {Words: 13},
+		"letter separator":     {Separator: str("x")},
+		"digit separator":      {Separator: str("1")},
+		"long separator":       {Separator: str("----")},
+		"unsupported language": {Language: "tlh"},
+		"unsupported mode":     {Mode: "hex"},
+	} {
+		if _, err := GenerateToken(req); !errors.Is(err, ErrInvalidTokenRequest) {
+			t.Errorf("%s: err = %v, want ErrInvalidTokenRequest", name, err)
+		}
+	}
+	// An explicit empty separator joins the words directly
+	if resp, err := GenerateToken(models.TokenRequest{Words: 3, Separator: str("")}); err != nil || strings.ContainsAny(resp.Token, " .") {
+		t.Errorf("empty separator: %+v, %v", resp, err)
+	}
+}
+
+func TestGenerateRandomToken(t *testing.T) {
+	resp, err := GenerateToken(models.TokenRequest{Mode: "random", Length: 24, Charset: "unambiguous"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Token) != 24 || resp.Length != 24 || resp.Charset != "unambiguous" {
+		t.Fatalf("resp = %+v", resp)
+	}
+	if strings.ContainsAny(resp.Token, "0O1lI") {
+		t.Errorf("unambiguous token %q has look-alike characters", resp.Token)
+	}
+	if want := math.Round(24*math.Log2(float64(len(tokenCharsets["unambiguous"])))*100) / 100; resp.EntropyBits != want {
+		t.Errorf("entropy = %v, want %v", resp.EntropyBits, want)
+	}
+
+	resp, err = GenerateToken(models.TokenRequest{Mode: "random"})
+	if err != nil || len(resp.Token) != defaultRandomLength || resp.Charset != TokenCharsetAlphanumeric {
+		t.Errorf("defaults: %+v, %v", resp, err)
+	}
+	for name, req := range map[string]models.TokenRequest{
+		"too short":           {Mode: "random", Length: 7},
+		"too long":            {Mode: "random", Length: 129},
+		"unsupported charset": {Mode: "random", Charset: "emoji"},
+	} {
+		if _, err := GenerateToken(req); !errors.Is(err, ErrInvalidTokenRequest) {
+			t.Errorf("%s: err = %v, want ErrInvalidTokenRequest", name, err)
+		}
+	}
+}
+
+// TestGenerateTokenConcurrentFirstAccess loads the wordlists from many
+// goroutines at once when it runs first, e.g. with -run and -race
+func TestGenerateTokenConcurrentFirstAccess(t *testing.T) {
+	var wg sync.WaitGroup
+	for range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := GenerateToken(models.TokenRequest{Words: 4}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
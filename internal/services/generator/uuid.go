@@ -0,0 +1,229 @@
+package generator
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+const (
+	UUIDVersion4 = "v4"
+	UUIDVersion7 = "v7"
+	UUIDULID     = "ulid"
+
+	// Formats of generated identifiers
+	UUIDFormatStandard = "standard"
+	UUIDFormatCompact  = "compact"
+	UUIDFormatURN      = "urn"
+
+	// MaxUUIDCount is the most identifiers one request generates
+	MaxUUIDCount = 1000
+)
+
+// ErrInvalidUUIDRequest is wrapped by UUID option errors
+var ErrInvalidUUIDRequest = errors.New("invalid UUID request")
+
+const (
+	lowerHex = "0123456789abcdef"
+	upperHex = "0123456789ABCDEF"
+	// crockfordBase32 is the ULID alphabet, in ASCII order so encoded
+	// ULIDs sort like their values
+	crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// UUIDGenerator produces the identifiers of one request. Version 7 UUIDs
+// and ULIDs share the request's timestamp and follow each other by a
+// random increment of their random bits, so they are strictly increasing
+// in the order generated, in their bytes and in their text.
+type UUIDGenerator struct {
+	req models.UUIDRequest
+	// ms is the Unix millisecond timestamp of v7 UUIDs and ULIDs
+	ms uint64
+	// hi and lo are the random bits following the timestamp: 12 and 62
+	// bits of a v7 UUID, 16 and 64 bits of a ULID
+	hi, lo  uint64
+	started bool
+}
+
+// NewUUIDGenerator checks req, filling in the defaults (v4, one,
+// standard format), and returns a generator of its identifiers stamped
+// with now
+func NewUUIDGenerator(req models.UUIDRequest, now time.Time) (*UUIDGenerator, error) {
+	switch req.Version {
+	case "":
+		req.Version = UUIDVersion4
+	case UUIDVersion4, UUIDVersion7, UUIDULID:
+	default:
+		return nil, fmt.Errorf("%w: unsupported version %q: must be %s, %s or %s", ErrInvalidUUIDRequest, req.Version, UUIDVersion4, UUIDVersion7, UUIDULID)
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	if req.Count < 1 || req.Count > MaxUUIDCount {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidUUIDRequest, MaxUUIDCount)
+	}
+	switch req.Format {
+	case "":
+		req.Format = UUIDFormatStandard
+	case UUIDFormatStandard, UUIDFormatCompact:
+	case UUIDFormatURN:
+		if req.Version == UUIDULID {
+			return nil, fmt.Errorf("%w: format %s is only for UUIDs", ErrInvalidUUIDRequest, UUIDFormatURN)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q: must be %s, %s or %s", ErrInvalidUUIDRequest, req.Format, UUIDFormatStandard, UUIDFormatCompact, UUIDFormatURN)
+	}
+	return &UUIDGenerator{req: req, ms: uint64(now.UnixMilli())}, nil
+}
+
+// Request returns the request with its defaults filled in
+func (g *UUIDGenerator) Request() models.UUIDRequest {
+	return g.req
+}
+
+// Append appends the next identifier to dst in the requested format
+func (g *UUIDGenerator) Append(dst []byte) []byte {
+	switch g.req.Version {
+	case UUIDVersion7:
+		return g.appendUUID(dst, g.nextV7())
+	case UUIDULID:
+		return appendULID(dst, g.nextULID())
+	default:
+		return g.appendUUID(dst, newV4())
+	}
+}
+
+// GenerateUUIDs returns the identifiers of req stamped with now
+func GenerateUUIDs(req models.UUIDRequest, now time.Time) ([]string, error) {
+	g, err := NewUUIDGenerator(req, now)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, g.req.Count)
+	buf := make([]byte, 0, 45)
+	for i := range ids {
+		ids[i] = string(g.Append(buf[:0]))
+	}
+	return ids, nil
+}
+
+// newV4 returns a random version 4 UUID
+func newV4() [16]byte {
+	var b [16]byte
+	// crypto/rand.Read never returns an error since Go 1.24
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return b
+}
+
+// nextV7 returns a version 7 UUID above the previous one
+func (g *UUIDGenerator) nextV7() [16]byte {
+	const loBits, hiBits = 62, 12
+	if !g.started {
+		g.hi, g.lo = randomBits(hiBits), randomBits(loBits)
+		g.started = true
+	} else {
+		g.lo += 1 + randomBits(32)
+		if g.lo >= 1<<loBits {
+			g.lo -= 1 << loBits
+			g.hi++
+		}
+		if g.hi >= 1<<hiBits {
+			// Out of random bits within this millisecond: take the next
+			// one, which keeps the order
+			g.hi = 0
+			g.ms++
+		}
+	}
+
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[8:], g.lo)
+	b[8] = b[8]&0x3f | 0x80
+	binary.BigEndian.PutUint16(b[6:], uint16(g.hi)|0x7000)
+	putUint48(b[:6], g.ms)
+	return b
+}
+
+// nextULID returns a ULID above the previous one
+func (g *UUIDGenerator) nextULID() [16]byte {
+	const hiBits = 16
+	if !g.started {
+		g.hi, g.lo = randomBits(hiBits), randomBits(64)
+		g.started = true
+	} else {
+		prev := g.lo
+		g.lo += 1 + randomBits(32)
+		if g.lo < prev {
+			g.hi++
+		}
+		if g.hi >= 1<<hiBits {
+			g.hi = 0
+			g.ms++
+		}
+	}
+
+	var b [16]byte
+	putUint48(b[:6], g.ms)
+	binary.BigEndian.PutUint16(b[6:], uint16(g.hi))
+	binary.BigEndian.PutUint64(b[8:], g.lo)
+	return b
+}
+
+// randomBits returns a random number below 1<<n, for n up to 64
+func randomBits(n uint) uint64 {
+	var b [8]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint64(b[:]) >> (64 - n)
+}
+
+// putUint48 writes the low 48 bits of v big-endian into b
+func putUint48(b []byte, v uint64) {
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+}
+
+// appendUUID appends u as 32 hex digits, grouped 8-4-4-4-12 by dashes
+// unless compact and prefixed with urn:uuid: for urn
+func (g *UUIDGenerator) appendUUID(dst []byte, u [16]byte) []byte {
+	digits := lowerHex
+	if g.req.Uppercase {
+		digits = upperHex
+	}
+	if g.req.Format == UUIDFormatURN {
+		dst = append(dst, "urn:uuid:"...)
+	}
+	for i, c := range u {
+		if g.req.Format != UUIDFormatCompact && (i == 4 || i == 6 || i == 8 || i == 10) {
+			dst = append(dst, '-')
+		}
+		dst = append(dst, digits[c>>4], digits[c&0x0f])
+	}
+	return dst
+}
+
+// appendULID appends u as 26 Crockford base32 digits, the 128 bits
+// preceded by two zero bits
+func appendULID(dst []byte, u [16]byte) []byte {
+	high := binary.BigEndian.Uint64(u[:8])
+	low := binary.BigEndian.Uint64(u[8:])
+	for shift := 125; shift >= 0; shift -= 5 {
+		var v uint64
+		switch {
+		case shift >= 64:
+			v = high >> (shift - 64)
+		case shift > 59:
+			v = high<<(64-shift) | low>>shift
+		default:
+			v = low >> shift
+		}
+		dst = append(dst, crockfordBase32[v&0x1f])
+	}
+	return dst
+}
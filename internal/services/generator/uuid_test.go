@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+var uuidNow = time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+
+func TestGenerateUUIDFormats(t *testing.T) {
+	tests := []struct {
+		req     models.UUIDRequest
+		pattern string
+	}{
+		{models.UUIDRequest{}, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{models.UUIDRequest{Version: "v4", Format: "compact", Uppercase: true}, `^[0-9A-F]{12}4[0-9A-F]{3}[89AB][0-9A-F]{15}$`},
+		{models.UUIDRequest{Version: "v4", Format: "urn"}, `^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		// 2026-03-02T09:30:00Z is 0x019cade211c0 ms
+		{models.UUIDRequest{Version: "v7"}, `^019cade2-11c0-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{models.UUIDRequest{Version: "ulid"}, `^01KJPY44E0[0-9A-HJKMNP-TV-Z]{16}$`},
+		{models.UUIDRequest{Version: "ulid", Format: "compact"}, `^01KJPY44E0[0-9A-HJKMNP-TV-Z]{16}$`},
+	}
+	for _, tt := range tests {
+		ids, err := GenerateUUIDs(tt.req, uuidNow)
+		if err != nil {
+			t.Errorf("%+v: %v", tt.req, err)
+			continue
+		}
+		if len(ids) != 1 || !regexp.MustCompile(tt.pattern).MatchString(ids[0]) {
+			t.Errorf("%+v = %q, want one matching %s", tt.req, ids, tt.pattern)
+		}
+	}
+}
+
+func TestGenerateUUIDRejects(t *testing.T) {
+	for _, req := range []models.UUIDRequest{
+		{Version: "v1"},
+		{Count: -1},
+		{Count: MaxUUIDCount + 1},
+		{Format: "braces"},
+		{Version: "ulid", Format: "urn"},
+	} {
+		if _, err := GenerateUUIDs(req, uuidNow); !errors.Is(err, ErrInvalidUUIDRequest) {
+			t.Errorf("%+v: err = %v, want ErrInvalidUUIDRequest", req, err)
+		}
+	}
+}
+
+// v7 UUIDs and ULIDs of one request sort in the order they were generated,
+// as text and as bytes
+func TestGenerateUUIDMonotonic(t *testing.T) {
+	for _, version := range []string{UUIDVersion7, UUIDULID} {
+		ids, err := GenerateUUIDs(models.UUIDRequest{Version: version, Count: MaxUUIDCount}, uuidNow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i-1] >= ids[i] {
+				t.Fatalf("%s: %s is not above %s", version, ids[i], ids[i-1])
+			}
+		}
+	}
+}
+
+// Running out of random bits moves to the next millisecond instead of
+// wrapping around
+func TestGenerateUUIDOverflow(t *testing.T) {
+	for _, version := range []string{UUIDVersion7, UUIDULID} {
+		g, err := NewUUIDGenerator(models.UUIDRequest{Version: version}, uuidNow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := string(g.Append(nil))
+		if version == UUIDVersion7 {
+			g.hi, g.lo = 1<<12-1, 1<<62-1
+		} else {
+			g.hi, g.lo = 1<<16-1, 1<<64-1
+		}
+		second := string(g.Append(nil))
+		if g.ms != uint64(uuidNow.UnixMilli())+1 || second <= first {
+			t.Errorf("%s: after overflow ms = %d, %s after %s", version, g.ms, second, first)
+		}
+	}
+}
+
+func TestAppendULID(t *testing.T) {
+	// The example of the ULID spec: 01ARZ3NDEKTSV4RRFFQ69G5FAV
+	b, _ := hex.DecodeString("01563e3ab5d3d6764c61efb99302bd5b")
+	var u [16]byte
+	copy(u[:], b)
+	if got := string(appendULID(nil, u)); got != "01ARZ3NDEKTSV4RRFFQ69G5FAV" {
+		t.Errorf("appendULID = %s", got)
+	}
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	if got := string(appendULID(nil, max)); got != "7"+strings.Repeat("Z", 25) {
+		t.Errorf("largest ULID = %s", got)
+	}
+}
+
+func TestGenerateUUIDv4Unique(t *testing.T) {
+	ids, err := GenerateUUIDs(models.UUIDRequest{Count: MaxUUIDCount}, uuidNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(ids)
+	if len(slices.Compact(ids)) != MaxUUIDCount {
+		t.Error("duplicate v4 UUIDs")
+	}
+}
@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/config"
+)
+
+// MailSender defines outgoing email delivery
+type MailSender interface {
+	Send(to, subject, body string) error
+}
+
+type smtpMailSender struct {
+	addr string
+	from string
+}
+
+// NewSMTPMailSender creates a sender that relays through an unauthenticated
+// SMTP relay. It returns nil when no relay is configured.
+func NewSMTPMailSender(cfg *config.Config) MailSender {
+	if cfg == nil || cfg.SMTPHost == "" || cfg.SMTPFrom == "" {
+		return nil
+	}
+	return &smtpMailSender{
+		addr: net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		from: cfg.SMTPFrom,
+	}
+}
+
+// Send delivers a plain-text message
+func (s *smtpMailSender) Send(to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid mail header value")
+	}
+	msg := "From: " + s.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + body
+	return smtp.SendMail(s.addr, nil, s.from, []string{to}, []byte(msg))
+}
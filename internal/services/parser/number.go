@@ -0,0 +1,598 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/innovelabs/microtools-go/internal/dataset"
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+const (
+	// ISO4217Dataset is the dataset registry name of the currency table
+	ISO4217Dataset = "iso-4217-currencies"
+
+	// MaxNumberBatchSize caps the values parsed in one request
+	MaxNumberBatchSize = 1000
+
+	ConventionSourceLocale   = "locale"
+	ConventionSourceDetected = "detected"
+)
+
+var (
+	ErrInvalidNumber     = errors.New("invalid number")
+	ErrUnsupportedLocale = errors.New("unsupported locale")
+	ErrTooManyValues     = errors.New("too many values")
+)
+
+// convention is a pair of group and decimal separators. No-break spaces and
+// U+2019 are folded into a plain space and apostrophe before a convention is
+// applied.
+type convention struct {
+	name    string
+	group   rune
+	decimal rune
+}
+
+var conventions = map[string]convention{
+	"en": {name: "en", group: ',', decimal: '.'},  // 1,234.56
+	"de": {name: "de", group: '.', decimal: ','},  // 1.234,56
+	"fr": {name: "fr", group: ' ', decimal: ','},  // 1 234,56
+	"si": {name: "si", group: ' ', decimal: '.'},  // 1 234.56
+	"ch": {name: "ch", group: '\'', decimal: '.'}, // 1'234.56
+}
+
+// localeConventions maps language tags, most specific first, to the
+// convention CLDR gives them
+var localeConventions = map[string]string{
+	"en": "en", "ja": "en", "zh": "en", "ko": "en", "th": "en", "he": "en", "hi": "en",
+	"bn": "en", "ta": "en", "te": "en", "ms": "en", "fil": "en", "ga": "en",
+	"es-mx": "en", "es-us": "en", "es-419": "en",
+	"de": "de", "nl": "de", "it": "de", "es": "de", "pt": "de", "da": "de", "id": "de",
+	"tr": "de", "el": "de", "ro": "de", "hr": "de", "sl": "de", "sr": "de", "is": "de",
+	"vi": "de", "ca": "de", "gl": "de", "eu": "de", "az": "de", "mk": "de", "bs": "de",
+	"fr": "fr", "ru": "fr", "pl": "fr", "cs": "fr", "sk": "fr", "sv": "fr", "nb": "fr",
+	"nn": "fr", "no": "fr", "fi": "fr", "uk": "fr", "hu": "fr", "bg": "fr", "lt": "fr",
+	"lv": "fr", "et": "fr", "be": "fr", "kk": "fr", "ka": "fr", "hy": "fr",
+	"pt-pt": "fr", "de-at": "fr", "en-za": "fr",
+	"de-ch": "ch", "it-ch": "ch", "de-li": "ch", "rm": "ch",
+}
+
+// currencySymbols maps symbols to the currencies using them, the most
+// common first. Three-letter ISO codes are recognised without an entry.
+var currencySymbols = map[string][]string{
+	"$":   {"USD", "CAD", "AUD", "NZD", "MXN", "SGD", "HKD", "ARS", "CLP", "COP", "UYU"},
+	"US$": {"USD"}, "C$": {"CAD"}, "CA$": {"CAD"}, "A$": {"AUD"}, "AU$": {"AUD"},
+	"NZ$": {"NZD"}, "HK$": {"HKD"}, "S$": {"SGD"}, "MX$": {"MXN"}, "NT$": {"TWD"},
+	"R$": {"BRL"},
+	"€":  {"EUR"}, "£": {"GBP"}, "¥": {"JPY", "CNY"}, "￥": {"JPY", "CNY"}, "円": {"JPY"},
+	"元": {"CNY"}, "₹": {"INR"}, "₩": {"KRW"}, "₽": {"RUB"}, "₺": {"TRY"}, "₪": {"ILS"},
+	"₫": {"VND"}, "฿": {"THB"}, "₴": {"UAH"}, "₦": {"NGN"}, "₱": {"PHP"}, "₡": {"CRC"},
+	"₲": {"PYG"}, "₸": {"KZT"}, "₼": {"AZN"}, "₾": {"GEL"}, "৳": {"BDT"}, "₵": {"GHS"},
+	"₨": {"PKR", "LKR", "NPR", "MUR"}, "Rs": {"INR", "PKR", "LKR", "NPR"},
+	"Rs.": {"INR", "PKR", "LKR", "NPR"},
+	"zł":  {"PLN"}, "Kč": {"CZK"}, "Ft": {"HUF"}, "lei": {"RON"}, "лв": {"BGN"}, "лв.": {"BGN"},
+	"kr": {"SEK", "NOK", "DKK", "ISK"}, "kr.": {"DKK", "ISK"},
+	"Fr": {"CHF"}, "Fr.": {"CHF"}, "fr.": {"CHF"}, "SFr.": {"CHF"},
+	"R": {"ZAR"}, "Rp": {"IDR"}, "RM": {"MYR"}, "S/": {"PEN"}, "din.": {"RSD"},
+	"CFA": {"XOF", "XAF"}, "FCFA": {"XAF", "XOF"},
+}
+
+// regionCurrencies and languageCurrencies pick between the currencies
+// sharing a symbol when a locale is given
+var regionCurrencies = map[string]string{
+	"us": "USD", "ca": "CAD", "au": "AUD", "nz": "NZD", "mx": "MXN", "sg": "SGD",
+	"hk": "HKD", "ar": "ARS", "cl": "CLP", "co": "COP", "uy": "UYU", "jp": "JPY",
+	"cn": "CNY", "se": "SEK", "no": "NOK", "dk": "DKK", "is": "ISK", "in": "INR",
+	"pk": "PKR", "lk": "LKR", "np": "NPR", "mu": "MUR",
+	"sn": "XOF", "ci": "XOF", "ml": "XOF", "bf": "XOF", "ne": "XOF", "tg": "XOF", "bj": "XOF",
+	"cm": "XAF", "ga": "XAF", "cg": "XAF", "td": "XAF", "cf": "XAF", "gq": "XAF",
+}
+
+var languageCurrencies = map[string]string{
+	"ja": "JPY", "zh": "CNY", "sv": "SEK", "nb": "NOK", "nn": "NOK", "no": "NOK",
+	"da": "DKK", "is": "ISK", "hi": "INR", "ur": "PKR", "si": "LKR", "ne": "NPR",
+}
+
+func init() {
+	for symbol, codes := range currencySymbols {
+		for _, code := range codes {
+			if _, ok := models.ISO4217Currencies[code]; !ok {
+				panic(fmt.Sprintf("currency symbol %s: unknown code %s", symbol, code))
+			}
+		}
+	}
+	dataset.Register(ISO4217Dataset, models.ISO4217Currencies)
+}
+
+// localeHint is what a locale tag tells the parser
+type localeHint struct {
+	convention *convention
+	currency   string
+}
+
+// resolveLocale maps a BCP 47 tag such as "de-CH" or "pt_BR" to its
+// convention, trying language-region before the bare language
+func resolveLocale(locale string) (localeHint, error) {
+	tag := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if tag == "" {
+		return localeHint{}, nil
+	}
+	subtags := strings.Split(tag, "-")
+	language, region := subtags[0], ""
+	for _, subtag := range subtags[1:] {
+		if len(subtag) == 2 || (len(subtag) == 3 && strings.Trim(subtag, "0123456789") == "") {
+			region = subtag
+			break
+		}
+	}
+
+	name, ok := localeConventions[language+"-"+region]
+	if !ok {
+		name, ok = localeConventions[language]
+	}
+	if !ok {
+		return localeHint{}, fmt.Errorf("%w: %q", ErrUnsupportedLocale, locale)
+	}
+	conv := conventions[name]
+	currency := regionCurrencies[region]
+	if currency == "" {
+		currency = languageCurrencies[language]
+	}
+	return localeHint{convention: &conv, currency: currency}, nil
+}
+
+// ParseNumber parses one numeric string. With a locale the separators must
+// follow its convention; without one the convention is detected:
+//
+//   - with two kinds of separator, the last one is the decimal separator
+//     and must occur once
+//   - a space or apostrophe alone is a group separator
+//   - a comma or dot occurring more than once is a group separator
+//   - a single comma or dot is a decimal separator unless exactly three
+//     digits follow it and one to three digits, not starting with 0,
+//     precede it
+//   - that remaining case ("1,234", "1.234") is ambiguous and is read in
+//     the en convention, so "1,234" is 1234 and "1.234" is 1.234, with
+//     Ambiguous set
+//
+// Groups must be three digits, or the Indian 2-digit grouping with commas.
+// Currency symbols and ISO codes, a percent sign, leading or trailing minus
+// signs (including U+2212) and accounting parentheses are accepted around
+// the number, as is a dashed Swiss fraction ("2'500.–"). The value of a
+// percentage is returned as written, so "12.5%" is "12.5" with IsPercent.
+func ParseNumber(input, locale string) (models.ParsedNumber, error) {
+	hint, err := resolveLocale(locale)
+	if err != nil {
+		return models.ParsedNumber{}, err
+	}
+	return parseNumber(input, hint)
+}
+
+// ParseNumbers parses up to MaxNumberBatchSize values with one locale,
+// reporting per value errors in the results
+func ParseNumbers(values []string, locale string) ([]models.ParsedNumber, error) {
+	if len(values) > MaxNumberBatchSize {
+		return nil, fmt.Errorf("%w: at most %d values per request", ErrTooManyValues, MaxNumberBatchSize)
+	}
+	hint, err := resolveLocale(locale)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]models.ParsedNumber, len(values))
+	for i, value := range values {
+		result, err := parseNumber(value, hint)
+		if err != nil {
+			result = models.ParsedNumber{Input: value, Error: err.Error()}
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+func invalidNumber(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidNumber, fmt.Sprintf(format, args...))
+}
+
+func parseNumber(input string, hint localeHint) (models.ParsedNumber, error) {
+	result := models.ParsedNumber{Input: input}
+	runes := []rune(strings.Map(foldFullwidth, strings.TrimSpace(input)))
+	if len(runes) == 0 {
+		return result, invalidNumber("value is empty")
+	}
+
+	first, last := -1, -1
+	for i, r := range runes {
+		if isDigit(r) {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return result, invalidNumber("no digits")
+	}
+	if first > 0 && (runes[first-1] == '.' || runes[first-1] == ',') {
+		first--
+	}
+	prefix, core, suffix := runes[:first], runes[first:last+1], runes[last+1:]
+
+	// A dash in place of the fraction ("2'500.–", "1.000,-") means no
+	// minor units and marks the decimal separator
+	var dashDecimal rune
+	if len(suffix) >= 2 && (suffix[0] == '.' || suffix[0] == ',') && isDash(suffix[1]) {
+		dashDecimal, suffix = suffix[0], suffix[2:]
+	}
+
+	affix, err := parseAffixes(prefix, suffix)
+	if err != nil {
+		return result, err
+	}
+	if affix.currency != "" {
+		currency, err := resolveCurrency(affix.currency, hint.currency)
+		if err != nil {
+			return result, err
+		}
+		if affix.percent {
+			return result, invalidNumber("a value cannot be both a percentage and an amount")
+		}
+		result.Currency = currency
+	}
+	result.IsPercent = affix.percent
+
+	core, err = foldSeparators(core)
+	if err != nil {
+		return result, err
+	}
+
+	conv, source, ambiguous := (*convention)(nil), ConventionSourceLocale, false
+	if hint.convention != nil {
+		conv = hint.convention
+		for _, r := range core {
+			if !isDigit(r) && r != conv.group && r != conv.decimal {
+				return result, invalidNumber("separator %q is not used by the %s convention", r, conv.name)
+			}
+		}
+		if dashDecimal != 0 && dashDecimal != conv.decimal {
+			return result, invalidNumber("%q is not the decimal separator of the %s convention", dashDecimal, conv.name)
+		}
+	} else {
+		source = ConventionSourceDetected
+		conv, ambiguous, err = detectConvention(core, dashDecimal)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	integer, fraction := string(core), ""
+	if conv != nil {
+		integer, fraction, err = splitNumber(core, *conv, dashDecimal != 0)
+		if err != nil {
+			return result, err
+		}
+		result.Convention = &models.NumberConvention{
+			Name:             conv.name,
+			DecimalSeparator: string(conv.decimal),
+			GroupSeparator:   string(conv.group),
+			Source:           source,
+		}
+	}
+	result.Ambiguous = ambiguous
+
+	integer = strings.TrimLeft(integer, "0")
+	if integer == "" {
+		integer = "0"
+	}
+	value := integer
+	if fraction != "" {
+		value += "." + fraction
+	}
+	if affix.negative && strings.Trim(value, "0.") != "" {
+		value = "-" + value
+	}
+	result.Value = value
+	return result, nil
+}
+
+// affixes is what surrounds the digits of a number
+type affixes struct {
+	negative bool
+	percent  bool
+	currency string
+}
+
+func parseAffixes(prefix, suffix []rune) (affixes, error) {
+	var (
+		affix        affixes
+		signs        int
+		open, closed bool
+		tokens       []string
+	)
+	scan := func(runes []rune, leading bool) error {
+		var token []rune
+		flush := func() {
+			if len(token) > 0 {
+				tokens = append(tokens, string(token))
+				token = nil
+			}
+		}
+		for _, r := range runes {
+			switch {
+			case unicode.IsSpace(r):
+				flush()
+			case r == '(' && leading && !open:
+				open = true
+				flush()
+			case r == ')' && !leading && !closed:
+				closed = true
+				flush()
+			case isMinus(r) || (leading && r == '–'):
+				signs++
+				affix.negative = true
+				flush()
+			case r == '+':
+				signs++
+				flush()
+			case r == '%':
+				if affix.percent {
+					return invalidNumber("more than one percent sign")
+				}
+				affix.percent = true
+				flush()
+			default:
+				token = append(token, r)
+			}
+		}
+		flush()
+		return nil
+	}
+	if err := scan(prefix, true); err != nil {
+		return affix, err
+	}
+	if err := scan(suffix, false); err != nil {
+		return affix, err
+	}
+
+	switch {
+	case signs > 1:
+		return affix, invalidNumber("more than one sign")
+	case open != closed:
+		return affix, invalidNumber("unbalanced parentheses")
+	case open && signs > 0:
+		return affix, invalidNumber("a sign cannot be combined with accounting parentheses")
+	case len(tokens) > 1:
+		return affix, invalidNumber("unexpected text %q", tokens[1])
+	}
+	affix.negative = affix.negative || open
+	if len(tokens) == 1 {
+		affix.currency = tokens[0]
+	}
+	return affix, nil
+}
+
+// resolveCurrency looks token up as a symbol, then as an ISO 4217 code.
+// A symbol shared by several currencies resolves to the locale's currency
+// when it is one of them, otherwise to the most common one with Ambiguous
+// set.
+func resolveCurrency(token, preferred string) (*models.DetectedCurrency, error) {
+	codes, ok := currencySymbols[token]
+	if !ok {
+		currency, found := models.ISO4217Currencies[strings.ToUpper(token)]
+		if !found {
+			return nil, invalidNumber("unrecognised currency or text %q", token)
+		}
+		codes = []string{currency.Code}
+	}
+
+	code, ambiguous := codes[0], len(codes) > 1
+	for _, candidate := range codes {
+		if ambiguous && candidate == preferred {
+			code, ambiguous = candidate, false
+		}
+	}
+	currency := models.ISO4217Currencies[code]
+	return &models.DetectedCurrency{
+		Code:       currency.Code,
+		Name:       currency.Name,
+		MinorUnits: currency.MinorUnits,
+		Symbol:     token,
+		Ambiguous:  ambiguous,
+	}, nil
+}
+
+// foldSeparators normalises the space and apostrophe variants in the digits
+// and rejects anything that is neither a digit nor a separator
+func foldSeparators(core []rune) ([]rune, error) {
+	folded := make([]rune, len(core))
+	for i, r := range core {
+		switch r {
+		case ' ', '\u00a0', '\u202f', '\u2009':
+			r = ' '
+		case '\u2019':
+			r = '\''
+		case '.', ',', '\'':
+		default:
+			if !isDigit(r) {
+				return nil, invalidNumber("unexpected character %q", r)
+			}
+		}
+		folded[i] = r
+	}
+	return folded, nil
+}
+
+// detectConvention applies the rules documented on ParseNumber. It returns
+// nil for a number without separators.
+func detectConvention(core []rune, dashDecimal rune) (*convention, bool, error) {
+	var kinds []rune
+	counts := map[rune]int{}
+	lastSep, lastPos := rune(0), -1
+	for i, r := range core {
+		if isDigit(r) {
+			continue
+		}
+		if counts[r] == 0 {
+			kinds = append(kinds, r)
+		}
+		counts[r]++
+		lastSep, lastPos = r, i
+	}
+
+	if dashDecimal != 0 {
+		switch {
+		case counts[dashDecimal] > 0:
+			return nil, false, invalidNumber("decimal separator %q appears in the integer part", dashDecimal)
+		case len(kinds) > 1:
+			return nil, false, invalidNumber("mixed group separators")
+		case len(kinds) == 1:
+			return lookupConvention(kinds[0], dashDecimal)
+		}
+		return defaultConvention(dashDecimal), false, nil
+	}
+
+	switch len(kinds) {
+	case 0:
+		return nil, false, nil
+	case 1:
+	case 2:
+		if counts[lastSep] > 1 {
+			return nil, false, invalidNumber("decimal separator %q appears more than once", lastSep)
+		}
+		group := kinds[0]
+		if group == lastSep {
+			group = kinds[1]
+		}
+		return lookupConvention(group, lastSep)
+	default:
+		return nil, false, invalidNumber("too many kinds of separator")
+	}
+
+	switch sep := kinds[0]; {
+	case sep == ' ':
+		conv := conventions["fr"]
+		return &conv, false, nil
+	case sep == '\'':
+		conv := conventions["ch"]
+		return &conv, false, nil
+	case counts[sep] > 1:
+		return lookupConvention(sep, 0)
+	}
+
+	before, after := core[:lastPos], core[lastPos+1:]
+	if len(after) != 3 || len(before) == 0 || len(before) > 3 || before[0] == '0' {
+		return defaultConvention(lastSep), false, nil
+	}
+	// Tie-break: "1,234" and "1.234" are read in the en convention
+	conv := conventions["en"]
+	return &conv, true, nil
+}
+
+// lookupConvention finds the convention with group and decimal separators;
+// a zero decimal matches on the group alone
+func lookupConvention(group, decimal rune) (*convention, bool, error) {
+	for _, name := range []string{"en", "de", "fr", "si", "ch"} {
+		conv := conventions[name]
+		if conv.group == group && (decimal == 0 || conv.decimal == decimal) {
+			return &conv, false, nil
+		}
+	}
+	return nil, false, invalidNumber("unsupported separators: group %q with decimal %q", group, decimal)
+}
+
+// defaultConvention is used when only the decimal separator is known
+func defaultConvention(decimal rune) *convention {
+	conv := conventions["en"]
+	if decimal == ',' {
+		conv = conventions["de"]
+	}
+	return &conv
+}
+
+// splitNumber splits core into integer and fraction digits under conv,
+// checking the grouping. dashed means the fraction was a dash.
+func splitNumber(core []rune, conv convention, dashed bool) (string, string, error) {
+	text := string(core)
+	integer, fraction, found := strings.Cut(text, string(conv.decimal))
+	if found && dashed {
+		return "", "", invalidNumber("decimal separator %q appears in the integer part", conv.decimal)
+	}
+	if strings.ContainsRune(fraction, conv.decimal) {
+		return "", "", invalidNumber("decimal separator %q appears more than once", conv.decimal)
+	}
+	if strings.ContainsRune(fraction, conv.group) {
+		return "", "", invalidNumber("group separator %q appears in the fraction", conv.group)
+	}
+	if found && fraction == "" {
+		return "", "", invalidNumber("no digits after the decimal separator")
+	}
+
+	if strings.ContainsRune(integer, conv.group) {
+		groups := strings.Split(integer, string(conv.group))
+		if !isThousandsGrouping(groups) && !(conv.group == ',' && isIndianGrouping(groups)) {
+			return "", "", invalidNumber("digits are not grouped in threes")
+		}
+		integer = strings.Join(groups, "")
+	}
+	return integer, fraction, nil
+}
+
+// isThousandsGrouping accepts 1,234,567
+func isThousandsGrouping(groups []string) bool {
+	if len(groups[0]) < 1 || len(groups[0]) > 3 {
+		return false
+	}
+	for _, group := range groups[1:] {
+		if len(group) != 3 {
+			return false
+		}
+	}
+	return true
+}
+
+// isIndianGrouping accepts 12,34,567
+func isIndianGrouping(groups []string) bool {
+	n := len(groups)
+	if n < 3 || len(groups[0]) < 1 || len(groups[0]) > 2 || len(groups[n-1]) != 3 {
+		return false
+	}
+	for _, group := range groups[1 : n-1] {
+		if len(group) != 2 {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isMinus(r rune) bool {
+	return r == '-' || r == '\u2212' || r == '\ufe63'
+}
+
+func isDash(r rune) bool {
+	return r == '-' || r == '–' || r == '—'
+}
+
+// foldFullwidth maps fullwidth digits and punctuation to ASCII
+func foldFullwidth(r rune) rune {
+	switch {
+	case r >= '０' && r <= '９':
+		return '0' + (r - '０')
+	case r == '，':
+		return ','
+	case r == '．':
+		return '.'
+	case r == '％':
+		return '%'
+	case r == '－':
+		return '-'
+	case r == '＋':
+		return '+'
+	}
+	return r
+}
@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		input, locale string
+		value         string
+		convention    string // "" when the number has no separators
+		source        string
+		ambiguous     bool
+		currency      string
+		percent       bool
+	}{
+		// The same text under different locales
+		{"1.234", "de", "1234", "de", ConventionSourceLocale, false, "", false},
+		{"1.234", "en", "1.234", "en", ConventionSourceLocale, false, "", false},
+		{"1.234", "de-CH", "1.234", "ch", ConventionSourceLocale, false, "", false},
+		{"1,234", "de", "1.234", "de", ConventionSourceLocale, false, "", false},
+		{"1,234", "en_US", "1234", "en", ConventionSourceLocale, false, "", false},
+		{"1 234,5", "fr", "1234.5", "fr", ConventionSourceLocale, false, "", false},
+		{"1 234,5", "pt-PT", "1234.5", "fr", ConventionSourceLocale, false, "", false},
+		{"1,234.5", "es-MX", "1234.5", "en", ConventionSourceLocale, false, "", false},
+
+		// Auto-detect tie-break: one comma or dot with three digits after
+		// and one to three before is read in the en convention
+		{"1.234", "", "1.234", "en", ConventionSourceDetected, true, "", false},
+		{"1,234", "", "1234", "en", ConventionSourceDetected, true, "", false},
+		{"123,456", "", "123456", "en", ConventionSourceDetected, true, "", false},
+		// Not the tie-break: the separator is a decimal separator
+		{"0,234", "", "0.234", "de", ConventionSourceDetected, false, "", false},
+		{"1234,567", "", "1234.567", "de", ConventionSourceDetected, false, "", false},
+		{"1,23", "", "1.23", "de", ConventionSourceDetected, false, "", false},
+		{"1.2345", "", "1.2345", "en", ConventionSourceDetected, false, "", false},
+		{",5", "", "0.5", "de", ConventionSourceDetected, false, "", false},
+		// A repeated comma or dot groups
+		{"1.234.567", "", "1234567", "de", ConventionSourceDetected, false, "", false},
+		{"1,234,567", "", "1234567", "en", ConventionSourceDetected, false, "", false},
+		// With two kinds, the last is the decimal separator
+		{"1.234,56", "", "1234.56", "de", ConventionSourceDetected, false, "", false},
+		{"1,234.56", "", "1234.56", "en", ConventionSourceDetected, false, "", false},
+		{"1 234.56", "", "1234.56", "si", ConventionSourceDetected, false, "", false},
+		{"1 234,56", "", "1234.56", "fr", ConventionSourceDetected, false, "", false},
+		{"1’234.56", "", "1234.56", "ch", ConventionSourceDetected, false, "", false},
+		// A space or apostrophe alone groups
+		{"1 234", "", "1234", "fr", ConventionSourceDetected, false, "", false},
+		{"1'234", "", "1234", "ch", ConventionSourceDetected, false, "", false},
+		// Indian grouping
+		{"12,34,567.89", "", "1234567.89", "en", ConventionSourceDetected, false, "", false},
+		{"1,23,456", "", "123456", "en", ConventionSourceDetected, false, "", false},
+
+		// Affixes
+		{"42", "", "42", "", "", false, "", false},
+		{"007", "", "7", "", "", false, "", false},
+		{"-5", "", "-5", "", "", false, "", false},
+		{"−5", "", "-5", "", "", false, "", false},
+		{"5-", "", "-5", "", "", false, "", false},
+		{"+5", "", "5", "", "", false, "", false},
+		{"-0,00", "", "0.00", "de", ConventionSourceDetected, false, "", false},
+		{"(1,234.50)", "", "-1234.50", "en", ConventionSourceDetected, false, "", false},
+		{"12.5%", "", "12.5", "en", ConventionSourceDetected, false, "", true},
+		{"-12,5 %", "fr", "-12.5", "fr", ConventionSourceLocale, false, "", true},
+		{"１２３", "", "123", "", "", false, "", false},
+
+		// Currencies
+		{"CHF 2'500.—", "", "2500", "ch", ConventionSourceDetected, false, "CHF", false},
+		{"2'500.–", "de-CH", "2500", "ch", ConventionSourceLocale, false, "", false},
+		{"1.000,-", "", "1000", "de", ConventionSourceDetected, false, "", false},
+		{"€1.234,56", "", "1234.56", "de", ConventionSourceDetected, false, "EUR", false},
+		{"1 234,56 €", "fr", "1234.56", "fr", ConventionSourceLocale, false, "EUR", false},
+		{"-$5.00", "", "-5.00", "en", ConventionSourceDetected, false, "USD", false},
+		{"$5.00", "en-CA", "5.00", "en", ConventionSourceLocale, false, "CAD", false},
+		{"usd 10", "", "10", "", "", false, "USD", false},
+		{"¥1,000", "ja", "1000", "en", ConventionSourceLocale, false, "JPY", false},
+		{"kr 100", "nb", "100", "fr", ConventionSourceLocale, false, "NOK", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseNumber(tt.input, tt.locale)
+		if err != nil {
+			t.Errorf("%q (%s): %v", tt.input, tt.locale, err)
+			continue
+		}
+		if got.Input != tt.input || got.Value != tt.value || got.Ambiguous != tt.ambiguous || got.IsPercent != tt.percent {
+			t.Errorf("%q (%s): value %q, ambiguous %v, percent %v; want %q, %v, %v",
+				tt.input, tt.locale, got.Value, got.Ambiguous, got.IsPercent, tt.value, tt.ambiguous, tt.percent)
+		}
+		switch {
+		case tt.convention == "" && got.Convention != nil:
+			t.Errorf("%q (%s): convention %+v, want none", tt.input, tt.locale, got.Convention)
+		case tt.convention != "" && (got.Convention == nil || got.Convention.Name != tt.convention || got.Convention.Source != tt.source):
+			t.Errorf("%q (%s): convention %+v, want %s from %s", tt.input, tt.locale, got.Convention, tt.convention, tt.source)
+		}
+		code := ""
+		if got.Currency != nil {
+			code = got.Currency.Code
+		}
+		if code != tt.currency {
+			t.Errorf("%q (%s): currency %q, want %q", tt.input, tt.locale, code, tt.currency)
+		}
+	}
+}
+
+func TestParseNumberAmbiguousCurrency(t *testing.T) {
+	got, err := ParseNumber("$5", "")
+	if err != nil || got.Currency == nil || got.Currency.Code != "USD" || !got.Currency.Ambiguous || got.Currency.Symbol != "$" {
+		t.Errorf("$5 = %+v, %v, want USD marked ambiguous", got.Currency, err)
+	}
+	got, err = ParseNumber("$5", "es-AR")
+	if err != nil || got.Currency == nil || got.Currency.Code != "ARS" || got.Currency.Ambiguous {
+		t.Errorf("$5 (es-AR) = %+v, %v, want ARS", got.Currency, err)
+	}
+}
+
+func TestParseNumberErrors(t *testing.T) {
+	for _, tt := range []struct{ input, locale string }{
+		{"", ""},
+		{"abc", ""},
+		{"1,2345,678", ""},
+		{"12,3456", "en"},
+		{"1.234.56", ""},
+		{"1,234.567,8", ""},
+		{"1.234,56", "en"},
+		{"1'234", "de"},
+		{"1,23,456", "de"},
+		{"1.", ""},
+		{"--5", ""},
+		{"(5", ""},
+		{"(-5)", ""},
+		{"5%%", ""},
+		{"€5%", ""},
+		{"5 apples", ""},
+		{"XYZ 5", ""},
+		{"1.000,-", "en"},
+		{"2'500,5.-", ""},
+	} {
+		if got, err := ParseNumber(tt.input, tt.locale); !errors.Is(err, ErrInvalidNumber) {
+			t.Errorf("%q (%s) = %+v, %v, want ErrInvalidNumber", tt.input, tt.locale, got, err)
+		}
+	}
+	if _, err := ParseNumber("1", "tlh"); !errors.Is(err, ErrUnsupportedLocale) {
+		t.Errorf("tlh: err = %v, want ErrUnsupportedLocale", err)
+	}
+}
+
+func TestParseNumbers(t *testing.T) {
+	results, err := ParseNumbers([]string{"1.234,5", "oops", "7"}, "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[0].Value != "1234.5" || results[2].Value != "7" {
+		t.Errorf("results = %+v", results)
+	}
+	if results[1].Input != "oops" || !strings.HasPrefix(results[1].Error, "invalid number") {
+		t.Errorf("bad value = %+v, want its error in the result", results[1])
+	}
+
+	if _, err := ParseNumbers(make([]string, MaxNumberBatchSize+1), ""); !errors.Is(err, ErrTooManyValues) {
+		t.Errorf("over the limit: err = %v, want ErrTooManyValues", err)
+	}
+	if _, err := ParseNumbers([]string{"1"}, "xx-YY"); !errors.Is(err, ErrUnsupportedLocale) {
+		t.Errorf("bad locale: err = %v, want ErrUnsupportedLocale", err)
+	}
+}
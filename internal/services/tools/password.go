@@ -0,0 +1,651 @@
+package tools
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// Kinds of password check findings
+const (
+	FindingCommonPassword   = "common_password"
+	FindingContainsCommon   = "contains_common_password"
+	FindingRepeated         = "repeated_characters"
+	FindingSequence         = "sequence"
+	FindingKeyboardSequence = "keyboard_sequence"
+	FindingDate             = "date"
+	FindingTooShort         = "too_short"
+)
+
+// Character classes of generated passwords
+const (
+	ClassLowercase = "lowercase"
+	ClassUppercase = "uppercase"
+	ClassDigits    = "digits"
+	ClassSymbols   = "symbols"
+)
+
+const (
+	// MaxPasswordBytes is the longest password one check estimates
+	MaxPasswordBytes = 256
+	// MinGoodPasswordLength is the length below which a check reports
+	// too_short
+	MinGoodPasswordLength = 8
+
+	DefaultGeneratedLength = 16
+	MinGeneratedLength     = 8
+	MaxGeneratedLength     = 128
+	// MaxGeneratedCount is the most passwords one request generates
+	MaxGeneratedCount = 50
+
+	// minCommonSubstring is the shortest common password reported inside
+	// a longer one
+	minCommonSubstring = 4
+	// minKeyboardRun is the shortest run of adjacent keys reported
+	minKeyboardRun = 4
+	// generateAttempts bounds the passwords drawn until one has every
+	// class; with at least 8 characters the chance of running out is
+	// below 1e-20
+	generateAttempts = 1000
+)
+
+var (
+	ErrInvalidPassword        = errors.New("invalid password check")
+	ErrPasswordTooLong        = errors.New("password too long")
+	ErrInvalidPasswordOptions = errors.New("invalid password options")
+)
+
+// passwordStrengths names the scores 0 to 4
+var passwordStrengths = [...]string{"very_weak", "weak", "fair", "strong", "very_strong"}
+
+// scoreThresholds are the log10 guesses from which each score from 1 up
+// starts, as in zxcvbn
+var scoreThresholds = [...]float64{3, 6, 8, 10}
+
+// Guesses per second of the crack-time scenarios: an online attack
+// throttled by the service, one that is not, and offline attacks on a
+// slow (bcrypt, scrypt) and a fast (unsalted SHA-1, MD5) hash
+const (
+	onlineThrottledRate   = 100.0 / 3600
+	onlineUnthrottledRate = 10.0
+	offlineSlowHashRate   = 1e4
+	offlineFastHashRate   = 1e10
+	// maxGuessesLog10 caps the guesses crack times are computed from, so
+	// long passwords do not overflow
+	maxGuessesLog10 = 100
+)
+
+// commonPasswordList holds widely published most-used passwords, most
+// frequent first, one per line in lower case. A longer list in the same
+// form can replace it.
+//
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords ranks the bundled passwords from 1, the most common;
+// maxCommonLength is the length of the longest in runes
+var commonPasswords, maxCommonLength = rankPasswords(commonPasswordList)
+
+func rankPasswords(list string) (map[string]int, int) {
+	ranks := map[string]int{}
+	longest := 0
+	for _, word := range strings.Fields(list) {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = len(ranks) + 1
+			longest = max(longest, utf8.RuneCountInString(word))
+		}
+	}
+	return ranks, longest
+}
+
+// leetSubstitutions undoes the digits and symbols commonly written for
+// letters
+var leetSubstitutions = map[rune]rune{
+	'@': 'a', '4': 'a', '3': 'e', '1': 'i', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't',
+}
+
+// keyboardRows are runs of adjacent keys on QWERTY, QWERTZ and AZERTY
+// layouts, including the columns walked top to bottom
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"qwertzuiop",
+	"yxcvbnm",
+	"azertyuiop",
+	"qsdfghjklm",
+	"wxcvbn",
+	"1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik,9ol.0p;/",
+}
+
+// Dates written with separators: year first, or day and month first in
+// either order
+var (
+	datePattern     = regexp.MustCompile(`\d{4}[-/.]\d{1,2}[-/.]\d{1,2}|\d{1,2}[-/.]\d{1,2}[-/.]\d{2}(\d{2})?`)
+	digitRunPattern = regexp.MustCompile(`\d+`)
+)
+
+// passwordMatch is a pattern found in a password: the runes [start, end)
+// and the bits an attacker trying that pattern needs for them
+type passwordMatch struct {
+	kind       string
+	start, end int
+	bits       float64
+	message    string
+}
+
+// CheckPassword estimates the strength of req.Password. The entropy is
+// that of the cheapest way to write the password as characters drawn
+// from the classes it uses and the patterns found in it: common
+// passwords (log2 of their rank), repeated characters, sequences,
+// keyboard runs and dates. The password is not logged or returned.
+func CheckPassword(req models.PasswordCheckRequest) (*models.PasswordCheckResult, error) {
+	if req.Password == "" {
+		return nil, fmt.Errorf("%w: password is required", ErrInvalidPassword)
+	}
+	if len(req.Password) > MaxPasswordBytes {
+		return nil, fmt.Errorf("%w: password exceeds maximum size of %d bytes", ErrPasswordTooLong, MaxPasswordBytes)
+	}
+	runes := []rune(req.Password)
+	matches := passwordMatches(runes)
+	entropy := passwordEntropy(runes, matches)
+	guessesLog10 := entropy * math.Log10(2)
+
+	result := &models.PasswordCheckResult{
+		Length:       len(runes),
+		EntropyBits:  math.Round(entropy*100) / 100,
+		GuessesLog10: math.Round(guessesLog10*100) / 100,
+		CrackTimes: models.PasswordCrackTimes{
+			OnlineThrottled:   crackTime(guessesLog10, onlineThrottledRate),
+			OnlineUnthrottled: crackTime(guessesLog10, onlineUnthrottledRate),
+			OfflineSlowHash:   crackTime(guessesLog10, offlineSlowHashRate),
+			OfflineFastHash:   crackTime(guessesLog10, offlineFastHashRate),
+		},
+		Findings: []models.PasswordFinding{},
+	}
+	for _, threshold := range scoreThresholds {
+		if guessesLog10 >= threshold {
+			result.Score++
+		}
+	}
+	if len(runes) < MinGoodPasswordLength {
+		result.Findings = append(result.Findings, models.PasswordFinding{
+			Type:    FindingTooShort,
+			End:     len(runes),
+			Message: fmt.Sprintf("Shorter than %d characters", MinGoodPasswordLength),
+		})
+	}
+	for _, m := range reportedMatches(matches) {
+		if m.kind == FindingCommonPassword {
+			result.Common = true
+			result.Score = 0
+		}
+		result.Findings = append(result.Findings, models.PasswordFinding{Type: m.kind, Start: m.start, End: m.end, Message: m.message})
+	}
+	result.Strength = passwordStrengths[result.Score]
+	return result, nil
+}
+
+// passwordEntropy returns the bits of the cheapest cover of the password
+// by single characters, each carrying log2 of the pool of the classes the
+// password uses, and matches
+func passwordEntropy(runes []rune, matches []passwordMatch) float64 {
+	charBits := math.Log2(float64(characterPool(runes)))
+	best := make([]float64, len(runes)+1)
+	for end := 1; end <= len(runes); end++ {
+		best[end] = best[end-1] + charBits
+		for _, m := range matches {
+			if m.end == end {
+				best[end] = min(best[end], best[m.start]+m.bits)
+			}
+		}
+	}
+	return best[len(runes)]
+}
+
+// characterPool returns how many characters the classes used by the
+// password hold: 26 lower and 26 upper case letters, 10 digits, 33 ASCII
+// symbols and space, and 100 for anything outside ASCII
+func characterPool(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	return pool
+}
+
+// passwordMatches finds every pattern in the password
+func passwordMatches(runes []rune) []passwordMatch {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	var matches []passwordMatch
+	matches = append(matches, commonMatches(runes, lower)...)
+	matches = append(matches, repeatMatches(runes)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, keyboardMatches(lower)...)
+	matches = append(matches, dateMatches(string(runes))...)
+	return matches
+}
+
+// commonMatches finds the bundled common passwords: the whole password,
+// or parts of at least minCommonSubstring characters. Upper case letters
+// and leet substitutions add a bit each.
+func commonMatches(runes, lower []rune) []passwordMatch {
+	var matches []passwordMatch
+	for start := range lower {
+		for end := start + 1; end <= min(len(lower), start+maxCommonLength); end++ {
+			whole := start == 0 && end == len(lower)
+			if !whole && end-start < minCommonSubstring {
+				continue
+			}
+			word := string(lower[start:end])
+			rank, ok := commonPasswords[word]
+			leet := false
+			if !ok {
+				rank, ok = commonPasswords[unleet(word)]
+				leet = ok
+			}
+			if !ok {
+				continue
+			}
+			m := passwordMatch{kind: FindingContainsCommon, start: start, end: end, bits: math.Log2(float64(rank)),
+				message: "Contains a common password"}
+			if whole {
+				m.kind, m.message = FindingCommonPassword, "Appears in a list of the most common passwords"
+			}
+			if slices.ContainsFunc(runes[start:end], unicode.IsUpper) {
+				m.bits++
+			}
+			if leet {
+				m.bits++
+			}
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+func unleet(word string) string {
+	return strings.Map(func(r rune) rune {
+		if letter, ok := leetSubstitutions[r]; ok {
+			return letter
+		}
+		return r
+	}, word)
+}
+
+// repeatMatches finds runs of three or more of the same character
+func repeatMatches(runes []rune) []passwordMatch {
+	var matches []passwordMatch
+	charBits := math.Log2(float64(characterPool(runes)))
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && runes[end] == runes[start] {
+			end++
+		}
+		if n := end - start; n >= 3 {
+			matches = append(matches, passwordMatch{kind: FindingRepeated, start: start, end: end,
+				bits: charBits + math.Log2(float64(n)), message: fmt.Sprintf("%d repeated characters", n)})
+		}
+		start = end
+	}
+	return matches
+}
+
+// sequenceMatches finds runs of three or more letters or digits counting
+// up or down by one, such as abc or 4321
+func sequenceMatches(runes []rune) []passwordMatch {
+	var matches []passwordMatch
+	for start := 0; start < len(runes)-2; {
+		size := sequenceClass(runes[start])
+		delta := runes[start+1] - runes[start]
+		end := start + 1
+		for end < len(runes) && runes[end]-runes[end-1] == delta && sequenceClass(runes[end]) == size {
+			end++
+		}
+		if n := end - start; size > 0 && (delta == 1 || delta == -1) && n >= 3 {
+			bits := math.Log2(float64(size)) + math.Log2(float64(n))
+			if delta < 0 {
+				bits++
+			}
+			matches = append(matches, passwordMatch{kind: FindingSequence, start: start, end: end,
+				bits: bits, message: fmt.Sprintf("Sequence of %d characters", n)})
+			start = end - 1
+			continue
+		}
+		start++
+	}
+	return matches
+}
+
+// sequenceClass returns the size of the alphabet r counts in, or 0
+func sequenceClass(r rune) int {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return 26
+	case r >= '0' && r <= '9':
+		return 10
+	}
+	return 0
+}
+
+// keyboardMatches finds the longest runs of at least minKeyboardRun
+// adjacent keys, in either direction
+func keyboardMatches(lower []rune) []passwordMatch {
+	var matches []passwordMatch
+	reachedEnd := 0
+	for start := range lower {
+		end := start
+		for next := start + minKeyboardRun; next <= len(lower); next++ {
+			if !onKeyboardRow(string(lower[start:next])) {
+				break
+			}
+			end = next
+		}
+		if end == start || end <= reachedEnd {
+			continue
+		}
+		n := end - start
+		matches = append(matches, passwordMatch{kind: FindingKeyboardSequence, start: start, end: end,
+			bits: math.Log2(47) + math.Log2(float64(n)), message: fmt.Sprintf("%d adjacent keyboard keys", n)})
+		reachedEnd = end
+	}
+	return matches
+}
+
+func onKeyboardRow(run string) bool {
+	reversed := []rune(run)
+	slices.Reverse(reversed)
+	for _, row := range keyboardRows {
+		if strings.Contains(row, run) || strings.Contains(row, string(reversed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// dateMatches finds dates written with separators and runs of digits
+// reading as a year from 1900 to 2049 or as a date with such a year or a
+// two-digit one
+func dateMatches(password string) []passwordMatch {
+	var matches []passwordMatch
+	add := func(loc []int, bits float64, message string) {
+		matches = append(matches, passwordMatch{kind: FindingDate,
+			start: utf8.RuneCountInString(password[:loc[0]]), end: utf8.RuneCountInString(password[:loc[1]]),
+			bits: bits, message: message})
+	}
+	for _, loc := range datePattern.FindAllStringIndex(password, -1) {
+		if isDate(strings.FieldsFunc(password[loc[0]:loc[1]], func(r rune) bool { return r == '-' || r == '/' || r == '.' })) {
+			add(loc, dateBits+1, "Looks like a date")
+		}
+	}
+	for _, loc := range digitRunPattern.FindAllStringIndex(password, -1) {
+		digits := password[loc[0]:loc[1]]
+		switch {
+		case len(digits) == 4 && isYear(digits):
+			add(loc, math.Log2(yearSpan), "Looks like a year")
+		case (len(digits) == 6 || len(digits) == 8) && isDigitDate(digits):
+			add(loc, dateBits, "Looks like a date")
+		}
+	}
+	return matches
+}
+
+// yearSpan is the number of years isYear accepts; dateBits is the
+// entropy of a day in one of them
+const yearSpan = 150
+
+var dateBits = math.Log2(yearSpan * 366)
+
+func isYear(digits string) bool {
+	year, err := strconv.Atoi(digits)
+	return err == nil && year >= 1900 && year < 1900+yearSpan
+}
+
+// isDate reports whether the parts are a year, month and day, or a day
+// and month in either order followed by a year
+func isDate(parts []string) bool {
+	if len(parts) != 3 {
+		return false
+	}
+	if len(parts[0]) == 4 {
+		return isYear(parts[0]) && isMonthDay(parts[1], parts[2])
+	}
+	if len(parts[2]) == 4 && !isYear(parts[2]) {
+		return false
+	}
+	return isMonthDay(parts[0], parts[1]) || isMonthDay(parts[1], parts[0])
+}
+
+// isDigitDate reads 6 or 8 digits as YYYYMMDD, DDMMYYYY, MMDDYYYY or
+// their two-digit year forms
+func isDigitDate(digits string) bool {
+	yearLen := len(digits) - 4
+	if yearLen == 4 && isYear(digits[:4]) && isMonthDay(digits[4:6], digits[6:]) {
+		return true
+	}
+	if yearLen == 2 && isMonthDay(digits[2:4], digits[4:]) {
+		return true
+	}
+	return isDate([]string{digits[:2], digits[2:4], digits[4:]})
+}
+
+func isMonthDay(month, day string) bool {
+	m, err1 := strconv.Atoi(month)
+	d, err2 := strconv.Atoi(day)
+	return err1 == nil && err2 == nil && m >= 1 && m <= 12 && d >= 1 && d <= 31
+}
+
+// reportedMatches orders the matches by position and leaves out common
+// passwords found inside a longer common password
+func reportedMatches(matches []passwordMatch) []passwordMatch {
+	var reported []passwordMatch
+	for i, m := range matches {
+		contained := m.kind == FindingContainsCommon && slices.ContainsFunc(matches, func(other passwordMatch) bool {
+			return (other.kind == FindingContainsCommon || other.kind == FindingCommonPassword) &&
+				other.start <= m.start && m.end <= other.end && other.end-other.start > m.end-m.start
+		})
+		if !contained && !slices.ContainsFunc(matches[:i], func(other passwordMatch) bool {
+			return other.kind == m.kind && other.start == m.start && other.end == m.end
+		}) {
+			reported = append(reported, m)
+		}
+	}
+	slices.SortStableFunc(reported, func(a, b passwordMatch) int { return a.start - b.start })
+	return reported
+}
+
+// crackTime is the time to try every guess at perSecond
+func crackTime(guessesLog10, perSecond float64) models.PasswordCrackTime {
+	seconds := math.Pow(10, min(guessesLog10, maxGuessesLog10)) / perSecond
+	return models.PasswordCrackTime{Seconds: seconds, Display: crackTimeDisplay(seconds)}
+}
+
+func crackTimeDisplay(seconds float64) string {
+	const year = 365.2425 * 24 * 3600
+	if seconds < 1 {
+		return "less than a second"
+	}
+	if seconds >= 100*year {
+		return "centuries"
+	}
+	for _, unit := range []struct {
+		name    string
+		seconds float64
+	}{{"year", year}, {"month", year / 12}, {"day", 24 * 3600}, {"hour", 3600}, {"minute", 60}, {"second", 1}} {
+		if seconds >= unit.seconds {
+			n := int(math.Round(seconds / unit.seconds))
+			if n == 1 {
+				return "1 " + unit.name
+			}
+			return strconv.Itoa(n) + " " + unit.name + "s"
+		}
+	}
+	return "less than a second"
+}
+
+// passwordClasses are the characters of each class; symbols leaves out
+// characters that need quoting in shells, as the token generator does
+var passwordClasses = []struct {
+	name  string
+	chars string
+}{
+	{ClassLowercase, "abcdefghijklmnopqrstuvwxyz"},
+	{ClassUppercase, "ABCDEFGHIJKLMNOPQRSTUVWXYZ"},
+	{ClassDigits, "0123456789"},
+	{ClassSymbols, "!#%*+-=?@^_~"},
+}
+
+// ambiguousChars are the look-alikes exclude_ambiguous leaves out
+const ambiguousChars = "0O1lI"
+
+// GeneratePasswords draws passwords from the enabled classes with
+// crypto/rand. Every password contains each enabled class: passwords
+// missing one are rejected and drawn again, which keeps them uniform over
+// the passwords that have every class. EntropyBits is log2 of how many
+// such passwords there are.
+func GeneratePasswords(req models.PasswordGenerateRequest) (*models.PasswordGenerateResult, error) {
+	length, count := req.Length, req.Count
+	if length == 0 {
+		length = DefaultGeneratedLength
+	}
+	if count == 0 {
+		count = 1
+	}
+	if length < MinGeneratedLength || length > MaxGeneratedLength {
+		return nil, fmt.Errorf("%w: length must be between %d and %d", ErrInvalidPasswordOptions, MinGeneratedLength, MaxGeneratedLength)
+	}
+	if count < 1 || count > MaxGeneratedCount {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidPasswordOptions, MaxGeneratedCount)
+	}
+
+	enabled := map[string]*bool{
+		ClassLowercase: req.Lowercase,
+		ClassUppercase: req.Uppercase,
+		ClassDigits:    req.Digits,
+		ClassSymbols:   req.Symbols,
+	}
+	var names, charsets []string
+	for _, class := range passwordClasses {
+		if on := enabled[class.name]; on != nil && !*on {
+			continue
+		}
+		chars := class.chars
+		if req.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguousChars, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		names = append(names, class.name)
+		charsets = append(charsets, chars)
+	}
+	if len(charsets) == 0 {
+		return nil, fmt.Errorf("%w: enable at least one of %s, %s, %s and %s", ErrInvalidPasswordOptions,
+			ClassLowercase, ClassUppercase, ClassDigits, ClassSymbols)
+	}
+
+	pool := strings.Join(charsets, "")
+	passwords := make([]string, count)
+	for i := range passwords {
+		password, err := generatePassword(pool, charsets, length)
+		if err != nil {
+			return nil, err
+		}
+		passwords[i] = password
+	}
+	sizes := make([]int, len(charsets))
+	for i, chars := range charsets {
+		sizes[i] = len(chars)
+	}
+	return &models.PasswordGenerateResult{
+		Passwords:   passwords,
+		Length:      length,
+		Classes:     names,
+		EntropyBits: math.Round(passwordSpaceBits(sizes, length)*100) / 100,
+	}, nil
+}
+
+// generatePassword draws length characters uniformly from pool until a
+// password contains a character of every charset
+func generatePassword(pool string, charsets []string, length int) (string, error) {
+	password := make([]byte, length)
+	for range generateAttempts {
+		for i := range password {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(pool))))
+			if err != nil {
+				return "", fmt.Errorf("failed to read random source: %w", err)
+			}
+			password[i] = pool[n.Int64()]
+		}
+		if !slices.ContainsFunc(charsets, func(chars string) bool { return !strings.ContainsAny(string(password), chars) }) {
+			return string(password), nil
+		}
+	}
+	return "", errors.New("failed to draw a password containing every class")
+}
+
+// passwordSpaceBits returns log2 of the number of passwords of length
+// that use every class, the classes having sizes characters: by
+// inclusion-exclusion, the passwords over all classes less those over
+// each subset of them
+func passwordSpaceBits(sizes []int, length int) float64 {
+	total := new(big.Int)
+	exponent := big.NewInt(int64(length))
+	for left := 0; left < 1<<len(sizes); left++ {
+		pool, excluded := 0, 0
+		for i, size := range sizes {
+			if left&(1<<i) != 0 {
+				excluded++
+			} else {
+				pool += size
+			}
+		}
+		term := new(big.Int).Exp(big.NewInt(int64(pool)), exponent, nil)
+		if excluded%2 == 1 {
+			total.Sub(total, term)
+		} else {
+			total.Add(total, term)
+		}
+	}
+	mantissa := new(big.Float)
+	exp := new(big.Float).SetInt(total).MantExp(mantissa)
+	m, _ := mantissa.Float64()
+	return float64(exp) + math.Log2(m)
+}
@@ -0,0 +1,269 @@
+package tools
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func checkPassword(t *testing.T, password string) *models.PasswordCheckResult {
+	t.Helper()
+	result, err := CheckPassword(models.PasswordCheckRequest{Password: password})
+	if err != nil {
+		t.Fatalf("%q: %v", password, err)
+	}
+	return result
+}
+
+func findings(result *models.PasswordCheckResult) map[string]models.PasswordFinding {
+	found := map[string]models.PasswordFinding{}
+	for _, f := range result.Findings {
+		if _, ok := found[f.Type]; !ok {
+			found[f.Type] = f
+		}
+	}
+	return found
+}
+
+func TestPasswordEntropy(t *testing.T) {
+	tests := []struct {
+		password string
+		bits     float64
+	}{
+		// Characters without patterns carry log2 of the pool of the
+		// classes used
+		{"kx9Qm2Lp", 8 * math.Log2(62)},
+		{"qmzk", 4 * math.Log2(26)},
+		{"k#9!", 4 * math.Log2(26+10+33)},
+		{"žluť", 4 * math.Log2(26+100)},
+		// A run of n characters costs one character and log2(n)
+		{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", math.Log2(26) + math.Log2(36)},
+		// An ascending sequence of n letters costs log2(26) + log2(n),
+		// a descending one a bit more
+		{"lmnopqrstuvwxyz", math.Log2(26) + math.Log2(15)},
+		{"zyxwvutsrqponml", math.Log2(26) + math.Log2(15) + 1},
+		// Patterns and characters add up
+		{"qmzk9876543", 4*math.Log2(36) + math.Log2(10) + math.Log2(7) + 1},
+	}
+	for _, tt := range tests {
+		result := checkPassword(t, tt.password)
+		if want := math.Round(tt.bits*100) / 100; result.EntropyBits != want {
+			t.Errorf("%q: %v bits, want %v", tt.password, result.EntropyBits, want)
+		}
+		if want := math.Round(tt.bits*math.Log10(2)*100) / 100; math.Abs(result.GuessesLog10-want) > 0.011 {
+			t.Errorf("%q: guessesLog10 %v, want %v", tt.password, result.GuessesLog10, want)
+		}
+	}
+}
+
+func TestPasswordCommon(t *testing.T) {
+	for _, password := range []string{"password", "123456", "Qwerty", "P@ssw0rd", "iloveyou", "dragon"} {
+		result := checkPassword(t, password)
+		f, ok := findings(result)[FindingCommonPassword]
+		if !result.Common || result.Score != 0 || result.Strength != "very_weak" || !ok || f.Start != 0 || f.End != len(password) {
+			t.Errorf("%q: common %v, score %d %s, findings %+v", password, result.Common, result.Score, result.Strength, result.Findings)
+		}
+		// Log2 of the rank, and a bit for each of upper case and leet
+		if result.EntropyBits > math.Log2(float64(len(commonPasswords)))+2 {
+			t.Errorf("%q: %v bits for a listed password", password, result.EntropyBits)
+		}
+	}
+
+	result := checkPassword(t, "xq!Vbaseball")
+	f, ok := findings(result)[FindingContainsCommon]
+	if result.Common || !ok || f.Start != 4 || f.End != 12 {
+		t.Errorf("contained: common %v, findings %+v", result.Common, result.Findings)
+	}
+	if result.EntropyBits >= 12*math.Log2(26+26+33) {
+		t.Errorf("contained: %v bits ignore the common part", result.EntropyBits)
+	}
+	// football is listed, and so is ball inside it: only football counts
+	for _, f := range checkPassword(t, "Z#football").Findings {
+		if f.Type == FindingContainsCommon && f.Start != 2 {
+			t.Errorf("nested common password reported: %+v", f)
+		}
+	}
+}
+
+func TestPasswordFindings(t *testing.T) {
+	tests := []struct {
+		password   string
+		kind       string
+		start, end int
+	}{
+		{"Zq!aaaa", FindingRepeated, 3, 7},
+		{"Zq!defgh", FindingSequence, 3, 8},
+		{"Zq!87654", FindingSequence, 3, 8},
+		{"Zq!asdfgh", FindingKeyboardSequence, 3, 9},
+		{"Zq!;lkjh", FindingKeyboardSequence, 3, 8},
+		{"Zq!1987", FindingDate, 3, 7},
+		{"Zq!04121987", FindingDate, 3, 11},
+		{"Zq!19870412", FindingDate, 3, 11},
+		{"Zq!12/04/87", FindingDate, 3, 11},
+		{"Zq!1987-04-12", FindingDate, 3, 13},
+		{"žZq!1987", FindingDate, 4, 8},
+		{"Zq!a", FindingTooShort, 0, 4},
+	}
+	for _, tt := range tests {
+		f, ok := findings(checkPassword(t, tt.password))[tt.kind]
+		if !ok || f.Start != tt.start || f.End != tt.end || f.Message == "" {
+			t.Errorf("%q: %s = %+v, want [%d, %d)", tt.password, tt.kind, f, tt.start, tt.end)
+		}
+	}
+
+	for _, password := range []string{"Zq!2187x", "Zq!13131313", "Zq!ace"} {
+		if f, ok := findings(checkPassword(t, password))[FindingDate]; ok {
+			t.Errorf("%q: date %+v", password, f)
+		}
+	}
+	// A finding never repeats the password
+	result := checkPassword(t, "Zq!1987-04-12aaaa")
+	for _, f := range result.Findings {
+		if strings.Contains(f.Message, "1987") || strings.Contains(f.Message, "aaa") {
+			t.Errorf("finding repeats the password: %+v", f)
+		}
+	}
+}
+
+func TestPasswordScoreAndCrackTimes(t *testing.T) {
+	weak := checkPassword(t, "qmzk")
+	strong := checkPassword(t, "Gh7#pLq2!vZx9@Wm")
+	if weak.Score != 1 || weak.Strength != "weak" || strong.Score != 4 || strong.Strength != "very_strong" {
+		t.Errorf("scores %d %s and %d %s", weak.Score, weak.Strength, strong.Score, strong.Strength)
+	}
+	// 26^4 guesses
+	times := weak.CrackTimes
+	if math.Abs(times.OnlineUnthrottled.Seconds-math.Pow(26, 4)/10) > 1 || times.OnlineUnthrottled.Display != "13 hours" ||
+		times.OnlineThrottled.Display != "6 months" || times.OfflineSlowHash.Display != "46 seconds" ||
+		times.OfflineFastHash.Display != "less than a second" {
+		t.Errorf("crack times %+v", times)
+	}
+	if strong.CrackTimes.OfflineFastHash.Display != "centuries" {
+		t.Errorf("strong offline crack time %+v", strong.CrackTimes.OfflineFastHash)
+	}
+	long := checkPassword(t, strings.Repeat("Gh7#pLq2!vZx9@Wm", 16))
+	if math.IsInf(long.CrackTimes.OfflineFastHash.Seconds, 0) {
+		t.Errorf("crack time overflows: %+v", long.CrackTimes)
+	}
+}
+
+func TestPasswordCheckErrors(t *testing.T) {
+	if _, err := CheckPassword(models.PasswordCheckRequest{}); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("empty: %v", err)
+	}
+	long := strings.Repeat("x", MaxPasswordBytes+1)
+	if _, err := CheckPassword(models.PasswordCheckRequest{Password: long}); !errors.Is(err, ErrPasswordTooLong) {
+		t.Errorf("too long: %v", err)
+	} else if strings.Contains(err.Error(), "xxx") {
+		t.Errorf("error repeats the password: %v", err)
+	}
+}
+
+func TestGeneratePasswordsClasses(t *testing.T) {
+	off := false
+	tests := []struct {
+		name string
+		req  models.PasswordGenerateRequest
+		want []string
+	}{
+		{"defaults", models.PasswordGenerateRequest{Count: MaxGeneratedCount},
+			[]string{"abcdefghijklmnopqrstuvwxyz", "ABCDEFGHIJKLMNOPQRSTUVWXYZ", "0123456789", "!#%*+-=?@^_~"}},
+		{"shortest with every class", models.PasswordGenerateRequest{Length: MinGeneratedLength, Count: MaxGeneratedCount, ExcludeAmbiguous: true},
+			[]string{"abcdefghijkmnopqrstuvwxyz", "ABCDEFGHJKLMNPQRSTUVWXYZ", "23456789", "!#%*+-=?@^_~"}},
+		{"letters and digits", models.PasswordGenerateRequest{Length: 12, Count: MaxGeneratedCount, Symbols: &off, Uppercase: &off},
+			[]string{"abcdefghijklmnopqrstuvwxyz", "0123456789"}},
+	}
+	for _, tt := range tests {
+		result, err := GeneratePasswords(tt.req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		allowed := strings.Join(tt.want, "")
+		if len(result.Passwords) != MaxGeneratedCount || len(result.Classes) != len(tt.want) {
+			t.Fatalf("%s: %d passwords, classes %v", tt.name, len(result.Passwords), result.Classes)
+		}
+		for _, password := range result.Passwords {
+			if len(password) != result.Length {
+				t.Errorf("%s: %q is not %d long", tt.name, password, result.Length)
+			}
+			if strings.Trim(password, allowed) != "" {
+				t.Errorf("%s: %q has characters outside %q", tt.name, password, allowed)
+			}
+			for _, chars := range tt.want {
+				if !strings.ContainsAny(password, chars) {
+					t.Errorf("%s: %q lacks a character of %q", tt.name, password, chars)
+				}
+			}
+		}
+	}
+}
+
+// TestGeneratePasswordsUniform draws 6400 characters from 74 and requires
+// every one to appear, none more than twice as often as expected
+func TestGeneratePasswordsUniform(t *testing.T) {
+	result, err := GeneratePasswords(models.PasswordGenerateRequest{Length: MaxGeneratedLength, Count: MaxGeneratedCount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[rune]int{}
+	for _, password := range result.Passwords {
+		for _, r := range password {
+			counts[r]++
+		}
+	}
+	pool := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!#%*+-=?@^_~"
+	expected := float64(MaxGeneratedLength*MaxGeneratedCount) / float64(len(pool))
+	for _, r := range pool {
+		if counts[r] == 0 || float64(counts[r]) > 2*expected {
+			t.Errorf("%q drawn %d times, expected about %.0f", r, counts[r], expected)
+		}
+	}
+	seen := map[string]bool{}
+	for _, password := range result.Passwords {
+		if seen[password] {
+			t.Errorf("%q drawn twice", password)
+		}
+		seen[password] = true
+	}
+}
+
+func TestPasswordSpaceBits(t *testing.T) {
+	tests := []struct {
+		sizes  []int
+		length int
+		want   float64
+	}{
+		{[]int{26}, 10, 10 * math.Log2(26)},
+		// 2^3 strings of two classes of one character, less aaa and bbb
+		{[]int{1, 1}, 3, math.Log2(6)},
+		// 36^8 less 26^8 without digits and 10^8 without letters
+		{[]int{26, 10}, 8, math.Log2(math.Pow(36, 8) - math.Pow(26, 8) - math.Pow(10, 8))},
+	}
+	for _, tt := range tests {
+		if got := passwordSpaceBits(tt.sizes, tt.length); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%v x %d: %v bits, want %v", tt.sizes, tt.length, got, tt.want)
+		}
+	}
+	result, _ := GeneratePasswords(models.PasswordGenerateRequest{Length: 16})
+	if all := 16 * math.Log2(74); result.EntropyBits >= all || result.EntropyBits < all-1 {
+		t.Errorf("entropy %v, want just under %v", result.EntropyBits, all)
+	}
+}
+
+func TestGeneratePasswordsErrors(t *testing.T) {
+	off := false
+	for _, req := range []models.PasswordGenerateRequest{
+		{Length: MinGeneratedLength - 1},
+		{Length: MaxGeneratedLength + 1},
+		{Count: -1},
+		{Count: MaxGeneratedCount + 1},
+		{Lowercase: &off, Uppercase: &off, Digits: &off, Symbols: &off},
+	} {
+		if _, err := GeneratePasswords(req); !errors.Is(err, ErrInvalidPasswordOptions) {
+			t.Errorf("%+v: %v", req, err)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+// Package tools holds developer utilities: testing regular expressions
+// against sample input, and checking and generating passwords.
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// Modes of a regex test request
+const (
+	RegexMatch   = "match"
+	RegexFindAll = "find_all"
+	RegexReplace = "replace"
+	RegexSplit   = "split"
+)
+
+const (
+	// MaxRegexPatternBytes is the longest pattern one request compiles
+	MaxRegexPatternBytes = 1024
+	// MaxRegexInputBytes is the largest input one request matches
+	MaxRegexInputBytes = 100 * 1024
+	// MaxRegexReplacementBytes is the longest replacement template
+	MaxRegexReplacementBytes = 256
+	// MaxRegexOutputBytes is the largest replace result returned; replace
+	// stops writing once its output would exceed it
+	MaxRegexOutputBytes = 1024 * 1024
+	// MaxRegexMatches is the most matches find_all returns
+	MaxRegexMatches = 1000
+	// RegexTimeout bounds the matching of one request
+	RegexTimeout = 2 * time.Second
+)
+
+var (
+	ErrRegexTooLarge       = errors.New("regex input too large")
+	ErrInvalidRegex        = errors.New("invalid regex test")
+	ErrInvalidPattern      = errors.New("invalid pattern")
+	ErrRegexTimeout        = fmt.Errorf("matching did not finish within %v", RegexTimeout)
+	ErrRegexOutputTooLarge = fmt.Errorf("replace result exceeds maximum size of %d KB", MaxRegexOutputBytes/1024)
+)
+
+// regexModes lists every mode, in the order of the error message
+var regexModes = []string{RegexMatch, RegexFindAll, RegexReplace, RegexSplit}
+
+// PatternError is a pattern RE2 does not compile. Position is the byte
+// offset in the pattern where parsing failed.
+type PatternError struct {
+	Message  string
+	Position int
+}
+
+func (e *PatternError) Error() string {
+	return e.Message
+}
+
+func (e *PatternError) Unwrap() error {
+	return ErrInvalidPattern
+}
+
+// TestRegex compiles the pattern of req and applies its mode to the input.
+// Mode defaults to find_all. RE2 matches in time linear in the input, so
+// the size limits bound the work; the matching still runs in a goroutine
+// that is abandoned after RegexTimeout, or when ctx is done, so a large
+// pattern over a large input cannot hold the request.
+func TestRegex(ctx context.Context, req models.RegexTestRequest) (*models.RegexTestResult, error) {
+	if len(req.Input) > MaxRegexInputBytes {
+		return nil, fmt.Errorf("%w: input exceeds maximum size of %d KB", ErrRegexTooLarge, MaxRegexInputBytes/1024)
+	}
+	if req.Pattern == "" {
+		return nil, fmt.Errorf("%w: pattern is required", ErrInvalidRegex)
+	}
+	if len(req.Pattern) > MaxRegexPatternBytes {
+		return nil, fmt.Errorf("%w: pattern exceeds maximum size of %d bytes", ErrRegexTooLarge, MaxRegexPatternBytes)
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = RegexFindAll
+	}
+	switch mode {
+	case RegexMatch, RegexFindAll, RegexSplit:
+		if req.Replacement != "" {
+			return nil, fmt.Errorf("%w: replacement is only used by %s", ErrInvalidRegex, RegexReplace)
+		}
+	case RegexReplace:
+		if len(req.Replacement) > MaxRegexReplacementBytes {
+			return nil, fmt.Errorf("%w: replacement exceeds maximum size of %d bytes", ErrRegexTooLarge, MaxRegexReplacementBytes)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %q; supported: %s", ErrInvalidRegex, mode, strings.Join(regexModes, ", "))
+	}
+	re, err := compileRegex(req.Pattern, req.Flags)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, RegexTimeout)
+	defer cancel()
+	type outcome struct {
+		result *models.RegexTestResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := runRegex(ctx, re, mode, req)
+		done <- outcome{result, err}
+	}()
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ErrRegexTimeout
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// compileRegex checks the pattern alone, so error positions are offsets
+// in it, then compiles it behind the (?flags) prefix
+func compileRegex(pattern, flags string) (*regexp.Regexp, error) {
+	for _, flag := range flags {
+		if !strings.ContainsRune("ims", flag) {
+			return nil, fmt.Errorf("%w: unknown flag %q; supported: i, m, s", ErrInvalidRegex, flag)
+		}
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, patternError(pattern, err)
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, &PatternError{Message: err.Error()}
+	}
+	return re, nil
+}
+
+// patternError locates a compile error. syntax.Error names the code and
+// the offending text but not its offset, so the shortest prefix of the
+// pattern failing with the same code is found: its error text ends the
+// prefix, or for an unexpected ) the ) does. A missing ) is reported at
+// the end of the pattern.
+func patternError(pattern string, err error) error {
+	var syntaxErr *syntax.Error
+	if !errors.As(err, &syntaxErr) {
+		return &PatternError{Message: err.Error()}
+	}
+	if syntaxErr.Code == syntax.ErrMissingParen {
+		return &PatternError{Message: syntaxErr.Error(), Position: len(pattern)}
+	}
+	position := max(strings.Index(pattern, syntaxErr.Expr), 0)
+	for end := 1; end <= len(pattern); end++ {
+		_, err := syntax.Parse(pattern[:end], syntax.Perl)
+		var prefixErr *syntax.Error
+		if !errors.As(err, &prefixErr) || prefixErr.Code != syntaxErr.Code || !strings.HasSuffix(pattern[:end], prefixErr.Expr) {
+			continue
+		}
+		position = end - len(prefixErr.Expr)
+		if prefixErr.Code == syntax.ErrUnexpectedParen {
+			position = end - 1
+		}
+		break
+	}
+	return &PatternError{Message: syntaxErr.Error(), Position: position}
+}
+
+// runRegex applies the mode, checking ctx between passes over the input
+func runRegex(ctx context.Context, re *regexp.Regexp, mode string, req models.RegexTestRequest) (*models.RegexTestResult, error) {
+	result := &models.RegexTestResult{Mode: mode}
+	switch mode {
+	case RegexMatch:
+		if loc := re.FindStringSubmatchIndex(req.Input); loc != nil {
+			result.Matches = []models.RegexMatch{regexMatch(re, req.Input, loc)}
+		}
+	case RegexFindAll:
+		locs := re.FindAllStringSubmatchIndex(req.Input, MaxRegexMatches+1)
+		if len(locs) > MaxRegexMatches {
+			locs = locs[:MaxRegexMatches]
+			result.Truncated = true
+		}
+		result.Matches = make([]models.RegexMatch, 0, len(locs))
+		for _, loc := range locs {
+			result.Matches = append(result.Matches, regexMatch(re, req.Input, loc))
+		}
+	case RegexReplace:
+		locs := re.FindAllStringSubmatchIndex(req.Input, -1)
+		result.MatchCount = len(locs)
+		replaced, err := replaceBounded(ctx, re, req.Input, req.Replacement, locs, MaxRegexOutputBytes)
+		if err != nil {
+			return nil, err
+		}
+		result.Result = &replaced
+	case RegexSplit:
+		result.MatchCount = len(re.FindAllStringIndex(req.Input, -1))
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result.Parts = re.Split(req.Input, -1)
+	}
+	if result.Matches != nil {
+		result.MatchCount = len(result.Matches)
+	}
+	result.Matched = result.MatchCount > 0
+	return result, nil
+}
+
+// replaceBounded replaces the matches at locs, as returned by
+// FindAllStringSubmatchIndex, with the expansions of template, like
+// ReplaceAllString. The output is checked against limit as it is written,
+// so a result over it fails without being built. Each piece of the
+// template holds at most one $ reference, which bounds what one
+// expansion adds before it is checked.
+func replaceBounded(ctx context.Context, re *regexp.Regexp, input, template string, locs [][]int, limit int) (string, error) {
+	pieces := templatePieces(template)
+	out := &boundedBuilder{limit: limit}
+	var expanded []byte
+	last := 0
+	for i, loc := range locs {
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+		}
+		if _, err := out.WriteString(input[last:loc[0]]); err != nil {
+			return "", err
+		}
+		for _, piece := range pieces {
+			expanded = re.ExpandString(expanded[:0], piece, input, loc)
+			if _, err := out.Write(expanded); err != nil {
+				return "", err
+			}
+		}
+		last = loc[1]
+	}
+	if _, err := out.WriteString(input[last:]); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// boundedBuilder is a strings.Builder refusing writes that would take it
+// past limit bytes
+type boundedBuilder struct {
+	strings.Builder
+	limit int
+}
+
+func (b *boundedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, ErrRegexOutputTooLarge
+	}
+	return b.Builder.Write(p)
+}
+
+func (b *boundedBuilder) WriteString(s string) (int, error) {
+	if b.Len()+len(s) > b.limit {
+		return 0, ErrRegexOutputTooLarge
+	}
+	return b.Builder.WriteString(s)
+}
+
+// templatePieces splits a replacement template before each $ reference.
+// $$ is an escaped $ and stays within its piece, so each piece expands
+// as it would within the whole template.
+func templatePieces(template string) []string {
+	var pieces []string
+	start := 0
+	for i := 0; i < len(template); i++ {
+		if template[i] != '$' {
+			continue
+		}
+		if i+1 < len(template) && template[i+1] == '$' {
+			i++
+			continue
+		}
+		if i > start {
+			pieces = append(pieces, template[start:i])
+		}
+		start = i
+	}
+	if start < len(template) {
+		pieces = append(pieces, template[start:])
+	}
+	return pieces
+}
+
+// regexMatch describes the match at loc, as returned by
+// FindStringSubmatchIndex. Groups that did not take part in the match
+// have offsets of -1.
+func regexMatch(re *regexp.Regexp, input string, loc []int) models.RegexMatch {
+	names := re.SubexpNames()
+	match := models.RegexMatch{
+		Start:  loc[0],
+		End:    loc[1],
+		Text:   input[loc[0]:loc[1]],
+		Groups: make([]models.RegexGroup, 0, len(names)-1),
+	}
+	for i := 1; i < len(names); i++ {
+		group := models.RegexGroup{Index: i, Name: names[i], Start: loc[2*i], End: loc[2*i+1]}
+		if group.Start >= 0 {
+			group.Matched = true
+			group.Text = input[group.Start:group.End]
+		}
+		match.Groups = append(match.Groups, group)
+	}
+	return match
+}
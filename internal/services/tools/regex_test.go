@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func testRegex(t *testing.T, req models.RegexTestRequest) *models.RegexTestResult {
+	t.Helper()
+	result, err := TestRegex(context.Background(), req)
+	if err != nil {
+		t.Fatalf("%q: %v", req.Pattern, err)
+	}
+	return result
+}
+
+func TestRegexNamedGroups(t *testing.T) {
+	result := testRegex(t, models.RegexTestRequest{
+		Pattern: `(?P<year>\d{4})-(?P<month>\d{2})(-(\d{2}))?`,
+		Input:   "from 2024-03 to 2025-11-30",
+		Mode:    RegexFindAll,
+	})
+	if !result.Matched || result.MatchCount != 2 || len(result.Matches) != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+	first, second := result.Matches[0], result.Matches[1]
+	if first.Start != 5 || first.End != 12 || first.Text != "2024-03" {
+		t.Errorf("first match = %+v", first)
+	}
+	want := []models.RegexGroup{
+		{Index: 1, Name: "year", Matched: true, Start: 5, End: 9, Text: "2024"},
+		{Index: 2, Name: "month", Matched: true, Start: 10, End: 12, Text: "03"},
+		{Index: 3, Start: -1, End: -1},
+		{Index: 4, Start: -1, End: -1},
+	}
+	if !slices.Equal(first.Groups, want) {
+		t.Errorf("groups = %+v, want %+v", first.Groups, want)
+	}
+	if second.Text != "2025-11-30" || second.Groups[3] != (models.RegexGroup{Index: 4, Matched: true, Start: 24, End: 26, Text: "30"}) {
+		t.Errorf("second match = %+v", second)
+	}
+}
+
+func TestRegexUnicode(t *testing.T) {
+	// Offsets are bytes: é and ü take two
+	result := testRegex(t, models.RegexTestRequest{Pattern: `\p{Lu}\p{Ll}+`, Input: "café Über Ωmega", Mode: RegexFindAll})
+	var texts []string
+	for _, m := range result.Matches {
+		texts = append(texts, m.Text)
+	}
+	if !slices.Equal(texts, []string{"Über", "Ωmega"}) || result.Matches[0].Start != 6 || result.Matches[0].End != 11 {
+		t.Errorf("matches = %+v", result.Matches)
+	}
+
+	result = testRegex(t, models.RegexTestRequest{Pattern: `\p{Han}+`, Input: "東京 tower 大阪", Mode: RegexFindAll})
+	if result.MatchCount != 2 || result.Matches[1].Text != "大阪" || result.Matches[1].Start != 13 {
+		t.Errorf("Han matches = %+v", result.Matches)
+	}
+
+	result = testRegex(t, models.RegexTestRequest{Pattern: `straße`, Flags: "i", Input: "STRAßE", Mode: RegexMatch})
+	if !result.Matched {
+		t.Errorf("case-insensitive match of STRAßE failed")
+	}
+}
+
+func TestRegexReplace(t *testing.T) {
+	tests := []struct {
+		pattern, flags, replacement, input, want string
+		count                                    int
+	}{
+		{`(\w+)@(\w+)\.com`, "", "$2 at $1", "ann@example.com, bob@test.com", "example at ann, test at bob", 2},
+		{`(?P<first>\w+) (?P<last>\w+)`, "", "${last}, ${first}", "Ada Lovelace", "Lovelace, Ada", 1},
+		// $1x is the group named 1x, which does not exist
+		{`(\d)`, "", "${1}x$1x", "a1b2", "a1xb2x", 2},
+		{`a`, "", "$$", "banana", "b$n$n$", 3},
+		{`^\s+|\s+$`, "m", "", "  one  \n two ", "one\ntwo", 4},
+		{`x`, "", "y", "abc", "abc", 0},
+	}
+	for _, tt := range tests {
+		result := testRegex(t, models.RegexTestRequest{Pattern: tt.pattern, Flags: tt.flags, Input: tt.input, Mode: RegexReplace, Replacement: tt.replacement})
+		if result.Result == nil || *result.Result != tt.want || result.MatchCount != tt.count || result.Matches != nil {
+			t.Errorf("replace %q in %q = %+v, want %q (%d matches)", tt.pattern, tt.input, result, tt.want, tt.count)
+		}
+	}
+}
+
+// replaceBounded gives the output of ReplaceAllString, and fails as soon
+// as it would write past the limit
+func TestReplaceBounded(t *testing.T) {
+	tests := []struct{ pattern, template, input string }{
+		{`(\w+)@(\w+)\.com`, "$2 at $1", "ann@example.com, bob@test.com"},
+		{`(?P<first>\w+) (?P<last>\w+)`, "${last}, ${first}", "Ada Lovelace and Alan Turing"},
+		{`(\d)`, "${1}x$1x$", "a1b2"},
+		{`a`, "$$1$$", "banana"},
+		{`y*`, "-", "xyyx"},
+		{`\b`, "|", "one two"},
+		{`(?m)^`, "> ", "one\ntwo\n"},
+		{`(a)|(b)`, "[$1$2]", "abcab"},
+		{`x`, "y", "abc"},
+		{`é`, "$0$0", "café crème"},
+	}
+	for _, tt := range tests {
+		re := regexp.MustCompile(tt.pattern)
+		want := re.ReplaceAllString(tt.input, tt.template)
+		locs := re.FindAllStringSubmatchIndex(tt.input, -1)
+		got, err := replaceBounded(context.Background(), re, tt.input, tt.template, locs, len(want))
+		if err != nil || got != want {
+			t.Errorf("%q with %q in %q = %q, %v, want %q", tt.pattern, tt.template, tt.input, got, err, want)
+		}
+		if _, err := replaceBounded(context.Background(), re, tt.input, tt.template, locs, len(want)-1); len(want) > 0 && !errors.Is(err, ErrRegexOutputTooLarge) {
+			t.Errorf("%q with %q in %q one byte over the limit: err = %v", tt.pattern, tt.template, tt.input, err)
+		}
+	}
+
+	// A template expanding a large match many times stops at the limit
+	// instead of building the whole result
+	re := regexp.MustCompile(`.+`)
+	input := strings.Repeat("x", MaxRegexInputBytes)
+	template := strings.Repeat("$0", MaxRegexReplacementBytes/2)
+	if _, err := replaceBounded(context.Background(), re, input, template, re.FindAllStringSubmatchIndex(input, -1), MaxRegexOutputBytes); !errors.Is(err, ErrRegexOutputTooLarge) {
+		t.Errorf("err = %v, want ErrRegexOutputTooLarge", err)
+	}
+}
+
+func TestRegexModes(t *testing.T) {
+	result := testRegex(t, models.RegexTestRequest{Pattern: `\s*,\s*`, Input: "a , b,c", Mode: RegexSplit})
+	if !slices.Equal(result.Parts, []string{"a", "b", "c"}) || result.MatchCount != 2 {
+		t.Errorf("split = %+v", result)
+	}
+
+	result = testRegex(t, models.RegexTestRequest{Pattern: `o`, Input: "foo boo"})
+	if result.Mode != RegexFindAll || result.MatchCount != 4 {
+		t.Errorf("default mode = %+v", result)
+	}
+
+	result = testRegex(t, models.RegexTestRequest{Pattern: `o+`, Input: "foo boo", Mode: RegexMatch})
+	if result.MatchCount != 1 || result.Matches[0].Text != "oo" || result.Matches[0].Start != 1 {
+		t.Errorf("match = %+v", result)
+	}
+
+	result = testRegex(t, models.RegexTestRequest{Pattern: `^b.r$`, Flags: "ms", Input: "foo\nbar\nb\nr"})
+	if result.MatchCount != 2 || result.Matches[1].Text != "b\nr" {
+		t.Errorf("flags ms = %+v", result.Matches)
+	}
+
+	result = testRegex(t, models.RegexTestRequest{Pattern: `x`, Input: strings.Repeat("x", MaxRegexMatches+5)})
+	if !result.Truncated || result.MatchCount != MaxRegexMatches || len(result.Matches) != MaxRegexMatches {
+		t.Errorf("find_all cap: truncated %v, %d matches", result.Truncated, result.MatchCount)
+	}
+
+	result = testRegex(t, models.RegexTestRequest{Pattern: `z`, Input: "abc", Mode: RegexMatch})
+	if result.Matched || result.MatchCount != 0 || result.Matches != nil {
+		t.Errorf("no match = %+v", result)
+	}
+}
+
+func TestRegexPatternErrors(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		message  string
+		position int
+	}{
+		{`ab\q`, "error parsing regexp: invalid escape sequence: `\\q`", 2},
+		{`a(b)c)`, "error parsing regexp: unexpected ): `a(b)c)`", 5},
+		{`x**`, "error parsing regexp: invalid nested repetition operator: `**`", 1},
+		{`(a)(b`, "error parsing regexp: missing closing ): `(a)(b`", 5},
+		{`ok[a-`, "error parsing regexp: missing closing ]: `[a-`", 2},
+		{`a{2,1}`, "error parsing regexp: invalid repeat count: `{2,1}`", 1},
+		{`\d+\q\q`, "error parsing regexp: invalid escape sequence: `\\q`", 3},
+		{`a(?<=a)b`, "error parsing regexp: invalid named capture: `(?<=a)b`", 1},
+	}
+	for _, tt := range tests {
+		_, err := TestRegex(context.Background(), models.RegexTestRequest{Pattern: tt.pattern, Flags: "i", Input: "x"})
+		var patternErr *PatternError
+		if !errors.As(err, &patternErr) || !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("%q: err = %v, want a PatternError", tt.pattern, err)
+			continue
+		}
+		if patternErr.Message != tt.message || patternErr.Position != tt.position {
+			t.Errorf("%q: %q at %d, want %q at %d", tt.pattern, patternErr.Message, patternErr.Position, tt.message, tt.position)
+		}
+	}
+}
+
+func TestRegexRejects(t *testing.T) {
+	tests := []struct {
+		req  models.RegexTestRequest
+		want error
+	}{
+		{models.RegexTestRequest{Input: "x"}, ErrInvalidRegex},
+		{models.RegexTestRequest{Pattern: "x", Flags: "g"}, ErrInvalidRegex},
+		{models.RegexTestRequest{Pattern: "x", Mode: "test"}, ErrInvalidRegex},
+		{models.RegexTestRequest{Pattern: "x", Mode: RegexSplit, Replacement: "y"}, ErrInvalidRegex},
+		{models.RegexTestRequest{Pattern: "x", Input: strings.Repeat("x", MaxRegexInputBytes+1)}, ErrRegexTooLarge},
+		{models.RegexTestRequest{Pattern: strings.Repeat("x", MaxRegexPatternBytes+1)}, ErrRegexTooLarge},
+		{models.RegexTestRequest{Pattern: "x", Mode: RegexReplace, Replacement: strings.Repeat("y", MaxRegexReplacementBytes+1)}, ErrRegexTooLarge},
+		{models.RegexTestRequest{Mode: RegexReplace, Replacement: "y", Input: "x"}, ErrInvalidRegex},
+		// Every empty match in 100 KB of input gains 20 bytes
+		{models.RegexTestRequest{Pattern: "y*", Mode: RegexReplace, Replacement: strings.Repeat("y", 20), Input: strings.Repeat("x", MaxRegexInputBytes)}, ErrRegexOutputTooLarge},
+	}
+	for _, tt := range tests {
+		if _, err := TestRegex(context.Background(), tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("%q %q: err = %v, want %v", tt.req.Pattern, tt.req.Mode, err, tt.want)
+		}
+	}
+}
+
+// A cancelled request stops waiting for the matching
+func TestRegexCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := TestRegex(ctx, models.RegexTestRequest{Pattern: `(\w+\s*)+$`, Input: strings.Repeat("word ", 20000)})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,434 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"golang.org/x/net/html"
+)
+
+const (
+	LinkModeInline = "inline"
+	LinkModeDrop   = "drop"
+
+	ImageModeAlt  = "alt"
+	ImageModeDrop = "drop"
+
+	maxHTMLInputBytes = 2 * 1024 * 1024
+	minWrapWidth      = 20
+	maxWrapWidth      = 1000
+)
+
+var (
+	ErrInputTooLarge = errors.New("input exceeds maximum size of 2 MB")
+	ErrInvalidOption = errors.New("invalid option")
+)
+
+// skippedElements are dropped together with everything inside them. The
+// document is parsed as a browser without scripts would, so an element
+// left open ends where the parser closes it, e.g. <head> at the first body
+// content, rather than swallowing the rest of the input.
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "head": true, "noscript": true,
+	"template": true, "iframe": true, "object": true, "svg": true,
+}
+
+// rawTextElements hold text the parser does not read as markup up to
+// their end tag. When the input never closes one, it would hide the rest
+// of the document, so its text from the first tag on is converted as
+// markup instead.
+var rawTextElements = []string{"script", "style", "iframe"}
+
+// blockElements start and end on their own line
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "div": true, "dl": true,
+	"dd": true, "dt": true, "fieldset": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "header": true, "main": true, "nav": true,
+	"section": true, "table": true, "tr": true, "ul": true, "ol": true, "li": true,
+	"center": true, "caption": true, "summary": true, "details": true,
+}
+
+// paragraphElements are separated from their neighbours by a blank line
+var paragraphElements = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "blockquote": true, "pre": true, "hr": true,
+}
+
+type listState struct {
+	ordered bool
+	count   int
+}
+
+type html2text struct {
+	opts models.HTML2TextOptions
+
+	out            strings.Builder
+	pendingBreak   int
+	trailingBreaks int
+	pendingSpace   bool
+	lineHasText    bool
+
+	preDepth  int
+	lists     []listState
+	cellIndex int
+
+	linkHref string
+	linkText strings.Builder
+	inLink   bool
+
+	// unclosed are the raw text elements without an end tag in the input
+	unclosed map[string]bool
+}
+
+// HTMLToText converts HTML markup into readable plain text
+func HTMLToText(req models.HTML2TextRequest) (models.HTML2TextResult, error) {
+	if len(req.HTML) > maxHTMLInputBytes {
+		return models.HTML2TextResult{}, ErrInputTooLarge
+	}
+	if err := applyHTML2TextDefaults(&req.Options); err != nil {
+		return models.HTML2TextResult{}, err
+	}
+
+	doc, err := html.ParseWithOptions(strings.NewReader(req.HTML), html.ParseOptionEnableScripting(false))
+	if err != nil {
+		return models.HTML2TextResult{}, err
+	}
+	c := &html2text{opts: req.Options, unclosed: map[string]bool{}}
+	lower := strings.ToLower(req.HTML)
+	for _, tag := range rawTextElements {
+		c.unclosed[tag] = !strings.Contains(lower, "</"+tag)
+	}
+	c.walk(doc)
+	c.finishLink()
+
+	text := strings.TrimSpace(c.out.String())
+	if req.Options.WrapWidth > 0 {
+		text = wrapText(text, req.Options.WrapWidth)
+	}
+
+	lines := 0
+	if text != "" {
+		lines = strings.Count(text, "\n") + 1
+	}
+	return models.HTML2TextResult{Text: text, Lines: lines}, nil
+}
+
+func applyHTML2TextDefaults(opts *models.HTML2TextOptions) error {
+	switch opts.Links {
+	case "":
+		opts.Links = LinkModeInline
+	case LinkModeInline, LinkModeDrop:
+	default:
+		return fmt.Errorf("%w: links must be %s or %s", ErrInvalidOption, LinkModeInline, LinkModeDrop)
+	}
+
+	switch opts.Images {
+	case "":
+		opts.Images = ImageModeAlt
+	case ImageModeAlt, ImageModeDrop:
+	default:
+		return fmt.Errorf("%w: images must be %s or %s", ErrInvalidOption, ImageModeAlt, ImageModeDrop)
+	}
+
+	if opts.WrapWidth != 0 && (opts.WrapWidth < minWrapWidth || opts.WrapWidth > maxWrapWidth) {
+		return fmt.Errorf("%w: wrap_width must be 0 or between %d and %d", ErrInvalidOption, minWrapWidth, maxWrapWidth)
+	}
+	return nil
+}
+
+// walk converts n and its descendants, leaving out skipped elements and
+// comments
+func (c *html2text) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		c.text(n.Data)
+		return
+	case html.ElementNode:
+		if c.unclosed[n.Data] && n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+			c.walkMarkup(n.FirstChild.Data)
+			return
+		}
+		if skippedElements[n.Data] {
+			c.skip(n)
+			return
+		}
+		attrs := make(map[string]string, len(n.Attr))
+		for _, attr := range n.Attr {
+			attrs[attr.Key] = attr.Val
+		}
+		c.startTag(n.Data, attrs)
+	case html.CommentNode, html.DoctypeNode:
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.walk(child)
+	}
+	if n.Type == html.ElementNode {
+		c.endTag(n.Data)
+	}
+}
+
+// skip leaves out the descendants of n, except an unclosed raw text
+// element among them
+func (c *html2text) skip(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if c.unclosed[child.Data] && child.FirstChild != nil && child.FirstChild.Type == html.TextNode {
+			c.walkMarkup(child.FirstChild.Data)
+			continue
+		}
+		c.skip(child)
+	}
+}
+
+// walkMarkup converts the text of an unclosed raw text element from its
+// first tag on as the body of a document of its own
+func (c *html2text) walkMarkup(text string) {
+	start := strings.IndexByte(text, '<')
+	if start < 0 {
+		return
+	}
+	doc, err := html.ParseWithOptions(strings.NewReader(text[start:]), html.ParseOptionEnableScripting(false))
+	if err != nil {
+		return
+	}
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == html.ElementNode && n.Data == "html" {
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				if child.Type == html.ElementNode && child.Data == "body" {
+					c.walk(child)
+				}
+			}
+		}
+	}
+}
+
+func (c *html2text) startTag(tag string, attrs map[string]string) {
+	switch {
+	case paragraphElements[tag]:
+		c.breakLine(2)
+	case blockElements[tag]:
+		c.breakLine(1)
+	}
+
+	switch tag {
+	case "br":
+		c.newline()
+	case "hr":
+		c.write("--------")
+		c.breakLine(2)
+	case "pre":
+		c.preDepth++
+	case "ul", "ol":
+		c.lists = append(c.lists, listState{ordered: tag == "ol"})
+	case "li":
+		c.listItem()
+	case "tr":
+		c.cellIndex = 0
+	case "td", "th":
+		if c.cellIndex > 0 {
+			c.write("\t")
+			c.pendingSpace = false
+			c.lineHasText = false
+		}
+		c.cellIndex++
+	case "a":
+		c.finishLink()
+		if href := strings.TrimSpace(attrs["href"]); href != "" && c.opts.Links == LinkModeInline {
+			c.inLink = true
+			c.linkHref = href
+			c.linkText.Reset()
+		}
+	case "img":
+		if alt := strings.TrimSpace(attrs["alt"]); alt != "" && c.opts.Images == ImageModeAlt {
+			c.text("[" + alt + "]")
+		}
+	}
+}
+
+func (c *html2text) endTag(tag string) {
+	switch tag {
+	case "pre":
+		if c.preDepth > 0 {
+			c.preDepth--
+		}
+	case "ul", "ol":
+		if len(c.lists) > 0 {
+			c.lists = c.lists[:len(c.lists)-1]
+		}
+	case "a":
+		c.finishLink()
+	}
+
+	switch {
+	case paragraphElements[tag]:
+		c.breakLine(2)
+	case blockElements[tag]:
+		c.breakLine(1)
+	}
+}
+
+func (c *html2text) listItem() {
+	depth := len(c.lists)
+	if depth == 0 {
+		c.lists = append(c.lists, listState{})
+		depth = 1
+	}
+	list := &c.lists[depth-1]
+	list.count++
+
+	c.write(strings.Repeat("  ", depth-1))
+	if list.ordered {
+		c.write(strconv.Itoa(list.count) + ". ")
+	} else {
+		c.write("* ")
+	}
+	c.pendingSpace = false
+	c.lineHasText = false
+}
+
+// finishLink appends the URL after the link text unless they are identical
+func (c *html2text) finishLink() {
+	if !c.inLink {
+		return
+	}
+	c.inLink = false
+	text := strings.TrimSpace(c.linkText.String())
+	href := c.linkHref
+	if href == text || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return
+	}
+	c.pendingSpace = true
+	if text == "" {
+		c.text(href)
+		return
+	}
+	c.text("(" + href + ")")
+}
+
+func (c *html2text) text(s string) {
+	if c.inLink {
+		c.linkText.WriteString(s)
+	}
+
+	if c.preDepth > 0 {
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			if i > 0 {
+				c.newline()
+			}
+			if line != "" {
+				c.write(line)
+			}
+		}
+		return
+	}
+
+	if s == "" {
+		return
+	}
+	if isHTMLSpace(s[0]) {
+		c.pendingSpace = true
+	}
+	words := strings.Fields(s)
+	for i, word := range words {
+		if i > 0 {
+			c.pendingSpace = true
+		}
+		c.flushBreaks()
+		if c.pendingSpace && c.lineHasText {
+			c.out.WriteByte(' ')
+		}
+		c.write(word)
+		c.pendingSpace = false
+	}
+	if isHTMLSpace(s[len(s)-1]) {
+		c.pendingSpace = true
+	}
+}
+
+func (c *html2text) write(s string) {
+	c.flushBreaks()
+	c.out.WriteString(s)
+	c.trailingBreaks = 0
+	c.lineHasText = true
+}
+
+// flushBreaks emits the pending line breaks, counting the ones already
+// written so that nested block boundaries never stack up blank lines.
+func (c *html2text) flushBreaks() {
+	if c.pendingBreak == 0 {
+		return
+	}
+	if c.out.Len() > 0 {
+		for i := c.trailingBreaks; i < c.pendingBreak; i++ {
+			c.out.WriteByte('\n')
+			c.trailingBreaks++
+		}
+	}
+	c.pendingBreak = 0
+	c.pendingSpace = false
+	c.lineHasText = false
+}
+
+// newline forces a line break even if the current line is empty
+func (c *html2text) newline() {
+	c.flushBreaks()
+	c.out.WriteByte('\n')
+	c.trailingBreaks++
+	c.pendingSpace = false
+	c.lineHasText = false
+}
+
+// breakLine requests that the next text starts after n line breaks
+func (c *html2text) breakLine(n int) {
+	if n > c.pendingBreak {
+		c.pendingBreak = n
+	}
+}
+
+func isHTMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// wrapText wraps lines longer than width characters at word boundaries
+func wrapText(text string, width int) string {
+	lines := strings.Split(text, "\n")
+	var out strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			out.WriteByte('\n')
+		}
+		if utf8.RuneCountInString(line) <= width || strings.Contains(line, "\t") {
+			out.WriteString(line)
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+		lineLen := 0
+		for j, word := range strings.Fields(line) {
+			wordLen := utf8.RuneCountInString(word)
+			switch {
+			case j == 0:
+				out.WriteString(indent)
+				lineLen = len(indent)
+			case lineLen+1+wordLen > width:
+				out.WriteByte('\n')
+				out.WriteString(indent)
+				lineLen = len(indent)
+			default:
+				out.WriteByte(' ')
+				lineLen++
+			}
+			out.WriteString(word)
+			lineLen += wordLen
+		}
+	}
+	return out.String()
+}
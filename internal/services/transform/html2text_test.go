@@ -0,0 +1,192 @@
+package transform
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{"paragraphs and links", `<h1>Hello</h1><p>World <a href="https://x.example">link</a></p>`, "Hello\n\nWorld link (https://x.example)"},
+		{"unordered list", `<ul><li>a<li>b</ul>`, "* a\n* b"},
+		{"nested ordered list", `<ol><li>x</li><li>y<ol><li>z</li></ol></li></ol>`, "1. x\n2. y\n  1. z"},
+		{"table", `<table><tr><td>1<td>2</tr></table>`, "1\t2"},
+		{"images", `<p>one <img alt="pic"> two<br>three</p>`, "one [pic] two\nthree"},
+		{"skipped elements", `<p>a<script>var x = "<b>no</b>";</script><style>p{}</style>b</p>`, "ab"},
+		{"unclosed head", `<html><head><title>T</title><body><p>body text</p>`, "body text"},
+		{"head without body", `<head><title>x</title><p>body text`, "body text"},
+		{"unclosed noscript", `<div>a<noscript>hidden</div><p>after</p>`, "a\n\nafter"},
+		{"unclosed script", `<p>before</p><script>var a; <p>rest`, "before\n\nrest"},
+		{"unclosed script in head", `<script>var a; <p>rest`, "rest"},
+		{"unclosed style", `<style>p { color: red } <p>rest`, "rest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTMLToText(models.HTML2TextRequest{HTML: tt.html})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Text != tt.want {
+				t.Errorf("text = %q, want %q", got.Text, tt.want)
+			}
+		})
+	}
+}
+
+// TestHTMLToTextGolden converts each testdata/<name>.html and compares
+// the text with testdata/golden/<name>.txt
+func TestHTMLToTextGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		opts models.HTML2TextOptions
+	}{
+		{"newsletter", models.HTML2TextOptions{}},
+		{"newsletter-wrapped", models.HTML2TextOptions{WrapWidth: 40}},
+		{"table_page", models.HTML2TextOptions{}},
+		{"malformed", models.HTML2TextOptions{}},
+	}
+	for _, tt := range tests {
+		input, err := os.ReadFile(filepath.Join("testdata", strings.TrimSuffix(tt.name, "-wrapped")+".html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := HTMLToText(models.HTML2TextRequest{HTML: string(input), Options: tt.opts})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		path := filepath.Join("testdata", "golden", tt.name+".txt")
+		if *update {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(got.Text+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%v (run go test -update to create it)", err)
+		}
+		if got.Text+"\n" != string(want) {
+			t.Errorf("%s: text differs from %s:\n%s", tt.name, path, got.Text)
+		}
+		if got.Lines != strings.Count(string(want), "\n") {
+			t.Errorf("%s: lines = %d, want %d", tt.name, got.Lines, strings.Count(string(want), "\n"))
+		}
+	}
+}
+
+func TestHTMLToTextOptions(t *testing.T) {
+	const page = `<p>See <a href="https://example.com/docs">the docs</a> <img src="x.png" alt="diagram"></p>`
+	tests := []struct {
+		name string
+		opts models.HTML2TextOptions
+		want string
+	}{
+		{"defaults", models.HTML2TextOptions{}, "See the docs (https://example.com/docs) [diagram]"},
+		{"drop links", models.HTML2TextOptions{Links: LinkModeDrop}, "See the docs [diagram]"},
+		{"drop images", models.HTML2TextOptions{Images: ImageModeDrop}, "See the docs (https://example.com/docs)"},
+		{"drop both", models.HTML2TextOptions{Links: LinkModeDrop, Images: ImageModeDrop}, "See the docs"},
+	}
+	for _, tt := range tests {
+		got, err := HTMLToText(models.HTML2TextRequest{HTML: page, Options: tt.opts})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got.Text != tt.want {
+			t.Errorf("%s: text = %q, want %q", tt.name, got.Text, tt.want)
+		}
+	}
+
+	for _, opts := range []models.HTML2TextOptions{
+		{Links: "footnote"},
+		{Images: "src"},
+		{WrapWidth: minWrapWidth - 1},
+		{WrapWidth: maxWrapWidth + 1},
+		{WrapWidth: -1},
+	} {
+		if _, err := HTMLToText(models.HTML2TextRequest{HTML: page, Options: opts}); err == nil {
+			t.Errorf("%+v: no error", opts)
+		}
+	}
+}
+
+func TestHTMLToTextWrapWidth(t *testing.T) {
+	const page = `<p>The quick brown fox jumps over the lazy dog and keeps running far away.</p>` +
+		`<ul><li>a list item long enough to be wrapped onto a second line</li></ul>` +
+		`<table><tr><td>a table row is never wrapped even when it is long</td><td>cell</td></tr></table>`
+	got, err := HTMLToText(models.HTML2TextRequest{HTML: page, Options: models.HTML2TextOptions{WrapWidth: 24}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "The quick brown fox\njumps over the lazy dog\nand keeps running far\naway.\n\n" +
+		"* a list item long\nenough to be wrapped\nonto a second line\n" +
+		"a table row is never wrapped even when it is long\tcell"
+	if got.Text != want {
+		t.Errorf("text = %q, want %q", got.Text, want)
+	}
+	if got.Lines != strings.Count(want, "\n")+1 {
+		t.Errorf("lines = %d", got.Lines)
+	}
+}
+
+// TestWrapTextCountsCharacters checks that width is measured in
+// characters, so text outside ASCII is not wrapped early
+func TestWrapTextCountsCharacters(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"äöü äöü äöü äöü äöü", 20, "äöü äöü äöü äöü äöü"},
+		{"日本語の 文章を 折り返す テスト", 20, "日本語の 文章を 折り返す テスト"},
+		{"café crème brûlée déjà vu naïve", 20, "café crème brûlée\ndéjà vu naïve"},
+		{"  Привет мир, как дела сегодня", 20, "  Привет мир, как\n  дела сегодня"},
+	}
+	for _, tt := range tests {
+		got := wrapText(tt.text, tt.width)
+		if got != tt.want {
+			t.Errorf("wrapText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+		for _, line := range strings.Split(got, "\n") {
+			if n := utf8.RuneCountInString(line); n > tt.width && strings.Contains(line, " ") {
+				t.Errorf("%q: line %q is %d characters", tt.text, line, n)
+			}
+		}
+	}
+}
+
+func TestHTMLToTextEntities(t *testing.T) {
+	tests := []struct {
+		html, want string
+	}{
+		{`<p>Fish &amp; chips &lt;b&gt;</p>`, "Fish & chips <b>"},
+		{`<p>&quot;quoted&quot; &#39;single&#39;</p>`, `"quoted" 'single'`},
+		{`<p>caf&eacute; &euro;5 &copy; 2026</p>`, "café €5 © 2026"},
+		{`<p>&#8212; &#x2014; &mdash;</p>`, "— — —"},
+		{`<p>a&nbsp;b</p>`, "a b"},
+		{`<p>AT&T &unknown; &amp</p>`, "AT&T &unknown; &"},
+		{`<a href="https://example.com/?a=1&amp;b=2">q</a>`, "q (https://example.com/?a=1&b=2)"},
+		{`<img alt="&lt;logo&gt;">`, "[<logo>]"},
+	}
+	for _, tt := range tests {
+		got, err := HTMLToText(models.HTML2TextRequest{HTML: tt.html})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Text != tt.want {
+			t.Errorf("%s: text = %q, want %q", tt.html, got.Text, tt.want)
+		}
+	}
+}
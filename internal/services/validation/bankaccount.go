@@ -0,0 +1,364 @@
+package validation
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// Bank account schemes reported in BankAccountValidation.Scheme
+const (
+	BankSchemeABA      = "aba-routing"
+	BankSchemeSortCode = "sort-code"
+	BankSchemeTransit  = "transit-number"
+)
+
+const (
+	bankCountryAliasUK  = "UK"
+	bankCountryCodeUK   = "GB"
+	minUSAccountLength  = 4
+	maxUSAccountLength  = 17
+	minCAAccountLength  = 7
+	maxCAAccountLength  = 12
+	ukAccountLength     = 8
+	minUKAccountLength  = 6
+	ukSortCodeLength    = 6
+	abaRoutingLength    = 9
+	caTransitLength     = 5
+	caInstitutionLength = 3
+)
+
+// bankAccountScheme validates the bank code and account number of one
+// country. Both arrive without spaces and separators; validate fills in
+// everything but the country and the overall verdict.
+type bankAccountScheme struct {
+	name     string
+	validate func(result *models.BankAccountValidation, bankCode, account string)
+}
+
+// bankAccountSchemes is the per-country registry; a country is supported
+// by adding its scheme here
+var bankAccountSchemes = map[string]bankAccountScheme{
+	"US": {name: BankSchemeABA, validate: validateUSBankAccount},
+	"GB": {name: BankSchemeSortCode, validate: validateUKBankAccount},
+	"CA": {name: BankSchemeTransit, validate: validateCABankAccount},
+}
+
+// BankAccountCountries returns the supported country codes in order
+func BankAccountCountries() []string {
+	countries := make([]string, 0, len(bankAccountSchemes))
+	for country := range bankAccountSchemes {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+	return countries
+}
+
+// NormalizeBankInput removes the spaces, dashes, dots and slashes people
+// write bank codes and account numbers with
+func NormalizeBankInput(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '-', '.', '/':
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// ValidateBankAccount checks a domestic bank account with the scheme of
+// req.Country. "UK" is accepted for GB.
+func ValidateBankAccount(req models.BankAccountRequest) models.BankAccountValidation {
+	country := strings.ToUpper(strings.TrimSpace(req.Country))
+	if country == bankCountryAliasUK {
+		country = bankCountryCodeUK
+	}
+	result := models.BankAccountValidation{
+		Country:       country,
+		BankCode:      NormalizeBankInput(req.BankCode),
+		AccountNumber: NormalizeBankInput(req.AccountNumber),
+	}
+
+	scheme, ok := bankAccountSchemes[country]
+	if !ok {
+		return result
+	}
+	result.IsCountrySupported = true
+	result.Scheme = scheme.name
+	scheme.validate(&result, result.BankCode, result.AccountNumber)
+
+	result.IsValid = result.IsBankCodeFormatValid && result.IsAccountNumberFormatValid &&
+		(result.IsBankCodeChecksumValid == nil || *result.IsBankCodeChecksumValid) &&
+		(result.IsAccountChecksumValid == nil || *result.IsAccountChecksumValid)
+	return result
+}
+
+func isAllDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func digitsOfLength(s string, min, max int) bool {
+	return len(s) >= min && len(s) <= max && isAllDigits(s)
+}
+
+// US: ABA routing numbers
+
+// federalReserveDistricts names the district banks by number
+var federalReserveDistricts = [...]string{
+	1: "Boston", 2: "New York", 3: "Philadelphia", 4: "Cleveland",
+	5: "Richmond", 6: "Atlanta", 7: "Chicago", 8: "St. Louis",
+	9: "Minneapolis", 10: "Kansas City", 11: "Dallas", 12: "San Francisco",
+}
+
+func validateUSBankAccount(result *models.BankAccountValidation, routing, account string) {
+	result.FormattedBankCode = routing
+	result.IsAccountNumberFormatValid = digitsOfLength(account, minUSAccountLength, maxUSAccountLength)
+
+	if len(routing) != abaRoutingLength || !isAllDigits(routing) {
+		return
+	}
+	institution, ok := abaRoutingInstitution(routing[:2])
+	if !ok {
+		return
+	}
+	result.IsBankCodeFormatValid = true
+	result.Institution = institution
+	checksum := abaChecksumValid(routing)
+	result.IsBankCodeChecksumValid = &checksum
+}
+
+// abaRoutingInstitution describes the first two routing digits: 00 for
+// the US government, 01-12 for the Federal Reserve district, 21-32 for a
+// thrift and 61-72 for an electronic transaction identifier in that
+// district, and 80 for traveler's cheques. Other prefixes are not issued.
+func abaRoutingInstitution(prefix string) (string, bool) {
+	n := int(prefix[0]-'0')*10 + int(prefix[1]-'0')
+	district := func(d int, kind string) (string, bool) {
+		return "Federal Reserve Bank of " + federalReserveDistricts[d] + " district" + kind, true
+	}
+	switch {
+	case n == 0:
+		return "United States Government", true
+	case n >= 1 && n <= 12:
+		return district(n, "")
+	case n >= 21 && n <= 32:
+		return district(n-20, " (thrift institution)")
+	case n >= 61 && n <= 72:
+		return district(n-60, " (electronic transactions)")
+	case n == 80:
+		return "Traveler's cheques", true
+	default:
+		return "", false
+	}
+}
+
+// abaChecksumValid applies the 3-7-1 weights: the weighted digit sum of a
+// routing number is a multiple of 10
+func abaChecksumValid(routing string) bool {
+	weights := [abaRoutingLength]int{3, 7, 1, 3, 7, 1, 3, 7, 1}
+	sum := 0
+	for i, w := range weights {
+		sum += int(routing[i]-'0') * w
+	}
+	return sum%10 == 0
+}
+
+// UK: sort code and account number with Vocalink modulus checking
+
+// Modulus checking methods of the Vocalink table
+const (
+	modulusMOD10 = "MOD10"
+	modulusMOD11 = "MOD11"
+	modulusDBLAL = "DBLAL"
+)
+
+// modulusForeignCurrency is the exception code of rows whose sort codes
+// also hold foreign currency accounts, which cannot be checked
+const modulusForeignCurrency = 6
+
+// modulusRule is one row of the Vocalink modulus weight table: sort codes
+// from start to end are checked with method over the 14 digits of sort
+// code and account number, weighted by weights. exception is the row's
+// exception code, 0 for none.
+type modulusRule struct {
+	start, end string
+	method     string
+	weights    [ukSortCodeLength + ukAccountLength]int
+	exception  int
+}
+
+// valacdosRows holds rows of Vocalink's valacdos.txt, see the file
+//
+//go:embed valacdos.txt
+var valacdosRows string
+
+// modulusRules are the rows of valacdosRows
+var modulusRules = parseModulusRules(valacdosRows)
+
+func parseModulusRules(table string) []modulusRule {
+	var rules []modulusRule
+	for i, line := range strings.Split(table, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		rule, err := parseModulusRule(fields)
+		if err != nil {
+			panic(fmt.Sprintf("valacdos.txt line %d: %v", i+1, err))
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func parseModulusRule(fields []string) (modulusRule, error) {
+	var rule modulusRule
+	if n := len(fields); n != 3+len(rule.weights) && n != 4+len(rule.weights) {
+		return rule, fmt.Errorf("%d fields", n)
+	}
+	rule.start, rule.end, rule.method = fields[0], fields[1], fields[2]
+	if !digitsOfLength(rule.start, ukSortCodeLength, ukSortCodeLength) || !digitsOfLength(rule.end, ukSortCodeLength, ukSortCodeLength) {
+		return rule, fmt.Errorf("sort codes %s %s", rule.start, rule.end)
+	}
+	switch rule.method {
+	case modulusMOD10, modulusMOD11, modulusDBLAL:
+	default:
+		return rule, fmt.Errorf("method %q", rule.method)
+	}
+	for i := range rule.weights {
+		w, err := strconv.Atoi(fields[3+i])
+		if err != nil {
+			return rule, err
+		}
+		rule.weights[i] = w
+	}
+	if len(fields) == 4+len(rule.weights) {
+		exception, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil {
+			return rule, err
+		}
+		rule.exception = exception
+	}
+	return rule, nil
+}
+
+func validateUKBankAccount(result *models.BankAccountValidation, sortCode, account string) {
+	// 6 and 7 digit accounts are written with leading zeros for checking
+	if digitsOfLength(account, minUKAccountLength, ukAccountLength) {
+		account = strings.Repeat("0", ukAccountLength-len(account)) + account
+		result.AccountNumber = account
+		result.IsAccountNumberFormatValid = true
+	}
+	if len(sortCode) != ukSortCodeLength || !isAllDigits(sortCode) {
+		result.FormattedBankCode = sortCode
+		return
+	}
+	result.IsBankCodeFormatValid = true
+	result.FormattedBankCode = sortCode[0:2] + "-" + sortCode[2:4] + "-" + sortCode[4:6]
+
+	if !result.IsAccountNumberFormatValid {
+		return
+	}
+	if valid, checked := modulusCheck(sortCode, account); checked {
+		result.IsAccountChecksumValid = &valid
+	}
+}
+
+// modulusCheck runs the checks of every row of sortCode, all of which
+// must pass. It reports checked false when there is no row, as Vocalink
+// treats such sort codes as passing, when an exception says the account
+// cannot be checked, and for exception codes not handled here.
+func modulusCheck(sortCode, account string) (valid, checked bool) {
+	var rules []modulusRule
+	for _, rule := range modulusRules {
+		if sortCode >= rule.start && sortCode <= rule.end {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return false, false
+	}
+	digits := sortCode + account
+	valid = true
+	for _, rule := range rules {
+		switch rule.exception {
+		case 0:
+		case modulusForeignCurrency:
+			// Accounts with a of 4 to 8 and g equal to h are foreign
+			// currency accounts
+			if a := digits[6]; a >= '4' && a <= '8' && digits[12] == digits[13] {
+				return false, false
+			}
+		default:
+			return false, false
+		}
+		valid = valid && rule.check(digits)
+	}
+	return valid, true
+}
+
+// check runs the rule's method over the 14 digits
+func (r modulusRule) check(digits string) bool {
+	sum := 0
+	for i, w := range r.weights {
+		product := int(digits[i]-'0') * w
+		if r.method == modulusDBLAL {
+			// double alternate adds the digits of each product
+			product = product/10 + product%10
+		}
+		sum += product
+	}
+	if r.method == modulusMOD11 {
+		return sum%11 == 0
+	}
+	return sum%10 == 0
+}
+
+// Canada: transit and institution numbers
+
+// canadianInstitutions names common financial institution numbers
+var canadianInstitutions = map[string]string{
+	"001": "Bank of Montreal",
+	"002": "Bank of Nova Scotia",
+	"003": "Royal Bank of Canada",
+	"004": "Toronto-Dominion Bank",
+	"006": "National Bank of Canada",
+	"010": "Canadian Imperial Bank of Commerce",
+	"016": "HSBC Bank Canada",
+	"039": "Laurentian Bank of Canada",
+	"177": "Bank of Canada",
+	"219": "ATB Financial",
+	"614": "Tangerine Bank",
+	"815": "Fédération des caisses Desjardins du Québec",
+}
+
+// validateCABankAccount accepts the bank code as printed on cheques
+// (transit then institution, TTTTT-III) or in the electronic funds
+// transfer form (0IIITTTTT). Canadian numbers carry no check digits.
+func validateCABankAccount(result *models.BankAccountValidation, bankCode, account string) {
+	result.IsAccountNumberFormatValid = digitsOfLength(account, minCAAccountLength, maxCAAccountLength)
+	result.FormattedBankCode = bankCode
+
+	var transit, institution string
+	switch {
+	case len(bankCode) == caTransitLength+caInstitutionLength && isAllDigits(bankCode):
+		transit, institution = bankCode[:caTransitLength], bankCode[caTransitLength:]
+	case len(bankCode) == 1+caInstitutionLength+caTransitLength && bankCode[0] == '0' && isAllDigits(bankCode):
+		institution, transit = bankCode[1:1+caInstitutionLength], bankCode[1+caInstitutionLength:]
+	default:
+		return
+	}
+	result.IsBankCodeFormatValid = true
+	result.BankCode = "0" + institution + transit
+	result.FormattedBankCode = transit + "-" + institution
+	result.Institution = canadianInstitutions[institution]
+}
@@ -0,0 +1,200 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// checksum renders an optional check as "none", "true" or "false"
+func checksum(passed *bool) string {
+	if passed == nil {
+		return "none"
+	}
+	if *passed {
+		return "true"
+	}
+	return "false"
+}
+
+func TestValidateBankAccount(t *testing.T) {
+	tests := []struct {
+		name                          string
+		req                           models.BankAccountRequest
+		valid                         bool
+		bankCode, formatted, account  string
+		bankChecksum, accountChecksum string
+		bankCodeValid, accountValid   bool
+		institution                   string
+	}{
+		// US: routing numbers from the Federal Reserve's E-Payments directory
+		{
+			name:  "Federal Reserve Bank of Boston",
+			req:   models.BankAccountRequest{Country: "us", BankCode: "011000015", AccountNumber: "1234-5678"},
+			valid: true, bankCode: "011000015", formatted: "011000015", account: "12345678",
+			bankChecksum: "true", accountChecksum: "none", bankCodeValid: true, accountValid: true,
+			institution: "Federal Reserve Bank of Boston district",
+		},
+		{
+			name:  "thrift routing number",
+			req:   models.BankAccountRequest{Country: "US", BankCode: "322271627", AccountNumber: "000123456789"},
+			valid: true, bankCode: "322271627", formatted: "322271627", account: "000123456789",
+			bankChecksum: "true", accountChecksum: "none", bankCodeValid: true, accountValid: true,
+			institution: "Federal Reserve Bank of San Francisco district (thrift institution)",
+		},
+		{
+			name:     "routing number failing 3-7-1",
+			req:      models.BankAccountRequest{Country: "US", BankCode: "021000022", AccountNumber: "12345678"},
+			bankCode: "021000022", formatted: "021000022", account: "12345678",
+			bankChecksum: "false", accountChecksum: "none", bankCodeValid: true, accountValid: true,
+			institution: "Federal Reserve Bank of New York district",
+		},
+		{
+			name:     "routing prefix not issued",
+			req:      models.BankAccountRequest{Country: "US", BankCode: "131000005", AccountNumber: "12345678"},
+			bankCode: "131000005", formatted: "131000005", account: "12345678",
+			bankChecksum: "none", accountChecksum: "none", accountValid: true,
+		},
+		{
+			name:     "US account too short",
+			req:      models.BankAccountRequest{Country: "US", BankCode: "011000015", AccountNumber: "123"},
+			bankCode: "011000015", formatted: "011000015", account: "123",
+			bankChecksum: "true", accountChecksum: "none", bankCodeValid: true,
+			institution: "Federal Reserve Bank of Boston district",
+		},
+		// GB: Vocalink's published modulus checking test cases
+		{
+			name:  "Vocalink: pass modulus 10 check",
+			req:   models.BankAccountRequest{Country: "GB", BankCode: "08-99-99", AccountNumber: "66374958"},
+			valid: true, bankCode: "089999", formatted: "08-99-99", account: "66374958",
+			bankChecksum: "none", accountChecksum: "true", bankCodeValid: true, accountValid: true,
+		},
+		{
+			name:  "Vocalink: pass modulus 11 check",
+			req:   models.BankAccountRequest{Country: "UK", BankCode: "107999", AccountNumber: "88837491"},
+			valid: true, bankCode: "107999", formatted: "10-79-99", account: "88837491",
+			bankChecksum: "none", accountChecksum: "true", bankCodeValid: true, accountValid: true,
+		},
+		{
+			name:  "Vocalink: pass UK modulus 11 and double alternate checks",
+			req:   models.BankAccountRequest{Country: "GB", BankCode: "202959", AccountNumber: "63748472"},
+			valid: true, bankCode: "202959", formatted: "20-29-59", account: "63748472",
+			bankChecksum: "none", accountChecksum: "true", bankCodeValid: true, accountValid: true,
+		},
+		{
+			name:  "Vocalink: exception 6 where the account fails standard check but is a foreign currency account",
+			req:   models.BankAccountRequest{Country: "GB", BankCode: "200915", AccountNumber: "41011166"},
+			valid: true, bankCode: "200915", formatted: "20-09-15", account: "41011166",
+			bankChecksum: "none", accountChecksum: "none", bankCodeValid: true, accountValid: true,
+		},
+		{
+			name:     "Vocalink: pass modulus 11 check and fail double alternate check",
+			req:      models.BankAccountRequest{Country: "GB", BankCode: "203099", AccountNumber: "66831036"},
+			bankCode: "203099", formatted: "20-30-99", account: "66831036",
+			bankChecksum: "none", accountChecksum: "false", bankCodeValid: true, accountValid: true,
+		},
+		{
+			name:     "Vocalink: fail modulus 11 check and pass double alternate check",
+			req:      models.BankAccountRequest{Country: "GB", BankCode: "203099", AccountNumber: "58716970"},
+			bankCode: "203099", formatted: "20-30-99", account: "58716970",
+			bankChecksum: "none", accountChecksum: "false", bankCodeValid: true, accountValid: true,
+		},
+		{
+			name:     "Vocalink: fail modulus 10 check",
+			req:      models.BankAccountRequest{Country: "GB", BankCode: "089999", AccountNumber: "66374959"},
+			bankCode: "089999", formatted: "08-99-99", account: "66374959",
+			bankChecksum: "none", accountChecksum: "false", bankCodeValid: true, accountValid: true,
+		},
+		{
+			name:     "Vocalink: fail modulus 11 check",
+			req:      models.BankAccountRequest{Country: "GB", BankCode: "107999", AccountNumber: "88837493"},
+			bankCode: "107999", formatted: "10-79-99", account: "88837493",
+			bankChecksum: "none", accountChecksum: "false", bankCodeValid: true, accountValid: true,
+		},
+		{
+			name:     "exception 6 row with a domestic account",
+			req:      models.BankAccountRequest{Country: "GB", BankCode: "200915", AccountNumber: "41011176"},
+			bankCode: "200915", formatted: "20-09-15", account: "41011176",
+			bankChecksum: "none", accountChecksum: "false", bankCodeValid: true, accountValid: true,
+		},
+		{
+			name:  "7 digit account, sort code without a row",
+			req:   models.BankAccountRequest{Country: "GB", BankCode: "20 00 00", AccountNumber: "1234567"},
+			valid: true, bankCode: "200000", formatted: "20-00-00", account: "01234567",
+			bankChecksum: "none", accountChecksum: "none", bankCodeValid: true, accountValid: true,
+		},
+		{
+			name:     "sort code too short",
+			req:      models.BankAccountRequest{Country: "GB", BankCode: "08999", AccountNumber: "66374958"},
+			bankCode: "08999", formatted: "08999", account: "66374958",
+			bankChecksum: "none", accountChecksum: "none", accountValid: true,
+		},
+		// CA: transit and institution numbers as printed on cheques and
+		// in the electronic funds transfer form
+		{
+			name:  "cheque form",
+			req:   models.BankAccountRequest{Country: "CA", BankCode: "12345-003", AccountNumber: "1234567"},
+			valid: true, bankCode: "000312345", formatted: "12345-003", account: "1234567",
+			bankChecksum: "none", accountChecksum: "none", bankCodeValid: true, accountValid: true,
+			institution: "Royal Bank of Canada",
+		},
+		{
+			name:  "electronic form",
+			req:   models.BankAccountRequest{Country: "CA", BankCode: "081500001", AccountNumber: "123456789012"},
+			valid: true, bankCode: "081500001", formatted: "00001-815", account: "123456789012",
+			bankChecksum: "none", accountChecksum: "none", bankCodeValid: true, accountValid: true,
+			institution: "Fédération des caisses Desjardins du Québec",
+		},
+		{
+			name:     "CA account too long",
+			req:      models.BankAccountRequest{Country: "CA", BankCode: "12345003", AccountNumber: "1234567890123"},
+			bankCode: "000312345", formatted: "12345-003", account: "1234567890123",
+			bankChecksum: "none", accountChecksum: "none", bankCodeValid: true,
+			institution: "Royal Bank of Canada",
+		},
+		{
+			name:     "transit number with letters",
+			req:      models.BankAccountRequest{Country: "CA", BankCode: "1234A003", AccountNumber: "1234567"},
+			bankCode: "1234A003", formatted: "1234A003", account: "1234567",
+			bankChecksum: "none", accountChecksum: "none", accountValid: true,
+		},
+	}
+	for _, tt := range tests {
+		got := ValidateBankAccount(tt.req)
+		if got.IsValid != tt.valid || got.BankCode != tt.bankCode || got.FormattedBankCode != tt.formatted ||
+			got.AccountNumber != tt.account || !got.IsCountrySupported {
+			t.Errorf("%s: %+v", tt.name, got)
+		}
+		if checksum(got.IsBankCodeChecksumValid) != tt.bankChecksum || checksum(got.IsAccountChecksumValid) != tt.accountChecksum {
+			t.Errorf("%s: bank code checksum %s, account checksum %s; want %s and %s", tt.name,
+				checksum(got.IsBankCodeChecksumValid), checksum(got.IsAccountChecksumValid), tt.bankChecksum, tt.accountChecksum)
+		}
+		if got.IsBankCodeFormatValid != tt.bankCodeValid || got.IsAccountNumberFormatValid != tt.accountValid || got.Institution != tt.institution {
+			t.Errorf("%s: bank code format %v, account format %v, institution %q", tt.name,
+				got.IsBankCodeFormatValid, got.IsAccountNumberFormatValid, got.Institution)
+		}
+	}
+}
+
+func TestValidateBankAccountUnsupportedCountry(t *testing.T) {
+	got := ValidateBankAccount(models.BankAccountRequest{Country: "DE", BankCode: "37040044", AccountNumber: "0532013000"})
+	if got.IsCountrySupported || got.IsValid || got.Scheme != "" || got.Country != "DE" {
+		t.Errorf("DE = %+v, want an unsupported country", got)
+	}
+	if countries := BankAccountCountries(); len(countries) != 3 || countries[0] != "CA" || countries[1] != "GB" || countries[2] != "US" {
+		t.Errorf("countries = %v", countries)
+	}
+}
+
+func TestNormalizeBankInput(t *testing.T) {
+	for input, want := range map[string]string{
+		"08-99-99":     "089999",
+		" 12345 003\t": "12345003",
+		"1234.5678/90": "1234567890",
+		"12a4":         "12a4",
+	} {
+		if got := NormalizeBankInput(input); got != want {
+			t.Errorf("NormalizeBankInput(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
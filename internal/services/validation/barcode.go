@@ -0,0 +1,258 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/pkg/generate/barcode"
+)
+
+// Symbologies a scanned value is classified as
+const (
+	ScanUPCA   = "UPC-A"
+	ScanEAN13  = "EAN-13"
+	ScanEAN8   = "EAN-8"
+	ScanITF14  = "ITF-14"
+	ScanCode39 = "Code39"
+	ScanGS1128 = "GS1-128"
+)
+
+// ErrUnknownSymbology is returned for a symbology hint that is not scanned
+var ErrUnknownSymbology = errors.New("symbology must be one of UPC-A, EAN-13, EAN-8, ITF-14, Code39, GS1-128")
+
+// scanSymbologies orders the symbologies for candidates that are otherwise
+// equally plausible: retail symbols before logistic ones, and Code 39,
+// which accepts nearly anything, last
+var scanSymbologies = []string{ScanEAN13, ScanUPCA, ScanEAN8, ScanITF14, ScanGS1128, ScanCode39}
+
+// gs1KeyLengths is the length of each retail and case symbol, check
+// digit included
+var gs1KeyLengths = map[string]int{
+	ScanUPCA:  12,
+	ScanEAN13: 13,
+	ScanEAN8:  8,
+	ScanITF14: 14,
+}
+
+// aimIdentifiers maps the AIM symbology identifiers scanners can prefix
+// to a value to the symbology they name
+var aimIdentifiers = map[string]string{
+	"]E0": ScanEAN13,
+	"]E4": ScanEAN8,
+	"]C1": ScanGS1128,
+	"]I0": ScanITF14,
+	"]I1": ScanITF14,
+	"]A0": ScanCode39,
+	"]A1": ScanCode39,
+	"]A3": ScanCode39,
+}
+
+// ParseSymbologyHint resolves a symbology hint regardless of case, dashes
+// and spaces; "" is no hint
+func ParseSymbologyHint(hint string) (string, error) {
+	key := strings.NewReplacer("-", "", " ", "", "_", "").Replace(strings.ToLower(hint))
+	if key == "" {
+		return "", nil
+	}
+	if key == "ean128" {
+		return ScanGS1128, nil
+	}
+	for _, symbology := range scanSymbologies {
+		if strings.ReplaceAll(strings.ToLower(symbology), "-", "") == key {
+			return symbology, nil
+		}
+	}
+	return "", ErrUnknownSymbology
+}
+
+// ValidateScannedBarcode lists the symbologies req.Value could have been
+// scanned from, checks each interpretation's length, characters and check
+// digits, and splits GS1 element strings into their fields. now decides
+// the century of GS1 dates.
+func ValidateScannedBarcode(req models.BarcodeValidateRequest, now time.Time) (models.BarcodeValidation, error) {
+	hint, err := ParseSymbologyHint(req.Symbology)
+	if err != nil {
+		return models.BarcodeValidation{}, err
+	}
+
+	value := strings.Trim(req.Value, " \t\r\n")
+	result := models.BarcodeValidation{Value: value, Candidates: []models.BarcodeCandidate{}}
+	if len(value) > 3 {
+		if scanned, ok := aimIdentifiers[value[:3]]; ok {
+			result.ScannerSymbology = scanned
+			value = value[3:]
+			if hint == "" {
+				hint = scanned
+			}
+		}
+	}
+	if value == "" {
+		return result, nil
+	}
+
+	for _, symbology := range scanSymbologies {
+		candidate, ok := scanCandidate(symbology, value, symbology == hint, now)
+		if ok {
+			result.Candidates = append(result.Candidates, candidate)
+		}
+	}
+	rankCandidates(result.Candidates, hint)
+	result.IsValid = len(result.Candidates) > 0 && result.Candidates[0].IsValid
+	return result, nil
+}
+
+// scanCandidate interprets value as symbology. Interpretations whose
+// length or characters rule them out are dropped unless hinted, so the
+// caller learns why the hinted symbology does not fit.
+func scanCandidate(symbology, value string, hinted bool, now time.Time) (models.BarcodeCandidate, bool) {
+	switch symbology {
+	case ScanGS1128:
+		return gs1Candidate(value, hinted, now)
+	case ScanCode39:
+		return code39Candidate(value, hinted)
+	default:
+		return gs1KeyCandidate(symbology, value, hinted)
+	}
+}
+
+// gs1KeyCandidate reads value as a retail or case symbol, either complete
+// or without its check digit
+func gs1KeyCandidate(symbology, value string, hinted bool) (models.BarcodeCandidate, bool) {
+	length := gs1KeyLengths[symbology]
+	candidate := models.BarcodeCandidate{Symbology: symbology, Data: value}
+	candidate.IsCharsetValid = isAllDigits(value)
+	candidate.IsLengthValid = len(value) == length || len(value) == length-1
+	if !candidate.IsCharsetValid || !candidate.IsLengthValid {
+		if !candidate.IsCharsetValid {
+			candidate.Errors = append(candidate.Errors, symbology+" data must be numeric")
+		}
+		if !candidate.IsLengthValid {
+			candidate.Errors = append(candidate.Errors, fmt.Sprintf("%s data must be %d digits, or %d without the check digit", symbology, length, length-1))
+		}
+		return candidate, hinted
+	}
+
+	body := value[:length-1]
+	check := barcode.GS1CheckDigit(body)
+	candidate.CheckDigit = strconv.Itoa(check)
+	complete := body + candidate.CheckDigit
+	if len(value) == length {
+		valid := int(value[length-1]-'0') == check
+		candidate.IsChecksumValid = &valid
+		if !valid {
+			candidate.Errors = append(candidate.Errors, fmt.Sprintf("check digit is %c, expected %d", value[length-1], check))
+		}
+	} else {
+		candidate.CompleteValue = complete
+	}
+	candidate.IsValid = len(candidate.Errors) == 0
+	if candidate.IsValid {
+		candidate.GTIN = strings.Repeat("0", 14-length) + complete
+	}
+	return candidate, true
+}
+
+// gs1Candidate reads value as a GS1 element string. Values without a GS1
+// marker (group separators, the bracketed form or a GS1-128 hint) are
+// only tried when they start with a key AI and parse cleanly, so plain
+// numbers are not taken apart into arbitrary fields.
+func gs1Candidate(value string, hinted bool, now time.Time) (models.BarcodeCandidate, bool) {
+	marked := hinted || strings.HasPrefix(value, "(") || strings.IndexByte(value, gs1Separator) >= 0
+	if !marked && !strings.HasPrefix(value, "00") && !strings.HasPrefix(value, "01") && !strings.HasPrefix(value, "02") {
+		return models.BarcodeCandidate{}, false
+	}
+	elements, problems := parseGS1(value, now)
+	if !marked && len(problems) > 0 {
+		return models.BarcodeCandidate{}, false
+	}
+
+	candidate := models.BarcodeCandidate{
+		Symbology:      ScanGS1128,
+		Data:           gs1HumanReadable(elements),
+		IsLengthValid:  true,
+		IsCharsetValid: true,
+		GS1:            gs1Fields(elements),
+		Errors:         problems,
+	}
+	var checked bool
+	for _, e := range elements {
+		if e.IsChecksumValid != nil {
+			checked = true
+			if !*e.IsChecksumValid {
+				candidate.IsChecksumValid = e.IsChecksumValid
+			}
+		}
+	}
+	if checked && candidate.IsChecksumValid == nil {
+		valid := true
+		candidate.IsChecksumValid = &valid
+	}
+	candidate.IsValid = len(problems) == 0
+	if candidate.IsValid {
+		candidate.GTIN = candidate.GS1.GTIN
+	}
+	return candidate, true
+}
+
+// code39Candidate reads value as standard Code 39, with or without the
+// start and stop asterisks. The mod 43 check character is optional, so it
+// is reported only when the last character matches it.
+func code39Candidate(value string, hinted bool) (models.BarcodeCandidate, bool) {
+	data := value
+	if len(data) > 2 && data[0] == '*' && data[len(data)-1] == '*' {
+		data = data[1 : len(data)-1]
+	}
+	candidate := models.BarcodeCandidate{Symbology: ScanCode39, Data: data, IsLengthValid: true}
+	candidate.IsCharsetValid = barcode.IsCode39(data)
+	if !candidate.IsCharsetValid {
+		candidate.Errors = append(candidate.Errors, "Code39 data must be digits, upper-case letters, space or -.$/+%")
+		return candidate, hinted
+	}
+	if len(data) > 1 {
+		if check, _ := barcode.Code39CheckCharacter(data[:len(data)-1]); check == data[len(data)-1] {
+			valid := true
+			candidate.IsChecksumValid = &valid
+			candidate.CheckDigit = string(check)
+		}
+	}
+	candidate.IsValid = true
+	return candidate, true
+}
+
+// rankCandidates orders candidates by validity, then the hint, then
+// whether a check digit confirmed them, then scanSymbologies, and numbers
+// them from 1
+func rankCandidates(candidates []models.BarcodeCandidate, hint string) {
+	order := func(symbology string) int {
+		for i, s := range scanSymbologies {
+			if s == symbology {
+				return i
+			}
+		}
+		return len(scanSymbologies)
+	}
+	confirmed := func(c models.BarcodeCandidate) bool {
+		return c.IsChecksumValid != nil && *c.IsChecksumValid
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.IsValid != b.IsValid {
+			return a.IsValid
+		}
+		if (a.Symbology == hint) != (b.Symbology == hint) {
+			return a.Symbology == hint
+		}
+		if confirmed(a) != confirmed(b) {
+			return confirmed(a)
+		}
+		return order(a.Symbology) < order(b.Symbology)
+	})
+	for i := range candidates {
+		candidates[i].Rank = i + 1
+	}
+}
@@ -0,0 +1,198 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+var scanTime = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+// scannedCandidate is the part of a candidate the tests compare
+type scannedCandidate struct {
+	symbology, checksum, checkDigit, complete, gtin string
+	valid                                           bool
+}
+
+func scannedCandidates(t *testing.T, req models.BarcodeValidateRequest) []scannedCandidate {
+	t.Helper()
+	result, err := ValidateScannedBarcode(req, scanTime)
+	if err != nil {
+		t.Fatalf("ValidateScannedBarcode(%q): %v", req.Value, err)
+	}
+	var got []scannedCandidate
+	for i, c := range result.Candidates {
+		if c.Rank != i+1 {
+			t.Errorf("candidate %d has rank %d", i, c.Rank)
+		}
+		got = append(got, scannedCandidate{
+			symbology: c.Symbology, checksum: checksum(c.IsChecksumValid), checkDigit: c.CheckDigit,
+			complete: c.CompleteValue, gtin: c.GTIN, valid: c.IsValid,
+		})
+	}
+	if len(got) > 0 && result.IsValid != got[0].valid {
+		t.Errorf("isValid = %v, want the top candidate's %v", result.IsValid, got[0].valid)
+	}
+	return got
+}
+
+func TestValidateScannedBarcodeCandidates(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.BarcodeValidateRequest
+		want []scannedCandidate
+	}{
+		{
+			// 12 digits are a complete UPC-A or an EAN-13 without its check digit
+			name: "12 digits with a valid UPC-A check digit",
+			req:  models.BarcodeValidateRequest{Value: "036000291452"},
+			want: []scannedCandidate{
+				{symbology: "UPC-A", checksum: "true", checkDigit: "2", gtin: "00036000291452", valid: true},
+				{symbology: "EAN-13", checksum: "none", checkDigit: "2", complete: "0360002914522", gtin: "00360002914522", valid: true},
+				{symbology: "Code39", checksum: "none", valid: true},
+			},
+		},
+		{
+			name: "12 digits failing the UPC-A check digit",
+			req:  models.BarcodeValidateRequest{Value: "036000291453"},
+			want: []scannedCandidate{
+				{symbology: "EAN-13", checksum: "none", checkDigit: "9", complete: "0360002914539", gtin: "00360002914539", valid: true},
+				{symbology: "Code39", checksum: "none", valid: true},
+				{symbology: "UPC-A", checksum: "false", checkDigit: "2"},
+			},
+		},
+		{
+			name: "hint ranks EAN-13 first",
+			req:  models.BarcodeValidateRequest{Value: "036000291452", Symbology: "ean13"},
+			want: []scannedCandidate{
+				{symbology: "EAN-13", checksum: "none", checkDigit: "2", complete: "0360002914522", gtin: "00360002914522", valid: true},
+				{symbology: "UPC-A", checksum: "true", checkDigit: "2", gtin: "00036000291452", valid: true},
+				{symbology: "Code39", checksum: "none", valid: true},
+			},
+		},
+		{
+			// 13 digits are a complete EAN-13 or an ITF-14 without its check digit
+			name: "13 digits",
+			req:  models.BarcodeValidateRequest{Value: "4006381333931"},
+			want: []scannedCandidate{
+				{symbology: "EAN-13", checksum: "true", checkDigit: "1", gtin: "04006381333931", valid: true},
+				{symbology: "ITF-14", checksum: "none", checkDigit: "4", complete: "40063813339314", gtin: "40063813339314", valid: true},
+				{symbology: "Code39", checksum: "none", valid: true},
+			},
+		},
+		{
+			name: "EAN-8",
+			req:  models.BarcodeValidateRequest{Value: "96385074"},
+			want: []scannedCandidate{
+				{symbology: "EAN-8", checksum: "true", checkDigit: "4", gtin: "00000096385074", valid: true},
+				{symbology: "Code39", checksum: "none", valid: true},
+			},
+		},
+		{
+			name: "Code 39 with a mod 43 check character",
+			req:  models.BarcodeValidateRequest{Value: "*CODE39W*"},
+			want: []scannedCandidate{
+				{symbology: "Code39", checksum: "true", checkDigit: "W", valid: true},
+			},
+		},
+		{
+			name: "hinted symbology that does not fit",
+			req:  models.BarcodeValidateRequest{Value: "ABC-1", Symbology: "EAN-8"},
+			want: []scannedCandidate{
+				{symbology: "Code39", checksum: "none", valid: true},
+				{symbology: "EAN-8", checksum: "none"},
+			},
+		},
+		{
+			name: "lower case matches nothing",
+			req:  models.BarcodeValidateRequest{Value: "hello"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scannedCandidates(t, tt.req); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("candidates =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateScannedBarcodeGS1(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		valid  bool
+		fields models.GS1Fields
+		data   string
+	}{
+		{
+			name:  "scanner identifier and group separator",
+			value: "]C101095011015300031726093010AB-123\x1d21SN0001",
+			valid: true,
+			data:  "(01)09501101530003(17)260930(10)AB-123(21)SN0001",
+			fields: models.GS1Fields{
+				GTIN: "09501101530003", ExpiryDate: "2026-09-30", Lot: "AB-123", Serial: "SN0001",
+			},
+		},
+		{
+			name:   "bracketed form with day 00 and the previous century",
+			value:  "(01)09501101530003(15)800200",
+			valid:  true,
+			data:   "(01)09501101530003(15)800200",
+			fields: models.GS1Fields{GTIN: "09501101530003", BestBefore: "1980-02-29"},
+		},
+		{
+			name:  "unmarked digits starting with a key",
+			value: "0109501101530003172702001012",
+			valid: true,
+			data:  "(01)09501101530003(17)270200(10)12",
+			fields: models.GS1Fields{
+				GTIN: "09501101530003", ExpiryDate: "2027-02-28", Lot: "12",
+			},
+		},
+		{
+			name:   "GTIN check digit",
+			value:  "]C1010950110153000417261301",
+			data:   "(01)09501101530004(17)261301",
+			fields: models.GS1Fields{GTIN: "09501101530004"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidateScannedBarcode(models.BarcodeValidateRequest{Value: tt.value}, scanTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gs1 *models.BarcodeCandidate
+			for i := range result.Candidates {
+				if result.Candidates[i].Symbology == ScanGS1128 {
+					gs1 = &result.Candidates[i]
+				}
+			}
+			if gs1 == nil {
+				t.Fatalf("no GS1-128 candidate in %+v", result.Candidates)
+			}
+			if gs1.IsValid != tt.valid || gs1.Data != tt.data {
+				t.Errorf("valid %v data %q, want %v %q (errors %v)", gs1.IsValid, gs1.Data, tt.valid, tt.data, gs1.Errors)
+			}
+			if tt.valid && result.Candidates[0].Symbology != ScanGS1128 {
+				t.Errorf("top candidate is %s", result.Candidates[0].Symbology)
+			}
+			fields := *gs1.GS1
+			fields.Elements = nil
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("fields = %+v, want %+v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestValidateScannedBarcodeUnknownHint(t *testing.T) {
+	_, err := ValidateScannedBarcode(models.BarcodeValidateRequest{Value: "123", Symbology: "QR"}, scanTime)
+	if !errors.Is(err, ErrUnknownSymbology) {
+		t.Errorf("err = %v, want ErrUnknownSymbology", err)
+	}
+}
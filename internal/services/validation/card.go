@@ -0,0 +1,144 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// Card schemes detected from the issuer identification number
+const (
+	CardSchemeVisa       = "Visa"
+	CardSchemeMastercard = "Mastercard"
+	CardSchemeAmex       = "Amex"
+	CardSchemeDiscover   = "Discover"
+	CardSchemeJCB        = "JCB"
+	CardSchemeDiners     = "Diners"
+	CardSchemeUnionPay   = "UnionPay"
+)
+
+// minCardDigits and maxCardDigits bound the length of a primary account
+// number, per ISO/IEC 7812
+const (
+	minCardDigits = 8
+	maxCardDigits = 19
+)
+
+// ErrInvalidCardNumber is returned for a card number with characters
+// other than digits, spaces and dashes, or of an impossible length. Its
+// messages never include the number.
+var ErrInvalidCardNumber = errors.New("invalid card number")
+
+// cardRange is an inclusive range of issuer prefixes, compared on the
+// first len(from) digits
+type cardRange struct {
+	from, to string
+}
+
+// cardScheme is a scheme's prefix ranges and the lengths its numbers have
+type cardScheme struct {
+	name    string
+	ranges  []cardRange
+	lengths []int
+}
+
+// cardSchemes are checked in order, so Discover's co-branded range
+// 622126-622925 is matched before UnionPay's 62
+var cardSchemes = []cardScheme{
+	{CardSchemeAmex, []cardRange{{"34", "34"}, {"37", "37"}}, []int{15}},
+	{CardSchemeDiners, []cardRange{{"300", "305"}, {"3095", "3095"}, {"36", "36"}, {"38", "39"}}, []int{14, 15, 16, 17, 18, 19}},
+	{CardSchemeJCB, []cardRange{{"3528", "3589"}}, []int{16, 17, 18, 19}},
+	{CardSchemeVisa, []cardRange{{"4", "4"}}, []int{13, 16, 19}},
+	{CardSchemeMastercard, []cardRange{{"51", "55"}, {"2221", "2720"}}, []int{16}},
+	{CardSchemeDiscover, []cardRange{{"6011", "6011"}, {"622126", "622925"}, {"644", "649"}, {"65", "65"}}, []int{16, 17, 18, 19}},
+	{CardSchemeUnionPay, []cardRange{{"62", "62"}, {"81", "81"}}, []int{16, 17, 18, 19}},
+}
+
+// ValidateCardNumber checks a payment card number's Luhn check digit and
+// detects its scheme. Spaces and dashes are ignored. The result only
+// carries the masked number.
+func ValidateCardNumber(number string) (models.CardValidation, error) {
+	digits, err := cardDigits(number)
+	if err != nil {
+		return models.CardValidation{}, err
+	}
+
+	result := models.CardValidation{
+		MaskedNumber: maskCardNumber(digits),
+		Length:       len(digits),
+		CheckDigit:   digits[len(digits)-1:],
+		IsValid:      luhnValid(digits),
+	}
+	if scheme, ok := detectCardScheme(digits); ok {
+		result.Scheme = scheme.name
+		result.IsLengthValidForScheme = slices.Contains(scheme.lengths, len(digits))
+	}
+	return result, nil
+}
+
+// cardDigits removes spaces and dashes from number and checks that only
+// digits of a possible card length are left
+func cardDigits(number string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(number))
+	for _, c := range []byte(digits) {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("%w: only digits, spaces and dashes are allowed", ErrInvalidCardNumber)
+		}
+	}
+	if len(digits) < minCardDigits || len(digits) > maxCardDigits {
+		return "", fmt.Errorf("%w: must have %d to %d digits, got %d", ErrInvalidCardNumber, minCardDigits, maxCardDigits, len(digits))
+	}
+	return digits, nil
+}
+
+// luhnValid reports whether the last digit of digits is their Luhn (mod
+// 10) check digit
+func luhnValid(digits string) bool {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+func detectCardScheme(digits string) (cardScheme, bool) {
+	for _, scheme := range cardSchemes {
+		for _, r := range scheme.ranges {
+			if len(digits) < len(r.from) {
+				continue
+			}
+			prefix, _ := strconv.Atoi(digits[:len(r.from)])
+			from, _ := strconv.Atoi(r.from)
+			to, _ := strconv.Atoi(r.to)
+			if prefix >= from && prefix <= to {
+				return scheme, true
+			}
+		}
+	}
+	return cardScheme{}, false
+}
+
+// maskCardNumber keeps the first six and last four digits, which PCI DSS
+// allows to be shown; numbers too short to hide anything between them
+// only keep the last four
+func maskCardNumber(digits string) string {
+	if len(digits) < 6+4+1 {
+		return strings.Repeat("*", len(digits)-4) + digits[len(digits)-4:]
+	}
+	return digits[:6] + strings.Repeat("*", len(digits)-10) + digits[len(digits)-4:]
+}
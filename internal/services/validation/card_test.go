@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+	"testing/quick"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/pkg/validate/testsupport"
+)
+
+func TestValidateCardNumber(t *testing.T) {
+	tests := []struct {
+		name, number string
+		want         models.CardValidation
+	}{
+		// Test numbers published by the card schemes and processors
+		{"Visa", "4111 1111 1111 1111", models.CardValidation{MaskedNumber: "411111******1111", Length: 16, IsValid: true, CheckDigit: "1", Scheme: CardSchemeVisa, IsLengthValidForScheme: true}},
+		{"Visa 13 digits", "4222222222222", models.CardValidation{MaskedNumber: "422222***2222", Length: 13, IsValid: true, CheckDigit: "2", Scheme: CardSchemeVisa, IsLengthValidForScheme: true}},
+		{"Mastercard", "5555-5555-5555-4444", models.CardValidation{MaskedNumber: "555555******4444", Length: 16, IsValid: true, CheckDigit: "4", Scheme: CardSchemeMastercard, IsLengthValidForScheme: true}},
+		{"Mastercard 2-series", "2223003122003222", models.CardValidation{MaskedNumber: "222300******3222", Length: 16, IsValid: true, CheckDigit: "2", Scheme: CardSchemeMastercard, IsLengthValidForScheme: true}},
+		{"Amex", "3782 822463 10005", models.CardValidation{MaskedNumber: "378282*****0005", Length: 15, IsValid: true, CheckDigit: "5", Scheme: CardSchemeAmex, IsLengthValidForScheme: true}},
+		{"Amex 37", "371449635398431", models.CardValidation{MaskedNumber: "371449*****8431", Length: 15, IsValid: true, CheckDigit: "1", Scheme: CardSchemeAmex, IsLengthValidForScheme: true}},
+		{"Discover", "6011111111111117", models.CardValidation{MaskedNumber: "601111******1117", Length: 16, IsValid: true, CheckDigit: "7", Scheme: CardSchemeDiscover, IsLengthValidForScheme: true}},
+		{"Discover co-branded", "6221260000000000", models.CardValidation{MaskedNumber: "622126******0000", Length: 16, IsValid: true, CheckDigit: "0", Scheme: CardSchemeDiscover, IsLengthValidForScheme: true}},
+		{"Discover 65", "6500000000000002", models.CardValidation{MaskedNumber: "650000******0002", Length: 16, IsValid: true, CheckDigit: "2", Scheme: CardSchemeDiscover, IsLengthValidForScheme: true}},
+		{"JCB", "3530111333300000", models.CardValidation{MaskedNumber: "353011******0000", Length: 16, IsValid: true, CheckDigit: "0", Scheme: CardSchemeJCB, IsLengthValidForScheme: true}},
+		{"Diners 305", "30569309025904", models.CardValidation{MaskedNumber: "305693****5904", Length: 14, IsValid: true, CheckDigit: "4", Scheme: CardSchemeDiners, IsLengthValidForScheme: true}},
+		{"Diners 36", "36227206271667", models.CardValidation{MaskedNumber: "362272****1667", Length: 14, IsValid: true, CheckDigit: "7", Scheme: CardSchemeDiners, IsLengthValidForScheme: true}},
+		{"Diners 38", "38520000023237", models.CardValidation{MaskedNumber: "385200****3237", Length: 14, IsValid: true, CheckDigit: "7", Scheme: CardSchemeDiners, IsLengthValidForScheme: true}},
+		{"UnionPay", "6200000000000005", models.CardValidation{MaskedNumber: "620000******0005", Length: 16, IsValid: true, CheckDigit: "5", Scheme: CardSchemeUnionPay, IsLengthValidForScheme: true}},
+		{"UnionPay 81", "8171999927660000", models.CardValidation{MaskedNumber: "817199******0000", Length: 16, IsValid: true, CheckDigit: "0", Scheme: CardSchemeUnionPay, IsLengthValidForScheme: true}},
+
+		// Failing Luhn checks
+		{"Visa wrong check digit", "4111111111111112", models.CardValidation{MaskedNumber: "411111******1112", Length: 16, CheckDigit: "2", Scheme: CardSchemeVisa, IsLengthValidForScheme: true}},
+		{"Mastercard transposed digits", "5555555555545444", models.CardValidation{MaskedNumber: "555555******5444", Length: 16, CheckDigit: "4", Scheme: CardSchemeMastercard, IsLengthValidForScheme: true}},
+		{"Amex wrong check digit", "378282246310006", models.CardValidation{MaskedNumber: "378282*****0006", Length: 15, CheckDigit: "6", Scheme: CardSchemeAmex, IsLengthValidForScheme: true}},
+
+		// Luhn and scheme are checked independently
+		{"Visa of 17 digits", "41111111111111113", models.CardValidation{MaskedNumber: "411111*******1113", Length: 17, IsValid: true, CheckDigit: "3", Scheme: CardSchemeVisa}},
+		{"Amex of 16 digits", "3782822463100005", models.CardValidation{MaskedNumber: "378282******0005", Length: 16, CheckDigit: "5", Scheme: CardSchemeAmex}},
+		{"unknown scheme", "9999999999999995", models.CardValidation{MaskedNumber: "999999******9995", Length: 16, IsValid: true, CheckDigit: "5"}},
+		{"short number", "12345674", models.CardValidation{MaskedNumber: "****5674", Length: 8, IsValid: true, CheckDigit: "4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateCardNumber(tt.number)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ValidateCardNumber(%q) = %+v, want %+v", tt.number, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCardNumberRejects(t *testing.T) {
+	for _, number := range []string{
+		"4111 1111 1111 111a",
+		"4111.1111.1111.1111",
+		"+4111111111111111",
+		"1234567",
+		"41111111111111111111",
+	} {
+		if _, err := ValidateCardNumber(number); !errors.Is(err, ErrInvalidCardNumber) {
+			t.Errorf("ValidateCardNumber(%q) error = %v, want ErrInvalidCardNumber", number, err)
+		}
+	}
+}
+
+// Generated numbers of every scheme length pass, and agree with the
+// reference Luhn check once a digit is changed
+func TestPropertyCardLuhn(t *testing.T) {
+	check := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		i := r.Intn(len(cardSchemes))
+		scheme := cardSchemes[i]
+		prefix := scheme.ranges[r.Intn(len(scheme.ranges))].from
+		length := scheme.lengths[r.Intn(len(scheme.lengths))]
+		number := testsupport.Luhn(r, prefix, length)
+		// A UnionPay 62 number may fall in Discover's co-branded range,
+		// which is checked first
+		if detected, _ := detectCardScheme(number); slices.ContainsFunc(cardSchemes[:i], func(s cardScheme) bool { return s.name == detected.name }) {
+			return true
+		}
+
+		result, err := ValidateCardNumber(number)
+		if err != nil || !result.IsValid || result.Scheme != scheme.name || !result.IsLengthValidForScheme {
+			t.Logf("%s: %+v, %v", number, result, err)
+			return false
+		}
+		mutated := testsupport.Substitute(r, number, r.Intn(len(number)))
+		result, err = ValidateCardNumber(mutated)
+		if err != nil || result.IsValid != testsupport.LuhnValid(mutated) {
+			t.Logf("%s: %+v, %v", mutated, result, err)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(check, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,110 +1,205 @@
 package validation
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
-	"regexp"
-	"strings"
+	"os"
+	"sync"
 
+	"github.com/innovelabs/microtools-go/internal/dataset"
+	"github.com/innovelabs/microtools-go/internal/dnscache"
+	"github.com/innovelabs/microtools-go/internal/logging"
 	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/resultmeta"
+	"github.com/innovelabs/microtools-go/internal/tenant"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"github.com/innovelabs/microtools-go/pkg/validate/email"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-var disposableEmailDomains = []string{
-	"mailinator.com",
-	"10minutemail.com",
-	"guerrillamail.com",
-	"tempmail.net",
-	"throwawaymail.com",
-	"yopmail.com",
-	"maildrop.cc",
-	"getnada.com",
-	"dispostable.com",
-	"fakeinbox.com",
-	"tempmail.org",
-	"spamgourmet.com",
-	"trashmail.com",
+// DisposableDomainsDataset is the dataset registry name of the disposable email domain list
+const DisposableDomainsDataset = "disposable-domains"
+
+func init() {
+	dataset.Register(DisposableDomainsDataset, email.DisposableDomains())
 }
 
-func isValidEmailSyntax(email string) bool {
-	var emailRegex = `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
-	re := regexp.MustCompile(emailRegex)
-	return re.MatchString(email)
+// disposable is the disposable domain list the email checks use: the file
+// at path, or the built-in list when path is ""
+var disposable = struct {
+	mu   sync.RWMutex
+	path string
+	list *email.DisposableList
+}{list: email.DefaultDisposableList()}
+
+// LoadDisposableDomains makes the email checks use the disposable domain
+// list in the file at path, one domain per line with "#" comments, or the
+// built-in list for "". It returns the number of domains. A file that
+// cannot be read or parsed keeps the list in use, but path is remembered
+// so ReloadDisposableDomains can retry it once fixed.
+func LoadDisposableDomains(path string) (int, error) {
+	disposable.mu.Lock()
+	disposable.path = path
+	disposable.mu.Unlock()
+	return ReloadDisposableDomains()
 }
 
-func extractDomain(email string) string {
-	parts := strings.Split(email, "@")
-	if len(parts) == 2 {
-		return parts[1]
+// ReloadDisposableDomains reads the disposable domain list again from the
+// path given to LoadDisposableDomains, e.g. after the file was updated,
+// and registers the new contents with the dataset registry
+func ReloadDisposableDomains() (int, error) {
+	disposable.mu.RLock()
+	path := disposable.path
+	disposable.mu.RUnlock()
+
+	list := email.DefaultDisposableList()
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read disposable domain list: %w", err)
+		}
+		defer f.Close()
+		if list, err = email.ParseDisposableList(f); err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
 	}
-	return ""
+
+	disposable.mu.Lock()
+	disposable.list = list
+	disposable.mu.Unlock()
+	dataset.Register(DisposableDomainsDataset, list.Domains())
+	return list.Len(), nil
 }
 
-func isValidDomain(domain string) bool {
-	_, err := net.LookupMX(domain)
-	if err == nil {
-		return true
-	}
-	_, err = net.LookupHost(domain)
-	return err == nil
+// disposableList returns the disposable domain list in use
+func disposableList() *email.DisposableList {
+	disposable.mu.RLock()
+	defer disposable.mu.RUnlock()
+	return disposable.list
 }
 
-func verifyMxRecords(email string) bool {
-	domain := extractDomain(email)
-	if domain == "" {
-		fmt.Println("Invalid email format")
-		return false
-	}
+// ErrDomainLookupFailed reports a DNS lookup that got no answer, e.g.
+// SERVFAIL or a timeout, so whether the domain exists is unknown
+var ErrDomainLookupFailed = email.ErrDomainLookupFailed
 
-	mxRecords, err := net.LookupMX(domain)
-	if err != nil || len(mxRecords) == 0 {
-		fmt.Println("No MX records found for domain", domain)
-		return false
-	}
+// tracedResolver wraps each lookup of a resolver in a DNS span
+type tracedResolver struct {
+	dnscache.Resolver
+}
 
-	return true
+func (r tracedResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	ctx, span := tracing.Start(ctx, "dns.lookup_mx", attribute.String("dns.question.name", domain))
+	records, err := r.Resolver.LookupMX(ctx, domain)
+	tracing.End(span, err)
+	return records, err
 }
 
-func isDisposableEmail(email string) bool {
-	domain := extractDomain(email)
-	if domain == "" {
-		fmt.Println("Invalid email format")
-		return false
-	}
+func (r tracedResolver) LookupHost(ctx context.Context, domain string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "dns.lookup_host", attribute.String("dns.question.name", domain))
+	addrs, err := r.Resolver.LookupHost(ctx, domain)
+	tracing.End(span, err)
+	return addrs, err
+}
 
-	for _, disposableDomain := range disposableEmailDomains {
-		if strings.ToLower(domain) == disposableDomain {
-			return true
-		}
-	}
-	return false
+// DomainChecker runs the network checks for the domain of an email address.
+// err wraps ErrDomainLookupFailed when DNS gave no answer; the results are
+// then unknown and must not be cached.
+type DomainChecker = email.DomainChecker
+
+// ExtractDomain returns the domain part of an email address, or "" when
+// the address does not contain exactly one @
+func ExtractDomain(address string) string {
+	return email.Domain(address)
+}
+
+// CheckEmailDomain resolves the domain of email and looks up its MX records
+// with the system resolver
+func CheckEmailDomain(ctx context.Context, address string) (domainValid, mxFound bool, err error) {
+	return NewDomainChecker(net.DefaultResolver)(ctx, address)
+}
+
+// NewDomainChecker returns a DomainChecker looking domains up with
+// resolver, e.g. a dnscache.Cache
+func NewDomainChecker(resolver dnscache.Resolver) DomainChecker {
+	return email.NewDomainChecker(tracedResolver{resolver})
+}
+
+// Email validation check names accepted in EmailRequest.Checks
+const (
+	CheckSyntax     = email.CheckSyntax
+	CheckDomain     = email.CheckDomain
+	CheckMX         = email.CheckMX
+	CheckDisposable = email.CheckDisposable
+)
+
+// ErrUnsupportedCheck is returned for an unknown email check name
+var ErrUnsupportedCheck = email.ErrUnsupportedCheck
+
+// EmailChecks selects the stages ValidateEmailChecks runs
+type EmailChecks = email.Checks
+
+// AllEmailChecks runs every stage, the behavior when no checks are requested
+var AllEmailChecks = email.AllChecks
+
+// ParseEmailChecks converts check names into an EmailChecks selection.
+// An empty list selects every check.
+func ParseEmailChecks(names []string) (EmailChecks, error) {
+	return email.ParseChecks(names)
 }
 
 // ValidateEmail validates an email address with comprehensive checks
-func ValidateEmail(email string) models.EmailValidation {
-	emailValidationResult := models.EmailValidation{
-		Email:          email,
-		IsSyntaxValid:  false,
-		IsDomainValid:  false,
-		MxRecordsFound: false,
-		IsDisposable:   false,
-	}
+func ValidateEmail(ctx context.Context, address string) models.EmailValidation {
+	return ValidateEmailWith(ctx, address, CheckEmailDomain)
+}
 
-	if isValidEmailSyntax(email) {
-		emailValidationResult.IsSyntaxValid = true
-	}
+// ValidateEmailWith runs every check, using checkDomain for the network
+// checks; when checkDomain is nil they are skipped and reported in
+// SkippedChecks
+func ValidateEmailWith(ctx context.Context, address string, checkDomain DomainChecker) models.EmailValidation {
+	return ValidateEmailChecks(ctx, address, AllEmailChecks, checkDomain)
+}
 
-	domain := extractDomain(email)
-	if isValidDomain(domain) {
-		emailValidationResult.IsDomainValid = true
+// ValidateEmailChecks runs the selected checks on address. Fields of checks
+// that were not selected stay nil; skipped checks are reported to the
+// resultmeta collector of ctx. The disposable check matches the list
+// loaded by LoadDisposableDomains and the domains added by the tenant of
+// ctx.
+func ValidateEmailChecks(ctx context.Context, address string, checks EmailChecks, checkDomain DomainChecker) models.EmailValidation {
+	if checks.Disposable {
+		resultmeta.FromContext(ctx).UseRegisteredDataset(DisposableDomainsDataset)
 	}
-
-	if verifyMxRecords(email) {
-		emailValidationResult.MxRecordsFound = true
+	validator := email.New(
+		email.WithChecks(checks),
+		email.WithDomainChecker(checkDomain),
+		email.WithDisposable(disposableList()),
+		email.WithExtraDisposable(tenant.FromContext(ctx).DisposableDomains),
+		email.WithSkipHandler(reportSkippedCheck),
+	)
+	result := validator.Validate(ctx, address)
+	return models.EmailValidation{
+		Email:          result.Email,
+		IsSyntaxValid:  result.IsSyntaxValid,
+		IsDomainValid:  result.IsDomainValid,
+		MxRecordsFound: result.MxRecordsFound,
+		IsDisposable:   result.IsDisposable,
+		DNSTimedOut:    result.DNSTimedOut,
+		SkippedChecks:  result.SkippedChecks,
 	}
+}
 
-	if isDisposableEmail(email) {
-		emailValidationResult.IsDisposable = true
+// reportSkippedCheck tells the caller why a check was skipped
+func reportSkippedCheck(ctx context.Context, check string, err error) {
+	reason := "network checks are not allowed on this route"
+	switch {
+	case errors.Is(err, email.ErrNetworkDisabled):
+	case email.IsTimeout(err):
+		logging.FromContext(ctx).Warn("Email check timed out", "check", check, "error", err)
+		reason = "DNS lookup timed out; the domain could not be checked"
+	default:
+		logging.FromContext(ctx).Warn("Email check skipped", "check", check, "error", err)
+		reason = "DNS lookup failed; the domain could not be checked"
 	}
-
-	return emailValidationResult
+	resultmeta.FromContext(ctx).SkipCheck(check, reason)
 }
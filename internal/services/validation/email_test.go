@@ -0,0 +1,63 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/dataset"
+	"github.com/innovelabs/microtools-go/pkg/validate/email"
+)
+
+func TestReloadDisposableDomains(t *testing.T) {
+	t.Cleanup(func() { LoadDisposableDomains("") })
+	path := filepath.Join(t.TempDir(), "disposable.txt")
+	write := func(text string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	isDisposable := func(address string) bool {
+		t.Helper()
+		result := ValidateEmailChecks(context.Background(), address, EmailChecks{Disposable: true}, nil)
+		return *result.IsDisposable
+	}
+
+	write("# local providers\nburner.example\n")
+	if n, err := LoadDisposableDomains(path); err != nil || n != 1 {
+		t.Fatalf("LoadDisposableDomains = %d, %v, want 1 domain", n, err)
+	}
+	if !isDisposable("user@mx.Burner.example") || isDisposable("user@mailinator.com") {
+		t.Error("the file did not replace the built-in list")
+	}
+	if info, _, _ := dataset.Get(DisposableDomainsDataset); info.Entries != 1 {
+		t.Errorf("dataset has %d entries, want 1", info.Entries)
+	}
+
+	// A file that fails to parse keeps the list in use
+	write("burner.example\nnot a domain\n")
+	if _, err := ReloadDisposableDomains(); !errors.Is(err, email.ErrInvalidDisposableList) {
+		t.Errorf("ReloadDisposableDomains = %v, want ErrInvalidDisposableList", err)
+	}
+	if !isDisposable("user@burner.example") {
+		t.Error("a failed reload dropped the list in use")
+	}
+
+	write("burner.example\ntrash.example\n")
+	if n, err := ReloadDisposableDomains(); err != nil || n != 2 {
+		t.Fatalf("ReloadDisposableDomains = %d, %v, want 2 domains", n, err)
+	}
+	if !isDisposable("user@trash.example") {
+		t.Error("reload did not pick up trash.example")
+	}
+
+	if _, err := LoadDisposableDomains(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("a missing file loaded")
+	}
+	if n, err := LoadDisposableDomains(""); err != nil || n != email.DefaultDisposableList().Len() || !isDisposable("user@mailinator.com") {
+		t.Errorf("LoadDisposableDomains(\"\") = %d, %v, want the built-in list", n, err)
+	}
+}
@@ -0,0 +1,274 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/pkg/generate/barcode"
+)
+
+// gs1Separator is the ASCII group separator scanners send for FNC1 after
+// a variable-length GS1 field
+const gs1Separator = '\x1d'
+
+// gs1Kind says how the data of an application identifier is checked
+type gs1Kind int
+
+const (
+	gs1Text    gs1Kind = iota
+	gs1Numeric         // digits only
+	gs1Key             // digits ending in a GS1 mod 10 check digit
+	gs1Date            // YYMMDD, DD 00 meaning the last day of the month
+	gs1Decimal         // digits with the decimal places in the AI's last digit
+)
+
+// gs1AI describes the data of one application identifier. length is the
+// fixed data length, or 0 for variable data of at most max characters.
+type gs1AI struct {
+	title  string
+	kind   gs1Kind
+	length int
+	max    int
+}
+
+// gs1AIs is the subset of the GS1 General Specifications AI table seen on
+// logistic and retail labels. Decimal families (310n, 320n, ...) are keyed
+// by their first three digits; no key is a prefix of another.
+var gs1AIs = map[string]gs1AI{
+	"00":  {title: "SSCC", kind: gs1Key, length: 18},
+	"01":  {title: "GTIN", kind: gs1Key, length: 14},
+	"02":  {title: "CONTENT", kind: gs1Key, length: 14},
+	"10":  {title: "BATCH/LOT", kind: gs1Text, max: 20},
+	"11":  {title: "PROD DATE", kind: gs1Date, length: 6},
+	"12":  {title: "DUE DATE", kind: gs1Date, length: 6},
+	"13":  {title: "PACK DATE", kind: gs1Date, length: 6},
+	"15":  {title: "BEST BEFORE or BEST BY", kind: gs1Date, length: 6},
+	"16":  {title: "SELL BY", kind: gs1Date, length: 6},
+	"17":  {title: "USE BY OR EXPIRY", kind: gs1Date, length: 6},
+	"20":  {title: "VARIANT", kind: gs1Numeric, length: 2},
+	"21":  {title: "SERIAL", kind: gs1Text, max: 20},
+	"22":  {title: "CPV", kind: gs1Text, max: 20},
+	"240": {title: "ADDITIONAL ID", kind: gs1Text, max: 30},
+	"241": {title: "CUST. PART No.", kind: gs1Text, max: 30},
+	"250": {title: "SECONDARY SERIAL", kind: gs1Text, max: 30},
+	"30":  {title: "VAR. COUNT", kind: gs1Numeric, max: 8},
+	"310": {title: "NET WEIGHT (kg)", kind: gs1Decimal, length: 6},
+	"320": {title: "NET WEIGHT (lb)", kind: gs1Decimal, length: 6},
+	"330": {title: "GROSS WEIGHT (kg)", kind: gs1Decimal, length: 6},
+	"37":  {title: "COUNT", kind: gs1Numeric, max: 8},
+	"392": {title: "PRICE", kind: gs1Decimal, max: 15},
+	"400": {title: "ORDER NUMBER", kind: gs1Text, max: 30},
+	"410": {title: "SHIP TO LOC", kind: gs1Key, length: 13},
+	"414": {title: "LOC No.", kind: gs1Key, length: 13},
+	"420": {title: "SHIP TO POST", kind: gs1Text, max: 20},
+	"422": {title: "ORIGIN", kind: gs1Numeric, length: 3},
+}
+
+// gs1TextChars matches GS1 AI encodable character set 82
+var gs1TextChars = regexp.MustCompile(`^[!"%&'()*+,\-./0-9:;<=>?A-Z_a-z]+$`)
+
+// gs1Bracketed matches one "(AI)data" of the human-readable form
+var gs1Bracketed = regexp.MustCompile(`\((\d{2,4})\)([^(]*)`)
+
+// lookupGS1AI finds the application identifier at the start of s and
+// returns it with its definition
+func lookupGS1AI(s string) (string, gs1AI, bool) {
+	for n := 2; n <= 3 && n <= len(s); n++ {
+		def, ok := gs1AIs[s[:n]]
+		if !ok {
+			continue
+		}
+		if def.kind == gs1Decimal {
+			if len(s) < 4 || s[3] < '0' || s[3] > '9' {
+				return "", gs1AI{}, false
+			}
+			return s[:4], def, true
+		}
+		return s[:n], def, true
+	}
+	return "", gs1AI{}, false
+}
+
+// parseGS1 splits a GS1 element string into its elements and checks each.
+// It takes the human-readable form "(01)...(17)..." or the scanned form,
+// where variable-length fields end at a group separator. The second
+// result lists the problems found; the elements read so far are returned
+// with them.
+func parseGS1(value string, now time.Time) ([]models.GS1Element, []string) {
+	if strings.HasPrefix(value, "(") {
+		return parseBracketedGS1(value, now)
+	}
+
+	var (
+		elements []models.GS1Element
+		problems []string
+	)
+	s := strings.TrimLeft(value, string(gs1Separator))
+	for s != "" {
+		ai, def, ok := lookupGS1AI(s)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown application identifier at %q", truncate(s, 4)))
+			return elements, problems
+		}
+		s = s[len(ai):]
+		var data string
+		if def.length > 0 {
+			if len(s) < def.length {
+				data, s = s, ""
+			} else {
+				data, s = s[:def.length], s[def.length:]
+			}
+		} else if end := strings.IndexByte(s, gs1Separator); end >= 0 {
+			data, s = s[:end], s[end:]
+		} else {
+			data, s = s, ""
+		}
+		s = strings.TrimLeft(s, string(gs1Separator))
+		element, problem := gs1Element(ai, def, data, now)
+		elements = append(elements, element)
+		if problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	if len(elements) == 0 {
+		problems = append(problems, "no application identifiers")
+	}
+	return elements, problems
+}
+
+func parseBracketedGS1(value string, now time.Time) ([]models.GS1Element, []string) {
+	var (
+		elements []models.GS1Element
+		problems []string
+		consumed int
+	)
+	for _, m := range gs1Bracketed.FindAllStringSubmatchIndex(value, -1) {
+		if m[0] != consumed {
+			break
+		}
+		consumed = m[1]
+		ai, data := value[m[2]:m[3]], value[m[4]:m[5]]
+		found, def, ok := lookupGS1AI(ai)
+		if !ok || found != ai {
+			problems = append(problems, fmt.Sprintf("unknown application identifier (%s)", ai))
+			continue
+		}
+		element, problem := gs1Element(ai, def, data, now)
+		elements = append(elements, element)
+		if problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	if consumed != len(value) {
+		problems = append(problems, fmt.Sprintf("unreadable element string at %q", truncate(value[consumed:], 8)))
+	}
+	return elements, problems
+}
+
+// gs1Element checks the data of one AI and returns it as an element
+// with a problem description, "" when there is none
+func gs1Element(ai string, def gs1AI, data string, now time.Time) (models.GS1Element, string) {
+	element := models.GS1Element{AI: ai, Title: def.title, Value: data}
+	switch {
+	case def.length > 0 && len(data) != def.length:
+		return element, fmt.Sprintf("(%s) must be %d characters, got %d", ai, def.length, len(data))
+	case def.length == 0 && (data == "" || len(data) > def.max):
+		return element, fmt.Sprintf("(%s) must be 1 to %d characters, got %d", ai, def.max, len(data))
+	}
+	if def.kind == gs1Text {
+		if !gs1TextChars.MatchString(data) {
+			return element, fmt.Sprintf("(%s) has characters outside the GS1 character set", ai)
+		}
+		return element, ""
+	}
+	if !isAllDigits(data) {
+		return element, fmt.Sprintf("(%s) must be numeric", ai)
+	}
+	switch def.kind {
+	case gs1Key:
+		last := len(data) - 1
+		expected := barcode.GS1CheckDigit(data[:last])
+		valid := int(data[last]-'0') == expected
+		element.IsChecksumValid = &valid
+		if !valid {
+			return element, fmt.Sprintf("(%s) check digit is %c, expected %d", ai, data[last], expected)
+		}
+	case gs1Date:
+		date, ok := parseGS1Date(data, now)
+		if !ok {
+			return element, fmt.Sprintf("(%s) is not a valid YYMMDD date", ai)
+		}
+		element.Date = date.Format("2006-01-02")
+	}
+	return element, ""
+}
+
+// parseGS1Date reads a YYMMDD date. The century puts the year within 49
+// years before and 50 years after now (GS1 General Specifications 7.12),
+// and day 00 is the last day of the month.
+func parseGS1Date(data string, now time.Time) (time.Time, bool) {
+	yy, _ := strconv.Atoi(data[0:2])
+	month, _ := strconv.Atoi(data[2:4])
+	day, _ := strconv.Atoi(data[4:6])
+	if month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+	century := now.Year() / 100 * 100
+	switch diff := yy - now.Year()%100; {
+	case diff >= 51:
+		century -= 100
+	case diff <= -50:
+		century += 100
+	}
+	year := century + yy
+	last := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day == 0 {
+		day = last
+	}
+	if day > last {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), true
+}
+
+// gs1Fields lifts the common elements into their own fields
+func gs1Fields(elements []models.GS1Element) *models.GS1Fields {
+	fields := &models.GS1Fields{Elements: elements}
+	for _, e := range elements {
+		switch e.AI {
+		case "00":
+			fields.SSCC = e.Value
+		case "01":
+			fields.GTIN = e.Value
+		case "10":
+			fields.Lot = e.Value
+		case "15":
+			fields.BestBefore = e.Date
+		case "17":
+			fields.ExpiryDate = e.Date
+		case "21":
+			fields.Serial = e.Value
+		}
+	}
+	return fields
+}
+
+// gs1HumanReadable writes elements in the "(AI)data" form
+func gs1HumanReadable(elements []models.GS1Element) string {
+	var b strings.Builder
+	for _, e := range elements {
+		b.WriteString("(" + e.AI + ")" + e.Value)
+	}
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
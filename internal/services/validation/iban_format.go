@@ -0,0 +1,156 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/pkg/validate/iban"
+)
+
+// ibanGroupSize is the print-format grouping used for every country
+const ibanGroupSize = 4
+
+// ErrUnsupportedCountry is returned for a country without an IBAN spec
+var ErrUnsupportedCountry = errors.New("unsupported IBAN country")
+
+func ibanClassName(class byte) string {
+	switch class {
+	case iban.ClassDigit:
+		return "digit"
+	case iban.ClassLetter:
+		return "letter"
+	default:
+		return "letter or digit"
+	}
+}
+
+// swiftBBANStructure writes classes in SWIFT notation, e.g. "5!n12!c"
+func swiftBBANStructure(classes []byte) string {
+	var b strings.Builder
+	for i := 0; i < len(classes); {
+		j := i
+		for j < len(classes) && classes[j] == classes[i] {
+			j++
+		}
+		fmt.Fprintf(&b, "%d!%c", j-i, classes[i])
+		i = j
+	}
+	return b.String()
+}
+
+// ibanGroups returns the print-format group sizes for an IBAN of length
+func ibanGroups(length int) []int {
+	groups := make([]int, 0, (length+ibanGroupSize-1)/ibanGroupSize)
+	for length > 0 {
+		size := min(length, ibanGroupSize)
+		groups = append(groups, size)
+		length -= size
+	}
+	return groups
+}
+
+// IBANFormatRules returns the formatting rules of the country code
+func IBANFormatRules(countryCode string) (models.IBANFormatRules, error) {
+	validator, err := ibanValidator.Get()
+	if err != nil {
+		return models.IBANFormatRules{}, err
+	}
+	countryCode = strings.ToUpper(strings.TrimSpace(countryCode))
+	spec, ok := validator.Spec(countryCode)
+	if !ok {
+		return models.IBANFormatRules{}, fmt.Errorf("%w: %q", ErrUnsupportedCountry, countryCode)
+	}
+	classes := validator.Classes(countryCode)
+
+	positions := make([]string, len(classes))
+	pattern := []byte(countryCode + "kk")
+	for i, class := range classes {
+		positions[i] = string(class)
+		pattern = append(pattern, class)
+	}
+
+	return models.IBANFormatRules{
+		CountryCode:      countryCode,
+		CountryName:      spec.CountryName,
+		Length:           spec.Length,
+		Groups:           ibanGroups(spec.Length),
+		Pattern:          iban.Format(string(pattern)),
+		BBANStructure:    swiftBBANStructure(classes),
+		BBANPositions:    positions,
+		Example:          spec.Example,
+		FormattedExample: iban.Format(spec.Example),
+	}, nil
+}
+
+// FormatPartialIBAN groups a possibly incomplete IBAN for display and
+// checks the characters typed so far against the country spec position by
+// position, so errors show before the full number is entered
+func FormatPartialIBAN(input string) (models.IBANPartialFormat, error) {
+	validator, err := ibanValidator.Get()
+	if err != nil {
+		return models.IBANPartialFormat{}, err
+	}
+	clean := strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '\t' {
+			return -1
+		}
+		return r
+	}, input))
+
+	result := models.IBANPartialFormat{
+		Input:        input,
+		Formatted:    iban.Format(clean),
+		IsConsistent: true,
+	}
+	fail := func(position int, message string) (models.IBANPartialFormat, error) {
+		result.IsConsistent = false
+		result.ErrorPosition = &position
+		result.Error = message
+		return result, nil
+	}
+
+	for i := 0; i < len(clean) && i < 2; i++ {
+		if clean[i] < 'A' || clean[i] > 'Z' {
+			return fail(i, "country code must be two letters")
+		}
+	}
+	if len(clean) < 2 {
+		return result, nil
+	}
+
+	countryCode := clean[:2]
+	spec, ok := validator.Spec(countryCode)
+	if !ok {
+		return fail(0, fmt.Sprintf("%s: %s", ErrUnsupportedCountry, countryCode))
+	}
+	result.CountryCode = countryCode
+	result.CountryName = spec.CountryName
+	result.Length = spec.Length
+	remaining := max(spec.Length-len(clean), 0)
+	result.Remaining = &remaining
+
+	for i := 2; i < len(clean) && i < 4; i++ {
+		if clean[i] < '0' || clean[i] > '9' {
+			return fail(i, "check digits must be digits")
+		}
+	}
+
+	classes := validator.Classes(countryCode)
+	for i := 4; i < len(clean); i++ {
+		if i >= spec.Length {
+			return fail(i, fmt.Sprintf("%s IBANs have %d characters", countryCode, spec.Length))
+		}
+		if class := classes[i-4]; !iban.ClassMatches(class, clean[i]) {
+			return fail(i, fmt.Sprintf("character %d must be a %s", i+1, ibanClassName(class)))
+		}
+	}
+
+	if len(clean) == spec.Length {
+		result.IsComplete = true
+		checksumValid := iban.ChecksumValid(clean)
+		result.IsChecksumValid = &checksumValid
+	}
+	return result, nil
+}
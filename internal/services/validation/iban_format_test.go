@@ -0,0 +1,124 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestIBANFormatRules(t *testing.T) {
+	tests := []struct {
+		country, structure, positions string
+		length                        int
+	}{
+		{"DE", "18!n", strings.Repeat("n", 18), 22},
+		{"IT", "1!a10!n12!c", "a" + strings.Repeat("n", 10) + strings.Repeat("c", 12), 27},
+		{"MT", "4!a5!n18!c", strings.Repeat("a", 4) + strings.Repeat("n", 5) + strings.Repeat("c", 18), 31},
+	}
+	for _, tt := range tests {
+		rules, err := IBANFormatRules(strings.ToLower(tt.country))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.country, err)
+		}
+		if rules.CountryCode != tt.country || rules.Length != tt.length || rules.BBANStructure != tt.structure ||
+			strings.Join(rules.BBANPositions, "") != tt.positions {
+			t.Errorf("%s: rules = %+v", tt.country, rules)
+		}
+		sum := 0
+		for _, group := range rules.Groups {
+			sum += group
+		}
+		if sum != tt.length || len(rules.Pattern) != tt.length+len(rules.Groups)-1 {
+			t.Errorf("%s: groups %v, pattern %q", tt.country, rules.Groups, rules.Pattern)
+		}
+		if rules.Example == "" || strings.ReplaceAll(rules.FormattedExample, " ", "") != rules.Example {
+			t.Errorf("%s: example %q formatted as %q", tt.country, rules.Example, rules.FormattedExample)
+		}
+	}
+
+	if _, err := IBANFormatRules("XX"); !errors.Is(err, ErrUnsupportedCountry) {
+		t.Errorf("XX: err = %v, want ErrUnsupportedCountry", err)
+	}
+}
+
+func TestFormatPartialIBAN(t *testing.T) {
+	const none = -1
+	tests := []struct {
+		input     string
+		formatted string
+		remaining int
+		errorAt   int
+		complete  bool
+		checksum  bool
+	}{
+		{"", "", none, none, false, false},
+		{"it", "IT", 25, none, false, false},
+		{"IT60 X05", "IT60 X05", 20, none, false, false},
+		// IT BBANs open with a letter, then ten digits
+		{"IT60 105", "IT60 105", 20, 4, false, false},
+		{"IT60 X0A", "IT60 X0A", 20, 6, false, false},
+		{"IT60X0542811101000000123456", "IT60 X054 2811 1010 0000 0123 456", 0, none, true, true},
+		{"IT61X0542811101000000123456", "IT61 X054 2811 1010 0000 0123 456", 0, none, true, false},
+		// MT BBANs open with four letters, then five digits
+		{"MT84 MAL1", "MT84 MAL1", 23, 7, false, false},
+		{"MT84-MALT-011A", "MT84 MALT 011A", 19, 11, false, false},
+		{"mt84 malt 0110 0001 2345 mtlc", "MT84 MALT 0110 0001 2345 MTLC", 7, none, false, false},
+		{"MT84 MALT 0110 0001 2345 MTLC AST0 01S", "MT84 MALT 0110 0001 2345 MTLC AST0 01S", 0, none, true, true},
+		{"1T", "1T", none, 0, false, false},
+		{"XX12", "XX12", none, 0, false, false},
+		{"DE8A", "DE8A", 18, 3, false, false},
+		{"DE89 3704 0044 0532 0130 001", "DE89 3704 0044 0532 0130 001", 0, 22, false, false},
+	}
+	for _, tt := range tests {
+		got, err := FormatPartialIBAN(tt.input)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.input, err)
+		}
+		if got.Formatted != tt.formatted {
+			t.Errorf("%q: formatted = %q, want %q", tt.input, got.Formatted, tt.formatted)
+		}
+		remaining := none
+		if got.Remaining != nil {
+			remaining = *got.Remaining
+		}
+		if remaining != tt.remaining {
+			t.Errorf("%q: remaining = %d, want %d", tt.input, remaining, tt.remaining)
+		}
+		errorAt := none
+		if got.ErrorPosition != nil {
+			errorAt = *got.ErrorPosition
+		}
+		if errorAt != tt.errorAt || got.IsConsistent != (tt.errorAt == none) || (got.Error != "") != (tt.errorAt != none) {
+			t.Errorf("%q: error at %d (%q), consistent %v, want the error at %d", tt.input, errorAt, got.Error, got.IsConsistent, tt.errorAt)
+		}
+		if got.IsComplete != tt.complete || (got.IsChecksumValid != nil) != tt.complete ||
+			(got.IsChecksumValid != nil && *got.IsChecksumValid != tt.checksum) {
+			t.Errorf("%q: complete %v, checksum %v, want %v, %v", tt.input, got.IsComplete, got.IsChecksumValid, tt.complete, tt.checksum)
+		}
+	}
+}
+
+// TestIBANConcurrentFirstAccess compiles the country formats from many
+// goroutines at once when it runs first, e.g. with -run and -race
+func TestIBANConcurrentFirstAccess(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				result, err := ValidateIBAN(context.Background(), "GB82WEST12345698765432")
+				if err != nil || !result.IsValid {
+					t.Errorf("ValidateIBAN = %+v, %v", result, err)
+				}
+				return
+			}
+			if _, err := FormatPartialIBAN("GB82 WEST"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
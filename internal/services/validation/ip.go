@@ -1,44 +1,362 @@
 package validation
 
 import (
+	"context"
+	_ "embed"
 	"errors"
-	"log"
+	"fmt"
 	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/innovelabs/microtools-go/internal/models"
-	"github.com/oschwald/geoip2-golang"
+	"github.com/innovelabs/microtools-go/internal/resultmeta"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"github.com/oschwald/maxminddb-golang"
 )
 
-// ValidateIP validates an IP address and returns geolocation information
-func ValidateIP(ipStr string) (models.GeoIPResponse, error) {
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
-		return models.GeoIPResponse{}, errors.New("Invalid IP address")
+const (
+	// DefaultGeoDBPath is the GeoLite2 City database shipped with the service
+	DefaultGeoDBPath = "./assets/geolite-2-city.mmdb"
+
+	// GeoLiteDataset is the name the geolocation database is reported under in result metadata
+	GeoLiteDataset = "geolite2-city"
+
+	// GeoLiteASNDataset is the name the optional ASN database is reported
+	// under in result metadata
+	GeoLiteASNDataset = "geolite2-asn"
+
+	// GeoLiteAttribution is the notice MaxMind's license requires wherever GeoLite2 data is shown
+	GeoLiteAttribution = "This product includes GeoLite2 data created by MaxMind, available from https://www.maxmind.com."
+)
+
+// Location granularities of a lookup result
+const (
+	GeoGranularityCity    = "city"
+	GeoGranularityCountry = "country"
+	GeoGranularityNone    = "none"
+)
+
+// Where the looked up IP of a single validation came from: the request
+// body, the ?ip= query parameter, or the caller's own address when the
+// request left it empty or set it to "self"
+const (
+	IPSourceBody     = "body"
+	IPSourceQuery    = "query"
+	IPSourceInferred = "inferred"
+)
+
+var (
+	ErrInvalidIP        = errors.New("Invalid IP address")
+	ErrIPNotFound       = errors.New("IP address not found")
+	ErrGeoDBUnavailable = errors.New("geolocation database unavailable")
+)
+
+// GeoIPService defines IP geolocation and database metadata access
+type GeoIPService interface {
+	Lookup(ctx context.Context, ip string) (models.GeoIPResponse, error)
+	Metadata() (models.GeoDatabaseInfo, error)
+	// Close releases the database; later calls return ErrGeoDBUnavailable
+	Close() error
+}
+
+// cityRecord is the part of a GeoLite2 City record the lookup reports.
+// Coordinates are pointers so a record without a location decodes as nil
+// rather than 0, 0.
+type cityRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	Location struct {
+		TimeZone  string   `maxminddb:"time_zone"`
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+// asnRecord is a GeoLite2 ASN record
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// hostingASNList is one autonomous system of a hosting, cloud or CDN
+// provider per line, the number first; # starts a comment
+//
+//go:embed hosting_asns.txt
+var hostingASNList string
+
+// hostingASNs holds the numbers of hostingASNList
+var hostingASNs = parseASNs(hostingASNList)
+
+func parseASNs(list string) map[uint]bool {
+	asns := map[uint]bool{}
+	for _, line := range strings.Split(list, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		n, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			panic("hosting_asns.txt: " + err.Error())
+		}
+		asns[uint(n)] = true
+	}
+	return asns
+}
+
+// reservedNetworks are the special-purpose ranges of the IANA registries
+// (RFC 6890 and later) other than the private and loopback ones: this
+// network, shared address space, link-local, documentation, benchmarking,
+// multicast and the future-use block. No database locates them.
+var reservedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:2::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("3fff::/20"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("fec0::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// ClassifyIP reports whether ip is private (RFC 1918 or an IPv6 unique
+// local address), loopback, or in another reserved range. At most one is
+// true; IPv4-mapped IPv6 addresses are classified as IPv4.
+func ClassifyIP(ip net.IP) (private, loopback, reserved bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false, false, false
+	}
+	addr = addr.Unmap()
+	switch {
+	case addr.IsPrivate():
+		return true, false, false
+	case addr.IsLoopback():
+		return false, true, false
+	}
+	return false, false, isReserved(addr)
+}
+
+// isReserved reports whether addr is in one of reservedNetworks
+func isReserved(addr netip.Addr) bool {
+	for _, network := range reservedNetworks {
+		if network.Contains(addr) {
+			return true
+		}
 	}
+	return false
+}
+
+// GeoIPOption configures a service created by OpenGeoIPService or
+// NewGeoIPService
+type GeoIPOption func(*geoIPOptions)
+
+type geoIPOptions struct {
+	asnPath string
+}
+
+// WithASNDatabase adds the autonomous system of each address, read from
+// the GeoLite2 ASN mmdb file at path; "" leaves it out
+func WithASNDatabase(path string) GeoIPOption {
+	return func(o *geoIPOptions) {
+		o.asnPath = path
+	}
+}
 
-	db, err := geoip2.Open("./assets/geolite-2-city.mmdb")
+// mmdbGeoIPService answers from a database opened once and shared by
+// every lookup; maxminddb readers are safe for concurrent use
+type mmdbGeoIPService struct {
+	mu   sync.RWMutex
+	db   *maxminddb.Reader
+	info models.GeoDatabaseInfo
+	// asn is the optional ASN database, nil without one
+	asn     *maxminddb.Reader
+	asnInfo models.GeoDatabaseInfo
+	// err is returned by every call once the database failed to open or
+	// was closed
+	err error
+}
+
+// OpenGeoIPService opens the mmdb file at path, and the ASN database
+// when one is configured, failing with ErrGeoDBUnavailable when either is
+// missing or unreadable
+func OpenGeoIPService(path string, opts ...GeoIPOption) (GeoIPService, error) {
+	var o geoIPOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	db, err := maxminddb.Open(path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("%w: %v", ErrGeoDBUnavailable, err)
 	}
-	defer db.Close()
+	service := &mmdbGeoIPService{db: db, info: geoDatabaseInfo(db)}
+	if o.asnPath != "" {
+		if service.asn, err = maxminddb.Open(o.asnPath); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%w: ASN database: %v", ErrGeoDBUnavailable, err)
+		}
+		service.asnInfo = geoDatabaseInfo(service.asn)
+	}
+	return service, nil
+}
 
-	record, err := db.City(ip)
+// NewGeoIPService creates a geolocation service backed by the mmdb file at
+// path. A database that fails to open makes every call return the
+// ErrGeoDBUnavailable it failed with.
+func NewGeoIPService(path string, opts ...GeoIPOption) GeoIPService {
+	service, err := OpenGeoIPService(path, opts...)
 	if err != nil {
-		return models.GeoIPResponse{}, errors.New("IP address not found")
+		return &mmdbGeoIPService{err: err}
 	}
+	return service
+}
+
+// NewDefaultGeoIPService creates a geolocation service for DefaultGeoDBPath
+func NewDefaultGeoIPService() GeoIPService {
+	return NewGeoIPService(DefaultGeoDBPath)
+}
 
-	resp := models.GeoIPResponse{
-		IP:        ipStr,
-		Country:   record.Country.Names["en"],
-		Region:    "",
-		City:      record.City.Names["en"],
-		Latitude:  record.Location.Latitude,
-		Longitude: record.Location.Longitude,
-		Timezone:  record.Location.TimeZone,
+// Lookup validates an IP address and returns geolocation information. An
+// address in no database record is a NotFound result, not an error;
+// private, loopback and reserved addresses are flagged without a lookup.
+func (s *mmdbGeoIPService) Lookup(ctx context.Context, ipStr string) (resp models.GeoIPResponse, err error) {
+	_, span := tracing.Start(ctx, "geoip.lookup")
+	defer func() { tracing.End(span, err) }()
+
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return models.GeoIPResponse{}, ErrInvalidIP
 	}
-	if len(record.Subdivisions) > 0 {
-		resp.Region = record.Subdivisions[0].Names["en"]
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.err != nil {
+		return models.GeoIPResponse{}, s.err
 	}
 
+	resp = models.GeoIPResponse{IP: ipStr, Granularity: GeoGranularityNone, Meta: s.info}
+	resp.IsPrivate, resp.IsLoopback, resp.IsReserved = ClassifyIP(ip)
+	if resp.IsPrivate || resp.IsLoopback || resp.IsReserved {
+		return resp, nil
+	}
+
+	var record cityRecord
+	_, found, err := s.db.LookupNetwork(ip, &record)
+	if err != nil {
+		return models.GeoIPResponse{}, ErrIPNotFound
+	}
+	resultmeta.FromContext(ctx).UseDataset(GeoLiteDataset, resp.Meta.DatabaseDate, resp.Meta.BuiltAt)
+	s.lookupASN(ctx, ip, &resp)
+	if !found {
+		resp.NotFound = true
+		return resp, nil
+	}
+
+	resp.Country = englishName(record.Country.Names)
+	resp.City = englishName(record.City.Names)
+	if len(record.Subdivisions) > 0 {
+		resp.Region = englishName(record.Subdivisions[0].Names)
+	}
+	if record.Location.Latitude != nil && record.Location.Longitude != nil {
+		resp.Latitude = record.Location.Latitude
+		resp.Longitude = record.Location.Longitude
+	}
+	if record.Location.TimeZone != "" {
+		resp.Timezone = &record.Location.TimeZone
+	}
+	switch {
+	case resp.City != nil:
+		resp.Granularity = GeoGranularityCity
+	case resp.Country != nil:
+		resp.Granularity = GeoGranularityCountry
+	}
 	return resp, nil
 }
+
+// lookupASN adds the autonomous system of ip from the ASN database, if
+// there is one and it lists ip
+func (s *mmdbGeoIPService) lookupASN(ctx context.Context, ip net.IP, resp *models.GeoIPResponse) {
+	if s.asn == nil {
+		return
+	}
+	resultmeta.FromContext(ctx).UseDataset(GeoLiteASNDataset, s.asnInfo.DatabaseDate, s.asnInfo.BuiltAt)
+	var record asnRecord
+	if _, found, err := s.asn.LookupNetwork(ip, &record); err != nil || !found || record.Number == 0 {
+		return
+	}
+	datacenter := hostingASNs[record.Number]
+	resp.ASN = &record.Number
+	resp.IsDatacenter = &datacenter
+	if record.Organization != "" {
+		resp.Organization = &record.Organization
+	}
+}
+
+// englishName returns the English name of a record, or nil when it has none
+func englishName(names map[string]string) *string {
+	if name, ok := names["en"]; ok && name != "" {
+		return &name
+	}
+	return nil
+}
+
+// Metadata returns the build date and size of the geolocation database
+func (s *mmdbGeoIPService) Metadata() (models.GeoDatabaseInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.err != nil {
+		return models.GeoDatabaseInfo{}, s.err
+	}
+	return s.info, nil
+}
+
+// Close unmaps the databases once the lookups in flight are done
+func (s *mmdbGeoIPService) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	if s.asn != nil {
+		err = errors.Join(err, s.asn.Close())
+		s.asn = nil
+	}
+	s.db = nil
+	s.err = fmt.Errorf("%w: closed", ErrGeoDBUnavailable)
+	return err
+}
+
+func geoDatabaseInfo(db *maxminddb.Reader) models.GeoDatabaseInfo {
+	meta := db.Metadata
+	built := time.Unix(int64(meta.BuildEpoch), 0).UTC()
+	return models.GeoDatabaseInfo{
+		DatabaseType: meta.DatabaseType,
+		DatabaseDate: built.Format("2006-01-02"),
+		NodeCount:    meta.NodeCount,
+		Attribution:  GeoLiteAttribution,
+		BuiltAt:      built,
+	}
+}
@@ -0,0 +1,347 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/resultmeta"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+func TestGeoIPServiceWithoutDatabase(t *testing.T) {
+	service := NewGeoIPService(filepath.Join(t.TempDir(), "missing.mmdb"))
+	if _, err := service.Metadata(); !errors.Is(err, ErrGeoDBUnavailable) {
+		t.Errorf("Metadata err = %v, want ErrGeoDBUnavailable", err)
+	}
+	if _, err := service.Lookup(context.Background(), "8.8.8.8"); !errors.Is(err, ErrGeoDBUnavailable) {
+		t.Errorf("Lookup err = %v, want ErrGeoDBUnavailable", err)
+	}
+	if _, err := service.Lookup(context.Background(), "not an IP"); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("invalid IP err = %v, want ErrInvalidIP before the database is needed", err)
+	}
+}
+
+func TestOpenGeoIPService(t *testing.T) {
+	if _, err := OpenGeoIPService(filepath.Join(t.TempDir(), "missing.mmdb")); !errors.Is(err, ErrGeoDBUnavailable) {
+		t.Errorf("missing database: err = %v, want ErrGeoDBUnavailable", err)
+	}
+
+	service, err := OpenGeoIPService(geoFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lookups share the one reader
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if resp, err := service.Lookup(context.Background(), "81.2.69.142"); err != nil || resp.Granularity != GeoGranularityCity {
+					t.Errorf("lookup = %+v, %v", resp, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := service.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Lookup(context.Background(), "81.2.69.142"); !errors.Is(err, ErrGeoDBUnavailable) {
+		t.Errorf("lookup after Close: err = %v, want ErrGeoDBUnavailable", err)
+	}
+	if err := service.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+// geoFixture writes a database locating 81.2.69.0/24 to London
+func geoFixture(t testing.TB) string {
+	names := func(name string) map[string]interface{} {
+		return map[string]interface{}{"names": map[string]interface{}{"en": name}}
+	}
+	return writeTestMMDB(t, []mmdbNetwork{{"81.2.69.0/24", map[string]interface{}{
+		"city":     names("London"),
+		"country":  names("United Kingdom"),
+		"location": map[string]interface{}{"latitude": 51.5, "longitude": -0.13, "time_zone": "Europe/London"},
+	}}})
+}
+
+// BenchmarkGeoIPLookup compares opening the database per lookup, as the
+// service used to, with the shared reader
+func BenchmarkGeoIPLookup(b *testing.B) {
+	path := geoFixture(b)
+	ctx := context.Background()
+	b.Run("open per lookup", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			db, err := maxminddb.Open(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var record cityRecord
+			if _, _, err := db.LookupNetwork(net.ParseIP("81.2.69.142"), &record); err != nil {
+				b.Fatal(err)
+			}
+			db.Close()
+		}
+	})
+	b.Run("shared reader", func(b *testing.B) {
+		service, err := OpenGeoIPService(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer service.Close()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := service.Lookup(ctx, "81.2.69.142"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestGeoIPMetadata(t *testing.T) {
+	service := NewGeoIPService(filepath.Join("..", "..", "..", DefaultGeoDBPath))
+	info, err := service.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DatabaseDate != info.BuiltAt.Format("2006-01-02") || info.BuiltAt.IsZero() {
+		t.Errorf("database date %q, built at %v", info.DatabaseDate, info.BuiltAt)
+	}
+	if info.NodeCount == 0 || info.Attribution != GeoLiteAttribution || info.DatabaseType == "" {
+		t.Errorf("info = %+v", info)
+	}
+
+	// Every lookup carries the metadata, found or not
+	for _, ip := range []string{"8.8.8.8", "10.0.0.1"} {
+		resp, err := service.Lookup(context.Background(), ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Meta != info {
+			t.Errorf("%s: meta = %+v, want %+v", ip, resp.Meta, info)
+		}
+	}
+}
+
+func TestGeoIPGranularity(t *testing.T) {
+	names := func(name string) map[string]interface{} {
+		return map[string]interface{}{"names": map[string]interface{}{"en": name}}
+	}
+	path := writeTestMMDB(t, []mmdbNetwork{
+		{"81.2.69.0/24", map[string]interface{}{
+			"city":         names("London"),
+			"country":      names("United Kingdom"),
+			"subdivisions": []interface{}{names("England")},
+			"location":     map[string]interface{}{"latitude": 51.5, "longitude": -0.13, "time_zone": "Europe/London"},
+		}},
+		{"2.125.0.0/16", map[string]interface{}{
+			"country":  names("Japan"),
+			"location": map[string]interface{}{"latitude": 35.69, "longitude": 139.69, "time_zone": "Asia/Tokyo"},
+		}},
+		// A network known to the database with nothing to place it
+		{"5.6.7.0/24", map[string]interface{}{}},
+	})
+	service := NewGeoIPService(path)
+
+	str := func(p *string) string {
+		if p == nil {
+			return "null"
+		}
+		return *p
+	}
+	tests := []struct {
+		ip, granularity, country, region, city, timezone string
+		located, notFound                                bool
+	}{
+		{"81.2.69.142", GeoGranularityCity, "United Kingdom", "England", "London", "Europe/London", true, false},
+		{"2.125.160.216", GeoGranularityCountry, "Japan", "null", "null", "Asia/Tokyo", true, false},
+		{"5.6.7.8", GeoGranularityNone, "null", "null", "null", "null", false, false},
+		{"8.8.8.8", GeoGranularityNone, "null", "null", "null", "null", false, true},
+		// Private addresses are flagged rather than looked up
+		{"10.1.2.3", GeoGranularityNone, "null", "null", "null", "null", false, false},
+	}
+	for _, tt := range tests {
+		resp, err := service.Lookup(context.Background(), tt.ip)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.ip, err)
+		}
+		if resp.Granularity != tt.granularity || resp.NotFound != tt.notFound || (resp.Latitude != nil) != tt.located ||
+			str(resp.Country) != tt.country || str(resp.Region) != tt.region || str(resp.City) != tt.city || str(resp.Timezone) != tt.timezone {
+			t.Errorf("%s: %s, not found %v, %s / %s / %s, %s, located %v", tt.ip, resp.Granularity, resp.NotFound,
+				str(resp.Country), str(resp.Region), str(resp.City), str(resp.Timezone), resp.Latitude != nil)
+		}
+	}
+
+	// Absent fields are null in JSON, not empty strings
+	resp, _ := service.Lookup(context.Background(), "2.125.160.216")
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"granularity":"country","country":"Japan","region":null,"city":null`)) {
+		t.Errorf("JSON = %s", data)
+	}
+
+	// Invalid input stays an error rather than a not-found result
+	if _, err := service.Lookup(context.Background(), "300.1.1.1"); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("300.1.1.1: err = %v, want ErrInvalidIP", err)
+	}
+}
+
+func TestClassifyIP(t *testing.T) {
+	tests := []struct {
+		ip                          string
+		private, loopback, reserved bool
+	}{
+		{"10.1.2.3", true, false, false},
+		{"172.16.5.4", true, false, false},
+		{"192.168.1.1", true, false, false},
+		{"fd12:3456::1", true, false, false},
+		{"::ffff:10.0.0.1", true, false, false},
+		{"127.0.0.1", false, true, false},
+		{"127.255.0.9", false, true, false},
+		{"::1", false, true, false},
+		{"0.0.0.0", false, false, true},
+		{"100.64.0.1", false, false, true},
+		{"169.254.169.254", false, false, true},
+		{"192.0.2.1", false, false, true},
+		{"198.18.0.1", false, false, true},
+		{"203.0.113.9", false, false, true},
+		{"224.0.0.251", false, false, true},
+		{"255.255.255.255", false, false, true},
+		{"::", false, false, true},
+		{"2001:db8::1", false, false, true},
+		{"fe80::1", false, false, true},
+		{"ff02::1", false, false, true},
+		{"8.8.8.8", false, false, false},
+		{"172.32.0.1", false, false, false},
+		{"100.128.0.1", false, false, false},
+		{"2606:4700::1111", false, false, false},
+	}
+	for _, tt := range tests {
+		private, loopback, reserved := ClassifyIP(net.ParseIP(tt.ip))
+		if private != tt.private || loopback != tt.loopback || reserved != tt.reserved {
+			t.Errorf("%s: private %v, loopback %v, reserved %v", tt.ip, private, loopback, reserved)
+		}
+	}
+}
+
+// asnFixture writes an ASN database listing a residential network, a
+// hosting one and a private range no lookup should reach
+func asnFixture(t testing.TB) string {
+	asn := func(number uint32, organization string) map[string]interface{} {
+		return map[string]interface{}{"autonomous_system_number": number, "autonomous_system_organization": organization}
+	}
+	return writeTestMMDB(t, []mmdbNetwork{
+		{"81.2.69.0/24", asn(20712, "Andrews & Arnold Ltd")},
+		{"104.16.0.0/13", asn(13335, "CLOUDFLARENET")},
+		{"10.0.0.0/8", asn(64512, "private")},
+	})
+}
+
+func TestGeoIPASN(t *testing.T) {
+	if _, err := OpenGeoIPService(geoFixture(t), WithASNDatabase(filepath.Join(t.TempDir(), "missing.mmdb"))); !errors.Is(err, ErrGeoDBUnavailable) {
+		t.Errorf("missing ASN database: err = %v, want ErrGeoDBUnavailable", err)
+	}
+
+	service, err := OpenGeoIPService(geoFixture(t), WithASNDatabase(asnFixture(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+	tests := []struct {
+		ip           string
+		asn          uint
+		organization string
+		datacenter   bool
+		notFound     bool
+	}{
+		{"81.2.69.142", 20712, "Andrews & Arnold Ltd", false, false},
+		// Known to the ASN database only
+		{"104.18.2.3", 13335, "CLOUDFLARENET", true, true},
+		{"8.8.8.8", 0, "", false, true},
+		{"10.1.2.3", 0, "", false, false},
+	}
+	for _, tt := range tests {
+		collector := resultmeta.New()
+		resp, err := service.Lookup(resultmeta.NewContext(context.Background(), collector), tt.ip)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.ip, err)
+		}
+		if resp.NotFound != tt.notFound {
+			t.Errorf("%s: not found %v, want %v", tt.ip, resp.NotFound, tt.notFound)
+		}
+		if tt.asn == 0 {
+			if resp.ASN != nil || resp.Organization != nil || resp.IsDatacenter != nil {
+				t.Errorf("%s: ASN fields set: %+v", tt.ip, resp)
+			}
+			continue
+		}
+		if resp.ASN == nil || *resp.ASN != tt.asn || resp.Organization == nil || *resp.Organization != tt.organization ||
+			resp.IsDatacenter == nil || *resp.IsDatacenter != tt.datacenter {
+			t.Errorf("%s: %+v", tt.ip, resp)
+		}
+		var datasets []string
+		for _, d := range collector.Meta(0).Datasets {
+			datasets = append(datasets, d.Name)
+		}
+		if !slices.Contains(datasets, GeoLiteASNDataset) {
+			t.Errorf("%s: datasets %v, want %s among them", tt.ip, datasets, GeoLiteASNDataset)
+		}
+	}
+
+	// Without an ASN database the fields are left out of the JSON
+	resp, err := NewGeoIPService(geoFixture(t)).Lookup(context.Background(), "81.2.69.142")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"asn", "organization", "isDatacenter", "isPrivate"} {
+		if bytes.Contains(data, []byte(`"`+key+`"`)) {
+			t.Errorf("JSON has %s: %s", key, data)
+		}
+	}
+}
+
+func TestGeoIPSkipsNonPublicAddresses(t *testing.T) {
+	service := NewGeoIPService(geoFixture(t))
+	tests := []struct {
+		ip                          string
+		private, loopback, reserved bool
+	}{
+		{"192.168.0.10", true, false, false},
+		{"127.0.0.1", false, true, false},
+		{"169.254.169.254", false, false, true},
+		{"2001:db8::1", false, false, true},
+	}
+	for _, tt := range tests {
+		collector := resultmeta.New()
+		resp, err := service.Lookup(resultmeta.NewContext(context.Background(), collector), tt.ip)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.ip, err)
+		}
+		if resp.NotFound || resp.Granularity != GeoGranularityNone || resp.IsPrivate != tt.private ||
+			resp.IsLoopback != tt.loopback || resp.IsReserved != tt.reserved || resp.Meta.DatabaseType == "" {
+			t.Errorf("%s: %+v", tt.ip, resp)
+		}
+		if datasets := collector.Meta(0).Datasets; len(datasets) != 0 {
+			t.Errorf("%s: datasets %v, want none as no database was read", tt.ip, datasets)
+		}
+	}
+}
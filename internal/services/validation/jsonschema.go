@@ -0,0 +1,242 @@
+package validation
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	JSONSchemaDraft2020 = "2020-12"
+	JSONSchemaDraft7    = "draft-07"
+
+	maxJSONSchemaBytes    = 256 * 1024
+	maxJSONDocumentBytes  = 1024 * 1024
+	maxJSONSchemaDepth    = 64
+	maxJSONSchemaDocs     = 100
+	maxJSONSchemaCacheLen = 128
+
+	jsonSchemaResourceURL = "mem://microtools/schema.json"
+)
+
+var (
+	ErrInvalidSchema   = errors.New("invalid schema")
+	ErrInvalidDocument = errors.New("invalid document")
+	errRemoteRef       = errors.New("remote $ref resolution is disabled; use embedded $defs")
+)
+
+var (
+	jsonSchemaCacheMu sync.Mutex
+	jsonSchemaCache   = map[string]*jsonschema.Schema{}
+
+	jsonSchemaPrinter = message.NewPrinter(language.English)
+)
+
+// rejectingLoader refuses every external resource so schemas can only
+// reference their own embedded definitions.
+type rejectingLoader struct{}
+
+func (rejectingLoader) Load(url string) (any, error) {
+	return nil, errRemoteRef
+}
+
+// ValidateJSONSchema validates one or more documents against a JSON Schema
+func ValidateJSONSchema(req models.JSONSchemaRequest) (models.JSONSchemaValidation, error) {
+	docs := req.Documents
+	if len(req.Document) > 0 {
+		docs = append([]json.RawMessage{req.Document}, docs...)
+	}
+	if len(docs) == 0 {
+		return models.JSONSchemaValidation{}, fmt.Errorf("%w: document or documents is required", ErrInvalidDocument)
+	}
+	if len(docs) > maxJSONSchemaDocs {
+		return models.JSONSchemaValidation{}, fmt.Errorf("%w: at most %d documents are allowed", ErrInvalidDocument, maxJSONSchemaDocs)
+	}
+
+	schema, err := compileJSONSchema(req.Schema, req.Draft)
+	if err != nil {
+		return models.JSONSchemaValidation{}, err
+	}
+
+	result := models.JSONSchemaValidation{
+		IsValid: true,
+		Results: make([]models.JSONSchemaDocumentResult, 0, len(docs)),
+	}
+	for i, raw := range docs {
+		docResult, err := validateJSONDocument(schema, raw)
+		if err != nil {
+			return models.JSONSchemaValidation{}, fmt.Errorf("documents[%d]: %w", i, err)
+		}
+		docResult.Index = i
+		result.IsValid = result.IsValid && docResult.IsValid
+		result.Results = append(result.Results, docResult)
+	}
+	return result, nil
+}
+
+func compileJSONSchema(raw json.RawMessage, draft string) (*jsonschema.Schema, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: schema is required", ErrInvalidSchema)
+	}
+	if len(raw) > maxJSONSchemaBytes {
+		return nil, fmt.Errorf("%w: schema exceeds %d bytes", ErrInvalidSchema, maxJSONSchemaBytes)
+	}
+	if jsonDepth(raw) > maxJSONSchemaDepth {
+		return nil, fmt.Errorf("%w: schema nesting exceeds depth %d", ErrInvalidSchema, maxJSONSchemaDepth)
+	}
+
+	if draft == "" {
+		draft = JSONSchemaDraft2020
+	}
+	var defaultDraft *jsonschema.Draft
+	switch draft {
+	case JSONSchemaDraft2020:
+		defaultDraft = jsonschema.Draft2020
+	case JSONSchemaDraft7:
+		defaultDraft = jsonschema.Draft7
+	default:
+		return nil, fmt.Errorf("%w: draft must be %s or %s", ErrInvalidSchema, JSONSchemaDraft2020, JSONSchemaDraft7)
+	}
+
+	sum := sha256.Sum256(append([]byte(draft+"\x00"), raw...))
+	key := hex.EncodeToString(sum[:])
+
+	jsonSchemaCacheMu.Lock()
+	cached, ok := jsonSchemaCache[key]
+	jsonSchemaCacheMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(defaultDraft)
+	compiler.UseLoader(rejectingLoader{})
+	if err := compiler.AddResource(jsonSchemaResourceURL, doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	schema, err := compiler.Compile(jsonSchemaResourceURL)
+	var loadErr *jsonschema.LoadURLError
+	if errors.As(err, &loadErr) && errors.Is(loadErr.Err, errRemoteRef) {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidSchema, loadErr.URL, errRemoteRef)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	jsonSchemaCacheMu.Lock()
+	if len(jsonSchemaCache) >= maxJSONSchemaCacheLen {
+		jsonSchemaCache = map[string]*jsonschema.Schema{}
+	}
+	jsonSchemaCache[key] = schema
+	jsonSchemaCacheMu.Unlock()
+
+	return schema, nil
+}
+
+func validateJSONDocument(schema *jsonschema.Schema, raw json.RawMessage) (models.JSONSchemaDocumentResult, error) {
+	if len(raw) > maxJSONDocumentBytes {
+		return models.JSONSchemaDocumentResult{}, fmt.Errorf("%w: document exceeds %d bytes", ErrInvalidDocument, maxJSONDocumentBytes)
+	}
+	if jsonDepth(raw) > maxJSONSchemaDepth {
+		return models.JSONSchemaDocumentResult{}, fmt.Errorf("%w: document nesting exceeds depth %d", ErrInvalidDocument, maxJSONSchemaDepth)
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return models.JSONSchemaDocumentResult{}, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+
+	result := models.JSONSchemaDocumentResult{IsValid: true, Violations: []models.JSONSchemaViolation{}}
+	err = schema.Validate(doc)
+	if err == nil {
+		return result, nil
+	}
+
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return models.JSONSchemaDocumentResult{}, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	result.IsValid = false
+	collectJSONSchemaViolations(verr, &result.Violations)
+	return result, nil
+}
+
+// collectJSONSchemaViolations flattens the error tree into its leaf failures,
+// which are the ones that name the keyword that actually rejected the value.
+func collectJSONSchemaViolations(verr *jsonschema.ValidationError, out *[]models.JSONSchemaViolation) {
+	if len(verr.Causes) > 0 {
+		for _, cause := range verr.Causes {
+			collectJSONSchemaViolations(cause, out)
+		}
+		return
+	}
+
+	keywordPath := verr.ErrorKind.KeywordPath()
+	keyword := ""
+	if len(keywordPath) > 0 {
+		keyword = keywordPath[len(keywordPath)-1]
+	}
+
+	schemaPointer := ""
+	if i := strings.IndexByte(verr.SchemaURL, '#'); i >= 0 {
+		schemaPointer = verr.SchemaURL[i+1:]
+	}
+	schemaPointer += jsonPointer(keywordPath)
+
+	*out = append(*out, models.JSONSchemaViolation{
+		InstancePointer: jsonPointer(verr.InstanceLocation),
+		SchemaPointer:   schemaPointer,
+		Keyword:         keyword,
+		Message:         verr.ErrorKind.LocalizedString(jsonSchemaPrinter),
+	})
+}
+
+func jsonPointer(tokens []string) string {
+	var sb strings.Builder
+	for _, tok := range tokens {
+		sb.WriteByte('/')
+		tok = strings.ReplaceAll(tok, "~", "~0")
+		sb.WriteString(strings.ReplaceAll(tok, "/", "~1"))
+	}
+	return sb.String()
+}
+
+// jsonDepth returns the maximum object/array nesting depth of raw JSON
+func jsonDepth(raw []byte) int {
+	depth, max := 0, 0
+	inString, escaped := false, false
+	for _, c := range raw {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{' || c == '[':
+			depth++
+			if depth > max {
+				max = depth
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return max
+}
@@ -0,0 +1,182 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+const personSchema = `{
+	"type": "object",
+	"required": ["name", "age"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"age": {"$ref": "#/$defs/age"},
+		"tags": {"type": "array", "items": {"type": "string"}}
+	},
+	"$defs": {"age": {"type": "integer", "minimum": 0}}
+}`
+
+func TestValidateJSONSchema(t *testing.T) {
+	req := models.JSONSchemaRequest{
+		Schema:   json.RawMessage(personSchema),
+		Document: json.RawMessage(`{"name": "Ada", "age": 36}`),
+		Documents: []json.RawMessage{
+			json.RawMessage(`{"name": "", "age": -1, "extra": true}`),
+			json.RawMessage(`{"name": "Bob", "age": 1, "tags": ["a", 2]}`),
+		},
+	}
+	result, err := ValidateJSONSchema(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsValid || len(result.Results) != 3 {
+		t.Fatalf("result = %+v, want three results, not all valid", result)
+	}
+	if first := result.Results[0]; !first.IsValid || first.Index != 0 || len(first.Violations) != 0 {
+		t.Errorf("document = %+v, want valid", first)
+	}
+
+	keywords := map[string]string{}
+	for _, v := range result.Results[1].Violations {
+		keywords[v.InstancePointer+" "+v.Keyword] = v.SchemaPointer
+	}
+	for _, want := range []string{"/name minLength", "/age minimum", " additionalProperties"} {
+		if _, ok := keywords[want]; !ok {
+			t.Errorf("documents[0] violations %v lack %q", keywords, want)
+		}
+	}
+	if got := keywords["/age minimum"]; got != "/$defs/age/minimum" {
+		t.Errorf("schema pointer of the $ref violation = %q, want /$defs/age/minimum", got)
+	}
+
+	violations := result.Results[2].Violations
+	if len(violations) != 1 || violations[0].InstancePointer != "/tags/1" || violations[0].Keyword != "type" {
+		t.Errorf("documents[1] violations = %+v, want the type of /tags/1", violations)
+	}
+}
+
+func TestValidateJSONSchemaDrafts(t *testing.T) {
+	// prefixItems exists in 2020-12 only; draft-07 ignores it
+	schema := json.RawMessage(`{"type": "array", "prefixItems": [{"type": "string"}]}`)
+	doc := json.RawMessage(`[1]`)
+	for draft, valid := range map[string]bool{"": false, JSONSchemaDraft2020: false, JSONSchemaDraft7: true} {
+		result, err := ValidateJSONSchema(models.JSONSchemaRequest{Schema: schema, Document: doc, Draft: draft})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.IsValid != valid {
+			t.Errorf("draft %q: isValid = %v, want %v", draft, result.IsValid, valid)
+		}
+	}
+}
+
+// TestJSONSchemaTestSuite runs the slice of the official
+// JSON-Schema-Test-Suite vendored under testdata, one request per group
+func TestJSONSchemaTestSuite(t *testing.T) {
+	for dir, draft := range map[string]string{"draft2020-12": JSONSchemaDraft2020, "draft7": JSONSchemaDraft7} {
+		files, err := filepath.Glob(filepath.Join("testdata", "JSON-Schema-Test-Suite", "tests", dir, "*.json"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("no suite files for %s: %v", dir, err)
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var groups []struct {
+				Description string
+				Schema      json.RawMessage
+				Tests       []struct {
+					Description string
+					Data        json.RawMessage
+					Valid       bool
+				}
+			}
+			if err := json.Unmarshal(data, &groups); err != nil {
+				t.Fatalf("%s: %v", file, err)
+			}
+			for _, group := range groups {
+				req := models.JSONSchemaRequest{Schema: group.Schema, Draft: draft}
+				for _, test := range group.Tests {
+					req.Documents = append(req.Documents, test.Data)
+				}
+				result, err := ValidateJSONSchema(req)
+				if err != nil {
+					t.Errorf("%s: %s: %v", file, group.Description, err)
+					continue
+				}
+				for i, test := range group.Tests {
+					if result.Results[i].IsValid != test.Valid {
+						t.Errorf("%s: %s: %s: isValid = %v, want %v", file, group.Description, test.Description, result.Results[i].IsValid, test.Valid)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestValidateJSONSchemaErrors(t *testing.T) {
+	valid := json.RawMessage(`{"type": "string"}`)
+	doc := json.RawMessage(`"x"`)
+	tooMany := make([]json.RawMessage, maxJSONSchemaDocs+1)
+	for i := range tooMany {
+		tooMany[i] = doc
+	}
+	tests := []struct {
+		name string
+		req  models.JSONSchemaRequest
+		want error
+	}{
+		{"no schema", models.JSONSchemaRequest{Document: doc}, ErrInvalidSchema},
+		{"schema is not JSON", models.JSONSchemaRequest{Schema: json.RawMessage(`{`), Document: doc}, ErrInvalidSchema},
+		{"unknown keyword type", models.JSONSchemaRequest{Schema: json.RawMessage(`{"type": "text"}`), Document: doc}, ErrInvalidSchema},
+		{"unknown draft", models.JSONSchemaRequest{Schema: valid, Document: doc, Draft: "draft-04"}, ErrInvalidSchema},
+		{"remote $ref", models.JSONSchemaRequest{Schema: json.RawMessage(`{"$ref": "https://example.com/s.json"}`), Document: doc}, errRemoteRef},
+		{"nested remote $ref", models.JSONSchemaRequest{Schema: json.RawMessage(`{"items": {"$ref": "https://example.com/s.json#/x"}}`), Document: doc}, errRemoteRef},
+		{"too deep", models.JSONSchemaRequest{Schema: json.RawMessage(strings.Repeat(`{"not":`, 70) + `{}` + strings.Repeat(`}`, 70)), Document: doc}, ErrInvalidSchema},
+		{"no document", models.JSONSchemaRequest{Schema: valid}, ErrInvalidDocument},
+		{"too many documents", models.JSONSchemaRequest{Schema: valid, Documents: tooMany}, ErrInvalidDocument},
+		{"document is not JSON", models.JSONSchemaRequest{Schema: valid, Document: json.RawMessage(`"x`)}, ErrInvalidDocument},
+	}
+	for _, tt := range tests {
+		if _, err := ValidateJSONSchema(tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestJSONSchemaCacheNormalizesDraft(t *testing.T) {
+	raw := json.RawMessage(`{"type": "string", "description": "cache key test"}`)
+	first, err := compileJSONSchema(raw, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := compileJSONSchema(raw, JSONSchemaDraft2020)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("the default draft and 2020-12 compiled the same schema twice")
+	}
+}
+
+func TestJSONDepth(t *testing.T) {
+	tests := map[string]int{
+		`1`:                    0,
+		`{"a": [1, {"b": 2}]}`: 3,
+		`"[[[{{{"`:             0,
+		`["\"[", [[]]]`:        3,
+	}
+	for raw, want := range tests {
+		if got := jsonDepth([]byte(raw)); got != want {
+			t.Errorf("jsonDepth(%s) = %d, want %d", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// mmdbNetwork is one network of a fixture database and its record
+type mmdbNetwork struct {
+	prefix string
+	record map[string]interface{}
+}
+
+// writeTestMMDB writes an IPv4 MaxMind DB with 24-bit records holding
+// networks and returns its path. Addresses outside them have no record.
+func writeTestMMDB(t testing.TB, networks []mmdbNetwork) string {
+	t.Helper()
+	type node struct {
+		next [2]*node
+		data [2]int // offset in the data section plus one, or zero
+	}
+	root := &node{}
+	var data []byte
+	for _, network := range networks {
+		prefix := netip.MustParsePrefix(network.prefix)
+		ip := prefix.Addr().As4()
+		offset := len(data)
+		data = append(data, encodeMMDB(network.record)...)
+		n := root
+		for i := 0; i < prefix.Bits(); i++ {
+			bit := int(ip[i/8]>>(7-i%8)) & 1
+			if i == prefix.Bits()-1 {
+				n.data[bit] = offset + 1
+				break
+			}
+			if n.next[bit] == nil {
+				n.next[bit] = &node{}
+			}
+			n = n.next[bit]
+		}
+	}
+
+	// Number the nodes breadth first, then write each as two records: a
+	// node number, the node count for no data, or a data pointer past the
+	// 16-byte separator
+	nodes := []*node{root}
+	for i := 0; i < len(nodes); i++ {
+		for _, next := range nodes[i].next {
+			if next != nil {
+				nodes = append(nodes, next)
+			}
+		}
+	}
+	count := len(nodes)
+	var file bytes.Buffer
+	for _, n := range nodes {
+		for bit := range 2 {
+			value := count
+			switch {
+			case n.next[bit] != nil:
+				value = slices.Index(nodes, n.next[bit])
+			case n.data[bit] != 0:
+				value = count + 16 + n.data[bit] - 1
+			}
+			file.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	file.Write(make([]byte, 16))
+	file.Write(data)
+	file.WriteString("\xab\xcd\xefMaxMind.com")
+	file.Write(encodeMMDB(map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1735689600), // 2025-01-01
+		"database_type":               "GeoLite2-City",
+		"description":                 map[string]interface{}{"en": "test fixture"},
+		"ip_version":                  uint16(4),
+		"languages":                   []interface{}{"en"},
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(24),
+	}))
+
+	path := filepath.Join(t.TempDir(), "fixture.mmdb")
+	if err := os.WriteFile(path, file.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// encodeMMDB encodes v in the MaxMind DB data format. Sizes must stay
+// below 285, which the fixtures do: from 29 on one extra byte holds them.
+func encodeMMDB(v interface{}) []byte {
+	control := func(kind, size int) []byte {
+		var extra []byte
+		if size >= 29 {
+			extra = []byte{byte(size - 29)}
+			size = 29
+		}
+		if kind > 7 {
+			return append([]byte{byte(size), byte(kind - 7)}, extra...)
+		}
+		return append([]byte{byte(kind<<5 | size)}, extra...)
+	}
+	unsigned := func(kind int, n uint64, width int) []byte {
+		b := binary.BigEndian.AppendUint64(nil, n)[8-width:]
+		for len(b) > 0 && b[0] == 0 {
+			b = b[1:]
+		}
+		return append(control(kind, len(b)), b...)
+	}
+	switch v := v.(type) {
+	case string:
+		return append(control(2, len(v)), v...)
+	case float64:
+		return binary.BigEndian.AppendUint64(control(3, 8), math.Float64bits(v))
+	case uint16:
+		return unsigned(5, uint64(v), 2)
+	case uint32:
+		return unsigned(6, uint64(v), 4)
+	case uint64:
+		return unsigned(9, v, 8)
+	case []interface{}:
+		out := control(11, len(v))
+		for _, item := range v {
+			out = append(out, encodeMMDB(item)...)
+		}
+		return out
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		out := control(7, len(v))
+		for _, key := range keys {
+			out = append(out, encodeMMDB(key)...)
+			out = append(out, encodeMMDB(v[key])...)
+		}
+		return out
+	}
+	panic("encodeMMDB: unsupported value")
+}
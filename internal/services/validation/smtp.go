@@ -0,0 +1,298 @@
+package validation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/textproto"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/dnscache"
+	"github.com/innovelabs/microtools-go/internal/logging"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/redact"
+	"github.com/innovelabs/microtools-go/internal/tracing"
+	"github.com/innovelabs/microtools-go/pkg/validate/email"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// SMTPCheckName names the SMTP mailbox check in skipped checks
+	SMTPCheckName = "smtp"
+
+	// SMTPCommandTimeout bounds the connection to a mail server and each
+	// of its replies
+	SMTPCommandTimeout = 3 * time.Second
+	// DefaultSMTPDeadline bounds a whole mailbox check when SMTPOptions
+	// sets none
+	DefaultSMTPDeadline = 10 * time.Second
+
+	smtpPort = "25"
+)
+
+// errPrivateMailServer refuses connections to mail servers outside the
+// public internet
+var errPrivateMailServer = errors.New("mail server address is not public")
+
+// errNoMailServer means the domain has no MX record, or the null MX of
+// RFC 7505 saying it accepts no mail
+var errNoMailServer = errors.New("no mail server")
+
+// SMTPDialer connects to mail servers. *net.Dialer implements it; tests
+// dial a fake server.
+type SMTPDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// NewSMTPDialer returns a dialer refusing loopback, private, link-local,
+// multicast and other reserved addresses, shared address space
+// (100.64.0.0/10, carrier-grade NAT) included, so an MX record cannot
+// point mailbox checks into the network the service runs in
+func NewSMTPDialer() *net.Dialer {
+	return &net.Dialer{Control: func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip, err := netip.ParseAddr(host)
+		if err != nil {
+			return err
+		}
+		ip = ip.Unmap()
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || ip.IsMulticast() || isReserved(ip) {
+			return fmt.Errorf("%w: %s", errPrivateMailServer, ip)
+		}
+		return nil
+	}}
+}
+
+// SMTPOptions configures an SMTPVerifier
+type SMTPOptions struct {
+	// HeloName is the host name sent with HELO
+	HeloName string
+	// Deadline bounds a whole check, however many commands the server
+	// stretches to SMTPCommandTimeout; 0 is DefaultSMTPDeadline
+	Deadline time.Duration
+}
+
+// SMTPVerifier asks the mail server of an address's domain whether it
+// accepts mail for the address, without sending any: it stops after RCPT
+// TO. The null sender is used in MAIL FROM, like bounces and the
+// callouts of other mail servers.
+type SMTPVerifier struct {
+	resolver dnscache.Resolver
+	dialer   SMTPDialer
+	opts     SMTPOptions
+}
+
+// NewSMTPVerifier creates an SMTPVerifier looking MX records up with
+// resolver and connecting with dialer
+func NewSMTPVerifier(resolver dnscache.Resolver, dialer SMTPDialer, opts SMTPOptions) *SMTPVerifier {
+	if opts.Deadline <= 0 {
+		opts.Deadline = DefaultSMTPDeadline
+	}
+	return &SMTPVerifier{resolver: tracedResolver{resolver}, dialer: dialer, opts: opts}
+}
+
+// Verify connects to the highest-priority MX of address's domain on port
+// 25 and reports whether it accepts RCPT TO for address and for a random
+// local part of the domain. Replies deferring the answer (4xx, e.g.
+// greylisting), policy refusals and servers that do not answer within
+// the deadline leave the results unknown.
+func (v *SMTPVerifier) Verify(ctx context.Context, address string) models.SMTPCheck {
+	if !email.IsSyntaxValid(address) {
+		return models.SMTPCheck{SMTPMessage: "the address is not valid"}
+	}
+	domain := strings.ToLower(ExtractDomain(address))
+	ctx, cancel := context.WithTimeout(ctx, v.opts.Deadline)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "smtp.verify", attribute.String("email.domain", domain))
+
+	host, err := v.mailHost(ctx, domain)
+	if errors.Is(err, errNoMailServer) {
+		span.End()
+		return models.SMTPCheck{SMTPMessage: "the domain has no mail server"}
+	}
+	if err != nil {
+		tracing.End(span, err)
+		logging.FromContext(ctx).Warn("SMTP check MX lookup failed", "domain", domain, "error", err)
+		return models.SMTPCheck{SMTPMessage: "the mail server could not be looked up"}
+	}
+	span.SetAttributes(attribute.String("server.address", host))
+
+	check, err := v.converse(ctx, host, address, domain)
+	tracing.End(span, err)
+	if err != nil {
+		logging.FromContext(ctx).Warn("SMTP check failed", "host", host, "error", err)
+	}
+	return check
+}
+
+// mailHost returns the MX host of domain with the lowest preference
+func (v *SMTPVerifier) mailHost(ctx context.Context, domain string) (string, error) {
+	records, err := v.resolver.LookupMX(ctx, domain)
+	if email.IsNotFound(err) {
+		return "", errNoMailServer
+	}
+	if err != nil {
+		return "", err
+	}
+	records = slices.DeleteFunc(slices.Clone(records), func(mx *net.MX) bool {
+		return mx == nil || strings.TrimSuffix(mx.Host, ".") == ""
+	})
+	if len(records) == 0 {
+		return "", errNoMailServer
+	}
+	best := slices.MinFunc(records, func(a, b *net.MX) int { return int(a.Pref) - int(b.Pref) })
+	return strings.TrimSuffix(best.Host, "."), nil
+}
+
+// converse runs the SMTP session with host. err is a connection or
+// protocol failure; refusals are answers, reported in the check.
+func (v *SMTPVerifier) converse(ctx context.Context, host, address, domain string) (models.SMTPCheck, error) {
+	check := models.SMTPCheck{Attempted: true}
+	dialCtx, cancel := context.WithTimeout(ctx, SMTPCommandTimeout)
+	conn, err := v.dialer.DialContext(dialCtx, "tcp", net.JoinHostPort(host, smtpPort))
+	cancel()
+	if err != nil {
+		check.SMTPMessage = "could not connect to the mail server"
+		return check, err
+	}
+	defer conn.Close()
+	// The per-command deadlines never pass the context's, but a canceled
+	// request must not wait for them either
+	defer context.AfterFunc(ctx, func() { conn.Close() })()
+	s := &smtpSession{ctx: ctx, conn: conn, text: textproto.NewConn(conn)}
+
+	for _, cmd := range []string{"", "HELO " + v.opts.HeloName, "MAIL FROM:<>"} {
+		code, msg, err := s.cmd(cmd)
+		if err != nil {
+			check.SMTPMessage = "the mail server did not answer"
+			return check, err
+		}
+		if code/100 != 2 {
+			check.SMTPMessage = scrubAddress(fmt.Sprintf("%d %s", code, msg), address)
+			return check, nil
+		}
+	}
+
+	code, msg, err := s.cmd("RCPT TO:<" + address + ">")
+	if err != nil {
+		check.SMTPMessage = "the mail server did not answer"
+		return check, err
+	}
+	check.SMTPMessage = scrubAddress(fmt.Sprintf("%d %s", code, msg), address)
+	switch {
+	case code/100 == 2:
+		check.MailboxExists = boolPtr(true)
+	case rejectsMailbox(code, msg):
+		check.MailboxExists = boolPtr(false)
+		// The server tells addresses apart, so it is no catch-all
+		check.CatchAll = boolPtr(false)
+		s.quit()
+		return check, nil
+	default:
+		s.quit()
+		return check, nil
+	}
+
+	code, msg, err = s.cmd("RCPT TO:<" + randomLocalPart() + "@" + domain + ">")
+	switch {
+	case err != nil:
+		// The address was accepted; only the probe went unanswered
+		return check, nil
+	case code/100 == 2:
+		// A server accepting any local part says nothing of this one
+		check.CatchAll = boolPtr(true)
+		check.MailboxExists = nil
+	case rejectsMailbox(code, msg):
+		check.CatchAll = boolPtr(false)
+	}
+	s.quit()
+	return check, nil
+}
+
+// smtpSession sends commands and reads replies, each within
+// SMTPCommandTimeout and the deadline of ctx
+type smtpSession struct {
+	ctx  context.Context
+	conn net.Conn
+	text *textproto.Conn
+}
+
+// cmd sends line, or only reads the greeting for "", and returns the
+// reply
+func (s *smtpSession) cmd(line string) (int, string, error) {
+	deadline := time.Now().Add(SMTPCommandTimeout)
+	if d, ok := s.ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := s.conn.SetDeadline(deadline); err != nil {
+		return 0, "", err
+	}
+	if line != "" {
+		if err := s.text.PrintfLine("%s", line); err != nil {
+			return 0, "", err
+		}
+	}
+	code, msg, err := s.text.ReadResponse(0)
+	if err != nil {
+		if ctxErr := s.ctx.Err(); ctxErr != nil {
+			return 0, "", fmt.Errorf("%w: %w", ctxErr, err)
+		}
+		return 0, "", err
+	}
+	return code, msg, nil
+}
+
+// quit ends the session politely; the reply does not matter
+func (s *smtpSession) quit() {
+	s.cmd("QUIT")
+}
+
+// rejectsMailbox reports whether a RCPT TO reply says the mailbox does not
+// exist. 5.7.x enhanced status codes are refusals by policy, e.g. of a
+// blocklisted client, which say nothing of the mailbox.
+func rejectsMailbox(code int, msg string) bool {
+	switch code {
+	case 550, 551, 553:
+		return !strings.HasPrefix(msg, "5.7.")
+	}
+	return false
+}
+
+// randomLocalPart returns a local part no mailbox has, to probe for
+// catch-all servers
+func randomLocalPart() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return "mt-probe-" + hex.EncodeToString(b[:])
+}
+
+// scrubAddress masks the local part of address where a reply repeats it
+func scrubAddress(msg, address string) string {
+	var b strings.Builder
+	for i := 0; i+len(address) <= len(msg); {
+		if strings.EqualFold(msg[i:i+len(address)], address) {
+			b.WriteString(redact.Email(address))
+			msg = msg[i+len(address):]
+			i = 0
+			continue
+		}
+		b.WriteByte(msg[i])
+		msg = msg[1:]
+	}
+	b.WriteString(msg)
+	return b.String()
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
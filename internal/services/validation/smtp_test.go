@@ -0,0 +1,130 @@
+package validation_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+func newSMTPVerifier(deadline time.Duration) (*validation.SMTPVerifier, *testutil.SMTPServer, *testutil.Resolver) {
+	resolver := testutil.NewResolver()
+	resolver.MX["example.com"] = []*net.MX{
+		{Host: "backup.example.com.", Pref: 20},
+		{Host: "mx.example.com.", Pref: 10},
+	}
+	server := testutil.NewSMTPServer()
+	return validation.NewSMTPVerifier(resolver, server, validation.SMTPOptions{HeloName: "api.test", Deadline: deadline}), server, resolver
+}
+
+func tristate(b *bool) string {
+	if b == nil {
+		return "unknown"
+	}
+	if *b {
+		return "true"
+	}
+	return "false"
+}
+
+func TestSMTPVerify(t *testing.T) {
+	tests := []struct {
+		name       string
+		recipients map[string]string
+		def        string
+		exists     string
+		catchAll   string
+		message    string
+	}{
+		{"accepted", map[string]string{"ada@example.com": "250 2.1.5 Ok"}, "", "true", "false", "250 2.1.5 Ok"},
+		{"rejected", nil, "550 5.1.1 <Ada@Example.com>: Recipient address rejected", "false", "false", "550 5.1.1 <***@example.com>: Recipient address rejected"},
+		{"greylisted", nil, "450 4.2.0 Greylisted, try again later", "unknown", "unknown", "450 4.2.0 Greylisted, try again later"},
+		{"policy refusal", nil, "550 5.7.1 Client host blocked", "unknown", "unknown", "550 5.7.1 Client host blocked"},
+		{"catch-all", nil, "250 2.1.5 Ok", "unknown", "true", "250 2.1.5 Ok"},
+		{"probe greylisted", map[string]string{"ada@example.com": "250 2.1.5 Ok"}, "451 4.7.1 Try later", "true", "unknown", "250 2.1.5 Ok"},
+	}
+	for _, tt := range tests {
+		verifier, server, _ := newSMTPVerifier(time.Second)
+		if tt.recipients != nil {
+			server.Recipients = tt.recipients
+		}
+		server.Default = tt.def
+		check := verifier.Verify(context.Background(), "ada@example.com")
+		if !check.Attempted || tristate(check.MailboxExists) != tt.exists || tristate(check.CatchAll) != tt.catchAll || check.SMTPMessage != tt.message {
+			t.Errorf("%s: got attempted %v, exists %s, catch-all %s, message %q", tt.name, check.Attempted,
+				tristate(check.MailboxExists), tristate(check.CatchAll), check.SMTPMessage)
+		}
+	}
+}
+
+func TestSMTPVerifySession(t *testing.T) {
+	verifier, server, _ := newSMTPVerifier(time.Second)
+	server.Recipients["ada@example.com"] = "250 2.1.5 Ok"
+	verifier.Verify(context.Background(), "ada@example.com")
+	if dialed := server.Dialed(); len(dialed) != 1 || dialed[0] != "mx.example.com:25" {
+		t.Errorf("dialed %q, want the lowest preference MX on port 25", dialed)
+	}
+	commands := server.Commands()
+	if len(commands) != 5 || commands[0] != "HELO api.test" || commands[1] != "MAIL FROM:<>" ||
+		commands[2] != "RCPT TO:<ada@example.com>" || !strings.HasSuffix(commands[3], "@example.com>") || commands[4] != "QUIT" {
+		t.Errorf("commands = %q", commands)
+	}
+	for _, cmd := range commands {
+		if strings.HasPrefix(cmd, "DATA") {
+			t.Error("the check sent mail")
+		}
+	}
+}
+
+// A server that never answers leaves the mailbox unknown within the
+// deadline
+func TestSMTPVerifyDeadline(t *testing.T) {
+	verifier, server, _ := newSMTPVerifier(50 * time.Millisecond)
+	server.Stall = true
+	start := time.Now()
+	check := verifier.Verify(context.Background(), "ada@example.com")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("answered after %v", elapsed)
+	}
+	if !check.Attempted || check.MailboxExists != nil || check.SMTPMessage != "the mail server did not answer" {
+		t.Errorf("check = %+v", check)
+	}
+}
+
+func TestSMTPVerifyNotAttempted(t *testing.T) {
+	verifier, server, resolver := newSMTPVerifier(time.Second)
+	resolver.MX["null.example"] = []*net.MX{{Host: ".", Pref: 0}}
+	for _, address := range []string{"not an address", "ada@nomx.example", "ada@null.example"} {
+		if check := verifier.Verify(context.Background(), address); check != (models.SMTPCheck{SMTPMessage: check.SMTPMessage}) || check.SMTPMessage == "" {
+			t.Errorf("%s: check = %+v", address, check)
+		}
+	}
+	resolver.Err = errors.New("SERVFAIL")
+	if check := verifier.Verify(context.Background(), "ada@example.com"); check.Attempted || check.MailboxExists != nil {
+		t.Errorf("failed lookup: check = %+v", check)
+	}
+	if dialed := server.Dialed(); len(dialed) != 0 {
+		t.Errorf("dialed %q", dialed)
+	}
+}
+
+func TestSMTPDialerRefusesPrivateAddresses(t *testing.T) {
+	dialer := validation.NewSMTPDialer()
+	for _, address := range []string{"127.0.0.1:25", "10.1.2.3:25", "[::1]:25", "169.254.169.254:25", "100.64.0.1:25", "100.127.255.254:25", "198.18.0.1:25"} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		cancel()
+		if err == nil {
+			conn.Close()
+		}
+		if err == nil || !strings.Contains(err.Error(), "not public") {
+			t.Errorf("%s: err = %v, want a refusal", address, err)
+		}
+	}
+}
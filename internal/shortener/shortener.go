@@ -0,0 +1,117 @@
+// Package shortener creates short links to http and https URLs and
+// resolves them on visit. A link gets a random base62 code, or an alias
+// its creator picks, and may expire after a number of days.
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/utils"
+)
+
+const (
+	// CodeLength is the length of generated codes: 62^7 is about 3.5e12
+	CodeLength = 7
+	// MaxURLLength is the longest URL a link may point to
+	MaxURLLength = 2048
+	// MaxExpiryDays is the longest expires_in_days; 0 never expires
+	MaxExpiryDays = 3650
+
+	// codeAttempts is how many generated codes Create tries before giving
+	// up, each taken code being a collision
+	codeAttempts = 5
+)
+
+var (
+	ErrInvalidShortURL = errors.New("invalid short link")
+	// ErrNoFreeCode means every generated code was taken
+	ErrNoFreeCode = errors.New("no free short link code found")
+)
+
+// aliasPattern restricts custom aliases to what needs no escaping in a path
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,64}$`)
+
+// Shortener creates and resolves short links kept in a store
+type Shortener struct {
+	store repository.ShortURLStore
+	clock clock.Clock
+}
+
+// New creates a Shortener keeping links in store
+func New(store repository.ShortURLStore, clk clock.Clock) *Shortener {
+	return &Shortener{store: store, clock: clk}
+}
+
+// Create stores a link to req.URL under req.CustomAlias, or under a free
+// generated code when no alias is given. A taken alias returns
+// repository.ErrShortURLExists.
+func (s *Shortener) Create(ctx context.Context, req models.ShortenRequest) (models.ShortURL, error) {
+	short, err := newShortURL(req, s.clock.Now().UTC().Truncate(time.Millisecond))
+	if err != nil {
+		return models.ShortURL{}, err
+	}
+	if req.CustomAlias != "" {
+		short.Code = req.CustomAlias
+		if err := s.store.Create(ctx, short); err != nil {
+			return models.ShortURL{}, err
+		}
+		return short, nil
+	}
+	for i := 0; i < codeAttempts; i++ {
+		if short.Code, err = utils.RandomBase62(CodeLength); err != nil {
+			return models.ShortURL{}, err
+		}
+		err = s.store.Create(ctx, short)
+		if !errors.Is(err, repository.ErrShortURLExists) {
+			if err != nil {
+				return models.ShortURL{}, err
+			}
+			return short, nil
+		}
+	}
+	return models.ShortURL{}, ErrNoFreeCode
+}
+
+// Visit counts a visit of the link with code and returns it
+func (s *Shortener) Visit(ctx context.Context, code string) (models.ShortURL, error) {
+	if !aliasPattern.MatchString(code) {
+		return models.ShortURL{}, repository.ErrShortURLNotFound
+	}
+	return s.store.Visit(ctx, code, s.clock.Now())
+}
+
+// newShortURL checks req and returns its link created at now, without a
+// code
+func newShortURL(req models.ShortenRequest, now time.Time) (models.ShortURL, error) {
+	if req.URL == "" {
+		return models.ShortURL{}, fmt.Errorf("%w: url is required", ErrInvalidShortURL)
+	}
+	if len(req.URL) > MaxURLLength {
+		return models.ShortURL{}, fmt.Errorf("%w: url must be at most %d characters", ErrInvalidShortURL, MaxURLLength)
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return models.ShortURL{}, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidShortURL)
+	}
+	if req.CustomAlias != "" && !aliasPattern.MatchString(req.CustomAlias) {
+		return models.ShortURL{}, fmt.Errorf("%w: custom_alias must be 3 to 64 letters, digits, - or _", ErrInvalidShortURL)
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > MaxExpiryDays {
+		return models.ShortURL{}, fmt.Errorf("%w: expires_in_days must be between 0 and %d", ErrInvalidShortURL, MaxExpiryDays)
+	}
+
+	short := models.ShortURL{URL: req.URL, CreatedAt: now}
+	if req.ExpiresInDays > 0 {
+		expires := now.AddDate(0, 0, req.ExpiresInDays)
+		short.ExpiresAt = &expires
+	}
+	return short, nil
+}
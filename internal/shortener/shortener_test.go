@@ -0,0 +1,75 @@
+package shortener_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/shortener"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+// collidingStore finds the first collisions codes it is given taken
+type collidingStore struct {
+	*testutil.ShortURLStore
+	collisions int
+	tried      []string
+}
+
+func (s *collidingStore) Create(ctx context.Context, short models.ShortURL) error {
+	s.tried = append(s.tried, short.Code)
+	if len(s.tried) <= s.collisions {
+		return repository.ErrShortURLExists
+	}
+	return s.ShortURLStore.Create(ctx, short)
+}
+
+func newShortener(collisions int) (*shortener.Shortener, *collidingStore) {
+	store := &collidingStore{ShortURLStore: testutil.NewShortURLStore(), collisions: collisions}
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+	return shortener.New(store, clock), store
+}
+
+func TestCreateRetriesTakenCodes(t *testing.T) {
+	s, store := newShortener(4)
+	short, err := s.Create(context.Background(), models.ShortenRequest{URL: "https://example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.tried) != 5 || short.Code != store.tried[4] || len(short.Code) != shortener.CodeLength {
+		t.Errorf("created %q after trying %v", short.Code, store.tried)
+	}
+	if _, ok := store.URLs[short.Code]; !ok {
+		t.Error("link not stored")
+	}
+}
+
+func TestCreateGivesUp(t *testing.T) {
+	s, store := newShortener(5)
+	if _, err := s.Create(context.Background(), models.ShortenRequest{URL: "https://example.com/"}); !errors.Is(err, shortener.ErrNoFreeCode) {
+		t.Errorf("err = %v, want ErrNoFreeCode", err)
+	}
+	if len(store.URLs) != 0 {
+		t.Errorf("stored %+v", store.URLs)
+	}
+}
+
+func TestCreateAliasIsNotRetried(t *testing.T) {
+	s, store := newShortener(1)
+	_, err := s.Create(context.Background(), models.ShortenRequest{URL: "https://example.com/", CustomAlias: "docs"})
+	if !errors.Is(err, repository.ErrShortURLExists) || len(store.tried) != 1 {
+		t.Errorf("err = %v after trying %v, want ErrShortURLExists once", err, store.tried)
+	}
+}
+
+func TestVisitRefusesImpossibleCodes(t *testing.T) {
+	s, _ := newShortener(0)
+	for _, code := range []string{"", "ab", "a/b", "with space"} {
+		if _, err := s.Visit(context.Background(), code); !errors.Is(err, repository.ErrShortURLNotFound) {
+			t.Errorf("Visit(%q) err = %v, want ErrShortURLNotFound", code, err)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/innovelabs/microtools-go/pkg/canonicaljson"
+)
+
+// Algorithm is the JWS algorithm of every signature
+const Algorithm = "ES256"
+
+var (
+	ErrNoKeys     = errors.New("no signing keys configured")
+	ErrInvalidKey = errors.New("invalid signing key")
+)
+
+// JWK is the public part of a signing key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+// JWKS is a published JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Signature is a detached JWS over the canonical JSON of a result
+type Signature struct {
+	JWS      string
+	KeyID    string
+	SignedAt time.Time
+}
+
+type signingKey struct {
+	id      string
+	private *ecdsa.PrivateKey
+	public  JWK
+}
+
+// Signer signs with its first key and publishes all of them, so a key can
+// be rotated by putting the new key first while signatures made with the
+// old one still verify
+type Signer struct {
+	keys []signingKey
+}
+
+// protectedHeader is the JWS header; iat binds the signing time to the
+// signature
+type protectedHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	IssuedAt  int64  `json:"iat"`
+}
+
+// NewSigner creates a Signer for P-256 keys, the first of which signs
+func NewSigner(keys ...*ecdsa.PrivateKey) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	s := &Signer{}
+	for _, key := range keys {
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%w: ES256 needs a P-256 key", ErrInvalidKey)
+		}
+		jwk := publicJWK(&key.PublicKey)
+		s.keys = append(s.keys, signingKey{id: jwk.KeyID, private: key, public: jwk})
+	}
+	return s, nil
+}
+
+// LoadSigner reads EC P-256 private keys from PEM files, in SEC 1
+// ("EC PRIVATE KEY") or PKCS #8 ("PRIVATE KEY") form. The first file holds
+// the active key.
+func LoadSigner(paths []string) (*Signer, error) {
+	keys := make([]*ecdsa.PrivateKey, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+		key, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewSigner(keys...)
+}
+
+func parsePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrInvalidKey)
+	}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		key, ok := parsed.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: not an EC key", ErrInvalidKey)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported PEM type %q", ErrInvalidKey, block.Type)
+	}
+}
+
+// publicJWK describes key with its RFC 7638 thumbprint as key id
+func publicJWK(key *ecdsa.PublicKey) JWK {
+	x := base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32)))
+	y := base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32)))
+	thumbprint := sha256.Sum256([]byte(`{"crv":"P-256","kty":"EC","x":"` + x + `","y":"` + y + `"}`))
+	return JWK{
+		KeyType:   "EC",
+		Curve:     "P-256",
+		X:         x,
+		Y:         y,
+		KeyID:     base64.RawURLEncoding.EncodeToString(thumbprint[:]),
+		Use:       "sig",
+		Algorithm: Algorithm,
+	}
+}
+
+// JWKS returns the public keys of every configured key
+func (s *Signer) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, len(s.keys))}
+	for i, key := range s.keys {
+		set.Keys[i] = key.public
+	}
+	return set
+}
+
+// Sign returns a detached JWS (header..signature) over the canonical JSON
+// of v, signed at the given time with the active key
+func (s *Signer) Sign(v interface{}, at time.Time) (Signature, error) {
+	payload, err := canonicaljson.Marshal(v)
+	if err != nil {
+		return Signature{}, fmt.Errorf("failed to canonicalize payload: %w", err)
+	}
+
+	key := s.keys[0]
+	at = at.UTC().Truncate(time.Second)
+	header, err := json.Marshal(protectedHeader{Algorithm: Algorithm, KeyID: key.id, IssuedAt: at.Unix()})
+	if err != nil {
+		return Signature{}, err
+	}
+	encodedHeader := base64.RawURLEncoding.EncodeToString(header)
+	signingInput := encodedHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, sig, err := ecdsa.Sign(rand.Reader, key.private, digest[:])
+	if err != nil {
+		return Signature{}, fmt.Errorf("failed to sign: %w", err)
+	}
+	// JWS ES256 signatures are R and S as fixed 32-byte big-endian values
+	raw := make([]byte, 64)
+	r.FillBytes(raw[:32])
+	sig.FillBytes(raw[32:])
+
+	return Signature{
+		JWS:      encodedHeader + ".." + base64.RawURLEncoding.EncodeToString(raw),
+		KeyID:    key.id,
+		SignedAt: at,
+	}, nil
+}
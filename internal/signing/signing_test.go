@@ -0,0 +1,106 @@
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/pkg/client"
+)
+
+func newKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// clientJWKS converts the published key set as a client decodes it
+func clientJWKS(t *testing.T, s *Signer) client.JWKS {
+	t.Helper()
+	data, _ := json.Marshal(s.JWKS())
+	var set client.JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func TestLoadSigner(t *testing.T) {
+	dir := t.TempDir()
+	sec1Key, pkcs8Key := newKey(t, elliptic.P256()), newKey(t, elliptic.P256())
+	sec1, _ := x509.MarshalECPrivateKey(sec1Key)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(pkcs8Key)
+	p384, _ := x509.MarshalECPrivateKey(newKey(t, elliptic.P384()))
+	write := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600)
+		return path
+	}
+	sec1Path := write("sec1.pem", "EC PRIVATE KEY", sec1)
+	pkcs8Path := write("pkcs8.pem", "PRIVATE KEY", pkcs8)
+	os.WriteFile(sec1Path+".der", sec1, 0o600)
+
+	signer, err := LoadSigner([]string{sec1Path, pkcs8Path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := signer.JWKS()
+	if len(set.Keys) != 2 || set.Keys[0].KeyID != publicJWK(&sec1Key.PublicKey).KeyID || set.Keys[0].Algorithm != Algorithm {
+		t.Errorf("key set = %+v, want both keys with the SEC 1 one first", set)
+	}
+
+	for name, paths := range map[string][]string{
+		"P-384 key":    {write("p384.pem", "EC PRIVATE KEY", p384)},
+		"missing file": {filepath.Join(dir, "missing.pem")},
+		"not PEM":      {sec1Path + ".der"},
+		"RSA PEM type": {write("rsa.pem", "RSA PRIVATE KEY", sec1)},
+		"no keys":      nil,
+	} {
+		if _, err := LoadSigner(paths); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+	if _, err := NewSigner(); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("NewSigner(): err = %v, want ErrNoKeys", err)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	oldKey, activeKey := newKey(t, elliptic.P256()), newKey(t, elliptic.P256())
+	old, _ := NewSigner(oldKey)
+	rotated, _ := NewSigner(activeKey, oldKey)
+
+	result := map[string]interface{}{"iban": "DE89370400440532013000", "isValid": true, "length": 22}
+	at := time.Date(2025, 1, 1, 12, 30, 45, 500, time.UTC)
+	for name, signer := range map[string]*Signer{"old key": old, "new key": rotated} {
+		sig, err := signer.Sign(result, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// After the rotation signatures of both keys verify
+		signedAt, err := clientJWKS(t, rotated).Verify(result, sig.JWS)
+		if err != nil || !signedAt.Equal(at.Truncate(time.Second)) || !sig.SignedAt.Equal(signedAt) {
+			t.Errorf("%s: signed at %v, %v", name, signedAt, err)
+		}
+	}
+
+	sig, _ := rotated.Sign(result, at)
+	if _, err := clientJWKS(t, old).Verify(result, sig.JWS); !errors.Is(err, client.ErrUnknownKey) {
+		t.Errorf("new key against the old set: err = %v, want ErrUnknownKey", err)
+	}
+	tampered := map[string]interface{}{"iban": "DE89370400440532013000", "isValid": false, "length": 22}
+	if _, err := clientJWKS(t, rotated).Verify(tampered, sig.JWS); !errors.Is(err, client.ErrSignatureInvalid) {
+		t.Errorf("tampered result: err = %v, want ErrSignatureInvalid", err)
+	}
+}
@@ -0,0 +1,284 @@
+// Package tenant resolves the white-label customer a request is made for.
+// Tenants are stored in MongoDB and cached in memory; a request belongs to
+// the tenant of its access token, else to the tenant owning its Host, else
+// to the default tenant, which keeps the service's own behavior.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+)
+
+const (
+	// DefaultID is the ID of the default tenant
+	DefaultID = "default"
+	// CacheTTL is how long the cached tenants are served before they are
+	// reloaded, so changes made on another replica apply within it
+	CacheTTL = 30 * time.Second
+	// retryInterval is how long a failed reload keeps serving the last
+	// tenants loaded before trying again
+	retryInterval = 5 * time.Second
+
+	// MaxHosts, MaxDisposableDomains and MaxWebhookSecretBytes bound a
+	// tenant's lists and secret
+	MaxHosts              = 20
+	MaxDisposableDomains  = 1000
+	MaxWebhookSecretBytes = 256
+	// minWebhookSecretBytes keeps webhook signatures from being guessed
+	minWebhookSecretBytes = 16
+)
+
+// Default is the tenant of requests no other tenant claims: no overrides
+var Default = models.Tenant{ID: DefaultID, Name: "Default"}
+
+// ErrInvalidTenant is returned for a tenant request that cannot be stored
+var ErrInvalidTenant = errors.New("invalid tenant")
+
+var (
+	idPattern    = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+	colorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+	hostPattern  = regexp.MustCompile(`^(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+)
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying t
+func NewContext(ctx context.Context, t models.Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant of ctx, or Default
+func FromContext(ctx context.Context) models.Tenant {
+	if t, ok := ctx.Value(contextKey{}).(models.Tenant); ok {
+		return t
+	}
+	return Default
+}
+
+// IsDefault reports whether t is the default tenant
+func IsDefault(t models.Tenant) bool {
+	return t.ID == DefaultID
+}
+
+// NormalizeHost returns host lower case without its port and trailing dot
+func NormalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// Registry serves tenants from memory, reloading them from the store once
+// CacheTTL has passed. When a reload fails the tenants loaded last are kept.
+// It is safe for concurrent use.
+type Registry struct {
+	store repository.TenantStore
+	clock clock.Clock
+	ttl   time.Duration
+
+	// reload lets a single request reload an expired cache
+	reload sync.Mutex
+
+	mu      sync.RWMutex
+	byID    map[string]models.Tenant
+	byHost  map[string]string
+	expires time.Time
+}
+
+// NewRegistry creates a Registry of the tenants in store, cached for ttl
+func NewRegistry(store repository.TenantStore, clk clock.Clock, ttl time.Duration) *Registry {
+	return &Registry{store: store, clock: clk, ttl: ttl}
+}
+
+// Lookup returns the tenant with id
+func (r *Registry) Lookup(ctx context.Context, id string) (models.Tenant, bool) {
+	r.refresh(ctx)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.byID[id]
+	return t, ok
+}
+
+// LookupHost returns the tenant serving host
+func (r *Registry) LookupHost(ctx context.Context, host string) (models.Tenant, bool) {
+	r.refresh(ctx)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.byHost[NormalizeHost(host)]
+	if !ok {
+		return models.Tenant{}, false
+	}
+	return r.byID[id], true
+}
+
+// Resolve returns the tenant with id when there is one, else the tenant
+// serving host, else Default. A token naming a deleted tenant falls back
+// the same way.
+func (r *Registry) Resolve(ctx context.Context, id, host string) models.Tenant {
+	if id != "" {
+		if t, ok := r.Lookup(ctx, id); ok {
+			return t
+		}
+	}
+	if t, ok := r.LookupHost(ctx, host); ok {
+		return t
+	}
+	return Default
+}
+
+// Invalidate drops the cached tenants so the next lookup reloads them,
+// e.g. after an admin change on this replica
+func (r *Registry) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expires = time.Time{}
+}
+
+// refresh reloads the tenants when the cache has expired
+func (r *Registry) refresh(ctx context.Context) {
+	if r.fresh() {
+		return
+	}
+	r.reload.Lock()
+	defer r.reload.Unlock()
+	// Another request may have reloaded while this one waited
+	if r.fresh() {
+		return
+	}
+
+	tenants, err := r.store.List(ctx)
+	now := r.clock.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		log.Printf("Failed to reload tenants, keeping %d cached: %v", len(r.byID), err)
+		r.expires = now.Add(retryInterval)
+		return
+	}
+	r.byID = make(map[string]models.Tenant, len(tenants))
+	r.byHost = map[string]string{}
+	for _, t := range tenants {
+		r.byID[t.ID] = t
+		for _, host := range t.Hosts {
+			r.byHost[host] = t.ID
+		}
+	}
+	r.expires = now.Add(r.ttl)
+}
+
+func (r *Registry) fresh() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clock.Now().Before(r.expires)
+}
+
+// FromRequest validates req and returns the tenant it describes, stamped
+// at now. The stored tenant, when there is one, keeps its creation time and
+// webhook secret unless req sets a new one.
+func FromRequest(id string, req models.TenantRequest, stored *models.Tenant, now time.Time) (models.Tenant, error) {
+	if !idPattern.MatchString(id) || id == DefaultID {
+		return models.Tenant{}, fmt.Errorf("%w: id must be 1 to 63 lower case letters, digits or -, and not %q", ErrInvalidTenant, DefaultID)
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return models.Tenant{}, fmt.Errorf("%w: name is required", ErrInvalidTenant)
+	}
+	if req.RequestsPerMinute < 0 {
+		return models.Tenant{}, fmt.Errorf("%w: requests_per_minute must not be negative", ErrInvalidTenant)
+	}
+	hosts, err := normalizeDomains("hosts", req.Hosts, MaxHosts)
+	if err != nil {
+		return models.Tenant{}, err
+	}
+	disposable, err := normalizeDomains("disposable_domains", req.DisposableDomains, MaxDisposableDomains)
+	if err != nil {
+		return models.Tenant{}, err
+	}
+	branding, err := brandingFromRequest(req.Branding)
+	if err != nil {
+		return models.Tenant{}, err
+	}
+	if req.WebhookSecret != "" && (len(req.WebhookSecret) < minWebhookSecretBytes || len(req.WebhookSecret) > MaxWebhookSecretBytes) {
+		return models.Tenant{}, fmt.Errorf("%w: webhook_secret must be %d to %d bytes", ErrInvalidTenant, minWebhookSecretBytes, MaxWebhookSecretBytes)
+	}
+
+	t := models.Tenant{
+		ID:                id,
+		Name:              strings.TrimSpace(req.Name),
+		Hosts:             hosts,
+		DisposableDomains: disposable,
+		RequestsPerMinute: req.RequestsPerMinute,
+		WebhookSecret:     req.WebhookSecret,
+		Branding:          branding,
+		CreatedAt:         now.UTC(),
+		UpdatedAt:         now.UTC(),
+	}
+	if stored != nil {
+		t.CreatedAt = stored.CreatedAt
+		if t.WebhookSecret == "" {
+			t.WebhookSecret = stored.WebhookSecret
+		}
+	}
+	return t, nil
+}
+
+// normalizeDomains lower-cases and deduplicates the domains of field
+func normalizeDomains(field string, domains []string, limit int) ([]string, error) {
+	if len(domains) > limit {
+		return nil, fmt.Errorf("%w: %s takes at most %d entries", ErrInvalidTenant, field, limit)
+	}
+	seen := make(map[string]bool, len(domains))
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = NormalizeHost(domain)
+		if !hostPattern.MatchString(domain) {
+			return nil, fmt.Errorf("%w: %s: %q is not a domain name", ErrInvalidTenant, field, domain)
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized, nil
+}
+
+// brandingFromRequest validates the branding values, which are rendered
+// into every page of the tenant's hosts
+func brandingFromRequest(req models.TenantBrandingRequest) (models.TenantBranding, error) {
+	b := models.TenantBranding{
+		SiteName:     strings.TrimSpace(req.SiteName),
+		LogoURL:      req.LogoURL,
+		PrimaryColor: req.PrimaryColor,
+		SupportEmail: req.SupportEmail,
+	}
+	if len(b.SiteName) > 100 {
+		return b, fmt.Errorf("%w: branding.site_name must be at most 100 bytes", ErrInvalidTenant)
+	}
+	if b.LogoURL != "" {
+		u, err := url.Parse(b.LogoURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return b, fmt.Errorf("%w: branding.logo_url must be an https URL", ErrInvalidTenant)
+		}
+	}
+	if b.PrimaryColor != "" && !colorPattern.MatchString(b.PrimaryColor) {
+		return b, fmt.Errorf("%w: branding.primary_color must be a hex color such as #0a84ff", ErrInvalidTenant)
+	}
+	if b.SupportEmail != "" {
+		if addr, err := mail.ParseAddress(b.SupportEmail); err != nil || addr.Address != b.SupportEmail {
+			return b, fmt.Errorf("%w: branding.support_email must be an email address", ErrInvalidTenant)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,138 @@
+package tenant_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/tenant"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+)
+
+var acme = models.Tenant{ID: "acme", Name: "Acme", Hosts: []string{"tools.acme.example"}}
+
+func newRegistry() (*tenant.Registry, *testutil.TenantStore, *testutil.Clock) {
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := testutil.NewTenantStore(acme)
+	return tenant.NewRegistry(store, clock, tenant.CacheTTL), store, clock
+}
+
+func TestRegistryCachesForTTL(t *testing.T) {
+	ctx := context.Background()
+	registry, store, clock := newRegistry()
+	if _, ok := registry.Lookup(ctx, "acme"); !ok {
+		t.Fatal("acme not found")
+	}
+	store.Put(ctx, models.Tenant{ID: "globex", Name: "Globex"})
+	if _, ok := registry.Lookup(ctx, "globex"); ok {
+		t.Error("tenant added to the store is served before the TTL passed")
+	}
+	if store.Lists != 1 {
+		t.Errorf("store listed %d times within the TTL, want 1", store.Lists)
+	}
+
+	clock.Advance(tenant.CacheTTL)
+	if _, ok := registry.Lookup(ctx, "globex"); !ok {
+		t.Error("tenant added to the store is not served after the TTL")
+	}
+
+	store.Delete(ctx, "globex")
+	registry.Invalidate()
+	if _, ok := registry.Lookup(ctx, "globex"); ok {
+		t.Error("deleted tenant served after Invalidate")
+	}
+}
+
+func TestRegistryKeepsTenantsWhenStoreFails(t *testing.T) {
+	ctx := context.Background()
+	registry, store, clock := newRegistry()
+	registry.Lookup(ctx, "acme")
+
+	store.Err = errors.New("mongo down")
+	clock.Advance(tenant.CacheTTL)
+	if _, ok := registry.Lookup(ctx, "acme"); !ok {
+		t.Error("cached tenant lost when the store failed")
+	}
+	lists := store.Lists
+	registry.Lookup(ctx, "acme")
+	if store.Lists != lists {
+		t.Error("failed reload retried on the next lookup")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+	registry, store, _ := newRegistry()
+	store.Put(ctx, models.Tenant{ID: "globex", Name: "Globex"})
+
+	tests := []struct {
+		name, id, host, want string
+	}{
+		{"token tenant", "globex", "tools.acme.example", "globex"},
+		{"host", "", "Tools.Acme.Example:8443", "acme"},
+		{"deleted token tenant falls back to host", "initech", "tools.acme.example", "acme"},
+		{"unknown host", "", "microapi.innovelabs.net", tenant.DefaultID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := registry.Resolve(ctx, tt.id, tt.host); got.ID != tt.want {
+				t.Errorf("Resolve(%q, %q) = %q, want %q", tt.id, tt.host, got.ID, tt.want)
+			}
+		})
+	}
+	if got := tenant.FromContext(ctx); !tenant.IsDefault(got) {
+		t.Errorf("FromContext without a tenant = %q, want the default", got.ID)
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := models.TenantRequest{
+		Name:              "Acme",
+		Hosts:             []string{"Tools.Acme.Example", "tools.acme.example"},
+		DisposableDomains: []string{"burner.example"},
+		WebhookSecret:     "0123456789abcdef",
+		Branding:          models.TenantBrandingRequest{PrimaryColor: "#0a84ff", LogoURL: "https://acme.example/logo.svg"},
+	}
+	got, err := tenant.FromRequest("acme", valid, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Hosts) != 1 || got.Hosts[0] != "tools.acme.example" {
+		t.Errorf("hosts = %v, want one normalized host", got.Hosts)
+	}
+
+	// A replacement without a secret keeps the stored one
+	stored := got
+	stored.CreatedAt = now.Add(-time.Hour)
+	valid.WebhookSecret = ""
+	replaced, err := tenant.FromRequest("acme", valid, &stored, now)
+	if err != nil || replaced.WebhookSecret != "0123456789abcdef" || !replaced.CreatedAt.Equal(stored.CreatedAt) {
+		t.Errorf("replacement = %+v, %v, want the stored secret and creation time", replaced, err)
+	}
+
+	invalid := []struct {
+		name string
+		id   string
+		req  models.TenantRequest
+	}{
+		{"default id", tenant.DefaultID, models.TenantRequest{Name: "x"}},
+		{"upper case id", "Acme", models.TenantRequest{Name: "x"}},
+		{"missing name", "acme", models.TenantRequest{}},
+		{"negative limit", "acme", models.TenantRequest{Name: "x", RequestsPerMinute: -1}},
+		{"host with a path", "acme", models.TenantRequest{Name: "x", Hosts: []string{"acme.example/x"}}},
+		{"color name", "acme", models.TenantRequest{Name: "x", Branding: models.TenantBrandingRequest{PrimaryColor: "red"}}},
+		{"style injection", "acme", models.TenantRequest{Name: "x", Branding: models.TenantBrandingRequest{PrimaryColor: "#fff;background:url(x)"}}},
+		{"http logo", "acme", models.TenantRequest{Name: "x", Branding: models.TenantBrandingRequest{LogoURL: "http://acme.example/logo.png"}}},
+		{"short secret", "acme", models.TenantRequest{Name: "x", WebhookSecret: "short"}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tenant.FromRequest(tt.id, tt.req, nil, now); !errors.Is(err, tenant.ErrInvalidTenant) {
+				t.Errorf("err = %v, want ErrInvalidTenant", err)
+			}
+		})
+	}
+}
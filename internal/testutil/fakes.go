@@ -0,0 +1,830 @@
+// Package testutil provides in-memory fakes of the handler dependencies so
+// handlers can be exercised without network access, Mongo, Redis or the
+// GeoLite database.
+package testutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"net"
+	"net/textproto"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/abuse"
+	"github.com/innovelabs/microtools-go/internal/cache"
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/config"
+	"github.com/innovelabs/microtools-go/internal/dnscache"
+	"github.com/innovelabs/microtools-go/internal/handlers"
+	"github.com/innovelabs/microtools-go/internal/jobs"
+	"github.com/innovelabs/microtools-go/internal/maintenance"
+	"github.com/innovelabs/microtools-go/internal/middleware"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/resultmeta"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+	"github.com/innovelabs/microtools-go/internal/services/notify"
+	"github.com/innovelabs/microtools-go/internal/services/validation"
+	"github.com/innovelabs/microtools-go/internal/shortener"
+	"github.com/innovelabs/microtools-go/internal/signing"
+	"github.com/innovelabs/microtools-go/internal/tenant"
+	"github.com/innovelabs/microtools-go/internal/toolstatus"
+	"github.com/innovelabs/microtools-go/internal/wifirotation"
+)
+
+var (
+	_ repository.UserRepository   = (*UserRepository)(nil)
+	_ repository.BlocklistStore   = (*BlocklistStore)(nil)
+	_ repository.WifiProfileStore = (*WifiProfileStore)(nil)
+	_ repository.ShortURLStore    = (*ShortURLStore)(nil)
+	_ repository.TenantStore      = (*TenantStore)(nil)
+	_ validation.GeoIPService     = (*GeoIP)(nil)
+	_ middleware.HitCounter       = (*HitCounter)(nil)
+	_ cache.Cache                 = (*Cache)(nil)
+	_ clock.Clock                 = (*Clock)(nil)
+	_ notify.MailSender           = (*MailSender)(nil)
+	_ dnscache.Resolver           = (*Resolver)(nil)
+	_ validation.SMTPDialer       = (*SMTPServer)(nil)
+)
+
+// UserRepository is an in-memory repository.UserRepository
+type UserRepository struct {
+	mu    sync.Mutex
+	Users map[string]models.User
+	// Err, when set, is returned by every method, like a database that
+	// cannot be reached
+	Err error
+}
+
+// NewUserRepository creates an empty UserRepository
+func NewUserRepository() *UserRepository {
+	return &UserRepository{Users: map[string]models.User{}}
+}
+
+// Create stores a new user, returning repository.ErrUserExists for a known
+// email and repository.ErrUserPendingDeletion for a deleted, unpurged one
+func (r *UserRepository) Create(ctx context.Context, user models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return r.Err
+	}
+	if existing, ok := r.Users[user.Email]; ok {
+		if existing.Deleted {
+			return repository.ErrUserPendingDeletion
+		}
+		return repository.ErrUserExists
+	}
+	r.Users[user.Email] = user
+	return nil
+}
+
+// ReplaceUnverified replaces the unverified user with user's email that
+// registered before createdBefore, or returns repository.ErrUserExists
+func (r *UserRepository) ReplaceUnverified(ctx context.Context, user models.User, createdBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return r.Err
+	}
+	existing, ok := r.Users[user.Email]
+	if !ok || existing.Verified || existing.Deleted || !existing.CreatedAt.Before(createdBefore) {
+		return repository.ErrUserExists
+	}
+	r.Users[user.Email] = user
+	return nil
+}
+
+// MarkVerified verifies the unverified user holding nonce and clears it
+func (r *UserRepository) MarkVerified(ctx context.Context, email, nonce string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return r.Err
+	}
+	user, ok := r.Users[email]
+	if !ok || user.Verified || user.Deleted || user.VerificationNonce != nonce {
+		return repository.ErrVerificationNotFound
+	}
+	user.Verified = true
+	user.VerifiedAt = at.UTC()
+	user.VerificationNonce = ""
+	r.Users[email] = user
+	return nil
+}
+
+// DeleteUnverified removes the unverified user holding nonce
+func (r *UserRepository) DeleteUnverified(ctx context.Context, email, nonce string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return r.Err
+	}
+	user, ok := r.Users[email]
+	if !ok || user.Verified || user.VerificationNonce != nonce {
+		return repository.ErrVerificationNotFound
+	}
+	delete(r.Users, email)
+	return nil
+}
+
+// Get returns the user with email, deleted or not
+func (r *UserRepository) Get(ctx context.Context, email string) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return models.User{}, r.Err
+	}
+	user, ok := r.Users[email]
+	if !ok {
+		return models.User{}, repository.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// SoftDelete marks the user deleted at the given time and invalidates
+// their access tokens
+func (r *UserRepository) SoftDelete(ctx context.Context, email string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return r.Err
+	}
+	user, ok := r.Users[email]
+	if !ok || user.Deleted {
+		return repository.ErrUserNotFound
+	}
+	user.Deleted = true
+	user.DeletedAt = at.UTC()
+	user.TokensInvalidBefore = at.UTC()
+	r.Users[email] = user
+	return nil
+}
+
+// DeletedBefore returns the emails of the users deleted before the given
+// time, in order
+func (r *UserRepository) DeletedBefore(ctx context.Context, before time.Time) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var emails []string
+	for email, user := range r.Users {
+		if user.Deleted && user.DeletedAt.Before(before) {
+			emails = append(emails, email)
+		}
+	}
+	sort.Strings(emails)
+	return emails, nil
+}
+
+// PurgeDeleted removes the users deleted before the given time
+func (r *UserRepository) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return 0, r.Err
+	}
+	purged := 0
+	for email, user := range r.Users {
+		if user.Deleted && user.DeletedAt.Before(before) {
+			delete(r.Users, email)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// BlocklistStore is an in-memory repository.BlocklistStore
+type BlocklistStore struct {
+	mu      sync.Mutex
+	Domains map[string]models.BlockedDomain
+}
+
+// NewBlocklistStore creates an empty BlocklistStore
+func NewBlocklistStore() *BlocklistStore {
+	return &BlocklistStore{Domains: map[string]models.BlockedDomain{}}
+}
+
+// List returns every blocked domain sorted by name
+func (s *BlocklistStore) List(ctx context.Context) ([]models.BlockedDomain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	domains := []models.BlockedDomain{}
+	for _, entry := range s.Domains {
+		domains = append(domains, entry)
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Domain < domains[j].Domain })
+	return domains, nil
+}
+
+// Add blocks the domain of entry, replacing its entry when already blocked
+func (s *BlocklistStore) Add(ctx context.Context, entry models.BlockedDomain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Domains[entry.Domain] = entry
+	return nil
+}
+
+// Remove unblocks domain, or returns repository.ErrBlockedDomainNotFound
+func (s *BlocklistStore) Remove(ctx context.Context, domain string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Domains[domain]; !ok {
+		return repository.ErrBlockedDomainNotFound
+	}
+	delete(s.Domains, domain)
+	return nil
+}
+
+// TenantStore is an in-memory repository.TenantStore. Calls fail with Err
+// when it is set; Lists counts the List calls.
+type TenantStore struct {
+	mu      sync.Mutex
+	Tenants map[string]models.Tenant
+	Err     error
+	Lists   int
+}
+
+// NewTenantStore creates a TenantStore holding tenants
+func NewTenantStore(tenants ...models.Tenant) *TenantStore {
+	s := &TenantStore{Tenants: map[string]models.Tenant{}}
+	for _, tenant := range tenants {
+		s.Tenants[tenant.ID] = tenant
+	}
+	return s
+}
+
+// List returns every tenant sorted by ID
+func (s *TenantStore) List(ctx context.Context) ([]models.Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Lists++
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	tenants := []models.Tenant{}
+	for _, tenant := range s.Tenants {
+		tenants = append(tenants, tenant)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants, nil
+}
+
+// Get returns the tenant with id, or repository.ErrTenantNotFound
+func (s *TenantStore) Get(ctx context.Context, id string) (models.Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return models.Tenant{}, s.Err
+	}
+	tenant, ok := s.Tenants[id]
+	if !ok {
+		return models.Tenant{}, repository.ErrTenantNotFound
+	}
+	return tenant, nil
+}
+
+// Put stores tenant, replacing the tenant with its ID
+func (s *TenantStore) Put(ctx context.Context, tenant models.Tenant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	s.Tenants[tenant.ID] = tenant
+	return nil
+}
+
+// Delete removes the tenant with id, or returns repository.ErrTenantNotFound
+func (s *TenantStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if _, ok := s.Tenants[id]; !ok {
+		return repository.ErrTenantNotFound
+	}
+	delete(s.Tenants, id)
+	return nil
+}
+
+// WifiProfileStore is an in-memory repository.WifiProfileStore
+type WifiProfileStore struct {
+	mu       sync.Mutex
+	Profiles map[string]models.WifiProfile
+}
+
+// NewWifiProfileStore creates an empty WifiProfileStore
+func NewWifiProfileStore() *WifiProfileStore {
+	return &WifiProfileStore{Profiles: map[string]models.WifiProfile{}}
+}
+
+// Create stores a new network, or returns repository.ErrWifiProfileExists
+func (s *WifiProfileStore) Create(ctx context.Context, profile models.WifiProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Profiles[profile.ID]; ok {
+		return repository.ErrWifiProfileExists
+	}
+	s.Profiles[profile.ID] = profile
+	return nil
+}
+
+// Get returns the owner's network called name, or
+// repository.ErrWifiProfileNotFound
+func (s *WifiProfileStore) Get(ctx context.Context, owner, name string) (models.WifiProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.Profiles[repository.WifiProfileID(owner, name)]
+	if !ok {
+		return models.WifiProfile{}, repository.ErrWifiProfileNotFound
+	}
+	return profile, nil
+}
+
+// Due returns the networks whose next rotation is at or before now,
+// earliest first
+func (s *WifiProfileStore) Due(ctx context.Context, now time.Time) ([]models.WifiProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []models.WifiProfile
+	for _, profile := range s.Profiles {
+		if !profile.NextRotationAt.After(now) {
+			due = append(due, profile)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextRotationAt.Before(due[j].NextRotationAt) })
+	return due, nil
+}
+
+// Rotate replaces the stored profile if its version is still version, or
+// returns repository.ErrWifiProfileConflict
+func (s *WifiProfileStore) Rotate(ctx context.Context, profile models.WifiProfile, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.Profiles[profile.ID]
+	if !ok || stored.Version != version {
+		return repository.ErrWifiProfileConflict
+	}
+	s.Profiles[profile.ID] = profile
+	return nil
+}
+
+// Delete removes the owner's network called name, or returns
+// repository.ErrWifiProfileNotFound
+func (s *WifiProfileStore) Delete(ctx context.Context, owner, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := repository.WifiProfileID(owner, name)
+	if _, ok := s.Profiles[id]; !ok {
+		return repository.ErrWifiProfileNotFound
+	}
+	delete(s.Profiles, id)
+	return nil
+}
+
+// ListByOwner returns the owner's networks ordered by name
+func (s *WifiProfileStore) ListByOwner(ctx context.Context, owner string) ([]models.WifiProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var profiles []models.WifiProfile
+	for _, profile := range s.Profiles {
+		if profile.Owner == owner {
+			profiles = append(profiles, profile)
+		}
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+// DeleteByOwner removes all the owner's networks and returns how many
+// there were
+func (s *WifiProfileStore) DeleteByOwner(ctx context.Context, owner string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := 0
+	for id, profile := range s.Profiles {
+		if profile.Owner == owner {
+			delete(s.Profiles, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// ShortURLStore is an in-memory repository.ShortURLStore
+type ShortURLStore struct {
+	mu   sync.Mutex
+	URLs map[string]models.ShortURL
+}
+
+// NewShortURLStore creates an empty ShortURLStore
+func NewShortURLStore() *ShortURLStore {
+	return &ShortURLStore{URLs: map[string]models.ShortURL{}}
+}
+
+// Create stores a new short link, or returns repository.ErrShortURLExists
+func (s *ShortURLStore) Create(ctx context.Context, short models.ShortURL) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.URLs[short.Code]; ok {
+		return repository.ErrShortURLExists
+	}
+	s.URLs[short.Code] = short
+	return nil
+}
+
+// Visit counts a visit of the link with code unless it expired by now
+func (s *ShortURLStore) Visit(ctx context.Context, code string, now time.Time) (models.ShortURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	short, ok := s.URLs[code]
+	if !ok {
+		return models.ShortURL{}, repository.ErrShortURLNotFound
+	}
+	if short.ExpiresAt != nil && !short.ExpiresAt.After(now) {
+		return models.ShortURL{}, repository.ErrShortURLExpired
+	}
+	short.Hits++
+	s.URLs[code] = short
+	return short, nil
+}
+
+// GeoIP is a validation.GeoIPService answering from a fixed table; other
+// valid addresses are NotFound, or flagged when private, loopback or
+// reserved as the real service does
+type GeoIP struct {
+	Results map[string]models.GeoIPResponse
+	Info    models.GeoDatabaseInfo
+	// Err, when set, is returned by every call, e.g. validation.ErrGeoDBUnavailable
+	Err error
+}
+
+// Lookup returns the table entry for ip
+func (g *GeoIP) Lookup(ctx context.Context, ip string) (models.GeoIPResponse, error) {
+	if g.Err != nil {
+		return models.GeoIPResponse{}, g.Err
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return models.GeoIPResponse{}, validation.ErrInvalidIP
+	}
+	result, ok := g.Results[ip]
+	if !ok {
+		result = models.GeoIPResponse{IP: ip, Granularity: validation.GeoGranularityNone}
+		result.IsPrivate, result.IsLoopback, result.IsReserved = validation.ClassifyIP(parsed)
+		result.NotFound = !result.IsPrivate && !result.IsLoopback && !result.IsReserved
+	}
+	result.Meta = g.Info
+	resultmeta.FromContext(ctx).UseDataset(validation.GeoLiteDataset, g.Info.DatabaseDate, g.Info.BuiltAt)
+	return result, nil
+}
+
+// Metadata returns Info
+func (g *GeoIP) Metadata() (models.GeoDatabaseInfo, error) {
+	if g.Err != nil {
+		return models.GeoDatabaseInfo{}, g.Err
+	}
+	return g.Info, nil
+}
+
+// Close does nothing
+func (g *GeoIP) Close() error {
+	return nil
+}
+
+// HitCounter records hits in memory
+type HitCounter struct {
+	mu   sync.Mutex
+	Hits map[string]int
+}
+
+// NewHitCounter creates an empty HitCounter
+func NewHitCounter() *HitCounter {
+	return &HitCounter{Hits: map[string]int{}}
+}
+
+// Hit counts one call of name
+func (c *HitCounter) Hit(ctx context.Context, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Hits[name]++
+}
+
+// Count returns the hits recorded for name
+func (c *HitCounter) Count(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Hits[name]
+}
+
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// Cache is an in-memory cache.Cache whose expiry follows Clock
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	Clock   *Clock
+	// Err, when set, is returned by every call, e.g. to simulate Redis being down
+	Err error
+}
+
+// NewCache creates an empty Cache that expires entries against clock
+func NewCache(clock *Clock) *Cache {
+	return &Cache{entries: map[string]cacheEntry{}, Clock: clock}
+}
+
+// Get returns the value for key and whether it was present
+func (c *Cache) Get(ctx context.Context, key string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return "", false, c.Err
+	}
+	entry, ok := c.entries[key]
+	if !ok || !c.Clock.Now().Before(entry.expires) {
+		return "", false, nil
+	}
+	return entry.value, true, nil
+}
+
+// Set stores value for key until ttl elapses
+func (c *Cache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return c.Err
+	}
+	c.entries[key] = cacheEntry{value: value, expires: c.Clock.Now().Add(ttl)}
+	return nil
+}
+
+// Resolver is a dnscache.Resolver answering from MX and Hosts. A name
+// missing from a map gets a not-found DNS error, an NXDOMAIN or NODATA
+// answer; Err, when set, fails every lookup instead, e.g. to simulate
+// SERVFAIL.
+type Resolver struct {
+	mu    sync.Mutex
+	MX    map[string][]*net.MX
+	Hosts map[string][]string
+	Err   error
+	calls int
+}
+
+// NewResolver creates a Resolver without any records
+func NewResolver() *Resolver {
+	return &Resolver{MX: map[string][]*net.MX{}, Hosts: map[string][]string{}}
+}
+
+// LookupMX returns the MX records of name
+func (r *Resolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	records, ok := r.MX[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+// LookupHost returns the addresses of host
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	addrs, ok := r.Hosts[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+// Calls returns the number of lookups that reached the resolver
+func (r *Resolver) Calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+// SMTPServer is a validation.SMTPDialer serving each connection as a fake
+// mail server over an in-memory pipe. RCPT TO is answered with the reply
+// line Recipients has for the address, or Default ("550 5.1.1 No such
+// user" when empty); Greeting replaces the 220 greeting and Stall makes
+// the server go silent after greeting.
+type SMTPServer struct {
+	Recipients map[string]string
+	Default    string
+	Greeting   string
+	Stall      bool
+
+	mu       sync.Mutex
+	dialed   []string
+	commands []string
+}
+
+// NewSMTPServer creates an SMTPServer knowing no recipients
+func NewSMTPServer() *SMTPServer {
+	return &SMTPServer{Recipients: map[string]string{}}
+}
+
+// DialContext records address and starts a session
+func (s *SMTPServer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	s.mu.Lock()
+	s.dialed = append(s.dialed, address)
+	s.mu.Unlock()
+	client, server := net.Pipe()
+	go s.serve(server)
+	return client, nil
+}
+
+// Dialed returns the addresses dialed so far
+func (s *SMTPServer) Dialed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.dialed)
+}
+
+// Commands returns the commands received so far
+func (s *SMTPServer) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands)
+}
+
+func (s *SMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	greeting := s.Greeting
+	if greeting == "" {
+		greeting = "220 mx.test ESMTP"
+	}
+	if text.PrintfLine("%s", greeting) != nil || s.Stall {
+		io.Copy(io.Discard, conn)
+		return
+	}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+
+		verb, arg, _ := strings.Cut(line, ":")
+		reply := "502 5.5.2 Command not recognized"
+		switch verb = strings.ToUpper(verb); {
+		case strings.HasPrefix(verb, "HELO "), strings.HasPrefix(verb, "EHLO "):
+			reply = "250 mx.test"
+		case verb == "MAIL FROM", verb == "RSET":
+			reply = "250 2.1.0 Ok"
+		case verb == "RCPT TO":
+			reply = s.Default
+			if r, ok := s.Recipients[strings.Trim(arg, "<> ")]; ok {
+				reply = r
+			}
+			if reply == "" {
+				reply = "550 5.1.1 No such user"
+			}
+		case verb == "QUIT":
+			text.PrintfLine("221 2.0.0 Bye")
+			return
+		}
+		if text.PrintfLine("%s", reply) != nil {
+			return
+		}
+	}
+}
+
+// Clock is a clock.Clock that only moves when told to
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock creates a Clock stopped at now
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the current fake time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// SentMail is one message captured by MailSender
+type SentMail struct {
+	To, Subject, Body string
+}
+
+// MailSender is a notify.MailSender that keeps messages in memory
+type MailSender struct {
+	mu   sync.Mutex
+	Sent []SentMail
+	// Err, when set, is returned instead of recording the message
+	Err error
+}
+
+// Send records the message
+func (m *MailSender) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Sent = append(m.Sent, SentMail{To: to, Subject: subject, Body: body})
+	return nil
+}
+
+// ValidEmailDomains is a validation.DomainChecker that accepts every domain
+func ValidEmailDomains(ctx context.Context, email string) (domainValid, mxFound bool, err error) {
+	return true, true, nil
+}
+
+// NewSigner returns a response signer with a freshly generated P-256 key
+func NewSigner() *signing.Signer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	signer, err := signing.NewSigner(key)
+	if err != nil {
+		panic(err)
+	}
+	return signer
+}
+
+// NewHandlers returns handlers wired entirely to fakes, with a JWT secret
+// and a generated signing key configured and the clock stopped at
+// 2025-01-01 UTC. Uploads stay disabled, as they need a directory.
+func NewHandlers() *handlers.Handlers {
+	fakeClock := NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	fakeCache := NewCache(fakeClock)
+	cfg := config.Default()
+	cfg.JWTSecret = "test-secret"
+	blocklist := NewBlocklistStore()
+	reputation, err := abuse.NewChecker(context.Background(), "", blocklist, nil)
+	if err != nil {
+		panic(err)
+	}
+	users := NewUserRepository()
+	rotator, err := wifirotation.NewRotator(NewWifiProfileStore(), users, make([]byte, 32), fakeClock)
+	if err != nil {
+		panic(err)
+	}
+	tenants := NewTenantStore()
+	return &handlers.Handlers{
+		Config:          cfg,
+		Users:           users,
+		Mailer:          &MailSender{},
+		GeoIP:           &GeoIP{},
+		Barcodes:        generator.NewDefaultBarcodeService(),
+		Labels:          generator.NewDefaultLabelService(),
+		Jobs:            jobs.NewStore(fakeClock),
+		EmailDomains:    ValidEmailDomains,
+		DoHEmailDomains: ValidEmailDomains,
+		DNSCache:        dnscache.New(NewResolver(), fakeClock, 0),
+		Cache:           fakeCache,
+		Shares:          repository.NewCacheShareStore(fakeCache),
+		Clock:           fakeClock,
+		Signer:          NewSigner(),
+		Status:          toolstatus.NewTracker(fakeClock, middleware.CounterNames()),
+		Hits:            middleware.NewHitStats(middleware.NopHitCounter(), fakeClock),
+		URLReputation:   reputation,
+		Blocklist:       blocklist,
+		Maintenance:     maintenance.NewSwitch(fakeCache, fakeClock, middleware.MaintenanceTools()),
+		WifiRotation:    rotator,
+		ShortURLs:       shortener.New(NewShortURLStore(), fakeClock),
+		Tenants:         tenants,
+		TenantRegistry:  tenant.NewRegistry(tenants, fakeClock, tenant.CacheTTL),
+	}
+}
@@ -0,0 +1,199 @@
+package textsafety
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/unicode/runenames"
+)
+
+const (
+	KindBidiControl = "bidi_control"
+	KindZeroWidth   = "zero_width"
+	KindUnassigned  = "unassigned"
+	KindControl     = "control"
+
+	maxTextSafetyInputs      = 100
+	maxTextSafetyInputLength = 10000
+
+	zeroWidthJoiner    = '\u200D'
+	zeroWidthNonJoiner = '\u200C'
+	variationSelector  = '\uFE0F'
+)
+
+// ErrInvalidRequest is returned for missing or oversized inputs
+var ErrInvalidRequest = errors.New("invalid text safety request")
+
+// bidiControls are the explicit directional formatting characters that can
+// reorder displayed text (e.g. "Trojan Source" spoofing)
+var bidiControls = map[rune]bool{
+	'\u061C': true, '\u200E': true, '\u200F': true,
+	'\u202A': true, '\u202B': true, '\u202C': true, '\u202D': true, '\u202E': true,
+	'\u2066': true, '\u2067': true, '\u2068': true, '\u2069': true,
+}
+
+// zeroWidthChars render as nothing but change how text compares and scans
+var zeroWidthChars = map[rune]bool{
+	'\u200B': true, zeroWidthNonJoiner: true, zeroWidthJoiner: true,
+	'\u2060': true, '\u180E': true, '\uFEFF': true,
+}
+
+// AnalyzeAll returns one report per input
+func AnalyzeAll(req models.TextSafetyRequest) ([]models.TextSafetyReport, error) {
+	if len(req.Inputs) == 0 {
+		return nil, fmt.Errorf("%w: inputs are required", ErrInvalidRequest)
+	}
+	if len(req.Inputs) > maxTextSafetyInputs {
+		return nil, fmt.Errorf("%w: at most %d inputs are allowed", ErrInvalidRequest, maxTextSafetyInputs)
+	}
+
+	reports := make([]models.TextSafetyReport, len(req.Inputs))
+	for i, input := range req.Inputs {
+		if len(input) > maxTextSafetyInputLength {
+			return nil, fmt.Errorf("%w: inputs[%d] exceeds %d bytes", ErrInvalidRequest, i, maxTextSafetyInputLength)
+		}
+		reports[i] = Analyze(input)
+		reports[i].Index = i
+	}
+	return reports, nil
+}
+
+// cjkScripts are routinely mixed within one word in Chinese, Japanese and
+// Korean text, so they count as a single script for mixing purposes
+var cjkScripts = map[string]bool{
+	"Han": true, "Hiragana": true, "Katakana": true, "Hangul": true, "Bopomofo": true,
+}
+
+// Analyze reports invisible, directional, unassigned and control characters
+// in s together with its scripts and an NFC-normalized cleaned suggestion.
+func Analyze(s string) models.TextSafetyReport {
+	runes := []rune(s)
+	report := models.TextSafetyReport{
+		Findings: []models.TextSafetyIssue{},
+		IsNFC:    IsNFC(s),
+	}
+
+	scripts := map[string]bool{}
+	for i, r := range runes {
+		if kind := classify(runes, i); kind != "" {
+			report.Findings = append(report.Findings, issue(kind, i, r))
+			continue
+		}
+		if name := scriptOf(r); name != "" {
+			scripts[name] = true
+		}
+	}
+
+	report.Scripts = make([]string, 0, len(scripts))
+	groups := map[string]bool{}
+	for name := range scripts {
+		report.Scripts = append(report.Scripts, name)
+		if cjkScripts[name] {
+			name = "CJK"
+		}
+		groups[name] = true
+	}
+	sort.Strings(report.Scripts)
+	report.MixedScripts = len(groups) > 1
+
+	report.Suggestion, _ = Sanitize(s)
+	report.Safe = len(report.Findings) == 0 && report.IsNFC && !report.MixedScripts
+	return report
+}
+
+// Sanitize strips bidi controls and stray zero-width characters and returns
+// the NFC-normalized result with the removed characters. Joiners inside
+// emoji sequences and non-joiners between letters are kept.
+func Sanitize(s string) (string, []models.TextSafetyIssue) {
+	runes := []rune(s)
+	removed := []models.TextSafetyIssue{}
+	out := make([]rune, 0, len(runes))
+	for i, r := range runes {
+		switch kind := classify(runes, i); kind {
+		case KindBidiControl, KindZeroWidth:
+			removed = append(removed, issue(kind, i, r))
+		default:
+			out = append(out, r)
+		}
+	}
+	return norm.NFC.String(string(out)), removed
+}
+
+// IsNFC reports whether s is already in Unicode Normalization Form C
+func IsNFC(s string) bool {
+	return norm.NFC.IsNormalString(s)
+}
+
+// classify returns the finding kind of runes[i], or "" if it is harmless
+func classify(runes []rune, i int) string {
+	r := runes[i]
+	switch {
+	case bidiControls[r]:
+		return KindBidiControl
+	case r == zeroWidthJoiner && i > 0 && i+1 < len(runes) && isEmojiPart(runes[i-1]) && isEmoji(runes[i+1]):
+		return ""
+	case r == zeroWidthNonJoiner && i > 0 && i+1 < len(runes) && isJoiningLetter(runes[i-1]) && isJoiningLetter(runes[i+1]):
+		return ""
+	case zeroWidthChars[r]:
+		return KindZeroWidth
+	case r == '\t' || r == '\n' || r == '\r':
+		return ""
+	case unicode.IsControl(r):
+		return KindControl
+	case !isAssigned(r):
+		return KindUnassigned
+	}
+	return ""
+}
+
+func issue(kind string, position int, r rune) models.TextSafetyIssue {
+	return models.TextSafetyIssue{
+		Kind:      kind,
+		Position:  position,
+		CodePoint: fmt.Sprintf("U+%04X", r),
+		Name:      runenames.Name(r),
+	}
+}
+
+// isEmoji reports whether r is a pictographic emoji base
+func isEmoji(r rune) bool {
+	return (r >= 0x1F000 && r <= 0x1FAFF) || (r >= 0x2600 && r <= 0x27BF) || (r >= 0x2B00 && r <= 0x2BFF)
+}
+
+// isEmojiPart also accepts the presentation selector and skin tone
+// modifiers that may precede a joiner in an emoji ZWJ sequence
+func isEmojiPart(r rune) bool {
+	return isEmoji(r) || r == variationSelector || (r >= 0x1F3FB && r <= 0x1F3FF)
+}
+
+// isJoiningLetter covers the letters and marks between which a ZWNJ is
+// meaningful, as in Persian and Indic scripts
+func isJoiningLetter(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r)
+}
+
+func isAssigned(r rune) bool {
+	// unicode.C spans the unassigned code points too, so list its subcategories
+	return unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Z,
+		unicode.Cc, unicode.Cf, unicode.Co, unicode.Cs)
+}
+
+// scriptOf returns the script of a letter, ignoring Common and Inherited
+func scriptOf(r rune) string {
+	if !unicode.IsLetter(r) {
+		return ""
+	}
+	for name, table := range unicode.Scripts {
+		if name == "Common" || name == "Inherited" {
+			continue
+		}
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package textsafety
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func kinds(issues []models.TextSafetyIssue) string {
+	var list []string
+	for _, issue := range issues {
+		list = append(list, issue.Kind+"@"+issue.CodePoint)
+	}
+	return strings.Join(list, " ")
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name, input, findings string
+		mixed, nfc, safe      bool
+	}{
+		{"plain", "Hello, world", "", false, true, true},
+		// "Trojan Source": the RLO reverses "exe.txt" into "txt.exe" on screen
+		{"RTL override spoof", "invoice\u202Etxt.exe", "bidi_control@U+202E", false, true, false},
+		{"isolates", "a\u2066b\u2069", "bidi_control@U+2066 bidi_control@U+2069", false, true, false},
+		{"zero-width space", "pay\u200Bpal", "zero_width@U+200B", false, true, false},
+		{"BOM", "\uFEFFtext", "zero_width@U+FEFF", false, true, false},
+		{"control character", "bell\u0007", "control@U+0007", false, true, false},
+		{"unassigned", "x\u0378", "unassigned@U+0378", false, true, false},
+		{"Cyrillic homoglyph", "p\u0430ypal", "", true, true, false},
+		{"decomposed", "Cafe\u0301", "", false, false, false},
+		{"Japanese", "東京タワーとスカイツリー", "", false, true, true},
+		// Family: man, ZWJ, woman, ZWJ, girl; and a skin-toned profession
+		{"ZWJ emoji", "\U0001F468\u200D\U0001F469\u200D\U0001F467", "", false, true, true},
+		{"ZWJ after skin tone", "\U0001F469\U0001F3FD\u200D\U0001F4BB", "", false, true, true},
+		{"ZWJ after a selector", "❤\uFE0F\u200D\U0001F525", "", false, true, true},
+		{"stray ZWJ", "ab\u200Dcd", "zero_width@U+200D", false, true, false},
+		// Persian "mi-khaham" keeps its meaningful non-joiner
+		{"ZWNJ in Persian", "می\u200Cخواهم", "", false, true, true},
+		{"newlines and tabs", "a\tb\nc\r\n", "", false, true, true},
+	}
+	for _, tt := range tests {
+		report := Analyze(tt.input)
+		if got := kinds(report.Findings); got != tt.findings {
+			t.Errorf("%s: findings = %q, want %q", tt.name, got, tt.findings)
+		}
+		if report.MixedScripts != tt.mixed || report.IsNFC != tt.nfc || report.Safe != tt.safe {
+			t.Errorf("%s: mixed, nfc, safe = %v, %v, %v, want %v, %v, %v",
+				tt.name, report.MixedScripts, report.IsNFC, report.Safe, tt.mixed, tt.nfc, tt.safe)
+		}
+	}
+
+	report := Analyze("invoice\u202Etxt")
+	if f := report.Findings[0]; f.Position != 7 || f.Name != "RIGHT-TO-LEFT OVERRIDE" {
+		t.Errorf("finding = %+v, want the RLO at rune 7", f)
+	}
+	if got := Analyze("p\u0430ypal").Scripts; strings.Join(got, ",") != "Cyrillic,Latin" {
+		t.Errorf("scripts = %v, want Cyrillic and Latin", got)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name, input, want, removed string
+	}{
+		{"RTL override spoof", "invoice\u202Etxt.exe", "invoicetxt.exe", "bidi_control@U+202E"},
+		{"zero-width characters", "\uFEFFpay\u200Bpal\u2060", "paypal", "zero_width@U+FEFF zero_width@U+200B zero_width@U+2060"},
+		{"NFC", "Cafe\u0301", "Café", ""},
+		{"ZWJ family kept", "\U0001F468\u200D\U0001F469\u200D\U0001F467", "\U0001F468\u200D\U0001F469\u200D\U0001F467", ""},
+		{"ZWJ profession kept", "\U0001F469\U0001F3FD\u200D\U0001F4BB", "\U0001F469\U0001F3FD\u200D\U0001F4BB", ""},
+		{"ZWJ kept, override removed", "\u202E\U0001F3F3\uFE0F\u200D\U0001F308", "\U0001F3F3\uFE0F\u200D\U0001F308", "bidi_control@U+202E"},
+		{"ZWNJ in Persian kept", "می\u200Cخواهم", "می\u200Cخواهم", ""},
+		// Control characters are reported, not removed
+		{"control kept", "a\u0007b", "a\u0007b", ""},
+	}
+	for _, tt := range tests {
+		got, removed := Sanitize(tt.input)
+		if got != tt.want {
+			t.Errorf("%s: Sanitize = %q, want %q", tt.name, got, tt.want)
+		}
+		if kinds(removed) != tt.removed {
+			t.Errorf("%s: removed = %q, want %q", tt.name, kinds(removed), tt.removed)
+		}
+		if again, removed := Sanitize(got); again != got || len(removed) != 0 {
+			t.Errorf("%s: Sanitize is not idempotent: %q", tt.name, again)
+		}
+	}
+}
+
+func TestAnalyzeAll(t *testing.T) {
+	reports, err := AnalyzeAll(models.TextSafetyRequest{Inputs: []string{"ok", "a\u202Eb"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[1].Index != 1 || reports[1].Safe || !reports[0].Safe {
+		t.Errorf("reports = %+v", reports)
+	}
+
+	for name, inputs := range map[string][]string{
+		"no inputs":       nil,
+		"too many inputs": make([]string, maxTextSafetyInputs+1),
+		"too long":        {strings.Repeat("a", maxTextSafetyInputLength+1)},
+	} {
+		if _, err := AnalyzeAll(models.TextSafetyRequest{Inputs: inputs}); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("%s: err = %v, want ErrInvalidRequest", name, err)
+		}
+	}
+}
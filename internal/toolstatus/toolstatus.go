@@ -0,0 +1,249 @@
+// Package toolstatus derives per-tool health from real traffic. Each tool
+// keeps a ring of time buckets covering a rolling window; requests update
+// the current bucket with atomic operations, so recording never takes a
+// lock and tools never contend with each other.
+package toolstatus
+
+import (
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+const (
+	// Window is how much traffic the status covers
+	Window = 5 * time.Minute
+	// BucketWidth is the granularity the window slides by
+	BucketWidth = 10 * time.Second
+	bucketCount = int(Window / BucketWidth)
+
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded"
+	StatusDown        = "down"
+
+	// A tool is down when at least downErrorRate of its requests failed,
+	// and degraded from degradedErrorRate or a p95 latency of
+	// degradedP95. Error rates need minRequests requests in the window so
+	// a single failure on an idle tool does not flip its status.
+	downErrorRate     = 0.5
+	degradedErrorRate = 0.05
+	degradedP95       = 2 * time.Second
+	minRequests       = 5
+)
+
+// latencyBounds are the upper bounds of the latency histogram; p95 is
+// reported as the bound of the bucket it falls in
+var latencyBounds = [...]time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// Outcome is how a request ended
+type Outcome int
+
+const (
+	// OutcomeOK is a response written in full, including client errors
+	OutcomeOK Outcome = iota
+	// OutcomeFailed is a 5xx response
+	OutcomeFailed
+	// OutcomeClientDisconnected is a request whose client went away before
+	// the response was written. It is counted on its own, outside requests,
+	// errors and latency.
+	OutcomeClientDisconnected
+)
+
+// bucket counts the requests of one BucketWidth slot. epoch is the slot
+// number the counts belong to; a bucket is reused once the ring wraps.
+type bucket struct {
+	epoch       atomic.Int64
+	requests    atomic.Int64
+	errors      atomic.Int64
+	disconnects atomic.Int64
+	latencies   [len(latencyBounds) + 1]atomic.Int64
+}
+
+// claim makes the bucket count for epoch, clearing an older slot. It
+// reports false for an epoch older than the bucket's, which can only be a
+// request that straddled a whole window. A request recorded while another
+// goroutine clears the bucket may be lost; the window is approximate.
+func (b *bucket) claim(epoch int64) bool {
+	for {
+		current := b.epoch.Load()
+		if current == epoch {
+			return true
+		}
+		if current > epoch {
+			return false
+		}
+		if b.epoch.CompareAndSwap(current, epoch) {
+			b.requests.Store(0)
+			b.errors.Store(0)
+			b.disconnects.Store(0)
+			for i := range b.latencies {
+				b.latencies[i].Store(0)
+			}
+			return true
+		}
+	}
+}
+
+type ring struct {
+	buckets [bucketCount]bucket
+}
+
+// Tracker records request outcomes per tool
+type Tracker struct {
+	clock clock.Clock
+	// tools is built once and only read afterwards, so lookups need no lock
+	tools map[string]*ring
+	names []string
+}
+
+// NewTracker tracks the named tools; outcomes of other names are ignored
+func NewTracker(c clock.Clock, tools []string) *Tracker {
+	t := &Tracker{clock: c, tools: make(map[string]*ring, len(tools))}
+	for _, name := range tools {
+		if _, exists := t.tools[name]; !exists {
+			t.tools[name] = &ring{}
+			t.names = append(t.names, name)
+		}
+	}
+	sort.Strings(t.names)
+	return t
+}
+
+func epochOf(at time.Time) int64 {
+	return at.UnixNano() / int64(BucketWidth)
+}
+
+// Record counts one request of tool that finished now after latency with
+// outcome
+func (t *Tracker) Record(tool string, outcome Outcome, latency time.Duration) {
+	r, ok := t.tools[tool]
+	if !ok {
+		return
+	}
+	epoch := epochOf(t.clock.Now())
+	b := &r.buckets[epoch%int64(bucketCount)]
+	if !b.claim(epoch) {
+		return
+	}
+	if outcome == OutcomeClientDisconnected {
+		b.disconnects.Add(1)
+		return
+	}
+	b.requests.Add(1)
+	if outcome == OutcomeFailed {
+		b.errors.Add(1)
+	}
+	b.latencies[latencyBucket(latency)].Add(1)
+}
+
+func latencyBucket(latency time.Duration) int {
+	return sort.Search(len(latencyBounds), func(i int) bool {
+		return latency <= latencyBounds[i]
+	})
+}
+
+// Report summarises the window ending now for every tracked tool. The
+// overall status is the worst tool status.
+func (t *Tracker) Report() models.StatusReport {
+	now := t.clock.Now()
+	current := epochOf(now)
+	report := models.StatusReport{
+		Status:        StatusOperational,
+		WindowSeconds: int(Window / time.Second),
+		GeneratedAt:   now.UTC(),
+		Tools:         make([]models.ToolStatus, 0, len(t.names)),
+	}
+	for _, name := range t.names {
+		tool := t.tools[name].summarise(name, current)
+		report.Tools = append(report.Tools, tool)
+		if statusRank(tool.Status) > statusRank(report.Status) {
+			report.Status = tool.Status
+		}
+	}
+	return report
+}
+
+func (r *ring) summarise(name string, current int64) models.ToolStatus {
+	var requests, errors, disconnects int64
+	var latencies [len(latencyBounds) + 1]int64
+	for i := range r.buckets {
+		b := &r.buckets[i]
+		if epoch := b.epoch.Load(); epoch <= current-int64(bucketCount) || epoch > current {
+			continue
+		}
+		requests += b.requests.Load()
+		errors += b.errors.Load()
+		disconnects += b.disconnects.Load()
+		for j := range latencies {
+			latencies[j] += b.latencies[j].Load()
+		}
+	}
+
+	tool := models.ToolStatus{
+		Tool:              name,
+		Status:            StatusOperational,
+		Requests:          requests,
+		Errors:            errors,
+		ClientDisconnects: disconnects,
+		RequestsPerMinute: math.Round(float64(requests)/Window.Minutes()*100) / 100,
+	}
+	if requests == 0 {
+		return tool
+	}
+	errorRate := float64(errors) / float64(requests)
+	tool.ErrorRate = math.Round(errorRate*10000) / 10000
+	p95 := percentile(latencies[:], requests, 0.95)
+	p95Ms := float64(p95) / float64(time.Millisecond)
+	tool.P95LatencyMs = &p95Ms
+
+	switch {
+	case requests >= minRequests && errorRate >= downErrorRate:
+		tool.Status = StatusDown
+	case (requests >= minRequests && errorRate >= degradedErrorRate) || p95 > degradedP95:
+		tool.Status = StatusDegraded
+	}
+	return tool
+}
+
+// percentile returns the upper bound of the histogram bucket holding the
+// q quantile of total observations; the overflow bucket reports the last
+// bound
+func percentile(latencies []int64, total int64, q float64) time.Duration {
+	rank := int64(math.Ceil(q * float64(total)))
+	var seen int64
+	for i, count := range latencies {
+		seen += count
+		if seen >= rank {
+			return latencyBounds[min(i, len(latencyBounds)-1)]
+		}
+	}
+	return latencyBounds[len(latencyBounds)-1]
+}
+
+func statusRank(status string) int {
+	switch status {
+	case StatusDown:
+		return 2
+	case StatusDegraded:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,159 @@
+package toolstatus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// start falls on a bucket boundary
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeClock stands in for testutil.Clock, which this package cannot
+// import because testutil imports it
+type fakeClock struct{ now time.Time }
+
+func newClock(now time.Time) *fakeClock      { return &fakeClock{now: now} }
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func record(tracker *Tracker, tool string, outcome Outcome, latency time.Duration, n int) {
+	for range n {
+		tracker.Record(tool, outcome, latency)
+	}
+}
+
+func toolStatus(t *testing.T, report models.StatusReport, name string) models.ToolStatus {
+	t.Helper()
+	for _, tool := range report.Tools {
+		if tool.Tool == name {
+			return tool
+		}
+	}
+	t.Fatalf("no status for %s in %+v", name, report.Tools)
+	return models.ToolStatus{}
+}
+
+func TestTrackerTransitions(t *testing.T) {
+	clock := newClock(start)
+	tracker := NewTracker(clock, []string{"qr-generate", "iban-validate", "iban-validate"})
+
+	report := tracker.Report()
+	if report.Status != StatusOperational || report.WindowSeconds != 300 || !report.GeneratedAt.Equal(start) ||
+		len(report.Tools) != 2 || report.Tools[0].Tool != "iban-validate" || report.Tools[1].Tool != "qr-generate" {
+		t.Fatalf("idle report = %+v", report)
+	}
+	if idle := report.Tools[0]; idle.Requests != 0 || idle.P95LatencyMs != nil {
+		t.Errorf("idle tool = %+v, want no requests and no p95", idle)
+	}
+
+	record(tracker, "iban-validate", OutcomeOK, 8*time.Millisecond, 20)
+	record(tracker, "iban-validate", OutcomeFailed, 8*time.Millisecond, 1)
+	tool := toolStatus(t, tracker.Report(), "iban-validate")
+	if tool.Status != StatusOperational || tool.Requests != 21 || tool.Errors != 1 || tool.RequestsPerMinute != 4.2 ||
+		tool.ErrorRate != 0.0476 || tool.P95LatencyMs == nil || *tool.P95LatencyMs != 10 {
+		t.Errorf("one failure in 21 = %+v, want operational", tool)
+	}
+
+	record(tracker, "iban-validate", OutcomeFailed, 8*time.Millisecond, 1)
+	report = tracker.Report()
+	if tool := toolStatus(t, report, "iban-validate"); tool.Status != StatusDegraded || tool.ErrorRate != 0.0909 {
+		t.Errorf("two failures in 22 = %+v, want degraded", tool)
+	}
+	if report.Status != StatusDegraded || toolStatus(t, report, "qr-generate").Status != StatusOperational {
+		t.Errorf("report = %+v, want degraded overall and the idle tool operational", report)
+	}
+
+	record(tracker, "iban-validate", OutcomeFailed, 8*time.Millisecond, 20)
+	report = tracker.Report()
+	if tool := toolStatus(t, report, "iban-validate"); tool.Status != StatusDown || tool.Errors != 22 || report.Status != StatusDown {
+		t.Errorf("22 failures in 42 = %+v, overall %s, want down", tool, report.Status)
+	}
+
+	// Recovery once the failures leave the window
+	clock.Advance(Window)
+	record(tracker, "iban-validate", OutcomeOK, 8*time.Millisecond, 5)
+	report = tracker.Report()
+	if tool := toolStatus(t, report, "iban-validate"); tool.Status != StatusOperational || tool.Requests != 5 || tool.Errors != 0 ||
+		report.Status != StatusOperational {
+		t.Errorf("after the window = %+v, want operational with only the new requests", tool)
+	}
+}
+
+func TestTrackerWindowSlides(t *testing.T) {
+	clock := newClock(start)
+	tracker := NewTracker(clock, []string{"qr-generate"})
+
+	record(tracker, "qr-generate", OutcomeFailed, time.Millisecond, 10)
+	clock.Advance(3 * time.Minute)
+	record(tracker, "qr-generate", OutcomeOK, time.Millisecond, 10)
+
+	// The failures stay counted until their bucket is a full window old
+	clock.Advance(2*time.Minute - BucketWidth)
+	if tool := toolStatus(t, tracker.Report(), "qr-generate"); tool.Requests != 20 || tool.Status != StatusDown {
+		t.Errorf("one bucket before the edge = %+v, want all 20 requests and down", tool)
+	}
+	clock.Advance(BucketWidth)
+	if tool := toolStatus(t, tracker.Report(), "qr-generate"); tool.Requests != 10 || tool.Errors != 0 || tool.Status != StatusOperational {
+		t.Errorf("at the edge = %+v, want only the 10 later requests", tool)
+	}
+
+	// A reused bucket starts from zero
+	clock.Advance(3 * Window)
+	record(tracker, "qr-generate", OutcomeOK, time.Millisecond, 1)
+	if tool := toolStatus(t, tracker.Report(), "qr-generate"); tool.Requests != 1 {
+		t.Errorf("after the ring wrapped = %+v, want one request", tool)
+	}
+}
+
+func TestTrackerMinRequests(t *testing.T) {
+	tracker := NewTracker(newClock(start), []string{"qr-generate"})
+	record(tracker, "qr-generate", OutcomeFailed, time.Millisecond, minRequests-1)
+	if tool := toolStatus(t, tracker.Report(), "qr-generate"); tool.Status != StatusOperational || tool.ErrorRate != 1 {
+		t.Errorf("%d failures = %+v, want operational below the request minimum", minRequests-1, tool)
+	}
+	record(tracker, "qr-generate", OutcomeFailed, time.Millisecond, 1)
+	if tool := toolStatus(t, tracker.Report(), "qr-generate"); tool.Status != StatusDown {
+		t.Errorf("%d failures = %+v, want down", minRequests, tool)
+	}
+}
+
+func TestTrackerLatency(t *testing.T) {
+	tracker := NewTracker(newClock(start), []string{"fast", "slow", "overflow"})
+	// The 95th of 20 requests is the 19th fastest
+	record(tracker, "fast", OutcomeOK, 30*time.Millisecond, 19)
+	record(tracker, "fast", OutcomeOK, 3*time.Second, 1)
+	record(tracker, "slow", OutcomeOK, 30*time.Millisecond, 18)
+	record(tracker, "slow", OutcomeOK, 3*time.Second, 2)
+	record(tracker, "overflow", OutcomeOK, time.Minute, 1)
+
+	report := tracker.Report()
+	for _, tt := range []struct {
+		tool   string
+		p95Ms  float64
+		status string
+	}{
+		{"fast", 50, StatusOperational},
+		{"slow", 5000, StatusDegraded},
+		{"overflow", 30000, StatusDegraded},
+	} {
+		tool := toolStatus(t, report, tt.tool)
+		if tool.P95LatencyMs == nil || *tool.P95LatencyMs != tt.p95Ms || tool.Status != tt.status {
+			t.Errorf("%s = %+v (p95 %v), want p95 %vms and %s", tt.tool, tool, tool.P95LatencyMs, tt.p95Ms, tt.status)
+		}
+	}
+}
+
+func TestTrackerDisconnects(t *testing.T) {
+	tracker := NewTracker(newClock(start), []string{"qr-generate"})
+	record(tracker, "qr-generate", OutcomeClientDisconnected, time.Minute, 10)
+	record(tracker, "qr-generate", OutcomeOK, time.Millisecond, 5)
+	record(tracker, "unknown", OutcomeFailed, time.Millisecond, 10)
+
+	tool := toolStatus(t, tracker.Report(), "qr-generate")
+	if tool.Requests != 5 || tool.Errors != 0 || tool.ClientDisconnects != 10 || tool.Status != StatusOperational ||
+		tool.P95LatencyMs == nil || *tool.P95LatencyMs != 5 {
+		t.Errorf("tool = %+v, want disconnects outside requests, errors and latency", tool)
+	}
+}
@@ -0,0 +1,108 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrObjectNotFound is returned by Open for a key that holds no object
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectStore keeps upload data by key. Keys are generated by Store and
+// contain only lower-case hex digits.
+type ObjectStore interface {
+	// WriteAt replaces the object key from offset on with the contents
+	// of r, creating it when offset is 0, and returns the bytes written.
+	// A failed write may leave any part of r stored; the next WriteAt at
+	// the same offset replaces it.
+	WriteAt(ctx context.Context, key string, offset int64, r io.Reader) (int64, error)
+	// Open reads the object key from the start
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object key; a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey matches the keys DirStore accepts, so a key can never name a
+// path outside its directory
+var validKey = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// DirStore is an ObjectStore keeping one file per object in a directory
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore creates dir when needed and stores objects in it
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+func (s *DirStore) path(key string) (string, error) {
+	if !validKey.MatchString(key) {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+// WriteAt implements ObjectStore
+func (s *DirStore) WriteAt(ctx context.Context, key string, offset int64, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	flags := os.O_WRONLY
+	if offset == 0 {
+		flags |= os.O_CREATE
+	}
+	f, err := os.OpenFile(path, flags, 0o600)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, ErrObjectNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if err := f.Truncate(offset); err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return n, err
+	}
+	return n, f.Close()
+}
+
+// Open implements ObjectStore
+func (s *DirStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return f, err
+}
+
+// Delete implements ObjectStore
+func (s *DirStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,408 @@
+// Package uploads receives large request bodies in chunks, so a client on
+// a flaky connection can resume an upload instead of starting over. A
+// completed upload is read by token in place of a request body. Uploads
+// belong to the user who created them, and only that user can see,
+// continue or read them.
+package uploads
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/clock"
+)
+
+const (
+	// DefaultTTL is how long an upload is kept after it is created
+	DefaultTTL = 24 * time.Hour
+	// DefaultMaxUploads caps the uploads held at once, complete or not,
+	// and DefaultMaxUploadsPerOwner those of one user
+	DefaultMaxUploads         = 100
+	DefaultMaxUploadsPerOwner = 3
+	// SweepInterval is how often Run deletes expired uploads
+	SweepInterval = 10 * time.Minute
+)
+
+var (
+	ErrUploadNotFound   = errors.New("upload not found")
+	ErrTooManyUploads   = errors.New("too many uploads in progress")
+	ErrOwnerUploadLimit = errors.New("too many uploads of this user; complete or let one expire first")
+	ErrUploadBusy       = errors.New("another chunk of this upload is being received")
+	ErrUploadComplete   = errors.New("upload is already complete")
+	ErrUploadIncomplete = errors.New("upload is incomplete")
+	ErrHashMismatch     = errors.New("uploaded data does not match its sha256")
+	ErrChunkMismatch    = errors.New("chunk differs from the one already received at this range")
+	ErrChunkLength      = errors.New("chunk length does not match its range")
+	ErrChunkRange       = errors.New("chunk range is empty or ends past the upload size")
+	ErrInvalidSHA256    = errors.New("sha256 must be 64 hex digits")
+)
+
+// OffsetError rejects a chunk that does not start where the received data
+// ends
+type OffsetError struct {
+	Start    int64
+	Expected int64
+}
+
+func (e *OffsetError) Error() string {
+	return fmt.Sprintf("chunk starts at byte %d, expected %d", e.Start, e.Expected)
+}
+
+// SizeError rejects an upload size outside what the store accepts
+type SizeError struct {
+	Size int64
+	Max  int64
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("size %d is outside 1..%d bytes", e.Size, e.Max)
+}
+
+// Upload is a snapshot of an upload's state
+type Upload struct {
+	ID string
+	// Owner is the user who created the upload
+	Owner       string
+	ContentType string
+	Size        int64
+	// SHA256 is the expected hex digest, "" when the client gave none
+	SHA256   string
+	Received int64
+	// Token reads the data once the upload is complete; "" until then
+	Token     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type upload struct {
+	Upload
+	// hashState is the marshalled sha256 of the Received bytes, so a
+	// failed chunk leaves it untouched
+	hashState []byte
+	// lastStart, lastEnd and lastSum identify the last chunk, so a client
+	// that missed its response can send it again
+	lastStart, lastEnd int64
+	lastSum            [sha256.Size]byte
+	busy               bool
+}
+
+// Store keeps upload sessions in memory and their data in an ObjectStore.
+// Sessions do not survive a restart; their objects are then orphaned and
+// left to the ObjectStore's own cleanup.
+type Store struct {
+	objects    ObjectStore
+	clock      clock.Clock
+	ttl        time.Duration
+	maxBytes   int64
+	maxUploads int
+	maxOwned   int
+
+	mu      sync.Mutex
+	uploads map[string]*upload
+	tokens  map[string]string
+}
+
+// NewStore creates a Store keeping data in objects, accepting uploads of
+// at most maxBytes and expiring them ttl after creation as told by clk
+func NewStore(objects ObjectStore, clk clock.Clock, maxBytes int64, ttl time.Duration) *Store {
+	return &Store{
+		objects:    objects,
+		clock:      clk,
+		ttl:        ttl,
+		maxBytes:   maxBytes,
+		maxUploads: DefaultMaxUploads,
+		maxOwned:   DefaultMaxUploadsPerOwner,
+		uploads:    map[string]*upload{},
+		tokens:     map[string]string{},
+	}
+}
+
+// Create starts an upload of size bytes for owner. sum is the expected hex
+// sha256 of the data, or "" to skip the check.
+func (s *Store) Create(ctx context.Context, owner string, size int64, sum, contentType string) (Upload, error) {
+	if size < 1 || size > s.maxBytes {
+		return Upload{}, &SizeError{Size: size, Max: s.maxBytes}
+	}
+	if sum != "" {
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+			return Upload{}, ErrInvalidSHA256
+		}
+	}
+	id, err := newID()
+	if err != nil {
+		return Upload{}, err
+	}
+	state, err := sha256.New().(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return Upload{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.uploads) >= s.maxUploads {
+		return Upload{}, ErrTooManyUploads
+	}
+	now := s.clock.Now().UTC()
+	owned := 0
+	for _, u := range s.uploads {
+		if u.Owner == owner && now.Before(u.ExpiresAt) {
+			owned++
+		}
+	}
+	if owned >= s.maxOwned {
+		return Upload{}, ErrOwnerUploadLimit
+	}
+	u := &upload{
+		Upload: Upload{
+			ID:          id,
+			Owner:       owner,
+			ContentType: contentType,
+			Size:        size,
+			SHA256:      hexLower(sum),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(s.ttl),
+		},
+		hashState: state,
+		lastStart: -1,
+	}
+	s.uploads[id] = u
+	return u.Upload, nil
+}
+
+// Get returns the state of owner's upload with id
+func (s *Store) Get(owner, id string) (Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.getLocked(owner, id)
+	if err != nil {
+		return Upload{}, err
+	}
+	return u.Upload, nil
+}
+
+// Append stores body as the bytes start to end (exclusive) of owner's
+// upload. The chunk must start where the received data ends, except that
+// the last chunk may be sent again: an identical copy is accepted without
+// being stored twice, a different one is rejected.
+func (s *Store) Append(ctx context.Context, owner, id string, start, end int64, body io.Reader) (Upload, error) {
+	s.mu.Lock()
+	u, err := s.getLocked(owner, id)
+	if err != nil {
+		s.mu.Unlock()
+		return Upload{}, err
+	}
+	switch {
+	case u.busy:
+		s.mu.Unlock()
+		return Upload{}, ErrUploadBusy
+	case end <= start || end > u.Size:
+		s.mu.Unlock()
+		return Upload{}, ErrChunkRange
+	case start == u.lastStart && end == u.lastEnd:
+		last := u.lastSum
+		snapshot := u.Upload
+		s.mu.Unlock()
+		sum, err := chunkSum(body, end-start)
+		if err != nil {
+			return Upload{}, err
+		}
+		if sum != last {
+			return Upload{}, ErrChunkMismatch
+		}
+		return snapshot, nil
+	case u.Token != "":
+		s.mu.Unlock()
+		return Upload{}, ErrUploadComplete
+	case start != u.Received:
+		s.mu.Unlock()
+		return Upload{}, &OffsetError{Start: start, Expected: u.Received}
+	}
+	u.busy = true
+	state := u.hashState
+	s.mu.Unlock()
+
+	hash := sha256.New()
+	chunk := sha256.New()
+	if err := hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		s.release(u)
+		return Upload{}, err
+	}
+	data := io.TeeReader(io.LimitReader(body, end-start+1), io.MultiWriter(hash, chunk))
+	n, err := s.objects.WriteAt(ctx, id, start, data)
+	if err == nil && n != end-start {
+		err = ErrChunkLength
+	}
+	if err == nil {
+		state, err = hash.(encoding.BinaryMarshaler).MarshalBinary()
+	}
+	if err != nil {
+		s.release(u)
+		return Upload{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u.busy = false
+	u.Received = end
+	u.hashState = state
+	u.lastStart, u.lastEnd = start, end
+	copy(u.lastSum[:], chunk.Sum(nil))
+	return u.Upload, nil
+}
+
+func (s *Store) release(u *upload) {
+	s.mu.Lock()
+	u.busy = false
+	s.mu.Unlock()
+}
+
+// Complete checks that all bytes of owner's upload arrived and match the
+// expected sha256, then returns the upload with its token. Completing twice
+// returns the same token. A hash mismatch deletes the upload.
+func (s *Store) Complete(ctx context.Context, owner, id string) (Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.getLocked(owner, id)
+	if err != nil {
+		return Upload{}, err
+	}
+	if u.Token != "" {
+		return u.Upload, nil
+	}
+	if u.busy || u.Received != u.Size {
+		return Upload{}, ErrUploadIncomplete
+	}
+	hash := sha256.New()
+	if err := hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(u.hashState); err != nil {
+		return Upload{}, err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if u.SHA256 != "" && sum != u.SHA256 {
+		s.deleteLocked(ctx, u)
+		return Upload{}, ErrHashMismatch
+	}
+	token, err := newID()
+	if err != nil {
+		return Upload{}, err
+	}
+	u.SHA256 = sum
+	u.Token = token
+	s.tokens[token] = id
+	return u.Upload, nil
+}
+
+// Open reads the data of owner's completed upload with token
+func (s *Store) Open(ctx context.Context, owner, token string) (io.ReadCloser, Upload, error) {
+	s.mu.Lock()
+	id, ok := s.tokens[token]
+	if !ok {
+		s.mu.Unlock()
+		return nil, Upload{}, ErrUploadNotFound
+	}
+	u, err := s.getLocked(owner, id)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, Upload{}, err
+	}
+	data, err := s.objects.Open(ctx, id)
+	if err != nil {
+		return nil, Upload{}, err
+	}
+	return data, u.Upload, nil
+}
+
+// getLocked returns owner's upload with id, deleting it when it has
+// expired. Uploads of other users are not found.
+func (s *Store) getLocked(owner, id string) (*upload, error) {
+	u, ok := s.uploads[id]
+	if !ok || u.Owner != owner {
+		return nil, ErrUploadNotFound
+	}
+	if !s.clock.Now().Before(u.ExpiresAt) && !u.busy {
+		s.deleteLocked(context.Background(), u)
+		return nil, ErrUploadNotFound
+	}
+	return u, nil
+}
+
+func (s *Store) deleteLocked(ctx context.Context, u *upload) {
+	delete(s.uploads, u.ID)
+	if u.Token != "" {
+		delete(s.tokens, u.Token)
+	}
+	if err := s.objects.Delete(ctx, u.ID); err != nil {
+		log.Printf("Failed to delete upload %s: %v", u.ID, err)
+	}
+}
+
+// Sweep deletes the expired uploads and returns how many there were.
+// Uploads receiving a chunk are kept until the chunk is stored.
+func (s *Store) Sweep(ctx context.Context) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	swept := 0
+	for _, u := range s.uploads {
+		if !now.Before(u.ExpiresAt) && !u.busy {
+			s.deleteLocked(ctx, u)
+			swept++
+		}
+	}
+	return swept
+}
+
+// Run sweeps every interval until ctx is done
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if swept := s.Sweep(ctx); swept > 0 {
+			log.Printf("Deleted %d expired uploads", swept)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// chunkSum hashes a re-sent chunk of length bytes
+func chunkSum(body io.Reader, length int64) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	hash := sha256.New()
+	n, err := io.Copy(hash, io.LimitReader(body, length+1))
+	if err != nil {
+		return sum, err
+	}
+	if n != length {
+		return sum, ErrChunkLength
+	}
+	copy(sum[:], hash.Sum(nil))
+	return sum, nil
+}
+
+func hexLower(s string) string {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return s
+	}
+	return hex.EncodeToString(b)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
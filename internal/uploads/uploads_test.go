@@ -0,0 +1,182 @@
+package uploads_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/testutil"
+	"github.com/innovelabs/microtools-go/internal/uploads"
+)
+
+func newTestStore(t *testing.T) (*uploads.Store, *testutil.Clock) {
+	t.Helper()
+	objects, err := uploads.NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	return uploads.NewStore(objects, clock, 1<<20, time.Hour), clock
+}
+
+func TestUploadsBelongToTheirOwner(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(t)
+	u, err := store.Create(ctx, "ada@example.com", 5, "", "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Get("eve@example.com", u.ID); !errors.Is(err, uploads.ErrUploadNotFound) {
+		t.Errorf("Get by another user: err = %v, want uploads.ErrUploadNotFound", err)
+	}
+	if _, err := store.Append(ctx, "eve@example.com", u.ID, 0, 5, strings.NewReader("hello")); !errors.Is(err, uploads.ErrUploadNotFound) {
+		t.Errorf("Append by another user: err = %v, want uploads.ErrUploadNotFound", err)
+	}
+	if _, err := store.Append(ctx, "ada@example.com", u.ID, 0, 5, strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Complete(ctx, "eve@example.com", u.ID); !errors.Is(err, uploads.ErrUploadNotFound) {
+		t.Errorf("Complete by another user: err = %v, want uploads.ErrUploadNotFound", err)
+	}
+	done, err := store.Complete(ctx, "ada@example.com", u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := store.Open(ctx, "eve@example.com", done.Token); !errors.Is(err, uploads.ErrUploadNotFound) {
+		t.Errorf("Open by another user: err = %v, want uploads.ErrUploadNotFound", err)
+	}
+	data, _, err := store.Open(ctx, "ada@example.com", done.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	if b, _ := io.ReadAll(data); string(b) != "hello" {
+		t.Errorf("data = %q, want %q", b, "hello")
+	}
+}
+
+func TestUploadsPerOwnerLimit(t *testing.T) {
+	ctx := context.Background()
+	store, clock := newTestStore(t)
+	for i := 0; i < uploads.DefaultMaxUploadsPerOwner; i++ {
+		if _, err := store.Create(ctx, "ada@example.com", 1, "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Create(ctx, "ada@example.com", 1, "", ""); !errors.Is(err, uploads.ErrOwnerUploadLimit) {
+		t.Fatalf("err = %v, want uploads.ErrOwnerUploadLimit", err)
+	}
+	if _, err := store.Create(ctx, "bob@example.com", 1, "", ""); err != nil {
+		t.Errorf("another user: %v", err)
+	}
+
+	clock.Advance(time.Hour)
+	if _, err := store.Create(ctx, "ada@example.com", 1, "", ""); err != nil {
+		t.Errorf("after the uploads expired: %v", err)
+	}
+}
+
+func TestUploadSizeLimit(t *testing.T) {
+	store, _ := newTestStore(t)
+	var size *uploads.SizeError
+	if _, err := store.Create(context.Background(), "ada@example.com", 1<<20+1, "", ""); !errors.As(err, &size) {
+		t.Errorf("err = %v, want a SizeError", err)
+	}
+}
+
+func TestUploadChunks(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(t)
+	u, err := store.Create(ctx, "ada@example.com", 10, "", "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Append(ctx, "ada@example.com", u.ID, 0, 5, strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A chunk past the received data is refused with where to resume
+	var offset *uploads.OffsetError
+	if _, err := store.Append(ctx, "ada@example.com", u.ID, 7, 10, strings.NewReader("rld")); !errors.As(err, &offset) || offset.Expected != 5 {
+		t.Fatalf("out of order chunk: err = %v, want an OffsetError expecting 5", err)
+	}
+
+	// The last chunk may be sent again; only an identical copy is accepted
+	// and it is not stored twice
+	got, err := store.Append(ctx, "ada@example.com", u.ID, 0, 5, strings.NewReader("hello"))
+	if err != nil || got.Received != 5 {
+		t.Fatalf("re-sent chunk: received %d, err = %v", got.Received, err)
+	}
+	if _, err := store.Append(ctx, "ada@example.com", u.ID, 0, 5, strings.NewReader("HELLO")); !errors.Is(err, uploads.ErrChunkMismatch) {
+		t.Fatalf("different re-sent chunk: err = %v, want uploads.ErrChunkMismatch", err)
+	}
+
+	if _, err := store.Append(ctx, "ada@example.com", u.ID, 5, 10, strings.NewReader("world")); err != nil {
+		t.Fatal(err)
+	}
+	done, err := store.Complete(ctx, "ada@example.com", u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _, err := store.Open(ctx, "ada@example.com", done.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	if b, _ := io.ReadAll(data); string(b) != "helloworld" {
+		t.Errorf("data = %q, want %q", b, "helloworld")
+	}
+}
+
+func TestUploadHashMismatch(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(t)
+	// The sha256 of "hello"
+	const sum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	u, err := store.Create(ctx, "ada@example.com", 5, sum, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Append(ctx, "ada@example.com", u.ID, 0, 5, strings.NewReader("jello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Complete(ctx, "ada@example.com", u.ID); !errors.Is(err, uploads.ErrHashMismatch) {
+		t.Fatalf("err = %v, want uploads.ErrHashMismatch", err)
+	}
+	if _, err := store.Get("ada@example.com", u.ID); !errors.Is(err, uploads.ErrUploadNotFound) {
+		t.Errorf("after the mismatch: err = %v, want the upload deleted", err)
+	}
+}
+
+func TestUploadSweep(t *testing.T) {
+	ctx := context.Background()
+	store, clock := newTestStore(t)
+	u, err := store.Create(ctx, "ada@example.com", 5, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Append(ctx, "ada@example.com", u.ID, 0, 5, strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	done, err := store.Complete(ctx, "ada@example.com", u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Hour - time.Second)
+	if swept := store.Sweep(ctx); swept != 0 {
+		t.Fatalf("before expiry: swept %d", swept)
+	}
+	clock.Advance(time.Second)
+	if swept := store.Sweep(ctx); swept != 1 {
+		t.Fatalf("after expiry: swept %d, want 1", swept)
+	}
+	if _, _, err := store.Open(ctx, "ada@example.com", done.Token); !errors.Is(err, uploads.ErrUploadNotFound) {
+		t.Errorf("Open after the sweep: err = %v, want uploads.ErrUploadNotFound", err)
+	}
+}
@@ -1,32 +1,252 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt"
-	"github.com/innovelabs/microtools-go/internal/config"
 )
 
-// GenerateJWT generates a JWT token for a user
-func GenerateJWT(email string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": email,
-		"exp":   time.Now().Add(time.Hour * 24 * 30).Unix(),
-	})
-	cfg := config.LoadConfig()
-	return token.SignedString([]byte(cfg.JWTSecret))
+const (
+	// TokenPurposeAccess marks API access tokens
+	TokenPurposeAccess = "access"
+	// TokenPurposeEmailVerification marks single-use email verification tokens
+	TokenPurposeEmailVerification = "email_verification"
+
+	// AccessTokenTTL is how long an access token stays valid
+	AccessTokenTTL = 30 * 24 * time.Hour
+	// VerificationTokenTTL is how long an email verification link stays valid
+	VerificationTokenTTL = 24 * time.Hour
+)
+
+var (
+	// ErrTokenInvalid is returned for a token that verifies but is not
+	// acceptable: no expiry, the wrong issuer or audience, or a missing
+	// claim. ErrTokenMalformed, ErrTokenInvalidSignature and
+	// ErrTokenExpired are the more specific failures.
+	ErrTokenInvalid          = errors.New("invalid token")
+	ErrTokenMalformed        = errors.New("malformed token")
+	ErrTokenInvalidSignature = errors.New("invalid token signature")
+	ErrTokenExpired          = errors.New("token expired")
+	ErrTokenPurpose          = errors.New("token has the wrong purpose")
+	ErrNoSecret              = errors.New("JWT secret is not configured")
+	// ErrTokenRevoked means a valid token was invalidated, e.g. because
+	// its user was deleted
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+// JWTConfig signs and scopes the service's tokens. Issuer and Audience,
+// when set, are written to the iss and aud claims of issued tokens and
+// required of validated ones, so setting them invalidates tokens issued
+// before.
+type JWTConfig struct {
+	Secret   string
+	Issuer   string
+	Audience string
+}
+
+// Claims are the claims of the service's tokens. Tokens issued before the
+// purpose claim existed are access tokens.
+type Claims struct {
+	Email   string `json:"email"`
+	Purpose string `json:"purpose,omitempty"`
+	// Tenant is the tenant of an access token, "" for the default one
+	Tenant string `json:"tenant,omitempty"`
+	// Nonce makes an email verification token single-use
+	Nonce string `json:"nonce,omitempty"`
+	jwt.StandardClaims
+}
+
+// IssuedAtTime returns the iat claim, or the zero time for tokens issued
+// before it existed
+func (c *Claims) IssuedAtTime() time.Time {
+	if c.IssuedAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(c.IssuedAt, 0)
+}
+
+// ExpiresAtTime returns the exp claim, which ParseToken requires
+func (c *Claims) ExpiresAtTime() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// TokenOption configures a token issued by IssueToken
+type TokenOption func(*tokenOptions)
+
+type tokenOptions struct {
+	claims Claims
+	ttl    time.Duration
+}
+
+// WithTTL sets how long the token stays valid; AccessTokenTTL by default
+func WithTTL(ttl time.Duration) TokenOption {
+	return func(o *tokenOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithPurpose sets the purpose claim; TokenPurposeAccess by default
+func WithPurpose(purpose string) TokenOption {
+	return func(o *tokenOptions) {
+		o.claims.Purpose = purpose
+	}
+}
+
+// WithTenant sets the tenant claim; none, the default tenant, by default
+func WithTenant(tenant string) TokenOption {
+	return func(o *tokenOptions) {
+		o.claims.Tenant = tenant
+	}
+}
+
+// WithNonce sets the nonce claim
+func WithNonce(nonce string) TokenOption {
+	return func(o *tokenOptions) {
+		o.claims.Nonce = nonce
+	}
+}
+
+// IssueToken signs a token for email with HS256
+func IssueToken(cfg JWTConfig, email string, opts ...TokenOption) (string, error) {
+	if cfg.Secret == "" {
+		return "", ErrNoSecret
+	}
+	o := tokenOptions{claims: Claims{Email: email, Purpose: TokenPurposeAccess}, ttl: AccessTokenTTL}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	now := time.Now()
+	o.claims.IssuedAt = now.Unix()
+	o.claims.ExpiresAt = now.Add(o.ttl).Unix()
+	o.claims.Issuer = cfg.Issuer
+	o.claims.Audience = cfg.Audience
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, o.claims).SignedString([]byte(cfg.Secret))
 }
 
-// ValidateJWT validates a JWT token
-func ValidateJWT(tokenString string) (string, error) {
-	cfg := config.LoadConfig()
+// GenerateJWT generates an access token for a user of the default tenant
+func GenerateJWT(cfg JWTConfig, email string) (string, error) {
+	return IssueToken(cfg, email)
+}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(cfg.JWTSecret), nil
+// ParseToken verifies a token signed with HS256 and returns its claims. It
+// requires an unexpired expiry, an iat not in the future when present, and
+// the configured issuer and audience, and never panics, whatever
+// tokenString holds.
+func ParseToken(cfg JWTConfig, tokenString string) (*Claims, error) {
+	if cfg.Secret == "" {
+		return nil, ErrNoSecret
+	}
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("%w: unexpected signing method %v", ErrTokenInvalidSignature, token.Header["alg"])
+		}
+		return []byte(cfg.Secret), nil
 	})
+	if err != nil {
+		return nil, tokenError(err)
+	}
+	switch {
+	case claims.ExpiresAt == 0:
+		return nil, fmt.Errorf("%w: no expiry", ErrTokenInvalid)
+	case cfg.Issuer != "" && !claims.VerifyIssuer(cfg.Issuer, true):
+		return nil, fmt.Errorf("%w: wrong issuer", ErrTokenInvalid)
+	case cfg.Audience != "" && !claims.VerifyAudience(cfg.Audience, true):
+		return nil, fmt.Errorf("%w: wrong audience", ErrTokenInvalid)
+	}
+	return claims, nil
+}
+
+// tokenError maps a jwt parse error to the package's errors, the signature
+// first, as the library also reports the claims of a forged token
+func tokenError(err error) error {
+	var verr *jwt.ValidationError
+	if !errors.As(err, &verr) {
+		return fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+	switch {
+	case verr.Errors&(jwt.ValidationErrorUnverifiable|jwt.ValidationErrorSignatureInvalid) != 0:
+		return fmt.Errorf("%w: %v", ErrTokenInvalidSignature, err)
+	case verr.Errors&jwt.ValidationErrorMalformed != 0:
+		return fmt.Errorf("%w: %v", ErrTokenMalformed, err)
+	case verr.Errors&jwt.ValidationErrorExpired != 0:
+		return ErrTokenExpired
+	default:
+		return fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+}
+
+// TokenTenant returns the tenant of a valid access token, or "" for the
+// default tenant and for tokens that do not verify
+func TokenTenant(cfg JWTConfig, tokenString string) string {
+	claims, err := ParseToken(cfg, tokenString)
+	if err != nil || !claims.isAccess() {
+		return ""
+	}
+	return claims.Tenant
+}
+
+// ValidateJWT validates an access token and returns its claims, with an
+// email. Errors wrap ErrTokenExpired, ErrTokenInvalidSignature and the
+// other token errors so callers can tell them apart.
+func ValidateJWT(cfg JWTConfig, tokenString string) (*Claims, error) {
+	claims, err := ParseToken(cfg, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.isAccess() {
+		return nil, ErrTokenPurpose
+	}
+	if claims.Email == "" {
+		return nil, fmt.Errorf("%w: no email", ErrTokenInvalid)
+	}
+	return claims, nil
+}
+
+func (c *Claims) isAccess() bool {
+	return c.Purpose == "" || c.Purpose == TokenPurposeAccess
+}
+
+// GenerateVerificationToken creates an email verification token valid for
+// VerificationTokenTTL. The returned nonce is stored with the user so the
+// token can be used once.
+func GenerateVerificationToken(cfg JWTConfig, email string) (token string, nonce string, err error) {
+	if cfg.Secret == "" {
+		return "", "", ErrNoSecret
+	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims["email"].(string), nil
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	nonce = hex.EncodeToString(buf)
+
+	token, err = IssueToken(cfg, email,
+		WithPurpose(TokenPurposeEmailVerification),
+		WithTTL(VerificationTokenTTL),
+		WithNonce(nonce))
+	if err != nil {
+		return "", "", err
+	}
+	return token, nonce, nil
+}
+
+// ValidateVerificationToken checks the signature, expiry and purpose of an
+// email verification token and returns its email and nonce.
+func ValidateVerificationToken(cfg JWTConfig, tokenString string) (email string, nonce string, err error) {
+	claims, err := ParseToken(cfg, tokenString)
+	if err != nil {
+		return "", "", err
+	}
+	if claims.Purpose != TokenPurposeEmailVerification {
+		return "", "", ErrTokenPurpose
+	}
+	if claims.Email == "" || claims.Nonce == "" {
+		return "", "", ErrTokenInvalid
 	}
-	return "", err
+	return claims.Email, claims.Nonce, nil
 }
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var testJWT = JWTConfig{Secret: "test-secret", Issuer: "microtools", Audience: "microtools-api"}
+
+// signed signs claims with method and key, bypassing IssueToken
+func signed(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestParseToken(t *testing.T) {
+	valid, err := IssueToken(testJWT, "ada@example.com", WithTenant("acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseToken(testJWT, valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Email != "ada@example.com" || claims.Tenant != "acme" || claims.Purpose != TokenPurposeAccess {
+		t.Errorf("claims = %+v", claims)
+	}
+	if ttl := time.Unix(claims.ExpiresAt, 0).Sub(claims.IssuedAtTime()); ttl != AccessTokenTTL {
+		t.Errorf("token valid for %v, want %v", ttl, AccessTokenTTL)
+	}
+
+	expired, err := IssueToken(testJWT, "ada@example.com", WithTTL(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherIssuer, err := IssueToken(JWTConfig{Secret: testJWT.Secret, Issuer: "elsewhere", Audience: testJWT.Audience}, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherAudience, err := IssueToken(JWTConfig{Secret: testJWT.Secret, Issuer: testJWT.Issuer, Audience: "elsewhere"}, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongSecret, err := IssueToken(JWTConfig{Secret: "another-secret", Issuer: testJWT.Issuer, Audience: testJWT.Audience}, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	standard := jwt.StandardClaims{Issuer: testJWT.Issuer, Audience: testJWT.Audience, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	algNone := signed(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, &Claims{Email: "ada@example.com", StandardClaims: standard})
+	hs512 := signed(t, jwt.SigningMethodHS512, []byte(testJWT.Secret), &Claims{Email: "ada@example.com", StandardClaims: standard})
+	future := standard
+	future.IssuedAt = time.Now().Add(time.Hour).Unix()
+	issuedLater := signed(t, jwt.SigningMethodHS256, []byte(testJWT.Secret), &Claims{Email: "ada@example.com", StandardClaims: future})
+	noExpiry := signed(t, jwt.SigningMethodHS256, []byte(testJWT.Secret), &Claims{Email: "ada@example.com", StandardClaims: jwt.StandardClaims{Issuer: testJWT.Issuer, Audience: testJWT.Audience}})
+
+	tests := []struct {
+		name, token string
+		want        error
+	}{
+		{"empty", "", ErrTokenMalformed},
+		{"not a JWT", "not-a-token", ErrTokenMalformed},
+		{"bad base64", "a.b.c", ErrTokenMalformed},
+		{"claims not JSON", "eyJhbGciOiJIUzI1NiJ9.bm90IGpzb24.c2ln", ErrTokenMalformed},
+		{"alg none", algNone, ErrTokenInvalidSignature},
+		{"other HMAC", hs512, ErrTokenInvalidSignature},
+		{"wrong secret", wrongSecret, ErrTokenInvalidSignature},
+		{"expired", expired, ErrTokenExpired},
+		{"no expiry", noExpiry, ErrTokenInvalid},
+		{"issued in the future", issuedLater, ErrTokenInvalid},
+		{"wrong issuer", otherIssuer, ErrTokenInvalid},
+		{"wrong audience", otherAudience, ErrTokenInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseToken(testJWT, tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseToken(JWTConfig{}, valid); !errors.Is(err, ErrNoSecret) {
+		t.Errorf("without a secret err = %v, want ErrNoSecret", err)
+	}
+	// Issuer and audience are only required once configured
+	if _, err := ParseToken(JWTConfig{Secret: testJWT.Secret}, otherIssuer); err != nil {
+		t.Errorf("unscoped config refused a token: %v", err)
+	}
+}
+
+func TestTokenPurposes(t *testing.T) {
+	token, nonce, err := GenerateVerificationToken(testJWT, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, gotNonce, err := ValidateVerificationToken(testJWT, token)
+	if err != nil || email != "ada@example.com" || gotNonce != nonce {
+		t.Errorf("ValidateVerificationToken = %q, %q, %v", email, gotNonce, err)
+	}
+	if _, err := ValidateJWT(testJWT, token); !errors.Is(err, ErrTokenPurpose) {
+		t.Errorf("verification token as access token: err = %v, want ErrTokenPurpose", err)
+	}
+
+	access, err := GenerateJWT(testJWT, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ValidateVerificationToken(testJWT, access); !errors.Is(err, ErrTokenPurpose) {
+		t.Errorf("access token as verification token: err = %v, want ErrTokenPurpose", err)
+	}
+	claims, err := ValidateJWT(testJWT, access)
+	if err != nil || claims.Email != "ada@example.com" || claims.ExpiresAtTime().Sub(claims.IssuedAtTime()) != AccessTokenTTL {
+		t.Errorf("ValidateJWT = %+v, %v", claims, err)
+	}
+}
+
+// FuzzParseToken checks that no input panics and that only the package's
+// errors are returned
+func FuzzParseToken(f *testing.F) {
+	valid, err := GenerateJWT(testJWT, "ada@example.com")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, seed := range []string{"", ".", "..", "a.b.c", valid, valid + "x", "eyJhbGciOiJub25lIn0.e30.", "eyJhbGciOiJIUzI1NiJ9.bnVsbA.c2ln"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, token string) {
+		claims, err := ParseToken(testJWT, token)
+		switch {
+		case err == nil && claims == nil:
+			t.Fatal("no claims and no error")
+		case err != nil && !errors.Is(err, ErrTokenMalformed) && !errors.Is(err, ErrTokenInvalidSignature) &&
+			!errors.Is(err, ErrTokenExpired) && !errors.Is(err, ErrTokenInvalid):
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+}
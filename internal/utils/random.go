@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// base58Alphabet leaves out 0, O, I and l, which are easily confused
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base62Alphabet is every ASCII letter and digit
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// RandomBase58 returns n characters drawn uniformly from the base58
+// alphabet with crypto/rand, for unguessable IDs
+func RandomBase58(n int) (string, error) {
+	return randomString(base58Alphabet, n)
+}
+
+// RandomBase62 returns n characters drawn uniformly from letters and
+// digits with crypto/rand, for short codes that need no escaping in URLs
+func RandomBase62(n int) (string, error) {
+	return randomString(base62Alphabet, n)
+}
+
+func randomString(alphabet string, n int) (string, error) {
+	// Rejecting bytes from the largest multiple of the alphabet's size up
+	// keeps b % size uniform
+	size := len(alphabet)
+	limit := 256 - 256%size
+	out := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(out) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(out) < n {
+				out = append(out, alphabet[int(b)%size])
+			}
+		}
+	}
+	return string(out), nil
+}
+
+// IsBase58 reports whether s consists of base58 characters only
+func IsBase58(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(base58Alphabet, s[i]) < 0 {
+			return false
+		}
+	}
+	return s != ""
+}
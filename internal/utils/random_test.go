@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRandomBase58(t *testing.T) {
+	seen := map[string]bool{}
+	for range 1000 {
+		id, err := RandomBase58(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(id) != 10 || !IsBase58(id) || strings.ContainsAny(id, "0OIl") {
+			t.Fatalf("RandomBase58(10) = %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("%q drawn twice", id)
+		}
+		seen[id] = true
+	}
+
+	for s, want := range map[string]bool{"": false, "4kTq9ZbWxY": true, "4kTq9ZbWx0": false, "4kTq9ZbWx/": false} {
+		if IsBase58(s) != want {
+			t.Errorf("IsBase58(%q) = %v, want %v", s, !want, want)
+		}
+	}
+}
+
+func TestRandomBase62(t *testing.T) {
+	counts := map[rune]int{}
+	for range 1000 {
+		code, err := RandomBase62(7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 7 {
+			t.Fatalf("RandomBase62(7) = %q", code)
+		}
+		for _, c := range code {
+			if !strings.ContainsRune(base62Alphabet, c) {
+				t.Fatalf("RandomBase62(7) = %q", code)
+			}
+			counts[c]++
+		}
+	}
+	if len(counts) != 62 {
+		t.Errorf("%d of 62 characters drawn in 7000", len(counts))
+	}
+}
@@ -0,0 +1,85 @@
+// Package warnings collects non-fatal issues found while serving a request:
+// the result is still returned, but part of the input was changed or
+// ignored. A Collector travels in the request context so services record
+// into it without a warnings return value of their own.
+package warnings
+
+import (
+	"context"
+	"sync"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+// Warning codes are stable: clients may match on them
+const (
+	CodeTextSanitized     = "text_sanitized"
+	CodeTextNormalized    = "text_normalized"
+	CodeOptionIgnored     = "option_ignored"
+	CodeLowModuleSize     = "low_module_size"
+	CodeIBANSeparators    = "iban_separators"
+	CodeFuzzyTimeBudget   = "fuzzy_time_budget"
+	CodeTextGlyphsMissing = "text_glyphs_missing"
+)
+
+type contextKey struct{}
+
+// Collector records the warnings of one request. Its methods are safe to
+// call on a nil Collector, which discards everything, so services need not
+// check whether their caller reports warnings.
+type Collector struct {
+	mu   sync.Mutex
+	list []models.Warning
+}
+
+// New creates an empty Collector
+func New() *Collector {
+	return &Collector{}
+}
+
+// NewContext returns a copy of ctx carrying c
+func NewContext(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the Collector of ctx, or nil when the caller does not
+// report warnings
+func FromContext(ctx context.Context) *Collector {
+	c, _ := ctx.Value(contextKey{}).(*Collector)
+	return c
+}
+
+// Add records a warning; field names the request field it concerns and may
+// be empty. A repeated code for the same field is recorded once.
+func (c *Collector) Add(code, field, message string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, w := range c.list {
+		if w.Code == code && w.Field == field {
+			return
+		}
+	}
+	c.list = append(c.list, models.Warning{Code: code, Message: message, Field: field})
+}
+
+// List returns the recorded warnings in the order they were added, or nil
+// when there are none
+func (c *Collector) List() []models.Warning {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.list) == 0 {
+		return nil
+	}
+	return append([]models.Warning(nil), c.list...)
+}
+
+// Add records a warning in the Collector of ctx, if any
+func Add(ctx context.Context, code, field, message string) {
+	FromContext(ctx).Add(code, field, message)
+}
@@ -0,0 +1,46 @@
+package warnings
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+)
+
+func TestCollector(t *testing.T) {
+	// Without a collector the warnings are discarded
+	Add(context.Background(), CodeOptionIgnored, "options.size", "ignored")
+	if list := FromContext(context.Background()).List(); list != nil {
+		t.Errorf("nil collector listed %v", list)
+	}
+
+	c := New()
+	ctx := NewContext(context.Background(), c)
+	Add(ctx, CodeOptionIgnored, "options.title", "first")
+	Add(ctx, CodeOptionIgnored, "options.title", "repeated")
+	Add(ctx, CodeOptionIgnored, "options.desc", "other field")
+	Add(ctx, CodeLowModuleSize, "", "no field")
+	want := []models.Warning{
+		{Code: CodeOptionIgnored, Field: "options.title", Message: "first"},
+		{Code: CodeOptionIgnored, Field: "options.desc", Message: "other field"},
+		{Code: CodeLowModuleSize, Message: "no field"},
+	}
+	list := c.List()
+	if len(list) != len(want) {
+		t.Fatalf("list = %+v", list)
+	}
+	for i := range want {
+		if list[i] != want[i] {
+			t.Errorf("warning %d = %+v, want %+v", i, list[i], want[i])
+		}
+	}
+
+	data, err := json.Marshal(list[2])
+	if err != nil || string(data) != `{"code":"low_module_size","message":"no field"}` {
+		t.Errorf("JSON = %s, %v, want the empty field omitted", data, err)
+	}
+	if New().List() != nil {
+		t.Error("an empty collector must list nil so the JSON field is omitted")
+	}
+}
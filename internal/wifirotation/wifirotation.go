@@ -0,0 +1,389 @@
+// Package wifirotation keeps guest WiFi networks whose password rotates on
+// a schedule or on demand. A network's current password is stored sealed
+// with the rotation key next to its hash, and is served as a WiFi QR code
+// to the user who created the network.
+package wifirotation
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/clock"
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/services/generator"
+)
+
+const (
+	// TickInterval is how often Run rotates the networks that are due
+	TickInterval = time.Minute
+	// RotationWindow is how long after a rotation an on-demand rotation
+	// returns the password just set instead of replacing it, so a double
+	// click or a retried request does not lock guests out twice
+	RotationWindow = time.Minute
+
+	DefaultInterval       = 24 * time.Hour
+	MinInterval           = 15 * time.Minute
+	MaxInterval           = 90 * 24 * time.Hour
+	DefaultPasswordLength = 20
+	// MaxPasswordLength is the longest WPA passphrase
+	MaxPasswordLength = 63
+	// MinPasswordBits is the least entropy a policy's passwords may carry
+	MinPasswordBits = 64
+	DefaultCharset  = generator.TokenCharsetUnambiguous
+
+	SecurityWPA = "WPA"
+	SecuritySAE = "SAE"
+
+	// maxSSIDBytes is the longest SSID 802.11 allows
+	maxSSIDBytes = 32
+	// lockStripes is the number of mutexes networks are spread over
+	lockStripes = 64
+)
+
+var (
+	ErrInvalidNetwork = errors.New("invalid WiFi network")
+	// ErrSealedPassword means a stored password does not open with the
+	// configured key, e.g. after the key was changed
+	ErrSealedPassword = errors.New("stored WiFi password cannot be decrypted with the configured key")
+)
+
+// namePattern restricts network names to what reads well in a URL
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Network is a stored network with its current password
+type Network struct {
+	Profile  models.WifiProfile
+	Password string
+}
+
+// Payload returns the WIFI: string encoding the network's current password
+func (n Network) Payload() string {
+	return generator.WifiPayload(n.WifiData())
+}
+
+// WifiData returns the network as the data of a "wifi" QR code
+func (n Network) WifiData() models.WifiData {
+	return models.WifiData{SSID: n.Profile.SSID, Password: n.Password, Security: n.Profile.Security}
+}
+
+// Rotator creates, serves and rotates networks. Rotations of one network
+// are serialized within the process by a striped lock and across replicas
+// by the store's version check, so concurrent requests rotate at most once.
+type Rotator struct {
+	store repository.WifiProfileStore
+	users repository.UserRepository
+	aead  cipher.AEAD
+	clock clock.Clock
+	locks [lockStripes]sync.Mutex
+}
+
+// NewRotator creates a Rotator keeping networks in store and sealing their
+// passwords with key, a 32-byte AES-256 key. Scheduled rotations skip the
+// networks of owners users reports deleted or unknown; with nil users
+// every due network rotates.
+func NewRotator(store repository.WifiProfileStore, users repository.UserRepository, key []byte, clk clock.Clock) (*Rotator, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Rotator{store: store, users: users, aead: aead, clock: clk}, nil
+}
+
+// Create stores owner's network described by req with a first password
+func (r *Rotator) Create(ctx context.Context, owner string, req models.WifiRotationRequest) (Network, error) {
+	profile, err := newProfile(owner, req)
+	if err != nil {
+		return Network{}, err
+	}
+	now := r.clock.Now().UTC()
+	profile.CreatedAt = now
+	network, err := r.nextPassword(profile, now)
+	if err != nil {
+		return Network{}, err
+	}
+	if err := r.store.Create(ctx, network.Profile); err != nil {
+		return Network{}, err
+	}
+	return network, nil
+}
+
+// Current returns owner's network called name with its current password
+func (r *Rotator) Current(ctx context.Context, owner, name string) (Network, error) {
+	profile, err := r.store.Get(ctx, owner, name)
+	if err != nil {
+		return Network{}, err
+	}
+	return r.open(profile)
+}
+
+// Rotate gives owner's network called name a new password now. Within
+// RotationWindow of the last rotation, or when another replica rotates it
+// at the same time, the password already set is returned with rotated
+// false.
+func (r *Rotator) Rotate(ctx context.Context, owner, name string) (network Network, rotated bool, err error) {
+	id := repository.WifiProfileID(owner, name)
+	unlock := r.lock(id)
+	defer unlock()
+
+	profile, err := r.store.Get(ctx, owner, name)
+	if err != nil {
+		return Network{}, false, err
+	}
+	if r.clock.Now().Sub(profile.RotatedAt) < RotationWindow {
+		network, err := r.open(profile)
+		return network, false, err
+	}
+	return r.rotate(ctx, profile)
+}
+
+// Delete removes owner's network called name
+func (r *Rotator) Delete(ctx context.Context, owner, name string) error {
+	unlock := r.lock(repository.WifiProfileID(owner, name))
+	defer unlock()
+	return r.store.Delete(ctx, owner, name)
+}
+
+// Networks returns owner's networks with their current passwords, ordered
+// by name
+func (r *Rotator) Networks(ctx context.Context, owner string) ([]Network, error) {
+	profiles, err := r.store.ListByOwner(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	networks := make([]Network, 0, len(profiles))
+	for _, profile := range profiles {
+		network, err := r.open(profile)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// DeleteOwner removes all of owner's networks and returns how many there
+// were. The retention sweep calls it for users being purged.
+func (r *Rotator) DeleteOwner(ctx context.Context, owner string) (int, error) {
+	return r.store.DeleteByOwner(ctx, owner)
+}
+
+// RotateDue rotates every network whose next rotation has come and returns
+// how many it rotated. A network that fails to rotate is logged and left
+// for the next call.
+func (r *Rotator) RotateDue(ctx context.Context) (int, error) {
+	due, err := r.store.Due(ctx, r.clock.Now())
+	if err != nil {
+		return 0, err
+	}
+	rotated := 0
+	for _, profile := range due {
+		ok, err := r.rotateDue(ctx, profile)
+		if err != nil {
+			log.Printf("Failed to rotate WiFi network %s: %v", profile.ID, err)
+			continue
+		}
+		if ok {
+			rotated++
+		}
+	}
+	return rotated, nil
+}
+
+// Run rotates the networks that are due every interval until ctx is done
+func (r *Rotator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RotateDue(ctx); err != nil {
+			log.Printf("Failed to list the WiFi networks due for rotation: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rotateDue rotates profile if it is still due once its lock is held: an
+// on-demand rotation may have run since it was listed
+func (r *Rotator) rotateDue(ctx context.Context, listed models.WifiProfile) (bool, error) {
+	unlock := r.lock(listed.ID)
+	defer unlock()
+
+	profile, err := r.store.Get(ctx, listed.Owner, listed.Name)
+	if errors.Is(err, repository.ErrWifiProfileNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if r.clock.Now().Before(profile.NextRotationAt) {
+		return false, nil
+	}
+	if active, err := r.ownerActive(ctx, profile.Owner); err != nil || !active {
+		return false, err
+	}
+	_, rotated, err := r.rotate(ctx, profile)
+	return rotated, err
+}
+
+// ownerActive reports whether owner is a user that is not deleted. The
+// networks of deleted users wait for the retention sweep without
+// rotating.
+func (r *Rotator) ownerActive(ctx context.Context, owner string) (bool, error) {
+	if r.users == nil {
+		return true, nil
+	}
+	user, err := r.users.Get(ctx, owner)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !user.Deleted, nil
+}
+
+// rotate stores a new password for profile unless its stored version
+// changed, in which case the password another replica set is returned
+func (r *Rotator) rotate(ctx context.Context, profile models.WifiProfile) (Network, bool, error) {
+	network, err := r.nextPassword(profile, r.clock.Now().UTC())
+	if err != nil {
+		return Network{}, false, err
+	}
+	err = r.store.Rotate(ctx, network.Profile, profile.Version)
+	if errors.Is(err, repository.ErrWifiProfileConflict) {
+		current, err := r.Current(ctx, profile.Owner, profile.Name)
+		return current, false, err
+	}
+	if err != nil {
+		return Network{}, false, err
+	}
+	return network, true, nil
+}
+
+// nextPassword returns profile with a new password generated by its
+// policy, rotated at now
+func (r *Rotator) nextPassword(profile models.WifiProfile, now time.Time) (Network, error) {
+	token, err := generator.GenerateToken(models.TokenRequest{
+		Mode:    generator.TokenModeRandom,
+		Length:  profile.PasswordLength,
+		Charset: profile.Charset,
+	})
+	if err != nil {
+		return Network{}, fmt.Errorf("%w: %v", ErrInvalidNetwork, err)
+	}
+	if token.EntropyBits < MinPasswordBits {
+		return Network{}, fmt.Errorf("%w: %d %s characters carry %.0f bits; passwords need at least %d",
+			ErrInvalidNetwork, profile.PasswordLength, profile.Charset, token.EntropyBits, MinPasswordBits)
+	}
+
+	sum := sha256.Sum256([]byte(token.Token))
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return Network{}, err
+	}
+	profile.PasswordHash = hex.EncodeToString(sum[:])
+	profile.SealedPassword = r.aead.Seal(nonce, nonce, []byte(token.Token), []byte(profile.ID))
+	profile.Version++
+	profile.RotatedAt = now
+	profile.NextRotationAt = now.Add(profile.Interval)
+	return Network{Profile: profile, Password: token.Token}, nil
+}
+
+// open decrypts profile's password and checks it against its hash. The
+// ID is the additional data, so a sealed password copied to another
+// network does not open.
+func (r *Rotator) open(profile models.WifiProfile) (Network, error) {
+	size := r.aead.NonceSize()
+	if len(profile.SealedPassword) < size {
+		return Network{}, ErrSealedPassword
+	}
+	nonce, sealed := profile.SealedPassword[:size], profile.SealedPassword[size:]
+	password, err := r.aead.Open(nil, nonce, sealed, []byte(profile.ID))
+	if err != nil {
+		return Network{}, ErrSealedPassword
+	}
+	if sum := sha256.Sum256(password); hex.EncodeToString(sum[:]) != profile.PasswordHash {
+		return Network{}, ErrSealedPassword
+	}
+	return Network{Profile: profile, Password: string(password)}, nil
+}
+
+// lock holds the stripe of the network with id and returns its release
+func (r *Rotator) lock(id string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	mu := &r.locks[h.Sum32()%lockStripes]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// newProfile validates req and applies its defaults
+func newProfile(owner string, req models.WifiRotationRequest) (models.WifiProfile, error) {
+	if !namePattern.MatchString(req.Name) {
+		return models.WifiProfile{}, fmt.Errorf("%w: name must be 1 to 64 lower case letters, digits, - or _", ErrInvalidNetwork)
+	}
+	if req.SSID == "" || len(req.SSID) > maxSSIDBytes {
+		return models.WifiProfile{}, fmt.Errorf("%w: ssid must be 1 to %d bytes", ErrInvalidNetwork, maxSSIDBytes)
+	}
+	security := strings.ToUpper(req.Security)
+	switch security {
+	case "":
+		security = SecurityWPA
+	case SecurityWPA, SecuritySAE:
+	default:
+		return models.WifiProfile{}, fmt.Errorf("%w: unsupported security %q: must be %s or %s", ErrInvalidNetwork, req.Security, SecurityWPA, SecuritySAE)
+	}
+
+	length := req.PasswordLength
+	if length == 0 {
+		length = DefaultPasswordLength
+	}
+	if length > MaxPasswordLength {
+		return models.WifiProfile{}, fmt.Errorf("%w: password_length must be at most %d", ErrInvalidNetwork, MaxPasswordLength)
+	}
+	charset := req.Charset
+	if charset == "" {
+		charset = DefaultCharset
+	}
+	interval := DefaultInterval
+	if req.Interval != "" {
+		var err error
+		if interval, err = time.ParseDuration(req.Interval); err != nil {
+			return models.WifiProfile{}, fmt.Errorf("%w: interval must be a duration such as \"24h\"", ErrInvalidNetwork)
+		}
+	}
+	if interval < MinInterval || interval > MaxInterval {
+		return models.WifiProfile{}, fmt.Errorf("%w: interval must be between %s and %s", ErrInvalidNetwork, MinInterval, MaxInterval)
+	}
+
+	return models.WifiProfile{
+		ID:             repository.WifiProfileID(owner, req.Name),
+		Owner:          owner,
+		Name:           req.Name,
+		SSID:           req.SSID,
+		Security:       security,
+		PasswordLength: length,
+		Charset:        charset,
+		Interval:       interval,
+	}, nil
+}
@@ -0,0 +1,271 @@
+package wifirotation_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/innovelabs/microtools-go/internal/models"
+	"github.com/innovelabs/microtools-go/internal/repository"
+	"github.com/innovelabs/microtools-go/internal/testutil"
+	"github.com/innovelabs/microtools-go/internal/wifirotation"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+func newRotator(t *testing.T) (*wifirotation.Rotator, *testutil.WifiProfileStore, *testutil.Clock) {
+	t.Helper()
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+	store := testutil.NewWifiProfileStore()
+	rotator, err := wifirotation.NewRotator(store, nil, testKey, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rotator, store, clock
+}
+
+var guest = models.WifiRotationRequest{Name: "lobby", SSID: "Office Guest", Interval: "1h"}
+
+func TestScheduledRotation(t *testing.T) {
+	ctx := context.Background()
+	rotator, store, clock := newRotator(t)
+	created, err := rotator.Create(ctx, "owner@example.com", guest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := store.Profiles[created.Profile.ID]
+	if strings.Contains(string(stored.SealedPassword), created.Password) {
+		t.Error("password is stored in plain text")
+	}
+
+	clock.Advance(59 * time.Minute)
+	if n, err := rotator.RotateDue(ctx); err != nil || n != 0 {
+		t.Fatalf("RotateDue before the interval = %d, %v, want 0", n, err)
+	}
+
+	clock.Advance(time.Minute)
+	if n, err := rotator.RotateDue(ctx); err != nil || n != 1 {
+		t.Fatalf("RotateDue at the interval = %d, %v, want 1", n, err)
+	}
+	current, err := rotator.Current(ctx, "owner@example.com", "lobby")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Password == created.Password || current.Profile.Version != 2 {
+		t.Errorf("after the scheduled rotation: version %d, password unchanged %v", current.Profile.Version, current.Password == created.Password)
+	}
+	if want := clock.Now().Add(time.Hour); !current.Profile.NextRotationAt.Equal(want) {
+		t.Errorf("next rotation at %v, want %v", current.Profile.NextRotationAt, want)
+	}
+	if n, _ := rotator.RotateDue(ctx); n != 0 {
+		t.Errorf("RotateDue right after a rotation = %d, want 0", n)
+	}
+}
+
+func TestOnDemandRotationWindow(t *testing.T) {
+	ctx := context.Background()
+	rotator, _, clock := newRotator(t)
+	created, err := rotator.Create(ctx, "owner@example.com", guest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A rotation within the window of creation keeps the first password
+	same, rotated, err := rotator.Rotate(ctx, "owner@example.com", "lobby")
+	if err != nil || rotated || same.Password != created.Password {
+		t.Fatalf("Rotate within the window: rotated %v, err %v, password kept %v", rotated, err, same.Password == created.Password)
+	}
+
+	clock.Advance(wifirotation.RotationWindow)
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		passwords = map[string]bool{}
+		rotations int
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			network, rotated, err := rotator.Rotate(ctx, "owner@example.com", "lobby")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			passwords[network.Password] = true
+			if rotated {
+				rotations++
+			}
+		}()
+	}
+	wg.Wait()
+	if rotations != 1 || len(passwords) != 1 || passwords[created.Password] {
+		t.Errorf("concurrent rotations: %d rotated, %d distinct passwords, want one new password", rotations, len(passwords))
+	}
+}
+
+func TestReplicaSkipsNetworkRotatedElsewhere(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+	store := testutil.NewWifiProfileStore()
+	first, _ := wifirotation.NewRotator(store, nil, testKey, clock)
+	second, _ := wifirotation.NewRotator(store, nil, testKey, clock)
+	if _, err := first.Create(ctx, "owner@example.com", guest); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+
+	// The second replica's rotation lands first; the first one's due list
+	// is stale and must not rotate again
+	rotated, ok, err := second.Rotate(ctx, "owner@example.com", "lobby")
+	if err != nil || !ok {
+		t.Fatalf("Rotate: rotated %v, err %v", ok, err)
+	}
+	if n, err := first.RotateDue(ctx); err != nil || n != 0 {
+		t.Errorf("RotateDue after another replica rotated = %d, %v, want 0", n, err)
+	}
+	current, _ := first.Current(ctx, "owner@example.com", "lobby")
+	if current.Password != rotated.Password {
+		t.Error("current password is not the one the other replica set")
+	}
+}
+
+func TestPayloadMatchesCurrentPassword(t *testing.T) {
+	ctx := context.Background()
+	rotator, _, clock := newRotator(t)
+	rotator.Create(ctx, "owner@example.com", models.WifiRotationRequest{Name: "lab", SSID: `Lab; "B"`, Security: "sae", Charset: "symbols"})
+	clock.Advance(wifirotation.RotationWindow)
+	rotated, _, err := rotator.Rotate(ctx, "owner@example.com", "lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := rotator.Current(ctx, "owner@example.com", "lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `WIFI:T:SAE;S:Lab\; \"B\";P:` + current.Password + ";;"
+	if current.Password != rotated.Password || current.Payload() != want {
+		t.Errorf("payload = %q, want %q", current.Payload(), want)
+	}
+}
+
+func TestNetworksBelongToTheirOwner(t *testing.T) {
+	ctx := context.Background()
+	rotator, _, _ := newRotator(t)
+	rotator.Create(ctx, "owner@example.com", guest)
+	if _, err := rotator.Current(ctx, "other@example.com", "lobby"); !errors.Is(err, repository.ErrWifiProfileNotFound) {
+		t.Errorf("another user's Current: err = %v, want ErrWifiProfileNotFound", err)
+	}
+	if _, err := rotator.Create(ctx, "owner@example.com", guest); !errors.Is(err, repository.ErrWifiProfileExists) {
+		t.Errorf("second Create: err = %v, want ErrWifiProfileExists", err)
+	}
+	if err := rotator.Delete(ctx, "other@example.com", "lobby"); !errors.Is(err, repository.ErrWifiProfileNotFound) {
+		t.Errorf("another user's Delete: err = %v, want ErrWifiProfileNotFound", err)
+	}
+}
+
+func TestScheduledRotationSkipsDeletedOwners(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+	store := testutil.NewWifiProfileStore()
+	users := testutil.NewUserRepository()
+	rotator, err := wifirotation.NewRotator(store, users, testKey, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, owner := range []string{"ada@example.com", "bob@example.com"} {
+		users.Create(ctx, models.User{Email: owner, Verified: true})
+		if _, err := rotator.Create(ctx, owner, guest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rotator.Create(ctx, "gone@example.com", guest)
+	users.SoftDelete(ctx, "bob@example.com", clock.Now())
+
+	clock.Advance(time.Hour)
+	if n, err := rotator.RotateDue(ctx); err != nil || n != 1 {
+		t.Fatalf("RotateDue = %d, %v, want only ada's network rotated", n, err)
+	}
+	for owner, version := range map[string]int64{"ada@example.com": 2, "bob@example.com": 1, "gone@example.com": 1} {
+		if got := store.Profiles[repository.WifiProfileID(owner, "lobby")].Version; got != version {
+			t.Errorf("%s: version %d, want %d", owner, got, version)
+		}
+	}
+
+	// A store that cannot be reached leaves the network for the next tick
+	users.Err = errors.New("server selection timeout")
+	clock.Advance(time.Hour)
+	if n, err := rotator.RotateDue(ctx); err != nil || n != 0 {
+		t.Errorf("RotateDue with the user store down = %d, %v, want 0", n, err)
+	}
+}
+
+func TestNetworksAndDeleteOwner(t *testing.T) {
+	ctx := context.Background()
+	rotator, store, _ := newRotator(t)
+	for _, name := range []string{"patio", "lobby"} {
+		req := guest
+		req.Name = name
+		if _, err := rotator.Create(ctx, "owner@example.com", req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rotator.Create(ctx, "other@example.com", guest)
+
+	networks, err := rotator.Networks(ctx, "owner@example.com")
+	if err != nil || len(networks) != 2 || networks[0].Profile.Name != "lobby" || networks[1].Profile.Name != "patio" {
+		t.Fatalf("Networks = %+v, %v, want lobby and patio", networks, err)
+	}
+	current, _ := rotator.Current(ctx, "owner@example.com", "lobby")
+	if networks[0].Password != current.Password {
+		t.Error("Networks does not return the current password")
+	}
+
+	if n, err := rotator.DeleteOwner(ctx, "owner@example.com"); err != nil || n != 2 {
+		t.Errorf("DeleteOwner = %d, %v, want 2", n, err)
+	}
+	if networks, err := rotator.Networks(ctx, "owner@example.com"); err != nil || len(networks) != 0 {
+		t.Errorf("Networks after DeleteOwner = %+v, %v", networks, err)
+	}
+	if _, ok := store.Profiles[repository.WifiProfileID("other@example.com", "lobby")]; !ok {
+		t.Error("DeleteOwner removed another user's network")
+	}
+}
+
+func TestSealedPasswordNeedsTheKey(t *testing.T) {
+	ctx := context.Background()
+	rotator, store, clock := newRotator(t)
+	rotator.Create(ctx, "owner@example.com", guest)
+	other, _ := wifirotation.NewRotator(store, nil, bytes.Repeat([]byte{8}, 32), clock)
+	if _, err := other.Current(ctx, "owner@example.com", "lobby"); !errors.Is(err, wifirotation.ErrSealedPassword) {
+		t.Errorf("Current with another key: err = %v, want ErrSealedPassword", err)
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.WifiRotationRequest
+	}{
+		{"name with a slash", models.WifiRotationRequest{Name: "a/b", SSID: "x"}},
+		{"missing ssid", models.WifiRotationRequest{Name: "a"}},
+		{"unknown security", models.WifiRotationRequest{Name: "a", SSID: "x", Security: "WEP"}},
+		{"interval too short", models.WifiRotationRequest{Name: "a", SSID: "x", Interval: "5m"}},
+		{"too few bits", models.WifiRotationRequest{Name: "a", SSID: "x", Charset: "digits", PasswordLength: 12}},
+		{"too long for WPA", models.WifiRotationRequest{Name: "a", SSID: "x", PasswordLength: 64}},
+	}
+	rotator, _, _ := newRotator(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rotator.Create(context.Background(), "owner@example.com", tt.req); !errors.Is(err, wifirotation.ErrInvalidNetwork) {
+				t.Errorf("err = %v, want ErrInvalidNetwork", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package canonicaljson_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/pkg/canonicaljson"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{"b": 1, "a": {"d": [true, null], "c": "x"}}`, `{"a":{"c":"x","d":[true,null]},"b":1}`},
+		{`{"html": "<a href=\"x\">&</a>"}`, `{"html":"<a href=\"x\">&</a>"}`},
+		{`{"n": 12345678901234567890, "f": 1.50}`, `{"f":1.50,"n":12345678901234567890}`},
+		{`{"é": 1, "z": 2, "A": 3}`, `{"A":3,"z":2,"é":1}`},
+		{` [ "a\u0001" ] `, `["a\u0001"]`},
+	}
+	for _, tt := range tests {
+		got, err := canonicaljson.Canonicalize([]byte(tt.in))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.in, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Canonicalize(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{`{"a":1} {"b":2}`, `{"a":`, ``} {
+		if _, err := canonicaljson.Canonicalize([]byte(in)); err == nil {
+			t.Errorf("Canonicalize(%q): no error", in)
+		}
+	}
+}
+
+// TestMarshalMatchesReceived checks that a struct and the JSON a client
+// receives for it canonicalize to the same bytes
+func TestMarshalMatchesReceived(t *testing.T) {
+	type result struct {
+		Valid  bool     `json:"isValid"`
+		IBAN   string   `json:"iban"`
+		Errors []string `json:"errors"`
+	}
+	v := result{Valid: true, IBAN: "DE89 <&>", Errors: []string{"b", "a"}}
+	fromStruct, err := canonicaljson.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received, _ := json.MarshalIndent(v, "", "  ")
+	fromReceived, err := canonicaljson.Marshal(json.RawMessage(received))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fromStruct) != string(fromReceived) {
+		t.Errorf("%s != %s", fromStruct, fromReceived)
+	}
+}
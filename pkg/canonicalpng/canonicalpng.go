@@ -0,0 +1,124 @@
+// Package canonicalpng writes PNGs whose bytes depend only on their
+// pixels, so hashes of generated images stay stable across Go releases.
+package canonicalpng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/adler32"
+	"hash/crc32"
+	"image"
+	"image/color"
+)
+
+// PNG color types written by Encode
+const (
+	pngColorGray = 0
+	pngColorRGBA = 6
+)
+
+// maxStoredBlock is the most data one stored deflate block holds
+const maxStoredBlock = 65535
+
+// Encode writes img as a PNG whose bytes depend on nothing but
+// its pixels, so they survive encoder and compressor upgrades: only the
+// IHDR, IDAT and IEND chunks, no filtering, and the image data in stored
+// (uncompressed) deflate blocks. The smallest lossless color type is
+// chosen: 1-bit gray for black and white, 8-bit gray for opaque grays and
+// 8-bit RGBA otherwise. Output is larger than a compressed PNG.
+func Encode(img image.Image) []byte {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	depth, colorType := canonicalPNGFormat(img)
+
+	rowBytes := (width*depth + 7) / 8
+	if colorType == pngColorRGBA {
+		rowBytes = width * 4
+	}
+	raw := make([]byte, 0, height*(rowBytes+1))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		raw = append(raw, 0) // filter type None
+		row := make([]byte, rowBytes)
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := x - b.Min.X
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			switch {
+			case colorType == pngColorRGBA:
+				row[i*4], row[i*4+1], row[i*4+2], row[i*4+3] = c.R, c.G, c.B, c.A
+			case depth == 8:
+				row[i] = c.R
+			case c.R != 0:
+				row[i/8] |= 0x80 >> (i % 8)
+			}
+		}
+		raw = append(raw, row...)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(height))
+	ihdr[8] = byte(depth)
+	ihdr[9] = colorType
+	writePNGChunk(&buf, "IHDR", ihdr)
+	writePNGChunk(&buf, "IDAT", storedZlib(raw))
+	writePNGChunk(&buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+// canonicalPNGFormat returns the bit depth and color type that hold every
+// pixel of img exactly
+func canonicalPNGFormat(img image.Image) (int, byte) {
+	b := img.Bounds()
+	blackAndWhite := true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A != 0xff || c.R != c.G || c.G != c.B {
+				return 8, pngColorRGBA
+			}
+			if c.R != 0 && c.R != 0xff {
+				blackAndWhite = false
+			}
+		}
+	}
+	if blackAndWhite {
+		return 1, pngColorGray
+	}
+	return 8, pngColorGray
+}
+
+// storedZlib wraps data in a zlib stream of stored deflate blocks
+func storedZlib(data []byte) []byte {
+	checksum := adler32.Checksum(data)
+	out := make([]byte, 0, len(data)+len(data)/maxStoredBlock*5+11)
+	out = append(out, 0x78, 0x01)
+	for {
+		n := min(len(data), maxStoredBlock)
+		final := byte(0)
+		if n == len(data) {
+			final = 1
+		}
+		out = append(out, final, byte(n), byte(n>>8), ^byte(n), ^byte(n>>8))
+		out = append(out, data[:n]...)
+		data = data[n:]
+		if final == 1 {
+			break
+		}
+	}
+	return binary.BigEndian.AppendUint32(out, checksum)
+}
+
+func writePNGChunk(buf *bytes.Buffer, kind string, data []byte) {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], kind)
+	buf.Write(header[:])
+	buf.Write(data)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	binary.BigEndian.PutUint32(header[:4], crc.Sum32())
+	buf.Write(header[:4])
+}
@@ -0,0 +1,108 @@
+package canonicalpng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"slices"
+	"testing"
+)
+
+// chunks returns the chunk types of a PNG
+func chunks(t *testing.T, data []byte) []string {
+	t.Helper()
+	var kinds []string
+	for rest := data[8:]; len(rest) >= 12; {
+		n := binary.BigEndian.Uint32(rest)
+		kinds = append(kinds, string(rest[4:8]))
+		rest = rest[12+n:]
+	}
+	return kinds
+}
+
+func checkRoundTrip(t *testing.T, name string, img image.Image, depth, colorType byte) []byte {
+	t.Helper()
+	data := Encode(img)
+	if got := chunks(t, data); !slices.Equal(got, []string{"IHDR", "IDAT", "IEND"}) {
+		t.Errorf("%s: chunks %v, want only the critical ones", name, got)
+	}
+	if data[24] != depth || data[25] != colorType {
+		t.Errorf("%s: depth %d, color type %d; want %d, %d", name, data[24], data[25], depth, colorType)
+	}
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	b := img.Bounds()
+	if decoded.Bounds().Dx() != b.Dx() || decoded.Bounds().Dy() != b.Dy() {
+		t.Fatalf("%s: decoded %v, want %v", name, decoded.Bounds(), b)
+	}
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			want := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y))
+			if got := color.NRGBAModel.Convert(decoded.At(x, y)); got != want {
+				t.Fatalf("%s: pixel %d,%d = %v, want %v", name, x, y, got, want)
+			}
+		}
+	}
+	return data
+}
+
+func TestEncode(t *testing.T) {
+	// Black and white, 13 pixels wide so rows end mid-byte
+	bw := image.NewGray(image.Rect(0, 0, 13, 5))
+	for i := range bw.Pix {
+		if i%3 == 0 {
+			bw.Pix[i] = 0xff
+		}
+	}
+	checkRoundTrip(t, "black and white", bw, 1, pngColorGray)
+
+	gray := image.NewGray(image.Rect(0, 0, 4, 4))
+	for i := range gray.Pix {
+		gray.Pix[i] = byte(i * 16)
+	}
+	checkRoundTrip(t, "gray", gray, 8, pngColorGray)
+
+	colored := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	colored.Set(0, 0, color.NRGBA{R: 0xff, A: 0xff})
+	colored.Set(1, 1, color.NRGBA{G: 0x80, B: 0x40, A: 0x80})
+	checkRoundTrip(t, "color with alpha", colored, 8, pngColorRGBA)
+
+	// Bounds that do not start at the origin
+	sub := bw.SubImage(image.Rect(2, 1, 11, 4))
+	checkRoundTrip(t, "sub-image", sub, 1, pngColorGray)
+
+	// More raw data than one stored deflate block holds
+	large := image.NewGray(image.Rect(0, 0, 300, 300))
+	for i := range large.Pix {
+		large.Pix[i] = byte(i)
+	}
+	checkRoundTrip(t, "large", large, 8, pngColorGray)
+}
+
+// TestEncodeDependsOnPixelsOnly encodes the same pixels held in different
+// image types, as an unrelated change to a renderer might produce them,
+// and expects identical bytes
+func TestEncodeDependsOnPixelsOnly(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 21, 21))
+	for i := range gray.Pix {
+		if i%7 < 3 {
+			gray.Pix[i] = 0xff
+		}
+	}
+	rgba := image.NewRGBA(gray.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), gray, image.Point{}, draw.Src)
+	paletted := image.NewPaletted(gray.Bounds(), color.Palette{color.Black, color.White})
+	draw.Draw(paletted, paletted.Bounds(), gray, image.Point{}, draw.Src)
+
+	want := Encode(gray)
+	for name, img := range map[string]image.Image{"RGBA": rgba, "paletted": paletted, "gray again": gray} {
+		if !bytes.Equal(Encode(img), want) {
+			t.Errorf("%s: bytes differ from the gray image's", name)
+		}
+	}
+}
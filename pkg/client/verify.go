@@ -0,0 +1,140 @@
+// Package client holds helpers for consumers of the Micro API
+package client
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/innovelabs/microtools-go/pkg/canonicaljson"
+)
+
+// JWKSPath is where the API publishes its response signing keys
+const JWKSPath = "/.well-known/microtools-jwks.json"
+
+var (
+	ErrSignatureInvalid = errors.New("signature is invalid")
+	ErrUnknownKey       = errors.New("signature key is not in the key set")
+)
+
+// JWK is a published EC P-256 signing key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+}
+
+// JWKS is the key set signatures are verified against
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// FetchJWKS downloads the key set from the API at baseURL, e.g.
+// "https://microapi.innovelabs.net". A nil client uses http.DefaultClient.
+func FetchJWKS(ctx context.Context, client *http.Client, baseURL string) (JWKS, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+JWKSPath, nil)
+	if err != nil {
+		return JWKS{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return JWKS{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return JWKS{}, fmt.Errorf("fetching key set: unexpected status %d", resp.StatusCode)
+	}
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return JWKS{}, fmt.Errorf("decoding key set: %w", err)
+	}
+	return set, nil
+}
+
+// Verify checks a detached JWS from a signed response against result, the
+// validationResult object exactly as received (a json.RawMessage or any
+// value that encodes to the same JSON). It returns the signing time bound
+// into the signature.
+func (s JWKS) Verify(result interface{}, signature string) (time.Time, error) {
+	parts := strings.Split(signature, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return time.Time{}, fmt.Errorf("%w: not a detached JWS", ErrSignatureInvalid)
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: malformed header", ErrSignatureInvalid)
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+		IssuedAt  int64  `json:"iat"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return time.Time{}, fmt.Errorf("%w: malformed header", ErrSignatureInvalid)
+	}
+	if header.Algorithm != "ES256" {
+		return time.Time{}, fmt.Errorf("%w: unsupported algorithm %q", ErrSignatureInvalid, header.Algorithm)
+	}
+
+	key, err := s.publicKey(header.KeyID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	payload, err := canonicaljson.Marshal(result)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("canonicalizing result: %w", err)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(raw) != 64 {
+		return time.Time{}, fmt.Errorf("%w: malformed signature", ErrSignatureInvalid)
+	}
+
+	signingInput := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	r := new(big.Int).SetBytes(raw[:32])
+	sig := new(big.Int).SetBytes(raw[32:])
+	if !ecdsa.Verify(key, digest[:], r, sig) {
+		return time.Time{}, ErrSignatureInvalid
+	}
+	return time.Unix(header.IssuedAt, 0).UTC(), nil
+}
+
+func (s JWKS) publicKey(keyID string) (*ecdsa.PublicKey, error) {
+	for _, jwk := range s.Keys {
+		if jwk.KeyID != keyID {
+			continue
+		}
+		if jwk.KeyType != "EC" || jwk.Curve != "P-256" {
+			return nil, fmt.Errorf("%w: key %q is not an EC P-256 key", ErrUnknownKey, keyID)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: key %q has malformed coordinates", ErrUnknownKey, keyID)
+		}
+		// ecdh rejects points that are not on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("%w: key %q is not a valid P-256 point", ErrUnknownKey, keyID)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+}
@@ -0,0 +1,260 @@
+// Package barcode validates and encodes 1D barcodes (UPC-A, EAN-13,
+// Code128, Code93, Code39, ITF-14 and Pharmacode) and renders their bars
+// as PNG or SVG. Check digits are verified when given and computed when
+// left out.
+package barcode
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/code39"
+	"github.com/boombuler/barcode/code93"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/twooffive"
+)
+
+// Symbologies accepted by Encode
+const (
+	TypeUPCA       = "UPC-A"
+	TypeEAN13      = "EAN-13"
+	TypeCode128    = "Code128"
+	TypeCode93     = "Code93"
+	TypeCode39     = "Code39"
+	TypeITF14      = "ITF-14"
+	TypePharmacode = "Pharmacode"
+)
+
+const (
+	maxCode128Length = 500
+	maxCode93Length  = 200
+	maxCode39Length  = 80
+)
+
+var (
+	ErrInvalidType      = errors.New("invalid barcode type: must be UPC-A, EAN-13, Code128, Code93, Code39, ITF-14 or Pharmacode")
+	ErrInvalidData      = errors.New("invalid data for the specified barcode type")
+	ErrChecksumMismatch = errors.New("checksum digit does not match computed value")
+)
+
+// Symbol is an encoded barcode: one dark or light flag per module, the
+// narrowest bar width
+type Symbol struct {
+	Type string
+	Data string
+
+	modules []bool
+}
+
+// EncodeOption configures Encode
+type EncodeOption func(*encodeOptions)
+
+type encodeOptions struct {
+	checkDigit bool
+}
+
+// WithCheckDigit appends the optional mod 43 check character to Code39.
+// Other symbologies ignore it: their check digits are not optional.
+func WithCheckDigit(include bool) EncodeOption {
+	return func(o *encodeOptions) {
+		o.checkDigit = include
+	}
+}
+
+// Validate checks data against the rules of symbology: digits and length
+// for UPC-A and EAN-13 including a given check digit, UTF-8 and length for
+// Code128, ASCII and length for Code93, the 43 character set for Code39,
+// digits, length and a given check digit for ITF-14 and the value range
+// for Pharmacode
+func Validate(symbology, data string) error {
+	if data == "" {
+		return fmt.Errorf("%w: data is required", ErrInvalidData)
+	}
+	switch symbology {
+	case TypeUPCA:
+		if !isNumeric(data) {
+			return fmt.Errorf("%w: UPC-A data must be numeric", ErrInvalidData)
+		}
+		n := len(data)
+		if n != 11 && n != 12 {
+			return fmt.Errorf("%w: UPC-A data must be 11 or 12 digits", ErrInvalidData)
+		}
+		if n == 12 {
+			return checkDigit(UPCACheckDigit(data[:11]), data[11])
+		}
+
+	case TypeEAN13:
+		if !isNumeric(data) {
+			return fmt.Errorf("%w: EAN-13 data must be numeric", ErrInvalidData)
+		}
+		n := len(data)
+		if n != 12 && n != 13 {
+			return fmt.Errorf("%w: EAN-13 data must be 12 or 13 digits", ErrInvalidData)
+		}
+		if n == 13 {
+			return checkDigit(EAN13CheckDigit(data[:12]), data[12])
+		}
+
+	case TypeCode128:
+		if !utf8.ValidString(data) {
+			return fmt.Errorf("%w: Code128 data must be valid UTF-8", ErrInvalidData)
+		}
+		if len(data) > maxCode128Length {
+			return fmt.Errorf("%w: Code128 data exceeds maximum length of %d characters", ErrInvalidData, maxCode128Length)
+		}
+
+	case TypeCode93:
+		for _, c := range data {
+			if c > 127 {
+				return fmt.Errorf("%w: Code93 data must be ASCII", ErrInvalidData)
+			}
+		}
+		if len(data) > maxCode93Length {
+			return fmt.Errorf("%w: Code93 data exceeds maximum length of %d characters", ErrInvalidData, maxCode93Length)
+		}
+
+	case TypeCode39:
+		if _, err := code39Payload(data); err != nil {
+			return err
+		}
+
+	case TypeITF14:
+		if !isNumeric(data) {
+			return fmt.Errorf("%w: ITF-14 data must be numeric", ErrInvalidData)
+		}
+		n := len(data)
+		if n != 13 && n != 14 {
+			return fmt.Errorf("%w: ITF-14 data must be 13 or 14 digits", ErrInvalidData)
+		}
+		if n == 14 {
+			return checkDigit(GS1CheckDigit(data[:13]), data[13])
+		}
+
+	case TypePharmacode:
+		if _, err := parsePharmacode(data); err != nil {
+			return err
+		}
+
+	default:
+		return ErrInvalidType
+	}
+	return nil
+}
+
+// Encode validates data and encodes it as symbology
+func Encode(symbology, data string, opts ...EncodeOption) (*Symbol, error) {
+	if err := Validate(symbology, data); err != nil {
+		return nil, err
+	}
+	var o encodeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var (
+		bc  barcode.Barcode
+		err error
+	)
+	switch symbology {
+	case TypeUPCA:
+		bc, err = ean.Encode("0" + data)
+	case TypeEAN13:
+		bc, err = ean.Encode(data)
+	case TypeCode128:
+		bc, err = code128.Encode(data)
+	case TypeCode93:
+		bc, err = code93.Encode(data, true, true)
+	case TypeCode39:
+		payload, _ := code39Payload(data)
+		bc, err = code39.Encode(payload, o.checkDigit, false)
+	case TypeITF14:
+		if len(data) == 13 {
+			data += strconv.Itoa(GS1CheckDigit(data))
+		}
+		bc, err = twooffive.Encode(data, true)
+	case TypePharmacode:
+		return &Symbol{Type: symbology, Data: data, modules: encodePharmacode(data)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	return &Symbol{Type: symbology, Data: data, modules: moduleRow(bc)}, nil
+}
+
+// code39Payload returns Code39 data without the optional * start and stop
+// characters, which the encoder adds itself
+func code39Payload(data string) (string, error) {
+	if len(data) >= 2 && data[0] == '*' && data[len(data)-1] == '*' {
+		data = data[1 : len(data)-1]
+		if data == "" {
+			return "", fmt.Errorf("%w: data is required between the Code39 start and stop characters", ErrInvalidData)
+		}
+	}
+	if strings.Contains(data, "*") {
+		return "", fmt.Errorf("%w: Code39 uses * only as the start and stop character, around the whole data", ErrInvalidData)
+	}
+	if !IsCode39(data) {
+		return "", fmt.Errorf("%w: Code39 data must use only A-Z, 0-9, space and - . $ / + %%", ErrInvalidData)
+	}
+	if len(data) > maxCode39Length {
+		return "", fmt.Errorf("%w: Code39 data exceeds maximum length of %d characters", ErrInvalidData, maxCode39Length)
+	}
+	return data, nil
+}
+
+// moduleRow reads the modules of a 1D barcode from the barcode library
+func moduleRow(bc barcode.Barcode) []bool {
+	bounds := bc.Bounds()
+	modules := make([]bool, 0, bounds.Dx())
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		r, _, _, _ := bc.At(x, bounds.Min.Y).RGBA()
+		modules = append(modules, r == 0)
+	}
+	return modules
+}
+
+// Modules returns the module row, dark modules true. It must not be
+// modified.
+func (s *Symbol) Modules() []bool {
+	return s.modules
+}
+
+// Width returns the number of modules across the symbol
+func (s *Symbol) Width() int {
+	return len(s.modules)
+}
+
+// UPCACheckDigit computes the check digit of the first 11 digits of a UPC-A
+func UPCACheckDigit(digits string) int {
+	return GS1CheckDigit(digits[:11])
+}
+
+// EAN13CheckDigit computes the check digit of the first 12 digits of an
+// EAN-13, ISBN-13 included
+func EAN13CheckDigit(digits string) int {
+	return GS1CheckDigit(digits[:12])
+}
+
+func checkDigit(expected int, given byte) error {
+	if actual := int(given - '0'); expected != actual {
+		return fmt.Errorf("%w: expected check digit %d, got %d", ErrChecksumMismatch, expected, actual)
+	}
+	return nil
+}
+
+func isNumeric(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,262 @@
+package barcode_test
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/pkg/generate/barcode"
+)
+
+func pattern(s *barcode.Symbol) string {
+	var b strings.Builder
+	for _, m := range s.Modules() {
+		if m {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+// decodePharmacode reads a Pharmacode back from its modules: from the
+// right, the bar at position i counts 2^i when narrow and 2^(i+1) when wide
+func decodePharmacode(t *testing.T, modules string) int {
+	t.Helper()
+	value, weight := 0, 1
+	bars := strings.FieldsFunc(modules, func(r rune) bool { return r == '0' })
+	for i := len(bars) - 1; i >= 0; i-- {
+		switch len(bars[i]) {
+		case 1:
+			value += weight
+		case 3:
+			value += 2 * weight
+		default:
+			t.Fatalf("bar of %d modules in %s", len(bars[i]), modules)
+		}
+		weight *= 2
+	}
+	if strings.Contains(modules, "000") {
+		t.Fatalf("space wider than 2 modules in %s", modules)
+	}
+	return value
+}
+
+func TestPharmacode(t *testing.T) {
+	// Published reference values: 3 is two narrow bars, 4 a narrow and a
+	// wide bar, 131070 sixteen wide bars
+	tests := map[string]string{
+		"3":      "1001",
+		"4":      "100111",
+		"6":      "11100111",
+		"91":     "1001110011100111001001",
+		"131070": strings.TrimSuffix(strings.Repeat("11100", 16), "00"),
+	}
+	for data, want := range tests {
+		sym, err := barcode.Encode(barcode.TypePharmacode, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pattern(sym); got != want {
+			t.Errorf("%s:\n got %s\nwant %s", data, got, want)
+		}
+	}
+
+	for _, n := range []int{3, 5, 100, 1000, 4095, 65535, 131069, 131070} {
+		sym, err := barcode.Encode(barcode.TypePharmacode, strconv.Itoa(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := decodePharmacode(t, pattern(sym)); got != n {
+			t.Errorf("%d decodes as %d", n, got)
+		}
+	}
+
+	for _, data := range []string{"2", "131071", "-5", "12a", "1.5"} {
+		if err := barcode.Validate(barcode.TypePharmacode, data); !errors.Is(err, barcode.ErrInvalidData) {
+			t.Errorf("%s: err = %v, want ErrInvalidData", data, err)
+		}
+	}
+}
+
+// Code93 patterns of the published table: every character is 9 modules
+// of 3 bars and 3 spaces
+const (
+	code93Start = "101011110"
+	code93A     = "110101000"
+	code93Zero  = "100010100"
+	code93One   = "101001000"
+	// code93Shift is (+), the escape of lowercase letters
+	code93Shift = "100110010"
+)
+
+func TestCode93(t *testing.T) {
+	sym, err := barcode.Encode(barcode.TypeCode93, "A01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := pattern(sym)
+	// Start, three characters, the C and K check characters, stop and
+	// the termination bar
+	if len(got) != 9*7+1 {
+		t.Fatalf("A01 is %d modules, want %d", len(got), 9*7+1)
+	}
+	if !strings.HasPrefix(got, code93Start+code93A+code93Zero+code93One) {
+		t.Errorf("A01 = %s, want start, A, 0 and 1 first", got)
+	}
+	if !strings.HasSuffix(got, code93Start+"1") {
+		t.Errorf("A01 = %s, want the stop character and termination bar last", got)
+	}
+
+	// Full ASCII: a lowercase letter is the shift character and its capital
+	lower, err := barcode.Encode(barcode.TypeCode93, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pattern(lower); len(got) != 9*6+1 || !strings.HasPrefix(got, code93Start+code93Shift+code93A) {
+		t.Errorf("a = %s, want start, (+) and A", got)
+	}
+
+	if err := barcode.Validate(barcode.TypeCode93, "café"); !errors.Is(err, barcode.ErrInvalidData) {
+		t.Errorf("non-ASCII data: err = %v, want ErrInvalidData", err)
+	}
+	if err := barcode.Validate(barcode.TypeCode93, strings.Repeat("A", 201)); !errors.Is(err, barcode.ErrInvalidData) {
+		t.Errorf("201 characters: err = %v, want ErrInvalidData", err)
+	}
+}
+
+func TestCode39(t *testing.T) {
+	sym, err := barcode.Encode(barcode.TypeCode39, "ABC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Start, three characters and stop, 12 modules each with a narrow gap
+	// between them
+	if got := sym.Width(); got != 5*12+4 {
+		t.Errorf("ABC is %d modules, want %d", got, 5*12+4)
+	}
+
+	// The * start and stop characters may be given; they are not encoded twice
+	starred, err := barcode.Encode(barcode.TypeCode39, "*ABC*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pattern(starred) != pattern(sym) {
+		t.Errorf("*ABC* = %s, want the pattern of ABC", pattern(starred))
+	}
+
+	// The mod 43 check character of ABC is 10+11+12 = 33, X
+	checked, err := barcode.Encode(barcode.TypeCode39, "ABC", barcode.WithCheckDigit(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withX, err := barcode.Encode(barcode.TypeCode39, "ABCX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pattern(checked) != pattern(withX) {
+		t.Errorf("ABC with check digit = %s, want the pattern of ABCX", pattern(checked))
+	}
+
+	for _, data := range []string{"abc", "A*B", "*ABC", "**", "A_B", strings.Repeat("A", 81)} {
+		if err := barcode.Validate(barcode.TypeCode39, data); !errors.Is(err, barcode.ErrInvalidData) {
+			t.Errorf("Validate(%q) = %v, want ErrInvalidData", data, err)
+		}
+	}
+}
+
+func TestITF14(t *testing.T) {
+	sym, err := barcode.Encode(barcode.TypeITF14, "1540014128876")
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := barcode.Encode(barcode.TypeITF14, "15400141288763")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The check digit 3 is appended to 13 digits
+	if pattern(sym) != pattern(full) {
+		t.Errorf("13 digits = %s, want the pattern of the 14 with check digit 3", pattern(sym))
+	}
+	// Start, 7 digit pairs of 18 modules and stop
+	if got := full.Width(); got != 4+7*18+5 {
+		t.Errorf("ITF-14 is %d modules, want %d", got, 4+7*18+5)
+	}
+
+	if err := barcode.Validate(barcode.TypeITF14, "15400141288764"); !errors.Is(err, barcode.ErrChecksumMismatch) {
+		t.Errorf("wrong check digit: err = %v, want ErrChecksumMismatch", err)
+	}
+	for _, data := range []string{"123456789012", "154001412887630", "154001412887A"} {
+		if err := barcode.Validate(barcode.TypeITF14, data); !errors.Is(err, barcode.ErrInvalidData) {
+			t.Errorf("Validate(%q) = %v, want ErrInvalidData", data, err)
+		}
+	}
+}
+
+func TestRenderLinearSymbologies(t *testing.T) {
+	for _, tt := range []struct{ symbology, data string }{
+		{barcode.TypePharmacode, "91"},
+		{barcode.TypeCode93, "TEST93"},
+		{barcode.TypeCode39, "CODE-39"},
+		{barcode.TypeITF14, "1540014128876"},
+	} {
+		sym, err := barcode.Encode(tt.symbology, tt.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		width := 3 * sym.Width()
+		data, err := sym.PNG(width, 40, barcode.WithPadding(5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != width+10 || b.Dy() != 50 {
+			t.Errorf("%s PNG is %v, want %dx50", tt.symbology, b, width+10)
+		}
+		// Every module is exactly 3 pixels wide
+		modules := sym.Modules()
+		for x := 0; x < width; x++ {
+			r, _, _, _ := img.At(5+x, 25).RGBA()
+			if dark := r < 0x8000; dark != modules[x/3] {
+				t.Fatalf("%s PNG column %d dark = %v, want %v", tt.symbology, x, dark, modules[x/3])
+			}
+		}
+
+		svg := string(sym.SVG(width, 40))
+		bars := strings.Count(pattern(sym), "01") + 1
+		if n := strings.Count(svg, `fill="black"`); n != bars {
+			t.Errorf("%s SVG has %d bars, want %d", tt.symbology, n, bars)
+		}
+	}
+}
+
+func TestGS1CheckDigit(t *testing.T) {
+	// Keys of every length share the mod 10 check digit
+	for digits, want := range map[string]int{
+		"9638507":           4, // EAN-8
+		"03600029145":       2, // UPC-A
+		"400638133393":      1, // EAN-13
+		"0950110153000":     3, // GTIN-14
+		"10614141123456789": 7, // SSCC
+	} {
+		if got := barcode.GS1CheckDigit(digits); got != want {
+			t.Errorf("GS1CheckDigit(%s) = %d, want %d", digits, got, want)
+		}
+	}
+}
+
+func TestCode39CheckCharacter(t *testing.T) {
+	if check, ok := barcode.Code39CheckCharacter("CODE39"); !ok || check != 'W' {
+		t.Errorf("Code39CheckCharacter(CODE39) = %c, %v, want W", check, ok)
+	}
+	if _, ok := barcode.Code39CheckCharacter("code39"); ok {
+		t.Error("lower case accepted as standard Code 39")
+	}
+}
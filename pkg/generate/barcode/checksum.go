@@ -0,0 +1,48 @@
+package barcode
+
+import "strings"
+
+// code39Charset orders the Code 39 characters by their mod 43 value
+const code39Charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ-. $/+%"
+
+// GS1CheckDigit computes the mod 10 check digit GS1 keys of any length
+// share (EAN-8, UPC-A, EAN-13, GTIN-14, SSCC, GLN): digits are weighted 3
+// and 1 alternately from the right. digits holds the key without its
+// check digit and must be numeric.
+func GS1CheckDigit(digits string) int {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// IsCode39 reports whether data uses only the 43 characters of standard
+// (not full ASCII) Code 39
+func IsCode39(data string) bool {
+	for i := 0; i < len(data); i++ {
+		if strings.IndexByte(code39Charset, data[i]) < 0 {
+			return false
+		}
+	}
+	return data != ""
+}
+
+// Code39CheckCharacter computes the optional mod 43 check character of
+// standard Code 39 data. ok is false when data has characters outside the
+// standard set.
+func Code39CheckCharacter(data string) (check byte, ok bool) {
+	sum := 0
+	for i := 0; i < len(data); i++ {
+		v := strings.IndexByte(code39Charset, data[i])
+		if v < 0 {
+			return 0, false
+		}
+		sum += v
+	}
+	return code39Charset[sum%43], true
+}
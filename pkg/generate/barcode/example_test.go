@@ -0,0 +1,29 @@
+package barcode_test
+
+import (
+	"fmt"
+
+	"github.com/innovelabs/microtools-go/pkg/generate/barcode"
+)
+
+func ExampleEncode() {
+	symbol, err := barcode.Encode(barcode.TypeEAN13, "400638133393")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(symbol.Width())
+	// Output: 95
+}
+
+func ExampleValidate() {
+	fmt.Println(barcode.Validate(barcode.TypeEAN13, "4006381333931"))
+	fmt.Println(barcode.Validate(barcode.TypeEAN13, "4006381333932"))
+	// Output:
+	// <nil>
+	// checksum digit does not match computed value: expected check digit 1, got 2
+}
+
+func ExampleEAN13CheckDigit() {
+	fmt.Println(barcode.EAN13CheckDigit("400638133393"))
+	// Output: 1
+}
@@ -0,0 +1,59 @@
+package barcode
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	minPharmacode = 3
+	maxPharmacode = 131070
+
+	// Pharmacode proportions: narrow bar, wide bar and the space between bars
+	pharmacodeNarrowModules = 1
+	pharmacodeWideModules   = 3
+	pharmacodeSpaceModules  = 2
+)
+
+func parsePharmacode(data string) (int, error) {
+	if !isNumeric(data) {
+		return 0, fmt.Errorf("%w: Pharmacode data must be numeric", ErrInvalidData)
+	}
+	n, err := strconv.Atoi(data)
+	if err != nil || n < minPharmacode || n > maxPharmacode {
+		return 0, fmt.Errorf("%w: Pharmacode value must be between %d and %d", ErrInvalidData, minPharmacode, maxPharmacode)
+	}
+	return n, nil
+}
+
+// encodePharmacode encodes a one-track Pharmacode, which the barcode
+// library does not provide. Each step emits a wide bar for an even value
+// or a narrow bar for an odd one, building the symbol from right to left.
+// data must have passed parsePharmacode.
+func encodePharmacode(data string) []bool {
+	n, _ := parsePharmacode(data)
+
+	var bars []int
+	for n > 0 {
+		if n%2 == 0 {
+			bars = append(bars, pharmacodeWideModules)
+			n = (n - 2) / 2
+		} else {
+			bars = append(bars, pharmacodeNarrowModules)
+			n = (n - 1) / 2
+		}
+	}
+
+	var modules []bool
+	for i := len(bars) - 1; i >= 0; i-- {
+		for j := 0; j < bars[i]; j++ {
+			modules = append(modules, true)
+		}
+		if i > 0 {
+			for j := 0; j < pharmacodeSpaceModules; j++ {
+				modules = append(modules, false)
+			}
+		}
+	}
+	return modules
+}
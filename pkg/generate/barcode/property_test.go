@@ -0,0 +1,56 @@
+package barcode_test
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/innovelabs/microtools-go/pkg/generate/barcode"
+	"github.com/innovelabs/microtools-go/pkg/validate/testsupport"
+)
+
+func quickConfig() *quick.Config {
+	if testing.Short() {
+		return &quick.Config{MaxCount: 20}
+	}
+	return &quick.Config{MaxCount: 500}
+}
+
+// Generated GTINs validate and encode with their check digit or without
+// it, and changing any one digit gives ErrChecksumMismatch, as the GS1 mod
+// 10 catches every single substitution
+func TestPropertyGS1CheckDigits(t *testing.T) {
+	symbologies := []struct {
+		symbology string
+		length    int
+	}{
+		{barcode.TypeUPCA, testsupport.UPCALength},
+		{barcode.TypeEAN13, testsupport.EAN13Length},
+	}
+	for _, s := range symbologies {
+		t.Run(s.symbology, func(t *testing.T) {
+			property := func(seed int64) bool {
+				r := rand.New(rand.NewSource(seed))
+				gtin := testsupport.GTIN(r, s.length)
+				if err := barcode.Validate(s.symbology, gtin); err != nil {
+					t.Logf("%s: %v", gtin, err)
+					return false
+				}
+				if _, err := barcode.Encode(s.symbology, gtin[:s.length-1]); err != nil {
+					t.Logf("%s without its check digit: %v", gtin, err)
+					return false
+				}
+				mutated := testsupport.Substitute(r, gtin, r.Intn(s.length))
+				if err := barcode.Validate(s.symbology, mutated); !errors.Is(err, barcode.ErrChecksumMismatch) {
+					t.Logf("%s: err = %v, want ErrChecksumMismatch", mutated, err)
+					return false
+				}
+				return true
+			}
+			if err := quick.Check(property, quickConfig()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/innovelabs/microtools-go/pkg/canonicalpng"
+	"github.com/innovelabs/microtools-go/pkg/generate/internal/mono"
+	"github.com/innovelabs/microtools-go/pkg/generate/svgmeta"
+)
+
+// Option tunes PNG, SVG and Image
+type Option func(*renderOptions)
+
+type renderOptions struct {
+	padding       int
+	deterministic bool
+	meta          svgmeta.Metadata
+	// colors is the background and bar color; nil is black on white
+	colors color.Palette
+}
+
+// WithColors draws the bars in foreground on background instead of black
+// on white. The caller is responsible for enough contrast to scan.
+func WithColors(background, foreground color.Color) Option {
+	return func(o *renderOptions) {
+		o.colors = color.Palette{background, foreground}
+	}
+}
+
+// WithPadding adds a quiet zone of px pixels around the bars
+func WithPadding(px int) Option {
+	return func(o *renderOptions) {
+		o.padding = max(px, 0)
+	}
+}
+
+// WithDeterministic writes PNGs with canonicalpng, so their bytes depend
+// only on the pixels
+func WithDeterministic(deterministic bool) Option {
+	return func(o *renderOptions) {
+		o.deterministic = deterministic
+	}
+}
+
+// WithAccessibleName gives SVG output a <title> and <desc>, referenced
+// from the root element for screen readers. Either may be empty; PNG
+// output ignores them.
+func WithAccessibleName(title, desc string) Option {
+	return func(o *renderOptions) {
+		o.meta = svgmeta.Metadata{Title: title, Desc: desc}
+	}
+}
+
+func newRenderOptions(opts []Option) renderOptions {
+	var o renderOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Columns scales the modules to width pixels and reports which pixel
+// columns are dark. Each module becomes the same whole number of pixels
+// and the bars are centered, as the barcode library scales them; a width
+// below one pixel per module is an error.
+func (s *Symbol) Columns(width int) ([]bool, error) {
+	modules := len(s.modules)
+	factor := width / modules
+	if factor <= 0 {
+		return nil, fmt.Errorf("%w: %d pixels are narrower than the %d modules of this barcode", ErrInvalidData, width, modules)
+	}
+	offset := (width - modules*factor) / 2
+	columns := make([]bool, width)
+	for x := range columns {
+		if m := (x - offset) / factor; x >= offset && m < modules {
+			columns[x] = s.modules[m]
+		}
+	}
+	return columns, nil
+}
+
+// Image returns the bars as a two-color image of width x height pixels
+// plus the padding, black on white unless WithColors is given. Pixels are
+// computed on demand.
+func (s *Symbol) Image(width, height int, opts ...Option) (image.Image, error) {
+	o := newRenderOptions(opts)
+	columns, err := s.Columns(width)
+	if err != nil {
+		return nil, err
+	}
+	bars := image.Rect(o.padding, o.padding, o.padding+width, o.padding+height)
+	return &mono.Image{
+		Rect: image.Rect(0, 0, width+2*o.padding, height+2*o.padding),
+		Dark: func(x, y int) bool {
+			return image.Pt(x, y).In(bars) && columns[x-o.padding]
+		},
+		Colors: o.colors,
+	}, nil
+}
+
+// PNG renders the bars as a PNG of width x height pixels plus the padding
+func (s *Symbol) PNG(width, height int, opts ...Option) ([]byte, error) {
+	img, err := s.Image(width, height, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if newRenderOptions(opts).deterministic {
+		return canonicalpng.Encode(img), nil
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the bars as an SVG document of width x height pixels plus
+// the padding. Bars are scaled to the exact width, not snapped to pixels.
+func (s *Symbol) SVG(width, height int, opts ...Option) []byte {
+	o := newRenderOptions(opts)
+	canvasWidth, canvasHeight := width+2*o.padding, height+2*o.padding
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d"%s>`,
+		canvasWidth, canvasHeight, canvasWidth, canvasHeight, o.meta.RootAttributes())
+	buf.WriteByte('\n')
+	if !o.meta.IsZero() {
+		o.meta.WriteElements(&buf)
+		buf.WriteByte('\n')
+	}
+	background, foreground := "white", "black"
+	if o.colors != nil {
+		background, foreground = svgColor(o.colors[0]), svgColor(o.colors[1])
+	}
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, canvasWidth, canvasHeight, background)
+	buf.WriteByte('\n')
+	s.WriteSVGBarsFill(&buf, o.padding, o.padding, width, height, foreground)
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+// WriteSVGBars writes one black rect element per bar, one per line,
+// filling the box at x, y of width x height. It lets callers compose the
+// bars with their own SVG content.
+func (s *Symbol) WriteSVGBars(w io.Writer, x, y, width, height int) {
+	s.WriteSVGBarsFill(w, x, y, width, height, "black")
+}
+
+// WriteSVGBarsFill is WriteSVGBars with the bars filled with fill, an SVG
+// color written as is
+func (s *Symbol) WriteSVGBarsFill(w io.Writer, x, y, width, height int, fill string) {
+	scaleX := float64(width) / float64(len(s.modules))
+	start := -1
+	for i := 0; i <= len(s.modules); i++ {
+		isBar := i < len(s.modules) && s.modules[i]
+		if isBar && start == -1 {
+			start = i
+		} else if !isBar && start != -1 {
+			svgX := float64(x) + float64(start)*scaleX
+			svgW := float64(i-start) * scaleX
+			fmt.Fprintf(w, "<rect x=\"%.2f\" y=\"%d\" width=\"%.2f\" height=\"%d\" fill=\"%s\"/>\n", svgX, y, svgW, height, fill)
+			start = -1
+		}
+	}
+}
+
+// svgColor returns c as an SVG #rrggbb color, dropping any alpha
+func svgColor(c color.Color) string {
+	rgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+}
@@ -0,0 +1,126 @@
+package barcode_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"runtime"
+	"testing"
+
+	boombuler "github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/innovelabs/microtools-go/pkg/generate/barcode"
+)
+
+const (
+	data    = "MICROTOOLS-0001"
+	padding = 10
+	// maxSize is the largest barcode the API renders, in both directions
+	maxSize = 1024
+)
+
+// canvas renders data the way the service did before streaming: the
+// scaled barcode drawn on a full-size white RGBA canvas
+func canvas(t testing.TB, width, height int) *image.RGBA {
+	t.Helper()
+	bc, err := code128.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scaled, err := boombuler.Scale(bc, width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width+2*padding, height+2*padding))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(padding, padding, padding+width, padding+height), scaled, scaled.Bounds().Min, draw.Over)
+	return img
+}
+
+func canvasPNG(t testing.TB, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas(t, width, height)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func streamedPNG(t testing.TB, width, height int) []byte {
+	t.Helper()
+	symbol, err := barcode.Encode(barcode.TypeCode128, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := symbol.PNG(width, height, barcode.WithPadding(padding))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// TestPNGMatchesCanvas requires the streamed bars to have the pixels of
+// the RGBA canvas they replaced, at widths that center the bars too
+func TestPNGMatchesCanvas(t *testing.T) {
+	for _, size := range [][2]int{{200, 50}, {301, 80}, {499, 7}, {maxSize, maxSize}} {
+		want := canvas(t, size[0], size[1])
+		got, err := png.Decode(bytes.NewReader(streamedPNG(t, size[0], size[1])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Bounds() != want.Bounds() {
+			t.Fatalf("%v: bounds %v, want %v", size, got.Bounds(), want.Bounds())
+		}
+		if _, ok := got.(*image.Paletted); !ok {
+			t.Errorf("%v: decoded a %T, want a paletted PNG", size, got)
+		}
+		for y := range want.Bounds().Dy() {
+			for x := range want.Bounds().Dx() {
+				r1, g1, b1, _ := got.At(x, y).RGBA()
+				r2, g2, b2, _ := want.At(x, y).RGBA()
+				if r1 != r2 || g1 != g2 || b1 != b2 {
+					t.Fatalf("%v: pixel %d,%d differs", size, x, y)
+				}
+			}
+		}
+	}
+}
+
+// allocated returns the bytes f allocates
+func allocated(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+// TestPNGAllocations requires a maximum-size barcode to allocate under a
+// quarter of what the RGBA canvas did
+func TestPNGAllocations(t *testing.T) {
+	streamedPNG(t, maxSize, maxSize)
+	rgba := allocated(func() { canvasPNG(t, maxSize, maxSize) })
+	streamed := allocated(func() { streamedPNG(t, maxSize, maxSize) })
+	t.Logf("%dx%d: %d bytes on a canvas, %d bytes streamed", maxSize, maxSize, rgba, streamed)
+	if streamed*4 > rgba {
+		t.Errorf("streamed PNG allocates %d bytes, more than a quarter of the canvas's %d", streamed, rgba)
+	}
+}
+
+func BenchmarkPNG(b *testing.B) {
+	b.Run("canvas", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			canvasPNG(b, maxSize, maxSize)
+		}
+	})
+	b.Run("streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			streamedPNG(b, maxSize, maxSize)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+// Package mono holds the two-color image type shared by the QR and
+// barcode generators
+package mono
+
+import (
+	"image"
+	"image/color"
+)
+
+// Palette is the background and foreground of black and white codes,
+// in the order go-qrcode writes them
+var Palette = color.Palette{color.White, color.Black}
+
+// Image is a two-color image whose pixels are computed on demand, so
+// encoding a large code never holds a full-size pixel buffer. It is an
+// image.PalettedImage, which png.Encode writes as a 1-bit paletted PNG one
+// row at a time.
+type Image struct {
+	Rect image.Rectangle
+	// Dark reports whether the pixel at x, y, within Rect, is foreground
+	Dark func(x, y int) bool
+	// Colors is the background and foreground; nil is Palette
+	Colors color.Palette
+}
+
+func (m *Image) ColorModel() color.Model {
+	return m.palette()
+}
+
+func (m *Image) palette() color.Palette {
+	if m.Colors == nil {
+		return Palette
+	}
+	return m.Colors
+}
+
+func (m *Image) Bounds() image.Rectangle {
+	return m.Rect
+}
+
+func (m *Image) At(x, y int) color.Color {
+	return m.palette()[m.ColorIndexAt(x, y)]
+}
+
+func (m *Image) ColorIndexAt(x, y int) uint8 {
+	if image.Pt(x, y).In(m.Rect) && m.Dark(x, y) {
+		return 1
+	}
+	return 0
+}
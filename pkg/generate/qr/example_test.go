@@ -0,0 +1,27 @@
+package qr_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/innovelabs/microtools-go/pkg/generate/qr"
+)
+
+func ExampleEncode() {
+	code, err := qr.Encode("https://example.com", qr.LevelMedium)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(code.Version, code.Modules())
+	// Output: 2 33
+}
+
+func ExampleCode_SVG() {
+	code, err := qr.Encode("hello", qr.LevelLow)
+	if err != nil {
+		panic(err)
+	}
+	svg := code.SVG(256, qr.WithAccessibleName("Greeting", "A QR code that reads hello"))
+	fmt.Println(bytes.HasPrefix(svg, []byte("<svg")), bytes.Contains(svg, []byte("<title")))
+	// Output: true true
+}
@@ -0,0 +1,158 @@
+// Package qr encodes payloads as QR codes and renders them as PNG or SVG.
+// Rendering depends only on the payload, level and size, so the same input
+// always gives the same image; deterministic PNGs are also stable across
+// Go releases.
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+
+	"github.com/innovelabs/microtools-go/pkg/canonicalpng"
+	"github.com/innovelabs/microtools-go/pkg/generate/internal/mono"
+	"github.com/innovelabs/microtools-go/pkg/generate/svgmeta"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Level is an error correction level, named by its letter
+type Level string
+
+// Error correction levels, from about 7% to 30% of the symbol recoverable
+const (
+	LevelLow      Level = "L"
+	LevelMedium   Level = "M"
+	LevelQuartile Level = "Q"
+	LevelHigh     Level = "H"
+)
+
+var (
+	// ErrInvalidLevel is returned for a level other than L, M, Q or H
+	ErrInvalidLevel = errors.New("error correction level must be L, M, Q or H")
+	// ErrEncode is returned for a payload that does not fit a QR code
+	ErrEncode = errors.New("failed to generate QR code")
+)
+
+var recoveryLevels = map[Level]qrcode.RecoveryLevel{
+	LevelLow:      qrcode.Low,
+	LevelMedium:   qrcode.Medium,
+	LevelQuartile: qrcode.High,
+	LevelHigh:     qrcode.Highest,
+}
+
+// Valid reports whether l is one of the four levels
+func (l Level) Valid() bool {
+	_, ok := recoveryLevels[l]
+	return ok
+}
+
+// Code is an encoded QR symbol
+type Code struct {
+	// Version is the symbol version, 1 to 40
+	Version int
+	// Level is the error correction level it was encoded with
+	Level Level
+
+	bitmap [][]bool
+}
+
+// Encode encodes payload at level, choosing the smallest version that
+// holds it and the mask with the lowest penalty
+func Encode(payload string, level Level) (*Code, error) {
+	recovery, ok := recoveryLevels[level]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidLevel, level)
+	}
+	qr, err := qrcode.New(payload, recovery)
+	if err != nil {
+		return nil, ErrEncode
+	}
+	return &Code{Version: qr.VersionNumber, Level: level, bitmap: qr.Bitmap()}, nil
+}
+
+// Bitmap returns the modules, quiet zone included, as rows of dark flags.
+// It must not be modified.
+func (c *Code) Bitmap() [][]bool {
+	return c.bitmap
+}
+
+// Modules returns the number of modules across the symbol, quiet zone
+// included
+func (c *Code) Modules() int {
+	return len(c.bitmap)
+}
+
+// Image returns the code as a size x size black and white image, mapping
+// each pixel to the nearest module exactly as go-qrcode does. A size below
+// one pixel per module is raised to it. Pixels are computed on demand.
+func (c *Code) Image(size int) image.Image {
+	modules := len(c.bitmap)
+	size = max(size, modules)
+	modulesPerPixel := float64(modules) / float64(size)
+	return &mono.Image{
+		Rect: image.Rect(0, 0, size, size),
+		Dark: func(x, y int) bool {
+			return c.bitmap[int(float64(y)*modulesPerPixel)][int(float64(x)*modulesPerPixel)]
+		},
+	}
+}
+
+// PNG renders the code as a size x size PNG, streaming rows to the
+// encoder; the bytes match go-qrcode's own PNG. With deterministic the
+// image is written by canonicalpng instead.
+func (c *Code) PNG(size int, deterministic bool) ([]byte, error) {
+	img := c.Image(size)
+	if deterministic {
+		return canonicalpng.Encode(img), nil
+	}
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, ErrEncode
+	}
+	return buf.Bytes(), nil
+}
+
+// SVGOption tunes SVG
+type SVGOption func(*svgmeta.Metadata)
+
+// WithAccessibleName gives the SVG a <title> and <desc>, referenced from
+// the root element for screen readers. Either may be empty.
+func WithAccessibleName(title, desc string) SVGOption {
+	return func(m *svgmeta.Metadata) {
+		m.Title, m.Desc = title, desc
+	}
+}
+
+// SVG draws the modules, quiet zone included, as one path of horizontal
+// runs in module units, scaled to size pixels. The output depends only on
+// the bitmap, size and options, so it is byte-for-byte reproducible and
+// safe to inline in HTML: the accessible name is escaped.
+func (c *Code) SVG(size int, opts ...SVGOption) []byte {
+	var meta svgmeta.Metadata
+	for _, opt := range opts {
+		opt(&meta)
+	}
+	n := len(c.bitmap)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges"%s>`, size, size, n, n, meta.RootAttributes())
+	meta.WriteElements(&buf)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/><path fill="#000000" d="`, n, n)
+	for y, row := range c.bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
@@ -0,0 +1,117 @@
+package qr_test
+
+import (
+	"bytes"
+	"image"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/pkg/generate/qr"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// maxSize is the largest QR code the API renders
+const maxSize = 2048
+
+var payloads = []string{"hello", "https://example.com/menu/today?table=12", strings.Repeat("MICROAPI-", 40)}
+
+// libraryPNG renders payload the way the service did before streaming:
+// go-qrcode fills a full-size image, then encodes it
+func libraryPNG(t testing.TB, payload string, size int) []byte {
+	t.Helper()
+	code, err := qrcode.New(payload, qrcode.Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := code.PNG(size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func streamedPNG(t testing.TB, payload string, size int) []byte {
+	t.Helper()
+	code, err := qr.Encode(payload, qr.LevelMedium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := code.PNG(size, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// TestPNGMatchesLibrary requires the streamed PNGs to be byte-identical
+// to go-qrcode's, sizes below one pixel per module included
+func TestPNGMatchesLibrary(t *testing.T) {
+	for _, payload := range payloads {
+		for _, size := range []int{10, 64, 100, 257, 1000, maxSize} {
+			if !bytes.Equal(streamedPNG(t, payload, size), libraryPNG(t, payload, size)) {
+				t.Errorf("%.20q at %dpx: PNG differs from go-qrcode's", payload, size)
+			}
+		}
+	}
+}
+
+func TestImageMatchesLibrary(t *testing.T) {
+	for _, size := range []int{33, 64, 99, 512} {
+		code, _ := qr.Encode(payloads[1], qr.LevelMedium)
+		library, _ := qrcode.New(payloads[1], qrcode.Medium)
+		got, want := code.Image(size), library.Image(size)
+		if got.Bounds() != want.Bounds() {
+			t.Fatalf("%dpx: bounds %v, want %v", size, got.Bounds(), want.Bounds())
+		}
+		paletted := got.(image.PalettedImage)
+		for y := range size {
+			for x := range size {
+				r1, _, _, _ := got.At(x, y).RGBA()
+				r2, _, _, _ := want.At(x, y).RGBA()
+				if r1 != r2 || (paletted.ColorIndexAt(x, y) == 1) != (r1 == 0) {
+					t.Fatalf("%dpx: pixel %d,%d differs", size, x, y)
+				}
+			}
+		}
+	}
+}
+
+// allocated returns the bytes f allocates
+func allocated(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+// TestPNGAllocations requires a maximum-size code to allocate under a
+// quarter of what filling a full-size image first did
+func TestPNGAllocations(t *testing.T) {
+	payload := payloads[1]
+	streamedPNG(t, payload, maxSize) // warm up lazily built tables
+	library := allocated(func() { libraryPNG(t, payload, maxSize) })
+	streamed := allocated(func() { streamedPNG(t, payload, maxSize) })
+	t.Logf("%dpx: %d bytes before, %d bytes streamed", maxSize, library, streamed)
+	if streamed*4 > library {
+		t.Errorf("streamed PNG allocates %d bytes, more than a quarter of the %d of a full-size image", streamed, library)
+	}
+}
+
+func BenchmarkPNG(b *testing.B) {
+	payload := payloads[1]
+	b.Run("library", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			libraryPNG(b, payload, maxSize)
+		}
+	})
+	b.Run("streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			streamedPNG(b, payload, maxSize)
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package svgmeta_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/innovelabs/microtools-go/pkg/generate/svgmeta"
+)
+
+func ExampleMetadata() {
+	m := svgmeta.Metadata{Title: "Sales & returns", Desc: "Bar chart"}
+	fmt.Printf("<svg%s>", m.RootAttributes())
+	m.WriteElements(os.Stdout)
+	fmt.Println("</svg>")
+	// Output: <svg role="img" aria-labelledby="title-16c0550f" aria-describedby="desc-16c0550f"><title id="title-16c0550f">Sales &amp; returns</title><desc id="desc-16c0550f">Bar chart</desc></svg>
+}
+
+func ExampleEscape() {
+	fmt.Println(svgmeta.Escape(`<b> "Q&A"`))
+	// Output: &lt;b&gt; &quot;Q&amp;A&quot;
+}
@@ -0,0 +1,85 @@
+// Package svgmeta writes the accessible name and description of generated
+// SVG documents: <title> and <desc> children referenced from the root
+// element, so screen readers announce the image instead of skipping it.
+package svgmeta
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Metadata is the accessible name and description of an SVG document.
+// The zero Metadata writes nothing.
+type Metadata struct {
+	Title string
+	Desc  string
+}
+
+// IsZero reports whether m has neither a title nor a description
+func (m Metadata) IsZero() bool {
+	return m.Title == "" && m.Desc == ""
+}
+
+// ids returns the element IDs of the title and description. They are
+// derived from the text, so output stays deterministic, and differ between
+// images, so several SVGs inlined in one page do not share IDs.
+func (m Metadata) ids() (title, desc string) {
+	sum := sha256.Sum256([]byte(m.Title + "\x00" + m.Desc))
+	suffix := hex.EncodeToString(sum[:4])
+	return "title-" + suffix, "desc-" + suffix
+}
+
+// RootAttributes returns the attributes the root <svg> element needs,
+// with a leading space: role="img" and aria-labelledby and
+// aria-describedby pointing at the children WriteElements writes
+func (m Metadata) RootAttributes() string {
+	if m.IsZero() {
+		return ""
+	}
+	titleID, descID := m.ids()
+	var attrs strings.Builder
+	attrs.WriteString(` role="img"`)
+	if m.Title != "" {
+		fmt.Fprintf(&attrs, ` aria-labelledby="%s"`, titleID)
+	}
+	if m.Desc != "" {
+		fmt.Fprintf(&attrs, ` aria-describedby="%s"`, descID)
+	}
+	return attrs.String()
+}
+
+// WriteElements writes the <title> and <desc> elements, which must be the
+// first children of the root element
+func (m Metadata) WriteElements(w io.Writer) {
+	titleID, descID := m.ids()
+	if m.Title != "" {
+		fmt.Fprintf(w, `<title id="%s">%s</title>`, titleID, Escape(m.Title))
+	}
+	if m.Desc != "" {
+		fmt.Fprintf(w, `<desc id="%s">%s</desc>`, descID, Escape(m.Desc))
+	}
+}
+
+// escaper escapes text for XML character data and attribute values
+var escaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+// Escape escapes s for XML text and attribute values. Control characters
+// XML does not allow are dropped.
+func Escape(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' || r == 0xFFFE || r == 0xFFFF {
+			return -1
+		}
+		return r
+	}, s)
+	return escaper.Replace(s)
+}
@@ -0,0 +1,146 @@
+package email
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidDisposableList is returned by ParseDisposableList for a list
+// that cannot be read or has a line that is not a domain
+var ErrInvalidDisposableList = errors.New("invalid disposable domain list")
+
+//go:embed disposable_domains.txt
+var builtinDisposableList string
+
+var defaultDisposable = mustParseDisposableList(builtinDisposableList)
+
+// DisposableList is a set of disposable email domains. A listed domain
+// also matches its subdomains. It is not modified after it is created and
+// is safe for concurrent use.
+type DisposableList struct {
+	domains map[string]struct{}
+}
+
+// NewDisposableList creates a list of domains, matched case-insensitively.
+// Entries that are not domains of at least two labels are left out.
+func NewDisposableList(domains []string) *DisposableList {
+	set := make(map[string]struct{}, len(domains))
+	for _, domain := range domains {
+		if domain = normalizeDisposableDomain(domain); domain != "" {
+			set[domain] = struct{}{}
+		}
+	}
+	return &DisposableList{domains: set}
+}
+
+// ParseDisposableList reads a list of one domain per line. Blank lines
+// and text after "#" are ignored; any other line that is not a domain of
+// at least two labels is an error naming the line, so a typo cannot list
+// a whole top-level domain.
+func ParseDisposableList(r io.Reader) (*DisposableList, error) {
+	set := map[string]struct{}{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		if text = strings.TrimSpace(text); text == "" {
+			continue
+		}
+		domain := normalizeDisposableDomain(text)
+		if domain == "" {
+			return nil, fmt.Errorf("%w: line %d: %q is not a domain", ErrInvalidDisposableList, line, text)
+		}
+		set[domain] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDisposableList, err)
+	}
+	return &DisposableList{domains: set}, nil
+}
+
+func mustParseDisposableList(text string) *DisposableList {
+	list, err := ParseDisposableList(strings.NewReader(text))
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
+// DefaultDisposableList returns the built-in list of disposable email
+// domains
+func DefaultDisposableList() *DisposableList {
+	return defaultDisposable
+}
+
+// DisposableDomains returns the built-in list of disposable email
+// domains, sorted
+func DisposableDomains() []string {
+	return defaultDisposable.Domains()
+}
+
+// Contains reports whether domain or one of its parent domains is listed.
+// The labels are walked from the right, so foo.mailinator.com matches
+// mailinator.com. A nil list contains nothing.
+func (l *DisposableList) Contains(domain string) bool {
+	if l == nil {
+		return false
+	}
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	for i := strings.LastIndexByte(domain, '.'); i >= 0; {
+		i = strings.LastIndexByte(domain[:i], '.')
+		if _, ok := l.domains[domain[i+1:]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of listed domains
+func (l *DisposableList) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.domains)
+}
+
+// Domains returns the listed domains, sorted
+func (l *DisposableList) Domains() []string {
+	if l == nil {
+		return nil
+	}
+	domains := make([]string, 0, len(l.domains))
+	for domain := range l.domains {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// normalizeDisposableDomain lower-cases domain and drops a trailing dot,
+// returning "" unless it is an ASCII host name of at least two labels
+func normalizeDisposableDomain(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return ""
+	}
+	for _, label := range labels {
+		if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+			return ""
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return ""
+			}
+		}
+	}
+	return domain
+}
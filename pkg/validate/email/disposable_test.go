@@ -0,0 +1,74 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDisposableListContains(t *testing.T) {
+	list := NewDisposableList([]string{"Mailinator.com", "mail.example.org."})
+	for domain, want := range map[string]bool{
+		"mailinator.com":         true,
+		"MAILINATOR.COM":         true,
+		"foo.mailinator.com":     true,
+		"a.b.Mailinator.com.":    true,
+		"notmailinator.com":      false,
+		"mailinator.com.example": false,
+		"mail.example.org":       true,
+		"x.mail.example.org":     true,
+		"example.org":            false,
+		"com":                    false,
+		"":                       false,
+	} {
+		if got := list.Contains(domain); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", domain, got, want)
+		}
+	}
+	if (*DisposableList)(nil).Contains("mailinator.com") {
+		t.Error("a nil list contains mailinator.com")
+	}
+}
+
+func TestParseDisposableList(t *testing.T) {
+	list, err := ParseDisposableList(strings.NewReader("# throwaway providers\n\nYopmail.com  # main domain\n  trashmail.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(list.Domains(), ","); got != "trashmail.com,yopmail.com" {
+		t.Errorf("Domains() = %s, want trashmail.com,yopmail.com", got)
+	}
+
+	for _, text := range []string{
+		"mailinator.com\ncom\n",
+		"mailinator.com\nhttps://yopmail.com/\n",
+		"two words.com\n",
+		"-bad.example\n",
+		"a..example\n",
+	} {
+		if _, err := ParseDisposableList(strings.NewReader(text)); !errors.Is(err, ErrInvalidDisposableList) {
+			t.Errorf("ParseDisposableList(%q) = %v, want ErrInvalidDisposableList", text, err)
+		}
+	}
+	_, err = ParseDisposableList(strings.NewReader("mailinator.com\n\nnot a domain\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("error %v does not name line 3", err)
+	}
+}
+
+func TestBuiltinDisposableList(t *testing.T) {
+	if n := DefaultDisposableList().Len(); n != len(DisposableDomains()) || n == 0 {
+		t.Errorf("built-in list has %d domains, DisposableDomains %d", n, len(DisposableDomains()))
+	}
+	v := New(WithChecks(Checks{Disposable: true}), WithExtraDisposable([]string{"burner.example"}))
+	for address, want := range map[string]bool{
+		"user@inbox.Mailinator.com": true,
+		"user@eu.burner.example":    true,
+		"user@example.com":          false,
+	} {
+		if got := *v.Validate(context.Background(), address).IsDisposable; got != want {
+			t.Errorf("%s: IsDisposable = %v, want %v", address, got, want)
+		}
+	}
+}
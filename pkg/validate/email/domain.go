@@ -0,0 +1,62 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Resolver looks up the DNS records the domain checks need. *net.Resolver
+// implements it.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) (addrs []string, err error)
+}
+
+// IsNotFound reports whether err is a DNS answer that the name has no
+// records, as opposed to a failed lookup
+func IsNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// DomainChecker runs the network checks for the domain of an email address.
+// err wraps ErrDomainLookupFailed when DNS gave no answer; the results are
+// then unknown and must not be cached.
+type DomainChecker func(ctx context.Context, email string) (domainValid, mxFound bool, err error)
+
+// IsTimeout reports whether err is a lookup that ran out of time, e.g. the
+// deadline of its context, rather than one DNS answered with a failure
+func IsTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// NewDomainChecker returns a DomainChecker looking domains up with
+// resolver. A domain is valid when it has MX records or addresses; the
+// addresses are only looked up for a domain without MX records.
+func NewDomainChecker(resolver Resolver) DomainChecker {
+	return func(ctx context.Context, email string) (bool, bool, error) {
+		domain := Domain(email)
+		if domain == "" {
+			return false, false, nil
+		}
+		records, err := resolver.LookupMX(ctx, domain)
+		switch {
+		case err == nil:
+			// NODATA without an error still proves the domain exists
+			return true, len(records) > 0, nil
+		case !IsNotFound(err):
+			return false, false, fmt.Errorf("%w: %w", ErrDomainLookupFailed, err)
+		}
+		_, err = resolver.LookupHost(ctx, domain)
+		switch {
+		case err == nil:
+			return true, false, nil
+		case !IsNotFound(err):
+			return false, false, fmt.Errorf("%w: %w", ErrDomainLookupFailed, err)
+		}
+		return false, false, nil
+	}
+}
@@ -0,0 +1,107 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeResolver answers from mx and hosts, counts its lookups and, when
+// block is set, waits for the context like a blackholed server
+type fakeResolver struct {
+	mx                 map[string][]*net.MX
+	hosts              map[string][]string
+	err                error
+	block              bool
+	mxCalls, hostCalls int
+}
+
+func (r *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	r.mxCalls++
+	if r.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	records, ok := r.mx[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.hostCalls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	addrs, ok := r.hosts[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func TestDomainChecker(t *testing.T) {
+	tests := []struct {
+		name                   string
+		resolver               fakeResolver
+		domainValid, mxFound   bool
+		failed                 bool
+		mxLookups, hostLookups int
+	}{
+		{"MX records", fakeResolver{mx: map[string][]*net.MX{"example.com": {{Host: "mx.example.com."}}}}, true, true, false, 1, 0},
+		{"NODATA MX", fakeResolver{mx: map[string][]*net.MX{"example.com": nil}}, true, false, false, 1, 0},
+		{"addresses only", fakeResolver{hosts: map[string][]string{"example.com": {"192.0.2.1"}}}, true, false, false, 1, 1},
+		{"no such domain", fakeResolver{}, false, false, false, 1, 1},
+		{"SERVFAIL", fakeResolver{err: &net.DNSError{Err: "server misbehaving", Name: "example.com"}}, false, false, true, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainValid, mxFound, err := NewDomainChecker(&tt.resolver)(context.Background(), "ada@example.com")
+			if domainValid != tt.domainValid || mxFound != tt.mxFound || errors.Is(err, ErrDomainLookupFailed) != tt.failed {
+				t.Errorf("= %v, %v, %v; want %v, %v, failed %v", domainValid, mxFound, err, tt.domainValid, tt.mxFound, tt.failed)
+			}
+			if tt.resolver.mxCalls != tt.mxLookups || tt.resolver.hostCalls != tt.hostLookups {
+				t.Errorf("%d MX and %d host lookups, want %d and %d", tt.resolver.mxCalls, tt.resolver.hostCalls, tt.mxLookups, tt.hostLookups)
+			}
+		})
+	}
+}
+
+func TestValidateTimeout(t *testing.T) {
+	resolver := &fakeResolver{block: true}
+	var skipped []string
+	v := New(WithResolver(resolver), WithSkipHandler(func(ctx context.Context, check string, err error) {
+		if !IsTimeout(err) || !errors.Is(err, ErrDomainLookupFailed) {
+			t.Errorf("%s skipped with %v, want a timeout", check, err)
+		}
+		skipped = append(skipped, check)
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result := v.Validate(ctx, "ada@mailinator.com")
+	if !result.DNSTimedOut || result.IsDomainValid == nil || *result.IsDomainValid ||
+		result.MxRecordsFound == nil || *result.MxRecordsFound {
+		t.Errorf("result = %+v, want domain and MX false with dnsTimedOut", result)
+	}
+	// The offline checks still answer
+	if result.IsSyntaxValid == nil || !*result.IsSyntaxValid || result.IsDisposable == nil || !*result.IsDisposable {
+		t.Errorf("result = %+v, want syntax and disposable results", result)
+	}
+	if len(skipped) != 2 || resolver.mxCalls != 1 {
+		t.Errorf("skipped %v after %d MX lookups", skipped, resolver.mxCalls)
+	}
+
+	// A failure DNS answered is not a timeout: the results stay unknown
+	resolver = &fakeResolver{err: &net.DNSError{Err: "server misbehaving", Name: "example.com"}}
+	result = New(WithResolver(resolver)).Validate(context.Background(), "ada@example.com")
+	if result.DNSTimedOut || result.IsDomainValid != nil || len(result.SkippedChecks) != 2 {
+		t.Errorf("SERVFAIL result = %+v", result)
+	}
+}
@@ -0,0 +1,269 @@
+// Package email validates email addresses: syntax, whether the domain
+// resolves, MX records and known disposable providers. The checks can be
+// selected per Validator, and the DNS lookups go through a Resolver the
+// caller may replace, e.g. with a caching one.
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Check names accepted by ParseChecks and reported in SkippedChecks
+const (
+	CheckSyntax     = "syntax"
+	CheckDomain     = "domain"
+	CheckMX         = "mx"
+	CheckDisposable = "disposable"
+)
+
+var (
+	// ErrUnsupportedCheck is returned by ParseChecks for an unknown name
+	ErrUnsupportedCheck = errors.New("unsupported check")
+	// ErrDomainLookupFailed reports a DNS lookup that got no answer, e.g.
+	// SERVFAIL or a timeout, so whether the domain exists is unknown
+	ErrDomainLookupFailed = errors.New("domain lookup failed")
+	// ErrNetworkDisabled is passed to the skip handler for domain and MX
+	// checks of a Validator without a domain checker
+	ErrNetworkDisabled = errors.New("network checks are disabled")
+)
+
+// Result is the outcome of Validate. Fields of checks that were not
+// selected stay nil; selected checks that could not run are listed in
+// SkippedChecks.
+type Result struct {
+	Email          string `json:"email"`
+	IsSyntaxValid  *bool  `json:"isSyntaxValid,omitempty"`
+	IsDomainValid  *bool  `json:"isDomainValid,omitempty"`
+	MxRecordsFound *bool  `json:"mxRecordsFound,omitempty"`
+	IsDisposable   *bool  `json:"isDisposable,omitempty"`
+	// DNSTimedOut marks domain and MX results that are false because the
+	// lookups ran out of time, not because DNS found no records
+	DNSTimedOut bool `json:"dnsTimedOut,omitempty"`
+
+	SkippedChecks []string `json:"skippedChecks,omitempty"`
+}
+
+// Checks selects the checks a Validator runs
+type Checks struct {
+	Syntax     bool
+	Domain     bool
+	MX         bool
+	Disposable bool
+}
+
+// AllChecks runs every check, the default
+var AllChecks = Checks{Syntax: true, Domain: true, MX: true, Disposable: true}
+
+// ParseChecks converts check names into a Checks selection. An empty list
+// selects every check.
+func ParseChecks(names []string) (Checks, error) {
+	if len(names) == 0 {
+		return AllChecks, nil
+	}
+	var checks Checks
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case CheckSyntax:
+			checks.Syntax = true
+		case CheckDomain:
+			checks.Domain = true
+		case CheckMX:
+			checks.MX = true
+		case CheckDisposable:
+			checks.Disposable = true
+		default:
+			return Checks{}, fmt.Errorf("%w: %q (supported: %s, %s, %s, %s)",
+				ErrUnsupportedCheck, name, CheckSyntax, CheckDomain, CheckMX, CheckDisposable)
+		}
+	}
+	return checks, nil
+}
+
+// syntaxPattern is compiled on first use and shared by every Validator
+var syntaxPattern = sync.OnceValue(func() *regexp.Regexp {
+	return regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+})
+
+// IsSyntaxValid reports whether email looks like local@domain.tld
+func IsSyntaxValid(email string) bool {
+	return syntaxPattern().MatchString(email)
+}
+
+// Domain returns the domain part of an email address, or "" when the
+// address does not contain exactly one @
+func Domain(email string) string {
+	parts := strings.Split(email, "@")
+	if len(parts) == 2 {
+		return parts[1]
+	}
+	return ""
+}
+
+// Option configures a Validator
+type Option func(*Validator)
+
+// WithChecks selects the checks to run instead of AllChecks
+func WithChecks(checks Checks) Option {
+	return func(v *Validator) {
+		v.checks = checks
+	}
+}
+
+// WithResolver looks domains up with resolver instead of the system
+// resolver
+func WithResolver(resolver Resolver) Option {
+	return func(v *Validator) {
+		v.checkDomain = NewDomainChecker(resolver)
+	}
+}
+
+// WithDomainChecker runs the domain and MX checks with check, e.g. one
+// that caches its answers. A nil check disables them: they are skipped
+// with ErrNetworkDisabled.
+func WithDomainChecker(check DomainChecker) Option {
+	return func(v *Validator) {
+		v.checkDomain = check
+	}
+}
+
+// WithDisposable replaces the built-in list of disposable email domains
+// with list
+func WithDisposable(list *DisposableList) Option {
+	return func(v *Validator) {
+		v.disposable = list
+	}
+}
+
+// WithDisposableList replaces the built-in list of disposable email
+// domains. Domains are matched case-insensitively, with their subdomains.
+func WithDisposableList(domains []string) Option {
+	return WithDisposable(NewDisposableList(domains))
+}
+
+// WithExtraDisposable reports domains as disposable on top of the list in
+// use, built in or set by WithDisposable
+func WithExtraDisposable(domains []string) Option {
+	return func(v *Validator) {
+		if len(domains) == 0 {
+			return
+		}
+		v.extraDisposable = append(v.extraDisposable, NewDisposableList(domains))
+	}
+}
+
+// WithSkipHandler calls skip for every selected check that could not run,
+// with ErrNetworkDisabled or an error wrapping ErrDomainLookupFailed
+func WithSkipHandler(skip func(ctx context.Context, check string, err error)) Option {
+	return func(v *Validator) {
+		v.onSkip = skip
+	}
+}
+
+// Validator runs the selected checks on email addresses. It is safe for
+// concurrent use.
+type Validator struct {
+	checks      Checks
+	checkDomain DomainChecker
+	disposable  *DisposableList
+	// extraDisposable are the lists of WithExtraDisposable, kept apart
+	// so a large list in use is not copied per Validator
+	extraDisposable []*DisposableList
+	onSkip          func(ctx context.Context, check string, err error)
+}
+
+// New creates a Validator running every check with the system resolver
+// and the built-in disposable domain list, changed by opts
+func New(opts ...Option) *Validator {
+	v := &Validator{
+		checks:      AllChecks,
+		checkDomain: NewDomainChecker(net.DefaultResolver),
+		disposable:  defaultDisposable,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Validate runs the selected checks on email. The domain and MX checks
+// share one call to the domain checker, since both come from the same DNS
+// queries; ctx bounds those lookups.
+func (v *Validator) Validate(ctx context.Context, email string) Result {
+	result := Result{Email: email}
+	if v.checks.Syntax {
+		valid := IsSyntaxValid(email)
+		result.IsSyntaxValid = &valid
+	}
+	if v.checks.Domain || v.checks.MX {
+		v.checkDomainStage(ctx, email, &result)
+	}
+	if v.checks.Disposable {
+		disposable := v.isDisposable(Domain(email))
+		result.IsDisposable = &disposable
+	}
+	return result
+}
+
+// isDisposable reports whether domain or a parent domain is on the list
+// in use or an extra list
+func (v *Validator) isDisposable(domain string) bool {
+	if v.disposable.Contains(domain) {
+		return true
+	}
+	for _, extra := range v.extraDisposable {
+		if extra.Contains(domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Validator) checkDomainStage(ctx context.Context, email string, result *Result) {
+	if v.checkDomain == nil {
+		v.skipDomainStage(ctx, ErrNetworkDisabled, result)
+		return
+	}
+	domainValid, mxFound, err := v.checkDomain(ctx, email)
+	if err != nil {
+		v.skipDomainStage(ctx, err, result)
+		if IsTimeout(err) {
+			result.DNSTimedOut = true
+			v.setDomainStage(false, false, result)
+		}
+		return
+	}
+	v.setDomainStage(domainValid, mxFound, result)
+}
+
+// setDomainStage sets the results of the selected domain and MX checks
+func (v *Validator) setDomainStage(domainValid, mxFound bool, result *Result) {
+	if v.checks.Domain {
+		result.IsDomainValid = &domainValid
+	}
+	if v.checks.MX {
+		result.MxRecordsFound = &mxFound
+	}
+}
+
+// skipDomainStage reports the selected domain and MX checks as skipped
+func (v *Validator) skipDomainStage(ctx context.Context, err error, result *Result) {
+	if v.checks.Domain {
+		v.skip(ctx, CheckDomain, err, result)
+	}
+	if v.checks.MX {
+		v.skip(ctx, CheckMX, err, result)
+	}
+}
+
+func (v *Validator) skip(ctx context.Context, check string, err error, result *Result) {
+	result.SkippedChecks = append(result.SkippedChecks, check)
+	if v.onSkip != nil {
+		v.onSkip(ctx, check, err)
+	}
+}
@@ -0,0 +1,52 @@
+package email_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/innovelabs/microtools-go/pkg/validate/email"
+)
+
+func ExampleValidator_Validate() {
+	// A fixed domain checker keeps the example off the network; by default
+	// the Validator looks the domain up with net.DefaultResolver
+	v := email.New(
+		email.WithDisposableList([]string{"mailinator.com"}),
+		email.WithDomainChecker(func(ctx context.Context, address string) (bool, bool, error) {
+			return true, true, nil
+		}),
+	)
+	result := v.Validate(context.Background(), "ada@mailinator.com")
+	fmt.Println(*result.IsSyntaxValid, *result.MxRecordsFound, *result.IsDisposable)
+	// Output: true true true
+}
+
+func ExampleWithChecks() {
+	checks, err := email.ParseChecks([]string{"syntax", "disposable"})
+	if err != nil {
+		panic(err)
+	}
+	v := email.New(email.WithChecks(checks))
+	result := v.Validate(context.Background(), "not an email")
+	fmt.Println(*result.IsSyntaxValid, result.IsDomainValid == nil)
+	// Output: false true
+}
+
+func ExampleWithExtraDisposable() {
+	v := email.New(
+		email.WithChecks(email.Checks{Syntax: true, Disposable: true}),
+		email.WithExtraDisposable([]string{"Burner.example"}),
+	)
+	for _, address := range []string{"ada@burner.example", "ada@mailinator.com", "ada@example.com"} {
+		fmt.Println(address, *v.Validate(context.Background(), address).IsDisposable)
+	}
+	// Output:
+	// ada@burner.example true
+	// ada@mailinator.com true
+	// ada@example.com false
+}
+
+func ExampleDomain() {
+	fmt.Println(email.Domain("ada@example.com"), email.IsSyntaxValid("ada@example"))
+	// Output: example.com false
+}
@@ -0,0 +1,80 @@
+package iban
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BBAN character classes, in the SWIFT IBAN registry notation
+const (
+	ClassDigit        = 'n'
+	ClassLetter       = 'a'
+	ClassAlphanumeric = 'c'
+)
+
+// compileFormat turns a spec regex such as ^[0-9]{5}[A-Z0-9]{12}$ into one
+// character class per position. Only the subset the specs use is accepted:
+// anchors, the classes [0-9], [A-Z] and [A-Z0-9], and {n} counts.
+func compileFormat(format string) ([]byte, error) {
+	rest := strings.TrimSuffix(strings.TrimPrefix(format, "^"), "$")
+	var classes []byte
+	for rest != "" {
+		var class byte
+		switch {
+		case strings.HasPrefix(rest, "[0-9]"):
+			class, rest = ClassDigit, rest[len("[0-9]"):]
+		case strings.HasPrefix(rest, "[A-Z0-9]"):
+			class, rest = ClassAlphanumeric, rest[len("[A-Z0-9]"):]
+		case strings.HasPrefix(rest, "[A-Z]"):
+			class, rest = ClassLetter, rest[len("[A-Z]"):]
+		default:
+			return nil, fmt.Errorf("unsupported BBAN format element at %q", rest)
+		}
+
+		count := 1
+		if strings.HasPrefix(rest, "{") {
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated repetition in %q", format)
+			}
+			n, err := strconv.Atoi(rest[1:end])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid repetition %q", rest[:end+1])
+			}
+			count, rest = n, rest[end+1:]
+		}
+		for i := 0; i < count; i++ {
+			classes = append(classes, class)
+		}
+	}
+	return classes, nil
+}
+
+// ClassMatches reports whether c, an upper-case character, belongs to class
+func ClassMatches(class byte, c byte) bool {
+	isDigit := c >= '0' && c <= '9'
+	isLetter := c >= 'A' && c <= 'Z'
+	switch class {
+	case ClassDigit:
+		return isDigit
+	case ClassLetter:
+		return isLetter
+	default:
+		return isDigit || isLetter
+	}
+}
+
+// matchesClasses reports whether bban, already upper-cased and of the spec
+// length, has a character of the right class at every position
+func matchesClasses(classes []byte, bban string) bool {
+	if len(classes) != len(bban) {
+		return false
+	}
+	for i := 0; i < len(bban); i++ {
+		if !ClassMatches(classes[i], bban[i]) {
+			return false
+		}
+	}
+	return true
+}
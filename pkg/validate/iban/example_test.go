@@ -0,0 +1,36 @@
+package iban_test
+
+import (
+	"fmt"
+
+	"github.com/innovelabs/microtools-go/pkg/validate/iban"
+)
+
+func ExampleValidate() {
+	result := iban.Validate("de89 3704 0044 0532 0130 00")
+	fmt.Println(result.IsValid, result.FormattedIBAN, result.CountryName, result.BankCode)
+
+	result = iban.Validate("DE89370400440532013001")
+	fmt.Println(result.IsValid, result.IsChecksumValid)
+	// Output:
+	// true DE89 3704 0044 0532 0130 00 Germany 37040044
+	// false false
+}
+
+func ExampleWithCountrySpecs() {
+	specs := iban.DefaultSpecs()
+	v, err := iban.New(iban.WithCountrySpecs(map[string]iban.Spec{"DE": specs["DE"]}))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(v.Validate("DE89370400440532013000").IsValid)
+	fmt.Println(v.Validate("GB82WEST12345698765432").IsCountrySupported)
+	// Output:
+	// true
+	// false
+}
+
+func ExampleFormat() {
+	fmt.Println(iban.Format("GB82WEST12345698765432"))
+	// Output: GB82 WEST 1234 5698 7654 32
+}
@@ -0,0 +1,256 @@
+// Package iban validates International Bank Account Numbers: country,
+// length, BBAN format, the ISO 13616 mod-97 checksum and, for some
+// countries, the national check digits inside the BBAN. It is pure
+// computation with no I/O.
+package iban
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// minLength is the length of the shortest IBAN of any country
+const minLength = 15
+
+// groupSize is the print-format grouping used for every country
+const groupSize = 4
+
+// Result is the outcome of Validate, one flag per check. Fields after a
+// failed check keep their zero values.
+type Result struct {
+	IBAN               string `json:"iban"`
+	IsValid            bool   `json:"isValid"`
+	FormattedIBAN      string `json:"formattedIban"`
+	CountryCode        string `json:"countryCode"`
+	CountryName        string `json:"countryName"`
+	CheckDigits        string `json:"checkDigits"`
+	BBAN               string `json:"bban"`
+	BankCode           string `json:"bankCode"`
+	AccountNumber      string `json:"accountNumber"`
+	IsFormatValid      bool   `json:"isFormatValid"`
+	IsCountrySupported bool   `json:"isCountrySupported"`
+	IsLengthValid      bool   `json:"isLengthValid"`
+	IsChecksumValid    bool   `json:"isChecksumValid"`
+	// IsNationalChecksumValid checks the check digits inside the BBAN; it
+	// is null for countries without any and when the format is invalid
+	IsNationalChecksumValid *bool `json:"isNationalChecksumValid"`
+
+	// ExpectedLength and ExampleIBAN come from the country spec; they are
+	// set once the country is supported
+	ExpectedLength int    `json:"expectedLength,omitempty"`
+	ExampleIBAN    string `json:"exampleIban,omitempty"`
+	// Failures describes each failed check in order; FailureReason is the
+	// first of them. Both are empty for a valid IBAN.
+	FailureReason string   `json:"failureReason,omitempty"`
+	Failures      []string `json:"failures,omitempty"`
+
+	// SeparatorsRemoved is set when dashes, dots or tabs were dropped from
+	// the input before validation
+	SeparatorsRemoved bool `json:"-"`
+}
+
+// Option configures a Validator
+type Option func(*Validator)
+
+// WithCountrySpecs replaces the built-in country specifications, e.g. to
+// restrict validation to some countries or add one
+func WithCountrySpecs(specs map[string]Spec) Option {
+	return func(v *Validator) {
+		v.specs = specs
+	}
+}
+
+// Validator validates IBANs against a set of country specifications. It
+// is safe for concurrent use.
+type Validator struct {
+	specs   map[string]Spec
+	classes map[string][]byte
+}
+
+// New creates a Validator. It fails when a spec's BBANFormat is outside
+// the supported regex subset or does not match its length.
+func New(opts ...Option) (*Validator, error) {
+	v := &Validator{specs: defaultSpecs}
+	for _, opt := range opts {
+		opt(v)
+	}
+	v.classes = make(map[string][]byte, len(v.specs))
+	for code, spec := range v.specs {
+		classes, err := compileFormat(spec.BBANFormat)
+		if err != nil {
+			return nil, fmt.Errorf("IBAN spec %s: %w", code, err)
+		}
+		if len(classes) != spec.Length-4 {
+			return nil, fmt.Errorf("IBAN spec %s: BBAN format covers %d characters, want %d", code, len(classes), spec.Length-4)
+		}
+		v.classes[code] = classes
+	}
+	return v, nil
+}
+
+var defaultValidator = sync.OnceValue(func() *Validator {
+	v, err := New()
+	if err != nil {
+		panic(err)
+	}
+	return v
+})
+
+// Validate validates iban against the built-in country specifications
+func Validate(iban string) Result {
+	return defaultValidator().Validate(iban)
+}
+
+// Spec returns the specification of an upper-case country code
+func (v *Validator) Spec(countryCode string) (Spec, bool) {
+	spec, ok := v.specs[countryCode]
+	return spec, ok
+}
+
+// Classes returns the character class of each BBAN position of a country,
+// ClassDigit, ClassLetter or ClassAlphanumeric, or nil for an unknown
+// country. It must not be modified.
+func (v *Validator) Classes(countryCode string) []byte {
+	return v.classes[countryCode]
+}
+
+func dropSeparator(r rune) rune {
+	if r == '-' || r == '.' || r == '\t' {
+		return -1
+	}
+	return r
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Validate validates iban. Spaces are ignored; dashes, dots and tabs are
+// removed and reported in SeparatorsRemoved.
+func (v *Validator) Validate(iban string) Result {
+	result := Result{IBAN: iban}
+
+	clean := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(iban), " ", ""))
+	if stripped := strings.Map(dropSeparator, clean); stripped != clean {
+		result.SeparatorsRemoved = true
+		clean = stripped
+	}
+
+	if len(clean) < minLength {
+		result.fail("length is %d but an IBAN has at least %d characters", len(clean), minLength)
+		return result
+	}
+	if !isLetter(clean[0]) || !isLetter(clean[1]) {
+		result.fail("country code %q is not two letters", clean[0:2])
+		return result
+	}
+	result.CountryCode = clean[0:2]
+
+	if !isDigit(clean[2]) || !isDigit(clean[3]) {
+		result.fail("check digits %q are not two digits", clean[2:4])
+		return result
+	}
+	result.CheckDigits = clean[2:4]
+
+	spec, exists := v.specs[result.CountryCode]
+	if !exists {
+		result.fail("country %s does not use IBANs or is not supported", result.CountryCode)
+		return result
+	}
+	result.IsCountrySupported = true
+	result.CountryName = spec.CountryName
+	result.ExpectedLength = spec.Length
+	result.ExampleIBAN = spec.Example
+
+	if len(clean) != spec.Length {
+		result.fail("length is %d but %s requires %d", len(clean), result.CountryCode, spec.Length)
+		return result
+	}
+	result.IsLengthValid = true
+
+	result.BBAN = clean[4:]
+	if !matchesClasses(v.classes[result.CountryCode], result.BBAN) {
+		result.fail("BBAN contains characters not allowed for country %s", result.CountryCode)
+		return result
+	}
+	result.IsFormatValid = true
+
+	if spec.BankCodeLen > 0 && spec.BankCodeStart+spec.BankCodeLen <= len(clean) {
+		result.BankCode = clean[spec.BankCodeStart : spec.BankCodeStart+spec.BankCodeLen]
+	}
+	if spec.AccountLen > 0 && spec.AccountStart+spec.AccountLen <= len(clean) {
+		result.AccountNumber = clean[spec.AccountStart : spec.AccountStart+spec.AccountLen]
+	}
+
+	remainder := checksumRemainder(clean)
+	result.IsChecksumValid = remainder == 1
+	if !result.IsChecksumValid {
+		result.fail("checksum mod-97 result was %d, expected 1", remainder)
+	}
+	result.IsNationalChecksumValid = validateNationalChecksum(result.CountryCode, result.BBAN)
+	if result.IsNationalChecksumValid != nil && !*result.IsNationalChecksumValid {
+		result.fail("national check digits in the BBAN are wrong for country %s", result.CountryCode)
+	}
+	result.FormattedIBAN = Format(clean)
+
+	result.IsValid = result.IsCountrySupported && result.IsLengthValid &&
+		result.IsFormatValid && result.IsChecksumValid &&
+		(result.IsNationalChecksumValid == nil || *result.IsNationalChecksumValid)
+	return result
+}
+
+// ChecksumValid reports whether the ISO 13616 mod-97 checksum of iban
+// holds: with the first four characters moved to the end and letters
+// replaced by 10 to 35, the number is 1 mod 97. Spaces are ignored.
+func ChecksumValid(iban string) bool {
+	return checksumRemainder(strings.ToUpper(strings.ReplaceAll(iban, " ", ""))) == 1
+}
+
+// checksumRemainder returns the mod-97 remainder of an upper-case iban
+// with its first four characters moved to the end, or -1 when it is too
+// short or has a character other than a digit or letter
+func checksumRemainder(iban string) int {
+	if len(iban) < 4 {
+		return -1
+	}
+
+	remainder := 0
+	for _, c := range []byte(iban[4:] + iban[0:4]) {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			value := int(c-'A') + 10
+			remainder = (remainder*100 + value) % 97
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		default:
+			return -1
+		}
+	}
+	return remainder
+}
+
+// fail records a failed check
+func (r *Result) fail(format string, args ...any) {
+	failure := fmt.Sprintf(format, args...)
+	if r.FailureReason == "" {
+		r.FailureReason = failure
+	}
+	r.Failures = append(r.Failures, failure)
+}
+
+// Format groups iban in blocks of four characters, the print format
+func Format(iban string) string {
+	var b strings.Builder
+	for i, c := range iban {
+		if i > 0 && i%groupSize == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package iban_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/pkg/validate/iban"
+)
+
+func TestValidateFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		iban     string
+		failures []string
+	}{
+		{"DE too short", "DE8937040044053201300", []string{"length is 21 but DE requires 22"}},
+		{"FR too long", "FR1420041010050500013M026061", []string{"length is 28 but FR requires 27"}},
+		{"GB too short", "GB29NWBK6016133192681", []string{"length is 21 but GB requires 22"}},
+		{"FR letter in bank code", "FR14A0041010050500013M02606", []string{"BBAN contains characters not allowed for country FR"}},
+		{"GB digit in bank code", "GB29NWB160161331926819", []string{"BBAN contains characters not allowed for country GB"}},
+		{"NL digit in bank code", "NL91ABN10417164300", []string{"BBAN contains characters not allowed for country NL"}},
+		{"DE wrong checksum", "DE89370400440532013001", []string{"checksum mod-97 result was 28, expected 1"}},
+		{"GB wrong checksum", "GB29NWBK60161331926818", []string{"checksum mod-97 result was 71, expected 1"}},
+		{"CH wrong checksum", "CH9300762011623852958", []string{"checksum mod-97 result was 28, expected 1"}},
+		{"IT wrong checksum and CIN", "IT60X0542811101000000123457", []string{
+			"checksum mod-97 result was 28, expected 1",
+			"national check digits in the BBAN are wrong for country IT",
+		}},
+		{"below minimum length", "DE8937040044", []string{"length is 12 but an IBAN has at least 15 characters"}},
+		{"digits for country", "1289370400440532013000", []string{`country code "12" is not two letters`}},
+		{"letters for check digits", "DEXX370400440532013000", []string{`check digits "XX" are not two digits`}},
+		{"unsupported country", "US89370400440532013000", []string{"country US does not use IBANs or is not supported"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := iban.Validate(tt.iban)
+			if result.IsValid {
+				t.Fatalf("Validate(%q) is valid", tt.iban)
+			}
+			if !slices.Equal(result.Failures, tt.failures) {
+				t.Errorf("Failures = %q, want %q", result.Failures, tt.failures)
+			}
+			if result.FailureReason != tt.failures[0] {
+				t.Errorf("FailureReason = %q, want %q", result.FailureReason, tt.failures[0])
+			}
+		})
+	}
+}
+
+func TestValidateCountrySpecFields(t *testing.T) {
+	tests := []struct {
+		iban, example string
+		length        int
+	}{
+		{"DE89370400440532013000", "DE89370400440532013000", 22},
+		{"FR1420041010050500013M0260", "FR1420041010050500013M02606", 27},
+		{"GB29NWBK60161331926818", "GB29NWBK60161331926819", 22},
+		{"US89370400440532013000", "", 0},
+	}
+	for _, tt := range tests {
+		result := iban.Validate(tt.iban)
+		if result.ExpectedLength != tt.length || result.ExampleIBAN != tt.example {
+			t.Errorf("Validate(%q) = length %d, example %q, want %d, %q",
+				tt.iban, result.ExpectedLength, result.ExampleIBAN, tt.length, tt.example)
+		}
+	}
+
+	result := iban.Validate("DE89370400440532013000")
+	if !result.IsValid || result.FailureReason != "" || result.Failures != nil {
+		t.Errorf("valid IBAN: IsValid %v, FailureReason %q, Failures %q", result.IsValid, result.FailureReason, result.Failures)
+	}
+}
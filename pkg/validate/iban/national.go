@@ -0,0 +1,156 @@
+package iban
+
+// nationalChecksums validates the national check digits some countries
+// embed in the BBAN, which can catch a transposition the IBAN mod-97
+// misses. Each function gets the upper-cased BBAN, already matched
+// against the country's format; a country is covered by adding it here.
+var nationalChecksums = map[string]func(bban string) bool{
+	"BE": belgianNationalChecksum,
+	"ES": spanishNationalChecksum,
+	"FR": frenchNationalChecksum,
+	"IT": italianNationalChecksum,
+	"NO": norwegianNationalChecksum,
+	"PT": portugueseNationalChecksum,
+}
+
+// validateNationalChecksum returns whether the BBAN's national check
+// digits are right, or nil when the country has none we check
+func validateNationalChecksum(countryCode, bban string) *bool {
+	check, ok := nationalChecksums[countryCode]
+	if !ok {
+		return nil
+	}
+	valid := check(bban)
+	return &valid
+}
+
+// digitValue returns the value of the ASCII digit c
+func digitValue(c byte) int {
+	return int(c - '0')
+}
+
+// mod97 returns the remainder of the decimal digits s divided by 97
+func mod97(s string) int {
+	remainder := 0
+	for i := 0; i < len(s); i++ {
+		remainder = (remainder*10 + digitValue(s[i])) % 97
+	}
+	return remainder
+}
+
+// belgianNationalChecksum checks the account number: the last two digits
+// are the first ten mod 97, with 97 in place of 0
+func belgianNationalChecksum(bban string) bool {
+	check := mod97(bban[:10])
+	if check == 0 {
+		check = 97
+	}
+	return check == digitValue(bban[10])*10+digitValue(bban[11])
+}
+
+// spanishWeights are the weights of the Spanish control digits (DC)
+var spanishWeights = [10]int{1, 2, 4, 8, 5, 10, 9, 7, 3, 6}
+
+// spanishNationalChecksum checks the two DC digits of bank (4), branch (4),
+// DC (2) and account (10): the first covers "00" + bank + branch, the
+// second the account
+func spanishNationalChecksum(bban string) bool {
+	return spanishControlDigit("00"+bban[:8]) == digitValue(bban[8]) &&
+		spanishControlDigit(bban[10:20]) == digitValue(bban[9])
+}
+
+func spanishControlDigit(digits string) int {
+	sum := 0
+	for i, w := range spanishWeights {
+		sum += digitValue(digits[i]) * w
+	}
+	switch d := 11 - sum%11; d {
+	case 11:
+		return 0
+	case 10:
+		return 1
+	default:
+		return d
+	}
+}
+
+// frenchLetterValues maps letters in a French account number to digits
+// for the clé RIB: A-I, J-R and S-Z count 1-9, with S starting at 2
+var frenchLetterValues = [26]int{
+	1, 2, 3, 4, 5, 6, 7, 8, 9, // A-I
+	1, 2, 3, 4, 5, 6, 7, 8, 9, // J-R
+	2, 3, 4, 5, 6, 7, 8, 9, // S-Z
+}
+
+// frenchNationalChecksum checks the clé RIB of bank (5), branch (5),
+// account (11) and key (2): the key is 97 minus (89 × bank + 15 × branch
+// + 3 × account) mod 97
+func frenchNationalChecksum(bban string) bool {
+	remainder := 0
+	for i := 0; i < 21; i++ {
+		c := bban[i]
+		value := 0
+		if c >= 'A' && c <= 'Z' {
+			value = frenchLetterValues[c-'A']
+		} else {
+			value = digitValue(c)
+		}
+		remainder = (remainder*10 + value) % 97
+	}
+	// as one number, bank and branch weigh 89 and 15 against the account
+	// modulo 97 once multiplied by 100, so this is the weighted sum
+	key := 97 - (remainder*100)%97
+	return key == digitValue(bban[21])*10+digitValue(bban[22])
+}
+
+// italianOddValues are the CIN values of characters in odd positions,
+// indexed by digit or letter (0-9 and A-Z share the index)
+var italianOddValues = [26]int{1, 0, 5, 7, 9, 13, 15, 17, 19, 21, 2, 4, 18, 20, 11, 3, 6, 8, 12, 14, 16, 10, 22, 25, 24, 23}
+
+// italianNationalChecksum checks the CIN letter that precedes ABI (5), CAB
+// (5) and account (12): characters in odd positions are mapped through
+// italianOddValues, those in even positions count their index, and the sum
+// mod 26 is the CIN as a letter
+func italianNationalChecksum(bban string) bool {
+	sum := 0
+	for i := 1; i < 23; i++ {
+		c := bban[i]
+		index := 0
+		if c >= 'A' && c <= 'Z' {
+			index = int(c - 'A')
+		} else {
+			index = digitValue(c)
+		}
+		if i%2 == 1 {
+			sum += italianOddValues[index]
+		} else {
+			sum += index
+		}
+	}
+	return bban[0] == byte('A'+sum%26)
+}
+
+// norwegianWeights are the MOD11 weights of a Norwegian account number
+var norwegianWeights = [10]int{5, 4, 3, 2, 7, 6, 5, 4, 3, 2}
+
+// norwegianNationalChecksum checks the last digit of the 11-digit account
+// number with MOD11; numbers whose check would be 10 are never issued
+func norwegianNationalChecksum(bban string) bool {
+	sum := 0
+	for i, w := range norwegianWeights {
+		sum += digitValue(bban[i]) * w
+	}
+	check := 11 - sum%11
+	if check == 11 {
+		check = 0
+	}
+	return check < 10 && check == digitValue(bban[10])
+}
+
+// portugueseNationalChecksum checks the two NIB check digits after bank
+// (4), branch (4) and account (11): 98 minus the first 19 digits times 100
+// mod 97
+func portugueseNationalChecksum(bban string) bool {
+	check := 98 - (mod97(bban[:19])*100)%97
+	return check == digitValue(bban[19])*10+digitValue(bban[20])
+}
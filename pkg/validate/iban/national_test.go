@@ -0,0 +1,85 @@
+package iban
+
+import (
+	"fmt"
+	"testing"
+)
+
+// withCheckDigits builds the IBAN of bban with correct mod-97 check
+// digits, so only the national check digits can fail
+func withCheckDigits(countryCode, bban string) string {
+	remainder := 0
+	for _, c := range []byte(bban + countryCode + "00") {
+		if c >= 'A' && c <= 'Z' {
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(c-'0')) % 97
+		}
+	}
+	return fmt.Sprintf("%s%02d%s", countryCode, 98-remainder, bban)
+}
+
+func TestNationalChecksums(t *testing.T) {
+	tests := []struct {
+		country, name, bban string
+		valid               bool
+	}{
+		// BE: the last two digits are the first ten mod 97
+		{"BE", "published example", "539007547034", true},
+		{"BE", "mod 97 of zero is 97", "000000000097", true},
+		{"BE", "wrong check", "539007547035", false},
+		{"BE", "transposed digits", "539070547034", false},
+
+		// ES: DC digits over bank and branch, then the account
+		{"ES", "published example", "21000418450200051332", true},
+		{"ES", "check of 11 is 0", "00491500051234567892", true},
+		{"ES", "wrong first DC", "21000418550200051332", false},
+		{"ES", "wrong second DC", "21000418440200051332", false},
+		{"ES", "transposed account digits", "21000418450200015332", false},
+
+		// FR: clé RIB, letters in the account mapped to digits
+		{"FR", "published example", "20041010050500013M02606", true},
+		{"FR", "numeric account", "30006000011234567890189", true},
+		{"FR", "wrong key", "20041010050500013M02607", false},
+		{"FR", "letter changed", "20041010050500013N02606", false},
+
+		// IT: CIN letter over ABI, CAB and account
+		{"IT", "published example", "X0542811101000000123456", true},
+		{"IT", "wrong CIN", "Y0542811101000000123456", false},
+		{"IT", "transposed ABI digits", "X5042811101000000123456", false},
+
+		// NO: MOD11 over the account number
+		{"NO", "published example", "86011117947", true},
+		{"NO", "wrong check", "86011117948", false},
+		{"NO", "check of 10 is never issued", "00000000060", false},
+
+		// PT: NIB check digits
+		{"PT", "published example", "000201231234567890154", true},
+		{"PT", "wrong check", "000201231234567890155", false},
+		{"PT", "transposed branch digits", "000202131234567890154", false},
+	}
+	for _, tt := range tests {
+		if got := nationalChecksums[tt.country](tt.bban); got != tt.valid {
+			t.Errorf("%s %s (%s): %v, want %v", tt.country, tt.name, tt.bban, got, tt.valid)
+		}
+
+		// Through Validate, with the mod-97 check digits made to pass
+		result := Validate(withCheckDigits(tt.country, tt.bban))
+		if !result.IsChecksumValid || result.IsNationalChecksumValid == nil || *result.IsNationalChecksumValid != tt.valid ||
+			result.IsValid != tt.valid {
+			t.Errorf("%s %s: Validate = %+v", tt.country, tt.name, result)
+		}
+	}
+}
+
+func TestNationalChecksumOptional(t *testing.T) {
+	result := Validate("DE89370400440532013000")
+	if result.IsNationalChecksumValid != nil || !result.IsValid {
+		t.Errorf("DE has no national check: %+v", result)
+	}
+
+	result = Validate("BE68539007547035")
+	if result.IsValid || result.IsChecksumValid || *result.IsNationalChecksumValid {
+		t.Errorf("both checks failing: %+v", result)
+	}
+}
@@ -0,0 +1,113 @@
+package iban_test
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/innovelabs/microtools-go/pkg/validate/iban"
+	"github.com/innovelabs/microtools-go/pkg/validate/testsupport"
+)
+
+// quickConfig keeps the properties of all countries within a few seconds
+func quickConfig() *quick.Config {
+	if testing.Short() {
+		return &quick.Config{MaxCount: 10}
+	}
+	return &quick.Config{MaxCount: 100}
+}
+
+// forEachCountry checks property, given a generated IBAN of each country
+// and a random source, with inputs from testing/quick; a failure reports
+// the seed that reproduces it
+func forEachCountry(t *testing.T, property func(t *testing.T, r *rand.Rand, country, generated string) bool) {
+	for _, country := range testsupport.IBANCountries() {
+		t.Run(country, func(t *testing.T) {
+			check := func(seed int64) bool {
+				r := rand.New(rand.NewSource(seed))
+				generated, err := testsupport.IBAN(r, country)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return property(t, r, country, generated)
+			}
+			if err := quick.Check(check, quickConfig()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPropertyGeneratedIBANIsValid(t *testing.T) {
+	forEachCountry(t, func(t *testing.T, r *rand.Rand, country, generated string) bool {
+		result := iban.Validate(generated)
+		if !result.IsValid {
+			t.Logf("%s: %+v", generated, result)
+		}
+		return result.IsValid
+	})
+}
+
+// Changing one character of the check digits or BBAN to another of the
+// same kind keeps the format and breaks the mod-97 checksum, which catches
+// every single substitution
+func TestPropertySubstitutionFailsChecksum(t *testing.T) {
+	forEachCountry(t, func(t *testing.T, r *rand.Rand, country, generated string) bool {
+		mutated := testsupport.Substitute(r, generated, 2+r.Intn(len(generated)-2))
+		result := iban.Validate(mutated)
+		if !result.IsFormatValid || result.IsChecksumValid || result.IsValid {
+			t.Logf("%s: %+v", mutated, result)
+			return false
+		}
+		return true
+	})
+}
+
+// A letter where the spec wants a digit, or a digit where it wants a
+// letter, fails the format; a character more or less fails the length
+func TestPropertyFormatAndLengthViolations(t *testing.T) {
+	forEachCountry(t, func(t *testing.T, r *rand.Rand, country, generated string) bool {
+		i := 4 + r.Intn(len(generated)-4)
+		b := []byte(generated)
+		switch testsupport.BBANClass(country, i-4) {
+		case iban.ClassDigit:
+			b[i] = 'A' + byte(r.Intn(26))
+		case iban.ClassLetter:
+			b[i] = '0' + byte(r.Intn(10))
+		}
+		if string(b) != generated && iban.Validate(string(b)).IsFormatValid {
+			t.Logf("%s passes the format", b)
+			return false
+		}
+
+		if iban.Validate(generated[:len(generated)-1]).IsLengthValid ||
+			iban.Validate(generated+"0").IsLengthValid {
+			t.Logf("%s shortened or lengthened passes the length", generated)
+			return false
+		}
+		return true
+	})
+}
+
+// With the mod-97 check digits recomputed after a substitution, the
+// national check digits are all that can fail, and the validator must
+// agree with the way banks compute them
+func TestPropertyNationalChecksumMatchesReference(t *testing.T) {
+	forEachCountry(t, func(t *testing.T, r *rand.Rand, country, generated string) bool {
+		bban := generated[4:]
+		bban = testsupport.Substitute(r, bban, r.Intn(len(bban)))
+		result := iban.Validate(testsupport.WithIBANCheckDigits(country, bban))
+		want, checked := testsupport.NationalChecksumValid(country, bban)
+		switch {
+		case !result.IsChecksumValid:
+			t.Logf("%s: recomputed check digits fail", bban)
+			return false
+		case !checked:
+			return result.IsNationalChecksumValid == nil && result.IsValid
+		case result.IsNationalChecksumValid == nil || *result.IsNationalChecksumValid != want:
+			t.Logf("%s: national checksum %v, want %t", bban, result.IsNationalChecksumValid, want)
+			return false
+		}
+		return result.IsValid == want
+	})
+}
@@ -0,0 +1,39 @@
+package testsupport_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/innovelabs/microtools-go/pkg/validate/testsupport"
+)
+
+// TestConformance runs the known values in testdata/corpus.txt and, when
+// CONFORMANCE_CORPUS names a file, that corpus too
+func TestConformance(t *testing.T) {
+	t.Run("builtin", func(t *testing.T) {
+		runCorpus(t, "testdata/corpus.txt")
+	})
+	t.Run("external", func(t *testing.T) {
+		path := os.Getenv(testsupport.CorpusEnv)
+		if path == "" {
+			t.Skipf("%s is not set", testsupport.CorpusEnv)
+		}
+		runCorpus(t, path)
+	})
+}
+
+func runCorpus(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	mismatches, err := testsupport.RunCorpus(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range mismatches {
+		t.Errorf("%s:%d: %s %q valid = %t, want %t", path, e.Line, e.Kind, e.Value, !e.Valid, e.Valid)
+	}
+}
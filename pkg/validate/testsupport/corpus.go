@@ -0,0 +1,94 @@
+package testsupport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/pkg/generate/barcode"
+	"github.com/innovelabs/microtools-go/pkg/validate/iban"
+)
+
+// CorpusEnv names the environment variable holding the path of an
+// external corpus; TestConformance runs it when set:
+//
+//	CONFORMANCE_CORPUS=/path/to/corpus.txt go test ./pkg/validate/testsupport -run Conformance
+const CorpusEnv = "CONFORMANCE_CORPUS"
+
+// Checks validate the values of each corpus kind with the package under
+// test
+var Checks = map[string]func(value string) bool{
+	"iban": func(value string) bool {
+		return iban.Validate(value).IsValid
+	},
+	"ean13": func(value string) bool {
+		return len(value) == EAN13Length && barcode.Validate(barcode.TypeEAN13, value) == nil
+	},
+	"upca": func(value string) bool {
+		return len(value) == UPCALength && barcode.Validate(barcode.TypeUPCA, value) == nil
+	},
+	"luhn": LuhnValid,
+}
+
+// CorpusEntry is one line of a corpus: the kind of value, its expected
+// verdict and the value, which may contain spaces
+type CorpusEntry struct {
+	Line  int
+	Kind  string
+	Valid bool
+	Value string
+}
+
+// ReadCorpus reads a corpus with one entry per line:
+//
+//	<kind> <valid|invalid> <value>
+//
+// kind is a key of Checks. Blank lines and lines starting with # are
+// skipped.
+func ReadCorpus(r io.Reader) ([]CorpusEntry, error) {
+	var entries []CorpusEntry
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("corpus line %d: want <kind> <valid|invalid> <value>", line)
+		}
+		if _, ok := Checks[fields[0]]; !ok {
+			return nil, fmt.Errorf("corpus line %d: unknown kind %q", line, fields[0])
+		}
+		if fields[1] != "valid" && fields[1] != "invalid" {
+			return nil, fmt.Errorf("corpus line %d: verdict must be valid or invalid, got %q", line, fields[1])
+		}
+		entries = append(entries, CorpusEntry{
+			Line:  line,
+			Kind:  fields[0],
+			Valid: fields[1] == "valid",
+			Value: strings.TrimSpace(fields[2]),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading corpus: %w", err)
+	}
+	return entries, nil
+}
+
+// RunCorpus checks every entry of the corpus read from r and returns those
+// whose verdict the validators disagree with
+func RunCorpus(r io.Reader) ([]CorpusEntry, error) {
+	entries, err := ReadCorpus(r)
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []CorpusEntry
+	for _, e := range entries {
+		if Checks[e.Kind](e.Value) != e.Valid {
+			mismatches = append(mismatches, e)
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,48 @@
+package testsupport_test
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/innovelabs/microtools-go/pkg/validate/iban"
+	"github.com/innovelabs/microtools-go/pkg/validate/testsupport"
+)
+
+// A corpus holds one value per line after its kind and expected verdict.
+// Save it to a file and run it against the validators with
+//
+//	CONFORMANCE_CORPUS=corpus.txt go test ./pkg/validate/testsupport -run Conformance
+func ExampleRunCorpus() {
+	corpus := `# kind verdict value
+iban valid DE89 3704 0044 0532 0130 00
+iban valid DE89 3704 0044 0532 0130 01
+ean13 valid 4006381333931
+luhn invalid 79927398713
+`
+	mismatches, err := testsupport.RunCorpus(strings.NewReader(corpus))
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range mismatches {
+		fmt.Printf("line %d: %s %s\n", e.Line, e.Kind, e.Value)
+	}
+	// Output:
+	// line 3: iban DE89 3704 0044 0532 0130 01
+	// line 5: luhn 79927398713
+}
+
+func ExampleIBAN() {
+	r := rand.New(rand.NewSource(1))
+	for _, country := range []string{"DE", "FR", "NO"} {
+		generated, err := testsupport.IBAN(r, country)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(country, iban.Validate(generated).IsValid)
+	}
+	// Output:
+	// DE true
+	// FR true
+	// NO true
+}
@@ -0,0 +1,47 @@
+package testsupport
+
+import "math/rand"
+
+// GTIN lengths of the symbologies the barcode package encodes
+const (
+	UPCALength  = 12
+	EAN13Length = 13
+)
+
+// GTIN returns a random GS1 key of length digits, the last one its check
+// digit: UPCALength for UPC-A, EAN13Length for EAN-13
+func GTIN(r *rand.Rand, length int) string {
+	b := digits(r, length)
+	b[length-1] = '0' + byte(gtinCheck(b[:length-1]))
+	return string(b)
+}
+
+// GTINValid reports whether s is all digits and its last digit is the GS1
+// check digit of the others
+func GTINValid(s string) bool {
+	if len(s) < 2 || !allDigits(s) {
+		return false
+	}
+	return gtinCheck([]byte(s[:len(s)-1])) == digitOf(s[len(s)-1])
+}
+
+// gtinCheck is the digit that brings the sum of payload to a multiple of
+// 10, the digit next to the check weighing 3 and the others 1 and 3 in turn
+func gtinCheck(payload []byte) int {
+	sum := 0
+	weight := 3
+	for i := len(payload) - 1; i >= 0; i-- {
+		sum += digitOf(payload[i]) * weight
+		weight = 4 - weight
+	}
+	return (10 - sum%10) % 10
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
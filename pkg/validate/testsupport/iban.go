@@ -0,0 +1,235 @@
+package testsupport
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/innovelabs/microtools-go/pkg/validate/iban"
+)
+
+// specValidator gives the BBAN character classes of the built-in specs
+var specValidator = sync.OnceValue(func() *iban.Validator {
+	v, err := iban.New()
+	if err != nil {
+		panic(err)
+	}
+	return v
+})
+
+// IBANCountries returns the country codes of the built-in specs, sorted
+func IBANCountries() []string {
+	specs := iban.DefaultSpecs()
+	codes := make([]string, 0, len(specs))
+	for code := range specs {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// IBAN returns a random valid IBAN of countryCode: a BBAN matching the
+// country's format with its national check digits set, and the mod-97
+// check digits
+func IBAN(r *rand.Rand, countryCode string) (string, error) {
+	classes := specValidator().Classes(countryCode)
+	if classes == nil {
+		return "", fmt.Errorf("no IBAN spec for %q", countryCode)
+	}
+	for {
+		bban := make([]byte, len(classes))
+		for i, class := range classes {
+			bban[i] = randomOfClass(r, class)
+		}
+		if setNationalCheck(countryCode, bban) {
+			return WithIBANCheckDigits(countryCode, string(bban)), nil
+		}
+		// e.g. a Norwegian account whose check would be 10 is never issued
+	}
+}
+
+// BBANClass returns the character class of BBAN position i of
+// countryCode: iban.ClassDigit, iban.ClassLetter or iban.ClassAlphanumeric
+func BBANClass(countryCode string, i int) byte {
+	return specValidator().Classes(countryCode)[i]
+}
+
+// WithIBANCheckDigits returns the IBAN of bban with correct mod-97 check
+// digits, whatever the BBAN holds. Tests mutate a BBAN and rebuild it with
+// this so only the national check digits can fail.
+func WithIBANCheckDigits(countryCode, bban string) string {
+	// The check digits make the rearranged IBAN, BBAN first, 1 mod 97
+	remainder := 0
+	for _, c := range []byte(bban + countryCode + "00") {
+		if c >= 'A' && c <= 'Z' {
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(c-'0')) % 97
+		}
+	}
+	return fmt.Sprintf("%s%02d%s", countryCode, 98-remainder, bban)
+}
+
+// NationalChecksumValid reports whether the national check digits of bban
+// are right, computed the way banks issue them. bban must match the
+// country's format; checked is false for countries without national check
+// digits.
+func NationalChecksumValid(countryCode, bban string) (valid, checked bool) {
+	if _, ok := nationalChecks[countryCode]; !ok {
+		return false, false
+	}
+	issued := []byte(bban)
+	return setNationalCheck(countryCode, issued) && string(issued) == bban, true
+}
+
+func randomOfClass(r *rand.Rand, class byte) byte {
+	switch class {
+	case iban.ClassDigit:
+		return '0' + byte(r.Intn(10))
+	case iban.ClassLetter:
+		return 'A' + byte(r.Intn(26))
+	default:
+		n := r.Intn(36)
+		if n < 10 {
+			return '0' + byte(n)
+		}
+		return 'A' + byte(n-10)
+	}
+}
+
+// nationalChecks set the national check digits of a BBAN in place and
+// report false when the rest of the BBAN has no valid check
+var nationalChecks = map[string]func(bban []byte) bool{
+	"BE": setBelgianCheck,
+	"ES": setSpanishCheck,
+	"FR": setFrenchKey,
+	"IT": setItalianCIN,
+	"NO": setNorwegianCheck,
+	"PT": setPortugueseCheck,
+}
+
+func setNationalCheck(countryCode string, bban []byte) bool {
+	set, ok := nationalChecks[countryCode]
+	return !ok || set(bban)
+}
+
+// remainder97 returns the value of decimal digits mod 97, with letters
+// counted through value
+func remainder97(s []byte, value func(c byte) int) int {
+	remainder := 0
+	for _, c := range s {
+		remainder = (remainder*10 + value(c)) % 97
+	}
+	return remainder
+}
+
+func digitOf(c byte) int {
+	return int(c - '0')
+}
+
+func putTwoDigits(b []byte, n int) {
+	b[0], b[1] = '0'+byte(n/10), '0'+byte(n%10)
+}
+
+// setBelgianCheck: bank (3) and account (7), then their number mod 97,
+// with 97 for 0
+func setBelgianCheck(bban []byte) bool {
+	check := remainder97(bban[:10], digitOf)
+	if check == 0 {
+		check = 97
+	}
+	putTwoDigits(bban[10:], check)
+	return true
+}
+
+// setSpanishCheck: bank (4), branch (4), DC (2) and account (10), each DC
+// digit 11 minus the weighted sum mod 11 of its part, 10 counting 1 and 11
+// counting 0
+func setSpanishCheck(bban []byte) bool {
+	control := func(part []byte) byte {
+		weights := []int{6, 3, 7, 9, 10, 5, 8, 4, 2, 1} // from the right
+		sum := 0
+		for i := range part {
+			sum += digitOf(part[len(part)-1-i]) * weights[i]
+		}
+		switch d := 11 - sum%11; d {
+		case 11:
+			return '0'
+		case 10:
+			return '1'
+		default:
+			return '0' + byte(d)
+		}
+	}
+	bban[8] = control(bban[:8])
+	bban[9] = control(bban[10:20])
+	return true
+}
+
+// setFrenchKey: bank (5), branch (5), account (11) and the clé RIB, 97
+// minus (89 × bank + 15 × branch + 3 × account) mod 97, account letters
+// counting as the digits of their RIB value
+func setFrenchKey(bban []byte) bool {
+	ribValue := func(c byte) int {
+		if c >= 'A' && c <= 'Z' {
+			n := int(c - 'A')
+			if n >= 18 { // S counts 2, not 1
+				n++
+			}
+			return n%9 + 1
+		}
+		return digitOf(c)
+	}
+	bank := remainder97(bban[:5], digitOf)
+	branch := remainder97(bban[5:10], digitOf)
+	account := remainder97(bban[10:21], ribValue)
+	putTwoDigits(bban[21:], 97-(89*bank+15*branch+3*account)%97)
+	return true
+}
+
+// italianOdd is the CIN value of a character in an odd position, indexed
+// by its digit or letter
+var italianOdd = [26]int{1, 0, 5, 7, 9, 13, 15, 17, 19, 21, 2, 4, 18, 20, 11, 3, 6, 8, 12, 14, 16, 10, 22, 25, 24, 23}
+
+// setItalianCIN: the CIN letter, then ABI (5), CAB (5) and account (12)
+// summed mod 26, odd positions through italianOdd
+func setItalianCIN(bban []byte) bool {
+	sum := 0
+	for pos, c := range bban[1:] {
+		index := int(c - '0')
+		if c >= 'A' {
+			index = int(c - 'A')
+		}
+		if pos%2 == 0 { // odd counting from 1
+			sum += italianOdd[index]
+		} else {
+			sum += index
+		}
+	}
+	bban[0] = 'A' + byte(sum%26)
+	return true
+}
+
+// setNorwegianCheck: the last of 11 digits is MOD11 over the first ten;
+// a check of 10 is never issued
+func setNorwegianCheck(bban []byte) bool {
+	weights := []int{2, 3, 4, 5, 6, 7} // cycled from the right
+	sum := 0
+	for i := 0; i < 10; i++ {
+		sum += digitOf(bban[9-i]) * weights[i%len(weights)]
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return false
+	}
+	bban[10] = '0' + byte(check)
+	return true
+}
+
+// setPortugueseCheck: bank (4), branch (4), account (11) and the NIB
+// check, 98 minus their number times 100 mod 97
+func setPortugueseCheck(bban []byte) bool {
+	putTwoDigits(bban[19:], 98-remainder97(bban[:19], digitOf)*100%97)
+	return true
+}
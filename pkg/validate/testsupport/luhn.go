@@ -0,0 +1,37 @@
+package testsupport
+
+import "math/rand"
+
+// Luhn returns a random number of length digits starting with prefix
+// whose last digit is its Luhn (mod 10) check digit, e.g. a payment card
+// number with the issuer's prefix
+func Luhn(r *rand.Rand, prefix string, length int) string {
+	b := append([]byte(prefix), digits(r, length-len(prefix))...)
+	b[length-1] = '0'
+	if sum := luhnSum(b); sum%10 != 0 {
+		b[length-1] = '0' + byte(10-sum%10)
+	}
+	return string(b)
+}
+
+// LuhnValid reports whether s, at least two digits, passes the Luhn check.
+// It is the reference the property tests hold card number validation to.
+func LuhnValid(s string) bool {
+	return len(s) >= 2 && allDigits(s) && luhnSum([]byte(s))%10 == 0
+}
+
+// luhnSum adds the digits of number, every second one from the right
+// doubled with its digits summed
+func luhnSum(number []byte) int {
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		d := digitOf(number[i])
+		if (len(number)-1-i)%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum
+}
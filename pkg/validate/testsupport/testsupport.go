@@ -0,0 +1,37 @@
+// Package testsupport generates valid identifiers for property tests of
+// the validate and generate packages: IBANs from the country specs, GTINs
+// for UPC-A and EAN-13, and Luhn numbers. Its check digits are computed
+// independently of the packages under test, so a generated value that
+// fails validation points at a bug on one side or the other.
+//
+// RunCorpus checks a corpus of known-good and known-bad values, one per
+// line, so callers can run their own data against the validators.
+package testsupport
+
+import (
+	"math/rand"
+)
+
+// Substitute returns s with the character at i replaced by a different
+// one of the same kind, digit for digit and letter for letter, so a value
+// keeps its format while its check digits break. s must be ASCII digits
+// and upper-case letters.
+func Substitute(r *rand.Rand, s string, i int) string {
+	b := []byte(s)
+	switch c := b[i]; {
+	case c >= '0' && c <= '9':
+		b[i] = '0' + byte((int(c-'0')+1+r.Intn(9))%10)
+	default:
+		b[i] = 'A' + byte((int(c-'A')+1+r.Intn(25))%26)
+	}
+	return string(b)
+}
+
+// digits returns n random decimal digits
+func digits(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = '0' + byte(r.Intn(10))
+	}
+	return b
+}
@@ -0,0 +1,73 @@
+package testsupport_test
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/innovelabs/microtools-go/pkg/validate/testsupport"
+)
+
+// quickConfig bounds each property to a few hundred cases, fewer with
+// -short
+func quickConfig() *quick.Config {
+	if testing.Short() {
+		return &quick.Config{MaxCount: 20}
+	}
+	return &quick.Config{MaxCount: 300}
+}
+
+func TestLuhnProperties(t *testing.T) {
+	generated := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		return testsupport.LuhnValid(testsupport.Luhn(r, "4", 16))
+	}
+	if err := quick.Check(generated, quickConfig()); err != nil {
+		t.Errorf("generated number fails the Luhn check: %v", err)
+	}
+
+	// The Luhn check catches every single digit error
+	substituted := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		number := testsupport.Luhn(r, "", 8+r.Intn(12))
+		return !testsupport.LuhnValid(testsupport.Substitute(r, number, r.Intn(len(number))))
+	}
+	if err := quick.Check(substituted, quickConfig()); err != nil {
+		t.Errorf("number with a substituted digit passes the Luhn check: %v", err)
+	}
+}
+
+func TestGTINProperties(t *testing.T) {
+	substituted := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		gtin := testsupport.GTIN(r, testsupport.EAN13Length)
+		return testsupport.GTINValid(gtin) &&
+			!testsupport.GTINValid(testsupport.Substitute(r, gtin, r.Intn(len(gtin))))
+	}
+	if err := quick.Check(substituted, quickConfig()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNationalChecksumValid(t *testing.T) {
+	tests := []struct {
+		country, bban  string
+		valid, checked bool
+	}{
+		{"BE", "539007547034", true, true},
+		{"ES", "21000418450200051332", true, true},
+		{"FR", "20041010050500013M02606", true, true},
+		{"IT", "X0542811101000000123456", true, true},
+		{"NO", "86011117947", true, true},
+		{"NO", "00000000060", false, true},
+		{"PT", "000201231234567890154", true, true},
+		{"PT", "000201231234567890155", false, true},
+		{"DE", "370400440532013000", false, false},
+	}
+	for _, tt := range tests {
+		valid, checked := testsupport.NationalChecksumValid(tt.country, tt.bban)
+		if valid != tt.valid || checked != tt.checked {
+			t.Errorf("NationalChecksumValid(%s, %s) = %t, %t, want %t, %t", tt.country, tt.bban, valid, checked, tt.valid, tt.checked)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+// Package web embeds the static assets served under /static/.
+package web
+
+import "embed"
+
+// Static holds the stylesheets and scripts shared by the tool pages
+//
+//go:embed static
+var Static embed.FS